@@ -13,7 +13,9 @@ import (
 	"github.com/hashicorp/vault/api"
 	"github.com/hashicorp/vault/audit"
 	"github.com/hashicorp/vault/builtin/logical/database"
+	"github.com/hashicorp/vault/builtin/logical/kv"
 	"github.com/hashicorp/vault/builtin/logical/pki"
+	"github.com/hashicorp/vault/builtin/logical/ssh"
 	"github.com/hashicorp/vault/builtin/logical/transit"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/vault"
@@ -52,7 +54,9 @@ func testVaultServerUnseal(t testing.TB) (*api.Client, []string, func()) {
 		LogicalBackends: map[string]logical.Factory{
 			"database":       database.Factory,
 			"generic-leased": vault.LeasedPassthroughBackendFactory,
+			"kv-v2":          kv.Factory,
 			"pki":            pki.Factory,
+			"ssh":            ssh.Factory,
 			"transit":        transit.Factory,
 		},
 	})
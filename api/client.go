@@ -1,8 +1,12 @@
 package api
 
 import (
+	"bytes"
+	"context"
 	"crypto/tls"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"net/url"
@@ -18,7 +22,6 @@ import (
 	"github.com/hashicorp/go-cleanhttp"
 	"github.com/hashicorp/go-rootcerts"
 	"github.com/hashicorp/vault/helper/parseutil"
-	"github.com/sethgrid/pester"
 	"golang.org/x/net/http2"
 )
 
@@ -34,6 +37,7 @@ const EnvVaultWrapTTL = "VAULT_WRAP_TTL"
 const EnvVaultMaxRetries = "VAULT_MAX_RETRIES"
 const EnvVaultToken = "VAULT_TOKEN"
 const EnvVaultMFA = "VAULT_MFA"
+const EnvVaultNamespace = "VAULT_NAMESPACE"
 
 // WrappingLookupFunc is a function that, given an HTTP verb and a path,
 // returns an optional string duration to be used for response wrapping (e.g.
@@ -42,6 +46,56 @@ const EnvVaultMFA = "VAULT_MFA"
 // called path precisely.
 type WrappingLookupFunc func(operation, path string) string
 
+const (
+	// DefaultMinRetryWait is the default minimum wait between retries
+	DefaultMinRetryWait = 1 * time.Second
+
+	// DefaultMaxRetryWait is the default maximum wait between retries
+	DefaultMaxRetryWait = 30 * time.Second
+)
+
+// CheckRetryFunc is called after each attempt of a request with its response
+// or error, and returns whether the request should be retried. If it returns
+// an error, that error is returned in place of the error of the attempt.
+type CheckRetryFunc func(ctx context.Context, resp *http.Response, err error) (bool, error)
+
+// BackoffFunc returns the wait before the retry following the given attempt,
+// numbered from 0, given its response if any
+type BackoffFunc func(min, max time.Duration, attempt int, resp *http.Response) time.Duration
+
+// DefaultRetryPolicy retries the requests which failed with an error, a 5xx
+// status code, or a 429 status code returned by the rate limit quotas. The
+// requests whose context is done are not retried.
+func DefaultRetryPolicy(ctx context.Context, resp *http.Response, err error) (bool, error) {
+	if ctx.Err() != nil {
+		return false, ctx.Err()
+	}
+	if err != nil {
+		return true, nil
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500, nil
+}
+
+// DefaultBackoff waits for the delay of the Retry-After header of the 429 and
+// 503 responses, if any. Otherwise, the wait doubles from min with each
+// attempt, up to max.
+func DefaultBackoff(min, max time.Duration, attempt int, resp *http.Response) time.Duration {
+	if resp != nil && (resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable) {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+			return time.Duration(seconds) * time.Second
+		}
+	}
+
+	wait := min
+	for i := 0; i < attempt && wait < max; i++ {
+		wait *= 2
+	}
+	if wait > max {
+		wait = max
+	}
+	return wait
+}
+
 // Config is used to configure the creation of the client.
 type Config struct {
 	modifyLock sync.RWMutex
@@ -49,7 +103,8 @@ type Config struct {
 	// Address is the address of the Vault server. This should be a complete
 	// URL such as "http://vault.example.com". If you need a custom SSL
 	// cert or want to enable insecure mode, you need to specify a custom
-	// HttpClient.
+	// HttpClient. The address of a unix socket listener is the path of the
+	// socket, e.g. "unix:///var/run/vault.sock".
 	Address string
 
 	// HttpClient is the HTTP client to use. Vault sets sane defaults for the
@@ -59,10 +114,27 @@ type Config struct {
 	// (or http.DefaultClient).
 	HttpClient *http.Client
 
-	// MaxRetries controls the maximum number of times to retry when a 5xx error
-	// occurs. Set to 0 or less to disable retrying. Defaults to 0.
+	// MaxRetries controls the maximum number of times to retry a request
+	// which the CheckRetry policy deems retryable, by default when an error,
+	// a 5xx or a 429 status code occurs. Set to 0 or less to disable retrying.
+	// Defaults to 0.
 	MaxRetries int
 
+	// MinRetryWait and MaxRetryWait bound the wait between the retries
+	// computed by Backoff. They default to DefaultMinRetryWait and
+	// DefaultMaxRetryWait.
+	MinRetryWait time.Duration
+	MaxRetryWait time.Duration
+
+	// CheckRetry decides whether a request is retried. Defaults to
+	// DefaultRetryPolicy.
+	CheckRetry CheckRetryFunc
+
+	// Backoff computes the wait before a retry. Defaults to DefaultBackoff,
+	// which honors the Retry-After header sent by the rate limit quotas of
+	// Vault.
+	Backoff BackoffFunc
+
 	// Timeout is for setting custom timeout parameter in the HttpClient
 	Timeout time.Duration
 
@@ -131,9 +203,9 @@ func DefaultConfig() *Config {
 	// but in e.g. http_test actual redirect handling is necessary
 	config.HttpClient.CheckRedirect = func(req *http.Request, via []*http.Request) error {
 		// Returning this value causes the Go net library to not close the
-		// response body and to nil out the error. Otherwise pester tries
-		// three times on every redirect because it sees an error from this
-		// function (to prevent redirects) passing through to it.
+		// response body and to nil out the error. Otherwise the request is
+		// retried on every redirect because of the error returned by this
+		// function (to prevent redirects).
 		return http.ErrUseLastResponse
 	}
 
@@ -269,7 +341,7 @@ func (c *Config) ReadEnvironment() error {
 	}
 
 	if envMaxRetries != nil {
-		c.MaxRetries = int(*envMaxRetries) + 1
+		c.MaxRetries = int(*envMaxRetries)
 	}
 
 	if envClientTimeout != 0 {
@@ -283,12 +355,26 @@ func (c *Config) ReadEnvironment() error {
 type Client struct {
 	modifyLock         sync.RWMutex
 	addr               *url.URL
+	socket             string
 	config             *Config
 	token              string
 	headers            http.Header
 	wrappingLookupFunc WrappingLookupFunc
 	mfaCreds           []string
 	policyOverride     bool
+	namespace          string
+	ctx                context.Context
+
+	// indexState records the last index state returned to the client when
+	// it reads its own writes, nil otherwise
+	indexState *indexState
+}
+
+// indexState holds the index state of the last write of a client, which is
+// shared with the copies of the client
+type indexState struct {
+	l     sync.Mutex
+	state string
 }
 
 // NewClient returns a new client for the given configuration.
@@ -315,11 +401,6 @@ func NewClient(c *Config) (*Client, error) {
 	c.modifyLock.Lock()
 	defer c.modifyLock.Unlock()
 
-	u, err := url.Parse(c.Address)
-	if err != nil {
-		return nil, err
-	}
-
 	if c.HttpClient == nil {
 		c.HttpClient = def.HttpClient
 	}
@@ -327,8 +408,14 @@ func NewClient(c *Config) (*Client, error) {
 		c.HttpClient.Transport = def.HttpClient.Transport
 	}
 
+	u, socket, err := c.parseAddress(c.Address)
+	if err != nil {
+		return nil, err
+	}
+
 	client := &Client{
 		addr:   u,
+		socket: socket,
 		config: c,
 	}
 
@@ -336,6 +423,10 @@ func NewClient(c *Config) (*Client, error) {
 		client.token = token
 	}
 
+	if namespace := os.Getenv(EnvVaultNamespace); namespace != "" {
+		client.namespace = namespace
+	}
+
 	return client, nil
 }
 
@@ -347,7 +438,7 @@ func (c *Client) SetAddress(addr string) error {
 	defer c.modifyLock.Unlock()
 
 	var err error
-	if c.addr, err = url.Parse(addr); err != nil {
+	if c.addr, c.socket, err = c.config.parseAddress(addr); err != nil {
 		return fmt.Errorf("failed to set address: %v", err)
 	}
 
@@ -359,9 +450,41 @@ func (c *Client) Address() string {
 	c.modifyLock.RLock()
 	defer c.modifyLock.RUnlock()
 
+	if c.socket != "" {
+		return "unix://" + c.socket
+	}
 	return c.addr.String()
 }
 
+// parseAddress parses the address of Vault. If it is the address of a unix
+// socket, the transport of the HTTP client is configured to dial the socket,
+// and the returned URL is the URL of the requests made over it along with the
+// path of the socket.
+func (c *Config) parseAddress(addr string) (*url.URL, string, error) {
+	u, err := url.Parse(addr)
+	if err != nil {
+		return nil, "", err
+	}
+	if u.Scheme != "unix" {
+		return u, "", nil
+	}
+
+	socket := u.Path
+	if socket == "" {
+		return nil, "", fmt.Errorf("missing path of the unix socket in address %q", addr)
+	}
+	transport, ok := c.HttpClient.Transport.(*http.Transport)
+	if !ok {
+		return nil, "", fmt.Errorf("unix socket addresses require an *http.Transport")
+	}
+	transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, "unix", socket)
+	}
+
+	return &url.URL{Scheme: "http", Host: "localhost"}, socket, nil
+}
+
 // SetMaxRetries sets the number of retries that will be used in the case of certain errors
 func (c *Client) SetMaxRetries(retries int) {
 	c.modifyLock.RLock()
@@ -372,6 +495,37 @@ func (c *Client) SetMaxRetries(retries int) {
 	c.config.MaxRetries = retries
 }
 
+// SetRetryWait sets the bounds of the wait between retries
+func (c *Client) SetRetryWait(min, max time.Duration) {
+	c.modifyLock.RLock()
+	c.config.modifyLock.Lock()
+	defer c.config.modifyLock.Unlock()
+	c.modifyLock.RUnlock()
+
+	c.config.MinRetryWait = min
+	c.config.MaxRetryWait = max
+}
+
+// SetCheckRetry sets the policy deciding whether a request is retried
+func (c *Client) SetCheckRetry(checkRetry CheckRetryFunc) {
+	c.modifyLock.RLock()
+	c.config.modifyLock.Lock()
+	defer c.config.modifyLock.Unlock()
+	c.modifyLock.RUnlock()
+
+	c.config.CheckRetry = checkRetry
+}
+
+// SetBackoff sets the function computing the wait before a retry
+func (c *Client) SetBackoff(backoff BackoffFunc) {
+	c.modifyLock.RLock()
+	c.config.modifyLock.Lock()
+	defer c.config.modifyLock.Unlock()
+	c.modifyLock.RUnlock()
+
+	c.config.Backoff = backoff
+}
+
 // SetClientTimeout sets the client request timeout
 func (c *Client) SetClientTimeout(timeout time.Duration) {
 	c.modifyLock.RLock()
@@ -400,6 +554,25 @@ func (c *Client) SetMFACreds(creds []string) {
 	c.mfaCreds = creds
 }
 
+// Namespace returns the namespace the requests of this client are made in. It
+// will return the empty string for the root namespace.
+func (c *Client) Namespace() string {
+	c.modifyLock.RLock()
+	defer c.modifyLock.RUnlock()
+
+	return c.namespace
+}
+
+// SetNamespace sets the namespace the requests of this client are made in,
+// which is sent in the X-Vault-Namespace header. Setting this on a client will
+// override the value of the VAULT_NAMESPACE environment variable.
+func (c *Client) SetNamespace(namespace string) {
+	c.modifyLock.Lock()
+	defer c.modifyLock.Unlock()
+
+	c.namespace = namespace
+}
+
 // Token returns the access token being used by this client. It will
 // return the empty string if there is no token set.
 func (c *Client) Token() string {
@@ -445,16 +618,54 @@ func (c *Client) Clone() (*Client, error) {
 	c.modifyLock.RUnlock()
 
 	newConfig := &Config{
-		Address:    config.Address,
-		HttpClient: config.HttpClient,
-		MaxRetries: config.MaxRetries,
-		Timeout:    config.Timeout,
+		Address:      config.Address,
+		HttpClient:   config.HttpClient,
+		MaxRetries:   config.MaxRetries,
+		MinRetryWait: config.MinRetryWait,
+		MaxRetryWait: config.MaxRetryWait,
+		CheckRetry:   config.CheckRetry,
+		Backoff:      config.Backoff,
+		Timeout:      config.Timeout,
 	}
 	config.modifyLock.RUnlock()
 
 	return NewClient(newConfig)
 }
 
+// WithContext returns a copy of the client whose requests are made with the
+// given context: the requests, and the waits between their retries, are
+// canceled with the context. The copy shares the configuration of the client.
+func (c *Client) WithContext(ctx context.Context) *Client {
+	c.modifyLock.RLock()
+	defer c.modifyLock.RUnlock()
+
+	return &Client{
+		addr:               c.addr,
+		socket:             c.socket,
+		config:             c.config,
+		token:              c.token,
+		headers:            c.headers,
+		wrappingLookupFunc: c.wrappingLookupFunc,
+		mfaCreds:           c.mfaCreds,
+		policyOverride:     c.policyOverride,
+		namespace:          c.namespace,
+		ctx:                ctx,
+		indexState:         c.indexState,
+	}
+}
+
+// Context returns the context of the requests of the client, which is the
+// background context unless the client was returned by WithContext
+func (c *Client) Context() context.Context {
+	c.modifyLock.RLock()
+	defer c.modifyLock.RUnlock()
+
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
+}
+
 // SetPolicyOverride sets whether requests should be sent with the policy
 // override flag to request overriding soft-mandatory Sentinel policies (both
 // RGPs and EGPs)
@@ -465,6 +676,30 @@ func (c *Client) SetPolicyOverride(override bool) {
 	c.policyOverride = override
 }
 
+// SetReadYourWrites sets whether the client reads its own writes. If set, the
+// client records the index state returned by the server on its writes and
+// sends the last one with its requests, so that the server waits for an
+// eventually consistent storage backend to reflect the writes before reading.
+func (c *Client) SetReadYourWrites(enabled bool) {
+	c.modifyLock.Lock()
+	defer c.modifyLock.Unlock()
+
+	switch {
+	case !enabled:
+		c.indexState = nil
+	case c.indexState == nil:
+		c.indexState = &indexState{}
+	}
+}
+
+// ReadYourWrites returns whether the client reads its own writes
+func (c *Client) ReadYourWrites() bool {
+	c.modifyLock.RLock()
+	defer c.modifyLock.RUnlock()
+
+	return c.indexState != nil
+}
+
 // NewRequest creates a new raw request object to query the Vault server
 // configured for this client. This is an advanced method and generally
 // doesn't need to be called externally.
@@ -475,7 +710,7 @@ func (c *Client) NewRequest(method, requestPath string) *Request {
 	// if SRV records exist (see https://tools.ietf.org/html/draft-andrews-http-srv-02), lookup the SRV
 	// record and take the highest match; this is not designed for high-availability, just discovery
 	var host string = c.addr.Host
-	if c.addr.Port() == "" {
+	if c.addr.Port() == "" && c.socket == "" {
 		// Internet Draft specifies that the SRV record is ignored if a port is given
 		_, addrs, err := net.LookupSRV("http", "tcp", c.addr.Hostname())
 		if err == nil && len(addrs) > 0 {
@@ -520,6 +755,13 @@ func (c *Client) NewRequest(method, requestPath string) *Request {
 	}
 
 	req.PolicyOverride = c.policyOverride
+	req.Namespace = c.namespace
+
+	if c.indexState != nil {
+		c.indexState.l.Lock()
+		req.IndexState = c.indexState.state
+		c.indexState.l.Unlock()
+	}
 
 	return req
 }
@@ -528,12 +770,36 @@ func (c *Client) NewRequest(method, requestPath string) *Request {
 // a Vault server not configured with this client. This is an advanced operation
 // that generally won't need to be called externally.
 func (c *Client) RawRequest(r *Request) (*Response, error) {
+	return c.RawRequestWithContext(c.Context(), r)
+}
+
+// RawRequestWithContext performs the raw request given with the context
+// given, retrying it according to the retry policy of the client.
+func (c *Client) RawRequestWithContext(ctx context.Context, r *Request) (*Response, error) {
 	c.modifyLock.RLock()
 	c.config.modifyLock.RLock()
 	defer c.config.modifyLock.RUnlock()
 	token := c.token
+	indexState := c.indexState
 	c.modifyLock.RUnlock()
 
+	checkRetry := c.config.CheckRetry
+	if checkRetry == nil {
+		checkRetry = DefaultRetryPolicy
+	}
+	backoff := c.config.Backoff
+	if backoff == nil {
+		backoff = DefaultBackoff
+	}
+	minRetryWait := c.config.MinRetryWait
+	if minRetryWait <= 0 {
+		minRetryWait = DefaultMinRetryWait
+	}
+	maxRetryWait := c.config.MaxRetryWait
+	if maxRetryWait <= 0 {
+		maxRetryWait = DefaultMaxRetryWait
+	}
+
 	// Sanity check the token before potentially erroring from the API
 	idx := strings.IndexFunc(token, func(c rune) bool {
 		return !unicode.IsPrint(c)
@@ -542,21 +808,65 @@ func (c *Client) RawRequest(r *Request) (*Response, error) {
 		return nil, fmt.Errorf("Configured Vault token contains non-printable characters and cannot be used.")
 	}
 
+	// Buffer the body so that it can be sent again by the retries and the
+	// redirect
+	var body []byte
+	if r.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(r.Body)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	redirectCount := 0
 START:
-	req, err := r.ToHTTP()
-	if err != nil {
-		return nil, err
-	}
+	var req *http.Request
+	var resp *http.Response
+	var err error
+	for attempt := 0; ; attempt++ {
+		if body != nil {
+			r.Body = bytes.NewReader(body)
+		}
+		req, err = r.ToHTTP()
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err = c.config.HttpClient.Do(req.WithContext(ctx))
+		retry, checkErr := checkRetry(ctx, resp, err)
+		if checkErr != nil {
+			err = checkErr
+		}
+		if !retry || attempt >= c.config.MaxRetries {
+			break
+		}
+
+		// Free the connection of the response before waiting
+		if resp != nil {
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
 
-	client := pester.NewExtendedClient(c.config.HttpClient)
-	client.Backoff = pester.LinearJitterBackoff
-	client.MaxRetries = c.config.MaxRetries
+		timer := time.NewTimer(backoff(minRetryWait, maxRetryWait, attempt, resp))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
 
 	var result *Response
-	resp, err := client.Do(req)
 	if resp != nil {
 		result = &Response{Response: resp}
+
+		// Record the index state of the write for the next requests
+		if state := resp.Header.Get("X-Vault-Index"); indexState != nil && state != "" {
+			indexState.l.Lock()
+			indexState.state = state
+			indexState.l.Unlock()
+		}
 	}
 	if err != nil {
 		if strings.Contains(err.Error(), "tls: oversized") {
@@ -591,11 +901,6 @@ START:
 		// Update the request
 		r.URL = respLoc
 
-		// Reset the request body if any
-		if err := r.ResetJSONBody(); err != nil {
-			return result, err
-		}
-
 		// Retry the request
 		redirectCount++
 		goto START
@@ -2,9 +2,13 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"io"
+	"io/ioutil"
+	"net"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -70,6 +74,53 @@ func TestClientSetAddress(t *testing.T) {
 	}
 }
 
+func TestClientUnixSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vault-api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	socket := filepath.Join(dir, "vault.sock")
+	ln, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	handler := func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(req.URL.Path))
+	}
+	go (&http.Server{Handler: http.HandlerFunc(handler)}).Serve(ln)
+
+	config := DefaultConfig()
+	config.Address = "unix://" + socket
+	client, err := NewClient(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addr := client.Address(); addr != "unix://"+socket {
+		t.Fatalf("bad: %s", addr)
+	}
+
+	resp, err := client.RawRequest(client.NewRequest("GET", "/v1/sys/health"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != "/v1/sys/health" {
+		t.Fatalf("bad: %s", body)
+	}
+
+	if err := client.SetAddress("unix://"); err == nil {
+		t.Fatal("expected error without socket path")
+	}
+}
+
 func TestClientToken(t *testing.T) {
 	tokenValue := "foo"
 	handler := func(w http.ResponseWriter, req *http.Request) {}
@@ -158,6 +209,114 @@ func TestClientRedirect(t *testing.T) {
 	}
 }
 
+func TestClientRetry(t *testing.T) {
+	var attempts int
+	var bodies []string
+	handler := func(w http.ResponseWriter, req *http.Request) {
+		attempts++
+		body, _ := ioutil.ReadAll(req.Body)
+		bodies = append(bodies, string(body))
+		switch attempts {
+		case 1:
+			w.WriteHeader(500)
+		case 2:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(429)
+		default:
+			w.Write([]byte("test"))
+		}
+	}
+	config, ln := testHTTPServer(t, http.HandlerFunc(handler))
+	defer ln.Close()
+
+	client, err := NewClient(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetRetryWait(time.Millisecond, time.Millisecond)
+
+	// Retrying is disabled by default
+	req := client.NewRequest("PUT", "/")
+	req.SetJSONBody(map[string]string{"foo": "bar"})
+	if _, err := client.RawRequest(req); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	// The body is sent again by each retry, and the 429 responses are retried
+	attempts = 0
+	bodies = nil
+	client.SetMaxRetries(2)
+	req = client.NewRequest("PUT", "/")
+	req.SetJSONBody(map[string]string{"foo": "bar"})
+	resp, err := client.RawRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if attempts != 3 || len(bodies) != 3 || bodies[2] != bodies[0] || !strings.Contains(bodies[0], "bar") {
+		t.Fatalf("bad: %d %#v", attempts, bodies)
+	}
+
+	// The retry policy decides which requests are retried
+	attempts = 0
+	client.SetCheckRetry(func(ctx context.Context, resp *http.Response, err error) (bool, error) {
+		return false, nil
+	})
+	if _, err := client.RawRequest(client.NewRequest("GET", "/")); err == nil || attempts != 1 {
+		t.Fatalf("expected a single attempt, got %d: %v", attempts, err)
+	}
+}
+
+func TestClientRetryContext(t *testing.T) {
+	handler := func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(429)
+	}
+	config, ln := testHTTPServer(t, http.HandlerFunc(handler))
+	defer ln.Close()
+
+	client, err := NewClient(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetMaxRetries(5)
+
+	// The wait for the Retry-After delay is canceled with the context
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = client.WithContext(ctx).Logical().Read("secret/foo")
+	if err != context.DeadlineExceeded {
+		t.Fatalf("expected the deadline to be exceeded, got %v", err)
+	}
+	if time.Since(start) > 10*time.Second {
+		t.Fatal("the retry was not canceled")
+	}
+	if client.Context() != context.Background() {
+		t.Fatal("expected the context of the client to be unchanged")
+	}
+}
+
+func TestDefaultBackoff(t *testing.T) {
+	cases := []struct {
+		attempt int
+		resp    *http.Response
+		wait    time.Duration
+	}{
+		{0, nil, time.Second},
+		{2, nil, 4 * time.Second},
+		{10, nil, 30 * time.Second},
+		{0, &http.Response{StatusCode: 500, Header: http.Header{"Retry-After": []string{"5"}}}, time.Second},
+		{0, &http.Response{StatusCode: 429, Header: http.Header{"Retry-After": []string{"5"}}}, 5 * time.Second},
+		{0, &http.Response{StatusCode: 503, Header: http.Header{"Retry-After": []string{"bad"}}}, time.Second},
+	}
+	for i, tc := range cases {
+		if wait := DefaultBackoff(DefaultMinRetryWait, DefaultMaxRetryWait, tc.attempt, tc.resp); wait != tc.wait {
+			t.Fatalf("%d: expected %s, got %s", i, tc.wait, wait)
+		}
+	}
+}
+
 func TestClientEnvSettings(t *testing.T) {
 	cwd, _ := os.Getwd()
 	oldCACert := os.Getenv(EnvVaultCACert)
@@ -194,6 +353,9 @@ func TestClientEnvSettings(t *testing.T) {
 	if tlsConfig.InsecureSkipVerify != true {
 		t.Fatalf("bad: %v", tlsConfig.InsecureSkipVerify)
 	}
+	if config.MaxRetries != 5 {
+		t.Fatalf("bad: %d", config.MaxRetries)
+	}
 }
 
 func TestClientTimeoutSetting(t *testing.T) {
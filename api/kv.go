@@ -0,0 +1,249 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/helper/parseutil"
+)
+
+// ErrSecretNotFound is returned by the key/value helpers when no secret, or no
+// version of a secret, exists at the path
+var ErrSecretNotFound = errors.New("secret not found")
+
+// KVv1 is used to perform operations on the secrets of a key/value secrets
+// engine, version 1.
+type KVv1 struct {
+	c         *Client
+	mountPath string
+}
+
+// KVv2 is used to perform operations on the secrets of a versioned key/value
+// secrets engine.
+type KVv2 struct {
+	c         *Client
+	mountPath string
+}
+
+// KVv1 returns the client for the key/value secrets engine mounted at the
+// mount path.
+func (c *Client) KVv1(mountPath string) *KVv1 {
+	return &KVv1{
+		c:         c,
+		mountPath: strings.Trim(mountPath, "/"),
+	}
+}
+
+// KVv2 returns the client for the versioned key/value secrets engine mounted
+// at the mount path.
+func (c *Client) KVv2(mountPath string) *KVv2 {
+	return &KVv2{
+		c:         c,
+		mountPath: strings.Trim(mountPath, "/"),
+	}
+}
+
+// KVSecret is a secret read from a key/value secrets engine
+type KVSecret struct {
+	// Data is the data of the secret. It is nil for the versions which were
+	// deleted or destroyed.
+	Data map[string]interface{}
+
+	// VersionMetadata is the metadata of the version of the secret read from
+	// a versioned engine, nil otherwise
+	VersionMetadata *KVVersionMetadata
+
+	// Raw is the response of Vault
+	Raw *Secret
+}
+
+// KVVersionMetadata is the metadata of a version of a secret of the versioned
+// key/value secrets engine
+type KVVersionMetadata struct {
+	Version      int
+	CreatedTime  time.Time
+	DeletionTime time.Time
+	Destroyed    bool
+}
+
+// KVOption is an option of the writes of the versioned key/value secrets
+// engine
+type KVOption func(options map[string]interface{})
+
+// WithCheckAndSet makes the write succeed only if the current version of the
+// secret is the given version, zero meaning that the secret must not exist
+func WithCheckAndSet(version int) KVOption {
+	return func(options map[string]interface{}) {
+		options["cas"] = version
+	}
+}
+
+// Get returns the secret at the path, or ErrSecretNotFound.
+func (kv *KVv1) Get(path string) (*KVSecret, error) {
+	secret, err := kv.c.Logical().Read(kv.mountPath + "/" + path)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil {
+		return nil, ErrSecretNotFound
+	}
+
+	return &KVSecret{
+		Data: secret.Data,
+		Raw:  secret,
+	}, nil
+}
+
+// Put writes the data of the secret at the path, replacing its previous data.
+// The engine does not support patches, which need the versioned engine.
+func (kv *KVv1) Put(path string, data map[string]interface{}) error {
+	_, err := kv.c.Logical().Write(kv.mountPath+"/"+path, data)
+	return err
+}
+
+// Delete deletes the secret at the path.
+func (kv *KVv1) Delete(path string) error {
+	_, err := kv.c.Logical().Delete(kv.mountPath + "/" + path)
+	return err
+}
+
+// Get returns the current version of the secret at the path, or
+// ErrSecretNotFound.
+func (kv *KVv2) Get(path string) (*KVSecret, error) {
+	return kv.GetVersion(path, 0)
+}
+
+// GetVersion returns the given version of the secret at the path, or
+// ErrSecretNotFound. The version 0 is the current version.
+func (kv *KVv2) GetVersion(path string, version int) (*KVSecret, error) {
+	r := kv.c.NewRequest("GET", "/v1/"+kv.dataPath(path))
+	if version != 0 {
+		r.Params.Set("version", fmt.Sprintf("%d", version))
+	}
+
+	resp, err := kv.c.RawRequest(r)
+	if resp != nil {
+		defer resp.Body.Close()
+	}
+	if resp != nil && resp.StatusCode == 404 {
+		return nil, ErrSecretNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := ParseSecret(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, ErrSecretNotFound
+	}
+
+	result := &KVSecret{
+		Raw: secret,
+	}
+	if data, ok := secret.Data["data"].(map[string]interface{}); ok {
+		result.Data = data
+	}
+	result.VersionMetadata, err = parseKVVersionMetadata(secret.Data["metadata"])
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Put writes the data as a new version of the secret at the path, returning
+// the metadata of the version.
+func (kv *KVv2) Put(path string, data map[string]interface{}, opts ...KVOption) (*KVVersionMetadata, error) {
+	secret, err := kv.c.Logical().Write(kv.dataPath(path), kv.body(data, opts))
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil {
+		return nil, fmt.Errorf("no metadata returned for the write of %q", path)
+	}
+	return parseKVVersionMetadata(secret.Data)
+}
+
+// Patch applies the data as a JSON merge patch (RFC 7386) to the current
+// version of the secret at the path, writing the result as a new version, and
+// returns the metadata of the version. It returns ErrSecretNotFound if the
+// secret does not exist or its current version was deleted.
+func (kv *KVv2) Patch(path string, data map[string]interface{}, opts ...KVOption) (*KVVersionMetadata, error) {
+	secret, err := kv.c.Logical().JSONMergePatch(kv.dataPath(path), kv.body(data, opts))
+	if err != nil {
+		return nil, err
+	}
+
+	// The patches of missing secrets return no content
+	if secret == nil {
+		return nil, ErrSecretNotFound
+	}
+	return parseKVVersionMetadata(secret.Data)
+}
+
+// Delete soft deletes the current version of the secret at the path, which
+// can be restored with an undelete.
+func (kv *KVv2) Delete(path string) error {
+	_, err := kv.c.Logical().Delete(kv.dataPath(path))
+	return err
+}
+
+func (kv *KVv2) dataPath(path string) string {
+	return kv.mountPath + "/data/" + path
+}
+
+// body returns the body of a write of the data with the options
+func (kv *KVv2) body(data map[string]interface{}, opts []KVOption) map[string]interface{} {
+	body := map[string]interface{}{
+		"data": data,
+	}
+	if len(opts) > 0 {
+		options := make(map[string]interface{}, len(opts))
+		for _, opt := range opts {
+			opt(options)
+		}
+		body["options"] = options
+	}
+	return body
+}
+
+// parseKVVersionMetadata parses the metadata of a version returned by the
+// versioned key/value secrets engine
+func parseKVVersionMetadata(raw interface{}) (*KVVersionMetadata, error) {
+	m, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("version metadata missing from server response")
+	}
+
+	version, err := parseutil.ParseInt(m["version"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid version: %v", err)
+	}
+	destroyed, err := parseutil.ParseBool(m["destroyed"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid destroyed: %v", err)
+	}
+	metadata := &KVVersionMetadata{
+		Version:   int(version),
+		Destroyed: destroyed,
+	}
+
+	for field, t := range map[string]*time.Time{
+		"created_time":  &metadata.CreatedTime,
+		"deletion_time": &metadata.DeletionTime,
+	} {
+		value, _ := m[field].(string)
+		if value == "" {
+			continue
+		}
+		*t, err = time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", field, err)
+		}
+	}
+	return metadata, nil
+}
@@ -0,0 +1,103 @@
+package api_test
+
+import (
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+)
+
+func TestKVHelpers(t *testing.T) {
+	client, closer := testVaultServer(t)
+	defer closer()
+
+	t.Run("v1", func(t *testing.T) {
+		kv := client.KVv1("secret")
+		if _, err := kv.Get("foo"); err != api.ErrSecretNotFound {
+			t.Fatalf("expected not found, got %v", err)
+		}
+		if err := kv.Put("foo", map[string]interface{}{"bar": "baz"}); err != nil {
+			t.Fatal(err)
+		}
+		secret, err := kv.Get("foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if secret.Data["bar"] != "baz" || secret.VersionMetadata != nil {
+			t.Fatalf("bad: %#v", secret)
+		}
+		if err := kv.Delete("foo"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := kv.Get("foo"); err != api.ErrSecretNotFound {
+			t.Fatalf("expected not found, got %v", err)
+		}
+	})
+
+	t.Run("v2", func(t *testing.T) {
+		if err := client.Sys().Mount("kv", &api.MountInput{
+			Type: "kv-v2",
+		}); err != nil {
+			t.Fatal(err)
+		}
+		kv := client.KVv2("kv")
+
+		if _, err := kv.Get("foo"); err != api.ErrSecretNotFound {
+			t.Fatalf("expected not found, got %v", err)
+		}
+		if _, err := kv.Patch("foo", map[string]interface{}{"bar": "baz"}); err != api.ErrSecretNotFound {
+			t.Fatalf("expected not found, got %v", err)
+		}
+
+		metadata, err := kv.Put("foo", map[string]interface{}{"bar": "baz"}, api.WithCheckAndSet(0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if metadata.Version != 1 || metadata.CreatedTime.IsZero() || !metadata.DeletionTime.IsZero() {
+			t.Fatalf("bad: %#v", metadata)
+		}
+
+		// The check-and-set option must match the current version
+		_, err = kv.Put("foo", map[string]interface{}{"bar": "qux"}, api.WithCheckAndSet(0))
+		if respErr, ok := err.(*api.ResponseError); !ok || respErr.StatusCode != 400 {
+			t.Fatalf("expected a bad request, got %v", err)
+		}
+
+		metadata, err = kv.Patch("foo", map[string]interface{}{"qux": "quux"}, api.WithCheckAndSet(1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if metadata.Version != 2 {
+			t.Fatalf("bad: %#v", metadata)
+		}
+
+		secret, err := kv.Get("foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if secret.Data["bar"] != "baz" || secret.Data["qux"] != "quux" || secret.VersionMetadata.Version != 2 {
+			t.Fatalf("bad: %#v", secret)
+		}
+		secret, err = kv.GetVersion("foo", 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(secret.Data) != 1 || secret.VersionMetadata.Version != 1 {
+			t.Fatalf("bad: %#v", secret)
+		}
+		if _, err := kv.GetVersion("foo", 3); err != api.ErrSecretNotFound {
+			t.Fatalf("expected not found, got %v", err)
+		}
+
+		// The metadata of deleted versions is still returned
+		if err := kv.Delete("foo"); err != nil {
+			t.Fatal(err)
+		}
+		secret, err = kv.Get("foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if secret.Data != nil || secret.VersionMetadata.DeletionTime.IsZero() {
+			t.Fatalf("bad: %#v", secret)
+		}
+	})
+}
@@ -0,0 +1,351 @@
+package api
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+var (
+	ErrLifetimeWatcherMissingInput  = errors.New("missing input to lifetime watcher")
+	ErrLifetimeWatcherMissingSecret = errors.New("missing secret to renew")
+	ErrLifetimeWatcherNotRenewable  = errors.New("secret is not renewable")
+	ErrLifetimeWatcherNoSecretData  = errors.New("returned empty secret data")
+
+	// DefaultLifetimeWatcherGrace is the default grace period
+	DefaultLifetimeWatcherGrace = 15 * time.Second
+
+	// DefaultLifetimeWatcherRenewBuffer is the default size of the buffer for
+	// renew messages on the channel.
+	DefaultLifetimeWatcherRenewBuffer = 5
+)
+
+// The names of the lifetime watcher before it was renamed from renewer
+var (
+	ErrRenewerMissingInput  = ErrLifetimeWatcherMissingInput
+	ErrRenewerMissingSecret = ErrLifetimeWatcherMissingSecret
+	ErrRenewerNotRenewable  = ErrLifetimeWatcherNotRenewable
+	ErrRenewerNoSecretData  = ErrLifetimeWatcherNoSecretData
+
+	DefaultRenewerGrace       = DefaultLifetimeWatcherGrace
+	DefaultRenewerRenewBuffer = DefaultLifetimeWatcherRenewBuffer
+)
+
+// LifetimeWatcher is a process for watching the lifetime of a secret, renewing
+// its lease or, when the secret has auth data, its token.
+//
+// 	watcher, err := client.NewLifetimeWatcher(&LifetimeWatcherInput{
+// 		Secret: mySecret,
+// 	})
+// 	go watcher.Start()
+// 	defer watcher.Stop()
+//
+// 	for {
+// 		select {
+// 		case err := <-watcher.DoneCh():
+// 			if err != nil {
+// 				log.Fatal(err)
+// 			}
+//
+// 			// Renewal is now over
+// 		case renewal := <-watcher.RenewCh():
+// 			log.Printf("Successfully renewed: %#v", renewal)
+// 		}
+// 	}
+//
+//
+// The `DoneCh` will return if renewal fails, or once the remaining lease
+// duration is within the grace period, which is the case when the lease can no
+// longer be extended, e.g. because of its max TTL. In both cases, the caller
+// should attempt a re-read of the secret. Clients should check the return
+// value of the channel to see if renewal was successful.
+//
+// The grace period is the grace of the input plus a random jitter of up to a
+// tenth of the lease duration, so that the clients sharing a max TTL do not
+// all re-read their secrets at once.
+type LifetimeWatcher struct {
+	l sync.Mutex
+
+	client    *Client
+	secret    *Secret
+	grace     time.Duration
+	random    *rand.Rand
+	increment int
+	doneCh    chan error
+	renewCh   chan *RenewOutput
+
+	stopped bool
+	stopCh  chan struct{}
+}
+
+// LifetimeWatcherInput is used as input to the lifetime watcher.
+type LifetimeWatcherInput struct {
+	// Secret is the secret to renew
+	Secret *Secret
+
+	// Grace is a minimum renewal before returning so the upstream client
+	// can do a re-read. This can be used to prevent clients from waiting
+	// too long to read a new credential and incur downtime.
+	Grace time.Duration
+
+	// Rand is the randomizer to use for underlying randomization. If not
+	// provided, one will be generated and seeded automatically. If provided, it
+	// is assumed to have already been seeded.
+	Rand *rand.Rand
+
+	// RenewBuffer is the size of the buffered channel where renew messages are
+	// dispatched.
+	RenewBuffer int
+
+	// The new TTL, in seconds, that should be set on the lease. The TTL set
+	// here may or may not be honored by the vault server, based on Vault
+	// configuration or any associated max TTL values.
+	Increment int
+}
+
+// Renewer and RenewerInput are the former names of LifetimeWatcher and
+// LifetimeWatcherInput.
+type Renewer = LifetimeWatcher
+type RenewerInput = LifetimeWatcherInput
+
+// RenewOutput is the metadata returned to the client (if it's listening) to
+// renew messages.
+type RenewOutput struct {
+	// RenewedAt is the timestamp when the renewal took place (UTC).
+	RenewedAt time.Time
+
+	// Secret is the underlying renewal data. It's the same struct as all data
+	// that is returned from Vault, but since this is renewal data, it will not
+	// usually include the secret itself.
+	Secret *Secret
+}
+
+// NewLifetimeWatcher creates a new lifetime watcher from the given input.
+func (c *Client) NewLifetimeWatcher(i *LifetimeWatcherInput) (*LifetimeWatcher, error) {
+	if i == nil {
+		return nil, ErrLifetimeWatcherMissingInput
+	}
+
+	secret := i.Secret
+	if secret == nil {
+		return nil, ErrLifetimeWatcherMissingSecret
+	}
+
+	grace := i.Grace
+	if grace == 0 {
+		grace = DefaultLifetimeWatcherGrace
+	}
+
+	random := i.Rand
+	if random == nil {
+		random = rand.New(rand.NewSource(int64(time.Now().Nanosecond())))
+	}
+
+	renewBuffer := i.RenewBuffer
+	if renewBuffer == 0 {
+		renewBuffer = DefaultLifetimeWatcherRenewBuffer
+	}
+
+	return &LifetimeWatcher{
+		client:    c,
+		secret:    secret,
+		grace:     grace,
+		increment: i.Increment,
+		random:    random,
+		doneCh:    make(chan error, 1),
+		renewCh:   make(chan *RenewOutput, renewBuffer),
+
+		stopped: false,
+		stopCh:  make(chan struct{}),
+	}, nil
+}
+
+// NewRenewer is the former name of NewLifetimeWatcher.
+func (c *Client) NewRenewer(i *RenewerInput) (*Renewer, error) {
+	return c.NewLifetimeWatcher(i)
+}
+
+// DoneCh returns the channel where the watcher will publish when renewal stops.
+// If there is an error, this will be an error.
+func (r *LifetimeWatcher) DoneCh() <-chan error {
+	return r.doneCh
+}
+
+// RenewCh is a channel that receives a message when a successful renewal takes
+// place and includes metadata about the renewal.
+func (r *LifetimeWatcher) RenewCh() <-chan *RenewOutput {
+	return r.renewCh
+}
+
+// Stop stops the watcher.
+func (r *LifetimeWatcher) Stop() {
+	r.l.Lock()
+	if !r.stopped {
+		close(r.stopCh)
+		r.stopped = true
+	}
+	r.l.Unlock()
+}
+
+// Start starts a background process for watching the lifetime of this secret.
+// When the secret has auth data, this attempts to renew the auth (token). When
+// the secret has a lease, this attempts to renew the lease.
+func (r *LifetimeWatcher) Start() {
+	var result error
+	if r.secret.Auth != nil {
+		result = r.renewAuth()
+	} else {
+		result = r.renewLease()
+	}
+
+	select {
+	case r.doneCh <- result:
+	case <-r.stopCh:
+	}
+}
+
+// Renew is the former name of Start.
+func (r *LifetimeWatcher) Renew() {
+	r.Start()
+}
+
+// renewAuth is a helper for renewing authentication.
+func (r *LifetimeWatcher) renewAuth() error {
+	if !r.secret.Auth.Renewable || r.secret.Auth.ClientToken == "" {
+		return ErrLifetimeWatcherNotRenewable
+	}
+
+	client, token := r.client, r.secret.Auth.ClientToken
+
+	return r.doRenew(func() (bool, time.Duration, error) {
+		renewal, err := client.Auth().Token().RenewTokenAsSelf(token, r.increment)
+		if err != nil {
+			return false, 0, err
+		}
+
+		// Push a message that a renewal took place.
+		r.pushRenewal(renewal)
+
+		// Somehow, sometimes, this happens.
+		if renewal == nil || renewal.Auth == nil {
+			return false, 0, ErrLifetimeWatcherNoSecretData
+		}
+
+		// Note that we grab the auth lease duration, not the secret lease
+		// duration.
+		return renewal.Auth.Renewable, time.Duration(renewal.Auth.LeaseDuration) * time.Second, nil
+	})
+}
+
+// renewLease is a helper for renewing a lease.
+func (r *LifetimeWatcher) renewLease() error {
+	if !r.secret.Renewable || r.secret.LeaseID == "" {
+		return ErrLifetimeWatcherNotRenewable
+	}
+
+	client, leaseID := r.client, r.secret.LeaseID
+
+	return r.doRenew(func() (bool, time.Duration, error) {
+		renewal, err := client.Sys().Renew(leaseID, r.increment)
+		if err != nil {
+			return false, 0, err
+		}
+
+		// Push a message that a renewal took place.
+		r.pushRenewal(renewal)
+
+		// Somehow, sometimes, this happens.
+		if renewal == nil {
+			return false, 0, ErrLifetimeWatcherNoSecretData
+		}
+
+		return renewal.Renewable, time.Duration(renewal.LeaseDuration) * time.Second, nil
+	})
+}
+
+// pushRenewal sends the renewal on the renew channel, unless its buffer is
+// full
+func (r *LifetimeWatcher) pushRenewal(renewal *Secret) {
+	select {
+	case r.renewCh <- &RenewOutput{time.Now().UTC(), renewal}:
+	default:
+	}
+}
+
+// doRenew calls the renew function until the watcher is stopped, the renewal
+// fails, or the remaining lease duration is within the grace period. The renew
+// function returns whether the secret is still renewable, and its remaining
+// lease duration.
+func (r *LifetimeWatcher) doRenew(renew func() (bool, time.Duration, error)) error {
+	for {
+		// Check if we are stopped.
+		select {
+		case <-r.stopCh:
+			return nil
+		default:
+		}
+
+		renewable, leaseDuration, err := renew()
+		if err != nil {
+			return err
+		}
+
+		// Do nothing if we are not renewable
+		if !renewable {
+			return ErrLifetimeWatcherNotRenewable
+		}
+
+		// If we are within grace, return now.
+		grace := r.calculateGrace(leaseDuration)
+		if leaseDuration <= grace {
+			return nil
+		}
+
+		// If the next renewal would happen within grace, the lease is not
+		// worth renewing again: wait until the grace period and return, so
+		// that the caller keeps using the secret until then.
+		sleepDuration := r.sleepDuration(leaseDuration)
+		last := leaseDuration-sleepDuration <= grace
+		if last {
+			sleepDuration = leaseDuration - grace
+		}
+
+		select {
+		case <-r.stopCh:
+			return nil
+		case <-time.After(sleepDuration):
+			if last {
+				return nil
+			}
+		}
+	}
+}
+
+// calculateGrace returns the grace period for the given lease duration, which
+// is the grace of the watcher plus a random jitter of up to a tenth of the
+// lease duration.
+func (r *LifetimeWatcher) calculateGrace(leaseDuration time.Duration) time.Duration {
+	jitterMax := int64(leaseDuration / 10)
+	if jitterMax <= 0 {
+		return r.grace
+	}
+	return r.grace + time.Duration(r.random.Int63n(jitterMax))
+}
+
+// sleepDuration calculates the time to sleep given the base lease duration. The
+// base is the resulting lease duration. It will be reduced to 1/3 and
+// multiplied by a random float between 0.5 and 1.0. This extra randomness
+// prevents multiple clients from all trying to renew simultaneously.
+func (r *LifetimeWatcher) sleepDuration(base time.Duration) time.Duration {
+	sleep := float64(base)
+
+	// Renew at 1/3 the remaining lease. This will give us an opportunity to retry
+	// at least one more time should the first renewal fail.
+	sleep = sleep / 3.0
+
+	// Use a randomness so many clients do not hit Vault simultaneously.
+	sleep = sleep * (r.random.Float64() + 1) / 2.0
+
+	return time.Duration(sleep)
+}
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testLeaseServer serves lease renewals with the given lease duration and
+// renewability, counting the renewals
+func testLeaseServer(t *testing.T, leaseDuration int, renewable bool, renewals *int32) (*Client, net.Listener) {
+	handler := func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(renewals, 1)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"lease_id":       "foo",
+			"renewable":      renewable,
+			"lease_duration": leaseDuration,
+		})
+	}
+	config, ln := testHTTPServer(t, http.HandlerFunc(handler))
+
+	client, err := NewClient(config)
+	if err != nil {
+		ln.Close()
+		t.Fatal(err)
+	}
+	return client, ln
+}
+
+func TestLifetimeWatcher_Start(t *testing.T) {
+	secret := &Secret{LeaseID: "foo", Renewable: true}
+
+	t.Run("renews", func(t *testing.T) {
+		var renewals int32
+		client, ln := testLeaseServer(t, 1, true, &renewals)
+		defer ln.Close()
+		watcher, err := client.NewLifetimeWatcher(&LifetimeWatcherInput{
+			Secret: secret,
+			Grace:  100 * time.Millisecond,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		go watcher.Start()
+		defer watcher.Stop()
+
+		for i := 0; i < 2; i++ {
+			select {
+			case err := <-watcher.DoneCh():
+				t.Fatalf("unexpected done: %v", err)
+			case <-watcher.RenewCh():
+			case <-time.After(5 * time.Second):
+				t.Fatal("timed out")
+			}
+		}
+	})
+
+	t.Run("waits_for_grace", func(t *testing.T) {
+		// The next renewal would be within grace, so the watcher is done once
+		// the lease reaches the grace period
+		var renewals int32
+		client, ln := testLeaseServer(t, 1, true, &renewals)
+		defer ln.Close()
+		watcher, err := client.NewLifetimeWatcher(&LifetimeWatcherInput{
+			Secret: secret,
+			Grace:  900 * time.Millisecond,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		go watcher.Start()
+		defer watcher.Stop()
+
+		select {
+		case err := <-watcher.DoneCh():
+			if err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out")
+		}
+		if n := atomic.LoadInt32(&renewals); n != 1 {
+			t.Fatalf("expected a single renewal, got %d", n)
+		}
+	})
+
+	t.Run("not_renewable", func(t *testing.T) {
+		var renewals int32
+		client, ln := testLeaseServer(t, 60, false, &renewals)
+		defer ln.Close()
+		watcher, err := client.NewLifetimeWatcher(&LifetimeWatcherInput{
+			Secret: secret,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		go watcher.Start()
+		defer watcher.Stop()
+
+		select {
+		case err := <-watcher.DoneCh():
+			if err != ErrLifetimeWatcherNotRenewable {
+				t.Fatalf("expected not renewable, got %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out")
+		}
+	})
+}
+
+func TestLifetimeWatcher_calculateGrace(t *testing.T) {
+	client, err := NewClient(DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	watcher, err := client.NewLifetimeWatcher(&LifetimeWatcherInput{
+		Secret: &Secret{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if grace := watcher.calculateGrace(0); grace != DefaultLifetimeWatcherGrace {
+		t.Fatalf("bad: %s", grace)
+	}
+	for i := 0; i < 100; i++ {
+		grace := watcher.calculateGrace(time.Hour)
+		if grace < DefaultLifetimeWatcherGrace || grace >= DefaultLifetimeWatcherGrace+6*time.Minute {
+			t.Fatalf("bad: %s", grace)
+		}
+	}
+}
@@ -100,6 +100,37 @@ func (c *Logical) Write(path string, data map[string]interface{}) (*Secret, erro
 	return nil, nil
 }
 
+// JSONMergePatch applies the data as a JSON merge patch (RFC 7386) to the
+// path, for backends supporting patches
+func (c *Logical) JSONMergePatch(path string, data map[string]interface{}) (*Secret, error) {
+	r := c.c.NewRequest("PATCH", "/v1/"+path)
+	if err := r.SetJSONBody(data); err != nil {
+		return nil, err
+	}
+
+	// The headers of the request are shared with the client
+	headers := http.Header{}
+	for header, vals := range r.Headers {
+		headers[header] = vals
+	}
+	headers.Set("Content-Type", "application/merge-patch+json")
+	r.Headers = headers
+
+	resp, err := c.c.RawRequest(r)
+	if resp != nil {
+		defer resp.Body.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == 200 {
+		return ParseSecret(resp.Body)
+	}
+
+	return nil, nil
+}
+
 func (c *Logical) Delete(path string) (*Secret, error) {
 	r := c.c.NewRequest("DELETE", "/v1/"+path)
 	resp, err := c.c.RawRequest(r)
@@ -26,6 +26,13 @@ type Request struct {
 	// EGPs). If set, the override flag will take effect for all policies
 	// evaluated during the request.
 	PolicyOverride bool
+
+	// Namespace is the path of the namespace the request is made in
+	Namespace string
+
+	// IndexState is the index state returned by an earlier write, which the
+	// storage backend must reflect before the reads of the request
+	IndexState string
 }
 
 // SetJSONBody is used to set a request body that is a JSON-encoded value.
@@ -93,5 +100,13 @@ func (r *Request) ToHTTP() (*http.Request, error) {
 		req.Header.Set("X-Vault-Policy-Override", "true")
 	}
 
+	if len(r.Namespace) != 0 {
+		req.Header.Set("X-Vault-Namespace", r.Namespace)
+	}
+
+	if len(r.IndexState) != 0 {
+		req.Header.Set("X-Vault-Index", r.IndexState)
+	}
+
 	return req, nil
 }
@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
+	"strings"
 
 	"github.com/hashicorp/vault/helper/jsonutil"
 )
@@ -22,8 +24,8 @@ func (r *Response) DecodeJSON(out interface{}) error {
 }
 
 // Error returns an error response if there is one. If there is an error,
-// this will fully consume the response body, but will not close it. The
-// body must still be closed manually.
+// the response body is read and replaced by a copy of it, which must still
+// be closed manually.
 func (r *Response) Error() error {
 	// 200 to 399 are okay status codes. 429 is the code for health status of
 	// standby nodes.
@@ -38,32 +40,68 @@ func (r *Response) Error() error {
 		return err
 	}
 
+	// Reset the body since it was consumed, so that callers proxying the
+	// response can still read it
+	r.Body.Close()
+	r.Body = ioutil.NopCloser(bytes.NewReader(bodyBuf.Bytes()))
+
+	respErr := &ResponseError{
+		HTTPMethod: r.Request.Method,
+		URL:        r.Request.URL.String(),
+		StatusCode: r.StatusCode,
+	}
+
 	// Decode the error response if we can. Note that we wrap the bodyBuf
 	// in a bytes.Reader here so that the JSON decoder doesn't move the
 	// read pointer for the original buffer.
 	var resp ErrorResponse
 	if err := jsonutil.DecodeJSON(bodyBuf.Bytes(), &resp); err != nil {
 		// Ignore the decoding error and just drop the raw response
-		return fmt.Errorf(
-			"Error making API request.\n\n"+
-				"URL: %s %s\n"+
-				"Code: %d. Raw Message:\n\n%s",
-			r.Request.Method, r.Request.URL.String(),
-			r.StatusCode, bodyBuf.String())
+		respErr.RawError = true
+		respErr.Errors = []string{bodyBuf.String()}
+		return respErr
 	}
+	respErr.Errors = resp.Errors
+
+	return respErr
+}
+
+// ResponseError is the error returned for the error responses of the Vault
+// API, so that callers can tell them apart by status code.
+type ResponseError struct {
+	// HTTPMethod and URL are the method and URL of the request
+	HTTPMethod string
+	URL        string
+
+	// StatusCode is the status code of the response
+	StatusCode int
+
+	// RawError is set if the body of the response could not be decoded, in
+	// which case Errors holds the raw body
+	RawError bool
 
+	// Errors are the errors returned by Vault
+	Errors []string
+}
+
+func (r *ResponseError) Error() string {
 	var errBody bytes.Buffer
 	errBody.WriteString(fmt.Sprintf(
 		"Error making API request.\n\n"+
-			"URL: %s %s\n"+
-			"Code: %d. Errors:\n\n",
-		r.Request.Method, r.Request.URL.String(),
-		r.StatusCode))
-	for _, err := range resp.Errors {
-		errBody.WriteString(fmt.Sprintf("* %s", err))
+			"URL: %s %s\n",
+		r.HTTPMethod, r.URL))
+
+	if r.RawError {
+		errBody.WriteString(fmt.Sprintf("Code: %d. Raw Message:\n\n", r.StatusCode))
+		errBody.WriteString(strings.Join(r.Errors, ""))
+		return errBody.String()
 	}
 
-	return fmt.Errorf(errBody.String())
+	errBody.WriteString(fmt.Sprintf("Code: %d. Errors:\n\n", r.StatusCode))
+	for _, err := range r.Errors {
+		errBody.WriteString(fmt.Sprintf("* %s", err))
+	}
+	return errBody.String()
 }
 
 // ErrorResponse is the raw structure of errors when they're returned by the
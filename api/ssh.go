@@ -1,6 +1,13 @@
 package api
 
-import "fmt"
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/helper/parseutil"
+)
 
 // SSH is used to return a client to invoke operations on SSH backend.
 type SSH struct {
@@ -53,3 +60,214 @@ func (c *SSH) SignKey(role string, data map[string]interface{}) (*Secret, error)
 
 	return ParseSecret(resp.Body)
 }
+
+// CAPublicKeys returns the CA public keys of the mount which the hosts and
+// clients should trust: its current key, the key replaced by its last
+// rotation and the keys of its named issuers. No keys are returned until the
+// CA of the mount is configured. Fetching the keys needs no token.
+func (c *SSH) CAPublicKeys() ([]string, error) {
+	r := c.c.NewRequest("GET", fmt.Sprintf("/v1/%s/public_key", c.MountPoint))
+	resp, err := c.c.RawRequest(r)
+	if resp != nil {
+		defer resp.Body.Close()
+	}
+	if resp != nil && resp.StatusCode == 404 {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	for _, line := range strings.Split(string(body), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			keys = append(keys, line)
+		}
+	}
+	return keys, nil
+}
+
+// SSHSignInput is the input of SignPublicKey. The optional fields left empty
+// take the defaults of the role.
+type SSHSignInput struct {
+	// PublicKey is the SSH public key to sign
+	PublicKey string
+
+	// CertType is the type of the certificate, either "user" or "host"
+	CertType string
+
+	// ValidPrincipals are the usernames or hostnames the certificate is
+	// signed for
+	ValidPrincipals []string
+
+	// KeyID is the key ID of the certificate
+	KeyID string
+
+	// TTL is the requested TTL of the certificate
+	TTL time.Duration
+
+	// CriticalOptions and Extensions are the critical options and extensions
+	// the certificate is signed for
+	CriticalOptions map[string]string
+	Extensions      map[string]string
+
+	// Comment is appended to the signed key
+	Comment string
+}
+
+// SSHSignedKey is a public key signed by the SSH secrets engine
+type SSHSignedKey struct {
+	// SignedKey is the signed certificate, in the authorized keys format
+	SignedKey string
+
+	SerialNumber    string
+	KeyID           string
+	CertType        string
+	ValidPrincipals []string
+	ValidAfter      time.Time
+	ValidBefore     time.Time
+
+	// Raw is the response of Vault
+	Raw *Secret
+}
+
+// SignPublicKey signs the public key of the input with the role, returning
+// the signed key along with the contents of its certificate.
+func (c *SSH) SignPublicKey(role string, input *SSHSignInput) (*SSHSignedKey, error) {
+	if input == nil || input.PublicKey == "" {
+		return nil, fmt.Errorf("missing public key to sign")
+	}
+
+	data := input.data()
+	data["public_key"] = input.PublicKey
+
+	secret, err := c.SignKey(role, data)
+	if err != nil {
+		return nil, err
+	}
+	return parseSSHSignedKey(secret)
+}
+
+// SSHIssuedKey is a key pair generated by the SSH secrets engine, along with
+// its signed public key
+type SSHIssuedKey struct {
+	*SSHSignedKey
+
+	// PrivateKey is the PEM encoded private key of the pair, which Vault
+	// does not keep
+	PrivateKey     string
+	PrivateKeyType string
+	PublicKey      string
+}
+
+// IssueKeyPair generates a key pair of the type configured by the role and
+// signs its public key with the role. The public key of the input is ignored.
+func (c *SSH) IssueKeyPair(role string, input *SSHSignInput) (*SSHIssuedKey, error) {
+	if input == nil {
+		input = &SSHSignInput{}
+	}
+
+	r := c.c.NewRequest("PUT", fmt.Sprintf("/v1/%s/issue/%s", c.MountPoint, role))
+	if err := r.SetJSONBody(input.data()); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.c.RawRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	secret, err := ParseSecret(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	signedKey, err := parseSSHSignedKey(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SSHIssuedKey{
+		SSHSignedKey: signedKey,
+	}
+	result.PrivateKey, _ = secret.Data["private_key"].(string)
+	result.PrivateKeyType, _ = secret.Data["private_key_type"].(string)
+	result.PublicKey, _ = secret.Data["public_key"].(string)
+	if result.PrivateKey == "" {
+		return nil, fmt.Errorf("private key missing from server response")
+	}
+	return result, nil
+}
+
+// data returns the request data of the optional fields of the input
+func (input *SSHSignInput) data() map[string]interface{} {
+	data := make(map[string]interface{})
+	if input.CertType != "" {
+		data["cert_type"] = input.CertType
+	}
+	if len(input.ValidPrincipals) > 0 {
+		data["valid_principals"] = strings.Join(input.ValidPrincipals, ",")
+	}
+	if input.KeyID != "" {
+		data["key_id"] = input.KeyID
+	}
+	if input.TTL > 0 {
+		data["ttl"] = int64(input.TTL.Seconds())
+	}
+	if input.CriticalOptions != nil {
+		data["critical_options"] = input.CriticalOptions
+	}
+	if input.Extensions != nil {
+		data["extensions"] = input.Extensions
+	}
+	if input.Comment != "" {
+		data["cert_comment"] = input.Comment
+	}
+	return data
+}
+
+// parseSSHSignedKey returns the signed key of the response of the sign and
+// issue endpoints
+func parseSSHSignedKey(secret *Secret) (*SSHSignedKey, error) {
+	if secret == nil || secret.Data == nil {
+		return nil, fmt.Errorf("data from server response is empty")
+	}
+
+	signedKey, ok := secret.Data["signed_key"].(string)
+	if !ok || signedKey == "" {
+		return nil, fmt.Errorf("signed key missing from server response")
+	}
+	result := &SSHSignedKey{
+		SignedKey: signedKey,
+		Raw:       secret,
+	}
+	result.SerialNumber, _ = secret.Data["serial_number"].(string)
+	result.KeyID, _ = secret.Data["key_id"].(string)
+	result.CertType, _ = secret.Data["cert_type"].(string)
+	if principals, ok := secret.Data["valid_principals"].([]interface{}); ok {
+		for _, principal := range principals {
+			if principal, ok := principal.(string); ok {
+				result.ValidPrincipals = append(result.ValidPrincipals, principal)
+			}
+		}
+	}
+	for field, t := range map[string]*time.Time{
+		"valid_after":  &result.ValidAfter,
+		"valid_before": &result.ValidBefore,
+	} {
+		if _, ok := secret.Data[field]; !ok {
+			continue
+		}
+		seconds, err := parseutil.ParseInt(secret.Data[field])
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", field, err)
+		}
+		*t = time.Unix(seconds, 0)
+	}
+
+	return result, nil
+}
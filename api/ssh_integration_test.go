@@ -0,0 +1,99 @@
+package api_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+const testSSHPublicKey = `ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAABAQDArgK0ilRRfk8E7HIsjz5l3BuxmwpDd8DHRCVfOhbZ4gOSVxjEOOqBwWGjygdboBIZwFXmwDlU6sWX0hBJAgpQz0Cjvbjxtq/NjkvATrYPgnrXUhTaEn2eQO0PsqRNSFH46SK/oJfTp0q8/WgojxWJ2L7FUV8PO8uIk49DzqAqPV7WXU63vFsjx+3WQOX/ILeQvHCvaqs3dWjjzEoDudRWCOdUqcHEOshV9azIzPrXlQVzRV3QAKl6u7pC+/Secorpwt6IHpMKoVPGiR0tMMuNOVH8zrAKzIxPGfy2WmNDpJopbXMTvSOGAqNcp49O4SKOQl9Fzfq2HEevJamKLrMB dummy@example.com`
+
+func TestSSH_SignPublicKey(t *testing.T) {
+	client, closer := testVaultServer(t)
+	defer closer()
+
+	if err := client.Sys().Mount("ssh-client-signer", &api.MountInput{
+		Type: "ssh",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Logical().Write("ssh-client-signer/config/ca", map[string]interface{}{
+		"generate_signing_key": true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Logical().Write("ssh-client-signer/roles/users", map[string]interface{}{
+		"key_type":                "ca",
+		"allow_user_certificates": true,
+		"allowed_users":           "*",
+		"allowed_extensions":      "permit-pty",
+		"allow_user_key_ids":      true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	ssh := client.SSHWithMountPoint("ssh-client-signer")
+	if _, err := ssh.SignPublicKey("users", &api.SSHSignInput{}); err == nil {
+		t.Fatal("expected an error for the missing public key")
+	}
+
+	signed, err := ssh.SignPublicKey("users", &api.SSHSignInput{
+		PublicKey:       testSSHPublicKey,
+		ValidPrincipals: []string{"alice", "bob"},
+		KeyID:           "test",
+		TTL:             time.Hour,
+		Extensions:      map[string]string{"permit-pty": ""},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(signed.SignedKey, "ssh-rsa-cert-v01@openssh.com ") || signed.SerialNumber == "" {
+		t.Fatalf("bad: %#v", signed)
+	}
+	if signed.KeyID != "test" || signed.CertType != "user" || strings.Join(signed.ValidPrincipals, ",") != "alice,bob" {
+		t.Fatalf("bad: %#v", signed)
+	}
+	if lifetime := signed.ValidBefore.Sub(signed.ValidAfter); lifetime < time.Hour || lifetime > time.Hour+time.Minute {
+		t.Fatalf("bad lifetime: %s", lifetime)
+	}
+}
+
+func TestSSH_IssueKeyPair(t *testing.T) {
+	client, closer := testVaultServer(t)
+	defer closer()
+
+	if err := client.Sys().Mount("ssh-client-signer", &api.MountInput{
+		Type: "ssh",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Logical().Write("ssh-client-signer/config/ca", map[string]interface{}{
+		"generate_signing_key": true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Logical().Write("ssh-client-signer/roles/users", map[string]interface{}{
+		"key_type":                "ca",
+		"allow_user_certificates": true,
+		"allowed_users":           "*",
+		"issued_key_type":         "ed25519",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	issued, err := client.SSHWithMountPoint("ssh-client-signer").IssueKeyPair("users", &api.SSHSignInput{
+		ValidPrincipals: []string{"alice"},
+		TTL:             time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(issued.SignedKey, "ssh-ed25519-cert-v01@openssh.com ") || issued.ValidBefore.IsZero() {
+		t.Fatalf("bad: %#v", issued.SSHSignedKey)
+	}
+	if issued.PrivateKeyType != "ed25519" || !strings.Contains(issued.PrivateKey, "OPENSSH PRIVATE KEY") {
+		t.Fatalf("bad: %#v", issued)
+	}
+}
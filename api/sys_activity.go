@@ -0,0 +1,134 @@
+package api
+
+import (
+	"fmt"
+	"io/ioutil"
+	"time"
+)
+
+func (c *Sys) ActivityConfig() (*ActivityConfig, error) {
+	r := c.c.NewRequest("GET", "/v1/sys/internal/counters/config")
+	resp, err := c.c.RawRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Data *ActivityConfig `json:"data"`
+	}
+	if err := resp.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+	if result.Data == nil {
+		return nil, fmt.Errorf("missing data in response")
+	}
+	return result.Data, nil
+}
+
+func (c *Sys) SetActivityConfig(config *ActivityConfigInput) error {
+	r := c.c.NewRequest("PUT", "/v1/sys/internal/counters/config")
+	if err := r.SetJSONBody(config); err != nil {
+		return err
+	}
+
+	resp, err := c.c.RawRequest(r)
+	if err == nil {
+		defer resp.Body.Close()
+	}
+	return err
+}
+
+// Activity returns the distinct clients active in the months between the
+// start and end times, which are unbounded if zero
+func (c *Sys) Activity(start, end time.Time) (*Activity, error) {
+	r := c.c.NewRequest("GET", "/v1/sys/internal/counters/activity")
+	setActivityTimeRange(r, start, end)
+
+	resp, err := c.c.RawRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Data *Activity `json:"data"`
+	}
+	if err := resp.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+	if result.Data == nil {
+		return nil, fmt.Errorf("missing data in response")
+	}
+	return result.Data, nil
+}
+
+// ActivityExport returns the records of the clients active in the months
+// between the start and end times, as JSON lines or CSV depending on the
+// format
+func (c *Sys) ActivityExport(start, end time.Time, format string) ([]byte, error) {
+	r := c.c.NewRequest("GET", "/v1/sys/internal/counters/activity/export")
+	setActivityTimeRange(r, start, end)
+	if format != "" {
+		r.Params.Set("format", format)
+	}
+
+	resp, err := c.c.RawRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return ioutil.ReadAll(resp.Body)
+}
+
+func setActivityTimeRange(r *Request, start, end time.Time) {
+	if !start.IsZero() {
+		r.Params.Set("start_time", start.Format(time.RFC3339))
+	}
+	if !end.IsZero() {
+		r.Params.Set("end_time", end.Format(time.RFC3339))
+	}
+}
+
+type ActivityConfig struct {
+	Enabled         bool `json:"enabled"`
+	RetentionMonths int  `json:"retention_months"`
+}
+
+type ActivityConfigInput struct {
+	Enabled         *bool `json:"enabled,omitempty"`
+	RetentionMonths int   `json:"retention_months,omitempty"`
+}
+
+type Activity struct {
+	StartTime   string               `json:"start_time"`
+	EndTime     string               `json:"end_time"`
+	Total       *ActivityCounts      `json:"total"`
+	ByNamespace []*NamespaceActivity `json:"by_namespace"`
+	Months      []*MonthActivity     `json:"months"`
+}
+
+type ActivityCounts struct {
+	EntityClients    int `json:"entity_clients"`
+	NonEntityClients int `json:"non_entity_clients"`
+	Clients          int `json:"clients"`
+}
+
+type NamespaceActivity struct {
+	NamespaceID   string           `json:"namespace_id"`
+	NamespacePath string           `json:"namespace_path"`
+	Counts        *ActivityCounts  `json:"counts"`
+	Mounts        []*MountActivity `json:"mounts"`
+}
+
+type MountActivity struct {
+	MountAccessor string          `json:"mount_accessor"`
+	MountPath     string          `json:"mount_path"`
+	Counts        *ActivityCounts `json:"counts"`
+}
+
+type MonthActivity struct {
+	Month  string          `json:"month"`
+	Counts *ActivityCounts `json:"counts"`
+}
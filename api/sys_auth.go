@@ -91,7 +91,17 @@ type EnableAuthOptions struct {
 }
 
 type AuthConfigInput struct {
-	PluginName string `json:"plugin_name,omitempty" structs:"plugin_name,omitempty" mapstructure:"plugin_name"`
+	PluginName         string `json:"plugin_name,omitempty" structs:"plugin_name,omitempty" mapstructure:"plugin_name"`
+	PluginVersion      string `json:"plugin_version,omitempty" structs:"plugin_version,omitempty" mapstructure:"plugin_version"`
+	SyncExternalGroups bool   `json:"sync_external_groups,omitempty" structs:"sync_external_groups,omitempty" mapstructure:"sync_external_groups"`
+	TokenType          string `json:"token_type,omitempty" structs:"token_type,omitempty" mapstructure:"token_type"`
+
+	AuditNonHMACRequestKeys   []string `json:"audit_non_hmac_request_keys,omitempty" structs:"audit_non_hmac_request_keys,omitempty" mapstructure:"audit_non_hmac_request_keys"`
+	AuditNonHMACResponseKeys  []string `json:"audit_non_hmac_response_keys,omitempty" structs:"audit_non_hmac_response_keys,omitempty" mapstructure:"audit_non_hmac_response_keys"`
+	ListingVisibility         string   `json:"listing_visibility,omitempty" structs:"listing_visibility,omitempty" mapstructure:"listing_visibility"`
+	PassthroughRequestHeaders []string `json:"passthrough_request_headers,omitempty" structs:"passthrough_request_headers,omitempty" mapstructure:"passthrough_request_headers"`
+	AllowedResponseHeaders    []string `json:"allowed_response_headers,omitempty" structs:"allowed_response_headers,omitempty" mapstructure:"allowed_response_headers"`
+	MaxInFlightRequests       int      `json:"max_in_flight_requests,omitempty" structs:"max_in_flight_requests,omitempty" mapstructure:"max_in_flight_requests"`
 }
 
 type AuthMount struct {
@@ -107,4 +117,15 @@ type AuthConfigOutput struct {
 	DefaultLeaseTTL int    `json:"default_lease_ttl" structs:"default_lease_ttl" mapstructure:"default_lease_ttl"`
 	MaxLeaseTTL     int    `json:"max_lease_ttl" structs:"max_lease_ttl" mapstructure:"max_lease_ttl"`
 	PluginName      string `json:"plugin_name,omitempty" structs:"plugin_name,omitempty" mapstructure:"plugin_name"`
+	PluginVersion   string `json:"plugin_version,omitempty" structs:"plugin_version,omitempty" mapstructure:"plugin_version"`
+
+	SyncExternalGroups bool   `json:"sync_external_groups,omitempty" structs:"sync_external_groups" mapstructure:"sync_external_groups"`
+	TokenType          string `json:"token_type,omitempty" structs:"token_type" mapstructure:"token_type"`
+
+	AuditNonHMACRequestKeys   []string `json:"audit_non_hmac_request_keys,omitempty" structs:"audit_non_hmac_request_keys" mapstructure:"audit_non_hmac_request_keys"`
+	AuditNonHMACResponseKeys  []string `json:"audit_non_hmac_response_keys,omitempty" structs:"audit_non_hmac_response_keys" mapstructure:"audit_non_hmac_response_keys"`
+	ListingVisibility         string   `json:"listing_visibility,omitempty" structs:"listing_visibility" mapstructure:"listing_visibility"`
+	PassthroughRequestHeaders []string `json:"passthrough_request_headers,omitempty" structs:"passthrough_request_headers" mapstructure:"passthrough_request_headers"`
+	AllowedResponseHeaders    []string `json:"allowed_response_headers,omitempty" structs:"allowed_response_headers" mapstructure:"allowed_response_headers"`
+	MaxInFlightRequests       int      `json:"max_in_flight_requests,omitempty" structs:"max_in_flight_requests" mapstructure:"max_in_flight_requests"`
 }
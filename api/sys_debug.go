@@ -0,0 +1,42 @@
+package api
+
+import (
+	"fmt"
+	"io/ioutil"
+)
+
+// Pprof returns the given runtime profile of the server, in the format read by
+// "go tool pprof". The seconds are the duration of the CPU profile, "profile",
+// and of the execution trace, "trace"; zero uses the default of the server.
+func (c *Sys) Pprof(profile string, seconds int) ([]byte, error) {
+	r := c.c.NewRequest("GET", "/v1/sys/debug/pprof/"+profile)
+	if seconds > 0 {
+		r.Params.Set("seconds", fmt.Sprintf("%d", seconds))
+	}
+
+	resp, err := c.c.RawRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return ioutil.ReadAll(resp.Body)
+}
+
+// RecentLogs returns the last log lines written by the server, oldest first
+func (c *Sys) RecentLogs() ([]string, error) {
+	r := c.c.NewRequest("GET", "/v1/sys/debug/logs")
+	resp, err := c.c.RawRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Data struct {
+			Lines []string `json:"lines"`
+		} `json:"data"`
+	}
+	err = resp.DecodeJSON(&result)
+	return result.Data.Lines, err
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/helper/websocketutil"
+)
+
+// SubscribeEvents streams the events of the namespace of the client matching
+// the event type and path patterns over a websocket. Empty patterns match all
+// the events.
+func (c *Sys) SubscribeEvents(eventTypes []string, path string) (*EventSubscription, error) {
+	r := c.c.NewRequest("GET", "/v1/sys/events/subscribe")
+	if len(eventTypes) > 0 {
+		r.Params.Set("event_type", strings.Join(eventTypes, ","))
+	}
+	if path != "" {
+		r.Params.Set("path", path)
+	}
+	req, err := r.ToHTTP()
+	if err != nil {
+		return nil, err
+	}
+
+	c.c.config.modifyLock.RLock()
+	var tlsConfig *tls.Config
+	if transport, ok := c.c.config.HttpClient.Transport.(*http.Transport); ok {
+		tlsConfig = transport.TLSClientConfig
+	}
+	timeout := c.c.config.Timeout
+	c.c.config.modifyLock.RUnlock()
+
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	conn, resp, err := websocketutil.Dial(ctx, req, tlsConfig)
+	if err != nil {
+		if resp != nil {
+			if respErr := (&Response{Response: resp}).Error(); respErr != nil {
+				return nil, respErr
+			}
+		}
+		return nil, err
+	}
+
+	return &EventSubscription{conn: conn}, nil
+}
+
+// EventSubscription is a stream of events
+type EventSubscription struct {
+	conn *websocketutil.Conn
+}
+
+// Next blocks until the next event is received. Once the subscription ends,
+// e.g. because Vault is sealed, an error is returned.
+func (s *EventSubscription) Next() (*Event, error) {
+	for {
+		opcode, message, err := s.conn.ReadMessage()
+		if err != nil {
+			return nil, err
+		}
+		if opcode != websocketutil.OpText {
+			continue
+		}
+
+		var event Event
+		if err := json.Unmarshal(message, &event); err != nil {
+			return nil, err
+		}
+		return &event, nil
+	}
+}
+
+// Close ends the subscription
+func (s *EventSubscription) Close() error {
+	s.conn.SetWriteDeadline(time.Now().Add(time.Second))
+	s.conn.WriteClose(websocketutil.CloseNormal, "")
+	return s.conn.Close()
+}
+
+// Event is a notification of a change in Vault
+type Event struct {
+	ID        string            `json:"id"`
+	EventType string            `json:"event_type"`
+	Namespace string            `json:"namespace"`
+	Path      string            `json:"path"`
+	Timestamp time.Time         `json:"timestamp"`
+	Metadata  map[string]string `json:"metadata"`
+}
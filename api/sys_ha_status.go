@@ -0,0 +1,30 @@
+package api
+
+import "time"
+
+func (c *Sys) HAStatus() (*HAStatusResponse, error) {
+	r := c.c.NewRequest("GET", "/v1/sys/ha-status")
+	resp, err := c.c.RawRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Data HAStatusResponse `json:"data"`
+	}
+	err = resp.DecodeJSON(&result)
+	return &result.Data, err
+}
+
+type HAStatusResponse struct {
+	Nodes []HANode `json:"nodes"`
+}
+
+type HANode struct {
+	Hostname       string     `json:"hostname"`
+	APIAddress     string     `json:"api_address"`
+	ClusterAddress string     `json:"cluster_address"`
+	ActiveNode     bool       `json:"active_node"`
+	LastEcho       *time.Time `json:"last_echo"`
+}
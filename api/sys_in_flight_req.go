@@ -0,0 +1,33 @@
+package api
+
+import "fmt"
+
+// InFlightRequests returns the requests being handled by the node, keyed by
+// request ID
+func (c *Sys) InFlightRequests() (map[string]*InFlightRequest, error) {
+	r := c.c.NewRequest("GET", "/v1/sys/in-flight-req")
+	resp, err := c.c.RawRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Data map[string]*InFlightRequest `json:"data"`
+	}
+	if err := resp.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+	if result.Data == nil {
+		return nil, fmt.Errorf("missing data in response")
+	}
+	return result.Data, nil
+}
+
+type InFlightRequest struct {
+	RequestPath         string `json:"request_path"`
+	ClientRemoteAddress string `json:"client_remote_address"`
+	MountAccessor       string `json:"mount_accessor"`
+	StartTime           string `json:"start_time"`
+	Duration            string `json:"duration"`
+}
@@ -1,5 +1,7 @@
 package api
 
+import "time"
+
 func (c *Sys) Leader() (*LeaderResponse, error) {
 	r := c.c.NewRequest("GET", "/v1/sys/leader")
 	resp, err := c.c.RawRequest(r)
@@ -14,8 +16,10 @@ func (c *Sys) Leader() (*LeaderResponse, error) {
 }
 
 type LeaderResponse struct {
-	HAEnabled            bool   `json:"ha_enabled"`
-	IsSelf               bool   `json:"is_self"`
-	LeaderAddress        string `json:"leader_address"`
-	LeaderClusterAddress string `json:"leader_cluster_address"`
+	HAEnabled            bool       `json:"ha_enabled"`
+	IsSelf               bool       `json:"is_self"`
+	LeaderAddress        string     `json:"leader_address"`
+	LeaderClusterAddress string     `json:"leader_cluster_address"`
+	LeaderHostname       string     `json:"leader_hostname"`
+	LastContact          *time.Time `json:"last_contact"`
 }
@@ -0,0 +1,20 @@
+package api
+
+import "io/ioutil"
+
+// Metrics returns the metrics of the server in the given format, "json" or
+// "prometheus". An empty format is the JSON summary.
+func (c *Sys) Metrics(format string) ([]byte, error) {
+	r := c.c.NewRequest("GET", "/v1/sys/metrics")
+	if format != "" {
+		r.Params.Set("format", format)
+	}
+
+	resp, err := c.c.RawRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return ioutil.ReadAll(resp.Body)
+}
@@ -2,6 +2,8 @@ package api
 
 import (
 	"fmt"
+	"io"
+	"time"
 
 	"github.com/fatih/structs"
 	"github.com/mitchellh/mapstructure"
@@ -69,6 +71,67 @@ func (c *Sys) Unmount(path string) error {
 	return err
 }
 
+// UnmountWithOptions unmounts the path, keeping it for recovery during the
+// recovery window of the options if set
+func (c *Sys) UnmountWithOptions(path string, opts *UnmountOptions) (*UnmountOutput, error) {
+	r := c.c.NewRequest("DELETE", fmt.Sprintf("/v1/sys/mounts/%s", path))
+	if opts != nil {
+		if opts.RecoveryWindow > 0 {
+			r.Params.Set("recovery_window", fmt.Sprintf("%d", int64(opts.RecoveryWindow.Seconds())))
+		}
+		if opts.Force {
+			r.Params.Set("force", "true")
+		}
+	}
+
+	resp, err := c.c.RawRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result UnmountOutput
+	secret, err := ParseSecret(resp.Body)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if secret != nil && secret.Data != nil {
+		if err := mapstructure.Decode(secret.Data, &result); err != nil {
+			return nil, err
+		}
+	}
+	return &result, nil
+}
+
+// RestoreMount puts back into service a mount marked for deletion
+func (c *Sys) RestoreMount(path string) error {
+	r := c.c.NewRequest("POST", fmt.Sprintf("/v1/sys/mounts/%s/restore", path))
+	resp, err := c.c.RawRequest(r)
+	if err == nil {
+		defer resp.Body.Close()
+	}
+	return err
+}
+
+// PurgeMount unmounts a mount marked for deletion without waiting for its
+// recovery window to elapse
+func (c *Sys) PurgeMount(path string, force bool) error {
+	body := map[string]interface{}{
+		"force": force,
+	}
+
+	r := c.c.NewRequest("POST", fmt.Sprintf("/v1/sys/mounts/%s/purge", path))
+	if err := r.SetJSONBody(body); err != nil {
+		return err
+	}
+
+	resp, err := c.c.RawRequest(r)
+	if err == nil {
+		defer resp.Body.Close()
+	}
+	return err
+}
+
 func (c *Sys) Remount(from, to string) error {
 	body := map[string]interface{}{
 		"from": from,
@@ -133,6 +196,17 @@ type MountConfigInput struct {
 	MaxLeaseTTL     string `json:"max_lease_ttl" structs:"max_lease_ttl" mapstructure:"max_lease_ttl"`
 	ForceNoCache    bool   `json:"force_no_cache" structs:"force_no_cache" mapstructure:"force_no_cache"`
 	PluginName      string `json:"plugin_name,omitempty" structs:"plugin_name,omitempty" mapstructure:"plugin_name"`
+	PluginVersion   string `json:"plugin_version,omitempty" structs:"plugin_version,omitempty" mapstructure:"plugin_version"`
+
+	SyncExternalGroups bool   `json:"sync_external_groups,omitempty" structs:"sync_external_groups,omitempty" mapstructure:"sync_external_groups"`
+	TokenType          string `json:"token_type,omitempty" structs:"token_type,omitempty" mapstructure:"token_type"`
+
+	AuditNonHMACRequestKeys   []string `json:"audit_non_hmac_request_keys,omitempty" structs:"audit_non_hmac_request_keys,omitempty" mapstructure:"audit_non_hmac_request_keys"`
+	AuditNonHMACResponseKeys  []string `json:"audit_non_hmac_response_keys,omitempty" structs:"audit_non_hmac_response_keys,omitempty" mapstructure:"audit_non_hmac_response_keys"`
+	ListingVisibility         string   `json:"listing_visibility,omitempty" structs:"listing_visibility,omitempty" mapstructure:"listing_visibility"`
+	PassthroughRequestHeaders []string `json:"passthrough_request_headers,omitempty" structs:"passthrough_request_headers,omitempty" mapstructure:"passthrough_request_headers"`
+	AllowedResponseHeaders    []string `json:"allowed_response_headers,omitempty" structs:"allowed_response_headers,omitempty" mapstructure:"allowed_response_headers"`
+	MaxInFlightRequests       int      `json:"max_in_flight_requests,omitempty" structs:"max_in_flight_requests,omitempty" mapstructure:"max_in_flight_requests"`
 }
 
 type MountOutput struct {
@@ -142,6 +216,16 @@ type MountOutput struct {
 	Config      MountConfigOutput `json:"config" structs:"config"`
 	Local       bool              `json:"local" structs:"local"`
 	SealWrap    bool              `json:"seal_wrap" structs:"seal_wrap" mapstructure:"seal_wrap"`
+	PurgeTime   string            `json:"purge_time,omitempty" structs:"purge_time" mapstructure:"purge_time"`
+}
+
+type UnmountOptions struct {
+	RecoveryWindow time.Duration
+	Force          bool
+}
+
+type UnmountOutput struct {
+	PurgeTime string `json:"purge_time" structs:"purge_time" mapstructure:"purge_time"`
 }
 
 type MountConfigOutput struct {
@@ -149,4 +233,15 @@ type MountConfigOutput struct {
 	MaxLeaseTTL     int    `json:"max_lease_ttl" structs:"max_lease_ttl" mapstructure:"max_lease_ttl"`
 	ForceNoCache    bool   `json:"force_no_cache" structs:"force_no_cache" mapstructure:"force_no_cache"`
 	PluginName      string `json:"plugin_name,omitempty" structs:"plugin_name,omitempty" mapstructure:"plugin_name"`
+	PluginVersion   string `json:"plugin_version,omitempty" structs:"plugin_version,omitempty" mapstructure:"plugin_version"`
+
+	SyncExternalGroups bool   `json:"sync_external_groups,omitempty" structs:"sync_external_groups" mapstructure:"sync_external_groups"`
+	TokenType          string `json:"token_type,omitempty" structs:"token_type" mapstructure:"token_type"`
+
+	AuditNonHMACRequestKeys   []string `json:"audit_non_hmac_request_keys,omitempty" structs:"audit_non_hmac_request_keys" mapstructure:"audit_non_hmac_request_keys"`
+	AuditNonHMACResponseKeys  []string `json:"audit_non_hmac_response_keys,omitempty" structs:"audit_non_hmac_response_keys" mapstructure:"audit_non_hmac_response_keys"`
+	ListingVisibility         string   `json:"listing_visibility,omitempty" structs:"listing_visibility" mapstructure:"listing_visibility"`
+	PassthroughRequestHeaders []string `json:"passthrough_request_headers,omitempty" structs:"passthrough_request_headers" mapstructure:"passthrough_request_headers"`
+	AllowedResponseHeaders    []string `json:"allowed_response_headers,omitempty" structs:"allowed_response_headers" mapstructure:"allowed_response_headers"`
+	MaxInFlightRequests       int      `json:"max_in_flight_requests,omitempty" structs:"max_in_flight_requests" mapstructure:"max_in_flight_requests"`
 }
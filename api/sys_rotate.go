@@ -1,6 +1,9 @@
 package api
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 func (c *Sys) Rotate() error {
 	r := c.c.NewRequest("POST", "/v1/sys/rotate")
@@ -24,7 +27,60 @@ func (c *Sys) KeyStatus() (*KeyStatus, error) {
 	return result, err
 }
 
+func (c *Sys) RotateConfig() (*RotateConfig, error) {
+	r := c.c.NewRequest("GET", "/v1/sys/rotate/config")
+	resp, err := c.c.RawRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Data *RotateConfig `json:"data"`
+	}
+	if err := resp.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+	if result.Data == nil {
+		return nil, fmt.Errorf("missing data in response")
+	}
+	return result.Data, nil
+}
+
+func (c *Sys) SetRotateConfig(config *RotateConfigInput) error {
+	r := c.c.NewRequest("PUT", "/v1/sys/rotate/config")
+	if err := r.SetJSONBody(config); err != nil {
+		return err
+	}
+
+	resp, err := c.c.RawRequest(r)
+	if err == nil {
+		defer resp.Body.Close()
+	}
+	return err
+}
+
 type KeyStatus struct {
+	Term        int              `json:"term"`
+	InstallTime time.Time        `json:"install_time"`
+	Encryptions uint64           `json:"encryptions"`
+	Keys        []*KeyTermStatus `json:"keys"`
+}
+
+type KeyTermStatus struct {
 	Term        int       `json:"term"`
 	InstallTime time.Time `json:"install_time"`
+	Encryptions uint64    `json:"encryptions"`
+}
+
+type RotateConfig struct {
+	Enabled       bool   `json:"enabled"`
+	MaxOperations uint64 `json:"max_operations"`
+	Interval      int64  `json:"interval"`
+}
+
+type RotateConfigInput struct {
+	Enabled       *bool  `json:"enabled,omitempty"`
+	MaxOperations uint64 `json:"max_operations,omitempty"`
+	Interval      string `json:"interval,omitempty"`
 }
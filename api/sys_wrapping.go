@@ -0,0 +1,116 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// WrapLookup looks up the creation path, time and TTL of a wrapping token.
+// The lookup fails if the token was already unwrapped, or if it was revoked
+// or expired.
+func (c *Sys) WrapLookup(token string) (*WrapLookupResponse, error) {
+	r := c.c.NewRequest("PUT", "/v1/sys/wrapping/lookup")
+	if err := r.SetJSONBody(map[string]interface{}{"token": token}); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.c.RawRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Data *WrapLookupResponse `json:"data"`
+	}
+	if err := resp.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+	if result.Data == nil {
+		return nil, fmt.Errorf("data from server response is empty")
+	}
+	return result.Data, nil
+}
+
+// ValidateWrapping checks that a wrapping token can still be unwrapped and
+// that it was created by a request to one of the given paths, which match
+// the paths starting with them if they end in '*'. It is meant to be called
+// before unwrapping a token, to detect the tokens that were tampered with or
+// already unwrapped by someone else.
+func (c *Sys) ValidateWrapping(token string, creationPaths ...string) (*WrapLookupResponse, error) {
+	lookup, err := c.WrapLookup(token)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, path := range creationPaths {
+		if strings.HasSuffix(path, "*") {
+			if strings.HasPrefix(lookup.CreationPath, strings.TrimSuffix(path, "*")) {
+				return lookup, nil
+			}
+		} else if lookup.CreationPath == path {
+			return lookup, nil
+		}
+	}
+	return nil, fmt.Errorf("wrapping token was created by an unexpected path %q", lookup.CreationPath)
+}
+
+// Rewrap rotates a wrapping token, returning the wrapping information of the
+// new token wrapping the same response. The original token is revoked.
+func (c *Sys) Rewrap(token string) (*SecretWrapInfo, error) {
+	r := c.c.NewRequest("PUT", "/v1/sys/wrapping/rewrap")
+	if err := r.SetJSONBody(map[string]interface{}{"token": token}); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.c.RawRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	secret, err := ParseSecret(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if secret.WrapInfo == nil {
+		return nil, fmt.Errorf("wrap info from server response is empty")
+	}
+	return secret.WrapInfo, nil
+}
+
+// RewrapBatch rotates a batch of wrapping tokens, returning the wrapping
+// information of the new tokens in the order of the tokens. The original
+// tokens are revoked.
+func (c *Sys) RewrapBatch(tokens []string) ([]*SecretWrapInfo, error) {
+	r := c.c.NewRequest("PUT", "/v1/sys/wrapping/rewrap")
+	if err := r.SetJSONBody(map[string]interface{}{"tokens": tokens}); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.c.RawRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Data struct {
+			WrapInfos []*SecretWrapInfo `json:"wrap_infos"`
+		} `json:"data"`
+	}
+	if err := resp.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+	if len(result.Data.WrapInfos) != len(tokens) {
+		return nil, fmt.Errorf("expected %d wrap infos in server response, got %d", len(tokens), len(result.Data.WrapInfos))
+	}
+	return result.Data.WrapInfos, nil
+}
+
+type WrapLookupResponse struct {
+	CreationPath string    `json:"creation_path"`
+	CreationTime time.Time `json:"creation_time"`
+	CreationTTL  int       `json:"creation_ttl"`
+}
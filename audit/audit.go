@@ -36,6 +36,13 @@ type Backend interface {
 	Invalidate(context.Context)
 }
 
+// Closer is implemented by the audit backends which hold resources, such as
+// the delivery of their buffered entries, which must be released when the
+// backend is disabled or Vault is sealed
+type Closer interface {
+	Close() error
+}
+
 type BackendConfig struct {
 	// The view to store the salt
 	SaltView logical.Storage
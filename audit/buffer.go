@@ -0,0 +1,263 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	multierror "github.com/hashicorp/go-multierror"
+)
+
+const (
+	// DefaultBufferMaxSize is the default maximum size, in bytes, of the
+	// entries held by an entry buffer
+	DefaultBufferMaxSize = 100 * 1024 * 1024
+
+	// bufferEntrySuffix is the suffix of the files of the buffered entries
+	bufferEntrySuffix = ".entry"
+)
+
+// SendFunc delivers a formatted audit entry to the destination of a backend
+type SendFunc func(context.Context, []byte) error
+
+// EntryBuffer delivers the audit entries of a backend to a remote
+// destination, keeping the entries which cannot be delivered in a bounded
+// on-disk queue until the destination is available again. Once an entry is
+// buffered, Vault considers it logged: the buffer is durable and survives
+// restarts. Entries are delivered in order and at least once, so new entries
+// are buffered as long as older ones are pending.
+//
+// When the buffer is full, entries are rejected so that the requests fail
+// rather than go unaudited.
+type EntryBuffer struct {
+	dir     string
+	maxSize int64
+	send    SendFunc
+
+	// retryMin and retryMax bound the exponential backoff between the
+	// delivery attempts of the buffered entries
+	retryMin time.Duration
+	retryMax time.Duration
+
+	l        sync.Mutex
+	pending  []uint64
+	sizes    map[uint64]int64
+	size     int64
+	next     uint64
+	draining bool
+	closed   bool
+	stopCh   chan struct{}
+}
+
+// NewEntryBuffer returns an entry buffer keeping its entries in the given
+// directory. The entries left in the directory, e.g. by a previous run of
+// Vault, are delivered first.
+func NewEntryBuffer(dir string, maxSize int64, send SendFunc) (*EntryBuffer, error) {
+	return newEntryBuffer(dir, maxSize, send, time.Second, time.Minute)
+}
+
+func newEntryBuffer(dir string, maxSize int64, send SendFunc, retryMin, retryMax time.Duration) (*EntryBuffer, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultBufferMaxSize
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create buffer directory: %v", err)
+	}
+
+	b := &EntryBuffer{
+		dir:      dir,
+		maxSize:  maxSize,
+		send:     send,
+		retryMin: retryMin,
+		retryMax: retryMax,
+		sizes:    make(map[uint64]int64),
+		stopCh:   make(chan struct{}),
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read buffer directory: %v", err)
+	}
+	for _, file := range files {
+		name := file.Name()
+		if !strings.HasSuffix(name, bufferEntrySuffix) {
+			// Leftovers of interrupted writes, which were never reported as
+			// logged
+			if strings.HasSuffix(name, ".tmp") {
+				os.Remove(filepath.Join(dir, name))
+			}
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, bufferEntrySuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		b.pending = append(b.pending, seq)
+		b.sizes[seq] = file.Size()
+		b.size += file.Size()
+		if seq >= b.next {
+			b.next = seq + 1
+		}
+	}
+	sort.Slice(b.pending, func(i, j int) bool { return b.pending[i] < b.pending[j] })
+
+	if len(b.pending) > 0 {
+		b.startDrainLocked()
+	}
+
+	return b, nil
+}
+
+// Write delivers the entry, or buffers it if it cannot be delivered or older
+// entries are still pending. An error is only returned when the entry could be
+// neither delivered nor buffered.
+func (b *EntryBuffer) Write(ctx context.Context, entry []byte) error {
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	if b.closed {
+		return fmt.Errorf("audit buffer is closed")
+	}
+
+	var retErr *multierror.Error
+	if len(b.pending) == 0 {
+		err := b.send(ctx, entry)
+		if err == nil {
+			return nil
+		}
+		retErr = multierror.Append(retErr, err)
+	}
+
+	if err := b.appendLocked(entry); err != nil {
+		retErr = multierror.Append(retErr, err)
+		return retErr.ErrorOrNil()
+	}
+	b.startDrainLocked()
+
+	return nil
+}
+
+// Len returns the number of buffered entries
+func (b *EntryBuffer) Len() int {
+	b.l.Lock()
+	defer b.l.Unlock()
+	return len(b.pending)
+}
+
+// Close stops the delivery of the buffered entries, which stay on disk
+func (b *EntryBuffer) Close() error {
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	if !b.closed {
+		b.closed = true
+		close(b.stopCh)
+	}
+	return nil
+}
+
+func (b *EntryBuffer) entryPath(seq uint64) string {
+	return filepath.Join(b.dir, fmt.Sprintf("%020d%s", seq, bufferEntrySuffix))
+}
+
+// appendLocked durably writes the entry at the end of the queue.
+// N.B.: This must be called with the lock held.
+func (b *EntryBuffer) appendLocked(entry []byte) error {
+	size := int64(len(entry))
+	if b.size+size > b.maxSize {
+		return fmt.Errorf("audit buffer is full")
+	}
+
+	seq := b.next
+	path := b.entryPath(seq)
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to buffer audit entry: %v", err)
+	}
+	_, err = f.Write(entry)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cErr := f.Close(); err == nil {
+		err = cErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to buffer audit entry: %v", err)
+	}
+
+	b.next++
+	b.pending = append(b.pending, seq)
+	b.sizes[seq] = size
+	b.size += size
+	return nil
+}
+
+// startDrainLocked starts the delivery of the buffered entries, unless it is
+// already running.
+// N.B.: This must be called with the lock held.
+func (b *EntryBuffer) startDrainLocked() {
+	if b.draining || b.closed {
+		return
+	}
+	b.draining = true
+	go b.drain()
+}
+
+// drain delivers the buffered entries in order, retrying with an exponential
+// backoff, until the buffer is empty or closed
+func (b *EntryBuffer) drain() {
+	backoff := b.retryMin
+	for {
+		b.l.Lock()
+		if b.closed || len(b.pending) == 0 {
+			b.draining = false
+			b.l.Unlock()
+			return
+		}
+		seq := b.pending[0]
+		b.l.Unlock()
+
+		// The head of the queue is only removed here, so the entry can be
+		// delivered without holding the lock
+		path := b.entryPath(seq)
+		entry, err := ioutil.ReadFile(path)
+		if err == nil {
+			err = b.send(context.Background(), entry)
+		} else if os.IsNotExist(err) {
+			// Removed from outside of Vault, nothing left to deliver
+			err = nil
+		}
+
+		if err != nil {
+			select {
+			case <-b.stopCh:
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+			if backoff > b.retryMax {
+				backoff = b.retryMax
+			}
+			continue
+		}
+		backoff = b.retryMin
+
+		b.l.Lock()
+		os.Remove(path)
+		b.pending = b.pending[1:]
+		b.size -= b.sizes[seq]
+		delete(b.sizes, seq)
+		b.l.Unlock()
+	}
+}
@@ -0,0 +1,163 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testSender records the delivered entries, failing while down is set
+type testSender struct {
+	l       sync.Mutex
+	down    bool
+	entries []string
+}
+
+func (s *testSender) send(_ context.Context, entry []byte) error {
+	s.l.Lock()
+	defer s.l.Unlock()
+	if s.down {
+		return fmt.Errorf("destination unavailable")
+	}
+	s.entries = append(s.entries, string(entry))
+	return nil
+}
+
+func (s *testSender) setDown(down bool) {
+	s.l.Lock()
+	defer s.l.Unlock()
+	s.down = down
+}
+
+func (s *testSender) delivered() []string {
+	s.l.Lock()
+	defer s.l.Unlock()
+	return append([]string(nil), s.entries...)
+}
+
+func testEntryBuffer(t *testing.T, dir string, maxSize int64, sender *testSender) *EntryBuffer {
+	t.Helper()
+
+	b, err := newEntryBuffer(dir, maxSize, sender.send, 10*time.Millisecond, 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func waitDrained(t *testing.T, b *EntryBuffer) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for b.Len() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("buffer not drained: %d entries left", b.Len())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestEntryBuffer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vault-audit-buffer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	sender := &testSender{}
+	b := testEntryBuffer(t, dir, 0, sender)
+	defer b.Close()
+
+	// Entries are delivered directly while the destination is available
+	if err := b.Write(context.Background(), []byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	if b.Len() != 0 {
+		t.Fatalf("expected no buffered entry, got %d", b.Len())
+	}
+
+	// Entries are buffered while it is not, and delivered in order once it is
+	// available again
+	sender.setDown(true)
+	for _, entry := range []string{"b", "c"} {
+		if err := b.Write(context.Background(), []byte(entry)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sender.setDown(false)
+	if err := b.Write(context.Background(), []byte("d")); err != nil {
+		t.Fatal(err)
+	}
+	waitDrained(t, b)
+
+	expected := []string{"a", "b", "c", "d"}
+	if actual := sender.delivered(); !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("expected %v to be %v", actual, expected)
+	}
+	if files, _ := ioutil.ReadDir(dir); len(files) != 0 {
+		t.Fatalf("expected empty buffer directory, got %d files", len(files))
+	}
+}
+
+func TestEntryBuffer_full(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vault-audit-buffer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	sender := &testSender{down: true}
+	b := testEntryBuffer(t, dir, 5, sender)
+	defer b.Close()
+
+	if err := b.Write(context.Background(), []byte("abc")); err != nil {
+		t.Fatal(err)
+	}
+	err = b.Write(context.Background(), []byte("def"))
+	if err == nil || !strings.Contains(err.Error(), "audit buffer is full") {
+		t.Fatalf("expected full buffer error, got %v", err)
+	}
+	if b.Len() != 1 {
+		t.Fatalf("expected 1 buffered entry, got %d", b.Len())
+	}
+}
+
+func TestEntryBuffer_restart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vault-audit-buffer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	sender := &testSender{down: true}
+	b := testEntryBuffer(t, dir, 0, sender)
+	for _, entry := range []string{"a", "b"} {
+		if err := b.Write(context.Background(), []byte(entry)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b.Close()
+	if err := b.Write(context.Background(), []byte("c")); err == nil {
+		t.Fatal("expected error writing to closed buffer")
+	}
+
+	// The entries left by the closed buffer are delivered by the next one
+	sender.setDown(false)
+	b = testEntryBuffer(t, dir, 0, sender)
+	defer b.Close()
+	if err := b.Write(context.Background(), []byte("c")); err != nil {
+		t.Fatal(err)
+	}
+	waitDrained(t, b)
+
+	expected := []string{"a", "b", "c"}
+	if actual := sender.delivered(); !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("expected %v to be %v", actual, expected)
+	}
+}
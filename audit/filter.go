@@ -0,0 +1,254 @@
+package audit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/hashicorp/vault/helper/strutil"
+)
+
+// FilterFields are the fields of the requests which filter expressions can
+// match
+var FilterFields = []string{
+	"mount_point",
+	"mount_type",
+	"namespace",
+	"operation",
+}
+
+// Filter is a parsed filter expression of an audit device, which selects the
+// requests logged by the device. Expressions compare fields to quoted strings
+// with == and !=, and combine the comparisons with and, or, not and
+// parentheses, e.g.:
+//
+//	mount_type == "kv" and not (operation == "read" or operation == "list")
+type Filter struct {
+	expr string
+	root filterNode
+}
+
+// filterNode is a node of the tree of a filter expression
+type filterNode interface {
+	match(values map[string]string) bool
+}
+
+type filterCompare struct {
+	field string
+	value string
+	equal bool
+}
+
+func (n *filterCompare) match(values map[string]string) bool {
+	return (values[n.field] == n.value) == n.equal
+}
+
+type filterAnd struct {
+	left, right filterNode
+}
+
+func (n *filterAnd) match(values map[string]string) bool {
+	return n.left.match(values) && n.right.match(values)
+}
+
+type filterOr struct {
+	left, right filterNode
+}
+
+func (n *filterOr) match(values map[string]string) bool {
+	return n.left.match(values) || n.right.match(values)
+}
+
+type filterNot struct {
+	node filterNode
+}
+
+func (n *filterNot) match(values map[string]string) bool {
+	return !n.node.match(values)
+}
+
+// ParseFilter parses a filter expression
+func ParseFilter(expr string) (*Filter, error) {
+	tokens, err := filterTokens(expr)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty filter expression")
+	}
+
+	p := &filterParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q in filter expression", p.tokens[p.pos])
+	}
+
+	return &Filter{
+		expr: expr,
+		root: root,
+	}, nil
+}
+
+// Match returns whether the request with the given field values is selected
+// by the filter
+func (f *Filter) Match(values map[string]string) bool {
+	if f == nil {
+		return true
+	}
+	return f.root.match(values)
+}
+
+func (f *Filter) String() string {
+	return f.expr
+}
+
+type filterParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *filterParser) next() string {
+	if p.pos >= len(p.tokens) {
+		return ""
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *filterParser) parseOr() (filterNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.next() == "or" {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &filterOr{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (filterNode, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for p.next() == "and" {
+		p.pos++
+		right, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		left = &filterAnd{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseTerm() (filterNode, error) {
+	switch token := p.next(); token {
+	case "":
+		return nil, fmt.Errorf("unexpected end of filter expression")
+
+	case "not":
+		p.pos++
+		node, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		return &filterNot{node: node}, nil
+
+	case "(":
+		p.pos++
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, fmt.Errorf("missing closing parenthesis in filter expression")
+		}
+		p.pos++
+		return node, nil
+
+	default:
+		if !strutil.StrListContains(FilterFields, token) {
+			return nil, fmt.Errorf("unknown filter field %q; supported fields are %s", token, strings.Join(FilterFields, ", "))
+		}
+		p.pos++
+
+		op := p.next()
+		if op != "==" && op != "!=" {
+			return nil, fmt.Errorf("expected == or != after %q in filter expression", token)
+		}
+		p.pos++
+
+		raw := p.next()
+		if !strings.HasPrefix(raw, `"`) {
+			return nil, fmt.Errorf("expected a quoted string after %q in filter expression", token+" "+op)
+		}
+		value, err := strconv.Unquote(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid string %s in filter expression", raw)
+		}
+		p.pos++
+
+		return &filterCompare{
+			field: token,
+			value: value,
+			equal: op == "==",
+		}, nil
+	}
+}
+
+// filterTokens splits a filter expression into identifiers, operators,
+// parentheses and quoted strings
+func filterTokens(expr string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case unicode.IsSpace(rune(c)):
+			i++
+
+		case c == '(' || c == ')':
+			tokens = append(tokens, string(c))
+			i++
+
+		case c == '=' || c == '!':
+			if i+1 >= len(expr) || expr[i+1] != '=' {
+				return nil, fmt.Errorf("invalid operator at position %d of filter expression", i)
+			}
+			tokens = append(tokens, expr[i:i+2])
+			i += 2
+
+		case c == '"':
+			j := i + 1
+			for ; j < len(expr) && expr[j] != '"'; j++ {
+				if expr[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(expr) {
+				return nil, fmt.Errorf("unterminated string in filter expression")
+			}
+			tokens = append(tokens, expr[i:j+1])
+			i = j + 1
+
+		case c == '_' || unicode.IsLetter(rune(c)):
+			j := i
+			for ; j < len(expr) && (expr[j] == '_' || unicode.IsLetter(rune(expr[j])) || unicode.IsDigit(rune(expr[j]))); j++ {
+			}
+			tokens = append(tokens, expr[i:j])
+			i = j
+
+		default:
+			return nil, fmt.Errorf("unexpected %q in filter expression", c)
+		}
+	}
+	return tokens, nil
+}
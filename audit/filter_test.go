@@ -0,0 +1,69 @@
+package audit
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseFilter(t *testing.T) {
+	values := map[string]string{
+		"mount_point": "secret/",
+		"mount_type":  "kv",
+		"namespace":   "ns1/",
+		"operation":   "read",
+	}
+
+	cases := []struct {
+		expr     string
+		expected bool
+	}{
+		{`mount_type == "kv"`, true},
+		{`mount_type != "kv"`, false},
+		{`mount_point == "secret/" and operation == "update"`, false},
+		{`mount_point == "secret/" and operation == "update" or namespace == "ns1/"`, true},
+		{`mount_point == "secret/" and (operation == "update" or namespace == "ns1/")`, true},
+		{`not (mount_type == "kv" and operation == "read")`, false},
+		{`not mount_type == "kv" or operation == "read"`, true},
+		{`namespace == ""`, false},
+		{`mount_point == "se\"cret/"`, false},
+	}
+
+	for _, tc := range cases {
+		filter, err := ParseFilter(tc.expr)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.expr, err)
+		}
+		if actual := filter.Match(values); actual != tc.expected {
+			t.Errorf("%s: expected %t, got %t", tc.expr, tc.expected, actual)
+		}
+	}
+
+	// A nil filter matches all the requests
+	var filter *Filter
+	if !filter.Match(values) {
+		t.Fatal("nil filter should match")
+	}
+}
+
+func TestParseFilter_errors(t *testing.T) {
+	cases := []struct {
+		expr     string
+		expected string
+	}{
+		{``, "empty filter expression"},
+		{`path == "secret/"`, `unknown filter field "path"`},
+		{`mount_type = "kv"`, "invalid operator"},
+		{`mount_type == kv`, "expected a quoted string"},
+		{`mount_type == "kv`, "unterminated string"},
+		{`(mount_type == "kv"`, "missing closing parenthesis"},
+		{`mount_type == "kv" and`, "unexpected end of filter expression"},
+		{`mount_type == "kv" operation == "read"`, `unexpected "operation"`},
+	}
+
+	for _, tc := range cases {
+		_, err := ParseFilter(tc.expr)
+		if err == nil || !strings.Contains(err.Error(), tc.expected) {
+			t.Errorf("%s: expected error containing %q, got %v", tc.expr, tc.expected, err)
+		}
+	}
+}
@@ -49,6 +49,9 @@ func (f *AuditFormatter) FormatRequest(
 		return errwrap.Wrapf("error fetching salt: {{err}}", err)
 	}
 
+	// The keys of the mount of the request which are not HMAC'd
+	mountNonHMACReqKeys, _ := req.AuditNonHMACKeys()
+
 	if !config.Raw {
 		// Before we copy the structure we must nil out some data
 		// otherwise we will cause reflection to panic and die
@@ -96,12 +99,14 @@ func (f *AuditFormatter) FormatRequest(
 		if !config.HMACAccessor && req != nil && req.ClientTokenAccessor != "" {
 			clientTokenAccessor = req.ClientTokenAccessor
 		}
+		nonHMACReqData := cacheNonHMACData(req.Data, config.NonHMACRequestDataKeys, mountNonHMACReqKeys)
 		if err := Hash(salt, req); err != nil {
 			return err
 		}
 		if clientTokenAccessor != "" {
 			req.ClientTokenAccessor = clientTokenAccessor
 		}
+		restoreNonHMACData(req.Data, nonHMACReqData)
 	}
 
 	// If auth is nil, make an empty one
@@ -177,6 +182,9 @@ func (f *AuditFormatter) FormatResponse(
 		return errwrap.Wrapf("error fetching salt: {{err}}", err)
 	}
 
+	// The keys of the mount of the request which are not HMAC'd
+	mountNonHMACReqKeys, mountNonHMACRespKeys := req.AuditNonHMACKeys()
+
 	if !config.Raw {
 		// Before we copy the structure we must nil out some data
 		// otherwise we will cause reflection to panic and die
@@ -233,12 +241,14 @@ func (f *AuditFormatter) FormatResponse(
 		if !config.HMACAccessor && req != nil && req.ClientTokenAccessor != "" {
 			clientTokenAccessor = req.ClientTokenAccessor
 		}
+		nonHMACReqData := cacheNonHMACData(req.Data, config.NonHMACRequestDataKeys, mountNonHMACReqKeys)
 		if err := Hash(salt, req); err != nil {
 			return err
 		}
 		if clientTokenAccessor != "" {
 			req.ClientTokenAccessor = clientTokenAccessor
 		}
+		restoreNonHMACData(req.Data, nonHMACReqData)
 
 		// Cache and restore accessor in the response
 		if resp != nil {
@@ -250,9 +260,11 @@ func (f *AuditFormatter) FormatResponse(
 				wrappedAccessor = resp.WrapInfo.WrappedAccessor
 				wrappingAccessor = resp.WrapInfo.Accessor
 			}
+			nonHMACRespData := cacheNonHMACData(resp.Data, config.NonHMACResponseDataKeys, mountNonHMACRespKeys)
 			if err := Hash(salt, resp); err != nil {
 				return err
 			}
+			restoreNonHMACData(resp.Data, nonHMACRespData)
 			if accessor != "" {
 				resp.Auth.Accessor = accessor
 			}
@@ -358,6 +370,33 @@ func (f *AuditFormatter) FormatResponse(
 	return f.AuditFormatWriter.WriteResponse(w, respEntry)
 }
 
+// cacheNonHMACData removes the values of the given keys from the copied data
+// so that they are not HMAC'd, returning them to be restored once the rest of
+// the data is hashed
+func cacheNonHMACData(data map[string]interface{}, keyLists ...[]string) map[string]interface{} {
+	if len(data) == 0 {
+		return nil
+	}
+
+	cached := make(map[string]interface{})
+	for _, keys := range keyLists {
+		for _, key := range keys {
+			if v, ok := data[key]; ok {
+				cached[key] = v
+				delete(data, key)
+			}
+		}
+	}
+	return cached
+}
+
+// restoreNonHMACData restores the values removed by cacheNonHMACData
+func restoreNonHMACData(data map[string]interface{}, cached map[string]interface{}) {
+	for k, v := range cached {
+		data[k] = v
+	}
+}
+
 // AuditRequest is the structure of a request audit log entry in Audit.
 type AuditRequestEntry struct {
 	Time    string       `json:"time,omitempty"`
@@ -3,6 +3,7 @@ package audit
 import (
 	"io"
 	"io/ioutil"
+	"reflect"
 	"testing"
 
 	"github.com/hashicorp/vault/helper/salt"
@@ -61,3 +62,114 @@ func TestFormatResponseErrors(t *testing.T) {
 		t.Fatal("expected error due to nil writer")
 	}
 }
+
+type entryFormatWriter struct {
+	noopFormatWriter
+	resp *AuditResponseEntry
+}
+
+func (n *entryFormatWriter) WriteResponse(_ io.Writer, e *AuditResponseEntry) error {
+	n.resp = e
+	return nil
+}
+
+func TestFormatResponse_nonHMACKeys(t *testing.T) {
+	config := FormatterConfig{
+		NonHMACRequestDataKeys:  []string{"name"},
+		NonHMACResponseDataKeys: []string{"version"},
+	}
+	writer := &entryFormatWriter{}
+	formatter := AuditFormatter{
+		AuditFormatWriter: writer,
+	}
+	salter, err := writer.Salt()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := &logical.Request{
+		Data: map[string]interface{}{
+			"name":     "foo",
+			"password": "bar",
+		},
+	}
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"version": "1",
+			"value":   "baz",
+		},
+	}
+	if err := formatter.FormatResponse(ioutil.Discard, config, nil, req, resp, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedReq := map[string]interface{}{
+		"name":     "foo",
+		"password": salter.GetIdentifiedHMAC("bar"),
+	}
+	if !reflect.DeepEqual(writer.resp.Request.Data, expectedReq) {
+		t.Fatalf("bad request data: %#v", writer.resp.Request.Data)
+	}
+	expectedResp := map[string]interface{}{
+		"version": "1",
+		"value":   salter.GetIdentifiedHMAC("baz"),
+	}
+	if !reflect.DeepEqual(writer.resp.Response.Data, expectedResp) {
+		t.Fatalf("bad response data: %#v", writer.resp.Response.Data)
+	}
+
+	// The data of the request and response are not modified
+	if req.Data["password"] != "bar" || resp.Data["value"] != "baz" {
+		t.Fatalf("data modified: %#v %#v", req.Data, resp.Data)
+	}
+}
+
+func TestFormatResponse_mountNonHMACKeys(t *testing.T) {
+	config := FormatterConfig{
+		NonHMACRequestDataKeys: []string{"name"},
+	}
+	writer := &entryFormatWriter{}
+	formatter := AuditFormatter{
+		AuditFormatWriter: writer,
+	}
+	salter, err := writer.Salt()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := &logical.Request{
+		Data: map[string]interface{}{
+			"name":     "foo",
+			"role":     "admin",
+			"password": "bar",
+		},
+	}
+	req.SetAuditNonHMACKeys([]string{"role"}, []string{"version"})
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"version": "1",
+			"value":   "baz",
+		},
+	}
+	if err := formatter.FormatResponse(ioutil.Discard, config, nil, req, resp, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// The keys of the mount are not HMAC'd in addition to the ones of the
+	// audit device
+	expectedReq := map[string]interface{}{
+		"name":     "foo",
+		"role":     "admin",
+		"password": salter.GetIdentifiedHMAC("bar"),
+	}
+	if !reflect.DeepEqual(writer.resp.Request.Data, expectedReq) {
+		t.Fatalf("bad request data: %#v", writer.resp.Request.Data)
+	}
+	expectedResp := map[string]interface{}{
+		"version": "1",
+		"value":   salter.GetIdentifiedHMAC("baz"),
+	}
+	if !reflect.DeepEqual(writer.resp.Response.Data, expectedResp) {
+		t.Fatalf("bad response data: %#v", writer.resp.Response.Data)
+	}
+}
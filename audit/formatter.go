@@ -20,6 +20,11 @@ type FormatterConfig struct {
 	Raw          bool
 	HMACAccessor bool
 
+	// NonHMACRequestDataKeys and NonHMACResponseDataKeys are the keys of the
+	// request and response data whose values are logged without being HMAC'd
+	NonHMACRequestDataKeys  []string
+	NonHMACResponseDataKeys []string
+
 	// This should only ever be used in a testing context
 	OmitTime bool
 }
@@ -0,0 +1,301 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/vault/audit"
+	"github.com/hashicorp/vault/helper/parseutil"
+	"github.com/hashicorp/vault/helper/salt"
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/logical"
+)
+
+func Factory(ctx context.Context, conf *audit.BackendConfig) (audit.Backend, error) {
+	if conf.SaltConfig == nil {
+		return nil, fmt.Errorf("nil salt config")
+	}
+	if conf.SaltView == nil {
+		return nil, fmt.Errorf("nil salt view")
+	}
+
+	address, ok := conf.Config["address"]
+	if !ok {
+		return nil, fmt.Errorf("address is required")
+	}
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address: %v", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("address must be an http or https URL")
+	}
+
+	writeDeadline, ok := conf.Config["write_timeout"]
+	if !ok {
+		writeDeadline = "2s"
+	}
+	writeDuration, err := parseutil.ParseDurationSecond(writeDeadline)
+	if err != nil {
+		return nil, err
+	}
+
+	format, ok := conf.Config["format"]
+	if !ok {
+		format = "json"
+	}
+	switch format {
+	case "json", "jsonx":
+	default:
+		return nil, fmt.Errorf("unknown format type %s", format)
+	}
+
+	// Check if hashing of accessor is disabled
+	hmacAccessor := true
+	if hmacAccessorRaw, ok := conf.Config["hmac_accessor"]; ok {
+		value, err := strconv.ParseBool(hmacAccessorRaw)
+		if err != nil {
+			return nil, err
+		}
+		hmacAccessor = value
+	}
+
+	// Check if raw logging is enabled
+	logRaw := false
+	if raw, ok := conf.Config["log_raw"]; ok {
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, err
+		}
+		logRaw = b
+	}
+
+	// Get the data keys whose values are not HMAC'd
+	var nonHMACReqKeys, nonHMACRespKeys []string
+	if keys, ok := conf.Config["non_hmac_request_keys"]; ok {
+		nonHMACReqKeys = strutil.ParseDedupAndSortStrings(keys, ",")
+	}
+	if keys, ok := conf.Config["non_hmac_response_keys"]; ok {
+		nonHMACRespKeys = strutil.ParseDedupAndSortStrings(keys, ",")
+	}
+
+	tlsConfig, err := parseTLSConfig(conf.Config)
+	if err != nil {
+		return nil, err
+	}
+	transport := cleanhttp.DefaultPooledTransport()
+	transport.TLSClientConfig = tlsConfig
+
+	b := &Backend{
+		address: address,
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   writeDuration,
+		},
+		saltConfig: conf.SaltConfig,
+		saltView:   conf.SaltView,
+		formatConfig: audit.FormatterConfig{
+			Raw:                     logRaw,
+			HMACAccessor:            hmacAccessor,
+			NonHMACRequestDataKeys:  nonHMACReqKeys,
+			NonHMACResponseDataKeys: nonHMACRespKeys,
+		},
+	}
+
+	switch format {
+	case "json":
+		b.contentType = "application/json"
+		b.formatter.AuditFormatWriter = &audit.JSONFormatWriter{
+			Prefix:   conf.Config["prefix"],
+			SaltFunc: b.Salt,
+		}
+	case "jsonx":
+		b.contentType = "application/xml"
+		b.formatter.AuditFormatWriter = &audit.JSONxFormatWriter{
+			Prefix:   conf.Config["prefix"],
+			SaltFunc: b.Salt,
+		}
+	}
+
+	// Buffer the entries on disk while the collector is unavailable, if
+	// enabled
+	if bufferPath, ok := conf.Config["buffer_path"]; ok {
+		maxSize := int64(audit.DefaultBufferMaxSize)
+		if maxSizeRaw, ok := conf.Config["buffer_max_size"]; ok {
+			maxSize, err = strconv.ParseInt(maxSizeRaw, 10, 64)
+			if err != nil {
+				return nil, err
+			}
+		}
+		b.buffer, err = audit.NewEntryBuffer(bufferPath, maxSize, b.send)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return b, nil
+}
+
+// parseTLSConfig returns the TLS configuration of the connections to the
+// collector
+func parseTLSConfig(conf map[string]string) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if skipRaw, ok := conf["tls_skip_verify"]; ok {
+		skip, err := strconv.ParseBool(skipRaw)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.InsecureSkipVerify = skip
+	}
+
+	if caCert, ok := conf["tls_ca_cert"]; ok {
+		pem, err := ioutil.ReadFile(caCert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA certificate: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in %s", caCert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	clientCert, certOK := conf["tls_client_cert"]
+	clientKey, keyOK := conf["tls_client_key"]
+	switch {
+	case certOK && keyOK:
+		cert, err := tls.LoadX509KeyPair(clientCert, clientKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	case certOK || keyOK:
+		return nil, fmt.Errorf("tls_client_cert and tls_client_key must be set together")
+	}
+
+	return tlsConfig, nil
+}
+
+// Backend is the audit backend posting the entries to an HTTP collector.
+type Backend struct {
+	address     string
+	contentType string
+	client      *http.Client
+
+	formatter    audit.AuditFormatter
+	formatConfig audit.FormatterConfig
+
+	// buffer holds the entries while the collector is unavailable, if enabled
+	buffer *audit.EntryBuffer
+
+	saltMutex  sync.RWMutex
+	salt       *salt.Salt
+	saltConfig *salt.Config
+	saltView   logical.Storage
+}
+
+func (b *Backend) GetHash(data string) (string, error) {
+	salt, err := b.Salt()
+	if err != nil {
+		return "", err
+	}
+	return audit.HashString(salt, data), nil
+}
+
+func (b *Backend) LogRequest(ctx context.Context, auth *logical.Auth, req *logical.Request, outerErr error) error {
+	var buf bytes.Buffer
+	if err := b.formatter.FormatRequest(&buf, b.formatConfig, auth, req, outerErr); err != nil {
+		return err
+	}
+
+	if b.buffer != nil {
+		return b.buffer.Write(ctx, buf.Bytes())
+	}
+	return b.send(ctx, buf.Bytes())
+}
+
+func (b *Backend) LogResponse(ctx context.Context, auth *logical.Auth, req *logical.Request,
+	resp *logical.Response, outerErr error) error {
+	var buf bytes.Buffer
+	if err := b.formatter.FormatResponse(&buf, b.formatConfig, auth, req, resp, outerErr); err != nil {
+		return err
+	}
+
+	if b.buffer != nil {
+		return b.buffer.Write(ctx, buf.Bytes())
+	}
+	return b.send(ctx, buf.Bytes())
+}
+
+// send posts the entry to the collector, which must reply with a 2xx status
+func (b *Backend) send(ctx context.Context, buf []byte) error {
+	req, err := http.NewRequest("POST", b.address, bytes.NewReader(buf))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", b.contentType)
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("audit collector returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (b *Backend) Reload(_ context.Context) error {
+	return nil
+}
+
+// Close stops the delivery of the buffered entries
+func (b *Backend) Close() error {
+	if b.buffer != nil {
+		return b.buffer.Close()
+	}
+	return nil
+}
+
+func (b *Backend) Salt() (*salt.Salt, error) {
+	b.saltMutex.RLock()
+	if b.salt != nil {
+		defer b.saltMutex.RUnlock()
+		return b.salt, nil
+	}
+	b.saltMutex.RUnlock()
+	b.saltMutex.Lock()
+	defer b.saltMutex.Unlock()
+	if b.salt != nil {
+		return b.salt, nil
+	}
+	salt, err := salt.NewSalt(b.saltView, b.saltConfig)
+	if err != nil {
+		return nil, err
+	}
+	b.salt = salt
+	return salt, nil
+}
+
+func (b *Backend) Invalidate(_ context.Context) {
+	b.saltMutex.Lock()
+	defer b.saltMutex.Unlock()
+	b.salt = nil
+}
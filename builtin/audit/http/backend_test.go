@@ -0,0 +1,137 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/audit"
+	"github.com/hashicorp/vault/helper/salt"
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestAuditHTTP_buffer(t *testing.T) {
+	var l sync.Mutex
+	var paths []string
+	down := true
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		l.Lock()
+		defer l.Unlock()
+		if down {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("bad content type: %s", r.Header.Get("Content-Type"))
+		}
+		var entry audit.AuditRequestEntry
+		if err := json.NewDecoder(r.Body).Decode(&entry); err != nil {
+			t.Error(err)
+		}
+		paths = append(paths, entry.Request.Path)
+	}))
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "vault-audit-http")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	config := map[string]string{
+		"address":     ts.URL,
+		"buffer_path": dir,
+	}
+	b, err := Factory(context.Background(), &audit.BackendConfig{
+		SaltConfig: &salt.Config{},
+		SaltView:   &logical.InmemStorage{},
+		Config:     config,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.(*Backend).Close()
+
+	// The request is buffered while the collector is unavailable, so it is
+	// logged successfully
+	req := &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "secret/foo",
+	}
+	if err := b.LogRequest(context.Background(), nil, req, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	l.Lock()
+	down = false
+	l.Unlock()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		l.Lock()
+		n := len(paths)
+		l.Unlock()
+		if n > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("buffered entry not delivered")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	if err := b.LogRequest(context.Background(), nil, req, nil); err != nil {
+		t.Fatal(err)
+	}
+	l.Lock()
+	defer l.Unlock()
+	if len(paths) != 2 || paths[0] != "secret/foo" || paths[1] != "secret/foo" {
+		t.Fatalf("bad delivered entries: %v", paths)
+	}
+}
+
+func TestAuditHTTP_unbuffered(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	b, err := Factory(context.Background(), &audit.BackendConfig{
+		SaltConfig: &salt.Config{},
+		SaltView:   &logical.InmemStorage{},
+		Config: map[string]string{
+			"address": ts.URL,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Without a buffer, the failure of the collector fails the request
+	req := &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "secret/foo",
+	}
+	if err := b.LogRequest(context.Background(), nil, req, nil); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestAuditHTTP_invalidAddress(t *testing.T) {
+	_, err := Factory(context.Background(), &audit.BackendConfig{
+		SaltConfig: &salt.Config{},
+		SaltView:   &logical.InmemStorage{},
+		Config: map[string]string{
+			"address": "127.0.0.1:8080",
+		},
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/hashicorp/vault/audit"
 	"github.com/hashicorp/vault/helper/parseutil"
 	"github.com/hashicorp/vault/helper/salt"
+	"github.com/hashicorp/vault/helper/strutil"
 	"github.com/hashicorp/vault/logical"
 )
 
@@ -73,12 +74,23 @@ func Factory(ctx context.Context, conf *audit.BackendConfig) (audit.Backend, err
 		logRaw = b
 	}
 
+	// Get the data keys whose values are not HMAC'd
+	var nonHMACReqKeys, nonHMACRespKeys []string
+	if keys, ok := conf.Config["non_hmac_request_keys"]; ok {
+		nonHMACReqKeys = strutil.ParseDedupAndSortStrings(keys, ",")
+	}
+	if keys, ok := conf.Config["non_hmac_response_keys"]; ok {
+		nonHMACRespKeys = strutil.ParseDedupAndSortStrings(keys, ",")
+	}
+
 	b := &Backend{
 		saltConfig: conf.SaltConfig,
 		saltView:   conf.SaltView,
 		formatConfig: audit.FormatterConfig{
-			Raw:          logRaw,
-			HMACAccessor: hmacAccessor,
+			Raw:                     logRaw,
+			HMACAccessor:            hmacAccessor,
+			NonHMACRequestDataKeys:  nonHMACReqKeys,
+			NonHMACResponseDataKeys: nonHMACRespKeys,
 		},
 
 		writeDuration: writeDuration,
@@ -99,6 +111,21 @@ func Factory(ctx context.Context, conf *audit.BackendConfig) (audit.Backend, err
 		}
 	}
 
+	// Buffer the entries on disk while the socket is unavailable, if enabled
+	if bufferPath, ok := conf.Config["buffer_path"]; ok {
+		maxSize := int64(audit.DefaultBufferMaxSize)
+		if maxSizeRaw, ok := conf.Config["buffer_max_size"]; ok {
+			maxSize, err = strconv.ParseInt(maxSizeRaw, 10, 64)
+			if err != nil {
+				return nil, err
+			}
+		}
+		b.buffer, err = audit.NewEntryBuffer(bufferPath, maxSize, b.send)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return b, nil
 }
 
@@ -113,6 +140,9 @@ type Backend struct {
 	address       string
 	socketType    string
 
+	// buffer holds the entries while the socket is unavailable, if enabled
+	buffer *audit.EntryBuffer
+
 	sync.Mutex
 
 	saltMutex  sync.RWMutex
@@ -135,21 +165,10 @@ func (b *Backend) LogRequest(ctx context.Context, auth *logical.Auth, req *logic
 		return err
 	}
 
-	b.Lock()
-	defer b.Unlock()
-
-	err := b.write(ctx, buf.Bytes())
-	if err != nil {
-		rErr := b.reconnect(ctx)
-		if rErr != nil {
-			err = multierror.Append(err, rErr)
-		} else {
-			// Try once more after reconnecting
-			err = b.write(ctx, buf.Bytes())
-		}
+	if b.buffer != nil {
+		return b.buffer.Write(ctx, buf.Bytes())
 	}
-
-	return err
+	return b.send(ctx, buf.Bytes())
 }
 
 func (b *Backend) LogResponse(ctx context.Context, auth *logical.Auth, req *logical.Request,
@@ -159,17 +178,25 @@ func (b *Backend) LogResponse(ctx context.Context, auth *logical.Auth, req *logi
 		return err
 	}
 
+	if b.buffer != nil {
+		return b.buffer.Write(ctx, buf.Bytes())
+	}
+	return b.send(ctx, buf.Bytes())
+}
+
+// send writes the entry to the socket, reconnecting once if the write fails
+func (b *Backend) send(ctx context.Context, buf []byte) error {
 	b.Lock()
 	defer b.Unlock()
 
-	err := b.write(ctx, buf.Bytes())
+	err := b.write(ctx, buf)
 	if err != nil {
 		rErr := b.reconnect(ctx)
 		if rErr != nil {
 			err = multierror.Append(err, rErr)
 		} else {
 			// Try once more after reconnecting
-			err = b.write(ctx, buf.Bytes())
+			err = b.write(ctx, buf)
 		}
 	}
 
@@ -222,6 +249,22 @@ func (b *Backend) Reload(ctx context.Context) error {
 	return err
 }
 
+// Close stops the delivery of the buffered entries and closes the socket
+func (b *Backend) Close() error {
+	if b.buffer != nil {
+		b.buffer.Close()
+	}
+
+	b.Lock()
+	defer b.Unlock()
+
+	if b.connection != nil {
+		b.connection.Close()
+		b.connection = nil
+	}
+	return nil
+}
+
 func (b *Backend) Salt() (*salt.Salt, error) {
 	b.saltMutex.RLock()
 	if b.salt != nil {
@@ -10,6 +10,7 @@ import (
 	"github.com/hashicorp/go-syslog"
 	"github.com/hashicorp/vault/audit"
 	"github.com/hashicorp/vault/helper/salt"
+	"github.com/hashicorp/vault/helper/strutil"
 	"github.com/hashicorp/vault/logical"
 )
 
@@ -63,6 +64,15 @@ func Factory(ctx context.Context, conf *audit.BackendConfig) (audit.Backend, err
 		logRaw = b
 	}
 
+	// Get the data keys whose values are not HMAC'd
+	var nonHMACReqKeys, nonHMACRespKeys []string
+	if keys, ok := conf.Config["non_hmac_request_keys"]; ok {
+		nonHMACReqKeys = strutil.ParseDedupAndSortStrings(keys, ",")
+	}
+	if keys, ok := conf.Config["non_hmac_response_keys"]; ok {
+		nonHMACRespKeys = strutil.ParseDedupAndSortStrings(keys, ",")
+	}
+
 	// Get the logger
 	logger, err := gsyslog.NewLogger(gsyslog.LOG_INFO, facility, tag)
 	if err != nil {
@@ -74,8 +84,10 @@ func Factory(ctx context.Context, conf *audit.BackendConfig) (audit.Backend, err
 		saltConfig: conf.SaltConfig,
 		saltView:   conf.SaltView,
 		formatConfig: audit.FormatterConfig{
-			Raw:          logRaw,
-			HMACAccessor: hmacAccessor,
+			Raw:                     logRaw,
+			HMACAccessor:            hmacAccessor,
+			NonHMACRequestDataKeys:  nonHMACReqKeys,
+			NonHMACResponseDataKeys: nonHMACRespKeys,
 		},
 	}
 
@@ -12,6 +12,7 @@ import (
 	"github.com/hashicorp/vault/helper/locksutil"
 	"github.com/hashicorp/vault/helper/policyutil"
 	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/helper/wrapping"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
 )
@@ -37,6 +38,11 @@ type roleStorageEntry struct {
 	// SecretID generated against the role will expire
 	SecretIDTTL time.Duration `json:"secret_id_ttl" structs:"secret_id_ttl" mapstructure:"secret_id_ttl"`
 
+	// Duration, if set, specifies that the SecretIDs generated against the
+	// role are always response wrapped, with a wrapping TTL of at most this
+	// value
+	SecretIDWrapTTL time.Duration `json:"secret_id_wrap_ttl" structs:"secret_id_wrap_ttl" mapstructure:"secret_id_wrap_ttl"`
+
 	// TokenNumUses defines the number of allowed uses of the token issued
 	TokenNumUses int `json:"token_num_uses" mapstructure:"token_num_uses" structs:"token_num_uses"`
 
@@ -77,6 +83,7 @@ type roleIDStorageEntry struct {
 // role/<role_name>/policies - For updating the param
 // role/<role_name>/secret-id-num-uses - For updating the param
 // role/<role_name>/secret-id-ttl - For updating the param
+// role/<role_name>/secret-id-wrap-ttl - For updating the param
 // role/<role_name>/token-ttl - For updating the param
 // role/<role_name>/token-max-ttl - For updating the param
 // role/<role_name>/token-num-uses - For updating the param
@@ -131,6 +138,12 @@ will expire. Defaults to 0 meaning that the the secret_id is of unlimited use.`,
 					Type: framework.TypeDurationSecond,
 					Description: `Duration in seconds after which the issued SecretID should expire. Defaults
 to 0, meaning no expiration.`,
+				},
+				"secret_id_wrap_ttl": &framework.FieldSchema{
+					Type: framework.TypeDurationSecond,
+					Description: `If set, the SecretIDs generated against the role are always response wrapped,
+with a wrapping TTL of at most this duration in seconds. Defaults to 0, meaning
+that wrapping is not enforced.`,
 				},
 				"token_num_uses": &framework.FieldSchema{
 					Type:        framework.TypeInt,
@@ -273,6 +286,28 @@ to 0, meaning no expiration.`,
 			HelpSynopsis:    strings.TrimSpace(roleHelp["role-secret-id-ttl"][0]),
 			HelpDescription: strings.TrimSpace(roleHelp["role-secret-id-ttl"][1]),
 		},
+		&framework.Path{
+			Pattern: "role/" + framework.GenericNameRegex("role_name") + "/secret-id-wrap-ttl$",
+			Fields: map[string]*framework.FieldSchema{
+				"role_name": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "Name of the role.",
+				},
+				"secret_id_wrap_ttl": &framework.FieldSchema{
+					Type: framework.TypeDurationSecond,
+					Description: `If set, the SecretIDs generated against the role are always response wrapped,
+with a wrapping TTL of at most this duration in seconds. Defaults to 0, meaning
+that wrapping is not enforced.`,
+				},
+			},
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: b.pathRoleSecretIDWrapTTLUpdate,
+				logical.ReadOperation:   b.pathRoleSecretIDWrapTTLRead,
+				logical.DeleteOperation: b.pathRoleSecretIDWrapTTLDelete,
+			},
+			HelpSynopsis:    strings.TrimSpace(roleHelp["role-secret-id-wrap-ttl"][0]),
+			HelpDescription: strings.TrimSpace(roleHelp["role-secret-id-wrap-ttl"][1]),
+		},
 		&framework.Path{
 			Pattern: "role/" + framework.GenericNameRegex("role_name") + "/period$",
 			Fields: map[string]*framework.FieldSchema{
@@ -820,6 +855,15 @@ func (b *backend) pathRoleCreateUpdate(ctx context.Context, req *logical.Request
 		role.SecretIDTTL = time.Second * time.Duration(data.Get("secret_id_ttl").(int))
 	}
 
+	if secretIDWrapTTLRaw, ok := data.GetOk("secret_id_wrap_ttl"); ok {
+		role.SecretIDWrapTTL = time.Second * time.Duration(secretIDWrapTTLRaw.(int))
+	} else if req.Operation == logical.CreateOperation {
+		role.SecretIDWrapTTL = time.Second * time.Duration(data.Get("secret_id_wrap_ttl").(int))
+	}
+	if role.SecretIDWrapTTL < 0 {
+		return logical.ErrorResponse("secret_id_wrap_ttl cannot be negative"), nil
+	}
+
 	if tokenNumUsesRaw, ok := data.GetOk("token_num_uses"); ok {
 		role.TokenNumUses = tokenNumUsesRaw.(int)
 	} else if req.Operation == logical.CreateOperation {
@@ -887,6 +931,7 @@ func (b *backend) pathRoleRead(ctx context.Context, req *logical.Request, data *
 		"policies":           role.Policies,
 		"secret_id_num_uses": role.SecretIDNumUses,
 		"secret_id_ttl":      role.SecretIDTTL / time.Second,
+		"secret_id_wrap_ttl": role.SecretIDWrapTTL / time.Second,
 		"token_max_ttl":      role.TokenMaxTTL / time.Second,
 		"token_num_uses":     role.TokenNumUses,
 		"token_ttl":          role.TokenTTL / time.Second,
@@ -1652,6 +1697,84 @@ func (b *backend) pathRoleSecretIDTTLDelete(ctx context.Context, req *logical.Re
 	return nil, b.setRoleEntry(ctx, req.Storage, roleName, role, "")
 }
 
+func (b *backend) pathRoleSecretIDWrapTTLUpdate(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	roleName := data.Get("role_name").(string)
+	if roleName == "" {
+		return logical.ErrorResponse("missing role_name"), nil
+	}
+
+	lock := b.roleLock(roleName)
+	lock.Lock()
+	defer lock.Unlock()
+
+	role, err := b.roleEntry(ctx, req.Storage, strings.ToLower(roleName))
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, nil
+	}
+
+	secretIDWrapTTLRaw, ok := data.GetOk("secret_id_wrap_ttl")
+	if !ok {
+		return logical.ErrorResponse("missing secret_id_wrap_ttl"), nil
+	}
+	role.SecretIDWrapTTL = time.Second * time.Duration(secretIDWrapTTLRaw.(int))
+	if role.SecretIDWrapTTL < 0 {
+		return logical.ErrorResponse("secret_id_wrap_ttl cannot be negative"), nil
+	}
+
+	return nil, b.setRoleEntry(ctx, req.Storage, roleName, role, "")
+}
+
+func (b *backend) pathRoleSecretIDWrapTTLRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	roleName := data.Get("role_name").(string)
+	if roleName == "" {
+		return logical.ErrorResponse("missing role_name"), nil
+	}
+
+	lock := b.roleLock(roleName)
+	lock.RLock()
+	defer lock.RUnlock()
+
+	role, err := b.roleEntry(ctx, req.Storage, strings.ToLower(roleName))
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"secret_id_wrap_ttl": role.SecretIDWrapTTL / time.Second,
+		},
+	}, nil
+}
+
+func (b *backend) pathRoleSecretIDWrapTTLDelete(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	roleName := data.Get("role_name").(string)
+	if roleName == "" {
+		return logical.ErrorResponse("missing role_name"), nil
+	}
+
+	lock := b.roleLock(roleName)
+	lock.Lock()
+	defer lock.Unlock()
+
+	role, err := b.roleEntry(ctx, req.Storage, strings.ToLower(roleName))
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, nil
+	}
+
+	role.SecretIDWrapTTL = time.Second * time.Duration(data.GetDefaultOrZero("secret_id_wrap_ttl").(int))
+
+	return nil, b.setRoleEntry(ctx, req.Storage, roleName, role, "")
+}
+
 func (b *backend) pathRolePeriodUpdate(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	roleName := data.Get("role_name").(string)
 	if roleName == "" {
@@ -2030,12 +2153,22 @@ func (b *backend) handleRoleSecretIDCommon(ctx context.Context, req *logical.Req
 		return nil, fmt.Errorf("failed to store secret_id: %v", err)
 	}
 
-	return &logical.Response{
+	resp := &logical.Response{
 		Data: map[string]interface{}{
 			"secret_id":          secretID,
 			"secret_id_accessor": secretIDStorage.SecretIDAccessor,
 		},
-	}, nil
+	}
+
+	// Enforce the response wrapping of the SecretID. If the request asks for
+	// a shorter wrapping TTL, the shorter one is used.
+	if role.SecretIDWrapTTL > 0 {
+		resp.WrapInfo = &wrapping.ResponseWrapInfo{
+			TTL: role.SecretIDWrapTTL,
+		}
+	}
+
+	return resp, nil
 }
 
 func (b *backend) roleIDLock(roleID string) *locksutil.LockEntry {
@@ -2165,6 +2298,15 @@ that are generated against the role using 'role/<role_name>/secret-id' or
 'role/<role_name>/custom-secret-id' endpoints.`,
 		``,
 	},
+	"role-secret-id-wrap-ttl": {
+		`Duration in seconds, representing the maximum wrapping TTL of the SecretIDs
+that are generated against the role`,
+		`If set, the SecretIDs generated against the role using the
+'role/<role_name>/secret-id' or 'role/<role_name>/custom-secret-id' endpoints
+are always returned in response-wrapping tokens, whose TTL is the shorter of
+this value and the wrapping TTL requested by the client. This ensures that the
+SecretIDs are only delivered through single-use wrapping tokens.`,
+	},
 	"role-secret-id-lookup": {
 		"Read the properties of an issued secret_id",
 		`This endpoint is used to read the properties of a secret_id associated to a
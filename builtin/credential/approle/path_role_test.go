@@ -818,6 +818,111 @@ func TestAppRole_RoleSecretID(t *testing.T) {
 	}
 }
 
+func TestAppRole_RoleSecretIDWrapTTL(t *testing.T) {
+	var resp *logical.Response
+	var err error
+	b, storage := createBackendWithStorage(t)
+
+	roleReq := &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "role/role1",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"policies":           "p,q",
+			"secret_id_wrap_ttl": -1,
+		},
+	}
+	resp, err = b.HandleRequest(context.Background(), roleReq)
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error for negative secret_id_wrap_ttl; err:%v resp:%#v", err, resp)
+	}
+
+	roleReq.Data["secret_id_wrap_ttl"] = 120
+	resp, err = b.HandleRequest(context.Background(), roleReq)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+
+	roleReq.Operation = logical.ReadOperation
+	resp, err = b.HandleRequest(context.Background(), roleReq)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	if resp.Data["secret_id_wrap_ttl"].(time.Duration) != 120 {
+		t.Fatalf("bad: secret_id_wrap_ttl: expected:120 actual:%d", resp.Data["secret_id_wrap_ttl"].(time.Duration))
+	}
+
+	// Both the generated and the custom SecretIDs should be wrapped
+	roleSecretIDReq := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "role/role1/secret-id",
+		Storage:   storage,
+	}
+	resp, err = b.HandleRequest(context.Background(), roleSecretIDReq)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	if resp.WrapInfo == nil || resp.WrapInfo.TTL != 120*time.Second {
+		t.Fatalf("expected the secret_id to be wrapped; resp:%#v", resp)
+	}
+
+	roleSecretIDReq.Path = "role/role1/custom-secret-id"
+	roleSecretIDReq.Data = map[string]interface{}{
+		"secret_id": "abcd123",
+	}
+	resp, err = b.HandleRequest(context.Background(), roleSecretIDReq)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	if resp.WrapInfo == nil || resp.WrapInfo.TTL != 120*time.Second {
+		t.Fatalf("expected the custom secret_id to be wrapped; resp:%#v", resp)
+	}
+
+	// RUD for secret_id_wrap_ttl field
+	roleReq.Path = "role/role1/secret-id-wrap-ttl"
+	roleReq.Data = map[string]interface{}{"secret_id_wrap_ttl": 60}
+	roleReq.Operation = logical.UpdateOperation
+	resp, err = b.HandleRequest(context.Background(), roleReq)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+
+	roleReq.Operation = logical.ReadOperation
+	resp, err = b.HandleRequest(context.Background(), roleReq)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	if resp.Data["secret_id_wrap_ttl"].(time.Duration) != 60 {
+		t.Fatalf("bad: secret_id_wrap_ttl: expected:60 actual:%d", resp.Data["secret_id_wrap_ttl"].(time.Duration))
+	}
+
+	roleReq.Operation = logical.DeleteOperation
+	resp, err = b.HandleRequest(context.Background(), roleReq)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+
+	roleReq.Operation = logical.ReadOperation
+	resp, err = b.HandleRequest(context.Background(), roleReq)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	if resp.Data["secret_id_wrap_ttl"].(time.Duration) != 0 {
+		t.Fatalf("expected value to be reset")
+	}
+
+	// Wrapping is no longer enforced once the field is reset
+	roleSecretIDReq.Path = "role/role1/secret-id"
+	roleSecretIDReq.Data = nil
+	resp, err = b.HandleRequest(context.Background(), roleSecretIDReq)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	if resp.WrapInfo != nil {
+		t.Fatalf("expected the secret_id to not be wrapped; resp:%#v", resp)
+	}
+}
+
 func TestAppRole_RoleCRUD(t *testing.T) {
 	var resp *logical.Response
 	var err error
@@ -2,9 +2,12 @@ package cert
 
 import (
 	"context"
+	"crypto/x509/pkix"
+	"net/http"
 	"strings"
 	"sync"
 
+	"github.com/hashicorp/go-cleanhttp"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
 )
@@ -40,6 +43,10 @@ func Backend() *backend {
 
 	b.crlUpdateMutex = &sync.RWMutex{}
 
+	b.revocationClient = cleanhttp.DefaultClient()
+	b.ocspCache = make(map[string]cachedOCSPResult)
+	b.crlDistributionPointCache = make(map[string]*pkix.CertificateList)
+
 	return &b
 }
 
@@ -49,6 +56,16 @@ type backend struct {
 
 	crls           map[string]CRLInfo
 	crlUpdateMutex *sync.RWMutex
+
+	// revocationClient is used to query the OCSP responders and to download
+	// the CRLs of the distribution points of the client certificates
+	revocationClient *http.Client
+
+	// The responses of the OCSP responders and the CRLs of the distribution
+	// points are cached until their next update
+	revocationCacheLock       sync.RWMutex
+	ocspCache                 map[string]cachedOCSPResult
+	crlDistributionPointCache map[string]*pkix.CertificateList
 }
 
 func (b *backend) invalidate(_ context.Context, key string) {
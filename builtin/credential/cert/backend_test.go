@@ -2,6 +2,8 @@ package cert
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/tls"
@@ -1278,3 +1280,71 @@ func Test_Renew(t *testing.T) {
 		t.Fatal("expected error")
 	}
 }
+
+func TestValidateConnState_listenerVerifiedChains(t *testing.T) {
+	root := newTestRevocationCA(t)
+
+	// The intermediate CA is known to the listener, but not sent by the
+	// client
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(2),
+		Subject:               pkix.Name{CommonName: "Intermediate CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	}
+	certBytes, err := x509.CreateCertificate(rand.Reader, template, root.cert, key.Public(), root.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	intermediateCert, err := x509.ParseCertificate(certBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	intermediate := &testRevocationCA{cert: intermediateCert, key: key}
+	clientCert := intermediate.issue(t, 3, nil, nil)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(root.cert)
+
+	chains, err := validateConnState(roots, &tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{clientCert},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chains) != 0 {
+		t.Fatalf("expected no trusted chain without the intermediate, got %d", len(chains))
+	}
+
+	chains, err = validateConnState(roots, &tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{clientCert},
+		VerifiedChains:   [][]*x509.Certificate{{clientCert, intermediateCert}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chains) != 1 || len(chains[0]) != 3 {
+		t.Fatalf("expected a trusted chain through the intermediate, got %v", chains)
+	}
+
+	// The chains of the listener are not trusted by themselves
+	otherRoots := x509.NewCertPool()
+	otherRoots.AddCert(newTestRevocationCA(t).cert)
+	chains, err = validateConnState(otherRoots, &tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{clientCert},
+		VerifiedChains:   [][]*x509.Certificate{{clientCert, intermediateCert, root.cert}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chains) != 0 {
+		t.Fatalf("expected no trusted chain, got %d", len(chains))
+	}
+}
@@ -0,0 +1,127 @@
+package cert
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/hashicorp/vault/helper/ocsp"
+)
+
+// This file queries the revocation status of client certificates from their
+// OCSP responders.
+
+// ocspStatus is the revocation status of a certificate as reported by an
+// OCSP responder
+type ocspStatus int
+
+const (
+	ocspStatusGood ocspStatus = iota
+	ocspStatusRevoked
+	ocspStatusUnknown
+)
+
+func (s ocspStatus) String() string {
+	switch s {
+	case ocspStatusGood:
+		return "good"
+	case ocspStatusRevoked:
+		return "revoked"
+	}
+	return "unknown"
+}
+
+const (
+	// maxOCSPResponseSize caps the size of the responses read from the OCSP
+	// responders
+	maxOCSPResponseSize = 1024 * 1024
+
+	// ocspClockSkew is the leeway allowed when checking the validity period
+	// of the OCSP responses
+	ocspClockSkew = 5 * time.Minute
+)
+
+// ocspResult is the verified status of a certificate returned by an OCSP
+// responder
+type ocspResult struct {
+	Status     ocspStatus
+	NextUpdate time.Time
+}
+
+// queryOCSP asks the OCSP responder at the given URL for the revocation
+// status of the certificate, and verifies the response against the issuer
+func queryOCSP(ctx context.Context, client *http.Client, server string, cert, issuer *x509.Certificate) (*ocspResult, error) {
+	certID, err := ocsp.NewCertID(cert.SerialNumber, issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	reqBytes, err := ocsp.CreateRequest(certID)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequest(http.MethodPost, server, bytes.NewReader(reqBytes))
+	if err != nil {
+		return nil, err
+	}
+	httpReq = httpReq.WithContext(ctx)
+	httpReq.Header.Set("Content-Type", "application/ocsp-request")
+	httpReq.Header.Set("Accept", "application/ocsp-response")
+
+	httpResp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d from the OCSP responder", httpResp.StatusCode)
+	}
+
+	respBytes, err := ioutil.ReadAll(io.LimitReader(httpResp.Body, maxOCSPResponseSize))
+	if err != nil {
+		return nil, err
+	}
+
+	return parseOCSPResponse(respBytes, cert, issuer)
+}
+
+// parseOCSPResponse parses a DER encoded OCSP response, verifies its
+// signature and returns the status of the certificate
+func parseOCSPResponse(respBytes []byte, cert, issuer *x509.Certificate) (*ocspResult, error) {
+	now := time.Now()
+	data, err := ocsp.ParseResponse(respBytes, issuer, now)
+	if err != nil {
+		return nil, err
+	}
+
+	single := data.FindResponse(cert.SerialNumber, issuer)
+	if single == nil {
+		return nil, errors.New("OCSP response does not contain the status of the certificate")
+	}
+	if single.ThisUpdate.After(now.Add(ocspClockSkew)) {
+		return nil, errors.New("OCSP response is not yet valid")
+	}
+	if !single.NextUpdate.IsZero() && single.NextUpdate.Add(ocspClockSkew).Before(now) {
+		return nil, errors.New("OCSP response has expired")
+	}
+
+	result := &ocspResult{
+		Status:     ocspStatusUnknown,
+		NextUpdate: single.NextUpdate,
+	}
+	switch {
+	case bool(single.Good):
+		result.Status = ocspStatusGood
+	case !single.Revoked.RevocationTime.IsZero():
+		result.Status = ocspStatusRevoked
+	}
+	return result, nil
+}
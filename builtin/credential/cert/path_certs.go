@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/x509"
 	"fmt"
+	"net/url"
 	"strings"
 	"time"
 
@@ -88,6 +89,33 @@ should never expire. The token should be renewed within the
 duration specified by this value. At each renewal, the token's
 TTL will be set to the value of this parameter.`,
 			},
+			"ocsp_enabled": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `If set, the revocation status of the client certificate
+is checked against the OCSP responders listed in the certificate.`,
+			},
+			"ocsp_servers_override": &framework.FieldSchema{
+				Type: framework.TypeCommaStringSlice,
+				Description: `A comma-separated list of OCSP responder URLs to
+query instead of the ones listed in the client certificate.`,
+			},
+			"ocsp_fail_open": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `If set, the login is allowed when the OCSP status of
+the client certificate cannot be determined. Revoked certificates are always
+rejected.`,
+			},
+			"crl_distribution_points_enabled": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `If set, the client certificate is checked against the
+CRLs published at the HTTP distribution points listed in the certificate.`,
+			},
+			"crl_distribution_points_fail_open": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `If set, the login is allowed when none of the CRLs of
+the distribution points of the client certificate can be retrieved. Revoked
+certificates are always rejected.`,
+			},
 		},
 
 		Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -151,6 +179,12 @@ func (b *backend) pathCertRead(ctx context.Context, req *logical.Request, d *fra
 			"max_ttl":       cert.MaxTTL / time.Second,
 			"period":        cert.Period / time.Second,
 			"allowed_names": cert.AllowedNames,
+
+			"ocsp_enabled":                      cert.OCSPEnabled,
+			"ocsp_servers_override":             cert.OCSPServersOverride,
+			"ocsp_fail_open":                    cert.OCSPFailOpen,
+			"crl_distribution_points_enabled":   cert.CRLDistributionPointsEnabled,
+			"crl_distribution_points_fail_open": cert.CRLDistributionPointsFailOpen,
 		},
 	}, nil
 }
@@ -162,6 +196,7 @@ func (b *backend) pathCertWrite(ctx context.Context, req *logical.Request, d *fr
 	policies := policyutil.ParsePolicies(d.Get("policies"))
 	allowedNames := d.Get("allowed_names").([]string)
 	requiredExtensions := d.Get("required_extensions").([]string)
+	ocspServersOverride := d.Get("ocsp_servers_override").([]string)
 
 	var resp logical.Response
 
@@ -204,6 +239,13 @@ func (b *backend) pathCertWrite(ctx context.Context, req *logical.Request, d *fr
 		return logical.ErrorResponse("period cannot be negative"), nil
 	}
 
+	for _, server := range ocspServersOverride {
+		u, err := url.Parse(server)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return logical.ErrorResponse(fmt.Sprintf("invalid OCSP responder URL %q", server)), nil
+		}
+	}
+
 	// Default the display name to the certificate name if not given
 	if displayName == "" {
 		displayName = name
@@ -238,6 +280,12 @@ func (b *backend) pathCertWrite(ctx context.Context, req *logical.Request, d *fr
 		TTL:                ttl,
 		MaxTTL:             maxTTL,
 		Period:             period,
+
+		OCSPEnabled:                   d.Get("ocsp_enabled").(bool),
+		OCSPServersOverride:           ocspServersOverride,
+		OCSPFailOpen:                  d.Get("ocsp_fail_open").(bool),
+		CRLDistributionPointsEnabled:  d.Get("crl_distribution_points_enabled").(bool),
+		CRLDistributionPointsFailOpen: d.Get("crl_distribution_points_fail_open").(bool),
 	}
 
 	// Store it
@@ -266,6 +314,17 @@ type CertEntry struct {
 	Period             time.Duration
 	AllowedNames       []string
 	RequiredExtensions []string
+
+	// OCSPEnabled enables the checking of the client certificate against the
+	// OCSP responders listed in the certificate, or OCSPServersOverride if set
+	OCSPEnabled         bool
+	OCSPServersOverride []string
+	OCSPFailOpen        bool
+
+	// CRLDistributionPointsEnabled enables the checking of the client
+	// certificate against the CRLs of its distribution points
+	CRLDistributionPointsEnabled  bool
+	CRLDistributionPointsFailOpen bool
 }
 
 const pathCertHelpSyn = `
@@ -223,10 +223,14 @@ func (b *backend) verifyCredentials(ctx context.Context, req *logical.Request, d
 	if len(trustedNonCAs) != 0 {
 		for _, trustedNonCA := range trustedNonCAs {
 			tCert := trustedNonCA.Certificates[0]
+			// The issuer of the client cert is either part of the configured
+			// bundle or presented by the client
+			issuers := append(append([]*x509.Certificate{}, trustedNonCA.Certificates[1:]...), connState.PeerCertificates[1:]...)
 			// Check for client cert being explicitly listed in the config (and matching other constraints)
 			if tCert.SerialNumber.Cmp(clientCert.SerialNumber) == 0 &&
 				bytes.Equal(tCert.AuthorityKeyId, clientCert.AuthorityKeyId) &&
-				b.matchesConstraints(clientCert, trustedNonCA.Certificates, trustedNonCA) {
+				b.matchesConstraints(clientCert, trustedNonCA.Certificates, trustedNonCA) &&
+				b.matchesRevocationStatus(ctx, clientCert, issuers, trustedNonCA) {
 				return trustedNonCA, nil, nil
 			}
 		}
@@ -245,7 +249,8 @@ func (b *backend) verifyCredentials(ctx context.Context, req *logical.Request, d
 			for _, chain := range trustedChains { // For each root chain that we matched
 				for _, cCert := range chain { // For each cert in the matched chain
 					if tCert.Equal(cCert) && // ParsedCert intersects with matched chain
-						b.matchesConstraints(clientCert, chain, trust) { // validate client cert + matched chain against the config
+						b.matchesConstraints(clientCert, chain, trust) && // validate client cert + matched chain against the config
+						b.matchesRevocationStatus(ctx, clientCert, chain[1:], trust) { // check the revocation status of the client cert
 						// Add the match to the list
 						matches = append(matches, trust)
 					}
@@ -421,6 +426,11 @@ func parsePEM(raw []byte) (certs []*x509.Certificate) {
 // by at trusted certificate. Most of this logic is lifted from the client
 // verification logic here:  http://golang.org/src/crypto/tls/handshake_server.go
 // The trusted chains are returned.
+//
+// The certificates of the chains verified by the listener, if it verifies the
+// client certificates, are used as intermediates, so that clients presenting
+// only their certificate can log in with the intermediates known to the
+// listener. They are not trusted unless they chain to the roots.
 func validateConnState(roots *x509.CertPool, cs *tls.ConnectionState) ([][]*x509.Certificate, error) {
 	certs := cs.PeerCertificates
 	if len(certs) == 0 {
@@ -438,6 +448,11 @@ func validateConnState(roots *x509.CertPool, cs *tls.ConnectionState) ([][]*x509
 			opts.Intermediates.AddCert(cert)
 		}
 	}
+	for _, chain := range cs.VerifiedChains {
+		for _, cert := range chain[1:] {
+			opts.Intermediates.AddCert(cert)
+		}
+	}
 
 	chains, err := certs[0].Verify(opts)
 	if err != nil {
@@ -0,0 +1,255 @@
+package cert
+
+import (
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/hashicorp/vault/helper/ocsp"
+)
+
+const (
+	// maxCRLSize caps the size of the CRLs downloaded from the distribution
+	// points of the client certificates
+	maxCRLSize = 32 * 1024 * 1024
+
+	// revocationCheckTimeout bounds the time spent querying an OCSP responder
+	// or downloading a CRL
+	revocationCheckTimeout = 10 * time.Second
+)
+
+// errCertificateRevoked is returned when the client certificate has been
+// revoked by its issuer
+var errCertificateRevoked = errors.New("certificate has been revoked")
+
+// cachedOCSPResult is an OCSP status cached until the next update announced
+// by the responder
+type cachedOCSPResult struct {
+	status     ocspStatus
+	nextUpdate time.Time
+}
+
+// matchesRevocationStatus verifies that the client certificate has not been
+// revoked, according to the OCSP and CRL distribution point checks enabled on
+// the configured certificate. The issuers are the candidate issuers of the
+// client certificate. Failures to determine the revocation status reject the
+// certificate unless the respective check is configured to fail open.
+func (b *backend) matchesRevocationStatus(ctx context.Context, clientCert *x509.Certificate, issuers []*x509.Certificate, config *ParsedCert) bool {
+	entry := config.Entry
+	if !entry.OCSPEnabled && !entry.CRLDistributionPointsEnabled {
+		return true
+	}
+
+	issuer := findIssuer(clientCert, issuers)
+
+	if entry.OCSPEnabled {
+		err := b.checkOCSP(ctx, clientCert, issuer, entry.OCSPServersOverride)
+		switch {
+		case err == errCertificateRevoked:
+			b.Logger().Debug("cert: client certificate revoked according to OCSP", "name", entry.Name, "serial", clientCert.SerialNumber.String())
+			return false
+		case err != nil && !entry.OCSPFailOpen:
+			b.Logger().Warn("cert: failed to check the OCSP status of the client certificate", "name", entry.Name, "serial", clientCert.SerialNumber.String(), "error", err)
+			return false
+		case err != nil:
+			b.Logger().Warn("cert: failed to check the OCSP status of the client certificate, failing open", "name", entry.Name, "serial", clientCert.SerialNumber.String(), "error", err)
+		}
+	}
+
+	if entry.CRLDistributionPointsEnabled {
+		err := b.checkCRLDistributionPoints(ctx, clientCert, issuer)
+		switch {
+		case err == errCertificateRevoked:
+			b.Logger().Debug("cert: client certificate revoked according to its CRL distribution points", "name", entry.Name, "serial", clientCert.SerialNumber.String())
+			return false
+		case err != nil && !entry.CRLDistributionPointsFailOpen:
+			b.Logger().Warn("cert: failed to check the CRL distribution points of the client certificate", "name", entry.Name, "serial", clientCert.SerialNumber.String(), "error", err)
+			return false
+		case err != nil:
+			b.Logger().Warn("cert: failed to check the CRL distribution points of the client certificate, failing open", "name", entry.Name, "serial", clientCert.SerialNumber.String(), "error", err)
+		}
+	}
+
+	return true
+}
+
+// findIssuer returns the certificate among the candidates that signed the
+// given certificate, or nil if there is none
+func findIssuer(cert *x509.Certificate, candidates []*x509.Certificate) *x509.Certificate {
+	for _, candidate := range candidates {
+		if candidate == nil || candidate.Equal(cert) {
+			continue
+		}
+		if cert.CheckSignatureFrom(candidate) == nil {
+			return candidate
+		}
+	}
+	return nil
+}
+
+// checkOCSP queries the OCSP responders for the status of the certificate.
+// The responders listed in the certificate are used, unless servers are
+// given. It returns errCertificateRevoked if the certificate is revoked, and
+// nil only if a responder reported the certificate as good.
+func (b *backend) checkOCSP(ctx context.Context, cert, issuer *x509.Certificate, servers []string) error {
+	if issuer == nil {
+		return errors.New("issuer of the certificate not found")
+	}
+	if len(servers) == 0 {
+		servers = cert.OCSPServer
+	}
+	if len(servers) == 0 {
+		return errors.New("no OCSP responders configured or listed in the certificate")
+	}
+
+	certID, err := ocsp.NewCertID(cert.SerialNumber, issuer)
+	if err != nil {
+		return err
+	}
+	cacheKey := fmt.Sprintf("%x/%s", certID.IssuerKeyHash, cert.SerialNumber.String())
+
+	b.revocationCacheLock.RLock()
+	cached, ok := b.ocspCache[cacheKey]
+	b.revocationCacheLock.RUnlock()
+	if ok && time.Now().Before(cached.nextUpdate) {
+		return ocspStatusError(cached.status)
+	}
+
+	var lastErr error
+	for _, server := range servers {
+		queryCtx, cancel := context.WithTimeout(ctx, revocationCheckTimeout)
+		result, err := queryOCSP(queryCtx, b.revocationClient, server, cert, issuer)
+		cancel()
+		if err != nil {
+			lastErr = fmt.Errorf("OCSP responder %q: %v", server, err)
+			continue
+		}
+		if result.Status == ocspStatusUnknown {
+			lastErr = fmt.Errorf("OCSP responder %q does not know the certificate", server)
+			continue
+		}
+
+		if !result.NextUpdate.IsZero() {
+			b.revocationCacheLock.Lock()
+			b.ocspCache[cacheKey] = cachedOCSPResult{
+				status:     result.Status,
+				nextUpdate: result.NextUpdate,
+			}
+			b.revocationCacheLock.Unlock()
+		}
+
+		return ocspStatusError(result.Status)
+	}
+
+	return lastErr
+}
+
+func ocspStatusError(status ocspStatus) error {
+	switch status {
+	case ocspStatusGood:
+		return nil
+	case ocspStatusRevoked:
+		return errCertificateRevoked
+	}
+	return errors.New("unknown OCSP status")
+}
+
+// checkCRLDistributionPoints downloads the CRLs published at the HTTP
+// distribution points of the certificate and looks up its serial number. It
+// returns errCertificateRevoked if the certificate is revoked, and nil only if
+// at least one valid CRL issued by the issuer could be checked.
+func (b *backend) checkCRLDistributionPoints(ctx context.Context, cert, issuer *x509.Certificate) error {
+	if issuer == nil {
+		return errors.New("issuer of the certificate not found")
+	}
+
+	var checked bool
+	var lastErr error
+	for _, point := range cert.CRLDistributionPoints {
+		u, err := url.Parse(point)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			lastErr = fmt.Errorf("unsupported CRL distribution point %q", point)
+			continue
+		}
+
+		crl, err := b.distributionPointCRL(ctx, point, issuer)
+		if err != nil {
+			lastErr = fmt.Errorf("CRL distribution point %q: %v", point, err)
+			continue
+		}
+
+		for _, revoked := range crl.TBSCertList.RevokedCertificates {
+			if revoked.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+				return errCertificateRevoked
+			}
+		}
+		checked = true
+	}
+
+	if checked {
+		return nil
+	}
+	if lastErr == nil {
+		lastErr = errors.New("no CRL distribution points listed in the certificate")
+	}
+	return lastErr
+}
+
+// distributionPointCRL returns the CRL published at the given URL, using the
+// cached copy until its next update
+func (b *backend) distributionPointCRL(ctx context.Context, point string, issuer *x509.Certificate) (*pkix.CertificateList, error) {
+	b.revocationCacheLock.RLock()
+	crl, ok := b.crlDistributionPointCache[point]
+	b.revocationCacheLock.RUnlock()
+	if ok && !crl.HasExpired(time.Now()) {
+		if err := issuer.CheckCRLSignature(crl); err == nil {
+			return crl, nil
+		}
+	}
+
+	fetchCtx, cancel := context.WithTimeout(ctx, revocationCheckTimeout)
+	defer cancel()
+
+	httpReq, err := http.NewRequest(http.MethodGet, point, nil)
+	if err != nil {
+		return nil, err
+	}
+	httpResp, err := b.revocationClient.Do(httpReq.WithContext(fetchCtx))
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", httpResp.StatusCode)
+	}
+
+	crlBytes, err := ioutil.ReadAll(io.LimitReader(httpResp.Body, maxCRLSize))
+	if err != nil {
+		return nil, err
+	}
+
+	crl, err = x509.ParseCRL(crlBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CRL: %v", err)
+	}
+	if err := issuer.CheckCRLSignature(crl); err != nil {
+		return nil, fmt.Errorf("CRL is not signed by the issuer of the certificate: %v", err)
+	}
+	if crl.HasExpired(time.Now()) {
+		return nil, errors.New("CRL has expired")
+	}
+
+	b.revocationCacheLock.Lock()
+	b.crlDistributionPointCache[point] = crl
+	b.revocationCacheLock.Unlock()
+
+	return crl, nil
+}
@@ -0,0 +1,358 @@
+package cert
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/helper/ocsp"
+	"github.com/hashicorp/vault/logical"
+)
+
+type testRevocationCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestRevocationCA(t *testing.T) *testRevocationCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Revocation Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	}
+	certBytes, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(certBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testRevocationCA{cert: cert, key: key}
+}
+
+func (ca *testRevocationCA) pem() string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw}))
+}
+
+func (ca *testRevocationCA) issue(t *testing.T, serial int64, ocspServers, crlDistributionPoints []string) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{CommonName: "client.example.com"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		OCSPServer:            ocspServers,
+		CRLDistributionPoints: crlDistributionPoints,
+	}
+	certBytes, err := x509.CreateCertificate(rand.Reader, template, ca.cert, key.Public(), ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(certBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+// ocspResponse returns a DER encoded OCSP response signed by the CA
+func (ca *testRevocationCA) ocspResponse(t *testing.T, certID ocsp.CertID, status ocspStatus) []byte {
+	now := time.Now().UTC().Truncate(time.Second)
+	single := ocsp.SingleResponse{
+		CertID:     certID,
+		ThisUpdate: now.Add(-time.Minute),
+		NextUpdate: now.Add(time.Hour),
+	}
+	switch status {
+	case ocspStatusGood:
+		single.Good = true
+	case ocspStatusRevoked:
+		single.Revoked = ocsp.RevokedInfo{RevocationTime: now.Add(-time.Minute)}
+	default:
+		single.Unknown = true
+	}
+
+	tbsBytes, err := asn1.Marshal(ocsp.ResponseData{
+		ResponderID: asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 1, IsCompound: true, Bytes: ca.cert.RawSubject},
+		ProducedAt:  now,
+		Responses:   []ocsp.SingleResponse{single},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256(tbsBytes)
+	signature, err := ca.key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	basicBytes, err := asn1.Marshal(ocsp.BasicResponse{
+		TBSResponseData:    ocsp.ResponseData{Raw: tbsBytes},
+		SignatureAlgorithm: pkix.AlgorithmIdentifier{Algorithm: asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}},
+		Signature:          asn1.BitString{Bytes: signature, BitLength: 8 * len(signature)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	respBytes, err := asn1.Marshal(ocsp.Response{
+		ResponseBytes: ocsp.ResponseBytes{
+			ResponseType: ocsp.OIDBasicResponse,
+			Response:     basicBytes,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return respBytes
+}
+
+// ocspResponder returns a responder answering with the status returned by
+// the given function, and counting the requests it serves
+func (ca *testRevocationCA) ocspResponder(t *testing.T, status func(serial *big.Int) ocspStatus, requests *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var req ocsp.Request
+		if _, err := asn1.Unmarshal(body, &req); err != nil || len(req.TBSRequest.RequestList) != 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		certID := req.TBSRequest.RequestList[0].CertID
+		w.Header().Set("Content-Type", "application/ocsp-response")
+		w.Write(ca.ocspResponse(t, certID, status(certID.SerialNumber)))
+	}))
+}
+
+func testRevocationLogin(t *testing.T, b logical.Backend, storage logical.Storage, clientCert *x509.Certificate) *logical.Response {
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "login",
+		Storage:   storage,
+		Connection: &logical.Connection{
+			ConnState: &tls.ConnectionState{
+				PeerCertificates: []*x509.Certificate{clientCert},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func testRevocationBackend(t *testing.T, ca *testRevocationCA, data map[string]interface{}) (logical.Backend, logical.Storage) {
+	storage := &logical.InmemStorage{}
+	b, err := Factory(context.Background(), &logical.BackendConfig{
+		System: &logical.StaticSystemView{
+			DefaultLeaseTTLVal: 1000 * time.Second,
+			MaxLeaseTTLVal:     1800 * time.Second,
+		},
+		StorageView: storage,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data["certificate"] = ca.pem()
+	data["policies"] = "foo"
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "certs/ca",
+		Storage:   storage,
+		Data:      data,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+	return b, storage
+}
+
+func TestBackend_OCSP(t *testing.T) {
+	ca := newTestRevocationCA(t)
+
+	var requests int32
+	responder := ca.ocspResponder(t, func(serial *big.Int) ocspStatus {
+		switch serial.Int64() {
+		case 10:
+			return ocspStatusGood
+		case 11:
+			return ocspStatusRevoked
+		}
+		return ocspStatusUnknown
+	}, &requests)
+	defer responder.Close()
+
+	b, storage := testRevocationBackend(t, ca, map[string]interface{}{
+		"ocsp_enabled": true,
+	})
+
+	// Good certificates are allowed, and their status is cached
+	goodCert := ca.issue(t, 10, []string{responder.URL}, nil)
+	for i := 0; i < 2; i++ {
+		resp := testRevocationLogin(t, b, storage, goodCert)
+		if resp == nil || resp.IsError() || resp.Auth == nil {
+			t.Fatalf("expected the login to succeed; resp: %#v", resp)
+		}
+	}
+	if requests != 1 {
+		t.Fatalf("expected the OCSP status to be cached; requests: %d", requests)
+	}
+
+	// Revoked and unknown certificates are rejected
+	resp := testRevocationLogin(t, b, storage, ca.issue(t, 11, []string{responder.URL}, nil))
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected the login of a revoked certificate to fail; resp: %#v", resp)
+	}
+	resp = testRevocationLogin(t, b, storage, ca.issue(t, 12, []string{responder.URL}, nil))
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected the login of an unknown certificate to fail; resp: %#v", resp)
+	}
+
+	// Certificates without responders can't be checked and are rejected
+	resp = testRevocationLogin(t, b, storage, ca.issue(t, 13, nil, nil))
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected the login without OCSP responders to fail; resp: %#v", resp)
+	}
+
+	// Overriding the responders takes precedence over the certificate
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachable.Close()
+	b, storage = testRevocationBackend(t, ca, map[string]interface{}{
+		"ocsp_enabled":          true,
+		"ocsp_servers_override": responder.URL,
+	})
+	resp = testRevocationLogin(t, b, storage, ca.issue(t, 10, []string{unreachable.URL}, nil))
+	if resp == nil || resp.IsError() || resp.Auth == nil {
+		t.Fatalf("expected the login to succeed; resp: %#v", resp)
+	}
+	resp = testRevocationLogin(t, b, storage, ca.issue(t, 11, []string{unreachable.URL}, nil))
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected the login of a revoked certificate to fail; resp: %#v", resp)
+	}
+
+	// Failing open allows the certificates whose status can't be determined,
+	// but still rejects the revoked ones
+	b, storage = testRevocationBackend(t, ca, map[string]interface{}{
+		"ocsp_enabled":   true,
+		"ocsp_fail_open": true,
+	})
+	resp = testRevocationLogin(t, b, storage, ca.issue(t, 10, []string{unreachable.URL}, nil))
+	if resp == nil || resp.IsError() || resp.Auth == nil {
+		t.Fatalf("expected the login to fail open; resp: %#v", resp)
+	}
+	resp = testRevocationLogin(t, b, storage, ca.issue(t, 11, []string{responder.URL}, nil))
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected the login of a revoked certificate to fail; resp: %#v", resp)
+	}
+
+	// Responses signed by another CA are not trusted
+	otherCA := newTestRevocationCA(t)
+	forged := otherCA.ocspResponder(t, func(*big.Int) ocspStatus {
+		return ocspStatusGood
+	}, new(int32))
+	defer forged.Close()
+	b, storage = testRevocationBackend(t, ca, map[string]interface{}{
+		"ocsp_enabled": true,
+	})
+	resp = testRevocationLogin(t, b, storage, ca.issue(t, 11, []string{forged.URL}, nil))
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected the login with a forged OCSP response to fail; resp: %#v", resp)
+	}
+}
+
+func TestBackend_CRLDistributionPoints(t *testing.T) {
+	ca := newTestRevocationCA(t)
+
+	crlBytes, err := ca.cert.CreateCRL(rand.Reader, ca.key, []pkix.RevokedCertificate{
+		{
+			SerialNumber:   big.NewInt(21),
+			RevocationTime: time.Now().Add(-time.Minute),
+		},
+	}, time.Now().Add(-time.Minute), time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var requests int32
+	crlServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Write(crlBytes)
+	}))
+	defer crlServer.Close()
+
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachable.Close()
+
+	b, storage := testRevocationBackend(t, ca, map[string]interface{}{
+		"crl_distribution_points_enabled": true,
+	})
+
+	// Certificates missing from the CRL are allowed, and the CRL is cached.
+	// Unreachable distribution points are skipped if another one is valid.
+	goodCert := ca.issue(t, 20, nil, []string{unreachable.URL, crlServer.URL})
+	for i := 0; i < 2; i++ {
+		resp := testRevocationLogin(t, b, storage, goodCert)
+		if resp == nil || resp.IsError() || resp.Auth == nil {
+			t.Fatalf("expected the login to succeed; resp: %#v", resp)
+		}
+	}
+	if requests != 1 {
+		t.Fatalf("expected the CRL to be cached; requests: %d", requests)
+	}
+
+	resp := testRevocationLogin(t, b, storage, ca.issue(t, 21, nil, []string{crlServer.URL}))
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected the login of a revoked certificate to fail; resp: %#v", resp)
+	}
+
+	resp = testRevocationLogin(t, b, storage, ca.issue(t, 22, nil, []string{unreachable.URL}))
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected the login with unreachable distribution points to fail; resp: %#v", resp)
+	}
+
+	b, storage = testRevocationBackend(t, ca, map[string]interface{}{
+		"crl_distribution_points_enabled":   true,
+		"crl_distribution_points_fail_open": true,
+	})
+	resp = testRevocationLogin(t, b, storage, ca.issue(t, 22, nil, []string{unreachable.URL}))
+	if resp == nil || resp.IsError() || resp.Auth == nil {
+		t.Fatalf("expected the login to fail open; resp: %#v", resp)
+	}
+	resp = testRevocationLogin(t, b, storage, ca.issue(t, 21, nil, []string{crlServer.URL}))
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected the login of a revoked certificate to fail; resp: %#v", resp)
+	}
+}
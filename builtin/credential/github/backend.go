@@ -35,7 +35,14 @@ func Backend() *backend {
 		DefaultKey: "default",
 	}
 
+	b.RoleMap = &framework.PolicyMap{
+		PathMap: framework.PathMap{
+			Name: "roles",
+		},
+	}
+
 	allPaths := append(b.TeamMap.Paths(), b.UserMap.Paths()...)
+	allPaths = append(allPaths, b.RoleMap.Paths()...)
 	b.Backend = &framework.Backend{
 		Help: backendHelp,
 
@@ -62,6 +69,10 @@ type backend struct {
 	TeamMap *framework.PolicyMap
 
 	UserMap *framework.PolicyMap
+
+	// RoleMap maps the roles of the users in the organization, which are
+	// "member", "admin" and "outside_collaborator", to policies
+	RoleMap *framework.PolicyMap
 }
 
 // Client returns the GitHub client to communicate to GitHub via the
@@ -91,7 +102,8 @@ The GitHub credential provider allows authentication via GitHub.
 Users provide a personal access token to log in, and the credential
 provider verifies they're part of the correct organization and then
 maps the user to a set of Vault policies according to the teams they're
-part of.
+part of and their role in the organization. Both classic and fine-grained
+personal access tokens are supported.
 
 After enabling the credential provider, use the "config" route to
 configure it.
@@ -2,8 +2,13 @@ package github
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"reflect"
+	"sort"
 	"strings"
 	"testing"
 	"time"
@@ -199,3 +204,241 @@ func testAccLogin(t *testing.T, policies []string) logicaltest.TestStep {
 		Check: logicaltest.TestCheckAuth(policies),
 	}
 }
+
+// testGitHubServer returns a fake GitHub API serving the users of the "acme"
+// organization, each identified by their token
+func testGitHubServer(t *testing.T) *httptest.Server {
+	type testUser struct {
+		login      string
+		membership map[string]interface{}
+		repoOwners []string
+	}
+	users := map[string]testUser{
+		"classic-admin": {
+			login: "alice",
+			membership: map[string]interface{}{
+				"state": "active",
+				"role":  "admin",
+			},
+		},
+		"github_pat_member": {
+			login: "bob",
+			membership: map[string]interface{}{
+				"state": "active",
+				"role":  "member",
+			},
+		},
+		"github_pat_pending": {
+			login: "erin",
+			membership: map[string]interface{}{
+				"state": "pending",
+				"role":  "member",
+			},
+		},
+		"github_pat_outside": {
+			login:      "carol",
+			repoOwners: []string{"someone", "ACME"},
+		},
+		"github_pat_stranger": {
+			login:      "dave",
+			repoOwners: []string{"someone"},
+		},
+	}
+
+	org := map[string]interface{}{
+		"login": "acme",
+		"id":    7,
+	}
+	teams := []map[string]interface{}{
+		{"id": 1, "name": "Dev Ops", "slug": "dev-ops", "organization": org},
+		{"id": 2, "name": "qa", "slug": "qa", "organization": org},
+	}
+	teamMembers := map[string][]string{
+		"dev-ops": {"alice", "bob"},
+		"qa":      {"bob"},
+	}
+
+	writeJSON := func(w http.ResponseWriter, v interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(v); err != nil {
+			t.Error(err)
+		}
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		user, ok := users[token]
+		if !ok {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fineGrained := strings.HasPrefix(token, "github_pat_")
+
+		// Like GitHub, the paths are case insensitive
+		path := strings.ToLower(r.URL.Path)
+
+		switch {
+		case path == "/user":
+			writeJSON(w, map[string]interface{}{"login": user.login})
+
+		case path == "/user/memberships/orgs/acme":
+			if user.membership == nil {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			membership := map[string]interface{}{"organization": org}
+			for k, v := range user.membership {
+				membership[k] = v
+			}
+			writeJSON(w, membership)
+
+		case path == "/user/teams" && !fineGrained:
+			var userTeams []map[string]interface{}
+			for _, team := range teams {
+				for _, member := range teamMembers[team["slug"].(string)] {
+					if member == user.login {
+						userTeams = append(userTeams, team)
+					}
+				}
+			}
+			writeJSON(w, userTeams)
+
+		case path == "/orgs/acme/teams":
+			writeJSON(w, teams)
+
+		case strings.HasPrefix(path, "/orgs/acme/teams/"):
+			parts := strings.Split(strings.TrimPrefix(path, "/orgs/acme/teams/"), "/")
+			if len(parts) != 3 || parts[1] != "memberships" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			if parts[0] == "qa" {
+				t.Errorf("membership of unmapped team %q queried", parts[0])
+			}
+			for _, member := range teamMembers[parts[0]] {
+				if member == parts[2] {
+					writeJSON(w, map[string]interface{}{"state": "active", "role": "member"})
+					return
+				}
+			}
+			w.WriteHeader(http.StatusNotFound)
+
+		case path == "/user/repos" && r.URL.Query().Get("affiliation") == "collaborator":
+			var repos []map[string]interface{}
+			for i, owner := range user.repoOwners {
+				repos = append(repos, map[string]interface{}{
+					"name":  fmt.Sprintf("repo%d", i),
+					"owner": map[string]interface{}{"login": owner},
+				})
+			}
+			writeJSON(w, repos)
+
+		case path == "/orgs/acme":
+			writeJSON(w, org)
+
+		default:
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+}
+
+func TestBackend_orgRolesAndFineGrainedTokens(t *testing.T) {
+	server := testGitHubServer(t)
+	defer server.Close()
+
+	storage := &logical.InmemStorage{}
+	b, err := Factory(context.Background(), &logical.BackendConfig{
+		System: &logical.StaticSystemView{
+			DefaultLeaseTTLVal: time.Hour,
+			MaxLeaseTTLVal:     time.Hour,
+		},
+		StorageView: storage,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	write := func(path string, data map[string]interface{}) {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Storage:   storage,
+			Data:      data,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: path: %s resp: %#v err: %v", path, resp, err)
+		}
+	}
+
+	write("config", map[string]interface{}{
+		"organization": "ACME",
+		"base_url":     server.URL + "/",
+	})
+	write("map/teams/dev-ops", map[string]interface{}{"value": "devops"})
+	write("map/roles/member", map[string]interface{}{"value": "members"})
+	write("map/roles/admin", map[string]interface{}{"value": "admins"})
+	write("map/roles/outside_collaborator", map[string]interface{}{"value": "contractors"})
+
+	login := func(token string) *logical.Response {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "login",
+			Storage:   storage,
+			Data: map[string]interface{}{
+				"token": token,
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	for _, tc := range []struct {
+		token        string
+		policies     []string
+		groupAliases []string
+	}{
+		{"classic-admin", []string{"admins", "devops", "members"}, []string{"Dev Ops", "dev-ops"}},
+		{"github_pat_member", []string{"devops", "members"}, []string{"Dev Ops", "dev-ops"}},
+		{"github_pat_outside", []string{"contractors"}, nil},
+	} {
+		resp := login(tc.token)
+		if resp == nil || resp.IsError() || resp.Auth == nil {
+			t.Fatalf("%s: bad: %#v", tc.token, resp)
+		}
+
+		policies := append([]string{}, resp.Auth.Policies...)
+		sort.Strings(policies)
+		if !reflect.DeepEqual(policies, tc.policies) {
+			t.Fatalf("%s: expected policies %v, got %v", tc.token, tc.policies, policies)
+		}
+
+		var groupAliases []string
+		for _, alias := range resp.Auth.GroupAliases {
+			groupAliases = append(groupAliases, alias.Name)
+		}
+		if !reflect.DeepEqual(groupAliases, tc.groupAliases) {
+			t.Fatalf("%s: expected group aliases %v, got %v", tc.token, tc.groupAliases, groupAliases)
+		}
+	}
+
+	for _, token := range []string{"github_pat_stranger", "github_pat_pending"} {
+		if resp := login(token); resp == nil || !resp.IsError() {
+			t.Fatalf("%s: expected login to fail, got %#v", token, resp)
+		}
+	}
+
+	// Outside collaborators are rejected unless policies are mapped to them
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.DeleteOperation,
+		Path:      "map/roles/outside_collaborator",
+		Storage:   storage,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v err: %v", resp, err)
+	}
+	if resp := login("github_pat_outside"); resp == nil || !resp.IsError() {
+		t.Fatalf("expected login to fail, got %#v", resp)
+	}
+}
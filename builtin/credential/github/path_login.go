@@ -3,6 +3,7 @@ package github
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"net/url"
 	"strings"
 
@@ -12,13 +13,23 @@ import (
 	"github.com/hashicorp/vault/logical/framework"
 )
 
+const (
+	// fineGrainedTokenPrefix is the prefix of the fine-grained personal
+	// access tokens
+	fineGrainedTokenPrefix = "github_pat_"
+
+	orgRoleMember              = "member"
+	orgRoleAdmin               = "admin"
+	orgRoleOutsideCollaborator = "outside_collaborator"
+)
+
 func pathLogin(b *backend) *framework.Path {
 	return &framework.Path{
 		Pattern: "login",
 		Fields: map[string]*framework.FieldSchema{
 			"token": &framework.FieldSchema{
 				Type:        framework.TypeString,
-				Description: "GitHub personal API token",
+				Description: "GitHub personal access token, either classic or fine-grained",
 			},
 		},
 
@@ -180,36 +191,121 @@ func (b *backend) verifyCredentials(ctx context.Context, req *logical.Request, t
 	}
 
 	// Verify that the user is part of the organization
-	var org *github.Organization
+	org, role, err := b.orgMembership(ctx, req.Storage, client, config.Organization)
+	if err != nil {
+		return nil, nil, err
+	}
+	if org == nil {
+		return nil, logical.ErrorResponse("user is not part of required org"), nil
+	}
 
-	orgOpt := &github.ListOptions{
-		PerPage: 100,
+	// Get the teams that this user is part of to determine the policies.
+	// Fine-grained personal access tokens can't list the teams of the user,
+	// so the membership of the user in the mapped teams is checked instead.
+	var teamNames []string
+	switch {
+	case role == orgRoleOutsideCollaborator:
+	case strings.HasPrefix(token, fineGrainedTokenPrefix):
+		teamNames, err = b.mappedTeamNames(ctx, req.Storage, client, org, user)
+	default:
+		teamNames, err = userTeamNames(ctx, client, org)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	groupPoliciesList, err := b.TeamMap.Policies(ctx, req.Storage, teamNames...)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	userPoliciesList, err := b.UserMap.Policies(ctx, req.Storage, []string{*user.Login}...)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Organization owners also get the policies of the members
+	roles := []string{role}
+	if role == orgRoleAdmin {
+		roles = append(roles, orgRoleMember)
+	}
+	rolePoliciesList, err := b.RoleMap.Policies(ctx, req.Storage, roles...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	policies := append(groupPoliciesList, userPoliciesList...)
+
+	return &verifyCredentialsResp{
+		User:      user,
+		Org:       org,
+		Role:      role,
+		Policies:  append(policies, rolePoliciesList...),
+		TeamNames: teamNames,
+	}, nil, nil
+}
+
+// orgMembership returns the organization and the role of the user in it, or
+// a nil organization if the user is not part of it. Users who are not members
+// of the organization are only considered part of it as outside collaborators
+// of its repositories, if policies are mapped to outside collaborators.
+func (b *backend) orgMembership(ctx context.Context, s logical.Storage, client *github.Client, orgName string) (*github.Organization, string, error) {
+	membership, resp, err := client.Organizations.GetOrgMembership(ctx, "", orgName)
+	switch {
+	case err == nil && membership.GetState() == "active":
+		return membership.GetOrganization(), membership.GetRole(), nil
+	case err == nil:
+		// Pending invitations do not grant membership
+		return nil, "", nil
+	case resp == nil || resp.StatusCode != http.StatusNotFound:
+		return nil, "", err
+	}
+
+	mapping, err := b.RoleMap.Get(ctx, s, orgRoleOutsideCollaborator)
+	if err != nil {
+		return nil, "", err
+	}
+	if mapping == nil {
+		return nil, "", nil
+	}
+
+	repoOpt := &github.RepositoryListOptions{
+		Affiliation: "collaborator",
+		ListOptions: github.ListOptions{
+			PerPage: 100,
+		},
 	}
 
-	var allOrgs []*github.Organization
 	for {
-		orgs, resp, err := client.Organizations.List(ctx, "", orgOpt)
+		repos, resp, err := client.Repositories.List(ctx, "", repoOpt)
 		if err != nil {
-			return nil, nil, err
+			return nil, "", err
+		}
+		for _, repo := range repos {
+			if !strings.EqualFold(repo.GetOwner().GetLogin(), orgName) {
+				continue
+			}
+
+			org, _, err := client.Organizations.Get(ctx, orgName)
+			if err != nil {
+				return nil, "", err
+			}
+			return org, orgRoleOutsideCollaborator, nil
 		}
-		allOrgs = append(allOrgs, orgs...)
 		if resp.NextPage == 0 {
 			break
 		}
-		orgOpt.Page = resp.NextPage
+		repoOpt.Page = resp.NextPage
 	}
 
-	for _, o := range allOrgs {
-		if strings.ToLower(*o.Login) == strings.ToLower(config.Organization) {
-			org = o
-			break
-		}
-	}
-	if org == nil {
-		return nil, logical.ErrorResponse("user is not part of required org"), nil
-	}
+	return nil, "", nil
+}
 
-	// Get the teams that this user is part of to determine the policies
+// userTeamNames returns the names and slugs of the teams of the organization
+// the user is part of
+func userTeamNames(ctx context.Context, client *github.Client, org *github.Organization) ([]string, error) {
 	var teamNames []string
 
 	teamOpt := &github.ListOptions{
@@ -220,7 +316,7 @@ func (b *backend) verifyCredentials(ctx context.Context, req *logical.Request, t
 	for {
 		teams, resp, err := client.Organizations.ListUserTeams(ctx, teamOpt)
 		if err != nil {
-			return nil, nil, err
+			return nil, err
 		}
 		allTeams = append(allTeams, teams...)
 		if resp.NextPage == 0 {
@@ -242,29 +338,89 @@ func (b *backend) verifyCredentials(ctx context.Context, req *logical.Request, t
 		}
 	}
 
-	groupPoliciesList, err := b.TeamMap.Policies(ctx, req.Storage, teamNames...)
+	return teamNames, nil
+}
 
-	if err != nil {
-		return nil, nil, err
+// mappedTeamNames returns the names and slugs of the teams of the
+// organization the user is part of, among the teams that are mapped to
+// policies
+func (b *backend) mappedTeamNames(ctx context.Context, s logical.Storage, client *github.Client, org *github.Organization, user *github.User) ([]string, error) {
+	var teamNames []string
+
+	teamOpt := &github.ListOptions{
+		PerPage: 100,
 	}
 
-	userPoliciesList, err := b.UserMap.Policies(ctx, req.Storage, []string{*user.Login}...)
+	var allTeams []*github.Team
+	for {
+		teams, resp, err := client.Organizations.ListTeams(ctx, org.GetLogin(), teamOpt)
+		if err != nil {
+			return nil, err
+		}
+		allTeams = append(allTeams, teams...)
+		if resp.NextPage == 0 {
+			break
+		}
+		teamOpt.Page = resp.NextPage
+	}
 
+	for _, t := range allTeams {
+		names := []string{t.GetName()}
+		if t.GetName() != t.GetSlug() {
+			names = append(names, t.GetSlug())
+		}
+
+		var mapped bool
+		for _, name := range names {
+			mapping, err := b.TeamMap.Get(ctx, s, name)
+			if err != nil {
+				return nil, err
+			}
+			if mapping != nil {
+				mapped = true
+				break
+			}
+		}
+		if !mapped {
+			continue
+		}
+
+		member, err := teamMember(ctx, client, org.GetLogin(), t.GetSlug(), user.GetLogin())
+		if err != nil {
+			return nil, err
+		}
+		if member {
+			teamNames = append(teamNames, names...)
+		}
+	}
+
+	return teamNames, nil
+}
+
+// teamMember checks whether the user is an active member of the team
+func teamMember(ctx context.Context, client *github.Client, org, teamSlug, username string) (bool, error) {
+	u := fmt.Sprintf("orgs/%v/teams/%v/memberships/%v", org, teamSlug, username)
+	req, err := client.NewRequest("GET", u, nil)
 	if err != nil {
-		return nil, nil, err
+		return false, err
 	}
 
-	return &verifyCredentialsResp{
-		User:      user,
-		Org:       org,
-		Policies:  append(groupPoliciesList, userPoliciesList...),
-		TeamNames: teamNames,
-	}, nil, nil
+	membership := new(github.Membership)
+	resp, err := client.Do(ctx, req, membership)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return membership.GetState() == "active", nil
 }
 
 type verifyCredentialsResp struct {
 	User      *github.User
 	Org       *github.Organization
+	Role      string
 	Policies  []string
 	TeamNames []string
 }
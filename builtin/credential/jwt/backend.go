@@ -0,0 +1,112 @@
+package jwt
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// Factory creates and configures the backend
+func Factory(ctx context.Context, conf *logical.BackendConfig) (logical.Backend, error) {
+	b := Backend()
+	if err := b.Setup(ctx, conf); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Backend returns a new JWT/OIDC auth backend
+func Backend() *backend {
+	var b backend
+	b.oidcStates = make(map[string]*oidcState)
+	b.GroupMap = &framework.PolicyMap{
+		PathMap: framework.PathMap{
+			Name: "groups",
+		},
+	}
+
+	b.Backend = &framework.Backend{
+		Help: backendHelp,
+
+		PathsSpecial: &logical.Paths{
+			Unauthenticated: []string{
+				"login",
+				"oidc/auth_url",
+				"oidc/callback",
+			},
+			SealWrapStorage: []string{
+				"config",
+			},
+		},
+
+		Paths: append([]*framework.Path{
+			pathConfig(&b),
+			pathRoleList(&b),
+			pathRole(&b),
+			pathLogin(&b),
+			pathOIDCAuthURL(&b),
+			pathOIDCCallback(&b),
+		}, b.GroupMap.Paths()...),
+
+		AuthRenew:   b.pathLoginRenew,
+		Clean:       b.cleanup,
+		Invalidate:  b.invalidate,
+		BackendType: logical.TypeCredential,
+	}
+
+	return &b
+}
+
+type backend struct {
+	*framework.Backend
+
+	// GroupMap maps the values of the groups claim to policies
+	GroupMap *framework.PolicyMap
+
+	// keySet is the cached key set of the configuration
+	keySet *keySet
+	// provider is the cached OIDC provider of the configuration
+	provider *oidcProvider
+	l        sync.RWMutex
+
+	// oidcStates are the pending authorization code flows, keyed by state
+	oidcStates map[string]*oidcState
+	oidcLock   sync.Mutex
+}
+
+// reset clears the cached keys and provider, which are fetched again on the
+// next login
+func (b *backend) reset() {
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	b.keySet = nil
+	b.provider = nil
+}
+
+func (b *backend) cleanup(_ context.Context) {
+	b.reset()
+}
+
+func (b *backend) invalidate(_ context.Context, key string) {
+	switch key {
+	case "config":
+		b.reset()
+	}
+}
+
+// oidcStateTTL is the time a user has to complete the authorization code flow
+const oidcStateTTL = 10 * time.Minute
+
+const backendHelp = `
+The JWT auth backend authenticates workloads with signed JWTs and humans with
+the OIDC authorization code flow of an identity provider.
+
+The keys validating the tokens are configured with the "config" endpoint, from
+OIDC discovery, a JWKS URL or static public keys. Roles bind the claims of the
+tokens and assign policies to the authenticated clients, and the "map/groups"
+endpoints map the values of the groups claim of a role to policies.
+`
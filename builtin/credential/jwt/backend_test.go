@@ -0,0 +1,459 @@
+package jwt
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+	jose "gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+func createBackendWithStorage(t *testing.T) (*backend, logical.Storage) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+
+	b := Backend()
+	if err := b.Setup(context.Background(), config); err != nil {
+		t.Fatal(err)
+	}
+	return b, config.StorageView
+}
+
+func testRequest(t *testing.T, b *backend, s logical.Storage, op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: op,
+		Path:      path,
+		Storage:   s,
+		Data:      data,
+	})
+	if err != nil {
+		t.Fatalf("%s %s: %v", op, path, err)
+	}
+	return resp
+}
+
+func testWrite(t *testing.T, b *backend, s logical.Storage, path string, data map[string]interface{}) {
+	resp := testRequest(t, b, s, logical.UpdateOperation, path, data)
+	if resp != nil && resp.IsError() {
+		t.Fatalf("write %s: %v", path, resp.Error())
+	}
+}
+
+func testKey(t *testing.T) (*rsa.PrivateKey, string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func testSign(t *testing.T, key *rsa.PrivateKey, keyID string, claims ...interface{}) string {
+	signer, err := jose.NewSigner(jose.SigningKey{
+		Algorithm: jose.RS256,
+		Key:       jose.JSONWebKey{Key: key, KeyID: keyID},
+	}, (&jose.SignerOptions{}).WithType("JWT"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	builder := jwt.Signed(signer)
+	for _, c := range claims {
+		builder = builder.Claims(c)
+	}
+	token, err := builder.CompactSerialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func testClaims(issuer string, audience ...string) jwt.Claims {
+	now := time.Now()
+	return jwt.Claims{
+		Issuer:    issuer,
+		Subject:   "r3qXcK2bix9eFECzsU3Sbmh0K16fatW6@clients",
+		Audience:  jwt.Audience(audience),
+		Expiry:    jwt.NewNumericDate(now.Add(5 * time.Minute)),
+		NotBefore: jwt.NewNumericDate(now.Add(-5 * time.Second)),
+		IssuedAt:  jwt.NewNumericDate(now),
+	}
+}
+
+func TestBackend_config(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+	_, pubKey := testKey(t)
+
+	for _, data := range []map[string]interface{}{
+		{},
+		{"jwt_validation_pubkeys": pubKey, "jwks_url": "https://example.com/keys"},
+		{"jwt_validation_pubkeys": "not a key"},
+		{"jwt_validation_pubkeys": pubKey, "jwt_supported_algs": "HS256"},
+		{"jwt_validation_pubkeys": pubKey, "oidc_client_id": "abc", "oidc_client_secret": "def"},
+		{"jwks_ca_pem": "-----BEGIN CERTIFICATE-----", "jwt_validation_pubkeys": pubKey},
+	} {
+		resp := testRequest(t, b, s, logical.UpdateOperation, "config", data)
+		if resp == nil || !resp.IsError() {
+			t.Fatalf("expected an error for %#v, got: %#v", data, resp)
+		}
+	}
+
+	testWrite(t, b, s, "config", map[string]interface{}{
+		"jwt_validation_pubkeys": pubKey,
+		"bound_issuer":           "https://team-vault.auth0.com/",
+	})
+
+	resp := testRequest(t, b, s, logical.ReadOperation, "config", nil)
+	if resp == nil || resp.IsError() {
+		t.Fatalf("bad: %#v", resp)
+	}
+	if resp.Data["bound_issuer"] != "https://team-vault.auth0.com/" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if !reflect.DeepEqual(resp.Data["jwt_validation_pubkeys"], []string{strings.TrimSpace(pubKey)}) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if _, ok := resp.Data["oidc_client_secret"]; ok {
+		t.Fatalf("client secret should not be returned: %#v", resp.Data)
+	}
+}
+
+func TestBackend_role(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+
+	for _, data := range []map[string]interface{}{
+		{"bound_audiences": "vault"},
+		{"user_claim": "sub"},
+		{"user_claim": "sub", "bound_subject": "a", "role_type": "saml"},
+		{"user_claim": "sub", "bound_subject": "a", "allowed_redirect_uris": "http://localhost:8250/oidc/callback"},
+		{"user_claim": "sub", "role_type": "oidc"},
+		{"user_claim": "sub", "bound_claims": map[string]interface{}{"n": 1}},
+		{"user_claim": "sub", "bound_subject": "a", "claim_mappings": "a=role"},
+		{"user_claim": "sub", "bound_subject": "a", "ttl": 600, "max_ttl": 300},
+	} {
+		resp := testRequest(t, b, s, logical.UpdateOperation, "role/test", data)
+		if resp == nil || !resp.IsError() {
+			t.Fatalf("expected an error for %#v, got: %#v", data, resp)
+		}
+	}
+
+	testWrite(t, b, s, "role/Test", map[string]interface{}{
+		"bound_audiences": "vault,other",
+		"bound_claims": map[string]interface{}{
+			"team": []interface{}{"eng", "ops"},
+		},
+		"claim_mappings": map[string]interface{}{"email": "email"},
+		"user_claim":     "sub",
+		"groups_claim":   "groups",
+		"policies":       "Dev,prod",
+		"ttl":            "5m",
+	})
+
+	resp := testRequest(t, b, s, logical.ReadOperation, "role/test", nil)
+	if resp == nil || resp.IsError() {
+		t.Fatalf("bad: %#v", resp)
+	}
+	expected := map[string]interface{}{
+		"role_type":             "jwt",
+		"bound_audiences":       []string{"vault", "other"},
+		"bound_subject":         "",
+		"bound_claims":          map[string]interface{}{"team": []interface{}{"eng", "ops"}},
+		"claim_mappings":        map[string]string{"email": "email"},
+		"user_claim":            "sub",
+		"groups_claim":          "groups",
+		"oidc_scopes":           []string{},
+		"allowed_redirect_uris": []string{},
+		"policies":              []string{"dev", "prod"},
+		"ttl":                   int64(300),
+		"max_ttl":               int64(0),
+		"period":                int64(0),
+	}
+	if !reflect.DeepEqual(resp.Data, expected) {
+		t.Fatalf("bad: expected %#v, got %#v", expected, resp.Data)
+	}
+
+	resp = testRequest(t, b, s, logical.ListOperation, "role/", nil)
+	if !reflect.DeepEqual(resp.Data["keys"], []string{"test"}) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	testRequest(t, b, s, logical.DeleteOperation, "role/test", nil)
+	if resp := testRequest(t, b, s, logical.ReadOperation, "role/test", nil); resp != nil {
+		t.Fatalf("expected role to be deleted, got: %#v", resp)
+	}
+}
+
+func TestBackend_loginStaticKeys(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+	key, pubKey := testKey(t)
+	issuer := "https://team-vault.auth0.com/"
+
+	testWrite(t, b, s, "config", map[string]interface{}{
+		"jwt_validation_pubkeys": pubKey,
+		"bound_issuer":           issuer,
+		"default_role":           "dev",
+	})
+	testWrite(t, b, s, "role/dev", map[string]interface{}{
+		"bound_audiences": "vault,other",
+		"bound_claims": map[string]interface{}{
+			"team": []interface{}{"eng", "ops"},
+		},
+		"claim_mappings": map[string]interface{}{"email": "email"},
+		"user_claim":     "email",
+		"groups_claim":   "groups",
+		"policies":       "dev",
+		"ttl":            "5m",
+		"max_ttl":        "1h",
+	})
+	testWrite(t, b, s, "map/groups/admins", map[string]interface{}{
+		"value": "admin",
+	})
+
+	custom := map[string]interface{}{
+		"email":  "bob@example.com",
+		"team":   "ops",
+		"groups": []string{"admins", "users"},
+	}
+
+	resp := testRequest(t, b, s, logical.UpdateOperation, "login", map[string]interface{}{
+		"jwt": testSign(t, key, "", testClaims(issuer, "vault"), custom),
+	})
+	if resp == nil || resp.IsError() || resp.Auth == nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	auth := resp.Auth
+	sort.Strings(auth.Policies)
+	if !reflect.DeepEqual(auth.Policies, []string{"admin", "dev"}) {
+		t.Fatalf("bad policies: %#v", auth.Policies)
+	}
+	if auth.Alias.Name != "bob@example.com" || auth.DisplayName != "bob@example.com" {
+		t.Fatalf("bad alias: %#v", auth.Alias)
+	}
+	if len(auth.GroupAliases) != 2 || auth.GroupAliases[0].Name != "admins" || auth.GroupAliases[1].Name != "users" {
+		t.Fatalf("bad group aliases: %#v", auth.GroupAliases)
+	}
+	if !reflect.DeepEqual(auth.Metadata, map[string]string{"role": "dev", "email": "bob@example.com"}) {
+		t.Fatalf("bad metadata: %#v", auth.Metadata)
+	}
+	if auth.TTL != 5*time.Minute || !auth.Renewable {
+		t.Fatalf("bad lease: %#v", auth.LeaseOptions)
+	}
+
+	otherKey, _ := testKey(t)
+	expired := testClaims(issuer, "vault")
+	expired.Expiry = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+	noExpiry := testClaims(issuer, "vault")
+	noExpiry.Expiry = 0
+
+	for name, token := range map[string]string{
+		"wrong key":      testSign(t, otherKey, "", testClaims(issuer, "vault"), custom),
+		"wrong issuer":   testSign(t, key, "", testClaims("https://example.com/", "vault"), custom),
+		"wrong audience": testSign(t, key, "", testClaims(issuer, "nomad"), custom),
+		"expired":        testSign(t, key, "", expired, custom),
+		"no expiry":      testSign(t, key, "", noExpiry, custom),
+		"wrong claim": testSign(t, key, "", testClaims(issuer, "vault"), map[string]interface{}{
+			"email": "bob@example.com",
+			"team":  "sales",
+		}),
+		"missing user claim": testSign(t, key, "", testClaims(issuer, "vault"), map[string]interface{}{
+			"team": "eng",
+		}),
+		"not a token": "abc",
+	} {
+		resp := testRequest(t, b, s, logical.UpdateOperation, "login", map[string]interface{}{
+			"role": "dev",
+			"jwt":  token,
+		})
+		if resp == nil || !resp.IsError() {
+			t.Fatalf("%s: expected an error, got: %#v", name, resp)
+		}
+	}
+}
+
+func TestBackend_loginJWKS(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+	key, _ := testKey(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(jose.JSONWebKeySet{
+			Keys: []jose.JSONWebKey{
+				{Key: &key.PublicKey, KeyID: "key-1", Algorithm: string(jose.RS256), Use: "sig"},
+			},
+		})
+	}))
+	defer server.Close()
+
+	testWrite(t, b, s, "config", map[string]interface{}{
+		"jwks_url": server.URL,
+	})
+	testWrite(t, b, s, "role/dev", map[string]interface{}{
+		"bound_subject": "r3qXcK2bix9eFECzsU3Sbmh0K16fatW6@clients",
+		"user_claim":    "sub",
+		"policies":      "dev",
+	})
+
+	resp := testRequest(t, b, s, logical.UpdateOperation, "login", map[string]interface{}{
+		"role": "dev",
+		"jwt":  testSign(t, key, "key-1", testClaims("")),
+	})
+	if resp == nil || resp.IsError() || resp.Auth == nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+	if !reflect.DeepEqual(resp.Auth.Policies, []string{"dev"}) {
+		t.Fatalf("bad policies: %#v", resp.Auth.Policies)
+	}
+
+	resp = testRequest(t, b, s, logical.UpdateOperation, "login", map[string]interface{}{
+		"role": "dev",
+		"jwt":  testSign(t, key, "key-2", testClaims("")),
+	})
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error for an unknown key, got: %#v", resp)
+	}
+}
+
+func TestBackend_oidc(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+	key, _ := testKey(t)
+	redirectURI := "http://localhost:8250/oidc/callback"
+
+	var nonce string
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			json.NewEncoder(w).Encode(discoveryDocument{
+				Issuer:                server.URL,
+				AuthorizationEndpoint: server.URL + "/auth",
+				TokenEndpoint:         server.URL + "/token",
+				JWKSURI:               server.URL + "/certs",
+			})
+		case "/certs":
+			json.NewEncoder(w).Encode(jose.JSONWebKeySet{
+				Keys: []jose.JSONWebKey{{Key: &key.PublicKey, KeyID: "key-1"}},
+			})
+		case "/token":
+			clientID, clientSecret, _ := r.BasicAuth()
+			if clientID != "abc" || clientSecret != "def" || r.FormValue("code") != "authcode" || r.FormValue("redirect_uri") != redirectURI {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			idToken := testSign(t, key, "key-1", testClaims(server.URL, "abc"), map[string]interface{}{
+				"nonce": nonce,
+				"email": "bob@example.com",
+			})
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"access_token": "access",
+				"token_type":   "Bearer",
+				"id_token":     idToken,
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	testWrite(t, b, s, "config", map[string]interface{}{
+		"oidc_discovery_url": server.URL,
+		"oidc_client_id":     "abc",
+		"oidc_client_secret": "def",
+	})
+	testWrite(t, b, s, "role/web", map[string]interface{}{
+		"role_type":             "oidc",
+		"user_claim":            "email",
+		"oidc_scopes":           "email",
+		"allowed_redirect_uris": redirectURI,
+		"policies":              "web",
+	})
+
+	// OIDC roles cannot log in with a JWT
+	resp := testRequest(t, b, s, logical.UpdateOperation, "login", map[string]interface{}{
+		"role": "web",
+		"jwt":  testSign(t, key, "key-1", testClaims(server.URL, "abc")),
+	})
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error, got: %#v", resp)
+	}
+
+	resp = testRequest(t, b, s, logical.UpdateOperation, "oidc/auth_url", map[string]interface{}{
+		"role":         "web",
+		"redirect_uri": "http://example.com/callback",
+	})
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error for an unauthorized redirect_uri, got: %#v", resp)
+	}
+
+	resp = testRequest(t, b, s, logical.UpdateOperation, "oidc/auth_url", map[string]interface{}{
+		"role":         "web",
+		"redirect_uri": redirectURI,
+	})
+	if resp == nil || resp.IsError() {
+		t.Fatalf("bad: %#v", resp)
+	}
+	authURL, err := url.Parse(resp.Data["auth_url"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := authURL.Query()
+	if !strings.HasPrefix(authURL.String(), server.URL+"/auth?") || query.Get("client_id") != "abc" || query.Get("scope") != "openid email" || query.Get("redirect_uri") != redirectURI {
+		t.Fatalf("bad auth_url: %s", authURL)
+	}
+	state := query.Get("state")
+	nonce = query.Get("nonce")
+
+	resp = testRequest(t, b, s, logical.ReadOperation, "oidc/callback", map[string]interface{}{
+		"state": state,
+		"code":  "authcode",
+	})
+	if resp == nil || resp.IsError() || resp.Auth == nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+	if resp.Auth.Alias.Name != "bob@example.com" || !reflect.DeepEqual(resp.Auth.Policies, []string{"web"}) {
+		t.Fatalf("bad auth: %#v", resp.Auth)
+	}
+
+	// The state can only be used once
+	resp = testRequest(t, b, s, logical.ReadOperation, "oidc/callback", map[string]interface{}{
+		"state": state,
+		"code":  "authcode",
+	})
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error, got: %#v", resp)
+	}
+
+	// The nonce of the ID token must match the one of the flow
+	resp = testRequest(t, b, s, logical.UpdateOperation, "oidc/auth_url", map[string]interface{}{
+		"role":         "web",
+		"redirect_uri": redirectURI,
+	})
+	authURL, err = url.Parse(resp.Data["auth_url"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce = "other"
+	resp = testRequest(t, b, s, logical.UpdateOperation, "oidc/callback", map[string]interface{}{
+		"state": authURL.Query().Get("state"),
+		"code":  "authcode",
+	})
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error, got: %#v", resp)
+	}
+}
@@ -0,0 +1,169 @@
+package jwt
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+)
+
+const (
+	defaultMount         = "oidc"
+	defaultListenAddress = "localhost"
+	defaultPort          = "8250"
+	defaultCallbackHost  = "localhost"
+)
+
+// CLIHandler logs in with the OIDC authorization code flow, receiving the
+// redirect of the browser on a local HTTP listener
+type CLIHandler struct{}
+
+type callbackResult struct {
+	secret *api.Secret
+	err    error
+}
+
+// Auth cli method
+func (h *CLIHandler) Auth(c *api.Client, m map[string]string) (*api.Secret, error) {
+	mount, ok := m["mount"]
+	if !ok {
+		mount = defaultMount
+	}
+
+	listenAddress, ok := m["listenaddress"]
+	if !ok {
+		listenAddress = defaultListenAddress
+	}
+
+	port, ok := m["port"]
+	if !ok {
+		port = defaultPort
+	}
+
+	callbackHost, ok := m["callbackhost"]
+	if !ok {
+		callbackHost = defaultCallbackHost
+	}
+
+	role := m["role"]
+	redirectURI := fmt.Sprintf("http://%s:%s/oidc/callback", callbackHost, port)
+
+	secret, err := c.Logical().Write(fmt.Sprintf("auth/%s/oidc/auth_url", mount), map[string]interface{}{
+		"role":         role,
+		"redirect_uri": redirectURI,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil {
+		return nil, errors.New("empty response from credential provider")
+	}
+	authURL, _ := secret.Data["auth_url"].(string)
+	if authURL == "" {
+		return nil, errors.New("no auth_url returned by the credential provider")
+	}
+
+	listener, err := net.Listen("tcp", net.JoinHostPort(listenAddress, port))
+	if err != nil {
+		return nil, err
+	}
+	defer listener.Close()
+
+	doneCh := make(chan callbackResult, 1)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/oidc/callback", func(w http.ResponseWriter, req *http.Request) {
+		query := req.URL.Query()
+		secret, err := c.Logical().Write(fmt.Sprintf("auth/%s/oidc/callback", mount), map[string]interface{}{
+			"state": query.Get("state"),
+			"code":  query.Get("code"),
+		})
+		if err != nil {
+			fmt.Fprintf(w, "Vault login failed: %s\n", err)
+		} else {
+			fmt.Fprintf(w, "Vault login succeeded, you can close this window.\n")
+		}
+
+		select {
+		case doneCh <- callbackResult{secret: secret, err: err}:
+		default:
+		}
+	})
+	go http.Serve(listener, mux)
+
+	fmt.Fprintf(os.Stderr, "Complete the login via your OIDC provider. Launching browser to:\n\n    %s\n\n\n", authURL)
+	if err := openURL(authURL); err != nil {
+		fmt.Fprintf(os.Stderr, "Error attempting to automatically open browser: '%s'.\nPlease visit the authorization URL manually.\n", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Waiting for OIDC authentication to complete...\n")
+
+	result := <-doneCh
+	if result.err != nil {
+		return nil, result.err
+	}
+	if result.secret == nil {
+		return nil, errors.New("empty response from credential provider")
+	}
+
+	return result.secret, nil
+}
+
+// openURL opens the URL in the default browser of the platform
+func openURL(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	return cmd.Start()
+}
+
+// Help method for the OIDC cli
+func (h *CLIHandler) Help() string {
+	help := `
+Usage: vault login -method=oidc [CONFIG K=V...]
+
+  The OIDC auth method allows users to authenticate using an OIDC provider.
+  The provider must be configured as part of a role by the operator.
+
+  Authenticate using role "engineering":
+
+      $ vault login -method=oidc role=engineering
+      Complete the login via your OIDC provider. Launching browser to:
+
+          https://accounts.google.com/o/oauth2/v2/...
+
+  The default browser will be opened for the user to complete the login.
+  Alternatively, the user may visit the provided URL directly.
+
+Configuration:
+
+  role=<string>
+      Vault role of type "oidc" to use for authentication. If not provided,
+      the default role of the auth method is used.
+
+  listenaddress=<string>
+      Optional address to bind the OIDC callback listener to. Defaults to
+      "localhost".
+
+  port=<string>
+      Optional localhost port to use for the OIDC callback. Defaults to 8250.
+
+  callbackhost=<string>
+      Optional host of the redirect URI registered with the role. Defaults to
+      "localhost".
+`
+
+	return strings.TrimSpace(help)
+}
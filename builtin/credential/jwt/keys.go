@@ -0,0 +1,203 @@
+package jwt
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/helper/strutil"
+	jose "gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+// supportedAlgs are the asymmetric signature algorithms accepted by default.
+// Symmetric algorithms are never accepted since the validation keys are not
+// secret.
+var supportedAlgs = []string{
+	string(jose.RS256), string(jose.RS384), string(jose.RS512),
+	string(jose.ES256), string(jose.ES384), string(jose.ES512),
+	string(jose.PS256), string(jose.PS384), string(jose.PS512),
+	string(jose.EdDSA),
+}
+
+// errNoMatchingKey is returned when none of the keys validates the signature
+// of a token
+var errNoMatchingKey = errors.New("failed to verify the signature of the token with the configured keys")
+
+// keySet validates the signatures of tokens with either static public keys or
+// the keys of a JWKS URL, which are fetched again when a token is signed by an
+// unknown key
+type keySet struct {
+	algs []string
+
+	staticKeys []interface{}
+
+	jwksURL    string
+	httpClient *http.Client
+	remoteKeys *jose.JSONWebKeySet
+	l          sync.Mutex
+}
+
+// newStaticKeySet returns a key set of PEM encoded public keys
+func newStaticKeySet(pemKeys []string, algs []string) (*keySet, error) {
+	ks := &keySet{
+		algs: algs,
+	}
+	for _, pemKey := range pemKeys {
+		key, err := parsePublicKeyPEM(pemKey)
+		if err != nil {
+			return nil, err
+		}
+		ks.staticKeys = append(ks.staticKeys, key)
+	}
+	return ks, nil
+}
+
+// newRemoteKeySet returns a key set fetching the keys of the JWKS URL with the
+// HTTP client
+func newRemoteKeySet(jwksURL string, httpClient *http.Client, algs []string) *keySet {
+	return &keySet{
+		algs:       algs,
+		jwksURL:    jwksURL,
+		httpClient: httpClient,
+	}
+}
+
+// verifiedClaims verifies the signature of the token and decodes its claims
+// into dest
+func (ks *keySet) verifiedClaims(ctx context.Context, token *jwt.JSONWebToken, dest ...interface{}) error {
+	if len(token.Headers) != 1 {
+		return errors.New("token must have exactly one signature")
+	}
+	header := token.Headers[0]
+
+	algs := ks.algs
+	if len(algs) == 0 {
+		algs = supportedAlgs
+	}
+	if !strutil.StrListContains(algs, header.Algorithm) {
+		return fmt.Errorf("unsupported signing algorithm %q", header.Algorithm)
+	}
+
+	if ks.jwksURL == "" {
+		return verifyWithKeys(token, ks.staticKeys, dest)
+	}
+
+	ks.l.Lock()
+	defer ks.l.Unlock()
+
+	if ks.remoteKeys != nil {
+		err := verifyWithKeys(token, ks.jwksKeys(header.KeyID), dest)
+		if err != errNoMatchingKey {
+			return err
+		}
+	}
+
+	// The keys may have been rotated, so fetch them again
+	if err := ks.fetch(ctx); err != nil {
+		return err
+	}
+	return verifyWithKeys(token, ks.jwksKeys(header.KeyID), dest)
+}
+
+// jwksKeys returns the remote keys with the key ID, or all the remote keys if
+// the token has no key ID
+func (ks *keySet) jwksKeys(keyID string) []interface{} {
+	jwks := ks.remoteKeys.Keys
+	if keyID != "" {
+		jwks = ks.remoteKeys.Key(keyID)
+	}
+
+	var keys []interface{}
+	for _, jwk := range jwks {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		keys = append(keys, jwk.Key)
+	}
+	return keys
+}
+
+// fetch fetches the keys of the JWKS URL
+func (ks *keySet) fetch(ctx context.Context) error {
+	var jwks jose.JSONWebKeySet
+	if err := getJSON(ctx, ks.httpClient, ks.jwksURL, &jwks); err != nil {
+		return errwrap.Wrapf("failed to fetch the JWKS: {{err}}", err)
+	}
+	ks.remoteKeys = &jwks
+	return nil
+}
+
+func verifyWithKeys(token *jwt.JSONWebToken, keys []interface{}, dest []interface{}) error {
+	for _, key := range keys {
+		if err := token.Claims(key, dest...); err == nil {
+			return nil
+		}
+	}
+	return errNoMatchingKey
+}
+
+// parsePublicKeyPEM parses a PEM encoded public key or certificate
+func parsePublicKeyPEM(data string) (interface{}, error) {
+	block, rest := pem.Decode([]byte(data))
+	if block == nil || len(strings.TrimSpace(string(rest))) != 0 {
+		return nil, errors.New("public key must be a single PEM block")
+	}
+
+	if cert, err := x509.ParseCertificate(block.Bytes); err == nil {
+		return cert.PublicKey, nil
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, errwrap.Wrapf("failed to parse public key: {{err}}", err)
+	}
+	return key, nil
+}
+
+// httpClientWithCA returns an HTTP client trusting the PEM encoded CA
+// certificates, or the system roots if caPEM is empty
+func httpClientWithCA(caPEM string) (*http.Client, error) {
+	client := cleanhttp.DefaultClient()
+	if caPEM == "" {
+		return client, nil
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM([]byte(caPEM)) {
+		return nil, errors.New("could not parse the CA certificates")
+	}
+	client.Transport.(*http.Transport).TLSClientConfig = &tls.Config{
+		RootCAs: pool,
+	}
+	return client, nil
+}
+
+// getJSON decodes the JSON document at the URL into out
+func getJSON(ctx context.Context, client *http.Client, url string, out interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected response from %s: %s", url, resp.Status)
+	}
+	return jsonutil.DecodeJSONFromReader(resp.Body, out)
+}
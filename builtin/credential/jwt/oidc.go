@@ -0,0 +1,329 @@
+package jwt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	uuid "github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+	"golang.org/x/oauth2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+// oidcProvider is the OIDC provider of the discovery URL
+type oidcProvider struct {
+	issuer     string
+	authURL    string
+	tokenURL   string
+	keySet     *keySet
+	httpClient *http.Client
+}
+
+// discoveryDocument is the subset of the OIDC discovery document used by the
+// backend
+type discoveryDocument struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// newOIDCProvider fetches the discovery document of the OIDC provider of the
+// configuration
+func newOIDCProvider(ctx context.Context, config *jwtConfig) (*oidcProvider, error) {
+	httpClient, err := httpClientWithCA(config.OIDCDiscoveryCAPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	issuer := strings.TrimSuffix(config.OIDCDiscoveryURL, "/")
+
+	var doc discoveryDocument
+	if err := getJSON(ctx, httpClient, issuer+"/.well-known/openid-configuration", &doc); err != nil {
+		return nil, errwrap.Wrapf("failed to fetch the discovery document: {{err}}", err)
+	}
+	if doc.Issuer != issuer {
+		return nil, fmt.Errorf("issuer %q of the discovery document does not match the discovery URL %q", doc.Issuer, issuer)
+	}
+	if doc.JWKSURI == "" {
+		return nil, errors.New("discovery document has no JWKS URI")
+	}
+
+	return &oidcProvider{
+		issuer:     doc.Issuer,
+		authURL:    doc.AuthorizationEndpoint,
+		tokenURL:   doc.TokenEndpoint,
+		keySet:     newRemoteKeySet(doc.JWKSURI, httpClient, config.JWTSupportedAlgs),
+		httpClient: httpClient,
+	}, nil
+}
+
+// oauth2Config returns the OAuth2 configuration of the authorization code flow
+// of the role
+func (p *oidcProvider) oauth2Config(config *jwtConfig, role *jwtRole, redirectURI string) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     config.OIDCClientID,
+		ClientSecret: config.OIDCClientSecret,
+		RedirectURL:  redirectURI,
+		Scopes:       append([]string{"openid"}, role.OIDCScopes...),
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  p.authURL,
+			TokenURL: p.tokenURL,
+		},
+	}
+}
+
+// oidcState is a pending authorization code flow
+type oidcState struct {
+	rolename    string
+	nonce       string
+	redirectURI string
+	expiration  time.Time
+}
+
+func pathOIDCAuthURL(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "oidc/auth_url",
+		Fields: map[string]*framework.FieldSchema{
+			"role": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "The role to log in against. Defaults to the default role of the configuration.",
+			},
+			"redirect_uri": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "The OAuth redirect URI, which must be one of the allowed redirect URIs of the role.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathOIDCAuthURL,
+		},
+
+		HelpSynopsis:    pathOIDCAuthURLHelpSyn,
+		HelpDescription: pathOIDCAuthURLHelpDesc,
+	}
+}
+
+func pathOIDCCallback(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "oidc/callback",
+		Fields: map[string]*framework.FieldSchema{
+			"state": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "The state returned by the OIDC provider.",
+			},
+			"code": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "The authorization code returned by the OIDC provider.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathOIDCCallback,
+			logical.UpdateOperation: b.pathOIDCCallback,
+		},
+
+		HelpSynopsis:    pathOIDCCallbackHelpSyn,
+		HelpDescription: pathOIDCCallbackHelpDesc,
+	}
+}
+
+func (b *backend) pathOIDCAuthURL(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config, err := b.config(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return logical.ErrorResponse("could not load configuration"), nil
+	}
+	if config.OIDCClientID == "" {
+		return logical.ErrorResponse("OIDC is not configured"), nil
+	}
+
+	roleName := d.Get("role").(string)
+	if roleName == "" {
+		roleName = config.DefaultRole
+	}
+	if roleName == "" {
+		return logical.ErrorResponse("missing role"), nil
+	}
+
+	redirectURI := d.Get("redirect_uri").(string)
+	if redirectURI == "" {
+		return logical.ErrorResponse("missing redirect_uri"), nil
+	}
+
+	role, err := b.role(ctx, req.Storage, roleName)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return logical.ErrorResponse(fmt.Sprintf("role %q could not be found", roleName)), nil
+	}
+	if role.RoleType != roleTypeOIDC {
+		return logical.ErrorResponse(fmt.Sprintf("role %q is not an %q role", roleName, roleTypeOIDC)), nil
+	}
+	if !strutil.StrListContains(role.AllowedRedirectURIs, redirectURI) {
+		return logical.ErrorResponse(fmt.Sprintf("unauthorized redirect_uri: %s", redirectURI)), nil
+	}
+
+	provider, err := b.getProvider(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	stateID, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+
+	b.oidcLock.Lock()
+	b.purgeOIDCStates()
+	b.oidcStates[stateID] = &oidcState{
+		rolename:    roleName,
+		nonce:       nonce,
+		redirectURI: redirectURI,
+		expiration:  time.Now().Add(oidcStateTTL),
+	}
+	b.oidcLock.Unlock()
+
+	authURL := provider.oauth2Config(config, role, redirectURI).AuthCodeURL(stateID, oauth2.SetAuthURLParam("nonce", nonce))
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"auth_url": authURL,
+		},
+	}, nil
+}
+
+func (b *backend) pathOIDCCallback(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	state := b.takeOIDCState(d.Get("state").(string))
+	if state == nil {
+		return logical.ErrorResponse("expired or missing OAuth state"), nil
+	}
+
+	code := d.Get("code").(string)
+	if code == "" {
+		return logical.ErrorResponse("missing code"), nil
+	}
+
+	config, err := b.config(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return logical.ErrorResponse("could not load configuration"), nil
+	}
+
+	role, err := b.role(ctx, req.Storage, state.rolename)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil || role.RoleType != roleTypeOIDC {
+		return logical.ErrorResponse(fmt.Sprintf("role %q could not be found", state.rolename)), nil
+	}
+
+	provider, err := b.getProvider(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	oauth2Ctx := context.WithValue(ctx, oauth2.HTTPClient, provider.httpClient)
+	oauth2Token, err := provider.oauth2Config(config, role, state.redirectURI).Exchange(oauth2Ctx, code)
+	if err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("error exchanging the authorization code: %s", err)), nil
+	}
+
+	rawToken, ok := oauth2Token.Extra("id_token").(string)
+	if !ok {
+		return logical.ErrorResponse("no id_token found in the response of the OIDC provider"), nil
+	}
+
+	token, err := jwt.ParseSigned(rawToken)
+	if err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("error parsing the ID token: %s", err)), nil
+	}
+
+	var claims jwt.Claims
+	var allClaims map[string]interface{}
+	if err := provider.keySet.verifiedClaims(ctx, token, &claims, &allClaims); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	if err := validateTimeClaims(claims, provider.issuer); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+	if nonce, _ := allClaims["nonce"].(string); nonce != state.nonce {
+		return logical.ErrorResponse("invalid ID token nonce"), nil
+	}
+
+	// ID tokens are always issued for the client
+	audiences := role.BoundAudiences
+	if len(audiences) == 0 {
+		audiences = []string{config.OIDCClientID}
+	}
+	if err := validateBoundClaims(role, audiences, claims, allClaims); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	return b.createAuth(ctx, req, state.rolename, role, allClaims)
+}
+
+// takeOIDCState removes and returns the pending authorization code flow of the
+// state, or nil if it does not exist or has expired
+func (b *backend) takeOIDCState(stateID string) *oidcState {
+	b.oidcLock.Lock()
+	defer b.oidcLock.Unlock()
+
+	state, ok := b.oidcStates[stateID]
+	if !ok {
+		return nil
+	}
+	delete(b.oidcStates, stateID)
+
+	if time.Now().After(state.expiration) {
+		return nil
+	}
+	return state
+}
+
+// purgeOIDCStates removes the expired authorization code flows. The caller
+// must hold oidcLock.
+func (b *backend) purgeOIDCStates() {
+	now := time.Now()
+	for stateID, state := range b.oidcStates {
+		if now.After(state.expiration) {
+			delete(b.oidcStates, stateID)
+		}
+	}
+}
+
+const pathOIDCAuthURLHelpSyn = `
+Request an authorization URL to start an OIDC login flow.
+`
+
+const pathOIDCAuthURLHelpDesc = `
+Returns the URL of the OIDC provider at which the user authenticates. The
+provider then redirects the user to the redirect URI with the state and code
+to complete the login with the "oidc/callback" endpoint.
+`
+
+const pathOIDCCallbackHelpSyn = `
+Callback endpoint to complete an OIDC login.
+`
+
+const pathOIDCCallbackHelpDesc = `
+Exchanges the authorization code returned by the OIDC provider for an ID
+token, which is validated against the role of the login flow.
+`
@@ -0,0 +1,290 @@
+package jwt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/fatih/structs"
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+func pathConfig(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config",
+		Fields: map[string]*framework.FieldSchema{
+			"oidc_discovery_url": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: `OIDC Discovery URL, without any .well-known component (base path). Cannot be used with "jwks_url" or "jwt_validation_pubkeys".`,
+			},
+			"oidc_discovery_ca_pem": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "The CA certificate or chain of certificates, in PEM format, to use to validate connections to the OIDC Discovery URL. If not set, system certificates are used.",
+			},
+			"oidc_client_id": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "The OAuth Client ID configured with your OIDC provider, required by the OIDC roles.",
+			},
+			"oidc_client_secret": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "The OAuth Client Secret configured with your OIDC provider, required by the OIDC roles.",
+			},
+			"jwks_url": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: `JWKS URL to use to authenticate signatures. Cannot be used with "oidc_discovery_url" or "jwt_validation_pubkeys".`,
+			},
+			"jwks_ca_pem": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "The CA certificate or chain of certificates, in PEM format, to use to validate connections to the JWKS URL. If not set, system certificates are used.",
+			},
+			"jwt_validation_pubkeys": &framework.FieldSchema{
+				Type:        framework.TypeCommaStringSlice,
+				Description: `A list of PEM-encoded public keys to use to authenticate signatures locally. Cannot be used with "jwks_url" or "oidc_discovery_url".`,
+			},
+			"jwt_supported_algs": &framework.FieldSchema{
+				Type:        framework.TypeCommaStringSlice,
+				Description: "A list of supported signing algorithms. Defaults to the RSA, ECDSA and EdDSA algorithms.",
+			},
+			"bound_issuer": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "The value against which to match the 'iss' claim in a JWT. Optional.",
+			},
+			"default_role": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "The default role to use if none is provided during login. If not set, a role is required during login.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathConfigRead,
+			logical.UpdateOperation: b.pathConfigWrite,
+		},
+
+		HelpSynopsis:    pathConfigHelpSyn,
+		HelpDescription: pathConfigHelpDesc,
+	}
+}
+
+// config returns the configuration of the backend, or nil if it has not been
+// configured
+func (b *backend) config(ctx context.Context, s logical.Storage) (*jwtConfig, error) {
+	entry, err := s.Get(ctx, "config")
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var config jwtConfig
+	if err := entry.DecodeJSON(&config); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
+
+func (b *backend) pathConfigRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config, err := b.config(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return nil, nil
+	}
+
+	data := structs.New(config).Map()
+	// The client secret is not returned
+	delete(data, "oidc_client_secret")
+
+	return &logical.Response{
+		Data: data,
+	}, nil
+}
+
+func (b *backend) pathConfigWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config := &jwtConfig{
+		OIDCDiscoveryURL:     d.Get("oidc_discovery_url").(string),
+		OIDCDiscoveryCAPEM:   d.Get("oidc_discovery_ca_pem").(string),
+		OIDCClientID:         d.Get("oidc_client_id").(string),
+		OIDCClientSecret:     d.Get("oidc_client_secret").(string),
+		JWKSURL:              d.Get("jwks_url").(string),
+		JWKSCAPEM:            d.Get("jwks_ca_pem").(string),
+		JWTValidationPubKeys: d.Get("jwt_validation_pubkeys").([]string),
+		JWTSupportedAlgs:     d.Get("jwt_supported_algs").([]string),
+		BoundIssuer:          d.Get("bound_issuer").(string),
+		DefaultRole:          d.Get("default_role").(string),
+	}
+
+	if err := config.validate(); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	// Verify that the keys can be fetched from the provider
+	switch {
+	case config.OIDCDiscoveryURL != "":
+		if _, err := newOIDCProvider(ctx, config); err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("error checking the OIDC discovery URL: %s", err)), nil
+		}
+	case config.JWKSURL != "":
+		ks, err := config.newKeySet()
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+		if err := ks.fetch(ctx); err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("error checking the JWKS URL: %s", err)), nil
+		}
+	}
+
+	entry, err := logical.StorageEntryJSON("config", config)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	b.reset()
+
+	return nil, nil
+}
+
+// jwtConfig is the configuration of the keys validating the tokens and of
+// the OIDC provider
+type jwtConfig struct {
+	OIDCDiscoveryURL     string   `json:"oidc_discovery_url" structs:"oidc_discovery_url" mapstructure:"oidc_discovery_url"`
+	OIDCDiscoveryCAPEM   string   `json:"oidc_discovery_ca_pem" structs:"oidc_discovery_ca_pem" mapstructure:"oidc_discovery_ca_pem"`
+	OIDCClientID         string   `json:"oidc_client_id" structs:"oidc_client_id" mapstructure:"oidc_client_id"`
+	OIDCClientSecret     string   `json:"oidc_client_secret" structs:"oidc_client_secret" mapstructure:"oidc_client_secret"`
+	JWKSURL              string   `json:"jwks_url" structs:"jwks_url" mapstructure:"jwks_url"`
+	JWKSCAPEM            string   `json:"jwks_ca_pem" structs:"jwks_ca_pem" mapstructure:"jwks_ca_pem"`
+	JWTValidationPubKeys []string `json:"jwt_validation_pubkeys" structs:"jwt_validation_pubkeys" mapstructure:"jwt_validation_pubkeys"`
+	JWTSupportedAlgs     []string `json:"jwt_supported_algs" structs:"jwt_supported_algs" mapstructure:"jwt_supported_algs"`
+	BoundIssuer          string   `json:"bound_issuer" structs:"bound_issuer" mapstructure:"bound_issuer"`
+	DefaultRole          string   `json:"default_role" structs:"default_role" mapstructure:"default_role"`
+}
+
+func (c *jwtConfig) validate() error {
+	sources := 0
+	for _, set := range []bool{c.OIDCDiscoveryURL != "", c.JWKSURL != "", len(c.JWTValidationPubKeys) != 0} {
+		if set {
+			sources++
+		}
+	}
+	switch {
+	case sources != 1:
+		return errors.New(`exactly one of "oidc_discovery_url", "jwks_url" or "jwt_validation_pubkeys" must be set`)
+	case c.OIDCDiscoveryURL == "" && (c.OIDCClientID != "" || c.OIDCClientSecret != ""):
+		return errors.New(`"oidc_client_id" and "oidc_client_secret" require "oidc_discovery_url"`)
+	case (c.OIDCClientID == "") != (c.OIDCClientSecret == ""):
+		return errors.New(`both "oidc_client_id" and "oidc_client_secret" must be set for OIDC`)
+	case c.OIDCDiscoveryURL == "" && c.OIDCDiscoveryCAPEM != "":
+		return errors.New(`"oidc_discovery_ca_pem" requires "oidc_discovery_url"`)
+	case c.JWKSURL == "" && c.JWKSCAPEM != "":
+		return errors.New(`"jwks_ca_pem" requires "jwks_url"`)
+	}
+
+	for _, alg := range c.JWTSupportedAlgs {
+		if !strutil.StrListContains(supportedAlgs, alg) {
+			return fmt.Errorf("unsupported signing algorithm %q", alg)
+		}
+	}
+
+	for _, pemKey := range c.JWTValidationPubKeys {
+		if _, err := parsePublicKeyPEM(pemKey); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// newKeySet returns the key set of the JWKS URL or of the static keys of the
+// configuration
+func (c *jwtConfig) newKeySet() (*keySet, error) {
+	if c.JWKSURL == "" {
+		return newStaticKeySet(c.JWTValidationPubKeys, c.JWTSupportedAlgs)
+	}
+
+	httpClient, err := httpClientWithCA(c.JWKSCAPEM)
+	if err != nil {
+		return nil, err
+	}
+	return newRemoteKeySet(c.JWKSURL, httpClient, c.JWTSupportedAlgs), nil
+}
+
+// getKeySet returns the cached key set validating the tokens
+func (b *backend) getKeySet(ctx context.Context, config *jwtConfig) (*keySet, error) {
+	if config.OIDCDiscoveryURL != "" {
+		provider, err := b.getProvider(ctx, config)
+		if err != nil {
+			return nil, err
+		}
+		return provider.keySet, nil
+	}
+
+	b.l.RLock()
+	ks := b.keySet
+	b.l.RUnlock()
+	if ks != nil {
+		return ks, nil
+	}
+
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	if b.keySet != nil {
+		return b.keySet, nil
+	}
+
+	ks, err := config.newKeySet()
+	if err != nil {
+		return nil, err
+	}
+	b.keySet = ks
+
+	return ks, nil
+}
+
+// getProvider returns the cached OIDC provider of the discovery URL
+func (b *backend) getProvider(ctx context.Context, config *jwtConfig) (*oidcProvider, error) {
+	if config.OIDCDiscoveryURL == "" {
+		return nil, errors.New("OIDC discovery is not configured")
+	}
+
+	b.l.RLock()
+	provider := b.provider
+	b.l.RUnlock()
+	if provider != nil {
+		return provider, nil
+	}
+
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	if b.provider != nil {
+		return b.provider, nil
+	}
+
+	provider, err := newOIDCProvider(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	b.provider = provider
+
+	return provider, nil
+}
+
+const pathConfigHelpSyn = `
+Configures the JWT authentication backend.
+`
+
+const pathConfigHelpDesc = `
+The JWT authentication backend validates JWTs (or OIDC ID tokens) with the
+keys of an OIDC provider ("oidc_discovery_url"), of a JWKS URL ("jwks_url") or
+with static public keys ("jwt_validation_pubkeys"). The OIDC authorization code
+flow additionally requires the "oidc_client_id" and "oidc_client_secret" of
+the client configured with the OIDC provider.
+`
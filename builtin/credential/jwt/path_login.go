@@ -0,0 +1,312 @@
+package jwt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/helper/policyutil"
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+// claimsLeeway is the clock skew tolerated when validating the time claims of
+// the tokens
+const claimsLeeway = 150 * time.Second
+
+func pathLogin(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "login$",
+		Fields: map[string]*framework.FieldSchema{
+			"role": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "The role to log in against. Defaults to the default role of the configuration.",
+			},
+			"jwt": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "The signed JWT to validate.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathLogin,
+		},
+
+		HelpSynopsis:    pathLoginHelpSyn,
+		HelpDescription: pathLoginHelpDesc,
+	}
+}
+
+func (b *backend) pathLogin(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config, err := b.config(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return logical.ErrorResponse("could not load configuration"), nil
+	}
+
+	roleName := d.Get("role").(string)
+	if roleName == "" {
+		roleName = config.DefaultRole
+	}
+	if roleName == "" {
+		return logical.ErrorResponse("missing role"), nil
+	}
+
+	role, err := b.role(ctx, req.Storage, roleName)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return logical.ErrorResponse(fmt.Sprintf("role %q could not be found", roleName)), nil
+	}
+	if role.RoleType != roleTypeJWT {
+		return logical.ErrorResponse(fmt.Sprintf("role %q is not a %q role", roleName, roleTypeJWT)), nil
+	}
+
+	rawToken := d.Get("jwt").(string)
+	if rawToken == "" {
+		return logical.ErrorResponse("missing token"), nil
+	}
+
+	token, err := jwt.ParseSigned(rawToken)
+	if err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("error parsing token: %s", err)), nil
+	}
+
+	ks, err := b.getKeySet(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	var claims jwt.Claims
+	var allClaims map[string]interface{}
+	if err := ks.verifiedClaims(ctx, token, &claims, &allClaims); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	if err := validateTimeClaims(claims, config.BoundIssuer); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+	if err := validateBoundClaims(role, role.BoundAudiences, claims, allClaims); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	return b.createAuth(ctx, req, roleName, role, allClaims)
+}
+
+func (b *backend) pathLoginRenew(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	roleName, ok := req.Auth.InternalData["role"].(string)
+	if !ok {
+		return nil, errors.New("failed to fetch role name during renewal")
+	}
+
+	role, err := b.role(ctx, req.Storage, roleName)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, fmt.Errorf("role %q no longer exists", roleName)
+	}
+
+	groups, _ := req.Auth.InternalData["groups"].([]interface{})
+	policies, err := b.policies(ctx, req.Storage, role, groups)
+	if err != nil {
+		return nil, err
+	}
+	if !policyutil.EquivalentPolicies(policies, req.Auth.Policies) {
+		return nil, errors.New("policies have changed, not renewing")
+	}
+
+	// If a period is provided, set that as part of resp.Auth.Period and return a
+	// response immediately. Let expiration manager handle renewal from there on.
+	if role.Period > time.Duration(0) {
+		resp := &logical.Response{
+			Auth: req.Auth,
+		}
+		resp.Auth.Period = role.Period
+		return resp, nil
+	}
+
+	return framework.LeaseExtend(role.TTL, role.MaxTTL, b.System())(ctx, req, d)
+}
+
+// validateTimeClaims checks the issuer and the time claims of the token, which
+// must expire
+func validateTimeClaims(claims jwt.Claims, issuer string) error {
+	if claims.Expiry == 0 {
+		return errors.New(`token must have an "exp" claim`)
+	}
+
+	err := claims.ValidateWithLeeway(jwt.Expected{
+		Issuer: issuer,
+		Time:   time.Now(),
+	}, claimsLeeway)
+	if err != nil {
+		return fmt.Errorf("error validating claims: %s", err)
+	}
+
+	if claims.IssuedAt != 0 && claims.IssuedAt.Time().After(time.Now().Add(claimsLeeway)) {
+		return errors.New("token is issued in the future")
+	}
+
+	return nil
+}
+
+// validateBoundClaims checks that the token has at least one of the audiences
+// and satisfies the bound subject and bound claims of the role
+func validateBoundClaims(role *jwtRole, audiences []string, claims jwt.Claims, allClaims map[string]interface{}) error {
+	if len(audiences) != 0 {
+		found := false
+		for _, aud := range audiences {
+			if claims.Audience.Contains(aud) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return errors.New("token audience does not match any of the bound audiences of the role")
+		}
+	}
+
+	if role.BoundSubject != "" && role.BoundSubject != claims.Subject {
+		return errors.New("token subject does not match the bound subject of the role")
+	}
+
+	for claim, expected := range role.BoundClaims {
+		actual, ok := allClaims[claim].(string)
+		if !ok {
+			return fmt.Errorf("claim %q is missing or is not a string", claim)
+		}
+
+		var allowed []string
+		switch v := expected.(type) {
+		case string:
+			allowed = []string{v}
+		case []interface{}:
+			for _, item := range v {
+				if s, ok := item.(string); ok {
+					allowed = append(allowed, s)
+				}
+			}
+		}
+		if !strutil.StrListContains(allowed, actual) {
+			return fmt.Errorf("claim %q does not match any of the bound values of the role", claim)
+		}
+	}
+
+	return nil
+}
+
+// groupsFromClaims returns the values of the groups claim of the role, which
+// may be a single string or a list of strings
+func groupsFromClaims(role *jwtRole, allClaims map[string]interface{}) ([]interface{}, error) {
+	if role.GroupsClaim == "" {
+		return nil, nil
+	}
+
+	switch v := allClaims[role.GroupsClaim].(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []interface{}{v}, nil
+	case []interface{}:
+		for _, group := range v {
+			if _, ok := group.(string); !ok {
+				return nil, fmt.Errorf("claim %q must be a string or a list of strings", role.GroupsClaim)
+			}
+		}
+		return v, nil
+	default:
+		return nil, fmt.Errorf("claim %q must be a string or a list of strings", role.GroupsClaim)
+	}
+}
+
+// policies returns the policies of the role and the policies mapped from the
+// groups
+func (b *backend) policies(ctx context.Context, s logical.Storage, role *jwtRole, groups []interface{}) ([]string, error) {
+	var names []string
+	for _, group := range groups {
+		names = append(names, group.(string))
+	}
+
+	groupPolicies, err := b.GroupMap.Policies(ctx, s, names...)
+	if err != nil {
+		return nil, err
+	}
+
+	return strutil.RemoveDuplicates(append(append([]string{}, role.Policies...), groupPolicies...), true), nil
+}
+
+// createAuth returns the response authenticating the client of the validated
+// claims against the role
+func (b *backend) createAuth(ctx context.Context, req *logical.Request, roleName string, role *jwtRole, allClaims map[string]interface{}) (*logical.Response, error) {
+	userName, ok := allClaims[role.UserClaim].(string)
+	if !ok || userName == "" {
+		return logical.ErrorResponse(fmt.Sprintf("claim %q is missing or is not a string", role.UserClaim)), nil
+	}
+
+	groups, err := groupsFromClaims(role, allClaims)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	policies, err := b.policies(ctx, req.Storage, role, groups)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := map[string]string{
+		"role": roleName,
+	}
+	for claim, key := range role.ClaimMappings {
+		switch v := allClaims[claim].(type) {
+		case string:
+			metadata[key] = v
+		case bool, float64:
+			metadata[key] = fmt.Sprintf("%v", v)
+		}
+	}
+
+	auth := &logical.Auth{
+		Policies:    policies,
+		DisplayName: userName,
+		Period:      role.Period,
+		Metadata:    metadata,
+		InternalData: map[string]interface{}{
+			"role":   roleName,
+			"groups": groups,
+		},
+		LeaseOptions: logical.LeaseOptions{
+			Renewable: true,
+			TTL:       role.TTL,
+		},
+		Alias: &logical.Alias{
+			Name: userName,
+		},
+	}
+
+	for _, group := range groups {
+		auth.GroupAliases = append(auth.GroupAliases, &logical.Alias{
+			Name: group.(string),
+		})
+	}
+
+	return &logical.Response{
+		Auth: auth,
+	}, nil
+}
+
+const pathLoginHelpSyn = `
+Authenticates to Vault with a JWT.
+`
+
+const pathLoginHelpDesc = `
+Authenticates a signed JWT against a "jwt" role. The signature of the token is
+verified with the configured keys, and its claims must satisfy the bound
+constraints of the role.
+`
@@ -0,0 +1,286 @@
+package jwt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/helper/policyutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+const (
+	roleTypeJWT  = "jwt"
+	roleTypeOIDC = "oidc"
+)
+
+func pathRoleList(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "role/?$",
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ListOperation: b.pathRoleList,
+		},
+		HelpSynopsis:    pathRoleHelpSyn,
+		HelpDescription: pathRoleHelpDesc,
+	}
+}
+
+func pathRole(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "role/" + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the role.",
+			},
+			"role_type": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Default:     roleTypeJWT,
+				Description: `Type of the role, either "jwt" to log in with signed JWTs or "oidc" to log in with the OIDC authorization code flow.`,
+			},
+			"bound_audiences": &framework.FieldSchema{
+				Type:        framework.TypeCommaStringSlice,
+				Description: `Comma-separated list of 'aud' claims of which the JWT must have at least one. Defaults to the client ID for "oidc" roles.`,
+			},
+			"bound_subject": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: `The 'sub' claim that is valid for login.`,
+			},
+			"bound_claims": &framework.FieldSchema{
+				Type:        framework.TypeMap,
+				Description: `Map of claims to a value or a list of values, one of which the claim of the JWT must match.`,
+			},
+			"claim_mappings": &framework.FieldSchema{
+				Type:        framework.TypeKVPairs,
+				Description: `Mappings of claims to the metadata keys in which their values are set.`,
+			},
+			"user_claim": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: `The claim to use for the Identity entity alias name.`,
+			},
+			"groups_claim": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: `The claim to use for the Identity group alias names, and whose values are mapped to policies with the "map/groups" endpoints.`,
+			},
+			"oidc_scopes": &framework.FieldSchema{
+				Type:        framework.TypeCommaStringSlice,
+				Description: `Comma-separated list of OIDC scopes requested in addition to "openid".`,
+			},
+			"allowed_redirect_uris": &framework.FieldSchema{
+				Type:        framework.TypeCommaStringSlice,
+				Description: `Comma-separated list of the allowed values for redirect_uri of the OIDC authorization code flow.`,
+			},
+			"policies": &framework.FieldSchema{
+				Type:        framework.TypeCommaStringSlice,
+				Description: "List of policies on the role.",
+			},
+			"ttl": &framework.FieldSchema{
+				Type:        framework.TypeDurationSecond,
+				Description: "Duration in seconds after which the issued token should expire. Defaults to the system/mount default TTL.",
+			},
+			"max_ttl": &framework.FieldSchema{
+				Type:        framework.TypeDurationSecond,
+				Description: "Duration in seconds after which the issued token should not be allowed to be renewed. Defaults to the system/mount maximum TTL.",
+			},
+			"period": &framework.FieldSchema{
+				Type:        framework.TypeDurationSecond,
+				Description: "If set, indicates that the token generated using this role should never expire. The token should be renewed within the duration specified by this value.",
+			},
+		},
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathRoleRead,
+			logical.UpdateOperation: b.pathRoleWrite,
+			logical.DeleteOperation: b.pathRoleDelete,
+		},
+		HelpSynopsis:    pathRoleHelpSyn,
+		HelpDescription: pathRoleHelpDesc,
+	}
+}
+
+// jwtRole is a role binding the claims of the tokens which can log in and
+// defining the tokens of the authenticated clients
+type jwtRole struct {
+	RoleType            string                 `json:"role_type"`
+	BoundAudiences      []string               `json:"bound_audiences"`
+	BoundSubject        string                 `json:"bound_subject"`
+	BoundClaims         map[string]interface{} `json:"bound_claims"`
+	ClaimMappings       map[string]string      `json:"claim_mappings"`
+	UserClaim           string                 `json:"user_claim"`
+	GroupsClaim         string                 `json:"groups_claim"`
+	OIDCScopes          []string               `json:"oidc_scopes"`
+	AllowedRedirectURIs []string               `json:"allowed_redirect_uris"`
+	Policies            []string               `json:"policies"`
+	TTL                 time.Duration          `json:"ttl"`
+	MaxTTL              time.Duration          `json:"max_ttl"`
+	Period              time.Duration          `json:"period"`
+}
+
+// role returns the role with the given name, or nil if there is no such role
+func (b *backend) role(ctx context.Context, s logical.Storage, name string) (*jwtRole, error) {
+	entry, err := s.Get(ctx, "role/"+strings.ToLower(name))
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var role jwtRole
+	if err := entry.DecodeJSON(&role); err != nil {
+		return nil, err
+	}
+
+	return &role, nil
+}
+
+func (b *backend) pathRoleList(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	roles, err := req.Storage.List(ctx, "role/")
+	if err != nil {
+		return nil, err
+	}
+	return logical.ListResponse(roles), nil
+}
+
+func (b *backend) pathRoleRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	role, err := b.role(ctx, req.Storage, d.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"role_type":             role.RoleType,
+			"bound_audiences":       role.BoundAudiences,
+			"bound_subject":         role.BoundSubject,
+			"bound_claims":          role.BoundClaims,
+			"claim_mappings":        role.ClaimMappings,
+			"user_claim":            role.UserClaim,
+			"groups_claim":          role.GroupsClaim,
+			"oidc_scopes":           role.OIDCScopes,
+			"allowed_redirect_uris": role.AllowedRedirectURIs,
+			"policies":              role.Policies,
+			"ttl":                   int64(role.TTL.Seconds()),
+			"max_ttl":               int64(role.MaxTTL.Seconds()),
+			"period":                int64(role.Period.Seconds()),
+		},
+	}, nil
+}
+
+func (b *backend) pathRoleDelete(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	return nil, req.Storage.Delete(ctx, "role/"+strings.ToLower(d.Get("name").(string)))
+}
+
+func (b *backend) pathRoleWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := strings.ToLower(d.Get("name").(string))
+
+	role := &jwtRole{
+		RoleType:            d.Get("role_type").(string),
+		BoundAudiences:      d.Get("bound_audiences").([]string),
+		BoundSubject:        d.Get("bound_subject").(string),
+		BoundClaims:         d.Get("bound_claims").(map[string]interface{}),
+		ClaimMappings:       d.Get("claim_mappings").(map[string]string),
+		UserClaim:           d.Get("user_claim").(string),
+		GroupsClaim:         d.Get("groups_claim").(string),
+		OIDCScopes:          d.Get("oidc_scopes").([]string),
+		AllowedRedirectURIs: d.Get("allowed_redirect_uris").([]string),
+		Policies:            policyutil.ParsePolicies(d.Get("policies")),
+		TTL:                 time.Duration(d.Get("ttl").(int)) * time.Second,
+		MaxTTL:              time.Duration(d.Get("max_ttl").(int)) * time.Second,
+		Period:              time.Duration(d.Get("period").(int)) * time.Second,
+	}
+
+	if err := role.validate(); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	var resp *logical.Response
+	if role.Period > b.System().MaxLeaseTTL() {
+		resp = &logical.Response{}
+		resp.AddWarning(fmt.Sprintf("period of %q is greater than the backend's maximum TTL of %q", role.Period.String(), b.System().MaxLeaseTTL().String()))
+	}
+
+	entry, err := logical.StorageEntryJSON("role/"+name, role)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (r *jwtRole) validate() error {
+	switch r.RoleType {
+	case roleTypeJWT:
+		if len(r.BoundAudiences) == 0 && r.BoundSubject == "" && len(r.BoundClaims) == 0 {
+			return errors.New(`must have at least one bound constraint ("bound_audiences", "bound_subject" or "bound_claims") for a "jwt" role`)
+		}
+		if len(r.OIDCScopes) != 0 || len(r.AllowedRedirectURIs) != 0 {
+			return errors.New(`"oidc_scopes" and "allowed_redirect_uris" are only valid for "oidc" roles`)
+		}
+	case roleTypeOIDC:
+		if len(r.AllowedRedirectURIs) == 0 {
+			return errors.New(`"allowed_redirect_uris" is required for "oidc" roles`)
+		}
+	default:
+		return fmt.Errorf("invalid role_type %q", r.RoleType)
+	}
+
+	if r.UserClaim == "" {
+		return errors.New(`a "user_claim" must be set`)
+	}
+	if r.MaxTTL > 0 && r.TTL > r.MaxTTL {
+		return errors.New("ttl should not be greater than max_ttl")
+	}
+
+	for claim, value := range r.BoundClaims {
+		switch v := value.(type) {
+		case string:
+		case []interface{}:
+			for _, item := range v {
+				if _, ok := item.(string); !ok {
+					return fmt.Errorf("bound claim %q must be a string or a list of strings", claim)
+				}
+			}
+		default:
+			return fmt.Errorf("bound claim %q must be a string or a list of strings", claim)
+		}
+	}
+
+	targets := make(map[string]bool)
+	for claim, target := range r.ClaimMappings {
+		if target == "" || target == "role" {
+			return fmt.Errorf("invalid metadata key %q for claim %q", target, claim)
+		}
+		if targets[target] {
+			return fmt.Errorf("metadata key %q is mapped from several claims", target)
+		}
+		targets[target] = true
+	}
+
+	return nil
+}
+
+const pathRoleHelpSyn = `
+Manage the roles that can be used to log in.
+`
+
+const pathRoleHelpDesc = `
+This path lets you manage the roles of the JWT authentication backend.
+
+"jwt" roles authenticate clients presenting a JWT signed by the configured
+keys, and "oidc" roles authenticate users with the authorization code flow of
+the configured OIDC provider. The claims of the tokens must satisfy the bound
+constraints of the role, the "user_claim" names the Identity entity alias of the
+client and the values of the "groups_claim" name its Identity group aliases.
+
+Logged in clients get the policies of the role, and the policies mapped from
+the values of the groups claim with the "map/groups" endpoints.
+`
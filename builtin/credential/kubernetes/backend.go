@@ -0,0 +1,120 @@
+package kubernetes
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+const (
+	configPath = "config"
+	rolePrefix = "role/"
+)
+
+// Factory creates and configures the backend
+func Factory(ctx context.Context, conf *logical.BackendConfig) (logical.Backend, error) {
+	b := Backend()
+	if err := b.Setup(ctx, conf); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Backend returns a new Kubernetes auth backend
+func Backend() *backend {
+	var b backend
+	b.reviewFactory = tokenReviewAPIFactory
+
+	b.Backend = &framework.Backend{
+		Help: backendHelp,
+
+		PathsSpecial: &logical.Paths{
+			Unauthenticated: []string{
+				"login",
+			},
+			SealWrapStorage: []string{
+				configPath,
+			},
+		},
+
+		Paths: []*framework.Path{
+			pathConfig(&b),
+			pathRoleList(&b),
+			pathRole(&b),
+			pathLogin(&b),
+		},
+
+		AuthRenew:   b.pathLoginRenew,
+		BackendType: logical.TypeCredential,
+	}
+
+	return &b
+}
+
+type backend struct {
+	*framework.Backend
+
+	// reviewFactory returns the token reviewer of the configuration, which
+	// calls the TokenReview API. Tests replace it with a mock.
+	reviewFactory tokenReviewFactory
+
+	l sync.RWMutex
+}
+
+// config returns the configuration of the backend, or nil if it has not been
+// configured
+func (b *backend) config(ctx context.Context, s logical.Storage) (*kubeConfig, error) {
+	entry, err := s.Get(ctx, configPath)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var config kubeConfig
+	if err := entry.DecodeJSON(&config); err != nil {
+		return nil, err
+	}
+
+	for _, pemKey := range config.PEMKeys {
+		key, err := parsePublicKeyPEM(pemKey)
+		if err != nil {
+			return nil, err
+		}
+		config.PublicKeys = append(config.PublicKeys, key)
+	}
+
+	return &config, nil
+}
+
+// role returns the role with the given name, or nil if there is no such role
+func (b *backend) role(ctx context.Context, s logical.Storage, name string) (*roleStorageEntry, error) {
+	entry, err := s.Get(ctx, rolePrefix+strings.ToLower(name))
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var role roleStorageEntry
+	if err := entry.DecodeJSON(&role); err != nil {
+		return nil, err
+	}
+
+	return &role, nil
+}
+
+const backendHelp = `
+The Kubernetes auth backend authenticates Kubernetes service accounts with
+their service account tokens, either the legacy tokens of the service account
+secrets or the projected tokens of the pods.
+
+The tokens are verified with the TokenReview API of the Kubernetes cluster, and
+roles bind the namespaces, names and audiences of the service accounts which
+can log in.
+`
@@ -0,0 +1,362 @@
+package kubernetes
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+	jose "gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+const (
+	testName      = "vault-auth"
+	testNamespace = "default"
+	testUID       = "d77f89bc-9055-11e7-a068-0800276d99bf"
+	testIssuer    = "https://kubernetes.default.svc"
+)
+
+type mockTokenReview struct {
+	name      string
+	namespace string
+	uid       string
+
+	audiences []string
+}
+
+func (t *mockTokenReview) Review(ctx context.Context, token string, audiences []string) (*tokenReviewResult, error) {
+	t.audiences = audiences
+	return &tokenReviewResult{
+		Name:      t.name,
+		Namespace: t.namespace,
+		UID:       t.uid,
+		Audiences: audiences,
+	}, nil
+}
+
+func setupBackend(t *testing.T, review *mockTokenReview) (*backend, logical.Storage, *ecdsa.PrivateKey) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+
+	b := Backend()
+	if err := b.Setup(context.Background(), config); err != nil {
+		t.Fatal(err)
+	}
+	b.reviewFactory = func(*kubeConfig) tokenReviewer {
+		return review
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testWrite(t, b, config.StorageView, "config", map[string]interface{}{
+		"kubernetes_host": "https://192.168.99.100:8443",
+		"pem_keys":        string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})),
+		"issuer":          testIssuer,
+	})
+	resp := testRequest(t, b, config.StorageView, logical.CreateOperation, "role/plugin-test", map[string]interface{}{
+		"bound_service_account_names":      testName,
+		"bound_service_account_namespaces": testNamespace,
+		"policies":                         "test",
+		"period":                           "3s",
+		"ttl":                              "1s",
+		"num_uses":                         12,
+		"max_ttl":                          "5s",
+	})
+	if resp != nil && resp.IsError() {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	return b, config.StorageView, key
+}
+
+func testRequest(t *testing.T, b *backend, s logical.Storage, op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: op,
+		Path:      path,
+		Storage:   s,
+		Data:      data,
+	})
+	if err != nil {
+		t.Fatalf("%s %s: %v", op, path, err)
+	}
+	return resp
+}
+
+func testWrite(t *testing.T, b *backend, s logical.Storage, path string, data map[string]interface{}) {
+	resp := testRequest(t, b, s, logical.UpdateOperation, path, data)
+	if resp != nil && resp.IsError() {
+		t.Fatalf("write %s: %v", path, resp.Error())
+	}
+}
+
+func testSign(t *testing.T, key *ecdsa.PrivateKey, claims ...interface{}) string {
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.ES256, Key: key}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	builder := jwt.Signed(signer)
+	for _, c := range claims {
+		builder = builder.Claims(c)
+	}
+	token, err := builder.CompactSerialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func testLegacyClaims(name string) map[string]interface{} {
+	return map[string]interface{}{
+		"iss":                                    legacyIssuer,
+		"kubernetes.io/serviceaccount/namespace": testNamespace,
+		"kubernetes.io/serviceaccount/secret.name":          name + "-token-t9m2q",
+		"kubernetes.io/serviceaccount/service-account.name": name,
+		"kubernetes.io/serviceaccount/service-account.uid":  testUID,
+		"sub": "system:serviceaccount:" + testNamespace + ":" + name,
+	}
+}
+
+func testProjectedClaims(name string, expiry time.Time, audience ...string) interface{} {
+	return struct {
+		jwt.Claims
+		Kubernetes interface{} `json:"kubernetes.io"`
+	}{
+		Claims: jwt.Claims{
+			Issuer:    testIssuer,
+			Subject:   "system:serviceaccount:" + testNamespace + ":" + name,
+			Audience:  jwt.Audience(audience),
+			Expiry:    jwt.NewNumericDate(expiry),
+			NotBefore: jwt.NewNumericDate(time.Now().Add(-time.Minute)),
+			IssuedAt:  jwt.NewNumericDate(time.Now().Add(-time.Minute)),
+		},
+		Kubernetes: map[string]interface{}{
+			"namespace": testNamespace,
+			"pod": map[string]interface{}{
+				"name": "vault-agent-5d8b7c7d8-x2kqz",
+				"uid":  "8b2a4e6e-62c9-4b4f-b5bc-33c1a4f5f0e2",
+			},
+			"serviceaccount": map[string]interface{}{
+				"name": name,
+				"uid":  testUID,
+			},
+		},
+	}
+}
+
+func TestBackend_role(t *testing.T) {
+	b, s, _ := setupBackend(t, &mockTokenReview{})
+
+	resp := testRequest(t, b, s, logical.ReadOperation, "role/plugin-test", nil)
+	if resp == nil || resp.IsError() {
+		t.Fatalf("bad: %#v", resp)
+	}
+	expected := map[string]interface{}{
+		"bound_service_account_names":      []string{testName},
+		"bound_service_account_namespaces": []string{testNamespace},
+		"audience":                         "",
+		"policies":                         []string{"test"},
+		"period":                           int64(3),
+		"ttl":                              int64(1),
+		"num_uses":                         12,
+		"max_ttl":                          int64(5),
+	}
+	if !reflect.DeepEqual(resp.Data, expected) {
+		t.Fatalf("bad: expected %#v, got %#v", expected, resp.Data)
+	}
+
+	// Updates only change the given fields
+	testWrite(t, b, s, "role/plugin-test", map[string]interface{}{
+		"audience": "vault",
+	})
+	resp = testRequest(t, b, s, logical.ReadOperation, "role/plugin-test", nil)
+	expected["audience"] = "vault"
+	if !reflect.DeepEqual(resp.Data, expected) {
+		t.Fatalf("bad: expected %#v, got %#v", expected, resp.Data)
+	}
+
+	for _, data := range []map[string]interface{}{
+		{"bound_service_account_names": "*", "bound_service_account_namespaces": "*"},
+		{"bound_service_account_names": "*,name", "bound_service_account_namespaces": testNamespace},
+		{"bound_service_account_namespaces": testNamespace},
+	} {
+		resp := testRequest(t, b, s, logical.CreateOperation, "role/bad", data)
+		if resp == nil || !resp.IsError() {
+			t.Fatalf("expected an error for %#v, got: %#v", data, resp)
+		}
+	}
+}
+
+func TestBackend_loginLegacyToken(t *testing.T) {
+	review := &mockTokenReview{name: testName, namespace: testNamespace, uid: testUID}
+	b, s, key := setupBackend(t, review)
+
+	resp := testRequest(t, b, s, logical.UpdateOperation, "login", map[string]interface{}{
+		"role": "plugin-test",
+		"jwt":  testSign(t, key, testLegacyClaims(testName)),
+	})
+	if resp == nil || resp.IsError() || resp.Auth == nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+	if resp.Auth.Alias.Name != testUID || resp.Auth.DisplayName != testName {
+		t.Fatalf("bad auth: %#v", resp.Auth)
+	}
+	if resp.Auth.Metadata["service_account_secret_name"] != testName+"-token-t9m2q" || resp.Auth.NumUses != 12 || resp.Auth.TTL != 3*time.Second {
+		t.Fatalf("bad auth: %#v", resp.Auth)
+	}
+	if review.audiences != nil {
+		t.Fatalf("expected no audiences in the review, got: %#v", review.audiences)
+	}
+
+	lookahead := testRequest(t, b, s, logical.AliasLookaheadOperation, "login", map[string]interface{}{
+		"jwt": testSign(t, key, testLegacyClaims(testName)),
+	})
+	if lookahead == nil || lookahead.Auth == nil || lookahead.Auth.Alias.Name != testUID {
+		t.Fatalf("bad: %#v", lookahead)
+	}
+
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrongIssuer := testLegacyClaims(testName)
+	wrongIssuer["iss"] = "https://example.com"
+
+	for name, token := range map[string]string{
+		"wrong key":       testSign(t, otherKey, testLegacyClaims(testName)),
+		"wrong name":      testSign(t, key, testLegacyClaims("other")),
+		"wrong issuer":    testSign(t, key, wrongIssuer),
+		"not a jwt":       "abc",
+		"missing account": testSign(t, key, map[string]interface{}{"iss": legacyIssuer}),
+	} {
+		resp := testRequest(t, b, s, logical.UpdateOperation, "login", map[string]interface{}{
+			"role": "plugin-test",
+			"jwt":  token,
+		})
+		if resp == nil || !resp.IsError() {
+			t.Fatalf("%s: expected an error, got: %#v", name, resp)
+		}
+	}
+
+	// The review must match the service account of the token
+	review.uid = "other"
+	resp = testRequest(t, b, s, logical.UpdateOperation, "login", map[string]interface{}{
+		"role": "plugin-test",
+		"jwt":  testSign(t, key, testLegacyClaims(testName)),
+	})
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error, got: %#v", resp)
+	}
+}
+
+func TestBackend_loginProjectedToken(t *testing.T) {
+	review := &mockTokenReview{name: testName, namespace: testNamespace, uid: testUID}
+	b, s, key := setupBackend(t, review)
+
+	testWrite(t, b, s, "role/plugin-test", map[string]interface{}{
+		"audience": "vault",
+	})
+
+	resp := testRequest(t, b, s, logical.UpdateOperation, "login", map[string]interface{}{
+		"role": "plugin-test",
+		"jwt":  testSign(t, key, testProjectedClaims(testName, time.Now().Add(time.Hour), "vault")),
+	})
+	if resp == nil || resp.IsError() || resp.Auth == nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+	if resp.Auth.Alias.Name != testUID || resp.Auth.Metadata["service_account_pod_name"] != "vault-agent-5d8b7c7d8-x2kqz" {
+		t.Fatalf("bad auth: %#v", resp.Auth)
+	}
+	if !reflect.DeepEqual(review.audiences, []string{"vault"}) {
+		t.Fatalf("expected the role audience in the review, got: %#v", review.audiences)
+	}
+
+	for name, token := range map[string]string{
+		"wrong audience": testSign(t, key, testProjectedClaims(testName, time.Now().Add(time.Hour), "kubernetes")),
+		"no audience":    testSign(t, key, testProjectedClaims(testName, time.Now().Add(time.Hour))),
+		"expired":        testSign(t, key, testProjectedClaims(testName, time.Now().Add(-time.Hour), "vault")),
+		"legacy token":   testSign(t, key, testLegacyClaims(testName)),
+	} {
+		resp := testRequest(t, b, s, logical.UpdateOperation, "login", map[string]interface{}{
+			"role": "plugin-test",
+			"jwt":  token,
+		})
+		if resp == nil || !resp.IsError() {
+			t.Fatalf("%s: expected an error, got: %#v", name, resp)
+		}
+	}
+}
+
+func TestTokenReviewAPI(t *testing.T) {
+	var request tokenReview
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/apis/authentication.k8s.io/v1/tokenreviews" || r.Header.Get("Authorization") != "Bearer reviewer" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Fatal(err)
+		}
+
+		request.Status = tokenReviewStatus{
+			Authenticated: request.Spec.Token == "valid",
+			User: userInfo{
+				Username: "system:serviceaccount:" + testNamespace + ":" + testName,
+				UID:      testUID,
+			},
+			Audiences: request.Spec.Audiences,
+		}
+		json.NewEncoder(w).Encode(&request)
+	}))
+	defer server.Close()
+
+	tr := tokenReviewAPIFactory(&kubeConfig{
+		Host:             server.URL,
+		TokenReviewerJWT: "reviewer",
+	})
+
+	result, err := tr.Review(context.Background(), "valid", []string{"vault"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &tokenReviewResult{
+		Name:      testName,
+		Namespace: testNamespace,
+		UID:       testUID,
+		Audiences: []string{"vault"},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Fatalf("bad: expected %#v, got %#v", expected, result)
+	}
+	if request.Kind != "TokenReview" || !reflect.DeepEqual(request.Spec.Audiences, []string{"vault"}) {
+		t.Fatalf("bad request: %#v", request)
+	}
+
+	if _, err := tr.Review(context.Background(), "invalid", nil); err == nil || !strings.Contains(err.Error(), "not valid") {
+		t.Fatalf("expected an error, got: %v", err)
+	}
+
+	tr = tokenReviewAPIFactory(&kubeConfig{
+		Host: server.URL,
+	})
+	if _, err := tr.Review(context.Background(), "valid", nil); err == nil || !strings.Contains(err.Error(), "unauthorized") {
+		t.Fatalf("expected an error, got: %v", err)
+	}
+}
@@ -0,0 +1,191 @@
+package kubernetes
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+// legacyIssuer is the issuer of the service account tokens stored in the
+// service account secrets
+const legacyIssuer = "kubernetes/serviceaccount"
+
+func pathConfig(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config$",
+		Fields: map[string]*framework.FieldSchema{
+			"kubernetes_host": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Host must be a host string, a host:port pair, or a URL to the base of the Kubernetes API server.",
+			},
+			"kubernetes_ca_cert": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "PEM encoded CA cert for use by the TLS client used to talk with the API.",
+			},
+			"token_reviewer_jwt": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `A service account JWT used to access the TokenReview API to validate
+other JWTs during login. If not set the JWT used for login will be used to
+access the API.`,
+			},
+			"pem_keys": &framework.FieldSchema{
+				Type: framework.TypeCommaStringSlice,
+				Description: `Optional list of PEM-formatted public keys or certificates used to verify
+the signatures of Kubernetes service account JWTs. If a certificate is given,
+its public key will be extracted. Not every installation of Kubernetes exposes
+these keys.`,
+			},
+			"issuer": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `Optional issuer of the projected service account tokens, as set by the
+--service-account-issuer flag of the API server. Tokens issued by
+"kubernetes/serviceaccount" are always accepted.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathConfigRead,
+			logical.UpdateOperation: b.pathConfigWrite,
+		},
+
+		HelpSynopsis:    pathConfigHelpSyn,
+		HelpDescription: pathConfigHelpDesc,
+	}
+}
+
+func (b *backend) pathConfigRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	b.l.RLock()
+	defer b.l.RUnlock()
+
+	config, err := b.config(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"kubernetes_host":    config.Host,
+			"kubernetes_ca_cert": config.CACert,
+			"token_reviewer_jwt": config.TokenReviewerJWT,
+			"pem_keys":           config.PEMKeys,
+			"issuer":             config.Issuer,
+		},
+	}, nil
+}
+
+func (b *backend) pathConfigWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config := &kubeConfig{
+		Host:             d.Get("kubernetes_host").(string),
+		CACert:           d.Get("kubernetes_ca_cert").(string),
+		TokenReviewerJWT: d.Get("token_reviewer_jwt").(string),
+		PEMKeys:          d.Get("pem_keys").([]string),
+		Issuer:           d.Get("issuer").(string),
+	}
+
+	if config.Host == "" {
+		return logical.ErrorResponse("no host provided"), nil
+	}
+	if len(config.PEMKeys) == 0 && config.CACert == "" {
+		return logical.ErrorResponse("one of pem_keys or kubernetes_ca_cert must be set"), nil
+	}
+	if config.CACert != "" && !x509.NewCertPool().AppendCertsFromPEM([]byte(config.CACert)) {
+		return logical.ErrorResponse("could not parse kubernetes_ca_cert"), nil
+	}
+	if config.TokenReviewerJWT != "" {
+		if _, err := jwt.ParseSigned(config.TokenReviewerJWT); err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("token_reviewer_jwt is not a valid JWT: %s", err)), nil
+		}
+	}
+	for _, pemKey := range config.PEMKeys {
+		if _, err := parsePublicKeyPEM(pemKey); err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+	}
+
+	entry, err := logical.StorageEntryJSON(configPath, config)
+	if err != nil {
+		return nil, err
+	}
+
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// kubeConfig contains the public keys used to verify the signature of the
+// service account JWTs and the information to access the Kubernetes API
+type kubeConfig struct {
+	// PublicKeys are the parsed PEMKeys
+	PublicKeys []interface{} `json:"-"`
+	// PEMKeys are the PEM encoded public keys verifying the JWTs
+	PEMKeys []string `json:"pem_keys"`
+	// Host is the URL of the Kubernetes API
+	Host string `json:"host"`
+	// CACert is the CA certificate of the Kubernetes API
+	CACert string `json:"ca_cert"`
+	// TokenReviewerJWT is the bearer token of the TokenReview API calls
+	TokenReviewerJWT string `json:"token_reviewer_jwt"`
+	// Issuer is the issuer of the projected service account tokens
+	Issuer string `json:"issuer"`
+}
+
+// issuers returns the accepted issuers of the service account tokens
+func (c *kubeConfig) issuers() []string {
+	issuers := []string{legacyIssuer}
+	if c.Issuer != "" && c.Issuer != legacyIssuer {
+		issuers = append(issuers, c.Issuer)
+	}
+	return issuers
+}
+
+// parsePublicKeyPEM parses a PEM encoded RSA or ECDSA public key or
+// certificate
+func parsePublicKeyPEM(data string) (interface{}, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, errors.New("data does not contain any valid RSA or ECDSA public keys")
+	}
+
+	var key interface{}
+	if cert, err := x509.ParseCertificate(block.Bytes); err == nil {
+		key = cert.PublicKey
+	} else if key, err = x509.ParsePKIXPublicKey(block.Bytes); err != nil {
+		return nil, errwrap.Wrapf("failed to parse public key: {{err}}", err)
+	}
+
+	switch key.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey:
+		return key, nil
+	default:
+		return nil, errors.New("data does not contain any valid RSA or ECDSA public keys")
+	}
+}
+
+const pathConfigHelpSyn = `
+Configures the JWT public keys and the Kubernetes API information.
+`
+
+const pathConfigHelpDesc = `
+The Kubernetes auth backend validates service account JWTs and verifies their
+existence with the Kubernetes TokenReview API. This endpoint configures the
+public keys used to validate the JWT signatures, the issuer of the projected
+service account tokens and the information necessary to access the Kubernetes
+API.
+`
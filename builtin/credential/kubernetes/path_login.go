@@ -0,0 +1,352 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+// claimsLeeway is the clock skew tolerated when validating the time claims of
+// the projected tokens
+const claimsLeeway = 60 * time.Second
+
+func pathLogin(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "login$",
+		Fields: map[string]*framework.FieldSchema{
+			"role": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the role against which the login is being attempted. This field is required.",
+			},
+			"jwt": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "A signed JWT for authenticating a service account. This field is required.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation:         b.pathLogin,
+			logical.AliasLookaheadOperation: b.pathLoginAliasLookahead,
+		},
+
+		HelpSynopsis:    pathLoginHelpSyn,
+		HelpDescription: pathLoginHelpDesc,
+	}
+}
+
+// serviceAccountClaims are the claims of the service account tokens. Legacy
+// tokens have the flat "kubernetes.io/serviceaccount/..." claims, and
+// projected tokens the nested "kubernetes.io" claim.
+type serviceAccountClaims struct {
+	jwt.Claims
+
+	LegacyName       string `json:"kubernetes.io/serviceaccount/service-account.name"`
+	LegacyUID        string `json:"kubernetes.io/serviceaccount/service-account.uid"`
+	LegacySecretName string `json:"kubernetes.io/serviceaccount/secret.name"`
+	LegacyNamespace  string `json:"kubernetes.io/serviceaccount/namespace"`
+
+	Kubernetes *projectedClaims `json:"kubernetes.io"`
+}
+
+type projectedClaims struct {
+	Namespace      string         `json:"namespace"`
+	ServiceAccount objectRefClaim `json:"serviceaccount"`
+	Pod            objectRefClaim `json:"pod"`
+}
+
+type objectRefClaim struct {
+	Name string `json:"name"`
+	UID  string `json:"uid"`
+}
+
+// serviceAccount is the service account of a token, which is compared with
+// the result of the TokenReview API
+type serviceAccount struct {
+	Name       string
+	UID        string
+	Namespace  string
+	SecretName string
+	PodName    string
+}
+
+func (c *serviceAccountClaims) serviceAccount() (*serviceAccount, error) {
+	sa := &serviceAccount{
+		Name:       c.LegacyName,
+		UID:        c.LegacyUID,
+		Namespace:  c.LegacyNamespace,
+		SecretName: c.LegacySecretName,
+	}
+	if c.Kubernetes != nil {
+		sa = &serviceAccount{
+			Name:      c.Kubernetes.ServiceAccount.Name,
+			UID:       c.Kubernetes.ServiceAccount.UID,
+			Namespace: c.Kubernetes.Namespace,
+			PodName:   c.Kubernetes.Pod.Name,
+		}
+	}
+
+	if sa.Name == "" || sa.UID == "" || sa.Namespace == "" {
+		return nil, errors.New("could not parse the service account from the claims")
+	}
+	return sa, nil
+}
+
+func (b *backend) pathLoginAliasLookahead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	jwtStr := d.Get("jwt").(string)
+	if jwtStr == "" {
+		return logical.ErrorResponse("missing jwt"), nil
+	}
+
+	var claims serviceAccountClaims
+	if err := unverifiedClaims(jwtStr, &claims); err != nil {
+		return nil, err
+	}
+	sa, err := claims.serviceAccount()
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Auth: &logical.Auth{
+			Alias: &logical.Alias{
+				Name: sa.UID,
+			},
+		},
+	}, nil
+}
+
+func (b *backend) pathLogin(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	roleName := d.Get("role").(string)
+	if roleName == "" {
+		return logical.ErrorResponse("missing role"), nil
+	}
+
+	jwtStr := d.Get("jwt").(string)
+	if jwtStr == "" {
+		return logical.ErrorResponse("missing jwt"), nil
+	}
+
+	b.l.RLock()
+	defer b.l.RUnlock()
+
+	role, err := b.role(ctx, req.Storage, roleName)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return logical.ErrorResponse(fmt.Sprintf("invalid role name %q", roleName)), nil
+	}
+
+	config, err := b.config(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return nil, errors.New("could not load backend configuration")
+	}
+
+	sa, err := parseAndValidateJWT(jwtStr, role, config)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	// Look up the token with the TokenReview API, which verifies that the
+	// service account still exists and that the token has not been revoked
+	if err := sa.lookup(ctx, jwtStr, role.Audience, b.reviewFactory(config)); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	auth := &logical.Auth{
+		NumUses: role.NumUses,
+		Period:  role.Period,
+		Alias: &logical.Alias{
+			Name: sa.UID,
+		},
+		InternalData: map[string]interface{}{
+			"role": roleName,
+		},
+		Policies: role.Policies,
+		Metadata: map[string]string{
+			"service_account_uid":         sa.UID,
+			"service_account_name":        sa.Name,
+			"service_account_namespace":   sa.Namespace,
+			"service_account_secret_name": sa.SecretName,
+			"role":                        roleName,
+		},
+		DisplayName: sa.Name,
+		LeaseOptions: logical.LeaseOptions{
+			Renewable: true,
+			TTL:       role.TTL,
+		},
+	}
+	if sa.PodName != "" {
+		auth.Metadata["service_account_pod_name"] = sa.PodName
+	}
+
+	// If 'Period' is set, use the value of 'Period' as the TTL
+	if role.Period > time.Duration(0) {
+		auth.TTL = role.Period
+	}
+
+	return &logical.Response{
+		Auth: auth,
+	}, nil
+}
+
+// parseAndValidateJWT parses the token, verifies its signature if public keys
+// are configured and validates its claims against the role
+func parseAndValidateJWT(jwtStr string, role *roleStorageEntry, config *kubeConfig) (*serviceAccount, error) {
+	token, err := jwt.ParseSigned(jwtStr)
+	if err != nil {
+		return nil, err
+	}
+
+	var claims serviceAccountClaims
+	if len(config.PublicKeys) == 0 {
+		// Without keys, the signature is only verified by the TokenReview API
+		if err := unverifiedClaims(jwtStr, &claims); err != nil {
+			return nil, err
+		}
+	} else {
+		verified := false
+		for _, key := range config.PublicKeys {
+			if err := token.Claims(key, &claims); err == nil {
+				verified = true
+				break
+			}
+		}
+		if !verified {
+			return nil, errors.New("failed to verify the signature of the JWT with the configured keys")
+		}
+	}
+
+	if !strutil.StrListContains(config.issuers(), claims.Issuer) {
+		return nil, fmt.Errorf("invalid issuer %q", claims.Issuer)
+	}
+
+	// Legacy tokens do not expire, projected tokens do
+	now := time.Now()
+	if claims.Expiry != 0 && now.Add(-claimsLeeway).After(claims.Expiry.Time()) {
+		return nil, errors.New("token is expired")
+	}
+	if claims.NotBefore != 0 && now.Add(claimsLeeway).Before(claims.NotBefore.Time()) {
+		return nil, errors.New("token is not valid yet")
+	}
+
+	sa, err := claims.serviceAccount()
+	if err != nil {
+		return nil, err
+	}
+
+	// verify the namespace is allowed
+	if len(role.ServiceAccountNamespaces) > 1 || role.ServiceAccountNamespaces[0] != "*" {
+		if !strutil.StrListContains(role.ServiceAccountNamespaces, sa.Namespace) {
+			return nil, errors.New("namespace not authorized")
+		}
+	}
+
+	// verify the service account name is allowed
+	if len(role.ServiceAccountNames) > 1 || role.ServiceAccountNames[0] != "*" {
+		if !strutil.StrListContains(role.ServiceAccountNames, sa.Name) {
+			return nil, errors.New("service account name not authorized")
+		}
+	}
+
+	if role.Audience != "" && !claims.Audience.Contains(role.Audience) {
+		return nil, errors.New("invalid audience (aud) claim: audience not authorized")
+	}
+
+	return sa, nil
+}
+
+// unverifiedClaims decodes the claims of the compact serialized JWT into dest
+// without verifying its signature
+func unverifiedClaims(jwtStr string, dest interface{}) error {
+	if _, err := jwt.ParseSigned(jwtStr); err != nil {
+		return err
+	}
+
+	parts := strings.Split(jwtStr, ".")
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return errwrap.Wrapf("failed to decode the JWT payload: {{err}}", err)
+	}
+	return jsonutil.DecodeJSON(payload, dest)
+}
+
+// lookup reviews the token with the TokenReview API and verifies that it is
+// the token of the service account, and of the audience if set
+func (s *serviceAccount) lookup(ctx context.Context, jwtStr, audience string, tr tokenReviewer) error {
+	var audiences []string
+	if audience != "" {
+		audiences = []string{audience}
+	}
+
+	r, err := tr.Review(ctx, jwtStr, audiences)
+	if err != nil {
+		return err
+	}
+
+	if s.Name != r.Name {
+		return errors.New("JWT names did not match")
+	}
+	if s.UID != r.UID {
+		return errors.New("JWT UIDs did not match")
+	}
+	if s.Namespace != r.Namespace {
+		return errors.New("JWT namespaces did not match")
+	}
+	if audience != "" && !strutil.StrListContains(r.Audiences, audience) {
+		return errors.New("JWT audience is not authorized by the token review")
+	}
+
+	return nil
+}
+
+func (b *backend) pathLoginRenew(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	roleName, ok := req.Auth.InternalData["role"].(string)
+	if !ok || roleName == "" {
+		return nil, errors.New("failed to fetch role_name during renewal")
+	}
+
+	b.l.RLock()
+	defer b.l.RUnlock()
+
+	// Ensure that the role still exists
+	role, err := b.role(ctx, req.Storage, roleName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to validate role %s during renewal: %s", roleName, err)
+	}
+	if role == nil {
+		return nil, fmt.Errorf("role %s does not exist during renewal", roleName)
+	}
+
+	// If 'Period' is set on the role, the token should never expire.
+	// Replenish the TTL with 'Period's value.
+	if role.Period > time.Duration(0) {
+		req.Auth.TTL = role.Period
+		return &logical.Response{Auth: req.Auth}, nil
+	}
+
+	return framework.LeaseExtend(role.TTL, role.MaxTTL, b.System())(ctx, req, d)
+}
+
+const pathLoginHelpSyn = `
+Authenticates Kubernetes service accounts with Vault.
+`
+
+const pathLoginHelpDesc = `
+Authenticates a Kubernetes service account with its legacy or projected service
+account token. The token is validated against the role and verified with the
+TokenReview API of the cluster.
+`
@@ -0,0 +1,271 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/helper/policyutil"
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+func pathRoleList(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "role/?$",
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ListOperation: b.pathRoleList,
+		},
+		HelpSynopsis:    pathRoleListHelpSyn,
+		HelpDescription: pathRoleListHelpDesc,
+	}
+}
+
+func pathRole(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "role/" + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the role.",
+			},
+			"bound_service_account_names": &framework.FieldSchema{
+				Type: framework.TypeCommaStringSlice,
+				Description: `List of service account names able to access this role. If set to "*" all
+names are allowed, both this and bound_service_account_namespaces can not be "*".`,
+			},
+			"bound_service_account_namespaces": &framework.FieldSchema{
+				Type: framework.TypeCommaStringSlice,
+				Description: `List of namespaces allowed to access this role. If set to "*" all namespaces
+are allowed, both this and bound_service_account_names can not be set to "*".`,
+			},
+			"audience": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `Optional audience which the service account tokens must have in their 'aud'
+claim, and which is validated with the TokenReview API.`,
+			},
+			"policies": &framework.FieldSchema{
+				Type:        framework.TypeCommaStringSlice,
+				Description: "List of policies on the role.",
+			},
+			"num_uses": &framework.FieldSchema{
+				Type:        framework.TypeInt,
+				Description: "Number of times issued tokens can be used.",
+			},
+			"ttl": &framework.FieldSchema{
+				Type: framework.TypeDurationSecond,
+				Description: `Duration in seconds after which the issued token should expire. Defaults to
+0, in which case the value will fall back to the system/mount defaults.`,
+			},
+			"max_ttl": &framework.FieldSchema{
+				Type: framework.TypeDurationSecond,
+				Description: `Duration in seconds after which the issued token should not be allowed to be
+renewed. Defaults to 0, in which case the value will fall back to the
+system/mount defaults.`,
+			},
+			"period": &framework.FieldSchema{
+				Type: framework.TypeDurationSecond,
+				Description: `If set, indicates that the token generated using this role should never
+expire. The token should be renewed within the duration specified by this
+value. At each renewal, the token's TTL will be set to the value of this
+parameter.`,
+			},
+		},
+
+		ExistenceCheck: b.pathRoleExistenceCheck,
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.CreateOperation: b.pathRoleCreateUpdate,
+			logical.UpdateOperation: b.pathRoleCreateUpdate,
+			logical.ReadOperation:   b.pathRoleRead,
+			logical.DeleteOperation: b.pathRoleDelete,
+		},
+
+		HelpSynopsis:    pathRoleHelpSyn,
+		HelpDescription: pathRoleHelpDesc,
+	}
+}
+
+// roleStorageEntry stores all the options that are set on a role
+type roleStorageEntry struct {
+	// Policies are the policies of the issued tokens
+	Policies []string `json:"policies" structs:"policies" mapstructure:"policies"`
+
+	// NumUses is the number of allowed uses of the issued tokens
+	NumUses int `json:"num_uses" structs:"num_uses" mapstructure:"num_uses"`
+
+	// TTL is the duration before which an issued token must be renewed
+	TTL time.Duration `json:"ttl" structs:"ttl" mapstructure:"ttl"`
+
+	// MaxTTL is the duration after which an issued token should not be
+	// allowed to be renewed
+	MaxTTL time.Duration `json:"max_ttl" structs:"max_ttl" mapstructure:"max_ttl"`
+
+	// Period, if set, indicates that the token generated using this role
+	// should never expire. The token should be renewed within the duration
+	// specified by this value.
+	Period time.Duration `json:"period" structs:"period" mapstructure:"period"`
+
+	// ServiceAccountNames are the names of the service accounts able to
+	// access this role
+	ServiceAccountNames []string `json:"bound_service_account_names" structs:"bound_service_account_names" mapstructure:"bound_service_account_names"`
+
+	// ServiceAccountNamespaces are the namespaces able to access this role
+	ServiceAccountNamespaces []string `json:"bound_service_account_namespaces" structs:"bound_service_account_namespaces" mapstructure:"bound_service_account_namespaces"`
+
+	// Audience, if set, is the audience the service account tokens must have
+	Audience string `json:"audience" structs:"audience" mapstructure:"audience"`
+}
+
+func (b *backend) pathRoleExistenceCheck(ctx context.Context, req *logical.Request, d *framework.FieldData) (bool, error) {
+	b.l.RLock()
+	defer b.l.RUnlock()
+
+	role, err := b.role(ctx, req.Storage, d.Get("name").(string))
+	if err != nil {
+		return false, err
+	}
+	return role != nil, nil
+}
+
+func (b *backend) pathRoleList(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	b.l.RLock()
+	defer b.l.RUnlock()
+
+	roles, err := req.Storage.List(ctx, rolePrefix)
+	if err != nil {
+		return nil, err
+	}
+	return logical.ListResponse(roles), nil
+}
+
+func (b *backend) pathRoleRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	b.l.RLock()
+	defer b.l.RUnlock()
+
+	role, err := b.role(ctx, req.Storage, d.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"bound_service_account_names":      role.ServiceAccountNames,
+			"bound_service_account_namespaces": role.ServiceAccountNamespaces,
+			"audience":                         role.Audience,
+			"max_ttl":                          int64(role.MaxTTL.Seconds()),
+			"num_uses":                         role.NumUses,
+			"policies":                         role.Policies,
+			"period":                           int64(role.Period.Seconds()),
+			"ttl":                              int64(role.TTL.Seconds()),
+		},
+	}, nil
+}
+
+func (b *backend) pathRoleDelete(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	return nil, req.Storage.Delete(ctx, rolePrefix+strings.ToLower(d.Get("name").(string)))
+}
+
+func (b *backend) pathRoleCreateUpdate(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	roleName := strings.ToLower(d.Get("name").(string))
+
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	role, err := b.role(ctx, req.Storage, roleName)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		if req.Operation != logical.CreateOperation {
+			return nil, fmt.Errorf("role entry not found during update operation")
+		}
+		role = &roleStorageEntry{}
+	}
+
+	if policiesRaw, ok := d.GetOk("policies"); ok {
+		role.Policies = policyutil.ParsePolicies(policiesRaw)
+	}
+	if numUsesRaw, ok := d.GetOk("num_uses"); ok {
+		role.NumUses = numUsesRaw.(int)
+	}
+	if ttlRaw, ok := d.GetOk("ttl"); ok {
+		role.TTL = time.Duration(ttlRaw.(int)) * time.Second
+	}
+	if maxTTLRaw, ok := d.GetOk("max_ttl"); ok {
+		role.MaxTTL = time.Duration(maxTTLRaw.(int)) * time.Second
+	}
+	if periodRaw, ok := d.GetOk("period"); ok {
+		role.Period = time.Duration(periodRaw.(int)) * time.Second
+	}
+	if namesRaw, ok := d.GetOk("bound_service_account_names"); ok {
+		role.ServiceAccountNames = namesRaw.([]string)
+	}
+	if namespacesRaw, ok := d.GetOk("bound_service_account_namespaces"); ok {
+		role.ServiceAccountNamespaces = namespacesRaw.([]string)
+	}
+	if audienceRaw, ok := d.GetOk("audience"); ok {
+		role.Audience = audienceRaw.(string)
+	}
+
+	switch {
+	case role.NumUses < 0:
+		return logical.ErrorResponse("num_uses cannot be negative"), nil
+	case role.MaxTTL > 0 && role.TTL > role.MaxTTL:
+		return logical.ErrorResponse("ttl should not be greater than max_ttl"), nil
+	case role.Period > b.System().MaxLeaseTTL():
+		return logical.ErrorResponse(fmt.Sprintf("period of %q is greater than the backend's maximum lease TTL of %q", role.Period.String(), b.System().MaxLeaseTTL().String())), nil
+	case len(role.ServiceAccountNames) == 0:
+		return logical.ErrorResponse(`"bound_service_account_names" can not be empty`), nil
+	case len(role.ServiceAccountNamespaces) == 0:
+		return logical.ErrorResponse(`"bound_service_account_namespaces" can not be empty`), nil
+	case len(role.ServiceAccountNames) > 1 && strutil.StrListContains(role.ServiceAccountNames, "*"),
+		len(role.ServiceAccountNamespaces) > 1 && strutil.StrListContains(role.ServiceAccountNamespaces, "*"):
+		return logical.ErrorResponse(`can not mix "*" with values`), nil
+	case strutil.StrListContains(role.ServiceAccountNames, "*") && strutil.StrListContains(role.ServiceAccountNamespaces, "*"):
+		return logical.ErrorResponse(`bound_service_account_names and bound_service_account_namespaces can not both be "*"`), nil
+	}
+
+	var resp *logical.Response
+	if role.MaxTTL > b.System().MaxLeaseTTL() {
+		resp = &logical.Response{}
+		resp.AddWarning("max_ttl is greater than the system or backend mount's maximum TTL value; issued tokens' max TTL value will be truncated")
+	}
+
+	entry, err := logical.StorageEntryJSON(rolePrefix+roleName, role)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+const pathRoleListHelpSyn = `
+Lists all the roles registered with the backend.
+`
+
+const pathRoleListHelpDesc = `
+The list will contain the names of the roles.
+`
+
+const pathRoleHelpSyn = `
+Register a role with the backend.
+`
+
+const pathRoleHelpDesc = `
+A role is required to authenticate with this backend. The role binds the
+namespaces, names and audience of the Kubernetes service accounts with token
+policies and settings. The bindings, token policies and token settings can all
+be configured using this endpoint.
+`
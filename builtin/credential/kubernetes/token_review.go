@@ -0,0 +1,158 @@
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/vault/helper/jsonutil"
+)
+
+// tokenReviewResult is the service account of a reviewed token
+type tokenReviewResult struct {
+	Name      string
+	Namespace string
+	UID       string
+	Audiences []string
+}
+
+// tokenReviewer reviews service account tokens. It exists so tests can mock
+// the TokenReview API.
+type tokenReviewer interface {
+	Review(ctx context.Context, token string, audiences []string) (*tokenReviewResult, error)
+}
+
+type tokenReviewFactory func(*kubeConfig) tokenReviewer
+
+// tokenReview is the subset of the TokenReview objects of the
+// authentication.k8s.io/v1 API used by the backend
+type tokenReview struct {
+	APIVersion string            `json:"apiVersion"`
+	Kind       string            `json:"kind"`
+	Spec       tokenReviewSpec   `json:"spec"`
+	Status     tokenReviewStatus `json:"status"`
+}
+
+type tokenReviewSpec struct {
+	Token     string   `json:"token"`
+	Audiences []string `json:"audiences,omitempty"`
+}
+
+type tokenReviewStatus struct {
+	Authenticated bool     `json:"authenticated"`
+	User          userInfo `json:"user"`
+	Audiences     []string `json:"audiences"`
+	Error         string   `json:"error"`
+}
+
+type userInfo struct {
+	Username string `json:"username"`
+	UID      string `json:"uid"`
+}
+
+// tokenReviewAPI reviews the tokens with the TokenReview API of the cluster
+type tokenReviewAPI struct {
+	config *kubeConfig
+}
+
+func tokenReviewAPIFactory(config *kubeConfig) tokenReviewer {
+	return &tokenReviewAPI{
+		config: config,
+	}
+}
+
+func (t *tokenReviewAPI) Review(ctx context.Context, token string, audiences []string) (*tokenReviewResult, error) {
+	client := cleanhttp.DefaultClient()
+	if t.config.CACert != "" {
+		certPool := x509.NewCertPool()
+		certPool.AppendCertsFromPEM([]byte(t.config.CACert))
+		client.Transport.(*http.Transport).TLSClientConfig = &tls.Config{
+			MinVersion: tls.VersionTLS12,
+			RootCAs:    certPool,
+		}
+	}
+
+	body, err := json.Marshal(&tokenReview{
+		APIVersion: "authentication.k8s.io/v1",
+		Kind:       "TokenReview",
+		Spec: tokenReviewSpec{
+			Token:     token,
+			Audiences: audiences,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	url := strings.TrimSuffix(t.config.Host, "/") + "/apis/authentication.k8s.io/v1/tokenreviews"
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+
+	// Use the configured reviewer JWT as the bearer, otherwise the JWT being
+	// reviewed, which requires its service account to be allowed to review
+	// tokens
+	bearer := token
+	if t.config.TokenReviewerJWT != "" {
+		bearer = t.config.TokenReviewerJWT
+	}
+	req.Header.Set("Authorization", "Bearer "+bearer)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		return nil, errors.New("lookup failed: service account unauthorized; this could mean it has been deleted")
+	case resp.StatusCode < http.StatusOK || resp.StatusCode > http.StatusPartialContent:
+		respBody, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("lookup failed: %s: %s", resp.Status, strings.TrimSpace(string(respBody)))
+	}
+
+	var review tokenReview
+	if err := jsonutil.DecodeJSONFromReader(resp.Body, &review); err != nil {
+		return nil, err
+	}
+
+	return reviewResult(&review.Status)
+}
+
+// reviewResult returns the service account of the status of a review
+func reviewResult(status *tokenReviewStatus) (*tokenReviewResult, error) {
+	if status.Error != "" {
+		return nil, fmt.Errorf("lookup failed: %s", status.Error)
+	}
+	if !status.Authenticated {
+		return nil, errors.New("lookup failed: service account jwt not valid")
+	}
+
+	// The username is of format: system:serviceaccount:(NAMESPACE):(SERVICEACCOUNT)
+	parts := strings.Split(status.User.Username, ":")
+	if len(parts) != 4 {
+		return nil, errors.New("lookup failed: unexpected username format")
+	}
+	if parts[0] != "system" || parts[1] != "serviceaccount" {
+		return nil, errors.New("lookup failed: username returned is not a service account")
+	}
+
+	return &tokenReviewResult{
+		Name:      parts[3],
+		Namespace: parts[2],
+		UID:       status.User.UID,
+		Audiences: status.Audiences,
+	}, nil
+}
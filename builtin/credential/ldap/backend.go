@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"strings"
 	"text/template"
 
 	"github.com/go-ldap/ldap"
@@ -49,6 +50,8 @@ func Backend() *backend {
 		),
 
 		AuthRenew:   b.pathLoginRenew,
+		Invalidate:  b.invalidate,
+		Clean:       b.cleanup,
 		BackendType: logical.TypeCredential,
 	}
 
@@ -57,6 +60,29 @@ func Backend() *backend {
 
 type backend struct {
 	*framework.Backend
+
+	// connPool holds the idle connections to the LDAP server
+	connPool connPool
+}
+
+func (b *backend) invalidate(_ context.Context, key string) {
+	switch key {
+	case "config":
+		b.connPool.flush()
+	}
+}
+
+func (b *backend) cleanup(_ context.Context) {
+	b.connPool.flush()
+}
+
+// search runs the search request, using paged results if max_page_size is
+// set so that the server-side size limits don't truncate the results
+func (b *backend) search(cfg *ConfigEntry, c *ldap.Conn, searchRequest *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	if cfg.MaxPageSize > 0 {
+		return c.SearchWithPaging(searchRequest, uint32(cfg.MaxPageSize))
+	}
+	return c.Search(searchRequest)
 }
 
 func EscapeLDAPValue(input string) string {
@@ -103,7 +129,7 @@ func (b *backend) Login(ctx context.Context, req *logical.Request, username stri
 		return nil, logical.ErrorResponse("ldap backend not configured"), nil, nil
 	}
 
-	c, err := cfg.DialLDAP()
+	c, generation, err := b.connPool.get(cfg)
 	if err != nil {
 		return nil, logical.ErrorResponse(err.Error()), nil, nil
 	}
@@ -111,8 +137,8 @@ func (b *backend) Login(ctx context.Context, req *logical.Request, username stri
 		return nil, logical.ErrorResponse("invalid connection returned from LDAP dial"), nil, nil
 	}
 
-	// Clean connection
-	defer c.Close()
+	// Return the connection to the pool, which closes it if pooling is disabled
+	defer b.connPool.put(cfg, c, generation)
 
 	userBindDN, err := b.getUserBindDN(cfg, c, username)
 	if err != nil {
@@ -261,7 +287,7 @@ func (b *backend) getUserBindDN(cfg *ConfigEntry, c *ldap.Conn, username string)
 		if b.Logger().IsDebug() {
 			b.Logger().Debug("auth/ldap: Discovering user", "userdn", cfg.UserDN, "filter", filter)
 		}
-		result, err := c.Search(&ldap.SearchRequest{
+		result, err := b.search(cfg, c, &ldap.SearchRequest{
 			BaseDN: cfg.UserDN,
 			Scope:  2, // subtree
 			Filter: filter,
@@ -295,7 +321,7 @@ func (b *backend) getUserDN(cfg *ConfigEntry, c *ldap.Conn, bindDN string) (stri
 		if b.Logger().IsDebug() {
 			b.Logger().Debug("auth/ldap: Searching UPN", "userdn", cfg.UserDN, "filter", filter)
 		}
-		result, err := c.Search(&ldap.SearchRequest{
+		result, err := b.search(cfg, c, &ldap.SearchRequest{
 			BaseDN: cfg.UserDN,
 			Scope:  2, // subtree
 			Filter: filter,
@@ -319,9 +345,11 @@ func (b *backend) getUserDN(cfg *ConfigEntry, c *ldap.Conn, bindDN string) (stri
  * The search query is constructed according to cfg.GroupFilter, and run in context of cfg.GroupDN.
  * Groups will be resolved from the query results by following the attribute defined in cfg.GroupAttr.
  *
- * cfg.GroupFilter is a go template and is compiled with the following context: [UserDN, Username]
+ * cfg.GroupFilter is a go template and is compiled with the following context: [UserDN, Username, UserAttributes]
  *    UserDN - The DN of the authenticated user
  *    Username - The Username of the authenticated user
+ *    UserAttributes - The attributes of the user object, mapped to their first value. The user
+ *                     object is only read if the template refers to UserAttributes.
  *
  * Example:
  *   cfg.GroupFilter = "(&(objectClass=group)(member:1.2.840.113556.1.4.1941:={{.UserDN}}))"
@@ -351,33 +379,28 @@ func (b *backend) getLdapGroups(cfg *ConfigEntry, c *ldap.Conn, userDN string, u
 		b.Logger().Debug("auth/ldap: Compiling group filter", "group_filter", cfg.GroupFilter)
 	}
 
-	// Parse the configuration as a template.
-	// Example template "(&(objectClass=group)(member:1.2.840.113556.1.4.1941:={{.UserDN}}))"
-	t, err := template.New("queryTemplate").Parse(cfg.GroupFilter)
-	if err != nil {
-		return nil, fmt.Errorf("LDAP search failed due to template compilation error: %v", err)
+	var userAttributes map[string]string
+	if strings.Contains(cfg.GroupFilter, "UserAttributes") {
+		var err error
+		userAttributes, err = b.getUserAttributes(c, userDN)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	// Build context to pass to template - we will be exposing UserDn and Username.
-	context := struct {
-		UserDN   string
-		Username string
-	}{
-		ldap.EscapeFilter(userDN),
-		ldap.EscapeFilter(username),
+	renderedQuery, err := renderGroupFilter(cfg.GroupFilter, userDN, username, userAttributes)
+	if err != nil {
+		return nil, err
 	}
 
-	var renderedQuery bytes.Buffer
-	t.Execute(&renderedQuery, context)
-
 	if b.Logger().IsDebug() {
-		b.Logger().Debug("auth/ldap: Searching", "groupdn", cfg.GroupDN, "rendered_query", renderedQuery.String())
+		b.Logger().Debug("auth/ldap: Searching", "groupdn", cfg.GroupDN, "rendered_query", renderedQuery)
 	}
 
-	result, err := c.Search(&ldap.SearchRequest{
+	result, err := b.search(cfg, c, &ldap.SearchRequest{
 		BaseDN: cfg.GroupDN,
 		Scope:  2, // subtree
-		Filter: renderedQuery.String(),
+		Filter: renderedQuery,
 		Attributes: []string{
 			cfg.GroupAttr,
 		},
@@ -414,6 +437,67 @@ func (b *backend) getLdapGroups(cfg *ConfigEntry, c *ldap.Conn, userDN string, u
 	return ldapGroups, nil
 }
 
+/*
+ * Returns the attributes of the user object, mapped to their first value.
+ */
+func (b *backend) getUserAttributes(c *ldap.Conn, userDN string) (map[string]string, error) {
+	result, err := c.Search(&ldap.SearchRequest{
+		BaseDN: userDN,
+		Scope:  ldap.ScopeBaseObject,
+		Filter: "(objectClass=*)",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("LDAP search failed for reading the user attributes: %v", err)
+	}
+	if len(result.Entries) != 1 {
+		return nil, fmt.Errorf("LDAP search for the user object 0 or not unique")
+	}
+
+	attributes := make(map[string]string, len(result.Entries[0].Attributes))
+	for _, attr := range result.Entries[0].Attributes {
+		if len(attr.Values) > 0 {
+			attributes[attr.Name] = attr.Values[0]
+		}
+	}
+	return attributes, nil
+}
+
+/*
+ * Renders the group filter template. All the values are escaped for use in
+ * search filters, and attributes missing from the user object render as empty
+ * strings.
+ */
+func renderGroupFilter(groupFilter, userDN, username string, userAttributes map[string]string) (string, error) {
+	// Parse the configuration as a template.
+	// Example template "(&(objectClass=group)(member:1.2.840.113556.1.4.1941:={{.UserDN}}))"
+	t, err := template.New("queryTemplate").Option("missingkey=zero").Parse(groupFilter)
+	if err != nil {
+		return "", fmt.Errorf("LDAP search failed due to template compilation error: %v", err)
+	}
+
+	// Build context to pass to template - we will be exposing UserDN, Username
+	// and UserAttributes.
+	context := struct {
+		UserDN         string
+		Username       string
+		UserAttributes map[string]string
+	}{
+		UserDN:         ldap.EscapeFilter(userDN),
+		Username:       ldap.EscapeFilter(username),
+		UserAttributes: make(map[string]string, len(userAttributes)),
+	}
+	for name, value := range userAttributes {
+		context.UserAttributes[name] = ldap.EscapeFilter(value)
+	}
+
+	var renderedQuery bytes.Buffer
+	if err := t.Execute(&renderedQuery, context); err != nil {
+		return "", fmt.Errorf("LDAP search failed due to template rendering error: %v", err)
+	}
+
+	return renderedQuery.String(), nil
+}
+
 const backendHelp = `
 The "ldap" credential provider allows authentication querying
 a LDAP server, checking username and password, and associating groups
@@ -0,0 +1,104 @@
+package ldap
+
+import (
+	"sync"
+	"time"
+
+	"github.com/go-ldap/ldap"
+)
+
+// connIdleTimeout is the duration after which idle pooled connections are
+// closed instead of being reused, since LDAP servers commonly drop the
+// connections that have been idle for a while
+const connIdleTimeout = time.Minute
+
+// connPool keeps the connections to the LDAP server open between logins so
+// that they can be reused, avoiding a new dial and TLS handshake on every
+// login. Every login binds before searching, so a connection bound by a
+// previous login can safely be handed to the next one.
+type connPool struct {
+	l    sync.Mutex
+	idle []*idleConn
+
+	// generation is incremented when the pool is flushed, so that the
+	// connections dialed against a previous configuration are not pooled
+	generation uint64
+}
+
+type idleConn struct {
+	conn      *ldap.Conn
+	idleSince time.Time
+}
+
+// get returns an idle connection of the pool that is still alive, or dials a
+// new one. The returned generation must be handed back to put.
+func (p *connPool) get(cfg *ConfigEntry) (*ldap.Conn, uint64, error) {
+	for {
+		p.l.Lock()
+		generation := p.generation
+		if len(p.idle) == 0 {
+			p.l.Unlock()
+
+			conn, err := cfg.DialLDAP()
+			return conn, generation, err
+		}
+		idle := p.idle[len(p.idle)-1]
+		p.idle = p.idle[:len(p.idle)-1]
+		p.l.Unlock()
+
+		if time.Since(idle.idleSince) > connIdleTimeout || !connAlive(idle.conn) {
+			idle.conn.Close()
+			continue
+		}
+		return idle.conn, generation, nil
+	}
+}
+
+// put returns the connection to the pool, or closes it if pooling is
+// disabled, the pool is full or the pool was flushed since the connection was
+// handed out
+func (p *connPool) put(cfg *ConfigEntry, conn *ldap.Conn, generation uint64) {
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	if generation != p.generation || len(p.idle) >= cfg.ConnectionPoolSize {
+		conn.Close()
+		return
+	}
+	p.idle = append(p.idle, &idleConn{
+		conn:      conn,
+		idleSince: time.Now(),
+	})
+}
+
+// flush closes all the idle connections of the pool
+func (p *connPool) flush() {
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	p.generation++
+	for _, idle := range p.idle {
+		idle.conn.Close()
+	}
+	p.idle = nil
+}
+
+// connAlive checks that the connection has not been closed, by reading the
+// root DSE. Result codes returned by the server, such as insufficient access,
+// still indicate a working connection.
+func connAlive(conn *ldap.Conn) bool {
+	_, err := conn.Search(&ldap.SearchRequest{
+		BaseDN:     "",
+		Scope:      ldap.ScopeBaseObject,
+		Filter:     "(objectClass=*)",
+		Attributes: []string{"1.1"},
+	})
+	if err == nil {
+		return true
+	}
+
+	// Failures of the connection while the request is in flight are not
+	// reported as LDAP errors
+	ldapErr, ok := err.(*ldap.Error)
+	return ok && ldapErr.ResultCode != ldap.ErrorNetwork
+}
@@ -0,0 +1,444 @@
+package ldap
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/go-ldap/ldap"
+	"github.com/hashicorp/vault/logical"
+	ber "gopkg.in/asn1-ber.v1"
+)
+
+const (
+	testLDAPUsersDN  = "ou=users,dc=example,dc=com"
+	testLDAPGroupsDN = "ou=groups,dc=example,dc=com"
+)
+
+// testLDAPServer is a minimal in-memory LDAP server supporting simple binds
+// and searches, including the paged results control. Searches returning more
+// entries than sizeLimit without paging fail with sizeLimitExceeded, as large
+// directories do.
+type testLDAPServer struct {
+	listener  net.Listener
+	passwords map[string]string
+	search    func(baseDN string, scope int64, filter string) []*ldap.Entry
+	sizeLimit int
+
+	conns int32
+
+	l         sync.Mutex
+	pageSizes []uint32
+	open      []net.Conn
+}
+
+func newTestLDAPServer(t *testing.T, passwords map[string]string, search func(string, int64, string) []*ldap.Entry) *testLDAPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &testLDAPServer{
+		listener:  listener,
+		passwords: passwords,
+		search:    search,
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			atomic.AddInt32(&s.conns, 1)
+			s.l.Lock()
+			s.open = append(s.open, conn)
+			s.l.Unlock()
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *testLDAPServer) url() string {
+	return "ldap://" + s.listener.Addr().String()
+}
+
+func (s *testLDAPServer) close() {
+	s.listener.Close()
+	s.closeConns()
+}
+
+// closeConns drops the open client connections
+func (s *testLDAPServer) closeConns() {
+	s.l.Lock()
+	defer s.l.Unlock()
+	for _, conn := range s.open {
+		conn.Close()
+	}
+	s.open = nil
+}
+
+func (s *testLDAPServer) serve(conn net.Conn) {
+	defer conn.Close()
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+		messageID := packet.Children[0].Value.(int64)
+		op := packet.Children[1]
+
+		var responses []*ber.Packet
+		switch op.Tag {
+		case ldap.ApplicationBindRequest:
+			dn := op.Children[1].Value.(string)
+			password := op.Children[2].Data.String()
+			code := int64(ldap.LDAPResultSuccess)
+			if expected, ok := s.passwords[dn]; dn != "" && (!ok || expected != password) {
+				code = ldap.LDAPResultInvalidCredentials
+			}
+			responses = append(responses, testLDAPResult(messageID, ldap.ApplicationBindResponse, code, nil))
+
+		case ldap.ApplicationSearchRequest:
+			responses = s.handleSearch(messageID, packet)
+
+		case ldap.ApplicationUnbindRequest:
+			return
+
+		default:
+			continue
+		}
+
+		for _, response := range responses {
+			if _, err := conn.Write(response.Bytes()); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *testLDAPServer) handleSearch(messageID int64, packet *ber.Packet) []*ber.Packet {
+	op := packet.Children[1]
+	baseDN := op.Children[0].Value.(string)
+	scope := op.Children[1].Value.(int64)
+	filter, err := ldap.DecompileFilter(op.Children[6])
+	if err != nil {
+		return []*ber.Packet{testLDAPResult(messageID, ldap.ApplicationSearchResultDone, ldap.LDAPResultProtocolError, nil)}
+	}
+
+	var paging *ldap.ControlPaging
+	if len(packet.Children) > 2 {
+		for _, child := range packet.Children[2].Children {
+			if control, ok := ldap.DecodeControl(child).(*ldap.ControlPaging); ok {
+				paging = control
+			}
+		}
+	}
+
+	var entries []*ldap.Entry
+	if baseDN != "" {
+		entries = s.search(baseDN, scope, filter)
+	}
+
+	var responses []*ber.Packet
+	var controls *ber.Packet
+	code := int64(ldap.LDAPResultSuccess)
+	switch {
+	case paging != nil && paging.PagingSize > 0:
+		s.l.Lock()
+		s.pageSizes = append(s.pageSizes, paging.PagingSize)
+		s.l.Unlock()
+
+		offset := 0
+		if len(paging.Cookie) > 0 {
+			offset, _ = strconv.Atoi(string(paging.Cookie))
+		}
+		end := offset + int(paging.PagingSize)
+		var cookie []byte
+		if end < len(entries) {
+			cookie = []byte(strconv.Itoa(end))
+		} else {
+			end = len(entries)
+		}
+		entries = entries[offset:end]
+
+		controls = ber.Encode(ber.ClassContext, ber.TypeConstructed, 0, nil, "Controls")
+		controls.AppendChild((&ldap.ControlPaging{Cookie: cookie}).Encode())
+
+	case paging != nil:
+		// Abandoned paged search
+		entries = nil
+
+	case s.sizeLimit > 0 && len(entries) > s.sizeLimit:
+		entries = entries[:s.sizeLimit]
+		code = ldap.LDAPResultSizeLimitExceeded
+	}
+
+	for _, entry := range entries {
+		envelope := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+		envelope.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, messageID, "MessageID"))
+		result := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "Search Result Entry")
+		result.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, entry.DN, "DN"))
+		attributes := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attributes")
+		for _, attr := range entry.Attributes {
+			attribute := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attribute")
+			attribute.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, attr.Name, "Type"))
+			values := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+			for _, value := range attr.Values {
+				values.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, "Value"))
+			}
+			attribute.AppendChild(values)
+			attributes.AppendChild(attribute)
+		}
+		result.AppendChild(attributes)
+		envelope.AppendChild(result)
+		responses = append(responses, envelope)
+	}
+
+	return append(responses, testLDAPResult(messageID, ldap.ApplicationSearchResultDone, code, controls))
+}
+
+func testLDAPResult(messageID int64, tag ber.Tag, code int64, controls *ber.Packet) *ber.Packet {
+	envelope := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+	envelope.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, messageID, "MessageID"))
+	result := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "Result")
+	result.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, code, "Result Code"))
+	result.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Matched DN"))
+	result.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Diagnostic Message"))
+	envelope.AppendChild(result)
+	if controls != nil {
+		envelope.AppendChild(controls)
+	}
+	return envelope
+}
+
+func testLDAPConfig(t *testing.T, b *backend, storage logical.Storage, data map[string]interface{}) {
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   storage,
+		Data:      data,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+}
+
+func testLDAPGroup(t *testing.T, b *backend, storage logical.Storage, name, policies string) {
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "groups/" + name,
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"policies": policies,
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+}
+
+func testLDAPLogin(t *testing.T, b *backend, storage logical.Storage, username, password string) *logical.Response {
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "login/" + username,
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"password": password,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestBackend_pagedGroupSearch(t *testing.T) {
+	userDN := "uid=alice," + testLDAPUsersDN
+
+	var groups []*ldap.Entry
+	for i := 0; i < 250; i++ {
+		groups = append(groups, ldap.NewEntry(fmt.Sprintf("cn=group%d,%s", i, testLDAPGroupsDN), map[string][]string{
+			"cn": []string{fmt.Sprintf("group%d", i)},
+		}))
+	}
+	server := newTestLDAPServer(t, map[string]string{userDN: "password"}, func(baseDN string, scope int64, filter string) []*ldap.Entry {
+		if baseDN == testLDAPGroupsDN && strings.Contains(filter, userDN) {
+			return groups
+		}
+		return nil
+	})
+	defer server.close()
+	server.sizeLimit = 100
+
+	b, storage := createBackendWithStorage(t)
+	testLDAPGroup(t, b, storage, "group249", "p249")
+
+	config := map[string]interface{}{
+		"url":      server.url(),
+		"userdn":   testLDAPUsersDN,
+		"userattr": "uid",
+		"groupdn":  testLDAPGroupsDN,
+	}
+	testLDAPConfig(t, b, storage, config)
+
+	// The group search exceeds the size limit of the server
+	resp := testLDAPLogin(t, b, storage, "alice", "password")
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected the login to fail; resp: %#v", resp)
+	}
+
+	config["max_page_size"] = 40
+	testLDAPConfig(t, b, storage, config)
+
+	resp = testLDAPLogin(t, b, storage, "alice", "password")
+	if resp == nil || resp.IsError() || resp.Auth == nil {
+		t.Fatalf("expected the login to succeed; resp: %#v", resp)
+	}
+	if !strings.Contains(strings.Join(resp.Auth.Policies, ","), "p249") {
+		t.Fatalf("expected the policies of the last page; policies: %v", resp.Auth.Policies)
+	}
+
+	server.l.Lock()
+	defer server.l.Unlock()
+	if len(server.pageSizes) != 7 || server.pageSizes[0] != 40 {
+		t.Fatalf("unexpected pages: %v", server.pageSizes)
+	}
+}
+
+func TestBackend_groupFilterUserAttributes(t *testing.T) {
+	userDN := "uid=alice," + testLDAPUsersDN
+
+	var filters []string
+	server := newTestLDAPServer(t, map[string]string{userDN: "password"}, func(baseDN string, scope int64, filter string) []*ldap.Entry {
+		switch {
+		case baseDN == userDN && scope == ldap.ScopeBaseObject:
+			return []*ldap.Entry{ldap.NewEntry(userDN, map[string][]string{
+				"uid":       []string{"alice"},
+				"gidNumber": []string{"5000"},
+			})}
+		case baseDN == testLDAPGroupsDN:
+			filters = append(filters, filter)
+			if strings.Contains(filter, "gidNumber=5000") {
+				return []*ldap.Entry{ldap.NewEntry("cn=staff,"+testLDAPGroupsDN, map[string][]string{
+					"cn": []string{"staff"},
+				})}
+			}
+		}
+		return nil
+	})
+	defer server.close()
+
+	b, storage := createBackendWithStorage(t)
+	testLDAPGroup(t, b, storage, "staff", "staff")
+	testLDAPConfig(t, b, storage, map[string]interface{}{
+		"url":         server.url(),
+		"userdn":      testLDAPUsersDN,
+		"userattr":    "uid",
+		"groupdn":     testLDAPGroupsDN,
+		"groupfilter": "(&(objectClass=posixGroup)(|(gidNumber={{.UserAttributes.gidNumber}})(memberUid={{.Username}})))",
+	})
+
+	resp := testLDAPLogin(t, b, storage, "alice", "password")
+	if resp == nil || resp.IsError() || resp.Auth == nil {
+		t.Fatalf("expected the login to succeed; resp: %#v\nfilters: %v", resp, filters)
+	}
+	if len(resp.Auth.Policies) != 1 || resp.Auth.Policies[0] != "staff" {
+		t.Fatalf("unexpected policies: %v", resp.Auth.Policies)
+	}
+}
+
+func TestBackend_renderGroupFilter(t *testing.T) {
+	rendered, err := renderGroupFilter(
+		"(&(member={{.UserDN}})(uid={{.Username}})(mail={{.UserAttributes.mail}})(x={{.UserAttributes.missing}}))",
+		"cn=a*(b),dc=example",
+		"a)b",
+		map[string]string{"mail": "a*@example.com"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `(&(member=cn=a\2a\28b\29,dc=example)(uid=a\29b)(mail=a\2a@example.com)(x=))`
+	if rendered != expected {
+		t.Fatalf("bad: expected: %s, actual: %s", expected, rendered)
+	}
+
+	if _, err := renderGroupFilter("({{.Unknown}})", "", "", nil); err == nil {
+		t.Fatalf("expected an error for an unknown field")
+	}
+}
+
+func TestBackend_connectionPool(t *testing.T) {
+	userDN := "uid=alice," + testLDAPUsersDN
+	server := newTestLDAPServer(t, map[string]string{userDN: "password"}, func(string, int64, string) []*ldap.Entry {
+		return nil
+	})
+	defer server.close()
+
+	b, storage := createBackendWithStorage(t)
+	testLDAPGroup(t, b, storage, "local", "local")
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "users/alice",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"groups": "local",
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+
+	config := map[string]interface{}{
+		"url":      server.url(),
+		"userdn":   testLDAPUsersDN,
+		"userattr": "uid",
+		"groupdn":  testLDAPGroupsDN,
+	}
+	testLDAPConfig(t, b, storage, config)
+
+	login := func(password string, success bool) {
+		resp := testLDAPLogin(t, b, storage, "alice", password)
+		if success && (resp == nil || resp.IsError() || resp.Auth == nil) {
+			t.Fatalf("expected the login to succeed; resp: %#v", resp)
+		}
+		if !success && (resp == nil || !resp.IsError()) {
+			t.Fatalf("expected the login to fail; resp: %#v", resp)
+		}
+	}
+
+	// Without pooling, every login dials
+	login("password", true)
+	login("password", true)
+	if conns := atomic.LoadInt32(&server.conns); conns != 2 {
+		t.Fatalf("expected 2 connections, got %d", conns)
+	}
+
+	// With pooling, the connection is reused, including after failed binds
+	config["connection_pool_size"] = 2
+	testLDAPConfig(t, b, storage, config)
+	login("password", true)
+	login("wrong", false)
+	login("password", true)
+	if conns := atomic.LoadInt32(&server.conns); conns != 3 {
+		t.Fatalf("expected 3 connections, got %d", conns)
+	}
+
+	// Updating the configuration flushes the pool
+	testLDAPConfig(t, b, storage, config)
+	login("password", true)
+	if conns := atomic.LoadInt32(&server.conns); conns != 4 {
+		t.Fatalf("expected 4 connections, got %d", conns)
+	}
+
+	// Connections closed by the server are not reused
+	server.closeConns()
+	login("password", true)
+	if conns := atomic.LoadInt32(&server.conns); conns != 5 {
+		t.Fatalf("expected 5 connections, got %d", conns)
+	}
+}
@@ -54,8 +54,9 @@ func pathConfig(b *backend) *framework.Path {
 				Type:    framework.TypeString,
 				Default: "(|(memberUid={{.Username}})(member={{.UserDN}})(uniqueMember={{.UserDN}}))",
 				Description: `Go template for querying group membership of user (optional)
-The template can access the following context variables: UserDN, Username
+The template can access the following context variables: UserDN, Username, UserAttributes
 Example: (&(objectClass=group)(member:1.2.840.113556.1.4.1941:={{.UserDN}}))
+Example: (&(objectClass=posixGroup)(gidNumber={{.UserAttributes.gidNumber}}))
 Default: (|(memberUid={{.Username}})(member={{.UserDN}})(uniqueMember={{.UserDN}}))`,
 			},
 
@@ -115,6 +116,18 @@ Default: cn`,
 				Default:     true,
 				Description: "Denies an unauthenticated LDAP bind request if the user's password is empty; defaults to true",
 			},
+
+			"max_page_size": &framework.FieldSchema{
+				Type:        framework.TypeInt,
+				Default:     0,
+				Description: "If set to a value greater than 0, the LDAP searches are paged with this page size, so that large results are not truncated by the server size limit; defaults to 0, disabling paging",
+			},
+
+			"connection_pool_size": &framework.FieldSchema{
+				Type:        framework.TypeInt,
+				Default:     0,
+				Description: "Maximum number of idle connections to the LDAP server kept open for reuse by subsequent logins; defaults to 0, disabling pooling",
+			},
 		},
 
 		Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -284,6 +297,14 @@ func (b *backend) newConfigEntry(d *framework.FieldData) (*ConfigEntry, error) {
 	if discoverDN {
 		cfg.DiscoverDN = discoverDN
 	}
+	cfg.MaxPageSize = d.Get("max_page_size").(int)
+	if cfg.MaxPageSize < 0 {
+		return nil, fmt.Errorf("'max_page_size' cannot be negative")
+	}
+	cfg.ConnectionPoolSize = d.Get("connection_pool_size").(int)
+	if cfg.ConnectionPoolSize < 0 {
+		return nil, fmt.Errorf("'connection_pool_size' cannot be negative")
+	}
 
 	return cfg, nil
 }
@@ -303,6 +324,9 @@ func (b *backend) pathConfigWrite(ctx context.Context, req *logical.Request, d *
 		return nil, err
 	}
 
+	// The pooled connections were dialed using the previous configuration
+	b.connPool.flush()
+
 	return nil, nil
 }
 
@@ -324,6 +348,9 @@ type ConfigEntry struct {
 	DiscoverDN    bool   `json:"discoverdn" structs:"discoverdn" mapstructure:"discoverdn"`
 	TLSMinVersion string `json:"tls_min_version" structs:"tls_min_version" mapstructure:"tls_min_version"`
 	TLSMaxVersion string `json:"tls_max_version" structs:"tls_max_version" mapstructure:"tls_max_version"`
+
+	MaxPageSize        int `json:"max_page_size" structs:"max_page_size" mapstructure:"max_page_size"`
+	ConnectionPoolSize int `json:"connection_pool_size" structs:"connection_pool_size" mapstructure:"connection_pool_size"`
 }
 
 func (c *ConfigEntry) GetTLSConfig(host string) (*tls.Config, error) {
@@ -0,0 +1,111 @@
+package aws
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+var identityTemplateRegex = regexp.MustCompile(`{{\s*([^}\s]*)\s*}}`)
+
+// renderIdentityTemplate replaces the identity templates in the given value
+// with the values of the entity. The supported templates are:
+//
+//	{{identity.entity.id}}
+//	{{identity.entity.name}}
+//	{{identity.entity.metadata.<key>}}
+//	{{identity.entity.aliases.<mount accessor>.name}}
+//
+// An error is returned if a template cannot be resolved for the entity.
+func renderIdentityTemplate(value string, entity *logical.Entity) (string, error) {
+	if !identityTemplateRegex.MatchString(value) {
+		return value, nil
+	}
+	if entity == nil {
+		return "", fmt.Errorf("no entity is associated with the request")
+	}
+
+	var renderErr error
+	rendered := identityTemplateRegex.ReplaceAllStringFunc(value, func(match string) string {
+		if renderErr != nil {
+			return ""
+		}
+		result, err := resolveIdentityTemplate(identityTemplateRegex.FindStringSubmatch(match)[1], entity)
+		if err != nil {
+			renderErr = err
+			return ""
+		}
+		return result
+	})
+	if renderErr != nil {
+		return "", renderErr
+	}
+
+	return rendered, nil
+}
+
+// validateIdentityTemplate returns an error if the given value holds a
+// template which is not supported by renderIdentityTemplate.
+func validateIdentityTemplate(value string) error {
+	for _, match := range identityTemplateRegex.FindAllStringSubmatch(value, -1) {
+		tpl := match[1]
+		switch {
+		case tpl == "identity.entity.id",
+			tpl == "identity.entity.name",
+			strings.HasPrefix(tpl, "identity.entity.metadata.") && tpl != "identity.entity.metadata.",
+			strings.HasPrefix(tpl, "identity.entity.aliases.") && strings.HasSuffix(tpl, ".name") && tpl != "identity.entity.aliases.name":
+		default:
+			return fmt.Errorf("unsupported template %q", "{{"+tpl+"}}")
+		}
+	}
+	return nil
+}
+
+func resolveIdentityTemplate(tpl string, entity *logical.Entity) (string, error) {
+	switch {
+	case tpl == "identity.entity.id":
+		return entity.ID, nil
+
+	case tpl == "identity.entity.name":
+		if entity.Name == "" {
+			return "", fmt.Errorf("entity has no name")
+		}
+		return entity.Name, nil
+
+	case strings.HasPrefix(tpl, "identity.entity.metadata."):
+		key := strings.TrimPrefix(tpl, "identity.entity.metadata.")
+		value, ok := entity.Metadata[key]
+		if !ok || value == "" {
+			return "", fmt.Errorf("entity has no metadata %q", key)
+		}
+		return value, nil
+
+	case strings.HasPrefix(tpl, "identity.entity.aliases.") && strings.HasSuffix(tpl, ".name"):
+		accessor := strings.TrimSuffix(strings.TrimPrefix(tpl, "identity.entity.aliases."), ".name")
+		for _, alias := range entity.Aliases {
+			if alias.MountAccessor == accessor {
+				return alias.Name, nil
+			}
+		}
+		return "", fmt.Errorf("entity has no alias for mount accessor %q", accessor)
+	}
+
+	return "", fmt.Errorf("unsupported template %q", "{{"+tpl+"}}")
+}
+
+// requestEntity returns the entity of the client making the request, or nil
+// if the token is not tied to an entity.
+func (b *backend) requestEntity(req *logical.Request) (*logical.Entity, error) {
+	if req.EntityID == "" {
+		return nil, nil
+	}
+
+	entity, err := b.System().EntityInfo(req.EntityID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up entity: %v", err)
+	}
+
+	return entity, nil
+}
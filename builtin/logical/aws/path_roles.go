@@ -5,10 +5,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"errors"
 	"strings"
 
+	"github.com/hashicorp/vault/helper/strutil"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
 )
@@ -44,6 +46,49 @@ func pathRoles() *framework.Path {
 				Type:        framework.TypeString,
 				Description: "IAM policy document",
 			},
+
+			"credential_type": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `Type of the credentials generated for the role. The only
+supported value is "assumed_role". If not set, the role is defined
+by either the "policy" or the "arn" parameter.`,
+			},
+
+			"role_arn": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "ARN of the IAM role to assume, for the assumed_role credential type",
+			},
+
+			"policy_arns": &framework.FieldSchema{
+				Type: framework.TypeCommaStringSlice,
+				Description: `ARNs of managed policies limiting the permissions of the
+assumed role session, for the assumed_role credential type`,
+			},
+
+			"policy_document": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `IAM policy document limiting the permissions of the assumed
+role session, for the assumed_role credential type`,
+			},
+
+			"default_sts_ttl": &framework.FieldSchema{
+				Type: framework.TypeDurationSecond,
+				Description: `Default lifetime of the assumed role session when no ttl
+is requested. Defaults to one hour.`,
+			},
+
+			"max_sts_ttl": &framework.FieldSchema{
+				Type: framework.TypeDurationSecond,
+				Description: `Maximum lifetime of the assumed role session. Requested
+ttls are capped to this value.`,
+			},
+
+			"session_tags": &framework.FieldSchema{
+				Type: framework.TypeKVPairs,
+				Description: `Session tags of the assumed role session. Values may hold
+identity templates, such as {{identity.entity.name}}, which are rendered
+with the entity of the requester.`,
+			},
 		},
 
 		Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -62,12 +107,20 @@ func (b *backend) pathRoleList(ctx context.Context, req *logical.Request, d *fra
 	if err != nil {
 		return nil, err
 	}
+	roleEntries, err := req.Storage.List(ctx, "role/")
+	if err != nil {
+		return nil, err
+	}
+	entries = strutil.RemoveDuplicates(append(entries, roleEntries...), false)
 	return logical.ListResponse(entries), nil
 }
 
 func pathRolesDelete(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
-	err := req.Storage.Delete(ctx, "policy/"+d.Get("name").(string))
-	if err != nil {
+	name := d.Get("name").(string)
+	if err := req.Storage.Delete(ctx, "policy/"+name); err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Delete(ctx, "role/"+name); err != nil {
 		return nil, err
 	}
 
@@ -75,6 +128,28 @@ func pathRolesDelete(ctx context.Context, req *logical.Request, d *framework.Fie
 }
 
 func pathRolesRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	role, err := getAssumedRole(ctx, req.Storage, d.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if role != nil {
+		sessionTags := role.SessionTags
+		if sessionTags == nil {
+			sessionTags = map[string]string{}
+		}
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"credential_type": credentialTypeAssumedRole,
+				"role_arn":        role.RoleARN,
+				"policy_arns":     role.PolicyARNs,
+				"policy_document": role.PolicyDocument,
+				"default_sts_ttl": int64(role.DefaultSTSTTL.Seconds()),
+				"max_sts_ttl":     int64(role.MaxSTSTTL.Seconds()),
+				"session_tags":    sessionTags,
+			},
+		}, nil
+	}
+
 	entry, err := req.Storage.Get(ctx, "policy/"+d.Get("name").(string))
 	if err != nil {
 		return nil, err
@@ -112,6 +187,15 @@ func useInlinePolicy(d *framework.FieldData) (bool, error) {
 }
 
 func pathRolesWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	switch credentialType := d.Get("credential_type").(string); credentialType {
+	case "":
+	case credentialTypeAssumedRole:
+		return pathAssumedRoleWrite(ctx, req, d)
+	default:
+		return logical.ErrorResponse(fmt.Sprintf(
+			"Unsupported credential_type %q", credentialType)), nil
+	}
+
 	var buf bytes.Buffer
 
 	uip, err := useInlinePolicy(d)
@@ -143,9 +227,136 @@ func pathRolesWrite(ctx context.Context, req *logical.Request, d *framework.Fiel
 		}
 	}
 
+	// Remove the role if it was previously of the assumed_role credential type
+	if err := req.Storage.Delete(ctx, "role/"+d.Get("name").(string)); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func pathAssumedRoleWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	if d.Get("policy").(string) != "" || d.Get("arn").(string) != "" {
+		return logical.ErrorResponse(
+			"policy and arn cannot be used with the assumed_role credential type; use policy_document and policy_arns instead"), nil
+	}
+
+	role := &assumedRoleEntry{
+		RoleARN:       d.Get("role_arn").(string),
+		PolicyARNs:    d.Get("policy_arns").([]string),
+		DefaultSTSTTL: time.Duration(d.Get("default_sts_ttl").(int)) * time.Second,
+		MaxSTSTTL:     time.Duration(d.Get("max_sts_ttl").(int)) * time.Second,
+		SessionTags:   d.Get("session_tags").(map[string]string),
+	}
+
+	if !strings.HasPrefix(role.RoleARN, "arn:") || !strings.Contains(role.RoleARN, ":role/") {
+		return logical.ErrorResponse("role_arn must be the ARN of an IAM role"), nil
+	}
+
+	if len(role.PolicyARNs) > maxSessionPolicyARNs {
+		return logical.ErrorResponse(fmt.Sprintf(
+			"At most %d policy_arns can be provided", maxSessionPolicyARNs)), nil
+	}
+	for _, policyARN := range role.PolicyARNs {
+		if !strings.HasPrefix(policyARN, "arn:") {
+			return logical.ErrorResponse(fmt.Sprintf(
+				"Invalid policy ARN %q", policyARN)), nil
+		}
+	}
+
+	if policyDocument := d.Get("policy_document").(string); policyDocument != "" {
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, []byte(policyDocument)); err != nil {
+			return logical.ErrorResponse(fmt.Sprintf(
+				"Error compacting policy_document: %s", err)), nil
+		}
+		role.PolicyDocument = buf.String()
+	}
+
+	for field, ttl := range map[string]time.Duration{"default_sts_ttl": role.DefaultSTSTTL, "max_sts_ttl": role.MaxSTSTTL} {
+		if ttl != 0 && (ttl < minAssumedRoleTTL || ttl > maxAssumedRoleTTL) {
+			return logical.ErrorResponse(fmt.Sprintf(
+				"%s must be between %d and %d seconds", field, int64(minAssumedRoleTTL.Seconds()), int64(maxAssumedRoleTTL.Seconds()))), nil
+		}
+	}
+	if role.MaxSTSTTL != 0 && role.DefaultSTSTTL > role.MaxSTSTTL {
+		return logical.ErrorResponse("default_sts_ttl cannot be greater than max_sts_ttl"), nil
+	}
+
+	if len(role.SessionTags) > maxSessionTags {
+		return logical.ErrorResponse(fmt.Sprintf(
+			"At most %d session_tags can be provided", maxSessionTags)), nil
+	}
+	for key, value := range role.SessionTags {
+		if len(key) == 0 || len(key) > maxSessionTagKeyLen {
+			return logical.ErrorResponse(fmt.Sprintf(
+				"Session tag keys must be between 1 and %d characters long", maxSessionTagKeyLen)), nil
+		}
+		if err := validateIdentityTemplate(value); err != nil {
+			return logical.ErrorResponse(fmt.Sprintf(
+				"Invalid value of session tag %q: %s", key, err)), nil
+		}
+	}
+
+	entry, err := logical.StorageEntryJSON("role/"+name, role)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	// Remove the legacy role, which would otherwise be listed twice
+	if err := req.Storage.Delete(ctx, "policy/"+name); err != nil {
+		return nil, err
+	}
+
 	return nil, nil
 }
 
+// getAssumedRole returns the role of the assumed_role credential type with the
+// given name, or nil if there is no such role.
+func getAssumedRole(ctx context.Context, s logical.Storage, name string) (*assumedRoleEntry, error) {
+	entry, err := s.Get(ctx, "role/"+name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var role assumedRoleEntry
+	if err := entry.DecodeJSON(&role); err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+const credentialTypeAssumedRole = "assumed_role"
+
+const (
+	minAssumedRoleTTL     = 15 * time.Minute
+	maxAssumedRoleTTL     = 12 * time.Hour
+	maxSessionPolicyARNs  = 10
+	maxSessionTags        = 50
+	maxSessionTagKeyLen   = 128
+	maxSessionTagValueLen = 256
+)
+
+// assumedRoleEntry is a role of the assumed_role credential type, whose
+// credentials are generated by assuming an IAM role. Roles of the other
+// credential types are stored as their raw policy or ARN under "policy/".
+type assumedRoleEntry struct {
+	RoleARN        string            `json:"role_arn"`
+	PolicyARNs     []string          `json:"policy_arns"`
+	PolicyDocument string            `json:"policy_document"`
+	DefaultSTSTTL  time.Duration     `json:"default_sts_ttl"`
+	MaxSTSTTL      time.Duration     `json:"max_sts_ttl"`
+	SessionTags    map[string]string `json:"session_tags"`
+}
+
 const pathListRolesHelpSyn = `List the existing roles in this backend`
 
 const pathListRolesHelpDesc = `Roles will be listed by the role name.`
@@ -167,5 +378,17 @@ reference (via the arn argument). Inline user policies written are normal
 IAM policies. Vault will not attempt to parse these except to validate
 that they're basic JSON. No validation is performed on arn references.
 
+Setting "credential_type" to "assumed_role" creates a role whose credentials
+are generated by assuming the IAM role of "role_arn" with sts:AssumeRole, at
+either "aws/creds/<name>" or "aws/sts/<name>". The permissions of the session
+can be limited with "policy_arns" and "policy_document", and "session_tags"
+are attached to the session. Session tag values may hold identity templates,
+which are rendered with the entity of the requester:
+
+	{{identity.entity.id}}
+	{{identity.entity.name}}
+	{{identity.entity.metadata.<key>}}
+	{{identity.entity.aliases.<mount accessor>.name}}
+
 To validate the keys, attempt to read an access key after writing the policy.
 `
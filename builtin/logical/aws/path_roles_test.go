@@ -2,8 +2,15 @@ package aws
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
 	"strconv"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/vault/logical"
 )
@@ -63,3 +70,232 @@ func TestBackend_PathListRoles(t *testing.T) {
 		t.Fatalf("failed to list all 10 roles")
 	}
 }
+
+func TestBackend_assumedRole(t *testing.T) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+
+	b := Backend()
+	if err := b.Setup(context.Background(), config); err != nil {
+		t.Fatal(err)
+	}
+
+	roleReq := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "roles/test",
+		Storage:   config.StorageView,
+		Data: map[string]interface{}{
+			"credential_type": "assumed_role",
+			"role_arn":        "arn:aws:iam::123456789012:role/test",
+			"policy_arns":     "arn:aws:iam::aws:policy/ReadOnlyAccess",
+			"policy_document": `{ "Version": "2012-10-17" }`,
+			"default_sts_ttl": "30m",
+			"max_sts_ttl":     "2h",
+			"session_tags":    []string{"user={{identity.entity.name}}", "team=platform"},
+		},
+	}
+	resp, err := b.HandleRequest(context.Background(), roleReq)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: role creation failed. resp:%#v\n err:%v", resp, err)
+	}
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "roles/test",
+		Storage:   config.StorageView,
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: reading role failed. resp:%#v\n err:%v", resp, err)
+	}
+	expected := map[string]interface{}{
+		"credential_type": "assumed_role",
+		"role_arn":        "arn:aws:iam::123456789012:role/test",
+		"policy_arns":     []string{"arn:aws:iam::aws:policy/ReadOnlyAccess"},
+		"policy_document": `{"Version":"2012-10-17"}`,
+		"default_sts_ttl": int64(1800),
+		"max_sts_ttl":     int64(7200),
+		"session_tags":    map[string]string{"user": "{{identity.entity.name}}", "team": "platform"},
+	}
+	if !reflect.DeepEqual(resp.Data, expected) {
+		t.Fatalf("bad: role: %#v", resp.Data)
+	}
+
+	// Overwriting the role with a legacy role leaves a single role
+	roleReq.Data = map[string]interface{}{
+		"arn": "arn:aws:iam::123456789012:role/test",
+	}
+	resp, err = b.HandleRequest(context.Background(), roleReq)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: role creation failed. resp:%#v\n err:%v", resp, err)
+	}
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ListOperation,
+		Path:      "roles/",
+		Storage:   config.StorageView,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: listing roles failed. resp:%#v\n err:%v", resp, err)
+	}
+	if keys := resp.Data["keys"].([]string); !reflect.DeepEqual(keys, []string{"test"}) {
+		t.Fatalf("bad: keys: %#v", keys)
+	}
+
+	for _, data := range []map[string]interface{}{
+		{"credential_type": "iam_user", "arn": "arn:aws:iam::123456789012:role/test"},
+		{"credential_type": "assumed_role"},
+		{"credential_type": "assumed_role", "role_arn": "arn:aws:iam::aws:policy/ReadOnlyAccess"},
+		{"credential_type": "assumed_role", "role_arn": "arn:aws:iam::123456789012:role/test", "arn": "arn:aws:iam::123456789012:role/test"},
+		{"credential_type": "assumed_role", "role_arn": "arn:aws:iam::123456789012:role/test", "policy_arns": "ReadOnlyAccess"},
+		{"credential_type": "assumed_role", "role_arn": "arn:aws:iam::123456789012:role/test", "policy_document": "{"},
+		{"credential_type": "assumed_role", "role_arn": "arn:aws:iam::123456789012:role/test", "default_sts_ttl": "1m"},
+		{"credential_type": "assumed_role", "role_arn": "arn:aws:iam::123456789012:role/test", "default_sts_ttl": "2h", "max_sts_ttl": "1h"},
+		{"credential_type": "assumed_role", "role_arn": "arn:aws:iam::123456789012:role/test", "session_tags": "user={{identity.entity.email}}"},
+	} {
+		roleReq.Data = data
+		resp, err = b.HandleRequest(context.Background(), roleReq)
+		if err != nil || resp == nil || !resp.IsError() {
+			t.Fatalf("%#v: expected an error response. resp:%#v\n err:%v", data, resp, err)
+		}
+	}
+}
+
+// fakeSTS is a fake STS endpoint answering AssumeRole requests and recording
+// their parameters
+type fakeSTS struct {
+	sync.Mutex
+	form url.Values
+}
+
+func (f *fakeSTS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	if err := r.ParseForm(); err != nil || r.Form.Get("Action") != "AssumeRole" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	f.form = r.Form
+
+	duration, _ := strconv.Atoi(r.Form.Get("DurationSeconds"))
+	expiration := time.Now().Add(time.Duration(duration) * time.Second).UTC().Format(time.RFC3339)
+	w.Header().Set("Content-Type", "text/xml")
+	fmt.Fprintf(w, `<AssumeRoleResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <AssumeRoleResult>
+    <Credentials>
+      <AccessKeyId>ASIAEXAMPLE</AccessKeyId>
+      <SecretAccessKey>secret</SecretAccessKey>
+      <SessionToken>token</SessionToken>
+      <Expiration>%s</Expiration>
+    </Credentials>
+  </AssumeRoleResult>
+  <ResponseMetadata>
+    <RequestId>c6104cbe-af31-11e0-8154-cbc7ccf896c7</RequestId>
+  </ResponseMetadata>
+</AssumeRoleResponse>`, expiration)
+}
+
+func TestBackend_assumedRoleSessionTags(t *testing.T) {
+	sts := &fakeSTS{}
+	server := httptest.NewServer(sts)
+	defer server.Close()
+
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	config.System = &logical.StaticSystemView{
+		DefaultLeaseTTLVal: 24 * time.Hour,
+		MaxLeaseTTLVal:     24 * time.Hour,
+		EntityVal: &logical.Entity{
+			ID:   "entity-id",
+			Name: "alice",
+		},
+	}
+
+	b := Backend()
+	if err := b.Setup(context.Background(), config); err != nil {
+		t.Fatal(err)
+	}
+
+	requests := []*logical.Request{
+		{
+			Operation: logical.UpdateOperation,
+			Path:      "config/root",
+			Data: map[string]interface{}{
+				"access_key":   "AKIAEXAMPLE",
+				"secret_key":   "secret",
+				"region":       "us-east-1",
+				"sts_endpoint": server.URL,
+			},
+		},
+		{
+			Operation: logical.UpdateOperation,
+			Path:      "roles/test",
+			Data: map[string]interface{}{
+				"credential_type": "assumed_role",
+				"role_arn":        "arn:aws:iam::123456789012:role/test",
+				"policy_arns":     "arn:aws:iam::aws:policy/ReadOnlyAccess",
+				"max_sts_ttl":     "2h",
+				"session_tags":    "user={{identity.entity.name}}",
+			},
+		},
+	}
+	for _, req := range requests {
+		req.Storage = config.StorageView
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: %s failed. resp:%#v\n err:%v", req.Path, resp, err)
+		}
+	}
+
+	// The session tags are rendered with the entity of the requester
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "creds/test",
+		Storage:   config.StorageView,
+		EntityID:  "entity-id",
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: generating credentials failed. resp:%#v\n err:%v", resp, err)
+	}
+	if resp.Data["access_key"] != "ASIAEXAMPLE" || resp.Data["security_token"] != "token" || resp.Secret.Renewable {
+		t.Fatalf("bad: %#v", resp)
+	}
+	expected := map[string]string{
+		"RoleArn":                 "arn:aws:iam::123456789012:role/test",
+		"DurationSeconds":         "3600",
+		"PolicyArns.member.1.arn": "arn:aws:iam::aws:policy/ReadOnlyAccess",
+		"Tags.member.1.Key":       "user",
+		"Tags.member.1.Value":     "alice",
+	}
+	for key, value := range expected {
+		if sts.form.Get(key) != value {
+			t.Fatalf("bad: %s: expected %q, got %q", key, value, sts.form.Get(key))
+		}
+	}
+
+	// The requested ttl is capped to the max_sts_ttl of the role
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "sts/test",
+		Storage:   config.StorageView,
+		EntityID:  "entity-id",
+		Data: map[string]interface{}{
+			"ttl": "4h",
+		},
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: generating credentials failed. resp:%#v\n err:%v", resp, err)
+	}
+	if sts.form.Get("DurationSeconds") != "7200" || len(resp.Warnings) == 0 {
+		t.Fatalf("bad: duration: %s, warnings: %#v", sts.form.Get("DurationSeconds"), resp.Warnings)
+	}
+
+	// The session tags cannot be rendered without an entity
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "creds/test",
+		Storage:   config.StorageView,
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error response. resp:%#v\n err:%v", resp, err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
@@ -44,6 +45,18 @@ func (b *backend) pathSTSRead(ctx context.Context, req *logical.Request, d *fram
 	policyName := d.Get("name").(string)
 	ttl := int64(d.Get("ttl").(int))
 
+	role, err := getAssumedRole(ctx, req.Storage, policyName)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving role: %s", err)
+	}
+	if role != nil {
+		var requestedTTL time.Duration
+		if ttlRaw, ok := d.GetOk("ttl"); ok {
+			requestedTTL = time.Duration(ttlRaw.(int)) * time.Second
+		}
+		return b.assumeRoleEntry(ctx, req, policyName, role, requestedTTL)
+	}
+
 	// Read the policy
 	policy, err := req.Storage.Get(ctx, "policy/"+policyName)
 	if err != nil {
@@ -77,6 +90,47 @@ func (b *backend) pathSTSRead(ctx context.Context, req *logical.Request, d *fram
 	)
 }
 
+// assumeRoleEntry generates the credentials of a role of the assumed_role
+// credential type. The lifetime of the session is the requested ttl if set,
+// or else the default_sts_ttl of the role, capped to its max_sts_ttl.
+func (b *backend) assumeRoleEntry(ctx context.Context, req *logical.Request, roleName string, role *assumedRoleEntry, requestedTTL time.Duration) (*logical.Response, error) {
+	ttl := requestedTTL
+	if ttl == 0 {
+		ttl = role.DefaultSTSTTL
+	}
+	if ttl == 0 {
+		ttl = time.Hour
+	}
+	var ttlWarning string
+	if role.MaxSTSTTL != 0 && ttl > role.MaxSTSTTL {
+		ttl = role.MaxSTSTTL
+		ttlWarning = fmt.Sprintf("the requested ttl was capped to the max_sts_ttl of the role, %d seconds", int64(ttl.Seconds()))
+	}
+
+	var entity *logical.Entity
+	if len(role.SessionTags) != 0 {
+		var err error
+		entity, err = b.requestEntity(req)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := b.assumeRoleWithSessionTags(
+		ctx,
+		req.Storage,
+		req.DisplayName, roleName, role, entity,
+		int64(ttl.Seconds()),
+	)
+	if err != nil || resp.IsError() {
+		return resp, err
+	}
+	if ttlWarning != "" {
+		resp.AddWarning(ttlWarning)
+	}
+	return resp, nil
+}
+
 const pathSTSHelpSyn = `
 Generate an access key pair + security token for a specific role.
 `
@@ -87,7 +141,9 @@ accessing AWS. The IAM policy used to back this key pair will be
 the "name" parameter. For example, if this backend is mounted at "aws",
 then "aws/sts/deploy" would generate access keys for the "deploy" role.
 
-Note, these credentials are instantiated using the AWS STS backend.
+Note, these credentials are instantiated using the AWS STS backend. For roles
+of the assumed_role credential type, the "ttl" parameter defaults to the
+"default_sts_ttl" of the role and is capped to its "max_sts_ttl".
 
 The access keys will have a lease associated with them, but revoking the lease
 does not revoke the access keys.
@@ -33,6 +33,14 @@ func pathUser(b *backend) *framework.Path {
 func (b *backend) pathUserRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
 	policyName := d.Get("name").(string)
 
+	role, err := getAssumedRole(ctx, req.Storage, policyName)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving role: %s", err)
+	}
+	if role != nil {
+		return b.assumeRoleEntry(ctx, req, policyName, role, 0)
+	}
+
 	// Read the policy
 	policy, err := req.Storage.Get(ctx, "policy/"+policyName)
 	if err != nil {
@@ -171,4 +179,7 @@ then "aws/creds/deploy" would generate access keys for the "deploy" role.
 
 The access keys will have a lease associated with them. The access keys
 can be revoked by using the lease ID.
+
+For roles of the assumed_role credential type, the IAM role of the role is
+assumed instead, and the credentials expire with the session.
 `
@@ -5,11 +5,13 @@ import (
 	"fmt"
 	"math/rand"
 	"regexp"
+	"sort"
 	"time"
 
 	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/iam"
 	"github.com/aws/aws-sdk-go/service/sts"
 	"github.com/hashicorp/vault/logical"
@@ -155,6 +157,115 @@ func (b *backend) assumeRole(ctx context.Context, s logical.Storage,
 	return resp, nil
 }
 
+// assumeRoleInput is the input of sts:AssumeRole with the session policies
+// and tags, which the vendored version of the SDK does not support.
+type assumeRoleInput struct {
+	_ struct{} `type:"structure"`
+
+	DurationSeconds *int64              `type:"integer"`
+	Policy          *string             `type:"string"`
+	PolicyArns      []*policyDescriptor `type:"list"`
+	RoleArn         *string             `type:"string"`
+	RoleSessionName *string             `type:"string"`
+	Tags            []*sessionTag       `type:"list"`
+}
+
+type policyDescriptor struct {
+	_ struct{} `type:"structure"`
+
+	Arn *string `locationName:"arn" type:"string"`
+}
+
+type sessionTag struct {
+	_ struct{} `type:"structure"`
+
+	Key   *string `type:"string"`
+	Value *string `type:"string"`
+}
+
+// assumeRoleWithSessionTags assumes the IAM role of a role of the
+// assumed_role credential type, with its session policies and its session
+// tags rendered for the entity of the requester.
+func (b *backend) assumeRoleWithSessionTags(ctx context.Context, s logical.Storage,
+	displayName, roleName string, role *assumedRoleEntry, entity *logical.Entity,
+	lifeTimeInSeconds int64) (*logical.Response, error) {
+	input := &assumeRoleInput{
+		RoleArn:         aws.String(role.RoleARN),
+		DurationSeconds: &lifeTimeInSeconds,
+	}
+	if role.PolicyDocument != "" {
+		input.Policy = aws.String(role.PolicyDocument)
+	}
+	for _, policyARN := range role.PolicyARNs {
+		input.PolicyArns = append(input.PolicyArns, &policyDescriptor{
+			Arn: aws.String(policyARN),
+		})
+	}
+
+	keys := make([]string, 0, len(role.SessionTags))
+	for key := range role.SessionTags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value, err := renderIdentityTemplate(role.SessionTags[key], entity)
+		if err != nil {
+			return logical.ErrorResponse(fmt.Sprintf(
+				"Error rendering session tag %q: %s", key, err)), nil
+		}
+		if len(value) > maxSessionTagValueLen {
+			return logical.ErrorResponse(fmt.Sprintf(
+				"Value of session tag %q is longer than %d characters", key, maxSessionTagValueLen)), nil
+		}
+		input.Tags = append(input.Tags, &sessionTag{
+			Key:   aws.String(key),
+			Value: aws.String(value),
+		})
+	}
+
+	STSClient, err := clientSTS(ctx, s)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	username, usernameWarning := genUsername(displayName, roleName, "iam_user")
+	input.RoleSessionName = aws.String(username)
+
+	tokenResp := &sts.AssumeRoleOutput{}
+	r := STSClient.NewRequest(&request.Operation{
+		Name:       "AssumeRole",
+		HTTPMethod: "POST",
+		HTTPPath:   "/",
+	}, input, tokenResp)
+	r.SetContext(ctx)
+	if err := r.Send(); err != nil {
+		return logical.ErrorResponse(fmt.Sprintf(
+			"Error assuming role: %s", err)), nil
+	}
+
+	resp := b.Secret(SecretAccessKeyType).Response(map[string]interface{}{
+		"access_key":     *tokenResp.Credentials.AccessKeyId,
+		"secret_key":     *tokenResp.Credentials.SecretAccessKey,
+		"security_token": *tokenResp.Credentials.SessionToken,
+	}, map[string]interface{}{
+		"username": username,
+		"policy":   role.RoleARN,
+		"is_sts":   true,
+	})
+
+	// Set the secret TTL to appropriately match the expiration of the token
+	resp.Secret.TTL = tokenResp.Credentials.Expiration.Sub(time.Now())
+
+	// STS are purposefully short-lived and aren't renewable
+	resp.Secret.Renewable = false
+
+	if usernameWarning != "" {
+		resp.AddWarning(usernameWarning)
+	}
+
+	return resp, nil
+}
+
 func (b *backend) secretAccessKeysCreate(
 	ctx context.Context,
 	s logical.Storage,
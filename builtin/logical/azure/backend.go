@@ -0,0 +1,81 @@
+package azure
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+func Factory(ctx context.Context, conf *logical.BackendConfig) (logical.Backend, error) {
+	b := Backend()
+	if err := b.Setup(ctx, conf); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func Backend() *backend {
+	var b backend
+	b.Backend = &framework.Backend{
+		Help: strings.TrimSpace(backendHelp),
+
+		PathsSpecial: &logical.Paths{
+			LocalStorage: []string{
+				framework.WALPrefix,
+			},
+			SealWrapStorage: []string{
+				"config",
+			},
+		},
+
+		Paths: []*framework.Path{
+			pathConfig(&b),
+			pathListRoles(&b),
+			pathRoles(&b),
+			pathCreds(&b),
+		},
+
+		Secrets: []*framework.Secret{
+			secretServicePrincipal(&b),
+		},
+
+		WALRollback:       b.walRollback,
+		WALRollbackMinAge: 5 * time.Minute,
+		BackendType:       logical.TypeLogical,
+	}
+
+	return &b
+}
+
+type backend struct {
+	*framework.Backend
+
+	// transport overrides the transport of the HTTP client, so that tests can
+	// reach a fake of the Azure APIs
+	transport http.RoundTripper
+}
+
+// httpClient returns the HTTP client used to call the Azure APIs and to fetch
+// OAuth2 tokens.
+func (b *backend) httpClient() *http.Client {
+	client := cleanhttp.DefaultClient()
+	if b.transport != nil {
+		client.Transport = b.transport
+	}
+	return client
+}
+
+const backendHelp = `
+The Azure backend dynamically generates Azure service principal credentials.
+
+Roles either define the Azure roles assigned to a service principal which is
+created for each lease, or refer to an existing application whose credentials
+are generated for each lease. After mounting this backend, configure the
+tenant and the credentials of Vault with the "config" endpoint and define
+roles with the "roles/" endpoints.
+`
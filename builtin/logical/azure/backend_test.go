@@ -0,0 +1,521 @@
+package azure
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+const (
+	testTenantID       = "tenant"
+	testSubscriptionID = "subscription"
+	testScope          = "/subscriptions/" + testSubscriptionID + "/resourceGroups/app"
+	testReaderRoleID   = "/subscriptions/" + testSubscriptionID + "/providers/Microsoft.Authorization/roleDefinitions/reader"
+)
+
+// fakeApplication is an application of the fake tenant
+type fakeApplication struct {
+	appID     string
+	passwords []*passwordCredential
+}
+
+// fakeAzure is a fake of the token endpoint of Azure Active Directory, of the
+// applications and service principals of the Graph API and of the role
+// definitions and assignments of the Resource Manager API
+type fakeAzure struct {
+	sync.Mutex
+
+	// failAssignments makes the role assignments fail
+	failAssignments bool
+
+	apps        map[string]*fakeApplication
+	principals  map[string]string
+	assignments map[string]string
+	count       int
+
+	// replicated tracks the service principals which were the subject of an
+	// assignment attempt, the first attempt failing as if the service
+	// principal had not replicated
+	replicated map[string]bool
+}
+
+func (f *fakeAzure) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	writeJSON := func(code int, body interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(body)
+	}
+	writeGraphError := func(code int, message string) {
+		writeJSON(code, map[string]interface{}{
+			"odata.error": map[string]interface{}{
+				"code":    "Request_BadRequest",
+				"message": map[string]string{"lang": "en", "value": message},
+			},
+		})
+	}
+	writeARMError := func(code int, errorCode, message string) {
+		writeJSON(code, map[string]interface{}{
+			"error": map[string]string{"code": errorCode, "message": message},
+		})
+	}
+
+	switch r.Host {
+	case "login.microsoftonline.com":
+		r.ParseForm()
+		if r.URL.Path != "/"+testTenantID+"/oauth2/token" || r.Form.Get("client_id") != "vault" || r.Form.Get("client_secret") != "secret" {
+			writeJSON(http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+			return
+		}
+		writeJSON(http.StatusOK, map[string]string{
+			"access_token": "token-" + r.Form.Get("resource"),
+			"token_type":   "Bearer",
+			"expires_in":   "3600",
+			"expires_on":   strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10),
+			"resource":     r.Form.Get("resource"),
+		})
+		return
+
+	case "graph.windows.net":
+		if r.Header.Get("Authorization") != "Bearer token-https://graph.windows.net/" || r.URL.Query().Get("api-version") != graphAPIVersion {
+			writeGraphError(http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		path := strings.Split(strings.TrimPrefix(r.URL.Path, "/"+testTenantID+"/"), "/")
+		switch {
+		case len(path) == 1 && path[0] == "applications" && r.Method == http.MethodPost:
+			app := &application{}
+			json.NewDecoder(r.Body).Decode(app)
+			f.count++
+			app.ObjectID = fmt.Sprintf("app-object-%d", f.count)
+			app.AppID = fmt.Sprintf("app-%d", f.count)
+			f.apps[app.ObjectID] = &fakeApplication{appID: app.AppID}
+			writeJSON(http.StatusCreated, app)
+
+		case len(path) == 1 && path[0] == "servicePrincipals" && r.Method == http.MethodPost:
+			sp := &servicePrincipal{}
+			json.NewDecoder(r.Body).Decode(sp)
+			f.count++
+			sp.ObjectID = fmt.Sprintf("sp-object-%d", f.count)
+			f.principals[sp.ObjectID] = sp.AppID
+			writeJSON(http.StatusCreated, sp)
+
+		case len(path) >= 2 && path[0] == "applications":
+			app, ok := f.apps[path[1]]
+			if !ok {
+				writeGraphError(http.StatusNotFound, "Resource '"+path[1]+"' does not exist")
+				return
+			}
+			switch {
+			case len(path) == 2 && r.Method == http.MethodGet:
+				writeJSON(http.StatusOK, &application{ObjectID: path[1], AppID: app.appID})
+			case len(path) == 2 && r.Method == http.MethodDelete:
+				// Deleting an application deletes its service principal
+				delete(f.apps, path[1])
+				for id, appID := range f.principals {
+					if appID == app.appID {
+						delete(f.principals, id)
+					}
+				}
+				w.WriteHeader(http.StatusNoContent)
+			case len(path) == 3 && path[2] == "passwordCredentials" && r.Method == http.MethodGet:
+				// Password values are never returned
+				passwords := make([]*passwordCredential, 0, len(app.passwords))
+				for _, password := range app.passwords {
+					passwords = append(passwords, &passwordCredential{
+						KeyID:     password.KeyID,
+						StartDate: password.StartDate,
+						EndDate:   password.EndDate,
+					})
+				}
+				writeJSON(http.StatusOK, map[string]interface{}{"value": passwords})
+			case len(path) == 3 && path[2] == "passwordCredentials" && r.Method == http.MethodPatch:
+				var update struct {
+					Value []*passwordCredential `json:"value"`
+				}
+				json.NewDecoder(r.Body).Decode(&update)
+				// Existing passwords are kept when they are sent without a value
+				existing := make(map[string]*passwordCredential)
+				for _, password := range app.passwords {
+					existing[password.KeyID] = password
+				}
+				for i, password := range update.Value {
+					if password.Value == "" && existing[password.KeyID] != nil {
+						update.Value[i] = existing[password.KeyID]
+					}
+				}
+				app.passwords = update.Value
+				w.WriteHeader(http.StatusNoContent)
+			default:
+				writeGraphError(http.StatusBadRequest, "unsupported request")
+			}
+
+		default:
+			writeGraphError(http.StatusBadRequest, "unsupported request")
+		}
+		return
+
+	case "management.azure.com":
+		if r.Header.Get("Authorization") != "Bearer token-https://management.azure.com/" {
+			writeARMError(http.StatusUnauthorized, "InvalidAuthenticationToken", "unauthorized")
+			return
+		}
+		reader := map[string]interface{}{
+			"id":         testReaderRoleID,
+			"properties": map[string]string{"roleName": "Reader"},
+		}
+		switch {
+		case r.URL.Path == testReaderRoleID && r.Method == http.MethodGet:
+			writeJSON(http.StatusOK, reader)
+
+		case strings.HasSuffix(r.URL.Path, "/providers/Microsoft.Authorization/roleDefinitions") && r.Method == http.MethodGet:
+			definitions := []interface{}{}
+			if r.URL.Query().Get("$filter") == "roleName eq 'Reader'" {
+				definitions = append(definitions, reader)
+			}
+			writeJSON(http.StatusOK, map[string]interface{}{"value": definitions})
+
+		case strings.Contains(r.URL.Path, "/providers/Microsoft.Authorization/roleAssignments/"):
+			if r.URL.Query().Get("api-version") != roleAssignmentAPIVersion {
+				writeARMError(http.StatusBadRequest, "InvalidApiVersionParameter", "invalid api-version")
+				return
+			}
+			switch r.Method {
+			case http.MethodPut:
+				var assignment struct {
+					Properties struct {
+						RoleDefinitionID string `json:"roleDefinitionId"`
+						PrincipalID      string `json:"principalId"`
+					} `json:"properties"`
+				}
+				json.NewDecoder(r.Body).Decode(&assignment)
+				principalID := assignment.Properties.PrincipalID
+				switch {
+				case f.failAssignments:
+					writeARMError(http.StatusForbidden, "AuthorizationFailed", "not authorized")
+				case f.principals[principalID] == "" || !f.replicated[principalID]:
+					f.replicated[principalID] = true
+					writeARMError(http.StatusBadRequest, "PrincipalNotFound", "principal "+principalID+" does not exist")
+				case assignment.Properties.RoleDefinitionID != testReaderRoleID:
+					writeARMError(http.StatusBadRequest, "RoleDefinitionDoesNotExist", "role does not exist")
+				default:
+					f.assignments[r.URL.Path] = principalID
+					writeJSON(http.StatusCreated, map[string]string{"id": r.URL.Path})
+				}
+			case http.MethodDelete:
+				if _, ok := f.assignments[r.URL.Path]; !ok {
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+				delete(f.assignments, r.URL.Path)
+				writeJSON(http.StatusOK, map[string]string{"id": r.URL.Path})
+			}
+
+		default:
+			writeARMError(http.StatusNotFound, "NotFound", "not found")
+		}
+		return
+	}
+
+	writeJSON(http.StatusNotFound, map[string]string{"error": "unknown host " + r.Host})
+}
+
+// rewriteTransport sends all the requests to the fake, keeping their
+// original host
+type rewriteTransport struct {
+	target *url.URL
+}
+
+func (t *rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rewritten := new(http.Request)
+	*rewritten = *req
+	u := *req.URL
+	u.Scheme = t.target.Scheme
+	u.Host = t.target.Host
+	rewritten.URL = &u
+	rewritten.Host = req.URL.Host
+	return http.DefaultTransport.RoundTrip(rewritten)
+}
+
+func prepareTestBackend(t *testing.T) (*backend, logical.Storage, *fakeAzure, func()) {
+	roleAssignmentRetryInterval = 10 * time.Millisecond
+
+	azure := &fakeAzure{
+		apps:        make(map[string]*fakeApplication),
+		principals:  make(map[string]string),
+		assignments: make(map[string]string),
+		replicated:  make(map[string]bool),
+	}
+	server := httptest.NewServer(azure)
+	target, _ := url.Parse(server.URL)
+
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+
+	b := Backend()
+	b.transport = &rewriteTransport{target: target}
+	if err := b.Setup(context.Background(), config); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   config.StorageView,
+		Data: map[string]interface{}{
+			"subscription_id": testSubscriptionID,
+			"tenant_id":       testTenantID,
+			"client_id":       "vault",
+			"client_secret":   "secret",
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: config failed. resp:%#v\n err:%v", resp, err)
+	}
+
+	return b, config.StorageView, azure, server.Close
+}
+
+func writeRole(t *testing.T, b *backend, s logical.Storage, name string, data map[string]interface{}) *logical.Response {
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "roles/" + name,
+		Storage:   s,
+		Data:      data,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestBackend_config(t *testing.T) {
+	b, s, _, cleanup := prepareTestBackend(t)
+	defer cleanup()
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "config",
+		Storage:   s,
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: config read failed. resp:%#v\n err:%v", resp, err)
+	}
+	if _, ok := resp.Data["client_secret"]; ok || resp.Data["environment"] != defaultEnvironment || resp.Data["tenant_id"] != testTenantID {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   s,
+		Data: map[string]interface{}{
+			"environment": "AzureMoonCloud",
+		},
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error response. resp:%#v\n err:%v", resp, err)
+	}
+}
+
+func TestBackend_servicePrincipal(t *testing.T) {
+	b, s, azure, cleanup := prepareTestBackend(t)
+	defer cleanup()
+
+	for _, azureRoles := range []string{
+		`[{ "role_name": "Owner", "scope": "` + testScope + `" }]`,
+		`[{ "role_name": "Reader" }]`,
+		`not json`,
+	} {
+		resp := writeRole(t, b, s, "test", map[string]interface{}{
+			"azure_roles": azureRoles,
+		})
+		if resp == nil || !resp.IsError() {
+			t.Fatalf("%s: expected an error response. resp:%#v", azureRoles, resp)
+		}
+	}
+
+	resp := writeRole(t, b, s, "test", map[string]interface{}{
+		"azure_roles": `[{ "role_name": "Reader", "scope": "` + testScope + `" }]`,
+		"ttl":         "1h",
+		"max_ttl":     "2h",
+	})
+	if resp != nil && resp.IsError() {
+		t.Fatalf("bad: role creation failed. resp:%#v", resp)
+	}
+	r, err := getRole(context.Background(), s, "test")
+	if err != nil || r == nil || len(r.AzureRoles) != 1 || r.AzureRoles[0].RoleID != testReaderRoleID {
+		t.Fatalf("bad: role: %#v, err: %v", r, err)
+	}
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "creds/test",
+		Storage:   s,
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: creds generation failed. resp:%#v\n err:%v", resp, err)
+	}
+	if resp.Secret == nil || resp.Secret.TTL != time.Hour {
+		t.Fatalf("bad: %#v", resp.Secret)
+	}
+	clientID := resp.Data["client_id"].(string)
+	if len(azure.apps) != 1 || len(azure.principals) != 1 || len(azure.assignments) != 1 {
+		t.Fatalf("bad: apps: %#v, principals: %#v, assignments: %#v", azure.apps, azure.principals, azure.assignments)
+	}
+	for _, app := range azure.apps {
+		if app.appID != clientID || len(app.passwords) != 1 || app.passwords[0].Value != resp.Data["client_secret"] {
+			t.Fatalf("bad: app: %#v", app)
+		}
+	}
+	walIDs, err := framework.ListWAL(context.Background(), s)
+	if err != nil || len(walIDs) != 0 {
+		t.Fatalf("bad: WAL entries: %#v, err: %v", walIDs, err)
+	}
+
+	// Revoking the lease deletes the role assignments and the application
+	req := &logical.Request{
+		Operation: logical.RevokeOperation,
+		Storage:   s,
+		Secret:    resp.Secret,
+	}
+	resp, err = b.secretServicePrincipalRevoke(context.Background(), req, nil)
+	if err != nil || resp != nil {
+		t.Fatalf("bad: revocation failed. resp:%#v\n err:%v", resp, err)
+	}
+	if len(azure.apps) != 0 || len(azure.principals) != 0 || len(azure.assignments) != 0 {
+		t.Fatalf("bad: apps: %#v, principals: %#v, assignments: %#v", azure.apps, azure.principals, azure.assignments)
+	}
+
+	// Revoking an already revoked lease succeeds
+	resp, err = b.secretServicePrincipalRevoke(context.Background(), req, nil)
+	if err != nil || resp != nil {
+		t.Fatalf("bad: revocation failed. resp:%#v\n err:%v", resp, err)
+	}
+}
+
+func TestBackend_existingApplication(t *testing.T) {
+	b, s, azure, cleanup := prepareTestBackend(t)
+	defer cleanup()
+
+	azure.apps["existing"] = &fakeApplication{
+		appID:     "existing-app",
+		passwords: []*passwordCredential{{KeyID: "initial", Value: "initial-secret"}},
+	}
+
+	resp := writeRole(t, b, s, "test", map[string]interface{}{
+		"application_object_id": "missing",
+	})
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error response. resp:%#v", resp)
+	}
+	resp = writeRole(t, b, s, "test", map[string]interface{}{
+		"application_object_id": "existing",
+		"azure_roles":           `[{ "role_name": "Reader", "scope": "` + testScope + `" }]`,
+	})
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error response. resp:%#v", resp)
+	}
+
+	resp = writeRole(t, b, s, "test", map[string]interface{}{
+		"application_object_id": "existing",
+	})
+	if resp != nil && resp.IsError() {
+		t.Fatalf("bad: role creation failed. resp:%#v", resp)
+	}
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "roles/test",
+		Storage:   s,
+	})
+	if err != nil || resp == nil || resp.Data["application_id"] != "existing-app" {
+		t.Fatalf("bad: role read failed. resp:%#v\n err:%v", resp, err)
+	}
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "creds/test",
+		Storage:   s,
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: creds generation failed. resp:%#v\n err:%v", resp, err)
+	}
+	passwords := azure.apps["existing"].passwords
+	if resp.Data["client_id"] != "existing-app" || len(passwords) != 2 || passwords[1].Value != resp.Data["client_secret"] {
+		t.Fatalf("bad: data: %#v, passwords: %#v", resp.Data, passwords)
+	}
+	if len(azure.principals) != 0 {
+		t.Fatalf("bad: principals: %#v", azure.principals)
+	}
+
+	// Revoking the lease only removes its password
+	resp, err = b.secretServicePrincipalRevoke(context.Background(), &logical.Request{
+		Operation: logical.RevokeOperation,
+		Storage:   s,
+		Secret:    resp.Secret,
+	}, nil)
+	if err != nil || resp != nil {
+		t.Fatalf("bad: revocation failed. resp:%#v\n err:%v", resp, err)
+	}
+	passwords = azure.apps["existing"].passwords
+	if len(passwords) != 1 || passwords[0].KeyID != "initial" || passwords[0].Value != "initial-secret" {
+		t.Fatalf("bad: passwords: %#v", passwords)
+	}
+}
+
+func TestBackend_servicePrincipalRollback(t *testing.T) {
+	b, s, azure, cleanup := prepareTestBackend(t)
+	defer cleanup()
+
+	resp := writeRole(t, b, s, "test", map[string]interface{}{
+		"azure_roles": `[{ "role_id": "` + testReaderRoleID + `", "scope": "` + testScope + `" }]`,
+	})
+	if resp != nil && resp.IsError() {
+		t.Fatalf("bad: role creation failed. resp:%#v", resp)
+	}
+
+	// The application is left over when its roles cannot be assigned
+	azure.failAssignments = true
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "creds/test",
+		Storage:   s,
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error response. resp:%#v\n err:%v", resp, err)
+	}
+	if len(azure.apps) != 1 {
+		t.Fatalf("bad: apps: %#v", azure.apps)
+	}
+
+	walIDs, err := framework.ListWAL(context.Background(), s)
+	if err != nil || len(walIDs) != 1 {
+		t.Fatalf("bad: WAL entries: %#v, err: %v", walIDs, err)
+	}
+	entry, err := framework.GetWAL(context.Background(), s, walIDs[0])
+	if err != nil || entry == nil {
+		t.Fatalf("bad: WAL entry: %#v, err: %v", entry, err)
+	}
+
+	err = b.walRollback(context.Background(), &logical.Request{Storage: s}, entry.Kind, entry.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(azure.apps) != 0 || len(azure.principals) != 0 {
+		t.Fatalf("bad: apps: %#v, principals: %#v", azure.apps, azure.principals)
+	}
+}
@@ -0,0 +1,134 @@
+package azure
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Azure/go-autorest/autorest/adal"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/helper/jsonutil"
+)
+
+const (
+	graphAPIVersion          = "1.6"
+	roleAssignmentAPIVersion = "2018-01-01-preview"
+	roleDefinitionAPIVersion = "2015-07-01"
+)
+
+// azureClient is a client of the Azure Active Directory Graph API, managing
+// applications and service principals, and of the Azure Resource Manager API,
+// managing role assignments
+type azureClient struct {
+	httpClient     *http.Client
+	env            azure.Environment
+	tenantID       string
+	subscriptionID string
+	graphToken     *adal.ServicePrincipalToken
+	armToken       *adal.ServicePrincipalToken
+}
+
+// azureError is an error response of an Azure API
+type azureError struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (e *azureError) Error() string {
+	return fmt.Sprintf("azure API error %d: %s", e.StatusCode, e.Message)
+}
+
+// isNotFound returns whether the error is an Azure API error for a missing
+// resource.
+func isNotFound(err error) bool {
+	apiErr, ok := errwrap.GetType(err, &azureError{}).(*azureError)
+	return ok && apiErr.StatusCode == http.StatusNotFound
+}
+
+// graph calls the Graph API on the path relative to the tenant.
+func (c *azureClient) graph(ctx context.Context, method, path string, in, out interface{}) error {
+	u := strings.TrimSuffix(c.env.GraphEndpoint, "/") + "/" + c.tenantID + "/" + path + "?api-version=" + graphAPIVersion
+	return c.do(ctx, c.graphToken, method, u, in, out)
+}
+
+// arm calls the Resource Manager API on the path of a resource.
+func (c *azureClient) arm(ctx context.Context, method, path string, query url.Values, in, out interface{}) error {
+	u := strings.TrimSuffix(c.env.ResourceManagerEndpoint, "/") + path + "?" + query.Encode()
+	return c.do(ctx, c.armToken, method, u, in, out)
+}
+
+func (c *azureClient) do(ctx context.Context, token *adal.ServicePrincipalToken, method, u string, in, out interface{}) error {
+	if err := token.EnsureFresh(); err != nil {
+		return errwrap.Wrapf("error acquiring azure token: {{err}}", err)
+	}
+
+	var body []byte
+	if in != nil {
+		var err error
+		body, err = json.Marshal(in)
+		if err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(method, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Authorization", "Bearer "+token.OAuthToken())
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		// The Graph API and the Resource Manager API have different error
+		// formats
+		var errResp struct {
+			GraphError struct {
+				Message struct {
+					Value string `json:"value"`
+				} `json:"message"`
+			} `json:"odata.error"`
+			Error struct {
+				Code    string `json:"code"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		apiErr := &azureError{StatusCode: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
+		if err := jsonutil.DecodeJSONFromReader(resp.Body, &errResp); err == nil {
+			switch {
+			case errResp.GraphError.Message.Value != "":
+				apiErr.Message = errResp.GraphError.Message.Value
+			case errResp.Error.Message != "":
+				apiErr.Code = errResp.Error.Code
+				apiErr.Message = errResp.Error.Message
+			}
+		}
+		return apiErr
+	}
+
+	if out == nil {
+		io.Copy(ioutil.Discard, resp.Body)
+		return nil
+	}
+	if err := jsonutil.DecodeJSONFromReader(resp.Body, out); err != nil {
+		return errwrap.Wrapf("error decoding azure API response: {{err}}", err)
+	}
+	return nil
+}
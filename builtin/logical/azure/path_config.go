@@ -0,0 +1,234 @@
+package azure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Azure/go-autorest/autorest/adal"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+const (
+	configKey = "config"
+
+	defaultEnvironment = "AzurePublicCloud"
+)
+
+func pathConfig(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config",
+		Fields: map[string]*framework.FieldSchema{
+			"subscription_id": {
+				Type:        framework.TypeString,
+				Description: "ID of the Azure subscription of the role assignments",
+			},
+
+			"tenant_id": {
+				Type:        framework.TypeString,
+				Description: "ID of the Azure Active Directory tenant of the service principals",
+			},
+
+			"client_id": {
+				Type:        framework.TypeString,
+				Description: "Client ID of the service principal used by Vault",
+			},
+
+			"client_secret": {
+				Type: framework.TypeString,
+				Description: `Client secret of the service principal used by Vault.
+If not set, the managed identity of the Vault server is used.`,
+			},
+
+			"environment": {
+				Type:        framework.TypeString,
+				Description: `Azure environment, defaults to "AzurePublicCloud"`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathConfigRead,
+			logical.UpdateOperation: b.pathConfigWrite,
+			logical.DeleteOperation: b.pathConfigDelete,
+		},
+
+		HelpSynopsis:    pathConfigHelpSyn,
+		HelpDescription: pathConfigHelpDesc,
+	}
+}
+
+func (b *backend) readConfig(ctx context.Context, s logical.Storage) (*config, error) {
+	entry, err := s.Get(ctx, configKey)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	conf := &config{}
+	if err := entry.DecodeJSON(conf); err != nil {
+		return nil, errwrap.Wrapf("error reading azure configuration: {{err}}", err)
+	}
+
+	return conf, nil
+}
+
+// getClient returns a client of the Azure APIs authenticated with the
+// credentials of the configuration.
+func (b *backend) getClient(ctx context.Context, s logical.Storage) (*azureClient, error) {
+	conf, err := b.readConfig(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	if conf == nil {
+		return nil, errors.New("azure backend not configured")
+	}
+
+	env, err := azure.EnvironmentFromName(conf.Environment)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &azureClient{
+		httpClient:     b.httpClient(),
+		env:            env,
+		tenantID:       conf.TenantID,
+		subscriptionID: conf.SubscriptionID,
+	}
+	client.graphToken, err = conf.token(env, env.GraphEndpoint)
+	if err != nil {
+		return nil, err
+	}
+	client.armToken, err = conf.token(env, env.ResourceManagerEndpoint)
+	if err != nil {
+		return nil, err
+	}
+	client.graphToken.SetSender(client.httpClient)
+	client.armToken.SetSender(client.httpClient)
+
+	return client, nil
+}
+
+func (b *backend) pathConfigRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	conf, err := b.readConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if conf == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"subscription_id": conf.SubscriptionID,
+			"tenant_id":       conf.TenantID,
+			"client_id":       conf.ClientID,
+			"environment":     conf.Environment,
+		},
+	}, nil
+}
+
+func (b *backend) pathConfigWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	conf, err := b.readConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if conf == nil {
+		conf = &config{
+			Environment: defaultEnvironment,
+		}
+	}
+
+	if subscriptionID, ok := d.GetOk("subscription_id"); ok {
+		conf.SubscriptionID = subscriptionID.(string)
+	}
+	if tenantID, ok := d.GetOk("tenant_id"); ok {
+		conf.TenantID = tenantID.(string)
+	}
+	if clientID, ok := d.GetOk("client_id"); ok {
+		conf.ClientID = clientID.(string)
+	}
+	if clientSecret, ok := d.GetOk("client_secret"); ok {
+		conf.ClientSecret = clientSecret.(string)
+	}
+	if environment, ok := d.GetOk("environment"); ok {
+		conf.Environment = environment.(string)
+	}
+
+	switch {
+	case conf.SubscriptionID == "":
+		return logical.ErrorResponse("subscription_id is required"), nil
+	case conf.TenantID == "":
+		return logical.ErrorResponse("tenant_id is required"), nil
+	case conf.ClientSecret != "" && conf.ClientID == "":
+		return logical.ErrorResponse("client_id is required with client_secret"), nil
+	}
+	if _, err := azure.EnvironmentFromName(conf.Environment); err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("Invalid environment: %s", err)), nil
+	}
+
+	entry, err := logical.StorageEntryJSON(configKey, conf)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (b *backend) pathConfigDelete(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	return nil, req.Storage.Delete(ctx, configKey)
+}
+
+type config struct {
+	SubscriptionID string `json:"subscription_id"`
+	TenantID       string `json:"tenant_id"`
+	ClientID       string `json:"client_id"`
+	ClientSecret   string `json:"client_secret"`
+	Environment    string `json:"environment"`
+}
+
+// token returns a token of the resource for the service principal of the
+// configuration, or for the managed identity of the Vault server if no client
+// secret is configured.
+func (c *config) token(env azure.Environment, resource string) (*adal.ServicePrincipalToken, error) {
+	if c.ClientSecret == "" {
+		msiEndpoint, err := adal.GetMSIVMEndpoint()
+		if err != nil {
+			return nil, err
+		}
+		if c.ClientID != "" {
+			return adal.NewServicePrincipalTokenFromMSIWithUserAssignedID(msiEndpoint, resource, c.ClientID)
+		}
+		return adal.NewServicePrincipalTokenFromMSI(msiEndpoint, resource)
+	}
+
+	oauthConfig, err := adal.NewOAuthConfig(env.ActiveDirectoryEndpoint, c.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	return adal.NewServicePrincipalToken(*oauthConfig, c.ClientID, c.ClientSecret, resource)
+}
+
+const pathConfigHelpSyn = `
+Configure the tenant and the credentials of the Azure backend.
+`
+
+const pathConfigHelpDesc = `
+This path configures the Azure subscription and Active Directory tenant in
+which the service principals are managed, and the client ID and secret of the
+service principal used by Vault to create them and to assign them roles. This
+service principal needs the permissions to manage the applications of the
+tenant in the Azure Active Directory Graph API, and to manage the role
+assignments of the scopes of the roles. If no client secret is configured,
+the managed identity of the Vault server is used.
+
+The environment parameter selects the Azure cloud, and defaults to
+"AzurePublicCloud".
+`
@@ -0,0 +1,167 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+const walTypeApplication = "application"
+
+// walApplication is the WAL entry of an application created for a
+// service principal, which is deleted if the credentials could not be
+// generated
+type walApplication struct {
+	ObjectID string `json:"object_id" mapstructure:"object_id"`
+}
+
+func pathCreds(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "creds/" + framework.GenericNameRegex("role"),
+		Fields: map[string]*framework.FieldSchema{
+			"role": {
+				Type:        framework.TypeString,
+				Description: "Name of the role",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathCredsRead,
+		},
+
+		HelpSynopsis:    pathCredsHelpSyn,
+		HelpDescription: pathCredsHelpDesc,
+	}
+}
+
+func (b *backend) pathCredsRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	roleName := d.Get("role").(string)
+
+	r, err := getRole(ctx, req.Storage, roleName)
+	if err != nil {
+		return nil, err
+	}
+	if r == nil {
+		return logical.ErrorResponse(fmt.Sprintf("role %q not found", roleName)), nil
+	}
+
+	client, err := b.getClient(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	// Passwords expire with the maximum lease duration of their credentials
+	maxTTL := r.MaxTTL
+	if maxTTL == 0 || maxTTL > b.System().MaxLeaseTTL() {
+		maxTTL = b.System().MaxLeaseTTL()
+	}
+	endDate := time.Now().Add(maxTTL)
+
+	var resp *logical.Response
+	if r.ApplicationObjectID != "" {
+		resp, err = b.createAppCreds(ctx, client, r, endDate)
+	} else {
+		resp, err = b.createServicePrincipal(ctx, req.Storage, client, roleName, r, endDate)
+	}
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	resp.Secret.InternalData["role"] = roleName
+	resp.Secret.TTL = r.TTL
+
+	return resp, nil
+}
+
+// createServicePrincipal creates an application and its service principal,
+// with a password credential and the Azure roles of the role.
+func (b *backend) createServicePrincipal(ctx context.Context, s logical.Storage, client *azureClient, roleName string, r *role, endDate time.Time) (*logical.Response, error) {
+	suffix, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+
+	app, err := client.createApplication(ctx, fmt.Sprintf("vault-%s-%s", roleName, suffix))
+	if err != nil {
+		return nil, err
+	}
+
+	// The application is deleted on rollback until the service principal is
+	// fully set up
+	walID, err := framework.PutWAL(ctx, s, walTypeApplication, &walApplication{
+		ObjectID: app.ObjectID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sp, err := client.createServicePrincipal(ctx, app.AppID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, password, err := client.addPassword(ctx, app.ObjectID, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	assignmentIDs := make([]string, 0, len(r.AzureRoles))
+	for _, azureRole := range r.AzureRoles {
+		id, err := client.createRoleAssignment(ctx, azureRole.Scope, azureRole.RoleID, sp.ObjectID)
+		if err != nil {
+			for _, id := range assignmentIDs {
+				client.deleteRoleAssignment(ctx, id)
+			}
+			return nil, err
+		}
+		assignmentIDs = append(assignmentIDs, id)
+	}
+
+	if err := framework.DeleteWAL(ctx, s, walID); err != nil {
+		return nil, err
+	}
+
+	return b.Secret(SecretServicePrincipalType).Response(map[string]interface{}{
+		"client_id":     app.AppID,
+		"client_secret": password,
+	}, map[string]interface{}{
+		"app_object_id":       app.ObjectID,
+		"role_assignment_ids": assignmentIDs,
+	}), nil
+}
+
+// createAppCreds adds a password credential to the existing application of
+// the role.
+func (b *backend) createAppCreds(ctx context.Context, client *azureClient, r *role, endDate time.Time) (*logical.Response, error) {
+	keyID, password, err := client.addPassword(ctx, r.ApplicationObjectID, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.Secret(SecretServicePrincipalType).Response(map[string]interface{}{
+		"client_id":     r.ApplicationID,
+		"client_secret": password,
+	}, map[string]interface{}{
+		"app_object_id": r.ApplicationObjectID,
+		"key_id":        keyID,
+	}), nil
+}
+
+const pathCredsHelpSyn = `
+Generate Azure service principal credentials of a role.
+`
+
+const pathCredsHelpDesc = `
+This path generates the client ID and secret of a service principal for a
+role. For example, if this backend is mounted at "azure", then
+"azure/creds/deploy" would generate credentials for the "deploy" role.
+
+For roles with Azure roles, a new service principal is created and assigned
+the Azure roles, and is deleted when the lease is revoked. For roles with an
+existing application, a password credential is added to the application, and
+is removed when the lease is revoked.
+`
@@ -0,0 +1,300 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// role defines either the Azure roles assigned to the service principals
+// created for the role, or the existing application whose credentials are
+// generated for the role
+type role struct {
+	AzureRoles          []*azureRole  `json:"azure_roles"`
+	ApplicationObjectID string        `json:"application_object_id"`
+	ApplicationID       string        `json:"application_id"`
+	TTL                 time.Duration `json:"ttl"`
+	MaxTTL              time.Duration `json:"max_ttl"`
+}
+
+// azureRole is an Azure role assigned on a scope
+type azureRole struct {
+	RoleName string `json:"role_name"`
+	RoleID   string `json:"role_id"`
+	Scope    string `json:"scope"`
+}
+
+func pathListRoles(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "roles/?$",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ListOperation: b.pathRoleList,
+		},
+
+		HelpSynopsis:    pathListRolesHelpSyn,
+		HelpDescription: pathListRolesHelpDesc,
+	}
+}
+
+func pathRoles(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "roles/" + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "Name of the role",
+			},
+
+			"azure_roles": {
+				Type: framework.TypeString,
+				Description: `JSON list of the Azure roles assigned to the service
+principals of the role, with the role_name or role_id and the scope of each
+role`,
+			},
+
+			"application_object_id": {
+				Type:        framework.TypeString,
+				Description: "Object ID of an existing application whose credentials are generated",
+			},
+
+			"ttl": {
+				Type:        framework.TypeDurationSecond,
+				Description: "Default lease duration of the generated credentials",
+			},
+
+			"max_ttl": {
+				Type:        framework.TypeDurationSecond,
+				Description: "Maximum lease duration of the generated credentials",
+			},
+		},
+
+		ExistenceCheck: b.pathRoleExistenceCheck,
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.DeleteOperation: b.pathRoleDelete,
+			logical.ReadOperation:   b.pathRoleRead,
+			logical.CreateOperation: b.pathRoleWrite,
+			logical.UpdateOperation: b.pathRoleWrite,
+		},
+
+		HelpSynopsis:    pathRolesHelpSyn,
+		HelpDescription: pathRolesHelpDesc,
+	}
+}
+
+func getRole(ctx context.Context, s logical.Storage, name string) (*role, error) {
+	entry, err := s.Get(ctx, "roles/"+name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var r role
+	if err := entry.DecodeJSON(&r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (b *backend) pathRoleExistenceCheck(ctx context.Context, req *logical.Request, d *framework.FieldData) (bool, error) {
+	r, err := getRole(ctx, req.Storage, d.Get("name").(string))
+	if err != nil {
+		return false, err
+	}
+	return r != nil, nil
+}
+
+func (b *backend) pathRoleList(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	entries, err := req.Storage.List(ctx, "roles/")
+	if err != nil {
+		return nil, err
+	}
+	return logical.ListResponse(entries), nil
+}
+
+func (b *backend) pathRoleRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	r, err := getRole(ctx, req.Storage, d.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if r == nil {
+		return nil, nil
+	}
+
+	data := map[string]interface{}{
+		"ttl":     int64(r.TTL.Seconds()),
+		"max_ttl": int64(r.MaxTTL.Seconds()),
+	}
+	if r.ApplicationObjectID != "" {
+		data["application_object_id"] = r.ApplicationObjectID
+		data["application_id"] = r.ApplicationID
+	} else {
+		data["azure_roles"] = r.AzureRoles
+	}
+
+	return &logical.Response{
+		Data: data,
+	}, nil
+}
+
+func (b *backend) pathRoleWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	r, err := getRole(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if r == nil {
+		r = &role{}
+	}
+
+	rawAzureRoles, azureRolesOk := d.GetOk("azure_roles")
+	if azureRolesOk {
+		var azureRoles []*azureRole
+		if err := jsonutil.DecodeJSON([]byte(rawAzureRoles.(string)), &azureRoles); err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("Invalid azure_roles: %s", err)), nil
+		}
+		r.AzureRoles = azureRoles
+		r.ApplicationObjectID = ""
+		r.ApplicationID = ""
+	}
+	if objectID, ok := d.GetOk("application_object_id"); ok {
+		if azureRolesOk {
+			return logical.ErrorResponse("only one of azure_roles and application_object_id can be set"), nil
+		}
+		r.ApplicationObjectID = objectID.(string)
+		r.AzureRoles = nil
+	}
+	if ttl, ok := d.GetOk("ttl"); ok {
+		r.TTL = time.Duration(ttl.(int)) * time.Second
+	}
+	if maxTTL, ok := d.GetOk("max_ttl"); ok {
+		r.MaxTTL = time.Duration(maxTTL.(int)) * time.Second
+	}
+
+	switch {
+	case len(r.AzureRoles) == 0 && r.ApplicationObjectID == "":
+		return logical.ErrorResponse("azure_roles or application_object_id is required"), nil
+	case r.MaxTTL != 0 && r.TTL > r.MaxTTL:
+		return logical.ErrorResponse("ttl cannot be greater than max_ttl"), nil
+	}
+	for _, azureRole := range r.AzureRoles {
+		switch {
+		case azureRole.Scope == "":
+			return logical.ErrorResponse("scope is required for each of the azure_roles"), nil
+		case azureRole.RoleName == "" && azureRole.RoleID == "":
+			return logical.ErrorResponse("role_name or role_id is required for each of the azure_roles"), nil
+		}
+	}
+
+	client, err := b.getClient(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	// Azure roles are stored with both their ID and name, which are looked up
+	// from each other
+	if azureRolesOk {
+		for _, azureRole := range r.AzureRoles {
+			if err := resolveAzureRole(ctx, client, azureRole); err != nil {
+				return logical.ErrorResponse(err.Error()), nil
+			}
+		}
+	}
+
+	if r.ApplicationObjectID != "" && r.ApplicationID == "" {
+		app, err := client.getApplication(ctx, r.ApplicationObjectID)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+		r.ApplicationID = app.AppID
+	}
+
+	entry, err := logical.StorageEntryJSON("roles/"+name, r)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (b *backend) pathRoleDelete(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	return nil, req.Storage.Delete(ctx, "roles/"+d.Get("name").(string))
+}
+
+// resolveAzureRole sets the ID and the name of the Azure role from the one
+// which is set.
+func resolveAzureRole(ctx context.Context, client *azureClient, r *azureRole) error {
+	if r.RoleID != "" {
+		definition, err := client.getRoleDefinition(ctx, r.RoleID)
+		if err != nil {
+			return err
+		}
+		r.RoleName = definition.Properties.RoleName
+		return nil
+	}
+
+	definitions, err := client.findRoleDefinitions(ctx, r.Scope, r.RoleName)
+	if err != nil {
+		return err
+	}
+	switch len(definitions) {
+	case 0:
+		return fmt.Errorf("no role named %q was found on scope %q", r.RoleName, r.Scope)
+	case 1:
+		r.RoleID = definitions[0].ID
+		return nil
+	default:
+		ids := make([]string, 0, len(definitions))
+		for _, definition := range definitions {
+			ids = append(ids, definition.ID)
+		}
+		return fmt.Errorf("multiple roles named %q were found on scope %q, use role_id with one of: %s", r.RoleName, r.Scope, strings.Join(ids, ", "))
+	}
+}
+
+const pathListRolesHelpSyn = `
+List the roles of the Azure backend.
+`
+
+const pathListRolesHelpDesc = `
+This path lists the names of the roles of the Azure backend.
+`
+
+const pathRolesHelpSyn = `
+Manage the roles generating Azure service principal credentials.
+`
+
+const pathRolesHelpDesc = `
+This path manages the roles of the Azure backend. A role either defines the
+Azure roles assigned to a service principal which is created for each lease,
+or refers to an existing application whose credentials are generated for each
+lease.
+
+The azure_roles parameter is a JSON list of the Azure roles assigned to the
+created service principals. Each role is identified by its role_name or its
+role_id, and is assigned on its scope, for example:
+
+  [{ "role_name": "Contributor", "scope": "/subscriptions/<uuid>/resourceGroups/app" }]
+
+The service principal and its application are deleted when the lease is
+revoked.
+
+The application_object_id parameter sets the object ID of an existing
+application instead. A password credential is added to the application for
+each lease, and removed when the lease is revoked.
+
+The ttl and max_ttl parameters set the lease durations of the generated
+credentials, and default to the lease durations of the mount.
+`
@@ -0,0 +1,224 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/go-uuid"
+)
+
+const (
+	// roleAssignmentAttempts is the number of attempts to assign a role to a
+	// new service principal, which takes some time to replicate
+	roleAssignmentAttempts = 12
+)
+
+// roleAssignmentRetryInterval is the interval between the attempts to assign
+// a role to a new service principal
+var roleAssignmentRetryInterval = 5 * time.Second
+
+type application struct {
+	ObjectID       string   `json:"objectId,omitempty"`
+	AppID          string   `json:"appId,omitempty"`
+	DisplayName    string   `json:"displayName"`
+	IdentifierURIs []string `json:"identifierUris,omitempty"`
+}
+
+type servicePrincipal struct {
+	ObjectID string `json:"objectId,omitempty"`
+	AppID    string `json:"appId"`
+}
+
+type passwordCredential struct {
+	KeyID     string `json:"keyId"`
+	Value     string `json:"value,omitempty"`
+	StartDate string `json:"startDate"`
+	EndDate   string `json:"endDate"`
+}
+
+type roleDefinition struct {
+	ID         string `json:"id"`
+	Properties struct {
+		RoleName string `json:"roleName"`
+	} `json:"properties"`
+}
+
+func (c *azureClient) createApplication(ctx context.Context, name string) (*application, error) {
+	app := &application{
+		DisplayName:    name,
+		IdentifierURIs: []string{"https://" + name},
+	}
+	if err := c.graph(ctx, http.MethodPost, "applications", app, app); err != nil {
+		return nil, errwrap.Wrapf("error creating application: {{err}}", err)
+	}
+	return app, nil
+}
+
+func (c *azureClient) getApplication(ctx context.Context, objectID string) (*application, error) {
+	app := &application{}
+	if err := c.graph(ctx, http.MethodGet, "applications/"+url.PathEscape(objectID), nil, app); err != nil {
+		return nil, errwrap.Wrapf(fmt.Sprintf("error reading application %q: {{err}}", objectID), err)
+	}
+	return app, nil
+}
+
+// deleteApplication deletes the application and its service principal.
+// Applications which no longer exist are considered deleted.
+func (c *azureClient) deleteApplication(ctx context.Context, objectID string) error {
+	err := c.graph(ctx, http.MethodDelete, "applications/"+url.PathEscape(objectID), nil, nil)
+	if err != nil && !isNotFound(err) {
+		return errwrap.Wrapf(fmt.Sprintf("error deleting application %q: {{err}}", objectID), err)
+	}
+	return nil
+}
+
+func (c *azureClient) createServicePrincipal(ctx context.Context, appID string) (*servicePrincipal, error) {
+	sp := &servicePrincipal{
+		AppID: appID,
+	}
+	if err := c.graph(ctx, http.MethodPost, "servicePrincipals", sp, sp); err != nil {
+		return nil, errwrap.Wrapf("error creating service principal: {{err}}", err)
+	}
+	return sp, nil
+}
+
+// addPassword adds a password credential valid until the end date to the
+// application, and returns its key ID and password.
+func (c *azureClient) addPassword(ctx context.Context, objectID string, endDate time.Time) (string, string, error) {
+	keyID, err := uuid.GenerateUUID()
+	if err != nil {
+		return "", "", err
+	}
+	password, err := uuid.GenerateUUID()
+	if err != nil {
+		return "", "", err
+	}
+
+	path := "applications/" + url.PathEscape(objectID) + "/passwordCredentials"
+	var credentials struct {
+		Value []*passwordCredential `json:"value"`
+	}
+	if err := c.graph(ctx, http.MethodGet, path, nil, &credentials); err != nil {
+		return "", "", errwrap.Wrapf(fmt.Sprintf("error reading password credentials of application %q: {{err}}", objectID), err)
+	}
+
+	credentials.Value = append(credentials.Value, &passwordCredential{
+		KeyID:     keyID,
+		Value:     password,
+		StartDate: time.Now().UTC().Format(time.RFC3339),
+		EndDate:   endDate.UTC().Format(time.RFC3339),
+	})
+	if err := c.graph(ctx, http.MethodPatch, path, &credentials, nil); err != nil {
+		return "", "", errwrap.Wrapf(fmt.Sprintf("error adding password credential to application %q: {{err}}", objectID), err)
+	}
+
+	return keyID, password, nil
+}
+
+// removePassword removes a password credential of the application.
+func (c *azureClient) removePassword(ctx context.Context, objectID, keyID string) error {
+	path := "applications/" + url.PathEscape(objectID) + "/passwordCredentials"
+	var credentials struct {
+		Value []*passwordCredential `json:"value"`
+	}
+	if err := c.graph(ctx, http.MethodGet, path, nil, &credentials); err != nil {
+		return errwrap.Wrapf(fmt.Sprintf("error reading password credentials of application %q: {{err}}", objectID), err)
+	}
+
+	remaining := credentials.Value[:0]
+	for _, credential := range credentials.Value {
+		if credential.KeyID != keyID {
+			remaining = append(remaining, credential)
+		}
+	}
+	if len(remaining) == len(credentials.Value) {
+		return nil
+	}
+	credentials.Value = remaining
+
+	if err := c.graph(ctx, http.MethodPatch, path, &credentials, nil); err != nil {
+		return errwrap.Wrapf(fmt.Sprintf("error removing password credential of application %q: {{err}}", objectID), err)
+	}
+	return nil
+}
+
+// createRoleAssignment assigns the role to the principal on the scope, and
+// returns the ID of the role assignment. Assigning a role to a new service
+// principal is retried until the principal has replicated.
+func (c *azureClient) createRoleAssignment(ctx context.Context, scope, roleID, principalID string) (string, error) {
+	name, err := uuid.GenerateUUID()
+	if err != nil {
+		return "", err
+	}
+
+	body := map[string]interface{}{
+		"properties": map[string]string{
+			"roleDefinitionId": roleID,
+			"principalId":      principalID,
+		},
+	}
+	var assignment struct {
+		ID string `json:"id"`
+	}
+	path := scope + "/providers/Microsoft.Authorization/roleAssignments/" + name
+	query := url.Values{"api-version": []string{roleAssignmentAPIVersion}}
+
+	for attempt := 1; ; attempt++ {
+		err = c.arm(ctx, http.MethodPut, path, query, body, &assignment)
+		apiErr, ok := err.(*azureError)
+		if !ok || apiErr.Code != "PrincipalNotFound" || attempt == roleAssignmentAttempts {
+			break
+		}
+
+		select {
+		case <-time.After(roleAssignmentRetryInterval):
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+	if err != nil {
+		return "", errwrap.Wrapf(fmt.Sprintf("error assigning role %q on scope %q: {{err}}", roleID, scope), err)
+	}
+	return assignment.ID, nil
+}
+
+// deleteRoleAssignment deletes a role assignment. Role assignments which no
+// longer exist are considered deleted.
+func (c *azureClient) deleteRoleAssignment(ctx context.Context, id string) error {
+	query := url.Values{"api-version": []string{roleAssignmentAPIVersion}}
+	err := c.arm(ctx, http.MethodDelete, id, query, nil, nil)
+	if err != nil && !isNotFound(err) {
+		return errwrap.Wrapf(fmt.Sprintf("error deleting role assignment %q: {{err}}", id), err)
+	}
+	return nil
+}
+
+// getRoleDefinition reads the role definition with the given ID.
+func (c *azureClient) getRoleDefinition(ctx context.Context, id string) (*roleDefinition, error) {
+	query := url.Values{"api-version": []string{roleDefinitionAPIVersion}}
+	definition := &roleDefinition{}
+	if err := c.arm(ctx, http.MethodGet, id, query, nil, definition); err != nil {
+		return nil, errwrap.Wrapf(fmt.Sprintf("error reading role definition %q: {{err}}", id), err)
+	}
+	return definition, nil
+}
+
+// findRoleDefinitions returns the role definitions with the given name which
+// can be assigned on the scope.
+func (c *azureClient) findRoleDefinitions(ctx context.Context, scope, name string) ([]*roleDefinition, error) {
+	query := url.Values{
+		"api-version": []string{roleDefinitionAPIVersion},
+		"$filter":     []string{fmt.Sprintf("roleName eq '%s'", name)},
+	}
+	var definitions struct {
+		Value []*roleDefinition `json:"value"`
+	}
+	if err := c.arm(ctx, http.MethodGet, scope+"/providers/Microsoft.Authorization/roleDefinitions", query, nil, &definitions); err != nil {
+		return nil, errwrap.Wrapf(fmt.Sprintf("error listing role definitions of scope %q: {{err}}", scope), err)
+	}
+	return definitions.Value, nil
+}
@@ -0,0 +1,38 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/vault/helper/consts"
+	"github.com/hashicorp/vault/logical"
+	"github.com/mitchellh/mapstructure"
+)
+
+func (b *backend) walRollback(ctx context.Context, req *logical.Request, kind string, data interface{}) error {
+	if !b.System().LocalMount() && b.System().ReplicationState().HasState(consts.ReplicationPerformancePrimary) {
+		return nil
+	}
+
+	switch kind {
+	case walTypeApplication:
+		return b.applicationRollback(ctx, req, data)
+	default:
+		return fmt.Errorf("unknown type to rollback")
+	}
+}
+
+// applicationRollback deletes an application, and its service principal,
+// whose credentials could not be generated.
+func (b *backend) applicationRollback(ctx context.Context, req *logical.Request, data interface{}) error {
+	var entry walApplication
+	if err := mapstructure.Decode(data, &entry); err != nil {
+		return err
+	}
+
+	client, err := b.getClient(ctx, req.Storage)
+	if err != nil {
+		return err
+	}
+	return client.deleteApplication(ctx, entry.ObjectID)
+}
@@ -0,0 +1,93 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+const SecretServicePrincipalType = "service_principal"
+
+func secretServicePrincipal(b *backend) *framework.Secret {
+	return &framework.Secret{
+		Type: SecretServicePrincipalType,
+		Fields: map[string]*framework.FieldSchema{
+			"client_id": {
+				Type:        framework.TypeString,
+				Description: "Client ID of the service principal",
+			},
+			"client_secret": {
+				Type:        framework.TypeString,
+				Description: "Client secret of the service principal",
+			},
+		},
+
+		Renew:  b.secretServicePrincipalRenew,
+		Revoke: b.secretServicePrincipalRevoke,
+	}
+}
+
+func (b *backend) secretServicePrincipalRenew(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	roleRaw, ok := req.Secret.InternalData["role"]
+	if !ok {
+		return nil, fmt.Errorf("secret is missing role internal data")
+	}
+
+	r, err := getRole(ctx, req.Storage, roleRaw.(string))
+	if err != nil {
+		return nil, err
+	}
+	if r == nil {
+		return logical.ErrorResponse(fmt.Sprintf("role %q not found", roleRaw)), nil
+	}
+
+	return framework.LeaseExtend(r.TTL, r.MaxTTL, b.System())(ctx, req, d)
+}
+
+func (b *backend) secretServicePrincipalRevoke(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	objectIDRaw, ok := req.Secret.InternalData["app_object_id"]
+	if !ok {
+		return nil, fmt.Errorf("secret is missing app_object_id internal data")
+	}
+	objectID, ok := objectIDRaw.(string)
+	if !ok {
+		return nil, fmt.Errorf("secret has app_object_id but value could not be understood")
+	}
+
+	client, err := b.getClient(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	// Credentials of existing applications only have a password credential
+	if keyID, ok := req.Secret.InternalData["key_id"]; ok {
+		if err := client.removePassword(ctx, objectID, keyID.(string)); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}
+
+	// The internal data may have been decoded from JSON
+	var assignmentIDs []string
+	switch raw := req.Secret.InternalData["role_assignment_ids"].(type) {
+	case []string:
+		assignmentIDs = raw
+	case []interface{}:
+		for _, id := range raw {
+			assignmentIDs = append(assignmentIDs, id.(string))
+		}
+	}
+
+	for _, id := range assignmentIDs {
+		if err := client.deleteRoleAssignment(ctx, id); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := client.deleteApplication(ctx, objectID); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
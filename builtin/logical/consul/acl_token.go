@@ -0,0 +1,141 @@
+package consul
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/vault/helper/jsonutil"
+)
+
+// aclToken is a token of the ACL system of Consul 1.4 and above, which is not
+// supported by the vendored Consul API client. Service identities require
+// Consul 1.5, node identities Consul 1.8 and namespaces Consul Enterprise 1.7.
+type aclToken struct {
+	AccessorID        string                `json:"AccessorID,omitempty"`
+	SecretID          string                `json:"SecretID,omitempty"`
+	Description       string                `json:"Description,omitempty"`
+	Policies          []*aclPolicyLink      `json:"Policies,omitempty"`
+	ServiceIdentities []*aclServiceIdentity `json:"ServiceIdentities,omitempty"`
+	NodeIdentities    []*aclNodeIdentity    `json:"NodeIdentities,omitempty"`
+	Namespace         string                `json:"Namespace,omitempty"`
+}
+
+type aclPolicyLink struct {
+	Name string `json:"Name"`
+}
+
+type aclServiceIdentity struct {
+	ServiceName string   `json:"ServiceName"`
+	Datacenters []string `json:"Datacenters,omitempty"`
+}
+
+type aclNodeIdentity struct {
+	NodeName   string `json:"NodeName"`
+	Datacenter string `json:"Datacenter"`
+}
+
+// createACLToken creates the token with the ACL token API.
+func createACLToken(ctx context.Context, conf *accessConfig, token *aclToken) (*aclToken, error) {
+	created := &aclToken{}
+	if err := aclTokenRequest(ctx, conf, http.MethodPut, "/v1/acl/token", token.Namespace, token, created); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// deleteACLToken deletes the token with the given accessor ID with the ACL
+// token API.
+func deleteACLToken(ctx context.Context, conf *accessConfig, accessorID, namespace string) error {
+	return aclTokenRequest(ctx, conf, http.MethodDelete, "/v1/acl/token/"+url.PathEscape(accessorID), namespace, nil, nil)
+}
+
+func aclTokenRequest(ctx context.Context, conf *accessConfig, method, path, namespace string, in, out interface{}) error {
+	var body []byte
+	if in != nil {
+		var err error
+		body, err = json.Marshal(in)
+		if err != nil {
+			return err
+		}
+	}
+
+	scheme := conf.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	u := &url.URL{
+		Scheme: scheme,
+		Host:   conf.Address,
+		Path:   path,
+	}
+	if namespace != "" {
+		u.RawQuery = url.Values{"ns": []string{namespace}}.Encode()
+	}
+
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	if conf.Token != "" {
+		req.Header.Set("X-Consul-Token", conf.Token)
+	}
+
+	resp, err := cleanhttp.DefaultClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		message, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("Unexpected response code: %d (%s)", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+
+	if out == nil {
+		io.Copy(ioutil.Discard, resp.Body)
+		return nil
+	}
+	return jsonutil.DecodeJSONFromReader(resp.Body, out)
+}
+
+// parseServiceIdentity parses a service identity of the form
+// "<service name>[:<datacenter>,<datacenter>...]".
+func parseServiceIdentity(raw string) (*aclServiceIdentity, error) {
+	parts := strings.SplitN(raw, ":", 2)
+	identity := &aclServiceIdentity{
+		ServiceName: strings.TrimSpace(parts[0]),
+	}
+	if identity.ServiceName == "" {
+		return nil, fmt.Errorf("invalid service identity %q: service name is required", raw)
+	}
+	if len(parts) == 2 {
+		for _, dc := range strings.Split(parts[1], ",") {
+			if dc = strings.TrimSpace(dc); dc != "" {
+				identity.Datacenters = append(identity.Datacenters, dc)
+			}
+		}
+	}
+	return identity, nil
+}
+
+// parseNodeIdentity parses a node identity of the form
+// "<node name>:<datacenter>".
+func parseNodeIdentity(raw string) (*aclNodeIdentity, error) {
+	parts := strings.SplitN(raw, ":", 2)
+	if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" || strings.TrimSpace(parts[1]) == "" {
+		return nil, fmt.Errorf("invalid node identity %q: expected <node name>:<datacenter>", raw)
+	}
+	return &aclNodeIdentity{
+		NodeName:   strings.TrimSpace(parts[0]),
+		Datacenter: strings.TrimSpace(parts[1]),
+	}, nil
+}
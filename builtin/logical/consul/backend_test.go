@@ -3,10 +3,14 @@ package consul
 import (
 	"context"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"log"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"reflect"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -315,6 +319,161 @@ func TestBackend_role_lease(t *testing.T) {
 	})
 }
 
+// fakeACLTokens is a fake of the ACL token API of Consul
+type fakeACLTokens struct {
+	sync.Mutex
+	tokens map[string]*aclToken
+	count  int
+}
+
+func (f *fakeACLTokens) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	if r.Header.Get("X-Consul-Token") != "root" {
+		http.Error(w, "ACL not found", http.StatusForbidden)
+		return
+	}
+
+	switch {
+	case r.URL.Path == "/v1/acl/token" && r.Method == http.MethodPut:
+		token := &aclToken{}
+		if err := json.NewDecoder(r.Body).Decode(token); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if token.Namespace != r.URL.Query().Get("ns") {
+			http.Error(w, "namespace mismatch", http.StatusBadRequest)
+			return
+		}
+		f.count++
+		token.AccessorID = fmt.Sprintf("accessor-%d", f.count)
+		token.SecretID = fmt.Sprintf("secret-%d", f.count)
+		f.tokens[token.AccessorID] = token
+		json.NewEncoder(w).Encode(token)
+
+	case strings.HasPrefix(r.URL.Path, "/v1/acl/token/") && r.Method == http.MethodDelete:
+		accessorID := strings.TrimPrefix(r.URL.Path, "/v1/acl/token/")
+		token, ok := f.tokens[accessorID]
+		if !ok || token.Namespace != r.URL.Query().Get("ns") {
+			http.Error(w, "ACL not found", http.StatusNotFound)
+			return
+		}
+		delete(f.tokens, accessorID)
+		w.Write([]byte("true"))
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestBackend_tokenAPI(t *testing.T) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	b, err := Factory(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	consul := &fakeACLTokens{tokens: make(map[string]*aclToken)}
+	server := httptest.NewServer(consul)
+	defer server.Close()
+
+	req := &logical.Request{
+		Storage:   config.StorageView,
+		Operation: logical.UpdateOperation,
+		Path:      "config/access",
+		Data: map[string]interface{}{
+			"address": strings.TrimPrefix(server.URL, "http://"),
+			"token":   "root",
+		},
+	}
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp:%#v\n err:%v", resp, err)
+	}
+
+	// Invalid identities and combinations with the legacy fields are rejected
+	req.Path = "roles/test"
+	for _, data := range []map[string]interface{}{
+		{"node_identities": []string{"node-1"}},
+		{"service_identities": []string{":dc1"}},
+		{"policies": "web", "token_type": "management"},
+		{"policies": "web", "policy": base64.StdEncoding.EncodeToString([]byte(testPolicy))},
+		{"consul_namespace": "team"},
+	} {
+		req.Data = data
+		resp, err = b.HandleRequest(context.Background(), req)
+		if err != nil || resp == nil || !resp.IsError() {
+			t.Fatalf("%#v: expected an error response. resp:%#v\n err:%v", data, resp, err)
+		}
+	}
+
+	req.Data = map[string]interface{}{
+		"policies":           "web,api",
+		"service_identities": []string{"web:dc1,dc2", "api"},
+		"node_identities":    []string{"node-1:dc1"},
+		"consul_namespace":   "team",
+		"lease":              "1h",
+	}
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp:%#v\n err:%v", resp, err)
+	}
+
+	req.Operation = logical.ReadOperation
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: resp:%#v\n err:%v", resp, err)
+	}
+	expected := map[string]interface{}{
+		"lease":              int64(3600),
+		"token_type":         "client",
+		"policies":           []string{"web", "api"},
+		"service_identities": []string{"web:dc1,dc2", "api"},
+		"node_identities":    []string{"node-1:dc1"},
+		"consul_namespace":   "team",
+	}
+	if !reflect.DeepEqual(resp.Data, expected) {
+		t.Fatalf("bad: expected:%#v\nactual:%#v\n", expected, resp.Data)
+	}
+
+	req.Path = "creds/test"
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: resp:%#v\n err:%v", resp, err)
+	}
+	if resp.Data["token"] != "secret-1" || resp.Data["accessor"] != "accessor-1" || resp.Data["consul_namespace"] != "team" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	token := consul.tokens["accessor-1"]
+	expectedToken := &aclToken{
+		AccessorID:  "accessor-1",
+		SecretID:    "secret-1",
+		Description: token.Description,
+		Policies:    []*aclPolicyLink{{Name: "web"}, {Name: "api"}},
+		ServiceIdentities: []*aclServiceIdentity{
+			{ServiceName: "web", Datacenters: []string{"dc1", "dc2"}},
+			{ServiceName: "api"},
+		},
+		NodeIdentities: []*aclNodeIdentity{{NodeName: "node-1", Datacenter: "dc1"}},
+		Namespace:      "team",
+	}
+	if !reflect.DeepEqual(token, expectedToken) {
+		t.Fatalf("bad: token: %#v", token)
+	}
+
+	req.Operation = logical.RevokeOperation
+	req.Secret = resp.Secret
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp != nil {
+		t.Fatalf("bad: resp:%#v\n err:%v", resp, err)
+	}
+	if len(consul.tokens) != 0 {
+		t.Fatalf("bad: tokens: %#v", consul.tokens)
+	}
+}
+
 func testAccStepConfig(
 	t *testing.T, config map[string]interface{}) logicaltest.TestStep {
 	return logicaltest.TestStep{
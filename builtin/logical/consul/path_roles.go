@@ -32,7 +32,33 @@ func pathRoles() *framework.Path {
 			"policy": &framework.FieldSchema{
 				Type: framework.TypeString,
 				Description: `Policy document, base64 encoded. Required
-for 'client' tokens.`,
+for 'client' tokens of the legacy ACL system.`,
+			},
+
+			"policies": &framework.FieldSchema{
+				Type: framework.TypeCommaStringSlice,
+				Description: `Names of the Consul ACL policies attached
+to the tokens. Requires Consul 1.4 or above.`,
+			},
+
+			"service_identities": &framework.FieldSchema{
+				Type: framework.TypeStringSlice,
+				Description: `Service identities attached to the tokens,
+each of the form "<service>[:<datacenter>,...]".
+Requires Consul 1.5 or above.`,
+			},
+
+			"node_identities": &framework.FieldSchema{
+				Type: framework.TypeStringSlice,
+				Description: `Node identities attached to the tokens,
+each of the form "<node>:<datacenter>". Requires
+Consul 1.8 or above.`,
+			},
+
+			"consul_namespace": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `Consul namespace of the tokens. Requires
+Consul Enterprise 1.7 or above.`,
 			},
 
 			"token_type": &framework.FieldSchema{
@@ -97,6 +123,18 @@ func pathRolesRead(ctx context.Context, req *logical.Request, d *framework.Field
 	if result.Policy != "" {
 		resp.Data["policy"] = base64.StdEncoding.EncodeToString([]byte(result.Policy))
 	}
+	if len(result.Policies) != 0 {
+		resp.Data["policies"] = result.Policies
+	}
+	if len(result.ServiceIdentities) != 0 {
+		resp.Data["service_identities"] = result.ServiceIdentities
+	}
+	if len(result.NodeIdentities) != 0 {
+		resp.Data["node_identities"] = result.NodeIdentities
+	}
+	if result.ConsulNamespace != "" {
+		resp.Data["consul_namespace"] = result.ConsulNamespace
+	}
 	return resp, nil
 }
 
@@ -113,31 +151,64 @@ func pathRolesWrite(ctx context.Context, req *logical.Request, d *framework.Fiel
 
 	name := d.Get("name").(string)
 	policy := d.Get("policy").(string)
-	var policyRaw []byte
-	var err error
-	if tokenType != "management" {
+	policies := d.Get("policies").([]string)
+	serviceIdentities := d.Get("service_identities").([]string)
+	nodeIdentities := d.Get("node_identities").([]string)
+	namespace := d.Get("consul_namespace").(string)
+
+	for _, raw := range serviceIdentities {
+		if _, err := parseServiceIdentity(raw); err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+	}
+	for _, raw := range nodeIdentities {
+		if _, err := parseNodeIdentity(raw); err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+	}
+
+	// Policies, identities and namespaces are only supported by the ACL
+	// token API, which does not use the legacy token types and rules
+	role := roleConfig{
+		Policies:          policies,
+		ServiceIdentities: serviceIdentities,
+		NodeIdentities:    nodeIdentities,
+		ConsulNamespace:   namespace,
+		TokenType:         tokenType,
+	}
+	tokenAPI := role.usesTokenAPI()
+	if tokenAPI && tokenType != "client" {
+		return logical.ErrorResponse(
+			"management tokens cannot have policies, service_identities, node_identities or consul_namespace"), nil
+	}
+	if tokenAPI && policy != "" {
+		return logical.ErrorResponse(
+			"policy cannot be used with policies, service_identities, node_identities or consul_namespace"), nil
+	}
+	if tokenAPI && len(policies) == 0 && len(serviceIdentities) == 0 && len(nodeIdentities) == 0 {
+		return logical.ErrorResponse(
+			"policies, service_identities or node_identities are required with consul_namespace"), nil
+	}
+
+	if tokenType != "management" && !tokenAPI {
 		if policy == "" {
 			return logical.ErrorResponse(
 				"policy cannot be empty when not using management tokens"), nil
 		}
-		policyRaw, err = base64.StdEncoding.DecodeString(d.Get("policy").(string))
+		policyRaw, err := base64.StdEncoding.DecodeString(d.Get("policy").(string))
 		if err != nil {
 			return logical.ErrorResponse(fmt.Sprintf(
 				"Error decoding policy base64: %s", err)), nil
 		}
+		role.Policy = string(policyRaw)
 	}
 
-	var lease time.Duration
 	leaseParamRaw, ok := d.GetOk("lease")
 	if ok {
-		lease = time.Second * time.Duration(leaseParamRaw.(int))
+		role.Lease = time.Second * time.Duration(leaseParamRaw.(int))
 	}
 
-	entry, err := logical.StorageEntryJSON("policy/"+name, roleConfig{
-		Policy:    string(policyRaw),
-		Lease:     lease,
-		TokenType: tokenType,
-	})
+	entry, err := logical.StorageEntryJSON("policy/"+name, role)
 	if err != nil {
 		return nil, err
 	}
@@ -158,7 +229,17 @@ func pathRolesDelete(ctx context.Context, req *logical.Request, d *framework.Fie
 }
 
 type roleConfig struct {
-	Policy    string        `json:"policy"`
-	Lease     time.Duration `json:"lease"`
-	TokenType string        `json:"token_type"`
+	Policy            string        `json:"policy"`
+	Policies          []string      `json:"policies"`
+	ServiceIdentities []string      `json:"service_identities"`
+	NodeIdentities    []string      `json:"node_identities"`
+	ConsulNamespace   string        `json:"consul_namespace"`
+	Lease             time.Duration `json:"lease"`
+	TokenType         string        `json:"token_type"`
+}
+
+// usesTokenAPI returns whether the tokens of the role are created with the
+// ACL token API rather than with the legacy ACL API.
+func (r *roleConfig) usesTokenAPI() bool {
+	return len(r.Policies) != 0 || len(r.ServiceIdentities) != 0 || len(r.NodeIdentities) != 0 || r.ConsulNamespace != ""
 }
@@ -46,6 +46,13 @@ func (b *backend) pathTokenRead(ctx context.Context, req *logical.Request, d *fr
 		result.TokenType = "client"
 	}
 
+	// Generate a name for the token
+	tokenName := fmt.Sprintf("Vault %s %s %d", role, req.DisplayName, time.Now().UnixNano())
+
+	if result.usesTokenAPI() {
+		return b.createACLToken(ctx, req, role, tokenName, &result)
+	}
+
 	// Get the consul client
 	c, userErr, intErr := client(ctx, req.Storage)
 	if intErr != nil {
@@ -55,9 +62,6 @@ func (b *backend) pathTokenRead(ctx context.Context, req *logical.Request, d *fr
 		return logical.ErrorResponse(userErr.Error()), nil
 	}
 
-	// Generate a name for the token
-	tokenName := fmt.Sprintf("Vault %s %s %d", role, req.DisplayName, time.Now().UnixNano())
-
 	// Create it
 	token, _, err := c.ACL().Create(&api.ACLEntry{
 		Name:  tokenName,
@@ -79,3 +83,59 @@ func (b *backend) pathTokenRead(ctx context.Context, req *logical.Request, d *fr
 
 	return s, nil
 }
+
+// createACLToken creates a token with the policies, identities and namespace
+// of the role with the ACL token API.
+func (b *backend) createACLToken(ctx context.Context, req *logical.Request, role, tokenName string, result *roleConfig) (*logical.Response, error) {
+	conf, userErr, intErr := readConfigAccess(ctx, req.Storage)
+	if intErr != nil {
+		return nil, intErr
+	}
+	if userErr != nil {
+		return logical.ErrorResponse(userErr.Error()), nil
+	}
+
+	token := &aclToken{
+		Description: tokenName,
+		Namespace:   result.ConsulNamespace,
+	}
+	for _, name := range result.Policies {
+		token.Policies = append(token.Policies, &aclPolicyLink{Name: name})
+	}
+	for _, raw := range result.ServiceIdentities {
+		identity, err := parseServiceIdentity(raw)
+		if err != nil {
+			return nil, err
+		}
+		token.ServiceIdentities = append(token.ServiceIdentities, identity)
+	}
+	for _, raw := range result.NodeIdentities {
+		identity, err := parseNodeIdentity(raw)
+		if err != nil {
+			return nil, err
+		}
+		token.NodeIdentities = append(token.NodeIdentities, identity)
+	}
+
+	created, err := createACLToken(ctx, conf, token)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	// Use the helper to create the secret
+	s := b.Secret(SecretTokenType).Response(map[string]interface{}{
+		"token":    created.SecretID,
+		"accessor": created.AccessorID,
+	}, map[string]interface{}{
+		"token":            created.SecretID,
+		"accessor":         created.AccessorID,
+		"consul_namespace": result.ConsulNamespace,
+		"role":             role,
+	})
+	if result.ConsulNamespace != "" {
+		s.Data["consul_namespace"] = result.ConsulNamespace
+	}
+	s.Secret.TTL = result.Lease
+
+	return s, nil
+}
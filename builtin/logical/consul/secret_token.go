@@ -20,6 +20,10 @@ func secretToken(b *backend) *framework.Secret {
 				Type:        framework.TypeString,
 				Description: "Request token",
 			},
+			"accessor": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Accessor ID of the token",
+			},
 		},
 
 		Renew:  b.secretTokenRenew,
@@ -55,6 +59,23 @@ func (b *backend) secretTokenRenew(ctx context.Context, req *logical.Request, d
 }
 
 func secretTokenRevoke(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	// Tokens created with the ACL token API are deleted by accessor
+	if accessorRaw, ok := req.Secret.InternalData["accessor"]; ok {
+		conf, userErr, intErr := readConfigAccess(ctx, req.Storage)
+		if intErr != nil {
+			return nil, intErr
+		}
+		if userErr != nil {
+			return nil, userErr
+		}
+
+		namespace, _ := req.Secret.InternalData["consul_namespace"].(string)
+		if err := deleteACLToken(ctx, conf, accessorRaw.(string), namespace); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}
+
 	c, userErr, intErr := client(ctx, req.Storage)
 	if intErr != nil {
 		return nil, intErr
@@ -10,6 +10,7 @@ import (
 	log "github.com/mgutz/logxi/v1"
 
 	"github.com/hashicorp/vault/builtin/logical/database/dbplugin"
+	"github.com/hashicorp/vault/helper/locksutil"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
 )
@@ -32,6 +33,7 @@ func Backend(conf *logical.BackendConfig) *databaseBackend {
 		PathsSpecial: &logical.Paths{
 			SealWrapStorage: []string{
 				"config/*",
+				"static-role/*",
 			},
 		},
 
@@ -41,19 +43,26 @@ func Backend(conf *logical.BackendConfig) *databaseBackend {
 			pathListRoles(&b),
 			pathRoles(&b),
 			pathCredsCreate(&b),
+			pathListStaticRoles(&b),
+			pathStaticRoles(&b),
+			pathStaticCreds(&b),
+			pathRotateRole(&b),
+			pathRotateRootCredentials(&b),
 			pathResetConnection(&b),
 		},
 
 		Secrets: []*framework.Secret{
 			secretCreds(&b),
 		},
-		Clean:       b.closeAllDBs,
-		Invalidate:  b.invalidate,
-		BackendType: logical.TypeLogical,
+		Clean:        b.closeAllDBs,
+		Invalidate:   b.invalidate,
+		PeriodicFunc: b.periodicFunc,
+		BackendType:  logical.TypeLogical,
 	}
 
 	b.logger = conf.Logger
 	b.connections = make(map[string]dbplugin.Database)
+	b.roleLocks = locksutil.CreateLocks()
 	return &b
 }
 
@@ -61,6 +70,10 @@ type databaseBackend struct {
 	connections map[string]dbplugin.Database
 	logger      log.Logger
 
+	// roleLocks serializes the updates and password rotations of each
+	// static role
+	roleLocks []*locksutil.LockEntry
+
 	*framework.Backend
 	sync.RWMutex
 }
@@ -215,4 +228,9 @@ cassandra, mssql, mysql, postgres
 
 After mounting this backend, configure it using the endpoints within
 the "database/config/" path.
+
+Dynamic roles, configured under "database/roles/", generate leased
+credentials on demand. Static roles, configured under "database/static-roles/",
+manage the password of an existing database user, which is rotated
+periodically and can be read from "database/static-creds/".
 `
@@ -17,6 +17,11 @@ It has these top-level messages:
 	CreateUserResponse
 	TypeResponse
 	Empty
+	SetCredentialsRequest
+	StaticUserConfig
+	SetCredentialsResponse
+	RotateRootCredentialsRequest
+	RotateRootCredentialsResponse
 */
 package dbplugin
 
@@ -158,6 +163,7 @@ type Statements struct {
 	RevocationStatements string `protobuf:"bytes,2,opt,name=revocation_statements,json=revocationStatements" json:"revocation_statements,omitempty"`
 	RollbackStatements   string `protobuf:"bytes,3,opt,name=rollback_statements,json=rollbackStatements" json:"rollback_statements,omitempty"`
 	RenewStatements      string `protobuf:"bytes,4,opt,name=renew_statements,json=renewStatements" json:"renew_statements,omitempty"`
+	RotationStatements   string `protobuf:"bytes,5,opt,name=rotation_statements,json=rotationStatements" json:"rotation_statements,omitempty"`
 }
 
 func (m *Statements) Reset()                    { *m = Statements{} }
@@ -193,9 +199,17 @@ func (m *Statements) GetRenewStatements() string {
 	return ""
 }
 
+func (m *Statements) GetRotationStatements() string {
+	if m != nil {
+		return m.RotationStatements
+	}
+	return ""
+}
+
 type UsernameConfig struct {
 	DisplayName string `protobuf:"bytes,1,opt,name=DisplayName" json:"DisplayName,omitempty"`
 	RoleName    string `protobuf:"bytes,2,opt,name=RoleName" json:"RoleName,omitempty"`
+	Template    string `protobuf:"bytes,3,opt,name=Template" json:"Template,omitempty"`
 }
 
 func (m *UsernameConfig) Reset()                    { *m = UsernameConfig{} }
@@ -217,6 +231,13 @@ func (m *UsernameConfig) GetRoleName() string {
 	return ""
 }
 
+func (m *UsernameConfig) GetTemplate() string {
+	if m != nil {
+		return m.Template
+	}
+	return ""
+}
+
 type CreateUserResponse struct {
 	Username string `protobuf:"bytes,1,opt,name=username" json:"username,omitempty"`
 	Password string `protobuf:"bytes,2,opt,name=password" json:"password,omitempty"`
@@ -265,6 +286,110 @@ func (m *Empty) String() string            { return proto.CompactTextString(m) }
 func (*Empty) ProtoMessage()               {}
 func (*Empty) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{8} }
 
+type SetCredentialsRequest struct {
+	Statements       *Statements       `protobuf:"bytes,1,opt,name=statements" json:"statements,omitempty"`
+	StaticUserConfig *StaticUserConfig `protobuf:"bytes,2,opt,name=static_user_config,json=staticUserConfig" json:"static_user_config,omitempty"`
+}
+
+func (m *SetCredentialsRequest) Reset()                    { *m = SetCredentialsRequest{} }
+func (m *SetCredentialsRequest) String() string            { return proto.CompactTextString(m) }
+func (*SetCredentialsRequest) ProtoMessage()               {}
+func (*SetCredentialsRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{9} }
+
+func (m *SetCredentialsRequest) GetStatements() *Statements {
+	if m != nil {
+		return m.Statements
+	}
+	return nil
+}
+
+func (m *SetCredentialsRequest) GetStaticUserConfig() *StaticUserConfig {
+	if m != nil {
+		return m.StaticUserConfig
+	}
+	return nil
+}
+
+type StaticUserConfig struct {
+	Username string `protobuf:"bytes,1,opt,name=username" json:"username,omitempty"`
+	Password string `protobuf:"bytes,2,opt,name=password" json:"password,omitempty"`
+}
+
+func (m *StaticUserConfig) Reset()                    { *m = StaticUserConfig{} }
+func (m *StaticUserConfig) String() string            { return proto.CompactTextString(m) }
+func (*StaticUserConfig) ProtoMessage()               {}
+func (*StaticUserConfig) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{10} }
+
+func (m *StaticUserConfig) GetUsername() string {
+	if m != nil {
+		return m.Username
+	}
+	return ""
+}
+
+func (m *StaticUserConfig) GetPassword() string {
+	if m != nil {
+		return m.Password
+	}
+	return ""
+}
+
+type SetCredentialsResponse struct {
+	Username string `protobuf:"bytes,1,opt,name=username" json:"username,omitempty"`
+	Password string `protobuf:"bytes,2,opt,name=password" json:"password,omitempty"`
+}
+
+func (m *SetCredentialsResponse) Reset()                    { *m = SetCredentialsResponse{} }
+func (m *SetCredentialsResponse) String() string            { return proto.CompactTextString(m) }
+func (*SetCredentialsResponse) ProtoMessage()               {}
+func (*SetCredentialsResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{11} }
+
+func (m *SetCredentialsResponse) GetUsername() string {
+	if m != nil {
+		return m.Username
+	}
+	return ""
+}
+
+func (m *SetCredentialsResponse) GetPassword() string {
+	if m != nil {
+		return m.Password
+	}
+	return ""
+}
+
+type RotateRootCredentialsRequest struct {
+	Statements []string `protobuf:"bytes,1,rep,name=statements" json:"statements,omitempty"`
+}
+
+func (m *RotateRootCredentialsRequest) Reset()                    { *m = RotateRootCredentialsRequest{} }
+func (m *RotateRootCredentialsRequest) String() string            { return proto.CompactTextString(m) }
+func (*RotateRootCredentialsRequest) ProtoMessage()               {}
+func (*RotateRootCredentialsRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{12} }
+
+func (m *RotateRootCredentialsRequest) GetStatements() []string {
+	if m != nil {
+		return m.Statements
+	}
+	return nil
+}
+
+type RotateRootCredentialsResponse struct {
+	Config []byte `protobuf:"bytes,1,opt,name=config,proto3" json:"config,omitempty"`
+}
+
+func (m *RotateRootCredentialsResponse) Reset()                    { *m = RotateRootCredentialsResponse{} }
+func (m *RotateRootCredentialsResponse) String() string            { return proto.CompactTextString(m) }
+func (*RotateRootCredentialsResponse) ProtoMessage()               {}
+func (*RotateRootCredentialsResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{13} }
+
+func (m *RotateRootCredentialsResponse) GetConfig() []byte {
+	if m != nil {
+		return m.Config
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*InitializeRequest)(nil), "dbplugin.InitializeRequest")
 	proto.RegisterType((*CreateUserRequest)(nil), "dbplugin.CreateUserRequest")
@@ -275,6 +400,11 @@ func init() {
 	proto.RegisterType((*CreateUserResponse)(nil), "dbplugin.CreateUserResponse")
 	proto.RegisterType((*TypeResponse)(nil), "dbplugin.TypeResponse")
 	proto.RegisterType((*Empty)(nil), "dbplugin.Empty")
+	proto.RegisterType((*SetCredentialsRequest)(nil), "dbplugin.SetCredentialsRequest")
+	proto.RegisterType((*StaticUserConfig)(nil), "dbplugin.StaticUserConfig")
+	proto.RegisterType((*SetCredentialsResponse)(nil), "dbplugin.SetCredentialsResponse")
+	proto.RegisterType((*RotateRootCredentialsRequest)(nil), "dbplugin.RotateRootCredentialsRequest")
+	proto.RegisterType((*RotateRootCredentialsResponse)(nil), "dbplugin.RotateRootCredentialsResponse")
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -294,6 +424,8 @@ type DatabaseClient interface {
 	RevokeUser(ctx context.Context, in *RevokeUserRequest, opts ...grpc.CallOption) (*Empty, error)
 	Initialize(ctx context.Context, in *InitializeRequest, opts ...grpc.CallOption) (*Empty, error)
 	Close(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Empty, error)
+	SetCredentials(ctx context.Context, in *SetCredentialsRequest, opts ...grpc.CallOption) (*SetCredentialsResponse, error)
+	RotateRootCredentials(ctx context.Context, in *RotateRootCredentialsRequest, opts ...grpc.CallOption) (*RotateRootCredentialsResponse, error)
 }
 
 type databaseClient struct {
@@ -358,6 +490,24 @@ func (c *databaseClient) Close(ctx context.Context, in *Empty, opts ...grpc.Call
 	return out, nil
 }
 
+func (c *databaseClient) SetCredentials(ctx context.Context, in *SetCredentialsRequest, opts ...grpc.CallOption) (*SetCredentialsResponse, error) {
+	out := new(SetCredentialsResponse)
+	err := grpc.Invoke(ctx, "/dbplugin.Database/SetCredentials", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseClient) RotateRootCredentials(ctx context.Context, in *RotateRootCredentialsRequest, opts ...grpc.CallOption) (*RotateRootCredentialsResponse, error) {
+	out := new(RotateRootCredentialsResponse)
+	err := grpc.Invoke(ctx, "/dbplugin.Database/RotateRootCredentials", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for Database service
 
 type DatabaseServer interface {
@@ -367,6 +517,8 @@ type DatabaseServer interface {
 	RevokeUser(context.Context, *RevokeUserRequest) (*Empty, error)
 	Initialize(context.Context, *InitializeRequest) (*Empty, error)
 	Close(context.Context, *Empty) (*Empty, error)
+	SetCredentials(context.Context, *SetCredentialsRequest) (*SetCredentialsResponse, error)
+	RotateRootCredentials(context.Context, *RotateRootCredentialsRequest) (*RotateRootCredentialsResponse, error)
 }
 
 func RegisterDatabaseServer(s *grpc.Server, srv DatabaseServer) {
@@ -481,6 +633,42 @@ func _Database_Close_Handler(srv interface{}, ctx context.Context, dec func(inte
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Database_SetCredentials_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetCredentialsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseServer).SetCredentials(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/dbplugin.Database/SetCredentials",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseServer).SetCredentials(ctx, req.(*SetCredentialsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Database_RotateRootCredentials_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RotateRootCredentialsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseServer).RotateRootCredentials(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/dbplugin.Database/RotateRootCredentials",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseServer).RotateRootCredentials(ctx, req.(*RotateRootCredentialsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _Database_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "dbplugin.Database",
 	HandlerType: (*DatabaseServer)(nil),
@@ -509,6 +697,14 @@ var _Database_serviceDesc = grpc.ServiceDesc{
 			MethodName: "Close",
 			Handler:    _Database_Close_Handler,
 		},
+		{
+			MethodName: "SetCredentials",
+			Handler:    _Database_SetCredentials_Handler,
+		},
+		{
+			MethodName: "RotateRootCredentials",
+			Handler:    _Database_RotateRootCredentials_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "builtin/logical/database/dbplugin/database.proto",
@@ -517,40 +713,49 @@ var _Database_serviceDesc = grpc.ServiceDesc{
 func init() { proto.RegisterFile("builtin/logical/database/dbplugin/database.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 548 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb4, 0x54, 0xcf, 0x6e, 0xd3, 0x4e,
-	0x10, 0x96, 0xdb, 0xb4, 0xbf, 0x64, 0x5a, 0x35, 0xc9, 0xfe, 0x4a, 0x15, 0x19, 0x24, 0x22, 0x9f,
-	0x5a, 0x21, 0xd9, 0xa8, 0xe5, 0x80, 0xb8, 0xa1, 0x14, 0x21, 0x24, 0x94, 0x83, 0x69, 0x25, 0x6e,
-	0xd1, 0xda, 0x99, 0x44, 0xab, 0x3a, 0xbb, 0xc6, 0xbb, 0x4e, 0x09, 0x4f, 0xc3, 0xe3, 0x70, 0xe2,
-	0x1d, 0x78, 0x13, 0xe4, 0x75, 0xd6, 0xbb, 0xf9, 0x73, 0xab, 0xb8, 0x79, 0x66, 0xbe, 0x6f, 0xf6,
-	0xf3, 0xb7, 0x33, 0x0b, 0xaf, 0x93, 0x92, 0x65, 0x8a, 0xf1, 0x28, 0x13, 0x73, 0x96, 0xd2, 0x2c,
-	0x9a, 0x52, 0x45, 0x13, 0x2a, 0x31, 0x9a, 0x26, 0x79, 0x56, 0xce, 0x19, 0x6f, 0x32, 0x61, 0x5e,
-	0x08, 0x25, 0x48, 0xdb, 0x14, 0xfc, 0x97, 0x73, 0x21, 0xe6, 0x19, 0x46, 0x3a, 0x9f, 0x94, 0xb3,
-	0x48, 0xb1, 0x05, 0x4a, 0x45, 0x17, 0x79, 0x0d, 0x0d, 0xbe, 0x42, 0xff, 0x13, 0x67, 0x8a, 0xd1,
-	0x8c, 0xfd, 0xc0, 0x18, 0xbf, 0x95, 0x28, 0x15, 0xb9, 0x80, 0xe3, 0x54, 0xf0, 0x19, 0x9b, 0x0f,
-	0xbc, 0xa1, 0x77, 0x79, 0x1a, 0xaf, 0x23, 0xf2, 0x0a, 0xfa, 0x4b, 0x2c, 0xd8, 0x6c, 0x35, 0x49,
-	0x05, 0xe7, 0x98, 0x2a, 0x26, 0xf8, 0xe0, 0x60, 0xe8, 0x5d, 0xb6, 0xe3, 0x5e, 0x5d, 0x18, 0x35,
-	0xf9, 0xe0, 0x97, 0x07, 0xfd, 0x51, 0x81, 0x54, 0xe1, 0xbd, 0xc4, 0xc2, 0xb4, 0x7e, 0x03, 0x20,
-	0x15, 0x55, 0xb8, 0x40, 0xae, 0xa4, 0x6e, 0x7f, 0x72, 0x7d, 0x1e, 0x1a, 0xbd, 0xe1, 0x97, 0xa6,
-	0x16, 0x3b, 0x38, 0xf2, 0x1e, 0xba, 0xa5, 0xc4, 0x82, 0xd3, 0x05, 0x4e, 0xd6, 0xca, 0x0e, 0x34,
-	0x75, 0x60, 0xa9, 0xf7, 0x6b, 0xc0, 0x48, 0xd7, 0xe3, 0xb3, 0x72, 0x23, 0x26, 0xef, 0x00, 0xf0,
-	0x7b, 0xce, 0x0a, 0xaa, 0x45, 0x1f, 0x6a, 0xb6, 0x1f, 0xd6, 0xf6, 0x84, 0xc6, 0x9e, 0xf0, 0xce,
-	0xd8, 0x13, 0x3b, 0xe8, 0xe0, 0xa7, 0x07, 0xbd, 0x18, 0x39, 0x3e, 0x3e, 0xfd, 0x4f, 0x7c, 0x68,
-	0x1b, 0x61, 0xfa, 0x17, 0x3a, 0x71, 0x13, 0x3f, 0x49, 0x22, 0x42, 0x3f, 0xc6, 0xa5, 0x78, 0xc0,
-	0x7f, 0x2a, 0x31, 0xf8, 0xed, 0x01, 0x58, 0x1a, 0x89, 0xe0, 0xff, 0xb4, 0xba, 0x62, 0x26, 0xf8,
-	0x64, 0xeb, 0xa4, 0x4e, 0x4c, 0x4c, 0xc9, 0x21, 0xdc, 0xc0, 0xb3, 0x02, 0x97, 0x22, 0xdd, 0xa1,
-	0xd4, 0x07, 0x9d, 0xdb, 0xe2, 0xe6, 0x29, 0x85, 0xc8, 0xb2, 0x84, 0xa6, 0x0f, 0x2e, 0xe5, 0xb0,
-	0x3e, 0xc5, 0x94, 0x1c, 0xc2, 0x15, 0xf4, 0x8a, 0xea, 0xba, 0x5c, 0x74, 0x4b, 0xa3, 0xbb, 0x3a,
-	0x6f, 0xa1, 0xc1, 0x18, 0xce, 0x36, 0x07, 0x87, 0x0c, 0xe1, 0xe4, 0x96, 0xc9, 0x3c, 0xa3, 0xab,
-	0x71, 0xe5, 0x40, 0xfd, 0x2f, 0x6e, 0xaa, 0x32, 0x28, 0x16, 0x19, 0x8e, 0x1d, 0x83, 0x4c, 0x1c,
-	0x7c, 0x06, 0xe2, 0x0e, 0xbd, 0xcc, 0x05, 0x97, 0xb8, 0x61, 0xa9, 0xb7, 0x75, 0xeb, 0x3e, 0xb4,
-	0x73, 0x2a, 0xe5, 0xa3, 0x28, 0xa6, 0xa6, 0x9b, 0x89, 0x83, 0x00, 0x4e, 0xef, 0x56, 0x39, 0x36,
-	0x7d, 0x08, 0xb4, 0xd4, 0x2a, 0x37, 0x3d, 0xf4, 0x77, 0xf0, 0x1f, 0x1c, 0x7d, 0x58, 0xe4, 0x6a,
-	0x75, 0xfd, 0xe7, 0x00, 0xda, 0xb7, 0xeb, 0x87, 0x80, 0x44, 0xd0, 0xaa, 0x98, 0xa4, 0x6b, 0xaf,
-	0x5b, 0xa3, 0xfc, 0x0b, 0x9b, 0xd8, 0x68, 0xfd, 0x11, 0xc0, 0x0a, 0x27, 0xcf, 0x2d, 0x6a, 0x67,
-	0x87, 0xfd, 0x17, 0xfb, 0x8b, 0xeb, 0x46, 0x6f, 0xa1, 0xd3, 0xec, 0x0a, 0xf1, 0x2d, 0x74, 0x7b,
-	0x81, 0xfc, 0x6d, 0x69, 0xd5, 0xfc, 0xdb, 0x19, 0x76, 0x25, 0xec, 0x4c, 0xf6, 0x5e, 0xae, 0x7d,
-	0xc7, 0x5c, 0xee, 0xce, 0xeb, 0xb6, 0xcb, 0xbd, 0x82, 0xa3, 0x51, 0x26, 0xe4, 0x1e, 0xb3, 0xb6,
-	0x13, 0xc9, 0xb1, 0x5e, 0xc3, 0x9b, 0xbf, 0x01, 0x00, 0x00, 0xff, 0xff, 0x8c, 0x55, 0x84, 0x56,
-	0x94, 0x05, 0x00, 0x00,
+	// 691 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb4, 0x55, 0xdd, 0x6e, 0xd3, 0x4a,
+	0x10, 0x96, 0xdb, 0xb4, 0x27, 0x99, 0x56, 0x6d, 0xb2, 0xa7, 0xad, 0x2a, 0x9f, 0x1e, 0x1a, 0xf9,
+	0x02, 0x5a, 0x21, 0xc5, 0xa8, 0x45, 0x02, 0x71, 0x81, 0x84, 0x52, 0xc4, 0x8f, 0x50, 0x85, 0xdc,
+	0x56, 0xe2, 0x2e, 0xda, 0x38, 0xd3, 0xb0, 0xd4, 0xd9, 0x35, 0xde, 0x4d, 0x4b, 0x78, 0x11, 0x6e,
+	0xfb, 0x38, 0xbc, 0x15, 0xc8, 0x3f, 0x6b, 0xaf, 0xed, 0x14, 0x24, 0x22, 0xee, 0x3c, 0x3f, 0xdf,
+	0xec, 0xb7, 0xdf, 0x8c, 0x67, 0xe1, 0xd1, 0x70, 0xca, 0x02, 0xc5, 0xb8, 0x1b, 0x88, 0x31, 0xf3,
+	0x69, 0xe0, 0x8e, 0xa8, 0xa2, 0x43, 0x2a, 0xd1, 0x1d, 0x0d, 0xc3, 0x60, 0x3a, 0x66, 0x3c, 0xf7,
+	0xf4, 0xc2, 0x48, 0x28, 0x41, 0x9a, 0x3a, 0x60, 0xef, 0x8f, 0x85, 0x18, 0x07, 0xe8, 0x26, 0xfe,
+	0xe1, 0xf4, 0xd2, 0x55, 0x6c, 0x82, 0x52, 0xd1, 0x49, 0x98, 0xa6, 0x3a, 0x1f, 0xa0, 0xf3, 0x86,
+	0x33, 0xc5, 0x68, 0xc0, 0xbe, 0xa2, 0x87, 0x9f, 0xa7, 0x28, 0x15, 0xd9, 0x81, 0x55, 0x5f, 0xf0,
+	0x4b, 0x36, 0xde, 0xb5, 0xba, 0xd6, 0xc1, 0xba, 0x97, 0x59, 0xe4, 0x21, 0x74, 0xae, 0x31, 0x62,
+	0x97, 0xb3, 0x81, 0x2f, 0x38, 0x47, 0x5f, 0x31, 0xc1, 0x77, 0x97, 0xba, 0xd6, 0x41, 0xd3, 0x6b,
+	0xa7, 0x81, 0x7e, 0xee, 0x77, 0xbe, 0x5b, 0xd0, 0xe9, 0x47, 0x48, 0x15, 0x5e, 0x48, 0x8c, 0x74,
+	0xe9, 0xc7, 0x00, 0x52, 0x51, 0x85, 0x13, 0xe4, 0x4a, 0x26, 0xe5, 0xd7, 0x8e, 0xb6, 0x7a, 0x9a,
+	0x6f, 0xef, 0x2c, 0x8f, 0x79, 0x46, 0x1e, 0x79, 0x01, 0x9b, 0x53, 0x89, 0x11, 0xa7, 0x13, 0x1c,
+	0x64, 0xcc, 0x96, 0x12, 0xe8, 0x6e, 0x01, 0xbd, 0xc8, 0x12, 0xfa, 0x49, 0xdc, 0xdb, 0x98, 0x96,
+	0x6c, 0xf2, 0x0c, 0x00, 0xbf, 0x84, 0x2c, 0xa2, 0x09, 0xe9, 0xe5, 0x04, 0x6d, 0xf7, 0x52, 0x79,
+	0x7a, 0x5a, 0x9e, 0xde, 0xb9, 0x96, 0xc7, 0x33, 0xb2, 0x9d, 0x5b, 0x0b, 0xda, 0x1e, 0x72, 0xbc,
+	0x59, 0xfc, 0x26, 0x36, 0x34, 0x35, 0xb1, 0xe4, 0x0a, 0x2d, 0x2f, 0xb7, 0x17, 0xa2, 0x88, 0xd0,
+	0xf1, 0xf0, 0x5a, 0x5c, 0xe1, 0x5f, 0xa5, 0xe8, 0xfc, 0xb0, 0x00, 0x0a, 0x18, 0x71, 0xe1, 0x5f,
+	0x3f, 0x6e, 0x31, 0x13, 0x7c, 0x50, 0x39, 0xa9, 0xe5, 0x11, 0x1d, 0x32, 0x00, 0xc7, 0xb0, 0x1d,
+	0xe1, 0xb5, 0xf0, 0x6b, 0x90, 0xf4, 0xa0, 0xad, 0x22, 0x58, 0x3e, 0x25, 0x12, 0x41, 0x30, 0xa4,
+	0xfe, 0x95, 0x09, 0x59, 0x4e, 0x4f, 0xd1, 0x21, 0x03, 0x70, 0x08, 0xed, 0x28, 0x6e, 0x97, 0x99,
+	0xdd, 0x48, 0xb2, 0x37, 0x13, 0x7f, 0xb5, 0xb6, 0xaa, 0xd1, 0x59, 0xd1, 0xb5, 0x55, 0x85, 0x8c,
+	0xf3, 0x09, 0x36, 0xca, 0x93, 0x46, 0xba, 0xb0, 0x76, 0xc2, 0x64, 0x18, 0xd0, 0xd9, 0x69, 0x2c,
+	0x59, 0x7a, 0x79, 0xd3, 0x15, 0x2b, 0xea, 0x89, 0x00, 0x4f, 0x0d, 0x45, 0xb5, 0x1d, 0xc7, 0xce,
+	0x71, 0x12, 0x06, 0x54, 0x61, 0x76, 0xa3, 0xdc, 0x76, 0xde, 0x01, 0x31, 0xff, 0x20, 0x19, 0x0a,
+	0x2e, 0xb1, 0xd4, 0x1f, 0xab, 0x32, 0x42, 0x36, 0x34, 0x43, 0x2a, 0xe5, 0x8d, 0x88, 0x46, 0xfa,
+	0x24, 0x6d, 0x3b, 0x0e, 0xac, 0x9f, 0xcf, 0x42, 0xcc, 0xeb, 0x10, 0x68, 0xa8, 0x59, 0xa8, 0x6b,
+	0x24, 0xdf, 0xce, 0x3f, 0xb0, 0xf2, 0x72, 0x12, 0xaa, 0x99, 0xf3, 0xcd, 0x82, 0xed, 0x33, 0x54,
+	0xfd, 0x08, 0x47, 0xc8, 0xe3, 0xf5, 0x20, 0x17, 0x1b, 0xaa, 0xd7, 0x40, 0x62, 0x8b, 0xf9, 0x83,
+	0x98, 0x6b, 0xf9, 0x27, 0xb6, 0xcb, 0x68, 0xe6, 0xc7, 0xd7, 0xcd, 0x7e, 0xe3, 0xb6, 0xac, 0x78,
+	0x9c, 0xb7, 0xd0, 0xae, 0x66, 0xfd, 0xb1, 0x24, 0xef, 0x61, 0xa7, 0x7a, 0xc9, 0x05, 0x45, 0x7e,
+	0x0e, 0x7b, 0x5e, 0x3c, 0x34, 0xe8, 0x09, 0x31, 0x4f, 0xbd, 0x7b, 0x15, 0xf5, 0x96, 0x0f, 0x5a,
+	0xa6, 0x4e, 0xce, 0x13, 0xf8, 0xff, 0x0e, 0x7c, 0x46, 0xec, 0x8e, 0xdd, 0x7c, 0x74, 0xdb, 0x80,
+	0xe6, 0x49, 0xf6, 0x0c, 0x10, 0x17, 0x1a, 0x71, 0xab, 0xc9, 0x66, 0xa1, 0x6c, 0xd2, 0x56, 0x7b,
+	0xa7, 0x70, 0x94, 0x66, 0xe1, 0x15, 0x40, 0x31, 0x69, 0xe4, 0xbf, 0x22, 0xab, 0xb6, 0xc1, 0xed,
+	0xbd, 0xf9, 0xc1, 0xac, 0xd0, 0x53, 0x68, 0xe5, 0x9b, 0x92, 0x18, 0x8d, 0xad, 0xae, 0x4f, 0xbb,
+	0x4a, 0x2d, 0xde, 0x7e, 0xc5, 0x06, 0x33, 0x29, 0xd4, 0xf6, 0xda, 0x5c, 0x6c, 0xf1, 0x8a, 0x99,
+	0xd8, 0xda, 0xdb, 0x56, 0xc7, 0x1e, 0xc2, 0x4a, 0x3f, 0x10, 0x72, 0x8e, 0x58, 0xb5, 0xd4, 0x33,
+	0xd8, 0x28, 0x8f, 0x0b, 0xd9, 0x37, 0x46, 0x77, 0xde, 0xdf, 0x62, 0x77, 0xef, 0x4e, 0xc8, 0x14,
+	0xfb, 0x08, 0xdb, 0x73, 0x3b, 0x4e, 0xee, 0x1b, 0x12, 0xfc, 0x62, 0xa4, 0xec, 0x07, 0xbf, 0xcd,
+	0x4b, 0x4f, 0x1a, 0xae, 0x26, 0x6f, 0xc8, 0xf1, 0xcf, 0x01, 0x00, 0xde, 0xba, 0x44, 0x4d, 0x51,
+	0x08, 0x00, 0x00,
 }
@@ -61,6 +61,31 @@ func (s *gRPCServer) RevokeUser(ctx context.Context, req *RevokeUserRequest) (*E
 	return &Empty{}, err
 }
 
+func (s *gRPCServer) SetCredentials(ctx context.Context, req *SetCredentialsRequest) (*SetCredentialsResponse, error) {
+	u, p, err := s.impl.SetCredentials(ctx, *req.Statements, *req.StaticUserConfig)
+
+	return &SetCredentialsResponse{
+		Username: u,
+		Password: p,
+	}, err
+}
+
+func (s *gRPCServer) RotateRootCredentials(ctx context.Context, req *RotateRootCredentialsRequest) (*RotateRootCredentialsResponse, error) {
+	config, err := s.impl.RotateRootCredentials(ctx, req.Statements)
+	if err != nil {
+		return nil, err
+	}
+
+	configRaw, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RotateRootCredentialsResponse{
+		Config: configRaw,
+	}, nil
+}
+
 func (s *gRPCServer) Initialize(ctx context.Context, req *InitializeRequest) (*Empty, error) {
 	config := map[string]interface{}{}
 
@@ -172,6 +197,51 @@ func (c *gRPCClient) RevokeUser(ctx context.Context, statements Statements, user
 	return nil
 }
 
+func (c *gRPCClient) SetCredentials(ctx context.Context, statements Statements, staticConfig StaticUserConfig) (username string, password string, err error) {
+	ctx, cancel := context.WithCancel(ctx)
+	quitCh := pluginutil.CtxCancelIfCanceled(cancel, c.doneCtx)
+	defer close(quitCh)
+	defer cancel()
+
+	resp, err := c.client.SetCredentials(ctx, &SetCredentialsRequest{
+		Statements:       &statements,
+		StaticUserConfig: &staticConfig,
+	})
+	if err != nil {
+		if c.doneCtx.Err() != nil {
+			return "", "", ErrPluginShutdown
+		}
+
+		return "", "", err
+	}
+
+	return resp.Username, resp.Password, err
+}
+
+func (c *gRPCClient) RotateRootCredentials(ctx context.Context, statements []string) (config map[string]interface{}, err error) {
+	ctx, cancel := context.WithCancel(ctx)
+	quitCh := pluginutil.CtxCancelIfCanceled(cancel, c.doneCtx)
+	defer close(quitCh)
+	defer cancel()
+
+	resp, err := c.client.RotateRootCredentials(ctx, &RotateRootCredentialsRequest{
+		Statements: statements,
+	})
+	if err != nil {
+		if c.doneCtx.Err() != nil {
+			return nil, ErrPluginShutdown
+		}
+
+		return nil, err
+	}
+
+	if err := json.Unmarshal(resp.Config, &config); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
 func (c *gRPCClient) Initialize(ctx context.Context, config map[string]interface{}, verifyConnection bool) error {
 	configRaw, err := json.Marshal(config)
 	if err != nil {
@@ -37,6 +37,18 @@ func (ds *databasePluginRPCServer) RevokeUser(args *RevokeUserRequestRPC, _ *str
 	return err
 }
 
+func (ds *databasePluginRPCServer) SetCredentials(args *SetCredentialsRequestRPC, resp *SetCredentialsResponse) error {
+	var err error
+	resp.Username, resp.Password, err = ds.impl.SetCredentials(context.Background(), args.Statements, args.StaticUserConfig)
+	return err
+}
+
+func (ds *databasePluginRPCServer) RotateRootCredentials(args *RotateRootCredentialsRequestRPC, resp *RotateRootCredentialsResponseRPC) error {
+	var err error
+	resp.Config, err = ds.impl.RotateRootCredentials(context.Background(), args.Statements)
+	return err
+}
+
 func (ds *databasePluginRPCServer) Initialize(args *InitializeRequestRPC, _ *struct{}) error {
 	err := ds.impl.Initialize(context.Background(), args.Config, args.VerifyConnection)
 	return err
@@ -97,6 +109,29 @@ func (dr *databasePluginRPCClient) RevokeUser(_ context.Context, statements Stat
 	return err
 }
 
+func (dr *databasePluginRPCClient) SetCredentials(_ context.Context, statements Statements, staticConfig StaticUserConfig) (username string, password string, err error) {
+	req := SetCredentialsRequestRPC{
+		Statements:       statements,
+		StaticUserConfig: staticConfig,
+	}
+
+	var resp SetCredentialsResponse
+	err = dr.client.Call("Plugin.SetCredentials", req, &resp)
+
+	return resp.Username, resp.Password, err
+}
+
+func (dr *databasePluginRPCClient) RotateRootCredentials(_ context.Context, statements []string) (config map[string]interface{}, err error) {
+	req := RotateRootCredentialsRequestRPC{
+		Statements: statements,
+	}
+
+	var resp RotateRootCredentialsResponseRPC
+	err = dr.client.Call("Plugin.RotateRootCredentials", req, &resp)
+
+	return resp.Config, err
+}
+
 func (dr *databasePluginRPCClient) Initialize(_ context.Context, conf map[string]interface{}, verifyConnection bool) error {
 	req := InitializeRequestRPC{
 		Config:           conf,
@@ -137,3 +172,16 @@ type RevokeUserRequestRPC struct {
 	Statements Statements
 	Username   string
 }
+
+type SetCredentialsRequestRPC struct {
+	Statements       Statements
+	StaticUserConfig StaticUserConfig
+}
+
+type RotateRootCredentialsRequestRPC struct {
+	Statements []string
+}
+
+type RotateRootCredentialsResponseRPC struct {
+	Config map[string]interface{}
+}
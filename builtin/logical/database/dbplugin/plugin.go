@@ -20,6 +20,18 @@ type Database interface {
 	RenewUser(ctx context.Context, statements Statements, username string, expiration time.Time) error
 	RevokeUser(ctx context.Context, statements Statements, username string) error
 
+	// SetCredentials sets the password of the existing user of the static
+	// configuration with the rotation statements, generating the password if
+	// the configuration does not set one. It is used to rotate the passwords
+	// of the users of static roles.
+	SetCredentials(ctx context.Context, statements Statements, staticConfig StaticUserConfig) (username string, password string, err error)
+
+	// RotateRootCredentials rotates the password of the user of the
+	// connection configuration with the given statements, or the plugin's
+	// default statements if none are given, and returns the updated
+	// connection configuration to be stored.
+	RotateRootCredentials(ctx context.Context, statements []string) (config map[string]interface{}, err error)
+
 	Initialize(ctx context.Context, config map[string]interface{}, verifyConnection bool) error
 	Close() error
 }
@@ -61,6 +61,31 @@ func (m *mockPlugin) RevokeUser(_ context.Context, statements dbplugin.Statement
 	delete(m.users, username)
 	return nil
 }
+func (m *mockPlugin) SetCredentials(_ context.Context, statements dbplugin.Statements, staticConfig dbplugin.StaticUserConfig) (username string, password string, err error) {
+	err = errors.New("err")
+	if staticConfig.Username == "" {
+		return "", "", err
+	}
+
+	password = staticConfig.Password
+	if password == "" {
+		password = "generated"
+	}
+	m.users[staticConfig.Username] = []string{password}
+
+	return staticConfig.Username, password, nil
+}
+func (m *mockPlugin) RotateRootCredentials(_ context.Context, statements []string) (config map[string]interface{}, err error) {
+	err = errors.New("err")
+	if len(statements) == 0 {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"username": "test",
+		"password": "rotated",
+	}, nil
+}
 func (m *mockPlugin) Initialize(_ context.Context, conf map[string]interface{}, _ bool) error {
 	err := errors.New("err")
 	if len(conf) != 1 {
@@ -271,6 +296,78 @@ func TestPlugin_RevokeUser(t *testing.T) {
 	}
 }
 
+func TestPlugin_SetCredentials(t *testing.T) {
+	cluster, sys := getCluster(t)
+	defer cluster.Cleanup()
+
+	db, err := dbplugin.PluginFactory(context.Background(), "test-plugin", sys, &log.NullLogger{})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer db.Close()
+
+	connectionDetails := map[string]interface{}{
+		"test": 1,
+	}
+	err = db.Initialize(context.Background(), connectionDetails, true)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	us, pw, err := db.SetCredentials(context.Background(), dbplugin.Statements{}, dbplugin.StaticUserConfig{Username: "test"})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if us != "test" || pw != "generated" {
+		t.Fatalf("bad: username %q, password %q", us, pw)
+	}
+
+	us, pw, err = db.SetCredentials(context.Background(), dbplugin.Statements{}, dbplugin.StaticUserConfig{Username: "test", Password: "foo"})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if us != "test" || pw != "foo" {
+		t.Fatalf("bad: username %q, password %q", us, pw)
+	}
+
+	_, _, err = db.SetCredentials(context.Background(), dbplugin.Statements{}, dbplugin.StaticUserConfig{})
+	if err == nil {
+		t.Fatal("expected an error for an empty username")
+	}
+}
+
+func TestPlugin_RotateRootCredentials(t *testing.T) {
+	cluster, sys := getCluster(t)
+	defer cluster.Cleanup()
+
+	db, err := dbplugin.PluginFactory(context.Background(), "test-plugin", sys, &log.NullLogger{})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer db.Close()
+
+	connectionDetails := map[string]interface{}{
+		"test": 1,
+	}
+	err = db.Initialize(context.Background(), connectionDetails, true)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	config, err := db.RotateRootCredentials(context.Background(), []string{"rotate"})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if config["username"] != "test" || config["password"] != "rotated" {
+		t.Fatalf("bad: %#v", config)
+	}
+
+	_, err = db.RotateRootCredentials(context.Background(), nil)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
 // Test the code is still compatible with an old netRPC plugin
 func TestPlugin_NetRPC_Initialize(t *testing.T) {
 	cluster, sys := getCluster(t)
@@ -410,3 +507,75 @@ func TestPlugin_NetRPC_RevokeUser(t *testing.T) {
 		t.Fatalf("err: %s", err)
 	}
 }
+
+func TestPlugin_NetRPC_SetCredentials(t *testing.T) {
+	cluster, sys := getCluster(t)
+	defer cluster.Cleanup()
+
+	db, err := dbplugin.PluginFactory(context.Background(), "test-plugin-netRPC", sys, &log.NullLogger{})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer db.Close()
+
+	connectionDetails := map[string]interface{}{
+		"test": 1,
+	}
+	err = db.Initialize(context.Background(), connectionDetails, true)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	us, pw, err := db.SetCredentials(context.Background(), dbplugin.Statements{}, dbplugin.StaticUserConfig{Username: "test"})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if us != "test" || pw != "generated" {
+		t.Fatalf("bad: username %q, password %q", us, pw)
+	}
+
+	us, pw, err = db.SetCredentials(context.Background(), dbplugin.Statements{}, dbplugin.StaticUserConfig{Username: "test", Password: "foo"})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if us != "test" || pw != "foo" {
+		t.Fatalf("bad: username %q, password %q", us, pw)
+	}
+
+	_, _, err = db.SetCredentials(context.Background(), dbplugin.Statements{}, dbplugin.StaticUserConfig{})
+	if err == nil {
+		t.Fatal("expected an error for an empty username")
+	}
+}
+
+func TestPlugin_NetRPC_RotateRootCredentials(t *testing.T) {
+	cluster, sys := getCluster(t)
+	defer cluster.Cleanup()
+
+	db, err := dbplugin.PluginFactory(context.Background(), "test-plugin-netRPC", sys, &log.NullLogger{})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer db.Close()
+
+	connectionDetails := map[string]interface{}{
+		"test": 1,
+	}
+	err = db.Initialize(context.Background(), connectionDetails, true)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	config, err := db.RotateRootCredentials(context.Background(), []string{"rotate"})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if config["username"] != "test" || config["password"] != "rotated" {
+		t.Fatalf("bad: %#v", config)
+	}
+
+	_, err = db.RotateRootCredentials(context.Background(), nil)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
@@ -24,6 +24,12 @@ type DatabaseConfig struct {
 	// by each database type.
 	ConnectionDetails map[string]interface{} `json:"connection_details" structs:"connection_details" mapstructure:"connection_details"`
 	AllowedRoles      []string               `json:"allowed_roles" structs:"allowed_roles" mapstructure:"allowed_roles"`
+
+	RootCredentialsRotateStatements []string `json:"root_credentials_rotate_statements" structs:"root_credentials_rotate_statements" mapstructure:"root_credentials_rotate_statements"`
+
+	// PasswordPolicy is the name of the password policy of the passwords of
+	// the static roles
+	PasswordPolicy string `json:"password_policy" structs:"password_policy" mapstructure:"password_policy"`
 }
 
 // pathResetConnection configures a path to reset a plugin.
@@ -103,6 +109,21 @@ func pathConfigurePluginConnection(b *databaseBackend) *framework.Path {
 				allowed to get creds from this database connection. If empty no
 				roles are allowed. If "*" all roles are allowed.`,
 			},
+
+			"root_rotation_statements": &framework.FieldSchema{
+				Type: framework.TypeStringSlice,
+				Description: `Specifies the database statements to be executed
+				to rotate the root user's credentials. See the plugin's API
+				page for more information on support and formatting for this
+				parameter.`,
+			},
+
+			"password_policy": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `Name of the password policy, configured in
+				sys/policies/password, used to generate the passwords of the
+				static roles. If empty the plugin generates the passwords.`,
+			},
 		},
 
 		Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -160,6 +181,11 @@ func (b *databaseBackend) connectionReadHandler() framework.OperationFunc {
 		if err := entry.DecodeJSON(&config); err != nil {
 			return nil, err
 		}
+
+		// The password is not returned, since it may have been rotated and
+		// only be known to Vault
+		delete(config.ConnectionDetails, "password")
+
 		return &logical.Response{
 			Data: structs.New(config).Map(),
 		}, nil
@@ -212,6 +238,8 @@ func (b *databaseBackend) connectionWriteHandler() framework.OperationFunc {
 		verifyConnection := data.Get("verify_connection").(bool)
 
 		allowedRoles := data.Get("allowed_roles").([]string)
+		rootRotationStatements := data.Get("root_rotation_statements").([]string)
+		passwordPolicy := data.Get("password_policy").(string)
 
 		// Remove these entries from the data before we store it keyed under
 		// ConnectionDetails.
@@ -219,11 +247,15 @@ func (b *databaseBackend) connectionWriteHandler() framework.OperationFunc {
 		delete(data.Raw, "plugin_name")
 		delete(data.Raw, "allowed_roles")
 		delete(data.Raw, "verify_connection")
+		delete(data.Raw, "root_rotation_statements")
+		delete(data.Raw, "password_policy")
 
 		config := &DatabaseConfig{
-			ConnectionDetails: data.Raw,
-			PluginName:        pluginName,
-			AllowedRoles:      allowedRoles,
+			ConnectionDetails:               data.Raw,
+			PluginName:                      pluginName,
+			AllowedRoles:                    allowedRoles,
+			RootCredentialsRotateStatements: rootRotationStatements,
+			PasswordPolicy:                  passwordPolicy,
 		}
 
 		db, err := dbplugin.PluginFactory(ctx, config.PluginName, b.System(), b.logger)
@@ -283,6 +315,12 @@ accepts:
 	* "verify_connection" (default: true) - A boolean value denoting if the plugin should verify
 	   it is able to connect to the database using the provided connection
        details.
+
+	* "root_rotation_statements" - The database statements used to rotate the
+	   password of the user of the connection with the "rotate-root" endpoint.
+
+	* "password_policy" - The name of the password policy used to generate the
+	   passwords of the static roles of the connection.
 `
 
 const pathResetConnectionHelpSyn = `
@@ -84,6 +84,7 @@ func (b *databaseBackend) pathCredsCreateRead() framework.OperationFunc {
 		usernameConfig := dbplugin.UsernameConfig{
 			DisplayName: req.DisplayName,
 			RoleName:    name,
+			Template:    role.UsernameTemplate,
 		}
 
 		// Create the user
@@ -2,9 +2,11 @@ package database
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/hashicorp/vault/builtin/logical/database/dbplugin"
+	"github.com/hashicorp/vault/helper/template"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
 )
@@ -63,6 +65,15 @@ func pathRoles(b *databaseBackend) *framework.Path {
 				parameter.`,
 			},
 
+			"username_template": {
+				Type: framework.TypeString,
+				Description: `Go template used to generate the usernames of the
+				role's credentials, with the fields "DisplayName" and
+				"RoleName" and the functions "random", "truncate",
+				"unix_time", "uppercase", "lowercase" and "replace". Defaults
+				to the username format of the plugin.`,
+			},
+
 			"default_ttl": {
 				Type:        framework.TypeDurationSecond,
 				Description: "Default ttl for role.",
@@ -113,6 +124,7 @@ func (b *databaseBackend) pathRoleRead() framework.OperationFunc {
 				"revocation_statements": role.Statements.RevocationStatements,
 				"rollback_statements":   role.Statements.RollbackStatements,
 				"renew_statements":      role.Statements.RenewStatements,
+				"username_template":     role.UsernameTemplate,
 				"default_ttl":           role.DefaultTTL.Seconds(),
 				"max_ttl":               role.MaxTTL.Seconds(),
 			},
@@ -149,6 +161,23 @@ func (b *databaseBackend) pathRoleCreate() framework.OperationFunc {
 		rollbackStmts := data.Get("rollback_statements").(string)
 		renewStmts := data.Get("renew_statements").(string)
 
+		// Make sure the username template renders, so that errors in it are
+		// caught here rather than when credentials are requested
+		usernameTemplate := data.Get("username_template").(string)
+		if usernameTemplate != "" {
+			tmpl, err := template.Parse(usernameTemplate)
+			if err != nil {
+				return logical.ErrorResponse(fmt.Sprintf("invalid username_template: %s", err)), nil
+			}
+			_, err = tmpl.Generate(dbplugin.UsernameConfig{
+				DisplayName: "token",
+				RoleName:    name,
+			})
+			if err != nil {
+				return logical.ErrorResponse(fmt.Sprintf("invalid username_template: %s", err)), nil
+			}
+		}
+
 		// Get TTLs
 		defaultTTLRaw := data.Get("default_ttl").(int)
 		maxTTLRaw := data.Get("max_ttl").(int)
@@ -164,10 +193,11 @@ func (b *databaseBackend) pathRoleCreate() framework.OperationFunc {
 
 		// Store it
 		entry, err := logical.StorageEntryJSON("role/"+name, &roleEntry{
-			DBName:     dbName,
-			Statements: statements,
-			DefaultTTL: defaultTTL,
-			MaxTTL:     maxTTL,
+			DBName:           dbName,
+			Statements:       statements,
+			UsernameTemplate: usernameTemplate,
+			DefaultTTL:       defaultTTL,
+			MaxTTL:           maxTTL,
 		})
 		if err != nil {
 			return nil, err
@@ -181,10 +211,11 @@ func (b *databaseBackend) pathRoleCreate() framework.OperationFunc {
 }
 
 type roleEntry struct {
-	DBName     string              `json:"db_name" mapstructure:"db_name" structs:"db_name"`
-	Statements dbplugin.Statements `json:"statements" mapstructure:"statements" structs:"statements"`
-	DefaultTTL time.Duration       `json:"default_ttl" mapstructure:"default_ttl" structs:"default_ttl"`
-	MaxTTL     time.Duration       `json:"max_ttl" mapstructure:"max_ttl" structs:"max_ttl"`
+	DBName           string              `json:"db_name" mapstructure:"db_name" structs:"db_name"`
+	Statements       dbplugin.Statements `json:"statements" mapstructure:"statements" structs:"statements"`
+	UsernameTemplate string              `json:"username_template" mapstructure:"username_template" structs:"username_template"`
+	DefaultTTL       time.Duration       `json:"default_ttl" mapstructure:"default_ttl" structs:"default_ttl"`
+	MaxTTL           time.Duration       `json:"max_ttl" mapstructure:"max_ttl" structs:"max_ttl"`
 }
 
 const pathRoleHelpSyn = `
@@ -226,6 +257,14 @@ database plugin:
 	REVOKE USAGE ON SCHEMA public FROM {{name}};
 	DROP ROLE IF EXISTS {{name}};
 
+The "username_template" parameter is a Go template generating the usernames of
+the credentials, replacing the username format of the plugin. It can reference
+the "DisplayName" of the token and the "RoleName", and use the "random",
+"truncate", "unix_time", "uppercase", "lowercase" and "replace" functions, for
+instance:
+
+	v_{{.RoleName | truncate 10}}_{{random 8}}_{{unix_time}}
+
 The "renew_statements" parameter customizes the statement string used to renew a
 user.
 The "rollback_statements' parameter customizes the statement string used to
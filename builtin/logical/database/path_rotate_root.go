@@ -0,0 +1,91 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"net/rpc"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/builtin/logical/database/dbplugin"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+func pathRotateRootCredentials(b *databaseBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "rotate-root/" + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of this database connection",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathRotateRootCredentialsUpdate(),
+		},
+
+		HelpSynopsis:    pathRotateRootCredentialsUpdateHelpSyn,
+		HelpDescription: pathRotateRootCredentialsUpdateHelpDesc,
+	}
+}
+
+func (b *databaseBackend) pathRotateRootCredentialsUpdate() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		name := data.Get("name").(string)
+		if name == "" {
+			return logical.ErrorResponse(respErrEmptyName), nil
+		}
+
+		config, err := b.DatabaseConfig(ctx, req.Storage, name)
+		if err != nil {
+			return nil, err
+		}
+
+		// Grab the write lock so that the connection is not used while its
+		// credentials are rotated
+		b.Lock()
+		defer b.Unlock()
+
+		db, err := b.createDBObj(ctx, req.Storage, name)
+		if err != nil {
+			return nil, err
+		}
+
+		connectionDetails, err := db.RotateRootCredentials(ctx, config.RootCredentialsRotateStatements)
+		if err != nil {
+			// Plugin has shutdown, close it so next call can reconnect.
+			switch err {
+			case rpc.ErrShutdown, dbplugin.ErrPluginShutdown:
+				b.clearConnection(name)
+			}
+			return nil, err
+		}
+
+		// The cached connection already uses the new credentials, so that
+		// the rotation can be retried if they cannot be stored
+		config.ConnectionDetails = connectionDetails
+		entry, err := logical.StorageEntryJSON(fmt.Sprintf("config/%s", name), config)
+		if err != nil {
+			return nil, err
+		}
+		if err := req.Storage.Put(ctx, entry); err != nil {
+			return nil, errwrap.Wrapf("the root credentials were rotated but could not be stored, retry the rotation: {{err}}", err)
+		}
+
+		return nil, nil
+	}
+}
+
+const pathRotateRootCredentialsUpdateHelpSyn = `
+Request to rotate the root credentials for a certain database connection.
+`
+
+const pathRotateRootCredentialsUpdateHelpDesc = `
+This path attempts to rotate the root credentials of the user of the given
+database connection, which must be configured with the "username" and
+"password" connection details. The password is rotated in the database with
+the "root_rotation_statements" of the connection, or the plugin's default
+statements, and is then stored in the connection configuration, so that the
+password the connection was configured with is no longer valid.
+`
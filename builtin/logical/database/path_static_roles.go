@@ -0,0 +1,482 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/vault/builtin/logical/database/dbplugin"
+	"github.com/hashicorp/vault/helper/consts"
+	"github.com/hashicorp/vault/helper/locksutil"
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+const staticRolePath = "static-role/"
+
+// minRotationPeriod is the shortest rotation period of static roles, since
+// their passwords are rotated by the periodic function
+const minRotationPeriod = 5 * time.Second
+
+func pathListStaticRoles(b *databaseBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "static-roles/?$",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ListOperation: b.pathStaticRoleList(),
+		},
+
+		HelpSynopsis:    pathStaticRoleHelpSyn,
+		HelpDescription: pathStaticRoleHelpDesc,
+	}
+}
+
+func pathStaticRoles(b *databaseBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "static-roles/" + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "Name of the role.",
+			},
+
+			"db_name": {
+				Type:        framework.TypeString,
+				Description: "Name of the database this role acts on.",
+			},
+			"username": {
+				Type: framework.TypeString,
+				Description: `Name of the existing database user whose password
+				is managed by this role. Cannot be changed once the role is
+				created.`,
+			},
+			"rotation_period": {
+				Type: framework.TypeDurationSecond,
+				Description: `Period after which the password of the user is
+				rotated. Must be at least 5 seconds.`,
+			},
+			"rotation_statements": {
+				Type: framework.TypeString,
+				Description: `Specifies the database statements to be executed
+				to rotate the password of the user. See the plugin's API page
+				for more information on support and formatting for this
+				parameter.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathStaticRoleRead(),
+			logical.UpdateOperation: b.pathStaticRoleCreate(),
+			logical.DeleteOperation: b.pathStaticRoleDelete(),
+		},
+
+		HelpSynopsis:    pathStaticRoleHelpSyn,
+		HelpDescription: pathStaticRoleHelpDesc,
+	}
+}
+
+func pathStaticCreds(b *databaseBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "static-creds/" + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "Name of the static role.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathStaticCredsRead(),
+		},
+
+		HelpSynopsis:    pathStaticCredsReadHelpSyn,
+		HelpDescription: pathStaticCredsReadHelpDesc,
+	}
+}
+
+func pathRotateRole(b *databaseBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "rotate-role/" + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "Name of the static role.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathRotateRoleWrite(),
+		},
+
+		HelpSynopsis:    pathRotateRoleHelpSyn,
+		HelpDescription: pathRotateRoleHelpDesc,
+	}
+}
+
+type staticRoleEntry struct {
+	DBName            string              `json:"db_name" mapstructure:"db_name" structs:"db_name"`
+	Username          string              `json:"username" mapstructure:"username" structs:"username"`
+	Password          string              `json:"password" mapstructure:"password" structs:"password"`
+	RotationPeriod    time.Duration       `json:"rotation_period" mapstructure:"rotation_period" structs:"rotation_period"`
+	Statements        dbplugin.Statements `json:"statements" mapstructure:"statements" structs:"statements"`
+	LastVaultRotation time.Time           `json:"last_vault_rotation" mapstructure:"last_vault_rotation" structs:"last_vault_rotation"`
+}
+
+// nextRotation returns the time at which the password of the role is due to
+// be rotated
+func (r *staticRoleEntry) nextRotation() time.Time {
+	return r.LastVaultRotation.Add(r.RotationPeriod)
+}
+
+func (b *databaseBackend) StaticRole(ctx context.Context, s logical.Storage, roleName string) (*staticRoleEntry, error) {
+	entry, err := s.Get(ctx, staticRolePath+roleName)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var result staticRoleEntry
+	if err := entry.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (b *databaseBackend) pathStaticRoleDelete() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		name := data.Get("name").(string)
+
+		lock := locksutil.LockForKey(b.roleLocks, name)
+		lock.Lock()
+		defer lock.Unlock()
+
+		err := req.Storage.Delete(ctx, staticRolePath+name)
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, nil
+	}
+}
+
+func (b *databaseBackend) pathStaticRoleRead() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		role, err := b.StaticRole(ctx, req.Storage, data.Get("name").(string))
+		if err != nil {
+			return nil, err
+		}
+		if role == nil {
+			return nil, nil
+		}
+
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"db_name":             role.DBName,
+				"username":            role.Username,
+				"rotation_period":     role.RotationPeriod.Seconds(),
+				"rotation_statements": role.Statements.RotationStatements,
+				"last_vault_rotation": role.LastVaultRotation,
+			},
+		}, nil
+	}
+}
+
+func (b *databaseBackend) pathStaticRoleList() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		entries, err := req.Storage.List(ctx, staticRolePath)
+		if err != nil {
+			return nil, err
+		}
+
+		return logical.ListResponse(entries), nil
+	}
+}
+
+func (b *databaseBackend) pathStaticRoleCreate() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		name := data.Get("name").(string)
+		if name == "" {
+			return logical.ErrorResponse("empty role name attribute given"), nil
+		}
+
+		lock := locksutil.LockForKey(b.roleLocks, name)
+		lock.Lock()
+		defer lock.Unlock()
+
+		role, err := b.StaticRole(ctx, req.Storage, name)
+		if err != nil {
+			return nil, err
+		}
+		created := role == nil
+		if created {
+			role = &staticRoleEntry{}
+		}
+
+		if dbNameRaw, ok := data.GetOk("db_name"); ok {
+			role.DBName = dbNameRaw.(string)
+		}
+		if role.DBName == "" {
+			return logical.ErrorResponse("empty database name attribute given"), nil
+		}
+
+		if usernameRaw, ok := data.GetOk("username"); ok {
+			username := usernameRaw.(string)
+			if !created && username != role.Username {
+				return logical.ErrorResponse("cannot update the username of a static role"), nil
+			}
+			role.Username = username
+		}
+		if role.Username == "" {
+			return logical.ErrorResponse("empty username attribute given"), nil
+		}
+
+		if rotationPeriodRaw, ok := data.GetOk("rotation_period"); ok {
+			role.RotationPeriod = time.Duration(rotationPeriodRaw.(int)) * time.Second
+		}
+		if role.RotationPeriod < minRotationPeriod {
+			return logical.ErrorResponse(fmt.Sprintf("rotation_period must be at least %s", minRotationPeriod)), nil
+		}
+
+		if rotationStmtsRaw, ok := data.GetOk("rotation_statements"); ok {
+			role.Statements.RotationStatements = rotationStmtsRaw.(string)
+		}
+
+		// Vault takes ownership of the user by rotating its password as
+		// soon as the role is created
+		if created {
+			return nil, b.setStaticCredentials(ctx, req.Storage, name, role)
+		}
+
+		return nil, b.putStaticRole(ctx, req.Storage, name, role)
+	}
+}
+
+func (b *databaseBackend) pathStaticCredsRead() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		name := data.Get("name").(string)
+
+		role, err := b.StaticRole(ctx, req.Storage, name)
+		if err != nil {
+			return nil, err
+		}
+		if role == nil {
+			return logical.ErrorResponse(fmt.Sprintf("unknown role: %s", name)), nil
+		}
+
+		ttl := role.nextRotation().Sub(time.Now())
+		if ttl < 0 {
+			ttl = 0
+		}
+
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"username":            role.Username,
+				"password":            role.Password,
+				"last_vault_rotation": role.LastVaultRotation,
+				"rotation_period":     role.RotationPeriod.Seconds(),
+				"ttl":                 int64(ttl.Seconds()),
+			},
+		}, nil
+	}
+}
+
+func (b *databaseBackend) pathRotateRoleWrite() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		name := data.Get("name").(string)
+
+		lock := locksutil.LockForKey(b.roleLocks, name)
+		lock.Lock()
+		defer lock.Unlock()
+
+		role, err := b.StaticRole(ctx, req.Storage, name)
+		if err != nil {
+			return nil, err
+		}
+		if role == nil {
+			return logical.ErrorResponse(fmt.Sprintf("unknown role: %s", name)), nil
+		}
+
+		return nil, b.setStaticCredentials(ctx, req.Storage, name, role)
+	}
+}
+
+func (b *databaseBackend) putStaticRole(ctx context.Context, s logical.Storage, name string, role *staticRoleEntry) error {
+	entry, err := logical.StorageEntryJSON(staticRolePath+name, role)
+	if err != nil {
+		return err
+	}
+	return s.Put(ctx, entry)
+}
+
+// setStaticCredentials rotates the password of the user of the static role
+// and stores the role with the new password. The caller of this function
+// needs to hold the lock of the role.
+func (b *databaseBackend) setStaticCredentials(ctx context.Context, s logical.Storage, name string, role *staticRoleEntry) error {
+	dbConfig, err := b.DatabaseConfig(ctx, s, role.DBName)
+	if err != nil {
+		return err
+	}
+
+	// If role name isn't in the database's allowed roles, send back a
+	// permission denied.
+	if !strutil.StrListContains(dbConfig.AllowedRoles, "*") && !strutil.StrListContainsGlob(dbConfig.AllowedRoles, name) {
+		return logical.ErrPermissionDenied
+	}
+
+	// Grab the read lock
+	b.RLock()
+	unlockFunc := b.RUnlock
+
+	// Get the Database object
+	db, ok := b.getDBObj(role.DBName)
+	if !ok {
+		// Upgrade lock
+		b.RUnlock()
+		b.Lock()
+		unlockFunc = b.Unlock
+
+		// Create a new DB object
+		db, err = b.createDBObj(ctx, s, role.DBName)
+		if err != nil {
+			unlockFunc()
+			return fmt.Errorf("cound not retrieve db with name: %s, got error: %s", role.DBName, err)
+		}
+	}
+
+	staticConfig := dbplugin.StaticUserConfig{
+		Username: role.Username,
+	}
+
+	// Generate the password from the password policy of the connection, or
+	// let the plugin generate a new password for the user
+	if dbConfig.PasswordPolicy != "" {
+		staticConfig.Password, err = b.System().GeneratePasswordFromPolicy(ctx, dbConfig.PasswordPolicy)
+		if err != nil {
+			unlockFunc()
+			return errwrap.Wrapf(fmt.Sprintf("failed to generate the password of static role %q: {{err}}", name), err)
+		}
+	}
+
+	_, password, err := db.SetCredentials(ctx, role.Statements, staticConfig)
+	unlockFunc()
+	if err != nil {
+		b.closeIfShutdown(role.DBName, err)
+		return err
+	}
+
+	role.Password = password
+	role.LastVaultRotation = time.Now()
+	if err := b.putStaticRole(ctx, s, name, role); err != nil {
+		return errwrap.Wrapf(fmt.Sprintf("failed to store the rotated password of static role %q: {{err}}", name), err)
+	}
+
+	return nil
+}
+
+func (b *databaseBackend) periodicFunc(ctx context.Context, req *logical.Request) error {
+	// Passwords of performance secondaries are rotated on the primary
+	if !b.System().LocalMount() && b.System().ReplicationState().HasState(consts.ReplicationPerformanceSecondary) {
+		return nil
+	}
+	return b.rotateDueStaticRoles(ctx, req.Storage, time.Now())
+}
+
+// rotateDueStaticRoles rotates the passwords of the static roles whose
+// rotation period has elapsed at the given time
+func (b *databaseBackend) rotateDueStaticRoles(ctx context.Context, s logical.Storage, now time.Time) error {
+	names, err := s.List(ctx, staticRolePath)
+	if err != nil {
+		return err
+	}
+
+	var result *multierror.Error
+	for _, name := range names {
+		if err := b.rotateStaticRoleIfDue(ctx, s, name, now); err != nil {
+			result = multierror.Append(result, errwrap.Wrapf(fmt.Sprintf("failed to rotate the password of static role %q: {{err}}", name), err))
+		}
+	}
+
+	return result.ErrorOrNil()
+}
+
+func (b *databaseBackend) rotateStaticRoleIfDue(ctx context.Context, s logical.Storage, name string, now time.Time) error {
+	lock := locksutil.LockForKey(b.roleLocks, name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	// The role may have been deleted or rotated since it was listed
+	role, err := b.StaticRole(ctx, s, name)
+	if err != nil {
+		return err
+	}
+	if role == nil || now.Before(role.nextRotation()) {
+		return nil
+	}
+
+	if b.logger.IsDebug() {
+		b.logger.Debug("database: rotating the password of static role", "name", name)
+	}
+	return b.setStaticCredentials(ctx, s, name, role)
+}
+
+const pathStaticRoleHelpSyn = `
+Manage the static roles that can be created with this backend.
+`
+
+const pathStaticRoleHelpDesc = `
+This path lets you manage the static roles of this backend. A static role
+maps to an existing database user, whose password Vault takes ownership of:
+the password is rotated when the role is created, and then again every time
+the rotation period elapses.
+
+The "db_name" parameter is required and configures the name of the database
+connection to use.
+
+The "username" parameter is required and is the name of the existing database
+user. It cannot be changed once the role is created.
+
+The "rotation_period" parameter is required and configures the period after
+which the password is rotated. It must be at least 5 seconds.
+
+The "rotation_statements" parameter customizes the statement string used to
+rotate the password. Some substitution will be done to the statement strings
+for certain keys. The names of the variables must be surrounded by "{{" and
+"}}" to be replaced.
+
+  * "name" - The username of the static role.
+
+  * "password" - The new password of the user.
+
+Example of a decent rotation_statements for a postgresql database plugin:
+
+	ALTER ROLE "{{name}}" WITH PASSWORD '{{password}}';
+
+Deleting a static role does not change the password of the user, which keeps
+the last password set by Vault.
+`
+
+const pathStaticCredsReadHelpSyn = `
+Request the credentials of a static role.
+`
+
+const pathStaticCredsReadHelpDesc = `
+This path reads the current credentials of a static role. Unlike the
+credentials of dynamic roles, these are not leased: the "ttl" is the number
+of seconds until the password is next rotated.
+`
+
+const pathRotateRoleHelpSyn = `
+Rotate the password of a static role.
+`
+
+const pathRotateRoleHelpDesc = `
+This path rotates the password of the user of a static role immediately,
+regardless of its rotation period. The next scheduled rotation happens one
+rotation period after this one.
+`
@@ -0,0 +1,127 @@
+package gcp
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/iam/v1"
+)
+
+// cloudPlatformScope is the OAuth2 scope of the calls to the GCP APIs
+const cloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+
+func Factory(ctx context.Context, conf *logical.BackendConfig) (logical.Backend, error) {
+	b := Backend()
+	if err := b.Setup(ctx, conf); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func Backend() *backend {
+	var b backend
+	b.Backend = &framework.Backend{
+		Help: strings.TrimSpace(backendHelp),
+
+		PathsSpecial: &logical.Paths{
+			LocalStorage: []string{
+				framework.WALPrefix,
+			},
+			SealWrapStorage: []string{
+				"config",
+				"roleset/",
+			},
+		},
+
+		Paths: []*framework.Path{
+			pathConfig(&b),
+			pathListRoleSets(&b),
+			pathRoleSet(&b),
+			pathRoleSetRotateAccount(&b),
+			pathRoleSetRotateKey(&b),
+			pathSecretAccessToken(&b),
+			pathSecretServiceAccountKey(&b),
+		},
+
+		Secrets: []*framework.Secret{
+			secretServiceAccountKey(&b),
+		},
+
+		WALRollback:       b.walRollback,
+		WALRollbackMinAge: 5 * time.Minute,
+		BackendType:       logical.TypeLogical,
+	}
+
+	return &b
+}
+
+type backend struct {
+	*framework.Backend
+
+	// rolesetLock serializes the changes of the service accounts and IAM
+	// bindings of the rolesets
+	rolesetLock sync.Mutex
+
+	// transport overrides the transport of the HTTP clients, so that tests
+	// can reach a fake of the GCP APIs
+	transport http.RoundTripper
+}
+
+// baseHTTPClient returns the unauthenticated HTTP client used to call the GCP
+// APIs and to fetch OAuth2 tokens.
+func (b *backend) baseHTTPClient() *http.Client {
+	client := cleanhttp.DefaultClient()
+	if b.transport != nil {
+		client.Transport = b.transport
+	}
+	return client
+}
+
+// httpClient returns an HTTP client authenticated with the credentials of the
+// configuration, or with the application default credentials if none are
+// configured.
+func (b *backend) httpClient(ctx context.Context, s logical.Storage) (*http.Client, error) {
+	conf, err := b.readConfig(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, b.baseHTTPClient())
+	if conf == nil || conf.Credentials == "" {
+		return google.DefaultClient(ctx, cloudPlatformScope)
+	}
+
+	jwtConfig, err := google.JWTConfigFromJSON([]byte(conf.Credentials), cloudPlatformScope)
+	if err != nil {
+		return nil, err
+	}
+	return jwtConfig.Client(ctx), nil
+}
+
+// iamClient returns a client of the IAM API.
+func (b *backend) iamClient(ctx context.Context, s logical.Storage) (*iam.Service, error) {
+	client, err := b.httpClient(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	return iam.New(client)
+}
+
+const backendHelp = `
+The GCP backend dynamically generates OAuth2 access tokens and service account
+keys of GCP service accounts.
+
+Rolesets define the IAM roles granted on GCP resources to a service account
+which Vault creates and manages for each of them. Access tokens and keys are
+then generated for the service account of a roleset. After mounting this
+backend, configure the credentials of Vault with the "config" endpoint and
+define rolesets with the "roleset/" endpoints.
+`
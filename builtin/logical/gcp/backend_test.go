@@ -0,0 +1,536 @@
+package gcp
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+	"google.golang.org/api/iam/v1"
+)
+
+const testProject = "test-project"
+
+const testProjectResource = "//cloudresourcemanager.googleapis.com/projects/" + testProject
+
+const testBindings = `
+resource "//cloudresourcemanager.googleapis.com/projects/test-project" {
+  roles = ["roles/viewer"]
+}
+`
+
+// fakeGCP is a fake of the OAuth2 token endpoint, of the service accounts of
+// the IAM API and of the IAM policies of the Resource Manager API
+type fakeGCP struct {
+	sync.Mutex
+	privateKey string
+
+	// failSetPolicy makes the updates of IAM policies fail
+	failSetPolicy bool
+
+	accounts map[string]bool
+	keys     map[string]bool
+	policies map[string]*iam.Policy
+	keyCount int
+}
+
+// credentials returns a JSON credentials file of the service account.
+func (f *fakeGCP) credentials(email string) string {
+	credentials, _ := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"client_email":   email,
+		"private_key_id": "1",
+		"private_key":    f.privateKey,
+		"token_uri":      "https://oauth2.googleapis.com/token",
+	})
+	return string(credentials)
+}
+
+func (f *fakeGCP) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	writeError := func(code int, message string) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error": map[string]interface{}{"code": code, "message": message},
+		})
+	}
+
+	if r.Host == "oauth2.googleapis.com" && r.URL.Path == "/token" {
+		// The access token is named after the issuer of the JWT assertion
+		r.ParseForm()
+		parts := strings.Split(r.Form.Get("assertion"), ".")
+		if len(parts) != 3 {
+			writeError(http.StatusBadRequest, "invalid assertion")
+			return
+		}
+		payload, _ := base64.RawURLEncoding.DecodeString(parts[1])
+		var claims struct {
+			Iss string `json:"iss"`
+		}
+		json.Unmarshal(payload, &claims)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "token-" + claims.Iss,
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+		})
+		return
+	}
+
+	if r.Header.Get("Authorization") != "Bearer token-vault@"+testProject+".iam.gserviceaccount.com" {
+		writeError(http.StatusUnauthorized, "unauthenticated")
+		return
+	}
+
+	path := r.URL.Path
+	switch {
+	case r.Host == "iam.googleapis.com" && path == "/v1/projects/"+testProject+"/serviceAccounts" && r.Method == http.MethodPost:
+		var req iam.CreateServiceAccountRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		email := fmt.Sprintf("%s@%s.iam.gserviceaccount.com", req.AccountId, testProject)
+		if len(req.AccountId) < 6 || len(req.AccountId) > 30 {
+			writeError(http.StatusBadRequest, "invalid account id")
+			return
+		}
+		f.accounts[email] = true
+		json.NewEncoder(w).Encode(&iam.ServiceAccount{
+			Email: email,
+			Name:  "projects/" + testProject + "/serviceAccounts/" + email,
+		})
+
+	case r.Host == "iam.googleapis.com" && strings.HasSuffix(path, "/keys") && r.Method == http.MethodPost:
+		email := strings.TrimSuffix(strings.TrimPrefix(path, "/v1/projects/"+testProject+"/serviceAccounts/"), "/keys")
+		if !f.accounts[email] {
+			writeError(http.StatusNotFound, "service account not found")
+			return
+		}
+		f.keyCount++
+		name := fmt.Sprintf("projects/%s/serviceAccounts/%s/keys/%d", testProject, email, f.keyCount)
+		f.keys[name] = true
+		json.NewEncoder(w).Encode(&iam.ServiceAccountKey{
+			Name:           name,
+			KeyAlgorithm:   keyAlgorithmRSA2048,
+			PrivateKeyType: keyTypeCredentialFile,
+			PrivateKeyData: base64.StdEncoding.EncodeToString([]byte(f.credentials(email))),
+		})
+
+	case r.Host == "iam.googleapis.com" && strings.Contains(path, "/keys/") && r.Method == http.MethodDelete:
+		name := strings.TrimPrefix(path, "/v1/")
+		if !f.keys[name] {
+			writeError(http.StatusNotFound, "key not found")
+			return
+		}
+		delete(f.keys, name)
+		w.Write([]byte("{}"))
+
+	case r.Host == "iam.googleapis.com" && r.Method == http.MethodDelete:
+		email := strings.TrimPrefix(path, "/v1/projects/"+testProject+"/serviceAccounts/")
+		if !f.accounts[email] {
+			writeError(http.StatusNotFound, "service account not found")
+			return
+		}
+		delete(f.accounts, email)
+		for name := range f.keys {
+			if strings.Contains(name, "/"+email+"/") {
+				delete(f.keys, name)
+			}
+		}
+		w.Write([]byte("{}"))
+
+	case r.Host == "cloudresourcemanager.googleapis.com" && strings.HasSuffix(path, ":getIamPolicy"):
+		policy, ok := f.policies[strings.TrimSuffix(path, ":getIamPolicy")]
+		if !ok {
+			writeError(http.StatusNotFound, "resource not found")
+			return
+		}
+		json.NewEncoder(w).Encode(policy)
+
+	case r.Host == "cloudresourcemanager.googleapis.com" && strings.HasSuffix(path, ":setIamPolicy"):
+		resource := strings.TrimSuffix(path, ":setIamPolicy")
+		if _, ok := f.policies[resource]; !ok {
+			writeError(http.StatusNotFound, "resource not found")
+			return
+		}
+		if f.failSetPolicy {
+			writeError(http.StatusForbidden, "permission denied")
+			return
+		}
+		var req iam.SetIamPolicyRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		f.policies[resource] = req.Policy
+		json.NewEncoder(w).Encode(req.Policy)
+
+	default:
+		writeError(http.StatusNotFound, "not found")
+	}
+}
+
+// boundRoles returns the roles granted to the service account on the project.
+func (f *fakeGCP) boundRoles(email string) []string {
+	f.Lock()
+	defer f.Unlock()
+
+	var roles []string
+	for _, binding := range f.policies["/v1/projects/"+testProject].Bindings {
+		if hasMember(binding, "serviceAccount:"+email) {
+			roles = append(roles, binding.Role)
+		}
+	}
+	return roles
+}
+
+// rewriteTransport sends all the requests to the fake, keeping their
+// original host
+type rewriteTransport struct {
+	target *url.URL
+}
+
+func (t *rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rewritten := new(http.Request)
+	*rewritten = *req
+	u := *req.URL
+	u.Scheme = t.target.Scheme
+	u.Host = t.target.Host
+	rewritten.URL = &u
+	rewritten.Host = req.URL.Host
+	return http.DefaultTransport.RoundTrip(rewritten)
+}
+
+func prepareTestBackend(t *testing.T) (*backend, logical.Storage, *fakeGCP, func()) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gcp := &fakeGCP{
+		privateKey: string(pem.EncodeToMemory(&pem.Block{
+			Type:  "RSA PRIVATE KEY",
+			Bytes: x509.MarshalPKCS1PrivateKey(key),
+		})),
+		accounts: make(map[string]bool),
+		keys:     make(map[string]bool),
+		policies: map[string]*iam.Policy{
+			"/v1/projects/" + testProject: {
+				Bindings: []*iam.Binding{
+					{Role: "roles/owner", Members: []string{"user:admin@example.com"}},
+				},
+			},
+		},
+	}
+	server := httptest.NewServer(gcp)
+	target, _ := url.Parse(server.URL)
+
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+
+	b := Backend()
+	b.transport = &rewriteTransport{target: target}
+	if err := b.Setup(context.Background(), config); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   config.StorageView,
+		Data: map[string]interface{}{
+			"credentials": gcp.credentials("vault@" + testProject + ".iam.gserviceaccount.com"),
+			"ttl":         "1h",
+			"max_ttl":     "2h",
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: config failed. resp:%#v\n err:%v", resp, err)
+	}
+
+	return b, config.StorageView, gcp, server.Close
+}
+
+func writeRoleSet(t *testing.T, b *backend, s logical.Storage, name string, data map[string]interface{}) *logical.Response {
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "roleset/" + name,
+		Storage:   s,
+		Data:      data,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestBackend_accessToken(t *testing.T) {
+	b, s, gcp, cleanup := prepareTestBackend(t)
+	defer cleanup()
+
+	resp := writeRoleSet(t, b, s, "test", map[string]interface{}{
+		"project":      testProject,
+		"bindings":     testBindings,
+		"token_scopes": "https://www.googleapis.com/auth/cloud-platform",
+	})
+	if resp != nil && resp.IsError() {
+		t.Fatalf("bad: roleset creation failed. resp:%#v", resp)
+	}
+
+	rs, err := getRoleSet(context.Background(), s, "test")
+	if err != nil || rs == nil || rs.Account == nil || rs.TokenKey == nil {
+		t.Fatalf("bad: roleset: %#v, err: %v", rs, err)
+	}
+	email := rs.Account.Email
+	if !gcp.accounts[email] || !gcp.keys[rs.TokenKey.Name] {
+		t.Fatalf("service account %q or its key was not created", email)
+	}
+	if roles := gcp.boundRoles(email); len(roles) != 1 || roles[0] != "roles/viewer" {
+		t.Fatalf("bad: roles: %#v", roles)
+	}
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "token/test",
+		Storage:   s,
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: token generation failed. resp:%#v\n err:%v", resp, err)
+	}
+	if resp.Data["token"] != "token-"+email || resp.Secret != nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "key/test",
+		Storage:   s,
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error response. resp:%#v\n err:%v", resp, err)
+	}
+
+	// Changing the bindings replaces the service account
+	resp = writeRoleSet(t, b, s, "test", map[string]interface{}{
+		"bindings": `resource "//cloudresourcemanager.googleapis.com/projects/test-project" { roles = ["roles/editor"] }`,
+	})
+	if resp != nil && resp.IsError() {
+		t.Fatalf("bad: roleset update failed. resp:%#v", resp)
+	}
+	rs, err = getRoleSet(context.Background(), s, "test")
+	if err != nil || rs == nil || rs.Account.Email == email {
+		t.Fatalf("bad: roleset: %#v, err: %v", rs, err)
+	}
+	if gcp.accounts[email] || len(gcp.boundRoles(email)) != 0 {
+		t.Fatalf("previous service account %q was not cleaned up", email)
+	}
+	if roles := gcp.boundRoles(rs.Account.Email); len(roles) != 1 || roles[0] != "roles/editor" {
+		t.Fatalf("bad: roles: %#v", roles)
+	}
+
+	// Rotating the key replaces the key used to generate access tokens
+	oldKey := rs.TokenKey.Name
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "roleset/test/rotate-key",
+		Storage:   s,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: key rotation failed. resp:%#v\n err:%v", resp, err)
+	}
+	rs, _ = getRoleSet(context.Background(), s, "test")
+	if rs.TokenKey.Name == oldKey || gcp.keys[oldKey] || !gcp.keys[rs.TokenKey.Name] {
+		t.Fatalf("bad: key was not rotated: %#v", rs.TokenKey)
+	}
+
+	// Deleting the roleset deletes its service account
+	email = rs.Account.Email
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.DeleteOperation,
+		Path:      "roleset/test",
+		Storage:   s,
+	})
+	if err != nil || resp != nil {
+		t.Fatalf("bad: roleset deletion failed. resp:%#v\n err:%v", resp, err)
+	}
+	if gcp.accounts[email] || len(gcp.boundRoles(email)) != 0 || len(gcp.keys) != 0 {
+		t.Fatalf("service account %q was not cleaned up", email)
+	}
+	if roles := gcp.boundRoles("admin@example.com"); len(roles) != 0 {
+		t.Fatalf("bad: roles: %#v", roles)
+	}
+}
+
+func TestBackend_serviceAccountKey(t *testing.T) {
+	b, s, gcp, cleanup := prepareTestBackend(t)
+	defer cleanup()
+
+	resp := writeRoleSet(t, b, s, "test", map[string]interface{}{
+		"secret_type": "service_account_key",
+		"project":     testProject,
+		"bindings":    base64.StdEncoding.EncodeToString([]byte(testBindings)),
+	})
+	if resp != nil && resp.IsError() {
+		t.Fatalf("bad: roleset creation failed. resp:%#v", resp)
+	}
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "roleset/test",
+		Storage:   s,
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: reading roleset failed. resp:%#v\n err:%v", resp, err)
+	}
+	bindings := resp.Data["bindings"].(resourceBindings)
+	if !bindings.equal(resourceBindings{testProjectResource: {"roles/viewer"}}) || resp.Data["secret_type"] != "service_account_key" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "key/test",
+		Storage:   s,
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: key generation failed. resp:%#v\n err:%v", resp, err)
+	}
+	if resp.Secret == nil || resp.Secret.TTL != time.Hour || resp.Data["private_key_data"] == "" {
+		t.Fatalf("bad: %#v", resp)
+	}
+	keyName := resp.Secret.InternalData["key_name"].(string)
+	if !gcp.keys[keyName] {
+		t.Fatalf("key %q was not created", keyName)
+	}
+
+	secret := resp.Secret
+	secret.IssueTime = time.Now()
+	req := &logical.Request{
+		Operation: logical.RenewOperation,
+		Storage:   s,
+		Secret:    secret,
+	}
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: renewal failed. resp:%#v\n err:%v", resp, err)
+	}
+
+	req.Operation = logical.RevokeOperation
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: revocation failed. resp:%#v\n err:%v", resp, err)
+	}
+	if gcp.keys[keyName] {
+		t.Fatalf("key %q was not deleted", keyName)
+	}
+
+	// Keys of rotated service accounts cannot be renewed, and are revoked
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "roleset/test/rotate",
+		Storage:   s,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: rotation failed. resp:%#v\n err:%v", resp, err)
+	}
+	req.Operation = logical.RenewOperation
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error response. resp:%#v\n err:%v", resp, err)
+	}
+	req.Operation = logical.RevokeOperation
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: revocation failed. resp:%#v\n err:%v", resp, err)
+	}
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "token/test",
+		Storage:   s,
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error response. resp:%#v\n err:%v", resp, err)
+	}
+}
+
+func TestBackend_roleSetRollback(t *testing.T) {
+	b, s, gcp, cleanup := prepareTestBackend(t)
+	defer cleanup()
+
+	// The service account is left over when its roles cannot be granted
+	gcp.failSetPolicy = true
+	resp := writeRoleSet(t, b, s, "test", map[string]interface{}{
+		"secret_type": "service_account_key",
+		"project":     testProject,
+		"bindings":    testBindings,
+	})
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error response. resp:%#v", resp)
+	}
+	if len(gcp.accounts) != 1 {
+		t.Fatalf("bad: accounts: %#v", gcp.accounts)
+	}
+
+	walIDs, err := framework.ListWAL(context.Background(), s)
+	if err != nil || len(walIDs) != 1 {
+		t.Fatalf("bad: WAL entries: %#v, err: %v", walIDs, err)
+	}
+	entry, err := framework.GetWAL(context.Background(), s, walIDs[0])
+	if err != nil || entry == nil {
+		t.Fatalf("bad: WAL entry: %#v, err: %v", entry, err)
+	}
+
+	gcp.failSetPolicy = false
+	err = b.walRollback(context.Background(), &logical.Request{Storage: s}, entry.Kind, entry.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gcp.accounts) != 0 {
+		t.Fatalf("bad: accounts: %#v", gcp.accounts)
+	}
+}
+
+func TestParseBindings(t *testing.T) {
+	bindings, err := parseBindings(`
+resource "//cloudresourcemanager.googleapis.com/projects/test-project" {
+  roles = ["roles/viewer", "roles/viewer"]
+}
+
+resource "//pubsub.googleapis.com/projects/test-project/topics/events" {
+  roles = ["roles/pubsub.publisher"]
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := resourceBindings{
+		testProjectResource: {"roles/viewer"},
+		"//pubsub.googleapis.com/projects/test-project/topics/events": {"roles/pubsub.publisher"},
+	}
+	if !bindings.equal(expected) {
+		t.Fatalf("bad: %#v", bindings)
+	}
+
+	for _, raw := range []string{
+		``,
+		`resource "projects/test-project" { roles = ["roles/viewer"] }`,
+		`resource "//storage.googleapis.com/buckets/test" { roles = ["roles/viewer"] }`,
+		`resource "//cloudresourcemanager.googleapis.com/projects/test-project" { roles = [] }`,
+		`project "test-project" { roles = ["roles/viewer"] }`,
+	} {
+		if _, err := parseBindings(raw); err == nil {
+			t.Fatalf("%q: expected an error", raw)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package gcp
+
+import (
+	"encoding/base64"
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/hcl"
+	"github.com/hashicorp/hcl/hcl/ast"
+	"github.com/hashicorp/vault/helper/strutil"
+)
+
+// resourceBindings maps the names of GCP resources to the IAM roles granted on
+// them to the service account of a roleset
+type resourceBindings map[string][]string
+
+// parseBindings parses the bindings of a roleset, given in HCL or JSON and
+// optionally base64-encoded, in the following format:
+//
+//	resource "//cloudresourcemanager.googleapis.com/projects/my-project" {
+//	  roles = ["roles/viewer", "roles/storage.objectViewer"]
+//	}
+func parseBindings(raw string) (resourceBindings, error) {
+	if decoded, err := base64.StdEncoding.DecodeString(raw); err == nil {
+		raw = string(decoded)
+	}
+
+	root, err := hcl.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse bindings: %s", err)
+	}
+	list, ok := root.Node.(*ast.ObjectList)
+	if !ok {
+		return nil, fmt.Errorf("unable to parse bindings: does not contain a root object")
+	}
+
+	bindings := make(resourceBindings)
+	for _, item := range list.Items {
+		if len(item.Keys) != 2 || item.Keys[0].Token.Value() != "resource" {
+			return nil, fmt.Errorf("unable to parse bindings: expected only resource blocks")
+		}
+		resource, ok := item.Keys[1].Token.Value().(string)
+		if !ok || resource == "" {
+			return nil, fmt.Errorf("unable to parse bindings: invalid resource name")
+		}
+		if _, err := parseIAMResource(resource); err != nil {
+			return nil, err
+		}
+
+		var binding struct {
+			Roles []string `hcl:"roles"`
+		}
+		if err := hcl.DecodeObject(&binding, item.Val); err != nil {
+			return nil, fmt.Errorf("unable to parse bindings of resource %q: %s", resource, err)
+		}
+		roles := strutil.RemoveDuplicates(append(bindings[resource], binding.Roles...), false)
+		if len(roles) == 0 {
+			return nil, fmt.Errorf("no roles are bound on resource %q", resource)
+		}
+		bindings[resource] = roles
+	}
+	if len(bindings) == 0 {
+		return nil, fmt.Errorf("at least one resource must be bound")
+	}
+
+	return bindings, nil
+}
+
+// resources returns the sorted names of the bound resources.
+func (rb resourceBindings) resources() []string {
+	resources := make([]string, 0, len(rb))
+	for resource := range rb {
+		resources = append(resources, resource)
+	}
+	sort.Strings(resources)
+	return resources
+}
+
+// equal returns whether both bindings grant the same roles on the same
+// resources.
+func (rb resourceBindings) equal(other resourceBindings) bool {
+	if len(rb) != len(other) {
+		return false
+	}
+	for resource, roles := range rb {
+		if !strutil.EquivalentSlices(roles, other[resource]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,223 @@
+package gcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"google.golang.org/api/iam/v1"
+)
+
+// maxPolicyUpdateAttempts is the number of attempts to update an IAM policy
+// which is concurrently modified
+const maxPolicyUpdateAttempts = 5
+
+// iamResourceTypes are the types of GCP resources whose IAM policies can be
+// bound, with the version of the API of their service
+var iamResourceTypes = []struct {
+	service string
+	version string
+	regex   *regexp.Regexp
+}{
+	{"cloudresourcemanager", "v1", regexp.MustCompile(`^projects/[^/]+$`)},
+	{"cloudresourcemanager", "v1", regexp.MustCompile(`^organizations/[^/]+$`)},
+	{"cloudresourcemanager", "v2", regexp.MustCompile(`^folders/[^/]+$`)},
+	{"iam", "v1", regexp.MustCompile(`^projects/[^/]+/serviceAccounts/[^/]+$`)},
+	{"pubsub", "v1", regexp.MustCompile(`^projects/[^/]+/(topics|subscriptions)/[^/]+$`)},
+}
+
+// iamResource is a GCP resource whose IAM policy is managed with the
+// getIamPolicy and setIamPolicy methods of the API of its service
+type iamResource struct {
+	name string
+	url  string
+}
+
+// parseIAMResource parses the full name of a GCP resource, such as
+// "//cloudresourcemanager.googleapis.com/projects/my-project".
+func parseIAMResource(name string) (*iamResource, error) {
+	trimmed := strings.TrimPrefix(name, "//")
+	parts := strings.SplitN(trimmed, "/", 2)
+	if trimmed == name || len(parts) != 2 || !strings.HasSuffix(parts[0], ".googleapis.com") {
+		return nil, fmt.Errorf("invalid resource name %q: expected a full resource name such as //cloudresourcemanager.googleapis.com/projects/my-project", name)
+	}
+	service := strings.TrimSuffix(parts[0], ".googleapis.com")
+
+	for _, resourceType := range iamResourceTypes {
+		if resourceType.service == service && resourceType.regex.MatchString(parts[1]) {
+			return &iamResource{
+				name: name,
+				url:  fmt.Sprintf("https://%s/%s/%s", parts[0], resourceType.version, parts[1]),
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported resource %q", name)
+}
+
+// getPolicy returns the IAM policy of the resource.
+func (r *iamResource) getPolicy(ctx context.Context, client *http.Client) (*iam.Policy, error) {
+	policy := &iam.Policy{}
+	if err := r.call(ctx, client, "getIamPolicy", struct{}{}, policy); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// setPolicy replaces the IAM policy of the resource. The call fails if the
+// policy was modified since its etag was read.
+func (r *iamResource) setPolicy(ctx context.Context, client *http.Client, policy *iam.Policy) error {
+	return r.call(ctx, client, "setIamPolicy", &iam.SetIamPolicyRequest{Policy: policy}, nil)
+}
+
+// updatePolicy applies the update to the IAM policy of the resource, retrying
+// if the policy is concurrently modified. The policy is not set if the
+// update returns false.
+func (r *iamResource) updatePolicy(ctx context.Context, client *http.Client, update func(*iam.Policy) bool) error {
+	var err error
+	for attempt := 0; attempt < maxPolicyUpdateAttempts; attempt++ {
+		var policy *iam.Policy
+		policy, err = r.getPolicy(ctx, client)
+		if err != nil {
+			return err
+		}
+		if !update(policy) {
+			return nil
+		}
+		err = r.setPolicy(ctx, client, policy)
+		if !isConflict(err) {
+			return err
+		}
+	}
+	return err
+}
+
+// addBindings grants the roles on the resource to the member.
+func (r *iamResource) addBindings(ctx context.Context, client *http.Client, member string, roles []string) error {
+	return r.updatePolicy(ctx, client, func(policy *iam.Policy) bool {
+		changed := false
+		for _, role := range roles {
+			var binding *iam.Binding
+			for _, b := range policy.Bindings {
+				if b.Role == role {
+					binding = b
+					break
+				}
+			}
+			if binding == nil {
+				binding = &iam.Binding{Role: role}
+				policy.Bindings = append(policy.Bindings, binding)
+			}
+			if !hasMember(binding, member) {
+				binding.Members = append(binding.Members, member)
+				changed = true
+			}
+		}
+		return changed
+	})
+}
+
+// removeBindings revokes all the roles granted on the resource to the member.
+func (r *iamResource) removeBindings(ctx context.Context, client *http.Client, member string) error {
+	return r.updatePolicy(ctx, client, func(policy *iam.Policy) bool {
+		changed := false
+		bindings := policy.Bindings[:0]
+		for _, binding := range policy.Bindings {
+			if hasMember(binding, member) {
+				members := binding.Members[:0]
+				for _, m := range binding.Members {
+					if m != member {
+						members = append(members, m)
+					}
+				}
+				binding.Members = members
+				changed = true
+			}
+			if len(binding.Members) != 0 {
+				bindings = append(bindings, binding)
+			}
+		}
+		policy.Bindings = bindings
+		return changed
+	})
+}
+
+func hasMember(binding *iam.Binding, member string) bool {
+	for _, m := range binding.Members {
+		if m == member {
+			return true
+		}
+	}
+	return false
+}
+
+// call calls a method of the resource and decodes the JSON response into out
+// if it is not nil.
+func (r *iamResource) call(ctx context.Context, client *http.Client, method string, in, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, r.url+":"+method, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var errResp struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		apiErr := &apiError{StatusCode: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
+		if err := jsonutil.DecodeJSONFromReader(resp.Body, &errResp); err == nil && errResp.Error.Message != "" {
+			apiErr.Message = errResp.Error.Message
+		}
+		return errwrap.Wrapf(fmt.Sprintf("error calling %s of resource %q: {{err}}", method, r.name), apiErr)
+	}
+
+	if out == nil {
+		io.Copy(ioutil.Discard, resp.Body)
+		return nil
+	}
+	if err := jsonutil.DecodeJSONFromReader(resp.Body, out); err != nil {
+		return errwrap.Wrapf(fmt.Sprintf("error decoding %s response of resource %q: {{err}}", method, r.name), err)
+	}
+	return nil
+}
+
+// apiError is an error response of a GCP API
+type apiError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("googleapi: error %d: %s", e.StatusCode, e.Message)
+}
+
+// isConflict returns whether the error is caused by a concurrent modification
+// of an IAM policy.
+func isConflict(err error) bool {
+	if err == nil {
+		return false
+	}
+	apiErr, ok := errwrap.GetType(err, &apiError{}).(*apiError)
+	return ok && apiErr.StatusCode == http.StatusConflict
+}
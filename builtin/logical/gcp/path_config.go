@@ -0,0 +1,140 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+	"golang.org/x/oauth2/google"
+)
+
+const configKey = "config"
+
+func pathConfig(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config",
+		Fields: map[string]*framework.FieldSchema{
+			"credentials": {
+				Type: framework.TypeString,
+				Description: `JSON credentials file of the service account used by
+Vault to manage the service accounts of the rolesets. If not set, the
+application default credentials are used.`,
+			},
+
+			"ttl": {
+				Type:        framework.TypeDurationSecond,
+				Description: "Default lease duration of the generated service account keys",
+			},
+
+			"max_ttl": {
+				Type:        framework.TypeDurationSecond,
+				Description: "Maximum lease duration of the generated service account keys",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathConfigRead,
+			logical.UpdateOperation: b.pathConfigWrite,
+		},
+
+		HelpSynopsis:    pathConfigHelpSyn,
+		HelpDescription: pathConfigHelpDesc,
+	}
+}
+
+func (b *backend) readConfig(ctx context.Context, s logical.Storage) (*config, error) {
+	entry, err := s.Get(ctx, configKey)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	conf := &config{}
+	if err := entry.DecodeJSON(conf); err != nil {
+		return nil, errwrap.Wrapf("error reading gcp configuration: {{err}}", err)
+	}
+
+	return conf, nil
+}
+
+func (b *backend) pathConfigRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	conf, err := b.readConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if conf == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"ttl":     int64(conf.TTL.Seconds()),
+			"max_ttl": int64(conf.MaxTTL.Seconds()),
+		},
+	}, nil
+}
+
+func (b *backend) pathConfigWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	conf, err := b.readConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if conf == nil {
+		conf = &config{}
+	}
+
+	if credentials, ok := d.GetOk("credentials"); ok {
+		conf.Credentials = credentials.(string)
+		if conf.Credentials != "" {
+			if _, err := google.JWTConfigFromJSON([]byte(conf.Credentials), cloudPlatformScope); err != nil {
+				return logical.ErrorResponse(fmt.Sprintf("Invalid credentials: %s", err)), nil
+			}
+		}
+	}
+	if ttl, ok := d.GetOk("ttl"); ok {
+		conf.TTL = time.Duration(ttl.(int)) * time.Second
+	}
+	if maxTTL, ok := d.GetOk("max_ttl"); ok {
+		conf.MaxTTL = time.Duration(maxTTL.(int)) * time.Second
+	}
+	if conf.MaxTTL != 0 && conf.TTL > conf.MaxTTL {
+		return logical.ErrorResponse("ttl cannot be greater than max_ttl"), nil
+	}
+
+	entry, err := logical.StorageEntryJSON(configKey, conf)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+type config struct {
+	Credentials string        `json:"credentials"`
+	TTL         time.Duration `json:"ttl"`
+	MaxTTL      time.Duration `json:"max_ttl"`
+}
+
+const pathConfigHelpSyn = `
+Configure the credentials and the lease durations of the GCP backend.
+`
+
+const pathConfigHelpDesc = `
+This path configures the JSON credentials file of the service account used by
+Vault to create the service accounts of the rolesets and to manage their IAM
+bindings. This service account needs the permissions to manage service
+accounts and their keys in the projects of the rolesets, and to set the IAM
+policies of the resources of their bindings. If no credentials are configured,
+the application default credentials of the Vault server are used.
+
+The ttl and max_ttl parameters set the lease durations of the generated
+service account keys, and default to the lease durations of the mount.
+`
@@ -0,0 +1,471 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+const (
+	secretTypeAccessToken = "access_token"
+	secretTypeKey         = "service_account_key"
+)
+
+// roleSet defines the IAM roles granted on GCP resources to the service
+// account of the roleset, for which access tokens or keys are generated
+type roleSet struct {
+	Name        string           `json:"name"`
+	SecretType  string           `json:"secret_type"`
+	Project     string           `json:"project"`
+	Bindings    resourceBindings `json:"bindings"`
+	TokenScopes []string         `json:"token_scopes"`
+	Account     *serviceAccount  `json:"account"`
+
+	// TokenKey is the key of the service account used to generate the
+	// access tokens of access_token rolesets
+	TokenKey *tokenKey `json:"token_key,omitempty"`
+}
+
+type tokenKey struct {
+	Name           string `json:"name"`
+	PrivateKeyData string `json:"private_key_data"`
+}
+
+func pathListRoleSets(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "rolesets/?$",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ListOperation: b.pathRoleSetList,
+		},
+
+		HelpSynopsis:    pathListRoleSetsHelpSyn,
+		HelpDescription: pathListRoleSetsHelpDesc,
+	}
+}
+
+func pathRoleSet(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "roleset/" + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "Name of the roleset",
+			},
+
+			"secret_type": {
+				Type: framework.TypeString,
+				Description: `Type of the secrets generated for the roleset, either
+"access_token" or "service_account_key". Defaults to "access_token".`,
+			},
+
+			"project": {
+				Type:        framework.TypeString,
+				Description: "Project in which the service account of the roleset is created",
+			},
+
+			"bindings": {
+				Type: framework.TypeString,
+				Description: `IAM roles granted on GCP resources to the service account
+of the roleset, in HCL or JSON, optionally base64-encoded`,
+			},
+
+			"token_scopes": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: "OAuth2 scopes of the access tokens of access_token rolesets",
+			},
+		},
+
+		ExistenceCheck: b.pathRoleSetExistenceCheck,
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.DeleteOperation: b.pathRoleSetDelete,
+			logical.ReadOperation:   b.pathRoleSetRead,
+			logical.CreateOperation: b.pathRoleSetWrite,
+			logical.UpdateOperation: b.pathRoleSetWrite,
+		},
+
+		HelpSynopsis:    pathRoleSetHelpSyn,
+		HelpDescription: pathRoleSetHelpDesc,
+	}
+}
+
+func pathRoleSetRotateAccount(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "roleset/" + framework.GenericNameRegex("name") + "/rotate",
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "Name of the roleset",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathRoleSetRotateAccount,
+		},
+
+		HelpSynopsis:    pathRoleSetRotateAccountHelpSyn,
+		HelpDescription: pathRoleSetRotateAccountHelpDesc,
+	}
+}
+
+func pathRoleSetRotateKey(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "roleset/" + framework.GenericNameRegex("name") + "/rotate-key",
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "Name of the roleset",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathRoleSetRotateKey,
+		},
+
+		HelpSynopsis:    pathRoleSetRotateKeyHelpSyn,
+		HelpDescription: pathRoleSetRotateKeyHelpDesc,
+	}
+}
+
+func getRoleSet(ctx context.Context, s logical.Storage, name string) (*roleSet, error) {
+	entry, err := s.Get(ctx, "roleset/"+name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var rs roleSet
+	if err := entry.DecodeJSON(&rs); err != nil {
+		return nil, err
+	}
+	return &rs, nil
+}
+
+func putRoleSet(ctx context.Context, s logical.Storage, rs *roleSet) error {
+	entry, err := logical.StorageEntryJSON("roleset/"+rs.Name, rs)
+	if err != nil {
+		return err
+	}
+	return s.Put(ctx, entry)
+}
+
+func (b *backend) pathRoleSetExistenceCheck(ctx context.Context, req *logical.Request, d *framework.FieldData) (bool, error) {
+	rs, err := getRoleSet(ctx, req.Storage, d.Get("name").(string))
+	if err != nil {
+		return false, err
+	}
+	return rs != nil, nil
+}
+
+func (b *backend) pathRoleSetList(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	entries, err := req.Storage.List(ctx, "roleset/")
+	if err != nil {
+		return nil, err
+	}
+	return logical.ListResponse(entries), nil
+}
+
+func (b *backend) pathRoleSetRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	rs, err := getRoleSet(ctx, req.Storage, d.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if rs == nil {
+		return nil, nil
+	}
+
+	data := map[string]interface{}{
+		"secret_type": rs.SecretType,
+		"project":     rs.Project,
+		"bindings":    rs.Bindings,
+	}
+	if rs.Account != nil {
+		data["service_account_email"] = rs.Account.Email
+	}
+	if rs.SecretType == secretTypeAccessToken {
+		data["token_scopes"] = rs.TokenScopes
+	}
+
+	return &logical.Response{
+		Data: data,
+	}, nil
+}
+
+func (b *backend) pathRoleSetWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	b.rolesetLock.Lock()
+	defer b.rolesetLock.Unlock()
+
+	existing, err := getRoleSet(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+
+	rs := &roleSet{
+		Name:       name,
+		SecretType: secretTypeAccessToken,
+	}
+	if existing != nil {
+		*rs = *existing
+	}
+
+	if secretType, ok := d.GetOk("secret_type"); ok {
+		rs.SecretType = secretType.(string)
+	}
+	if project, ok := d.GetOk("project"); ok {
+		rs.Project = project.(string)
+	}
+	if rawBindings, ok := d.GetOk("bindings"); ok {
+		bindings, err := parseBindings(rawBindings.(string))
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+		rs.Bindings = bindings
+	}
+	if tokenScopes, ok := d.GetOk("token_scopes"); ok {
+		rs.TokenScopes = tokenScopes.([]string)
+	}
+
+	switch {
+	case rs.SecretType != secretTypeAccessToken && rs.SecretType != secretTypeKey:
+		return logical.ErrorResponse(fmt.Sprintf("invalid secret_type %q", rs.SecretType)), nil
+	case rs.Project == "":
+		return logical.ErrorResponse("project is required"), nil
+	case len(rs.Bindings) == 0:
+		return logical.ErrorResponse("bindings are required"), nil
+	case rs.SecretType == secretTypeAccessToken && len(rs.TokenScopes) == 0:
+		return logical.ErrorResponse("token_scopes are required for the access_token secret type"), nil
+	}
+
+	// A new service account is created when its project or IAM roles change
+	if existing == nil || existing.Project != rs.Project || !existing.Bindings.equal(rs.Bindings) {
+		return b.rotateServiceAccount(ctx, req.Storage, rs, existing)
+	}
+
+	var resp *logical.Response
+	oldKey := rs.TokenKey
+	switch {
+	case rs.SecretType == secretTypeAccessToken && rs.TokenKey == nil:
+		key, err := b.createServiceAccountKey(ctx, req.Storage, rs.Account, keyAlgorithmRSA2048, keyTypeCredentialFile)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+		rs.TokenKey = &tokenKey{
+			Name:           key.Name,
+			PrivateKeyData: key.PrivateKeyData,
+		}
+		oldKey = nil
+	case rs.SecretType == secretTypeKey:
+		rs.TokenKey = nil
+	}
+
+	if err := putRoleSet(ctx, req.Storage, rs); err != nil {
+		return nil, err
+	}
+
+	if oldKey != nil && rs.TokenKey == nil {
+		if err := b.deleteServiceAccountKey(ctx, req.Storage, oldKey.Name); err != nil {
+			resp = &logical.Response{}
+			resp.AddWarning(fmt.Sprintf("unable to delete the key used to generate access tokens: %s", err))
+		}
+	}
+
+	return resp, nil
+}
+
+// rotateServiceAccount creates a new service account for the roleset, with its
+// IAM roles and the key generating its access tokens, then deletes the
+// previous service account of the roleset. The caller must hold the roleset
+// lock.
+func (b *backend) rotateServiceAccount(ctx context.Context, s logical.Storage, rs *roleSet, existing *roleSet) (*logical.Response, error) {
+	account, walID, err := b.createServiceAccount(ctx, s, rs)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+	rs.Account = account
+
+	rs.TokenKey = nil
+	if rs.SecretType == secretTypeAccessToken {
+		key, err := b.createServiceAccountKey(ctx, s, account, keyAlgorithmRSA2048, keyTypeCredentialFile)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+		rs.TokenKey = &tokenKey{
+			Name:           key.Name,
+			PrivateKeyData: key.PrivateKeyData,
+		}
+	}
+
+	if err := putRoleSet(ctx, s, rs); err != nil {
+		return nil, err
+	}
+	if err := framework.DeleteWAL(ctx, s, walID); err != nil {
+		return nil, err
+	}
+
+	if existing == nil || existing.Account == nil {
+		return nil, nil
+	}
+	return b.cleanupServiceAccount(ctx, s, existing)
+}
+
+// cleanupServiceAccount deletes the service account of a roleset which was
+// rotated or deleted. If the service account cannot be deleted, its deletion
+// is retried later on.
+func (b *backend) cleanupServiceAccount(ctx context.Context, s logical.Storage, rs *roleSet) (*logical.Response, error) {
+	walID, err := framework.PutWAL(ctx, s, walTypeServiceAccount, &walServiceAccount{
+		RoleSet: rs.Name,
+		Account: rs.Account,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error writing WAL entry: %s", err)
+	}
+
+	if err := b.deleteServiceAccount(ctx, s, rs.Account); err != nil {
+		resp := &logical.Response{}
+		resp.AddWarning(fmt.Sprintf("unable to delete previous service account %q, its deletion will be retried: %s", rs.Account.Email, err))
+		return resp, nil
+	}
+
+	if err := framework.DeleteWAL(ctx, s, walID); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (b *backend) pathRoleSetDelete(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	b.rolesetLock.Lock()
+	defer b.rolesetLock.Unlock()
+
+	rs, err := getRoleSet(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if rs == nil {
+		return nil, nil
+	}
+
+	if err := req.Storage.Delete(ctx, "roleset/"+name); err != nil {
+		return nil, err
+	}
+
+	if rs.Account == nil {
+		return nil, nil
+	}
+	return b.cleanupServiceAccount(ctx, req.Storage, rs)
+}
+
+func (b *backend) pathRoleSetRotateAccount(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	b.rolesetLock.Lock()
+	defer b.rolesetLock.Unlock()
+
+	existing, err := getRoleSet(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return logical.ErrorResponse(fmt.Sprintf("roleset %q not found", name)), nil
+	}
+
+	rs := &roleSet{}
+	*rs = *existing
+	return b.rotateServiceAccount(ctx, req.Storage, rs, existing)
+}
+
+func (b *backend) pathRoleSetRotateKey(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	b.rolesetLock.Lock()
+	defer b.rolesetLock.Unlock()
+
+	rs, err := getRoleSet(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if rs == nil {
+		return logical.ErrorResponse(fmt.Sprintf("roleset %q not found", name)), nil
+	}
+	if rs.SecretType != secretTypeAccessToken {
+		return logical.ErrorResponse(fmt.Sprintf("roleset %q does not generate access tokens", name)), nil
+	}
+
+	key, err := b.createServiceAccountKey(ctx, req.Storage, rs.Account, keyAlgorithmRSA2048, keyTypeCredentialFile)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+	oldKey := rs.TokenKey
+	rs.TokenKey = &tokenKey{
+		Name:           key.Name,
+		PrivateKeyData: key.PrivateKeyData,
+	}
+	if err := putRoleSet(ctx, req.Storage, rs); err != nil {
+		return nil, err
+	}
+
+	if oldKey != nil {
+		if err := b.deleteServiceAccountKey(ctx, req.Storage, oldKey.Name); err != nil {
+			resp := &logical.Response{}
+			resp.AddWarning(fmt.Sprintf("unable to delete the previous key used to generate access tokens: %s", err))
+			return resp, nil
+		}
+	}
+
+	return nil, nil
+}
+
+const pathListRoleSetsHelpSyn = `List the existing rolesets in this backend`
+
+const pathListRoleSetsHelpDesc = `Rolesets will be listed by the roleset name.`
+
+const pathRoleSetHelpSyn = `
+Read, write and delete the rolesets of which access tokens or keys can be
+generated.
+`
+
+const pathRoleSetHelpDesc = `
+This path allows you to read, write and delete rolesets. A roleset binds IAM
+roles on GCP resources to a service account which Vault creates in the
+project of the roleset. For example, if the backend is mounted at "gcp" and
+you create a roleset at "gcp/roleset/deploy", then a user could request access
+tokens at "gcp/token/deploy", or service account keys at "gcp/key/deploy".
+
+The bindings are given in HCL or JSON, optionally base64-encoded:
+
+	resource "//cloudresourcemanager.googleapis.com/projects/my-project" {
+	  roles = ["roles/viewer"]
+	}
+
+A new service account is created, and the previous one deleted, whenever the
+project or the bindings of the roleset change. This revokes the keys and the
+access tokens generated for the previous service account.
+`
+
+const pathRoleSetRotateAccountHelpSyn = `
+Rotate the service account of a roleset.
+`
+
+const pathRoleSetRotateAccountHelpDesc = `
+This path creates a new service account for the roleset, with the same IAM
+roles, and deletes the previous service account. This revokes the keys and the
+access tokens generated for the previous service account.
+`
+
+const pathRoleSetRotateKeyHelpSyn = `
+Rotate the key used to generate the access tokens of a roleset.
+`
+
+const pathRoleSetRotateKeyHelpDesc = `
+This path replaces the service account key which Vault uses to generate the
+access tokens of an access_token roleset. Access tokens which were already
+generated remain valid until they expire.
+`
@@ -0,0 +1,95 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+func pathSecretServiceAccountKey(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "key/" + framework.GenericNameRegex("roleset"),
+		Fields: map[string]*framework.FieldSchema{
+			"roleset": {
+				Type:        framework.TypeString,
+				Description: "Name of the roleset",
+			},
+
+			"key_algorithm": {
+				Type:        framework.TypeString,
+				Description: "Algorithm of the generated key",
+				Default:     keyAlgorithmRSA2048,
+			},
+
+			"key_type": {
+				Type:        framework.TypeString,
+				Description: "Format of the private key data of the generated key",
+				Default:     keyTypeCredentialFile,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathServiceAccountKeyRead,
+			logical.UpdateOperation: b.pathServiceAccountKeyRead,
+		},
+
+		HelpSynopsis:    pathServiceAccountKeyHelpSyn,
+		HelpDescription: pathServiceAccountKeyHelpDesc,
+	}
+}
+
+func (b *backend) pathServiceAccountKeyRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("roleset").(string)
+
+	rs, err := getRoleSet(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if rs == nil {
+		return logical.ErrorResponse(fmt.Sprintf("roleset %q not found", name)), nil
+	}
+	if rs.SecretType != secretTypeKey {
+		return logical.ErrorResponse(fmt.Sprintf("roleset %q does not generate service account keys", name)), nil
+	}
+
+	conf, err := b.readConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if conf == nil {
+		conf = &config{}
+	}
+
+	key, err := b.createServiceAccountKey(ctx, req.Storage, rs.Account, d.Get("key_algorithm").(string), d.Get("key_type").(string))
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	resp := b.Secret(SecretServiceAccountKeyType).Response(map[string]interface{}{
+		"private_key_data": key.PrivateKeyData,
+		"key_algorithm":    key.KeyAlgorithm,
+		"key_type":         key.PrivateKeyType,
+	}, map[string]interface{}{
+		"key_name":              key.Name,
+		"role_set":              rs.Name,
+		"service_account_email": rs.Account.Email,
+	})
+	resp.Secret.TTL = conf.TTL
+
+	return resp, nil
+}
+
+const pathServiceAccountKeyHelpSyn = `
+Generate a key of the service account of a roleset.
+`
+
+const pathServiceAccountKeyHelpDesc = `
+This path generates a key of the service account of a service_account_key
+roleset. For example, if this backend is mounted at "gcp", then
+"gcp/key/deploy" would generate keys for the "deploy" roleset.
+
+The key is deleted when its lease is revoked, or when the service account of
+the roleset is rotated or deleted.
+`
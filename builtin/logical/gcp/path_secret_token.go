@@ -0,0 +1,86 @@
+package gcp
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+func pathSecretAccessToken(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "token/" + framework.GenericNameRegex("roleset"),
+		Fields: map[string]*framework.FieldSchema{
+			"roleset": {
+				Type:        framework.TypeString,
+				Description: "Name of the roleset",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathAccessTokenRead,
+			logical.UpdateOperation: b.pathAccessTokenRead,
+		},
+
+		HelpSynopsis:    pathAccessTokenHelpSyn,
+		HelpDescription: pathAccessTokenHelpDesc,
+	}
+}
+
+func (b *backend) pathAccessTokenRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("roleset").(string)
+
+	rs, err := getRoleSet(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if rs == nil {
+		return logical.ErrorResponse(fmt.Sprintf("roleset %q not found", name)), nil
+	}
+	if rs.SecretType != secretTypeAccessToken || rs.TokenKey == nil {
+		return logical.ErrorResponse(fmt.Sprintf("roleset %q does not generate access tokens", name)), nil
+	}
+
+	credentials, err := base64.StdEncoding.DecodeString(rs.TokenKey.PrivateKeyData)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding the key of roleset %q: %s", name, err)
+	}
+	jwtConfig, err := google.JWTConfigFromJSON(credentials, rs.TokenScopes...)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing the key of roleset %q: %s", name, err)
+	}
+
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, b.baseHTTPClient())
+	token, err := jwtConfig.TokenSource(ctx).Token()
+	if err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("error generating access token: %s", err)), nil
+	}
+
+	// Access tokens cannot be revoked, so they are returned without a lease
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"token":              token.AccessToken,
+			"expires_at_seconds": token.Expiry.Unix(),
+			"token_ttl":          int64(token.Expiry.Sub(time.Now()).Seconds()),
+		},
+	}, nil
+}
+
+const pathAccessTokenHelpSyn = `
+Generate an OAuth2 access token of the service account of a roleset.
+`
+
+const pathAccessTokenHelpDesc = `
+This path generates an OAuth2 access token, with the token_scopes of the
+roleset, of the service account of an access_token roleset. For example, if
+this backend is mounted at "gcp", then "gcp/token/deploy" would generate
+access tokens for the "deploy" roleset.
+
+Access tokens cannot be revoked, so they have no lease and remain valid until
+they expire, usually after an hour.
+`
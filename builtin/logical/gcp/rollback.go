@@ -0,0 +1,49 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/vault/helper/consts"
+	"github.com/hashicorp/vault/logical"
+	"github.com/mitchellh/mapstructure"
+)
+
+func (b *backend) walRollback(ctx context.Context, req *logical.Request, kind string, data interface{}) error {
+	if !b.System().LocalMount() && b.System().ReplicationState().HasState(consts.ReplicationPerformancePrimary) {
+		return nil
+	}
+
+	switch kind {
+	case walTypeServiceAccount:
+		return b.serviceAccountRollback(ctx, req, data)
+	default:
+		return fmt.Errorf("unknown type to rollback")
+	}
+}
+
+// serviceAccountRollback deletes a service account which is not used by its
+// roleset, because the roleset could not be written, was rotated or was
+// deleted.
+func (b *backend) serviceAccountRollback(ctx context.Context, req *logical.Request, data interface{}) error {
+	var entry walServiceAccount
+	if err := mapstructure.Decode(data, &entry); err != nil {
+		return err
+	}
+	if entry.Account == nil {
+		return nil
+	}
+
+	b.rolesetLock.Lock()
+	defer b.rolesetLock.Unlock()
+
+	rs, err := getRoleSet(ctx, req.Storage, entry.RoleSet)
+	if err != nil {
+		return err
+	}
+	if rs != nil && rs.Account != nil && rs.Account.Email == entry.Account.Email {
+		return nil
+	}
+
+	return b.deleteServiceAccount(ctx, req.Storage, entry.Account)
+}
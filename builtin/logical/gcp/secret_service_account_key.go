@@ -0,0 +1,80 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+const SecretServiceAccountKeyType = "service_account_key"
+
+func secretServiceAccountKey(b *backend) *framework.Secret {
+	return &framework.Secret{
+		Type: SecretServiceAccountKeyType,
+		Fields: map[string]*framework.FieldSchema{
+			"private_key_data": {
+				Type:        framework.TypeString,
+				Description: "Base64-encoded private key data of the key",
+			},
+			"key_algorithm": {
+				Type:        framework.TypeString,
+				Description: "Algorithm of the key",
+			},
+			"key_type": {
+				Type:        framework.TypeString,
+				Description: "Format of the private key data",
+			},
+		},
+
+		Renew:  b.secretServiceAccountKeyRenew,
+		Revoke: b.secretServiceAccountKeyRevoke,
+	}
+}
+
+func (b *backend) secretServiceAccountKeyRenew(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	roleSetRaw, ok := req.Secret.InternalData["role_set"]
+	if !ok {
+		return nil, fmt.Errorf("secret is missing role_set internal data")
+	}
+	emailRaw, ok := req.Secret.InternalData["service_account_email"]
+	if !ok {
+		return nil, fmt.Errorf("secret is missing service_account_email internal data")
+	}
+
+	// Keys of rotated or deleted service accounts no longer exist
+	rs, err := getRoleSet(ctx, req.Storage, roleSetRaw.(string))
+	if err != nil {
+		return nil, err
+	}
+	if rs == nil || rs.Account == nil || rs.Account.Email != emailRaw.(string) {
+		return logical.ErrorResponse("the service account of the key was rotated or deleted"), nil
+	}
+
+	conf, err := b.readConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if conf == nil {
+		conf = &config{}
+	}
+
+	return framework.LeaseExtend(conf.TTL, conf.MaxTTL, b.System())(ctx, req, d)
+}
+
+func (b *backend) secretServiceAccountKeyRevoke(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	keyNameRaw, ok := req.Secret.InternalData["key_name"]
+	if !ok {
+		return nil, fmt.Errorf("secret is missing key_name internal data")
+	}
+	keyName, ok := keyNameRaw.(string)
+	if !ok {
+		return nil, fmt.Errorf("secret has key_name but value could not be understood")
+	}
+
+	if err := b.deleteServiceAccountKey(ctx, req.Storage, keyName); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
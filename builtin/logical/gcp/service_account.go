@@ -0,0 +1,201 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/iam/v1"
+)
+
+const (
+	walTypeServiceAccount = "service_account"
+
+	keyAlgorithmRSA2048   = "KEY_ALG_RSA_2048"
+	keyTypeCredentialFile = "TYPE_GOOGLE_CREDENTIALS_FILE"
+)
+
+var invalidAccountIDChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// serviceAccount is a service account created for a roleset, with the IAM
+// roles granted to it
+type serviceAccount struct {
+	Project  string           `json:"project" mapstructure:"project"`
+	Email    string           `json:"email" mapstructure:"email"`
+	Bindings resourceBindings `json:"bindings" mapstructure:"bindings"`
+}
+
+func (sa *serviceAccount) resourceName() string {
+	return fmt.Sprintf("projects/%s/serviceAccounts/%s", sa.Project, sa.Email)
+}
+
+func (sa *serviceAccount) member() string {
+	return "serviceAccount:" + sa.Email
+}
+
+// walServiceAccount is the WAL entry of a service account which must be
+// deleted unless its roleset still uses it
+type walServiceAccount struct {
+	RoleSet string          `json:"role_set" mapstructure:"role_set"`
+	Account *serviceAccount `json:"account" mapstructure:"account"`
+}
+
+// accountID returns a new service account ID for the roleset. Service account
+// IDs are 6 to 30 characters long, and made of lowercase letters, digits and
+// hyphens.
+func accountID(roleSetName string) (string, error) {
+	suffix, err := uuid.GenerateRandomBytes(4)
+	if err != nil {
+		return "", err
+	}
+
+	name := invalidAccountIDChars.ReplaceAllString(strings.ToLower(roleSetName), "-")
+	if len(name) > 16 {
+		name = name[:16]
+	}
+	name = strings.Trim(name, "-")
+	return fmt.Sprintf("vault%s-%x", name, suffix), nil
+}
+
+// createServiceAccount creates a service account for the roleset and grants
+// it the IAM roles of the roleset. A WAL entry deleting the service account is
+// written first, and its ID is returned so that the caller can remove the entry
+// once the roleset uses the service account.
+func (b *backend) createServiceAccount(ctx context.Context, s logical.Storage, rs *roleSet) (*serviceAccount, string, error) {
+	id, err := accountID(rs.Name)
+	if err != nil {
+		return nil, "", err
+	}
+	account := &serviceAccount{
+		Project:  rs.Project,
+		Email:    fmt.Sprintf("%s@%s.iam.gserviceaccount.com", id, rs.Project),
+		Bindings: rs.Bindings,
+	}
+
+	walID, err := framework.PutWAL(ctx, s, walTypeServiceAccount, &walServiceAccount{
+		RoleSet: rs.Name,
+		Account: account,
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("error writing WAL entry: %s", err)
+	}
+
+	iamClient, err := b.iamClient(ctx, s)
+	if err != nil {
+		return nil, "", err
+	}
+	created, err := iamClient.Projects.ServiceAccounts.Create("projects/"+rs.Project, &iam.CreateServiceAccountRequest{
+		AccountId: id,
+		ServiceAccount: &iam.ServiceAccount{
+			DisplayName: fmt.Sprintf("Service account of Vault roleset %s", rs.Name),
+		},
+	}).Context(ctx).Do()
+	if err != nil {
+		return nil, "", fmt.Errorf("error creating service account: %s", err)
+	}
+	account.Email = created.Email
+
+	client, err := b.httpClient(ctx, s)
+	if err != nil {
+		return nil, "", err
+	}
+	for _, name := range account.Bindings.resources() {
+		resource, err := parseIAMResource(name)
+		if err != nil {
+			return nil, "", err
+		}
+		if err := resource.addBindings(ctx, client, account.member(), account.Bindings[name]); err != nil {
+			return nil, "", err
+		}
+	}
+
+	return account, walID, nil
+}
+
+// deleteServiceAccount revokes the IAM roles granted to the service account
+// and deletes it. Resources and service accounts which no longer exist are
+// considered cleaned up.
+func (b *backend) deleteServiceAccount(ctx context.Context, s logical.Storage, account *serviceAccount) error {
+	client, err := b.httpClient(ctx, s)
+	if err != nil {
+		return err
+	}
+
+	var result error
+	for _, name := range account.Bindings.resources() {
+		resource, err := parseIAMResource(name)
+		if err != nil {
+			result = multierror.Append(result, err)
+			continue
+		}
+		if err := resource.removeBindings(ctx, client, account.member()); err != nil && !isNotFound(err) {
+			result = multierror.Append(result, err)
+		}
+	}
+	if result != nil {
+		return result
+	}
+
+	iamClient, err := b.iamClient(ctx, s)
+	if err != nil {
+		return err
+	}
+	_, err = iamClient.Projects.ServiceAccounts.Delete(account.resourceName()).Context(ctx).Do()
+	if err != nil && !isNotFound(err) {
+		return fmt.Errorf("error deleting service account %q: %s", account.Email, err)
+	}
+	return nil
+}
+
+// createServiceAccountKey creates a key of the service account.
+func (b *backend) createServiceAccountKey(ctx context.Context, s logical.Storage, account *serviceAccount, keyAlgorithm, keyType string) (*iam.ServiceAccountKey, error) {
+	iamClient, err := b.iamClient(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := iamClient.Projects.ServiceAccounts.Keys.Create(account.resourceName(), &iam.CreateServiceAccountKeyRequest{
+		KeyAlgorithm:   keyAlgorithm,
+		PrivateKeyType: keyType,
+	}).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("error creating key of service account %q: %s", account.Email, err)
+	}
+	return key, nil
+}
+
+// deleteServiceAccountKey deletes a key of a service account. Keys which no
+// longer exist, including those of deleted service accounts, are considered
+// deleted.
+func (b *backend) deleteServiceAccountKey(ctx context.Context, s logical.Storage, keyName string) error {
+	iamClient, err := b.iamClient(ctx, s)
+	if err != nil {
+		return err
+	}
+
+	_, err = iamClient.Projects.ServiceAccounts.Keys.Delete(keyName).Context(ctx).Do()
+	if err != nil && !isNotFound(err) {
+		return fmt.Errorf("error deleting service account key %q: %s", keyName, err)
+	}
+	return nil
+}
+
+// isNotFound returns whether the error is a GCP API error for a missing
+// resource.
+func isNotFound(err error) bool {
+	if gErr, ok := err.(*googleapi.Error); ok {
+		return gErr.Code == http.StatusNotFound
+	}
+	if apiErr, ok := errwrap.GetType(err, &apiError{}).(*apiError); ok {
+		return apiErr.StatusCode == http.StatusNotFound
+	}
+	return false
+}
@@ -0,0 +1,214 @@
+package kv
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/helper/consts"
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/helper/locksutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// defaultMaxVersions is the number of versions kept of each key when neither
+// the backend nor the key configure it
+const defaultMaxVersions = 10
+
+func Factory(ctx context.Context, conf *logical.BackendConfig) (logical.Backend, error) {
+	b := Backend()
+	if err := b.Setup(ctx, conf); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func Backend() *backend {
+	var b backend
+	b.Backend = &framework.Backend{
+		Help: strings.TrimSpace(backendHelp),
+
+		PathsSpecial: &logical.Paths{
+			SealWrapStorage: []string{
+				"versions/",
+			},
+		},
+
+		Paths: []*framework.Path{
+			pathConfig(&b),
+			pathData(&b),
+			pathMetadata(&b),
+			pathDelete(&b),
+			pathUndelete(&b),
+			pathDestroy(&b),
+			pathSubkeys(&b),
+		},
+
+		Secrets:      []*framework.Secret{},
+		PeriodicFunc: b.periodicFunc,
+		BackendType:  logical.TypeLogical,
+	}
+
+	b.locks = locksutil.CreateLocks()
+
+	return &b
+}
+
+func (b *backend) periodicFunc(ctx context.Context, req *logical.Request) error {
+	// Versions of performance secondaries are deleted on the primary
+	if !b.System().LocalMount() && b.System().ReplicationState().HasState(consts.ReplicationPerformanceSecondary) {
+		return nil
+	}
+	return b.deleteExpiredVersions(ctx, req.Storage, time.Now())
+}
+
+type backend struct {
+	*framework.Backend
+
+	// locks serialize the updates of the metadata of each key
+	locks []*locksutil.LockEntry
+}
+
+// versionMetadata is the metadata of a version of a key
+type versionMetadata struct {
+	CreatedTime  time.Time `json:"created_time"`
+	DeletionTime time.Time `json:"deletion_time"`
+	Destroyed    bool      `json:"destroyed"`
+}
+
+// deleted returns whether the version is deleted or destroyed
+func (v *versionMetadata) deleted() bool {
+	return v.Destroyed || !v.DeletionTime.IsZero()
+}
+
+func (v *versionMetadata) response(version int) map[string]interface{} {
+	return map[string]interface{}{
+		"version":       version,
+		"created_time":  formatTime(v.CreatedTime),
+		"deletion_time": formatTime(v.DeletionTime),
+		"destroyed":     v.Destroyed,
+	}
+}
+
+// keyMetadata is the metadata of a key and of the versions which are kept
+type keyMetadata struct {
+	Key                string                   `json:"key"`
+	Versions           map[int]*versionMetadata `json:"versions"`
+	CurrentVersion     int                      `json:"current_version"`
+	OldestVersion      int                      `json:"oldest_version"`
+	MaxVersions        int                      `json:"max_versions"`
+	CASRequired        bool                     `json:"cas_required"`
+	CreatedTime        time.Time                `json:"created_time"`
+	UpdatedTime        time.Time                `json:"updated_time"`
+	DeleteVersionAfter time.Duration            `json:"delete_version_after"`
+}
+
+func newKeyMetadata(key string, now time.Time) *keyMetadata {
+	return &keyMetadata{
+		Key:         key,
+		Versions:    map[int]*versionMetadata{},
+		CreatedTime: now,
+		UpdatedTime: now,
+	}
+}
+
+func getKeyMetadata(ctx context.Context, s logical.Storage, key string) (*keyMetadata, error) {
+	entry, err := s.Get(ctx, "metadata/"+key)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var meta keyMetadata
+	if err := jsonutil.DecodeJSON(entry.Value, &meta); err != nil {
+		return nil, fmt.Errorf("failed to decode the metadata of %q: %v", key, err)
+	}
+	if meta.Versions == nil {
+		meta.Versions = map[int]*versionMetadata{}
+	}
+	return &meta, nil
+}
+
+func (m *keyMetadata) persist(ctx context.Context, s logical.Storage) error {
+	entry, err := logical.StorageEntryJSON("metadata/"+m.Key, m)
+	if err != nil {
+		return err
+	}
+	return s.Put(ctx, entry)
+}
+
+// maxVersions returns the number of versions kept of the key, which is
+// configured on the key or else on the backend
+func (m *keyMetadata) maxVersions(config *kvConfig) int {
+	switch {
+	case m.MaxVersions != 0:
+		return m.MaxVersions
+	case config.MaxVersions != 0:
+		return config.MaxVersions
+	default:
+		return defaultMaxVersions
+	}
+}
+
+// deleteVersionAfter returns the duration after which versions of the key are
+// deleted, which is configured on the key or else on the backend
+func (m *keyMetadata) deleteVersionAfter(config *kvConfig) time.Duration {
+	if m.DeleteVersionAfter != 0 {
+		return m.DeleteVersionAfter
+	}
+	return config.DeleteVersionAfter
+}
+
+// validKey returns whether secrets can be written to the key, which cannot end
+// with a slash since it would be listed as a prefix
+func validKey(key string) bool {
+	return key != "" && !strings.HasSuffix(key, "/")
+}
+
+// versionKey returns the storage key of a version of the key. Keys are
+// hashed so that their versions are not listed with the keys themselves.
+func versionKey(key string, version int) string {
+	hash := sha256.Sum256([]byte(key))
+	return "versions/" + hex.EncodeToString(hash[:]) + "/" + strconv.Itoa(version)
+}
+
+// pruneVersions permanently deletes the oldest versions of the key beyond the
+// number of versions to keep
+func pruneVersions(ctx context.Context, s logical.Storage, meta *keyMetadata, maxVersions int) error {
+	for ; meta.OldestVersion <= meta.CurrentVersion-maxVersions; meta.OldestVersion++ {
+		if err := s.Delete(ctx, versionKey(meta.Key, meta.OldestVersion)); err != nil {
+			return err
+		}
+		delete(meta.Versions, meta.OldestVersion)
+	}
+	return nil
+}
+
+// formatTime returns the time in RFC 3339 format, or an empty string if it is
+// not set
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+const backendHelp = `
+The versioned key/value backend stores arbitrary secrets, keeping a number of
+previous versions of each of them so that they can be read or restored after
+being overwritten or deleted.
+
+Secrets are written to and read from "data/", and the versions of each secret
+are managed with "metadata/", "delete/", "undelete/" and "destroy/". The
+structure of secrets can be read without their values from "subkeys/".
+
+Versions can be deleted automatically once they are older than a duration
+configured on the backend or on each secret.
+`
@@ -0,0 +1,67 @@
+package kv
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func createBackendWithStorage(t *testing.T) (*backend, logical.Storage) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+
+	b := Backend()
+	if err := b.Setup(context.Background(), config); err != nil {
+		t.Fatal(err)
+	}
+	return b, config.StorageView
+}
+
+// testRequests returns helpers for requests to the backend, which fail the
+// test on unexpected errors
+func testRequests(t *testing.T, b *backend, storage logical.Storage) (
+	func(op logical.Operation, path string, data map[string]interface{}) *logical.Response,
+	func(op logical.Operation, path string, data map[string]interface{})) {
+
+	request := func(op logical.Operation, path string, data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Operation: op,
+			Path:      path,
+			Storage:   storage,
+			Data:      data,
+		})
+	}
+	doReq := func(op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		resp, err := request(op, path, data)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: path: %s, err: %v, resp: %#v", path, err, resp)
+		}
+		return resp
+	}
+	expectError := func(op logical.Operation, path string, data map[string]interface{}) {
+		resp, err := request(op, path, data)
+		if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() {
+			t.Fatalf("expected an error: path: %s, err: %v, resp: %#v", path, err, resp)
+		}
+	}
+	return doReq, expectError
+}
+
+func TestKV_Config(t *testing.T) {
+	b, storage := createBackendWithStorage(t)
+	doReq, expectError := testRequests(t, b, storage)
+
+	resp := doReq(logical.ReadOperation, "config", nil)
+	if resp.Data["max_versions"] != defaultMaxVersions || resp.Data["cas_required"] != false {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	expectError(logical.UpdateOperation, "config", map[string]interface{}{"max_versions": -1})
+	doReq(logical.UpdateOperation, "config", map[string]interface{}{"max_versions": 2, "cas_required": true})
+	doReq(logical.UpdateOperation, "config", map[string]interface{}{"max_versions": 3})
+	resp = doReq(logical.ReadOperation, "config", nil)
+	if resp.Data["max_versions"] != 3 || resp.Data["cas_required"] != true {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+}
@@ -0,0 +1,125 @@
+package kv
+
+import (
+	"context"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// kvConfig is the configuration of the backend, applying to all keys
+type kvConfig struct {
+	MaxVersions        int           `json:"max_versions"`
+	CASRequired        bool          `json:"cas_required"`
+	DeleteVersionAfter time.Duration `json:"delete_version_after"`
+}
+
+func pathConfig(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config$",
+		Fields: map[string]*framework.FieldSchema{
+			"max_versions": {
+				Type: framework.TypeInt,
+				Description: `The number of versions kept of each key, unless
+configured on the key. Defaults to 10.`,
+			},
+
+			"cas_required": {
+				Type: framework.TypeBool,
+				Description: `Whether all writes must use the check-and-set
+option, whatever the configuration of the key.`,
+			},
+
+			"delete_version_after": {
+				Type: framework.TypeDurationSecond,
+				Description: `The duration after which versions are deleted,
+unless configured on the key. Zero disables the
+deletion of versions.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathConfigRead,
+			logical.UpdateOperation: b.pathConfigWrite,
+		},
+
+		HelpSynopsis:    pathConfigHelpSyn,
+		HelpDescription: pathConfigHelpDesc,
+	}
+}
+
+func (b *backend) config(ctx context.Context, s logical.Storage) (*kvConfig, error) {
+	config := &kvConfig{}
+	entry, err := s.Get(ctx, "config")
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return config, nil
+	}
+	if err := entry.DecodeJSON(config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func (b *backend) pathConfigRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config, err := b.config(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	maxVersions := config.MaxVersions
+	if maxVersions == 0 {
+		maxVersions = defaultMaxVersions
+	}
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"max_versions":         maxVersions,
+			"cas_required":         config.CASRequired,
+			"delete_version_after": int64(config.DeleteVersionAfter.Seconds()),
+		},
+	}, nil
+}
+
+func (b *backend) pathConfigWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config, err := b.config(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	if maxVersionsRaw, ok := d.GetOk("max_versions"); ok {
+		config.MaxVersions = maxVersionsRaw.(int)
+		if config.MaxVersions < 0 {
+			return logical.ErrorResponse("max_versions cannot be negative"), logical.ErrInvalidRequest
+		}
+	}
+	if casRequiredRaw, ok := d.GetOk("cas_required"); ok {
+		config.CASRequired = casRequiredRaw.(bool)
+	}
+	if deleteVersionAfterRaw, ok := d.GetOk("delete_version_after"); ok {
+		config.DeleteVersionAfter = time.Duration(deleteVersionAfterRaw.(int)) * time.Second
+		if config.DeleteVersionAfter < 0 {
+			return logical.ErrorResponse("delete_version_after cannot be negative"), logical.ErrInvalidRequest
+		}
+	}
+
+	entry, err := logical.StorageEntryJSON("config", config)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+const pathConfigHelpSyn = `Configure the versioning of all keys`
+
+const pathConfigHelpDesc = `
+Configures the number of versions kept of each key, whether writes must use
+the check-and-set option, and the duration after which versions are deleted,
+for the keys which do not configure them in their metadata.
+`
@@ -0,0 +1,330 @@
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/helper/locksutil"
+	"github.com/hashicorp/vault/helper/parseutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+func pathData(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "data/(?P<path>.+)",
+		Fields: map[string]*framework.FieldSchema{
+			"path": {
+				Type:        framework.TypeString,
+				Description: "The key of the secret",
+			},
+
+			"version": {
+				Type:        framework.TypeInt,
+				Description: "The version to read; defaults to the current version.",
+			},
+
+			"data": {
+				Type:        framework.TypeMap,
+				Description: "The secret data to write as a new version",
+			},
+
+			"options": {
+				Type: framework.TypeMap,
+				Description: `Options of the write. If "cas" is set, the write
+succeeds only if the current version of the
+key matches it, zero meaning that the key
+must not exist.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathDataRead,
+			logical.CreateOperation: b.pathDataWrite,
+			logical.UpdateOperation: b.pathDataWrite,
+			logical.PatchOperation:  b.pathDataPatch,
+			logical.DeleteOperation: b.pathDataDelete,
+		},
+
+		ExistenceCheck: b.pathExistenceCheck,
+
+		HelpSynopsis:    pathDataHelpSyn,
+		HelpDescription: pathDataHelpDesc,
+	}
+}
+
+func (b *backend) pathExistenceCheck(ctx context.Context, req *logical.Request, d *framework.FieldData) (bool, error) {
+	meta, err := getKeyMetadata(ctx, req.Storage, d.Get("path").(string))
+	if err != nil {
+		return false, err
+	}
+	return meta != nil, nil
+}
+
+func (b *backend) pathDataRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	key := d.Get("path").(string)
+
+	lock := locksutil.LockForKey(b.locks, key)
+	lock.RLock()
+	defer lock.RUnlock()
+
+	meta, err := getKeyMetadata(ctx, req.Storage, key)
+	if err != nil {
+		return nil, err
+	}
+	if meta == nil {
+		return nil, nil
+	}
+
+	version := d.Get("version").(int)
+	if version == 0 {
+		version = meta.CurrentVersion
+	}
+	vm, ok := meta.Versions[version]
+	if !ok {
+		return nil, nil
+	}
+
+	// The metadata of deleted and destroyed versions is still returned, so
+	// that they can be told apart from versions which do not exist
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"data":     nil,
+			"metadata": vm.response(version),
+		},
+	}
+	if vm.deleted() {
+		return resp, nil
+	}
+
+	data, err := readVersion(ctx, req.Storage, key, version)
+	if err != nil {
+		return nil, err
+	}
+	resp.Data["data"] = data
+
+	return resp, nil
+}
+
+// readVersion returns the data of the version of the key
+func readVersion(ctx context.Context, s logical.Storage, key string, version int) (map[string]interface{}, error) {
+	entry, err := s.Get(ctx, versionKey(key, version))
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, fmt.Errorf("version %d of %q not found", version, key)
+	}
+	var data map[string]interface{}
+	if err := jsonutil.DecodeJSON(entry.Value, &data); err != nil {
+		return nil, fmt.Errorf("json decoding failed: %v", err)
+	}
+	return data, nil
+}
+
+func (b *backend) pathDataWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	key := d.Get("path").(string)
+	if !validKey(key) {
+		return logical.ErrorResponse("invalid key"), logical.ErrInvalidRequest
+	}
+
+	dataRaw, ok := d.GetOk("data")
+	if !ok {
+		return logical.ErrorResponse("missing data"), logical.ErrInvalidRequest
+	}
+	buf, err := json.Marshal(dataRaw)
+	if err != nil {
+		return nil, fmt.Errorf("json encoding failed: %v", err)
+	}
+
+	config, err := b.config(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	lock := locksutil.LockForKey(b.locks, key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	now := time.Now()
+	meta, err := getKeyMetadata(ctx, req.Storage, key)
+	if err != nil {
+		return nil, err
+	}
+	if meta == nil {
+		meta = newKeyMetadata(key, now)
+	}
+
+	if err := checkCAS(d, meta, config); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	resp, err := writeVersion(ctx, req.Storage, meta, config, buf, now)
+	if err != nil {
+		return nil, err
+	}
+	b.sendEvent(ctx, logical.EventTypeKVWrite, key, meta.CurrentVersion)
+	return resp, nil
+}
+
+// pathDataPatch applies a JSON merge patch to the data of the current version
+// of the key and writes the result as a new version. Since the key is locked
+// while the patch is applied, concurrent patches of different fields do not
+// overwrite each other.
+func (b *backend) pathDataPatch(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	key := d.Get("path").(string)
+
+	patchRaw, ok := d.GetOk("data")
+	if !ok {
+		return logical.ErrorResponse("missing data"), logical.ErrInvalidRequest
+	}
+	patch := patchRaw.(map[string]interface{})
+
+	config, err := b.config(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	lock := locksutil.LockForKey(b.locks, key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	meta, err := getKeyMetadata(ctx, req.Storage, key)
+	if err != nil {
+		return nil, err
+	}
+	if meta == nil {
+		return nil, nil
+	}
+	vm, ok := meta.Versions[meta.CurrentVersion]
+	if !ok || vm.deleted() {
+		return nil, nil
+	}
+
+	if err := checkCAS(d, meta, config); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	data, err := readVersion(ctx, req.Storage, key, meta.CurrentVersion)
+	if err != nil {
+		return nil, err
+	}
+	buf, err := json.Marshal(framework.MergePatch(data, patch))
+	if err != nil {
+		return nil, fmt.Errorf("json encoding failed: %v", err)
+	}
+
+	resp, err := writeVersion(ctx, req.Storage, meta, config, buf, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	b.sendEvent(ctx, logical.EventTypeKVWrite, key, meta.CurrentVersion)
+	return resp, nil
+}
+
+// checkCAS returns an error if the check-and-set option of the request does
+// not match the current version of the key, or is missing but required
+func checkCAS(d *framework.FieldData, meta *keyMetadata, config *kvConfig) error {
+	casRaw, casSet := d.Get("options").(map[string]interface{})["cas"]
+	switch {
+	case casSet:
+		cas, err := parseutil.ParseInt(casRaw)
+		if err != nil {
+			return fmt.Errorf("invalid check-and-set option: %v", err)
+		}
+		if int(cas) != meta.CurrentVersion {
+			return errors.New("check-and-set option did not match the current version")
+		}
+	case config.CASRequired || meta.CASRequired:
+		return errors.New("check-and-set option required for this key")
+	}
+	return nil
+}
+
+// writeVersion stores the data as a new version of the key, pruning the
+// versions exceeding the maximum number of versions
+func writeVersion(ctx context.Context, s logical.Storage, meta *keyMetadata, config *kvConfig, buf []byte, now time.Time) (*logical.Response, error) {
+	version := meta.CurrentVersion + 1
+	if err := s.Put(ctx, &logical.StorageEntry{
+		Key:   versionKey(meta.Key, version),
+		Value: buf,
+	}); err != nil {
+		return nil, err
+	}
+
+	vm := &versionMetadata{
+		CreatedTime: now,
+	}
+	meta.Versions[version] = vm
+	meta.CurrentVersion = version
+	if meta.OldestVersion == 0 {
+		meta.OldestVersion = version
+	}
+	meta.UpdatedTime = now
+	if err := pruneVersions(ctx, s, meta, meta.maxVersions(config)); err != nil {
+		return nil, err
+	}
+	if err := meta.persist(ctx, s); err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: vm.response(version),
+	}, nil
+}
+
+func (b *backend) pathDataDelete(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	key := d.Get("path").(string)
+
+	lock := locksutil.LockForKey(b.locks, key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	meta, err := getKeyMetadata(ctx, req.Storage, key)
+	if err != nil {
+		return nil, err
+	}
+	if meta == nil {
+		return nil, nil
+	}
+
+	vm, ok := meta.Versions[meta.CurrentVersion]
+	if !ok || vm.deleted() {
+		return nil, nil
+	}
+	vm.DeletionTime = time.Now()
+	if err := meta.persist(ctx, req.Storage); err != nil {
+		return nil, err
+	}
+	b.sendEvent(ctx, logical.EventTypeKVDelete, key, meta.CurrentVersion)
+
+	return nil, nil
+}
+
+// sendEvent notifies the subscribers of the change of the version of the
+// key. The change is made even if the event cannot be published.
+func (b *backend) sendEvent(ctx context.Context, eventType, key string, version int) {
+	metadata := map[string]string{
+		"version": strconv.Itoa(version),
+	}
+	if err := b.System().SendEvent(ctx, eventType, "data/"+key, metadata); err != nil {
+		b.Logger().Warn("kv: failed to publish event", "event_type", eventType, "error", err)
+	}
+}
+
+const pathDataHelpSyn = `Write, patch, read, and delete versions of secrets`
+
+const pathDataHelpDesc = `
+Writing a secret stores its data as a new version, keeping the previous
+versions up to the number configured on the key or on the backend. Patching a
+secret applies a JSON merge patch (RFC 7386) to the data of its current
+version and writes the result as a new version. Reading a secret returns its
+current version, or the requested version. Deleting a
+secret soft deletes its current version, which can be restored with
+"undelete/".
+`
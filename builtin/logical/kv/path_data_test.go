@@ -0,0 +1,209 @@
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestKV_Data(t *testing.T) {
+	b, storage := createBackendWithStorage(t)
+	doReq, expectError := testRequests(t, b, storage)
+
+	if resp := doReq(logical.ReadOperation, "data/foo", nil); resp != nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+	expectError(logical.CreateOperation, "data/foo", nil)
+	expectError(logical.CreateOperation, "data/foo/", map[string]interface{}{"data": map[string]interface{}{"a": "b"}})
+
+	// Each write creates a new version
+	for i := 1; i <= 3; i++ {
+		resp := doReq(logical.CreateOperation, "data/foo", map[string]interface{}{
+			"data": map[string]interface{}{"value": i},
+		})
+		if resp.Data["version"] != i || resp.Data["created_time"] == "" || resp.Data["deletion_time"] != "" || resp.Data["destroyed"] != false {
+			t.Fatalf("bad: %#v", resp.Data)
+		}
+	}
+	resp := doReq(logical.ReadOperation, "data/foo", nil)
+	if !reflect.DeepEqual(resp.Data["data"], map[string]interface{}{"value": json.Number("3")}) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if resp.Data["metadata"].(map[string]interface{})["version"] != 3 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	resp = doReq(logical.ReadOperation, "data/foo", map[string]interface{}{"version": 1})
+	if resp.Data["metadata"].(map[string]interface{})["version"] != 1 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if resp := doReq(logical.ReadOperation, "data/foo", map[string]interface{}{"version": 4}); resp != nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// Check-and-set writes only succeed with the current version
+	expectError(logical.UpdateOperation, "data/foo", map[string]interface{}{
+		"data":    map[string]interface{}{"value": 4},
+		"options": map[string]interface{}{"cas": 2},
+	})
+	expectError(logical.CreateOperation, "data/bar", map[string]interface{}{
+		"data":    map[string]interface{}{"value": 1},
+		"options": map[string]interface{}{"cas": 1},
+	})
+	doReq(logical.CreateOperation, "data/bar", map[string]interface{}{
+		"data":    map[string]interface{}{"value": 1},
+		"options": map[string]interface{}{"cas": 0},
+	})
+	doReq(logical.UpdateOperation, "data/foo", map[string]interface{}{
+		"data":    map[string]interface{}{"value": 4},
+		"options": map[string]interface{}{"cas": 3},
+	})
+
+	// Check-and-set can be required by the key or by the backend
+	doReq(logical.UpdateOperation, "metadata/bar", map[string]interface{}{"cas_required": true})
+	expectError(logical.UpdateOperation, "data/bar", map[string]interface{}{
+		"data": map[string]interface{}{"value": 2},
+	})
+	doReq(logical.UpdateOperation, "config", map[string]interface{}{"cas_required": true})
+	expectError(logical.UpdateOperation, "data/foo", map[string]interface{}{
+		"data": map[string]interface{}{"value": 5},
+	})
+	doReq(logical.UpdateOperation, "config", map[string]interface{}{"cas_required": false})
+
+	// Deleting soft deletes the current version only
+	doReq(logical.DeleteOperation, "data/foo", nil)
+	resp = doReq(logical.ReadOperation, "data/foo", nil)
+	metadata := resp.Data["metadata"].(map[string]interface{})
+	if resp.Data["data"] != nil || metadata["version"] != 4 || metadata["deletion_time"] == "" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	resp = doReq(logical.ReadOperation, "data/foo", map[string]interface{}{"version": 3})
+	if resp.Data["data"] == nil {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Writing after a deletion creates a new version
+	resp = doReq(logical.UpdateOperation, "data/foo", map[string]interface{}{
+		"data": map[string]interface{}{"value": 5},
+	})
+	if resp.Data["version"] != 5 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+}
+
+func TestKV_Data_Patch(t *testing.T) {
+	b, storage := createBackendWithStorage(t)
+	doReq, expectError := testRequests(t, b, storage)
+
+	// Only existing secrets can be patched
+	if resp := doReq(logical.PatchOperation, "data/foo", map[string]interface{}{
+		"data": map[string]interface{}{"a": "b"},
+	}); resp != nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	doReq(logical.CreateOperation, "data/foo", map[string]interface{}{
+		"data": map[string]interface{}{
+			"a": "b",
+			"c": "d",
+			"e": map[string]interface{}{"f": "g", "h": "i"},
+		},
+	})
+	expectError(logical.PatchOperation, "data/foo", nil)
+
+	// Patches write a new version
+	resp := doReq(logical.PatchOperation, "data/foo", map[string]interface{}{
+		"data": map[string]interface{}{
+			"a": "j",
+			"c": nil,
+			"e": map[string]interface{}{"h": nil, "k": "l"},
+		},
+	})
+	if resp.Data["version"] != 2 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	resp = doReq(logical.ReadOperation, "data/foo", nil)
+	expected := map[string]interface{}{
+		"a": "j",
+		"e": map[string]interface{}{"f": "g", "k": "l"},
+	}
+	if !reflect.DeepEqual(resp.Data["data"], expected) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	resp = doReq(logical.ReadOperation, "data/foo", map[string]interface{}{"version": 1})
+	if resp.Data["data"].(map[string]interface{})["c"] != "d" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Check-and-set patches only succeed with the current version
+	expectError(logical.PatchOperation, "data/foo", map[string]interface{}{
+		"data":    map[string]interface{}{"a": "m"},
+		"options": map[string]interface{}{"cas": 1},
+	})
+	doReq(logical.PatchOperation, "data/foo", map[string]interface{}{
+		"data":    map[string]interface{}{"a": "m"},
+		"options": map[string]interface{}{"cas": 2},
+	})
+	doReq(logical.UpdateOperation, "metadata/foo", map[string]interface{}{"cas_required": true})
+	expectError(logical.PatchOperation, "data/foo", map[string]interface{}{
+		"data": map[string]interface{}{"a": "n"},
+	})
+
+	// Deleted versions cannot be patched
+	doReq(logical.DeleteOperation, "data/foo", nil)
+	if resp := doReq(logical.PatchOperation, "data/foo", map[string]interface{}{
+		"data":    map[string]interface{}{"a": "n"},
+		"options": map[string]interface{}{"cas": 3},
+	}); resp != nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+}
+
+func TestKV_Data_MaxVersions(t *testing.T) {
+	b, storage := createBackendWithStorage(t)
+	doReq, _ := testRequests(t, b, storage)
+
+	write := func(path string, count int) {
+		for i := 0; i < count; i++ {
+			doReq(logical.UpdateOperation, "data/"+path, map[string]interface{}{
+				"data": map[string]interface{}{"value": i},
+			})
+		}
+	}
+	versions := func(path string) (int, int, int) {
+		resp := doReq(logical.ReadOperation, "metadata/"+path, nil)
+		return len(resp.Data["versions"].(map[string]interface{})), resp.Data["oldest_version"].(int), resp.Data["current_version"].(int)
+	}
+
+	write("foo", defaultMaxVersions+2)
+	if count, oldest, current := versions("foo"); count != defaultMaxVersions || oldest != 3 || current != defaultMaxVersions+2 {
+		t.Fatalf("bad: %d versions from %d to %d", count, oldest, current)
+	}
+	if resp := doReq(logical.ReadOperation, "data/foo", map[string]interface{}{"version": 2}); resp != nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// Pruned versions are deleted from the storage
+	keys, err := storage.List(context.Background(), "versions/")
+	if err != nil || len(keys) != 1 {
+		t.Fatalf("bad: err: %v, keys: %v", err, keys)
+	}
+	versionKeys, err := storage.List(context.Background(), "versions/"+keys[0])
+	if err != nil || len(versionKeys) != defaultMaxVersions {
+		t.Fatalf("bad: err: %v, keys: %v", err, versionKeys)
+	}
+
+	// The number of versions of the key overrides the one of the backend
+	doReq(logical.UpdateOperation, "config", map[string]interface{}{"max_versions": 3})
+	write("bar", 5)
+	if count, oldest, _ := versions("bar"); count != 3 || oldest != 3 {
+		t.Fatalf("bad: %d versions from %d", count, oldest)
+	}
+	doReq(logical.UpdateOperation, "metadata/bar", map[string]interface{}{"max_versions": 1})
+	write("bar", 1)
+	if count, oldest, current := versions("bar"); count != 1 || oldest != 6 || current != 6 {
+		t.Fatalf("bad: %d versions from %d to %d", count, oldest, current)
+	}
+}
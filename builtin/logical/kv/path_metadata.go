@@ -0,0 +1,185 @@
+package kv
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/helper/locksutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+func pathMetadata(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "metadata/(?P<path>.*)",
+		Fields: map[string]*framework.FieldSchema{
+			"path": {
+				Type:        framework.TypeString,
+				Description: "The key of the secret",
+			},
+
+			"max_versions": {
+				Type: framework.TypeInt,
+				Description: `The number of versions kept of the key. Zero
+uses the number configured on the backend.`,
+			},
+
+			"cas_required": {
+				Type: framework.TypeBool,
+				Description: `Whether writes of the key must use the
+check-and-set option.`,
+			},
+
+			"delete_version_after": {
+				Type: framework.TypeDurationSecond,
+				Description: `The duration after which versions of the key are
+deleted. Zero uses the duration configured on the
+backend.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathMetadataRead,
+			logical.CreateOperation: b.pathMetadataWrite,
+			logical.UpdateOperation: b.pathMetadataWrite,
+			logical.DeleteOperation: b.pathMetadataDelete,
+			logical.ListOperation:   b.pathMetadataList,
+		},
+
+		ExistenceCheck: b.pathExistenceCheck,
+
+		HelpSynopsis:    pathMetadataHelpSyn,
+		HelpDescription: pathMetadataHelpDesc,
+	}
+}
+
+func (b *backend) pathMetadataList(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	path := d.Get("path").(string)
+	if path != "" && !strings.HasSuffix(path, "/") {
+		path = path + "/"
+	}
+
+	keys, err := req.Storage.List(ctx, "metadata/"+path)
+	if err != nil {
+		return nil, err
+	}
+	return logical.ListResponse(keys), nil
+}
+
+func (b *backend) pathMetadataRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	key := d.Get("path").(string)
+
+	lock := locksutil.LockForKey(b.locks, key)
+	lock.RLock()
+	defer lock.RUnlock()
+
+	meta, err := getKeyMetadata(ctx, req.Storage, key)
+	if err != nil {
+		return nil, err
+	}
+	if meta == nil {
+		return nil, nil
+	}
+
+	versions := make(map[string]interface{}, len(meta.Versions))
+	for version, vm := range meta.Versions {
+		versionData := vm.response(version)
+		delete(versionData, "version")
+		versions[strconv.Itoa(version)] = versionData
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"versions":             versions,
+			"current_version":      meta.CurrentVersion,
+			"oldest_version":       meta.OldestVersion,
+			"max_versions":         meta.MaxVersions,
+			"cas_required":         meta.CASRequired,
+			"created_time":         formatTime(meta.CreatedTime),
+			"updated_time":         formatTime(meta.UpdatedTime),
+			"delete_version_after": int64(meta.DeleteVersionAfter.Seconds()),
+		},
+	}, nil
+}
+
+func (b *backend) pathMetadataWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	key := d.Get("path").(string)
+	if !validKey(key) {
+		return logical.ErrorResponse("invalid key"), logical.ErrInvalidRequest
+	}
+
+	lock := locksutil.LockForKey(b.locks, key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	now := time.Now()
+	meta, err := getKeyMetadata(ctx, req.Storage, key)
+	if err != nil {
+		return nil, err
+	}
+	if meta == nil {
+		meta = newKeyMetadata(key, now)
+	}
+
+	if maxVersionsRaw, ok := d.GetOk("max_versions"); ok {
+		meta.MaxVersions = maxVersionsRaw.(int)
+		if meta.MaxVersions < 0 {
+			return logical.ErrorResponse("max_versions cannot be negative"), logical.ErrInvalidRequest
+		}
+	}
+	if casRequiredRaw, ok := d.GetOk("cas_required"); ok {
+		meta.CASRequired = casRequiredRaw.(bool)
+	}
+	if deleteVersionAfterRaw, ok := d.GetOk("delete_version_after"); ok {
+		meta.DeleteVersionAfter = time.Duration(deleteVersionAfterRaw.(int)) * time.Second
+		if meta.DeleteVersionAfter < 0 {
+			return logical.ErrorResponse("delete_version_after cannot be negative"), logical.ErrInvalidRequest
+		}
+	}
+	meta.UpdatedTime = now
+
+	if err := meta.persist(ctx, req.Storage); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (b *backend) pathMetadataDelete(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	key := d.Get("path").(string)
+
+	lock := locksutil.LockForKey(b.locks, key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	meta, err := getKeyMetadata(ctx, req.Storage, key)
+	if err != nil {
+		return nil, err
+	}
+	if meta == nil {
+		return nil, nil
+	}
+
+	for version := range meta.Versions {
+		if err := req.Storage.Delete(ctx, versionKey(key, version)); err != nil {
+			return nil, err
+		}
+	}
+	if err := req.Storage.Delete(ctx, "metadata/"+key); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+const pathMetadataHelpSyn = `Configure, read, and delete the versions of secrets`
+
+const pathMetadataHelpDesc = `
+Reading the metadata of a secret returns the metadata of all the versions which
+are kept, including deleted and destroyed versions, and its configuration.
+Writing it configures the number of versions kept and whether writes must use
+the check-and-set option, overriding the configuration of the backend.
+Deleting it permanently deletes all the versions of the secret.
+
+Listing the metadata lists the secrets at the given prefix.
+`
@@ -0,0 +1,71 @@
+package kv
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestKV_Metadata(t *testing.T) {
+	b, storage := createBackendWithStorage(t)
+	doReq, expectError := testRequests(t, b, storage)
+
+	if resp := doReq(logical.ReadOperation, "metadata/foo", nil); resp != nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	for _, path := range []string{"foo", "dir/bar", "dir/baz"} {
+		doReq(logical.CreateOperation, "data/"+path, map[string]interface{}{
+			"data": map[string]interface{}{"value": "1"},
+		})
+	}
+	doReq(logical.UpdateOperation, "data/foo", map[string]interface{}{
+		"data": map[string]interface{}{"value": "2"},
+	})
+
+	resp := doReq(logical.ReadOperation, "metadata/foo", nil)
+	versions := resp.Data["versions"].(map[string]interface{})
+	if len(versions) != 2 || resp.Data["current_version"] != 2 || resp.Data["oldest_version"] != 1 ||
+		resp.Data["max_versions"] != 0 || resp.Data["cas_required"] != false || resp.Data["created_time"] == resp.Data["updated_time"] {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if v := versions["1"].(map[string]interface{}); v["created_time"] == "" || v["deletion_time"] != "" || v["destroyed"] != false {
+		t.Fatalf("bad: %#v", versions)
+	}
+
+	// Listing the metadata lists the keys
+	resp = doReq(logical.ListOperation, "metadata/", nil)
+	if !reflect.DeepEqual(resp.Data["keys"], []string{"dir/", "foo"}) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	resp = doReq(logical.ListOperation, "metadata/dir", nil)
+	if !reflect.DeepEqual(resp.Data["keys"], []string{"bar", "baz"}) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// The metadata can be written before the key
+	expectError(logical.UpdateOperation, "metadata/foo", map[string]interface{}{"max_versions": -1})
+	expectError(logical.CreateOperation, "metadata/dir/", map[string]interface{}{"max_versions": 1})
+	doReq(logical.CreateOperation, "metadata/new", map[string]interface{}{"max_versions": 5, "cas_required": true})
+	resp = doReq(logical.ReadOperation, "metadata/new", nil)
+	if resp.Data["max_versions"] != 5 || resp.Data["cas_required"] != true || resp.Data["current_version"] != 0 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Deleting the metadata deletes all the versions
+	doReq(logical.DeleteOperation, "metadata/foo", nil)
+	if resp := doReq(logical.ReadOperation, "metadata/foo", nil); resp != nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+	if resp := doReq(logical.ReadOperation, "data/foo", map[string]interface{}{"version": 1}); resp != nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+	for _, version := range []int{1, 2} {
+		entry, err := storage.Get(context.Background(), versionKey("foo", version))
+		if err != nil || entry != nil {
+			t.Fatalf("bad: err: %v, entry: %#v", err, entry)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package kv
+
+import (
+	"context"
+
+	"github.com/hashicorp/vault/helper/locksutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+func pathSubkeys(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "subkeys/(?P<path>.+)",
+		Fields: map[string]*framework.FieldSchema{
+			"path": {
+				Type:        framework.TypeString,
+				Description: "The key of the secret",
+			},
+
+			"version": {
+				Type:        framework.TypeInt,
+				Description: "The version to read; defaults to the current version.",
+			},
+
+			"depth": {
+				Type: framework.TypeInt,
+				Description: `The depth of the nested keys to return; defaults to
+0, returning all of them.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathSubkeysRead,
+		},
+
+		HelpSynopsis:    pathSubkeysHelpSyn,
+		HelpDescription: pathSubkeysHelpDesc,
+	}
+}
+
+func (b *backend) pathSubkeysRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	key := d.Get("path").(string)
+	depth := d.Get("depth").(int)
+	if depth < 0 {
+		return logical.ErrorResponse("depth cannot be negative"), logical.ErrInvalidRequest
+	}
+
+	lock := locksutil.LockForKey(b.locks, key)
+	lock.RLock()
+	defer lock.RUnlock()
+
+	meta, err := getKeyMetadata(ctx, req.Storage, key)
+	if err != nil {
+		return nil, err
+	}
+	if meta == nil {
+		return nil, nil
+	}
+
+	version := d.Get("version").(int)
+	if version == 0 {
+		version = meta.CurrentVersion
+	}
+	vm, ok := meta.Versions[version]
+	if !ok {
+		return nil, nil
+	}
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"subkeys":  nil,
+			"metadata": vm.response(version),
+		},
+	}
+	if vm.deleted() {
+		return resp, nil
+	}
+
+	data, err := readVersion(ctx, req.Storage, key, version)
+	if err != nil {
+		return nil, err
+	}
+	resp.Data["subkeys"] = subkeys(data, depth)
+
+	return resp, nil
+}
+
+// subkeys returns the keys of the data with their values replaced by nil,
+// except for nested objects whose keys are returned down to the depth, zero
+// meaning all of them
+func subkeys(data map[string]interface{}, depth int) map[string]interface{} {
+	result := make(map[string]interface{}, len(data))
+	for k, v := range data {
+		nested, ok := v.(map[string]interface{})
+		if ok && depth != 1 {
+			result[k] = subkeys(nested, depth-1)
+		} else {
+			result[k] = nil
+		}
+	}
+	return result
+}
+
+const pathSubkeysHelpSyn = `Read the structure of versions of secrets`
+
+const pathSubkeysHelpDesc = `
+Reading the subkeys of a secret returns the keys of the data of its current
+version, or of the requested version, with all values replaced by null except
+for nested objects, so that the fields of a secret can be listed without
+access to their values. Nested objects are returned down to the given depth.
+`
@@ -0,0 +1,79 @@
+package kv
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestKV_Subkeys(t *testing.T) {
+	b, storage := createBackendWithStorage(t)
+	doReq, expectError := testRequests(t, b, storage)
+
+	if resp := doReq(logical.ReadOperation, "subkeys/foo", nil); resp != nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	doReq(logical.CreateOperation, "data/foo", map[string]interface{}{
+		"data": map[string]interface{}{"a": "b"},
+	})
+	doReq(logical.CreateOperation, "data/foo", map[string]interface{}{
+		"data": map[string]interface{}{
+			"a": "b",
+			"c": map[string]interface{}{
+				"d": "e",
+				"f": map[string]interface{}{"g": "h"},
+			},
+			"i": []interface{}{"j"},
+		},
+	})
+
+	// Values are replaced by nil, except for nested objects
+	resp := doReq(logical.ReadOperation, "subkeys/foo", nil)
+	expected := map[string]interface{}{
+		"a": nil,
+		"c": map[string]interface{}{
+			"d": nil,
+			"f": map[string]interface{}{"g": nil},
+		},
+		"i": nil,
+	}
+	if !reflect.DeepEqual(resp.Data["subkeys"], expected) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if resp.Data["metadata"].(map[string]interface{})["version"] != 2 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	resp = doReq(logical.ReadOperation, "subkeys/foo", map[string]interface{}{"depth": 2})
+	expected = map[string]interface{}{
+		"a": nil,
+		"c": map[string]interface{}{"d": nil, "f": nil},
+		"i": nil,
+	}
+	if !reflect.DeepEqual(resp.Data["subkeys"], expected) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	resp = doReq(logical.ReadOperation, "subkeys/foo", map[string]interface{}{"depth": 1})
+	expected = map[string]interface{}{"a": nil, "c": nil, "i": nil}
+	if !reflect.DeepEqual(resp.Data["subkeys"], expected) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	expectError(logical.ReadOperation, "subkeys/foo", map[string]interface{}{"depth": -1})
+
+	resp = doReq(logical.ReadOperation, "subkeys/foo", map[string]interface{}{"version": 1})
+	if !reflect.DeepEqual(resp.Data["subkeys"], map[string]interface{}{"a": nil}) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if resp := doReq(logical.ReadOperation, "subkeys/foo", map[string]interface{}{"version": 3}); resp != nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// Deleted versions have no subkeys
+	doReq(logical.DeleteOperation, "data/foo", nil)
+	resp = doReq(logical.ReadOperation, "subkeys/foo", nil)
+	if resp.Data["subkeys"] != nil {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+}
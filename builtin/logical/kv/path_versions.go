@@ -0,0 +1,233 @@
+package kv
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/vault/helper/locksutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// versionsFields returns the fields of the paths updating versions of keys
+func versionsFields(description string) map[string]*framework.FieldSchema {
+	return map[string]*framework.FieldSchema{
+		"path": {
+			Type:        framework.TypeString,
+			Description: "The key of the secret",
+		},
+
+		"versions": {
+			Type:        framework.TypeCommaStringSlice,
+			Description: description,
+		},
+	}
+}
+
+func pathDelete(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "delete/(?P<path>.+)",
+		Fields:  versionsFields("The versions to soft delete"),
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathDeleteWrite,
+		},
+
+		HelpSynopsis:    pathDeleteHelpSyn,
+		HelpDescription: pathDeleteHelpDesc,
+	}
+}
+
+func pathUndelete(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "undelete/(?P<path>.+)",
+		Fields:  versionsFields("The versions to restore"),
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathUndeleteWrite,
+		},
+
+		HelpSynopsis:    pathUndeleteHelpSyn,
+		HelpDescription: pathUndeleteHelpDesc,
+	}
+}
+
+func pathDestroy(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "destroy/(?P<path>.+)",
+		Fields:  versionsFields("The versions to permanently delete"),
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathDestroyWrite,
+		},
+
+		HelpSynopsis:    pathDestroyHelpSyn,
+		HelpDescription: pathDestroyHelpDesc,
+	}
+}
+
+// updateVersions calls the update function with the metadata of each of the
+// requested versions of the key which is not destroyed, and persists the
+// metadata of the key. Versions which do not exist are ignored.
+func (b *backend) updateVersions(ctx context.Context, req *logical.Request, d *framework.FieldData, update func(version int, vm *versionMetadata) error) (*logical.Response, error) {
+	key := d.Get("path").(string)
+
+	versionsRaw := d.Get("versions").([]string)
+	if len(versionsRaw) == 0 {
+		return logical.ErrorResponse("no versions provided"), logical.ErrInvalidRequest
+	}
+	versions := make([]int, 0, len(versionsRaw))
+	for _, versionRaw := range versionsRaw {
+		version, err := strconv.Atoi(versionRaw)
+		if err != nil || version <= 0 {
+			return logical.ErrorResponse(fmt.Sprintf("invalid version %q", versionRaw)), logical.ErrInvalidRequest
+		}
+		versions = append(versions, version)
+	}
+
+	lock := locksutil.LockForKey(b.locks, key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	meta, err := getKeyMetadata(ctx, req.Storage, key)
+	if err != nil {
+		return nil, err
+	}
+	if meta == nil {
+		return nil, nil
+	}
+
+	for _, version := range versions {
+		vm, ok := meta.Versions[version]
+		if !ok || vm.Destroyed {
+			continue
+		}
+		if err := update(version, vm); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := meta.persist(ctx, req.Storage); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// deleteExpiredVersions soft deletes the versions of all keys which are older
+// than the duration configured on the key or on the backend. Keys are walked
+// from "metadata/" since they can be nested.
+func (b *backend) deleteExpiredVersions(ctx context.Context, s logical.Storage, now time.Time) error {
+	config, err := b.config(ctx, s)
+	if err != nil {
+		return err
+	}
+
+	var mErr *multierror.Error
+	frontier := []string{""}
+	for len(frontier) > 0 {
+		prefix := frontier[len(frontier)-1]
+		frontier = frontier[:len(frontier)-1]
+
+		keys, err := s.List(ctx, "metadata/"+prefix)
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			if strings.HasSuffix(key, "/") {
+				frontier = append(frontier, prefix+key)
+				continue
+			}
+			if err := b.deleteExpiredKeyVersions(ctx, s, config, prefix+key, now); err != nil {
+				mErr = multierror.Append(mErr, fmt.Errorf("failed to delete versions of %q: %v", prefix+key, err))
+			}
+		}
+	}
+	return mErr.ErrorOrNil()
+}
+
+func (b *backend) deleteExpiredKeyVersions(ctx context.Context, s logical.Storage, config *kvConfig, key string, now time.Time) error {
+	lock := locksutil.LockForKey(b.locks, key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	meta, err := getKeyMetadata(ctx, s, key)
+	if err != nil || meta == nil {
+		return err
+	}
+	deleteAfter := meta.deleteVersionAfter(config)
+	if deleteAfter == 0 {
+		return nil
+	}
+
+	var deleted int
+	for _, vm := range meta.Versions {
+		if !vm.deleted() && !vm.CreatedTime.Add(deleteAfter).After(now) {
+			vm.DeletionTime = now
+			deleted++
+		}
+	}
+	if deleted == 0 {
+		return nil
+	}
+	if err := meta.persist(ctx, s); err != nil {
+		return err
+	}
+	b.Logger().Debug("kv: deleted expired versions", "key", key, "count", deleted)
+	return nil
+}
+
+func (b *backend) pathDeleteWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	now := time.Now()
+	return b.updateVersions(ctx, req, d, func(version int, vm *versionMetadata) error {
+		if vm.DeletionTime.IsZero() {
+			vm.DeletionTime = now
+		}
+		return nil
+	})
+}
+
+func (b *backend) pathUndeleteWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	return b.updateVersions(ctx, req, d, func(version int, vm *versionMetadata) error {
+		vm.DeletionTime = time.Time{}
+		return nil
+	})
+}
+
+func (b *backend) pathDestroyWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	key := d.Get("path").(string)
+	return b.updateVersions(ctx, req, d, func(version int, vm *versionMetadata) error {
+		if err := req.Storage.Delete(ctx, versionKey(key, version)); err != nil {
+			return err
+		}
+		vm.Destroyed = true
+		return nil
+	})
+}
+
+const pathDeleteHelpSyn = `Soft delete versions of a secret`
+
+const pathDeleteHelpDesc = `
+Marks the given versions of the secret as deleted, so that they are not
+returned when reading the secret. Their data is kept, and they can be restored
+with "undelete/". Versions are also deleted automatically once they are older
+than the "delete_version_after" duration of the secret or of the backend.
+`
+
+const pathUndeleteHelpSyn = `Restore deleted versions of a secret`
+
+const pathUndeleteHelpDesc = `
+Restores the given soft deleted versions of the secret, so that they are
+returned again when reading the secret. Destroyed versions cannot be restored.
+`
+
+const pathDestroyHelpSyn = `Permanently delete versions of a secret`
+
+const pathDestroyHelpDesc = `
+Permanently deletes the data of the given versions of the secret. Their
+metadata is kept, marked as destroyed, until they are pruned or the metadata
+of the secret is deleted.
+`
@@ -0,0 +1,127 @@
+package kv
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestKV_Versions(t *testing.T) {
+	b, storage := createBackendWithStorage(t)
+	doReq, expectError := testRequests(t, b, storage)
+
+	for i := 1; i <= 3; i++ {
+		doReq(logical.UpdateOperation, "data/foo", map[string]interface{}{
+			"data": map[string]interface{}{"value": i},
+		})
+	}
+	versionMetadata := func(version int) map[string]interface{} {
+		resp := doReq(logical.ReadOperation, "metadata/foo", nil)
+		return resp.Data["versions"].(map[string]interface{})[strconv.Itoa(version)].(map[string]interface{})
+	}
+	readData := func(version int) interface{} {
+		resp := doReq(logical.ReadOperation, "data/foo", map[string]interface{}{"version": version})
+		return resp.Data["data"]
+	}
+
+	for _, path := range []string{"delete/foo", "undelete/foo", "destroy/foo"} {
+		expectError(logical.UpdateOperation, path, nil)
+		expectError(logical.UpdateOperation, path, map[string]interface{}{"versions": "1,a"})
+		expectError(logical.UpdateOperation, path, map[string]interface{}{"versions": []int{0}})
+	}
+
+	// Soft deleted versions can be restored
+	doReq(logical.UpdateOperation, "delete/foo", map[string]interface{}{"versions": "1,2,7"})
+	if readData(1) != nil || readData(2) != nil || readData(3) == nil {
+		t.Fatal("expected versions 1 and 2 to be deleted")
+	}
+	if versionMetadata(1)["deletion_time"] == "" || versionMetadata(3)["deletion_time"] != "" {
+		t.Fatal("bad: deletion times")
+	}
+	doReq(logical.UpdateOperation, "undelete/foo", map[string]interface{}{"versions": []int{2}})
+	if readData(1) != nil || readData(2) == nil {
+		t.Fatal("expected version 2 to be restored")
+	}
+	if versionMetadata(2)["deletion_time"] != "" {
+		t.Fatal("bad: deletion time")
+	}
+
+	// Destroyed versions cannot be restored, and their data is deleted
+	doReq(logical.UpdateOperation, "destroy/foo", map[string]interface{}{"versions": []int{1, 3}})
+	doReq(logical.UpdateOperation, "undelete/foo", map[string]interface{}{"versions": []int{1, 3}})
+	if readData(1) != nil || readData(3) != nil || readData(2) == nil {
+		t.Fatal("expected versions 1 and 3 to be destroyed")
+	}
+	if versionMetadata(1)["destroyed"] != true || versionMetadata(2)["destroyed"] != false || versionMetadata(3)["destroyed"] != true {
+		t.Fatal("bad: destroyed versions")
+	}
+	for _, version := range []int{1, 3} {
+		entry, err := storage.Get(context.Background(), versionKey("foo", version))
+		if err != nil || entry != nil {
+			t.Fatalf("bad: err: %v, entry: %#v", err, entry)
+		}
+	}
+
+	// Updating the versions of a missing key does nothing
+	doReq(logical.UpdateOperation, "destroy/missing", map[string]interface{}{"versions": []int{1}})
+	if resp := doReq(logical.ReadOperation, "metadata/missing", nil); resp != nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+}
+
+func TestKV_DeleteVersionAfter(t *testing.T) {
+	b, storage := createBackendWithStorage(t)
+	doReq, expectError := testRequests(t, b, storage)
+
+	expectError(logical.UpdateOperation, "config", map[string]interface{}{"delete_version_after": -1})
+	expectError(logical.UpdateOperation, "metadata/foo", map[string]interface{}{"delete_version_after": -1})
+
+	for _, key := range []string{"foo", "bar", "nested/baz"} {
+		doReq(logical.UpdateOperation, "data/"+key, map[string]interface{}{
+			"data": map[string]interface{}{"value": 1},
+		})
+	}
+	deleted := func(key string) bool {
+		resp := doReq(logical.ReadOperation, "data/"+key, nil)
+		return resp.Data["metadata"].(map[string]interface{})["deletion_time"] != ""
+	}
+	sweep := func(after time.Duration) {
+		if err := b.deleteExpiredVersions(context.Background(), storage, time.Now().Add(after)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Versions are kept unless configured otherwise
+	sweep(24 * time.Hour)
+	if deleted("foo") || deleted("bar") || deleted("nested/baz") {
+		t.Fatal("expected versions not to be deleted")
+	}
+
+	// The duration of the key overrides the one of the backend
+	doReq(logical.UpdateOperation, "config", map[string]interface{}{"delete_version_after": "1h"})
+	doReq(logical.UpdateOperation, "metadata/bar", map[string]interface{}{"delete_version_after": "2h"})
+	resp := doReq(logical.ReadOperation, "config", nil)
+	if resp.Data["delete_version_after"] != int64(3600) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	resp = doReq(logical.ReadOperation, "metadata/bar", nil)
+	if resp.Data["delete_version_after"] != int64(7200) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	sweep(30 * time.Minute)
+	if deleted("foo") || deleted("bar") || deleted("nested/baz") {
+		t.Fatal("expected versions not to be deleted")
+	}
+	sweep(90 * time.Minute)
+	if !deleted("foo") || deleted("bar") || !deleted("nested/baz") {
+		t.Fatal("expected versions of foo and nested/baz to be deleted")
+	}
+	sweep(3 * time.Hour)
+	if !deleted("bar") {
+		t.Fatal("expected version of bar to be deleted")
+	}
+}
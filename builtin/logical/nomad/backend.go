@@ -31,6 +31,7 @@ func Backend() *backend {
 			pathListRoles(&b),
 			pathRoles(&b),
 			pathCredsCreate(&b),
+			pathCredsServiceIdentity(&b),
 		},
 
 		Secrets: []*framework.Secret{
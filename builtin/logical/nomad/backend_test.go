@@ -2,9 +2,14 @@ package nomad
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"reflect"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -301,3 +306,198 @@ func TestBackend_CredsCreateEnvVar(t *testing.T) {
 		t.Fatalf("resp is error: %v", resp.Error())
 	}
 }
+
+// fakeNomad is a fake of the ACL tokens and allocations of the Nomad API
+type fakeNomad struct {
+	sync.Mutex
+	tokens map[string]*nomadapi.ACLToken
+	allocs map[string]*nomadapi.Allocation
+	count  int
+}
+
+func (f *fakeNomad) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	if r.Header.Get("X-Nomad-Token") != "root" {
+		http.Error(w, "Permission denied", http.StatusForbidden)
+		return
+	}
+
+	switch {
+	case r.URL.Path == "/v1/acl/token" && r.Method == http.MethodPut:
+		token := &nomadapi.ACLToken{}
+		if err := json.NewDecoder(r.Body).Decode(token); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.count++
+		token.AccessorID = fmt.Sprintf("accessor-%d", f.count)
+		token.SecretID = fmt.Sprintf("secret-%d", f.count)
+		f.tokens[token.AccessorID] = token
+		json.NewEncoder(w).Encode(token)
+
+	case strings.HasPrefix(r.URL.Path, "/v1/acl/token/") && r.Method == http.MethodDelete:
+		delete(f.tokens, strings.TrimPrefix(r.URL.Path, "/v1/acl/token/"))
+
+	case strings.HasPrefix(r.URL.Path, "/v1/allocation/") && r.Method == http.MethodGet:
+		alloc, ok := f.allocs[strings.TrimPrefix(r.URL.Path, "/v1/allocation/")]
+		if !ok {
+			http.Error(w, "alloc not found", http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(alloc)
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestBackend_serviceIdentity(t *testing.T) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	b, err := Factory(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nomad := &fakeNomad{
+		tokens: make(map[string]*nomadapi.ACLToken),
+		allocs: map[string]*nomadapi.Allocation{
+			"5a3e1b2c-61f2-4c5e-9d2f-4f1ce2a9b7d3": {
+				ID:           "5a3e1b2c-61f2-4c5e-9d2f-4f1ce2a9b7d3",
+				JobID:        "web",
+				ClientStatus: "running",
+				TaskStates:   map[string]*nomadapi.TaskState{"server": {State: "running"}},
+			},
+		},
+	}
+	server := httptest.NewServer(nomad)
+	defer server.Close()
+
+	req := &logical.Request{
+		Storage:   config.StorageView,
+		Operation: logical.UpdateOperation,
+		Path:      "config/access",
+		Data: map[string]interface{}{
+			"address": server.URL,
+			"token":   "root",
+		},
+	}
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp:%#v\n err:%v", resp, err)
+	}
+
+	req.Path = "config/lease"
+	req.Data = map[string]interface{}{
+		"ttl":     "1h",
+		"max_ttl": "24h",
+	}
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp:%#v\n err:%v", resp, err)
+	}
+
+	req.Path = "role/test"
+	req.Data = map[string]interface{}{
+		"policies": []string{"web"},
+		"ttl":      "2h",
+		"max_ttl":  "1h",
+	}
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error response. resp:%#v\n err:%v", resp, err)
+	}
+	req.Data["max_ttl"] = "4h"
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp:%#v\n err:%v", resp, err)
+	}
+
+	// Tokens of the role use its lease durations
+	req.Operation = logical.ReadOperation
+	req.Path = "creds/test"
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: resp:%#v\n err:%v", resp, err)
+	}
+	if resp.Secret.TTL != 2*time.Hour {
+		t.Fatalf("bad: ttl: %v", resp.Secret.TTL)
+	}
+
+	req.Operation = logical.UpdateOperation
+	req.Path = "si/test"
+	for _, data := range []map[string]interface{}{
+		{"alloc_id": "missing", "task": "server"},
+		{"alloc_id": "5a3e1b2c-61f2-4c5e-9d2f-4f1ce2a9b7d3", "task": "missing"},
+		{"alloc_id": "5a3e1b2c-61f2-4c5e-9d2f-4f1ce2a9b7d3"},
+	} {
+		req.Data = data
+		resp, err = b.HandleRequest(context.Background(), req)
+		if err != nil || resp == nil || !resp.IsError() {
+			t.Fatalf("%#v: expected an error response. resp:%#v\n err:%v", data, resp, err)
+		}
+	}
+
+	req.Data = map[string]interface{}{
+		"alloc_id": "5a3e1b2c-61f2-4c5e-9d2f-4f1ce2a9b7d3",
+		"task":     "server",
+	}
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: resp:%#v\n err:%v", resp, err)
+	}
+	if resp.Data["job_id"] != "web" || resp.Data["task"] != "server" || resp.Secret.TTL != 2*time.Hour {
+		t.Fatalf("bad: %#v", resp)
+	}
+	token := nomad.tokens[resp.Data["accessor_id"].(string)]
+	if token == nil || !strings.HasPrefix(token.Name, "vault-si-test-web-server-5a3e1b2c-") || !reflect.DeepEqual(token.Policies, []string{"web"}) {
+		t.Fatalf("bad: token: %#v", token)
+	}
+
+	secret := resp.Secret
+	secret.IssueTime = time.Now()
+	req.Operation = logical.RenewOperation
+	req.Secret = secret
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: resp:%#v\n err:%v", resp, err)
+	}
+
+	// The token cannot be renewed once its allocation stopped
+	nomad.allocs["5a3e1b2c-61f2-4c5e-9d2f-4f1ce2a9b7d3"].ClientStatus = "complete"
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error response. resp:%#v\n err:%v", resp, err)
+	}
+
+	req.Operation = logical.RevokeOperation
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp != nil {
+		t.Fatalf("bad: resp:%#v\n err:%v", resp, err)
+	}
+	if _, ok := nomad.tokens[token.AccessorID]; ok {
+		t.Fatalf("token %q was not revoked", token.AccessorID)
+	}
+
+	// Management roles cannot derive service identity tokens
+	req.Operation = logical.UpdateOperation
+	req.Path = "role/management"
+	req.Data = map[string]interface{}{
+		"type": "management",
+	}
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp:%#v\n err:%v", resp, err)
+	}
+	req.Path = "si/management"
+	req.Data = map[string]interface{}{
+		"alloc_id": "5a3e1b2c-61f2-4c5e-9d2f-4f1ce2a9b7d3",
+		"task":     "server",
+	}
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error response. resp:%#v\n err:%v", resp, err)
+	}
+}
@@ -43,9 +43,6 @@ func (b *backend) pathTokenRead(ctx context.Context, req *logical.Request, d *fr
 	if err != nil {
 		return nil, err
 	}
-	if leaseConfig == nil {
-		leaseConfig = &configLease{}
-	}
 
 	// Get the nomad client
 	c, err := b.client(ctx, req.Storage)
@@ -73,8 +70,9 @@ func (b *backend) pathTokenRead(ctx context.Context, req *logical.Request, d *fr
 		"accessor_id": token.AccessorID,
 	}, map[string]interface{}{
 		"accessor_id": token.AccessorID,
+		"role":        name,
 	})
-	resp.Secret.TTL = leaseConfig.TTL
+	resp.Secret.TTL, _ = role.leaseDurations(leaseConfig)
 
 	return resp, nil
 }
@@ -0,0 +1,148 @@
+package nomad
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/nomad/api"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// allocClientStatusRunning is the client status of running allocations
+const allocClientStatusRunning = "running"
+
+func pathCredsServiceIdentity(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "si/" + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the role",
+			},
+
+			"alloc_id": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "ID of the running allocation of the workload",
+			},
+
+			"task": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the task of the workload in the allocation",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathServiceIdentityTokenCreate,
+		},
+
+		HelpSynopsis:    pathCredsServiceIdentityHelpSyn,
+		HelpDescription: pathCredsServiceIdentityHelpDesc,
+	}
+}
+
+func (b *backend) pathServiceIdentityTokenCreate(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+	allocID := d.Get("alloc_id").(string)
+	task := d.Get("task").(string)
+	if allocID == "" {
+		return logical.ErrorResponse("alloc_id is required"), nil
+	}
+	if task == "" {
+		return logical.ErrorResponse("task is required"), nil
+	}
+
+	role, err := b.Role(ctx, req.Storage, name)
+	if err != nil {
+		return nil, errwrap.Wrapf("error retrieving role: {{err}}", err)
+	}
+	if role == nil {
+		return logical.ErrorResponse(fmt.Sprintf("role %q not found", name)), nil
+	}
+	if role.TokenType != "client" {
+		return logical.ErrorResponse("service identity tokens can only be derived from client roles"), nil
+	}
+
+	leaseConfig, err := b.LeaseConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := b.client(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	// Tokens are only derived for the tasks of running allocations
+	alloc, err := runningAllocation(c, allocID)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+	if _, ok := alloc.TaskStates[task]; !ok {
+		return logical.ErrorResponse(fmt.Sprintf("task %q not found in allocation %q", task, allocID)), nil
+	}
+
+	// Generate a name for the token identifying the workload
+	tokenName := fmt.Sprintf("vault-si-%s-%s-%s-%s-%d", name, alloc.JobID, task, shortID(alloc.ID), time.Now().UnixNano())
+
+	token, _, err := c.ACLTokens().Create(&api.ACLToken{
+		Name:     tokenName,
+		Type:     role.TokenType,
+		Policies: role.Policies,
+		Global:   role.Global,
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := b.Secret(SecretTokenType).Response(map[string]interface{}{
+		"secret_id":   token.SecretID,
+		"accessor_id": token.AccessorID,
+		"job_id":      alloc.JobID,
+		"alloc_id":    alloc.ID,
+		"task":        task,
+	}, map[string]interface{}{
+		"accessor_id": token.AccessorID,
+		"role":        name,
+		"alloc_id":    alloc.ID,
+	})
+	resp.Secret.TTL, _ = role.leaseDurations(leaseConfig)
+
+	return resp, nil
+}
+
+// runningAllocation returns the allocation if it is running.
+func runningAllocation(c *api.Client, allocID string) (*api.Allocation, error) {
+	alloc, _, err := c.Allocations().Info(allocID, nil)
+	if err != nil {
+		return nil, errwrap.Wrapf(fmt.Sprintf("error reading allocation %q: {{err}}", allocID), err)
+	}
+	if alloc.ClientStatus != allocClientStatusRunning {
+		return nil, fmt.Errorf("allocation %q is not running", allocID)
+	}
+	return alloc, nil
+}
+
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
+
+const pathCredsServiceIdentityHelpSyn = `
+Derive a service identity token for the task of a running allocation.
+`
+
+const pathCredsServiceIdentityHelpDesc = `
+This path derives a Nomad token with the policies of a client role for a
+workload, identified by the ID of its allocation and the name of its task. For
+example, if this backend is mounted at "nomad", then writing the alloc_id and
+task to "nomad/si/web" would derive a token for the "web" role.
+
+The allocation must be running and contain the task. The token is named after
+the job, the task and the allocation, and its lease can only be renewed while
+the allocation is running. The token is deleted when its lease is revoked.
+`
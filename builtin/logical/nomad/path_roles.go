@@ -3,6 +3,7 @@ package nomad
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/hashicorp/errwrap"
 	"github.com/hashicorp/vault/logical"
@@ -46,6 +47,18 @@ or 'management'. If a 'management' token,
 the "policies" parameter is not required.
 Defaults to 'client'.`,
 			},
+
+			"ttl": &framework.FieldSchema{
+				Type: framework.TypeDurationSecond,
+				Description: `Lease duration of the tokens of the role. Defaults
+to the ttl of the lease configuration.`,
+			},
+
+			"max_ttl": &framework.FieldSchema{
+				Type: framework.TypeDurationSecond,
+				Description: `Maximum lease duration of the tokens of the role.
+Defaults to the max_ttl of the lease configuration.`,
+			},
 		},
 
 		Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -116,6 +129,8 @@ func (b *backend) pathRolesRead(ctx context.Context, req *logical.Request, d *fr
 			"type":     role.TokenType,
 			"global":   role.Global,
 			"policies": role.Policies,
+			"ttl":      int64(role.TTL.Seconds()),
+			"max_ttl":  int64(role.MaxTTL.Seconds()),
 		},
 	}
 	return resp, nil
@@ -159,6 +174,16 @@ func (b *backend) pathRolesWrite(ctx context.Context, req *logical.Request, d *f
 		role.Global = global.(bool)
 	}
 
+	if ttl, ok := d.GetOk("ttl"); ok {
+		role.TTL = time.Duration(ttl.(int)) * time.Second
+	}
+	if maxTTL, ok := d.GetOk("max_ttl"); ok {
+		role.MaxTTL = time.Duration(maxTTL.(int)) * time.Second
+	}
+	if role.MaxTTL != 0 && role.TTL > role.MaxTTL {
+		return logical.ErrorResponse("ttl cannot be greater than max_ttl"), nil
+	}
+
 	entry, err := logical.StorageEntryJSON("role/"+name, role)
 	if err != nil {
 		return nil, err
@@ -180,7 +205,24 @@ func (b *backend) pathRolesDelete(ctx context.Context, req *logical.Request, d *
 }
 
 type roleConfig struct {
-	Policies  []string `json:"policies"`
-	TokenType string   `json:"type"`
-	Global    bool     `json:"global"`
+	Policies  []string      `json:"policies"`
+	TokenType string        `json:"type"`
+	Global    bool          `json:"global"`
+	TTL       time.Duration `json:"ttl"`
+	MaxTTL    time.Duration `json:"max_ttl"`
+}
+
+// leaseDurations returns the lease durations of the tokens of the role, which
+// default to the ones of the lease configuration.
+func (r *roleConfig) leaseDurations(lease *configLease) (time.Duration, time.Duration) {
+	ttl, maxTTL := r.TTL, r.MaxTTL
+	if lease != nil {
+		if ttl == 0 {
+			ttl = lease.TTL
+		}
+		if maxTTL == 0 {
+			maxTTL = lease.MaxTTL
+		}
+	}
+	return ttl, maxTTL
 }
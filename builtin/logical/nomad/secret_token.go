@@ -37,7 +37,33 @@ func (b *backend) secretTokenRenew(ctx context.Context, req *logical.Request, d
 		lease = &configLease{}
 	}
 
-	return framework.LeaseExtend(lease.TTL, lease.MaxTTL, b.System())(ctx, req, d)
+	// Tokens issued before roles had lease durations only have the lease
+	// configuration
+	roleRaw, ok := req.Secret.InternalData["role"]
+	if !ok {
+		return framework.LeaseExtend(lease.TTL, lease.MaxTTL, b.System())(ctx, req, d)
+	}
+	role, err := b.Role(ctx, req.Storage, roleRaw.(string))
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return logical.ErrorResponse(fmt.Sprintf("issuing role %q not found", roleRaw)), nil
+	}
+
+	// Service identity tokens are only renewed while their allocation runs
+	if allocIDRaw, ok := req.Secret.InternalData["alloc_id"]; ok {
+		c, err := b.client(ctx, req.Storage)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := runningAllocation(c, allocIDRaw.(string)); err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+	}
+
+	ttl, maxTTL := role.leaseDurations(lease)
+	return framework.LeaseExtend(ttl, maxTTL, b.System())(ctx, req, d)
 }
 
 func (b *backend) secretTokenRevoke(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
@@ -0,0 +1,494 @@
+package pki
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+const (
+	acmeProblemPrefix = "urn:ietf:params:acme:error:"
+
+	// acmeNonceLifetime is the time after which unused nonces are forgotten
+	acmeNonceLifetime = 30 * time.Minute
+
+	// acmeOrderLifetime is the time ACME clients have to complete the
+	// validation of the identifiers of an order
+	acmeOrderLifetime = 24 * time.Hour
+
+	// acmeValidationTimeout bounds the time spent validating a challenge
+	acmeValidationTimeout = 10 * time.Second
+
+	// acmeHTTP01Port is the port http-01 challenges are validated on
+	acmeHTTP01Port = 80
+)
+
+const (
+	acmeStatusPending     = "pending"
+	acmeStatusReady       = "ready"
+	acmeStatusProcessing  = "processing"
+	acmeStatusValid       = "valid"
+	acmeStatusInvalid     = "invalid"
+	acmeStatusExpired     = "expired"
+	acmeStatusDeactivated = "deactivated"
+)
+
+const (
+	acmeChallengeHTTP01 = "http-01"
+	acmeChallengeDNS01  = "dns-01"
+)
+
+// acmeSignatureAlgorithms are the algorithms requests may be signed with;
+// symmetric algorithms are never acceptable
+var acmeSignatureAlgorithms = []string{
+	string(jose.RS256), string(jose.RS384), string(jose.RS512),
+	string(jose.PS256), string(jose.PS384), string(jose.PS512),
+	string(jose.ES256), string(jose.ES384), string(jose.ES512),
+	string(jose.EdDSA),
+}
+
+// acmeProblem is an ACME error, returned to clients as a problem document
+// (RFC 7807)
+type acmeProblem struct {
+	Type   string `json:"type"`
+	Detail string `json:"detail"`
+	Status int    `json:"status"`
+}
+
+func (p *acmeProblem) Error() string {
+	return fmt.Sprintf("%s: %s", p.Type, p.Detail)
+}
+
+func newACMEProblem(status int, errorType, format string, args ...interface{}) *acmeProblem {
+	return &acmeProblem{
+		Type:   acmeProblemPrefix + errorType,
+		Detail: fmt.Sprintf(format, args...),
+		Status: status,
+	}
+}
+
+func acmeMalformed(format string, args ...interface{}) *acmeProblem {
+	return newACMEProblem(http.StatusBadRequest, "malformed", format, args...)
+}
+
+// acmeNonceStore keeps the nonces handed out to ACME clients until they are
+// used, so that each signed request can only be submitted once. Nonces are
+// kept in memory: they are only valid on the node that issued them.
+type acmeNonceStore struct {
+	lock      sync.Mutex
+	nonces    map[string]time.Time
+	lastSweep time.Time
+}
+
+func (s *acmeNonceStore) get() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	nonce := base64.RawURLEncoding.EncodeToString(buf)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := time.Now()
+	if s.nonces == nil {
+		s.nonces = map[string]time.Time{}
+	}
+	if now.Sub(s.lastSweep) > time.Minute {
+		for n, expiry := range s.nonces {
+			if now.After(expiry) {
+				delete(s.nonces, n)
+			}
+		}
+		s.lastSweep = now
+	}
+	s.nonces[nonce] = now.Add(acmeNonceLifetime)
+
+	return nonce, nil
+}
+
+// redeem reports whether the nonce was handed out and not used yet, and
+// invalidates it.
+func (s *acmeNonceStore) redeem(nonce string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	expiry, ok := s.nonces[nonce]
+	if !ok {
+		return false
+	}
+	delete(s.nonces, nonce)
+	return time.Now().Before(expiry)
+}
+
+type acmeAuth int
+
+const (
+	// acmeAuthNone is for the directory and nonce endpoints, which take
+	// unsigned GET requests
+	acmeAuthNone acmeAuth = iota
+	// acmeAuthJWK is for requests signed with a key embedded in the request,
+	// to create accounts
+	acmeAuthJWK
+	// acmeAuthKID is for requests signed with the key of an account
+	acmeAuthKID
+)
+
+// acmeRequest is a verified ACME request
+type acmeRequest struct {
+	config  *acmeConfig
+	url     string
+	payload []byte
+
+	// jwk is the key the request was signed with, if embedded in it
+	jwk *jose.JSONWebKey
+
+	// account is the account that signed the request, if signed with the
+	// key of an account
+	account *acmeAccount
+}
+
+// postAsGet reports whether the request is a POST-as-GET request, which
+// fetches a resource without changing it.
+func (r *acmeRequest) postAsGet() bool {
+	return len(r.payload) == 0
+}
+
+func (r *acmeRequest) decodePayload(out interface{}) error {
+	if err := json.Unmarshal(r.payload, out); err != nil {
+		return acmeMalformed("failed to parse the payload: %v", err)
+	}
+	return nil
+}
+
+// acmeReply is the response to an ACME request
+type acmeReply struct {
+	status int
+
+	// body is marshaled as JSON unless raw is set, and omitted for
+	// http.StatusNoContent
+	body        interface{}
+	raw         []byte
+	contentType string
+
+	location string
+	links    []string
+}
+
+type acmeOperation func(ctx context.Context, req *logical.Request, data *framework.FieldData, r *acmeRequest) (*acmeReply, error)
+
+// acmeFields are the members of the flattened JWS serialization ACME
+// requests are sent as
+func acmeFields(fields map[string]*framework.FieldSchema) map[string]*framework.FieldSchema {
+	fields["protected"] = &framework.FieldSchema{
+		Type:        framework.TypeString,
+		Description: `Protected header of the JWS`,
+	}
+	fields["payload"] = &framework.FieldSchema{
+		Type:        framework.TypeString,
+		Description: `Payload of the JWS`,
+	}
+	fields["signature"] = &framework.FieldSchema{
+		Type:        framework.TypeString,
+		Description: `Signature of the JWS`,
+	}
+	return fields
+}
+
+// acmeHandler returns the callback of an ACME endpoint, which verifies the
+// request as required by auth, runs the operation and turns its result or
+// error into an ACME response.
+func (b *backend) acmeHandler(auth acmeAuth, op acmeOperation) framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		config, err := getACMEConfig(ctx, req.Storage)
+		if err != nil {
+			return nil, err
+		}
+
+		reply, err := func() (*acmeReply, error) {
+			if !config.Enabled {
+				return nil, newACMEProblem(http.StatusForbidden, "unauthorized", "ACME is not enabled on this mount")
+			}
+
+			r := &acmeRequest{
+				config: config,
+				url:    config.BaseURL + "/" + req.Path,
+			}
+			if auth != acmeAuthNone {
+				if err := b.verifyACMERequest(ctx, req, data, r, auth); err != nil {
+					return nil, err
+				}
+			}
+
+			return op(ctx, req, data, r)
+		}()
+		if err != nil {
+			problem, ok := err.(*acmeProblem)
+			if !ok {
+				b.Logger().Error("pki: ACME request failed", "path", req.Path, "error", err)
+				problem = newACMEProblem(http.StatusInternalServerError, "serverInternal", "internal error")
+			}
+			reply = &acmeReply{
+				status:      problem.Status,
+				body:        problem,
+				contentType: "application/problem+json",
+			}
+		}
+
+		return b.acmeResponse(config, reply)
+	}
+}
+
+// verifyACMERequest checks the JWS of a request and fills in the request
+// with its payload and signer.
+func (b *backend) verifyACMERequest(ctx context.Context, req *logical.Request, data *framework.FieldData, r *acmeRequest, auth acmeAuth) error {
+	protected := data.Get("protected").(string)
+	payload := data.Get("payload").(string)
+	signature := data.Get("signature").(string)
+	if protected == "" || signature == "" {
+		return acmeMalformed("the request must be a JWS in the flattened JSON serialization")
+	}
+
+	jws, err := jose.ParseSigned(protected + "." + payload + "." + signature)
+	if err != nil {
+		return acmeMalformed("failed to parse the JWS: %v", err)
+	}
+	if len(jws.Signatures) != 1 {
+		return acmeMalformed("the JWS must have exactly one signature")
+	}
+
+	// The compact serialization only has a protected header
+	header := jws.Signatures[0].Header
+	if !strutil.StrListContains(acmeSignatureAlgorithms, header.Algorithm) {
+		return newACMEProblem(http.StatusBadRequest, "badSignatureAlgorithm", "unsupported signature algorithm %q", header.Algorithm)
+	}
+	if !b.acmeNonces.redeem(header.Nonce) {
+		return newACMEProblem(http.StatusBadRequest, "badNonce", "the nonce is invalid or was already used")
+	}
+	if headerURL, _ := header.ExtraHeaders["url"].(string); headerURL != r.url {
+		return newACMEProblem(http.StatusUnauthorized, "unauthorized", "the url of the JWS header must be %q", r.url)
+	}
+	if header.JSONWebKey != nil && header.KeyID != "" {
+		return acmeMalformed("the JWS header must not have both jwk and kid")
+	}
+
+	var key *jose.JSONWebKey
+	if auth == acmeAuthJWK {
+		if header.JSONWebKey == nil {
+			return acmeMalformed("the request must be signed with the key given in the jwk header")
+		}
+		key = header.JSONWebKey
+		if !key.Valid() || !key.IsPublic() {
+			return acmeMalformed("the jwk header must be a valid public key")
+		}
+		r.jwk = key
+	} else {
+		if header.KeyID == "" {
+			return acmeMalformed("the request must be signed with the key of the account given in the kid header")
+		}
+		accountPrefix := r.config.BaseURL + "/acme/account/"
+		if !strings.HasPrefix(header.KeyID, accountPrefix) {
+			return newACMEProblem(http.StatusBadRequest, "accountDoesNotExist", "unknown account %q", header.KeyID)
+		}
+		account, err := getACMEAccount(ctx, req.Storage, strings.TrimPrefix(header.KeyID, accountPrefix))
+		if err != nil {
+			return err
+		}
+		if account == nil {
+			return newACMEProblem(http.StatusBadRequest, "accountDoesNotExist", "unknown account %q", header.KeyID)
+		}
+		if account.Status != acmeStatusValid {
+			return newACMEProblem(http.StatusUnauthorized, "unauthorized", "the account is %s", account.Status)
+		}
+		key, err = account.jwk()
+		if err != nil {
+			return err
+		}
+		r.account = account
+	}
+
+	r.payload, err = jws.Verify(key)
+	if err != nil {
+		return acmeMalformed("the JWS signature is invalid")
+	}
+
+	return nil
+}
+
+// acmeResponse returns the raw HTTP response of an ACME reply, with a fresh
+// nonce for the next request of the client.
+func (b *backend) acmeResponse(config *acmeConfig, reply *acmeReply) (*logical.Response, error) {
+	nonce, err := b.acmeNonces.get()
+	if err != nil {
+		return nil, err
+	}
+
+	headers := map[string][]string{
+		"Replay-Nonce":  []string{nonce},
+		"Cache-Control": []string{"no-store"},
+	}
+	links := reply.links
+	if config.BaseURL != "" {
+		links = append(links, fmt.Sprintf(`<%s/acme/directory>;rel="index"`, config.BaseURL))
+	}
+	if len(links) != 0 {
+		headers["Link"] = links
+	}
+	if reply.location != "" {
+		headers["Location"] = []string{reply.location}
+	}
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPStatusCode: reply.status,
+			logical.HTTPRawHeaders: headers,
+		},
+	}
+	if reply.status == http.StatusNoContent {
+		return resp, nil
+	}
+
+	body := reply.raw
+	contentType := reply.contentType
+	if body == nil {
+		body, err = json.Marshal(reply.body)
+		if err != nil {
+			return nil, err
+		}
+		if contentType == "" {
+			contentType = "application/json"
+		}
+	}
+	resp.Data[logical.HTTPContentType] = contentType
+	resp.Data[logical.HTTPRawBody] = body
+
+	return resp, nil
+}
+
+// acmeThumbprint returns the base64url-encoded SHA-256 thumbprint of a key
+// (RFC 7638), which identifies the account of the key.
+func acmeThumbprint(key *jose.JSONWebKey) (string, error) {
+	thumbprint, err := key.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(thumbprint), nil
+}
+
+// acmeKeyAuthorization returns the key authorization of a challenge token
+// for an account key (RFC 8555, section 8.1).
+func acmeKeyAuthorization(token string, key *jose.JSONWebKey) (string, error) {
+	thumbprint, err := acmeThumbprint(key)
+	if err != nil {
+		return "", err
+	}
+	return token + "." + thumbprint, nil
+}
+
+func acmeRandomToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// validateACMEChallenge checks that the challenge is fulfilled for the
+// domain, returning the problem found otherwise.
+func (b *backend) validateACMEChallenge(ctx context.Context, challenge *acmeChallenge, domain, keyAuthorization string) *acmeProblem {
+	ctx, cancel := context.WithTimeout(ctx, acmeValidationTimeout)
+	defer cancel()
+
+	switch challenge.Type {
+	case acmeChallengeHTTP01:
+		return b.validateHTTP01(ctx, domain, challenge.Token, keyAuthorization)
+	case acmeChallengeDNS01:
+		return b.validateDNS01(ctx, domain, keyAuthorization)
+	}
+	return acmeMalformed("unsupported challenge type %q", challenge.Type)
+}
+
+// validateHTTP01 fetches the key authorization from the well-known URL of
+// the domain (RFC 8555, section 8.3).
+func (b *backend) validateHTTP01(ctx context.Context, domain, token, keyAuthorization string) *acmeProblem {
+	host := domain
+	if b.acmeHTTP01Port != acmeHTTP01Port {
+		host = net.JoinHostPort(domain, strconv.Itoa(b.acmeHTTP01Port))
+	}
+	challengeURL := fmt.Sprintf("http://%s/.well-known/acme-challenge/%s", host, token)
+
+	httpReq, err := http.NewRequest("GET", challengeURL, nil)
+	if err != nil {
+		return acmeMalformed("invalid challenge URL %q: %v", challengeURL, err)
+	}
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return fmt.Errorf("stopped after 10 redirects")
+			}
+			return nil
+		},
+	}
+	resp, err := client.Do(httpReq.WithContext(ctx))
+	if err != nil {
+		return newACMEProblem(http.StatusBadRequest, "connection", "failed to fetch %s: %v", challengeURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return newACMEProblem(http.StatusForbidden, "unauthorized", "fetching %s returned status %d", challengeURL, resp.StatusCode)
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	if err != nil {
+		return newACMEProblem(http.StatusBadRequest, "connection", "failed to read %s: %v", challengeURL, err)
+	}
+	if strings.TrimSpace(string(body)) != keyAuthorization {
+		return newACMEProblem(http.StatusForbidden, "incorrectResponse", "%s does not serve the key authorization of the challenge", challengeURL)
+	}
+
+	return nil
+}
+
+// validateDNS01 looks up the digest of the key authorization in the TXT
+// records of the domain (RFC 8555, section 8.4).
+func (b *backend) validateDNS01(ctx context.Context, domain, keyAuthorization string) *acmeProblem {
+	digest := sha256.Sum256([]byte(keyAuthorization))
+	expected := base64.RawURLEncoding.EncodeToString(digest[:])
+
+	name := "_acme-challenge." + domain
+	records, err := b.acmeLookupTXT(ctx, name)
+	if err != nil {
+		return newACMEProblem(http.StatusBadRequest, "dns", "failed to look up the TXT records of %s: %v", name, err)
+	}
+	for _, record := range records {
+		if record == expected {
+			return nil
+		}
+	}
+
+	return newACMEProblem(http.StatusForbidden, "incorrectResponse", "no TXT record of %s has the digest of the key authorization of the challenge", name)
+}
+
+func formatACMETime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -2,10 +2,13 @@ package pki
 
 import (
 	"context"
+	"fmt"
+	"net"
 	"strings"
 	"sync"
 	"time"
 
+	multierror "github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
 )
@@ -33,12 +36,25 @@ func Backend() *backend {
 				"ca",
 				"crl/pem",
 				"crl",
+				"crl/delta",
+				"crl/delta/pem",
+				"acme/*",
+				"est/*",
+				"ocsp",
+				"ocsp/*",
+				"ca/issuer/*",
+				"crl/issuer/*",
 			},
 
 			LocalStorage: []string{
 				"revoked/",
 				"crl",
+				"crls/",
 				"certs/",
+				"crl_state",
+				"delta_crl",
+				"delta_crls/",
+				"delta_wal/",
 			},
 
 			Root: []string{
@@ -48,6 +64,9 @@ func Backend() *backend {
 
 			SealWrapStorage: []string{
 				"config/ca_bundle",
+				"config/ocsp_bundle",
+				"issuers/",
+				"intermediate/pending/",
 			},
 		},
 
@@ -75,16 +94,55 @@ func Backend() *backend {
 			pathFetchListCerts(&b),
 			pathRevoke(&b),
 			pathTidy(&b),
+			pathTidyStatus(&b),
+			pathConfigAutoTidy(&b),
+			pathConfigACME(&b),
+			pathACMEDirectory(&b),
+			pathACMENewNonce(&b),
+			pathACMENewAccount(&b),
+			pathACMEAccount(&b),
+			pathACMEAccountOrders(&b),
+			pathACMENewOrder(&b),
+			pathACMEOrder(&b),
+			pathACMEOrderFinalize(&b),
+			pathACMEOrderCert(&b),
+			pathACMEAuthorization(&b),
+			pathACMEChallenge(&b),
+			pathACMERevokeCert(&b),
+			pathConfigEST(&b),
+			pathConfigIssuanceWebhook(&b),
+			pathESTCACerts(&b),
+			pathESTSimpleEnroll(&b),
+			pathESTSimpleReenroll(&b),
+			pathConfigOCSP(&b),
+			pathOCSP(&b),
+			pathOCSPGet(&b),
+			pathListIssuers(&b),
+			pathImportIssuers(&b),
+			pathGenerateRootIssuer(&b),
+			pathIssuer(&b),
+			pathConfigIssuers(&b),
+			pathIssuerCrossSign(&b),
+			pathIssuerIssue(&b),
+			pathIssuerSign(&b),
+			pathIssuerSignVerbatim(&b),
+			pathIssuerRevoke(&b),
+			pathFetchIssuerCA(&b),
+			pathFetchIssuerCRL(&b),
 		},
 
 		Secrets: []*framework.Secret{
 			secretCerts(&b),
 		},
 
-		BackendType: logical.TypeLogical,
+		PeriodicFunc: b.periodicFunc,
+		BackendType:  logical.TypeLogical,
 	}
 
 	b.crlLifetime = time.Hour * 72
+	b.lastTidy = time.Now()
+	b.acmeHTTP01Port = acmeHTTP01Port
+	b.acmeLookupTXT = net.DefaultResolver.LookupTXT
 
 	return &b
 }
@@ -94,6 +152,40 @@ type backend struct {
 
 	crlLifetime       time.Duration
 	revokeStorageLock sync.RWMutex
+
+	// crlLock serializes the CRL builds, which are numbered
+	crlLock sync.Mutex
+
+	// tidyLock guards tidyStatus, the status of the last tidy operation,
+	// and lastTidy, the time it started
+	tidyLock   sync.RWMutex
+	tidyStatus *tidyStatus
+	lastTidy   time.Time
+
+	// issuersLock serializes changes to the issuers and the default issuer
+	issuersLock sync.Mutex
+
+	// acmeLock serializes changes to the ACME accounts, orders and
+	// authorizations
+	acmeLock   sync.Mutex
+	acmeNonces acmeNonceStore
+
+	// acmeHTTP01Port and acmeLookupTXT are used to validate ACME challenges
+	// and are overridden in tests
+	acmeHTTP01Port int
+	acmeLookupTXT  func(ctx context.Context, name string) ([]string, error)
+}
+
+// periodicFunc rebuilds the CRLs and tidies the storage on schedule
+func (b *backend) periodicFunc(ctx context.Context, req *logical.Request) error {
+	var mErr *multierror.Error
+	if err := b.autoRebuildCRLs(ctx, req); err != nil {
+		mErr = multierror.Append(mErr, fmt.Errorf("failed to rebuild CRLs: %v", err))
+	}
+	if err := b.autoTidy(ctx, req); err != nil {
+		mErr = multierror.Append(mErr, fmt.Errorf("failed to tidy: %v", err))
+	}
+	return mErr.ErrorOrNil()
 }
 
 const backendHelp = `
@@ -14,6 +14,7 @@ import (
 	"encoding/base64"
 	"encoding/pem"
 	"fmt"
+	"io"
 	"net"
 	"regexp"
 	"strings"
@@ -22,6 +23,7 @@ import (
 	"github.com/hashicorp/errwrap"
 	"github.com/hashicorp/vault/helper/certutil"
 	"github.com/hashicorp/vault/helper/errutil"
+	"github.com/hashicorp/vault/helper/fips"
 	"github.com/hashicorp/vault/helper/parseutil"
 	"github.com/hashicorp/vault/helper/strutil"
 	"github.com/hashicorp/vault/logical"
@@ -142,6 +144,11 @@ func validateKeyTypeLength(keyType string, keyBits int) *logical.Response {
 			return logical.ErrorResponse(fmt.Sprintf(
 				"unsupported bit length for EC key: %d", keyBits))
 		}
+	case "ed25519":
+		// Ed25519 keys have a fixed size, so the key bits are ignored
+		if err := fips.CheckApproved("key type ed25519", false); err != nil {
+			return logical.ErrorResponse(err.Error())
+		}
 	default:
 		return logical.ErrorResponse(fmt.Sprintf(
 			"unknown key type %s", keyType))
@@ -150,29 +157,33 @@ func validateKeyTypeLength(keyType string, keyBits int) *logical.Response {
 	return nil
 }
 
-// Fetches the CA info. Unlike other certificates, the CA info is stored
-// in the backend as a CertBundle, because we are storing its private key
-func fetchCAInfo(ctx context.Context, req *logical.Request) (*caInfoBundle, error) {
-	bundleEntry, err := req.Storage.Get(ctx, "config/ca_bundle")
+// Fetches the CA info of an issuer, referenced by ID or name, or of the
+// default issuer. Unlike other certificates, the CA info is stored in the
+// backend as a CertBundle, because we are storing its private key
+func fetchCAInfo(ctx context.Context, b *backend, req *logical.Request, issuerRef string) (*caInfoBundle, error) {
+	issuer, err := b.resolveIssuer(ctx, req.Storage, issuerRef)
 	if err != nil {
-		return nil, errutil.InternalError{Err: fmt.Sprintf("unable to fetch local CA certificate/key: %v", err)}
+		return nil, err
 	}
-	if bundleEntry == nil {
-		return nil, errutil.UserError{Err: "backend must be configured with a CA certificate/key"}
+	if !issuer.hasKey() {
+		return nil, errutil.UserError{Err: fmt.Sprintf("issuer %s has no private key and cannot be used for signing", issuer.ID)}
 	}
 
-	var bundle certutil.CertBundle
-	if err := bundleEntry.DecodeJSON(&bundle); err != nil {
-		return nil, errutil.InternalError{Err: fmt.Sprintf("unable to decode local CA certificate/key: %v", err)}
-	}
-
-	parsedBundle, err := bundle.ToParsedCertBundle()
+	caInfo, err := issuerCAInfo(ctx, req, issuer)
 	if err != nil {
-		return nil, errutil.InternalError{Err: err.Error()}
+		return nil, err
 	}
+	if err := fips.CheckApproved("key type ed25519", caInfo.PrivateKeyType != certutil.Ed25519PrivateKey); err != nil {
+		return nil, errutil.UserError{Err: fmt.Sprintf("issuer %s cannot be used for signing: %v", issuer.ID, err)}
+	}
+	return caInfo, nil
+}
 
-	if parsedBundle.Certificate == nil {
-		return nil, errutil.InternalError{Err: "stored CA information not able to be parsed"}
+// issuerCAInfo returns the CA info of an issuer, with the configured URLs
+func issuerCAInfo(ctx context.Context, req *logical.Request, issuer *issuerEntry) (*caInfoBundle, error) {
+	parsedBundle, err := issuer.parse()
+	if err != nil {
+		return nil, err
 	}
 
 	caInfo := &caInfoBundle{*parsedBundle, nil}
@@ -213,6 +224,8 @@ func fetchCertBySerial(ctx context.Context, req *logical.Request, prefix, serial
 		path = "ca"
 	case serial == "crl":
 		path = "crl"
+	case serial == "delta_crl":
+		path = "delta_crl"
 	default:
 		legacyPath = "certs/" + colonSerial
 		path = "certs/" + hyphenSerial
@@ -258,6 +271,34 @@ func fetchCertBySerial(ctx context.Context, req *logical.Request, prefix, serial
 	return certEntry, nil
 }
 
+// putCertMetadata stores the metadata given when issuing the certificate
+// with the serial, alongside its entry in the certificate store
+func putCertMetadata(ctx context.Context, s logical.Storage, serial string, metadata map[string]string) error {
+	entry, err := logical.StorageEntryJSON("cert_metadata/"+normalizeSerial(serial), metadata)
+	if err != nil {
+		return err
+	}
+	return s.Put(ctx, entry)
+}
+
+// fetchCertMetadata returns the metadata of the certificate with the serial,
+// or nil if none was given
+func fetchCertMetadata(ctx context.Context, s logical.Storage, serial string) (map[string]string, error) {
+	entry, err := s.Get(ctx, "cert_metadata/"+normalizeSerial(serial))
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var metadata map[string]string
+	if err := entry.DecodeJSON(&metadata); err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
+
 // Given a set of requested names for a certificate, verifies that all of them
 // match the various toggles set in the role for controlling issuance.
 // If one does not pass, it is returned in the string argument.
@@ -475,9 +516,14 @@ func generateCert(ctx context.Context,
 				creationBundle.MaxPathLength = *role.MaxPathLength
 			}
 		}
+	} else {
+		err := applyIssuanceWebhook(ctx, req, role, data.Get("role").(string), nil, creationBundle)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	parsedBundle, err := createCertificate(creationBundle)
+	parsedBundle, err := createCertificate(creationBundle, b.GetRandomReader())
 	if err != nil {
 		return nil, err
 	}
@@ -498,7 +544,7 @@ func generateIntermediateCSR(b *backend,
 		return nil, err
 	}
 
-	parsedBundle, err := createCSR(creationBundle)
+	parsedBundle, err := createCSR(creationBundle, b.GetRandomReader())
 	if err != nil {
 		return nil, err
 	}
@@ -506,7 +552,8 @@ func generateIntermediateCSR(b *backend,
 	return parsedBundle, nil
 }
 
-func signCert(b *backend,
+func signCert(ctx context.Context,
+	b *backend,
 	role *roleEntry,
 	signingBundle *caInfoBundle,
 	isCA bool,
@@ -528,6 +575,9 @@ func signCert(b *backend,
 	if err != nil {
 		return nil, errutil.UserError{Err: fmt.Sprintf("certificate request could not be parsed: %v", err)}
 	}
+	if err := fips.CheckApproved("key type ed25519", csr.PublicKeyAlgorithm != x509.Ed25519); err != nil {
+		return nil, errutil.UserError{Err: fmt.Sprintf("CSR's key cannot be signed: %v", err)}
+	}
 
 	switch role.KeyType {
 	case "rsa":
@@ -575,6 +625,14 @@ func signCert(b *backend,
 				pubKey.Params().BitSize)}
 		}
 
+	case "ed25519":
+		// Verify that the key matches the role type
+		if csr.PublicKeyAlgorithm != x509.Ed25519 {
+			return nil, errutil.UserError{Err: fmt.Sprintf(
+				"role requires keys of type %s",
+				role.KeyType)}
+		}
+
 	case "any":
 		// We only care about running RSA < 2048 bit checks, so if not RSA
 		// break out
@@ -603,6 +661,11 @@ func signCert(b *backend,
 
 	if isCA {
 		creationBundle.PermittedDNSDomains = data.Get("permitted_dns_domains").([]string)
+	} else {
+		err := applyIssuanceWebhook(ctx, req, role, data.Get("role").(string), csr, creationBundle)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	parsedBundle, err := signCertificate(creationBundle, csr)
@@ -894,9 +957,9 @@ func addKeyUsages(creationInfo *creationBundle, certTemplate *x509.Certificate)
 	}
 }
 
-// Performs the heavy lifting of creating a certificate. Returns
-// a fully-filled-in ParsedCertBundle.
-func createCertificate(creationInfo *creationBundle) (*certutil.ParsedCertBundle, error) {
+// Performs the heavy lifting of creating a certificate, generating its
+// private key from randReader. Returns a fully-filled-in ParsedCertBundle.
+func createCertificate(creationInfo *creationBundle, randReader io.Reader) (*certutil.ParsedCertBundle, error) {
 	var err error
 	result := &certutil.ParsedCertBundle{}
 
@@ -905,9 +968,10 @@ func createCertificate(creationInfo *creationBundle) (*certutil.ParsedCertBundle
 		return nil, err
 	}
 
-	if err := certutil.GeneratePrivateKey(creationInfo.KeyType,
+	if err := certutil.GeneratePrivateKeyWithRandomSource(creationInfo.KeyType,
 		creationInfo.KeyBits,
-		result); err != nil {
+		result,
+		randReader); err != nil {
 		return nil, err
 	}
 
@@ -958,6 +1022,8 @@ func createCertificate(creationInfo *creationBundle) (*certutil.ParsedCertBundle
 			certTemplate.SignatureAlgorithm = x509.SHA256WithRSA
 		case certutil.ECPrivateKey:
 			certTemplate.SignatureAlgorithm = x509.ECDSAWithSHA256
+		case certutil.Ed25519PrivateKey:
+			certTemplate.SignatureAlgorithm = x509.PureEd25519
 		}
 
 		caCert := creationInfo.SigningBundle.Certificate
@@ -983,6 +1049,8 @@ func createCertificate(creationInfo *creationBundle) (*certutil.ParsedCertBundle
 			certTemplate.SignatureAlgorithm = x509.SHA256WithRSA
 		case "ec":
 			certTemplate.SignatureAlgorithm = x509.ECDSAWithSHA256
+		case "ed25519":
+			certTemplate.SignatureAlgorithm = x509.PureEd25519
 		}
 
 		certTemplate.AuthorityKeyId = subjKeyID
@@ -1017,15 +1085,16 @@ func createCertificate(creationInfo *creationBundle) (*certutil.ParsedCertBundle
 	return result, nil
 }
 
-// Creates a CSR. This is currently only meant for use when
-// generating an intermediate certificate.
-func createCSR(creationInfo *creationBundle) (*certutil.ParsedCSRBundle, error) {
+// Creates a CSR, generating its private key from randReader. This is
+// currently only meant for use when generating an intermediate certificate.
+func createCSR(creationInfo *creationBundle, randReader io.Reader) (*certutil.ParsedCSRBundle, error) {
 	var err error
 	result := &certutil.ParsedCSRBundle{}
 
-	if err := certutil.GeneratePrivateKey(creationInfo.KeyType,
+	if err := certutil.GeneratePrivateKeyWithRandomSource(creationInfo.KeyType,
 		creationInfo.KeyBits,
-		result); err != nil {
+		result,
+		randReader); err != nil {
 		return nil, err
 	}
 
@@ -1046,6 +1115,8 @@ func createCSR(creationInfo *creationBundle) (*certutil.ParsedCSRBundle, error)
 		csrTemplate.SignatureAlgorithm = x509.SHA256WithRSA
 	case "ec":
 		csrTemplate.SignatureAlgorithm = x509.ECDSAWithSHA256
+	case "ed25519":
+		csrTemplate.SignatureAlgorithm = x509.PureEd25519
 	}
 
 	csr, err := x509.CreateCertificateRequest(rand.Reader, csrTemplate, result.PrivateKey)
@@ -1115,6 +1186,8 @@ func signCertificate(creationInfo *creationBundle,
 		certTemplate.SignatureAlgorithm = x509.SHA256WithRSA
 	case certutil.ECPrivateKey:
 		certTemplate.SignatureAlgorithm = x509.ECDSAWithSHA256
+	case certutil.Ed25519PrivateKey:
+		certTemplate.SignatureAlgorithm = x509.PureEd25519
 	}
 
 	if creationInfo.UseCSRValues {
@@ -1256,6 +1329,9 @@ func convertRespToPKCS8(resp *logical.Response) error {
 		signer, err = x509.ParsePKCS1PrivateKey(keyData)
 	case certutil.ECPrivateKey:
 		signer, err = x509.ParseECPrivateKey(keyData)
+	case certutil.Ed25519PrivateKey:
+		// Ed25519 keys are always PKCS#8 encoded
+		return nil
 	default:
 		return fmt.Errorf("unknown private key type %q", privKeyType)
 	}
@@ -2,11 +2,22 @@ package pki
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
 	"fmt"
+	"reflect"
 	"testing"
 
 	"strings"
 
+	"github.com/hashicorp/vault/helper/certutil"
+	"github.com/hashicorp/vault/helper/fips"
 	"github.com/hashicorp/vault/logical"
 )
 
@@ -123,3 +134,310 @@ func TestPki_FetchCertBySerial(t *testing.T) {
 		}
 	}
 }
+
+func TestPki_Ed25519(t *testing.T) {
+	b, storage := createBackendWithStorage(t)
+
+	request := func(op logical.Operation, path string, data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Operation: op,
+			Path:      path,
+			Storage:   storage,
+			Data:      data,
+		})
+	}
+	doReq := func(op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		resp, err := request(op, path, data)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: path: %s, err: %v, resp: %#v", path, err, resp)
+		}
+		return resp
+	}
+	parseCert := func(resp *logical.Response) *x509.Certificate {
+		bundle, err := certutil.ParsePEMBundle(resp.Data["certificate"].(string))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return bundle.Certificate
+	}
+	csrPEM := func(key interface{}) string {
+		csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+			Subject: pkix.Name{CommonName: "csr.example.com"},
+		}, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csr}))
+	}
+
+	resp := doReq(logical.UpdateOperation, "root/generate/exported", map[string]interface{}{
+		"common_name": "Root CA",
+		"key_type":    "ed25519",
+		"ttl":         "8760h",
+	})
+	if resp.Data["private_key_type"] != certutil.Ed25519PrivateKey {
+		t.Fatalf("bad: %#v", resp.Data["private_key_type"])
+	}
+	caCert := parseCert(resp)
+	if caCert.PublicKeyAlgorithm != x509.Ed25519 || caCert.SignatureAlgorithm != x509.PureEd25519 {
+		t.Fatalf("bad: key: %s, signature: %s", caCert.PublicKeyAlgorithm, caCert.SignatureAlgorithm)
+	}
+	if err := caCert.CheckSignatureFrom(caCert); err != nil {
+		t.Fatal(err)
+	}
+
+	doReq(logical.UpdateOperation, "roles/test", map[string]interface{}{
+		"allowed_domains":  "example.com",
+		"allow_subdomains": true,
+		"key_type":         "ed25519",
+		"ttl":              "1h",
+	})
+
+	// Issued keys are Ed25519, in both formats
+	var serial string
+	for _, format := range []string{"der", "pkcs8"} {
+		resp = doReq(logical.UpdateOperation, "issue/test", map[string]interface{}{
+			"common_name":        "test.example.com",
+			"private_key_format": format,
+		})
+		cert := parseCert(resp)
+		if cert.PublicKeyAlgorithm != x509.Ed25519 {
+			t.Fatalf("bad: %s", cert.PublicKeyAlgorithm)
+		}
+		if err := cert.CheckSignatureFrom(caCert); err != nil {
+			t.Fatal(err)
+		}
+		bundle, err := certutil.ParsePEMBundle(resp.Data["private_key"].(string))
+		if format == "der" {
+			// DER keys are base64-encoded PKCS#8
+			bundle, err = (&certutil.CertBundle{PrivateKey: resp.Data["private_key"].(string)}).ToParsedCertBundle()
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bundle.PrivateKeyType != certutil.Ed25519PrivateKey {
+			t.Fatalf("bad: %s", bundle.PrivateKeyType)
+		}
+		serial = resp.Data["serial_number"].(string)
+	}
+
+	// Only Ed25519 CSRs are signed with the role
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp = doReq(logical.UpdateOperation, "sign/test", map[string]interface{}{
+		"common_name": "test.example.com",
+		"csr":         csrPEM(edKey),
+	})
+	if cert := parseCert(resp); cert.PublicKeyAlgorithm != x509.Ed25519 || cert.CheckSignatureFrom(caCert) != nil {
+		t.Fatalf("bad: %#v", cert)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err = request(logical.UpdateOperation, "sign/test", map[string]interface{}{
+		"common_name": "test.example.com",
+		"csr":         csrPEM(ecKey),
+	})
+	if err != nil || resp == nil || !resp.IsError() ||
+		!strings.Contains(resp.Data["error"].(string), "role requires keys of type ed25519") {
+		t.Fatalf("expected the EC CSR to be rejected: err: %v, resp: %#v", err, resp)
+	}
+
+	// The CRL is signed with the Ed25519 CA key
+	doReq(logical.UpdateOperation, "revoke", map[string]interface{}{"serial_number": serial})
+	resp = doReq(logical.ReadOperation, "crl", nil)
+	crl, err := x509.ParseRevocationList(resp.Data[logical.HTTPRawBody].([]byte))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(crl.RevokedCertificateEntries) != 1 {
+		t.Fatalf("bad: %#v", crl.RevokedCertificateEntries)
+	}
+	if err := crl.CheckSignatureFrom(caCert); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPki_CertMetadata(t *testing.T) {
+	b, storage := createBackendWithStorage(t)
+
+	request := func(op logical.Operation, path string, data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Operation: op,
+			Path:      path,
+			Storage:   storage,
+			Data:      data,
+		})
+	}
+	doReq := func(op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		resp, err := request(op, path, data)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: path: %s, err: %v, resp: %#v", path, err, resp)
+		}
+		return resp
+	}
+
+	doReq(logical.UpdateOperation, "root/generate/internal", map[string]interface{}{
+		"common_name": "Root CA",
+		"ttl":         "8760h",
+	})
+	doReq(logical.UpdateOperation, "roles/test", map[string]interface{}{
+		"allowed_domains":  "example.com",
+		"allow_subdomains": true,
+	})
+	doReq(logical.UpdateOperation, "roles/nostore", map[string]interface{}{
+		"allowed_domains":  "example.com",
+		"allow_subdomains": true,
+		"no_store":         true,
+	})
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: "csr.example.com"},
+	}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	csrPEM := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csr}))
+
+	// Metadata is accepted as a map or as key=value pairs
+	issued := doReq(logical.UpdateOperation, "issue/test", map[string]interface{}{
+		"common_name": "test.example.com",
+		"metadata": map[string]interface{}{
+			"requester": "alice",
+			"ticket":    "OPS-1234",
+		},
+	}).Data["serial_number"].(string)
+	signed := doReq(logical.UpdateOperation, "sign/test", map[string]interface{}{
+		"csr":      csrPEM,
+		"metadata": []interface{}{"workload=billing"},
+	}).Data["serial_number"].(string)
+	plain := doReq(logical.UpdateOperation, "issue/test", map[string]interface{}{
+		"common_name": "plain.example.com",
+	}).Data["serial_number"].(string)
+
+	for serial, expected := range map[string]map[string]string{
+		issued: {"requester": "alice", "ticket": "OPS-1234"},
+		signed: {"workload": "billing"},
+	} {
+		resp := doReq(logical.ReadOperation, "cert/"+serial, nil)
+		if !reflect.DeepEqual(resp.Data["metadata"], expected) {
+			t.Fatalf("bad: serial: %s, metadata: %#v", serial, resp.Data["metadata"])
+		}
+	}
+	if resp := doReq(logical.ReadOperation, "cert/"+plain, nil); resp.Data["metadata"] != nil {
+		t.Fatalf("bad: %#v", resp.Data["metadata"])
+	}
+
+	resp := doReq(logical.UpdateOperation, "revoke", map[string]interface{}{"serial_number": issued})
+	if !reflect.DeepEqual(resp.Data["metadata"], map[string]string{"requester": "alice", "ticket": "OPS-1234"}) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Metadata cannot be stored without the certificate
+	if resp, err := request(logical.UpdateOperation, "issue/nostore", map[string]interface{}{
+		"common_name": "test.example.com",
+		"metadata":    map[string]interface{}{"requester": "alice"},
+	}); err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error: err: %v, resp: %#v", err, resp)
+	}
+	if resp, err := request(logical.UpdateOperation, "issue/test", map[string]interface{}{
+		"common_name": "test.example.com",
+		"metadata":    []interface{}{"bad"},
+	}); err == nil {
+		t.Fatalf("expected an error: resp: %#v", resp)
+	}
+}
+
+func TestPki_FIPSMode(t *testing.T) {
+	defer fips.SetEnabled(false)
+
+	request := func(b *backend, storage logical.Storage, path string, data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Storage:   storage,
+			Data:      data,
+		})
+	}
+	isRejected := func(resp *logical.Response, err error, reason string) bool {
+		return (err != nil || (resp != nil && resp.IsError())) &&
+			strings.Contains(fmt.Sprintf("%v %v", err, resp), reason)
+	}
+
+	// The issuers are generated before the FIPS mode is enabled
+	b, storage := createBackendWithStorage(t)
+	edB, edStorage := createBackendWithStorage(t)
+	for _, mount := range []struct {
+		b       *backend
+		storage logical.Storage
+		keyType string
+	}{
+		{b, storage, "rsa"},
+		{edB, edStorage, "ed25519"},
+	} {
+		resp, err := request(mount.b, mount.storage, "root/generate/internal", map[string]interface{}{
+			"common_name": "Root CA",
+			"key_type":    mount.keyType,
+			"ttl":         "8760h",
+		})
+		if err != nil || resp.IsError() {
+			t.Fatalf("bad: err: %v, resp: %#v", err, resp)
+		}
+		resp, err = request(mount.b, mount.storage, "roles/test", map[string]interface{}{
+			"allowed_domains":  "example.com",
+			"allow_subdomains": true,
+			"ttl":              "1h",
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: err: %v, resp: %#v", err, resp)
+		}
+	}
+
+	fips.SetEnabled(true)
+
+	resp, err := request(b, storage, "roles/ed25519", map[string]interface{}{
+		"allowed_domains": "example.com",
+		"key_type":        "ed25519",
+	})
+	if !isRejected(resp, err, "is not FIPS approved") {
+		t.Fatalf("expected the Ed25519 role to be rejected, got err: %v, resp: %#v", err, resp)
+	}
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: "test.example.com"},
+	}, edKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err = request(b, storage, "sign-verbatim", map[string]interface{}{
+		"csr": string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csr})),
+	})
+	if !isRejected(resp, err, "CSR's key cannot be signed") {
+		t.Fatalf("expected the Ed25519 CSR to be rejected, got err: %v, resp: %#v", err, resp)
+	}
+
+	resp, err = request(b, storage, "issue/test", map[string]interface{}{
+		"common_name": "test.example.com",
+	})
+	if err != nil || resp.IsError() {
+		t.Fatalf("bad: err: %v, resp: %#v", err, resp)
+	}
+
+	resp, err = request(edB, edStorage, "issue/test", map[string]interface{}{
+		"common_name": "test.example.com",
+	})
+	if !isRejected(resp, err, "cannot be used for signing") {
+		t.Fatalf("expected the Ed25519 issuer to be rejected, got err: %v, resp: %#v", err, resp)
+	}
+}
@@ -2,16 +2,28 @@ package pki
 
 import (
 	"context"
-	"crypto/rand"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/asn1"
 	"fmt"
+	"math/big"
 	"time"
 
+	"github.com/hashicorp/vault/helper/certutil"
 	"github.com/hashicorp/vault/helper/errutil"
 	"github.com/hashicorp/vault/logical"
 )
 
+var (
+	oidExtensionAuthorityKeyID    = asn1.ObjectIdentifier{2, 5, 29, 35}
+	oidExtensionCRLNumber         = asn1.ObjectIdentifier{2, 5, 29, 20}
+	oidExtensionDeltaCRLIndicator = asn1.ObjectIdentifier{2, 5, 29, 27}
+)
+
+type authorityKeyID struct {
+	ID []byte `asn1:"optional,tag:0"`
+}
+
 type revocationInfo struct {
 	CertificateBytes  []byte    `json:"certificate_bytes"`
 	RevocationTime    int64     `json:"revocation_time"`
@@ -99,12 +111,33 @@ func revokeCert(ctx context.Context, b *backend, req *logical.Request, serial st
 
 	}
 
-	crlErr := buildCRL(ctx, b, req)
-	switch crlErr.(type) {
-	case errutil.UserError:
-		return logical.ErrorResponse(fmt.Sprintf("Error during CRL building: %s", crlErr)), nil
-	case errutil.InternalError:
-		return nil, fmt.Errorf("Error encountered during CRL building: %s", crlErr)
+	crlInfo, err := b.CRL(ctx, req.Storage)
+	if err != nil {
+		return nil, fmt.Errorf("Error fetching CRL config information: %s", err)
+	}
+
+	switch {
+	// The complete CRLs are rebuilt on schedule, so the revocation is only
+	// recorded for the delta CRLs
+	case crlInfo != nil && crlInfo.AutoRebuild:
+		if !alreadyRevoked {
+			err = req.Storage.Put(ctx, &logical.StorageEntry{
+				Key:   "delta_wal/" + normalizeSerial(serial),
+				Value: []byte(serial),
+			})
+			if err != nil {
+				return nil, fmt.Errorf("Error saving revocation for the delta CRL")
+			}
+		}
+
+	default:
+		crlErr := buildCRL(ctx, b, req)
+		switch crlErr.(type) {
+		case errutil.UserError:
+			return logical.ErrorResponse(fmt.Sprintf("Error during CRL building: %s", crlErr)), nil
+		case errutil.InternalError:
+			return nil, fmt.Errorf("Error encountered during CRL building: %s", crlErr)
+		}
 	}
 
 	resp := &logical.Response{
@@ -115,42 +148,203 @@ func revokeCert(ctx context.Context, b *backend, req *logical.Request, serial st
 	if !revInfo.RevocationTimeUTC.IsZero() {
 		resp.Data["revocation_time_rfc3339"] = revInfo.RevocationTimeUTC.Format(time.RFC3339Nano)
 	}
+	metadata, err := fetchCertMetadata(ctx, req.Storage, serial)
+	if err != nil {
+		return nil, fmt.Errorf("Error fetching certificate metadata: %s", err)
+	}
+	if metadata != nil {
+		resp.Data["metadata"] = metadata
+	}
 	return resp, nil
 }
 
-// Builds a CRL by going through the list of revoked certificates and building
-// a new CRL with the stored revocation times and serial numbers.
+// crlState numbers the CRLs of the mount; complete and delta CRLs share the
+// same sequence of numbers
+type crlState struct {
+	// Number is the number of the last CRL built
+	Number int64 `json:"number"`
+
+	// BaseNumber is the number of the last complete CRL, which the delta
+	// CRLs apply to
+	BaseNumber int64 `json:"base_number"`
+
+	LastRebuild      time.Time `json:"last_rebuild"`
+	LastDeltaRebuild time.Time `json:"last_delta_rebuild"`
+}
+
+func getCRLState(ctx context.Context, s logical.Storage) (*crlState, error) {
+	entry, err := s.Get(ctx, "crl_state")
+	if err != nil {
+		return nil, err
+	}
+
+	var state crlState
+	if entry != nil {
+		if err := entry.DecodeJSON(&state); err != nil {
+			return nil, err
+		}
+	}
+	return &state, nil
+}
+
+func putCRLState(ctx context.Context, s logical.Storage, state *crlState) error {
+	entry, err := logical.StorageEntryJSON("crl_state", state)
+	if err != nil {
+		return err
+	}
+	return s.Put(ctx, entry)
+}
+
+// Builds the CRLs by going through the list of revoked certificates and
+// building new CRLs with the stored revocation times and serial numbers.
+// Each issuer holding a key gets a CRL of the certificates it issued; the
+// CRL of the mount, signed by the default issuer, lists all the revoked
+// certificates. If enabled, the delta CRLs are rebuilt along with them.
 func buildCRL(ctx context.Context, b *backend, req *logical.Request) error {
+	b.crlLock.Lock()
+	defer b.crlLock.Unlock()
+
+	return buildCompleteCRLs(ctx, b, req)
+}
+
+// buildDeltaCRL builds the delta CRLs, which list the certificates revoked
+// since the complete CRLs were last built
+func buildDeltaCRL(ctx context.Context, b *backend, req *logical.Request) error {
+	b.crlLock.Lock()
+	defer b.crlLock.Unlock()
+
+	state, err := getCRLState(ctx, req.Storage)
+	if err != nil {
+		return errutil.InternalError{Err: fmt.Sprintf("Error fetching CRL state: %s", err)}
+	}
+
+	// Delta CRLs need a numbered complete CRL to apply to
+	if state.BaseNumber == 0 {
+		return buildCompleteCRLs(ctx, b, req)
+	}
+
+	return buildDeltaCRLs(ctx, b, req, state)
+}
+
+// buildCompleteCRLs builds the complete CRLs. The caller must hold the CRL
+// lock.
+func buildCompleteCRLs(ctx context.Context, b *backend, req *logical.Request) error {
+	// Revocations recorded for the delta CRLs before listing the revoked
+	// certificates are all in the complete CRLs
+	deltaSerials, err := req.Storage.List(ctx, "delta_wal/")
+	if err != nil {
+		return errutil.InternalError{Err: fmt.Sprintf("Error fetching list of revocations since the last CRL: %s", err)}
+	}
+
 	revokedSerials, err := req.Storage.List(ctx, "revoked/")
 	if err != nil {
 		return errutil.InternalError{Err: fmt.Sprintf("Error fetching list of revoked certs: %s", err)}
 	}
+	revokedCerts, parsedCerts, err := fetchRevokedCerts(ctx, req.Storage, revokedSerials, false)
+	if err != nil {
+		return err
+	}
+
+	state, err := getCRLState(ctx, req.Storage)
+	if err != nil {
+		return errutil.InternalError{Err: fmt.Sprintf("Error fetching CRL state: %s", err)}
+	}
+	state.Number++
+	state.BaseNumber = state.Number
+	state.LastRebuild = time.Now()
+	if err := putCRLState(ctx, req.Storage, state); err != nil {
+		return errutil.InternalError{Err: fmt.Sprintf("Error storing CRL state: %s", err)}
+	}
+
+	if err := writeCRLs(ctx, b, req, revokedCerts, parsedCerts, state, false); err != nil {
+		return err
+	}
+
+	for _, serial := range deltaSerials {
+		if err := req.Storage.Delete(ctx, "delta_wal/"+serial); err != nil {
+			return errutil.InternalError{Err: fmt.Sprintf("Error deleting revocation of serial %s since the last CRL: %s", serial, err)}
+		}
+	}
+
+	crlInfo, err := b.CRL(ctx, req.Storage)
+	if err != nil {
+		return errutil.InternalError{Err: fmt.Sprintf("Error fetching CRL config information: %s", err)}
+	}
+	if crlInfo != nil && crlInfo.EnableDelta {
+		return buildDeltaCRLs(ctx, b, req, state)
+	}
+
+	return nil
+}
+
+// buildDeltaCRLs builds the delta CRLs from the revocations recorded since
+// the last complete CRLs. The caller must hold the CRL lock.
+func buildDeltaCRLs(ctx context.Context, b *backend, req *logical.Request, state *crlState) error {
+	deltaSerials, err := req.Storage.List(ctx, "delta_wal/")
+	if err != nil {
+		return errutil.InternalError{Err: fmt.Sprintf("Error fetching list of revocations since the last CRL: %s", err)}
+	}
+	// Certificates tidied since they were revoked are skipped
+	revokedCerts, parsedCerts, err := fetchRevokedCerts(ctx, req.Storage, deltaSerials, true)
+	if err != nil {
+		return err
+	}
+
+	state.Number++
+	state.LastDeltaRebuild = time.Now()
+	if err := putCRLState(ctx, req.Storage, state); err != nil {
+		return errutil.InternalError{Err: fmt.Sprintf("Error storing CRL state: %s", err)}
+	}
+
+	return writeCRLs(ctx, b, req, revokedCerts, parsedCerts, state, true)
+}
+
+// deleteDeltaCRLs removes the delta CRLs of the issuers and of the mount
+func deleteDeltaCRLs(ctx context.Context, s logical.Storage) error {
+	ids, err := s.List(ctx, "delta_crls/")
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := s.Delete(ctx, "delta_crls/"+id); err != nil {
+			return err
+		}
+	}
+	return s.Delete(ctx, "delta_crl")
+}
 
+// fetchRevokedCerts returns the CRL entries of the revoked certificates with
+// the given serials, along with the certificates
+func fetchRevokedCerts(ctx context.Context, s logical.Storage, serials []string, skipMissing bool) ([]pkix.RevokedCertificate, []*x509.Certificate, error) {
 	revokedCerts := []pkix.RevokedCertificate{}
+	var parsedCerts []*x509.Certificate
 	var revInfo revocationInfo
-	for _, serial := range revokedSerials {
-		revokedEntry, err := req.Storage.Get(ctx, "revoked/"+serial)
+	for _, serial := range serials {
+		revokedEntry, err := s.Get(ctx, "revoked/"+serial)
 		if err != nil {
-			return errutil.InternalError{Err: fmt.Sprintf("Unable to fetch revoked cert with serial %s: %s", serial, err)}
+			return nil, nil, errutil.InternalError{Err: fmt.Sprintf("Unable to fetch revoked cert with serial %s: %s", serial, err)}
 		}
 		if revokedEntry == nil {
-			return errutil.InternalError{Err: fmt.Sprintf("Revoked certificate entry for serial %s is nil", serial)}
+			if skipMissing {
+				continue
+			}
+			return nil, nil, errutil.InternalError{Err: fmt.Sprintf("Revoked certificate entry for serial %s is nil", serial)}
 		}
 		if revokedEntry.Value == nil || len(revokedEntry.Value) == 0 {
 			// TODO: In this case, remove it and continue? How likely is this to
 			// happen? Alternately, could skip it entirely, or could implement a
 			// delete function so that there is a way to remove these
-			return errutil.InternalError{Err: fmt.Sprintf("Found revoked serial but actual certificate is empty")}
+			return nil, nil, errutil.InternalError{Err: fmt.Sprintf("Found revoked serial but actual certificate is empty")}
 		}
 
 		err = revokedEntry.DecodeJSON(&revInfo)
 		if err != nil {
-			return errutil.InternalError{Err: fmt.Sprintf("Error decoding revocation entry for serial %s: %s", serial, err)}
+			return nil, nil, errutil.InternalError{Err: fmt.Sprintf("Error decoding revocation entry for serial %s: %s", serial, err)}
 		}
 
 		revokedCert, err := x509.ParseCertificate(revInfo.CertificateBytes)
 		if err != nil {
-			return errutil.InternalError{Err: fmt.Sprintf("Unable to parse stored revoked certificate with serial %s: %s", serial, err)}
+			return nil, nil, errutil.InternalError{Err: fmt.Sprintf("Unable to parse stored revoked certificate with serial %s: %s", serial, err)}
 		}
 
 		// NOTE: We have to change this to UTC time because the CRL standard
@@ -164,36 +358,76 @@ func buildCRL(ctx context.Context, b *backend, req *logical.Request) error {
 			newRevCert.RevocationTime = time.Unix(revInfo.RevocationTime, 0).UTC()
 		}
 		revokedCerts = append(revokedCerts, newRevCert)
+		parsedCerts = append(parsedCerts, revokedCert)
 	}
 
-	signingBundle, caErr := fetchCAInfo(ctx, req)
-	switch caErr.(type) {
-	case errutil.UserError:
-		return errutil.UserError{Err: fmt.Sprintf("Could not fetch the CA certificate: %s", caErr)}
-	case errutil.InternalError:
-		return errutil.InternalError{Err: fmt.Sprintf("Error fetching CA certificate: %s", caErr)}
+	return revokedCerts, parsedCerts, nil
+}
+
+// writeCRLs signs and stores the CRLs of the issuers and of the mount, with
+// the number of the state
+func writeCRLs(ctx context.Context, b *backend, req *logical.Request, revokedCerts []pkix.RevokedCertificate, parsedCerts []*x509.Certificate, state *crlState, delta bool) error {
+	crlLifetime, err := b.configuredCRLLifetime(ctx, req.Storage)
+	if err != nil {
+		return errutil.InternalError{Err: err.Error()}
 	}
 
-	crlLifetime := b.crlLifetime
-	crlInfo, err := b.CRL(ctx, req.Storage)
+	issuerPrefix, mountKey := "crls/", "crl"
+	var baseNumber int64
+	if delta {
+		issuerPrefix, mountKey = "delta_crls/", "delta_crl"
+		baseNumber = state.BaseNumber
+	}
+
+	issuers, err := b.fetchIssuers(ctx, req.Storage)
 	if err != nil {
-		return errutil.InternalError{Err: fmt.Sprintf("Error fetching CRL config information: %s", err)}
+		return errutil.InternalError{Err: fmt.Sprintf("Error fetching issuers: %s", err)}
 	}
-	if crlInfo != nil {
-		crlDur, err := time.ParseDuration(crlInfo.Expiry)
+	for _, issuer := range issuers {
+		if !issuer.hasKey() || issuer.ID == legacyIssuerID {
+			continue
+		}
+		issuerBundle, err := issuer.parse()
+		if err != nil {
+			return err
+		}
+
+		issuedCerts := []pkix.RevokedCertificate{}
+		for i, revokedCert := range parsedCerts {
+			if revokedCert.CheckSignatureFrom(issuerBundle.Certificate) == nil {
+				issuedCerts = append(issuedCerts, revokedCerts[i])
+			}
+		}
+
+		crlBytes, err := createCRL(issuerBundle, issuedCerts, state.Number, baseNumber, crlLifetime)
 		if err != nil {
-			return errutil.InternalError{Err: fmt.Sprintf("Error parsing CRL duration of %s", crlInfo.Expiry)}
+			return errutil.InternalError{Err: fmt.Sprintf("Error creating new CRL for issuer %s: %s", issuer.ID, err)}
+		}
+
+		err = req.Storage.Put(ctx, &logical.StorageEntry{
+			Key:   issuerPrefix + issuer.ID,
+			Value: crlBytes,
+		})
+		if err != nil {
+			return errutil.InternalError{Err: fmt.Sprintf("Error storing CRL: %s", err)}
 		}
-		crlLifetime = crlDur
 	}
 
-	crlBytes, err := signingBundle.Certificate.CreateCRL(rand.Reader, signingBundle.PrivateKey, revokedCerts, time.Now(), time.Now().Add(crlLifetime))
+	signingBundle, caErr := fetchCAInfo(ctx, b, req, defaultIssuerRef)
+	switch caErr.(type) {
+	case errutil.UserError:
+		return errutil.UserError{Err: fmt.Sprintf("Could not fetch the CA certificate: %s", caErr)}
+	case errutil.InternalError:
+		return errutil.InternalError{Err: fmt.Sprintf("Error fetching CA certificate: %s", caErr)}
+	}
+
+	crlBytes, err := createCRL(&signingBundle.ParsedCertBundle, revokedCerts, state.Number, baseNumber, crlLifetime)
 	if err != nil {
 		return errutil.InternalError{Err: fmt.Sprintf("Error creating new CRL: %s", err)}
 	}
 
 	err = req.Storage.Put(ctx, &logical.StorageEntry{
-		Key:   "crl",
+		Key:   mountKey,
 		Value: crlBytes,
 	})
 	if err != nil {
@@ -202,3 +436,123 @@ func buildCRL(ctx context.Context, b *backend, req *logical.Request) error {
 
 	return nil
 }
+
+// createCRL signs a CRL with the given number. Delta CRLs also reference the
+// number of the complete CRL they apply to, which is zero for complete CRLs.
+func createCRL(bundle *certutil.ParsedCertBundle, revokedCerts []pkix.RevokedCertificate, number, baseNumber int64, lifetime time.Duration) ([]byte, error) {
+	numberBytes, err := asn1.Marshal(big.NewInt(number))
+	if err != nil {
+		return nil, err
+	}
+	extensions := []pkix.Extension{{Id: oidExtensionCRLNumber, Value: numberBytes}}
+	if baseNumber != 0 {
+		baseNumberBytes, err := asn1.Marshal(big.NewInt(baseNumber))
+		if err != nil {
+			return nil, err
+		}
+		extensions = append(extensions, pkix.Extension{Id: oidExtensionDeltaCRLIndicator, Critical: true, Value: baseNumberBytes})
+	}
+	if len(bundle.Certificate.SubjectKeyId) != 0 {
+		keyIDBytes, err := asn1.Marshal(authorityKeyID{ID: bundle.Certificate.SubjectKeyId})
+		if err != nil {
+			return nil, err
+		}
+		extensions = append(extensions, pkix.Extension{Id: oidExtensionAuthorityKeyID, Value: keyIDBytes})
+	}
+
+	algorithm, hash, err := certutil.SignatureAlgorithm(bundle.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	tbs := pkix.TBSCertificateList{
+		Version:             1,
+		Signature:           algorithm,
+		Issuer:              bundle.Certificate.Subject.ToRDNSequence(),
+		ThisUpdate:          now,
+		NextUpdate:          now.Add(lifetime),
+		RevokedCertificates: revokedCerts,
+		Extensions:          extensions,
+	}
+	tbs.Raw, err = asn1.Marshal(tbs)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := certutil.SignData(bundle.PrivateKey, hash, tbs.Raw)
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(pkix.CertificateList{
+		TBSCertList:        tbs,
+		SignatureAlgorithm: algorithm,
+		SignatureValue: asn1.BitString{
+			Bytes:     signature,
+			BitLength: 8 * len(signature),
+		},
+	})
+}
+
+// autoRebuildCRLs rebuilds the CRLs on schedule if automatic rebuilds are
+// enabled
+func (b *backend) autoRebuildCRLs(ctx context.Context, req *logical.Request) error {
+	crlInfo, err := b.CRL(ctx, req.Storage)
+	if err != nil {
+		return err
+	}
+	if crlInfo == nil || !crlInfo.AutoRebuild {
+		return nil
+	}
+
+	rebuildInterval, err := time.ParseDuration(crlInfo.RebuildInterval)
+	if err != nil {
+		return fmt.Errorf("Error parsing CRL rebuild interval of %s", crlInfo.RebuildInterval)
+	}
+	deltaRebuildInterval, err := time.ParseDuration(crlInfo.DeltaRebuildInterval)
+	if err != nil {
+		return fmt.Errorf("Error parsing delta CRL rebuild interval of %s", crlInfo.DeltaRebuildInterval)
+	}
+
+	state, err := getCRLState(ctx, req.Storage)
+	if err != nil {
+		return err
+	}
+
+	b.revokeStorageLock.RLock()
+	defer b.revokeStorageLock.RUnlock()
+
+	var crlErr error
+	now := time.Now()
+	switch {
+	case !now.Before(state.LastRebuild.Add(rebuildInterval)):
+		crlErr = buildCRL(ctx, b, req)
+	case crlInfo.EnableDelta && !now.Before(state.LastDeltaRebuild.Add(deltaRebuildInterval)):
+		crlErr = buildDeltaCRL(ctx, b, req)
+	}
+
+	// Mounts without a CA have no CRL to build
+	if _, ok := crlErr.(errutil.UserError); ok {
+		return nil
+	}
+	return crlErr
+}
+
+// configuredCRLLifetime returns the duration for which CRLs, and OCSP
+// responses, are marked valid
+func (b *backend) configuredCRLLifetime(ctx context.Context, s logical.Storage) (time.Duration, error) {
+	crlInfo, err := b.CRL(ctx, s)
+	if err != nil {
+		return 0, fmt.Errorf("Error fetching CRL config information: %s", err)
+	}
+	if crlInfo == nil {
+		return b.crlLifetime, nil
+	}
+
+	crlDur, err := time.ParseDuration(crlInfo.Expiry)
+	if err != nil {
+		return 0, fmt.Errorf("Error parsing CRL duration of %s", crlInfo.Expiry)
+	}
+	return crlDur, nil
+}
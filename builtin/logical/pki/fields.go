@@ -78,6 +78,14 @@ default TTL is used, in that order. Cannot
 be later than the role max TTL.`,
 	}
 
+	fields["metadata"] = &framework.FieldSchema{
+		Type: framework.TypeKVPairs,
+		Description: `Metadata to store with the certificate, such as
+the requester or a ticket ID. It is returned
+when reading or revoking the certificate. Not
+allowed if the role has no_store set.`,
+	}
+
 	return fields
 }
 
@@ -139,8 +147,8 @@ the key_type.`,
 	fields["key_type"] = &framework.FieldSchema{
 		Type:    framework.TypeString,
 		Default: "rsa",
-		Description: `The type of key to use; defaults to RSA. "rsa"
-and "ec" are the only valid values.`,
+		Description: `The type of key to use; defaults to RSA. "rsa",
+"ec" and "ed25519" are the only valid values.`,
 	}
 
 	return fields
@@ -162,3 +170,16 @@ func addCAIssueFields(fields map[string]*framework.FieldSchema) map[string]*fram
 
 	return fields
 }
+
+// addIssuerRefField adds the field selecting the issuer to sign with
+func addIssuerRefField(fields map[string]*framework.FieldSchema) map[string]*framework.FieldSchema {
+	fields["issuer_ref"] = &framework.FieldSchema{
+		Type:    framework.TypeString,
+		Default: defaultIssuerRef,
+		Description: `ID or name of the issuer signing the
+certificate. Defaults to the default issuer
+of the mount.`,
+	}
+
+	return fields
+}
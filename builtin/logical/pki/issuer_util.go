@@ -0,0 +1,539 @@
+package pki
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/helper/certutil"
+	"github.com/hashicorp/vault/helper/consts"
+	"github.com/hashicorp/vault/helper/errutil"
+	"github.com/hashicorp/vault/logical"
+)
+
+// defaultIssuerRef refers to the default issuer of the mount, used by the
+// paths and roles which do not select one
+const defaultIssuerRef = "default"
+
+// legacyIssuerID identifies the CA bundle of mounts that have not been
+// migrated to multiple issuers yet, which happens on performance secondaries
+// until the primary has migrated it
+const legacyIssuerID = "legacy"
+
+var issuerNameRegex = regexp.MustCompile(`^[\w-]+$`)
+
+// issuerEntry is a CA certificate of the mount, along with the private key
+// used to sign with it, if the mount holds it
+type issuerEntry struct {
+	ID     string               `json:"id"`
+	Name   string               `json:"name"`
+	Bundle *certutil.CertBundle `json:"bundle"`
+}
+
+type issuersConfig struct {
+	Default string `json:"default"`
+}
+
+func (i *issuerEntry) hasKey() bool {
+	return len(i.Bundle.PrivateKey) != 0
+}
+
+func (i *issuerEntry) parse() (*certutil.ParsedCertBundle, error) {
+	parsedBundle, err := i.Bundle.ToParsedCertBundle()
+	if err != nil {
+		return nil, errutil.InternalError{Err: fmt.Sprintf("unable to parse issuer %s: %v", i.ID, err)}
+	}
+	if parsedBundle.Certificate == nil {
+		return nil, errutil.InternalError{Err: "stored CA information not able to be parsed"}
+	}
+	return parsedBundle, nil
+}
+
+func getIssuer(ctx context.Context, s logical.Storage, id string) (*issuerEntry, error) {
+	entry, err := s.Get(ctx, "issuers/"+id)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var issuer issuerEntry
+	if err := entry.DecodeJSON(&issuer); err != nil {
+		return nil, err
+	}
+	return &issuer, nil
+}
+
+func putIssuer(ctx context.Context, s logical.Storage, issuer *issuerEntry) error {
+	entry, err := logical.StorageEntryJSON("issuers/"+issuer.ID, issuer)
+	if err != nil {
+		return err
+	}
+	return s.Put(ctx, entry)
+}
+
+func listIssuers(ctx context.Context, s logical.Storage) ([]*issuerEntry, error) {
+	ids, err := s.List(ctx, "issuers/")
+	if err != nil {
+		return nil, err
+	}
+
+	var issuers []*issuerEntry
+	for _, id := range ids {
+		issuer, err := getIssuer(ctx, s, id)
+		if err != nil {
+			return nil, err
+		}
+		if issuer != nil {
+			issuers = append(issuers, issuer)
+		}
+	}
+	return issuers, nil
+}
+
+// fetchIssuers returns all the issuers of the mount, including the legacy
+// CA bundle which could not be migrated
+func (b *backend) fetchIssuers(ctx context.Context, s logical.Storage) ([]*issuerEntry, error) {
+	legacy, err := b.migrateLegacyCA(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	issuers, err := listIssuers(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	if legacy != nil {
+		issuers = append(issuers, legacy)
+	}
+	return issuers, nil
+}
+
+func getIssuersConfig(ctx context.Context, s logical.Storage) (*issuersConfig, error) {
+	entry, err := s.Get(ctx, "config/issuers")
+	if err != nil {
+		return nil, err
+	}
+
+	var config issuersConfig
+	if entry != nil {
+		if err := entry.DecodeJSON(&config); err != nil {
+			return nil, err
+		}
+	}
+	return &config, nil
+}
+
+// setDefaultIssuer makes the issuer the default of the mount, or leaves the
+// mount without a default if it is nil
+func setDefaultIssuer(ctx context.Context, s logical.Storage, issuer *issuerEntry) error {
+	config := &issuersConfig{}
+	if issuer != nil {
+		config.Default = issuer.ID
+	}
+	entry, err := logical.StorageEntryJSON("config/issuers", config)
+	if err != nil {
+		return err
+	}
+	if err := s.Put(ctx, entry); err != nil {
+		return err
+	}
+
+	if issuer == nil {
+		if err := s.Delete(ctx, "ca"); err != nil {
+			return err
+		}
+		if err := s.Delete(ctx, "delta_crl"); err != nil {
+			return err
+		}
+		return s.Delete(ctx, "crl")
+	}
+
+	// Issuers without a key cannot sign the CRLs of the mount
+	if !issuer.hasKey() {
+		if err := s.Delete(ctx, "crl"); err != nil {
+			return err
+		}
+		if err := s.Delete(ctx, "delta_crl"); err != nil {
+			return err
+		}
+	}
+
+	// For ease of later use, also store just the certificate of the default
+	// issuer at a known location
+	parsedBundle, err := issuer.parse()
+	if err != nil {
+		return err
+	}
+	return s.Put(ctx, &logical.StorageEntry{
+		Key:   "ca",
+		Value: parsedBundle.CertificateBytes,
+	})
+}
+
+// resolveIssuer returns the issuer with the given ID or name, or the default
+// issuer for "default"
+func (b *backend) resolveIssuer(ctx context.Context, s logical.Storage, ref string) (*issuerEntry, error) {
+	legacy, err := b.migrateLegacyCA(ctx, s)
+	if err != nil {
+		return nil, errutil.InternalError{Err: fmt.Sprintf("unable to migrate the CA certificate/key: %v", err)}
+	}
+
+	if ref == "" || ref == defaultIssuerRef {
+		if legacy != nil {
+			return legacy, nil
+		}
+		config, err := getIssuersConfig(ctx, s)
+		if err != nil {
+			return nil, errutil.InternalError{Err: fmt.Sprintf("unable to fetch the issuers configuration: %v", err)}
+		}
+		if config.Default == "" {
+			return nil, errutil.UserError{Err: "backend must be configured with a CA certificate/key"}
+		}
+		ref = config.Default
+	}
+	if legacy != nil && ref == legacyIssuerID {
+		return legacy, nil
+	}
+
+	if !strings.Contains(ref, "/") {
+		issuer, err := getIssuer(ctx, s, ref)
+		if err != nil {
+			return nil, errutil.InternalError{Err: fmt.Sprintf("unable to fetch issuer %s: %v", ref, err)}
+		}
+		if issuer != nil {
+			return issuer, nil
+		}
+	}
+
+	issuers, err := listIssuers(ctx, s)
+	if err != nil {
+		return nil, errutil.InternalError{Err: fmt.Sprintf("unable to fetch the issuers: %v", err)}
+	}
+	for _, issuer := range issuers {
+		if issuer.Name == ref {
+			return issuer, nil
+		}
+	}
+
+	return nil, errutil.UserError{Err: fmt.Sprintf("unknown issuer %q", ref)}
+}
+
+// validateIssuerName checks that a name can be given to an issuer: names
+// are unique, and cannot be mistaken for the default issuer
+func validateIssuerName(issuers []*issuerEntry, name, id string) error {
+	if !issuerNameRegex.MatchString(name) || name == defaultIssuerRef || name == legacyIssuerID {
+		return errutil.UserError{Err: fmt.Sprintf("invalid issuer name %q", name)}
+	}
+	for _, issuer := range issuers {
+		if issuer.ID != id && (issuer.Name == name || issuer.ID == name) {
+			return errutil.UserError{Err: fmt.Sprintf("issuer name %q is already in use", name)}
+		}
+	}
+	return nil
+}
+
+// lockIssuers takes the lock serializing changes to the issuers, after
+// migrating the legacy CA bundle which requires it
+func (b *backend) lockIssuers(ctx context.Context, s logical.Storage) error {
+	if _, err := b.migrateLegacyCA(ctx, s); err != nil {
+		return errutil.InternalError{Err: fmt.Sprintf("unable to migrate the CA certificate/key: %v", err)}
+	}
+	b.issuersLock.Lock()
+	return nil
+}
+
+// importCAIssuer imports a CA certificate as an issuer, which becomes the
+// default issuer if requested or if the mount has none
+func (b *backend) importCAIssuer(ctx context.Context, s logical.Storage, parsedBundle *certutil.ParsedCertBundle, name string, makeDefault, requireKey bool) (*issuerEntry, bool, error) {
+	if err := b.lockIssuers(ctx, s); err != nil {
+		return nil, false, err
+	}
+	defer b.issuersLock.Unlock()
+
+	issuer, existing, err := importIssuer(ctx, s, parsedBundle, name, requireKey)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if !makeDefault {
+		config, err := getIssuersConfig(ctx, s)
+		if err != nil {
+			return nil, false, err
+		}
+		makeDefault = config.Default == ""
+	}
+	if makeDefault {
+		if err := setDefaultIssuer(ctx, s, issuer); err != nil {
+			return nil, false, err
+		}
+		b.sendCARotatedEvent(ctx, issuer)
+	}
+
+	return issuer, existing, nil
+}
+
+// sendCARotatedEvent notifies the subscribers that the issuer became the
+// default issuer of the mount, which serves its certificate at the ca path
+func (b *backend) sendCARotatedEvent(ctx context.Context, issuer *issuerEntry) {
+	if err := b.System().SendEvent(ctx, logical.EventTypePKICARotated, "ca", map[string]string{
+		"issuer_id":     issuer.ID,
+		"issuer_name":   issuer.Name,
+		"serial_number": issuer.Bundle.SerialNumber,
+	}); err != nil {
+		b.Logger().Warn("pki: failed to publish event", "event_type", logical.EventTypePKICARotated, "error", err)
+	}
+}
+
+// importIssuer stores a CA certificate as an issuer, along with its private
+// key if given. Certificates that were already imported are returned as is,
+// with the key and chain added if they lacked them. Without a key, the key
+// of a pending intermediate CA, or of another issuer, matching the
+// certificate is used. The caller must hold the issuers lock.
+func importIssuer(ctx context.Context, s logical.Storage, parsedBundle *certutil.ParsedCertBundle, name string, requireKey bool) (*issuerEntry, bool, error) {
+	if parsedBundle.Certificate == nil {
+		return nil, false, errutil.UserError{Err: "no certificate found in the PEM bundle"}
+	}
+	if !parsedBundle.Certificate.IsCA {
+		return nil, false, errutil.UserError{Err: "the given certificate is not marked for CA use and cannot be used with this backend"}
+	}
+	if parsedBundle.PrivateKey != nil {
+		match, err := certutil.ComparePublicKeys(parsedBundle.Certificate.PublicKey, parsedBundle.PrivateKey.Public())
+		if err != nil || !match {
+			return nil, false, errutil.UserError{Err: "the private key does not match the certificate"}
+		}
+	}
+
+	issuers, err := listIssuers(ctx, s)
+	if err != nil {
+		return nil, false, err
+	}
+	if name != "" {
+		if err := validateIssuerName(issuers, name, ""); err != nil {
+			return nil, false, err
+		}
+	}
+
+	cb, err := parsedBundle.ToCertBundle()
+	if err != nil {
+		return nil, false, fmt.Errorf("error converting raw values into cert bundle: %s", err)
+	}
+
+	var keyOwner *certutil.CertBundle
+	for _, issuer := range issuers {
+		existing, err := issuer.parse()
+		if err != nil {
+			return nil, false, err
+		}
+
+		if bytes.Equal(existing.CertificateBytes, parsedBundle.CertificateBytes) {
+			modified := false
+			if !issuer.hasKey() && cb.PrivateKey != "" {
+				issuer.Bundle.PrivateKey = cb.PrivateKey
+				issuer.Bundle.PrivateKeyType = cb.PrivateKeyType
+				modified = true
+			}
+			if len(issuer.Bundle.CAChain) == 0 && len(cb.CAChain) != 0 {
+				issuer.Bundle.CAChain = cb.CAChain
+				modified = true
+			}
+			if requireKey && !issuer.hasKey() {
+				return nil, false, errutil.UserError{Err: "could not find an existing private key"}
+			}
+			if modified {
+				if err := putIssuer(ctx, s, issuer); err != nil {
+					return nil, false, err
+				}
+			}
+			return issuer, true, nil
+		}
+
+		if keyOwner == nil && issuer.hasKey() && cb.PrivateKey == "" {
+			match, err := certutil.ComparePublicKeys(parsedBundle.Certificate.PublicKey, existing.PrivateKey.Public())
+			if err == nil && match {
+				keyOwner = issuer.Bundle
+			}
+		}
+	}
+
+	var pendingKeyID string
+	if cb.PrivateKey == "" {
+		pendingKeyID, keyOwner, err = findPendingKey(ctx, s, parsedBundle, keyOwner)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+	if keyOwner != nil {
+		cb.PrivateKey = keyOwner.PrivateKey
+		cb.PrivateKeyType = keyOwner.PrivateKeyType
+	}
+	if requireKey && cb.PrivateKey == "" {
+		return nil, false, errutil.UserError{Err: "could not find an existing private key"}
+	}
+
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, false, err
+	}
+	issuer := &issuerEntry{
+		ID:     id,
+		Name:   name,
+		Bundle: cb,
+	}
+	if err := putIssuer(ctx, s, issuer); err != nil {
+		return nil, false, err
+	}
+
+	// The key of the pending intermediate CA now belongs to the issuer
+	if pendingKeyID != "" {
+		if err := s.Delete(ctx, "intermediate/pending/"+pendingKeyID); err != nil {
+			return nil, false, err
+		}
+	}
+
+	return issuer, false, nil
+}
+
+// putPendingKey stores the private key of an intermediate CA until its
+// certificate is set
+func putPendingKey(ctx context.Context, s logical.Storage, cb *certutil.CertBundle) error {
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		return err
+	}
+	entry, err := logical.StorageEntryJSON("intermediate/pending/"+id, &certutil.CertBundle{
+		PrivateKey:     cb.PrivateKey,
+		PrivateKeyType: cb.PrivateKeyType,
+	})
+	if err != nil {
+		return err
+	}
+	return s.Put(ctx, entry)
+}
+
+// findPendingKey returns the pending intermediate CA key matching the
+// certificate, if any, and the fallback key otherwise
+func findPendingKey(ctx context.Context, s logical.Storage, parsedBundle *certutil.ParsedCertBundle, fallback *certutil.CertBundle) (string, *certutil.CertBundle, error) {
+	ids, err := s.List(ctx, "intermediate/pending/")
+	if err != nil {
+		return "", nil, err
+	}
+	for _, id := range ids {
+		entry, err := s.Get(ctx, "intermediate/pending/"+id)
+		if err != nil {
+			return "", nil, err
+		}
+		if entry == nil {
+			continue
+		}
+		var cb certutil.CertBundle
+		if err := entry.DecodeJSON(&cb); err != nil {
+			return "", nil, err
+		}
+		pending, err := cb.ToParsedCertBundle()
+		if err != nil || pending.PrivateKey == nil {
+			continue
+		}
+		match, err := certutil.ComparePublicKeys(parsedBundle.Certificate.PublicKey, pending.PrivateKey.Public())
+		if err == nil && match {
+			return id, &cb, nil
+		}
+	}
+	return "", fallback, nil
+}
+
+// deleteIssuers removes all the issuers of the mount, as well as the keys of
+// pending intermediate CAs. The caller must hold the issuers lock.
+func deleteIssuers(ctx context.Context, s logical.Storage) error {
+	for _, prefix := range []string{"issuers/", "crls/", "delta_crls/", "intermediate/pending/"} {
+		keys, err := s.List(ctx, prefix)
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			if err := s.Delete(ctx, prefix+key); err != nil {
+				return err
+			}
+		}
+	}
+	if err := s.Delete(ctx, "config/ca_bundle"); err != nil {
+		return err
+	}
+	return setDefaultIssuer(ctx, s, nil)
+}
+
+// migrateLegacyCA moves the single CA bundle of mounts created before
+// multiple issuers were supported to an issuer, which becomes the default.
+// Where storage cannot be written, the legacy bundle is returned as is to be
+// used as the default issuer.
+func (b *backend) migrateLegacyCA(ctx context.Context, s logical.Storage) (*issuerEntry, error) {
+	entry, err := s.Get(ctx, "config/ca_bundle")
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var bundle certutil.CertBundle
+	if err := entry.DecodeJSON(&bundle); err != nil {
+		return nil, err
+	}
+
+	// Only perform upgrades on replication primary
+	if !b.System().LocalMount() && b.System().ReplicationState().HasState(consts.ReplicationPerformanceSecondary) {
+		if len(bundle.Certificate) == 0 {
+			return nil, nil
+		}
+		return &issuerEntry{
+			ID:     legacyIssuerID,
+			Bundle: &bundle,
+		}, nil
+	}
+
+	b.issuersLock.Lock()
+	defer b.issuersLock.Unlock()
+
+	// Another request may have migrated it in the meantime
+	entry, err = s.Get(ctx, "config/ca_bundle")
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	switch {
+	// Only the key of an intermediate CA was generated
+	case len(bundle.Certificate) == 0:
+		if len(bundle.PrivateKey) != 0 {
+			if err := putPendingKey(ctx, s, &bundle); err != nil {
+				return nil, err
+			}
+		}
+
+	default:
+		parsedBundle, err := bundle.ToParsedCertBundle()
+		if err != nil {
+			return nil, err
+		}
+		issuer, _, err := importIssuer(ctx, s, parsedBundle, "", false)
+		if err != nil {
+			return nil, err
+		}
+		config, err := getIssuersConfig(ctx, s)
+		if err != nil {
+			return nil, err
+		}
+		if config.Default == "" {
+			if err := setDefaultIssuer(ctx, s, issuer); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return nil, s.Delete(ctx, "config/ca_bundle")
+}
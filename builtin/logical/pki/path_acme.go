@@ -0,0 +1,1161 @@
+package pki
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/helper/certutil"
+	"github.com/hashicorp/vault/helper/errutil"
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+// acmeIDRegex matches the identifiers of ACME resources in paths. Account
+// identifiers are base64url-encoded key thumbprints, which may start or end
+// with a dash.
+func acmeIDRegex(name string) string {
+	return fmt.Sprintf(`(?P<%s>[\w-]+)`, name)
+}
+
+type acmeAccount struct {
+	ID           string    `json:"id"`
+	Status       string    `json:"status"`
+	Contact      []string  `json:"contact"`
+	Key          string    `json:"key"`
+	CreationTime time.Time `json:"creation_time"`
+}
+
+func (a *acmeAccount) jwk() (*jose.JSONWebKey, error) {
+	var key jose.JSONWebKey
+	if err := key.UnmarshalJSON([]byte(a.Key)); err != nil {
+		return nil, fmt.Errorf("failed to parse the key of ACME account %q: %v", a.ID, err)
+	}
+	return &key, nil
+}
+
+type acmeIdentifier struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type acmeChallenge struct {
+	Type      string       `json:"type"`
+	Token     string       `json:"token"`
+	Status    string       `json:"status"`
+	Validated time.Time    `json:"validated"`
+	Error     *acmeProblem `json:"error"`
+}
+
+type acmeAuthorization struct {
+	ID         string           `json:"id"`
+	AccountID  string           `json:"account_id"`
+	Identifier acmeIdentifier   `json:"identifier"`
+	Wildcard   bool             `json:"wildcard"`
+	Status     string           `json:"status"`
+	Expires    time.Time        `json:"expires"`
+	Challenges []*acmeChallenge `json:"challenges"`
+}
+
+type acmeOrder struct {
+	ID               string           `json:"id"`
+	AccountID        string           `json:"account_id"`
+	Status           string           `json:"status"`
+	Expires          time.Time        `json:"expires"`
+	Identifiers      []acmeIdentifier `json:"identifiers"`
+	AuthorizationIDs []string         `json:"authorization_ids"`
+	SerialNumber     string           `json:"serial_number"`
+	Certificate      string           `json:"certificate"`
+	Error            *acmeProblem     `json:"error"`
+}
+
+// acmeCertificate records the account that ordered a certificate, which
+// may revoke it
+type acmeCertificate struct {
+	AccountID string `json:"account_id"`
+	OrderID   string `json:"order_id"`
+}
+
+func pathACMEDirectory(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "acme/directory",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.acmeHandler(acmeAuthNone, b.pathACMEDirectoryRead),
+		},
+
+		HelpSynopsis:    pathACMEDirectoryHelpSyn,
+		HelpDescription: pathACMEHelpDesc,
+	}
+}
+
+func pathACMENewNonce(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "acme/new-nonce",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.acmeHandler(acmeAuthNone, b.pathACMENewNonceRead),
+		},
+
+		HelpSynopsis:    pathACMENewNonceHelpSyn,
+		HelpDescription: pathACMEHelpDesc,
+	}
+}
+
+func pathACMENewAccount(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "acme/new-account",
+		Fields:  acmeFields(map[string]*framework.FieldSchema{}),
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.acmeHandler(acmeAuthJWK, b.pathACMENewAccountWrite),
+		},
+
+		HelpSynopsis:    pathACMENewAccountHelpSyn,
+		HelpDescription: pathACMEHelpDesc,
+	}
+}
+
+func pathACMEAccount(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "acme/account/" + acmeIDRegex("account_id"),
+		Fields: acmeFields(map[string]*framework.FieldSchema{
+			"account_id": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: `Identifier of the account`,
+			},
+		}),
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.acmeHandler(acmeAuthKID, b.pathACMEAccountWrite),
+		},
+
+		HelpSynopsis:    pathACMEAccountHelpSyn,
+		HelpDescription: pathACMEHelpDesc,
+	}
+}
+
+func pathACMEAccountOrders(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "acme/account/" + acmeIDRegex("account_id") + "/orders",
+		Fields: acmeFields(map[string]*framework.FieldSchema{
+			"account_id": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: `Identifier of the account`,
+			},
+		}),
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.acmeHandler(acmeAuthKID, b.pathACMEAccountOrdersRead),
+		},
+
+		HelpSynopsis:    pathACMEAccountOrdersHelpSyn,
+		HelpDescription: pathACMEHelpDesc,
+	}
+}
+
+func pathACMENewOrder(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "acme/new-order",
+		Fields:  acmeFields(map[string]*framework.FieldSchema{}),
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.acmeHandler(acmeAuthKID, b.pathACMENewOrderWrite),
+		},
+
+		HelpSynopsis:    pathACMENewOrderHelpSyn,
+		HelpDescription: pathACMEHelpDesc,
+	}
+}
+
+func acmeOrderFields() map[string]*framework.FieldSchema {
+	return acmeFields(map[string]*framework.FieldSchema{
+		"order_id": &framework.FieldSchema{
+			Type:        framework.TypeString,
+			Description: `Identifier of the order`,
+		},
+	})
+}
+
+func pathACMEOrder(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "acme/order/" + acmeIDRegex("order_id"),
+		Fields:  acmeOrderFields(),
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.acmeHandler(acmeAuthKID, b.pathACMEOrderRead),
+		},
+
+		HelpSynopsis:    pathACMEOrderHelpSyn,
+		HelpDescription: pathACMEHelpDesc,
+	}
+}
+
+func pathACMEOrderFinalize(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "acme/order/" + acmeIDRegex("order_id") + "/finalize",
+		Fields:  acmeOrderFields(),
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.acmeHandler(acmeAuthKID, b.pathACMEOrderFinalizeWrite),
+		},
+
+		HelpSynopsis:    pathACMEOrderFinalizeHelpSyn,
+		HelpDescription: pathACMEHelpDesc,
+	}
+}
+
+func pathACMEOrderCert(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "acme/order/" + acmeIDRegex("order_id") + "/cert",
+		Fields:  acmeOrderFields(),
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.acmeHandler(acmeAuthKID, b.pathACMEOrderCertRead),
+		},
+
+		HelpSynopsis:    pathACMEOrderCertHelpSyn,
+		HelpDescription: pathACMEHelpDesc,
+	}
+}
+
+func pathACMEAuthorization(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "acme/authorization/" + acmeIDRegex("authorization_id"),
+		Fields: acmeFields(map[string]*framework.FieldSchema{
+			"authorization_id": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: `Identifier of the authorization`,
+			},
+		}),
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.acmeHandler(acmeAuthKID, b.pathACMEAuthorizationWrite),
+		},
+
+		HelpSynopsis:    pathACMEAuthorizationHelpSyn,
+		HelpDescription: pathACMEHelpDesc,
+	}
+}
+
+func pathACMEChallenge(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "acme/challenge/" + acmeIDRegex("authorization_id") + "/" + acmeIDRegex("challenge_type"),
+		Fields: acmeFields(map[string]*framework.FieldSchema{
+			"authorization_id": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: `Identifier of the authorization`,
+			},
+			"challenge_type": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: `Type of the challenge`,
+			},
+		}),
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.acmeHandler(acmeAuthKID, b.pathACMEChallengeWrite),
+		},
+
+		HelpSynopsis:    pathACMEChallengeHelpSyn,
+		HelpDescription: pathACMEHelpDesc,
+	}
+}
+
+func pathACMERevokeCert(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "acme/revoke-cert",
+		Fields:  acmeFields(map[string]*framework.FieldSchema{}),
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.acmeHandler(acmeAuthKID, b.pathACMERevokeCertWrite),
+		},
+
+		HelpSynopsis:    pathACMERevokeCertHelpSyn,
+		HelpDescription: pathACMEHelpDesc,
+	}
+}
+
+func (b *backend) pathACMEDirectoryRead(ctx context.Context, req *logical.Request, data *framework.FieldData, r *acmeRequest) (*acmeReply, error) {
+	base := r.config.BaseURL + "/acme/"
+	return &acmeReply{
+		status: http.StatusOK,
+		body: map[string]interface{}{
+			"newNonce":   base + "new-nonce",
+			"newAccount": base + "new-account",
+			"newOrder":   base + "new-order",
+			"revokeCert": base + "revoke-cert",
+			"meta": map[string]interface{}{
+				"externalAccountRequired": false,
+			},
+		},
+	}, nil
+}
+
+// pathACMENewNonceRead answers both HEAD and GET requests, which are the same
+// operation to the backend, with the status RFC 8555 specifies for GET; the
+// nonce itself is added to every ACME response.
+func (b *backend) pathACMENewNonceRead(ctx context.Context, req *logical.Request, data *framework.FieldData, r *acmeRequest) (*acmeReply, error) {
+	return &acmeReply{status: http.StatusNoContent}, nil
+}
+
+func (b *backend) pathACMENewAccountWrite(ctx context.Context, req *logical.Request, data *framework.FieldData, r *acmeRequest) (*acmeReply, error) {
+	var payload struct {
+		Contact              []string `json:"contact"`
+		TermsOfServiceAgreed bool     `json:"termsOfServiceAgreed"`
+		OnlyReturnExisting   bool     `json:"onlyReturnExisting"`
+	}
+	if err := r.decodePayload(&payload); err != nil {
+		return nil, err
+	}
+
+	id, err := acmeThumbprint(r.jwk)
+	if err != nil {
+		return nil, acmeMalformed("failed to compute the thumbprint of the key: %v", err)
+	}
+
+	b.acmeLock.Lock()
+	defer b.acmeLock.Unlock()
+
+	account, err := getACMEAccount(ctx, req.Storage, id)
+	if err != nil {
+		return nil, err
+	}
+	if account != nil {
+		return b.acmeAccountReply(r, account, http.StatusOK), nil
+	}
+	if payload.OnlyReturnExisting {
+		return nil, newACMEProblem(http.StatusBadRequest, "accountDoesNotExist", "no account exists for the key")
+	}
+	if err := validateACMEContacts(payload.Contact); err != nil {
+		return nil, err
+	}
+
+	key, err := r.jwk.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	account = &acmeAccount{
+		ID:           id,
+		Status:       acmeStatusValid,
+		Contact:      payload.Contact,
+		Key:          string(key),
+		CreationTime: time.Now(),
+	}
+	if err := putACMEEntry(ctx, req.Storage, "acme/accounts/"+id, account); err != nil {
+		return nil, err
+	}
+
+	return b.acmeAccountReply(r, account, http.StatusCreated), nil
+}
+
+func (b *backend) pathACMEAccountWrite(ctx context.Context, req *logical.Request, data *framework.FieldData, r *acmeRequest) (*acmeReply, error) {
+	if data.Get("account_id").(string) != r.account.ID {
+		return nil, newACMEProblem(http.StatusUnauthorized, "unauthorized", "the request must be signed with the key of the account")
+	}
+	if r.postAsGet() {
+		return b.acmeAccountReply(r, r.account, http.StatusOK), nil
+	}
+
+	var payload struct {
+		Contact *[]string `json:"contact"`
+		Status  string    `json:"status"`
+	}
+	if err := r.decodePayload(&payload); err != nil {
+		return nil, err
+	}
+
+	b.acmeLock.Lock()
+	defer b.acmeLock.Unlock()
+
+	account := r.account
+	if payload.Contact != nil {
+		if err := validateACMEContacts(*payload.Contact); err != nil {
+			return nil, err
+		}
+		account.Contact = *payload.Contact
+	}
+	switch payload.Status {
+	case "":
+	case acmeStatusDeactivated:
+		account.Status = acmeStatusDeactivated
+	default:
+		return nil, acmeMalformed("the status of an account can only be changed to %q", acmeStatusDeactivated)
+	}
+
+	if err := putACMEEntry(ctx, req.Storage, "acme/accounts/"+account.ID, account); err != nil {
+		return nil, err
+	}
+
+	return b.acmeAccountReply(r, account, http.StatusOK), nil
+}
+
+func (b *backend) acmeAccountReply(r *acmeRequest, account *acmeAccount, status int) *acmeReply {
+	accountURL := r.config.BaseURL + "/acme/account/" + account.ID
+	contact := account.Contact
+	if contact == nil {
+		contact = []string{}
+	}
+
+	return &acmeReply{
+		status:   status,
+		location: accountURL,
+		body: map[string]interface{}{
+			"status":  account.Status,
+			"contact": contact,
+			"orders":  accountURL + "/orders",
+		},
+	}
+}
+
+// validateACMEContacts checks the contact URLs of an account; only email
+// addresses are supported.
+func validateACMEContacts(contacts []string) error {
+	for _, contact := range contacts {
+		if !strings.HasPrefix(contact, "mailto:") {
+			return newACMEProblem(http.StatusBadRequest, "unsupportedContact", "unsupported contact %q: only mailto: URLs are supported", contact)
+		}
+		if address := strings.TrimPrefix(contact, "mailto:"); !strings.Contains(address, "@") || strings.ContainsAny(address, ",?") {
+			return newACMEProblem(http.StatusBadRequest, "invalidContact", "invalid email address in contact %q", contact)
+		}
+	}
+	return nil
+}
+
+func (b *backend) pathACMEAccountOrdersRead(ctx context.Context, req *logical.Request, data *framework.FieldData, r *acmeRequest) (*acmeReply, error) {
+	if data.Get("account_id").(string) != r.account.ID {
+		return nil, newACMEProblem(http.StatusUnauthorized, "unauthorized", "the request must be signed with the key of the account")
+	}
+	if !r.postAsGet() {
+		return nil, acmeMalformed("the orders of an account are fetched with POST-as-GET requests")
+	}
+
+	ids, err := req.Storage.List(ctx, "acme/orders/"+r.account.ID+"/")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(ids)
+
+	orders := []string{}
+	for _, id := range ids {
+		orders = append(orders, r.config.BaseURL+"/acme/order/"+id)
+	}
+
+	return &acmeReply{
+		status: http.StatusOK,
+		body: map[string]interface{}{
+			"orders": orders,
+		},
+	}, nil
+}
+
+func (b *backend) pathACMENewOrderWrite(ctx context.Context, req *logical.Request, data *framework.FieldData, r *acmeRequest) (*acmeReply, error) {
+	var payload struct {
+		Identifiers []acmeIdentifier `json:"identifiers"`
+		NotBefore   string           `json:"notBefore"`
+		NotAfter    string           `json:"notAfter"`
+	}
+	if err := r.decodePayload(&payload); err != nil {
+		return nil, err
+	}
+	if len(payload.Identifiers) == 0 {
+		return nil, acmeMalformed("the order must have at least one identifier")
+	}
+	if payload.NotBefore != "" || payload.NotAfter != "" {
+		return nil, acmeMalformed("notBefore and notAfter are not supported; the validity of certificates is set by the role")
+	}
+
+	role, err := b.getRole(ctx, req.Storage, r.config.Role)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, fmt.Errorf("the ACME role %q does not exist", r.config.Role)
+	}
+
+	var identifiers []acmeIdentifier
+	var names []string
+	for _, identifier := range payload.Identifiers {
+		if identifier.Type != "dns" {
+			return nil, newACMEProblem(http.StatusBadRequest, "unsupportedIdentifier", "unsupported identifier type %q", identifier.Type)
+		}
+		name := strings.ToLower(strings.TrimSuffix(identifier.Value, "."))
+		if strutil.StrListContains(names, name) {
+			continue
+		}
+		if name == "" || validateNames(req, []string{name}, role) != "" {
+			return nil, newACMEProblem(http.StatusBadRequest, "rejectedIdentifier", "%q is not allowed", identifier.Value)
+		}
+		names = append(names, name)
+		identifiers = append(identifiers, acmeIdentifier{Type: "dns", Value: name})
+	}
+
+	orderID, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+	order := &acmeOrder{
+		ID:          orderID,
+		AccountID:   r.account.ID,
+		Status:      acmeStatusPending,
+		Expires:     time.Now().Add(acmeOrderLifetime),
+		Identifiers: identifiers,
+	}
+
+	for _, identifier := range identifiers {
+		authz, err := newACMEAuthorization(r.account.ID, identifier.Value, order.Expires)
+		if err != nil {
+			return nil, err
+		}
+		if err := putACMEEntry(ctx, req.Storage, "acme/authorizations/"+r.account.ID+"/"+authz.ID, authz); err != nil {
+			return nil, err
+		}
+		order.AuthorizationIDs = append(order.AuthorizationIDs, authz.ID)
+	}
+
+	if err := putACMEEntry(ctx, req.Storage, "acme/orders/"+r.account.ID+"/"+order.ID, order); err != nil {
+		return nil, err
+	}
+
+	return b.acmeOrderReply(r, order, http.StatusCreated), nil
+}
+
+// newACMEAuthorization returns a pending authorization of a domain. Wildcard
+// domains can only be validated with the dns-01 challenge of their base
+// domain.
+func newACMEAuthorization(accountID, name string, expires time.Time) (*acmeAuthorization, error) {
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+
+	authz := &acmeAuthorization{
+		ID:         id,
+		AccountID:  accountID,
+		Identifier: acmeIdentifier{Type: "dns", Value: name},
+		Status:     acmeStatusPending,
+		Expires:    expires,
+	}
+
+	challengeTypes := []string{acmeChallengeHTTP01, acmeChallengeDNS01}
+	if strings.HasPrefix(name, "*.") {
+		authz.Identifier.Value = strings.TrimPrefix(name, "*.")
+		authz.Wildcard = true
+		challengeTypes = []string{acmeChallengeDNS01}
+	}
+
+	for _, challengeType := range challengeTypes {
+		token, err := acmeRandomToken()
+		if err != nil {
+			return nil, err
+		}
+		authz.Challenges = append(authz.Challenges, &acmeChallenge{
+			Type:   challengeType,
+			Token:  token,
+			Status: acmeStatusPending,
+		})
+	}
+
+	return authz, nil
+}
+
+func (b *backend) pathACMEOrderRead(ctx context.Context, req *logical.Request, data *framework.FieldData, r *acmeRequest) (*acmeReply, error) {
+	b.acmeLock.Lock()
+	defer b.acmeLock.Unlock()
+
+	order, err := b.acmeOrder(ctx, req, data, r)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.acmeOrderReply(r, order, http.StatusOK), nil
+}
+
+// acmeOrder returns the order of the request, owned by the account that
+// signed it, with its status brought up to date. The caller must hold
+// acmeLock.
+func (b *backend) acmeOrder(ctx context.Context, req *logical.Request, data *framework.FieldData, r *acmeRequest) (*acmeOrder, error) {
+	orderID := data.Get("order_id").(string)
+	order, err := getACMEOrder(ctx, req.Storage, r.account.ID, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order == nil {
+		return nil, newACMEProblem(http.StatusNotFound, "malformed", "unknown order %q", orderID)
+	}
+
+	if order.Status != acmeStatusPending {
+		return order, nil
+	}
+
+	status := acmeStatusReady
+	if time.Now().After(order.Expires) {
+		status = acmeStatusInvalid
+	}
+	for _, authzID := range order.AuthorizationIDs {
+		authz, err := getACMEAuthorization(ctx, req.Storage, r.account.ID, authzID)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case authz == nil || authz.Status == acmeStatusInvalid || authz.Status == acmeStatusDeactivated:
+			status = acmeStatusInvalid
+		case authz.Status != acmeStatusValid && status == acmeStatusReady:
+			status = acmeStatusPending
+		}
+	}
+
+	if status != acmeStatusPending {
+		order.Status = status
+		if err := putACMEEntry(ctx, req.Storage, "acme/orders/"+r.account.ID+"/"+order.ID, order); err != nil {
+			return nil, err
+		}
+	}
+
+	return order, nil
+}
+
+func (b *backend) acmeOrderReply(r *acmeRequest, order *acmeOrder, status int) *acmeReply {
+	base := r.config.BaseURL + "/acme/"
+	orderURL := base + "order/" + order.ID
+
+	authorizations := []string{}
+	for _, authzID := range order.AuthorizationIDs {
+		authorizations = append(authorizations, base+"authorization/"+authzID)
+	}
+
+	body := map[string]interface{}{
+		"status":         order.Status,
+		"expires":        formatACMETime(order.Expires),
+		"identifiers":    order.Identifiers,
+		"authorizations": authorizations,
+		"finalize":       orderURL + "/finalize",
+	}
+	if order.Certificate != "" {
+		body["certificate"] = orderURL + "/cert"
+	}
+	if order.Error != nil {
+		body["error"] = order.Error
+	}
+
+	return &acmeReply{
+		status:   status,
+		location: orderURL,
+		body:     body,
+	}
+}
+
+func (b *backend) pathACMEOrderFinalizeWrite(ctx context.Context, req *logical.Request, data *framework.FieldData, r *acmeRequest) (*acmeReply, error) {
+	var payload struct {
+		CSR string `json:"csr"`
+	}
+	if err := r.decodePayload(&payload); err != nil {
+		return nil, err
+	}
+
+	b.acmeLock.Lock()
+	defer b.acmeLock.Unlock()
+
+	order, err := b.acmeOrder(ctx, req, data, r)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != acmeStatusReady {
+		return nil, newACMEProblem(http.StatusForbidden, "orderNotReady", "the order is %s", order.Status)
+	}
+
+	csrBytes, err := base64.RawURLEncoding.DecodeString(payload.CSR)
+	if err != nil {
+		return nil, newACMEProblem(http.StatusBadRequest, "badCSR", "the csr must be base64url-encoded")
+	}
+	csr, err := x509.ParseCertificateRequest(csrBytes)
+	if err != nil {
+		return nil, newACMEProblem(http.StatusBadRequest, "badCSR", "failed to parse the csr: %v", err)
+	}
+	if err := csr.CheckSignature(); err != nil {
+		return nil, newACMEProblem(http.StatusBadRequest, "badCSR", "the signature of the csr is invalid: %v", err)
+	}
+	if len(csr.IPAddresses) != 0 || len(csr.EmailAddresses) != 0 {
+		return nil, newACMEProblem(http.StatusBadRequest, "badCSR", "the csr must only request DNS names")
+	}
+
+	// The CSR must request exactly the identifiers of the order
+	var orderNames []string
+	for _, identifier := range order.Identifiers {
+		orderNames = append(orderNames, identifier.Value)
+	}
+	csrNames := append([]string{}, csr.DNSNames...)
+	if csr.Subject.CommonName != "" {
+		csrNames = append(csrNames, csr.Subject.CommonName)
+	}
+	csrNames = strutil.RemoveDuplicates(csrNames, true)
+	sort.Strings(csrNames)
+	sort.Strings(orderNames)
+	if strings.Join(csrNames, ",") != strings.Join(orderNames, ",") {
+		return nil, newACMEProblem(http.StatusBadRequest, "badCSR", "the csr must request the names %v of the order, not %v", orderNames, csrNames)
+	}
+
+	role, err := b.getRole(ctx, req.Storage, r.config.Role)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, fmt.Errorf("the ACME role %q does not exist", r.config.Role)
+	}
+
+	// The names are taken from the order, which were checked against the role
+	// and validated, rather than from the CSR as configured by the role
+	acmeRole := *role
+	acmeRole.UseCSRCommonName = false
+	acmeRole.UseCSRSANs = false
+	commonName := strings.ToLower(csr.Subject.CommonName)
+	if commonName == "" {
+		commonName = order.Identifiers[0].Value
+	}
+	var altNames []string
+	for _, name := range orderNames {
+		if name != commonName {
+			altNames = append(altNames, name)
+		}
+	}
+	signData := &framework.FieldData{
+		Raw: map[string]interface{}{
+			"csr":         string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csrBytes})),
+			"common_name": commonName,
+			"alt_names":   strings.Join(altNames, ","),
+			"role":        r.config.Role,
+		},
+		Schema: pathSign(b).Fields,
+	}
+
+	signingBundle, err := fetchCAInfo(ctx, b, req, role.IssuerRef)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch the CA certificate: %v", err)
+	}
+	parsedBundle, err := signCert(ctx, b, &acmeRole, signingBundle, false, false, req, signData)
+	switch err.(type) {
+	case nil:
+	case errutil.UserError:
+		return nil, newACMEProblem(http.StatusBadRequest, "badCSR", "%v", err)
+	default:
+		return nil, err
+	}
+
+	cb, err := parsedBundle.ToCertBundle()
+	if err != nil {
+		return nil, fmt.Errorf("error converting raw cert bundle to cert bundle: %v", err)
+	}
+
+	if !role.NoStore {
+		err = req.Storage.Put(ctx, &logical.StorageEntry{
+			Key:   "certs/" + normalizeSerial(cb.SerialNumber),
+			Value: parsedBundle.CertificateBytes,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("unable to store certificate locally: %v", err)
+		}
+	}
+	err = putACMEEntry(ctx, req.Storage, "acme/certs/"+normalizeSerial(cb.SerialNumber), &acmeCertificate{
+		AccountID: r.account.ID,
+		OrderID:   order.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	chain := []string{cb.Certificate}
+	for _, caCert := range cb.CAChain {
+		chain = append(chain, caCert)
+	}
+	order.Status = acmeStatusValid
+	order.SerialNumber = cb.SerialNumber
+	order.Certificate = strings.Join(chain, "\n") + "\n"
+	if err := putACMEEntry(ctx, req.Storage, "acme/orders/"+r.account.ID+"/"+order.ID, order); err != nil {
+		return nil, err
+	}
+
+	return b.acmeOrderReply(r, order, http.StatusOK), nil
+}
+
+func (b *backend) pathACMEOrderCertRead(ctx context.Context, req *logical.Request, data *framework.FieldData, r *acmeRequest) (*acmeReply, error) {
+	if !r.postAsGet() {
+		return nil, acmeMalformed("certificates are fetched with POST-as-GET requests")
+	}
+
+	b.acmeLock.Lock()
+	defer b.acmeLock.Unlock()
+
+	order, err := b.acmeOrder(ctx, req, data, r)
+	if err != nil {
+		return nil, err
+	}
+	if order.Certificate == "" {
+		return nil, newACMEProblem(http.StatusNotFound, "malformed", "the certificate of the order has not been issued")
+	}
+
+	return &acmeReply{
+		status:      http.StatusOK,
+		raw:         []byte(order.Certificate),
+		contentType: "application/pem-certificate-chain",
+	}, nil
+}
+
+func (b *backend) pathACMEAuthorizationWrite(ctx context.Context, req *logical.Request, data *framework.FieldData, r *acmeRequest) (*acmeReply, error) {
+	var payload struct {
+		Status string `json:"status"`
+	}
+	if !r.postAsGet() {
+		if err := r.decodePayload(&payload); err != nil {
+			return nil, err
+		}
+	}
+
+	b.acmeLock.Lock()
+	defer b.acmeLock.Unlock()
+
+	authz, err := b.acmeAuthorization(ctx, req, data.Get("authorization_id").(string), r)
+	if err != nil {
+		return nil, err
+	}
+
+	switch payload.Status {
+	case "":
+	case acmeStatusDeactivated:
+		if authz.Status != acmeStatusPending && authz.Status != acmeStatusValid {
+			return nil, acmeMalformed("the authorization is %s", authz.Status)
+		}
+		authz.Status = acmeStatusDeactivated
+		if err := putACMEEntry(ctx, req.Storage, "acme/authorizations/"+r.account.ID+"/"+authz.ID, authz); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, acmeMalformed("the status of an authorization can only be changed to %q", acmeStatusDeactivated)
+	}
+
+	return &acmeReply{
+		status: http.StatusOK,
+		body:   acmeAuthorizationView(r, authz),
+	}, nil
+}
+
+// acmeAuthorization returns an authorization owned by the account that
+// signed the request, expiring it if needed. The caller must hold acmeLock.
+func (b *backend) acmeAuthorization(ctx context.Context, req *logical.Request, authzID string, r *acmeRequest) (*acmeAuthorization, error) {
+	authz, err := getACMEAuthorization(ctx, req.Storage, r.account.ID, authzID)
+	if err != nil {
+		return nil, err
+	}
+	if authz == nil {
+		return nil, newACMEProblem(http.StatusNotFound, "malformed", "unknown authorization %q", authzID)
+	}
+
+	if authz.Status == acmeStatusPending && time.Now().After(authz.Expires) {
+		authz.Status = acmeStatusExpired
+		if err := putACMEEntry(ctx, req.Storage, "acme/authorizations/"+r.account.ID+"/"+authz.ID, authz); err != nil {
+			return nil, err
+		}
+	}
+
+	return authz, nil
+}
+
+func acmeAuthorizationView(r *acmeRequest, authz *acmeAuthorization) map[string]interface{} {
+	challenges := []map[string]interface{}{}
+	for _, challenge := range authz.Challenges {
+		challenges = append(challenges, acmeChallengeView(r, authz, challenge))
+	}
+
+	view := map[string]interface{}{
+		"identifier": authz.Identifier,
+		"status":     authz.Status,
+		"expires":    formatACMETime(authz.Expires),
+		"challenges": challenges,
+	}
+	if authz.Wildcard {
+		view["wildcard"] = true
+	}
+	return view
+}
+
+func acmeChallengeView(r *acmeRequest, authz *acmeAuthorization, challenge *acmeChallenge) map[string]interface{} {
+	view := map[string]interface{}{
+		"type":   challenge.Type,
+		"url":    r.config.BaseURL + "/acme/challenge/" + authz.ID + "/" + challenge.Type,
+		"token":  challenge.Token,
+		"status": challenge.Status,
+	}
+	if !challenge.Validated.IsZero() {
+		view["validated"] = formatACMETime(challenge.Validated)
+	}
+	if challenge.Error != nil {
+		view["error"] = challenge.Error
+	}
+	return view
+}
+
+func (b *backend) pathACMEChallengeWrite(ctx context.Context, req *logical.Request, data *framework.FieldData, r *acmeRequest) (*acmeReply, error) {
+	authzID := data.Get("authorization_id").(string)
+	challengeType := data.Get("challenge_type").(string)
+
+	authz, challenge, err := b.startACMEChallenge(ctx, req, authzID, challengeType, r)
+	if err != nil {
+		return nil, err
+	}
+
+	// The challenge is validated synchronously, without holding the lock,
+	// so the client finds it valid or invalid once the request returns
+	if challenge.Status == acmeStatusProcessing {
+		key, err := r.account.jwk()
+		if err != nil {
+			return nil, err
+		}
+		keyAuthorization, err := acmeKeyAuthorization(challenge.Token, key)
+		if err != nil {
+			return nil, err
+		}
+		problem := b.validateACMEChallenge(ctx, challenge, authz.Identifier.Value, keyAuthorization)
+
+		authz, challenge, err = b.completeACMEChallenge(ctx, req, authzID, challengeType, problem, r)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &acmeReply{
+		status: http.StatusOK,
+		body:   acmeChallengeView(r, authz, challenge),
+		links:  []string{fmt.Sprintf(`<%s/acme/authorization/%s>;rel="up"`, r.config.BaseURL, authz.ID)},
+	}, nil
+}
+
+// startACMEChallenge returns the challenge of the request, marked as being
+// processed if the client asked for it to be validated.
+func (b *backend) startACMEChallenge(ctx context.Context, req *logical.Request, authzID, challengeType string, r *acmeRequest) (*acmeAuthorization, *acmeChallenge, error) {
+	b.acmeLock.Lock()
+	defer b.acmeLock.Unlock()
+
+	authz, err := b.acmeAuthorization(ctx, req, authzID, r)
+	if err != nil {
+		return nil, nil, err
+	}
+	challenge := authz.challenge(challengeType)
+	if challenge == nil {
+		return nil, nil, newACMEProblem(http.StatusNotFound, "malformed", "the authorization has no %s challenge", challengeType)
+	}
+
+	if r.postAsGet() || authz.Status != acmeStatusPending || challenge.Status != acmeStatusPending {
+		return authz, challenge, nil
+	}
+
+	challenge.Status = acmeStatusProcessing
+	if err := putACMEEntry(ctx, req.Storage, "acme/authorizations/"+r.account.ID+"/"+authz.ID, authz); err != nil {
+		return nil, nil, err
+	}
+
+	return authz, challenge, nil
+}
+
+// completeACMEChallenge records the result of the validation of a challenge
+// and of its authorization.
+func (b *backend) completeACMEChallenge(ctx context.Context, req *logical.Request, authzID, challengeType string, problem *acmeProblem, r *acmeRequest) (*acmeAuthorization, *acmeChallenge, error) {
+	b.acmeLock.Lock()
+	defer b.acmeLock.Unlock()
+
+	authz, err := b.acmeAuthorization(ctx, req, authzID, r)
+	if err != nil {
+		return nil, nil, err
+	}
+	challenge := authz.challenge(challengeType)
+	if challenge == nil || challenge.Status != acmeStatusProcessing {
+		return nil, nil, fmt.Errorf("challenge %s of authorization %q is no longer being processed", challengeType, authzID)
+	}
+
+	if problem != nil {
+		challenge.Status = acmeStatusInvalid
+		challenge.Error = problem
+		if authz.Status == acmeStatusPending {
+			authz.Status = acmeStatusInvalid
+		}
+	} else {
+		challenge.Status = acmeStatusValid
+		challenge.Validated = time.Now()
+		if authz.Status == acmeStatusPending {
+			authz.Status = acmeStatusValid
+		}
+	}
+
+	if err := putACMEEntry(ctx, req.Storage, "acme/authorizations/"+r.account.ID+"/"+authz.ID, authz); err != nil {
+		return nil, nil, err
+	}
+
+	return authz, challenge, nil
+}
+
+func (a *acmeAuthorization) challenge(challengeType string) *acmeChallenge {
+	for _, challenge := range a.Challenges {
+		if challenge.Type == challengeType {
+			return challenge
+		}
+	}
+	return nil
+}
+
+func (b *backend) pathACMERevokeCertWrite(ctx context.Context, req *logical.Request, data *framework.FieldData, r *acmeRequest) (*acmeReply, error) {
+	var payload struct {
+		Certificate string `json:"certificate"`
+		Reason      int    `json:"reason"`
+	}
+	if err := r.decodePayload(&payload); err != nil {
+		return nil, err
+	}
+
+	certBytes, err := base64.RawURLEncoding.DecodeString(payload.Certificate)
+	if err != nil {
+		return nil, acmeMalformed("the certificate must be base64url-encoded")
+	}
+	cert, err := x509.ParseCertificate(certBytes)
+	if err != nil {
+		return nil, acmeMalformed("failed to parse the certificate: %v", err)
+	}
+	serial := certutil.GetHexFormatted(cert.SerialNumber.Bytes(), ":")
+
+	// Only the account that ordered a certificate can revoke it
+	entry, err := req.Storage.Get(ctx, "acme/certs/"+normalizeSerial(serial))
+	if err != nil {
+		return nil, err
+	}
+	var owner acmeCertificate
+	if entry != nil {
+		if err := entry.DecodeJSON(&owner); err != nil {
+			return nil, err
+		}
+	}
+	if owner.AccountID != r.account.ID {
+		return nil, newACMEProblem(http.StatusForbidden, "unauthorized", "the certificate was not ordered by the account")
+	}
+
+	b.revokeStorageLock.Lock()
+	defer b.revokeStorageLock.Unlock()
+
+	resp, err := revokeCert(ctx, b, req, serial, false)
+	if err != nil {
+		return nil, err
+	}
+	if resp != nil && resp.IsError() {
+		return nil, acmeMalformed("%v", resp.Data["error"])
+	}
+
+	return &acmeReply{status: http.StatusOK, raw: []byte{}, contentType: "application/json"}, nil
+}
+
+func putACMEEntry(ctx context.Context, s logical.Storage, path string, value interface{}) error {
+	entry, err := logical.StorageEntryJSON(path, value)
+	if err != nil {
+		return err
+	}
+	return s.Put(ctx, entry)
+}
+
+func getACMEEntry(ctx context.Context, s logical.Storage, path string, out interface{}) (bool, error) {
+	entry, err := s.Get(ctx, path)
+	if err != nil {
+		return false, err
+	}
+	if entry == nil {
+		return false, nil
+	}
+	if err := json.Unmarshal(entry.Value, out); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func getACMEAccount(ctx context.Context, s logical.Storage, id string) (*acmeAccount, error) {
+	var account acmeAccount
+	found, err := getACMEEntry(ctx, s, "acme/accounts/"+id, &account)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &account, nil
+}
+
+func getACMEOrder(ctx context.Context, s logical.Storage, accountID, id string) (*acmeOrder, error) {
+	var order acmeOrder
+	found, err := getACMEEntry(ctx, s, "acme/orders/"+accountID+"/"+id, &order)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &order, nil
+}
+
+func getACMEAuthorization(ctx context.Context, s logical.Storage, accountID, id string) (*acmeAuthorization, error) {
+	var authz acmeAuthorization
+	found, err := getACMEEntry(ctx, s, "acme/authorizations/"+accountID+"/"+id, &authz)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &authz, nil
+}
+
+const pathACMEDirectoryHelpSyn = `
+Fetch the directory of the ACME server.
+`
+
+const pathACMENewNonceHelpSyn = `
+Fetch a nonce for the next ACME request.
+`
+
+const pathACMENewAccountHelpSyn = `
+Create an ACME account, or find the account of a key.
+`
+
+const pathACMEAccountHelpSyn = `
+Fetch, update or deactivate an ACME account.
+`
+
+const pathACMEAccountOrdersHelpSyn = `
+List the orders of an ACME account.
+`
+
+const pathACMENewOrderHelpSyn = `
+Order a certificate through ACME.
+`
+
+const pathACMEOrderHelpSyn = `
+Fetch an ACME order.
+`
+
+const pathACMEOrderFinalizeHelpSyn = `
+Submit the CSR of a ready ACME order to have its certificate issued.
+`
+
+const pathACMEOrderCertHelpSyn = `
+Download the certificate chain of an ACME order.
+`
+
+const pathACMEAuthorizationHelpSyn = `
+Fetch or deactivate an ACME authorization.
+`
+
+const pathACMEChallengeHelpSyn = `
+Fetch an ACME challenge, or have it validated.
+`
+
+const pathACMERevokeCertHelpSyn = `
+Revoke a certificate ordered through ACME.
+`
+
+const pathACMEHelpDesc = `
+This is an endpoint of the ACME (RFC 8555) server of the backend, enabled
+and configured with "config/acme". Requests other than for the directory and
+nonces are JWS signed by the ACME client and do not require a Vault token.
+`
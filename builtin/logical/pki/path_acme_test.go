@@ -0,0 +1,391 @@
+package pki
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/helper/certutil"
+	"github.com/hashicorp/vault/logical"
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+const testACMEBaseURL = "https://vault.example.com/v1/pki"
+
+// testACMEClient signs ACME requests to a backend the way ACME clients do
+type testACMEClient struct {
+	t     *testing.T
+	b     *backend
+	s     logical.Storage
+	key   *ecdsa.PrivateKey
+	kid   string
+	nonce string
+}
+
+func (c *testACMEClient) Nonce() (string, error) {
+	if c.nonce == "" {
+		c.do(logical.ReadOperation, "acme/new-nonce", nil)
+	}
+	nonce := c.nonce
+	c.nonce = ""
+	return nonce, nil
+}
+
+// post sends a JWS signed request; a nil payload makes a POST-as-GET request
+func (c *testACMEClient) post(path string, payload interface{}) (int, map[string][]string, []byte) {
+	var key interface{} = c.key
+	if c.kid != "" {
+		key = jose.JSONWebKey{Key: c.key, KeyID: c.kid}
+	}
+	options := (&jose.SignerOptions{
+		NonceSource: c,
+		EmbedJWK:    c.kid == "",
+	}).WithHeader("url", testACMEBaseURL+"/"+path)
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.ES256, Key: key}, options)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+
+	payloadBytes := []byte{}
+	if payload != nil {
+		if payloadBytes, err = json.Marshal(payload); err != nil {
+			c.t.Fatal(err)
+		}
+	}
+	jws, err := signer.Sign(payloadBytes)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	serialized, err := jws.CompactSerialize()
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	parts := strings.Split(serialized, ".")
+
+	return c.do(logical.UpdateOperation, path, map[string]interface{}{
+		"protected": parts[0],
+		"payload":   parts[1],
+		"signature": parts[2],
+	})
+}
+
+func (c *testACMEClient) do(op logical.Operation, path string, data map[string]interface{}) (int, map[string][]string, []byte) {
+	resp, err := c.b.HandleRequest(context.Background(), &logical.Request{
+		Operation: op,
+		Path:      path,
+		Storage:   c.s,
+		Data:      data,
+	})
+	if err != nil || resp == nil {
+		c.t.Fatalf("bad: path: %s, err: %v, resp: %#v", path, err, resp)
+	}
+
+	headers := resp.Data[logical.HTTPRawHeaders].(map[string][]string)
+	c.nonce = headers["Replay-Nonce"][0]
+	body, _ := resp.Data[logical.HTTPRawBody].([]byte)
+	return resp.Data[logical.HTTPStatusCode].(int), headers, body
+}
+
+// postOK sends a request that must succeed and decodes its JSON response
+func (c *testACMEClient) postOK(path string, payload interface{}) (map[string][]string, map[string]interface{}) {
+	status, headers, body := c.post(path, payload)
+	if status != http.StatusOK && status != http.StatusCreated {
+		c.t.Fatalf("bad: path: %s, status: %d, body: %s", path, status, body)
+	}
+	var result map[string]interface{}
+	if err := json.Unmarshal(body, &result); err != nil {
+		c.t.Fatalf("bad: path: %s, body: %s", path, body)
+	}
+	return headers, result
+}
+
+// postProblem sends a request that must fail with the given problem
+func (c *testACMEClient) postProblem(path string, payload interface{}, problemType string) {
+	status, _, body := c.post(path, payload)
+	var problem acmeProblem
+	if err := json.Unmarshal(body, &problem); err != nil {
+		c.t.Fatalf("bad: path: %s, body: %s", path, body)
+	}
+	if problem.Type != acmeProblemPrefix+problemType || problem.Status != status {
+		c.t.Fatalf("bad: path: %s, expected a %s problem, got status %d: %s", path, problemType, status, body)
+	}
+}
+
+func TestPki_ACME(t *testing.T) {
+	b, storage := createBackendWithStorage(t)
+
+	doReq := func(path string, data map[string]interface{}) *logical.Response {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Storage:   storage,
+			Data:      data,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: path: %s, err: %v, resp: %#v", path, err, resp)
+		}
+		return resp
+	}
+
+	doReq("root/generate/internal", map[string]interface{}{
+		"common_name": "Root CA",
+		"ttl":         "8760h",
+		"key_type":    "ec",
+		"key_bits":    256,
+	})
+	doReq("roles/acme", map[string]interface{}{
+		"allowed_domains":  "example.com",
+		"allow_subdomains": true,
+		"allow_localhost":  true,
+		"key_type":         "ec",
+		"key_bits":         256,
+		"ttl":              "1h",
+	})
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &testACMEClient{t: t, b: b, s: storage, key: key}
+
+	// ACME must be enabled first
+	client.postProblem("acme/new-account", map[string]interface{}{}, "unauthorized")
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config/acme",
+		Storage:   storage,
+		Data:      map[string]interface{}{"enabled": true},
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error without role and base_url: err: %v, resp: %#v", err, resp)
+	}
+	doReq("config/acme", map[string]interface{}{
+		"enabled":  true,
+		"role":     "acme",
+		"base_url": testACMEBaseURL + "/",
+	})
+
+	status, _, body := client.do(logical.ReadOperation, "acme/directory", nil)
+	var directory map[string]interface{}
+	if err := json.Unmarshal(body, &directory); err != nil || status != http.StatusOK {
+		t.Fatalf("bad: status: %d, body: %s", status, body)
+	}
+	if directory["newOrder"] != testACMEBaseURL+"/acme/new-order" {
+		t.Fatalf("bad: directory: %#v", directory)
+	}
+
+	// Unknown accounts are not created when only looking them up
+	client.postProblem("acme/new-account", map[string]interface{}{"onlyReturnExisting": true}, "accountDoesNotExist")
+
+	headers, account := client.postOK("acme/new-account", map[string]interface{}{
+		"contact":              []string{"mailto:admin@example.com"},
+		"termsOfServiceAgreed": true,
+	})
+	if account["status"] != acmeStatusValid || len(headers["Location"]) != 1 {
+		t.Fatalf("bad: headers: %#v, account: %#v", headers, account)
+	}
+	client.kid = headers["Location"][0]
+
+	status, _, _ = client.post("acme/account/"+strings.TrimPrefix(client.kid, testACMEBaseURL+"/acme/account/"), nil)
+	if status != http.StatusOK {
+		t.Fatalf("bad: status: %d", status)
+	}
+
+	// Nonces must have been issued by the server
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.ES256, Key: jose.JSONWebKey{Key: key, KeyID: client.kid}},
+		(&jose.SignerOptions{}).WithHeader("nonce", "replayed").WithHeader("url", testACMEBaseURL+"/acme/new-order"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	jws, err := signer.Sign([]byte("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	serialized, _ := jws.CompactSerialize()
+	parts := strings.Split(serialized, ".")
+	status, _, body = client.do(logical.UpdateOperation, "acme/new-order", map[string]interface{}{
+		"protected": parts[0],
+		"payload":   parts[1],
+		"signature": parts[2],
+	})
+	if status != http.StatusBadRequest || !strings.Contains(string(body), "badNonce") {
+		t.Fatalf("bad: status: %d, body: %s", status, body)
+	}
+
+	// Names not allowed by the role cannot be ordered
+	client.postProblem("acme/new-order", map[string]interface{}{
+		"identifiers": []acmeIdentifier{{Type: "dns", Value: "www.example.org"}},
+	}, "rejectedIdentifier")
+
+	headers, order := client.postOK("acme/new-order", map[string]interface{}{
+		"identifiers": []acmeIdentifier{
+			{Type: "dns", Value: "localhost"},
+			{Type: "dns", Value: "*.example.com"},
+		},
+	})
+	if order["status"] != acmeStatusPending || len(order["authorizations"].([]interface{})) != 2 {
+		t.Fatalf("bad: order: %#v", order)
+	}
+	orderPath := strings.TrimPrefix(headers["Location"][0], testACMEBaseURL+"/")
+
+	// The order cannot be finalized before its authorizations are valid
+	client.postProblem(orderPath+"/finalize", map[string]interface{}{"csr": ""}, "orderNotReady")
+
+	jwk := &jose.JSONWebKey{Key: key.Public()}
+	challengeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.URL.Path, "/.well-known/acme-challenge/")
+		keyAuthorization, err := acmeKeyAuthorization(token, jwk)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(keyAuthorization))
+	}))
+	defer challengeServer.Close()
+	_, port, err := net.SplitHostPort(strings.TrimPrefix(challengeServer.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.acmeHTTP01Port, _ = strconv.Atoi(port)
+
+	var dnsRecords []string
+	b.acmeLookupTXT = func(ctx context.Context, name string) ([]string, error) {
+		if name != "_acme-challenge.example.com" {
+			return nil, &net.DNSError{Err: "no such host", Name: name}
+		}
+		return dnsRecords, nil
+	}
+
+	for _, authzURL := range order["authorizations"].([]interface{}) {
+		_, authz := client.postOK(strings.TrimPrefix(authzURL.(string), testACMEBaseURL+"/"), nil)
+		identifier := authz["identifier"].(map[string]interface{})["value"].(string)
+
+		challengeType := acmeChallengeHTTP01
+		if identifier == "example.com" {
+			if authz["wildcard"] != true || len(authz["challenges"].([]interface{})) != 1 {
+				t.Fatalf("bad: authorization: %#v", authz)
+			}
+			challengeType = acmeChallengeDNS01
+		}
+
+		var challenge map[string]interface{}
+		for _, raw := range authz["challenges"].([]interface{}) {
+			if raw.(map[string]interface{})["type"] == challengeType {
+				challenge = raw.(map[string]interface{})
+			}
+		}
+		if challengeType == acmeChallengeDNS01 {
+			keyAuthorization, err := acmeKeyAuthorization(challenge["token"].(string), jwk)
+			if err != nil {
+				t.Fatal(err)
+			}
+			digest := sha256.Sum256([]byte(keyAuthorization))
+			dnsRecords = []string{base64.RawURLEncoding.EncodeToString(digest[:])}
+		}
+
+		challengePath := strings.TrimPrefix(challenge["url"].(string), testACMEBaseURL+"/")
+		headers, challenge = client.postOK(challengePath, map[string]interface{}{})
+		if challenge["status"] != acmeStatusValid || challenge["validated"] == nil {
+			t.Fatalf("bad: challenge: %#v", challenge)
+		}
+		if !strings.Contains(strings.Join(headers["Link"], ","), `rel="up"`) {
+			t.Fatalf("bad: headers: %#v", headers)
+		}
+	}
+
+	_, order = client.postOK(orderPath, nil)
+	if order["status"] != acmeStatusReady {
+		t.Fatalf("bad: order: %#v", order)
+	}
+
+	csrKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newCSR := func(names ...string) string {
+		csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+			Subject:  pkix.Name{CommonName: names[0]},
+			DNSNames: names,
+		}, csrKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(csr)
+	}
+
+	// The CSR must request the names of the order
+	client.postProblem(orderPath+"/finalize", map[string]interface{}{"csr": newCSR("localhost")}, "badCSR")
+
+	_, order = client.postOK(orderPath+"/finalize", map[string]interface{}{
+		"csr": newCSR("localhost", "*.example.com"),
+	})
+	if order["status"] != acmeStatusValid || order["certificate"] != testACMEBaseURL+"/"+orderPath+"/cert" {
+		t.Fatalf("bad: order: %#v", order)
+	}
+
+	status, headers, body = client.post(orderPath+"/cert", nil)
+	if status != http.StatusOK {
+		t.Fatalf("bad: status: %d, body: %s", status, body)
+	}
+	// The chain does not include the root CA which issued the certificate
+	block, rest := pem.Decode(body)
+	if block == nil || strings.TrimSpace(string(rest)) != "" || headers["Content-Type"] != nil {
+		t.Fatalf("bad: chain: %s", body)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cert.Subject.CommonName != "localhost" || len(cert.DNSNames) != 2 {
+		t.Fatalf("bad: certificate: %#v", cert)
+	}
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "cert/" + certutil.GetHexFormatted(cert.SerialNumber.Bytes(), "-"),
+		Storage:   storage,
+	})
+	if err != nil || resp == nil || resp.Data["certificate"] == "" {
+		t.Fatalf("bad: err: %v, resp: %#v", err, resp)
+	}
+
+	// Only the account that ordered a certificate can revoke it
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := &testACMEClient{t: t, b: b, s: storage, key: otherKey}
+	headers, _ = other.postOK("acme/new-account", map[string]interface{}{"termsOfServiceAgreed": true})
+	other.kid = headers["Location"][0]
+	revokePayload := map[string]interface{}{
+		"certificate": base64.RawURLEncoding.EncodeToString(cert.Raw),
+	}
+	other.postProblem("acme/revoke-cert", revokePayload, "unauthorized")
+
+	status, _, body = client.post("acme/revoke-cert", revokePayload)
+	if status != http.StatusOK {
+		t.Fatalf("bad: status: %d, body: %s", status, body)
+	}
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "cert/" + certutil.GetHexFormatted(cert.SerialNumber.Bytes(), "-"),
+		Storage:   storage,
+	})
+	if err != nil || resp == nil || resp.Data["revocation_time"].(int64) == 0 {
+		t.Fatalf("bad: err: %v, resp: %#v", err, resp)
+	}
+}
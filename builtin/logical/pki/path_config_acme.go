@@ -0,0 +1,153 @@
+package pki
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// acmeConfig holds the configuration of the ACME server of the backend
+type acmeConfig struct {
+	Enabled bool   `json:"enabled" mapstructure:"enabled" structs:"enabled"`
+	Role    string `json:"role" mapstructure:"role" structs:"role"`
+	BaseURL string `json:"base_url" mapstructure:"base_url" structs:"base_url"`
+}
+
+func pathConfigACME(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config/acme",
+		Fields: map[string]*framework.FieldSchema{
+			"enabled": &framework.FieldSchema{
+				Type:        framework.TypeBool,
+				Description: `Whether the ACME endpoints under "acme/" are enabled`,
+			},
+
+			"role": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `Name of the role certificates ordered through
+ACME are issued with; required when enabled`,
+			},
+
+			"base_url": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `URL of this mount as seen by ACME clients, e.g.
+"https://vault.example.com:8200/v1/pki"; required when enabled`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathACMEConfigRead,
+			logical.UpdateOperation: b.pathACMEConfigWrite,
+		},
+
+		HelpSynopsis:    pathConfigACMEHelpSyn,
+		HelpDescription: pathConfigACMEHelpDesc,
+	}
+}
+
+func getACMEConfig(ctx context.Context, s logical.Storage) (*acmeConfig, error) {
+	entry, err := s.Get(ctx, "config/acme")
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return &acmeConfig{}, nil
+	}
+
+	var result acmeConfig
+	if err := entry.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (b *backend) pathACMEConfigRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := getACMEConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"enabled":  config.Enabled,
+			"role":     config.Role,
+			"base_url": config.BaseURL,
+		},
+	}, nil
+}
+
+func (b *backend) pathACMEConfigWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := getACMEConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	if enabledRaw, ok := data.GetOk("enabled"); ok {
+		config.Enabled = enabledRaw.(bool)
+	}
+	if roleRaw, ok := data.GetOk("role"); ok {
+		config.Role = roleRaw.(string)
+	}
+	if baseURLRaw, ok := data.GetOk("base_url"); ok {
+		config.BaseURL = strings.TrimSuffix(baseURLRaw.(string), "/")
+	}
+
+	if config.BaseURL != "" {
+		parsed, err := url.Parse(config.BaseURL)
+		if err != nil || !parsed.IsAbs() || parsed.Host == "" {
+			return logical.ErrorResponse(fmt.Sprintf("invalid base_url %q", config.BaseURL)), nil
+		}
+		if parsed.Scheme != "https" && parsed.Scheme != "http" {
+			return logical.ErrorResponse("base_url must be an http or https URL"), nil
+		}
+	}
+
+	var resp *logical.Response
+	if config.Enabled {
+		if config.Role == "" || config.BaseURL == "" {
+			return logical.ErrorResponse("role and base_url are required to enable ACME"), nil
+		}
+		role, err := b.getRole(ctx, req.Storage, config.Role)
+		if err != nil {
+			return nil, err
+		}
+		if role == nil {
+			return logical.ErrorResponse(fmt.Sprintf("unknown role %q", config.Role)), nil
+		}
+		if !strings.HasPrefix(config.BaseURL, "https://") {
+			resp = &logical.Response{}
+			resp.AddWarning("base_url is not an https URL; ACME clients usually require https")
+		}
+	}
+
+	entry, err := logical.StorageEntryJSON("config/acme", config)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+const pathConfigACMEHelpSyn = `
+Configure the ACME server of the backend.
+`
+
+const pathConfigACMEHelpDesc = `
+When enabled, the unauthenticated endpoints under "acme/" implement an ACME
+(RFC 8555) server, so that ACME clients such as certbot can order
+certificates. Identifiers are validated with the http-01 or dns-01 challenges
+and certificates are issued with the configured role, which restricts the
+names that can be ordered.
+
+The "base_url" is the URL of this mount as seen by the clients; the URLs
+of the ACME resources are built from it, and the requests signed by the
+clients must target them.
+`
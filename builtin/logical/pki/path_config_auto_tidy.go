@@ -0,0 +1,200 @@
+package pki
+
+import (
+	"context"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+const autoTidyConfigStoragePath = "config/auto_tidy"
+
+// autoTidyConfig holds the schedule and options of the automatic tidy
+// operations
+type autoTidyConfig struct {
+	Enabled            bool          `json:"enabled" mapstructure:"enabled" structs:"enabled"`
+	Interval           time.Duration `json:"interval_duration" mapstructure:"interval_duration" structs:"interval_duration"`
+	TidyCertStore      bool          `json:"tidy_cert_store" mapstructure:"tidy_cert_store" structs:"tidy_cert_store"`
+	TidyRevocationList bool          `json:"tidy_revocation_list" mapstructure:"tidy_revocation_list" structs:"tidy_revocation_list"`
+	SafetyBuffer       time.Duration `json:"safety_buffer" mapstructure:"safety_buffer" structs:"safety_buffer"`
+}
+
+var defaultAutoTidyConfig = autoTidyConfig{
+	Interval:     12 * time.Hour,
+	SafetyBuffer: 72 * time.Hour,
+}
+
+func pathConfigAutoTidy(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config/auto-tidy",
+		Fields: map[string]*framework.FieldSchema{
+			"enabled": &framework.FieldSchema{
+				Type:        framework.TypeBool,
+				Description: `Set to true to run the tidy operation every interval_duration`,
+			},
+
+			"interval_duration": &framework.FieldSchema{
+				Type: framework.TypeDurationSecond,
+				Description: `The interval between automatic tidy
+operations. Defaults to 12 hours.`,
+				Default: 43200, //12h, but TypeDurationSecond currently requires defaults to be int
+			},
+
+			"tidy_cert_store": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `Set to true to enable tidying up
+the certificate store`,
+			},
+
+			"tidy_revocation_list": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `Set to true to enable tidying up
+the revocation list`,
+			},
+
+			"safety_buffer": &framework.FieldSchema{
+				Type: framework.TypeDurationSecond,
+				Description: `The amount of extra time that must have passed
+beyond certificate expiration before it is removed
+from the backend storage and/or revocation list.
+Defaults to 72 hours.`,
+				Default: 259200, //72h, but TypeDurationSecond currently requires defaults to be int
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathConfigAutoTidyRead,
+			logical.UpdateOperation: b.pathConfigAutoTidyWrite,
+		},
+
+		HelpSynopsis:    pathConfigAutoTidyHelpSyn,
+		HelpDescription: pathConfigAutoTidyHelpDesc,
+	}
+}
+
+func getAutoTidyConfig(ctx context.Context, s logical.Storage) (*autoTidyConfig, error) {
+	entry, err := s.Get(ctx, autoTidyConfigStoragePath)
+	if err != nil {
+		return nil, err
+	}
+
+	result := defaultAutoTidyConfig
+	if entry == nil {
+		return &result, nil
+	}
+	if err := entry.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (b *backend) pathConfigAutoTidyRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config, err := getAutoTidyConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"enabled":              config.Enabled,
+			"interval_duration":    int64(config.Interval.Seconds()),
+			"tidy_cert_store":      config.TidyCertStore,
+			"tidy_revocation_list": config.TidyRevocationList,
+			"safety_buffer":        int64(config.SafetyBuffer.Seconds()),
+		},
+	}, nil
+}
+
+func (b *backend) pathConfigAutoTidyWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config, err := getAutoTidyConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	if enabledRaw, ok := d.GetOk("enabled"); ok {
+		config.Enabled = enabledRaw.(bool)
+	}
+	if intervalRaw, ok := d.GetOk("interval_duration"); ok {
+		config.Interval = time.Duration(intervalRaw.(int)) * time.Second
+	}
+	if tidyCertStoreRaw, ok := d.GetOk("tidy_cert_store"); ok {
+		config.TidyCertStore = tidyCertStoreRaw.(bool)
+	}
+	if tidyRevocationListRaw, ok := d.GetOk("tidy_revocation_list"); ok {
+		config.TidyRevocationList = tidyRevocationListRaw.(bool)
+	}
+	if safetyBufferRaw, ok := d.GetOk("safety_buffer"); ok {
+		config.SafetyBuffer = time.Duration(safetyBufferRaw.(int)) * time.Second
+	}
+
+	if config.Interval <= 0 {
+		return logical.ErrorResponse("interval_duration must be positive"), nil
+	}
+	if config.SafetyBuffer < 0 {
+		return logical.ErrorResponse("safety_buffer must not be negative"), nil
+	}
+	if config.Enabled && !config.TidyCertStore && !config.TidyRevocationList {
+		return logical.ErrorResponse("at least one of tidy_cert_store and tidy_revocation_list must be set"), nil
+	}
+
+	entry, err := logical.StorageEntryJSON(autoTidyConfigStoragePath, config)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// autoTidy runs the tidy operation once the configured interval has passed
+// since the last one started on this node. Each node starts counting when the
+// backend is set up.
+func (b *backend) autoTidy(ctx context.Context, req *logical.Request) error {
+	config, err := getAutoTidyConfig(ctx, req.Storage)
+	if err != nil {
+		return err
+	}
+	if !config.Enabled {
+		return nil
+	}
+
+	b.tidyLock.RLock()
+	lastTidy := b.lastTidy
+	b.tidyLock.RUnlock()
+	if time.Now().Before(lastTidy.Add(config.Interval)) {
+		return nil
+	}
+
+	status := &tidyStatus{
+		SafetyBuffer:       config.SafetyBuffer,
+		TidyCertStore:      config.TidyCertStore,
+		TidyRevocationList: config.TidyRevocationList,
+	}
+	if !b.startTidy(status) {
+		return nil
+	}
+
+	err = b.tidy(ctx, req, status)
+	b.finishTidy(status, err)
+	return err
+}
+
+const pathConfigAutoTidyHelpSyn = `
+Configure automatic tidy operations.
+`
+
+const pathConfigAutoTidyHelpDesc = `
+This endpoint runs the operation of the 'tidy' endpoint on a schedule, so
+that expired certificates and revocation information are removed without
+calling it from outside. With 'enabled' set, it runs every
+'interval_duration' with the given 'tidy_cert_store', 'tidy_revocation_list'
+and 'safety_buffer', which have the same meaning as for the 'tidy' endpoint.
+
+The interval is counted from the start of the last tidy operation on the
+node, requested or automatic, and from the setup of the backend. The outcome
+of the automatic runs is reported by the 'tidy-status' endpoint.
+`
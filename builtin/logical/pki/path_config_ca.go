@@ -2,7 +2,6 @@ package pki
 
 import (
 	"context"
-	"fmt"
 
 	"github.com/hashicorp/vault/helper/certutil"
 	"github.com/hashicorp/vault/helper/errutil"
@@ -56,29 +55,17 @@ func (b *backend) pathCAWrite(ctx context.Context, req *logical.Request, data *f
 		return logical.ErrorResponse("the given certificate is not marked for CA use and cannot be used with this backend"), nil
 	}
 
-	cb, err := parsedBundle.ToCertBundle()
-	if err != nil {
-		return nil, fmt.Errorf("error converting raw values into cert bundle: %s", err)
-	}
-
-	entry, err := logical.StorageEntryJSON("config/ca_bundle", cb)
-	if err != nil {
-		return nil, err
-	}
-	err = req.Storage.Put(ctx, entry)
-	if err != nil {
-		return nil, err
-	}
-
-	// For ease of later use, also store just the certificate at a known
-	// location, plus a fresh CRL
-	entry.Key = "ca"
-	entry.Value = parsedBundle.CertificateBytes
-	err = req.Storage.Put(ctx, entry)
-	if err != nil {
+	// The CA becomes the default issuer of the mount
+	_, _, err = b.importCAIssuer(ctx, req.Storage, parsedBundle, "", true, true)
+	switch err.(type) {
+	case nil:
+	case errutil.UserError:
+		return logical.ErrorResponse(err.Error()), nil
+	default:
 		return nil, err
 	}
 
+	// Build a fresh CRL
 	err = buildCRL(ctx, b, req)
 
 	return nil, err
@@ -91,7 +78,9 @@ Set the CA certificate and private key used for generated credentials.
 const pathConfigCAHelpDesc = `
 This sets the CA information used for credentials generated by this
 by this mount. This must be a PEM-format, concatenated unencrypted
-secret key and certificate.
+secret key and certificate. The CA is stored as an issuer and
+becomes the default issuer of the mount; other CAs can be added
+with the "issuers/import" endpoint.
 
 For security reasons, the secret key cannot be retrieved later.
 `
@@ -11,7 +11,11 @@ import (
 
 // CRLConfig holds basic CRL configuration information
 type crlConfig struct {
-	Expiry string `json:"expiry" mapstructure:"expiry" structs:"expiry"`
+	Expiry               string `json:"expiry" mapstructure:"expiry" structs:"expiry"`
+	AutoRebuild          bool   `json:"auto_rebuild" mapstructure:"auto_rebuild" structs:"auto_rebuild"`
+	RebuildInterval      string `json:"rebuild_interval" mapstructure:"rebuild_interval" structs:"rebuild_interval"`
+	EnableDelta          bool   `json:"enable_delta" mapstructure:"enable_delta" structs:"enable_delta"`
+	DeltaRebuildInterval string `json:"delta_rebuild_interval" mapstructure:"delta_rebuild_interval" structs:"delta_rebuild_interval"`
 }
 
 func pathConfigCRL(b *backend) *framework.Path {
@@ -24,6 +28,31 @@ func pathConfigCRL(b *backend) *framework.Path {
 valid; defaults to 72 hours`,
 				Default: "72h",
 			},
+			"auto_rebuild": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `If set, the CRLs are rebuilt every
+rebuild_interval instead of on every revocation`,
+			},
+			"rebuild_interval": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `The interval between automatic rebuilds
+of the CRLs, which must be shorter than the
+expiry; defaults to 12 hours`,
+				Default: "12h",
+			},
+			"enable_delta": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `If set, delta CRLs listing the
+certificates revoked since the last rebuild are
+built every delta_rebuild_interval; requires
+auto_rebuild`,
+			},
+			"delta_rebuild_interval": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `The interval between rebuilds of the
+delta CRLs; defaults to 15 minutes`,
+				Default: "15m",
+			},
 		},
 
 		Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -64,7 +93,11 @@ func (b *backend) pathCRLRead(ctx context.Context, req *logical.Request, data *f
 
 	return &logical.Response{
 		Data: map[string]interface{}{
-			"expiry": config.Expiry,
+			"expiry":                 config.Expiry,
+			"auto_rebuild":           config.AutoRebuild,
+			"rebuild_interval":       config.RebuildInterval,
+			"enable_delta":           config.EnableDelta,
+			"delta_rebuild_interval": config.DeltaRebuildInterval,
 		},
 	}, nil
 }
@@ -72,13 +105,39 @@ func (b *backend) pathCRLRead(ctx context.Context, req *logical.Request, data *f
 func (b *backend) pathCRLWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
 	expiry := d.Get("expiry").(string)
 
-	_, err := time.ParseDuration(expiry)
+	expiryDur, err := time.ParseDuration(expiry)
 	if err != nil {
 		return logical.ErrorResponse(fmt.Sprintf("Given expiry could not be decoded: %s", err)), nil
 	}
 
 	config := &crlConfig{
-		Expiry: expiry,
+		Expiry:               expiry,
+		AutoRebuild:          d.Get("auto_rebuild").(bool),
+		RebuildInterval:      d.Get("rebuild_interval").(string),
+		EnableDelta:          d.Get("enable_delta").(bool),
+		DeltaRebuildInterval: d.Get("delta_rebuild_interval").(string),
+	}
+
+	rebuildInterval, err := time.ParseDuration(config.RebuildInterval)
+	if err != nil || rebuildInterval <= 0 {
+		return logical.ErrorResponse(fmt.Sprintf("Given rebuild_interval could not be decoded: %s", config.RebuildInterval)), nil
+	}
+	deltaRebuildInterval, err := time.ParseDuration(config.DeltaRebuildInterval)
+	if err != nil || deltaRebuildInterval <= 0 {
+		return logical.ErrorResponse(fmt.Sprintf("Given delta_rebuild_interval could not be decoded: %s", config.DeltaRebuildInterval)), nil
+	}
+
+	// The CRLs must be rebuilt before they expire
+	if config.AutoRebuild && rebuildInterval >= expiryDur {
+		return logical.ErrorResponse("rebuild_interval must be shorter than the expiry"), nil
+	}
+	if config.EnableDelta {
+		if !config.AutoRebuild {
+			return logical.ErrorResponse("delta CRLs require auto_rebuild to be set"), nil
+		}
+		if deltaRebuildInterval >= rebuildInterval {
+			return logical.ErrorResponse("delta_rebuild_interval must be shorter than rebuild_interval"), nil
+		}
 	}
 
 	entry, err := logical.StorageEntryJSON("config/crl", config)
@@ -90,13 +149,25 @@ func (b *backend) pathCRLWrite(ctx context.Context, req *logical.Request, d *fra
 		return nil, err
 	}
 
+	// Delta CRLs are no longer updated
+	if !config.EnableDelta {
+		if err := deleteDeltaCRLs(ctx, req.Storage); err != nil {
+			return nil, err
+		}
+	}
+
 	return nil, nil
 }
 
 const pathConfigCRLHelpSyn = `
-Configure the CRL expiration.
+Configure the CRL expiration and rebuilds.
 `
 
 const pathConfigCRLHelpDesc = `
-This endpoint allows configuration of the CRL lifetime.
+This endpoint allows configuration of the CRL lifetime, and of automatic
+rebuilds of the CRLs. By default, the CRLs are rebuilt on every revocation,
+which becomes costly with many revoked certificates. With "auto_rebuild"
+set, they are instead rebuilt every "rebuild_interval", and with
+"enable_delta" set, delta CRLs of the certificates revoked since are built
+every "delta_rebuild_interval" and served at "crl/delta".
 `
@@ -0,0 +1,175 @@
+package pki
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/helper/certutil"
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestPki_CRLAutoRebuild(t *testing.T) {
+	b, storage := createBackendWithStorage(t)
+
+	request := func(op logical.Operation, path string, data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Operation: op,
+			Path:      path,
+			Storage:   storage,
+			Data:      data,
+		})
+	}
+	doReq := func(op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		resp, err := request(op, path, data)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: path: %s, err: %v, resp: %#v", path, err, resp)
+		}
+		return resp
+	}
+	periodic := func() {
+		if err := b.periodicFunc(context.Background(), &logical.Request{Storage: storage}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// fetchCRL returns the CRL of the path, and the base CRL number of delta
+	// CRLs
+	fetchCRL := func(path string) (*x509.RevocationList, int64) {
+		resp := doReq(logical.ReadOperation, path, nil)
+		if resp.Data[logical.HTTPStatusCode] != 200 {
+			t.Fatalf("bad: path: %s, resp: %#v", path, resp.Data)
+		}
+		der := resp.Data[logical.HTTPRawBody].([]byte)
+		if strings.HasSuffix(path, "/pem") {
+			block, _ := pem.Decode(der)
+			der = block.Bytes
+		}
+		crl, err := x509.ParseRevocationList(der)
+		if err != nil {
+			t.Fatalf("bad: path: %s, err: %v", path, err)
+		}
+		var baseNumber int64
+		for _, ext := range crl.Extensions {
+			if ext.Id.Equal(oidExtensionDeltaCRLIndicator) {
+				var base *big.Int
+				if _, err := asn1.Unmarshal(ext.Value, &base); err != nil {
+					t.Fatal(err)
+				}
+				baseNumber = base.Int64()
+			}
+		}
+		return crl, baseNumber
+	}
+
+	resp := doReq(logical.UpdateOperation, "root/generate/internal", map[string]interface{}{
+		"common_name": "Root CA",
+		"ttl":         "8760h",
+	})
+	caCert, err := certutil.ParsePEMBundle(resp.Data["certificate"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuerID := resp.Data["issuer_id"].(string)
+
+	doReq(logical.UpdateOperation, "roles/test", map[string]interface{}{
+		"allowed_domains":  "example.com",
+		"allow_subdomains": true,
+		"ttl":              "1h",
+	})
+	var serials []string
+	for i := 0; i < 2; i++ {
+		resp := doReq(logical.UpdateOperation, "issue/test", map[string]interface{}{
+			"common_name": "test.example.com",
+		})
+		serials = append(serials, resp.Data["serial_number"].(string))
+	}
+
+	for _, data := range []map[string]interface{}{
+		{"enable_delta": true},
+		{"auto_rebuild": true, "rebuild_interval": "72h"},
+		{"auto_rebuild": true, "enable_delta": true, "delta_rebuild_interval": "12h"},
+		{"auto_rebuild": true, "rebuild_interval": "bad"},
+	} {
+		if resp, err := request(logical.UpdateOperation, "config/crl", data); err != nil || resp == nil || !resp.IsError() {
+			t.Fatalf("expected an error: data: %v, err: %v, resp: %#v", data, err, resp)
+		}
+	}
+	doReq(logical.UpdateOperation, "config/crl", map[string]interface{}{
+		"auto_rebuild": true,
+		"enable_delta": true,
+	})
+	resp = doReq(logical.ReadOperation, "config/crl", nil)
+	if resp.Data["auto_rebuild"] != true || resp.Data["rebuild_interval"] != "12h" ||
+		resp.Data["enable_delta"] != true || resp.Data["delta_rebuild_interval"] != "15m" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	complete, _ := fetchCRL("crl")
+	if len(complete.RevokedCertificateEntries) != 0 {
+		t.Fatalf("bad: %#v", complete.RevokedCertificateEntries)
+	}
+	if err := complete.CheckSignatureFrom(caCert.Certificate); err != nil {
+		t.Fatal(err)
+	}
+
+	// Revocations do not rebuild the complete CRL, but are listed in the
+	// delta CRL
+	doReq(logical.UpdateOperation, "revoke", map[string]interface{}{"serial_number": serials[0]})
+	if crl, _ := fetchCRL("crl"); crl.Number.Cmp(complete.Number) != 0 || len(crl.RevokedCertificateEntries) != 0 {
+		t.Fatalf("expected the complete CRL to be left as is: %#v", crl.RevokedCertificateEntries)
+	}
+	periodic()
+	delta, baseNumber := fetchCRL("crl/delta")
+	if baseNumber != complete.Number.Int64() || delta.Number.Cmp(complete.Number) <= 0 ||
+		len(delta.RevokedCertificateEntries) != 1 {
+		t.Fatalf("bad: base: %d, delta: %#v", baseNumber, delta)
+	}
+	if err := delta.CheckSignatureFrom(caCert.Certificate); err != nil {
+		t.Fatal(err)
+	}
+	if issuerDelta, _ := fetchCRL("crl/issuer/" + issuerID + "/delta"); len(issuerDelta.RevokedCertificateEntries) != 1 {
+		t.Fatalf("bad: %#v", issuerDelta.RevokedCertificateEntries)
+	}
+
+	// The delta CRL is only rebuilt after its interval
+	doReq(logical.UpdateOperation, "revoke", map[string]interface{}{"serial_number": serials[1]})
+	periodic()
+	if crl, _ := fetchCRL("crl/delta/pem"); crl.Number.Cmp(delta.Number) != 0 {
+		t.Fatalf("expected the delta CRL to be left as is")
+	}
+
+	// After the rebuild interval, the complete CRL lists all the revoked
+	// certificates, and the delta CRL applies to it
+	state, err := getCRLState(context.Background(), storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state.LastRebuild = state.LastRebuild.Add(-13 * time.Hour)
+	if err := putCRLState(context.Background(), storage, state); err != nil {
+		t.Fatal(err)
+	}
+	periodic()
+	complete, _ = fetchCRL("crl")
+	if len(complete.RevokedCertificateEntries) != 2 || complete.Number.Cmp(delta.Number) <= 0 {
+		t.Fatalf("bad: %#v", complete)
+	}
+	delta, baseNumber = fetchCRL("crl/delta")
+	if baseNumber != complete.Number.Int64() || len(delta.RevokedCertificateEntries) != 0 {
+		t.Fatalf("bad: base: %d, delta: %#v", baseNumber, delta)
+	}
+	if keys, err := storage.List(context.Background(), "delta_wal/"); err != nil || len(keys) != 0 {
+		t.Fatalf("bad: keys: %v, err: %v", keys, err)
+	}
+
+	// Without delta CRLs, none is served
+	doReq(logical.UpdateOperation, "config/crl", map[string]interface{}{"auto_rebuild": true})
+	if resp := doReq(logical.ReadOperation, "crl/delta", nil); resp.Data[logical.HTTPStatusCode] != 204 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+}
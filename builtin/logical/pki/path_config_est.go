@@ -0,0 +1,175 @@
+package pki
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/vault/helper/certutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// estConfig holds the configuration of the EST server of the backend
+type estConfig struct {
+	Enabled             bool   `json:"enabled" mapstructure:"enabled" structs:"enabled"`
+	Role                string `json:"role" mapstructure:"role" structs:"role"`
+	TrustedCertificates string `json:"trusted_certificates" mapstructure:"trusted_certificates" structs:"trusted_certificates"`
+}
+
+func pathConfigEST(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config/est",
+		Fields: map[string]*framework.FieldSchema{
+			"enabled": &framework.FieldSchema{
+				Type:        framework.TypeBool,
+				Description: `Whether the EST endpoints under "est/" are enabled`,
+			},
+
+			"role": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `Name of the role certificates enrolled through
+EST are issued with; required when enabled`,
+			},
+
+			"trusted_certificates": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `PEM-encoded CA certificates; clients presenting
+a TLS certificate issued by one of them may
+enroll`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathESTConfigRead,
+			logical.UpdateOperation: b.pathESTConfigWrite,
+		},
+
+		HelpSynopsis:    pathConfigESTHelpSyn,
+		HelpDescription: pathConfigESTHelpDesc,
+	}
+}
+
+func getESTConfig(ctx context.Context, s logical.Storage) (*estConfig, error) {
+	entry, err := s.Get(ctx, "config/est")
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return &estConfig{}, nil
+	}
+
+	var result estConfig
+	if err := entry.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// trustedPool returns the pool of the trusted certificates, or nil if there
+// are none
+func (c *estConfig) trustedPool() (*x509.CertPool, error) {
+	if c.TrustedCertificates == "" {
+		return nil, nil
+	}
+	parsedBundle, err := certutil.ParsePEMBundle(c.TrustedCertificates)
+	if err != nil {
+		return nil, err
+	}
+	if parsedBundle.PrivateKey != nil {
+		return nil, fmt.Errorf("trusted_certificates must not contain a private key")
+	}
+	if parsedBundle.Certificate == nil {
+		return nil, fmt.Errorf("trusted_certificates contains no certificate")
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(parsedBundle.Certificate)
+	for _, caCert := range parsedBundle.CAChain {
+		pool.AddCert(caCert.Certificate)
+	}
+	return pool, nil
+}
+
+func (b *backend) pathESTConfigRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := getESTConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"enabled":              config.Enabled,
+			"role":                 config.Role,
+			"trusted_certificates": config.TrustedCertificates,
+		},
+	}, nil
+}
+
+func (b *backend) pathESTConfigWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := getESTConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	if enabledRaw, ok := data.GetOk("enabled"); ok {
+		config.Enabled = enabledRaw.(bool)
+	}
+	if roleRaw, ok := data.GetOk("role"); ok {
+		config.Role = roleRaw.(string)
+	}
+	if trustedRaw, ok := data.GetOk("trusted_certificates"); ok {
+		config.TrustedCertificates = strings.TrimSpace(trustedRaw.(string))
+	}
+
+	if _, err := config.trustedPool(); err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("invalid trusted_certificates: %v", err)), nil
+	}
+
+	var resp *logical.Response
+	if config.Enabled {
+		if config.Role == "" {
+			return logical.ErrorResponse("role is required to enable EST"), nil
+		}
+		role, err := b.getRole(ctx, req.Storage, config.Role)
+		if err != nil {
+			return nil, err
+		}
+		if role == nil {
+			return logical.ErrorResponse(fmt.Sprintf("unknown role %q", config.Role)), nil
+		}
+		if config.TrustedCertificates == "" {
+			resp = &logical.Response{}
+			resp.AddWarning("trusted_certificates is empty; clients can only re-enroll with certificates issued by this mount")
+		}
+	}
+
+	entry, err := logical.StorageEntryJSON("config/est", config)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+const pathConfigESTHelpSyn = `
+Configure the EST server of the backend.
+`
+
+const pathConfigESTHelpDesc = `
+When enabled, the unauthenticated endpoints under "est/" implement the
+cacerts, simpleenroll and simplereenroll operations of EST (RFC 7030), so that
+devices which only speak EST can enroll. Certificates are issued with the
+configured role, which restricts the names that can be requested.
+
+Clients authenticate with their TLS client certificate. Enrolling requires a
+certificate issued by one of the "trusted_certificates", such as the CA of the
+manufacturer certificates of the devices. Re-enrolling requires the current,
+unrevoked certificate issued by this mount, and the new certificate must
+keep its common name and alternative names.
+`
@@ -0,0 +1,333 @@
+package pki
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/vault/helper/errutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+const issuanceWebhookConfigStoragePath = "config/issuance_webhook"
+
+// issuanceWebhookConfig holds the external endpoint consulted before
+// certificates are issued
+type issuanceWebhookConfig struct {
+	URL           string        `json:"url" mapstructure:"url" structs:"url"`
+	Timeout       time.Duration `json:"timeout" mapstructure:"timeout" structs:"timeout"`
+	CACertificate string        `json:"ca_certificate" mapstructure:"ca_certificate" structs:"ca_certificate"`
+}
+
+// issuanceWebhookRequest is the body POSTed to the webhook
+type issuanceWebhookRequest struct {
+	Path           string                 `json:"path"`
+	DisplayName    string                 `json:"display_name"`
+	RoleName       string                 `json:"role_name"`
+	Role           map[string]interface{} `json:"role"`
+	CSR            string                 `json:"csr,omitempty"`
+	CommonName     string                 `json:"common_name"`
+	DNSNames       []string               `json:"dns_names"`
+	EmailAddresses []string               `json:"email_addresses"`
+	IPAddresses    []string               `json:"ip_addresses"`
+	NotAfter       time.Time              `json:"not_after"`
+}
+
+// issuanceWebhookResponse is the decision of the webhook. Omitted lists of
+// names are left as requested.
+type issuanceWebhookResponse struct {
+	Allow          bool      `json:"allow"`
+	Reason         string    `json:"reason"`
+	DNSNames       *[]string `json:"dns_names"`
+	EmailAddresses *[]string `json:"email_addresses"`
+	IPAddresses    *[]string `json:"ip_addresses"`
+}
+
+func pathConfigIssuanceWebhook(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config/issuance-webhook",
+		Fields: map[string]*framework.FieldSchema{
+			"url": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `The HTTP or HTTPS URL the certificate requests
+are POSTed to before issuing`,
+			},
+
+			"timeout": &framework.FieldSchema{
+				Type: framework.TypeDurationSecond,
+				Description: `How long to wait for the webhook to answer.
+Defaults to 10 seconds.`,
+				Default: 10,
+			},
+
+			"ca_certificate": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `PEM-encoded CA certificates to verify the
+TLS certificate of the webhook with; if not
+set, the system roots are used`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathIssuanceWebhookConfigRead,
+			logical.UpdateOperation: b.pathIssuanceWebhookConfigWrite,
+			logical.DeleteOperation: b.pathIssuanceWebhookConfigDelete,
+		},
+
+		HelpSynopsis:    pathConfigIssuanceWebhookHelpSyn,
+		HelpDescription: pathConfigIssuanceWebhookHelpDesc,
+	}
+}
+
+// getIssuanceWebhookConfig returns the webhook configuration, or nil if none
+// is set
+func getIssuanceWebhookConfig(ctx context.Context, s logical.Storage) (*issuanceWebhookConfig, error) {
+	entry, err := s.Get(ctx, issuanceWebhookConfigStoragePath)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var result issuanceWebhookConfig
+	if err := entry.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// client returns the HTTP client calling the webhook
+func (c *issuanceWebhookConfig) client() (*http.Client, error) {
+	client := cleanhttp.DefaultClient()
+	client.Timeout = c.Timeout
+	if c.CACertificate != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(c.CACertificate)) {
+			return nil, fmt.Errorf("no certificate found in ca_certificate")
+		}
+		transport := cleanhttp.DefaultTransport()
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+		client.Transport = transport
+	}
+	return client, nil
+}
+
+func (b *backend) pathIssuanceWebhookConfigRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := getIssuanceWebhookConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"url":            config.URL,
+			"timeout":        int64(config.Timeout.Seconds()),
+			"ca_certificate": config.CACertificate,
+		},
+	}, nil
+}
+
+func (b *backend) pathIssuanceWebhookConfigWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := getIssuanceWebhookConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		config = &issuanceWebhookConfig{
+			Timeout: time.Duration(data.Get("timeout").(int)) * time.Second,
+		}
+	}
+
+	if urlRaw, ok := data.GetOk("url"); ok {
+		config.URL = urlRaw.(string)
+	}
+	if timeoutRaw, ok := data.GetOk("timeout"); ok {
+		config.Timeout = time.Duration(timeoutRaw.(int)) * time.Second
+	}
+	if caRaw, ok := data.GetOk("ca_certificate"); ok {
+		config.CACertificate = strings.TrimSpace(caRaw.(string))
+	}
+
+	parsedURL, err := url.Parse(config.URL)
+	if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") || parsedURL.Host == "" {
+		return logical.ErrorResponse(fmt.Sprintf("url must be an HTTP or HTTPS URL, not %q", config.URL)), nil
+	}
+	if config.Timeout <= 0 {
+		return logical.ErrorResponse("timeout must be positive"), nil
+	}
+	if _, err := config.client(); err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("invalid ca_certificate: %v", err)), nil
+	}
+
+	entry, err := logical.StorageEntryJSON(issuanceWebhookConfigStoragePath, config)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (b *backend) pathIssuanceWebhookConfigDelete(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	return nil, req.Storage.Delete(ctx, issuanceWebhookConfigStoragePath)
+}
+
+// applyIssuanceWebhook sends the certificate request to the webhook, if one
+// is configured, and applies its decision to the creation bundle. The
+// certificate is not issued if the webhook cannot be reached or denies it,
+// and the names it returns must still be allowed by the role.
+func applyIssuanceWebhook(ctx context.Context, req *logical.Request, role *roleEntry, roleName string, csr *x509.CertificateRequest, creationInfo *creationBundle) error {
+	config, err := getIssuanceWebhookConfig(ctx, req.Storage)
+	if err != nil {
+		return errutil.InternalError{Err: fmt.Sprintf("unable to fetch the issuance webhook configuration: %v", err)}
+	}
+	if config == nil {
+		return nil
+	}
+
+	webhookReq := &issuanceWebhookRequest{
+		Path:           req.Path,
+		DisplayName:    req.DisplayName,
+		RoleName:       roleName,
+		Role:           role.ToResponseData(),
+		CommonName:     creationInfo.CommonName,
+		DNSNames:       creationInfo.DNSNames,
+		EmailAddresses: creationInfo.EmailAddresses,
+		NotAfter:       creationInfo.NotAfter.UTC(),
+	}
+	if csr != nil {
+		webhookReq.CSR = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csr.Raw}))
+	}
+	if creationInfo.UseCSRValues {
+		webhookReq.CommonName = csr.Subject.CommonName
+		webhookReq.DNSNames = csr.DNSNames
+		webhookReq.EmailAddresses = csr.EmailAddresses
+		webhookReq.IPAddresses = ipStrings(csr.IPAddresses)
+	} else {
+		webhookReq.IPAddresses = ipStrings(creationInfo.IPAddresses)
+	}
+
+	webhookResp, err := config.call(ctx, webhookReq)
+	if err != nil {
+		return errutil.InternalError{Err: fmt.Sprintf("error calling the issuance webhook: %v", err)}
+	}
+	if !webhookResp.Allow {
+		if webhookResp.Reason == "" {
+			return errutil.UserError{Err: "the issuance webhook denied the request"}
+		}
+		return errutil.UserError{Err: fmt.Sprintf("the issuance webhook denied the request: %s", webhookResp.Reason)}
+	}
+
+	if webhookResp.DNSNames == nil && webhookResp.EmailAddresses == nil && webhookResp.IPAddresses == nil {
+		return nil
+	}
+	if creationInfo.UseCSRValues {
+		return errutil.UserError{Err: "the issuance webhook cannot change the names of a certificate signed verbatim"}
+	}
+	if webhookResp.DNSNames != nil {
+		if badName := validateNames(req, *webhookResp.DNSNames, role); badName != "" {
+			return errutil.UserError{Err: fmt.Sprintf("subject alternate name %s returned by the issuance webhook not allowed by this role", badName)}
+		}
+		creationInfo.DNSNames = *webhookResp.DNSNames
+	}
+	if webhookResp.EmailAddresses != nil {
+		if badName := validateNames(req, *webhookResp.EmailAddresses, role); badName != "" {
+			return errutil.UserError{Err: fmt.Sprintf("email address %s returned by the issuance webhook not allowed by this role", badName)}
+		}
+		creationInfo.EmailAddresses = *webhookResp.EmailAddresses
+	}
+	if webhookResp.IPAddresses != nil {
+		ipAddresses := []net.IP{}
+		for _, v := range *webhookResp.IPAddresses {
+			parsedIP := net.ParseIP(v)
+			if parsedIP == nil {
+				return errutil.UserError{Err: fmt.Sprintf("the value '%s' returned by the issuance webhook is not a valid IP address", v)}
+			}
+			ipAddresses = append(ipAddresses, parsedIP)
+		}
+		if len(ipAddresses) > 0 && !role.AllowIPSANs {
+			return errutil.UserError{Err: "IP Subject Alternative Names are not allowed in this role, but were returned by the issuance webhook"}
+		}
+		creationInfo.IPAddresses = ipAddresses
+	}
+
+	return nil
+}
+
+// call POSTs the request to the webhook and decodes its decision
+func (c *issuanceWebhookConfig) call(ctx context.Context, webhookReq *issuanceWebhookRequest) (*issuanceWebhookResponse, error) {
+	client, err := c.client()
+	if err != nil {
+		return nil, err
+	}
+	body, err := json.Marshal(webhookReq)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequest("POST", c.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	httpResp, err := client.Do(httpReq.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+	respBody, err := ioutil.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", httpResp.StatusCode)
+	}
+
+	var webhookResp issuanceWebhookResponse
+	if err := json.Unmarshal(respBody, &webhookResp); err != nil {
+		return nil, fmt.Errorf("invalid response: %v", err)
+	}
+	return &webhookResp, nil
+}
+
+const pathConfigIssuanceWebhookHelpSyn = `
+Configure a webhook deciding on certificate requests.
+`
+
+const pathConfigIssuanceWebhookHelpDesc = `
+Once configured, the certificate requests of the roles, through the issue,
+sign, sign-verbatim, ACME and EST endpoints, are POSTed as JSON to the URL
+after they pass the checks of the role and before the certificate is
+signed. The request holds the path and display name of the requester, the
+name and configuration of the role, the CSR if any, and the names and
+expiration of the certificate.
+
+The webhook answers with "allow" set to true to issue the certificate, or
+false, with an optional "reason", to deny it. It may also return
+"dns_names", "email_addresses" or "ip_addresses" to replace the requested
+alternative names, which must still be allowed by the role. If the webhook
+cannot be reached in time or does not answer with a 200 status and a valid
+decision, the certificate is not issued. CA certificates are not sent to
+the webhook.
+
+Deleting the configuration disables the webhook.
+`
@@ -0,0 +1,196 @@
+package pki
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/vault/helper/certutil"
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestPki_IssuanceWebhook(t *testing.T) {
+	b, storage := createBackendWithStorage(t)
+
+	request := func(op logical.Operation, path string, data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Operation:   op,
+			Path:        path,
+			Storage:     storage,
+			Data:        data,
+			DisplayName: "token-alice",
+		})
+	}
+	doReq := func(op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		resp, err := request(op, path, data)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: path: %s, err: %v, resp: %#v", path, err, resp)
+		}
+		return resp
+	}
+	expectError := func(path string, data map[string]interface{}, message string) {
+		resp, err := request(logical.UpdateOperation, path, data)
+		if err == nil && (resp == nil || !resp.IsError()) {
+			t.Fatalf("expected an error: path: %s, resp: %#v", path, resp)
+		}
+		if err == nil {
+			err = resp.Error()
+		}
+		if !strings.Contains(err.Error(), message) {
+			t.Fatalf("bad: path: %s, err: %v", path, err)
+		}
+	}
+	parseCert := func(resp *logical.Response) *x509.Certificate {
+		bundle, err := certutil.ParsePEMBundle(resp.Data["certificate"].(string))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return bundle.Certificate
+	}
+
+	// The webhook answers with the next decision, and records the requests
+	var lock sync.Mutex
+	var received []issuanceWebhookRequest
+	status := http.StatusOK
+	decision := `{"allow": true}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		var webhookReq issuanceWebhookRequest
+		if err := json.NewDecoder(r.Body).Decode(&webhookReq); err != nil || r.Method != "POST" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received = append(received, webhookReq)
+		w.WriteHeader(status)
+		w.Write([]byte(decision))
+	}))
+	defer server.Close()
+	respond := func(code int, body string) {
+		lock.Lock()
+		defer lock.Unlock()
+		status = code
+		decision = body
+	}
+	lastRequest := func() issuanceWebhookRequest {
+		lock.Lock()
+		defer lock.Unlock()
+		if len(received) == 0 {
+			t.Fatal("the webhook was not called")
+		}
+		return received[len(received)-1]
+	}
+
+	doReq(logical.UpdateOperation, "root/generate/internal", map[string]interface{}{
+		"common_name": "Root CA",
+		"ttl":         "8760h",
+	})
+	doReq(logical.UpdateOperation, "roles/test", map[string]interface{}{
+		"allowed_domains":  "example.com",
+		"allow_subdomains": true,
+	})
+
+	for _, data := range []map[string]interface{}{
+		{"url": "ftp://policy.example.com"},
+		{"url": server.URL, "timeout": 0},
+		{"url": server.URL, "ca_certificate": "bad"},
+	} {
+		if resp, err := request(logical.UpdateOperation, "config/issuance-webhook", data); err != nil || resp == nil || !resp.IsError() {
+			t.Fatalf("expected an error: data: %v, err: %v, resp: %#v", data, err, resp)
+		}
+	}
+	if resp := doReq(logical.ReadOperation, "config/issuance-webhook", nil); resp != nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+	doReq(logical.UpdateOperation, "config/issuance-webhook", map[string]interface{}{"url": server.URL})
+	resp := doReq(logical.ReadOperation, "config/issuance-webhook", nil)
+	if resp.Data["url"] != server.URL || resp.Data["timeout"] != int64(10) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Allowed requests are issued as requested
+	resp = doReq(logical.UpdateOperation, "issue/test", map[string]interface{}{
+		"common_name": "test.example.com",
+		"alt_names":   "alt.example.com",
+	})
+	if cert := parseCert(resp); !reflect.DeepEqual(cert.DNSNames, []string{"test.example.com", "alt.example.com"}) {
+		t.Fatalf("bad: %v", cert.DNSNames)
+	}
+	webhookReq := lastRequest()
+	if webhookReq.Path != "issue/test" || webhookReq.DisplayName != "token-alice" ||
+		webhookReq.RoleName != "test" || webhookReq.Role["allowed_domains"] == nil ||
+		webhookReq.CSR != "" || webhookReq.CommonName != "test.example.com" ||
+		!reflect.DeepEqual(webhookReq.DNSNames, []string{"test.example.com", "alt.example.com"}) ||
+		webhookReq.NotAfter.IsZero() {
+		t.Fatalf("bad: %#v", webhookReq)
+	}
+
+	respond(http.StatusOK, `{"allow": false, "reason": "outside of the change window"}`)
+	expectError("issue/test", map[string]interface{}{"common_name": "test.example.com"}, "outside of the change window")
+
+	// The names returned replace the requested ones, within the role
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: "csr.example.com"},
+		DNSNames: []string{"csr.example.com", "extra.example.com"},
+	}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	csrPEM := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csr}))
+
+	respond(http.StatusOK, `{"allow": true, "dns_names": ["csr.example.com"]}`)
+	resp = doReq(logical.UpdateOperation, "sign/test", map[string]interface{}{
+		"csr":         csrPEM,
+		"common_name": "csr.example.com",
+		"alt_names":   "extra.example.com",
+	})
+	if cert := parseCert(resp); !reflect.DeepEqual(cert.DNSNames, []string{"csr.example.com"}) {
+		t.Fatalf("bad: %v", cert.DNSNames)
+	}
+	if webhookReq := lastRequest(); webhookReq.Path != "sign/test" || webhookReq.CSR != csrPEM {
+		t.Fatalf("bad: %#v", webhookReq)
+	}
+
+	respond(http.StatusOK, `{"allow": true, "dns_names": ["evil.example.org"]}`)
+	expectError("sign/test", map[string]interface{}{"csr": csrPEM, "common_name": "csr.example.com"}, "evil.example.org")
+	respond(http.StatusOK, `{"allow": true, "ip_addresses": ["bad"]}`)
+	expectError("sign/test", map[string]interface{}{"csr": csrPEM, "common_name": "csr.example.com"}, "not a valid IP address")
+	respond(http.StatusOK, `{"allow": true, "dns_names": []}`)
+	expectError("sign-verbatim", map[string]interface{}{"csr": csrPEM}, "signed verbatim")
+
+	// Certificates are not issued if the webhook fails
+	respond(http.StatusInternalServerError, `{"allow": true}`)
+	expectError("issue/test", map[string]interface{}{"common_name": "test.example.com"}, "unexpected status 500")
+	respond(http.StatusOK, `not json`)
+	expectError("issue/test", map[string]interface{}{"common_name": "test.example.com"}, "invalid response")
+
+	// CA certificates are not sent to the webhook
+	received = nil
+	doReq(logical.UpdateOperation, "root/sign-intermediate", map[string]interface{}{
+		"csr":         csrPEM,
+		"common_name": "Intermediate CA",
+	})
+	if len(received) != 0 {
+		t.Fatalf("bad: %#v", received)
+	}
+
+	doReq(logical.DeleteOperation, "config/issuance-webhook", nil)
+	doReq(logical.UpdateOperation, "issue/test", map[string]interface{}{"common_name": "test.example.com"})
+	if len(received) != 0 {
+		t.Fatalf("bad: %#v", received)
+	}
+}
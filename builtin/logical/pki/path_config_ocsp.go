@@ -0,0 +1,166 @@
+package pki
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/helper/certutil"
+	"github.com/hashicorp/vault/helper/errutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+func pathConfigOCSP(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config/ocsp",
+		Fields: map[string]*framework.FieldSchema{
+			"pem_bundle": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `PEM-format, concatenated unencrypted
+secret key and certificate of the responder. The certificate must be
+issued by an issuer of this backend for OCSP signing.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathOCSPConfigRead,
+			logical.UpdateOperation: b.pathOCSPConfigWrite,
+			logical.DeleteOperation: b.pathOCSPConfigDelete,
+		},
+
+		HelpSynopsis:    pathConfigOCSPHelpSyn,
+		HelpDescription: pathConfigOCSPHelpDesc,
+	}
+}
+
+// fetchOCSPResponder returns the delegated OCSP responder, or nil if the
+// responses are signed by the CA
+func fetchOCSPResponder(ctx context.Context, s logical.Storage) (*certutil.ParsedCertBundle, error) {
+	entry, err := s.Get(ctx, "config/ocsp_bundle")
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var bundle certutil.CertBundle
+	if err := entry.DecodeJSON(&bundle); err != nil {
+		return nil, err
+	}
+	return bundle.ToParsedCertBundle()
+}
+
+func (b *backend) pathOCSPConfigRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	responder, err := fetchOCSPResponder(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"delegated": responder != nil,
+		},
+	}
+	if responder != nil {
+		cb, err := responder.ToCertBundle()
+		if err != nil {
+			return nil, err
+		}
+		resp.Data["certificate"] = cb.Certificate
+		resp.Data["serial_number"] = cb.SerialNumber
+		resp.Data["expiration"] = responder.Certificate.NotAfter.Unix()
+	}
+
+	return resp, nil
+}
+
+func (b *backend) pathOCSPConfigWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	parsedBundle, err := certutil.ParsePEMBundle(data.Get("pem_bundle").(string))
+	if err != nil {
+		switch err.(type) {
+		case errutil.InternalError:
+			return nil, err
+		default:
+			return logical.ErrorResponse(err.Error()), nil
+		}
+	}
+
+	if parsedBundle.PrivateKey == nil ||
+		parsedBundle.PrivateKeyType == certutil.UnknownPrivateKey {
+		return logical.ErrorResponse("private key not found in the PEM bundle"), nil
+	}
+	if parsedBundle.Certificate == nil {
+		return logical.ErrorResponse("no certificate found in the PEM bundle"), nil
+	}
+	if match, err := certutil.ComparePublicKeys(parsedBundle.Certificate.PublicKey, parsedBundle.PrivateKey.Public()); err != nil || !match {
+		return logical.ErrorResponse("the private key does not match the certificate"), nil
+	}
+
+	// The responder must be authorized by an issuer to sign OCSP responses
+	// for its certificates (RFC 6960, section 4.2.2.2)
+	issuers, err := b.fetchIssuers(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	cert := parsedBundle.Certificate
+	issued := false
+	for _, issuer := range issuers {
+		issuerBundle, err := issuer.parse()
+		if err != nil {
+			return nil, err
+		}
+		if cert.CheckSignatureFrom(issuerBundle.Certificate) == nil {
+			issued = true
+			break
+		}
+	}
+	if !issued {
+		return logical.ErrorResponse("the responder certificate is not issued by an issuer of this backend"), nil
+	}
+	ocspSigning := false
+	for _, usage := range cert.ExtKeyUsage {
+		if usage == x509.ExtKeyUsageOCSPSigning {
+			ocspSigning = true
+		}
+	}
+	if !ocspSigning {
+		return logical.ErrorResponse("the responder certificate does not have the OCSP signing extended key usage"), nil
+	}
+	if time.Now().After(cert.NotAfter) {
+		return logical.ErrorResponse("the responder certificate has expired"), nil
+	}
+
+	cb, err := parsedBundle.ToCertBundle()
+	if err != nil {
+		return nil, fmt.Errorf("error converting raw values into cert bundle: %s", err)
+	}
+	entry, err := logical.StorageEntryJSON("config/ocsp_bundle", cb)
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, req.Storage.Put(ctx, entry)
+}
+
+func (b *backend) pathOCSPConfigDelete(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	return nil, req.Storage.Delete(ctx, "config/ocsp_bundle")
+}
+
+const pathConfigOCSPHelpSyn = `
+Configure the delegated responder signing OCSP responses.
+`
+
+const pathConfigOCSPHelpDesc = `
+By default, the responses of the "ocsp" endpoint are signed by the
+issuer of the certificates. This sets a delegated responder instead: a
+certificate issued by an issuer with the OCSP signing extended key
+usage, and its private key, which must be a PEM-format, concatenated
+unencrypted secret key and certificate. The responder signs the
+responses for the certificates of its issuer. Deleting the
+configuration reverts to signing with the issuers.
+
+For security reasons, the secret key cannot be retrieved later.
+`
@@ -0,0 +1,360 @@
+package pki
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/fullsailor/pkcs7"
+	"github.com/hashicorp/vault/helper/certutil"
+	"github.com/hashicorp/vault/helper/errutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// EST (RFC 7030) messages are base64-encoded DER: PKCS#10 requests, and
+// certs-only PKCS#7 responses
+const estPKCS7ContentType = "application/pkcs7-mime; smime-type=certs-only"
+
+func pathESTCACerts(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "est/cacerts",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathESTCACertsRead,
+		},
+
+		HelpSynopsis:    pathESTCACertsHelpSyn,
+		HelpDescription: pathESTCACertsHelpDesc,
+	}
+}
+
+func pathESTSimpleEnroll(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "est/simpleenroll",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathESTSimpleEnrollWrite,
+		},
+
+		HelpSynopsis:    pathESTSimpleEnrollHelpSyn,
+		HelpDescription: pathESTSimpleEnrollHelpDesc,
+	}
+}
+
+func pathESTSimpleReenroll(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "est/simplereenroll",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathESTSimpleReenrollWrite,
+		},
+
+		HelpSynopsis:    pathESTSimpleReenrollHelpSyn,
+		HelpDescription: pathESTSimpleReenrollHelpDesc,
+	}
+}
+
+// estRole returns the EST role, or the response to send if EST is not
+// enabled
+func (b *backend) estRole(ctx context.Context, req *logical.Request) (*estConfig, *roleEntry, *logical.Response, error) {
+	config, err := getESTConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if !config.Enabled {
+		return nil, nil, estErrorResponse(http.StatusNotFound, "EST is not enabled"), nil
+	}
+
+	role, err := b.getRole(ctx, req.Storage, config.Role)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if role == nil {
+		return nil, nil, nil, fmt.Errorf("the EST role %q does not exist", config.Role)
+	}
+	return config, role, nil, nil
+}
+
+func (b *backend) pathESTCACertsRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	_, role, resp, err := b.estRole(ctx, req)
+	if resp != nil || err != nil {
+		return resp, err
+	}
+
+	issuer, err := b.resolveIssuer(ctx, req.Storage, role.IssuerRef)
+	switch err.(type) {
+	case nil:
+	case errutil.UserError:
+		return estErrorResponse(http.StatusServiceUnavailable, err.Error()), nil
+	default:
+		return nil, err
+	}
+	parsedBundle, err := issuer.parse()
+	if err != nil {
+		return nil, err
+	}
+
+	chain := append([]byte{}, parsedBundle.CertificateBytes...)
+	for _, caCert := range parsedBundle.CAChain {
+		chain = append(chain, caCert.Bytes...)
+	}
+	return estPKCS7Response(chain)
+}
+
+func (b *backend) pathESTSimpleEnrollWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	return b.estEnroll(ctx, req, false)
+}
+
+func (b *backend) pathESTSimpleReenrollWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	return b.estEnroll(ctx, req, true)
+}
+
+// estEnroll issues a certificate for the CSR of an authenticated client. Only
+// clients with a certificate of this mount may re-enroll, and they must keep
+// its names.
+func (b *backend) estEnroll(ctx context.Context, req *logical.Request, reenroll bool) (*logical.Response, error) {
+	config, role, resp, err := b.estRole(ctx, req)
+	if resp != nil || err != nil {
+		return resp, err
+	}
+
+	if req.Connection == nil || req.Connection.ConnState == nil || len(req.Connection.ConnState.PeerCertificates) == 0 {
+		return estErrorResponse(http.StatusUnauthorized, "a TLS client certificate is required"), nil
+	}
+	clientCerts := req.Connection.ConnState.PeerCertificates
+
+	body, _ := req.Data[logical.HTTPRawBody].([]byte)
+	csr, err := parseESTCSR(body)
+	if err != nil {
+		return estErrorResponse(http.StatusBadRequest, fmt.Sprintf("invalid certificate request: %v", err)), nil
+	}
+
+	if reenroll {
+		resp, err = b.estVerifyReenroll(ctx, req, clientCerts, csr)
+	} else {
+		resp, err = estVerifyEnroll(config, clientCerts)
+	}
+	if resp != nil || err != nil {
+		return resp, err
+	}
+
+	altNames := append(append([]string{}, csr.DNSNames...), csr.EmailAddresses...)
+	signData := &framework.FieldData{
+		Raw: map[string]interface{}{
+			"csr":         string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csr.Raw})),
+			"common_name": csr.Subject.CommonName,
+			"alt_names":   strings.Join(altNames, ","),
+			"ip_sans":     strings.Join(ipStrings(csr.IPAddresses), ","),
+			"role":        config.Role,
+		},
+		Schema: pathSign(b).Fields,
+	}
+
+	signingBundle, err := fetchCAInfo(ctx, b, req, role.IssuerRef)
+	switch err.(type) {
+	case nil:
+	case errutil.UserError:
+		return estErrorResponse(http.StatusServiceUnavailable, err.Error()), nil
+	default:
+		return nil, err
+	}
+	parsedBundle, err := signCert(ctx, b, role, signingBundle, false, false, req, signData)
+	switch err.(type) {
+	case nil:
+	case errutil.UserError:
+		return estErrorResponse(http.StatusBadRequest, err.Error()), nil
+	default:
+		return nil, err
+	}
+
+	if !role.NoStore {
+		serial := certutil.GetHexFormatted(parsedBundle.Certificate.SerialNumber.Bytes(), ":")
+		err = req.Storage.Put(ctx, &logical.StorageEntry{
+			Key:   "certs/" + normalizeSerial(serial),
+			Value: parsedBundle.CertificateBytes,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("unable to store certificate locally: %v", err)
+		}
+	}
+
+	return estPKCS7Response(parsedBundle.CertificateBytes)
+}
+
+// estVerifyEnroll checks that the client certificate was issued by one of
+// the trusted certificates
+func estVerifyEnroll(config *estConfig, clientCerts []*x509.Certificate) (*logical.Response, error) {
+	pool, err := config.trustedPool()
+	if err != nil {
+		return nil, err
+	}
+	if pool == nil {
+		return estErrorResponse(http.StatusForbidden, "enrollment is not allowed without trusted_certificates"), nil
+	}
+
+	if err := verifyESTClient(pool, clientCerts); err != nil {
+		return estErrorResponse(http.StatusForbidden, fmt.Sprintf("the client certificate is not trusted: %v", err)), nil
+	}
+	return nil, nil
+}
+
+// estVerifyReenroll checks that the client certificate is a valid certificate
+// of this mount, whose common name and alternative names are requested again
+func (b *backend) estVerifyReenroll(ctx context.Context, req *logical.Request, clientCerts []*x509.Certificate, csr *x509.CertificateRequest) (*logical.Response, error) {
+	issuers, err := b.fetchIssuers(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	for _, issuer := range issuers {
+		parsedBundle, err := issuer.parse()
+		if err != nil {
+			return nil, err
+		}
+		pool.AddCert(parsedBundle.Certificate)
+	}
+
+	cert := clientCerts[0]
+	if err := verifyESTClient(pool, clientCerts); err != nil {
+		return estErrorResponse(http.StatusForbidden, fmt.Sprintf("the client certificate was not issued by this mount: %v", err)), nil
+	}
+	serial := certutil.GetHexFormatted(cert.SerialNumber.Bytes(), ":")
+	revokedEntry, err := fetchCertBySerial(ctx, req, "revoked/", serial)
+	if err != nil {
+		return nil, err
+	}
+	if revokedEntry != nil {
+		return estErrorResponse(http.StatusForbidden, "the client certificate is revoked"), nil
+	}
+
+	// The common name is also added to the alternative names when issuing, so
+	// the names are compared together
+	csrNames := sortedNames(csr.Subject.CommonName, csr.DNSNames, csr.EmailAddresses, ipStrings(csr.IPAddresses))
+	certNames := sortedNames(cert.Subject.CommonName, cert.DNSNames, cert.EmailAddresses, ipStrings(cert.IPAddresses))
+	if csr.Subject.CommonName != cert.Subject.CommonName || strings.Join(csrNames, ",") != strings.Join(certNames, ",") {
+		return estErrorResponse(http.StatusBadRequest, "the common name and alternative names of the certificate request must be those of the client certificate"), nil
+	}
+	return nil, nil
+}
+
+// verifyESTClient verifies the client certificate against the pool, with the
+// other certificates sent by the client as intermediates
+func verifyESTClient(pool *x509.CertPool, clientCerts []*x509.Certificate) error {
+	intermediates := x509.NewCertPool()
+	for _, cert := range clientCerts[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := clientCerts[0].Verify(x509.VerifyOptions{
+		Roots:         pool,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	return err
+}
+
+// parseESTCSR parses a base64-encoded DER certificate request, which may
+// also be PEM-encoded
+func parseESTCSR(body []byte) (*x509.CertificateRequest, error) {
+	var der []byte
+	if block, _ := pem.Decode(body); block != nil {
+		der = block.Bytes
+	} else {
+		var err error
+		der, err = base64.StdEncoding.DecodeString(strings.Join(strings.Fields(string(body)), ""))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	csr, err := x509.ParseCertificateRequest(der)
+	if err != nil {
+		return nil, err
+	}
+	if err := csr.CheckSignature(); err != nil {
+		return nil, err
+	}
+	return csr, nil
+}
+
+// sortedNames returns the distinct names of the common name and lists, sorted
+func sortedNames(commonName string, lists ...[]string) []string {
+	set := map[string]bool{commonName: true}
+	for _, list := range lists {
+		for _, name := range list {
+			set[name] = true
+		}
+	}
+	var result []string
+	for name := range set {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// estPKCS7Response returns the certificates, concatenated DER, as a
+// certs-only PKCS#7
+func estPKCS7Response(certs []byte) (*logical.Response, error) {
+	p7, err := pkcs7.DegenerateCertificate(certs)
+	if err != nil {
+		return nil, err
+	}
+	return &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPContentType: estPKCS7ContentType,
+			logical.HTTPRawBody:     []byte(base64.StdEncoding.EncodeToString(p7)),
+			logical.HTTPStatusCode:  http.StatusOK,
+		},
+	}, nil
+}
+
+func estErrorResponse(status int, message string) *logical.Response {
+	return &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPContentType: "text/plain",
+			logical.HTTPRawBody:     []byte(message),
+			logical.HTTPStatusCode:  status,
+		},
+	}
+}
+
+const pathESTCACertsHelpSyn = `
+Fetch the CA certificates with EST.
+`
+
+const pathESTCACertsHelpDesc = `
+This endpoint implements the EST (RFC 7030) cacerts operation. It returns the
+certificate of the issuer of the EST role, along with its chain, as a
+base64-encoded certs-only PKCS#7.
+`
+
+const pathESTSimpleEnrollHelpSyn = `
+Enroll a certificate with EST.
+`
+
+const pathESTSimpleEnrollHelpDesc = `
+This endpoint implements the EST (RFC 7030) simpleenroll operation. The
+client POSTs a base64-encoded PKCS#10 certificate request, with the
+"application/pkcs10" content type, and authenticates with a TLS client
+certificate issued by one of the trusted certificates of "config/est". The
+certificate is issued with the EST role and returned as a base64-encoded
+certs-only PKCS#7.
+`
+
+const pathESTSimpleReenrollHelpSyn = `
+Renew a certificate with EST.
+`
+
+const pathESTSimpleReenrollHelpDesc = `
+This endpoint implements the EST (RFC 7030) simplereenroll operation. It is
+like "est/simpleenroll", except that the client authenticates with its
+current certificate, which must have been issued by this mount and not be
+revoked, and the certificate request must keep its common name and
+alternative names.
+`
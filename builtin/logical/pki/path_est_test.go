@@ -0,0 +1,223 @@
+package pki
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/fullsailor/pkcs7"
+	"github.com/hashicorp/vault/helper/certutil"
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestPki_EST(t *testing.T) {
+	b, storage := createBackendWithStorage(t)
+
+	request := func(op logical.Operation, path string, data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Operation: op,
+			Path:      path,
+			Storage:   storage,
+			Data:      data,
+		})
+	}
+	doReq := func(op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		resp, err := request(op, path, data)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: path: %s, err: %v, resp: %#v", path, err, resp)
+		}
+		return resp
+	}
+
+	// estReq sends an EST request, authenticated with the client
+	// certificates, and returns the HTTP status and body of the response
+	estReq := func(op logical.Operation, path string, body []byte, clientCerts ...*x509.Certificate) (int, []byte) {
+		req := &logical.Request{
+			Operation: op,
+			Path:      path,
+			Storage:   storage,
+			Data:      map[string]interface{}{logical.HTTPRawBody: body},
+		}
+		if len(clientCerts) > 0 {
+			req.Connection = &logical.Connection{
+				ConnState: &tls.ConnectionState{PeerCertificates: clientCerts},
+			}
+		}
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil {
+			t.Fatalf("bad: path: %s, err: %v", path, err)
+		}
+		return resp.Data[logical.HTTPStatusCode].(int), resp.Data[logical.HTTPRawBody].([]byte)
+	}
+	parsePKCS7 := func(body []byte) []*x509.Certificate {
+		der, err := base64.StdEncoding.DecodeString(string(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		p7, err := pkcs7.Parse(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p7.Certificates
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	createCSR := func(commonName string, dnsNames ...string) []byte {
+		der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+			Subject:  pkix.Name{CommonName: commonName},
+			DNSNames: dnsNames,
+		}, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return []byte(base64.StdEncoding.EncodeToString(der))
+	}
+
+	// The bootstrap certificate of the device, from another CA
+	bootstrapTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Manufacturer CA"},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	bootstrapCADER, err := x509.CreateCertificate(rand.Reader, bootstrapTemplate, bootstrapTemplate, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bootstrapCA, err := x509.ParseCertificate(bootstrapCADER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bootstrapDER, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "device-1"},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, bootstrapCA, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bootstrapCert, err := x509.ParseCertificate(bootstrapDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp := doReq(logical.UpdateOperation, "root/generate/internal", map[string]interface{}{
+		"common_name": "Root CA",
+		"ttl":         "8760h",
+	})
+	caCert, err := certutil.ParsePEMBundle(resp.Data["certificate"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doReq(logical.UpdateOperation, "roles/est", map[string]interface{}{
+		"allowed_domains":  "example.com",
+		"allow_subdomains": true,
+		"key_type":         "ec",
+		"key_bits":         256,
+		"ttl":              "1h",
+	})
+
+	// Nothing is served until EST is enabled
+	if status, _ := estReq(logical.ReadOperation, "est/cacerts", nil); status != 404 {
+		t.Fatalf("bad: status: %d", status)
+	}
+	for _, data := range []map[string]interface{}{
+		{"enabled": true},
+		{"enabled": true, "role": "missing"},
+		{"trusted_certificates": "bad"},
+	} {
+		if resp, err := request(logical.UpdateOperation, "config/est", data); err != nil || resp == nil || !resp.IsError() {
+			t.Fatalf("expected an error: data: %v, err: %v, resp: %#v", data, err, resp)
+		}
+	}
+	resp = doReq(logical.UpdateOperation, "config/est", map[string]interface{}{
+		"enabled": true,
+		"role":    "est",
+	})
+	if resp == nil || len(resp.Warnings) != 1 {
+		t.Fatalf("expected a warning: %#v", resp)
+	}
+
+	status, body := estReq(logical.ReadOperation, "est/cacerts", nil)
+	if status != 200 {
+		t.Fatalf("bad: status: %d, body: %s", status, body)
+	}
+	if certs := parsePKCS7(body); len(certs) != 1 || !certs[0].Equal(caCert.Certificate) {
+		t.Fatalf("bad: %#v", certs)
+	}
+
+	// Enrolling requires a trusted client certificate
+	csr := createCSR("device-1.example.com", "device-1.example.com")
+	if status, _ := estReq(logical.UpdateOperation, "est/simpleenroll", csr); status != 401 {
+		t.Fatalf("bad: status: %d", status)
+	}
+	if status, _ := estReq(logical.UpdateOperation, "est/simpleenroll", csr, bootstrapCert); status != 403 {
+		t.Fatalf("bad: status: %d", status)
+	}
+	doReq(logical.UpdateOperation, "config/est", map[string]interface{}{
+		"trusted_certificates": string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: bootstrapCADER})),
+	})
+	resp = doReq(logical.ReadOperation, "config/est", nil)
+	if resp.Data["enabled"] != true || resp.Data["role"] != "est" || resp.Data["trusted_certificates"] == "" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	if status, _ := estReq(logical.UpdateOperation, "est/simpleenroll", []byte("bad"), bootstrapCert); status != 400 {
+		t.Fatalf("bad: status: %d", status)
+	}
+	if status, _ := estReq(logical.UpdateOperation, "est/simpleenroll", createCSR("device-1.example.org"), bootstrapCert); status != 400 {
+		t.Fatalf("bad: status: %d", status)
+	}
+	status, body = estReq(logical.UpdateOperation, "est/simpleenroll", csr, bootstrapCert)
+	if status != 200 {
+		t.Fatalf("bad: status: %d, body: %s", status, body)
+	}
+	certs := parsePKCS7(body)
+	if len(certs) != 1 || certs[0].Subject.CommonName != "device-1.example.com" {
+		t.Fatalf("bad: %#v", certs)
+	}
+	if err := certs[0].CheckSignatureFrom(caCert.Certificate); err != nil {
+		t.Fatal(err)
+	}
+	deviceCert := certs[0]
+	serial := certutil.GetHexFormatted(deviceCert.SerialNumber.Bytes(), ":")
+	doReq(logical.ReadOperation, "cert/"+serial, nil)
+
+	// Re-enrolling requires a certificate of the mount, with the same names
+	if status, _ := estReq(logical.UpdateOperation, "est/simplereenroll", csr, bootstrapCert); status != 403 {
+		t.Fatalf("bad: status: %d", status)
+	}
+	if status, _ := estReq(logical.UpdateOperation, "est/simplereenroll", createCSR("device-2.example.com", "device-2.example.com"), deviceCert); status != 400 {
+		t.Fatalf("bad: status: %d", status)
+	}
+	status, body = estReq(logical.UpdateOperation, "est/simplereenroll", csr, deviceCert)
+	if status != 200 {
+		t.Fatalf("bad: status: %d, body: %s", status, body)
+	}
+	if certs := parsePKCS7(body); len(certs) != 1 || certs[0].Subject.CommonName != "device-1.example.com" ||
+		certs[0].SerialNumber.Cmp(deviceCert.SerialNumber) == 0 {
+		t.Fatalf("bad: %#v", certs)
+	}
+
+	doReq(logical.UpdateOperation, "revoke", map[string]interface{}{"serial_number": serial})
+	if status, _ := estReq(logical.UpdateOperation, "est/simplereenroll", csr, deviceCert); status != 403 {
+		t.Fatalf("bad: status: %d", status)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/pem"
 	"fmt"
+	"strings"
 
 	"github.com/hashicorp/vault/helper/errutil"
 	"github.com/hashicorp/vault/logical"
@@ -41,7 +42,7 @@ func pathFetchCAChain(b *backend) *framework.Path {
 // Returns the CRL in raw format
 func pathFetchCRL(b *backend) *framework.Path {
 	return &framework.Path{
-		Pattern: `crl(/pem)?`,
+		Pattern: `crl(/delta)?(/pem)?`,
 
 		Callbacks: map[logical.Operation]framework.OperationFunc{
 			logical.ReadOperation: b.pathFetchRead,
@@ -88,6 +89,46 @@ func pathFetchCRLViaCertPath(b *backend) *framework.Path {
 	}
 }
 
+// Returns the certificate of an issuer in raw format
+func pathFetchIssuerCA(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: `ca/issuer/` + framework.GenericNameRegex("issuer_ref") + `(/pem)?`,
+		Fields: map[string]*framework.FieldSchema{
+			"issuer_ref": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: `ID or name of the issuer`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathFetchIssuerRead,
+		},
+
+		HelpSynopsis:    pathFetchHelpSyn,
+		HelpDescription: pathFetchHelpDesc,
+	}
+}
+
+// Returns the CRL of an issuer in raw format
+func pathFetchIssuerCRL(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: `crl/issuer/` + framework.GenericNameRegex("issuer_ref") + `(/delta)?(/pem)?`,
+		Fields: map[string]*framework.FieldSchema{
+			"issuer_ref": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: `ID or name of the issuer`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathFetchIssuerRead,
+		},
+
+		HelpSynopsis:    pathFetchHelpSyn,
+		HelpDescription: pathFetchHelpDesc,
+	}
+}
+
 // This returns the list of serial numbers for certs
 func pathFetchListCerts(b *backend) *framework.Path {
 	return &framework.Path{
@@ -103,7 +144,7 @@ func pathFetchListCerts(b *backend) *framework.Path {
 }
 
 func (b *backend) pathFetchCertList(ctx context.Context, req *logical.Request, data *framework.FieldData) (response *logical.Response, retErr error) {
-	entries, err := req.Storage.List(ctx, "certs/")
+	entries, err := logical.ListPage(ctx, req.Storage, "certs/", data.Get("after").(string), data.Get("limit").(int))
 	if err != nil {
 		return nil, err
 	}
@@ -117,6 +158,7 @@ func (b *backend) pathFetchRead(ctx context.Context, req *logical.Request, data
 	var funcErr error
 	var certificate []byte
 	var revocationTime int64
+	var metadata map[string]string
 	response = &logical.Response{
 		Data: map[string]interface{}{},
 	}
@@ -144,6 +186,12 @@ func (b *backend) pathFetchRead(ctx context.Context, req *logical.Request, data
 		if req.Path == "crl/pem" {
 			pemType = "X509 CRL"
 		}
+	case req.Path == "crl/delta" || req.Path == "crl/delta/pem":
+		serial = "delta_crl"
+		contentType = "application/pkix-crl"
+		if req.Path == "crl/delta/pem" {
+			pemType = "X509 CRL"
+		}
 	case req.Path == "cert/crl":
 		serial = "crl"
 		pemType = "X509 CRL"
@@ -157,7 +205,11 @@ func (b *backend) pathFetchRead(ctx context.Context, req *logical.Request, data
 	}
 
 	if serial == "ca_chain" {
-		caInfo, err := fetchCAInfo(ctx, req)
+		var caInfo *caInfoBundle
+		issuer, err := b.resolveIssuer(ctx, req.Storage, defaultIssuerRef)
+		if err == nil {
+			caInfo, err = issuerCAInfo(ctx, req, issuer)
+		}
 		switch err.(type) {
 		case errutil.UserError:
 			response = logical.ErrorResponse(err.Error())
@@ -224,6 +276,10 @@ func (b *backend) pathFetchRead(ctx context.Context, req *logical.Request, data
 		revocationTime = revInfo.RevocationTime
 	}
 
+	if len(contentType) == 0 {
+		metadata, retErr = fetchCertMetadata(ctx, req.Storage, serial)
+	}
+
 reply:
 	switch {
 	case len(contentType) != 0:
@@ -253,11 +309,72 @@ reply:
 	default:
 		response.Data["certificate"] = string(certificate)
 		response.Data["revocation_time"] = revocationTime
+		if metadata != nil {
+			response.Data["metadata"] = metadata
+		}
 	}
 
 	return
 }
 
+func (b *backend) pathFetchIssuerRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	issuer, err := b.resolveIssuer(ctx, req.Storage, data.Get("issuer_ref").(string))
+	switch err.(type) {
+	case nil:
+	case errutil.UserError:
+		return logical.ErrorResponse(err.Error()), nil
+	default:
+		return nil, err
+	}
+
+	var contentType, pemType string
+	var raw []byte
+	switch {
+	case strings.HasPrefix(req.Path, "ca/"):
+		contentType = "application/pkix-cert"
+		pemType = "CERTIFICATE"
+		parsedBundle, err := issuer.parse()
+		if err != nil {
+			return nil, err
+		}
+		raw = parsedBundle.CertificateBytes
+
+	default:
+		contentType = "application/pkix-crl"
+		pemType = "X509 CRL"
+		key := "crls/" + issuer.ID
+		if strings.HasPrefix(strings.TrimPrefix(req.Path, "crl/issuer/"+data.Get("issuer_ref").(string)), "/delta") {
+			key = "delta_crls/" + issuer.ID
+		}
+		entry, err := req.Storage.Get(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		if entry != nil {
+			raw = entry.Value
+		}
+	}
+
+	if strings.HasSuffix(req.Path, "/pem") && len(raw) > 0 {
+		raw = pem.EncodeToMemory(&pem.Block{
+			Type:  pemType,
+			Bytes: raw,
+		})
+	}
+
+	response := &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPContentType: contentType,
+			logical.HTTPRawBody:     raw,
+			logical.HTTPStatusCode:  200,
+		},
+	}
+	if len(raw) == 0 {
+		response.Data[logical.HTTPStatusCode] = 204
+	}
+	return response, nil
+}
+
 const pathFetchHelpSyn = `
 Fetch a CA, CRL, CA Chain, or non-revoked certificate.
 `
@@ -265,7 +382,13 @@ Fetch a CA, CRL, CA Chain, or non-revoked certificate.
 const pathFetchHelpDesc = `
 This allows certificates to be fetched. If using the fetch/ prefix any non-revoked certificate can be fetched.
 
+Reading "cert/<serial>" also returns the metadata given when the certificate was issued, if any.
+
 Using "ca" or "crl" as the value fetches the appropriate information in DER encoding. Add "/pem" to either to get PEM encoding.
 
 Using "ca_chain" as the value fetches the certificate authority trust chain in PEM encoding.
+
+These return the default issuer of the mount; "ca/issuer/<issuer_ref>" and "crl/issuer/<issuer_ref>" fetch the certificate and the CRL of a given issuer, referenced by ID or name, in the same way.
+
+If delta CRLs are enabled in "config/crl", "crl/delta" and "crl/issuer/<issuer_ref>/delta" fetch them in the same way.
 `
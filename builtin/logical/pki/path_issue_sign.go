@@ -147,6 +147,7 @@ func (b *backend) pathSignVerbatim(ctx context.Context, req *logical.Request, da
 			entry.MaxTTL = role.MaxTTL
 		}
 		entry.NoStore = role.NoStore
+		entry.IssuerRef = role.IssuerRef
 	}
 
 	*entry.GenerateLease = false
@@ -164,8 +165,20 @@ func (b *backend) pathIssueSignCert(ctx context.Context, req *logical.Request, d
 			`the "format" path parameter must be "pem", "der", or "pem_bundle"`), nil
 	}
 
+	metadataRaw, ok, err := data.GetOkErr("metadata")
+	if err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("failed to parse metadata: %v", err)), nil
+	}
+	var metadata map[string]string
+	if ok {
+		metadata = metadataRaw.(map[string]string)
+	}
+	if len(metadata) > 0 && role.NoStore {
+		return logical.ErrorResponse("metadata cannot be stored as the role has no_store set"), nil
+	}
+
 	var caErr error
-	signingBundle, caErr := fetchCAInfo(ctx, req)
+	signingBundle, caErr := fetchCAInfo(ctx, b, req, issuerRefFromRequest(data, role))
 	switch caErr.(type) {
 	case errutil.UserError:
 		return nil, errutil.UserError{Err: fmt.Sprintf(
@@ -176,9 +189,8 @@ func (b *backend) pathIssueSignCert(ctx context.Context, req *logical.Request, d
 	}
 
 	var parsedBundle *certutil.ParsedCertBundle
-	var err error
 	if useCSR {
-		parsedBundle, err = signCert(b, role, signingBundle, false, useCSRValues, req, data)
+		parsedBundle, err = signCert(ctx, b, role, signingBundle, false, useCSRValues, req, data)
 	} else {
 		parsedBundle, err = generateCert(ctx, b, role, signingBundle, false, req, data)
 	}
@@ -280,6 +292,11 @@ func (b *backend) pathIssueSignCert(ctx context.Context, req *logical.Request, d
 		if err != nil {
 			return nil, fmt.Errorf("unable to store certificate locally: %v", err)
 		}
+		if len(metadata) > 0 {
+			if err := putCertMetadata(ctx, req.Storage, cb.SerialNumber, metadata); err != nil {
+				return nil, fmt.Errorf("unable to store certificate metadata: %v", err)
+			}
+		}
 	}
 
 	if useCSR {
@@ -291,6 +308,12 @@ func (b *backend) pathIssueSignCert(ctx context.Context, req *logical.Request, d
 		}
 	}
 
+	if err := b.System().SendEvent(ctx, logical.EventTypePKICertIssued, req.Path, map[string]string{
+		"serial_number": cb.SerialNumber,
+	}); err != nil {
+		b.Logger().Warn("pki: failed to publish event", "event_type", logical.EventTypePKICertIssued, "error", err)
+	}
+
 	return resp, nil
 }
 
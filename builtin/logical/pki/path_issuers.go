@@ -0,0 +1,677 @@
+package pki
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/x509"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/helper/certutil"
+	"github.com/hashicorp/vault/helper/errutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+func pathListIssuers(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "issuers/?$",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ListOperation: b.pathIssuersList,
+		},
+
+		HelpSynopsis:    pathListIssuersHelpSyn,
+		HelpDescription: pathListIssuersHelpDesc,
+	}
+}
+
+func pathImportIssuers(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "issuers/import",
+		Fields: map[string]*framework.FieldSchema{
+			"pem_bundle": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `PEM-format CA certificate, optionally
+followed by the certificates of its chain, and
+optionally an unencrypted secret key.`,
+			},
+			"issuer_name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: `Name of the first issuer of the bundle.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathIssuersImport,
+		},
+
+		HelpSynopsis:    pathImportIssuersHelpSyn,
+		HelpDescription: pathImportIssuersHelpDesc,
+	}
+}
+
+func pathGenerateRootIssuer(b *backend) *framework.Path {
+	ret := &framework.Path{
+		Pattern: "issuers/generate/root/" + framework.GenericNameRegex("exported"),
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathIssuersGenerateRoot,
+		},
+
+		HelpSynopsis:    pathGenerateRootIssuerHelpSyn,
+		HelpDescription: pathGenerateRootIssuerHelpDesc,
+	}
+
+	ret.Fields = addCACommonFields(map[string]*framework.FieldSchema{})
+	ret.Fields = addCAKeyGenerationFields(ret.Fields)
+	ret.Fields = addCAIssueFields(ret.Fields)
+
+	ret.Fields["issuer_name"] = &framework.FieldSchema{
+		Type:        framework.TypeString,
+		Description: `Name of the new issuer.`,
+	}
+
+	return ret
+}
+
+func pathIssuer(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "issuer/" + framework.GenericNameRegex("issuer_ref"),
+		Fields: map[string]*framework.FieldSchema{
+			"issuer_ref": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: `ID or name of the issuer, or "default".`,
+			},
+			"issuer_name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: `Name of the issuer.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathIssuerRead,
+			logical.UpdateOperation: b.pathIssuerWrite,
+			logical.DeleteOperation: b.pathIssuerDelete,
+		},
+
+		HelpSynopsis:    pathIssuerHelpSyn,
+		HelpDescription: pathIssuerHelpDesc,
+	}
+}
+
+func pathConfigIssuers(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config/issuers",
+		Fields: map[string]*framework.FieldSchema{
+			"default": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: `ID or name of the default issuer.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathIssuersConfigRead,
+			logical.UpdateOperation: b.pathIssuersConfigWrite,
+		},
+
+		HelpSynopsis:    pathConfigIssuersHelpSyn,
+		HelpDescription: pathConfigIssuersHelpDesc,
+	}
+}
+
+func pathIssuerCrossSign(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "issuer/" + framework.GenericNameRegex("issuer_ref") + "/cross-sign",
+		Fields: map[string]*framework.FieldSchema{
+			"issuer_ref": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: `ID or name of the signing issuer, or "default".`,
+			},
+			"target_issuer": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: `ID or name of the issuer to cross-sign.`,
+			},
+			"issuer_name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: `Name of the cross-signed issuer.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathIssuerCrossSignWrite,
+		},
+
+		HelpSynopsis:    pathIssuerCrossSignHelpSyn,
+		HelpDescription: pathIssuerCrossSignHelpDesc,
+	}
+}
+
+func pathIssuerIssue(b *backend) *framework.Path {
+	return issuerPath(pathIssue(b))
+}
+
+func pathIssuerSign(b *backend) *framework.Path {
+	return issuerPath(pathSign(b))
+}
+
+func pathIssuerSignVerbatim(b *backend) *framework.Path {
+	return issuerPath(pathSignVerbatim(b))
+}
+
+func pathIssuerRevoke(b *backend) *framework.Path {
+	ret := issuerPath(pathRevoke(b))
+	ret.Callbacks[logical.UpdateOperation] = b.pathIssuerRevokeWrite
+	return ret
+}
+
+// issuerPath moves a path under "issuer/<issuer_ref>/", to use the given
+// issuer instead of the one of the role
+func issuerPath(p *framework.Path) *framework.Path {
+	p.Pattern = "issuer/" + framework.GenericNameRegex("issuer_ref") + "/" + p.Pattern
+	p.Fields["issuer_ref"] = &framework.FieldSchema{
+		Type:        framework.TypeString,
+		Description: `ID or name of the issuer, or "default".`,
+	}
+	return p
+}
+
+// issuerRefFromRequest returns the issuer selected by the path, or else by
+// the role
+func issuerRefFromRequest(data *framework.FieldData, role *roleEntry) string {
+	if _, ok := data.Schema["issuer_ref"]; ok {
+		return data.Get("issuer_ref").(string)
+	}
+	if role != nil && role.IssuerRef != "" {
+		return role.IssuerRef
+	}
+	return defaultIssuerRef
+}
+
+func (b *backend) pathIssuersList(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	issuers, err := b.fetchIssuers(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	config, err := getIssuersConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	keyInfo := map[string]interface{}{}
+	for _, issuer := range issuers {
+		keys = append(keys, issuer.ID)
+		keyInfo[issuer.ID] = map[string]interface{}{
+			"issuer_name":   issuer.Name,
+			"serial_number": issuer.Bundle.SerialNumber,
+			"is_default":    issuer.ID == config.Default || issuer.ID == legacyIssuerID,
+		}
+	}
+
+	return logical.ListResponseWithInfo(keys, keyInfo), nil
+}
+
+func (b *backend) pathIssuersImport(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	parsedBundle, err := certutil.ParsePEMBundle(data.Get("pem_bundle").(string))
+	if err != nil {
+		switch err.(type) {
+		case errutil.InternalError:
+			return nil, err
+		default:
+			return logical.ErrorResponse(err.Error()), nil
+		}
+	}
+	if parsedBundle.Certificate == nil {
+		return logical.ErrorResponse("no certificate found in the PEM bundle"), nil
+	}
+
+	// Each certificate of the chain becomes an issuer, with the rest of the
+	// chain as its own
+	bundles := []*certutil.ParsedCertBundle{parsedBundle}
+	for i, caCert := range parsedBundle.CAChain {
+		bundles = append(bundles, &certutil.ParsedCertBundle{
+			Certificate:      caCert.Certificate,
+			CertificateBytes: caCert.Bytes,
+			CAChain:          parsedBundle.CAChain[i+1:],
+		})
+	}
+
+	var imported, existing []string
+	for i, bundle := range bundles {
+		name := ""
+		if i == 0 {
+			name = data.Get("issuer_name").(string)
+		}
+		issuer, found, err := b.importCAIssuer(ctx, req.Storage, bundle, name, false, false)
+		switch err.(type) {
+		case nil:
+		case errutil.UserError:
+			return logical.ErrorResponse(err.Error()), nil
+		default:
+			return nil, err
+		}
+		if found {
+			existing = append(existing, issuer.ID)
+		} else {
+			imported = append(imported, issuer.ID)
+		}
+	}
+
+	// The mount has no CRL while its default issuer has no key
+	switch err := buildCRL(ctx, b, req); err.(type) {
+	case nil, errutil.UserError:
+	default:
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"imported_issuers": imported,
+			"existing_issuers": existing,
+		},
+	}, nil
+}
+
+func (b *backend) pathIssuersGenerateRoot(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	return b.generateRoot(ctx, req, data, data.Get("issuer_name").(string), false)
+}
+
+func (b *backend) pathIssuerRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	issuer, err := b.resolveIssuer(ctx, req.Storage, data.Get("issuer_ref").(string))
+	switch err.(type) {
+	case nil:
+	case errutil.UserError:
+		return logical.ErrorResponse(err.Error()), nil
+	default:
+		return nil, err
+	}
+
+	parsedBundle, err := issuer.parse()
+	if err != nil {
+		return nil, err
+	}
+	config, err := getIssuersConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	caChain := issuer.Bundle.CAChain
+	if caChain == nil {
+		caChain = []string{}
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"issuer_id":       issuer.ID,
+			"issuer_name":     issuer.Name,
+			"certificate":     issuer.Bundle.Certificate,
+			"ca_chain":        caChain,
+			"serial_number":   issuer.Bundle.SerialNumber,
+			"expiration":      parsedBundle.Certificate.NotAfter.Unix(),
+			"has_private_key": issuer.hasKey(),
+			"is_default":      issuer.ID == config.Default || issuer.ID == legacyIssuerID,
+		},
+	}, nil
+}
+
+func (b *backend) pathIssuerWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	if err := b.lockIssuers(ctx, req.Storage); err != nil {
+		return nil, err
+	}
+	defer b.issuersLock.Unlock()
+
+	issuer, err := b.resolveIssuer(ctx, req.Storage, data.Get("issuer_ref").(string))
+	switch err.(type) {
+	case nil:
+	case errutil.UserError:
+		return logical.ErrorResponse(err.Error()), nil
+	default:
+		return nil, err
+	}
+
+	name := data.Get("issuer_name").(string)
+	if name != "" {
+		issuers, err := listIssuers(ctx, req.Storage)
+		if err != nil {
+			return nil, err
+		}
+		if err := validateIssuerName(issuers, name, issuer.ID); err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+	}
+	issuer.Name = name
+
+	return nil, putIssuer(ctx, req.Storage, issuer)
+}
+
+func (b *backend) pathIssuerDelete(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	if err := b.lockIssuers(ctx, req.Storage); err != nil {
+		return nil, err
+	}
+	defer b.issuersLock.Unlock()
+
+	issuer, err := b.resolveIssuer(ctx, req.Storage, data.Get("issuer_ref").(string))
+	switch err.(type) {
+	case nil:
+	case errutil.UserError:
+		return nil, nil
+	default:
+		return nil, err
+	}
+
+	if err := req.Storage.Delete(ctx, "issuers/"+issuer.ID); err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Delete(ctx, "crls/"+issuer.ID); err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Delete(ctx, "delta_crls/"+issuer.ID); err != nil {
+		return nil, err
+	}
+
+	// The mount is left without a default issuer until one is set
+	config, err := getIssuersConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config.Default == issuer.ID {
+		if err := setDefaultIssuer(ctx, req.Storage, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, nil
+}
+
+func (b *backend) pathIssuersConfigRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	if _, err := b.migrateLegacyCA(ctx, req.Storage); err != nil {
+		return nil, err
+	}
+	config, err := getIssuersConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"default": config.Default,
+		},
+	}, nil
+}
+
+func (b *backend) pathIssuersConfigWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	ref := data.Get("default").(string)
+	if ref == "" {
+		return logical.ErrorResponse("the default issuer must be provided"), nil
+	}
+
+	if err := b.lockIssuers(ctx, req.Storage); err != nil {
+		return nil, err
+	}
+	issuer, err := b.resolveIssuer(ctx, req.Storage, ref)
+	if err == nil {
+		err = setDefaultIssuer(ctx, req.Storage, issuer)
+	}
+	b.issuersLock.Unlock()
+	switch err.(type) {
+	case nil:
+		b.sendCARotatedEvent(ctx, issuer)
+	case errutil.UserError:
+		return logical.ErrorResponse(err.Error()), nil
+	default:
+		return nil, err
+	}
+
+	// The CRL of the mount is signed by the default issuer
+	switch err := buildCRL(ctx, b, req); err.(type) {
+	case nil, errutil.UserError:
+	default:
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (b *backend) pathIssuerCrossSignWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	signingBundle, caErr := fetchCAInfo(ctx, b, req, data.Get("issuer_ref").(string))
+	switch caErr.(type) {
+	case errutil.UserError:
+		return logical.ErrorResponse(caErr.Error()), nil
+	case errutil.InternalError:
+		return nil, caErr
+	}
+
+	targetRef := data.Get("target_issuer").(string)
+	if targetRef == "" {
+		return logical.ErrorResponse("the issuer to cross-sign must be provided in the \"target_issuer\" parameter"), nil
+	}
+	target, err := b.resolveIssuer(ctx, req.Storage, targetRef)
+	switch err.(type) {
+	case nil:
+	case errutil.UserError:
+		return logical.ErrorResponse(err.Error()), nil
+	default:
+		return nil, err
+	}
+	targetBundle, err := target.parse()
+	if err != nil {
+		return nil, err
+	}
+
+	targetCert := targetBundle.Certificate
+	if bytes.Equal(targetCert.RawSubjectPublicKeyInfo, signingBundle.Certificate.RawSubjectPublicKeyInfo) {
+		return logical.ErrorResponse("an issuer cannot cross-sign itself"), nil
+	}
+
+	// The cross-signed certificate has the subject and key of the target,
+	// so that certificates it issued chain to the signing issuer as well
+	notAfter := targetCert.NotAfter
+	if signingBundle.Certificate.NotAfter.Before(notAfter) {
+		notAfter = signingBundle.Certificate.NotAfter
+	}
+	serialNumber, err := certutil.GenerateSerialNumber()
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber:                serialNumber,
+		Subject:                     targetCert.Subject,
+		NotBefore:                   time.Now().Add(-30 * time.Second),
+		NotAfter:                    notAfter,
+		KeyUsage:                    targetCert.KeyUsage,
+		ExtKeyUsage:                 targetCert.ExtKeyUsage,
+		BasicConstraintsValid:       true,
+		IsCA:                        true,
+		MaxPathLen:                  targetCert.MaxPathLen,
+		MaxPathLenZero:              targetCert.MaxPathLenZero,
+		SubjectKeyId:                targetCert.SubjectKeyId,
+		PermittedDNSDomains:         targetCert.PermittedDNSDomains,
+		PermittedDNSDomainsCritical: targetCert.PermittedDNSDomainsCritical,
+		IssuingCertificateURL:       signingBundle.URLs.IssuingCertificates,
+		CRLDistributionPoints:       signingBundle.URLs.CRLDistributionPoints,
+		OCSPServer:                  signingBundle.URLs.OCSPServers,
+	}
+
+	certBytes, err := x509.CreateCertificate(rand.Reader, template, signingBundle.Certificate, targetCert.PublicKey, signingBundle.PrivateKey)
+	if err != nil {
+		return nil, errwrap.Wrapf("error cross-signing the issuer: {{err}}", err)
+	}
+	cert, err := x509.ParseCertificate(certBytes)
+	if err != nil {
+		return nil, errwrap.Wrapf("error parsing the cross-signed certificate: {{err}}", err)
+	}
+
+	// The new issuer uses the key of the target, if the mount holds it
+	parsedBundle := &certutil.ParsedCertBundle{
+		Certificate:      cert,
+		CertificateBytes: certBytes,
+		CAChain: append([]*certutil.CertBlock{&certutil.CertBlock{
+			Certificate: signingBundle.Certificate,
+			Bytes:       signingBundle.CertificateBytes,
+		}}, signingBundle.CAChain...),
+	}
+	issuer, _, err := b.importCAIssuer(ctx, req.Storage, parsedBundle, data.Get("issuer_name").(string), false, false)
+	switch err.(type) {
+	case nil:
+	case errutil.UserError:
+		return logical.ErrorResponse(err.Error()), nil
+	default:
+		return nil, err
+	}
+
+	err = req.Storage.Put(ctx, &logical.StorageEntry{
+		Key:   "certs/" + normalizeSerial(issuer.Bundle.SerialNumber),
+		Value: certBytes,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to store certificate locally: %v", err)
+	}
+
+	switch err := buildCRL(ctx, b, req); err.(type) {
+	case nil, errutil.UserError:
+	default:
+		return nil, err
+	}
+
+	signingCB, err := signingBundle.ToCertBundle()
+	if err != nil {
+		return nil, fmt.Errorf("error converting raw signing bundle to cert bundle: %s", err)
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"issuer_id":     issuer.ID,
+			"certificate":   issuer.Bundle.Certificate,
+			"issuing_ca":    signingCB.Certificate,
+			"ca_chain":      issuer.Bundle.CAChain,
+			"serial_number": issuer.Bundle.SerialNumber,
+			"expiration":    cert.NotAfter.Unix(),
+		},
+	}, nil
+}
+
+// pathIssuerRevokeWrite revokes a certificate after checking that it was
+// issued by the issuer
+func (b *backend) pathIssuerRevokeWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	serial := data.Get("serial_number").(string)
+	if len(serial) == 0 {
+		return logical.ErrorResponse("The serial number must be provided"), nil
+	}
+
+	issuer, err := b.resolveIssuer(ctx, req.Storage, data.Get("issuer_ref").(string))
+	switch err.(type) {
+	case nil:
+	case errutil.UserError:
+		return logical.ErrorResponse(err.Error()), nil
+	default:
+		return nil, err
+	}
+	issuerBundle, err := issuer.parse()
+	if err != nil {
+		return nil, err
+	}
+
+	var certBytes []byte
+	certEntry, err := fetchCertBySerial(ctx, req, "certs/", serial)
+	if err != nil {
+		return nil, err
+	}
+	if certEntry != nil {
+		certBytes = certEntry.Value
+	} else {
+		revokedEntry, err := fetchCertBySerial(ctx, req, "revoked/", serial)
+		if err != nil {
+			return nil, err
+		}
+		if revokedEntry == nil {
+			return logical.ErrorResponse(fmt.Sprintf("certificate with serial %s not found", serial)), nil
+		}
+		var revInfo revocationInfo
+		if err := revokedEntry.DecodeJSON(&revInfo); err != nil {
+			return nil, fmt.Errorf("error decoding existing revocation info: %v", err)
+		}
+		certBytes = revInfo.CertificateBytes
+	}
+
+	cert, err := x509.ParseCertificate(certBytes)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing certificate: %v", err)
+	}
+	if err := cert.CheckSignatureFrom(issuerBundle.Certificate); err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("certificate with serial %s was not issued by issuer %s", serial, issuer.ID)), nil
+	}
+
+	return b.pathRevokeWrite(ctx, req, data)
+}
+
+const pathListIssuersHelpSyn = `
+List the issuers of this backend.
+`
+
+const pathListIssuersHelpDesc = `
+Issuers are listed by ID, along with their name, serial number, and
+whether they are the default issuer of the mount.
+`
+
+const pathImportIssuersHelpSyn = `
+Import CA certificates as issuers.
+`
+
+const pathImportIssuersHelpDesc = `
+This imports the CA certificates of the PEM bundle as issuers: the first
+certificate, with the secret key if the bundle has one, and each
+certificate of its chain. Certificates which were already imported are
+left as is. The first imported issuer becomes the default issuer of
+mounts without one.
+
+Without a secret key, the key of an intermediate CA generated with
+"intermediate/generate", or of an existing issuer, is used if it matches
+the certificate; otherwise the issuer cannot sign certificates.
+`
+
+const pathGenerateRootIssuerHelpSyn = `
+Generate a new root CA certificate and private key as a new issuer.
+`
+
+const pathGenerateRootIssuerHelpDesc = `
+Unlike "root/generate", this always generates a new issuer, even if the
+mount already has a CA; the new issuer only becomes the default issuer of
+mounts without one. See the API documentation for more information.
+`
+
+const pathIssuerHelpSyn = `
+Read, rename, or delete an issuer.
+`
+
+const pathIssuerHelpDesc = `
+Issuers are referenced by ID, by name, or as "default" for the default
+issuer of the mount. Deleting the default issuer leaves the mount without
+one until "config/issuers" is set. Certificates issued by a deleted issuer
+remain stored and can still be revoked.
+`
+
+const pathConfigIssuersHelpSyn = `
+Configure the default issuer of this backend.
+`
+
+const pathConfigIssuersHelpDesc = `
+The default issuer signs the certificates of the roles and paths which do
+not select an issuer, and the CRL of the mount, and is served by the "ca"
+and "ca_chain" endpoints.
+`
+
+const pathIssuerCrossSignHelpSyn = `
+Cross-sign an issuer with another issuer.
+`
+
+const pathIssuerCrossSignHelpDesc = `
+This signs a CA certificate with the subject and key of the target issuer,
+and imports it as a new issuer whose chain leads to the signing issuer.
+Certificates issued by the target issuer then chain to either CA, which is
+used to rotate to a new root or intermediate CA without reissuing them.
+The cross-signed certificate expires no later than the target and the
+signing issuers.
+`
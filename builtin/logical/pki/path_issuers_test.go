@@ -0,0 +1,291 @@
+package pki
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/helper/certutil"
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestPki_Issuers(t *testing.T) {
+	b, storage := createBackendWithStorage(t)
+
+	request := func(op logical.Operation, path string, data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Operation: op,
+			Path:      path,
+			Storage:   storage,
+			Data:      data,
+		})
+	}
+	doReq := func(op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		resp, err := request(op, path, data)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: path: %s, err: %v, resp: %#v", path, err, resp)
+		}
+		return resp
+	}
+	expectError := func(op logical.Operation, path string, data map[string]interface{}) {
+		resp, err := request(op, path, data)
+		if err != nil || resp == nil || !resp.IsError() {
+			t.Fatalf("expected an error: path: %s, err: %v, resp: %#v", path, err, resp)
+		}
+	}
+	parsePEM := func(pemCert string) *x509.Certificate {
+		block, _ := pem.Decode([]byte(pemCert))
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cert
+	}
+
+	resp := doReq(logical.UpdateOperation, "root/generate/internal", map[string]interface{}{
+		"common_name": "Root A",
+		"ttl":         "8760h",
+	})
+	rootA := parsePEM(resp.Data["certificate"].(string))
+	idA := resp.Data["issuer_id"].(string)
+
+	// A second root is only generated as a new issuer
+	if resp := doReq(logical.UpdateOperation, "root/generate/internal", map[string]interface{}{
+		"common_name": "Root B",
+	}); resp != nil {
+		t.Fatalf("expected no response: %#v", resp)
+	}
+	resp = doReq(logical.UpdateOperation, "issuers/generate/root/internal", map[string]interface{}{
+		"common_name": "Root B",
+		"ttl":         "4380h",
+		"issuer_name": "root-b",
+	})
+	rootB := parsePEM(resp.Data["certificate"].(string))
+	idB := resp.Data["issuer_id"].(string)
+
+	resp = doReq(logical.ListOperation, "issuers/", nil)
+	if keys := resp.Data["keys"].([]string); len(keys) != 2 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	keyInfo := resp.Data["key_info"].(map[string]interface{})
+	if keyInfo[idA].(map[string]interface{})["is_default"] != true ||
+		keyInfo[idB].(map[string]interface{})["is_default"] != false ||
+		keyInfo[idB].(map[string]interface{})["issuer_name"] != "root-b" {
+		t.Fatalf("bad: %#v", keyInfo)
+	}
+
+	resp = doReq(logical.ReadOperation, "issuer/root-b", nil)
+	if resp.Data["issuer_id"] != idB || resp.Data["has_private_key"] != true || resp.Data["is_default"] != false {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	expectError(logical.ReadOperation, "issuer/unknown", nil)
+	expectError(logical.UpdateOperation, "issuer/"+idA, map[string]interface{}{"issuer_name": "root-b"})
+	expectError(logical.UpdateOperation, "issuer/"+idA, map[string]interface{}{"issuer_name": "default"})
+	doReq(logical.UpdateOperation, "issuer/"+idA, map[string]interface{}{"issuer_name": "root-a"})
+
+	// Certificates are issued by the default issuer, the issuer of the role,
+	// or the issuer of the path
+	doReq(logical.UpdateOperation, "roles/test", map[string]interface{}{
+		"allowed_domains":  "example.com",
+		"allow_subdomains": true,
+		"ttl":              "1h",
+	})
+	expectError(logical.UpdateOperation, "roles/bad", map[string]interface{}{
+		"allowed_domains": "example.com",
+		"issuer_ref":      "unknown",
+	})
+	doReq(logical.UpdateOperation, "roles/test-b", map[string]interface{}{
+		"allowed_domains":  "example.com",
+		"allow_subdomains": true,
+		"ttl":              "1h",
+		"issuer_ref":       "root-b",
+	})
+	if resp := doReq(logical.ReadOperation, "roles/test", nil); resp.Data["issuer_ref"] != defaultIssuerRef {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	issue := func(path string) *x509.Certificate {
+		return parsePEM(doReq(logical.UpdateOperation, path, map[string]interface{}{
+			"common_name": "test.example.com",
+		}).Data["certificate"].(string))
+	}
+	if cert := issue("issue/test"); cert.CheckSignatureFrom(rootA) != nil {
+		t.Fatalf("expected a certificate issued by root A")
+	}
+	if cert := issue("issue/test-b"); cert.CheckSignatureFrom(rootB) != nil {
+		t.Fatalf("expected a certificate issued by root B")
+	}
+	certB := issue("issuer/root-b/issue/test")
+	if certB.CheckSignatureFrom(rootB) != nil {
+		t.Fatalf("expected a certificate issued by root B")
+	}
+
+	// Changing the default issuer changes the CA of the mount
+	doReq(logical.UpdateOperation, "config/issuers", map[string]interface{}{"default": "root-b"})
+	if resp := doReq(logical.ReadOperation, "config/issuers", nil); resp.Data["default"] != idB {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if cert := issue("issue/test"); cert.CheckSignatureFrom(rootB) != nil {
+		t.Fatalf("expected a certificate issued by root B")
+	}
+	resp = doReq(logical.ReadOperation, "cert/ca", nil)
+	if parsePEM(resp.Data["certificate"].(string)).SerialNumber.Cmp(rootB.SerialNumber) != 0 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	doReq(logical.UpdateOperation, "config/issuers", map[string]interface{}{"default": "root-a"})
+
+	// Revoking with an issuer only accepts its certificates
+	serialB := certutil.GetHexFormatted(certB.SerialNumber.Bytes(), ":")
+	expectError(logical.UpdateOperation, "issuer/root-a/revoke", map[string]interface{}{"serial_number": serialB})
+	doReq(logical.UpdateOperation, "issuer/root-b/revoke", map[string]interface{}{"serial_number": serialB})
+
+	fetchCRL := func(ref string) {
+		resp := doReq(logical.ReadOperation, "crl/issuer/"+ref, nil)
+		crl, err := x509.ParseCRL(resp.Data[logical.HTTPRawBody].([]byte))
+		if err != nil {
+			t.Fatal(err)
+		}
+		signer := rootA
+		if ref == "root-b" {
+			signer = rootB
+		}
+		if err := signer.CheckCRLSignature(crl); err != nil {
+			t.Fatalf("bad CRL signature: %v", err)
+		}
+		if ref == "root-b" && (len(crl.TBSCertList.RevokedCertificates) != 1 ||
+			crl.TBSCertList.RevokedCertificates[0].SerialNumber.Cmp(certB.SerialNumber) != 0) {
+			t.Fatalf("bad: CRL of %s: %#v", ref, crl.TBSCertList.RevokedCertificates)
+		}
+		if ref == "root-a" && len(crl.TBSCertList.RevokedCertificates) != 0 {
+			t.Fatalf("bad: CRL of %s: %#v", ref, crl.TBSCertList.RevokedCertificates)
+		}
+	}
+	fetchCRL("root-a")
+	fetchCRL("root-b")
+
+	resp = doReq(logical.ReadOperation, "ca/issuer/root-b/pem", nil)
+	if !strings.Contains(string(resp.Data[logical.HTTPRawBody].([]byte)), "BEGIN CERTIFICATE") {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// A cross-signed issuer has the key of the target, and chains to the
+	// signing issuer
+	resp = doReq(logical.UpdateOperation, "issuer/root-a/cross-sign", map[string]interface{}{
+		"target_issuer": "root-b",
+		"issuer_name":   "root-b-cross",
+	})
+	crossCert := parsePEM(resp.Data["certificate"].(string))
+	if crossCert.CheckSignatureFrom(rootA) != nil || string(crossCert.RawSubjectPublicKeyInfo) != string(rootB.RawSubjectPublicKeyInfo) ||
+		crossCert.NotAfter.After(rootB.NotAfter) {
+		t.Fatalf("bad: cross-signed certificate: %#v", crossCert)
+	}
+	if resp := doReq(logical.ReadOperation, "issuer/root-b-cross", nil); resp.Data["has_private_key"] != true {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if cert := issue("issuer/root-b-cross/issue/test"); cert.CheckSignatureFrom(crossCert) != nil ||
+		cert.CheckSignatureFrom(rootB) != nil {
+		t.Fatalf("expected a certificate issued with the key of root B")
+	}
+	expectError(logical.UpdateOperation, "issuer/root-a/cross-sign", map[string]interface{}{"target_issuer": "root-a"})
+
+	// Importing an existing certificate is a no-op, and certificates without
+	// a key cannot sign
+	resp = doReq(logical.ReadOperation, "issuer/root-b", nil)
+	doReq(logical.UpdateOperation, "issuers/import", map[string]interface{}{"pem_bundle": resp.Data["certificate"]})
+	resp = doReq(logical.UpdateOperation, "issuers/import", map[string]interface{}{"pem_bundle": resp.Data["certificate"]})
+	if existing := resp.Data["existing_issuers"].([]string); len(existing) != 1 || existing[0] != idB {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Deleting the default issuer leaves the mount without a CA
+	doReq(logical.DeleteOperation, "issuer/root-b-cross", nil)
+	doReq(logical.DeleteOperation, "issuer/root-a", nil)
+	doReq(logical.DeleteOperation, "issuer/root-a", nil)
+	if resp := doReq(logical.ReadOperation, "config/issuers", nil); resp.Data["default"] != "" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if _, err := request(logical.UpdateOperation, "issue/test", map[string]interface{}{"common_name": "test.example.com"}); err == nil {
+		t.Fatalf("expected an error without a default issuer")
+	}
+	if cert := issue("issue/test-b"); cert.CheckSignatureFrom(rootB) != nil {
+		t.Fatalf("expected a certificate issued by root B")
+	}
+}
+
+func TestPki_IssuersLegacyMigration(t *testing.T) {
+	b, storage := createBackendWithStorage(t)
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "root/generate/exported",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"common_name": "Legacy Root",
+			"ttl":         "8760h",
+		},
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: err: %v, resp: %#v", err, resp)
+	}
+	certPEM := resp.Data["certificate"].(string)
+	keyPEM := resp.Data["private_key"].(string)
+
+	// Store the CA bundle the way mounts did before supporting multiple
+	// issuers
+	parsedBundle, err := certutil.ParsePEMBundle(keyPEM + "\n" + certPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cb, err := parsedBundle.ToCertBundle()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := storage.List(context.Background(), "issuers/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range keys {
+		if err := storage.Delete(context.Background(), "issuers/"+key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := storage.Delete(context.Background(), "config/issuers"); err != nil {
+		t.Fatal(err)
+	}
+	entry, err := logical.StorageEntryJSON("config/ca_bundle", cb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.Put(context.Background(), entry); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "issuer/default",
+		Storage:   storage,
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: err: %v, resp: %#v", err, resp)
+	}
+	if resp.Data["certificate"] != certPEM || resp.Data["has_private_key"] != true || resp.Data["is_default"] != true {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	entry, err = storage.Get(context.Background(), "config/ca_bundle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry != nil {
+		t.Fatalf("expected the legacy CA bundle to be removed")
+	}
+	issuers, err := listIssuers(context.Background(), storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issuers) != 1 || issuers[0].ID != resp.Data["issuer_id"] {
+		t.Fatalf("bad: %#v", issuers)
+	}
+}
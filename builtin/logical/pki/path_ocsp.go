@@ -0,0 +1,201 @@
+package pki
+
+import (
+	"context"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"net/http"
+	"time"
+
+	"github.com/hashicorp/vault/helper/certutil"
+	"github.com/hashicorp/vault/helper/ocsp"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+func pathOCSP(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "ocsp",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathOCSPWrite,
+		},
+
+		HelpSynopsis:    pathOCSPHelpSyn,
+		HelpDescription: pathOCSPHelpDesc,
+	}
+}
+
+func pathOCSPGet(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "ocsp/(?P<request>.+)",
+		Fields: map[string]*framework.FieldSchema{
+			"request": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: `Base64-encoded DER OCSP request`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathOCSPRead,
+		},
+
+		HelpSynopsis:    pathOCSPHelpSyn,
+		HelpDescription: pathOCSPHelpDesc,
+	}
+}
+
+// pathOCSPWrite answers OCSP requests POSTed with the
+// application/ocsp-request content type, whose body is passed as is
+func (b *backend) pathOCSPWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	der, _ := req.Data[logical.HTTPRawBody].([]byte)
+	return b.ocspResponse(ctx, req, der)
+}
+
+func (b *backend) pathOCSPRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	der, err := base64.StdEncoding.DecodeString(data.Get("request").(string))
+	if err != nil {
+		return ocspRawResponse(ocsp.ErrorResponse(ocsp.StatusMalformedRequest)), nil
+	}
+	return b.ocspResponse(ctx, req, der)
+}
+
+// ocspResponse answers a DER encoded OCSP request. Failures are reported to
+// clients as unsuccessful OCSP responses rather than HTTP errors.
+func (b *backend) ocspResponse(ctx context.Context, req *logical.Request, der []byte) (*logical.Response, error) {
+	ocspReq, err := ocsp.ParseRequest(der)
+	if err != nil {
+		return ocspRawResponse(ocsp.ErrorResponse(ocsp.StatusMalformedRequest)), nil
+	}
+
+	issuers, err := b.fetchIssuers(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	// The certificates must all be issued by one of the issuers of the
+	// mount holding its key
+	var issuerBundle *certutil.ParsedCertBundle
+	for _, issuer := range issuers {
+		if !issuer.hasKey() {
+			continue
+		}
+		parsedBundle, err := issuer.parse()
+		if err != nil {
+			return nil, err
+		}
+		if matches, err := ocspReq.TBSRequest.RequestList[0].CertID.MatchesIssuer(parsedBundle.Certificate); err == nil && matches {
+			issuerBundle = parsedBundle
+			break
+		}
+	}
+	if issuerBundle == nil {
+		return ocspRawResponse(ocsp.ErrorResponse(ocsp.StatusUnauthorized)), nil
+	}
+
+	signer := &ocsp.Signer{
+		Certificate: issuerBundle.Certificate,
+		PrivateKey:  issuerBundle.PrivateKey,
+	}
+	responder, err := fetchOCSPResponder(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if responder != nil && time.Now().Before(responder.Certificate.NotAfter) &&
+		responder.Certificate.CheckSignatureFrom(issuerBundle.Certificate) == nil {
+		signer = &ocsp.Signer{
+			Certificate: responder.Certificate,
+			PrivateKey:  responder.PrivateKey,
+			Delegated:   true,
+		}
+	}
+
+	nextUpdate, err := b.configuredCRLLifetime(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+
+	var responses []ocsp.SingleResponse
+	for _, single := range ocspReq.TBSRequest.RequestList {
+		// Only the certificates of the same CA can be answered for
+		matches, err := single.CertID.MatchesIssuer(issuerBundle.Certificate)
+		if err != nil || !matches {
+			return ocspRawResponse(ocsp.ErrorResponse(ocsp.StatusUnauthorized)), nil
+		}
+
+		response := ocsp.SingleResponse{
+			CertID:     single.CertID,
+			ThisUpdate: now,
+			NextUpdate: now.Add(nextUpdate),
+		}
+
+		serial := certutil.GetHexFormatted(single.CertID.SerialNumber.Bytes(), ":")
+		revokedEntry, err := fetchCertBySerial(ctx, req, "revoked/", serial)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case revokedEntry != nil:
+			var revInfo revocationInfo
+			if err := revokedEntry.DecodeJSON(&revInfo); err != nil {
+				return nil, err
+			}
+			revocationTime := revInfo.RevocationTimeUTC
+			if revocationTime.IsZero() {
+				revocationTime = time.Unix(revInfo.RevocationTime, 0)
+			}
+			response.Revoked.RevocationTime = revocationTime.UTC()
+		default:
+			// Certificates that were not stored are unknown rather than good
+			certEntry, err := fetchCertBySerial(ctx, req, "certs/", serial)
+			if err != nil {
+				return nil, err
+			}
+			if certEntry != nil {
+				response.Good = true
+			} else {
+				response.Unknown = true
+			}
+		}
+
+		responses = append(responses, response)
+	}
+
+	var extensions []pkix.Extension
+	if nonce := ocspReq.Nonce(); nonce != nil {
+		extensions = append(extensions, *nonce)
+	}
+
+	ocspResp, err := signer.Sign(responses, extensions)
+	if err != nil {
+		return nil, err
+	}
+
+	return ocspRawResponse(ocspResp), nil
+}
+
+func ocspRawResponse(body []byte) *logical.Response {
+	return &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPContentType: "application/ocsp-response",
+			logical.HTTPRawBody:     body,
+			logical.HTTPStatusCode:  http.StatusOK,
+		},
+	}
+}
+
+const pathOCSPHelpSyn = `
+Query the revocation status of certificates with OCSP.
+`
+
+const pathOCSPHelpDesc = `
+This endpoint answers OCSP (RFC 6960) requests for the certificates issued
+by the issuers of this backend, POSTed with the "application/ocsp-request"
+content type or base64-encoded in the path of a GET request. Responses are
+signed by the issuer, or by the delegated responder set with "config/ocsp"
+if it was issued by the same issuer.
+
+Certificates that are neither revoked nor stored by the backend, such as
+those issued by roles with "no_store" set, are reported as unknown.
+`
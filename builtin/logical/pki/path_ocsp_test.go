@@ -0,0 +1,239 @@
+package pki
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/helper/certutil"
+	"github.com/hashicorp/vault/helper/ocsp"
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestPki_OCSP(t *testing.T) {
+	b, storage := createBackendWithStorage(t)
+
+	doReq := func(op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: op,
+			Path:      path,
+			Storage:   storage,
+			Data:      data,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: path: %s, err: %v, resp: %#v", path, err, resp)
+		}
+		return resp
+	}
+	parsePEM := func(pemCert string) *x509.Certificate {
+		block, _ := pem.Decode([]byte(pemCert))
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cert
+	}
+
+	resp := doReq(logical.UpdateOperation, "root/generate/exported", map[string]interface{}{
+		"common_name": "Root CA",
+		"ttl":         "8760h",
+		"key_type":    "ec",
+		"key_bits":    256,
+	})
+	caCert := parsePEM(resp.Data["certificate"].(string))
+	caKeyBlock, _ := pem.Decode([]byte(resp.Data["private_key"].(string)))
+	caKey, err := x509.ParseECPrivateKey(caKeyBlock.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doReq(logical.UpdateOperation, "roles/test", map[string]interface{}{
+		"allowed_domains":  "example.com",
+		"allow_subdomains": true,
+		"ttl":              "1h",
+	})
+	goodCert := parsePEM(doReq(logical.UpdateOperation, "issue/test", map[string]interface{}{
+		"common_name": "good.example.com",
+	}).Data["certificate"].(string))
+	revokedCert := parsePEM(doReq(logical.UpdateOperation, "issue/test", map[string]interface{}{
+		"common_name": "revoked.example.com",
+	}).Data["certificate"].(string))
+	doReq(logical.UpdateOperation, "revoke", map[string]interface{}{
+		"serial_number": certutil.GetHexFormatted(revokedCert.SerialNumber.Bytes(), ":"),
+	})
+
+	spki, err := ocsp.SubjectPublicKeyBytes(caCert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nameHash := crypto.SHA1.New()
+	nameHash.Write(caCert.RawSubject)
+	keyHash := crypto.SHA1.New()
+	keyHash.Write(spki)
+	certID := func(serial *big.Int) ocsp.CertID {
+		return ocsp.CertID{
+			HashAlgorithm: pkix.AlgorithmIdentifier{
+				Algorithm:  asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26},
+				Parameters: asn1.NullRawValue,
+			},
+			NameHash:      nameHash.Sum(nil),
+			IssuerKeyHash: keyHash.Sum(nil),
+			SerialNumber:  serial,
+		}
+	}
+	newRequest := func(extensions []pkix.Extension, ids ...ocsp.CertID) []byte {
+		req := ocsp.Request{TBSRequest: ocsp.TBSRequest{RequestExtensions: extensions}}
+		for _, id := range ids {
+			req.TBSRequest.RequestList = append(req.TBSRequest.RequestList, ocsp.SingleRequest{CertID: id})
+		}
+		der, err := asn1.Marshal(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return der
+	}
+
+	// parseResponse returns the status of an OCSP response, and the data of
+	// successful ones after verifying their signature
+	parseResponse := func(resp *logical.Response, signer *x509.Certificate) (int, *ocsp.ResponseData, *ocsp.BasicResponse) {
+		if resp.Data[logical.HTTPContentType] != "application/ocsp-response" || resp.Data[logical.HTTPStatusCode] != 200 {
+			t.Fatalf("bad: %#v", resp.Data)
+		}
+		var ocspResp ocsp.Response
+		if _, err := asn1.Unmarshal(resp.Data[logical.HTTPRawBody].([]byte), &ocspResp); err != nil {
+			t.Fatal(err)
+		}
+		if ocspResp.Status != ocsp.StatusSuccessful {
+			return int(ocspResp.Status), nil, nil
+		}
+		if !ocspResp.ResponseBytes.ResponseType.Equal(ocsp.OIDBasicResponse) {
+			t.Fatalf("bad: response type: %v", ocspResp.ResponseBytes.ResponseType)
+		}
+
+		var basic ocsp.BasicResponse
+		if _, err := asn1.Unmarshal(ocspResp.ResponseBytes.Response, &basic); err != nil {
+			t.Fatal(err)
+		}
+		if err := signer.CheckSignature(x509.ECDSAWithSHA256, basic.TBSResponseData.Raw, basic.Signature.Bytes); err != nil {
+			t.Fatalf("bad signature: %v", err)
+		}
+		return ocsp.StatusSuccessful, &basic.TBSResponseData, &basic
+	}
+
+	ocspPost := func(der []byte) *logical.Response {
+		return doReq(logical.UpdateOperation, "ocsp", map[string]interface{}{
+			logical.HTTPRawBody: der,
+		})
+	}
+
+	unknownSerial := big.NewInt(12345)
+	status, data, basic := parseResponse(ocspPost(newRequest(nil,
+		certID(goodCert.SerialNumber), certID(revokedCert.SerialNumber), certID(unknownSerial))), caCert)
+	if status != ocsp.StatusSuccessful || len(data.Responses) != 3 || len(basic.Certificates) != 0 {
+		t.Fatalf("bad: status: %d, data: %#v", status, data)
+	}
+	good, revoked, unknown := data.Responses[0], data.Responses[1], data.Responses[2]
+	if !bool(good.Good) || bool(good.Unknown) || !good.Revoked.RevocationTime.IsZero() ||
+		good.CertID.SerialNumber.Cmp(goodCert.SerialNumber) != 0 {
+		t.Fatalf("bad: good response: %#v", good)
+	}
+	if bool(revoked.Good) || revoked.Revoked.RevocationTime.IsZero() {
+		t.Fatalf("bad: revoked response: %#v", revoked)
+	}
+	if !bool(unknown.Unknown) || bool(unknown.Good) {
+		t.Fatalf("bad: unknown response: %#v", unknown)
+	}
+	if good.NextUpdate.Sub(good.ThisUpdate) != 72*time.Hour {
+		t.Fatalf("bad: validity: %v to %v", good.ThisUpdate, good.NextUpdate)
+	}
+
+	// GET requests have the request in the path, and nonces are echoed
+	nonce := []pkix.Extension{{Id: ocsp.OIDNonce, Value: []byte{0x04, 0x02, 0x01, 0x02}}}
+	status, data, _ = parseResponse(doReq(logical.ReadOperation,
+		"ocsp/"+base64.StdEncoding.EncodeToString(newRequest(nonce, certID(revokedCert.SerialNumber))), nil), caCert)
+	if status != ocsp.StatusSuccessful || len(data.Responses) != 1 || data.Responses[0].Revoked.RevocationTime.IsZero() {
+		t.Fatalf("bad: status: %d, data: %#v", status, data)
+	}
+	if len(data.ResponseExtensions) != 1 || string(data.ResponseExtensions[0].Value) != string(nonce[0].Value) {
+		t.Fatalf("bad: extensions: %#v", data.ResponseExtensions)
+	}
+
+	if status, _, _ := parseResponse(ocspPost([]byte("garbage")), caCert); status != ocsp.StatusMalformedRequest {
+		t.Fatalf("bad: status: %d", status)
+	}
+
+	// Certificates of other CAs cannot be answered for
+	otherID := certID(goodCert.SerialNumber)
+	otherID.IssuerKeyHash = make([]byte, len(otherID.IssuerKeyHash))
+	if status, _, _ := parseResponse(ocspPost(newRequest(nil, otherID)), caCert); status != ocsp.StatusUnauthorized {
+		t.Fatalf("bad: status: %d", status)
+	}
+
+	// Responses can be signed by a delegated responder
+	newResponder := func(usage []x509.ExtKeyUsage) (*x509.Certificate, string) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		der, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+			SerialNumber: big.NewInt(time.Now().UnixNano()),
+			Subject:      pkix.Name{CommonName: "OCSP Responder"},
+			NotBefore:    time.Now().Add(-time.Minute),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  usage,
+		}, caCert, key.Public(), caKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keyDER, err := x509.MarshalECPrivateKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cert, string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})) +
+			string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	}
+
+	_, bundle := newResponder([]x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth})
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config/ocsp",
+		Storage:   storage,
+		Data:      map[string]interface{}{"pem_bundle": bundle},
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error without the OCSP signing usage: err: %v, resp: %#v", err, resp)
+	}
+
+	responderCert, bundle := newResponder([]x509.ExtKeyUsage{x509.ExtKeyUsageOCSPSigning})
+	doReq(logical.UpdateOperation, "config/ocsp", map[string]interface{}{"pem_bundle": bundle})
+	resp = doReq(logical.ReadOperation, "config/ocsp", nil)
+	if resp.Data["delegated"] != true || parsePEM(resp.Data["certificate"].(string)).SerialNumber.Cmp(responderCert.SerialNumber) != 0 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	status, data, basic = parseResponse(ocspPost(newRequest(nil, certID(goodCert.SerialNumber))), responderCert)
+	if status != ocsp.StatusSuccessful || !data.Responses[0].Good || len(basic.Certificates) != 1 {
+		t.Fatalf("bad: status: %d, data: %#v", status, data)
+	}
+
+	// Without the delegated responder, the CA signs again
+	doReq(logical.DeleteOperation, "config/ocsp", nil)
+	if status, _, _ := parseResponse(ocspPost(newRequest(nil, certID(goodCert.SerialNumber))), caCert); status != ocsp.StatusSuccessful {
+		t.Fatalf("bad: status: %d", status)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/hashicorp/vault/helper/consts"
+	"github.com/hashicorp/vault/helper/errutil"
 	"github.com/hashicorp/vault/helper/parseutil"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
@@ -145,8 +146,8 @@ protection use. Defaults to false.`,
 			"key_type": &framework.FieldSchema{
 				Type:    framework.TypeString,
 				Default: "rsa",
-				Description: `The type of key to use; defaults to RSA. "rsa"
-and "ec" are the only valid values.`,
+				Description: `The type of key to use; defaults to RSA. "rsa",
+"ec" and "ed25519" are the only valid values.`,
 			},
 
 			"key_bits": &framework.FieldSchema{
@@ -226,6 +227,13 @@ for "generate_lease".`,
 				Default:     true,
 				Description: `If set to false, makes the 'common_name' field optional while generating a certificate.`,
 			},
+			"issuer_ref": &framework.FieldSchema{
+				Type:    framework.TypeString,
+				Default: defaultIssuerRef,
+				Description: `ID or name of the issuer of the certificates
+issued/signed against this role. Defaults to the default
+issuer of the mount.`,
+			},
 		},
 
 		Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -300,6 +308,12 @@ func (b *backend) getRole(ctx context.Context, s logical.Storage, n string) (*ro
 		modified = true
 	}
 
+	// Roles created before multiple issuers were supported use the default
+	// issuer
+	if result.IssuerRef == "" {
+		result.IssuerRef = defaultIssuerRef
+	}
+
 	// Upgrade key usages
 	if result.KeyUsageOld != "" {
 		result.KeyUsage = strings.Split(result.KeyUsageOld, ",")
@@ -417,6 +431,7 @@ func (b *backend) pathRoleCreate(ctx context.Context, req *logical.Request, data
 		GenerateLease:       new(bool),
 		NoStore:             data.Get("no_store").(bool),
 		RequireCN:           data.Get("require_cn").(bool),
+		IssuerRef:           data.Get("issuer_ref").(string),
 	}
 
 	// no_store implies generate_lease := false
@@ -473,6 +488,17 @@ func (b *backend) pathRoleCreate(ctx context.Context, req *logical.Request, data
 		return errResp, nil
 	}
 
+	if entry.IssuerRef != defaultIssuerRef {
+		_, err := b.resolveIssuer(ctx, req.Storage, entry.IssuerRef)
+		switch err.(type) {
+		case nil:
+		case errutil.UserError:
+			return logical.ErrorResponse(err.Error()), nil
+		default:
+			return nil, err
+		}
+	}
+
 	// Store it
 	jsonEntry, err := logical.StorageEntryJSON("role/"+name, entry)
 	if err != nil {
@@ -548,6 +574,7 @@ type roleEntry struct {
 	GenerateLease         *bool    `json:"generate_lease,omitempty"`
 	NoStore               bool     `json:"no_store" mapstructure:"no_store"`
 	RequireCN             bool     `json:"require_cn" mapstructure:"require_cn"`
+	IssuerRef             string   `json:"issuer_ref" mapstructure:"issuer_ref"`
 
 	// Used internally for signing intermediates
 	AllowExpirationPastCA bool
@@ -578,6 +605,7 @@ func (r *roleEntry) ToResponseData() map[string]interface{} {
 		"ou":                      r.OU,
 		"organization":            r.Organization,
 		"no_store":                r.NoStore,
+		"issuer_ref":              r.IssuerRef,
 	}
 	if r.MaxPathLength != nil {
 		responseData["max_path_length"] = r.MaxPathLength
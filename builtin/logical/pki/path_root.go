@@ -64,6 +64,7 @@ func pathSignIntermediate(b *backend) *framework.Path {
 
 	ret.Fields = addCACommonFields(map[string]*framework.FieldSchema{})
 	ret.Fields = addCAIssueFields(ret.Fields)
+	ret.Fields = addIssuerRefField(ret.Fields)
 
 	ret.Fields["csr"] = &framework.FieldSchema{
 		Type:        framework.TypeString,
@@ -107,24 +108,39 @@ func pathSignSelfIssued(b *backend) *framework.Path {
 		HelpDescription: pathSignSelfIssuedHelpDesc,
 	}
 
+	ret.Fields = addIssuerRefField(ret.Fields)
+
 	return ret
 }
 
 func (b *backend) pathCADeleteRoot(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	return nil, req.Storage.Delete(ctx, "config/ca_bundle")
+	if err := b.lockIssuers(ctx, req.Storage); err != nil {
+		return nil, err
+	}
+	defer b.issuersLock.Unlock()
+
+	return nil, deleteIssuers(ctx, req.Storage)
 }
 
 func (b *backend) pathCAGenerateRoot(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	var err error
-
-	entry, err := req.Storage.Get(ctx, "config/ca_bundle")
-	if err != nil {
-		return nil, err
-	}
-	if entry != nil {
+	// This is a no-op if the mount already has a CA; additional roots are
+	// generated with "issuers/generate/root"
+	_, err := b.resolveIssuer(ctx, req.Storage, defaultIssuerRef)
+	switch err.(type) {
+	case nil:
 		return nil, nil
+	case errutil.UserError:
+	default:
+		return nil, err
 	}
 
+	return b.generateRoot(ctx, req, data, "", true)
+}
+
+// generateRoot generates a root CA certificate and stores it as an issuer
+func (b *backend) generateRoot(ctx context.Context, req *logical.Request, data *framework.FieldData, issuerName string, makeDefault bool) (*logical.Response, error) {
+	var err error
+
 	exported, format, role, errorResp := b.getGenerationParams(data)
 	if errorResp != nil {
 		return errorResp, nil
@@ -194,15 +210,16 @@ func (b *backend) pathCAGenerateRoot(ctx context.Context, req *logical.Request,
 		}
 	}
 
-	// Store it as the CA bundle
-	entry, err = logical.StorageEntryJSON("config/ca_bundle", cb)
-	if err != nil {
-		return nil, err
-	}
-	err = req.Storage.Put(ctx, entry)
-	if err != nil {
+	// Store it as an issuer
+	issuer, _, err := b.importCAIssuer(ctx, req.Storage, parsedBundle, issuerName, makeDefault, true)
+	switch err.(type) {
+	case nil:
+	case errutil.UserError:
+		return logical.ErrorResponse(err.Error()), nil
+	default:
 		return nil, err
 	}
+	resp.Data["issuer_id"] = issuer.ID
 
 	// Also store it as just the certificate identified by serial number, so it
 	// can be revoked
@@ -214,15 +231,6 @@ func (b *backend) pathCAGenerateRoot(ctx context.Context, req *logical.Request,
 		return nil, errwrap.Wrapf("unable to store certificate locally: {{err}}", err)
 	}
 
-	// For ease of later use, also store just the certificate at a known
-	// location
-	entry.Key = "ca"
-	entry.Value = parsedBundle.CertificateBytes
-	err = req.Storage.Put(ctx, entry)
-	if err != nil {
-		return nil, err
-	}
-
 	// Build a fresh CRL
 	err = buildCRL(ctx, b, req)
 	if err != nil {
@@ -261,7 +269,7 @@ func (b *backend) pathCASignIntermediate(ctx context.Context, req *logical.Reque
 	}
 
 	var caErr error
-	signingBundle, caErr := fetchCAInfo(ctx, req)
+	signingBundle, caErr := fetchCAInfo(ctx, b, req, data.Get("issuer_ref").(string))
 	switch caErr.(type) {
 	case errutil.UserError:
 		return nil, errutil.UserError{Err: fmt.Sprintf(
@@ -279,7 +287,7 @@ func (b *backend) pathCASignIntermediate(ctx context.Context, req *logical.Reque
 		role.MaxPathLength = &maxPathLength
 	}
 
-	parsedBundle, err := signCert(b, role, signingBundle, true, useCSRValues, req, data)
+	parsedBundle, err := signCert(ctx, b, role, signingBundle, true, useCSRValues, req, data)
 	if err != nil {
 		switch err.(type) {
 		case errutil.UserError:
@@ -382,7 +390,7 @@ func (b *backend) pathCASignSelfIssued(ctx context.Context, req *logical.Request
 	}
 
 	var caErr error
-	signingBundle, caErr := fetchCAInfo(ctx, req)
+	signingBundle, caErr := fetchCAInfo(ctx, b, req, data.Get("issuer_ref").(string))
 	switch caErr.(type) {
 	case errutil.UserError:
 		return nil, errutil.UserError{Err: fmt.Sprintf(
@@ -458,5 +466,5 @@ Signs another CA's self-issued certificate. This is most often used for rolling
 
 Note that this is a very privileged operation and should be extremely restricted in terms of who is allowed to use it. All values will be taken directly from the incoming certificate and only verification that it is self-issued will be performed.
 
-Configured URLs for CRLs/OCSP/etc. will be copied over and the issuer will be this mount's CA cert. Other than that, all other values will be used verbatim.
+Configured URLs for CRLs/OCSP/etc. will be copied over and the issuer will be this mount's default CA cert, or the one selected with issuer_ref. Other than that, all other values will be used verbatim.
 `
@@ -10,6 +10,32 @@ import (
 	"github.com/hashicorp/vault/logical/framework"
 )
 
+const (
+	tidyStateInactive = "Inactive"
+	tidyStateRunning  = "Running"
+	tidyStateFinished = "Finished"
+	tidyStateError    = "Error"
+)
+
+// tidyStatus reports the progress of the last tidy operation run on this
+// node, whether requested or automatic. It is kept in memory only.
+type tidyStatus struct {
+	State        string
+	Error        string
+	TimeStarted  time.Time
+	TimeFinished time.Time
+
+	SafetyBuffer       time.Duration
+	TidyCertStore      bool
+	TidyRevocationList bool
+
+	CurrentStep    string
+	EntriesChecked int
+
+	CertsDeleted        int
+	RevokedCertsDeleted int
+}
+
 func pathTidy(b *backend) *framework.Path {
 	return &framework.Path{
 		Pattern: "tidy",
@@ -47,47 +73,176 @@ Defaults to 72 hours.`,
 	}
 }
 
+func pathTidyStatus(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "tidy-status",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathTidyStatusRead,
+		},
+
+		HelpSynopsis:    pathTidyStatusHelpSyn,
+		HelpDescription: pathTidyStatusHelpDesc,
+	}
+}
+
 func (b *backend) pathTidyWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
 	safetyBuffer := d.Get("safety_buffer").(int)
-	tidyCertStore := d.Get("tidy_cert_store").(bool)
-	tidyRevocationList := d.Get("tidy_revocation_list").(bool)
+	if safetyBuffer < 0 {
+		return logical.ErrorResponse("safety_buffer must not be negative"), nil
+	}
+
+	status := &tidyStatus{
+		SafetyBuffer:       time.Duration(safetyBuffer) * time.Second,
+		TidyCertStore:      d.Get("tidy_cert_store").(bool),
+		TidyRevocationList: d.Get("tidy_revocation_list").(bool),
+	}
+	if !b.startTidy(status) {
+		return logical.ErrorResponse("a tidy operation is already running"), nil
+	}
+
+	err := b.tidy(ctx, req, status)
+	b.finishTidy(status, err)
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (b *backend) pathTidyStatusRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	b.tidyLock.RLock()
+	defer b.tidyLock.RUnlock()
+
+	status := b.tidyStatus
+	if status == nil {
+		status = &tidyStatus{State: tidyStateInactive}
+	}
 
-	bufferDuration := time.Duration(safetyBuffer) * time.Second
+	var timeStarted, timeFinished interface{}
+	if !status.TimeStarted.IsZero() {
+		timeStarted = status.TimeStarted.Unix()
+	}
+	if !status.TimeFinished.IsZero() {
+		timeFinished = status.TimeFinished.Unix()
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"state":                       status.State,
+			"error":                       status.Error,
+			"time_started":                timeStarted,
+			"time_finished":               timeFinished,
+			"safety_buffer":               int64(status.SafetyBuffer.Seconds()),
+			"tidy_cert_store":             status.TidyCertStore,
+			"tidy_revocation_list":        status.TidyRevocationList,
+			"current_step":                status.CurrentStep,
+			"entries_checked":             status.EntriesChecked,
+			"cert_store_deleted_count":    status.CertsDeleted,
+			"revoked_certs_deleted_count": status.RevokedCertsDeleted,
+		},
+	}, nil
+}
+
+// startTidy records the start of a tidy operation, unless one is already
+// running.
+func (b *backend) startTidy(status *tidyStatus) bool {
+	b.tidyLock.Lock()
+	defer b.tidyLock.Unlock()
+
+	if b.tidyStatus != nil && b.tidyStatus.State == tidyStateRunning {
+		return false
+	}
+	status.State = tidyStateRunning
+	status.TimeStarted = time.Now()
+	b.tidyStatus = status
+	b.lastTidy = status.TimeStarted
+	return true
+}
+
+// finishTidy records the outcome of a tidy operation.
+func (b *backend) finishTidy(status *tidyStatus, err error) {
+	b.tidyLock.Lock()
+	defer b.tidyLock.Unlock()
+
+	status.CurrentStep = ""
+	status.TimeFinished = time.Now()
+	if err != nil {
+		status.State = tidyStateError
+		status.Error = err.Error()
+		b.Logger().Error("pki: tidy failed", "error", err)
+		return
+	}
+	status.State = tidyStateFinished
+	if b.Logger().IsInfo() {
+		b.Logger().Info("pki: tidy finished", "certs_deleted", status.CertsDeleted, "revoked_certs_deleted", status.RevokedCertsDeleted)
+	}
+}
+
+// updateTidyStatus applies a change to the status of the running tidy
+// operation.
+func (b *backend) updateTidyStatus(update func()) {
+	b.tidyLock.Lock()
+	defer b.tidyLock.Unlock()
+	update()
+}
+
+func (b *backend) tidy(ctx context.Context, req *logical.Request, status *tidyStatus) error {
+	if status.TidyCertStore {
+		b.updateTidyStatus(func() {
+			status.CurrentStep = "cert_store"
+			status.EntriesChecked = 0
+		})
 
-	if tidyCertStore {
 		serials, err := req.Storage.List(ctx, "certs/")
 		if err != nil {
-			return nil, fmt.Errorf("error fetching list of certs: %s", err)
+			return fmt.Errorf("error fetching list of certs: %s", err)
 		}
 
 		for _, serial := range serials {
 			certEntry, err := req.Storage.Get(ctx, "certs/"+serial)
 			if err != nil {
-				return nil, fmt.Errorf("error fetching certificate %s: %s", serial, err)
+				return fmt.Errorf("error fetching certificate %s: %s", serial, err)
 			}
 
 			if certEntry == nil {
-				return nil, fmt.Errorf("certificate entry for serial %s is nil", serial)
+				return fmt.Errorf("certificate entry for serial %s is nil", serial)
 			}
 
 			if certEntry.Value == nil || len(certEntry.Value) == 0 {
-				return nil, fmt.Errorf("found entry for serial %s but actual certificate is empty", serial)
+				return fmt.Errorf("found entry for serial %s but actual certificate is empty", serial)
 			}
 
 			cert, err := x509.ParseCertificate(certEntry.Value)
 			if err != nil {
-				return nil, fmt.Errorf("unable to parse stored certificate with serial %s: %s", serial, err)
+				return fmt.Errorf("unable to parse stored certificate with serial %s: %s", serial, err)
 			}
 
-			if time.Now().After(cert.NotAfter.Add(bufferDuration)) {
+			deleted := false
+			if time.Now().After(cert.NotAfter.Add(status.SafetyBuffer)) {
 				if err := req.Storage.Delete(ctx, "certs/"+serial); err != nil {
-					return nil, fmt.Errorf("error deleting serial %s from storage: %s", serial, err)
+					return fmt.Errorf("error deleting serial %s from storage: %s", serial, err)
+				}
+				if err := req.Storage.Delete(ctx, "cert_metadata/"+serial); err != nil {
+					return fmt.Errorf("error deleting metadata of serial %s from storage: %s", serial, err)
 				}
+				deleted = true
 			}
+			b.updateTidyStatus(func() {
+				status.EntriesChecked++
+				if deleted {
+					status.CertsDeleted++
+				}
+			})
 		}
 	}
 
-	if tidyRevocationList {
+	if status.TidyRevocationList {
+		b.updateTidyStatus(func() {
+			status.CurrentStep = "revocation_list"
+			status.EntriesChecked = 0
+		})
+
 		b.revokeStorageLock.Lock()
 		defer b.revokeStorageLock.Unlock()
 
@@ -95,51 +250,62 @@ func (b *backend) pathTidyWrite(ctx context.Context, req *logical.Request, d *fr
 
 		revokedSerials, err := req.Storage.List(ctx, "revoked/")
 		if err != nil {
-			return nil, fmt.Errorf("error fetching list of revoked certs: %s", err)
+			return fmt.Errorf("error fetching list of revoked certs: %s", err)
 		}
 
 		var revInfo revocationInfo
 		for _, serial := range revokedSerials {
 			revokedEntry, err := req.Storage.Get(ctx, "revoked/"+serial)
 			if err != nil {
-				return nil, fmt.Errorf("unable to fetch revoked cert with serial %s: %s", serial, err)
+				return fmt.Errorf("unable to fetch revoked cert with serial %s: %s", serial, err)
 			}
 			if revokedEntry == nil {
-				return nil, fmt.Errorf("revoked certificate entry for serial %s is nil", serial)
+				return fmt.Errorf("revoked certificate entry for serial %s is nil", serial)
 			}
 			if revokedEntry.Value == nil || len(revokedEntry.Value) == 0 {
 				// TODO: In this case, remove it and continue? How likely is this to
 				// happen? Alternately, could skip it entirely, or could implement a
 				// delete function so that there is a way to remove these
-				return nil, fmt.Errorf("found revoked serial but actual certificate is empty")
+				return fmt.Errorf("found revoked serial but actual certificate is empty")
 			}
 
 			err = revokedEntry.DecodeJSON(&revInfo)
 			if err != nil {
-				return nil, fmt.Errorf("error decoding revocation entry for serial %s: %s", serial, err)
+				return fmt.Errorf("error decoding revocation entry for serial %s: %s", serial, err)
 			}
 
 			revokedCert, err := x509.ParseCertificate(revInfo.CertificateBytes)
 			if err != nil {
-				return nil, fmt.Errorf("unable to parse stored revoked certificate with serial %s: %s", serial, err)
+				return fmt.Errorf("unable to parse stored revoked certificate with serial %s: %s", serial, err)
 			}
 
-			if time.Now().After(revokedCert.NotAfter.Add(bufferDuration)) {
+			deleted := false
+			if time.Now().After(revokedCert.NotAfter.Add(status.SafetyBuffer)) {
 				if err := req.Storage.Delete(ctx, "revoked/"+serial); err != nil {
-					return nil, fmt.Errorf("error deleting serial %s from revoked list: %s", serial, err)
+					return fmt.Errorf("error deleting serial %s from revoked list: %s", serial, err)
 				}
 				tidiedRevoked = true
+				deleted = true
 			}
+			b.updateTidyStatus(func() {
+				status.EntriesChecked++
+				if deleted {
+					status.RevokedCertsDeleted++
+				}
+			})
 		}
 
 		if tidiedRevoked {
+			b.updateTidyStatus(func() {
+				status.CurrentStep = "crl"
+			})
 			if err := buildCRL(ctx, b, req); err != nil {
-				return nil, err
+				return err
 			}
 		}
 	}
 
-	return nil, nil
+	return nil
 }
 
 const pathTidyHelpSyn = `
@@ -161,6 +327,10 @@ considered valid by other hosts (for instance, if their clocks are a few
 minutes behind). The 'safety_buffer' parameter can be an integer number of
 seconds or a string duration like "72h".
 
+The operation runs until done; its progress, and the outcome of the last run,
+are reported by the 'tidy-status' endpoint. The 'config/auto-tidy' endpoint
+runs it on a schedule instead.
+
 All certificates and/or revocation information currently stored in the backend
 will be checked when this endpoint is hit. The expiration of the
 certificate/revocation information of each certificate being held in
@@ -168,3 +338,15 @@ certificate storage or in revocation infomation will then be checked. If the
 current time, minus the value of 'safety_buffer', is greater than the
 expiration, it will be removed.
 `
+
+const pathTidyStatusHelpSyn = `
+Report the progress of the last tidy operation.
+`
+
+const pathTidyStatusHelpDesc = `
+This path returns the state of the last tidy operation run on this node,
+requested or automatic, one of 'Inactive', 'Running', 'Finished' or 'Error',
+the options it was run with, the step it is running and the number of entries
+checked in that step, and the number of certificates and revocations removed
+so far.
+`
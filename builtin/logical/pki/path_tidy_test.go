@@ -0,0 +1,141 @@
+package pki
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestPki_TidyStatusAndAutoTidy(t *testing.T) {
+	b, storage := createBackendWithStorage(t)
+
+	request := func(op logical.Operation, path string, data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Operation: op,
+			Path:      path,
+			Storage:   storage,
+			Data:      data,
+		})
+	}
+	doReq := func(op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		resp, err := request(op, path, data)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: path: %s, err: %v, resp: %#v", path, err, resp)
+		}
+		return resp
+	}
+	periodic := func() {
+		if err := b.periodicFunc(context.Background(), &logical.Request{Storage: storage}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	countEntries := func(prefix string) int {
+		keys, err := storage.List(context.Background(), prefix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(keys)
+	}
+
+	resp := doReq(logical.ReadOperation, "tidy-status", nil)
+	if resp.Data["state"] != tidyStateInactive || resp.Data["time_started"] != nil {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	doReq(logical.UpdateOperation, "root/generate/internal", map[string]interface{}{
+		"common_name": "Root CA",
+		"ttl":         "8760h",
+	})
+	doReq(logical.UpdateOperation, "roles/test", map[string]interface{}{
+		"allowed_domains":  "example.com",
+		"allow_subdomains": true,
+	})
+	issue := func(ttl string) string {
+		resp := doReq(logical.UpdateOperation, "issue/test", map[string]interface{}{
+			"common_name": "test.example.com",
+			"ttl":         ttl,
+		})
+		return resp.Data["serial_number"].(string)
+	}
+	// Revoking an expired certificate does nothing, so they expire afterwards
+	for i := 0; i < 2; i++ {
+		doReq(logical.UpdateOperation, "revoke", map[string]interface{}{"serial_number": issue("3s")})
+	}
+	issue("3s")
+	issue("1h")
+	time.Sleep(4 * time.Second)
+
+	// A requested tidy reports how many entries it removed
+	doReq(logical.UpdateOperation, "tidy", map[string]interface{}{
+		"tidy_cert_store": true,
+		"safety_buffer":   "1s",
+	})
+	resp = doReq(logical.ReadOperation, "tidy-status", nil)
+	if resp.Data["state"] != tidyStateFinished || resp.Data["time_finished"] == nil ||
+		resp.Data["tidy_cert_store"] != true || resp.Data["tidy_revocation_list"] != false ||
+		resp.Data["safety_buffer"] != int64(1) ||
+		resp.Data["cert_store_deleted_count"] != 3 || resp.Data["revoked_certs_deleted_count"] != 0 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	// The CA certificate and the unexpired one are left
+	if n := countEntries("certs/"); n != 2 {
+		t.Fatalf("expected 2 remaining certificates, got %d", n)
+	}
+
+	for _, data := range []map[string]interface{}{
+		{"interval_duration": 0},
+		{"safety_buffer": -1},
+		{"enabled": true},
+	} {
+		if resp, err := request(logical.UpdateOperation, "config/auto-tidy", data); err != nil || resp == nil || !resp.IsError() {
+			t.Fatalf("expected an error: data: %v, err: %v, resp: %#v", data, err, resp)
+		}
+	}
+	resp = doReq(logical.ReadOperation, "config/auto-tidy", nil)
+	if resp.Data["enabled"] != false || resp.Data["interval_duration"] != int64(43200) ||
+		resp.Data["safety_buffer"] != int64(259200) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	doReq(logical.UpdateOperation, "config/auto-tidy", map[string]interface{}{
+		"enabled":              true,
+		"interval_duration":    "1h",
+		"tidy_revocation_list": true,
+	})
+	doReq(logical.UpdateOperation, "config/auto-tidy", map[string]interface{}{
+		"safety_buffer": "1s",
+	})
+	resp = doReq(logical.ReadOperation, "config/auto-tidy", nil)
+	if resp.Data["enabled"] != true || resp.Data["interval_duration"] != int64(3600) ||
+		resp.Data["tidy_cert_store"] != false || resp.Data["tidy_revocation_list"] != true ||
+		resp.Data["safety_buffer"] != int64(1) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Nothing runs until the interval has passed since the last tidy
+	periodic()
+	if n := countEntries("revoked/"); n != 2 {
+		t.Fatalf("expected 2 revocations, got %d", n)
+	}
+
+	b.tidyLock.Lock()
+	b.lastTidy = b.lastTidy.Add(-time.Hour)
+	b.tidyLock.Unlock()
+	periodic()
+	if n := countEntries("revoked/"); n != 0 {
+		t.Fatalf("expected no revocations, got %d", n)
+	}
+	resp = doReq(logical.ReadOperation, "tidy-status", nil)
+	if resp.Data["state"] != tidyStateFinished || resp.Data["tidy_revocation_list"] != true ||
+		resp.Data["cert_store_deleted_count"] != 0 || resp.Data["revoked_certs_deleted_count"] != 2 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// A running tidy is not started again
+	b.tidyStatus.State = tidyStateRunning
+	if resp, err := request(logical.UpdateOperation, "tidy", map[string]interface{}{"tidy_cert_store": true}); err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error: err: %v, resp: %#v", err, resp)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"sync"
 
 	"github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/go-uuid"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
 	"github.com/michaelklishin/rabbit-hole"
@@ -137,6 +138,27 @@ func (b *backend) Lease(ctx context.Context, s logical.Storage) (*configLease, e
 	return &result, nil
 }
 
+// generatePassword returns a password from the configured password policy,
+// or a UUID if no policy is configured
+func (b *backend) generatePassword(ctx context.Context, s logical.Storage) (string, error) {
+	entry, err := s.Get(ctx, "config/connection")
+	if err != nil {
+		return "", err
+	}
+
+	var connConfig connectionConfig
+	if entry != nil {
+		if err := entry.DecodeJSON(&connConfig); err != nil {
+			return "", err
+		}
+	}
+	if connConfig.PasswordPolicy == "" {
+		return uuid.GenerateUUID()
+	}
+
+	return b.System().GeneratePasswordFromPolicy(ctx, connConfig.PasswordPolicy)
+}
+
 const backendHelp = `
 The RabbitMQ backend dynamically generates RabbitMQ users.
 
@@ -2,9 +2,16 @@ package rabbitmq
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/hashicorp/vault/helper/jsonutil"
@@ -57,6 +64,193 @@ func TestBackend_roleCrud(t *testing.T) {
 	})
 }
 
+// fakeRabbitMQ is a fake of the RabbitMQ management API recording the users
+// and their permissions
+type fakeRabbitMQ struct {
+	sync.Mutex
+	users            map[string]rabbithole.UserSettings
+	permissions      map[string]rabbithole.Permissions
+	topicPermissions map[string]topicPermissionsRequest
+}
+
+func (f *fakeRabbitMQ) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	var parts []string
+	for _, part := range strings.Split(strings.TrimPrefix(r.URL.EscapedPath(), "/api/"), "/") {
+		part, _ = url.PathUnescape(part)
+		parts = append(parts, part)
+	}
+
+	switch {
+	case len(parts) == 2 && parts[0] == "users" && r.Method == http.MethodPut:
+		var user rabbithole.UserSettings
+		json.NewDecoder(r.Body).Decode(&user)
+		f.users[parts[1]] = user
+		w.WriteHeader(http.StatusCreated)
+	case len(parts) == 2 && parts[0] == "users" && r.Method == http.MethodDelete:
+		delete(f.users, parts[1])
+		for key := range f.permissions {
+			if strings.HasSuffix(key, "/"+parts[1]) {
+				delete(f.permissions, key)
+			}
+		}
+		for key := range f.topicPermissions {
+			if strings.HasSuffix(key, "/"+parts[1]) {
+				delete(f.topicPermissions, key)
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case len(parts) == 3 && parts[0] == "permissions" && r.Method == http.MethodPut:
+		var permissions rabbithole.Permissions
+		json.NewDecoder(r.Body).Decode(&permissions)
+		f.permissions[parts[1]+"/"+parts[2]] = permissions
+		w.WriteHeader(http.StatusCreated)
+	case len(parts) == 3 && parts[0] == "topic-permissions" && r.Method == http.MethodPut:
+		if _, ok := f.users[parts[2]]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var permissions topicPermissionsRequest
+		json.NewDecoder(r.Body).Decode(&permissions)
+		f.topicPermissions[parts[1]+"/"+permissions.Exchange+"/"+parts[2]] = permissions
+		w.WriteHeader(http.StatusCreated)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Object Not Found", "reason": "Not Found"})
+	}
+}
+
+func TestBackend_topicPermissionsAndPasswordPolicy(t *testing.T) {
+	rmq := &fakeRabbitMQ{
+		users:            make(map[string]rabbithole.UserSettings),
+		permissions:      make(map[string]rabbithole.Permissions),
+		topicPermissions: make(map[string]topicPermissionsRequest),
+	}
+	server := httptest.NewServer(rmq)
+	defer server.Close()
+
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	sysView := logical.TestSystemView()
+	sysView.PasswordPolicies = map[string]string{
+		"digits": "length = 10\nrule \"charset\" {\n  charset = \"0123456789\"\n}",
+	}
+	config.System = sysView
+	b, err := Factory(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config/connection",
+		Storage:   config.StorageView,
+		Data: map[string]interface{}{
+			"connection_uri":    server.URL,
+			"username":          "admin",
+			"password":          "password",
+			"verify_connection": false,
+			"password_policy":   "digits",
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "roles/web",
+		Storage:   config.StorageView,
+		Data: map[string]interface{}{
+			"vhosts":       `{"/": {"configure": "", "write": "", "read": ".*"}}`,
+			"vhost_topics": `{"/": {"amq.topic": {"write": "^events\\.", "read": ".*"}}}`,
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "roles/web",
+		Storage:   config.StorageView,
+	})
+	if err != nil || resp == nil {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	expectedTopics := map[string]map[string]topicPermission{
+		"/": {"amq.topic": {Write: "^events\\.", Read: ".*"}},
+	}
+	if !reflect.DeepEqual(resp.Data["vhost_topics"], expectedTopics) {
+		t.Fatalf("bad: %#v", resp.Data["vhost_topics"])
+	}
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation:   logical.ReadOperation,
+		Path:        "creds/web",
+		Storage:     config.StorageView,
+		DisplayName: "test",
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	username := resp.Data["username"].(string)
+	password := resp.Data["password"].(string)
+	if len(password) != 10 || strings.Trim(password, "0123456789") != "" {
+		t.Fatalf("bad: password %q does not match the password policy", password)
+	}
+	if rmq.users[username].Password != password {
+		t.Fatalf("bad: user %q: %#v", username, rmq.users[username])
+	}
+	expectedPermissions := topicPermissionsRequest{Exchange: "amq.topic", Write: "^events\\.", Read: ".*"}
+	if rmq.topicPermissions["//amq.topic/"+username] != expectedPermissions {
+		t.Fatalf("bad: topic permissions: %#v", rmq.topicPermissions)
+	}
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.RevokeOperation,
+		Storage:   config.StorageView,
+		Secret:    resp.Secret,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	if len(rmq.users) != 0 || len(rmq.topicPermissions) != 0 {
+		t.Fatalf("bad: user %q was not revoked", username)
+	}
+
+	// Credentials cannot be generated from missing password policies
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config/connection",
+		Storage:   config.StorageView,
+		Data: map[string]interface{}{
+			"connection_uri":    server.URL,
+			"username":          "admin",
+			"password":          "password",
+			"verify_connection": false,
+			"password_policy":   "missing",
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	_, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation:   logical.ReadOperation,
+		Path:        "creds/web",
+		Storage:     config.StorageView,
+		DisplayName: "test",
+	})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if len(rmq.users) != 0 {
+		t.Fatalf("bad: users: %#v", rmq.users)
+	}
+}
+
 const (
 	envRabbitMQConnectionURI = "RABBITMQ_CONNECTION_URI"
 	envRabbitMQUsername      = "RABBITMQ_USERNAME"
@@ -30,6 +30,10 @@ func pathConfigConnection(b *backend) *framework.Path {
 				Default:     true,
 				Description: `If set, connection_uri is verified by actually connecting to the RabbitMQ management API`,
 			},
+			"password_policy": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the password policy of the passwords of the generated users. If not set, passwords are UUIDs.",
+			},
 		},
 
 		Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -57,6 +61,8 @@ func (b *backend) pathConnectionUpdate(ctx context.Context, req *logical.Request
 		return logical.ErrorResponse("missing password"), nil
 	}
 
+	passwordPolicy := data.Get("password_policy").(string)
+
 	// Don't check the connection_url if verification is disabled
 	verifyConnection := data.Get("verify_connection").(bool)
 	if verifyConnection {
@@ -74,9 +80,10 @@ func (b *backend) pathConnectionUpdate(ctx context.Context, req *logical.Request
 
 	// Store it
 	entry, err := logical.StorageEntryJSON("config/connection", connectionConfig{
-		URI:      uri,
-		Username: username,
-		Password: password,
+		URI:            uri,
+		Username:       username,
+		Password:       password,
+		PasswordPolicy: passwordPolicy,
 	})
 	if err != nil {
 		return nil, err
@@ -101,6 +108,9 @@ type connectionConfig struct {
 
 	// Password for the Username
 	Password string `json:"password"`
+
+	// PasswordPolicy is the name of the password policy of the generated users
+	PasswordPolicy string `json:"password_policy"`
 }
 
 const pathConfigConnectionHelpSyn = `
@@ -112,7 +122,8 @@ This path configures the connection properties used to connect to RabbitMQ manag
 The "connection_uri" parameter is a string that is used to connect to the API. The "username"
 and "password" parameters are strings that are used as credentials to the API. The "verify_connection"
 parameter is a boolean that is used to verify whether the provided connection URI, username, and password
-are valid.
+are valid. The "password_policy" parameter is the name of the password policy, configured in
+sys/policies/password, of the passwords of the generated users.
 
 The URI looks like:
 "http://localhost:15672"
@@ -52,7 +52,7 @@ func (b *backend) pathCredsRead(ctx context.Context, req *logical.Request, d *fr
 	}
 	username := fmt.Sprintf("%s-%s", req.DisplayName, uuidVal)
 
-	password, err := uuid.GenerateUUID()
+	password, err := b.generatePassword(ctx, req.Storage)
 	if err != nil {
 		return nil, err
 	}
@@ -90,6 +90,20 @@ func (b *backend) pathCredsRead(ctx context.Context, req *logical.Request, d *fr
 		}
 	}
 
+	// If the role had vhost topic permissions specified, assign those
+	// permissions to the created username for respective exchanges.
+	for vhost, exchanges := range role.VHostTopics {
+		for exchange, permission := range exchanges {
+			if err := updateTopicPermissionsIn(client, vhost, username, exchange, permission); err != nil {
+				// Delete the user because it's in an unknown state
+				if _, rmErr := client.DeleteUser(username); rmErr != nil {
+					return nil, fmt.Errorf("failed to delete user:%s, err: %s. %s", username, err, rmErr)
+				}
+				return nil, fmt.Errorf("failed to update topic permissions to the %s user. err:%s", username, err)
+			}
+		}
+	}
+
 	// Return the secret
 	resp := b.Secret(SecretCredsType).Response(map[string]interface{}{
 		"username": username,
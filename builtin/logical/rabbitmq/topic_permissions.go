@@ -0,0 +1,55 @@
+package rabbitmq
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/michaelklishin/rabbit-hole"
+)
+
+// topicPermissionsRequest is the body of a request to the topic permissions
+// API of RabbitMQ management, which is not supported by rabbit-hole
+type topicPermissionsRequest struct {
+	Exchange string `json:"exchange"`
+	Write    string `json:"write"`
+	Read     string `json:"read"`
+}
+
+// updateTopicPermissionsIn sets the topic permissions of the user on the
+// exchange of the vhost
+func updateTopicPermissionsIn(client *rabbithole.Client, vhost, username, exchange string, permission topicPermission) error {
+	body, err := json.Marshal(&topicPermissionsRequest{
+		Exchange: exchange,
+		Write:    permission.Write,
+		Read:     permission.Read,
+	})
+	if err != nil {
+		return err
+	}
+
+	url := client.Endpoint + "/api/topic-permissions/" + rabbithole.PathEscape(vhost) + "/" + rabbithole.PathEscape(username)
+	req, err := http.NewRequest(http.MethodPut, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(client.Username, client.Password)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := cleanhttp.DefaultClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected response updating topic permissions: %s", resp.Status)
+	}
+
+	return nil
+}
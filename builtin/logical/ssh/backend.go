@@ -2,6 +2,7 @@ package ssh
 
 import (
 	"context"
+	"net"
 	"strings"
 	"sync"
 
@@ -15,6 +16,18 @@ type backend struct {
 	view      logical.Storage
 	salt      *salt.Salt
 	saltMutex sync.RWMutex
+
+	// caLock serializes changes to the CA key pair, including automatic
+	// rotation.
+	caLock sync.Mutex
+
+	// tidyLock guards tidyStatus, the status of the last tidy operation.
+	tidyLock   sync.RWMutex
+	tidyStatus *tidyStatus
+
+	// lookupHost resolves host names when validating host certificate
+	// principals; it is replaced in tests.
+	lookupHost func(ctx context.Context, host string) ([]string, error)
 }
 
 func Factory(ctx context.Context, conf *logical.BackendConfig) (logical.Backend, error) {
@@ -31,23 +44,25 @@ func Factory(ctx context.Context, conf *logical.BackendConfig) (logical.Backend,
 func Backend(conf *logical.BackendConfig) (*backend, error) {
 	var b backend
 	b.view = conf.StorageView
+	b.lookupHost = net.DefaultResolver.LookupHost
 	b.Backend = &framework.Backend{
 		Help: strings.TrimSpace(backendHelp),
 
 		PathsSpecial: &logical.Paths{
 			Unauthenticated: []string{
 				"verify",
-				"public_key",
 			},
 
 			LocalStorage: []string{
 				"otp/",
+				dynamicKeysStoragePrefix,
 			},
 
 			SealWrapStorage: []string{
 				caPrivateKey,
 				caPrivateKeyStoragePath,
 				"keys/",
+				issuersStoragePrefix,
 			},
 		},
 
@@ -60,8 +75,24 @@ func Backend(conf *logical.BackendConfig) (*backend, error) {
 			pathLookup(&b),
 			pathVerify(&b),
 			pathConfigCA(&b),
+			pathConfigCAAutoRotate(&b),
+			pathConfigCARotation(&b),
+			pathConfigFIPS(&b),
+			pathListIssuers(&b),
+			pathIssuers(&b),
+			pathConfigIssuers(&b),
 			pathSign(&b),
+			pathIssue(&b),
 			pathFetchPublicKey(&b),
+			pathListCerts(&b),
+			pathFetchCert(&b),
+			pathCertsExpiry(&b),
+			pathRevoke(&b),
+			pathFetchKRL(&b),
+			pathVerifyCert(&b),
+			pathTidy(&b),
+			pathTidyStatus(&b),
+			pathMigrateDynamicToCA(&b),
 		},
 
 		Secrets: []*framework.Secret{
@@ -69,8 +100,9 @@ func Backend(conf *logical.BackendConfig) (*backend, error) {
 			secretOTP(&b),
 		},
 
-		Invalidate:  b.invalidate,
-		BackendType: logical.TypeLogical,
+		Invalidate:   b.invalidate,
+		PeriodicFunc: b.periodicFunc,
+		BackendType:  logical.TypeLogical,
 	}
 	return &b, nil
 }
@@ -2,8 +2,16 @@ package ssh
 
 import (
 	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
 	"fmt"
+	"net"
 	"reflect"
+	"regexp"
+	"strconv"
 	"testing"
 	"time"
 
@@ -293,6 +301,83 @@ func TestSSHBackend_RoleList(t *testing.T) {
 	})
 }
 
+func TestSSHBackend_RoleListDetailed(t *testing.T) {
+	testOTPRoleData := map[string]interface{}{
+		"key_type":      testOTPKeyType,
+		"default_user":  testUserName,
+		"allowed_users": "admin",
+		"cidr_list":     testCIDRList,
+	}
+	testCARoleData := map[string]interface{}{
+		"key_type":                "ca",
+		"allow_user_certificates": true,
+		"allowed_users":           "ubuntu",
+		"allowed_user_key_types":  "ed25519",
+		"ttl":                     "1h",
+		"max_ttl":                 "4h",
+	}
+	listStep := func(data, expected map[string]interface{}) logicaltest.TestStep {
+		step := testRoleList(t, expected)
+		step.Data = data
+		return step
+	}
+
+	otpInfo := map[string]interface{}{
+		"key_type":      testOTPKeyType,
+		"default_user":  testUserName,
+		"allowed_users": "admin",
+	}
+	caInfo := map[string]interface{}{
+		"key_type":               "ca",
+		"default_user":           "",
+		"allowed_users":          "ubuntu",
+		"ttl":                    int64(3600),
+		"max_ttl":                int64(14400),
+		"allowed_user_key_types": "ed25519",
+	}
+	logicaltest.Test(t, logicaltest.TestCase{
+		Factory: testingFactory,
+		Steps: []logicaltest.TestStep{
+			testRoleWrite(t, testOTPRoleName, testOTPRoleData),
+			testRoleWrite(t, "ca-role", testCARoleData),
+			listStep(map[string]interface{}{"detailed": true}, map[string]interface{}{
+				"keys": []string{"ca-role", testOTPRoleName},
+				"key_info": map[string]interface{}{
+					"ca-role":       caInfo,
+					testOTPRoleName: otpInfo,
+				},
+			}),
+			listStep(map[string]interface{}{"detailed": "true", "key_type": "ca"}, map[string]interface{}{
+				"keys": []string{"ca-role"},
+				"key_info": map[string]interface{}{
+					"ca-role": caInfo,
+				},
+			}),
+			listStep(map[string]interface{}{"key_type": testOTPKeyType}, map[string]interface{}{
+				"keys": []string{testOTPRoleName},
+				"key_info": map[string]interface{}{
+					testOTPRoleName: map[string]interface{}{
+						"key_type": testOTPKeyType,
+					},
+				},
+			}),
+			listStep(map[string]interface{}{"key_type": testDynamicKeyType}, map[string]interface{}{}),
+			logicaltest.TestStep{
+				Operation: logical.ListOperation,
+				Path:      "roles",
+				Data:      map[string]interface{}{"key_type": "unknown"},
+				ErrorOk:   true,
+				Check: func(resp *logical.Response) error {
+					if resp == nil || !resp.IsError() {
+						return fmt.Errorf("expected an error, got %#v", resp)
+					}
+					return nil
+				},
+			},
+		},
+	})
+}
+
 func TestSSHBackend_DynamicKeyCreate(t *testing.T) {
 	testDynamicRoleData := map[string]interface{}{
 		"key_type":     testDynamicKeyType,
@@ -544,6 +629,111 @@ func TestBackend_AbleToRetrievePublicKey(t *testing.T) {
 	logicaltest.Test(t, testCase)
 }
 
+func TestBackend_PublicKeyDownload(t *testing.T) {
+	config := logical.TestBackendConfig()
+
+	b, err := Factory(context.Background(), config)
+	if err != nil {
+		t.Fatalf("Cannot create backend: %s", err)
+	}
+
+	testCase := logicaltest.TestCase{
+		Backend: b,
+		Steps: []logicaltest.TestStep{
+			configCaStep(),
+
+			logicaltest.TestStep{
+				Operation:       logical.ReadOperation,
+				Path:            "public_key",
+				Unauthenticated: true,
+
+				Check: func(resp *logical.Response) error {
+					if _, ok := resp.Data[logical.HTTPContentDisposition]; ok {
+						return fmt.Errorf("expected inline response, got %v", resp.Data[logical.HTTPContentDisposition])
+					}
+					return nil
+				},
+			},
+
+			logicaltest.TestStep{
+				Operation:       logical.ReadOperation,
+				Path:            "public_key",
+				Unauthenticated: true,
+				Data: map[string]interface{}{
+					"download": "true",
+				},
+
+				Check: func(resp *logical.Response) error {
+					if resp.Data[logical.HTTPContentType] != "text/plain" {
+						return fmt.Errorf("bad content type: %v", resp.Data[logical.HTTPContentType])
+					}
+					disposition := resp.Data[logical.HTTPContentDisposition]
+					if disposition != `attachment; filename="mnt-ca.pub"` {
+						return fmt.Errorf("bad content disposition: %v", disposition)
+					}
+					if key := string(resp.Data[logical.HTTPRawBody].([]byte)); key != publicKey {
+						return fmt.Errorf("public_key incorrect. Expected %v, actual %v", publicKey, key)
+					}
+					return nil
+				},
+			},
+		},
+	}
+
+	logicaltest.Test(t, testCase)
+}
+
+func TestBackend_PublicKeyTrailingNewline(t *testing.T) {
+	config := logical.TestBackendConfig()
+
+	b, err := Factory(context.Background(), config)
+	if err != nil {
+		t.Fatalf("Cannot create backend: %s", err)
+	}
+
+	testCase := logicaltest.TestCase{
+		Backend: b,
+		Steps: []logicaltest.TestStep{
+			logicaltest.TestStep{
+				Operation: logical.UpdateOperation,
+				Path:      "config/ca",
+				Data: map[string]interface{}{
+					"public_key":  strings.TrimSpace(publicKey),
+					"private_key": privateKey,
+				},
+			},
+
+			logicaltest.TestStep{
+				Operation:       logical.ReadOperation,
+				Path:            "public_key",
+				Unauthenticated: true,
+
+				Check: func(resp *logical.Response) error {
+					if key := string(resp.Data[logical.HTTPRawBody].([]byte)); key != publicKey {
+						return fmt.Errorf("public_key incorrect. Expected %q, actual %q", publicKey, key)
+					}
+					return nil
+				},
+			},
+		},
+	}
+
+	logicaltest.Test(t, testCase)
+}
+
+func TestBackend_publicKeyFileName(t *testing.T) {
+	cases := map[string]string{
+		"":                    "ca.pub",
+		"ssh/":                "ssh-ca.pub",
+		"team/client-signer/": "team-client-signer-ca.pub",
+	}
+	for mountPoint, expected := range cases {
+		if actual := publicKeyFileName(mountPoint); actual != expected {
+			t.Fatalf("bad: mount point %q: expected %q, got %q", mountPoint, expected, actual)
+		}
+	}
+}
+
 func TestBackend_AbleToAutoGenerateSigningKeys(t *testing.T) {
 
 	config := logical.TestBackendConfig()
@@ -626,6 +816,208 @@ func TestBackend_ValidPrincipalsValidatedForHostCertificates(t *testing.T) {
 	logicaltest.Test(t, testCase)
 }
 
+func TestBackend_GlobDomainsForHostCertificates(t *testing.T) {
+	config := logical.TestBackendConfig()
+
+	b, err := Factory(context.Background(), config)
+	if err != nil {
+		t.Fatalf("Cannot create backend: %s", err)
+	}
+
+	testCase := logicaltest.TestCase{
+		Backend: b,
+		Steps: []logicaltest.TestStep{
+			configCaStep(),
+
+			createRoleStep("testing", map[string]interface{}{
+				"key_type":                "ca",
+				"allow_host_certificates": true,
+				"allowed_domains":         "web-*.example.com,example.org",
+				"allow_glob_domains":      true,
+			}),
+
+			signCertificateStep("testing", "vault-root-22608f5ef173aabf700797cb95c5641e792698ec6380e8e1eb55523e39aa5e51", ssh.HostCert, []string{"web-01.example.com", "web-02.example.com"}, map[string]string{}, map[string]string{},
+				2*time.Hour, map[string]interface{}{
+					"public_key":       publicKey2,
+					"ttl":              "2h",
+					"cert_type":        "host",
+					"valid_principals": "web-01.example.com,web-02.example.com",
+				}),
+
+			logicaltest.TestStep{
+				Operation: logical.UpdateOperation,
+				Path:      "sign/testing",
+				Data: map[string]interface{}{
+					"public_key":       publicKey2,
+					"cert_type":        "host",
+					"valid_principals": "db-01.example.com",
+				},
+				ErrorOk: true,
+				Check: func(resp *logical.Response) error {
+					if resp.Data["error"] != "db-01.example.com is not a valid value for valid_principals" {
+						return fmt.Errorf("expected principal to be rejected, got %v", resp.Data)
+					}
+					return nil
+				},
+			},
+		},
+	}
+
+	logicaltest.Test(t, testCase)
+}
+
+func TestBackend_validateValidPrincipalForHosts(t *testing.T) {
+	allowed := []string{"example.com", "web-*.example.org"}
+	cases := []struct {
+		role      sshRole
+		principal string
+		expected  bool
+	}{
+		{sshRole{}, "example.com", false},
+		{sshRole{AllowBareDomains: true}, "example.com", true},
+		{sshRole{AllowSubdomains: true}, "host.example.com", true},
+		{sshRole{AllowSubdomains: true}, "badexample.com", false},
+		{sshRole{}, "web-01.example.org", false},
+		{sshRole{AllowGlobDomains: true}, "web-01.example.org", true},
+		{sshRole{AllowGlobDomains: true}, "db-01.example.org", false},
+		// Globs only apply to entries containing a wildcard
+		{sshRole{AllowGlobDomains: true}, "example.com", false},
+	}
+
+	for _, tc := range cases {
+		role := tc.role
+		if actual := validateValidPrincipalForHosts(&role)(allowed, tc.principal); actual != tc.expected {
+			t.Errorf("%+v: principal %q: expected %t, got %t", tc.role, tc.principal, tc.expected, actual)
+		}
+	}
+}
+
+func TestBackend_AlgorithmSigner(t *testing.T) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+
+	b, err := Factory(context.Background(), config)
+	if err != nil {
+		t.Fatalf("Cannot create backend: %s", err)
+	}
+
+	doReq := func(op logical.Operation, path string, data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Operation: op,
+			Path:      path,
+			Storage:   config.StorageView,
+			Data:      data,
+		})
+	}
+
+	resp, err := doReq(logical.UpdateOperation, "config/ca", map[string]interface{}{
+		"public_key":  publicKey,
+		"private_key": privateKey,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: err: %v, resp: %v", err, resp)
+	}
+
+	resp, err = doReq(logical.UpdateOperation, "roles/bad", map[string]interface{}{
+		"key_type":                "ca",
+		"allow_user_certificates": true,
+		"algorithm_signer":        "rsa-sha1",
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error: err: %v, resp: %v", err, resp)
+	}
+
+	cases := []struct {
+		algorithmSigner string
+		expectedFormat  string
+		hash            crypto.Hash
+		hashed          func([]byte) []byte
+	}{
+		{"default", "ssh-rsa", 0, nil},
+		{"ssh-rsa", "ssh-rsa", 0, nil},
+		{"rsa-sha2-256", "rsa-sha2-256", crypto.SHA256, func(data []byte) []byte {
+			digest := sha256.Sum256(data)
+			return digest[:]
+		}},
+		{"rsa-sha2-512", "rsa-sha2-512", crypto.SHA512, func(data []byte) []byte {
+			digest := sha512.Sum512(data)
+			return digest[:]
+		}},
+	}
+	for _, tc := range cases {
+		resp, err := doReq(logical.UpdateOperation, "roles/test", map[string]interface{}{
+			"key_type":                "ca",
+			"allow_user_certificates": true,
+			"allowed_users":           "*",
+			"algorithm_signer":        tc.algorithmSigner,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: err: %v, resp: %v", err, resp)
+		}
+
+		resp, err = doReq(logical.UpdateOperation, "sign/test", map[string]interface{}{
+			"public_key":       publicKey2,
+			"valid_principals": "tuber",
+		})
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("%s: bad: err: %v, resp: %v", tc.algorithmSigner, err, resp)
+		}
+
+		parsedKey, err := parsePublicSSHKey(strings.TrimSpace(resp.Data["signed_key"].(string)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cert := parsedKey.(*ssh.Certificate)
+		if cert.Signature.Format != tc.expectedFormat {
+			t.Fatalf("%s: expected a %s signature, got %s", tc.algorithmSigner, tc.expectedFormat, cert.Signature.Format)
+		}
+
+		if tc.hashed == nil {
+			checker := ssh.CertChecker{
+				IsUserAuthority: func(auth ssh.PublicKey) bool { return true },
+			}
+			if err := checker.CheckCert("tuber", cert); err != nil {
+				t.Fatalf("%s: failed to verify certificate: %v", tc.algorithmSigner, err)
+			}
+			continue
+		}
+
+		// The vendored ssh package cannot verify RFC 8332 signatures itself
+		unsigned := *cert
+		unsigned.Signature = nil
+		signedBytes := unsigned.Marshal()
+		signedBytes = signedBytes[:len(signedBytes)-4]
+		caPublic := cert.SignatureKey.(ssh.CryptoPublicKey).CryptoPublicKey().(*rsa.PublicKey)
+		if err := rsa.VerifyPKCS1v15(caPublic, tc.hash, tc.hashed(signedBytes), cert.Signature.Blob); err != nil {
+			t.Fatalf("%s: failed to verify certificate signature: %v", tc.algorithmSigner, err)
+		}
+	}
+
+	// SHA-1 signatures cannot be requested in FIPS mode
+	resp, err = doReq(logical.UpdateOperation, "config/fips", map[string]interface{}{
+		"fips_mode": true,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: err: %v, resp: %v", err, resp)
+	}
+	resp, err = doReq(logical.UpdateOperation, "roles/test", map[string]interface{}{
+		"key_type":                "ca",
+		"allow_user_certificates": true,
+		"allowed_users":           "*",
+		"algorithm_signer":        "ssh-rsa",
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: err: %v, resp: %v", err, resp)
+	}
+	resp, err = doReq(logical.UpdateOperation, "sign/test", map[string]interface{}{
+		"public_key":       publicKey2,
+		"valid_principals": "tuber",
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error: err: %v, resp: %v", err, resp)
+	}
+}
+
 func TestBackend_OptionsOverrideDefaults(t *testing.T) {
 	config := logical.TestBackendConfig()
 
@@ -762,57 +1154,375 @@ func TestBackend_DisallowUserProvidedKeyIDs(t *testing.T) {
 	logicaltest.Test(t, testCase)
 }
 
-func configCaStep() logicaltest.TestStep {
-	return logicaltest.TestStep{
-		Operation: logical.UpdateOperation,
-		Path:      "config/ca",
-		Data: map[string]interface{}{
-			"public_key":  publicKey,
-			"private_key": privateKey,
-		},
-	}
-}
+func TestBackend_CertComment(t *testing.T) {
+	config := logical.TestBackendConfig()
 
-func createRoleStep(name string, parameters map[string]interface{}) logicaltest.TestStep {
-	return logicaltest.TestStep{
-		Operation: logical.CreateOperation,
-		Path:      "roles/" + name,
-		Data:      parameters,
+	b, err := Factory(context.Background(), config)
+	if err != nil {
+		t.Fatalf("Cannot create backend: %s", err)
 	}
-}
-
-func signCertificateStep(
-	role, keyId string, certType int, validPrincipals []string,
-	criticalOptionPermissions, extensionPermissions map[string]string,
-	ttl time.Duration,
-	requestParameters map[string]interface{}) logicaltest.TestStep {
-	return logicaltest.TestStep{
-		Operation: logical.UpdateOperation,
-		Path:      "sign/" + role,
-		Data:      requestParameters,
-
-		Check: func(resp *logical.Response) error {
-
-			serialNumber := resp.Data["serial_number"].(string)
-			if serialNumber == "" {
-				return errors.New("No serial number in response")
-			}
 
-			signedKey := strings.TrimSpace(resp.Data["signed_key"].(string))
-			if signedKey == "" {
-				return errors.New("No signed key in response")
-			}
+	testCase := logicaltest.TestCase{
+		Backend: b,
+		Steps: []logicaltest.TestStep{
+			configCaStep(),
 
-			key, _ := base64.StdEncoding.DecodeString(strings.Split(signedKey, " ")[1])
+			logicaltest.TestStep{
+				Operation: logical.CreateOperation,
+				Path:      "roles/badregex",
+				Data: map[string]interface{}{
+					"key_type":                "ca",
+					"allow_user_certificates": true,
+					"allowed_comment_regex":   "build-[0-9",
+				},
+				ErrorOk: true,
+				Check: func(resp *logical.Response) error {
+					if !resp.IsError() {
+						return errors.New("expected an invalid allowed_comment_regex to be rejected")
+					}
+					return nil
+				},
+			},
 
-			parsedKey, err := ssh.ParsePublicKey(key)
-			if err != nil {
-				return err
-			}
+			createRoleStep("nocomment", map[string]interface{}{
+				"key_type":                "ca",
+				"allow_user_certificates": true,
+			}),
+			logicaltest.TestStep{
+				Operation: logical.UpdateOperation,
+				Path:      "sign/nocomment",
+				Data: map[string]interface{}{
+					"public_key":   publicKey2,
+					"cert_comment": "build-42",
+				},
+				ErrorOk: true,
+				Check: func(resp *logical.Response) error {
+					if resp.Data["error"] != "setting cert_comment is not allowed by role" {
+						return fmt.Errorf("expected comment to be rejected, got %v", resp.Data)
+					}
+					return nil
+				},
+			},
+			logicaltest.TestStep{
+				Operation: logical.UpdateOperation,
+				Path:      "sign/nocomment",
+				Data: map[string]interface{}{
+					"public_key": publicKey2,
+				},
+				Check: func(resp *logical.Response) error {
+					if fields := strings.Fields(resp.Data["signed_key"].(string)); len(fields) != 2 {
+						return fmt.Errorf("expected no comment on signed key, got %q", resp.Data["signed_key"])
+					}
+					return nil
+				},
+			},
 
-			return validateSSHCertificate(parsedKey.(*ssh.Certificate), keyId, certType, validPrincipals, criticalOptionPermissions, extensionPermissions, ttl)
-		},
-	}
+			createRoleStep("comment", map[string]interface{}{
+				"key_type":                "ca",
+				"allow_user_certificates": true,
+				"allowed_comment_regex":   "build-[0-9]+",
+			}),
+			logicaltest.TestStep{
+				Operation: logical.UpdateOperation,
+				Path:      "sign/comment",
+				Data: map[string]interface{}{
+					"public_key":   publicKey2,
+					"cert_comment": "build-42",
+				},
+				Check: func(resp *logical.Response) error {
+					signedKey := resp.Data["signed_key"].(string)
+					if !strings.HasSuffix(signedKey, " build-42\n") {
+						return fmt.Errorf("expected comment on signed key, got %q", signedKey)
+					}
+					if _, err := parsePublicSSHKey(signedKey); err != nil {
+						return err
+					}
+					return nil
+				},
+			},
+			logicaltest.TestStep{
+				Operation: logical.UpdateOperation,
+				Path:      "sign/comment",
+				Data: map[string]interface{}{
+					"public_key":   publicKey2,
+					"cert_comment": "build-42-evil",
+				},
+				ErrorOk: true,
+				Check: func(resp *logical.Response) error {
+					if !resp.IsError() {
+						return errors.New("expected a non-matching comment to be rejected")
+					}
+					return nil
+				},
+			},
+		},
+	}
+
+	logicaltest.Test(t, testCase)
+}
+
+func TestBackend_EmitSSHConfig(t *testing.T) {
+	config := logical.TestBackendConfig()
+
+	b, err := Factory(context.Background(), config)
+	if err != nil {
+		t.Fatalf("Cannot create backend: %s", err)
+	}
+
+	testCase := logicaltest.TestCase{
+		Backend: b,
+		Steps: []logicaltest.TestStep{
+			configCaStep(),
+
+			createRoleStep("testing", map[string]interface{}{
+				"key_type":                "ca",
+				"allowed_users":           "tuber,admin",
+				"default_user":            "tuber",
+				"allow_user_certificates": true,
+				"allow_host_certificates": true,
+				"allowed_domains":         "example.com",
+				"allow_bare_domains":      true,
+			}),
+
+			logicaltest.TestStep{
+				Operation: logical.UpdateOperation,
+				Path:      "sign/testing",
+				Data: map[string]interface{}{
+					"public_key": publicKey2,
+				},
+				Check: func(resp *logical.Response) error {
+					if _, ok := resp.Data["ssh_config"]; ok {
+						return errors.New("ssh_config returned without emit_ssh_config")
+					}
+					return nil
+				},
+			},
+
+			logicaltest.TestStep{
+				Operation: logical.UpdateOperation,
+				Path:      "sign/testing",
+				Data: map[string]interface{}{
+					"public_key":       publicKey2,
+					"valid_principals": "tuber,admin",
+					"emit_ssh_config":  true,
+					"ssh_config_host":  "*.example.com",
+				},
+				Check: func(resp *logical.Response) error {
+					expected := "Host *.example.com\n" +
+						"    User tuber\n" +
+						"    CertificateFile ~/.ssh/id_rsa-cert.pub\n" +
+						"    # Certificate is also valid for: admin\n"
+					if resp.Data["ssh_config"] != expected {
+						return fmt.Errorf("bad ssh_config: expected %q, got %q", expected, resp.Data["ssh_config"])
+					}
+					return nil
+				},
+			},
+
+			logicaltest.TestStep{
+				Operation: logical.UpdateOperation,
+				Path:      "sign/testing",
+				Data: map[string]interface{}{
+					"public_key":       publicKey2,
+					"cert_type":        "host",
+					"valid_principals": "example.com",
+					"emit_ssh_config":  true,
+				},
+				ErrorOk: true,
+				Check: func(resp *logical.Response) error {
+					if resp.Data["error"] != "emit_ssh_config is only supported for user certificates" {
+						return fmt.Errorf("expected emit_ssh_config to be rejected for host certificates, got %v", resp.Data)
+					}
+					return nil
+				},
+			},
+		},
+	}
+
+	logicaltest.Test(t, testCase)
+}
+
+func TestBackend_ValidateHostPrincipalsDNS(t *testing.T) {
+	config := logical.TestBackendConfig()
+
+	b, err := Backend(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Setup(context.Background(), config); err != nil {
+		t.Fatal(err)
+	}
+
+	var lookedUp []string
+	b.lookupHost = func(ctx context.Context, host string) ([]string, error) {
+		lookedUp = append(lookedUp, host)
+		if _, ok := ctx.Deadline(); !ok {
+			return nil, errors.New("lookup is not bounded by a deadline")
+		}
+		if host == "web.example.com" {
+			return []string{"192.0.2.10"}, nil
+		}
+		return nil, &net.DNSError{Err: "no such host", Name: host}
+	}
+
+	testCase := logicaltest.TestCase{
+		Backend: b,
+		Steps: []logicaltest.TestStep{
+			configCaStep(),
+
+			createRoleStep("nodns", map[string]interface{}{
+				"key_type":                "ca",
+				"allow_host_certificates": true,
+				"allowed_domains":         "example.com",
+				"allow_subdomains":        true,
+			}),
+			logicaltest.TestStep{
+				Operation: logical.UpdateOperation,
+				Path:      "sign/nodns",
+				Data: map[string]interface{}{
+					"public_key":       publicKey2,
+					"cert_type":        "host",
+					"valid_principals": "typo.example.com",
+				},
+				Check: func(resp *logical.Response) error {
+					if len(lookedUp) != 0 {
+						return fmt.Errorf("unexpected DNS lookups: %v", lookedUp)
+					}
+					return nil
+				},
+			},
+
+			createRoleStep("dns", map[string]interface{}{
+				"key_type":                     "ca",
+				"allow_host_certificates":      true,
+				"allowed_domains":              "example.com",
+				"allow_subdomains":             true,
+				"validate_host_principals_dns": true,
+			}),
+			logicaltest.TestStep{
+				Operation: logical.UpdateOperation,
+				Path:      "sign/dns",
+				Data: map[string]interface{}{
+					"public_key":       publicKey2,
+					"cert_type":        "host",
+					"valid_principals": "web.example.com",
+				},
+			},
+			logicaltest.TestStep{
+				Operation: logical.UpdateOperation,
+				Path:      "sign/dns",
+				Data: map[string]interface{}{
+					"public_key":       publicKey2,
+					"cert_type":        "host",
+					"valid_principals": "web.example.com,typo.example.com,db.example.com",
+				},
+				ErrorOk: true,
+				Check: func(resp *logical.Response) error {
+					expected := "valid_principals do not resolve in DNS: typo.example.com, db.example.com"
+					if resp.Data["error"] != expected {
+						return fmt.Errorf("bad error: expected %q, got %v", expected, resp.Data["error"])
+					}
+					return nil
+				},
+			},
+		},
+	}
+
+	logicaltest.Test(t, testCase)
+}
+
+func TestBackend_PrincipalOrderPreserved(t *testing.T) {
+	config := logical.TestBackendConfig()
+
+	b, err := Factory(context.Background(), config)
+	if err != nil {
+		t.Fatalf("Cannot create backend: %s", err)
+	}
+
+	principals := []string{"zeta", "alpha", "mu", "beta"}
+	steps := []logicaltest.TestStep{
+		configCaStep(),
+
+		createRoleStep("testing", map[string]interface{}{
+			"key_type":                "ca",
+			"allowed_users":           "*",
+			"default_user":            "zeta,alpha,zeta",
+			"allow_user_certificates": true,
+		}),
+	}
+
+	// Repeated signs must yield the same order every time
+	for i := 0; i < 10; i++ {
+		steps = append(steps, signCertificateStep("testing", "vault-root-22608f5ef173aabf700797cb95c5641e792698ec6380e8e1eb55523e39aa5e51", ssh.UserCert, principals, map[string]string{}, map[string]string{}, 2*time.Hour, map[string]interface{}{
+			"public_key":       publicKey2,
+			"ttl":              "2h",
+			"valid_principals": "zeta,alpha,mu,alpha,beta",
+		}))
+	}
+
+	// The role's default keeps its order as well
+	steps = append(steps, signCertificateStep("testing", "vault-root-22608f5ef173aabf700797cb95c5641e792698ec6380e8e1eb55523e39aa5e51", ssh.UserCert, []string{"zeta", "alpha"}, map[string]string{}, map[string]string{}, 2*time.Hour, map[string]interface{}{
+		"public_key": publicKey2,
+		"ttl":        "2h",
+	}))
+
+	logicaltest.Test(t, logicaltest.TestCase{
+		Backend: b,
+		Steps:   steps,
+	})
+}
+
+func configCaStep() logicaltest.TestStep {
+	return logicaltest.TestStep{
+		Operation: logical.UpdateOperation,
+		Path:      "config/ca",
+		Data: map[string]interface{}{
+			"public_key":  publicKey,
+			"private_key": privateKey,
+		},
+	}
+}
+
+func createRoleStep(name string, parameters map[string]interface{}) logicaltest.TestStep {
+	return logicaltest.TestStep{
+		Operation: logical.CreateOperation,
+		Path:      "roles/" + name,
+		Data:      parameters,
+	}
+}
+
+func signCertificateStep(
+	role, keyId string, certType int, validPrincipals []string,
+	criticalOptionPermissions, extensionPermissions map[string]string,
+	ttl time.Duration,
+	requestParameters map[string]interface{}) logicaltest.TestStep {
+	return logicaltest.TestStep{
+		Operation: logical.UpdateOperation,
+		Path:      "sign/" + role,
+		Data:      requestParameters,
+
+		Check: func(resp *logical.Response) error {
+
+			serialNumber := resp.Data["serial_number"].(string)
+			if serialNumber == "" {
+				return errors.New("No serial number in response")
+			}
+
+			signedKey := strings.TrimSpace(resp.Data["signed_key"].(string))
+			if signedKey == "" {
+				return errors.New("No signed key in response")
+			}
+
+			key, _ := base64.StdEncoding.DecodeString(strings.Split(signedKey, " ")[1])
+
+			parsedKey, err := ssh.ParsePublicKey(key)
+			if err != nil {
+				return err
+			}
+
+			return validateSSHCertificate(parsedKey.(*ssh.Certificate), keyId, certType, validPrincipals, criticalOptionPermissions, extensionPermissions, ttl)
+		},
+	}
 }
 
 func validateSSHCertificate(cert *ssh.Certificate, keyId string, certType int, validPrincipals []string, criticalOptionPermissions, extensionPermissions map[string]string,
@@ -1091,3 +1801,673 @@ func testCredsWrite(t *testing.T, roleName string, data map[string]interface{},
 		},
 	}
 }
+
+func TestBackend_UserKeyRestrictions(t *testing.T) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+
+	b, err := Factory(context.Background(), config)
+	if err != nil {
+		t.Fatalf("Cannot create backend: %s", err)
+	}
+
+	doReq := func(op logical.Operation, path string, data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Operation: op,
+			Path:      path,
+			Storage:   config.StorageView,
+			Data:      data,
+		})
+	}
+
+	resp, err := doReq(logical.UpdateOperation, "config/ca", map[string]interface{}{
+		"public_key":  publicKey,
+		"private_key": privateKey,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: err: %v, resp: %v", err, resp)
+	}
+
+	for _, roleData := range []map[string]interface{}{
+		{"allowed_user_key_types": "rsa,dss"},
+		{"user_key_min_bits": map[string]interface{}{"dss": 1024}},
+		{"user_key_min_bits": map[string]interface{}{"rsa": "large"}},
+	} {
+		roleData["key_type"] = "ca"
+		roleData["allow_user_certificates"] = true
+		resp, err = doReq(logical.UpdateOperation, "roles/bad", roleData)
+		if err != nil || resp == nil || !resp.IsError() {
+			t.Fatalf("expected an error for %v: err: %v, resp: %v", roleData, err, resp)
+		}
+	}
+
+	resp, err = doReq(logical.UpdateOperation, "roles/test", map[string]interface{}{
+		"key_type":                "ca",
+		"allow_user_certificates": true,
+		"allowed_users":           "*",
+		"allowed_user_key_types":  "rsa,ecdsa",
+		"user_key_min_bits":       map[string]interface{}{"rsa": 3072, "ecdsa": 384},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: err: %v, resp: %v", err, resp)
+	}
+
+	cases := []struct {
+		keyType string
+		keyBits int
+		allowed bool
+	}{
+		{caKeyTypeRSA, 2048, false},
+		{caKeyTypeRSA, 3072, true},
+		{caKeyTypeECDSAP256, 0, false},
+		{caKeyTypeECDSAP384, 0, true},
+		{caKeyTypeEd25519, 0, false},
+	}
+	for _, tc := range cases {
+		userPublicKey, _, err := generateSSHKeyPair(rand.Reader, tc.keyType, tc.keyBits)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resp, err := doReq(logical.UpdateOperation, "sign/test", map[string]interface{}{
+			"public_key":       userPublicKey,
+			"valid_principals": "ubuntu",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tc.allowed == (resp != nil && resp.IsError()) {
+			t.Fatalf("%s %d: expected allowed to be %t, got resp: %v", tc.keyType, tc.keyBits, tc.allowed, resp)
+		}
+	}
+}
+
+func TestBackend_NotBeforeDuration(t *testing.T) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+
+	b, err := Factory(context.Background(), config)
+	if err != nil {
+		t.Fatalf("Cannot create backend: %s", err)
+	}
+
+	doReq := func(op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: op,
+			Path:      path,
+			Storage:   config.StorageView,
+			Data:      data,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: path: %s, err: %v, resp: %v", path, err, resp)
+		}
+		return resp
+	}
+
+	doReq(logical.UpdateOperation, "config/ca", map[string]interface{}{
+		"public_key":  publicKey,
+		"private_key": privateKey,
+	})
+
+	cases := []struct {
+		notBeforeDuration interface{}
+		expected          time.Duration
+	}{
+		{nil, 30 * time.Second},
+		{"1h", time.Hour},
+		{0, 0},
+	}
+	for _, tc := range cases {
+		roleData := map[string]interface{}{
+			"key_type":                "ca",
+			"allow_user_certificates": true,
+			"allowed_users":           "*",
+		}
+		if tc.notBeforeDuration != nil {
+			roleData["not_before_duration"] = tc.notBeforeDuration
+		}
+		doReq(logical.UpdateOperation, "roles/test", roleData)
+
+		resp := doReq(logical.ReadOperation, "roles/test", nil)
+		if resp.Data["not_before_duration"] != int64(tc.expected.Seconds()) {
+			t.Fatalf("bad: not_before_duration: expected %v, got %v", int64(tc.expected.Seconds()), resp.Data["not_before_duration"])
+		}
+
+		now := time.Now()
+		resp = doReq(logical.UpdateOperation, "sign/test", map[string]interface{}{
+			"public_key":       publicKey2,
+			"valid_principals": "ubuntu",
+		})
+		parsedKey, err := parsePublicSSHKey(resp.Data["signed_key"].(string))
+		if err != nil {
+			t.Fatal(err)
+		}
+		validAfter := time.Unix(int64(parsedKey.(*ssh.Certificate).ValidAfter), 0)
+		if diff := now.Add(-tc.expected).Sub(validAfter); diff < -2*time.Second || diff > 2*time.Second {
+			t.Fatalf("bad: expected valid after %s, got %s", now.Add(-tc.expected), validAfter)
+		}
+	}
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "roles/test",
+		Storage:   config.StorageView,
+		Data: map[string]interface{}{
+			"key_type":                "ca",
+			"allow_user_certificates": true,
+			"not_before_duration":     -10,
+		},
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error: err: %v, resp: %v", err, resp)
+	}
+}
+
+func TestBackend_SignBatch(t *testing.T) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+
+	b, err := Factory(context.Background(), config)
+	if err != nil {
+		t.Fatalf("Cannot create backend: %s", err)
+	}
+
+	doReq := func(op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: op,
+			Path:      path,
+			Storage:   config.StorageView,
+			Data:      data,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: path: %s, err: %v, resp: %v", path, err, resp)
+		}
+		return resp
+	}
+
+	doReq(logical.UpdateOperation, "config/ca", map[string]interface{}{
+		"public_key":  publicKey,
+		"private_key": privateKey,
+	})
+	doReq(logical.UpdateOperation, "roles/test", map[string]interface{}{
+		"key_type":                "ca",
+		"allow_user_certificates": true,
+		"allowed_users":           "*",
+		"allow_user_key_ids":      true,
+	})
+
+	otherPublicKey, _, err := generateSSHKeyPair(rand.Reader, caKeyTypeEd25519, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp := doReq(logical.UpdateOperation, "sign/test", map[string]interface{}{
+		"valid_principals": "ubuntu",
+		"batch_input": []interface{}{
+			map[string]interface{}{"public_key": publicKey2, "key_id": "ci-1"},
+			map[string]interface{}{"public_key": "not a key", "key_id": "ci-2"},
+			map[string]interface{}{"public_key": otherPublicKey},
+		},
+	})
+
+	results := resp.Data["batch_results"].([]map[string]interface{})
+	if len(results) != 3 {
+		t.Fatalf("bad: expected 3 results, got %d", len(results))
+	}
+	if results[1]["error"] == nil || results[1]["signed_key"] != nil {
+		t.Fatalf("bad: expected an error for the invalid key, got %v", results[1])
+	}
+
+	for i, expectedKeyId := range map[int]string{0: "ci-1", 2: "vault-"} {
+		if results[i]["error"] != nil {
+			t.Fatalf("bad: result %d: %v", i, results[i]["error"])
+		}
+		parsedKey, err := parsePublicSSHKey(results[i]["signed_key"].(string))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cert := parsedKey.(*ssh.Certificate)
+		if !strings.HasPrefix(cert.KeyId, expectedKeyId) {
+			t.Fatalf("bad: result %d: expected key ID %q, got %q", i, expectedKeyId, cert.KeyId)
+		}
+		if !reflect.DeepEqual(cert.ValidPrincipals, []string{"ubuntu"}) {
+			t.Fatalf("bad: result %d: principals: %v", i, cert.ValidPrincipals)
+		}
+	}
+	if results[0]["serial_number"] == results[2]["serial_number"] {
+		t.Fatalf("bad: duplicate serial numbers")
+	}
+}
+
+func TestBackend_OTPFormat(t *testing.T) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+
+	b, err := Factory(context.Background(), config)
+	if err != nil {
+		t.Fatalf("Cannot create backend: %s", err)
+	}
+
+	doReq := func(path string, data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Storage:   config.StorageView,
+			Data:      data,
+		})
+	}
+
+	for _, roleData := range []map[string]interface{}{
+		{"otp_length": 4},
+		{"otp_length": 256},
+		{"otp_charset": "0123456789"},
+		{"otp_length": 8, "otp_charset": "0"},
+		{"otp_length": 8, "otp_charset": "0123456789 "},
+		{"otp_length": 8, "otp_charset": "00123"},
+		// Too little entropy
+		{"otp_length": 6, "otp_charset": "01"},
+		{"otp_length": 19, "otp_charset": "0123456789"},
+		{"otp_length": 10},
+	} {
+		roleData["key_type"] = testOTPKeyType
+		roleData["default_user"] = testUserName
+		roleData["cidr_list"] = testCIDRList
+		resp, err := doReq("roles/bad", roleData)
+		if err != nil || resp == nil || !resp.IsError() {
+			t.Fatalf("expected an error for %v: err: %v, resp: %v", roleData, err, resp)
+		}
+	}
+
+	cases := []struct {
+		roleData map[string]interface{}
+		pattern  string
+	}{
+		{map[string]interface{}{}, `^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`},
+		{map[string]interface{}{"otp_length": 20, "otp_charset": "0123456789"}, `^[0-9]{20}$`},
+		{map[string]interface{}{"otp_length": 20}, `^[A-Za-z0-9]{20}$`},
+	}
+	for _, tc := range cases {
+		tc.roleData["key_type"] = testOTPKeyType
+		tc.roleData["default_user"] = testUserName
+		tc.roleData["cidr_list"] = testCIDRList
+		resp, err := doReq("roles/test", tc.roleData)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: err: %v, resp: %v", err, resp)
+		}
+
+		resp, err = doReq("creds/test", map[string]interface{}{
+			"ip": testIP,
+		})
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("bad: err: %v, resp: %v", err, resp)
+		}
+		otp := resp.Data["key"].(string)
+		if !regexp.MustCompile(tc.pattern).MatchString(otp) {
+			t.Fatalf("bad: OTP %q does not match %q", otp, tc.pattern)
+		}
+
+		resp, err = doReq("verify", map[string]interface{}{
+			"otp": otp,
+		})
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("bad: err: %v, resp: %v", err, resp)
+		}
+		if resp.Data["username"] != testUserName {
+			t.Fatalf("bad: verify: %v", resp.Data)
+		}
+	}
+}
+
+func TestBackend_TTLCapping(t *testing.T) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	config.System = &logical.StaticSystemView{
+		DefaultLeaseTTLVal: time.Hour,
+		MaxLeaseTTLVal:     24 * time.Hour,
+	}
+
+	b, err := Factory(context.Background(), config)
+	if err != nil {
+		t.Fatalf("Cannot create backend: %s", err)
+	}
+
+	doReq := func(path string, data map[string]interface{}) *logical.Response {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Storage:   config.StorageView,
+			Data:      data,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: path: %s, err: %v, resp: %v", path, err, resp)
+		}
+		return resp
+	}
+
+	doReq("config/ca", map[string]interface{}{
+		"public_key":  publicKey,
+		"private_key": privateKey,
+	})
+
+	// A role max TTL above the mount's is accepted with a warning
+	resp := doReq("roles/long", map[string]interface{}{
+		"key_type":                "ca",
+		"allow_user_certificates": true,
+		"allowed_users":           "*",
+		"max_ttl":                 "48h",
+	})
+	if resp == nil || len(resp.Warnings) != 1 {
+		t.Fatalf("bad: expected a warning, got %#v", resp)
+	}
+	doReq("roles/short", map[string]interface{}{
+		"key_type":                "ca",
+		"allow_user_certificates": true,
+		"allowed_users":           "*",
+		"ttl":                     "30m",
+		"max_ttl":                 "2h",
+	})
+
+	cases := []struct {
+		role     string
+		ttl      interface{}
+		expected time.Duration
+		warning  bool
+	}{
+		// Role, mount and system defaults
+		{"short", nil, 30 * time.Minute, false},
+		{"long", nil, time.Hour, false},
+		// Requested TTLs within the limits
+		{"short", "90m", 90 * time.Minute, false},
+		{"long", "20h", 20 * time.Hour, false},
+		// Requested TTLs are capped to the role max TTL, which is capped to
+		// the mount max TTL
+		{"short", "3h", 2 * time.Hour, true},
+		{"long", "36h", 24 * time.Hour, true},
+	}
+	for _, tc := range cases {
+		data := map[string]interface{}{
+			"public_key":       publicKey2,
+			"valid_principals": "ubuntu",
+		}
+		if tc.ttl != nil {
+			data["ttl"] = tc.ttl
+		}
+		resp := doReq("sign/"+tc.role, data)
+
+		parsedKey, err := parsePublicSSHKey(resp.Data["signed_key"].(string))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cert := parsedKey.(*ssh.Certificate)
+		validFor := time.Duration(cert.ValidBefore-cert.ValidAfter)*time.Second - defaultNotBeforeDuration
+		if diff := validFor - tc.expected; diff < -2*time.Second || diff > 2*time.Second {
+			t.Fatalf("%s %v: expected a TTL of %s, got %s", tc.role, tc.ttl, tc.expected, validFor)
+		}
+		if tc.warning != (len(resp.Warnings) != 0) {
+			t.Fatalf("%s %v: expected warning %t, got %v", tc.role, tc.ttl, tc.warning, resp.Warnings)
+		}
+	}
+}
+
+func TestBackend_CriticalOptions(t *testing.T) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	config.System = &logical.StaticSystemView{
+		DefaultLeaseTTLVal: time.Hour,
+		MaxLeaseTTLVal:     24 * time.Hour,
+		EntityVal: &logical.Entity{
+			ID:       "entity-id",
+			Name:     "alice",
+			Metadata: map[string]string{"office_cidr": "10.1.0.0/16"},
+		},
+	}
+
+	b, err := Factory(context.Background(), config)
+	if err != nil {
+		t.Fatalf("Cannot create backend: %s", err)
+	}
+
+	doReq := func(path string, data map[string]interface{}) *logical.Response {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Storage:   config.StorageView,
+			Data:      data,
+			EntityID:  "entity-id",
+		})
+		if err != nil {
+			t.Fatalf("bad: path: %s, err: %v", path, err)
+		}
+		return resp
+	}
+
+	signCriticalOptions := func(data map[string]interface{}) map[string]string {
+		data["public_key"] = publicKey2
+		data["valid_principals"] = "ubuntu"
+		resp := doReq("sign/test", data)
+		if resp == nil || resp.IsError() {
+			t.Fatalf("bad: %#v", resp)
+		}
+		parsedKey, err := parsePublicSSHKey(resp.Data["signed_key"].(string))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsedKey.(*ssh.Certificate).CriticalOptions
+	}
+
+	doReq("config/ca", map[string]interface{}{
+		"public_key":  publicKey,
+		"private_key": privateKey,
+	})
+
+	resp := doReq("roles/test", map[string]interface{}{
+		"key_type":                 "ca",
+		"allow_user_certificates":  true,
+		"allowed_users":            "*",
+		"allowed_critical_options": "source-address",
+		"default_critical_options": map[string]interface{}{
+			"source-address": "not-an-address",
+		},
+	})
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error for an invalid source-address, got %#v", resp)
+	}
+
+	resp = doReq("roles/test", map[string]interface{}{
+		"key_type":                          "ca",
+		"allow_user_certificates":           true,
+		"allowed_users":                     "*",
+		"allowed_critical_options":          "source-address",
+		"default_critical_options_template": true,
+		"default_critical_options": map[string]interface{}{
+			"force-command":  "/usr/local/bin/audit-shell {{identity.entity.name}}",
+			"source-address": "{{identity.entity.metadata.office_cidr}}",
+		},
+	})
+	if resp != nil && resp.IsError() {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// The defaults are rendered for the entity
+	criticalOptions := signCriticalOptions(map[string]interface{}{})
+	expected := map[string]string{
+		"force-command":  "/usr/local/bin/audit-shell alice",
+		"source-address": "10.1.0.0/16",
+	}
+	if !reflect.DeepEqual(criticalOptions, expected) {
+		t.Fatalf("bad: expected %v, got %v", expected, criticalOptions)
+	}
+
+	// Requesting an allowed option keeps the default force-command, which is
+	// not on the allowed list
+	criticalOptions = signCriticalOptions(map[string]interface{}{
+		"critical_options": map[string]interface{}{
+			"source-address": "10.1.2.3,192.168.0.0/24",
+		},
+	})
+	expected = map[string]string{
+		"force-command":  "/usr/local/bin/audit-shell alice",
+		"source-address": "10.1.2.3,192.168.0.0/24",
+	}
+	if !reflect.DeepEqual(criticalOptions, expected) {
+		t.Fatalf("bad: expected %v, got %v", expected, criticalOptions)
+	}
+
+	for _, criticalOptions := range []map[string]interface{}{
+		{"force-command": "/bin/bash"},
+		{"source-address": "10.1.2.3,anywhere"},
+	} {
+		resp = doReq("sign/test", map[string]interface{}{
+			"public_key":       publicKey2,
+			"valid_principals": "ubuntu",
+			"critical_options": criticalOptions,
+		})
+		if resp == nil || !resp.IsError() {
+			t.Fatalf("expected an error for %v, got %#v", criticalOptions, resp)
+		}
+	}
+}
+
+func TestBackend_DeniedUsersAndExtensions(t *testing.T) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+
+	b, err := Factory(context.Background(), config)
+	if err != nil {
+		t.Fatalf("Cannot create backend: %s", err)
+	}
+
+	doReq := func(path string, data map[string]interface{}) *logical.Response {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Storage:   config.StorageView,
+			Data:      data,
+		})
+		if err != nil {
+			t.Fatalf("bad: path: %s, err: %v", path, err)
+		}
+		return resp
+	}
+
+	doReq("config/ca", map[string]interface{}{
+		"public_key":  publicKey,
+		"private_key": privateKey,
+	})
+
+	roleData := map[string]interface{}{
+		"key_type":                "ca",
+		"allow_user_certificates": true,
+		"allowed_users":           "*",
+		"default_user":            "ubuntu",
+		"default_extensions":      map[string]interface{}{"permit-pty": ""},
+		"denied_users":            "root,admin",
+		"denied_extensions":       "permit-port-forwarding",
+	}
+	if resp := doReq("roles/test", roleData); resp != nil && resp.IsError() {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// Denied defaults are rejected
+	for field, value := range map[string]interface{}{
+		"default_user":       "root",
+		"default_extensions": map[string]interface{}{"permit-port-forwarding": ""},
+	} {
+		data := map[string]interface{}{}
+		for k, v := range roleData {
+			data[k] = v
+		}
+		data[field] = value
+		if resp := doReq("roles/invalid", data); resp == nil || !resp.IsError() {
+			t.Fatalf("expected an error for %s, got %#v", field, resp)
+		}
+	}
+
+	for _, tc := range []struct {
+		principals string
+		extensions map[string]interface{}
+		allowed    bool
+	}{
+		{"ubuntu", nil, true},
+		{"", nil, true},
+		{"ubuntu,root", nil, false},
+		{"admin", nil, false},
+		{"ubuntu", map[string]interface{}{"permit-agent-forwarding": ""}, true},
+		{"ubuntu", map[string]interface{}{"permit-pty": "", "permit-port-forwarding": ""}, false},
+	} {
+		data := map[string]interface{}{
+			"public_key": publicKey2,
+			"extensions": tc.extensions,
+		}
+		if tc.principals != "" {
+			data["valid_principals"] = tc.principals
+		}
+		resp := doReq("sign/test", data)
+		if allowed := resp != nil && !resp.IsError(); allowed != tc.allowed {
+			t.Fatalf("bad: principals %q, extensions %v: expected allowed: %t, got %#v", tc.principals, tc.extensions, tc.allowed, resp)
+		}
+	}
+}
+
+func TestBackend_SignResponseFields(t *testing.T) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+
+	b, err := Factory(context.Background(), config)
+	if err != nil {
+		t.Fatalf("Cannot create backend: %s", err)
+	}
+
+	doReq := func(path string, data map[string]interface{}) *logical.Response {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Storage:   config.StorageView,
+			Data:      data,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: path: %s, err: %v, resp: %v", path, err, resp)
+		}
+		return resp
+	}
+
+	doReq("config/ca", map[string]interface{}{
+		"public_key":  publicKey,
+		"private_key": privateKey,
+	})
+	doReq("roles/test", map[string]interface{}{
+		"key_type":                 "ca",
+		"allow_user_certificates":  true,
+		"allowed_users":            "*",
+		"default_extensions":       map[string]interface{}{"permit-pty": ""},
+		"default_critical_options": map[string]interface{}{"force-command": "/bin/true"},
+	})
+
+	resp := doReq("sign/test", map[string]interface{}{
+		"public_key":       publicKey2,
+		"valid_principals": "ubuntu,admin",
+	})
+
+	parsedKey, err := parsePublicSSHKey(resp.Data["signed_key"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert := parsedKey.(*ssh.Certificate)
+
+	// The fields describe the signed certificate
+	expected := map[string]interface{}{
+		"serial_number":    strconv.FormatUint(cert.Serial, 16),
+		"key_id":           cert.KeyId,
+		"cert_type":        "user",
+		"valid_principals": []string{"ubuntu", "admin"},
+		"critical_options": map[string]string{"force-command": "/bin/true"},
+		"extensions":       map[string]string{"permit-pty": ""},
+		"valid_after":      int64(cert.ValidAfter),
+		"valid_before":     int64(cert.ValidBefore),
+	}
+	for key, value := range expected {
+		if !reflect.DeepEqual(resp.Data[key], value) {
+			t.Fatalf("bad: %s: expected %#v, got %#v", key, value, resp.Data[key])
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package ssh
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/vault/helper/errutil"
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+var identityTemplateRegex = regexp.MustCompile(`{{\s*([^}\s]*)\s*}}`)
+
+// renderIdentityTemplate replaces the identity templates in the given value
+// with the values of the entity. The supported templates are:
+//
+//	{{identity.entity.id}}
+//	{{identity.entity.name}}
+//	{{identity.entity.metadata.<key>}}
+//	{{identity.entity.aliases.<mount accessor>.name}}
+//
+// An error is returned if a template cannot be resolved for the entity.
+func renderIdentityTemplate(value string, entity *logical.Entity) (string, error) {
+	if !identityTemplateRegex.MatchString(value) {
+		return value, nil
+	}
+	if entity == nil {
+		return "", fmt.Errorf("no entity is associated with the request")
+	}
+
+	var renderErr error
+	rendered := identityTemplateRegex.ReplaceAllStringFunc(value, func(match string) string {
+		if renderErr != nil {
+			return ""
+		}
+		result, err := resolveIdentityTemplate(identityTemplateRegex.FindStringSubmatch(match)[1], entity)
+		if err != nil {
+			renderErr = err
+			return ""
+		}
+		return result
+	})
+	if renderErr != nil {
+		return "", renderErr
+	}
+
+	return rendered, nil
+}
+
+func resolveIdentityTemplate(tpl string, entity *logical.Entity) (string, error) {
+	switch {
+	case tpl == "identity.entity.id":
+		return entity.ID, nil
+
+	case tpl == "identity.entity.name":
+		if entity.Name == "" {
+			return "", fmt.Errorf("entity has no name")
+		}
+		return entity.Name, nil
+
+	case strings.HasPrefix(tpl, "identity.entity.metadata."):
+		key := strings.TrimPrefix(tpl, "identity.entity.metadata.")
+		value, ok := entity.Metadata[key]
+		if !ok || value == "" {
+			return "", fmt.Errorf("entity has no metadata %q", key)
+		}
+		return value, nil
+
+	case strings.HasPrefix(tpl, "identity.entity.aliases.") && strings.HasSuffix(tpl, ".name"):
+		accessor := strings.TrimSuffix(strings.TrimPrefix(tpl, "identity.entity.aliases."), ".name")
+		for _, alias := range entity.Aliases {
+			if alias.MountAccessor == accessor {
+				return alias.Name, nil
+			}
+		}
+		return "", fmt.Errorf("entity has no alias for mount accessor %q", accessor)
+	}
+
+	return "", fmt.Errorf("unsupported template %q", "{{"+tpl+"}}")
+}
+
+// requestEntity returns the entity of the client making the request, or nil
+// if the token is not tied to an entity.
+func (b *backend) requestEntity(req *logical.Request) (*logical.Entity, error) {
+	if req.EntityID == "" {
+		return nil, nil
+	}
+
+	entity, err := b.System().EntityInfo(req.EntityID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up entity: %v", err)
+	}
+
+	return entity, nil
+}
+
+// calculateUsers returns the default user and the allowed users of the role
+// with their identity templates rendered, if enabled. Allowed users whose
+// templates cannot be resolved for the entity are left out, so that the
+// remaining ones can still be requested. The default user must resolve only
+// when it is used, i.e. no valid_principals are requested.
+func (b *backend) calculateUsers(data *framework.FieldData, req *logical.Request, role *sshRole) (string, string, error) {
+	defaultUser := role.DefaultUser
+	allowedUsers := role.AllowedUsers
+	if !role.DefaultUserTemplate && !role.AllowedUsersTemplate {
+		return defaultUser, allowedUsers, nil
+	}
+
+	entity, err := b.requestEntity(req)
+	if err != nil {
+		return "", "", err
+	}
+
+	if role.DefaultUserTemplate {
+		rendered, err := renderIdentityTemplate(defaultUser, entity)
+		if err != nil {
+			if _, ok := data.GetOk("valid_principals"); !ok {
+				return "", "", errutil.UserError{Err: fmt.Sprintf("failed to render default_user: %v", err)}
+			}
+		}
+		defaultUser = rendered
+	}
+
+	if role.AllowedUsersTemplate && allowedUsers != "*" {
+		var rendered []string
+		for _, user := range strutil.ParseStringSlice(allowedUsers, ",") {
+			renderedUser, err := renderIdentityTemplate(user, entity)
+			if err != nil || renderedUser == "" {
+				continue
+			}
+			rendered = append(rendered, renderedUser)
+		}
+		allowedUsers = strings.Join(rendered, ",")
+	}
+
+	return defaultUser, allowedUsers, nil
+}
@@ -0,0 +1,163 @@
+package ssh
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestSSH_renderIdentityTemplate(t *testing.T) {
+	entity := &logical.Entity{
+		ID:       "entity-id",
+		Name:     "alice",
+		Metadata: map[string]string{"team": "infra"},
+		Aliases: []*logical.Alias{
+			{MountType: "userpass", MountAccessor: "auth_userpass_1234", Name: "asmith"},
+		},
+	}
+
+	cases := []struct {
+		value    string
+		expected string
+		err      bool
+	}{
+		{"root", "root", false},
+		{"{{identity.entity.id}}", "entity-id", false},
+		{"{{ identity.entity.name }}", "alice", false},
+		{"{{identity.entity.metadata.team}}-admin", "infra-admin", false},
+		{"{{identity.entity.aliases.auth_userpass_1234.name}}", "asmith", false},
+		{"{{identity.entity.aliases.auth_ldap_5678.name}}", "", true},
+		{"{{identity.entity.metadata.missing}}", "", true},
+		{"{{identity.groups.names}}", "", true},
+	}
+	for _, c := range cases {
+		rendered, err := renderIdentityTemplate(c.value, entity)
+		if (err != nil) != c.err {
+			t.Fatalf("%q: expected error %t, got %v", c.value, c.err, err)
+		}
+		if rendered != c.expected {
+			t.Fatalf("%q: expected %q, got %q", c.value, c.expected, rendered)
+		}
+	}
+
+	if _, err := renderIdentityTemplate("{{identity.entity.id}}", nil); err == nil {
+		t.Fatal("expected an error without an entity")
+	}
+}
+
+func TestSSH_SignIdentityTemplates(t *testing.T) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	config.System = &logical.StaticSystemView{
+		DefaultLeaseTTLVal: 24 * time.Hour,
+		MaxLeaseTTLVal:     2 * 24 * time.Hour,
+		EntityVal: &logical.Entity{
+			ID:       "entity-id",
+			Name:     "alice",
+			Metadata: map[string]string{"team": "infra"},
+			Aliases: []*logical.Alias{
+				{MountType: "userpass", MountAccessor: "auth_userpass_1234", Name: "asmith"},
+			},
+		},
+	}
+
+	b, err := Factory(context.Background(), config)
+	if err != nil {
+		t.Fatalf("Cannot create backend: %s", err)
+	}
+
+	doReq := func(path, entityID string, data map[string]interface{}) *logical.Response {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Storage:   config.StorageView,
+			Data:      data,
+			EntityID:  entityID,
+		})
+		if err != nil {
+			t.Fatalf("bad: path: %s, err: %v", path, err)
+		}
+		return resp
+	}
+
+	resp := doReq("config/ca", "", map[string]interface{}{
+		"public_key":  publicKey,
+		"private_key": privateKey,
+	})
+	if resp != nil && resp.IsError() {
+		t.Fatalf("bad: %#v", resp)
+	}
+	resp = doReq("roles/test", "", map[string]interface{}{
+		"key_type":                    "ca",
+		"allow_user_certificates":     true,
+		"allowed_users":               "ubuntu,{{identity.entity.aliases.auth_userpass_1234.name}},{{identity.entity.aliases.auth_ldap_5678.name}}",
+		"allowed_users_template":      true,
+		"default_user":                "{{identity.entity.aliases.auth_userpass_1234.name}}",
+		"default_user_template":       true,
+		"default_extensions":          map[string]interface{}{"login@example.com": "{{identity.entity.metadata.team}}"},
+		"default_extensions_template": true,
+	})
+	if resp != nil && resp.IsError() {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	parseCert := func(resp *logical.Response) *ssh.Certificate {
+		if resp == nil || resp.IsError() {
+			t.Fatalf("bad: %#v", resp)
+		}
+		parsedKey, err := parsePublicSSHKey(resp.Data["signed_key"].(string))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsedKey.(*ssh.Certificate)
+	}
+
+	// The default user and extensions are rendered for the entity
+	cert := parseCert(doReq("sign/test", "entity-id", map[string]interface{}{
+		"public_key": publicKey2,
+	}))
+	if !reflect.DeepEqual(cert.ValidPrincipals, []string{"asmith"}) {
+		t.Fatalf("bad: principals: %v", cert.ValidPrincipals)
+	}
+	if !reflect.DeepEqual(cert.Extensions, map[string]string{"login@example.com": "infra"}) {
+		t.Fatalf("bad: extensions: %v", cert.Extensions)
+	}
+
+	// Static entries keep working along with the rendered ones
+	cert = parseCert(doReq("sign/test", "entity-id", map[string]interface{}{
+		"public_key":       publicKey2,
+		"valid_principals": "ubuntu,asmith",
+	}))
+	if !reflect.DeepEqual(cert.ValidPrincipals, []string{"ubuntu", "asmith"}) {
+		t.Fatalf("bad: principals: %v", cert.ValidPrincipals)
+	}
+
+	// Users of other entities cannot be requested
+	resp = doReq("sign/test", "entity-id", map[string]interface{}{
+		"public_key":       publicKey2,
+		"valid_principals": "bob",
+	})
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error, got %#v", resp)
+	}
+
+	// Without an entity, the default user cannot be rendered and only the
+	// static users are allowed
+	resp = doReq("sign/test", "", map[string]interface{}{
+		"public_key": publicKey2,
+	})
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error, got %#v", resp)
+	}
+	resp = doReq("sign/test", "", map[string]interface{}{
+		"public_key":       publicKey2,
+		"valid_principals": "asmith",
+	})
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error, got %#v", resp)
+	}
+}
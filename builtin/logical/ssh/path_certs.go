@@ -0,0 +1,288 @@
+package ssh
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+	"golang.org/x/crypto/ssh"
+)
+
+const issuedCertsStoragePrefix = "certs/"
+
+// issuedCertificate is the metadata stored for every certificate signed by
+// the backend, keyed by its serial number.
+type issuedCertificate struct {
+	SerialNumber    string   `json:"serial_number" structs:"serial_number" mapstructure:"serial_number"`
+	KeyID           string   `json:"key_id" structs:"key_id" mapstructure:"key_id"`
+	CertType        string   `json:"cert_type" structs:"cert_type" mapstructure:"cert_type"`
+	ValidPrincipals []string `json:"valid_principals" structs:"valid_principals" mapstructure:"valid_principals"`
+	ValidAfter      int64    `json:"valid_after" structs:"valid_after" mapstructure:"valid_after"`
+	ValidBefore     int64    `json:"valid_before" structs:"valid_before" mapstructure:"valid_before"`
+	RoleName        string   `json:"role_name" structs:"role_name" mapstructure:"role_name"`
+	Issuer          string   `json:"issuer" structs:"issuer" mapstructure:"issuer"`
+	SignedKey       string   `json:"signed_key" structs:"signed_key" mapstructure:"signed_key"`
+}
+
+func pathListCerts(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "certs/?$",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ListOperation: b.pathCertsList,
+		},
+
+		HelpSynopsis:    pathListCertsHelpSyn,
+		HelpDescription: pathListCertsHelpDesc,
+	}
+}
+
+func pathFetchCert(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "cert/" + framework.GenericNameRegex("serial"),
+		Fields: map[string]*framework.FieldSchema{
+			"serial": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: `Serial number of the certificate, in hexadecimal.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathCertRead,
+		},
+
+		HelpSynopsis:    pathFetchCertHelpSyn,
+		HelpDescription: pathFetchCertHelpDesc,
+	}
+}
+
+func pathCertsExpiry(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "certs/expiry",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathCertsExpiryRead,
+		},
+
+		HelpSynopsis:    pathCertsExpiryHelpSyn,
+		HelpDescription: pathCertsExpiryHelpDesc,
+	}
+}
+
+// certificateFields returns the contents of a certificate as response data,
+// in the form they are reported by the endpoints of the backend.
+func certificateFields(cert *ssh.Certificate) map[string]interface{} {
+	return map[string]interface{}{
+		"serial_number":    strconv.FormatUint(cert.Serial, 16),
+		"key_id":           cert.KeyId,
+		"cert_type":        certificateTypeName(cert),
+		"valid_principals": cert.ValidPrincipals,
+		"critical_options": cert.CriticalOptions,
+		"extensions":       cert.Extensions,
+		"valid_after":      int64(cert.ValidAfter),
+		"valid_before":     int64(cert.ValidBefore),
+	}
+}
+
+func certificateTypeName(cert *ssh.Certificate) string {
+	if cert.CertType == ssh.HostCert {
+		return "host"
+	}
+	return "user"
+}
+
+func storeIssuedCertificate(ctx context.Context, s logical.Storage, roleName, issuer string, certificate *ssh.Certificate, signedKey string) error {
+	serial := strconv.FormatUint(certificate.Serial, 16)
+	entry, err := logical.StorageEntryJSON(issuedCertsStoragePrefix+serial, &issuedCertificate{
+		SerialNumber:    serial,
+		KeyID:           certificate.KeyId,
+		CertType:        certificateTypeName(certificate),
+		ValidPrincipals: certificate.ValidPrincipals,
+		ValidAfter:      int64(certificate.ValidAfter),
+		ValidBefore:     int64(certificate.ValidBefore),
+		RoleName:        roleName,
+		Issuer:          issuer,
+		SignedKey:       signedKey,
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.Put(ctx, entry)
+}
+
+func getIssuedCertificate(ctx context.Context, s logical.Storage, serial string) (*issuedCertificate, error) {
+	entry, err := s.Get(ctx, issuedCertsStoragePrefix+serial)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var result issuedCertificate
+	if err := entry.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// normalizeSerial returns the hexadecimal serial number in the form
+// certificates are stored under: lower case, without leading zeros.
+func normalizeSerial(serial string) (string, error) {
+	value, err := strconv.ParseUint(strings.ToLower(serial), 16, 64)
+	if err != nil {
+		return "", fmt.Errorf("invalid serial_number %q", serial)
+	}
+	return strconv.FormatUint(value, 16), nil
+}
+
+func (b *backend) pathCertsList(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	serials, err := req.Storage.List(ctx, issuedCertsStoragePrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	return logical.ListResponse(serials), nil
+}
+
+func (b *backend) pathCertRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	serial, err := normalizeSerial(d.Get("serial").(string))
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	cert, err := getIssuedCertificate(ctx, req.Storage, serial)
+	if err != nil {
+		return nil, err
+	}
+	if cert == nil {
+		return nil, nil
+	}
+
+	var revocationTime int64
+	revoked, err := getRevokedCertificate(ctx, req.Storage, serial)
+	if err != nil {
+		return nil, err
+	}
+	if revoked != nil {
+		revocationTime = revoked.RevocationTime.Unix()
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"serial_number":    cert.SerialNumber,
+			"key_id":           cert.KeyID,
+			"cert_type":        cert.CertType,
+			"valid_principals": cert.ValidPrincipals,
+			"valid_after":      cert.ValidAfter,
+			"valid_before":     cert.ValidBefore,
+			"role_name":        cert.RoleName,
+			"issuer":           cert.Issuer,
+			"signed_key":       cert.SignedKey,
+			"revocation_time":  revocationTime,
+		},
+	}, nil
+}
+
+// expiryBuckets are the upper bounds of the time-to-expiry ranges reported
+// by certs/expiry, in ascending order. Certificates outliving the last bound
+// are counted separately.
+var expiryBuckets = []struct {
+	name  string
+	bound time.Duration
+}{
+	{"1h", time.Hour},
+	{"1d", 24 * time.Hour},
+	{"7d", 7 * 24 * time.Hour},
+}
+
+func (b *backend) pathCertsExpiryRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	serials, err := req.Storage.List(ctx, issuedCertsStoragePrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make([]int, len(expiryBuckets)+1)
+	total := 0
+	now := time.Now()
+
+	// Only the counters are kept around, so memory use does not grow with
+	// the number of stored certificates.
+	for _, serial := range serials {
+		cert, err := getIssuedCertificate(ctx, req.Storage, serial)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read certificate %q: %v", serial, err)
+		}
+		if cert == nil {
+			continue
+		}
+
+		remaining := time.Unix(cert.ValidBefore, 0).Sub(now)
+		if remaining <= 0 {
+			continue
+		}
+
+		bucket := len(expiryBuckets)
+		for i, eb := range expiryBuckets {
+			if remaining < eb.bound {
+				bucket = i
+				break
+			}
+		}
+		counts[bucket]++
+		total++
+	}
+
+	distribution := map[string]interface{}{}
+	for i, eb := range expiryBuckets {
+		distribution["lt_"+eb.name] = counts[i]
+	}
+	distribution["gte_"+expiryBuckets[len(expiryBuckets)-1].name] = counts[len(expiryBuckets)]
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"total":        total,
+			"distribution": distribution,
+		},
+	}, nil
+}
+
+const pathListCertsHelpSyn = `
+List the serial numbers of the certificates signed by this backend.
+`
+
+const pathListCertsHelpDesc = `
+This path lists the serial numbers, in hexadecimal, of all certificates this
+backend has signed, including expired and revoked ones. Use 'cert/<serial>' to
+read the details of a certificate.
+`
+
+const pathFetchCertHelpSyn = `
+Read the details of a certificate signed by this backend.
+`
+
+const pathFetchCertHelpDesc = `
+This path returns the metadata recorded when the certificate with the given
+serial number was signed: its key ID, type, principals, validity period, the
+role and issuer it was signed with and the signed key itself. A non-zero
+'revocation_time' indicates the certificate has been revoked.
+`
+
+const pathCertsExpiryHelpSyn = `
+Count the live certificates by time remaining until they expire.
+`
+
+const pathCertsExpiryHelpDesc = `
+This path returns the number of certificates signed by this backend that have
+not yet expired, bucketed by the time left until they expire: less than an
+hour ('lt_1h'), less than a day ('lt_1d'), less than seven days ('lt_7d') and
+seven days or more ('gte_7d'). Each certificate is counted in the smallest
+bucket it fits in. The distribution is computed over the stored certificates
+on every read.
+`
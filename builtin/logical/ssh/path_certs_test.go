@@ -0,0 +1,164 @@
+package ssh
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestSSH_CertsExpiry(t *testing.T) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	// Certificates are capped to the mount's max TTL
+	config.System = &logical.StaticSystemView{
+		DefaultLeaseTTLVal: 24 * time.Hour,
+		MaxLeaseTTLVal:     720 * time.Hour,
+	}
+
+	b, err := Factory(context.Background(), config)
+	if err != nil {
+		t.Fatalf("Cannot create backend: %s", err)
+	}
+
+	doReq := func(op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: op,
+			Path:      path,
+			Storage:   config.StorageView,
+			Data:      data,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: path: %s, err: %v, resp: %v", path, err, resp)
+		}
+		return resp
+	}
+
+	doReq(logical.UpdateOperation, "config/ca", map[string]interface{}{
+		"public_key":  publicKey,
+		"private_key": privateKey,
+	})
+	doReq(logical.UpdateOperation, "roles/test", map[string]interface{}{
+		"key_type":                "ca",
+		"allow_user_certificates": true,
+		"max_ttl":                 "720h",
+	})
+
+	for _, ttl := range []string{"30m", "2h", "3h", "48h", "200h"} {
+		resp := doReq(logical.UpdateOperation, "sign/test", map[string]interface{}{
+			"public_key": publicKey2,
+			"ttl":        ttl,
+		})
+
+		// Signed certificates are recorded
+		cert, err := getIssuedCertificate(context.Background(), config.StorageView, resp.Data["serial_number"].(string))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cert == nil || cert.RoleName != "test" || cert.SignedKey != resp.Data["signed_key"] {
+			t.Fatalf("bad: stored certificate: %#v", cert)
+		}
+	}
+
+	// Expired certificates are not counted
+	entry, err := logical.StorageEntryJSON(issuedCertsStoragePrefix+"1", &issuedCertificate{
+		SerialNumber: "1",
+		ValidBefore:  time.Now().Add(-time.Hour).Unix(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := config.StorageView.Put(context.Background(), entry); err != nil {
+		t.Fatal(err)
+	}
+
+	resp := doReq(logical.ReadOperation, "certs/expiry", nil)
+	expected := map[string]interface{}{
+		"lt_1h":  1,
+		"lt_1d":  2,
+		"lt_7d":  1,
+		"gte_7d": 1,
+	}
+	if !reflect.DeepEqual(resp.Data["distribution"], expected) {
+		t.Fatalf("bad: expected %#v, got %#v", expected, resp.Data["distribution"])
+	}
+	if resp.Data["total"] != 5 {
+		t.Fatalf("bad: expected 5 live certificates, got %v", resp.Data["total"])
+	}
+}
+
+func TestSSH_CertsListRead(t *testing.T) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+
+	b, err := Factory(context.Background(), config)
+	if err != nil {
+		t.Fatalf("Cannot create backend: %s", err)
+	}
+
+	doReq := func(op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: op,
+			Path:      path,
+			Storage:   config.StorageView,
+			Data:      data,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: path: %s, err: %v, resp: %v", path, err, resp)
+		}
+		return resp
+	}
+
+	doReq(logical.UpdateOperation, "config/ca", map[string]interface{}{
+		"public_key":  publicKey,
+		"private_key": privateKey,
+	})
+	doReq(logical.UpdateOperation, "roles/test", map[string]interface{}{
+		"key_type":                "ca",
+		"allow_user_certificates": true,
+		"allowed_users":           "*",
+		"allow_user_key_ids":      true,
+	})
+
+	resp := doReq(logical.UpdateOperation, "sign/test", map[string]interface{}{
+		"public_key":       publicKey2,
+		"valid_principals": "tuber,root",
+		"key_id":           "audit-me",
+	})
+	serial := resp.Data["serial_number"].(string)
+	signedKey := resp.Data["signed_key"]
+
+	resp = doReq(logical.ListOperation, "certs/", nil)
+	if !reflect.DeepEqual(resp.Data["keys"], []string{serial}) {
+		t.Fatalf("bad: expected [%s], got %v", serial, resp.Data["keys"])
+	}
+
+	// Serials are accepted with leading zeros and in upper case
+	resp = doReq(logical.ReadOperation, "cert/00"+strings.ToUpper(serial), nil)
+	if resp == nil {
+		t.Fatalf("expected certificate %s to be found", serial)
+	}
+	if resp.Data["serial_number"] != serial || resp.Data["role_name"] != "test" || resp.Data["issuer"] != "default" ||
+		resp.Data["cert_type"] != "user" || resp.Data["key_id"] != "audit-me" || resp.Data["signed_key"] != signedKey ||
+		!reflect.DeepEqual(resp.Data["valid_principals"], []string{"tuber", "root"}) {
+		t.Fatalf("bad certificate: %#v", resp.Data)
+	}
+	if resp.Data["revocation_time"] != int64(0) {
+		t.Fatalf("expected certificate not to be revoked, got %v", resp.Data["revocation_time"])
+	}
+
+	doReq(logical.UpdateOperation, "revoke", map[string]interface{}{
+		"serial_number": serial,
+	})
+	resp = doReq(logical.ReadOperation, "cert/"+serial, nil)
+	if resp.Data["revocation_time"] == int64(0) {
+		t.Fatalf("expected certificate to be revoked")
+	}
+
+	if resp = doReq(logical.ReadOperation, "cert/abcdef", nil); resp != nil {
+		t.Fatalf("expected no certificate, got %#v", resp.Data)
+	}
+}
@@ -2,15 +2,21 @@ package ssh
 
 import (
 	"context"
-	"crypto/rand"
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
 	"fmt"
+	"io"
+	"time"
 
 	multierror "github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/vault/helper/errutil"
+	"github.com/hashicorp/vault/helper/fips"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
+	"golang.org/x/crypto/ed25519"
 	"golang.org/x/crypto/ssh"
 )
 
@@ -23,28 +29,66 @@ const (
 	caPrivateKeyStoragePathDeprecated = "config/ca_bundle"
 )
 
+const (
+	caKeyTypeRSA       = "rsa"
+	caKeyTypeECDSAP256 = "ecdsa-p256"
+	caKeyTypeECDSAP384 = "ecdsa-p384"
+	caKeyTypeECDSAP521 = "ecdsa-p521"
+	caKeyTypeEd25519   = "ed25519"
+
+	defaultCARSAKeyBits = 4096
+)
+
 type keyStorageEntry struct {
-	Key string `json:"key" structs:"key" mapstructure:"key"`
+	Key          string    `json:"key" structs:"key" mapstructure:"key"`
+	CreationTime time.Time `json:"creation_time" structs:"creation_time" mapstructure:"creation_time"`
+
+	// Generation is the value of the CA generation counter when the key pair
+	// was stored; zero for key pairs stored before it was kept.
+	Generation uint64 `json:"generation" structs:"generation" mapstructure:"generation"`
+}
+
+// caKeyPairFields returns the fields used to import or generate a CA key
+// pair, shared by config/ca and the issuers paths.
+func caKeyPairFields() map[string]*framework.FieldSchema {
+	return map[string]*framework.FieldSchema{
+		"private_key": &framework.FieldSchema{
+			Type:        framework.TypeString,
+			Description: `Private half of the SSH key that will be used to sign certificates.`,
+		},
+		"public_key": &framework.FieldSchema{
+			Type:        framework.TypeString,
+			Description: `Public half of the SSH key that will be used to sign certificates.`,
+		},
+		"generate_signing_key": &framework.FieldSchema{
+			Type:        framework.TypeBool,
+			Description: `Generate SSH key pair internally rather than use the private_key and public_key fields.`,
+			Default:     true,
+		},
+		"key_type": &framework.FieldSchema{
+			Type:        framework.TypeString,
+			Description: `Type of the key pair to generate; one of "rsa", "ecdsa-p256", "ecdsa-p384", "ecdsa-p521" or "ed25519".`,
+			Default:     caKeyTypeRSA,
+			AllowedValues: []interface{}{
+				caKeyTypeRSA,
+				caKeyTypeECDSAP256,
+				caKeyTypeECDSAP384,
+				caKeyTypeECDSAP521,
+				caKeyTypeEd25519,
+			},
+		},
+		"key_bits": &framework.FieldSchema{
+			Type:          framework.TypeInt,
+			Description:   `Size in bits of a generated RSA key; one of 2048, 3072 or 4096. Defaults to 4096. Not applicable to other key types.`,
+			AllowedValues: []interface{}{0, 2048, 3072, 4096},
+		},
+	}
 }
 
 func pathConfigCA(b *backend) *framework.Path {
 	return &framework.Path{
 		Pattern: "config/ca",
-		Fields: map[string]*framework.FieldSchema{
-			"private_key": &framework.FieldSchema{
-				Type:        framework.TypeString,
-				Description: `Private half of the SSH key that will be used to sign certificates.`,
-			},
-			"public_key": &framework.FieldSchema{
-				Type:        framework.TypeString,
-				Description: `Public half of the SSH key that will be used to sign certificates.`,
-			},
-			"generate_signing_key": &framework.FieldSchema{
-				Type:        framework.TypeBool,
-				Description: `Generate SSH key pair internally rather than use the private_key and public_key fields.`,
-				Default:     true,
-			},
-		},
+		Fields:  caKeyPairFields(),
 
 		Callbacks: map[logical.Operation]framework.OperationFunc{
 			logical.UpdateOperation: b.pathConfigCAUpdate,
@@ -74,20 +118,35 @@ func (b *backend) pathConfigCARead(ctx context.Context, req *logical.Request, da
 
 	response := &logical.Response{
 		Data: map[string]interface{}{
-			"public_key": publicKeyEntry.Key,
+			"public_key":    publicKeyEntry.Key,
+			"ca_generation": publicKeyEntry.Generation,
 		},
 	}
 
+	previousKeyEntry, err := previousCAPublicKey(ctx, req.Storage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read previous CA public key: %v", err)
+	}
+	if previousKeyEntry != nil {
+		response.Data["previous_public_key"] = previousKeyEntry.Key
+	}
+
 	return response, nil
 }
 
 func (b *backend) pathConfigCADelete(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	b.caLock.Lock()
+	defer b.caLock.Unlock()
+
 	if err := req.Storage.Delete(ctx, caPrivateKeyStoragePath); err != nil {
 		return nil, err
 	}
 	if err := req.Storage.Delete(ctx, caPublicKeyStoragePath); err != nil {
 		return nil, err
 	}
+	if err := req.Storage.Delete(ctx, caPreviousPublicKeyStoragePath); err != nil {
+		return nil, err
+	}
 	return nil, nil
 }
 
@@ -144,7 +203,116 @@ func caKey(ctx context.Context, storage logical.Storage, keyType string) (*keySt
 }
 
 func (b *backend) pathConfigCAUpdate(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	var err error
+	publicKey, privateKey, generateSigningKey, err := b.caKeyPairFromRequest(ctx, req, data)
+	switch err.(type) {
+	case nil:
+	case errutil.UserError:
+		return logical.ErrorResponse(err.Error()), nil
+	default:
+		return nil, err
+	}
+
+	b.caLock.Lock()
+	defer b.caLock.Unlock()
+
+	configured, err := caKeyPairConfigured(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if configured {
+		return nil, fmt.Errorf("keys are already configured; delete them before reconfiguring")
+	}
+
+	if err := storeCAKeyPair(ctx, req.Storage, publicKey, privateKey); err != nil {
+		return nil, err
+	}
+
+	if generateSigningKey {
+		response := &logical.Response{
+			Data: map[string]interface{}{
+				"public_key": publicKey,
+			},
+		}
+
+		return response, nil
+	}
+
+	return nil, nil
+}
+
+// caKeyPairConfigured reports whether either half of the CA key pair of the
+// backend is configured.
+func caKeyPairConfigured(ctx context.Context, s logical.Storage) (bool, error) {
+	publicKeyEntry, err := caKey(ctx, s, caPublicKey)
+	if err != nil {
+		return false, fmt.Errorf("failed to read CA public key: %v", err)
+	}
+
+	privateKeyEntry, err := caKey(ctx, s, caPrivateKey)
+	if err != nil {
+		return false, fmt.Errorf("failed to read CA private key: %v", err)
+	}
+
+	return (publicKeyEntry != nil && publicKeyEntry.Key != "") || (privateKeyEntry != nil && privateKeyEntry.Key != ""), nil
+}
+
+// storeCAKeyPair stores the CA key pair of the backend. The caller must hold
+// caLock and have checked that no key pair is configured.
+func storeCAKeyPair(ctx context.Context, s logical.Storage, publicKey, privateKey string) error {
+	generation, err := nextCAGeneration(ctx, s)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	entry, err := logical.StorageEntryJSON(caPublicKeyStoragePath, &keyStorageEntry{
+		Key:          publicKey,
+		CreationTime: now,
+		Generation:   generation,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Save the public key
+	err = s.Put(ctx, entry)
+	if err != nil {
+		return err
+	}
+
+	entry, err = logical.StorageEntryJSON(caPrivateKeyStoragePath, &keyStorageEntry{
+		Key:          privateKey,
+		CreationTime: now,
+		Generation:   generation,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Save the private key
+	err = s.Put(ctx, entry)
+	if err != nil {
+		var mErr *multierror.Error
+
+		mErr = multierror.Append(mErr, fmt.Errorf("failed to store CA private key: %v", err))
+
+		// If storing private key fails, the corresponding public key should be
+		// removed
+		if delErr := s.Delete(ctx, caPublicKeyStoragePath); delErr != nil {
+			mErr = multierror.Append(mErr, fmt.Errorf("failed to cleanup CA public key: %v", delErr))
+			return mErr
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// caKeyPairFromRequest returns the CA key pair described by the fields of
+// caKeyPairFields, generating it if requested, and whether it was generated.
+// Invalid requests are reported as errutil.UserError.
+func (b *backend) caKeyPairFromRequest(ctx context.Context, req *logical.Request, data *framework.FieldData) (string, string, bool, error) {
 	publicKey := data.Get("public_key").(string)
 	privateKey := data.Get("private_key").(string)
 
@@ -155,7 +323,7 @@ func (b *backend) pathConfigCAUpdate(ctx context.Context, req *logical.Request,
 	// explicitly set true
 	case ok && generateSigningKeyRaw.(bool):
 		if publicKey != "" || privateKey != "" {
-			return logical.ErrorResponse("public_key and private_key must not be set when generate_signing_key is set to true"), nil
+			return "", "", false, errutil.UserError{Err: "public_key and private_key must not be set when generate_signing_key is set to true"}
 		}
 
 		generateSigningKey = true
@@ -163,21 +331,21 @@ func (b *backend) pathConfigCAUpdate(ctx context.Context, req *logical.Request,
 	// explicitly set to false, or not set and we have both a public and private key
 	case ok, publicKey != "" && privateKey != "":
 		if publicKey == "" {
-			return logical.ErrorResponse("missing public_key"), nil
+			return "", "", false, errutil.UserError{Err: "missing public_key"}
 		}
 
 		if privateKey == "" {
-			return logical.ErrorResponse("missing private_key"), nil
+			return "", "", false, errutil.UserError{Err: "missing private_key"}
 		}
 
 		_, err := ssh.ParsePrivateKey([]byte(privateKey))
 		if err != nil {
-			return logical.ErrorResponse(fmt.Sprintf("Unable to parse private_key as an SSH private key: %v", err)), nil
+			return "", "", false, errutil.UserError{Err: fmt.Sprintf("Unable to parse private_key as an SSH private key: %v", err)}
 		}
 
 		_, err = parsePublicSSHKey(publicKey)
 		if err != nil {
-			return logical.ErrorResponse(fmt.Sprintf("Unable to parse public_key as an SSH public key: %v", err)), nil
+			return "", "", false, errutil.UserError{Err: fmt.Sprintf("Unable to parse public_key as an SSH public key: %v", err)}
 		}
 
 	// not set and no public/private key provided so generate
@@ -186,100 +354,163 @@ func (b *backend) pathConfigCAUpdate(ctx context.Context, req *logical.Request,
 
 	// not set, but one or the other supplied
 	default:
-		return logical.ErrorResponse("only one of public_key and private_key set; both must be set to use, or both must be blank to auto-generate"), nil
+		return "", "", false, errutil.UserError{Err: "only one of public_key and private_key set; both must be set to use, or both must be blank to auto-generate"}
 	}
 
 	if generateSigningKey {
-		publicKey, privateKey, err = generateSSHKeyPair()
+		keyType := data.Get("key_type").(string)
+		keyBits := data.Get("key_bits").(int)
+		if err := validateCAKeyTypeAndBits(keyType, keyBits); err != nil {
+			return "", "", false, errutil.UserError{Err: err.Error()}
+		}
+
+		var err error
+		publicKey, privateKey, err = generateSSHKeyPair(b.GetRandomReader(), keyType, keyBits)
 		if err != nil {
-			return nil, err
+			return "", "", false, err
 		}
 	}
 
 	if publicKey == "" || privateKey == "" {
-		return nil, fmt.Errorf("failed to generate or parse the keys")
+		return "", "", false, fmt.Errorf("failed to generate or parse the keys")
 	}
 
-	publicKeyEntry, err := caKey(ctx, req.Storage, caPublicKey)
+	fipsMode, err := b.fipsModeEnabled(ctx, req.Storage)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read CA public key: %v", err)
+		return "", "", false, err
 	}
-
-	privateKeyEntry, err := caKey(ctx, req.Storage, caPrivateKey)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read CA private key: %v", err)
+	if fipsMode {
+		parsedPublicKey, err := parsePublicSSHKey(publicKey)
+		if err != nil {
+			return "", "", false, fmt.Errorf("failed to parse public key: %v", err)
+		}
+		if err := validateFIPSPublicKey(parsedPublicKey); err != nil {
+			return "", "", false, errutil.UserError{Err: fmt.Sprintf("CA key is not FIPS compliant: %v", err)}
+		}
 	}
 
-	if (publicKeyEntry != nil && publicKeyEntry.Key != "") || (privateKeyEntry != nil && privateKeyEntry.Key != "") {
-		return nil, fmt.Errorf("keys are already configured; delete them before reconfiguring")
-	}
+	return publicKey, privateKey, generateSigningKey, nil
+}
 
-	entry, err := logical.StorageEntryJSON(caPublicKeyStoragePath, &keyStorageEntry{
-		Key: publicKey,
-	})
-	if err != nil {
-		return nil, err
+// validateCAKeyTypeAndBits checks the parameters of a key pair to be
+// generated, either for the CA or by the issue endpoint. A zero keyBits
+// selects the default for the key type.
+func validateCAKeyTypeAndBits(keyType string, keyBits int) error {
+	switch keyType {
+	case caKeyTypeRSA:
+		switch keyBits {
+		case 0, 2048, 3072, 4096:
+		default:
+			return fmt.Errorf("invalid key_bits %d for key_type %q; must be 2048, 3072 or 4096", keyBits, keyType)
+		}
+	case caKeyTypeECDSAP256, caKeyTypeECDSAP384, caKeyTypeECDSAP521, caKeyTypeEd25519:
+		if keyBits != 0 {
+			return fmt.Errorf("key_bits is not applicable to key_type %q", keyType)
+		}
+	default:
+		return fmt.Errorf("unknown key_type %q", keyType)
 	}
 
-	// Save the public key
-	err = req.Storage.Put(ctx, entry)
-	if err != nil {
-		return nil, err
-	}
+	return nil
+}
 
-	entry, err = logical.StorageEntryJSON(caPrivateKeyStoragePath, &keyStorageEntry{
-		Key: privateKey,
-	})
-	if err != nil {
-		return nil, err
+// generateSSHKeyPair generates a key pair of the given type from the random
+// data of randReader, returning the public key in authorized_keys format and
+// the private key PEM encoded.
+func generateSSHKeyPair(randReader io.Reader, keyType string, keyBits int) (string, string, error) {
+	// Only the key types accepted by validateFIPSPublicKey are generated in
+	// the FIPS mode of Vault
+	approved := keyType != caKeyTypeEd25519 && keyType != caKeyTypeECDSAP521 &&
+		(keyType != caKeyTypeRSA || keyBits == 0 || keyBits >= fipsMinRSAKeyBits)
+	if err := fips.CheckApproved(fmt.Sprintf("key type %q", keyType), approved); err != nil {
+		return "", "", err
 	}
 
-	// Save the private key
-	err = req.Storage.Put(ctx, entry)
-	if err != nil {
-		var mErr *multierror.Error
-
-		mErr = multierror.Append(mErr, fmt.Errorf("failed to store CA private key: %v", err))
+	var publicKey interface{}
+	var privateBlock *pem.Block
 
-		// If storing private key fails, the corresponding public key should be
-		// removed
-		if delErr := req.Storage.Delete(ctx, caPublicKeyStoragePath); delErr != nil {
-			mErr = multierror.Append(mErr, fmt.Errorf("failed to cleanup CA public key: %v", delErr))
-			return nil, mErr
+	switch keyType {
+	case caKeyTypeRSA:
+		if keyBits == 0 {
+			keyBits = defaultCARSAKeyBits
+		}
+		rsaKey, err := rsa.GenerateKey(randReader, keyBits)
+		if err != nil {
+			return "", "", err
+		}
+		publicKey = &rsaKey.PublicKey
+		privateBlock = &pem.Block{
+			Type:  "RSA PRIVATE KEY",
+			Bytes: x509.MarshalPKCS1PrivateKey(rsaKey),
 		}
 
-		return nil, err
-	}
+	case caKeyTypeECDSAP256, caKeyTypeECDSAP384, caKeyTypeECDSAP521:
+		curve := map[string]elliptic.Curve{
+			caKeyTypeECDSAP256: elliptic.P256(),
+			caKeyTypeECDSAP384: elliptic.P384(),
+			caKeyTypeECDSAP521: elliptic.P521(),
+		}[keyType]
+		ecKey, err := ecdsa.GenerateKey(curve, randReader)
+		if err != nil {
+			return "", "", err
+		}
+		der, err := x509.MarshalECPrivateKey(ecKey)
+		if err != nil {
+			return "", "", err
+		}
+		publicKey = &ecKey.PublicKey
+		privateBlock = &pem.Block{
+			Type:  "EC PRIVATE KEY",
+			Bytes: der,
+		}
 
-	if generateSigningKey {
-		response := &logical.Response{
-			Data: map[string]interface{}{
-				"public_key": publicKey,
-			},
+	case caKeyTypeEd25519:
+		edPublic, edPrivate, err := ed25519.GenerateKey(randReader)
+		if err != nil {
+			return "", "", err
+		}
+		publicKey = edPublic
+		privateBlock, err = marshalOpenSSHEd25519PrivateKey(edPublic, edPrivate)
+		if err != nil {
+			return "", "", err
 		}
 
-		return response, nil
+	default:
+		return "", "", fmt.Errorf("unknown key type %q", keyType)
 	}
 
-	return nil, nil
-}
-
-func generateSSHKeyPair() (string, string, error) {
-	privateSeed, err := rsa.GenerateKey(rand.Reader, 4096)
+	public, err := ssh.NewPublicKey(publicKey)
 	if err != nil {
 		return "", "", err
 	}
 
-	privateBlock := &pem.Block{
-		Type:    "RSA PRIVATE KEY",
-		Headers: nil,
-		Bytes:   x509.MarshalPKCS1PrivateKey(privateSeed),
+	return string(ssh.MarshalAuthorizedKey(public)), string(pem.EncodeToMemory(privateBlock)), nil
+}
+
+// caKeyTypeAndBits returns the parameters generateSSHKeyPair needs to create
+// a key of the same kind as the given one.
+func caKeyTypeAndBits(key ssh.PublicKey) (string, int, error) {
+	cryptoKey, ok := key.(ssh.CryptoPublicKey)
+	if !ok {
+		return "", 0, fmt.Errorf("unsupported key type %q", key.Type())
 	}
 
-	public, err := ssh.NewPublicKey(&privateSeed.PublicKey)
-	if err != nil {
-		return "", "", err
+	switch k := cryptoKey.CryptoPublicKey().(type) {
+	case *rsa.PublicKey:
+		return caKeyTypeRSA, k.N.BitLen(), nil
+	case *ecdsa.PublicKey:
+		switch k.Curve {
+		case elliptic.P256():
+			return caKeyTypeECDSAP256, 0, nil
+		case elliptic.P384():
+			return caKeyTypeECDSAP384, 0, nil
+		case elliptic.P521():
+			return caKeyTypeECDSAP521, 0, nil
+		}
+	}
+	if key.Type() == ssh.KeyAlgoED25519 {
+		return caKeyTypeEd25519, 0, nil
 	}
 
-	return string(ssh.MarshalAuthorizedKey(public)), string(pem.EncodeToMemory(privateBlock)), nil
+	return "", 0, fmt.Errorf("unsupported key type %q", key.Type())
 }
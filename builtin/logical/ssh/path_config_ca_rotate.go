@@ -0,0 +1,357 @@
+package ssh
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/helper/consts"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	caAutoRotateStoragePath        = "config/ca_auto_rotate"
+	caPreviousPublicKeyStoragePath = "config/ca_previous_public_key"
+
+	// caGenerationStoragePath holds the CA generation counter. It is not
+	// removed with the key pair, so generations keep increasing when the CA
+	// is deleted and configured again.
+	caGenerationStoragePath = "config/ca_generation"
+
+	// minCARotationPeriod guards against a misconfigured period rotating the
+	// CA faster than hosts can pick up the new public key.
+	minCARotationPeriod = time.Hour
+)
+
+type caGeneration struct {
+	Generation uint64 `json:"generation" structs:"generation" mapstructure:"generation"`
+}
+
+type caAutoRotateConfig struct {
+	RotationPeriod time.Duration `json:"rotation_period" structs:"rotation_period" mapstructure:"rotation_period"`
+}
+
+func pathConfigCAAutoRotate(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config/ca/auto-rotate",
+		Fields: map[string]*framework.FieldSchema{
+			"rotation_period": &framework.FieldSchema{
+				Type:        framework.TypeDurationSecond,
+				Description: `Age at which the CA key is automatically replaced by a newly generated key. Set to 0 to disable automatic rotation.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathConfigCAAutoRotateRead,
+			logical.UpdateOperation: b.pathConfigCAAutoRotateWrite,
+		},
+
+		HelpSynopsis:    pathConfigCAAutoRotateHelpSyn,
+		HelpDescription: pathConfigCAAutoRotateHelpDesc,
+	}
+}
+
+func getCAAutoRotateConfig(ctx context.Context, s logical.Storage) (*caAutoRotateConfig, error) {
+	entry, err := s.Get(ctx, caAutoRotateStoragePath)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return &caAutoRotateConfig{}, nil
+	}
+
+	var result caAutoRotateConfig
+	if err := entry.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (b *backend) pathConfigCAAutoRotateRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config, err := getCAAutoRotateConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"rotation_period": int64(config.RotationPeriod.Seconds()),
+		},
+	}
+
+	if config.RotationPeriod != 0 {
+		publicKeyEntry, err := caKey(ctx, req.Storage, caPublicKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA public key: %v", err)
+		}
+		if publicKeyEntry != nil && !publicKeyEntry.CreationTime.IsZero() {
+			resp.Data["next_rotation"] = publicKeyEntry.CreationTime.Add(config.RotationPeriod).Format(time.RFC3339)
+		}
+	}
+
+	return resp, nil
+}
+
+func (b *backend) pathConfigCAAutoRotateWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config := &caAutoRotateConfig{
+		RotationPeriod: time.Duration(d.Get("rotation_period").(int)) * time.Second,
+	}
+
+	if config.RotationPeriod < 0 || (config.RotationPeriod != 0 && config.RotationPeriod < minCARotationPeriod) {
+		return logical.ErrorResponse(fmt.Sprintf("rotation_period must be 0 or at least %s", minCARotationPeriod)), nil
+	}
+
+	entry, err := logical.StorageEntryJSON(caAutoRotateStoragePath, config)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// periodicFunc is invoked by the RollbackManager, which only runs on the
+// active node. Performance secondaries share the CA of their primary and
+// must not rotate it themselves.
+func (b *backend) periodicFunc(ctx context.Context, req *logical.Request) error {
+	if !b.System().LocalMount() && b.System().ReplicationState().HasState(consts.ReplicationPerformanceSecondary) {
+		return nil
+	}
+
+	return b.autoRotateCA(ctx, req.Storage)
+}
+
+func (b *backend) autoRotateCA(ctx context.Context, s logical.Storage) error {
+	b.caLock.Lock()
+	defer b.caLock.Unlock()
+
+	config, err := getCAAutoRotateConfig(ctx, s)
+	if err != nil {
+		return err
+	}
+	if config.RotationPeriod == 0 {
+		return nil
+	}
+
+	publicKeyEntry, err := caKey(ctx, s, caPublicKey)
+	if err != nil {
+		return err
+	}
+	if publicKeyEntry == nil || publicKeyEntry.Key == "" {
+		return nil
+	}
+
+	// Keys stored before their creation time was recorded are considered
+	// created now, so they are rotated one period from now.
+	if publicKeyEntry.CreationTime.IsZero() {
+		publicKeyEntry.CreationTime = time.Now()
+		entry, err := logical.StorageEntryJSON(caPublicKeyStoragePath, publicKeyEntry)
+		if err != nil {
+			return err
+		}
+		return s.Put(ctx, entry)
+	}
+
+	if time.Now().Before(publicKeyEntry.CreationTime.Add(config.RotationPeriod)) {
+		return nil
+	}
+
+	return b.rotateCA(ctx, s, publicKeyEntry)
+}
+
+// rotateCA replaces the CA key pair with a newly generated one, keeping the
+// current public key as the previous key so that certificates it signed are
+// still trusted by hosts that fetch the public keys after the rotation.
+func (b *backend) rotateCA(ctx context.Context, s logical.Storage, current *keyStorageEntry) error {
+	// Keep the algorithm of the current key, which hosts and clients are
+	// known to support.
+	currentPublicKey, err := parsePublicSSHKey(current.Key)
+	if err != nil {
+		return fmt.Errorf("failed to parse CA public key: %v", err)
+	}
+	keyType, keyBits, err := caKeyTypeAndBits(currentPublicKey)
+	if err != nil {
+		return err
+	}
+
+	publicKey, privateKey, err := generateSSHKeyPair(b.GetRandomReader(), keyType, keyBits)
+	if err != nil {
+		return err
+	}
+	generation, err := nextCAGeneration(ctx, s)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+
+	// The public keys are written before the private key: should the last
+	// write fail, certificates are still signed by the previous key, which
+	// remains trusted.
+	entries := []struct {
+		path  string
+		entry *keyStorageEntry
+	}{
+		{caPreviousPublicKeyStoragePath, &keyStorageEntry{Key: current.Key, CreationTime: current.CreationTime, Generation: current.Generation}},
+		{caPublicKeyStoragePath, &keyStorageEntry{Key: publicKey, CreationTime: now, Generation: generation}},
+		{caPrivateKeyStoragePath, &keyStorageEntry{Key: privateKey, CreationTime: now, Generation: generation}},
+	}
+	for _, e := range entries {
+		entry, err := logical.StorageEntryJSON(e.path, e.entry)
+		if err != nil {
+			return err
+		}
+		if err := s.Put(ctx, entry); err != nil {
+			return fmt.Errorf("failed to store rotated CA key: %v", err)
+		}
+	}
+
+	if b.Logger().IsInfo() {
+		b.Logger().Info("ssh: rotated CA key", "previous", publicKeyFingerprint(current.Key), "current", publicKeyFingerprint(publicKey), "generation", generation)
+	}
+
+	return nil
+}
+
+func previousCAPublicKey(ctx context.Context, s logical.Storage) (*keyStorageEntry, error) {
+	entry, err := s.Get(ctx, caPreviousPublicKeyStoragePath)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var keyEntry keyStorageEntry
+	if err := entry.DecodeJSON(&keyEntry); err != nil {
+		return nil, err
+	}
+
+	return &keyEntry, nil
+}
+
+// nextCAGeneration increments the CA generation counter and returns its new
+// value. The caller must hold caLock.
+func nextCAGeneration(ctx context.Context, s logical.Storage) (uint64, error) {
+	var current caGeneration
+	entry, err := s.Get(ctx, caGenerationStoragePath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read CA generation: %v", err)
+	}
+	if entry != nil {
+		if err := entry.DecodeJSON(&current); err != nil {
+			return 0, err
+		}
+	}
+
+	next := &caGeneration{Generation: current.Generation + 1}
+	entry, err = logical.StorageEntryJSON(caGenerationStoragePath, next)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.Put(ctx, entry); err != nil {
+		return 0, fmt.Errorf("failed to store CA generation: %v", err)
+	}
+
+	return next.Generation, nil
+}
+
+func pathConfigCARotation(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config/ca/rotation",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathConfigCARotationRead,
+		},
+
+		Properties: map[logical.Operation]logical.OperationProperties{
+			logical.ReadOperation: {Unauthenticated: true, ReadOnly: true},
+		},
+
+		HelpSynopsis:    pathConfigCARotationHelpSyn,
+		HelpDescription: pathConfigCARotationHelpDesc,
+	}
+}
+
+func (b *backend) pathConfigCARotationRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	publicKeyEntry, err := caKey(ctx, req.Storage, caPublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA public key: %v", err)
+	}
+	if publicKeyEntry == nil || publicKeyEntry.Key == "" {
+		return logical.ErrorResponse("keys haven't been configured yet"), nil
+	}
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"ca_generation":          publicKeyEntry.Generation,
+			"public_key":             publicKeyEntry.Key,
+			"creation_time":          formatCAKeyCreationTime(publicKeyEntry),
+			"previous_ca_generation": uint64(0),
+			"previous_public_key":    "",
+			"previous_creation_time": "",
+		},
+	}
+
+	previousKeyEntry, err := previousCAPublicKey(ctx, req.Storage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read previous CA public key: %v", err)
+	}
+	if previousKeyEntry != nil {
+		resp.Data["previous_ca_generation"] = previousKeyEntry.Generation
+		resp.Data["previous_public_key"] = previousKeyEntry.Key
+		resp.Data["previous_creation_time"] = formatCAKeyCreationTime(previousKeyEntry)
+	}
+
+	return resp, nil
+}
+
+// formatCAKeyCreationTime returns the creation time of a CA key in RFC 3339
+// format, or an empty string for keys stored before it was recorded.
+func formatCAKeyCreationTime(entry *keyStorageEntry) string {
+	if entry.CreationTime.IsZero() {
+		return ""
+	}
+	return entry.CreationTime.Format(time.RFC3339)
+}
+
+func publicKeyFingerprint(key string) string {
+	parsedKey, err := parsePublicSSHKey(key)
+	if err != nil {
+		return "unknown"
+	}
+	return ssh.FingerprintSHA256(parsedKey)
+}
+
+const pathConfigCAAutoRotateHelpSyn = `
+Configure automatic rotation of the CA key.
+`
+
+const pathConfigCAAutoRotateHelpDesc = `
+When 'rotation_period' is set, the CA key pair is replaced by a newly
+generated one of the same type and size once it is older than the period.
+The replaced public key is kept as the previous key and is served by the
+'public_key' endpoint along with the current one, so that hosts trusting both
+keys keep accepting certificates signed before the rotation. Only the most recent previous key is
+kept; certificate TTLs should not exceed the rotation period.
+
+Rotation is checked about once a minute by the active node. Setting the period
+to 0, the default, disables automatic rotation.
+`
+
+const pathConfigCARotationHelpSyn = `
+Return the current and previous CA public keys with their generations.
+`
+
+const pathConfigCARotationHelpDesc = `
+Every time a CA key pair is stored, through 'config/ca' or by automatic
+rotation, the CA generation is incremented. This endpoint returns the
+generation, public key and creation time of the current CA key and of the
+previous one, if any, so that hosts can poll it and replace their
+TrustedUserCAKeys file whenever the generation changes. It does not require
+authentication.
+`
@@ -1,10 +1,15 @@
 package ssh
 
 import (
+	"bytes"
 	"context"
+	"crypto/rsa"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/vault/logical"
+	"golang.org/x/crypto/ssh"
 )
 
 func TestSSH_ConfigCAStorageUpgrade(t *testing.T) {
@@ -162,3 +167,297 @@ func TestSSH_ConfigCAUpdateDelete(t *testing.T) {
 		t.Fatalf("bad: err: %v, resp:%v", err, resp)
 	}
 }
+
+func TestSSH_ConfigCAAutoRotate(t *testing.T) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+
+	b, err := Backend(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Setup(context.Background(), config); err != nil {
+		t.Fatal(err)
+	}
+
+	doReq := func(op logical.Operation, path string, data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Operation: op,
+			Path:      path,
+			Storage:   config.StorageView,
+			Data:      data,
+		})
+	}
+
+	resp, err := doReq(logical.UpdateOperation, "config/ca/auto-rotate", map[string]interface{}{
+		"rotation_period": "1m",
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected a too short rotation_period to be rejected: err: %v, resp: %v", err, resp)
+	}
+
+	resp, err = doReq(logical.UpdateOperation, "config/ca/auto-rotate", map[string]interface{}{
+		"rotation_period": "24h",
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: err: %v, resp: %v", err, resp)
+	}
+
+	resp, err = doReq(logical.UpdateOperation, "config/ca", map[string]interface{}{
+		"public_key":  publicKey,
+		"private_key": privateKey,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: err: %v, resp: %v", err, resp)
+	}
+
+	// A fresh key is left alone
+	if err := b.periodicFunc(context.Background(), &logical.Request{Storage: config.StorageView}); err != nil {
+		t.Fatal(err)
+	}
+	publicKeyEntry, err := caKey(context.Background(), config.StorageView, caPublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if publicKeyEntry.Key != publicKey {
+		t.Fatalf("CA key was rotated before the rotation period elapsed")
+	}
+
+	resp, err = doReq(logical.ReadOperation, "config/ca/auto-rotate", nil)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: err: %v, resp: %v", err, resp)
+	}
+	if resp.Data["rotation_period"] != int64(86400) || resp.Data["next_rotation"] == nil {
+		t.Fatalf("bad: auto-rotate config: %#v", resp.Data)
+	}
+
+	// Age the key past the rotation period
+	publicKeyEntry.CreationTime = time.Now().Add(-25 * time.Hour)
+	entry, err := logical.StorageEntryJSON(caPublicKeyStoragePath, publicKeyEntry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := config.StorageView.Put(context.Background(), entry); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.periodicFunc(context.Background(), &logical.Request{Storage: config.StorageView}); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err = doReq(logical.ReadOperation, "config/ca", nil)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: err: %v, resp: %v", err, resp)
+	}
+	newPublicKey := resp.Data["public_key"].(string)
+	if newPublicKey == publicKey {
+		t.Fatalf("CA key was not rotated")
+	}
+	if resp.Data["previous_public_key"] != publicKey {
+		t.Fatalf("previous CA key was not retained: %#v", resp.Data)
+	}
+
+	// Both keys are distributed for trust
+	resp, err = doReq(logical.ReadOperation, "public_key", nil)
+	if err != nil || resp == nil {
+		t.Fatalf("bad: err: %v, resp: %v", err, resp)
+	}
+	if body := string(resp.Data[logical.HTTPRawBody].([]byte)); body != newPublicKey+publicKey {
+		t.Fatalf("bad: public keys: %q", body)
+	}
+
+	// The new private key matches the new public key
+	privateKeyEntry, err := caKey(context.Background(), config.StorageView, caPrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.ParsePrivateKey([]byte(privateKeyEntry.Key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(ssh.MarshalAuthorizedKey(signer.PublicKey())) != newPublicKey {
+		t.Fatalf("rotated private key does not match the rotated public key")
+	}
+}
+
+func TestSSH_ConfigCARotation(t *testing.T) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+
+	b, err := Backend(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Setup(context.Background(), config); err != nil {
+		t.Fatal(err)
+	}
+
+	doReq := func(op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: op,
+			Path:      path,
+			Storage:   config.StorageView,
+			Data:      data,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: path: %s, err: %v, resp: %v", path, err, resp)
+		}
+		return resp
+	}
+
+	checkRotation := func(generation uint64, currentKey string, previousGeneration uint64, previousKey string) {
+		t.Helper()
+		resp := doReq(logical.ReadOperation, "config/ca/rotation", nil)
+		if resp.Data["ca_generation"] != generation || resp.Data["previous_ca_generation"] != previousGeneration ||
+			resp.Data["previous_public_key"] != previousKey || resp.Data["creation_time"] == "" {
+			t.Fatalf("bad: %#v", resp.Data)
+		}
+		if currentKey != "" && resp.Data["public_key"] != currentKey {
+			t.Fatalf("bad: public key: %#v", resp.Data["public_key"])
+		}
+		if (previousKey == "") != (resp.Data["previous_creation_time"] == "") {
+			t.Fatalf("bad: previous creation time: %#v", resp.Data["previous_creation_time"])
+		}
+	}
+
+	doReq(logical.UpdateOperation, "config/ca", map[string]interface{}{
+		"public_key":  publicKey,
+		"private_key": privateKey,
+	})
+	checkRotation(1, publicKey, 0, "")
+
+	// Automatic rotation increments the generation
+	doReq(logical.UpdateOperation, "config/ca/auto-rotate", map[string]interface{}{
+		"rotation_period": "24h",
+	})
+	publicKeyEntry, err := caKey(context.Background(), config.StorageView, caPublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKeyEntry.CreationTime = time.Now().Add(-25 * time.Hour)
+	entry, err := logical.StorageEntryJSON(caPublicKeyStoragePath, publicKeyEntry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := config.StorageView.Put(context.Background(), entry); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.periodicFunc(context.Background(), &logical.Request{Storage: config.StorageView}); err != nil {
+		t.Fatal(err)
+	}
+	checkRotation(2, "", 1, publicKey)
+
+	// The counter survives deleting the CA
+	doReq(logical.DeleteOperation, "config/ca", nil)
+	doReq(logical.UpdateOperation, "config/ca", map[string]interface{}{
+		"public_key":  publicKey,
+		"private_key": privateKey,
+	})
+	checkRotation(3, publicKey, 0, "")
+
+	resp := doReq(logical.ReadOperation, "config/ca", nil)
+	if resp.Data["ca_generation"] != uint64(3) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+}
+
+func TestSSH_ConfigCAKeyTypes(t *testing.T) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+
+	b, err := Factory(context.Background(), config)
+	if err != nil {
+		t.Fatalf("Cannot create backend: %s", err)
+	}
+
+	doReq := func(op logical.Operation, path string, data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Operation: op,
+			Path:      path,
+			Storage:   config.StorageView,
+			Data:      data,
+		})
+	}
+
+	for _, params := range []map[string]interface{}{
+		{"key_type": "dsa"},
+		{"key_type": "rsa", "key_bits": 1024},
+		{"key_type": "ed25519", "key_bits": 256},
+	} {
+		resp, err := doReq(logical.UpdateOperation, "config/ca", params)
+		if err != nil || resp == nil || !resp.IsError() {
+			t.Fatalf("expected an error for %v: err: %v, resp: %v", params, err, resp)
+		}
+	}
+
+	resp, err := doReq(logical.UpdateOperation, "roles/test", map[string]interface{}{
+		"key_type":                "ca",
+		"allow_user_certificates": true,
+		"allowed_users":           "*",
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: err: %v, resp: %v", err, resp)
+	}
+
+	cases := []struct {
+		keyType      string
+		keyBits      int
+		expectedType string
+	}{
+		{"rsa", 2048, ssh.KeyAlgoRSA},
+		{"ecdsa-p256", 0, ssh.KeyAlgoECDSA256},
+		{"ecdsa-p384", 0, ssh.KeyAlgoECDSA384},
+		{"ecdsa-p521", 0, ssh.KeyAlgoECDSA521},
+		{"ed25519", 0, ssh.KeyAlgoED25519},
+	}
+	for _, tc := range cases {
+		resp, err := doReq(logical.UpdateOperation, "config/ca", map[string]interface{}{
+			"key_type": tc.keyType,
+			"key_bits": tc.keyBits,
+		})
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("%s: bad: err: %v, resp: %v", tc.keyType, err, resp)
+		}
+
+		caPublic, err := parsePublicSSHKey(resp.Data["public_key"].(string))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if caPublic.Type() != tc.expectedType {
+			t.Fatalf("%s: expected a %s CA key, got %s", tc.keyType, tc.expectedType, caPublic.Type())
+		}
+		if tc.keyType == "rsa" {
+			if bits := caPublic.(ssh.CryptoPublicKey).CryptoPublicKey().(*rsa.PublicKey).N.BitLen(); bits != tc.keyBits {
+				t.Fatalf("expected a %d bit RSA key, got %d", tc.keyBits, bits)
+			}
+		}
+
+		resp, err = doReq(logical.UpdateOperation, "sign/test", map[string]interface{}{
+			"public_key":       publicKey2,
+			"valid_principals": "tuber",
+		})
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("%s: bad: err: %v, resp: %v", tc.keyType, err, resp)
+		}
+
+		parsedKey, err := parsePublicSSHKey(strings.TrimSpace(resp.Data["signed_key"].(string)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cert := parsedKey.(*ssh.Certificate)
+		if !bytes.Equal(cert.SignatureKey.Marshal(), caPublic.Marshal()) {
+			t.Fatalf("%s: certificate not signed by the generated CA key", tc.keyType)
+		}
+		checker := ssh.CertChecker{
+			IsUserAuthority: func(auth ssh.PublicKey) bool { return true },
+		}
+		if err := checker.CheckCert("tuber", cert); err != nil {
+			t.Fatalf("%s: failed to verify certificate: %v", tc.keyType, err)
+		}
+
+		resp, err = doReq(logical.DeleteOperation, "config/ca", nil)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: err: %v, resp: %v", err, resp)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package ssh
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"fmt"
+
+	"github.com/hashicorp/vault/helper/fips"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+	"golang.org/x/crypto/ssh"
+)
+
+const fipsConfigStoragePath = "config/fips"
+
+// fipsMinRSAKeyBits is the smallest RSA modulus accepted in FIPS mode.
+const fipsMinRSAKeyBits = 2048
+
+type fipsConfig struct {
+	FIPSMode bool `json:"fips_mode" structs:"fips_mode" mapstructure:"fips_mode"`
+}
+
+func pathConfigFIPS(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config/fips",
+		Fields: map[string]*framework.FieldSchema{
+			"fips_mode": &framework.FieldSchema{
+				Type:        framework.TypeBool,
+				Description: `If set, only FIPS-approved algorithms may be used to generate, import and sign keys.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathConfigFIPSRead,
+			logical.UpdateOperation: b.pathConfigFIPSWrite,
+		},
+
+		HelpSynopsis:    pathConfigFIPSHelpSyn,
+		HelpDescription: pathConfigFIPSHelpDesc,
+	}
+}
+
+func (b *backend) getFIPSConfig(ctx context.Context, s logical.Storage) (*fipsConfig, error) {
+	entry, err := s.Get(ctx, fipsConfigStoragePath)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return &fipsConfig{}, nil
+	}
+
+	var result fipsConfig
+	if err := entry.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (b *backend) fipsModeEnabled(ctx context.Context, s logical.Storage) (bool, error) {
+	// The FIPS mode of Vault applies to every mount, whatever their own
+	// configuration
+	if fips.Enabled() {
+		return true, nil
+	}
+
+	config, err := b.getFIPSConfig(ctx, s)
+	if err != nil {
+		return false, fmt.Errorf("failed to read FIPS configuration: %v", err)
+	}
+	return config.FIPSMode, nil
+}
+
+func (b *backend) pathConfigFIPSRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config, err := b.getFIPSConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"fips_mode": config.FIPSMode,
+		},
+	}, nil
+}
+
+func (b *backend) pathConfigFIPSWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config := &fipsConfig{
+		FIPSMode: d.Get("fips_mode").(bool),
+	}
+
+	// Refuse to turn on FIPS mode when a configured CA key could not be used
+	// under it; every request signed with it would fail otherwise.
+	if config.FIPSMode {
+		publicKeyEntry, err := caKey(ctx, req.Storage, caPublicKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA public key: %v", err)
+		}
+		if publicKeyEntry != nil && publicKeyEntry.Key != "" {
+			publicKey, err := parsePublicSSHKey(publicKeyEntry.Key)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse stored CA public key: %v", err)
+			}
+			if err := validateFIPSPublicKey(publicKey); err != nil {
+				return logical.ErrorResponse(fmt.Sprintf("configured CA key is not FIPS compliant: %v", err)), nil
+			}
+		}
+
+		names, err := req.Storage.List(ctx, issuersStoragePrefix)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			issuer, err := getIssuer(ctx, req.Storage, name)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read issuer %q: %v", name, err)
+			}
+			if issuer == nil {
+				continue
+			}
+			publicKey, err := parsePublicSSHKey(issuer.PublicKey)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse public key of issuer %q: %v", name, err)
+			}
+			if err := validateFIPSPublicKey(publicKey); err != nil {
+				return logical.ErrorResponse(fmt.Sprintf("key of issuer %q is not FIPS compliant: %v", name, err)), nil
+			}
+		}
+	}
+
+	entry, err := logical.StorageEntryJSON(fipsConfigStoragePath, config)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// validateFIPSPublicKey returns an error if the given key does not use a
+// FIPS-approved algorithm. Only RSA keys of at least 2048 bits and ECDSA keys
+// on the NIST P-256 and P-384 curves are accepted.
+func validateFIPSPublicKey(key ssh.PublicKey) error {
+	cryptoKey, ok := key.(ssh.CryptoPublicKey)
+	if !ok {
+		return fmt.Errorf("key type %q is not FIPS approved", key.Type())
+	}
+
+	switch k := cryptoKey.CryptoPublicKey().(type) {
+	case *rsa.PublicKey:
+		if k.N.BitLen() < fipsMinRSAKeyBits {
+			return fmt.Errorf("RSA keys must be at least %d bits in FIPS mode", fipsMinRSAKeyBits)
+		}
+	case *ecdsa.PublicKey:
+		if k.Curve != elliptic.P256() && k.Curve != elliptic.P384() {
+			return fmt.Errorf("ECDSA curve %q is not FIPS approved", k.Params().Name)
+		}
+	default:
+		return fmt.Errorf("key type %q is not FIPS approved", key.Type())
+	}
+
+	return nil
+}
+
+const pathConfigFIPSHelpSyn = `
+Restrict the backend to FIPS-approved algorithms.
+`
+
+const pathConfigFIPSHelpDesc = `
+When 'fips_mode' is enabled, the backend only generates, imports and signs
+keys using FIPS-approved algorithms:
+
+  * RSA keys with a modulus of at least 2048 bits, signed using SHA-256
+    ('rsa-sha2-256'), or SHA-512 if the role's 'algorithm_signer' is
+    'rsa-sha2-512'. SHA-1 based 'ssh-rsa' signatures are not produced.
+  * ECDSA keys on the NIST P-256 and P-384 curves.
+
+Ed25519 and DSA keys, ECDSA keys on any other curve, and RSA keys smaller
+than 2048 bits are rejected, both as the CA key and as public keys submitted
+for signing. FIPS mode cannot be enabled while a non-compliant CA key is
+configured.
+
+FIPS mode is always enabled when Vault itself runs in FIPS mode, whatever
+the value of 'fips_mode'.
+`
@@ -0,0 +1,219 @@
+package ssh
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/helper/fips"
+	"github.com/hashicorp/vault/logical"
+	"golang.org/x/crypto/ed25519"
+	"golang.org/x/crypto/ssh"
+)
+
+func testECDSAKeyPair(t *testing.T, curve elliptic.Curve) (string, string) {
+	key, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := ssh.NewPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(ssh.MarshalAuthorizedKey(pub)), string(pem.EncodeToMemory(&pem.Block{
+		Type:  "EC PRIVATE KEY",
+		Bytes: der,
+	}))
+}
+
+func TestSSH_FIPSMode(t *testing.T) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+
+	b, err := Factory(context.Background(), config)
+	if err != nil {
+		t.Fatalf("Cannot create backend: %s", err)
+	}
+
+	doReq := func(op logical.Operation, path string, data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Operation: op,
+			Path:      path,
+			Storage:   config.StorageView,
+			Data:      data,
+		})
+	}
+
+	// A P-521 CA key is fine outside of FIPS mode...
+	p521Public, p521Private := testECDSAKeyPair(t, elliptic.P521())
+	resp, err := doReq(logical.UpdateOperation, "config/ca", map[string]interface{}{
+		"public_key":  p521Public,
+		"private_key": p521Private,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: err: %v, resp: %v", err, resp)
+	}
+
+	// ...but FIPS mode cannot be enabled while it is configured
+	resp, err = doReq(logical.UpdateOperation, "config/fips", map[string]interface{}{
+		"fips_mode": true,
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error enabling FIPS mode: err: %v, resp: %v", err, resp)
+	}
+
+	resp, err = doReq(logical.DeleteOperation, "config/ca", nil)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: err: %v, resp: %v", err, resp)
+	}
+
+	resp, err = doReq(logical.UpdateOperation, "config/fips", map[string]interface{}{
+		"fips_mode": true,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: err: %v, resp: %v", err, resp)
+	}
+
+	resp, err = doReq(logical.ReadOperation, "config/fips", nil)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: err: %v, resp: %v", err, resp)
+	}
+	if resp.Data["fips_mode"] != true {
+		t.Fatalf("expected fips_mode to be enabled, got %v", resp.Data["fips_mode"])
+	}
+
+	// Importing a non-compliant CA key is rejected
+	resp, err = doReq(logical.UpdateOperation, "config/ca", map[string]interface{}{
+		"public_key":  p521Public,
+		"private_key": p521Private,
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error importing P-521 CA key: err: %v, resp: %v", err, resp)
+	}
+
+	// A 2048 bit RSA key is allowed
+	resp, err = doReq(logical.UpdateOperation, "config/ca", map[string]interface{}{
+		"public_key":  publicKey,
+		"private_key": privateKey,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: err: %v, resp: %v", err, resp)
+	}
+
+	resp, err = doReq(logical.UpdateOperation, "roles/fips", map[string]interface{}{
+		"key_type":                "ca",
+		"allow_user_certificates": true,
+		"allowed_users":           "*",
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: err: %v, resp: %v", err, resp)
+	}
+
+	// Signing an ed25519 public key is rejected
+	edPublic, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edSSHPublic, err := ssh.NewPublicKey(edPublic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err = doReq(logical.UpdateOperation, "sign/fips", map[string]interface{}{
+		"public_key": string(ssh.MarshalAuthorizedKey(edSSHPublic)),
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error signing an ed25519 key: err: %v, resp: %v", err, resp)
+	}
+
+	// Signing a small RSA public key is rejected
+	smallRSA, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	smallRSAPublic, err := ssh.NewPublicKey(&smallRSA.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err = doReq(logical.UpdateOperation, "sign/fips", map[string]interface{}{
+		"public_key": string(ssh.MarshalAuthorizedKey(smallRSAPublic)),
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error signing a 1024 bit RSA key: err: %v, resp: %v", err, resp)
+	}
+
+	// A compliant key is signed using SHA-256 rather than SHA-1
+	resp, err = doReq(logical.UpdateOperation, "sign/fips", map[string]interface{}{
+		"public_key": publicKey2,
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: err: %v, resp: %v", err, resp)
+	}
+
+	parsedKey, err := parsePublicSSHKey(strings.TrimSpace(resp.Data["signed_key"].(string)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert := parsedKey.(*ssh.Certificate)
+	if cert.Signature.Format != "rsa-sha2-256" {
+		t.Fatalf("expected an rsa-sha2-256 signature, got %q", cert.Signature.Format)
+	}
+
+	unsigned := *cert
+	unsigned.Signature = nil
+	signedBytes := unsigned.Marshal()
+	// Strip the length prefix of the empty signature
+	signedBytes = signedBytes[:len(signedBytes)-4]
+	digest := sha256.Sum256(signedBytes)
+
+	caPublic := cert.SignatureKey.(ssh.CryptoPublicKey).CryptoPublicKey().(*rsa.PublicKey)
+	if err := rsa.VerifyPKCS1v15(caPublic, crypto.SHA256, digest[:], cert.Signature.Blob); err != nil {
+		t.Fatalf("failed to verify certificate signature: %v", err)
+	}
+}
+
+func TestSSH_VaultFIPSMode(t *testing.T) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+
+	b, err := Factory(context.Background(), config)
+	if err != nil {
+		t.Fatalf("Cannot create backend: %s", err)
+	}
+	defer fips.SetEnabled(false)
+	fips.SetEnabled(true)
+
+	// The FIPS mode of Vault applies without the mount's configuration
+	for _, keyType := range []string{caKeyTypeEd25519, caKeyTypeECDSAP521} {
+		if _, _, err := generateSSHKeyPair(rand.Reader, keyType, 0); err == nil || !strings.Contains(err.Error(), "is not FIPS approved") {
+			t.Fatalf("expected the %s key pair to be rejected, got %v", keyType, err)
+		}
+	}
+	if _, _, err := generateSSHKeyPair(rand.Reader, caKeyTypeECDSAP256, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config/ca",
+		Storage:   config.StorageView,
+		Data: map[string]interface{}{
+			"generate_signing_key": true,
+			"key_type":             caKeyTypeEd25519,
+		},
+	})
+	if err == nil && (resp == nil || !resp.IsError()) {
+		t.Fatalf("expected the Ed25519 CA key to be rejected, got %#v", resp)
+	}
+}
@@ -2,19 +2,38 @@ package ssh
 
 import (
 	"context"
+	"crypto/rand"
 	"fmt"
+	"math"
+	"math/big"
 	"net"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/go-uuid"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
 )
 
+const (
+	minOTPLength = 6
+	maxOTPLength = 128
+
+	// minOTPEntropyBits is the entropy the OTPs must have at least, since
+	// they are verified by an unauthenticated endpoint
+	minOTPEntropyBits = 64
+
+	defaultOTPCharset = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+)
+
 type sshOTP struct {
 	Username string `json:"username" structs:"username" mapstructure:"username"`
 	IP       string `json:"ip" structs:"ip" mapstructure:"ip"`
 	RoleName string `json:"role_name" structs:"role_name" mapstructure:"role_name"`
+
+	// CreationTime lets tidy find OTPs left behind after their lease ended.
+	// It is zero for OTPs created before it was recorded.
+	CreationTime time.Time `json:"creation_time" structs:"creation_time" mapstructure:"creation_time"`
 }
 
 func pathCredsCreate(b *backend) *framework.Path {
@@ -112,10 +131,11 @@ func (b *backend) pathCredsCreateWrite(ctx context.Context, req *logical.Request
 	var result *logical.Response
 	if role.KeyType == KeyTypeOTP {
 		// Generate an OTP
-		otp, err := b.GenerateOTPCredential(ctx, req, &sshOTP{
-			Username: username,
-			IP:       ip,
-			RoleName: roleName,
+		otp, err := b.GenerateOTPCredential(ctx, req, role, &sshOTP{
+			Username:     username,
+			IP:           ip,
+			RoleName:     roleName,
+			CreationTime: time.Now(),
 		})
 		if err != nil {
 			return nil, err
@@ -142,6 +162,22 @@ func (b *backend) pathCredsCreateWrite(ctx context.Context, req *logical.Request
 			return nil, err
 		}
 
+		// Track the installed key so that tidy can remove it from the host
+		// should the lease never be revoked.
+		if err := storeDynamicKey(ctx, req.Storage, &dynamicKeyEntry{
+			AdminUser:        role.AdminUser,
+			Username:         username,
+			IP:               ip,
+			HostKeyName:      role.KeyName,
+			DynamicPublicKey: dynamicPublicKey,
+			InstallScript:    role.InstallScript,
+			Port:             role.Port,
+			RoleName:         roleName,
+			CreationTime:     time.Now(),
+		}); err != nil {
+			return nil, err
+		}
+
 		// Return the information relevant to user of dynamic type and save
 		// information required for later use in internal section of secret.
 		result = b.Secret(SecretDynamicKeyType).Response(map[string]interface{}{
@@ -215,9 +251,33 @@ func (b *backend) GenerateSaltedOTP() (string, string, error) {
 	return str, salt.SaltID(str), nil
 }
 
-// Generates an UUID OTP and creates an entry for the same in storage backend with its salted string.
-func (b *backend) GenerateOTPCredential(ctx context.Context, req *logical.Request, sshOTPEntry *sshOTP) (string, error) {
-	otp, otpSalted, err := b.GenerateSaltedOTP()
+// generateSaltedRoleOTP returns an OTP in the format configured by the role
+// along with its salted value.
+func (b *backend) generateSaltedRoleOTP(role *sshRole) (string, string, error) {
+	if role.OTPLength == 0 {
+		return b.GenerateSaltedOTP()
+	}
+
+	charset := role.OTPCharset
+	if charset == "" {
+		charset = defaultOTPCharset
+	}
+	str, err := randomString(role.OTPLength, charset)
+	if err != nil {
+		return "", "", err
+	}
+	salt, err := b.Salt()
+	if err != nil {
+		return "", "", err
+	}
+
+	return str, salt.SaltID(str), nil
+}
+
+// Generates an OTP in the format of the role and creates an entry for the
+// same in storage backend with its salted string.
+func (b *backend) GenerateOTPCredential(ctx context.Context, req *logical.Request, role *sshRole, sshOTPEntry *sshOTP) (string, error) {
+	otp, otpSalted, err := b.generateSaltedRoleOTP(role)
 	if err != nil {
 		return "", err
 	}
@@ -230,7 +290,7 @@ func (b *backend) GenerateOTPCredential(ctx context.Context, req *logical.Reques
 	// OTP is generated. It is very unlikely that this is the case and this
 	// code is just for safety.
 	for err == nil && entry != nil {
-		otp, otpSalted, err = b.GenerateSaltedOTP()
+		otp, otpSalted, err = b.generateSaltedRoleOTP(role)
 		if err != nil {
 			return "", err
 		}
@@ -329,3 +389,59 @@ then "ssh/creds/web" would generate a key for 'web' role.
 Keys will have a lease associated with them. The access keys can be
 revoked by using the lease ID.
 `
+
+// validateOTPFormat checks the OTP length and character set of a role. OTPs
+// are typed in at password prompts, so only printable ASCII characters other
+// than space are allowed.
+func validateOTPFormat(length int, charset string) error {
+	if length == 0 {
+		if charset != "" {
+			return fmt.Errorf("otp_charset requires otp_length to be set")
+		}
+		return nil
+	}
+	if length < minOTPLength || length > maxOTPLength {
+		return fmt.Errorf("otp_length must be between %d and %d", minOTPLength, maxOTPLength)
+	}
+
+	seen := map[rune]bool{}
+	for _, c := range charset {
+		if c <= ' ' || c > '~' {
+			return fmt.Errorf("otp_charset must only contain printable ASCII characters other than space")
+		}
+		if seen[c] {
+			return fmt.Errorf("otp_charset contains %q more than once", c)
+		}
+		seen[c] = true
+	}
+	if charset != "" && len(seen) < 2 {
+		return fmt.Errorf("otp_charset must contain at least 2 characters")
+	}
+
+	size := len(seen)
+	if charset == "" {
+		size = len(defaultOTPCharset)
+	}
+	bitsPerChar := math.Log2(float64(size))
+	if float64(length)*bitsPerChar < minOTPEntropyBits {
+		return fmt.Errorf("otp_length must be at least %d for OTPs of %d possible characters to have %d bits of entropy", int(math.Ceil(minOTPEntropyBits/bitsPerChar)), size, minOTPEntropyBits)
+	}
+
+	return nil
+}
+
+// randomString returns a string of the given length made of characters of
+// charset chosen uniformly at random.
+func randomString(length int, charset string) (string, error) {
+	max := big.NewInt(int64(len(charset)))
+	result := make([]byte, length)
+	for i := range result {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		result[i] = charset[n.Int64()]
+	}
+
+	return string(result), nil
+}
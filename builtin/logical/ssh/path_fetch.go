@@ -2,6 +2,8 @@ package ssh
 
 import (
 	"context"
+	"fmt"
+	"strings"
 
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
@@ -11,31 +13,98 @@ func pathFetchPublicKey(b *backend) *framework.Path {
 	return &framework.Path{
 		Pattern: `public_key`,
 
+		Fields: map[string]*framework.FieldSchema{
+			"download": &framework.FieldSchema{
+				Type:        framework.TypeBool,
+				Description: `If set, the key is returned as a file attachment rather than for inline display.`,
+			},
+		},
+
 		Callbacks: map[logical.Operation]framework.OperationFunc{
 			logical.ReadOperation: b.pathFetchPublicKey,
 		},
 
-		HelpSynopsis:    `Retrieve the public key.`,
-		HelpDescription: `This allows the public key, that this backend has been configured with, to be fetched.`,
+		Properties: map[logical.Operation]logical.OperationProperties{
+			logical.ReadOperation: {Unauthenticated: true, ReadOnly: true},
+		},
+
+		HelpSynopsis: `Retrieve the public key.`,
+		HelpDescription: `This allows the public key, that this backend has been configured with, to be fetched.
+The key is returned as plain text for inline display. Set 'download' to have it
+served as a file attachment instead.`,
 	}
 }
 
 func (b *backend) pathFetchPublicKey(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	publicKeyEntry, err := caKey(ctx, req.Storage, caPublicKey)
+	keys, err := trustedCAPublicKeys(ctx, req.Storage)
 	if err != nil {
 		return nil, err
 	}
-	if publicKeyEntry == nil || publicKeyEntry.Key == "" {
+	if len(keys) == 0 {
 		return nil, nil
 	}
 
+	// Every key is terminated by a newline, also when it was imported without
+	// one, so the response can be written or appended as is to a
+	// TrustedUserCAKeys or known_hosts file.
+	var publicKeys string
+	for _, key := range keys {
+		publicKeys += strings.TrimSpace(key) + "\n"
+	}
+
 	response := &logical.Response{
 		Data: map[string]interface{}{
 			logical.HTTPContentType: "text/plain",
-			logical.HTTPRawBody:     []byte(publicKeyEntry.Key),
+			logical.HTTPRawBody:     []byte(publicKeys),
 			logical.HTTPStatusCode:  200,
 		},
 	}
 
+	if data.Get("download").(bool) {
+		response.Data[logical.HTTPContentDisposition] = fmt.Sprintf("attachment; filename=%q", publicKeyFileName(req.MountPoint))
+	}
+
 	return response, nil
 }
+
+// trustedCAPublicKeys returns the public keys hosts should trust: the CA key,
+// followed by the key replaced by the last rotation, so that hosts keep
+// trusting the certificates it signed, and those of all named issuers, so
+// that hosts trust an issuer before it becomes the default. No keys are
+// returned until the CA is configured.
+func trustedCAPublicKeys(ctx context.Context, s logical.Storage) ([]string, error) {
+	publicKeyEntry, err := caKey(ctx, s, caPublicKey)
+	if err != nil {
+		return nil, err
+	}
+	if publicKeyEntry == nil || publicKeyEntry.Key == "" {
+		return nil, nil
+	}
+	keys := []string{publicKeyEntry.Key}
+
+	previousKeyEntry, err := previousCAPublicKey(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	if previousKeyEntry != nil && previousKeyEntry.Key != "" {
+		keys = append(keys, previousKeyEntry.Key)
+	}
+
+	issuerKeys, err := issuerPublicKeys(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(keys, issuerKeys...), nil
+}
+
+// publicKeyFileName returns the name under which the CA public key is
+// offered for download, derived from the mount point so that keys of several
+// mounts do not overwrite each other, e.g. "ssh-client-signer-ca.pub".
+func publicKeyFileName(mountPoint string) string {
+	name := strings.Replace(strings.Trim(mountPoint, "/"), "/", "-", -1)
+	if name == "" {
+		return "ca.pub"
+	}
+	return name + "-ca.pub"
+}
@@ -0,0 +1,90 @@
+package ssh
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+func pathIssue(b *backend) *framework.Path {
+	fields := signFields()
+	delete(fields, "public_key")
+
+	return &framework.Path{
+		Pattern: "issue/" + framework.GenericNameRegex("role"),
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathIssue,
+		},
+
+		Fields: fields,
+
+		HelpSynopsis:    pathIssueHelpSyn,
+		HelpDescription: pathIssueHelpDesc,
+	}
+}
+
+func (b *backend) pathIssue(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	roleName := data.Get("role").(string)
+
+	role, err := b.getRole(ctx, req.Storage, roleName)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return logical.ErrorResponse(fmt.Sprintf("Unknown role: %s", roleName)), nil
+	}
+	if role.KeyType != KeyTypeCA {
+		return logical.ErrorResponse(fmt.Sprintf("role %q is not of type %q", roleName, KeyTypeCA)), nil
+	}
+
+	// Roles written before keys could be issued have no key type set
+	keyType := role.IssuedKeyType
+	if keyType == "" {
+		keyType = caKeyTypeRSA
+	}
+
+	publicKey, privateKey, err := generateSSHKeyPair(b.GetRandomReader(), keyType, role.IssuedKeyBits)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key pair: %v", err)
+	}
+
+	userPublicKey, err := parsePublicSSHKey(publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse generated public key: %v", err)
+	}
+
+	fipsMode, err := b.fipsModeEnabled(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if fipsMode {
+		if err := validateFIPSPublicKey(userPublicKey); err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("issued_key_type of role %q is not FIPS compliant: %v", roleName, err)), nil
+		}
+	}
+
+	resp, err := b.signPublicKey(ctx, req, data, role, userPublicKey)
+	if err != nil || resp == nil || resp.IsError() {
+		return resp, err
+	}
+
+	resp.Data["private_key"] = privateKey
+	resp.Data["private_key_type"] = keyType
+	resp.Data["public_key"] = publicKey
+
+	return resp, nil
+}
+
+const pathIssueHelpSyn = `
+Generate a key pair and sign its public key using a certain role.
+`
+
+const pathIssueHelpDesc = `
+This path generates a key pair of the type configured by the role's
+'issued_key_type' and 'issued_key_bits', and signs its public key as the
+'sign' path would. The private key is returned along with the signed
+certificate and is not stored; it cannot be retrieved again.
+`
@@ -0,0 +1,112 @@
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/logical"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestSSH_Issue(t *testing.T) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+
+	b, err := Factory(context.Background(), config)
+	if err != nil {
+		t.Fatalf("Cannot create backend: %s", err)
+	}
+
+	doReq := func(op logical.Operation, path string, data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Operation: op,
+			Path:      path,
+			Storage:   config.StorageView,
+			Data:      data,
+		})
+	}
+
+	resp, err := doReq(logical.UpdateOperation, "config/ca", map[string]interface{}{
+		"public_key":  publicKey,
+		"private_key": privateKey,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: err: %v, resp: %v", err, resp)
+	}
+
+	resp, err = doReq(logical.UpdateOperation, "roles/bad", map[string]interface{}{
+		"key_type":                "ca",
+		"allow_user_certificates": true,
+		"issued_key_type":         "ed25519",
+		"issued_key_bits":         2048,
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error: err: %v, resp: %v", err, resp)
+	}
+
+	resp, err = doReq(logical.UpdateOperation, "roles/test", map[string]interface{}{
+		"key_type":                "ca",
+		"allow_user_certificates": true,
+		"allowed_users":           "tuber",
+		"issued_key_type":         "ed25519",
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: err: %v, resp: %v", err, resp)
+	}
+
+	resp, err = doReq(logical.UpdateOperation, "issue/test", map[string]interface{}{
+		"valid_principals": "root",
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected principal to be rejected: err: %v, resp: %v", err, resp)
+	}
+
+	resp, err = doReq(logical.UpdateOperation, "issue/test", map[string]interface{}{
+		"valid_principals": "tuber",
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: err: %v, resp: %v", err, resp)
+	}
+	if resp.Data["private_key_type"] != "ed25519" {
+		t.Fatalf("bad private_key_type: %v", resp.Data["private_key_type"])
+	}
+
+	parsedKey, err := parsePublicSSHKey(strings.TrimSpace(resp.Data["signed_key"].(string)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert := parsedKey.(*ssh.Certificate)
+
+	signer, err := ssh.ParsePrivateKey([]byte(resp.Data["private_key"].(string)))
+	if err != nil {
+		t.Fatalf("failed to parse issued private key: %v", err)
+	}
+	if !bytes.Equal(signer.PublicKey().Marshal(), cert.Key.Marshal()) {
+		t.Fatalf("certificate is not for the issued private key")
+	}
+	if string(ssh.MarshalAuthorizedKey(signer.PublicKey())) != resp.Data["public_key"] {
+		t.Fatalf("bad public_key: %v", resp.Data["public_key"])
+	}
+
+	checker := ssh.CertChecker{
+		IsUserAuthority: func(auth ssh.PublicKey) bool { return true },
+	}
+	if err := checker.CheckCert("tuber", cert); err != nil {
+		t.Fatalf("failed to verify certificate: %v", err)
+	}
+
+	// The issued key is usable to authenticate with the certificate
+	certSigner, err := ssh.NewCertSigner(cert, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := certSigner.Sign(nil, []byte("data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cert.Key.Verify([]byte("data"), sig); err != nil {
+		t.Fatalf("failed to verify signature of issued key: %v", err)
+	}
+}
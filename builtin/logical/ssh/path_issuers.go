@@ -0,0 +1,397 @@
+package ssh
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/helper/errutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+const (
+	issuersStoragePrefix     = "issuers/"
+	issuersConfigStoragePath = "config/issuers"
+
+	// legacyIssuerName refers to the key pair managed through config/ca,
+	// which is the default issuer unless another one is configured.
+	legacyIssuerName = "default"
+)
+
+// sshIssuer is a named CA key pair that certificates can be signed with in
+// addition to the one managed through config/ca.
+type sshIssuer struct {
+	Name         string    `json:"name" structs:"name" mapstructure:"name"`
+	PublicKey    string    `json:"public_key" structs:"public_key" mapstructure:"public_key"`
+	PrivateKey   string    `json:"private_key" structs:"private_key" mapstructure:"private_key"`
+	CreationTime time.Time `json:"creation_time" structs:"creation_time" mapstructure:"creation_time"`
+}
+
+type issuersConfig struct {
+	DefaultIssuer string `json:"default_issuer" structs:"default_issuer" mapstructure:"default_issuer"`
+}
+
+func pathListIssuers(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "issuers/?$",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ListOperation: b.pathIssuersList,
+		},
+
+		HelpSynopsis:    pathIssuersHelpSyn,
+		HelpDescription: pathIssuersHelpDesc,
+	}
+}
+
+func pathIssuers(b *backend) *framework.Path {
+	fields := caKeyPairFields()
+	fields["issuer_name"] = &framework.FieldSchema{
+		Type:        framework.TypeString,
+		Description: `Name of the issuer.`,
+	}
+
+	return &framework.Path{
+		Pattern: "issuers/" + framework.GenericNameRegex("issuer_name"),
+		Fields:  fields,
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathIssuerWrite,
+			logical.ReadOperation:   b.pathIssuerRead,
+			logical.DeleteOperation: b.pathIssuerDelete,
+		},
+
+		HelpSynopsis:    pathIssuersHelpSyn,
+		HelpDescription: pathIssuersHelpDesc,
+	}
+}
+
+func pathConfigIssuers(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config/issuers",
+		Fields: map[string]*framework.FieldSchema{
+			"default_issuer": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: `Name of the issuer certificates are signed with when the sign request does not name one.`,
+				Default:     legacyIssuerName,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathConfigIssuersRead,
+			logical.UpdateOperation: b.pathConfigIssuersWrite,
+		},
+
+		HelpSynopsis:    pathConfigIssuersHelpSyn,
+		HelpDescription: pathConfigIssuersHelpDesc,
+	}
+}
+
+func getIssuer(ctx context.Context, s logical.Storage, name string) (*sshIssuer, error) {
+	entry, err := s.Get(ctx, issuersStoragePrefix+name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var result sshIssuer
+	if err := entry.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func getIssuersConfig(ctx context.Context, s logical.Storage) (*issuersConfig, error) {
+	entry, err := s.Get(ctx, issuersConfigStoragePath)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &issuersConfig{}
+	if entry != nil {
+		if err := entry.DecodeJSON(result); err != nil {
+			return nil, err
+		}
+	}
+	if result.DefaultIssuer == "" {
+		result.DefaultIssuer = legacyIssuerName
+	}
+
+	return result, nil
+}
+
+// issuerPrivateKey returns the private key of the named issuer, or of the
+// default issuer if name is empty. Unknown issuers are reported as
+// errutil.UserError.
+func issuerPrivateKey(ctx context.Context, s logical.Storage, name string) (string, string, error) {
+	if name == "" {
+		config, err := getIssuersConfig(ctx, s)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to read issuers configuration: %v", err)
+		}
+		name = config.DefaultIssuer
+	}
+
+	if name == legacyIssuerName {
+		privateKeyEntry, err := caKey(ctx, s, caPrivateKey)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to read CA private key: %v", err)
+		}
+		if privateKeyEntry == nil || privateKeyEntry.Key == "" {
+			return "", "", fmt.Errorf("failed to read CA private key")
+		}
+		return name, privateKeyEntry.Key, nil
+	}
+
+	issuer, err := getIssuer(ctx, s, name)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read issuer %q: %v", name, err)
+	}
+	if issuer == nil {
+		return "", "", errutil.UserError{Err: fmt.Sprintf("unknown issuer %q", name)}
+	}
+
+	return name, issuer.PrivateKey, nil
+}
+
+// issuerPublicKeys returns the public keys of all named issuers, in the order
+// of their names.
+func issuerPublicKeys(ctx context.Context, s logical.Storage) ([]string, error) {
+	names, err := s.List(ctx, issuersStoragePrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for _, name := range names {
+		issuer, err := getIssuer(ctx, s, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read issuer %q: %v", name, err)
+		}
+		if issuer != nil {
+			keys = append(keys, issuer.PublicKey)
+		}
+	}
+
+	return keys, nil
+}
+
+func (b *backend) pathIssuersList(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	names, err := req.Storage.List(ctx, issuersStoragePrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := getIssuersConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKeyEntry, err := caKey(ctx, req.Storage, caPublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA public key: %v", err)
+	}
+	if publicKeyEntry != nil && publicKeyEntry.Key != "" {
+		names = append([]string{legacyIssuerName}, names...)
+	}
+
+	keyInfo := make(map[string]interface{}, len(names))
+	for _, name := range names {
+		keyInfo[name] = map[string]interface{}{
+			"is_default": name == config.DefaultIssuer,
+		}
+	}
+
+	return logical.ListResponseWithInfo(names, keyInfo), nil
+}
+
+func (b *backend) pathIssuerWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("issuer_name").(string)
+	if name == legacyIssuerName {
+		return logical.ErrorResponse(fmt.Sprintf("issuer %q is managed through config/ca", legacyIssuerName)), nil
+	}
+
+	publicKey, privateKey, generated, err := b.caKeyPairFromRequest(ctx, req, d)
+	switch err.(type) {
+	case nil:
+	case errutil.UserError:
+		return logical.ErrorResponse(err.Error()), nil
+	default:
+		return nil, err
+	}
+
+	b.caLock.Lock()
+	defer b.caLock.Unlock()
+
+	existing, err := getIssuer(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return logical.ErrorResponse(fmt.Sprintf("issuer %q already exists; delete it before reconfiguring", name)), nil
+	}
+
+	entry, err := logical.StorageEntryJSON(issuersStoragePrefix+name, &sshIssuer{
+		Name:         name,
+		PublicKey:    publicKey,
+		PrivateKey:   privateKey,
+		CreationTime: time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	if generated {
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"public_key": publicKey,
+			},
+		}, nil
+	}
+
+	return nil, nil
+}
+
+func (b *backend) pathIssuerRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("issuer_name").(string)
+
+	config, err := getIssuersConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	var publicKey string
+	var creationTime time.Time
+	if name == legacyIssuerName {
+		publicKeyEntry, err := caKey(ctx, req.Storage, caPublicKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA public key: %v", err)
+		}
+		if publicKeyEntry == nil || publicKeyEntry.Key == "" {
+			return nil, nil
+		}
+		publicKey, creationTime = publicKeyEntry.Key, publicKeyEntry.CreationTime
+	} else {
+		issuer, err := getIssuer(ctx, req.Storage, name)
+		if err != nil {
+			return nil, err
+		}
+		if issuer == nil {
+			return nil, nil
+		}
+		publicKey, creationTime = issuer.PublicKey, issuer.CreationTime
+	}
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"name":       name,
+			"public_key": publicKey,
+			"is_default": name == config.DefaultIssuer,
+		},
+	}
+	if !creationTime.IsZero() {
+		resp.Data["creation_time"] = creationTime.Format(time.RFC3339)
+	}
+
+	return resp, nil
+}
+
+func (b *backend) pathIssuerDelete(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("issuer_name").(string)
+	if name == legacyIssuerName {
+		return logical.ErrorResponse(fmt.Sprintf("issuer %q is managed through config/ca", legacyIssuerName)), nil
+	}
+
+	b.caLock.Lock()
+	defer b.caLock.Unlock()
+
+	config, err := getIssuersConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if name == config.DefaultIssuer {
+		return logical.ErrorResponse(fmt.Sprintf("issuer %q is the default issuer; set another default_issuer before deleting it", name)), nil
+	}
+
+	if err := req.Storage.Delete(ctx, issuersStoragePrefix+name); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (b *backend) pathConfigIssuersRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config, err := getIssuersConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"default_issuer": config.DefaultIssuer,
+		},
+	}, nil
+}
+
+func (b *backend) pathConfigIssuersWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := strings.TrimSpace(d.Get("default_issuer").(string))
+
+	b.caLock.Lock()
+	defer b.caLock.Unlock()
+
+	if name != legacyIssuerName {
+		issuer, err := getIssuer(ctx, req.Storage, name)
+		if err != nil {
+			return nil, err
+		}
+		if issuer == nil {
+			return logical.ErrorResponse(fmt.Sprintf("unknown issuer %q", name)), nil
+		}
+	}
+
+	entry, err := logical.StorageEntryJSON(issuersConfigStoragePath, &issuersConfig{
+		DefaultIssuer: name,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+const pathIssuersHelpSyn = `
+Manage additional named CA key pairs to sign certificates with.
+`
+
+const pathIssuersHelpDesc = `
+Besides the key pair configured through 'config/ca', which is the issuer named
+'default', any number of named issuers can be created, by either importing or
+generating a key pair with the same parameters as 'config/ca'. Sign requests
+select an issuer with the 'issuer' parameter, or are signed by the default
+issuer set in 'config/issuers'.
+
+The public keys of all issuers are served by the 'public_key' endpoint. To
+rotate the CA without downtime, create a new issuer, wait for hosts to trust
+its public key, make it the default issuer and delete the old one once the
+certificates it signed have expired. The default issuer cannot be deleted.
+`
+
+const pathConfigIssuersHelpSyn = `
+Configure the issuer that signs certificates by default.
+`
+
+const pathConfigIssuersHelpDesc = `
+Sign requests that do not name an issuer are signed by 'default_issuer'.
+It defaults to 'default', the key pair configured through 'config/ca'.
+`
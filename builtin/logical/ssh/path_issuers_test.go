@@ -0,0 +1,142 @@
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/logical"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestSSH_Issuers(t *testing.T) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+
+	b, err := Factory(context.Background(), config)
+	if err != nil {
+		t.Fatalf("Cannot create backend: %s", err)
+	}
+
+	doReq := func(op logical.Operation, path string, data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Operation: op,
+			Path:      path,
+			Storage:   config.StorageView,
+			Data:      data,
+		})
+	}
+
+	mustSucceed := func(resp *logical.Response, err error) *logical.Response {
+		t.Helper()
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: err: %v, resp: %v", err, resp)
+		}
+		return resp
+	}
+
+	mustFail := func(resp *logical.Response, err error) {
+		t.Helper()
+		if err != nil || resp == nil || !resp.IsError() {
+			t.Fatalf("expected an error response: err: %v, resp: %v", err, resp)
+		}
+	}
+
+	signingKey := func(data map[string]interface{}) ssh.PublicKey {
+		t.Helper()
+		data["public_key"] = publicKey2
+		data["valid_principals"] = "tuber"
+		resp := mustSucceed(doReq(logical.UpdateOperation, "sign/test", data))
+		parsedKey, err := parsePublicSSHKey(strings.TrimSpace(resp.Data["signed_key"].(string)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsedKey.(*ssh.Certificate).SignatureKey
+	}
+
+	mustSucceed(doReq(logical.UpdateOperation, "config/ca", map[string]interface{}{
+		"public_key":  publicKey,
+		"private_key": privateKey,
+	}))
+	mustSucceed(doReq(logical.UpdateOperation, "roles/test", map[string]interface{}{
+		"key_type":                "ca",
+		"allow_user_certificates": true,
+		"allowed_users":           "*",
+	}))
+
+	// The config/ca key pair is the default issuer
+	legacyKey, err := parsePublicSSHKey(publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key := signingKey(map[string]interface{}{}); !bytes.Equal(key.Marshal(), legacyKey.Marshal()) {
+		t.Fatalf("expected the config/ca key to sign by default")
+	}
+
+	resp := mustSucceed(doReq(logical.UpdateOperation, "issuers/next", map[string]interface{}{
+		"key_type": "ed25519",
+	}))
+	nextKey, err := parsePublicSSHKey(resp.Data["public_key"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mustFail(doReq(logical.UpdateOperation, "issuers/next", map[string]interface{}{}))
+	mustFail(doReq(logical.UpdateOperation, "issuers/default", map[string]interface{}{}))
+
+	resp = mustSucceed(doReq(logical.ListOperation, "issuers/", nil))
+	keys := resp.Data["keys"].([]string)
+	if len(keys) != 2 || keys[0] != "default" || keys[1] != "next" {
+		t.Fatalf("bad issuers: %v", keys)
+	}
+	keyInfo := resp.Data["key_info"].(map[string]interface{})
+	if keyInfo["default"].(map[string]interface{})["is_default"] != true || keyInfo["next"].(map[string]interface{})["is_default"] != false {
+		t.Fatalf("bad key_info: %v", keyInfo)
+	}
+
+	// Hosts trust both issuers
+	resp = mustSucceed(doReq(logical.ReadOperation, "public_key", nil))
+	body := string(resp.Data[logical.HTTPRawBody].([]byte))
+	if body != publicKey+string(ssh.MarshalAuthorizedKey(nextKey)) {
+		t.Fatalf("bad public keys: %q", body)
+	}
+
+	// Sign with a named issuer
+	if key := signingKey(map[string]interface{}{"issuer": "next"}); !bytes.Equal(key.Marshal(), nextKey.Marshal()) {
+		t.Fatalf("expected the next issuer to sign")
+	}
+	mustFail(doReq(logical.UpdateOperation, "sign/test", map[string]interface{}{
+		"public_key":       publicKey2,
+		"valid_principals": "tuber",
+		"issuer":           "unknown",
+	}))
+
+	// Make the new issuer the default
+	mustFail(doReq(logical.UpdateOperation, "config/issuers", map[string]interface{}{
+		"default_issuer": "unknown",
+	}))
+	mustSucceed(doReq(logical.UpdateOperation, "config/issuers", map[string]interface{}{
+		"default_issuer": "next",
+	}))
+	resp = mustSucceed(doReq(logical.ReadOperation, "issuers/next", nil))
+	if resp.Data["is_default"] != true || resp.Data["public_key"] != string(ssh.MarshalAuthorizedKey(nextKey)) {
+		t.Fatalf("bad issuer: %v", resp.Data)
+	}
+	if key := signingKey(map[string]interface{}{}); !bytes.Equal(key.Marshal(), nextKey.Marshal()) {
+		t.Fatalf("expected the next issuer to sign by default")
+	}
+
+	// The default issuer cannot be deleted
+	mustFail(doReq(logical.DeleteOperation, "issuers/next", nil))
+
+	mustSucceed(doReq(logical.UpdateOperation, "config/issuers", map[string]interface{}{
+		"default_issuer": "default",
+	}))
+	mustSucceed(doReq(logical.DeleteOperation, "issuers/next", nil))
+
+	resp = mustSucceed(doReq(logical.ReadOperation, "issuers/next", nil))
+	if resp != nil {
+		t.Fatalf("expected the issuer to be deleted, got %v", resp.Data)
+	}
+}
@@ -0,0 +1,286 @@
+package ssh
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/vault/helper/errutil"
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// migratedDefaultExtensions are the extensions of the certificates signed by
+// migrated roles, granting what a dynamic key in authorized_keys did. They
+// are the extensions ssh-keygen sets by default.
+var migratedDefaultExtensions = map[string]string{
+	"permit-X11-forwarding":   "",
+	"permit-agent-forwarding": "",
+	"permit-port-forwarding":  "",
+	"permit-pty":              "",
+	"permit-user-rc":          "",
+}
+
+func pathMigrateDynamicToCA(b *backend) *framework.Path {
+	fields := caKeyPairFields()
+	fields["roles"] = &framework.FieldSchema{
+		Type:        framework.TypeCommaStringSlice,
+		Description: `Names of the dynamic roles to migrate. Defaults to all dynamic roles.`,
+	}
+	fields["dry_run"] = &framework.FieldSchema{
+		Type:        framework.TypeBool,
+		Description: `If set, report what would be migrated without changing anything.`,
+	}
+
+	return &framework.Path{
+		Pattern: "migrate/dynamic-to-ca",
+		Fields:  fields,
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathMigrateDynamicToCAWrite,
+		},
+
+		HelpSynopsis:    pathMigrateDynamicToCAHelpSyn,
+		HelpDescription: pathMigrateDynamicToCAHelpDesc,
+	}
+}
+
+func (b *backend) pathMigrateDynamicToCAWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	dryRun := d.Get("dry_run").(bool)
+
+	roleNames := d.Get("roles").([]string)
+	explicit := len(roleNames) != 0
+	if !explicit {
+		var err error
+		roleNames, err = req.Storage.List(ctx, "roles/")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	migratedRoles := map[string]*sshRole{}
+	roleResults := map[string]interface{}{}
+	for _, roleName := range roleNames {
+		role, err := b.getRole(ctx, req.Storage, roleName)
+		if err != nil {
+			return nil, fmt.Errorf("error retrieving role %q: %v", roleName, err)
+		}
+		if role == nil || role.KeyType != KeyTypeDynamic {
+			if explicit {
+				return logical.ErrorResponse(fmt.Sprintf("role %q is not a dynamic role", roleName)), nil
+			}
+			continue
+		}
+
+		caRole, notes := migrateDynamicRole(role)
+		migratedRoles[roleName] = caRole
+		roleResults[roleName] = map[string]interface{}{
+			"allowed_users": caRole.AllowedUsers,
+			"default_user":  caRole.DefaultUser,
+			"notes":         notes,
+		}
+	}
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"dry_run":    dryRun,
+			"roles":      roleResults,
+			"ca_created": false,
+		},
+	}
+
+	if len(migratedRoles) != 0 {
+		publicKey, created, err := b.ensureMigrationCA(ctx, req, d, dryRun)
+		switch err.(type) {
+		case nil:
+		case errutil.UserError:
+			return logical.ErrorResponse(err.Error()), nil
+		default:
+			return nil, err
+		}
+		resp.Data["ca_created"] = created
+		if publicKey != "" {
+			resp.Data["ca_public_key"] = publicKey
+		}
+
+		if !dryRun {
+			for roleName, caRole := range migratedRoles {
+				entry, err := logical.StorageEntryJSON("roles/"+roleName, caRole)
+				if err != nil {
+					return nil, err
+				}
+				if err := req.Storage.Put(ctx, entry); err != nil {
+					return nil, err
+				}
+			}
+			resp.AddWarning("Clients of the migrated roles must request certificates from 'sign/<role>' instead of keys from 'creds/<role>', and hosts must list the CA public key in TrustedUserCAKeys.")
+		}
+	}
+
+	hosts, err := dynamicKeyHosts(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	resp.Data["hosts"] = hosts
+	if len(hosts) != 0 {
+		resp.AddWarning("The listed hosts still have dynamic keys installed by Vault. They are removed when their leases are revoked, or by 'tidy' with 'tidy_dynamic_keys' once expired; keys installed before Vault tracked them are not listed.")
+	}
+
+	return resp, nil
+}
+
+// ensureMigrationCA returns the CA public key of the backend, configuring the
+// CA key pair described by the request if there is none, and whether it was
+// created. In a dry run, no key pair is generated or stored.
+func (b *backend) ensureMigrationCA(ctx context.Context, req *logical.Request, d *framework.FieldData, dryRun bool) (string, bool, error) {
+	b.caLock.Lock()
+	defer b.caLock.Unlock()
+
+	configured, err := caKeyPairConfigured(ctx, req.Storage)
+	if err != nil {
+		return "", false, err
+	}
+	if configured {
+		publicKeyEntry, err := caKey(ctx, req.Storage, caPublicKey)
+		if err != nil {
+			return "", false, fmt.Errorf("failed to read CA public key: %v", err)
+		}
+		if publicKeyEntry == nil {
+			return "", false, nil
+		}
+		return publicKeyEntry.Key, false, nil
+	}
+	if dryRun {
+		return "", true, nil
+	}
+
+	publicKey, privateKey, _, err := b.caKeyPairFromRequest(ctx, req, d)
+	if err != nil {
+		return "", false, err
+	}
+	if err := storeCAKeyPair(ctx, req.Storage, publicKey, privateKey); err != nil {
+		return "", false, err
+	}
+
+	return publicKey, true, nil
+}
+
+// migrateDynamicRole returns the CA role equivalent to a dynamic role, along
+// with notes on the settings that could not be carried over.
+func migrateDynamicRole(role *sshRole) (*sshRole, []string) {
+	// Dynamic roles always allow their default user, CA roles only the
+	// allowed users
+	allowedUsers := role.AllowedUsers
+	if allowedUsers != "*" && role.DefaultUser != "" {
+		users := strutil.ParseStringSlice(allowedUsers, ",")
+		if !strutil.StrListContains(users, role.DefaultUser) {
+			allowedUsers = strings.Join(append(users, role.DefaultUser), ",")
+		}
+	}
+
+	defaultExtensions := make(map[string]string, len(migratedDefaultExtensions))
+	for extension, value := range migratedDefaultExtensions {
+		defaultExtensions[extension] = value
+	}
+
+	caRole := &sshRole{
+		KeyType:               KeyTypeCA,
+		AllowUserCertificates: true,
+		AllowedUsers:          allowedUsers,
+		DefaultUser:           role.DefaultUser,
+		DefaultExtensions:     defaultExtensions,
+		TTL:                   "0s",
+		MaxTTL:                "0s",
+		NotBeforeDuration:     defaultNotBeforeDuration.String(),
+	}
+
+	notes := []string{}
+	if role.CIDRList != "" || role.ExcludeCIDRList != "" {
+		notes = append(notes, "cidr_list and exclude_cidr_list are not carried over: certificates are accepted by every host trusting the CA public key, so only deploy it to the intended hosts")
+	}
+	if role.KeyOptionSpecs != "" {
+		notes = append(notes, fmt.Sprintf("key_option_specs %q is not carried over; use default_critical_options such as force-command and source-address instead", role.KeyOptionSpecs))
+	}
+
+	return caRole, notes
+}
+
+// dynamicKeyHosts returns the hosts that have dynamic keys tracked by the
+// backend installed, with the number of keys per user.
+func dynamicKeyHosts(ctx context.Context, s logical.Storage) ([]map[string]interface{}, error) {
+	ids, err := s.List(ctx, dynamicKeysStoragePrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	type host struct {
+		ip       string
+		port     int
+		username string
+		roles    []string
+		keys     int
+	}
+	hostsByKey := map[string]*host{}
+	for _, id := range ids {
+		key, err := getDynamicKey(ctx, s, id)
+		if err != nil {
+			return nil, fmt.Errorf("error reading dynamic key %q: %v", id, err)
+		}
+		if key == nil {
+			continue
+		}
+
+		hostKey := fmt.Sprintf("%s:%d:%s", key.IP, key.Port, key.Username)
+		h, ok := hostsByKey[hostKey]
+		if !ok {
+			h = &host{ip: key.IP, port: key.Port, username: key.Username}
+			hostsByKey[hostKey] = h
+		}
+		h.keys++
+		if key.RoleName != "" && !strutil.StrListContains(h.roles, key.RoleName) {
+			h.roles = append(h.roles, key.RoleName)
+		}
+	}
+
+	hostKeys := make([]string, 0, len(hostsByKey))
+	for hostKey := range hostsByKey {
+		hostKeys = append(hostKeys, hostKey)
+	}
+	sort.Strings(hostKeys)
+
+	hosts := []map[string]interface{}{}
+	for _, hostKey := range hostKeys {
+		h := hostsByKey[hostKey]
+		sort.Strings(h.roles)
+		hosts = append(hosts, map[string]interface{}{
+			"ip":       h.ip,
+			"port":     h.port,
+			"username": h.username,
+			"roles":    h.roles,
+			"keys":     h.keys,
+		})
+	}
+
+	return hosts, nil
+}
+
+const pathMigrateDynamicToCAHelpSyn = `
+Convert dynamic key roles to CA roles.
+`
+
+const pathMigrateDynamicToCAHelpDesc = `
+The dynamic key type is deprecated. This endpoint converts the given dynamic
+roles, or all of them, to CA roles of the same name that sign user
+certificates for the same users, with the extensions granted by a key in
+authorized_keys. If no CA key pair is configured, one is created from the
+'public_key' and 'private_key' fields, or generated as by 'config/ca'.
+
+The response lists, for every migrated role, the settings that could not be
+carried over, and the hosts that still have dynamic keys installed by Vault.
+Existing leases keep working and uninstall their keys when revoked.
+
+With 'dry_run', the outcome is reported without changing any role or
+creating the CA.
+`
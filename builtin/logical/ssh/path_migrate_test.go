@@ -0,0 +1,150 @@
+package ssh
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestSSH_MigrateDynamicToCA(t *testing.T) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+
+	b, err := Factory(context.Background(), config)
+	if err != nil {
+		t.Fatalf("Cannot create backend: %s", err)
+	}
+	s := config.StorageView
+
+	doReq := func(path string, data map[string]interface{}) *logical.Response {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Storage:   s,
+			Data:      data,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: path: %s, err: %v, resp: %v", path, err, resp)
+		}
+		return resp
+	}
+
+	doReq("keys/"+testKeyName, map[string]interface{}{
+		"key": testSharedPrivateKey,
+	})
+	resp := doReq("roles/dynamic", map[string]interface{}{
+		"key_type":      "dynamic",
+		"key":           testKeyName,
+		"admin_user":    testAdminUser,
+		"default_user":  testAdminUser,
+		"allowed_users": "ubuntu",
+		"cidr_list":     "10.0.0.0/8",
+	})
+	if resp == nil || len(resp.Warnings) != 1 {
+		t.Fatalf("expected a deprecation warning, got %#v", resp)
+	}
+	doReq("roles/otp", map[string]interface{}{
+		"key_type":     "otp",
+		"default_user": "ubuntu",
+		"cidr_list":    "10.0.0.0/8",
+	})
+
+	entry, err := logical.StorageEntryJSON(dynamicKeyStoragePath("ssh-rsa AAAA"), &dynamicKeyEntry{
+		Username:         testAdminUser,
+		IP:               "10.0.0.1",
+		Port:             22,
+		HostKeyName:      testKeyName,
+		DynamicPublicKey: "ssh-rsa AAAA",
+		RoleName:         "dynamic",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Put(context.Background(), entry); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only dynamic roles can be migrated
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "migrate/dynamic-to-ca",
+		Storage:   s,
+		Data:      map[string]interface{}{"roles": "otp"},
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error: err: %v, resp: %v", err, resp)
+	}
+
+	expectedHosts := []map[string]interface{}{
+		{
+			"ip":       "10.0.0.1",
+			"port":     22,
+			"username": testAdminUser,
+			"roles":    []string{"dynamic"},
+			"keys":     1,
+		},
+	}
+
+	// A dry run changes nothing
+	resp = doReq("migrate/dynamic-to-ca", map[string]interface{}{"dry_run": true})
+	if resp.Data["ca_created"] != true || resp.Data["ca_public_key"] != nil {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if !reflect.DeepEqual(resp.Data["hosts"], expectedHosts) {
+		t.Fatalf("bad: hosts: %#v", resp.Data["hosts"])
+	}
+	role, err := b.(*backend).getRole(context.Background(), s, "dynamic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if role.KeyType != KeyTypeDynamic {
+		t.Fatalf("bad: role migrated in a dry run: %#v", role)
+	}
+
+	resp = doReq("migrate/dynamic-to-ca", map[string]interface{}{
+		"public_key":  publicKey,
+		"private_key": privateKey,
+	})
+	if resp.Data["ca_created"] != true || resp.Data["ca_public_key"] != publicKey {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	roles := resp.Data["roles"].(map[string]interface{})
+	if len(roles) != 1 {
+		t.Fatalf("bad: roles: %#v", roles)
+	}
+	if notes := roles["dynamic"].(map[string]interface{})["notes"].([]string); len(notes) != 1 {
+		t.Fatalf("bad: notes: %#v", notes)
+	}
+	if !reflect.DeepEqual(resp.Data["hosts"], expectedHosts) {
+		t.Fatalf("bad: hosts: %#v", resp.Data["hosts"])
+	}
+
+	role, err = b.(*backend).getRole(context.Background(), s, "dynamic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if role.KeyType != KeyTypeCA || !role.AllowUserCertificates ||
+		role.AllowedUsers != "ubuntu,"+testAdminUser || role.DefaultUser != testAdminUser {
+		t.Fatalf("bad: migrated role: %#v", role)
+	}
+	if !reflect.DeepEqual(role.DefaultExtensions, migratedDefaultExtensions) {
+		t.Fatalf("bad: extensions: %#v", role.DefaultExtensions)
+	}
+
+	// The migrated role signs keys with the CA
+	resp = doReq("sign/dynamic", map[string]interface{}{
+		"public_key":       publicKey2,
+		"valid_principals": testAdminUser,
+	})
+	if resp.Data["signed_key"] == "" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Once no dynamic role is left, the existing CA is kept
+	resp = doReq("migrate/dynamic-to-ca", nil)
+	if len(resp.Data["roles"].(map[string]interface{})) != 0 || resp.Data["ca_created"] != false {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+}
@@ -0,0 +1,272 @@
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+	"golang.org/x/crypto/ssh"
+)
+
+const revokedCertsStoragePrefix = "revoked/"
+
+// Constants of the OpenSSH KRL format, see PROTOCOL.krl in the OpenSSH
+// sources.
+const (
+	krlMagic                 = 0x5353484b524c0a00
+	krlFormatVersion         = 1
+	krlSectionCertificates   = 1
+	krlSectionCertSerialList = 0x20
+)
+
+// revokedCertificate records the revocation of an issued certificate. The
+// key of the CA that signed it is kept so that the KRL can scope the serial
+// to that CA.
+type revokedCertificate struct {
+	SerialNumber   string    `json:"serial_number" structs:"serial_number" mapstructure:"serial_number"`
+	CAPublicKey    string    `json:"ca_public_key" structs:"ca_public_key" mapstructure:"ca_public_key"`
+	ValidBefore    int64     `json:"valid_before" structs:"valid_before" mapstructure:"valid_before"`
+	RevocationTime time.Time `json:"revocation_time" structs:"revocation_time" mapstructure:"revocation_time"`
+}
+
+func pathRevoke(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "revoke",
+		Fields: map[string]*framework.FieldSchema{
+			"serial_number": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: `Serial number of the certificate to revoke, as returned when it was signed.`,
+				Required:    true,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathRevokeWrite,
+		},
+
+		HelpSynopsis:    pathRevokeHelpSyn,
+		HelpDescription: pathRevokeHelpDesc,
+	}
+}
+
+func pathFetchKRL(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "krl",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathFetchKRL,
+		},
+
+		Properties: map[logical.Operation]logical.OperationProperties{
+			logical.ReadOperation: {Unauthenticated: true, ReadOnly: true},
+		},
+
+		HelpSynopsis:    pathFetchKRLHelpSyn,
+		HelpDescription: pathFetchKRLHelpDesc,
+	}
+}
+
+func getRevokedCertificate(ctx context.Context, s logical.Storage, serial string) (*revokedCertificate, error) {
+	entry, err := s.Get(ctx, revokedCertsStoragePrefix+serial)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var result revokedCertificate
+	if err := entry.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (b *backend) pathRevokeWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	serial, err := normalizeSerial(strings.TrimSpace(d.Get("serial_number").(string)))
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	revoked, err := getRevokedCertificate(ctx, req.Storage, serial)
+	if err != nil {
+		return nil, err
+	}
+	if revoked != nil {
+		return revokeResponse(revoked), nil
+	}
+
+	issued, err := getIssuedCertificate(ctx, req.Storage, serial)
+	if err != nil {
+		return nil, err
+	}
+	if issued == nil {
+		return logical.ErrorResponse(fmt.Sprintf("certificate with serial_number %q not found", serial)), nil
+	}
+
+	parsedKey, err := parsePublicSSHKey(strings.TrimSpace(issued.SignedKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse stored certificate %q: %v", serial, err)
+	}
+	cert, ok := parsedKey.(*ssh.Certificate)
+	if !ok {
+		return nil, fmt.Errorf("stored certificate %q is not an SSH certificate", serial)
+	}
+
+	revoked = &revokedCertificate{
+		SerialNumber:   serial,
+		CAPublicKey:    string(ssh.MarshalAuthorizedKey(cert.SignatureKey)),
+		ValidBefore:    issued.ValidBefore,
+		RevocationTime: time.Now(),
+	}
+	entry, err := logical.StorageEntryJSON(revokedCertsStoragePrefix+serial, revoked)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	return revokeResponse(revoked), nil
+}
+
+func revokeResponse(revoked *revokedCertificate) *logical.Response {
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"serial_number":   revoked.SerialNumber,
+			"revocation_time": revoked.RevocationTime.Unix(),
+		},
+	}
+}
+
+func (b *backend) pathFetchKRL(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	serials, err := req.Storage.List(ctx, revokedCertsStoragePrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var revoked []*revokedCertificate
+	for _, serial := range serials {
+		cert, err := getRevokedCertificate(ctx, req.Storage, serial)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read revoked certificate %q: %v", serial, err)
+		}
+		if cert != nil {
+			revoked = append(revoked, cert)
+		}
+	}
+
+	krl, err := buildKRL(revoked, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPContentType: "application/octet-stream",
+			logical.HTTPRawBody:     krl,
+			logical.HTTPStatusCode:  200,
+		},
+	}, nil
+}
+
+// buildKRL encodes the certificates that are revoked and not yet expired as
+// an OpenSSH key revocation list, with one certificates section per CA key.
+// The version of the KRL is the time of the most recent revocation, so it
+// increases whenever a certificate is revoked.
+func buildKRL(revoked []*revokedCertificate, now time.Time) ([]byte, error) {
+	serialsByCA := map[string][]uint64{}
+	var caKeys []string
+	var version int64
+	for _, cert := range revoked {
+		if cert.RevocationTime.Unix() > version {
+			version = cert.RevocationTime.Unix()
+		}
+
+		// Expired certificates are rejected by sshd anyway
+		if uint64(cert.ValidBefore) != ssh.CertTimeInfinity && cert.ValidBefore < now.Unix() {
+			continue
+		}
+
+		serial, err := strconv.ParseUint(cert.SerialNumber, 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid serial number %q: %v", cert.SerialNumber, err)
+		}
+		if _, ok := serialsByCA[cert.CAPublicKey]; !ok {
+			caKeys = append(caKeys, cert.CAPublicKey)
+		}
+		serialsByCA[cert.CAPublicKey] = append(serialsByCA[cert.CAPublicKey], serial)
+	}
+	sort.Strings(caKeys)
+
+	var buf bytes.Buffer
+	writeUint64 := func(v uint64) {
+		binary.Write(&buf, binary.BigEndian, v)
+	}
+
+	writeUint64(krlMagic)
+	binary.Write(&buf, binary.BigEndian, uint32(krlFormatVersion))
+	writeUint64(uint64(version))
+	writeUint64(uint64(now.Unix()))
+	// flags
+	writeUint64(0)
+	// reserved and comment
+	buf.Write(ssh.Marshal(struct{ Reserved, Comment string }{}))
+
+	for _, caKey := range caKeys {
+		parsedKey, err := parsePublicSSHKey(caKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse CA public key: %v", err)
+		}
+
+		serials := serialsByCA[caKey]
+		sort.Slice(serials, func(i, j int) bool { return serials[i] < serials[j] })
+
+		var serialList bytes.Buffer
+		for _, serial := range serials {
+			binary.Write(&serialList, binary.BigEndian, serial)
+		}
+
+		var section bytes.Buffer
+		section.Write(ssh.Marshal(struct {
+			CAKey    []byte
+			Reserved string
+		}{parsedKey.Marshal(), ""}))
+		section.WriteByte(krlSectionCertSerialList)
+		section.Write(ssh.Marshal(struct{ Data []byte }{serialList.Bytes()}))
+
+		buf.WriteByte(krlSectionCertificates)
+		buf.Write(ssh.Marshal(struct{ Data []byte }{section.Bytes()}))
+	}
+
+	return buf.Bytes(), nil
+}
+
+const pathRevokeHelpSyn = `
+Revoke a signed certificate.
+`
+
+const pathRevokeHelpDesc = `
+This path revokes the certificate with the given serial number, which must
+have been signed by this backend. Revoked certificates are listed in the key
+revocation list served by the 'krl' endpoint until they expire. Revoking a
+certificate again has no effect.
+`
+
+const pathFetchKRLHelpSyn = `
+Retrieve the key revocation list of revoked certificates.
+`
+
+const pathFetchKRLHelpDesc = `
+This path returns the revoked certificates that have not yet expired as a
+binary OpenSSH key revocation list (KRL), suitable for the 'RevokedKeys'
+option of sshd. This is an unauthenticated endpoint.
+`
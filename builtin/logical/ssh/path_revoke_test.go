@@ -0,0 +1,162 @@
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestSSH_RevokeKRL(t *testing.T) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+
+	b, err := Factory(context.Background(), config)
+	if err != nil {
+		t.Fatalf("Cannot create backend: %s", err)
+	}
+
+	doReq := func(op logical.Operation, path string, data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Operation: op,
+			Path:      path,
+			Storage:   config.StorageView,
+			Data:      data,
+		})
+	}
+
+	resp, err := doReq(logical.UpdateOperation, "config/ca", map[string]interface{}{
+		"public_key":  publicKey,
+		"private_key": privateKey,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: err: %v, resp: %v", err, resp)
+	}
+
+	resp, err = doReq(logical.UpdateOperation, "roles/test", map[string]interface{}{
+		"key_type":                "ca",
+		"allow_user_certificates": true,
+		"allowed_users":           "*",
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: err: %v, resp: %v", err, resp)
+	}
+
+	var serials []string
+	for i := 0; i < 2; i++ {
+		resp, err = doReq(logical.UpdateOperation, "sign/test", map[string]interface{}{
+			"public_key":       publicKey2,
+			"valid_principals": "tuber",
+		})
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("bad: err: %v, resp: %v", err, resp)
+		}
+		serials = append(serials, resp.Data["serial_number"].(string))
+	}
+
+	resp, err = doReq(logical.UpdateOperation, "revoke", map[string]interface{}{
+		"serial_number": "abc",
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error revoking an unknown serial: err: %v, resp: %v", err, resp)
+	}
+
+	for i := 0; i < 2; i++ {
+		resp, err = doReq(logical.UpdateOperation, "revoke", map[string]interface{}{
+			"serial_number": strings.ToUpper(serials[0]),
+		})
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("bad: err: %v, resp: %v", err, resp)
+		}
+		if resp.Data["serial_number"] != serials[0] {
+			t.Fatalf("bad serial_number: %v", resp.Data["serial_number"])
+		}
+	}
+
+	resp, err = doReq(logical.ReadOperation, "krl", nil)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: err: %v, resp: %v", err, resp)
+	}
+	if resp.Data[logical.HTTPContentType] != "application/octet-stream" {
+		t.Fatalf("bad content type: %v", resp.Data[logical.HTTPContentType])
+	}
+	krl := resp.Data[logical.HTTPRawBody].([]byte)
+
+	// Header: magic, format version, KRL version, generated date, flags,
+	// reserved and comment
+	if magic := binary.BigEndian.Uint64(krl); magic != krlMagic {
+		t.Fatalf("bad magic: %x", magic)
+	}
+	if version := binary.BigEndian.Uint32(krl[8:]); version != krlFormatVersion {
+		t.Fatalf("bad format version: %d", version)
+	}
+	rest := krl[8+4+8+8+8+4+4:]
+
+	// A single certificates section scoped to the CA key
+	if rest[0] != krlSectionCertificates {
+		t.Fatalf("bad section type: %d", rest[0])
+	}
+	var section struct {
+		Data []byte
+	}
+	if err := ssh.Unmarshal(rest[1:], &section); err != nil {
+		t.Fatal(err)
+	}
+	var certs struct {
+		CAKey    []byte
+		Reserved string
+		Rest     []byte `ssh:"rest"`
+	}
+	if err := ssh.Unmarshal(section.Data, &certs); err != nil {
+		t.Fatal(err)
+	}
+	caKey, err := parsePublicSSHKey(publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(certs.CAKey, caKey.Marshal()) {
+		t.Fatalf("section is not scoped to the CA key")
+	}
+	if certs.Rest[0] != krlSectionCertSerialList {
+		t.Fatalf("bad certificate section type: %d", certs.Rest[0])
+	}
+	var serialList struct {
+		Data []byte
+	}
+	if err := ssh.Unmarshal(certs.Rest[1:], &serialList); err != nil {
+		t.Fatal(err)
+	}
+	expected, _ := strconv.ParseUint(serials[0], 16, 64)
+	if len(serialList.Data) != 8 || binary.BigEndian.Uint64(serialList.Data) != expected {
+		t.Fatalf("expected only serial %s to be revoked, got %x", serials[0], serialList.Data)
+	}
+}
+
+func TestSSH_buildKRLSkipsExpired(t *testing.T) {
+	now := time.Now()
+	krl, err := buildKRL([]*revokedCertificate{
+		{
+			SerialNumber:   "1",
+			CAPublicKey:    publicKey,
+			ValidBefore:    now.Add(-time.Minute).Unix(),
+			RevocationTime: now.Add(-time.Hour),
+		},
+	}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the header remains
+	if len(krl) != 8+4+8+8+8+4+4 {
+		t.Fatalf("expected an empty KRL, got %d bytes", len(krl))
+	}
+	if version := binary.BigEndian.Uint64(krl[12:]); version != uint64(now.Add(-time.Hour).Unix()) {
+		t.Fatalf("bad KRL version: %d", version)
+	}
+}
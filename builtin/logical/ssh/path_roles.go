@@ -3,12 +3,14 @@ package ssh
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"strings"
 
 	"time"
 
 	"github.com/hashicorp/vault/helper/cidrutil"
 	"github.com/hashicorp/vault/helper/parseutil"
+	"github.com/hashicorp/vault/helper/strutil"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
 )
@@ -19,39 +21,76 @@ const (
 	KeyTypeCA      = "ca"
 )
 
+// defaultNotBeforeDuration is the default duration by which the validity of
+// signed certificates is backdated.
+const defaultNotBeforeDuration = 30 * time.Second
+
 // Structure that represents a role in SSH backend. This is a common role structure
 // for both OTP and Dynamic roles. Not all the fields are mandatory for both type.
 // Some are applicable for one and not for other. It doesn't matter.
 type sshRole struct {
-	KeyType                string            `mapstructure:"key_type" json:"key_type"`
-	KeyName                string            `mapstructure:"key" json:"key"`
-	KeyBits                int               `mapstructure:"key_bits" json:"key_bits"`
-	AdminUser              string            `mapstructure:"admin_user" json:"admin_user"`
-	DefaultUser            string            `mapstructure:"default_user" json:"default_user"`
-	CIDRList               string            `mapstructure:"cidr_list" json:"cidr_list"`
-	ExcludeCIDRList        string            `mapstructure:"exclude_cidr_list" json:"exclude_cidr_list"`
-	Port                   int               `mapstructure:"port" json:"port"`
-	InstallScript          string            `mapstructure:"install_script" json:"install_script"`
-	AllowedUsers           string            `mapstructure:"allowed_users" json:"allowed_users"`
-	AllowedDomains         string            `mapstructure:"allowed_domains" json:"allowed_domains"`
-	KeyOptionSpecs         string            `mapstructure:"key_option_specs" json:"key_option_specs"`
-	MaxTTL                 string            `mapstructure:"max_ttl" json:"max_ttl"`
-	TTL                    string            `mapstructure:"ttl" json:"ttl"`
-	DefaultCriticalOptions map[string]string `mapstructure:"default_critical_options" json:"default_critical_options"`
-	DefaultExtensions      map[string]string `mapstructure:"default_extensions" json:"default_extensions"`
-	AllowedCriticalOptions string            `mapstructure:"allowed_critical_options" json:"allowed_critical_options"`
-	AllowedExtensions      string            `mapstructure:"allowed_extensions" json:"allowed_extensions"`
-	AllowUserCertificates  bool              `mapstructure:"allow_user_certificates" json:"allow_user_certificates"`
-	AllowHostCertificates  bool              `mapstructure:"allow_host_certificates" json:"allow_host_certificates"`
-	AllowBareDomains       bool              `mapstructure:"allow_bare_domains" json:"allow_bare_domains"`
-	AllowSubdomains        bool              `mapstructure:"allow_subdomains" json:"allow_subdomains"`
-	AllowUserKeyIDs        bool              `mapstructure:"allow_user_key_ids" json:"allow_user_key_ids"`
-	KeyIDFormat            string            `mapstructure:"key_id_format" json:"key_id_format"`
+	KeyType                        string            `mapstructure:"key_type" json:"key_type"`
+	KeyName                        string            `mapstructure:"key" json:"key"`
+	KeyBits                        int               `mapstructure:"key_bits" json:"key_bits"`
+	AdminUser                      string            `mapstructure:"admin_user" json:"admin_user"`
+	DefaultUser                    string            `mapstructure:"default_user" json:"default_user"`
+	CIDRList                       string            `mapstructure:"cidr_list" json:"cidr_list"`
+	ExcludeCIDRList                string            `mapstructure:"exclude_cidr_list" json:"exclude_cidr_list"`
+	Port                           int               `mapstructure:"port" json:"port"`
+	InstallScript                  string            `mapstructure:"install_script" json:"install_script"`
+	AllowedUsers                   string            `mapstructure:"allowed_users" json:"allowed_users"`
+	AllowedDomains                 string            `mapstructure:"allowed_domains" json:"allowed_domains"`
+	KeyOptionSpecs                 string            `mapstructure:"key_option_specs" json:"key_option_specs"`
+	MaxTTL                         string            `mapstructure:"max_ttl" json:"max_ttl"`
+	TTL                            string            `mapstructure:"ttl" json:"ttl"`
+	DefaultCriticalOptions         map[string]string `mapstructure:"default_critical_options" json:"default_critical_options"`
+	DefaultExtensions              map[string]string `mapstructure:"default_extensions" json:"default_extensions"`
+	AllowedCriticalOptions         string            `mapstructure:"allowed_critical_options" json:"allowed_critical_options"`
+	AllowedExtensions              string            `mapstructure:"allowed_extensions" json:"allowed_extensions"`
+	DeniedUsers                    string            `mapstructure:"denied_users" json:"denied_users"`
+	DeniedExtensions               string            `mapstructure:"denied_extensions" json:"denied_extensions"`
+	AllowUserCertificates          bool              `mapstructure:"allow_user_certificates" json:"allow_user_certificates"`
+	AllowHostCertificates          bool              `mapstructure:"allow_host_certificates" json:"allow_host_certificates"`
+	AllowBareDomains               bool              `mapstructure:"allow_bare_domains" json:"allow_bare_domains"`
+	AllowSubdomains                bool              `mapstructure:"allow_subdomains" json:"allow_subdomains"`
+	AllowGlobDomains               bool              `mapstructure:"allow_glob_domains" json:"allow_glob_domains"`
+	AllowUserKeyIDs                bool              `mapstructure:"allow_user_key_ids" json:"allow_user_key_ids"`
+	KeyIDFormat                    string            `mapstructure:"key_id_format" json:"key_id_format"`
+	AllowedCommentRegex            string            `mapstructure:"allowed_comment_regex" json:"allowed_comment_regex"`
+	ValidateHostPrincipalsDNS      bool              `mapstructure:"validate_host_principals_dns" json:"validate_host_principals_dns"`
+	IssuedKeyType                  string            `mapstructure:"issued_key_type" json:"issued_key_type"`
+	IssuedKeyBits                  int               `mapstructure:"issued_key_bits" json:"issued_key_bits"`
+	AlgorithmSigner                string            `mapstructure:"algorithm_signer" json:"algorithm_signer"`
+	AllowedUsersTemplate           bool              `mapstructure:"allowed_users_template" json:"allowed_users_template"`
+	DefaultUserTemplate            bool              `mapstructure:"default_user_template" json:"default_user_template"`
+	DefaultExtensionsTemplate      bool              `mapstructure:"default_extensions_template" json:"default_extensions_template"`
+	DefaultCriticalOptionsTemplate bool              `mapstructure:"default_critical_options_template" json:"default_critical_options_template"`
+	AllowedUserKeyTypes            string            `mapstructure:"allowed_user_key_types" json:"allowed_user_key_types"`
+	UserKeyMinBits                 map[string]int    `mapstructure:"user_key_min_bits" json:"user_key_min_bits"`
+	NotBeforeDuration              string            `mapstructure:"not_before_duration" json:"not_before_duration"`
+	OTPLength                      int               `mapstructure:"otp_length" json:"otp_length"`
+	OTPCharset                     string            `mapstructure:"otp_charset" json:"otp_charset"`
 }
 
 func pathListRoles(b *backend) *framework.Path {
 	return &framework.Path{
 		Pattern: "roles/?$",
+		Fields: map[string]*framework.FieldSchema{
+			"detailed": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `
+				If set, the key information of each role also includes its allowed users,
+				default user and TTLs, and the key types or sizes of its keys.
+				`,
+			},
+			"key_type": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `
+				If set, only the roles of this type are listed: 'otp', 'dynamic' or 'ca'.
+				`,
+				AllowedValues: []interface{}{KeyTypeOTP, KeyTypeDynamic, KeyTypeCA},
+			},
+		},
 
 		Callbacks: map[logical.Operation]framework.OperationFunc{
 			logical.ListOperation: b.pathRoleList,
@@ -121,12 +160,29 @@ func pathRoles(b *backend) *framework.Path {
 				to inform client about the port number to use. Port number will be
 				returned to client by Vault server along with OTP.`,
 			},
+			"otp_length": &framework.FieldSchema{
+				Type: framework.TypeInt,
+				Description: `
+				[Not applicable for Dynamic type] [Optional for OTP type] [Not applicable for CA type]
+				Number of characters of the generated OTPs, between 6 and 128. The OTPs must
+				have at least 64 bits of entropy, e.g. 11 characters with the default charset
+				or 20 digits. When not set, OTPs are UUIDs.`,
+			},
+			"otp_charset": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `
+				[Not applicable for Dynamic type] [Optional for OTP type] [Not applicable for CA type]
+				Characters the generated OTPs are made of, e.g. '0123456789' for digits only.
+				Requires 'otp_length' to be set. Defaults to upper and lower case letters and
+				digits.`,
+			},
 			"key_type": &framework.FieldSchema{
 				Type: framework.TypeString,
 				Description: `
 				[Required for all types]
 				Type of key used to login to hosts. It can be either 'otp', 'dynamic' or 'ca'.
 				'otp' type requires agent to be installed in remote hosts.`,
+				Required: true,
 			},
 			"key_bits": &framework.FieldSchema{
 				Type: framework.TypeInt,
@@ -207,6 +263,22 @@ func pathRoles(b *backend) *framework.Path {
 				To allow any extensions, set this to an empty string.
 				`,
 			},
+			"denied_users": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `
+				[Not applicable for Dynamic type] [Not applicable for OTP type] [Optional for CA type]
+				A comma-separated list of users that user certificates are never signed for,
+				even if "allowed_users" allows them, e.g. 'root' when "allowed_users" is '*'.
+				`,
+			},
+			"denied_extensions": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `
+				[Not applicable for Dynamic type] [Not applicable for OTP type] [Optional for CA type]
+				A comma-separated list of extensions that certificates are never signed with,
+				even if "allowed_extensions" allows them or is empty.
+				`,
+			},
 			"default_critical_options": &framework.FieldSchema{
 				Type: framework.TypeMap,
 				Description: `
@@ -214,7 +286,9 @@ func pathRoles(b *backend) *framework.Path {
 				[Optional for CA type] Critical options certificates should
 				have if none are provided when signing. This field takes in key
 				value pairs in JSON format.  Note that these are not restricted
-				by "allowed_critical_options". Defaults to none.
+				by "allowed_critical_options". When "allowed_critical_options"
+				is set, the default options that are not on it are kept even if
+				other options are provided when signing. Defaults to none.
 				`,
 			},
 			"default_extensions": &framework.FieldSchema{
@@ -259,6 +333,13 @@ func pathRoles(b *backend) *framework.Path {
 				If set, host certificates that are requested are allowed to use subdomains of those listed in "allowed_domains".
 				`,
 			},
+			"allow_glob_domains": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `
+				[Not applicable for Dynamic type] [Not applicable for OTP type] [Optional for CA type]
+				If set, domains specified in "allowed_domains" can include glob patterns, e.g. "web-*.example.com".
+				`,
+			},
 			"allow_user_key_ids": &framework.FieldSchema{
 				Type: framework.TypeBool,
 				Description: `
@@ -278,6 +359,110 @@ func pathRoles(b *backend) *framework.Path {
 				'{{public_key_hash}}' - A SHA256 checksum of the public key that is being signed.
 				`,
 			},
+			"allowed_comment_regex": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `
+				[Not applicable for Dynamic type] [Not applicable for OTP type] [Optional for CA type]
+				A regular expression that the "cert_comment" supplied when signing must
+				match in full. When not set, clients cannot supply a comment and signed
+				certificates are returned without one.
+				`,
+			},
+			"validate_host_principals_dns": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `
+				[Not applicable for Dynamic type] [Not applicable for OTP type] [Optional for CA type]
+				If set, each principal of a requested host certificate must resolve in DNS.
+				Principals that do not resolve are reported and the request is rejected.
+				Lookups add latency to signing and depend on the resolver of the Vault server.
+				`,
+			},
+			"issued_key_type": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `
+				[Not applicable for Dynamic type] [Not applicable for OTP type] [Optional for CA type]
+				Type of the key pairs generated by the 'issue' endpoint; one of "rsa", "ecdsa-p256",
+				"ecdsa-p384", "ecdsa-p521" or "ed25519".
+				`,
+				Default: caKeyTypeRSA,
+			},
+			"issued_key_bits": &framework.FieldSchema{
+				Type: framework.TypeInt,
+				Description: `
+				[Not applicable for Dynamic type] [Not applicable for OTP type] [Optional for CA type]
+				Size in bits of the RSA keys generated by the 'issue' endpoint; one of 2048, 3072 or 4096.
+				Defaults to 4096. Not applicable to other key types.
+				`,
+			},
+			"algorithm_signer": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `
+				[Not applicable for Dynamic type] [Not applicable for OTP type] [Optional for CA type]
+				Signature algorithm used by RSA CA keys; one of "ssh-rsa", "rsa-sha2-256", "rsa-sha2-512"
+				or "default". The default is "ssh-rsa", or "rsa-sha2-256" in FIPS mode.
+				Not applicable to other CA key types.
+				`,
+				Default: defaultAlgorithmSigner,
+			},
+			"allowed_users_template": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `
+				[Not applicable for Dynamic type] [Not applicable for OTP type] [Optional for CA type]
+				If set, entries of "allowed_users" can contain identity templates such as
+				'{{identity.entity.aliases.<mount accessor>.name}}', which are replaced with
+				values of the entity of the requesting token.
+				`,
+			},
+			"default_user_template": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `
+				[Not applicable for Dynamic type] [Not applicable for OTP type] [Optional for CA type]
+				If set, "default_user" can contain identity templates, as for "allowed_users_template".
+				`,
+			},
+			"default_extensions_template": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `
+				[Not applicable for Dynamic type] [Not applicable for OTP type] [Optional for CA type]
+				If set, values of "default_extensions" can contain identity templates, as for
+				"allowed_users_template".
+				`,
+			},
+			"default_critical_options_template": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `
+				[Not applicable for Dynamic type] [Not applicable for OTP type] [Optional for CA type]
+				If set, values of "default_critical_options" such as 'force-command' and
+				'source-address' can contain identity templates, as for "allowed_users_template".
+				`,
+			},
+			"not_before_duration": &framework.FieldSchema{
+				Type: framework.TypeDurationSecond,
+				Description: `
+				[Not applicable for Dynamic type] [Not applicable for OTP type] [Optional for CA type]
+				The duration by which the start of the validity of signed certificates is
+				backdated, to allow for clock skew between Vault and the hosts. Defaults to 30s.
+				`,
+				Default: int(defaultNotBeforeDuration.Seconds()),
+			},
+			"allowed_user_key_types": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `
+				[Not applicable for Dynamic type] [Not applicable for OTP type] [Optional for CA type]
+				A comma-separated list of the types of public keys that can be signed; any of
+				"rsa", "dsa", "ecdsa", "ed25519", "sk-ecdsa" and "sk-ed25519". To allow any type,
+				set this to an empty string.
+				`,
+			},
+			"user_key_min_bits": &framework.FieldSchema{
+				Type: framework.TypeMap,
+				Description: `
+				[Not applicable for Dynamic type] [Not applicable for OTP type] [Optional for CA type]
+				Minimum size in bits of the public keys that can be signed, by key type, e.g.
+				{"rsa": 2048, "ecdsa": 384}. The size of ECDSA keys is the size of their curve.
+				Types that are not listed have no minimum size.
+				`,
+			},
 		},
 
 		Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -329,11 +514,7 @@ func (b *backend) pathRoleWrite(ctx context.Context, req *logical.Request, d *fr
 		port = 22
 	}
 
-	keyType := d.Get("key_type").(string)
-	if keyType == "" {
-		return logical.ErrorResponse("missing key type"), nil
-	}
-	keyType = strings.ToLower(keyType)
+	keyType := strings.ToLower(d.Get("key_type").(string))
 
 	var roleEntry sshRole
 	if keyType == KeyTypeOTP {
@@ -349,6 +530,12 @@ func (b *backend) pathRoleWrite(ctx context.Context, req *logical.Request, d *fr
 			return logical.ErrorResponse("admin user not required for OTP type"), nil
 		}
 
+		otpLength := d.Get("otp_length").(int)
+		otpCharset := d.Get("otp_charset").(string)
+		if err := validateOTPFormat(otpLength, otpCharset); err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+
 		// Below are the only fields used from the role structure for OTP type.
 		roleEntry = sshRole{
 			DefaultUser:     defaultUser,
@@ -357,6 +544,8 @@ func (b *backend) pathRoleWrite(ctx context.Context, req *logical.Request, d *fr
 			KeyType:         KeyTypeOTP,
 			Port:            port,
 			AllowedUsers:    allowedUsers,
+			OTPLength:       otpLength,
+			OTPCharset:      otpCharset,
 		}
 	} else if keyType == KeyTypeDynamic {
 		defaultUser := d.Get("default_user").(string)
@@ -398,6 +587,14 @@ func (b *backend) pathRoleWrite(ctx context.Context, req *logical.Request, d *fr
 			keyBits = 2048
 		}
 
+		fipsMode, err := b.fipsModeEnabled(ctx, req.Storage)
+		if err != nil {
+			return nil, err
+		}
+		if fipsMode && keyBits < fipsMinRSAKeyBits {
+			return logical.ErrorResponse(fmt.Sprintf("key_bits must be at least %d in FIPS mode", fipsMinRSAKeyBits)), nil
+		}
+
 		// Store all the fields required by dynamic key type
 		roleEntry = sshRole{
 			KeyName:         keyName,
@@ -430,48 +627,152 @@ func (b *backend) pathRoleWrite(ctx context.Context, req *logical.Request, d *fr
 	if err := req.Storage.Put(ctx, entry); err != nil {
 		return nil, err
 	}
+
+	if roleEntry.KeyType == KeyTypeCA {
+		systemMaxTTL := b.System().MaxLeaseTTL()
+		var resp *logical.Response
+		for _, field := range []string{"ttl", "max_ttl"} {
+			if value := time.Duration(d.Get(field).(int)) * time.Second; value > systemMaxTTL {
+				if resp == nil {
+					resp = &logical.Response{}
+				}
+				resp.AddWarning(fmt.Sprintf("%s is greater than the backend or system max TTL of %d; certificates will be capped to it", field, systemMaxTTL/time.Second))
+			}
+		}
+		return resp, nil
+	}
+
+	if roleEntry.KeyType == KeyTypeDynamic {
+		resp := &logical.Response{}
+		resp.AddWarning("The dynamic key type is deprecated; consider migrating the role to the CA type with 'migrate/dynamic-to-ca'.")
+		return resp, nil
+	}
+
 	return nil, nil
 }
 
 func (b *backend) createCARole(allowedUsers, defaultUser string, data *framework.FieldData) (*sshRole, *logical.Response) {
 	ttl := time.Duration(data.Get("ttl").(int)) * time.Second
 	maxTTL := time.Duration(data.Get("max_ttl").(int)) * time.Second
+	notBeforeDuration := time.Duration(data.Get("not_before_duration").(int)) * time.Second
 	role := &sshRole{
-		AllowedCriticalOptions: data.Get("allowed_critical_options").(string),
-		AllowedExtensions:      data.Get("allowed_extensions").(string),
-		AllowUserCertificates:  data.Get("allow_user_certificates").(bool),
-		AllowHostCertificates:  data.Get("allow_host_certificates").(bool),
-		AllowedUsers:           allowedUsers,
-		AllowedDomains:         data.Get("allowed_domains").(string),
-		DefaultUser:            defaultUser,
-		AllowBareDomains:       data.Get("allow_bare_domains").(bool),
-		AllowSubdomains:        data.Get("allow_subdomains").(bool),
-		AllowUserKeyIDs:        data.Get("allow_user_key_ids").(bool),
-		KeyIDFormat:            data.Get("key_id_format").(string),
-		KeyType:                KeyTypeCA,
+		AllowedCriticalOptions:         data.Get("allowed_critical_options").(string),
+		AllowedExtensions:              data.Get("allowed_extensions").(string),
+		DeniedUsers:                    data.Get("denied_users").(string),
+		DeniedExtensions:               data.Get("denied_extensions").(string),
+		AllowUserCertificates:          data.Get("allow_user_certificates").(bool),
+		AllowHostCertificates:          data.Get("allow_host_certificates").(bool),
+		AllowedUsers:                   allowedUsers,
+		AllowedDomains:                 data.Get("allowed_domains").(string),
+		DefaultUser:                    defaultUser,
+		AllowBareDomains:               data.Get("allow_bare_domains").(bool),
+		AllowSubdomains:                data.Get("allow_subdomains").(bool),
+		AllowGlobDomains:               data.Get("allow_glob_domains").(bool),
+		AllowUserKeyIDs:                data.Get("allow_user_key_ids").(bool),
+		KeyIDFormat:                    data.Get("key_id_format").(string),
+		AllowedCommentRegex:            data.Get("allowed_comment_regex").(string),
+		ValidateHostPrincipalsDNS:      data.Get("validate_host_principals_dns").(bool),
+		IssuedKeyType:                  data.Get("issued_key_type").(string),
+		IssuedKeyBits:                  data.Get("issued_key_bits").(int),
+		AlgorithmSigner:                data.Get("algorithm_signer").(string),
+		AllowedUsersTemplate:           data.Get("allowed_users_template").(bool),
+		DefaultUserTemplate:            data.Get("default_user_template").(bool),
+		DefaultExtensionsTemplate:      data.Get("default_extensions_template").(bool),
+		DefaultCriticalOptionsTemplate: data.Get("default_critical_options_template").(bool),
+		AllowedUserKeyTypes:            data.Get("allowed_user_key_types").(string),
+		KeyType:                        KeyTypeCA,
 	}
 
 	if !role.AllowUserCertificates && !role.AllowHostCertificates {
 		return nil, logical.ErrorResponse("Either 'allow_user_certificates' or 'allow_host_certificates' must be set to 'true'")
 	}
 
+	if role.AllowedCommentRegex != "" {
+		if _, err := compileCommentRegex(role.AllowedCommentRegex); err != nil {
+			return nil, logical.ErrorResponse(fmt.Sprintf("invalid allowed_comment_regex: %v", err))
+		}
+	}
+
+	if err := validateCAKeyTypeAndBits(role.IssuedKeyType, role.IssuedKeyBits); err != nil {
+		return nil, logical.ErrorResponse(fmt.Sprintf("invalid issued key: %v", err))
+	}
+
+	switch role.AlgorithmSigner {
+	case defaultAlgorithmSigner, sigAlgoRSA, sigAlgoRSASHA256, sigAlgoRSASHA512:
+	default:
+		return nil, logical.ErrorResponse(fmt.Sprintf("invalid algorithm_signer %q", role.AlgorithmSigner))
+	}
+
+	for _, keyType := range strutil.ParseStringSlice(role.AllowedUserKeyTypes, ",") {
+		if !strutil.StrListContains(userKeyTypes, keyType) {
+			return nil, logical.ErrorResponse(fmt.Sprintf("invalid allowed_user_key_types: unknown key type %q", keyType))
+		}
+	}
+
+	userKeyMinBits := map[string]int{}
+	for keyType, bits := range convertMapToStringValue(data.Get("user_key_min_bits").(map[string]interface{})) {
+		if !strutil.StrListContains(userKeyTypes, keyType) {
+			return nil, logical.ErrorResponse(fmt.Sprintf("invalid user_key_min_bits: unknown key type %q", keyType))
+		}
+		minBits, err := strconv.Atoi(bits)
+		if err != nil || minBits < 0 {
+			return nil, logical.ErrorResponse(fmt.Sprintf("invalid user_key_min_bits: %q is not a valid size for key type %q", bits, keyType))
+		}
+		userKeyMinBits[keyType] = minBits
+	}
+
 	defaultCriticalOptions := convertMapToStringValue(data.Get("default_critical_options").(map[string]interface{}))
 	defaultExtensions := convertMapToStringValue(data.Get("default_extensions").(map[string]interface{}))
 
+	// Defaults that are denied could never be signed
+	if !role.DefaultUserTemplate && role.DefaultUser != "" && strutil.StrListContains(strutil.ParseStringSlice(role.DeniedUsers, ","), role.DefaultUser) {
+		return nil, logical.ErrorResponse(fmt.Sprintf("default_user %q is on denied_users", role.DefaultUser))
+	}
+	if denied := deniedExtensions(role, defaultExtensions); len(denied) != 0 {
+		return nil, logical.ErrorResponse(fmt.Sprintf("default_extensions %v are on denied_extensions", denied))
+	}
+
+	// Templated values can only be checked once rendered when signing
+	if sourceAddress, ok := defaultCriticalOptions[criticalOptionSourceAddress]; ok && !(role.DefaultCriticalOptionsTemplate && identityTemplateRegex.MatchString(sourceAddress)) {
+		if err := validateSourceAddress(sourceAddress); err != nil {
+			return nil, logical.ErrorResponse(fmt.Sprintf("invalid default_critical_options: %v", err))
+		}
+	}
+
+	if ttl < 0 || maxTTL < 0 {
+		return nil, logical.ErrorResponse(`"ttl" and "max_ttl" must not be negative`)
+	}
+
 	if ttl != 0 && maxTTL != 0 && ttl > maxTTL {
 		return nil, logical.ErrorResponse(
 			`"ttl" value must be less than "max_ttl" when both are specified`)
 	}
 
+	if notBeforeDuration < 0 {
+		return nil, logical.ErrorResponse("not_before_duration must not be negative")
+	}
+
 	// Persist TTLs
 	role.TTL = ttl.String()
 	role.MaxTTL = maxTTL.String()
+	role.NotBeforeDuration = notBeforeDuration.String()
 	role.DefaultCriticalOptions = defaultCriticalOptions
 	role.DefaultExtensions = defaultExtensions
+	role.UserKeyMinBits = userKeyMinBits
 
 	return role, nil
 }
 
+// notBeforeDuration returns the duration by which certificates signed by the
+// role are backdated. Roles written before it was configurable use the
+// default.
+func (r *sshRole) notBeforeDuration() (time.Duration, error) {
+	if r.NotBeforeDuration == "" {
+		return defaultNotBeforeDuration, nil
+	}
+	return parseutil.ParseDurationSecond(r.NotBeforeDuration)
+}
+
 func (b *backend) getRole(ctx context.Context, s logical.Storage, n string) (*sshRole, error) {
 	entry, err := s.Get(ctx, "roles/"+n)
 	if err != nil {
@@ -504,6 +805,8 @@ func (b *backend) parseRole(role *sshRole) (map[string]interface{}, error) {
 			"key_type":          role.KeyType,
 			"port":              role.Port,
 			"allowed_users":     role.AllowedUsers,
+			"otp_length":        role.OTPLength,
+			"otp_charset":       role.OTPCharset,
 		}
 	case KeyTypeCA:
 		ttl, err := parseutil.ParseDurationSecond(role.TTL)
@@ -514,24 +817,43 @@ func (b *backend) parseRole(role *sshRole) (map[string]interface{}, error) {
 		if err != nil {
 			return nil, err
 		}
+		notBeforeDuration, err := role.notBeforeDuration()
+		if err != nil {
+			return nil, err
+		}
 
 		result = map[string]interface{}{
-			"allowed_users":            role.AllowedUsers,
-			"allowed_domains":          role.AllowedDomains,
-			"default_user":             role.DefaultUser,
-			"ttl":                      int64(ttl.Seconds()),
-			"max_ttl":                  int64(maxTTL.Seconds()),
-			"allowed_critical_options": role.AllowedCriticalOptions,
-			"allowed_extensions":       role.AllowedExtensions,
-			"allow_user_certificates":  role.AllowUserCertificates,
-			"allow_host_certificates":  role.AllowHostCertificates,
-			"allow_bare_domains":       role.AllowBareDomains,
-			"allow_subdomains":         role.AllowSubdomains,
-			"allow_user_key_ids":       role.AllowUserKeyIDs,
-			"key_id_format":            role.KeyIDFormat,
-			"key_type":                 role.KeyType,
-			"default_critical_options": role.DefaultCriticalOptions,
-			"default_extensions":       role.DefaultExtensions,
+			"allowed_users":                     role.AllowedUsers,
+			"allowed_domains":                   role.AllowedDomains,
+			"default_user":                      role.DefaultUser,
+			"ttl":                               int64(ttl.Seconds()),
+			"max_ttl":                           int64(maxTTL.Seconds()),
+			"allowed_critical_options":          role.AllowedCriticalOptions,
+			"allowed_extensions":                role.AllowedExtensions,
+			"denied_users":                      role.DeniedUsers,
+			"denied_extensions":                 role.DeniedExtensions,
+			"allow_user_certificates":           role.AllowUserCertificates,
+			"allow_host_certificates":           role.AllowHostCertificates,
+			"allow_bare_domains":                role.AllowBareDomains,
+			"allow_subdomains":                  role.AllowSubdomains,
+			"allow_glob_domains":                role.AllowGlobDomains,
+			"allow_user_key_ids":                role.AllowUserKeyIDs,
+			"key_id_format":                     role.KeyIDFormat,
+			"allowed_comment_regex":             role.AllowedCommentRegex,
+			"validate_host_principals_dns":      role.ValidateHostPrincipalsDNS,
+			"issued_key_type":                   role.IssuedKeyType,
+			"issued_key_bits":                   role.IssuedKeyBits,
+			"algorithm_signer":                  role.AlgorithmSigner,
+			"allowed_users_template":            role.AllowedUsersTemplate,
+			"default_user_template":             role.DefaultUserTemplate,
+			"default_extensions_template":       role.DefaultExtensionsTemplate,
+			"default_critical_options_template": role.DefaultCriticalOptionsTemplate,
+			"not_before_duration":               int64(notBeforeDuration.Seconds()),
+			"allowed_user_key_types":            role.AllowedUserKeyTypes,
+			"user_key_min_bits":                 role.UserKeyMinBits,
+			"key_type":                          role.KeyType,
+			"default_critical_options":          role.DefaultCriticalOptions,
+			"default_extensions":                role.DefaultExtensions,
 		}
 	case KeyTypeDynamic:
 		result = map[string]interface{}{
@@ -558,12 +880,27 @@ func (b *backend) parseRole(role *sshRole) (map[string]interface{}, error) {
 	return result, nil
 }
 
+// detailedRoleListFields are the fields of a role, as read, included in the
+// key information of a detailed role list where the role has them.
+var detailedRoleListFields = []string{
+	"allowed_users",
+	"default_user",
+	"ttl",
+	"max_ttl",
+	"allowed_user_key_types",
+	"key_bits",
+}
+
 func (b *backend) pathRoleList(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	detailed := d.Get("detailed").(bool)
+	keyTypeFilter := d.Get("key_type").(string)
+
 	entries, err := req.Storage.List(ctx, "roles/")
 	if err != nil {
 		return nil, err
 	}
 
+	keys := []string{}
 	keyInfo := map[string]interface{}{}
 	for _, entry := range entries {
 		role, err := b.getRole(ctx, req.Storage, entry)
@@ -572,6 +909,9 @@ func (b *backend) pathRoleList(ctx context.Context, req *logical.Request, d *fra
 			if b.Logger().IsWarn() {
 				b.Logger().Warn("ssh: error getting role info", "role", entry, "error", err)
 			}
+			if keyTypeFilter == "" {
+				keys = append(keys, entry)
+			}
 			continue
 		}
 		if role == nil {
@@ -579,9 +919,17 @@ func (b *backend) pathRoleList(ctx context.Context, req *logical.Request, d *fra
 			if b.Logger().IsWarn() {
 				b.Logger().Warn("ssh: no role info found", "role", entry)
 			}
+			if keyTypeFilter == "" {
+				keys = append(keys, entry)
+			}
 			continue
 		}
 
+		if keyTypeFilter != "" && role.KeyType != keyTypeFilter {
+			continue
+		}
+		keys = append(keys, entry)
+
 		roleInfo, err := b.parseRole(role)
 		if err != nil {
 			if b.Logger().IsWarn() {
@@ -591,13 +939,21 @@ func (b *backend) pathRoleList(ctx context.Context, req *logical.Request, d *fra
 		}
 
 		if keyType, ok := roleInfo["key_type"]; ok {
-			keyInfo[entry] = map[string]interface{}{
+			info := map[string]interface{}{
 				"key_type": keyType,
 			}
+			if detailed {
+				for _, field := range detailedRoleListFields {
+					if value, ok := roleInfo[field]; ok {
+						info[field] = value
+					}
+				}
+			}
+			keyInfo[entry] = info
 		}
 	}
 
-	return logical.ListResponseWithInfo(entries, keyInfo), nil
+	return logical.ListResponseWithInfo(keys, keyInfo), nil
 }
 
 func (b *backend) pathRoleRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
@@ -1,29 +1,58 @@
 package ssh
 
 import (
+	"bytes"
 	"context"
+	"crypto"
+	"crypto/dsa"
+	"crypto/ecdsa"
 	"crypto/rand"
+	"crypto/rsa"
 	"crypto/sha256"
 	"errors"
 	"fmt"
-	"strconv"
+	"net"
+	"regexp"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/hashicorp/vault/helper/certutil"
+	"github.com/hashicorp/vault/helper/errutil"
 	"github.com/hashicorp/vault/helper/parseutil"
 	"github.com/hashicorp/vault/helper/strutil"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
+	"github.com/mitchellh/mapstructure"
+	"github.com/ryanuber/go-glob"
+	"golang.org/x/crypto/ed25519"
 	"golang.org/x/crypto/ssh"
 )
 
+// Key types of the public keys that can be submitted for signing.
+const (
+	userKeyTypeRSA     = "rsa"
+	userKeyTypeDSA     = "dsa"
+	userKeyTypeECDSA   = "ecdsa"
+	userKeyTypeEd25519 = "ed25519"
+
+	userKeyTypeSKECDSA   = "sk-ecdsa"
+	userKeyTypeSKEd25519 = "sk-ed25519"
+)
+
+var userKeyTypes = []string{userKeyTypeRSA, userKeyTypeDSA, userKeyTypeECDSA, userKeyTypeEd25519, userKeyTypeSKECDSA, userKeyTypeSKEd25519}
+
+// criticalOptionSourceAddress is the critical option restricting the
+// addresses a certificate can be used from.
+const criticalOptionSourceAddress = "source-address"
+
 type creationBundle struct {
 	KeyId           string
 	ValidPrincipals []string
 	PublicKey       ssh.PublicKey
 	CertificateType uint32
 	TTL             time.Duration
+	NotBefore       time.Duration
 	Signer          ssh.Signer
 	Role            *sshRole
 	CriticalOptions map[string]string
@@ -38,48 +67,72 @@ func pathSign(b *backend) *framework.Path {
 			logical.UpdateOperation: b.pathSign,
 		},
 
-		Fields: map[string]*framework.FieldSchema{
-			"role": &framework.FieldSchema{
-				Type:        framework.TypeString,
-				Description: `The desired role with configuration for this request.`,
-			},
-			"ttl": &framework.FieldSchema{
-				Type: framework.TypeDurationSecond,
-				Description: `The requested Time To Live for the SSH certificate;
+		Fields: signFields(),
+
+		HelpSynopsis:    `Request signing an SSH key using a certain role with the provided details.`,
+		HelpDescription: `This path allows SSH keys to be signed according to the policy of the given role. Several keys can be signed at once by passing them in "batch_input".`,
+	}
+}
+
+// signFields returns the fields of the sign path. The issue path accepts the
+// same fields, except for the public key it generates itself.
+func signFields() map[string]*framework.FieldSchema {
+	return map[string]*framework.FieldSchema{
+		"role": &framework.FieldSchema{
+			Type:        framework.TypeString,
+			Description: `The desired role with configuration for this request.`,
+		},
+		"ttl": &framework.FieldSchema{
+			Type: framework.TypeDurationSecond,
+			Description: `The requested Time To Live for the SSH certificate;
 sets the expiration date. If not specified
 the role default, backend default, or system
-default TTL is used, in that order. Cannot
-be later than the role max TTL.`,
-			},
-			"public_key": &framework.FieldSchema{
-				Type:        framework.TypeString,
-				Description: `SSH public key that should be signed.`,
-			},
-			"valid_principals": &framework.FieldSchema{
-				Type:        framework.TypeString,
-				Description: `Valid principals, either usernames or hostnames, that the certificate should be signed for.`,
-			},
-			"cert_type": &framework.FieldSchema{
-				Type:        framework.TypeString,
-				Description: `Type of certificate to be created; either "user" or "host".`,
-				Default:     "user",
-			},
-			"key_id": &framework.FieldSchema{
-				Type:        framework.TypeString,
-				Description: `Key id that the created certificate should have. If not specified, the display name of the token will be used.`,
-			},
-			"critical_options": &framework.FieldSchema{
-				Type:        framework.TypeMap,
-				Description: `Critical options that the certificate should be signed for.`,
-			},
-			"extensions": &framework.FieldSchema{
-				Type:        framework.TypeMap,
-				Description: `Extensions that the certificate should be signed for.`,
-			},
+default TTL is used, in that order. Capped
+to the role max TTL, or the backend or system
+max TTL if it is lower.`,
+		},
+		"public_key": &framework.FieldSchema{
+			Type:        framework.TypeString,
+			Description: `SSH public key that should be signed.`,
+		},
+		"valid_principals": &framework.FieldSchema{
+			Type:        framework.TypeString,
+			Description: `Valid principals, either usernames or hostnames, that the certificate should be signed for.`,
+		},
+		"cert_type": &framework.FieldSchema{
+			Type:        framework.TypeString,
+			Description: `Type of certificate to be created; either "user" or "host".`,
+			Default:     "user",
+		},
+		"key_id": &framework.FieldSchema{
+			Type:        framework.TypeString,
+			Description: `Key id that the created certificate should have. If not specified, the display name of the token will be used.`,
+		},
+		"critical_options": &framework.FieldSchema{
+			Type:        framework.TypeMap,
+			Description: `Critical options that the certificate should be signed for.`,
+		},
+		"extensions": &framework.FieldSchema{
+			Type:        framework.TypeMap,
+			Description: `Extensions that the certificate should be signed for.`,
+		},
+		"cert_comment": &framework.FieldSchema{
+			Type:        framework.TypeString,
+			Description: `Comment appended to the returned signed key. Must match the role's allowed_comment_regex.`,
+		},
+		"emit_ssh_config": &framework.FieldSchema{
+			Type:        framework.TypeBool,
+			Description: `If set, an ssh_config Host block using the signed certificate is returned in "ssh_config". Only applies to user certificates.`,
+		},
+		"ssh_config_host": &framework.FieldSchema{
+			Type:        framework.TypeString,
+			Description: `Host pattern of the Host block returned when "emit_ssh_config" is set.`,
+			Default:     "*",
+		},
+		"issuer": &framework.FieldSchema{
+			Type:        framework.TypeString,
+			Description: `Name of the issuer to sign the certificate with. If not specified, the default issuer is used.`,
 		},
-
-		HelpSynopsis:    `Request signing an SSH key using a certain role with the provided details.`,
-		HelpDescription: `This path allows SSH keys to be signed according to the policy of the given role.`,
 	}
 }
 
@@ -95,9 +148,68 @@ func (b *backend) pathSign(ctx context.Context, req *logical.Request, data *fram
 		return logical.ErrorResponse(fmt.Sprintf("Unknown role: %s", roleName)), nil
 	}
 
+	if batchInputRaw := data.Raw["batch_input"]; batchInputRaw != nil {
+		return b.pathSignBatch(ctx, req, data, role, batchInputRaw)
+	}
+
 	return b.pathSignCertificate(ctx, req, data, role)
 }
 
+// signBatchRequestItem is a public key to sign in a batch sign request.
+type signBatchRequestItem struct {
+	PublicKey string `json:"public_key" structs:"public_key" mapstructure:"public_key"`
+	KeyId     string `json:"key_id" structs:"key_id" mapstructure:"key_id"`
+}
+
+// pathSignBatch signs each public key of the batch with the other parameters
+// of the request. Keys that cannot be signed are reported in the error of
+// their result, without failing the other keys.
+func (b *backend) pathSignBatch(ctx context.Context, req *logical.Request, data *framework.FieldData, role *sshRole, batchInputRaw interface{}) (*logical.Response, error) {
+	var batchInputItems []signBatchRequestItem
+	if err := mapstructure.Decode(batchInputRaw, &batchInputItems); err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("failed to parse batch input: %v", err)), nil
+	}
+	if len(batchInputItems) == 0 {
+		return logical.ErrorResponse("missing batch input to process"), nil
+	}
+
+	batchResults := make([]map[string]interface{}, len(batchInputItems))
+	for i, item := range batchInputItems {
+		raw := make(map[string]interface{}, len(data.Raw))
+		for k, v := range data.Raw {
+			raw[k] = v
+		}
+		delete(raw, "batch_input")
+		raw["public_key"] = item.PublicKey
+		if item.KeyId != "" {
+			raw["key_id"] = item.KeyId
+		} else {
+			delete(raw, "key_id")
+		}
+
+		resp, err := b.pathSignCertificate(ctx, req, &framework.FieldData{Raw: raw, Schema: data.Schema}, role)
+		if err != nil {
+			return nil, err
+		}
+		if resp == nil {
+			return nil, fmt.Errorf("no response signing batch input item %d", i)
+		}
+		if resp.IsError() {
+			batchResults[i] = map[string]interface{}{
+				"error": resp.Error().Error(),
+			}
+			continue
+		}
+		batchResults[i] = resp.Data
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"batch_results": batchResults,
+		},
+	}, nil
+}
+
 func (b *backend) pathSignCertificate(ctx context.Context, req *logical.Request, data *framework.FieldData, role *sshRole) (*logical.Response, error) {
 	publicKey := data.Get("public_key").(string)
 	if publicKey == "" {
@@ -109,6 +221,16 @@ func (b *backend) pathSignCertificate(ctx context.Context, req *logical.Request,
 		return logical.ErrorResponse(fmt.Sprintf("failed to parse public_key as SSH key: %s", err)), nil
 	}
 
+	if err := validateUserPublicKey(role, userPublicKey); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	return b.signPublicKey(ctx, req, data, role, userPublicKey)
+}
+
+// signPublicKey signs userPublicKey according to the role and the sign
+// parameters of the request, which are shared by the sign and issue paths.
+func (b *backend) signPublicKey(ctx context.Context, req *logical.Request, data *framework.FieldData, role *sshRole, userPublicKey ssh.PublicKey) (*logical.Response, error) {
 	// Note that these various functions always return "user errors" so we pass
 	// them as 4xx values
 	keyId, err := b.calculateKeyId(data, req, role, userPublicKey)
@@ -127,47 +249,134 @@ func (b *backend) pathSignCertificate(ctx context.Context, req *logical.Request,
 		if err != nil {
 			return logical.ErrorResponse(err.Error()), nil
 		}
+		if role.ValidateHostPrincipalsDNS {
+			if unresolved := b.unresolvedHostPrincipals(ctx, parsedPrincipals); len(unresolved) != 0 {
+				return logical.ErrorResponse(fmt.Sprintf("valid_principals do not resolve in DNS: %s", strings.Join(unresolved, ", "))), nil
+			}
+		}
 	} else {
-		parsedPrincipals, err = b.calculateValidPrincipals(data, role.DefaultUser, role.AllowedUsers, strutil.StrListContains)
+		defaultUser, allowedUsers, err := b.calculateUsers(data, req, role)
+		switch err.(type) {
+		case nil:
+		case errutil.UserError:
+			return logical.ErrorResponse(err.Error()), nil
+		default:
+			return nil, err
+		}
+
+		parsedPrincipals, err = b.calculateValidPrincipals(data, defaultUser, allowedUsers, strutil.StrListContains)
 		if err != nil {
 			return logical.ErrorResponse(err.Error()), nil
 		}
+
+		deniedUsers := strutil.ParseStringSlice(role.DeniedUsers, ",")
+		for _, principal := range parsedPrincipals {
+			if strutil.StrListContains(deniedUsers, principal) {
+				return logical.ErrorResponse(fmt.Sprintf("%v is denied by the role", principal)), nil
+			}
+		}
 	}
 
-	ttl, err := b.calculateTTL(data, role)
+	emitSSHConfig := data.Get("emit_ssh_config").(bool)
+	if emitSSHConfig && certificateType != ssh.UserCert {
+		return logical.ErrorResponse("emit_ssh_config is only supported for user certificates"), nil
+	}
+
+	ttl, ttlWarnings, err := b.calculateTTL(data, role)
 	if err != nil {
 		return logical.ErrorResponse(err.Error()), nil
 	}
 
-	criticalOptions, err := b.calculateCriticalOptions(data, role)
+	comment, err := b.calculateComment(data, role)
 	if err != nil {
 		return logical.ErrorResponse(err.Error()), nil
 	}
 
-	extensions, err := b.calculateExtensions(data, role)
-	if err != nil {
+	criticalOptions, err := b.calculateCriticalOptions(data, req, role)
+	switch err.(type) {
+	case nil:
+	case errutil.UserError:
 		return logical.ErrorResponse(err.Error()), nil
+	default:
+		return nil, err
 	}
 
-	privateKeyEntry, err := caKey(ctx, req.Storage, caPrivateKey)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read CA private key: %v", err)
+	extensions, err := b.calculateExtensions(data, req, role)
+	switch err.(type) {
+	case nil:
+	case errutil.UserError:
+		return logical.ErrorResponse(err.Error()), nil
+	default:
+		return nil, err
 	}
-	if privateKeyEntry == nil || privateKeyEntry.Key == "" {
-		return nil, fmt.Errorf("failed to read CA private key")
+
+	issuerName, privateKey, err := issuerPrivateKey(ctx, req.Storage, data.Get("issuer").(string))
+	switch err.(type) {
+	case nil:
+	case errutil.UserError:
+		return logical.ErrorResponse(err.Error()), nil
+	default:
+		return nil, err
 	}
 
-	signer, err := ssh.ParsePrivateKey([]byte(privateKeyEntry.Key))
+	signer, err := ssh.ParsePrivateKey([]byte(privateKey))
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse stored CA private key: %v", err)
 	}
 
+	fipsMode, err := b.fipsModeEnabled(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if fipsMode {
+		if err := validateFIPSPublicKey(userPublicKey); err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("public_key is not FIPS compliant: %v", err)), nil
+		}
+		if err := validateFIPSPublicKey(signer.PublicKey()); err != nil {
+			return nil, fmt.Errorf("configured CA key is not FIPS compliant: %v", err)
+		}
+	}
+
+	// The algorithm only matters for RSA keys, whose default signature
+	// algorithm hashes with SHA-1
+	if signer.PublicKey().Type() == ssh.KeyAlgoRSA {
+		algorithm := role.AlgorithmSigner
+		if algorithm == "" || algorithm == defaultAlgorithmSigner {
+			algorithm = sigAlgoRSA
+			if fipsMode {
+				algorithm = sigAlgoRSASHA256
+			}
+		}
+
+		switch algorithm {
+		case sigAlgoRSA:
+			if fipsMode {
+				return logical.ErrorResponse(fmt.Sprintf("algorithm_signer %q is not FIPS approved", algorithm)), nil
+			}
+		case sigAlgoRSASHA256:
+			signer, err = newRSASHA2Signer(privateKey, crypto.SHA256)
+		case sigAlgoRSASHA512:
+			signer, err = newRSASHA2Signer(privateKey, crypto.SHA512)
+		default:
+			return nil, fmt.Errorf("unknown algorithm_signer %q", algorithm)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to create CA signer: %v", err)
+		}
+	}
+
+	notBefore, err := role.notBeforeDuration()
+	if err != nil {
+		return nil, err
+	}
+
 	cBundle := creationBundle{
 		KeyId:           keyId,
 		PublicKey:       userPublicKey,
 		Signer:          signer,
 		ValidPrincipals: parsedPrincipals,
 		TTL:             ttl,
+		NotBefore:       notBefore,
 		CertificateType: certificateType,
 		Role:            role,
 		CriticalOptions: criticalOptions,
@@ -179,21 +388,71 @@ func (b *backend) pathSignCertificate(ctx context.Context, req *logical.Request,
 		return nil, err
 	}
 
-	signedSSHCertificate := ssh.MarshalAuthorizedKey(certificate)
+	signedSSHCertificate := marshalAuthorizedCertificate(certificate)
 	if len(signedSSHCertificate) == 0 {
 		return nil, fmt.Errorf("error marshaling signed certificate")
 	}
+	if comment != "" {
+		signedSSHCertificate = append(bytes.TrimSuffix(signedSSHCertificate, []byte("\n")), []byte(" "+comment+"\n")...)
+	}
 
+	if err := storeIssuedCertificate(ctx, req.Storage, data.Get("role").(string), issuerName, certificate, string(signedSSHCertificate)); err != nil {
+		return nil, fmt.Errorf("failed to store certificate: %v", err)
+	}
+
+	// The contents of the certificate are returned along with it, so that
+	// clients do not need to parse it to learn e.g. its serial number
 	response := &logical.Response{
-		Data: map[string]interface{}{
-			"serial_number": strconv.FormatUint(certificate.Serial, 16),
-			"signed_key":    string(signedSSHCertificate),
-		},
+		Data: certificateFields(certificate),
+	}
+	response.Data["signed_key"] = string(signedSSHCertificate)
+
+	if emitSSHConfig {
+		response.Data["ssh_config"] = sshConfigSnippet(data.Get("ssh_config_host").(string), certificate)
+	}
+
+	for _, warning := range ttlWarnings {
+		response.AddWarning(warning)
 	}
 
 	return response, nil
 }
 
+// sshConfigSnippet renders an ssh_config Host block that makes the client
+// present the given certificate. The certificate file is expected at the
+// location OpenSSH looks for it next to the default identity of its key type.
+func sshConfigSnippet(host string, certificate *ssh.Certificate) string {
+	if host == "" {
+		host = "*"
+	}
+
+	identity := "id_rsa"
+	switch certificate.Key.Type() {
+	case ssh.KeyAlgoDSA:
+		identity = "id_dsa"
+	case ssh.KeyAlgoECDSA256, ssh.KeyAlgoECDSA384, ssh.KeyAlgoECDSA521:
+		identity = "id_ecdsa"
+	case ssh.KeyAlgoED25519:
+		identity = "id_ed25519"
+	case keyAlgoSKECDSA256:
+		identity = "id_ecdsa_sk"
+	case keyAlgoSKED25519:
+		identity = "id_ed25519_sk"
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "Host %s\n", host)
+	if len(certificate.ValidPrincipals) > 0 {
+		fmt.Fprintf(&buf, "    User %s\n", certificate.ValidPrincipals[0])
+	}
+	fmt.Fprintf(&buf, "    CertificateFile ~/.ssh/%s-cert.pub\n", identity)
+	if len(certificate.ValidPrincipals) > 1 {
+		fmt.Fprintf(&buf, "    # Certificate is also valid for: %s\n", strings.Join(certificate.ValidPrincipals[1:], ", "))
+	}
+
+	return buf.String()
+}
+
 func (b *backend) calculateValidPrincipals(data *framework.FieldData, defaultPrincipal, principalsAllowedByRole string, validatePrincipal func([]string, string) bool) ([]string, error) {
 	validPrincipals := ""
 	validPrincipalsRaw, ok := data.GetOk("valid_principals")
@@ -203,8 +462,10 @@ func (b *backend) calculateValidPrincipals(data *framework.FieldData, defaultPri
 		validPrincipals = defaultPrincipal
 	}
 
-	parsedPrincipals := strutil.RemoveDuplicates(strutil.ParseStringSlice(validPrincipals, ","), false)
-	allowedPrincipals := strutil.RemoveDuplicates(strutil.ParseStringSlice(principalsAllowedByRole, ","), false)
+	// The order of the principals given in the request, or in the role when
+	// the default is used, is preserved in the signed certificate.
+	parsedPrincipals := strutil.RemoveDuplicatesStable(strutil.ParseStringSlice(validPrincipals, ","), false)
+	allowedPrincipals := strutil.RemoveDuplicatesStable(strutil.ParseStringSlice(principalsAllowedByRole, ","), false)
 	switch {
 	case len(parsedPrincipals) == 0:
 		// There is nothing to process
@@ -237,12 +498,39 @@ func validateValidPrincipalForHosts(role *sshRole) func([]string, string) bool {
 			if role.AllowSubdomains && strings.HasSuffix(validPrincipal, "."+allowedPrincipal) {
 				return true
 			}
+			if role.AllowGlobDomains && strings.Contains(allowedPrincipal, "*") && glob.Glob(allowedPrincipal, validPrincipal) {
+				return true
+			}
 		}
 
 		return false
 	}
 }
 
+// hostPrincipalsDNSTimeout bounds the time spent resolving the principals of
+// a single host certificate request.
+const hostPrincipalsDNSTimeout = 10 * time.Second
+
+// unresolvedHostPrincipals returns the principals that could not be resolved
+// to at least one address, including those whose lookup timed out.
+func (b *backend) unresolvedHostPrincipals(ctx context.Context, principals []string) []string {
+	ctx, cancel := context.WithTimeout(ctx, hostPrincipalsDNSTimeout)
+	defer cancel()
+
+	var unresolved []string
+	for _, principal := range principals {
+		addrs, err := b.lookupHost(ctx, principal)
+		if err != nil || len(addrs) == 0 {
+			if b.Logger().IsDebug() {
+				b.Logger().Debug("ssh: host principal did not resolve", "principal", principal, "error", err)
+			}
+			unresolved = append(unresolved, principal)
+		}
+	}
+
+	return unresolved
+}
+
 func (b *backend) calculateCertificateType(data *framework.FieldData, role *sshRole) (uint32, error) {
 	requestedCertificateType := data.Get("cert_type").(string)
 
@@ -293,36 +581,103 @@ func (b *backend) calculateKeyId(data *framework.FieldData, req *logical.Request
 	return keyID, nil
 }
 
-func (b *backend) calculateCriticalOptions(data *framework.FieldData, role *sshRole) (map[string]string, error) {
-	unparsedCriticalOptions := data.Get("critical_options").(map[string]interface{})
-	if len(unparsedCriticalOptions) == 0 {
-		return role.DefaultCriticalOptions, nil
+// calculateCriticalOptions returns the critical options of the certificate:
+// the requested ones if any, else the defaults of the role. Defaults that are
+// not on the allowed list of the role cannot be requested and are always set,
+// so that clients cannot drop e.g. a force-command by requesting other
+// options.
+func (b *backend) calculateCriticalOptions(data *framework.FieldData, req *logical.Request, role *sshRole) (map[string]string, error) {
+	defaultCriticalOptions := role.DefaultCriticalOptions
+	if role.DefaultCriticalOptionsTemplate && len(role.DefaultCriticalOptions) != 0 {
+		entity, err := b.requestEntity(req)
+		if err != nil {
+			return nil, err
+		}
+
+		defaultCriticalOptions = make(map[string]string, len(role.DefaultCriticalOptions))
+		for option, value := range role.DefaultCriticalOptions {
+			rendered, err := renderIdentityTemplate(value, entity)
+			if err != nil {
+				return nil, errutil.UserError{Err: fmt.Sprintf("failed to render default critical option %q: %v", option, err)}
+			}
+			defaultCriticalOptions[option] = rendered
+		}
 	}
 
-	criticalOptions := convertMapToStringValue(unparsedCriticalOptions)
+	criticalOptions := defaultCriticalOptions
+	unparsedCriticalOptions := data.Get("critical_options").(map[string]interface{})
+	if len(unparsedCriticalOptions) != 0 {
+		criticalOptions = convertMapToStringValue(unparsedCriticalOptions)
+
+		if role.AllowedCriticalOptions != "" {
+			notAllowedOptions := []string{}
+			allowedCriticalOptions := strings.Split(role.AllowedCriticalOptions, ",")
 
-	if role.AllowedCriticalOptions != "" {
-		notAllowedOptions := []string{}
-		allowedCriticalOptions := strings.Split(role.AllowedCriticalOptions, ",")
+			for option := range criticalOptions {
+				if !strutil.StrListContains(allowedCriticalOptions, option) {
+					notAllowedOptions = append(notAllowedOptions, option)
+				}
+			}
 
-		for option := range criticalOptions {
-			if !strutil.StrListContains(allowedCriticalOptions, option) {
-				notAllowedOptions = append(notAllowedOptions, option)
+			if len(notAllowedOptions) != 0 {
+				sort.Strings(notAllowedOptions)
+				return nil, errutil.UserError{Err: fmt.Sprintf("Critical options not on allowed list: %v", notAllowedOptions)}
+			}
+
+			for option, value := range defaultCriticalOptions {
+				if !strutil.StrListContains(allowedCriticalOptions, option) {
+					criticalOptions[option] = value
+				}
 			}
 		}
+	}
 
-		if len(notAllowedOptions) != 0 {
-			return nil, fmt.Errorf("Critical options not on allowed list: %v", notAllowedOptions)
+	if sourceAddress, ok := criticalOptions[criticalOptionSourceAddress]; ok {
+		if err := validateSourceAddress(sourceAddress); err != nil {
+			return nil, errutil.UserError{Err: fmt.Sprintf("invalid critical options: %v", err)}
 		}
 	}
 
 	return criticalOptions, nil
 }
 
-func (b *backend) calculateExtensions(data *framework.FieldData, role *sshRole) (map[string]string, error) {
+// validateSourceAddress checks the value of a source-address critical option:
+// a comma-separated list of addresses and CIDR blocks. sshd rejects
+// certificates with any other value.
+func validateSourceAddress(value string) error {
+	for _, address := range strings.Split(value, ",") {
+		address = strings.TrimSpace(address)
+		if net.ParseIP(address) != nil {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(address); err != nil {
+			return fmt.Errorf("%s %q is not an IP address or CIDR block", criticalOptionSourceAddress, address)
+		}
+	}
+	return nil
+}
+
+func (b *backend) calculateExtensions(data *framework.FieldData, req *logical.Request, role *sshRole) (map[string]string, error) {
 	unparsedExtensions := data.Get("extensions").(map[string]interface{})
 	if len(unparsedExtensions) == 0 {
-		return role.DefaultExtensions, nil
+		if !role.DefaultExtensionsTemplate || len(role.DefaultExtensions) == 0 {
+			return role.DefaultExtensions, nil
+		}
+
+		entity, err := b.requestEntity(req)
+		if err != nil {
+			return nil, err
+		}
+
+		extensions := make(map[string]string, len(role.DefaultExtensions))
+		for extension, value := range role.DefaultExtensions {
+			rendered, err := renderIdentityTemplate(value, entity)
+			if err != nil {
+				return nil, errutil.UserError{Err: fmt.Sprintf("failed to render default extension %q: %v", extension, err)}
+			}
+			extensions[extension] = rendered
+		}
+		return extensions, nil
 	}
 
 	extensions := convertMapToStringValue(unparsedExtensions)
@@ -338,24 +693,137 @@ func (b *backend) calculateExtensions(data *framework.FieldData, role *sshRole)
 		}
 
 		if len(notAllowed) != 0 {
-			return nil, fmt.Errorf("extensions %v are not on allowed list", notAllowed)
+			sort.Strings(notAllowed)
+			return nil, errutil.UserError{Err: fmt.Sprintf("extensions %v are not on allowed list", notAllowed)}
 		}
 	}
 
+	if denied := deniedExtensions(role, extensions); len(denied) != 0 {
+		return nil, errutil.UserError{Err: fmt.Sprintf("extensions %v are denied by the role", denied)}
+	}
+
 	return extensions, nil
 }
 
-func (b *backend) calculateTTL(data *framework.FieldData, role *sshRole) (time.Duration, error) {
+// deniedExtensions returns the sorted extensions that are on the denied list
+// of the role. Role writes reject denied default extensions, so only the
+// requested ones need checking when signing.
+func deniedExtensions(role *sshRole, extensions map[string]string) []string {
+	if role.DeniedExtensions == "" {
+		return nil
+	}
+
+	denied := []string{}
+	deniedList := strutil.ParseStringSlice(role.DeniedExtensions, ",")
+	for extension := range extensions {
+		if strutil.StrListContains(deniedList, extension) {
+			denied = append(denied, extension)
+		}
+	}
+	sort.Strings(denied)
+	return denied
+}
+
+// validateUserPublicKey checks a public key submitted for signing against
+// the key types and minimum sizes allowed by the role.
+func validateUserPublicKey(role *sshRole, key ssh.PublicKey) error {
+	keyType, keyBits, err := userKeyTypeAndBits(key)
+	if err != nil {
+		return err
+	}
+
+	if role.AllowedUserKeyTypes != "" && !strutil.StrListContains(strutil.ParseStringSlice(role.AllowedUserKeyTypes, ","), keyType) {
+		return fmt.Errorf("public_key of type %q is not allowed by role", keyType)
+	}
+
+	if minBits, ok := role.UserKeyMinBits[keyType]; ok && keyBits < minBits {
+		return fmt.Errorf("public_key of type %q must be at least %d bits, got %d", keyType, minBits, keyBits)
+	}
+
+	return nil
+}
+
+// userKeyTypeAndBits returns the type of the key, as used by the role's
+// allowed_user_key_types, and its size in bits.
+func userKeyTypeAndBits(key ssh.PublicKey) (string, int, error) {
+	// Both security key types are on 256 bit curves
+	switch key.Type() {
+	case keyAlgoSKECDSA256:
+		return userKeyTypeSKECDSA, 256, nil
+	case keyAlgoSKED25519:
+		return userKeyTypeSKEd25519, 256, nil
+	}
+
+	cryptoKey, ok := key.(ssh.CryptoPublicKey)
+	if !ok {
+		return "", 0, fmt.Errorf("unsupported public_key type %q", key.Type())
+	}
+
+	switch k := cryptoKey.CryptoPublicKey().(type) {
+	case *rsa.PublicKey:
+		return userKeyTypeRSA, k.N.BitLen(), nil
+	case *dsa.PublicKey:
+		return userKeyTypeDSA, k.P.BitLen(), nil
+	case *ecdsa.PublicKey:
+		return userKeyTypeECDSA, k.Curve.Params().BitSize, nil
+	case ed25519.PublicKey:
+		return userKeyTypeEd25519, 256, nil
+	}
+
+	return "", 0, fmt.Errorf("unsupported public_key type %q", key.Type())
+}
+
+func (b *backend) calculateComment(data *framework.FieldData, role *sshRole) (string, error) {
+	comment := data.Get("cert_comment").(string)
+	if comment == "" {
+		return "", nil
+	}
+
+	if role.AllowedCommentRegex == "" {
+		return "", fmt.Errorf("setting cert_comment is not allowed by role")
+	}
+
+	// The comment is the last field of the authorized key line; it must not
+	// be able to start a new line.
+	if strings.ContainsAny(comment, "\r\n") {
+		return "", fmt.Errorf("cert_comment must not contain line breaks")
+	}
+
+	re, err := compileCommentRegex(role.AllowedCommentRegex)
+	if err != nil {
+		return "", fmt.Errorf("role has an invalid allowed_comment_regex: %v", err)
+	}
+	if !re.MatchString(comment) {
+		return "", fmt.Errorf("cert_comment %q does not match the role's allowed_comment_regex", comment)
+	}
+
+	return comment, nil
+}
+
+// compileCommentRegex compiles the given expression so that it must match
+// the entire comment rather than any substring of it.
+func compileCommentRegex(expr string) (*regexp.Regexp, error) {
+	return regexp.Compile("^(?:" + expr + ")$")
+}
+
+// calculateTTL returns the TTL of the certificate: the requested TTL, or
+// the role's, the mount's or the system default TTL, in that order. The TTL
+// is capped to the role's max TTL, which in turn is capped to the mount's.
+// A warning is returned when a requested TTL is capped.
+func (b *backend) calculateTTL(data *framework.FieldData, role *sshRole) (time.Duration, []string, error) {
 	var ttl, maxTTL time.Duration
 	var err error
 
 	ttlRaw, specifiedTTL := data.GetOk("ttl")
 	if specifiedTTL {
 		ttl = time.Duration(ttlRaw.(int)) * time.Second
+		if ttl < 0 {
+			return 0, nil, fmt.Errorf("ttl must not be negative")
+		}
 	} else {
 		ttl, err = parseutil.ParseDurationSecond(role.TTL)
 		if err != nil {
-			return 0, err
+			return 0, nil, err
 		}
 	}
 	if ttl == 0 {
@@ -364,23 +832,23 @@ func (b *backend) calculateTTL(data *framework.FieldData, role *sshRole) (time.D
 
 	maxTTL, err = parseutil.ParseDurationSecond(role.MaxTTL)
 	if err != nil {
-		return 0, err
+		return 0, nil, err
 	}
-	if maxTTL == 0 {
-		maxTTL = b.System().MaxLeaseTTL()
+	if systemMaxTTL := b.System().MaxLeaseTTL(); maxTTL == 0 || maxTTL > systemMaxTTL {
+		maxTTL = systemMaxTTL
 	}
 
+	var warnings []string
 	if ttl > maxTTL {
-		// Don't error if they were using system defaults, only error if
-		// they specifically chose a bad TTL
-		if !specifiedTTL {
-			ttl = maxTTL
-		} else {
-			return 0, fmt.Errorf("ttl is larger than maximum allowed (%d)", maxTTL/time.Second)
+		// Only warn if they specifically chose a TTL, not when the defaults
+		// exceed the maximum
+		if specifiedTTL {
+			warnings = append(warnings, fmt.Sprintf("ttl of %d is greater than the maximum allowed of %d; capping", ttl/time.Second, maxTTL/time.Second))
 		}
+		ttl = maxTTL
 	}
 
-	return ttl, nil
+	return ttl, warnings, nil
 }
 
 func (b *creationBundle) sign() (retCert *ssh.Certificate, retErr error) {
@@ -406,7 +874,7 @@ func (b *creationBundle) sign() (retCert *ssh.Certificate, retErr error) {
 		Key:             b.PublicKey,
 		KeyId:           b.KeyId,
 		ValidPrincipals: b.ValidPrincipals,
-		ValidAfter:      uint64(now.Add(-30 * time.Second).In(time.UTC).Unix()),
+		ValidAfter:      uint64(now.Add(-b.NotBefore).In(time.UTC).Unix()),
 		ValidBefore:     uint64(now.Add(b.TTL).In(time.UTC).Unix()),
 		CertType:        b.CertificateType,
 		Permissions: ssh.Permissions{
@@ -415,7 +883,11 @@ func (b *creationBundle) sign() (retCert *ssh.Certificate, retErr error) {
 		},
 	}
 
-	err = certificate.SignCert(rand.Reader, b.Signer)
+	if isSKKey(b.PublicKey) {
+		err = signSKCertificate(certificate, rand.Reader, b.Signer)
+	} else {
+		err = certificate.SignCert(rand.Reader, b.Signer)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate signed SSH key")
 	}
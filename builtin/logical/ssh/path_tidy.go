@@ -0,0 +1,379 @@
+package ssh
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	tidyStateInactive = "Inactive"
+	tidyStateRunning  = "Running"
+	tidyStateFinished = "Finished"
+	tidyStateError    = "Error"
+)
+
+// tidyStatus reports the progress of the last tidy operation run on this
+// node. It is kept in memory only.
+type tidyStatus struct {
+	State        string
+	Error        string
+	TimeStarted  time.Time
+	TimeFinished time.Time
+
+	SafetyBuffer     time.Duration
+	TidyOTPs         bool
+	TidyDynamicKeys  bool
+	TidyCertStore    bool
+	TidyRevokedCerts bool
+
+	CurrentStep    string
+	EntriesChecked int
+
+	OTPsDeleted         int
+	DynamicKeysDeleted  int
+	DynamicKeysFailed   int
+	CertsDeleted        int
+	RevokedCertsDeleted int
+}
+
+func pathTidy(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "tidy",
+		Fields: map[string]*framework.FieldSchema{
+			"tidy_otps": &framework.FieldSchema{
+				Type:        framework.TypeBool,
+				Description: `Set to true to remove OTPs whose lease has ended without them being used.`,
+			},
+			"tidy_dynamic_keys": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `Set to true to uninstall from their hosts the dynamic keys whose lease has
+ended without being revoked.`,
+			},
+			"tidy_cert_store": &framework.FieldSchema{
+				Type:        framework.TypeBool,
+				Description: `Set to true to remove the records of expired certificates.`,
+			},
+			"tidy_revoked_certs": &framework.FieldSchema{
+				Type:        framework.TypeBool,
+				Description: `Set to true to remove the revocations of expired certificates.`,
+			},
+			"safety_buffer": &framework.FieldSchema{
+				Type: framework.TypeDurationSecond,
+				Description: `The amount of extra time that must have passed beyond the expiration of an
+entry before it is removed. Defaults to 72 hours.`,
+				Default: 259200, //72h, but TypeDurationSecond currently requires defaults to be int
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathTidyWrite,
+		},
+
+		HelpSynopsis:    pathTidyHelpSyn,
+		HelpDescription: pathTidyHelpDesc,
+	}
+}
+
+func pathTidyStatus(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "tidy-status",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathTidyStatusRead,
+		},
+
+		HelpSynopsis:    pathTidyStatusHelpSyn,
+		HelpDescription: pathTidyStatusHelpDesc,
+	}
+}
+
+func (b *backend) pathTidyWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	safetyBuffer := d.Get("safety_buffer").(int)
+	if safetyBuffer < 0 {
+		return logical.ErrorResponse("safety_buffer must not be negative"), nil
+	}
+
+	status := &tidyStatus{
+		State:            tidyStateRunning,
+		TimeStarted:      time.Now(),
+		SafetyBuffer:     time.Duration(safetyBuffer) * time.Second,
+		TidyOTPs:         d.Get("tidy_otps").(bool),
+		TidyDynamicKeys:  d.Get("tidy_dynamic_keys").(bool),
+		TidyCertStore:    d.Get("tidy_cert_store").(bool),
+		TidyRevokedCerts: d.Get("tidy_revoked_certs").(bool),
+	}
+	if !status.TidyOTPs && !status.TidyDynamicKeys && !status.TidyCertStore && !status.TidyRevokedCerts {
+		return logical.ErrorResponse("at least one of tidy_otps, tidy_dynamic_keys, tidy_cert_store and tidy_revoked_certs must be set"), nil
+	}
+
+	b.tidyLock.Lock()
+	if b.tidyStatus != nil && b.tidyStatus.State == tidyStateRunning {
+		b.tidyLock.Unlock()
+		return logical.ErrorResponse("a tidy operation is already running"), nil
+	}
+	b.tidyStatus = status
+	b.tidyLock.Unlock()
+
+	// The operation outlives the request, so it does not use its context
+	s := req.Storage
+	go func() {
+		err := b.tidy(context.Background(), s, status)
+
+		b.tidyLock.Lock()
+		defer b.tidyLock.Unlock()
+		status.CurrentStep = ""
+		status.TimeFinished = time.Now()
+		if err != nil {
+			status.State = tidyStateError
+			status.Error = err.Error()
+			b.Logger().Error("ssh: tidy failed", "error", err)
+			return
+		}
+		status.State = tidyStateFinished
+		if b.Logger().IsInfo() {
+			b.Logger().Info("ssh: tidy finished", "otps_deleted", status.OTPsDeleted, "dynamic_keys_deleted", status.DynamicKeysDeleted, "dynamic_keys_failed", status.DynamicKeysFailed, "certs_deleted", status.CertsDeleted, "revoked_certs_deleted", status.RevokedCertsDeleted)
+		}
+	}()
+
+	resp := &logical.Response{}
+	resp.AddWarning("Tidy operation successfully started. Its progress is reported by the 'tidy-status' endpoint and its outcome is written to the server logs.")
+	return resp, nil
+}
+
+func (b *backend) pathTidyStatusRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	b.tidyLock.RLock()
+	defer b.tidyLock.RUnlock()
+
+	status := b.tidyStatus
+	if status == nil {
+		status = &tidyStatus{State: tidyStateInactive}
+	}
+
+	var timeStarted, timeFinished interface{}
+	if !status.TimeStarted.IsZero() {
+		timeStarted = status.TimeStarted.Unix()
+	}
+	if !status.TimeFinished.IsZero() {
+		timeFinished = status.TimeFinished.Unix()
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"state":                       status.State,
+			"error":                       status.Error,
+			"time_started":                timeStarted,
+			"time_finished":               timeFinished,
+			"safety_buffer":               int64(status.SafetyBuffer.Seconds()),
+			"tidy_otps":                   status.TidyOTPs,
+			"tidy_dynamic_keys":           status.TidyDynamicKeys,
+			"tidy_cert_store":             status.TidyCertStore,
+			"tidy_revoked_certs":          status.TidyRevokedCerts,
+			"current_step":                status.CurrentStep,
+			"entries_checked":             status.EntriesChecked,
+			"otps_deleted_count":          status.OTPsDeleted,
+			"dynamic_keys_deleted_count":  status.DynamicKeysDeleted,
+			"dynamic_keys_failed_count":   status.DynamicKeysFailed,
+			"cert_store_deleted_count":    status.CertsDeleted,
+			"revoked_certs_deleted_count": status.RevokedCertsDeleted,
+		},
+	}, nil
+}
+
+// updateTidyStatus applies a change to the status of the running tidy
+// operation.
+func (b *backend) updateTidyStatus(update func()) {
+	b.tidyLock.Lock()
+	defer b.tidyLock.Unlock()
+	update()
+}
+
+func (b *backend) tidy(ctx context.Context, s logical.Storage, status *tidyStatus) error {
+	steps := []struct {
+		name    string
+		enabled bool
+		prefix  string
+		tidy    func(ctx context.Context, s logical.Storage, id string, now time.Time, buffer time.Duration) error
+	}{
+		{"otps", status.TidyOTPs, "otp/", b.tidyOTP},
+		{"dynamic_keys", status.TidyDynamicKeys, dynamicKeysStoragePrefix, b.tidyDynamicKey},
+		{"cert_store", status.TidyCertStore, issuedCertsStoragePrefix, b.tidyIssuedCert},
+		{"revoked_certs", status.TidyRevokedCerts, revokedCertsStoragePrefix, b.tidyRevokedCert},
+	}
+
+	for _, step := range steps {
+		if !step.enabled {
+			continue
+		}
+		b.updateTidyStatus(func() {
+			status.CurrentStep = step.name
+			status.EntriesChecked = 0
+		})
+
+		ids, err := s.List(ctx, step.prefix)
+		if err != nil {
+			return fmt.Errorf("error listing %q: %v", step.prefix, err)
+		}
+		for _, id := range ids {
+			if err := step.tidy(ctx, s, id, time.Now(), status.SafetyBuffer); err != nil {
+				return err
+			}
+			b.updateTidyStatus(func() {
+				status.EntriesChecked++
+			})
+		}
+	}
+
+	return nil
+}
+
+// tidyOTP removes an OTP older than the longest lease it could have had. OTPs
+// created before their creation time was recorded are given the current time,
+// so that they are removed by a later run.
+func (b *backend) tidyOTP(ctx context.Context, s logical.Storage, id string, now time.Time, buffer time.Duration) error {
+	otp, err := b.getOTP(ctx, s, id)
+	if err != nil {
+		return fmt.Errorf("error reading OTP: %v", err)
+	}
+	if otp == nil {
+		return nil
+	}
+
+	if otp.CreationTime.IsZero() {
+		otp.CreationTime = now
+		entry, err := logical.StorageEntryJSON("otp/"+id, otp)
+		if err != nil {
+			return err
+		}
+		return s.Put(ctx, entry)
+	}
+
+	if now.Before(otp.CreationTime.Add(b.System().MaxLeaseTTL() + buffer)) {
+		return nil
+	}
+	if err := s.Delete(ctx, "otp/"+id); err != nil {
+		return fmt.Errorf("error deleting OTP: %v", err)
+	}
+	b.updateTidyStatus(func() {
+		b.tidyStatus.OTPsDeleted++
+	})
+	return nil
+}
+
+// tidyDynamicKey uninstalls a dynamic key older than the longest lease it
+// could have had. The record is kept if the key cannot be uninstalled, so that
+// uninstalling it is retried by the next run.
+func (b *backend) tidyDynamicKey(ctx context.Context, s logical.Storage, id string, now time.Time, buffer time.Duration) error {
+	key, err := getDynamicKey(ctx, s, id)
+	if err != nil {
+		return fmt.Errorf("error reading dynamic key %q: %v", id, err)
+	}
+	if key == nil || now.Before(key.CreationTime.Add(b.System().MaxLeaseTTL()+buffer)) {
+		return nil
+	}
+
+	if err := b.uninstallDynamicKey(ctx, s, key); err != nil {
+		if b.Logger().IsWarn() {
+			b.Logger().Warn("ssh: tidy failed to uninstall dynamic key", "username", key.Username, "ip", key.IP, "error", err)
+		}
+		b.updateTidyStatus(func() {
+			b.tidyStatus.DynamicKeysFailed++
+		})
+		return nil
+	}
+	if err := s.Delete(ctx, dynamicKeysStoragePrefix+id); err != nil {
+		return fmt.Errorf("error deleting dynamic key %q: %v", id, err)
+	}
+	b.updateTidyStatus(func() {
+		b.tidyStatus.DynamicKeysDeleted++
+	})
+	return nil
+}
+
+func (b *backend) tidyIssuedCert(ctx context.Context, s logical.Storage, serial string, now time.Time, buffer time.Duration) error {
+	cert, err := getIssuedCertificate(ctx, s, serial)
+	if err != nil {
+		return fmt.Errorf("error reading certificate %q: %v", serial, err)
+	}
+	if cert == nil || !certExpiredBefore(cert.ValidBefore, now.Add(-buffer)) {
+		return nil
+	}
+
+	if err := s.Delete(ctx, issuedCertsStoragePrefix+serial); err != nil {
+		return fmt.Errorf("error deleting certificate %q: %v", serial, err)
+	}
+	b.updateTidyStatus(func() {
+		b.tidyStatus.CertsDeleted++
+	})
+	return nil
+}
+
+// tidyRevokedCert removes the revocation of an expired certificate. Such
+// revocations are already left out of the KRL.
+func (b *backend) tidyRevokedCert(ctx context.Context, s logical.Storage, serial string, now time.Time, buffer time.Duration) error {
+	revoked, err := getRevokedCertificate(ctx, s, serial)
+	if err != nil {
+		return fmt.Errorf("error reading revoked certificate %q: %v", serial, err)
+	}
+	if revoked == nil || !certExpiredBefore(revoked.ValidBefore, now.Add(-buffer)) {
+		return nil
+	}
+
+	if err := s.Delete(ctx, revokedCertsStoragePrefix+serial); err != nil {
+		return fmt.Errorf("error deleting revoked certificate %q: %v", serial, err)
+	}
+	b.updateTidyStatus(func() {
+		b.tidyStatus.RevokedCertsDeleted++
+	})
+	return nil
+}
+
+// certExpiredBefore reports whether a certificate valid until validBefore had
+// expired at the given time.
+func certExpiredBefore(validBefore int64, t time.Time) bool {
+	return uint64(validBefore) != ssh.CertTimeInfinity && validBefore < t.Unix()
+}
+
+const pathTidyHelpSyn = `
+Tidy up the backend by removing expired OTPs, dynamic keys and certificates.
+`
+
+const pathTidyHelpDesc = `
+This endpoint removes the entries left behind by expired credentials, so that
+the storage of long-lived mounts does not grow without bounds. The operation
+runs in the background; its progress is reported by the 'tidy-status'
+endpoint.
+
+For safety, this function does nothing unless one of the following is set:
+
+'tidy_otps' removes the OTPs that were neither used nor revoked, once older
+than the max lease TTL of the mount.
+
+'tidy_dynamic_keys' uninstalls from their hosts the dynamic keys whose lease
+was never revoked, once older than the max lease TTL of the mount. Keys that
+cannot be uninstalled are kept and retried by the next run.
+
+'tidy_cert_store' removes the records of expired certificates, see 'certs/'.
+
+'tidy_revoked_certs' removes the revocations of expired certificates, which
+are no longer listed in the KRL.
+
+An entry is only removed once 'safety_buffer' has passed since its expiration,
+to account for clock skew between hosts. It can be an integer number of
+seconds or a string duration like "72h".
+`
+
+const pathTidyStatusHelpSyn = `
+Report the progress of the last tidy operation.
+`
+
+const pathTidyStatusHelpDesc = `
+This path returns the state of the last tidy operation started on this node,
+one of 'Inactive', 'Running', 'Finished' or 'Error', the options it was
+started with, the step it is running and the number of entries checked in that
+step, and the number of entries removed so far by each step.
+`
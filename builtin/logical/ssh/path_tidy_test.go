@@ -0,0 +1,149 @@
+package ssh
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestSSH_Tidy(t *testing.T) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	config.System = &logical.StaticSystemView{
+		DefaultLeaseTTLVal: time.Hour,
+		MaxLeaseTTLVal:     time.Hour,
+	}
+
+	b, err := Factory(context.Background(), config)
+	if err != nil {
+		t.Fatalf("Cannot create backend: %s", err)
+	}
+	s := config.StorageView
+	now := time.Now()
+	infinity := uint64(ssh.CertTimeInfinity)
+
+	put := func(path string, value interface{}) {
+		entry, err := logical.StorageEntryJSON(path, value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Put(context.Background(), entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Entries past the max lease TTL of the mount and the safety buffer of an
+	// hour are removed, the others are kept
+	put("otp/stale", &sshOTP{Username: "ubuntu", CreationTime: now.Add(-3 * time.Hour)})
+	put("otp/recent", &sshOTP{Username: "ubuntu", CreationTime: now.Add(-90 * time.Minute)})
+	put("otp/legacy", &sshOTP{Username: "ubuntu"})
+	put(issuedCertsStoragePrefix+"1", &issuedCertificate{SerialNumber: "1", ValidBefore: now.Add(-2 * time.Hour).Unix()})
+	put(issuedCertsStoragePrefix+"2", &issuedCertificate{SerialNumber: "2", ValidBefore: now.Add(-30 * time.Minute).Unix()})
+	put(issuedCertsStoragePrefix+"3", &issuedCertificate{SerialNumber: "3", ValidBefore: int64(infinity)})
+	put(revokedCertsStoragePrefix+"1", &revokedCertificate{SerialNumber: "1", ValidBefore: now.Add(-2 * time.Hour).Unix()})
+	put(revokedCertsStoragePrefix+"2", &revokedCertificate{SerialNumber: "2", ValidBefore: now.Add(-30 * time.Minute).Unix()})
+
+	// The host key of this dynamic key does not exist, so it cannot be
+	// uninstalled and is kept
+	put(dynamicKeyStoragePath("ssh-rsa AAAA"), &dynamicKeyEntry{
+		Username:         "ubuntu",
+		IP:               "127.0.0.1",
+		HostKeyName:      "missing",
+		DynamicPublicKey: "ssh-rsa AAAA",
+		CreationTime:     now.Add(-3 * time.Hour),
+	})
+
+	doReq := func(operation logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: operation,
+			Path:      path,
+			Storage:   s,
+			Data:      data,
+		})
+		if err != nil {
+			t.Fatalf("bad: path: %s, err: %v", path, err)
+		}
+		return resp
+	}
+
+	resp := doReq(logical.ReadOperation, "tidy-status", nil)
+	if resp.Data["state"] != tidyStateInactive {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	resp = doReq(logical.UpdateOperation, "tidy", nil)
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error without any tidy option, got %#v", resp)
+	}
+
+	resp = doReq(logical.UpdateOperation, "tidy", map[string]interface{}{
+		"tidy_otps":          true,
+		"tidy_dynamic_keys":  true,
+		"tidy_cert_store":    true,
+		"tidy_revoked_certs": true,
+		"safety_buffer":      "1h",
+	})
+	if resp == nil || resp.IsError() || len(resp.Warnings) != 1 {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		resp = doReq(logical.ReadOperation, "tidy-status", nil)
+		if resp.Data["state"] != tidyStateRunning {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("tidy did not finish in time")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	expected := map[string]interface{}{
+		"state":                       tidyStateFinished,
+		"error":                       "",
+		"safety_buffer":               int64(3600),
+		"otps_deleted_count":          1,
+		"dynamic_keys_deleted_count":  0,
+		"dynamic_keys_failed_count":   1,
+		"cert_store_deleted_count":    1,
+		"revoked_certs_deleted_count": 1,
+	}
+	for key, value := range expected {
+		if resp.Data[key] != value {
+			t.Fatalf("bad: %s: expected %v, got %v", key, value, resp.Data[key])
+		}
+	}
+
+	for path, exists := range map[string]bool{
+		"otp/stale":                           false,
+		"otp/recent":                          true,
+		"otp/legacy":                          true,
+		issuedCertsStoragePrefix + "1":        false,
+		issuedCertsStoragePrefix + "2":        true,
+		issuedCertsStoragePrefix + "3":        true,
+		revokedCertsStoragePrefix + "1":       false,
+		revokedCertsStoragePrefix + "2":       true,
+		dynamicKeyStoragePath("ssh-rsa AAAA"): true,
+	} {
+		entry, err := s.Get(context.Background(), path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (entry != nil) != exists {
+			t.Fatalf("bad: %s: expected to exist: %t", path, exists)
+		}
+	}
+
+	// OTPs without a creation time are given one, to be removed later
+	otp, err := b.(*backend).getOTP(context.Background(), s, "legacy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if otp.CreationTime.IsZero() {
+		t.Fatal("expected a creation time for the legacy OTP")
+	}
+}
@@ -0,0 +1,122 @@
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+	"golang.org/x/crypto/ssh"
+)
+
+func pathVerifyCert(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "verify_cert",
+		Fields: map[string]*framework.FieldSchema{
+			"signed_key": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: `SSH certificate to verify, in the authorized_keys format returned when signing.`,
+				Required:    true,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathVerifyCertWrite,
+		},
+
+		HelpSynopsis:    pathVerifyCertHelpSyn,
+		HelpDescription: pathVerifyCertHelpDesc,
+	}
+}
+
+func (b *backend) pathVerifyCertWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	signedKey := strings.TrimSpace(d.Get("signed_key").(string))
+	parsedKey, err := parsePublicSSHKey(signedKey)
+	if err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("failed to parse signed_key: %v", err)), nil
+	}
+	cert, ok := parsedKey.(*ssh.Certificate)
+	if !ok {
+		return logical.ErrorResponse("signed_key is not an SSH certificate"), nil
+	}
+
+	// Reasons the certificate would be rejected by a host trusting the keys
+	// of this mount and its key revocation list
+	var reasons []string
+
+	trustedKeys, err := trustedCAPublicKeys(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	trusted := false
+	for _, key := range trustedKeys {
+		trustedKey, err := parsePublicSSHKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse CA public key: %v", err)
+		}
+		if bytes.Equal(trustedKey.Marshal(), cert.SignatureKey.Marshal()) {
+			trusted = true
+			break
+		}
+	}
+	if !trusted {
+		reasons = append(reasons, "certificate is not signed by a CA key of this backend")
+	}
+
+	if err := verifyCertificateSignature(cert); err != nil {
+		reasons = append(reasons, fmt.Sprintf("certificate signature does not verify: %v", err))
+	}
+
+	now := uint64(time.Now().Unix())
+	if now < cert.ValidAfter {
+		reasons = append(reasons, "certificate is not yet valid")
+	}
+	if cert.ValidBefore != ssh.CertTimeInfinity && now >= cert.ValidBefore {
+		reasons = append(reasons, "certificate has expired")
+	}
+
+	serial := strconv.FormatUint(cert.Serial, 16)
+	var revocationTime int64
+	revoked, err := getRevokedCertificate(ctx, req.Storage, serial)
+	if err != nil {
+		return nil, err
+	}
+	if revoked != nil {
+		// Revocations are scoped to the CA that signed the certificate
+		revokedCAKey, err := parsePublicSSHKey(revoked.CAPublicKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse CA public key of revoked certificate %q: %v", serial, err)
+		}
+		if bytes.Equal(revokedCAKey.Marshal(), cert.SignatureKey.Marshal()) {
+			revocationTime = revoked.RevocationTime.Unix()
+			reasons = append(reasons, "certificate has been revoked")
+		}
+	}
+
+	resp := &logical.Response{
+		Data: certificateFields(cert),
+	}
+	resp.Data["valid"] = len(reasons) == 0
+	resp.Data["reasons"] = reasons
+	resp.Data["signing_key"] = ssh.FingerprintSHA256(cert.SignatureKey)
+	resp.Data["revocation_time"] = revocationTime
+
+	return resp, nil
+}
+
+const pathVerifyCertHelpSyn = `
+Verify an SSH certificate and return its contents.
+`
+
+const pathVerifyCertHelpDesc = `
+This path parses the given SSH certificate and reports whether a host trusting
+the CA public keys and the key revocation list of this backend would accept
+it: the certificate must be signed by one of the CA keys, be within its
+validity window and not be revoked. The reasons the certificate is not valid
+are listed in 'reasons'. The principals, options, extensions and validity
+window of the certificate are returned in any case.
+`
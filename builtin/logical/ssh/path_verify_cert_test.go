@@ -0,0 +1,132 @@
+package ssh
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/vault/logical"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestSSH_VerifyCert(t *testing.T) {
+	newBackend := func() (logical.Backend, logical.Storage) {
+		config := logical.TestBackendConfig()
+		config.StorageView = &logical.InmemStorage{}
+
+		b, err := Factory(context.Background(), config)
+		if err != nil {
+			t.Fatalf("Cannot create backend: %s", err)
+		}
+		return b, config.StorageView
+	}
+
+	doReq := func(b logical.Backend, s logical.Storage, path string, data map[string]interface{}) *logical.Response {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Storage:   s,
+			Data:      data,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: path: %s, err: %v, resp: %v", path, err, resp)
+		}
+		return resp
+	}
+
+	b, s := newBackend()
+	doReq(b, s, "config/ca", map[string]interface{}{
+		"public_key":  publicKey,
+		"private_key": privateKey,
+	})
+	doReq(b, s, "roles/test", map[string]interface{}{
+		"key_type":                "ca",
+		"allow_user_certificates": true,
+		"allowed_users":           "*",
+		"allow_user_key_ids":      true,
+		"algorithm_signer":        "rsa-sha2-256",
+		"default_extensions":      map[string]interface{}{"permit-pty": ""},
+	})
+
+	resp := doReq(b, s, "sign/test", map[string]interface{}{
+		"public_key":       publicKey2,
+		"valid_principals": "ubuntu,admin",
+		"key_id":           "verify-me",
+	})
+	signedKey := resp.Data["signed_key"].(string)
+	serial := resp.Data["serial_number"].(string)
+
+	resp = doReq(b, s, "verify_cert", map[string]interface{}{
+		"signed_key": signedKey,
+	})
+	if resp.Data["valid"] != true {
+		t.Fatalf("bad: expected a valid certificate, got reasons %v", resp.Data["reasons"])
+	}
+	if resp.Data["serial_number"] != serial || resp.Data["key_id"] != "verify-me" || resp.Data["cert_type"] != "user" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if !reflect.DeepEqual(resp.Data["valid_principals"], []string{"ubuntu", "admin"}) {
+		t.Fatalf("bad: valid_principals: %v", resp.Data["valid_principals"])
+	}
+	if !reflect.DeepEqual(resp.Data["extensions"], map[string]string{"permit-pty": ""}) {
+		t.Fatalf("bad: extensions: %v", resp.Data["extensions"])
+	}
+
+	// A certificate altered after signing does not verify
+	parsedKey, err := parsePublicSSHKey(signedKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert := parsedKey.(*ssh.Certificate)
+	cert.ValidPrincipals = []string{"root"}
+	resp = doReq(b, s, "verify_cert", map[string]interface{}{
+		"signed_key": string(ssh.MarshalAuthorizedKey(cert)),
+	})
+	if resp.Data["valid"] != false || len(resp.Data["reasons"].([]string)) != 1 {
+		t.Fatalf("bad: expected an invalid signature, got %#v", resp.Data)
+	}
+
+	// A certificate signed by the CA of another mount is not trusted
+	otherB, otherS := newBackend()
+	doReq(otherB, otherS, "config/ca", map[string]interface{}{
+		"generate_signing_key": true,
+	})
+	doReq(otherB, otherS, "roles/test", map[string]interface{}{
+		"key_type":                "ca",
+		"allow_user_certificates": true,
+		"allowed_users":           "*",
+	})
+	resp = doReq(otherB, otherS, "sign/test", map[string]interface{}{
+		"public_key":       publicKey2,
+		"valid_principals": "ubuntu",
+	})
+	resp = doReq(b, s, "verify_cert", map[string]interface{}{
+		"signed_key": resp.Data["signed_key"],
+	})
+	if resp.Data["valid"] != false || len(resp.Data["reasons"].([]string)) != 1 {
+		t.Fatalf("bad: expected an untrusted certificate, got %#v", resp.Data)
+	}
+
+	// Revoked certificates are reported as such
+	doReq(b, s, "revoke", map[string]interface{}{
+		"serial_number": serial,
+	})
+	resp = doReq(b, s, "verify_cert", map[string]interface{}{
+		"signed_key": signedKey,
+	})
+	if resp.Data["valid"] != false || resp.Data["revocation_time"].(int64) == 0 {
+		t.Fatalf("bad: expected a revoked certificate, got %#v", resp.Data)
+	}
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "verify_cert",
+		Storage:   s,
+		Data: map[string]interface{}{
+			"signed_key": publicKey2,
+		},
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error for a plain public key: err: %v, resp: %v", err, resp)
+	}
+}
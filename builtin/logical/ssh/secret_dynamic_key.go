@@ -2,7 +2,10 @@ package ssh
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"time"
 
 	"github.com/hashicorp/errwrap"
 	"github.com/hashicorp/vault/logical"
@@ -12,6 +15,23 @@ import (
 
 const SecretDynamicKeyType = "secret_dynamic_key_type"
 
+const dynamicKeysStoragePrefix = "dynamic_keys/"
+
+// dynamicKeyEntry records a dynamic key installed in a host, so that tidy can
+// uninstall keys whose lease was never revoked. It holds the internal data of
+// the secret.
+type dynamicKeyEntry struct {
+	AdminUser        string    `json:"admin_user" structs:"admin_user" mapstructure:"admin_user"`
+	Username         string    `json:"username" structs:"username" mapstructure:"username"`
+	IP               string    `json:"ip" structs:"ip" mapstructure:"ip"`
+	HostKeyName      string    `json:"host_key_name" structs:"host_key_name" mapstructure:"host_key_name"`
+	DynamicPublicKey string    `json:"dynamic_public_key" structs:"dynamic_public_key" mapstructure:"dynamic_public_key"`
+	InstallScript    string    `json:"install_script" structs:"install_script" mapstructure:"install_script"`
+	Port             int       `json:"port" structs:"port" mapstructure:"port"`
+	RoleName         string    `json:"role_name" structs:"role_name" mapstructure:"role_name"`
+	CreationTime     time.Time `json:"creation_time" structs:"creation_time" mapstructure:"creation_time"`
+}
+
 func secretDynamicKey(b *backend) *framework.Secret {
 	return &framework.Secret{
 		Type: SecretDynamicKeyType,
@@ -31,42 +51,79 @@ func secretDynamicKey(b *backend) *framework.Secret {
 	}
 }
 
-func (b *backend) secretDynamicKeyRenew(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
-	f := framework.LeaseExtend(0, 0, b.System())
-	return f(ctx, req, d)
+// dynamicKeyStoragePath returns the storage path of the record of a dynamic
+// key, derived from its public key so that it can be found on revocation.
+func dynamicKeyStoragePath(dynamicPublicKey string) string {
+	sum := sha256.Sum256([]byte(dynamicPublicKey))
+	return dynamicKeysStoragePrefix + hex.EncodeToString(sum[:])
 }
 
-func (b *backend) secretDynamicKeyRevoke(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
-	type sec struct {
-		AdminUser        string `mapstructure:"admin_user"`
-		Username         string `mapstructure:"username"`
-		IP               string `mapstructure:"ip"`
-		HostKeyName      string `mapstructure:"host_key_name"`
-		DynamicPublicKey string `mapstructure:"dynamic_public_key"`
-		InstallScript    string `mapstructure:"install_script"`
-		Port             int    `mapstructure:"port"`
+func storeDynamicKey(ctx context.Context, s logical.Storage, key *dynamicKeyEntry) error {
+	entry, err := logical.StorageEntryJSON(dynamicKeyStoragePath(key.DynamicPublicKey), key)
+	if err != nil {
+		return err
 	}
+	return s.Put(ctx, entry)
+}
 
-	intSec := &sec{}
-	err := mapstructure.Decode(req.Secret.InternalData, intSec)
+func getDynamicKey(ctx context.Context, s logical.Storage, id string) (*dynamicKeyEntry, error) {
+	entry, err := s.Get(ctx, dynamicKeysStoragePrefix+id)
 	if err != nil {
-		return nil, errwrap.Wrapf("secret internal data could not be decoded: {{err}}", err)
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
 	}
 
+	var result dynamicKeyEntry
+	if err := entry.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// uninstallDynamicKey removes a dynamic key from the authorized_keys file of
+// its host.
+func (b *backend) uninstallDynamicKey(ctx context.Context, s logical.Storage, key *dynamicKeyEntry) error {
 	// Fetch the host key using the key name
-	hostKey, err := b.getKey(ctx, req.Storage, intSec.HostKeyName)
+	hostKey, err := b.getKey(ctx, s, key.HostKeyName)
 	if err != nil {
-		return nil, fmt.Errorf("key %q not found error: %v", intSec.HostKeyName, err)
+		return fmt.Errorf("key %q not found error: %v", key.HostKeyName, err)
 	}
 	if hostKey == nil {
-		return nil, fmt.Errorf("key %q not found", intSec.HostKeyName)
+		return fmt.Errorf("key %q not found", key.HostKeyName)
 	}
 
 	// Remove the public key from authorized_keys file in target machine
 	// The last param 'false' indicates that the key should be uninstalled.
-	err = b.installPublicKeyInTarget(intSec.AdminUser, intSec.Username, intSec.IP, intSec.Port, hostKey.Key, intSec.DynamicPublicKey, intSec.InstallScript, false)
+	err = b.installPublicKeyInTarget(key.AdminUser, key.Username, key.IP, key.Port, hostKey.Key, key.DynamicPublicKey, key.InstallScript, false)
 	if err != nil {
-		return nil, fmt.Errorf("error removing public key from authorized_keys file in target")
+		return fmt.Errorf("error removing public key from authorized_keys file in target")
+	}
+	return nil
+}
+
+func (b *backend) secretDynamicKeyRenew(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	f := framework.LeaseExtend(0, 0, b.System())
+	return f(ctx, req, d)
+}
+
+func (b *backend) secretDynamicKeyRevoke(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	intSec := &dynamicKeyEntry{}
+	err := mapstructure.Decode(req.Secret.InternalData, intSec)
+	if err != nil {
+		return nil, errwrap.Wrapf("secret internal data could not be decoded: {{err}}", err)
+	}
+
+	if err := b.uninstallDynamicKey(ctx, req.Storage, intSec); err != nil {
+		return nil, err
+	}
+
+	// Keys installed before the keys were tracked have no record, in which
+	// case this is a no-op
+	if err := req.Storage.Delete(ctx, dynamicKeyStoragePath(intSec.DynamicPublicKey)); err != nil {
+		return nil, err
 	}
 	return nil, nil
 }
@@ -0,0 +1,354 @@
+package ssh
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Key and certificate algorithms of FIDO2 security keys, see PROTOCOL.u2f
+// and PROTOCOL.certkeys in the OpenSSH sources. The ssh package does not
+// support them, so their keys and certificates are encoded here.
+const (
+	keyAlgoSKECDSA256  = "sk-ecdsa-sha2-nistp256@openssh.com"
+	keyAlgoSKED25519   = "sk-ssh-ed25519@openssh.com"
+	certAlgoSKECDSA256 = "sk-ecdsa-sha2-nistp256-cert-v01@openssh.com"
+	certAlgoSKED25519  = "sk-ssh-ed25519-cert-v01@openssh.com"
+)
+
+var skCertAlgos = map[string]string{
+	keyAlgoSKECDSA256: certAlgoSKECDSA256,
+	keyAlgoSKED25519:  certAlgoSKED25519,
+}
+
+// skPublicKey is the public key of a FIDO2 security key. Its fields are kept
+// in their wire encoding, which includes the application string the key
+// was registered for, so the key is signed exactly as submitted.
+type skPublicKey struct {
+	algo   string
+	fields []byte
+}
+
+func (k *skPublicKey) Type() string {
+	return k.algo
+}
+
+func (k *skPublicKey) Marshal() []byte {
+	return append(ssh.Marshal(struct{ Algo string }{k.algo}), k.fields...)
+}
+
+// Verify is not supported: the backend signs security keys but never needs
+// to check their signatures.
+func (k *skPublicKey) Verify(data []byte, sig *ssh.Signature) error {
+	return fmt.Errorf("verifying %s signatures is not supported", k.algo)
+}
+
+// isSKKey reports whether the key is a FIDO2 security key.
+func isSKKey(key ssh.PublicKey) bool {
+	_, ok := key.(*skPublicKey)
+	return ok
+}
+
+// parseSKPublicKey parses the wire encoding of a security key public key or
+// of a certificate of one.
+func parseSKPublicKey(in []byte) (ssh.PublicKey, error) {
+	r := &wireReader{buf: in}
+	algo := string(r.readString())
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	switch algo {
+	case keyAlgoSKECDSA256, keyAlgoSKED25519:
+		fields, err := readSKKeyFields(r, algo)
+		if err != nil {
+			return nil, err
+		}
+		if len(r.buf) != 0 {
+			return nil, fmt.Errorf("trailing data after %s public key", algo)
+		}
+		return &skPublicKey{algo: algo, fields: fields}, nil
+
+	case certAlgoSKECDSA256, certAlgoSKED25519:
+		return parseSKCertificate(r, algo)
+	}
+
+	return nil, fmt.Errorf("unknown key algorithm %q", algo)
+}
+
+// readSKKeyFields returns the wire encoding of the fields of a security key
+// following its algorithm name.
+func readSKKeyFields(r *wireReader, algo string) ([]byte, error) {
+	start := r.buf
+	if algo == keyAlgoSKECDSA256 || algo == certAlgoSKECDSA256 {
+		if curve := string(r.readString()); r.err == nil && curve != "nistp256" {
+			return nil, fmt.Errorf("unsupported curve %q for %s", curve, algo)
+		}
+	}
+	// public key and application
+	r.readString()
+	r.readString()
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	return start[:len(start)-len(r.buf)], nil
+}
+
+func parseSKCertificate(r *wireReader, certAlgo string) (*ssh.Certificate, error) {
+	keyAlgo := keyAlgoSKED25519
+	if certAlgo == certAlgoSKECDSA256 {
+		keyAlgo = keyAlgoSKECDSA256
+	}
+
+	cert := &ssh.Certificate{}
+	cert.Nonce = r.readString()
+	fields, err := readSKKeyFields(r, certAlgo)
+	if err != nil {
+		return nil, err
+	}
+	cert.Key = &skPublicKey{algo: keyAlgo, fields: fields}
+
+	cert.Serial = r.readUint64()
+	cert.CertType = r.readUint32()
+	cert.KeyId = string(r.readString())
+
+	principals := &wireReader{buf: r.readString()}
+	for len(principals.buf) != 0 && principals.err == nil {
+		cert.ValidPrincipals = append(cert.ValidPrincipals, string(principals.readString()))
+	}
+
+	cert.ValidAfter = r.readUint64()
+	cert.ValidBefore = r.readUint64()
+	cert.CriticalOptions = readTuples(r.readString(), &r.err)
+	cert.Extensions = readTuples(r.readString(), &r.err)
+	cert.Reserved = r.readString()
+	signatureKey := r.readString()
+	signature := &wireReader{buf: r.readString()}
+	if r.err == nil && principals.err != nil {
+		r.err = principals.err
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("failed to parse %s certificate: %v", certAlgo, r.err)
+	}
+	if len(r.buf) != 0 {
+		return nil, fmt.Errorf("trailing data after %s certificate", certAlgo)
+	}
+
+	cert.SignatureKey, err = ssh.ParsePublicKey(signatureKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse signature key of %s certificate: %v", certAlgo, err)
+	}
+	cert.Signature = &ssh.Signature{
+		Format: string(signature.readString()),
+		Blob:   signature.readString(),
+	}
+	if signature.err != nil {
+		return nil, fmt.Errorf("failed to parse signature of %s certificate: %v", certAlgo, signature.err)
+	}
+
+	return cert, nil
+}
+
+// marshalSKCertificate encodes a certificate of a security key, laid out as
+// the ssh package does for the key types it supports. The signature is
+// omitted when the certificate is not signed yet.
+func marshalSKCertificate(cert *ssh.Certificate) []byte {
+	key := cert.Key.(*skPublicKey)
+
+	var buf bytes.Buffer
+	buf.Write(ssh.Marshal(struct {
+		Algo  string
+		Nonce []byte
+	}{skCertAlgos[key.algo], cert.Nonce}))
+	buf.Write(key.fields)
+
+	var principals []byte
+	for _, principal := range cert.ValidPrincipals {
+		principals = append(principals, ssh.Marshal(struct{ Principal string }{principal})...)
+	}
+
+	var signature []byte
+	if cert.Signature != nil {
+		signature = ssh.Marshal(cert.Signature)
+	}
+
+	buf.Write(ssh.Marshal(struct {
+		Serial          uint64
+		CertType        uint32
+		KeyId           string
+		ValidPrincipals []byte
+		ValidAfter      uint64
+		ValidBefore     uint64
+		CriticalOptions []byte
+		Extensions      []byte
+		Reserved        []byte
+		SignatureKey    []byte
+		Signature       []byte
+	}{
+		cert.Serial,
+		cert.CertType,
+		cert.KeyId,
+		principals,
+		cert.ValidAfter,
+		cert.ValidBefore,
+		marshalTuples(cert.CriticalOptions),
+		marshalTuples(cert.Extensions),
+		cert.Reserved,
+		cert.SignatureKey.Marshal(),
+		signature,
+	}))
+
+	return buf.Bytes()
+}
+
+// signSKCertificate signs a certificate of a security key, as
+// ssh.Certificate.SignCert does for other keys.
+func signSKCertificate(cert *ssh.Certificate, rand io.Reader, authority ssh.Signer) error {
+	cert.Nonce = make([]byte, 32)
+	if _, err := io.ReadFull(rand, cert.Nonce); err != nil {
+		return err
+	}
+	cert.SignatureKey = authority.PublicKey()
+	cert.Signature = nil
+
+	sig, err := authority.Sign(rand, certificateSigningBytes(cert))
+	if err != nil {
+		return err
+	}
+	cert.Signature = sig
+	return nil
+}
+
+// certificateSigningBytes returns the data the signature of the certificate
+// is made over: the certificate without its signature.
+func certificateSigningBytes(cert *ssh.Certificate) []byte {
+	unsigned := *cert
+	unsigned.Signature = nil
+
+	var out []byte
+	if isSKKey(cert.Key) {
+		out = marshalSKCertificate(&unsigned)
+	} else {
+		out = unsigned.Marshal()
+	}
+	// Drop the length of the empty signature
+	return out[:len(out)-4]
+}
+
+// skCertificate wraps a certificate of a security key so that it can be
+// marshaled with the ssh package, e.g. by ssh.MarshalAuthorizedKey.
+type skCertificate struct {
+	*ssh.Certificate
+}
+
+func (c skCertificate) Type() string {
+	return skCertAlgos[c.Key.Type()]
+}
+
+func (c skCertificate) Marshal() []byte {
+	return marshalSKCertificate(c.Certificate)
+}
+
+// marshalAuthorizedCertificate encodes a certificate in the authorized_keys
+// format, including certificates of security keys.
+func marshalAuthorizedCertificate(cert *ssh.Certificate) []byte {
+	if isSKKey(cert.Key) {
+		return ssh.MarshalAuthorizedKey(skCertificate{cert})
+	}
+	return ssh.MarshalAuthorizedKey(cert)
+}
+
+func marshalTuples(tuples map[string]string) []byte {
+	keys := make([]string, 0, len(tuples))
+	for key := range tuples {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var out []byte
+	for _, key := range keys {
+		// Non-empty values are strings embedded in the data string
+		var value []byte
+		if tuples[key] != "" {
+			value = ssh.Marshal(struct{ Value string }{tuples[key]})
+		}
+		out = append(out, ssh.Marshal(struct {
+			Key   string
+			Value []byte
+		}{key, value})...)
+	}
+	return out
+}
+
+func readTuples(in []byte, err *error) map[string]string {
+	tuples := map[string]string{}
+	r := &wireReader{buf: in}
+	for len(r.buf) != 0 && r.err == nil {
+		key := string(r.readString())
+		value := r.readString()
+		if len(value) != 0 {
+			inner := &wireReader{buf: value}
+			value = inner.readString()
+			if inner.err != nil {
+				r.err = inner.err
+			}
+		}
+		tuples[key] = string(value)
+	}
+	if r.err != nil && *err == nil {
+		*err = r.err
+	}
+	return tuples
+}
+
+// wireReader reads the SSH wire encoding. After an error, reads return zero
+// values and the error is kept.
+type wireReader struct {
+	buf []byte
+	err error
+}
+
+func (r *wireReader) readUint32() uint32 {
+	if r.err != nil {
+		return 0
+	}
+	if len(r.buf) < 4 {
+		r.err = io.ErrUnexpectedEOF
+		return 0
+	}
+	v := binary.BigEndian.Uint32(r.buf)
+	r.buf = r.buf[4:]
+	return v
+}
+
+func (r *wireReader) readUint64() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	if len(r.buf) < 8 {
+		r.err = io.ErrUnexpectedEOF
+		return 0
+	}
+	v := binary.BigEndian.Uint64(r.buf)
+	r.buf = r.buf[8:]
+	return v
+}
+
+func (r *wireReader) readString() []byte {
+	length := r.readUint32()
+	if r.err != nil {
+		return nil
+	}
+	if uint32(len(r.buf)) < length {
+		r.err = io.ErrUnexpectedEOF
+		return nil
+	}
+	v := r.buf[:length]
+	r.buf = r.buf[length:]
+	return v
+}
@@ -0,0 +1,148 @@
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/logical"
+	"golang.org/x/crypto/ed25519"
+	"golang.org/x/crypto/ssh"
+)
+
+// testSKPublicKeys returns security key public keys in the authorized_keys
+// format, registered for the given application.
+func testSKPublicKeys(t *testing.T, application string) []string {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edKey, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ecWire := ssh.Marshal(struct {
+		Algo        string
+		Curve       string
+		Point       []byte
+		Application string
+	}{keyAlgoSKECDSA256, "nistp256", elliptic.Marshal(elliptic.P256(), ecKey.X, ecKey.Y), application})
+	edWire := ssh.Marshal(struct {
+		Algo        string
+		Key         []byte
+		Application string
+	}{keyAlgoSKED25519, []byte(edKey), application})
+
+	return []string{
+		keyAlgoSKECDSA256 + " " + base64.StdEncoding.EncodeToString(ecWire) + " user@yubikey",
+		keyAlgoSKED25519 + " " + base64.StdEncoding.EncodeToString(edWire) + " user@yubikey",
+	}
+}
+
+func TestSSH_SignSKKeys(t *testing.T) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+
+	b, err := Factory(context.Background(), config)
+	if err != nil {
+		t.Fatalf("Cannot create backend: %s", err)
+	}
+
+	doReq := func(path string, data map[string]interface{}) *logical.Response {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Storage:   config.StorageView,
+			Data:      data,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: path: %s, err: %v, resp: %v", path, err, resp)
+		}
+		return resp
+	}
+
+	doReq("config/ca", map[string]interface{}{
+		"public_key":  publicKey,
+		"private_key": privateKey,
+	})
+	doReq("roles/test", map[string]interface{}{
+		"key_type":                "ca",
+		"allow_user_certificates": true,
+		"allowed_users":           "*",
+		"algorithm_signer":        "rsa-sha2-256",
+		"allowed_user_key_types":  "sk-ecdsa,sk-ed25519",
+		"default_extensions":      map[string]interface{}{"permit-pty": ""},
+		"default_critical_options": map[string]interface{}{
+			"source-address": "10.0.0.0/8",
+		},
+	})
+
+	for _, userPublicKey := range testSKPublicKeys(t, "ssh:vault") {
+		submittedKey, err := parsePublicSSHKey(userPublicKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resp := doReq("sign/test", map[string]interface{}{
+			"public_key":       userPublicKey,
+			"valid_principals": "ubuntu,admin",
+			"emit_ssh_config":  true,
+		})
+		signedKey := resp.Data["signed_key"].(string)
+		if !strings.HasPrefix(signedKey, skCertAlgos[submittedKey.Type()]+" ") {
+			t.Fatalf("bad: signed key %q", signedKey)
+		}
+
+		parsedKey, err := parsePublicSSHKey(strings.TrimSpace(signedKey))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cert := parsedKey.(*ssh.Certificate)
+
+		// The key is signed as submitted, including its application
+		if !bytes.Equal(cert.Key.Marshal(), submittedKey.Marshal()) {
+			t.Fatalf("bad: certified key differs from the submitted key")
+		}
+		if !reflect.DeepEqual(cert.ValidPrincipals, []string{"ubuntu", "admin"}) {
+			t.Fatalf("bad: principals: %v", cert.ValidPrincipals)
+		}
+		if !reflect.DeepEqual(cert.Extensions, map[string]string{"permit-pty": ""}) {
+			t.Fatalf("bad: extensions: %v", cert.Extensions)
+		}
+		if !reflect.DeepEqual(cert.CriticalOptions, map[string]string{"source-address": "10.0.0.0/8"}) {
+			t.Fatalf("bad: critical options: %v", cert.CriticalOptions)
+		}
+		if err := verifyCertificateSignature(cert); err != nil {
+			t.Fatalf("bad: signature does not verify: %v", err)
+		}
+		if !strings.Contains(resp.Data["ssh_config"].(string), "_sk-cert.pub") {
+			t.Fatalf("bad: ssh_config: %q", resp.Data["ssh_config"])
+		}
+
+		// Re-encoding the parsed certificate yields the signed one
+		if reencoded := string(marshalAuthorizedCertificate(cert)); reencoded != signedKey {
+			t.Fatalf("bad: expected %q, got %q", signedKey, reencoded)
+		}
+	}
+
+	// Other key types are not allowed by the role
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "sign/test",
+		Storage:   config.StorageView,
+		Data: map[string]interface{}{
+			"public_key":       publicKey2,
+			"valid_principals": "ubuntu",
+		},
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error: err: %v, resp: %v", err, resp)
+	}
+}
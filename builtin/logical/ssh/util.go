@@ -3,12 +3,15 @@ package ssh
 import (
 	"bytes"
 	"context"
+	"crypto"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/base64"
+	"encoding/binary"
 	"encoding/pem"
 	"fmt"
+	"io"
 	"net"
 	"strings"
 	"time"
@@ -16,6 +19,7 @@ import (
 	"github.com/hashicorp/vault/logical"
 
 	log "github.com/mgutz/logxi/v1"
+	"golang.org/x/crypto/ed25519"
 	"golang.org/x/crypto/ssh"
 )
 
@@ -203,7 +207,16 @@ func parsePublicSSHKey(key string) (ssh.PublicKey, error) {
 		return nil, err
 	}
 
-	return ssh.ParsePublicKey([]byte(decodedKey))
+	parsedKey, err := ssh.ParsePublicKey([]byte(decodedKey))
+	if err != nil {
+		// The ssh package does not know about security keys
+		if skKey, skErr := parseSKPublicKey(decodedKey); skErr == nil {
+			return skKey, nil
+		}
+		return nil, err
+	}
+
+	return parsedKey, nil
 }
 
 func convertMapToStringValue(initial map[string]interface{}) map[string]string {
@@ -222,3 +235,154 @@ func substQuery(tpl string, data map[string]string) string {
 
 	return tpl
 }
+
+// Signature algorithms of RSA keys, see RFC 8332.
+const (
+	sigAlgoRSA       = "ssh-rsa"
+	sigAlgoRSASHA256 = "rsa-sha2-256"
+	sigAlgoRSASHA512 = "rsa-sha2-512"
+
+	// defaultAlgorithmSigner selects the ssh package's default algorithm
+	// for the CA key, or its FIPS-approved replacement in FIPS mode.
+	defaultAlgorithmSigner = "default"
+)
+
+// rsaSHA2Signer signs with an RSA key using a SHA-2 hash rather than the
+// SHA-1 hash the ssh package's own RSA signer is limited to. The signature
+// format is the name defined for the hash by RFC 8332.
+type rsaSHA2Signer struct {
+	key    *rsa.PrivateKey
+	pub    ssh.PublicKey
+	hash   crypto.Hash
+	format string
+}
+
+func newRSASHA2Signer(privateKey string, hash crypto.Hash) (ssh.Signer, error) {
+	rawKey, err := ssh.ParseRawPrivateKey([]byte(privateKey))
+	if err != nil {
+		return nil, err
+	}
+	key, ok := rawKey.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key is not an RSA key")
+	}
+
+	var format string
+	switch hash {
+	case crypto.SHA256:
+		format = sigAlgoRSASHA256
+	case crypto.SHA512:
+		format = sigAlgoRSASHA512
+	default:
+		return nil, fmt.Errorf("unsupported hash for RSA signatures: %v", hash)
+	}
+
+	pub, err := ssh.NewPublicKey(&key.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rsaSHA2Signer{
+		key:    key,
+		pub:    pub,
+		hash:   hash,
+		format: format,
+	}, nil
+}
+
+func (s *rsaSHA2Signer) PublicKey() ssh.PublicKey {
+	return s.pub
+}
+
+func (s *rsaSHA2Signer) Sign(rand io.Reader, data []byte) (*ssh.Signature, error) {
+	h := s.hash.New()
+	h.Write(data)
+
+	blob, err := rsa.SignPKCS1v15(rand, s.key, s.hash, h.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+
+	return &ssh.Signature{
+		Format: s.format,
+		Blob:   blob,
+	}, nil
+}
+
+// verifyCertificateSignature checks the signature of the certificate with its
+// signature key. RSA signatures using SHA-2 hashes, which the ssh package
+// cannot verify, are verified here.
+func verifyCertificateSignature(cert *ssh.Certificate) error {
+	if cert.Signature == nil || cert.SignatureKey == nil {
+		return fmt.Errorf("certificate is not signed")
+	}
+
+	data := certificateSigningBytes(cert)
+
+	var hash crypto.Hash
+	switch cert.Signature.Format {
+	case sigAlgoRSASHA256:
+		hash = crypto.SHA256
+	case sigAlgoRSASHA512:
+		hash = crypto.SHA512
+	default:
+		return cert.SignatureKey.Verify(data, cert.Signature)
+	}
+
+	cryptoKey, ok := cert.SignatureKey.(ssh.CryptoPublicKey)
+	if !ok {
+		return fmt.Errorf("signature format %q does not match key type %q", cert.Signature.Format, cert.SignatureKey.Type())
+	}
+	rsaKey, ok := cryptoKey.CryptoPublicKey().(*rsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("signature format %q does not match key type %q", cert.Signature.Format, cert.SignatureKey.Type())
+	}
+
+	h := hash.New()
+	h.Write(data)
+	return rsa.VerifyPKCS1v15(rsaKey, hash, h.Sum(nil), cert.Signature.Blob)
+}
+
+// marshalOpenSSHEd25519PrivateKey encodes an unencrypted Ed25519 private
+// key in the OpenSSH private key format, the only PEM format the ssh package
+// parses Ed25519 keys from. See PROTOCOL.key in the OpenSSH sources.
+func marshalOpenSSHEd25519PrivateKey(publicKey ed25519.PublicKey, privateKey ed25519.PrivateKey) (*pem.Block, error) {
+	var check [4]byte
+	if _, err := rand.Read(check[:]); err != nil {
+		return nil, err
+	}
+	checkInt := binary.BigEndian.Uint32(check[:])
+
+	pubKey := ssh.Marshal(struct {
+		KeyType string
+		Pub     []byte
+	}{ssh.KeyAlgoED25519, publicKey})
+
+	privKeyBlock := ssh.Marshal(struct {
+		Check1  uint32
+		Check2  uint32
+		KeyType string
+		Pub     []byte
+		Priv    []byte
+		Comment string
+	}{checkInt, checkInt, ssh.KeyAlgoED25519, publicKey, privateKey, ""})
+
+	// Pad to the cipher block size, which is 8 for the "none" cipher
+	for i := 1; len(privKeyBlock)%8 != 0; i++ {
+		privKeyBlock = append(privKeyBlock, byte(i))
+	}
+
+	key := ssh.Marshal(struct {
+		CipherName   string
+		KdfName      string
+		KdfOpts      string
+		NumKeys      uint32
+		PubKey       []byte
+		PrivKeyBlock []byte
+	}{"none", "none", "", 1, pubKey, privKeyBlock})
+
+	return &pem.Block{
+		Type:  "OPENSSH PRIVATE KEY",
+		Bytes: append([]byte("openssh-key-v1\x00"), key...),
+	}, nil
+}
@@ -2,8 +2,11 @@ package transit
 
 import (
 	"context"
+	"crypto/rsa"
 	"strings"
+	"sync"
 
+	"github.com/hashicorp/vault/helper/consts"
 	"github.com/hashicorp/vault/helper/keysutil"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
@@ -14,6 +17,9 @@ func Factory(ctx context.Context, conf *logical.BackendConfig) (logical.Backend,
 	if err := b.Setup(ctx, conf); err != nil {
 		return nil, err
 	}
+	if err := b.loadCacheConfig(ctx, conf.StorageView); err != nil {
+		return nil, err
+	}
 	return b, nil
 }
 
@@ -24,6 +30,8 @@ func Backend(conf *logical.BackendConfig) *backend {
 			SealWrapStorage: []string{
 				"archive/",
 				"policy/",
+				"token/",
+				"wrapping_key",
 			},
 		},
 
@@ -31,7 +39,9 @@ func Backend(conf *logical.BackendConfig) *backend {
 			// Rotate/Config needs to come before Keys
 			// as the handler is greedy
 			b.pathConfig(),
+			b.pathCacheConfig(),
 			b.pathRotate(),
+			b.pathImport(),
 			b.pathRewrap(),
 			b.pathKeys(),
 			b.pathListKeys(),
@@ -44,26 +54,49 @@ func Backend(conf *logical.BackendConfig) *backend {
 			b.pathHMAC(),
 			b.pathSign(),
 			b.pathVerify(),
+			b.pathCMAC(),
+			b.pathDerive(),
 			b.pathBackup(),
 			b.pathRestore(),
+			b.pathWrappingKey(),
+			b.pathListTransformations(),
+			b.pathTransformations(),
+			b.pathEncode(),
+			b.pathDecode(),
+			b.pathTokenMetadata(),
 		},
 
-		Secrets:     []*framework.Secret{},
-		Invalidate:  b.invalidate,
-		BackendType: logical.TypeLogical,
+		Secrets:      []*framework.Secret{},
+		Invalidate:   b.invalidate,
+		BackendType:  logical.TypeLogical,
+		PeriodicFunc: b.periodicFunc,
 	}
 
 	b.lm = keysutil.NewLockManager(conf.System.CachingDisabled())
+	b.view = conf.StorageView
 
 	return &b
 }
 
 type backend struct {
 	*framework.Backend
-	lm *keysutil.LockManager
+	lm   *keysutil.LockManager
+	view logical.Storage
+
+	// wrappingKey caches the RSA key used to wrap imported keys
+	wrappingKey     *rsa.PrivateKey
+	wrappingKeyLock sync.Mutex
+}
+
+func (b *backend) periodicFunc(ctx context.Context, req *logical.Request) error {
+	// Keys of performance secondaries are rotated on the primary
+	if !b.System().LocalMount() && b.System().ReplicationState().HasState(consts.ReplicationPerformanceSecondary) {
+		return nil
+	}
+	return b.autoRotateKeys(ctx, req)
 }
 
-func (b *backend) invalidate(_ context.Context, key string) {
+func (b *backend) invalidate(ctx context.Context, key string) {
 	if b.Logger().IsTrace() {
 		b.Logger().Trace("transit: invalidating key", "key", key)
 	}
@@ -71,5 +104,13 @@ func (b *backend) invalidate(_ context.Context, key string) {
 	case strings.HasPrefix(key, "policy/"):
 		name := strings.TrimPrefix(key, "policy/")
 		b.lm.InvalidatePolicy(name)
+	case key == cacheConfigPath:
+		if err := b.loadCacheConfig(ctx, b.view); err != nil {
+			b.Logger().Error("transit: failed to load the cache configuration", "error", err)
+		}
+	case key == "wrapping_key":
+		b.wrappingKeyLock.Lock()
+		b.wrappingKey = nil
+		b.wrappingKeyLock.Unlock()
 	}
 }
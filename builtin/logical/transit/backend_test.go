@@ -1,7 +1,11 @@
 package transit
 
 import (
+	"bytes"
 	"context"
+	"crypto/hmac"
+	cryptorand "crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
 	"fmt"
 	"math/rand"
@@ -13,6 +17,7 @@ import (
 	"time"
 
 	uuid "github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/helper/fips"
 	"github.com/hashicorp/vault/helper/keysutil"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
@@ -224,6 +229,100 @@ func testTransit_RSA(t *testing.T, keyType string) {
 	}
 }
 
+func TestTransit_SymmetricKeyTypes(t *testing.T) {
+	testTransit_SymmetricKeyType(t, "chacha20-poly1305", 32)
+	testTransit_SymmetricKeyType(t, "aes256-cbc-hmac-sha512", 64)
+}
+
+func testTransit_SymmetricKeyType(t *testing.T, keyType string, keySize int) {
+	b, storage := createBackendWithStorage(t)
+
+	doReq := func(path string, data map[string]interface{}) *logical.Response {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Path:      path,
+			Operation: logical.UpdateOperation,
+			Storage:   storage,
+			Data:      data,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: type: %s, path: %s, err: %v, resp: %#v", keyType, path, err, resp)
+		}
+		return resp
+	}
+	plaintext := base64.StdEncoding.EncodeToString([]byte(testPlaintext))
+	keyContext := base64.StdEncoding.EncodeToString([]byte("context"))
+
+	doReq("keys/plain", map[string]interface{}{"type": keyType, "exportable": true})
+	doReq("keys/convergent", map[string]interface{}{"type": keyType, "derived": true, "convergent_encryption": true})
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Path:      "keys/plain",
+		Operation: logical.ReadOperation,
+		Storage:   storage,
+	})
+	if err != nil || resp.Data["type"] != keyType || resp.Data["supports_encryption"] != true {
+		t.Fatalf("bad: err: %v, resp: %#v", err, resp)
+	}
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Path:      "export/encryption-key/plain/1",
+		Operation: logical.ReadOperation,
+		Storage:   storage,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key, err := base64.StdEncoding.DecodeString(resp.Data["keys"].(map[string]string)["1"]); err != nil || len(key) != keySize {
+		t.Fatalf("bad: type: %s, key: %x, err: %v", keyType, key, err)
+	}
+
+	ciphertext1 := doReq("encrypt/plain", map[string]interface{}{"plaintext": plaintext}).Data["ciphertext"].(string)
+	ciphertext2 := doReq("encrypt/plain", map[string]interface{}{"plaintext": plaintext}).Data["ciphertext"].(string)
+	if ciphertext1 == ciphertext2 {
+		t.Fatalf("bad: type: %s, equal ciphertexts", keyType)
+	}
+	doReq("keys/plain/rotate", nil)
+	for _, ciphertext := range []string{ciphertext1, doReq("encrypt/plain", map[string]interface{}{"plaintext": plaintext}).Data["ciphertext"].(string)} {
+		if resp := doReq("decrypt/plain", map[string]interface{}{"ciphertext": ciphertext}); resp.Data["plaintext"] != plaintext {
+			t.Fatalf("bad: type: %s, resp: %#v", keyType, resp)
+		}
+	}
+
+	// Tampered ciphertexts are rejected
+	decoded, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(ciphertext1, "vault:v1:"))
+	decoded[len(decoded)-1] ^= 1
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Path:      "decrypt/plain",
+		Operation: logical.UpdateOperation,
+		Storage:   storage,
+		Data:      map[string]interface{}{"ciphertext": "vault:v1:" + base64.StdEncoding.EncodeToString(decoded)},
+	})
+	if err == nil && (resp == nil || !resp.IsError()) {
+		t.Fatalf("bad: type: %s, tampered ciphertext decrypted: %#v", keyType, resp)
+	}
+
+	// Convergent encryption is deterministic for a given context
+	data := map[string]interface{}{"plaintext": plaintext, "context": keyContext}
+	ciphertext1 = doReq("encrypt/convergent", data).Data["ciphertext"].(string)
+	if ciphertext2 := doReq("encrypt/convergent", data).Data["ciphertext"].(string); ciphertext1 != ciphertext2 {
+		t.Fatalf("bad: type: %s, ciphertexts: %s, %s", keyType, ciphertext1, ciphertext2)
+	}
+	if resp := doReq("decrypt/convergent", map[string]interface{}{"ciphertext": ciphertext1, "context": keyContext}); resp.Data["plaintext"] != plaintext {
+		t.Fatalf("bad: type: %s, resp: %#v", keyType, resp)
+	}
+
+	// Keys of the type can be created by upserts
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Path:      "encrypt/upserted",
+		Operation: logical.CreateOperation,
+		Storage:   storage,
+		Data:      map[string]interface{}{"plaintext": plaintext, "type": keyType},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: type: %s, err: %v, resp: %#v", keyType, err, resp)
+	}
+	doReq("datakey/plaintext/upserted", nil)
+}
+
 func TestBackend_basic(t *testing.T) {
 	decryptData := make(map[string]interface{})
 	logicaltest.Test(t, logicaltest.TestCase{
@@ -885,6 +984,75 @@ func TestDerivedKeyUpgrade(t *testing.T) {
 func TestConvergentEncryption(t *testing.T) {
 	testConvergentEncryptionCommon(t, 0)
 	testConvergentEncryptionCommon(t, 2)
+	testConvergentEncryptionCommon(t, 3)
+}
+
+func TestConvergentEncryption_Version3(t *testing.T) {
+	b, storage := createBackendWithStorage(t)
+
+	request := func(path string, data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Storage:   storage,
+			Data:      data,
+		})
+	}
+	doReq := func(path string, data map[string]interface{}) *logical.Response {
+		resp, err := request(path, data)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: path: %s, err: %v, resp: %#v", path, err, resp)
+		}
+		return resp
+	}
+	expectError := func(path string, data map[string]interface{}) {
+		resp, err := request(path, data)
+		if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() {
+			t.Fatalf("expected an error: path: %s, err: %v, resp: %#v", path, err, resp)
+		}
+	}
+
+	expectError("keys/bad", map[string]interface{}{"derived": true, "convergent_version": 3})
+	expectError("keys/bad", map[string]interface{}{"derived": true, "convergent_encryption": true, "convergent_version": 4})
+
+	doReq("keys/v2", map[string]interface{}{"derived": true, "convergent_encryption": true})
+	doReq("keys/v3", map[string]interface{}{"derived": true, "convergent_encryption": true, "convergent_version": 3})
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "keys/v3",
+		Storage:   storage,
+	})
+	if err != nil || resp == nil || resp.Data["convergent_encryption_version"] != 3 {
+		t.Fatalf("bad: err: %v, resp: %#v", err, resp)
+	}
+
+	plaintext := base64.StdEncoding.EncodeToString([]byte(testPlaintext))
+	keyContext := base64.StdEncoding.EncodeToString([]byte("context"))
+	data := map[string]interface{}{"plaintext": plaintext, "context": keyContext}
+	ciphertext := doReq("encrypt/v3", data).Data["ciphertext"].(string)
+	if resp := doReq("encrypt/v3", data); resp.Data["ciphertext"] != ciphertext {
+		t.Fatalf("bad: expected %s, got %#v", ciphertext, resp.Data)
+	}
+	resp = doReq("decrypt/v3", map[string]interface{}{"ciphertext": ciphertext, "context": keyContext})
+	if resp.Data["plaintext"] != plaintext {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// The nonce of version 2 can be computed from the context and the
+	// plaintext, while the one of version 3 cannot
+	nonceHmac := hmac.New(sha256.New, []byte("context"))
+	nonceHmac.Write([]byte(testPlaintext))
+	v2Nonce := nonceHmac.Sum(nil)[:12]
+	for name, expected := range map[string]bool{"v2": true, "v3": false} {
+		ciphertext := doReq("encrypt/"+name, data).Data["ciphertext"].(string)
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(ciphertext, "vault:v1:"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Equal(decoded[:12], v2Nonce) != expected {
+			t.Fatalf("bad: %s: %s", name, ciphertext)
+		}
+	}
 }
 
 func testConvergentEncryptionCommon(t *testing.T, ver int) {
@@ -926,7 +1094,7 @@ func testConvergentEncryptionCommon(t *testing.T, ver int) {
 		ConvergentVersion:    ver,
 	}
 
-	err = p.Rotate(context.Background(), storage)
+	err = p.Rotate(context.Background(), storage, cryptorand.Reader)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -1312,3 +1480,135 @@ func TestBadInput(t *testing.T) {
 		t.Fatal("expected error")
 	}
 }
+
+func TestTransit_AllowedOperations(t *testing.T) {
+	b, storage := createBackendWithStorage(t)
+
+	request := func(op logical.Operation, path string, data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Operation: op,
+			Path:      path,
+			Storage:   storage,
+			Data:      data,
+		})
+	}
+	doReq := func(path string, data map[string]interface{}) *logical.Response {
+		resp, err := request(logical.UpdateOperation, path, data)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: path: %s, err: %v, resp: %#v", path, err, resp)
+		}
+		return resp
+	}
+	expectError := func(path string, data map[string]interface{}, message string) {
+		resp, err := request(logical.UpdateOperation, path, data)
+		if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() || !strings.Contains(resp.Error().Error(), message) {
+			t.Fatalf("expected an error: path: %s, err: %v, resp: %#v", path, err, resp)
+		}
+	}
+
+	expectError("keys/bad", map[string]interface{}{"allowed_operations": "encrypt,bogus"}, "unknown key operation")
+
+	doReq("keys/signer", map[string]interface{}{"type": "ed25519", "allowed_operations": "sign,verify,sign"})
+	doReq("keys/enc", map[string]interface{}{"allowed_operations": "encrypt"})
+	doReq("keys/all", nil)
+
+	resp, err := request(logical.ReadOperation, "keys/signer", nil)
+	if err != nil || resp == nil || !reflect.DeepEqual(resp.Data["allowed_operations"], []string{"sign", "verify"}) {
+		t.Fatalf("bad: err: %v, resp: %#v", err, resp)
+	}
+	resp, err = request(logical.ReadOperation, "keys/all", nil)
+	if err != nil || resp == nil {
+		t.Fatalf("bad: err: %v, resp: %#v", err, resp)
+	}
+	if _, ok := resp.Data["allowed_operations"]; ok {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// The signing key can only sign and verify
+	input := base64.StdEncoding.EncodeToString([]byte(testPlaintext))
+	resp = doReq("sign/signer", map[string]interface{}{"input": input})
+	signature := resp.Data["signature"].(string)
+	if resp := doReq("verify/signer", map[string]interface{}{"input": input, "signature": signature}); resp.Data["valid"] != true {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	expectError("hmac/signer", map[string]interface{}{"input": input}, "does not allow the hmac operation")
+	expectError("encrypt/signer", map[string]interface{}{"plaintext": input}, "does not allow the encrypt operation")
+	expectError("verify/signer", map[string]interface{}{"input": input, "hmac": "vault:v1:AAAA"}, "does not allow the hmac operation")
+	resp = doReq("verify/signer", map[string]interface{}{
+		"batch_input": []interface{}{
+			map[string]interface{}{"input": input, "signature": signature},
+			map[string]interface{}{"input": input, "hmac": "vault:v1:AAAA"},
+		},
+	})
+	results := resp.Data["batch_results"].([]batchResponseVerifyItem)
+	if !results[0].Valid || !strings.Contains(results[1].Error, "does not allow the hmac operation") {
+		t.Fatalf("bad: %#v", results)
+	}
+
+	// The encryption key can only encrypt
+	resp = doReq("encrypt/enc", map[string]interface{}{"plaintext": input})
+	ciphertext := resp.Data["ciphertext"].(string)
+	for path, data := range map[string]map[string]interface{}{
+		"decrypt/enc":           {"ciphertext": ciphertext},
+		"rewrap/enc":            {"ciphertext": ciphertext},
+		"datakey/plaintext/enc": nil,
+		"hmac/enc":              {"input": input},
+		"sign/enc":              {"input": input},
+		"cmac/enc":              {"input": input},
+		"derive/enc":            nil,
+	} {
+		expectError(path, data, "does not allow the")
+	}
+
+	// Transformations encode with the encryption and decode with the
+	// decryption of their key
+	doReq("transformations/ccn", map[string]interface{}{"key": "enc"})
+	resp = doReq("encode/ccn", map[string]interface{}{"value": "4111111111111111"})
+	expectError("decode/ccn", map[string]interface{}{"value": resp.Data["encoded_value"]}, "does not allow the decrypt operation")
+
+	// Keys without allowed operations allow all of them
+	resp = doReq("encrypt/all", map[string]interface{}{"plaintext": input})
+	doReq("decrypt/all", map[string]interface{}{"ciphertext": resp.Data["ciphertext"]})
+	doReq("datakey/plaintext/all", nil)
+	doReq("hmac/all", map[string]interface{}{"input": input})
+	doReq("derive/all", nil)
+}
+
+func TestTransit_FIPSMode(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+	defer fips.SetEnabled(false)
+
+	request := func(path string, data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Storage:   s,
+			Data:      data,
+		})
+	}
+
+	// The ChaCha20-Poly1305 key was created before the FIPS mode was enabled
+	if resp, err := request("keys/chacha", map[string]interface{}{"type": "chacha20-poly1305"}); err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err: %v, resp: %#v", err, resp)
+	}
+
+	fips.SetEnabled(true)
+
+	for _, keyType := range []string{"ed25519", "chacha20-poly1305"} {
+		resp, err := request("keys/new-"+keyType, map[string]interface{}{"type": keyType})
+		if err != logical.ErrInvalidRequest || !strings.Contains(resp.Error().Error(), "is not FIPS approved") {
+			t.Fatalf("expected the %s key to be rejected, got err: %v, resp: %#v", keyType, err, resp)
+		}
+	}
+
+	resp, err := request("encrypt/chacha", map[string]interface{}{"plaintext": "dGhlIHF1aWNrIGJyb3duIGZveA=="})
+	if err != logical.ErrInvalidRequest || !strings.Contains(resp.Error().Error(), "is not FIPS approved") {
+		t.Fatalf("expected the existing key to be rejected, got err: %v, resp: %#v", err, resp)
+	}
+
+	for _, keyType := range []string{"aes256-gcm96", "ecdsa-p256", "rsa-2048"} {
+		if resp, err := request("keys/new-"+keyType, map[string]interface{}{"type": keyType}); err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("expected the %s key to be created, got err: %v, resp: %#v", keyType, err, resp)
+		}
+	}
+}
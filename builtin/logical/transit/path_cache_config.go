@@ -0,0 +1,137 @@
+package transit
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/vault/helper/keysutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// cacheConfigPath is the storage path of the policy cache configuration
+const cacheConfigPath = "config/cache"
+
+// cacheConfig is the configuration of the in-memory cache of the decoded
+// key policies of the mount
+type cacheConfig struct {
+	Disabled bool `json:"disabled"`
+	Size     int  `json:"size"`
+}
+
+func (b *backend) pathCacheConfig() *framework.Path {
+	return &framework.Path{
+		Pattern: "cache-config",
+		Fields: map[string]*framework.FieldSchema{
+			"size": &framework.FieldSchema{
+				Type: framework.TypeInt,
+				Description: fmt.Sprintf(`The maximum number of keys held in the cache,
+at least %d. The least recently used keys are
+evicted first. Zero means unbounded.`, keysutil.MinCacheSize),
+			},
+
+			"disabled": &framework.FieldSchema{
+				Type:        framework.TypeBool,
+				Description: "Whether to disable the cache",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathCacheConfigRead,
+			logical.UpdateOperation: b.pathCacheConfigWrite,
+		},
+
+		HelpSynopsis:    pathCacheConfigHelpSyn,
+		HelpDescription: pathCacheConfigHelpDesc,
+	}
+}
+
+// getCacheConfig returns the stored cache configuration, or the default one
+func getCacheConfig(ctx context.Context, s logical.Storage) (*cacheConfig, error) {
+	entry, err := s.Get(ctx, cacheConfigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &cacheConfig{}
+	if entry == nil {
+		return config, nil
+	}
+	if err := entry.DecodeJSON(config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// loadCacheConfig configures the policy cache from the stored configuration
+func (b *backend) loadCacheConfig(ctx context.Context, s logical.Storage) error {
+	config, err := getCacheConfig(ctx, s)
+	if err != nil {
+		return err
+	}
+	return b.lm.ConfigureCache(config.Disabled, config.Size)
+}
+
+func (b *backend) pathCacheConfigRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config, err := getCacheConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"size":     config.Size,
+			"disabled": config.Disabled,
+		},
+	}, nil
+}
+
+func (b *backend) pathCacheConfigWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config, err := getCacheConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	if sizeRaw, ok := d.GetOk("size"); ok {
+		config.Size = sizeRaw.(int)
+	}
+	if disabledRaw, ok := d.GetOk("disabled"); ok {
+		config.Disabled = disabledRaw.(bool)
+	}
+
+	// Validate the size before storing it
+	if config.Size != 0 {
+		if _, err := keysutil.NewCache(config.Size); err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+	}
+
+	entry, err := logical.StorageEntryJSON(cacheConfigPath, config)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	if err := b.lm.ConfigureCache(config.Disabled, config.Size); err != nil {
+		return nil, err
+	}
+
+	var resp *logical.Response
+	if !config.Disabled && b.System().CachingDisabled() {
+		resp = &logical.Response{}
+		resp.AddWarning("caching is disabled for the server, the cache configuration has no effect until it is enabled")
+	}
+	return resp, nil
+}
+
+const pathCacheConfigHelpSyn = `Configure the in-memory cache of the keys`
+
+const pathCacheConfigHelpDesc = `
+This path configures the cache of the decoded keys of the mount, which spares
+reading and decoding the keys from storage on each operation. By default the
+cache holds all the keys used. Setting a size bounds it to that many keys,
+evicting the least recently used ones. Changing the configuration empties the
+cache. The cache is always disabled when caching is disabled for the server.
+`
@@ -0,0 +1,76 @@
+package transit
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/vault/helper/keysutil"
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestTransit_CacheConfig(t *testing.T) {
+	b, storage := createBackendWithStorage(t)
+
+	doReq := func(op logical.Operation, data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Storage:   storage,
+			Operation: op,
+			Path:      "cache-config",
+			Data:      data,
+		})
+	}
+	checkConfig := func(expected map[string]interface{}) {
+		resp, err := doReq(logical.ReadOperation, nil)
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("bad: %#v %v", resp, err)
+		}
+		if !reflect.DeepEqual(resp.Data, expected) {
+			t.Fatalf("bad: expected %#v, got %#v", expected, resp.Data)
+		}
+	}
+
+	// The cache is unbounded by default
+	checkConfig(map[string]interface{}{
+		"size":     0,
+		"disabled": false,
+	})
+
+	if _, err := doReq(logical.UpdateOperation, map[string]interface{}{"size": 50}); err != nil {
+		t.Fatal(err)
+	}
+	checkConfig(map[string]interface{}{
+		"size":     50,
+		"disabled": false,
+	})
+	if b.lm.CacheSize() != 50 {
+		t.Fatalf("bad: %d", b.lm.CacheSize())
+	}
+
+	resp, err := doReq(logical.UpdateOperation, map[string]interface{}{"size": keysutil.MinCacheSize - 1})
+	if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() {
+		t.Fatalf("expected invalid size error: %#v %v", resp, err)
+	}
+
+	if _, err := doReq(logical.UpdateOperation, map[string]interface{}{"disabled": true}); err != nil {
+		t.Fatal(err)
+	}
+	checkConfig(map[string]interface{}{
+		"size":     50,
+		"disabled": true,
+	})
+	if b.lm.CacheActive() {
+		t.Fatal("expected disabled cache")
+	}
+
+	// A new backend of the mount loads the configuration
+	config := logical.TestBackendConfig()
+	config.StorageView = storage
+	loaded, err := Factory(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.(*backend).lm.CacheActive() {
+		t.Fatal("expected disabled cache")
+	}
+}
@@ -0,0 +1,162 @@
+package transit
+
+import (
+	"context"
+	"crypto/aes"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+
+	"github.com/hashicorp/vault/helper/cmac"
+	"github.com/hashicorp/vault/helper/errutil"
+	"github.com/hashicorp/vault/helper/keysutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// minCMACLength is the shortest CMAC which can be requested, following the
+// recommendation of NIST SP 800-38B
+const minCMACLength = 8
+
+func (b *backend) pathCMAC() *framework.Path {
+	return &framework.Path{
+		Pattern: "cmac/" + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "The aes256-gcm96 key to use for the CMAC",
+			},
+
+			"input": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "The base64-encoded input data",
+			},
+
+			"context": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Base64 encoded context for key derivation. Required if key derivation is enabled",
+			},
+
+			"mac_length": &framework.FieldSchema{
+				Type:    framework.TypeInt,
+				Default: cmac.Size,
+				Description: `The length in bytes of the CMAC, which is truncated
+to this length. Must be between 8 and 16; defaults to 16.`,
+			},
+
+			"key_version": &framework.FieldSchema{
+				Type: framework.TypeInt,
+				Description: `The version of the key to use for generating the CMAC.
+Must be 0 (for latest) or a value greater than or equal
+to the min_encryption_version configured on the key.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathCMACWrite,
+		},
+
+		HelpSynopsis:    pathCMACHelpSyn,
+		HelpDescription: pathCMACHelpDesc,
+	}
+}
+
+// keyVersion returns the key version to use for generating values with the
+// policy, such as MACs and derived keys
+func keyVersion(p *keysutil.Policy, ver int) (int, error) {
+	switch {
+	case ver == 0:
+		return p.LatestVersion, nil
+	case ver < 0 || ver > p.LatestVersion:
+		return 0, errutil.UserError{Err: "invalid key version"}
+	case p.MinEncryptionVersion > 0 && ver < p.MinEncryptionVersion:
+		return 0, errutil.UserError{Err: "version is too old (disallowed by policy)"}
+	}
+	return ver, nil
+}
+
+func (b *backend) pathCMACWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+	macLength := d.Get("mac_length").(int)
+	if macLength < minCMACLength || macLength > cmac.Size {
+		return logical.ErrorResponse(fmt.Sprintf("mac_length must be between %d and %d", minCMACLength, cmac.Size)), logical.ErrInvalidRequest
+	}
+
+	input, err := base64.StdEncoding.DecodeString(d.Get("input").(string))
+	if err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("unable to decode input as base64: %s", err)), logical.ErrInvalidRequest
+	}
+
+	var context []byte
+	if contextRaw := d.Get("context").(string); len(contextRaw) != 0 {
+		context, err = base64.StdEncoding.DecodeString(contextRaw)
+		if err != nil {
+			return logical.ErrorResponse("failed to base64-decode context"), logical.ErrInvalidRequest
+		}
+	}
+
+	// Get the policy
+	p, lock, err := b.lm.GetPolicyShared(ctx, req.Storage, name)
+	if lock != nil {
+		defer lock.RUnlock()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return logical.ErrorResponse("encryption key not found"), logical.ErrInvalidRequest
+	}
+
+	if err := checkOperation(p, keysutil.KeyOperationCMAC); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	if p.Type != keysutil.KeyType_AES256_GCM96 {
+		return logical.ErrorResponse(fmt.Sprintf("key type %v does not support CMAC", p.Type)), logical.ErrInvalidRequest
+	}
+
+	ver, err := keyVersion(p, d.Get("key_version").(int))
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	key, err := p.DeriveKey(context, ver)
+	if err != nil {
+		switch err.(type) {
+		case errutil.UserError:
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		default:
+			return nil, err
+		}
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	mac, err := cmac.Sum(block, input)
+	if err != nil {
+		return nil, err
+	}
+
+	retStr := base64.StdEncoding.EncodeToString(mac[:macLength])
+	retStr = fmt.Sprintf("vault:v%s:%s", strconv.Itoa(ver), retStr)
+
+	// Generate the response
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"cmac": retStr,
+		},
+	}
+	return resp, nil
+}
+
+const pathCMACHelpSyn = `Generate an AES-CMAC for input data using the named key`
+
+const pathCMACHelpDesc = `
+Generates the AES-CMAC of NIST SP 800-38B and RFC 4493 of the given input data
+with the named aes256-gcm96 key, optionally truncated to the given length.
+Since the CMAC is computed with the key itself rather than with a key derived
+from it, keys used for CMACs should be dedicated to them, for instance keys
+imported for a payment protocol.
+`
@@ -0,0 +1,93 @@
+package transit
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"strconv"
+	"testing"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestTransit_CMAC(t *testing.T) {
+	b, storage := createBackendWithStorage(t)
+
+	request := func(path string, data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Data:      data,
+		})
+	}
+	doReq := func(path string, data map[string]interface{}) *logical.Response {
+		resp, err := request(path, data)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: path: %s, err: %v, resp: %#v", path, err, resp)
+		}
+		return resp
+	}
+	expectError := func(path string, data map[string]interface{}) {
+		resp, err := request(path, data)
+		if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() {
+			t.Fatalf("expected an error: path: %s, err: %v, resp: %#v", path, err, resp)
+		}
+	}
+
+	doReq("keys/foo", nil)
+	doReq("keys/derived", map[string]interface{}{"derived": true})
+	doReq("keys/ed", map[string]interface{}{"type": "ed25519"})
+
+	// Change the key value to the AES-256 key of the NIST SP 800-38B examples
+	p, lock, err := b.lm.GetPolicyShared(context.Background(), storage, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lock.RUnlock()
+	keyEntry := p.Keys[strconv.Itoa(p.LatestVersion)]
+	keyEntry.Key, _ = hex.DecodeString("603deb1015ca71be2b73aef0857d77811f352c073b6108d72d9810a30914dff4")
+	p.Keys[strconv.Itoa(p.LatestVersion)] = keyEntry
+	if err := p.Persist(context.Background(), storage); err != nil {
+		t.Fatal(err)
+	}
+
+	input, _ := hex.DecodeString("6bc1bee22e409f96e93d7e117393172a")
+	data := map[string]interface{}{"input": base64.StdEncoding.EncodeToString(input)}
+	mac, _ := hex.DecodeString("28a7023f452e8f82bd4bf28d8c37c35c")
+	if resp := doReq("cmac/foo", data); resp.Data["cmac"] != "vault:v1:"+base64.StdEncoding.EncodeToString(mac) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	data["mac_length"] = 8
+	if resp := doReq("cmac/foo", data); resp.Data["cmac"] != "vault:v1:"+base64.StdEncoding.EncodeToString(mac[:8]) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Older versions can be used until they are disallowed by the policy
+	doReq("keys/foo/rotate", nil)
+	data["key_version"] = 1
+	if resp := doReq("cmac/foo", data); resp.Data["cmac"] != "vault:v1:"+base64.StdEncoding.EncodeToString(mac[:8]) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	delete(data, "key_version")
+	if resp := doReq("cmac/foo", data); resp.Data["cmac"] == "vault:v1:"+base64.StdEncoding.EncodeToString(mac[:8]) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	doReq("keys/foo/config", map[string]interface{}{"min_encryption_version": 2})
+	expectError("cmac/foo", map[string]interface{}{"input": "", "key_version": 1})
+
+	// Derived keys require a context
+	macs := map[string]bool{}
+	for _, context := range []string{"YWJjZA==", "ZWZnaA=="} {
+		macs[doReq("cmac/derived", map[string]interface{}{"input": "", "context": context}).Data["cmac"].(string)] = true
+	}
+	if len(macs) != 2 {
+		t.Fatalf("bad: %v", macs)
+	}
+	expectError("cmac/derived", map[string]interface{}{"input": ""})
+
+	expectError("cmac/ed", map[string]interface{}{"input": ""})
+	expectError("cmac/missing", map[string]interface{}{"input": ""})
+	expectError("cmac/foo", map[string]interface{}{"input": "", "mac_length": 4})
+	expectError("cmac/foo", map[string]interface{}{"input": "not base64"})
+}
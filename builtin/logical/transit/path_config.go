@@ -3,6 +3,7 @@ package transit
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
@@ -42,10 +43,22 @@ the latest version of the key is allowed.`,
 				Description: `Enables export of the key. Once set, this cannot be disabled.`,
 			},
 
+			"wrapped_export_only": &framework.FieldSchema{
+				Type:        framework.TypeBool,
+				Description: `Only allows the key to be exported wrapped under a public key. Once set, this cannot be disabled.`,
+			},
+
 			"allow_plaintext_backup": &framework.FieldSchema{
 				Type:        framework.TypeBool,
 				Description: `Enables taking a backup of the named key in plaintext format. Once set, this cannot be disabled.`,
 			},
+
+			"auto_rotate_period": &framework.FieldSchema{
+				Type: framework.TypeDurationSecond,
+				Description: `The period after which the latest version is
+rotated automatically, at least one hour. Zero
+disables automatic rotation.`,
+			},
 		},
 
 		Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -154,6 +167,19 @@ func (b *backend) pathConfigWrite(ctx context.Context, req *logical.Request, d *
 		}
 	}
 
+	wrappedExportOnlyRaw, ok := d.GetOk("wrapped_export_only")
+	if ok {
+		wrappedExportOnly := wrappedExportOnlyRaw.(bool)
+		// Don't unset the already set value
+		if wrappedExportOnly && !p.WrappedExportOnly {
+			if !p.Exportable {
+				return logical.ErrorResponse("wrapped-only export requires the key to be exportable"), nil
+			}
+			p.WrappedExportOnly = wrappedExportOnly
+			persistNeeded = true
+		}
+	}
+
 	allowPlaintextBackupRaw, ok := d.GetOk("allow_plaintext_backup")
 	if ok {
 		allowPlaintextBackup := allowPlaintextBackupRaw.(bool)
@@ -164,6 +190,21 @@ func (b *backend) pathConfigWrite(ctx context.Context, req *logical.Request, d *
 		}
 	}
 
+	autoRotatePeriodRaw, ok := d.GetOk("auto_rotate_period")
+	if ok {
+		autoRotatePeriod := time.Duration(autoRotatePeriodRaw.(int)) * time.Second
+		if err := validateAutoRotatePeriod(autoRotatePeriod); err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+		if autoRotatePeriod > 0 && p.Imported && !p.AllowImportedKeyRotation {
+			return logical.ErrorResponse("imported key does not allow rotation"), nil
+		}
+		if autoRotatePeriod != p.AutoRotatePeriod {
+			p.AutoRotatePeriod = autoRotatePeriod
+			persistNeeded = true
+		}
+	}
+
 	if !persistNeeded {
 		return nil, nil
 	}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 
 	"github.com/hashicorp/vault/helper/errutil"
+	"github.com/hashicorp/vault/helper/keysutil"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
 )
@@ -109,6 +110,10 @@ func (b *backend) pathDatakeyWrite(ctx context.Context, req *logical.Request, d
 		return logical.ErrorResponse("encryption key not found"), logical.ErrInvalidRequest
 	}
 
+	if err := checkOperation(p, keysutil.KeyOperationDatakey); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
 	newKey := make([]byte, 32)
 	bits := d.Get("bits").(int)
 	switch bits {
@@ -6,6 +6,7 @@ import (
 	"fmt"
 
 	"github.com/hashicorp/vault/helper/errutil"
+	"github.com/hashicorp/vault/helper/keysutil"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
 	"github.com/mitchellh/mapstructure"
@@ -122,6 +123,10 @@ func (b *backend) pathDecryptWrite(ctx context.Context, req *logical.Request, d
 		return logical.ErrorResponse("encryption key not found"), logical.ErrInvalidRequest
 	}
 
+	if err := checkOperation(p, keysutil.KeyOperationDecrypt); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
 	for i, item := range batchInputItems {
 		if batchResponseItems[i].Error != "" {
 			continue
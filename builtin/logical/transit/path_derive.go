@@ -0,0 +1,174 @@
+package transit
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"io"
+
+	"github.com/hashicorp/vault/helper/errutil"
+	"github.com/hashicorp/vault/helper/kdf"
+	"github.com/hashicorp/vault/helper/keysutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+	"golang.org/x/crypto/hkdf"
+)
+
+// maxDerivedKeyLength is the longest key, in bytes, which can be derived
+const maxDerivedKeyLength = 1024
+
+func (b *backend) pathDerive() *framework.Path {
+	return &framework.Path{
+		Pattern: "derive/" + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "The key to derive from",
+			},
+
+			"algorithm": &framework.FieldSchema{
+				Type:    framework.TypeString,
+				Default: "hkdf-sha256",
+				Description: `The key derivation function to use. Valid values are:
+
+* hkdf-sha256
+* hkdf-sha512
+* hmac-sha256-counter
+
+Defaults to "hkdf-sha256".`,
+			},
+
+			"salt": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Base64 encoded salt of HKDF. Not valid for hmac-sha256-counter.",
+			},
+
+			"info": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `Base64 encoded info of HKDF, or context of the
+hmac-sha256-counter KDF of NIST SP 800-108`,
+			},
+
+			"length": &framework.FieldSchema{
+				Type:        framework.TypeInt,
+				Default:     32,
+				Description: "The length in bytes of the derived key; defaults to 32.",
+			},
+
+			"key_version": &framework.FieldSchema{
+				Type: framework.TypeInt,
+				Description: `The version of the key to derive from.
+Must be 0 (for latest) or a value greater than or equal
+to the min_encryption_version configured on the key.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathDeriveWrite,
+		},
+
+		HelpSynopsis:    pathDeriveHelpSyn,
+		HelpDescription: pathDeriveHelpDesc,
+	}
+}
+
+func (b *backend) pathDeriveWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+	algorithm := d.Get("algorithm").(string)
+	length := d.Get("length").(int)
+	if length <= 0 || length > maxDerivedKeyLength {
+		return logical.ErrorResponse(fmt.Sprintf("length must be between 1 and %d", maxDerivedKeyLength)), logical.ErrInvalidRequest
+	}
+
+	salt, err := base64.StdEncoding.DecodeString(d.Get("salt").(string))
+	if err != nil {
+		return logical.ErrorResponse("failed to base64-decode salt"), logical.ErrInvalidRequest
+	}
+	info, err := base64.StdEncoding.DecodeString(d.Get("info").(string))
+	if err != nil {
+		return logical.ErrorResponse("failed to base64-decode info"), logical.ErrInvalidRequest
+	}
+
+	var hf func() hash.Hash
+	switch algorithm {
+	case "hkdf-sha256":
+		hf = sha256.New
+	case "hkdf-sha512":
+		hf = sha512.New
+	case "hmac-sha256-counter":
+		if len(salt) != 0 {
+			return logical.ErrorResponse("salt is not valid for hmac-sha256-counter"), logical.ErrInvalidRequest
+		}
+	default:
+		return logical.ErrorResponse(fmt.Sprintf("unsupported algorithm %s", algorithm)), logical.ErrInvalidRequest
+	}
+
+	// Get the policy
+	p, lock, err := b.lm.GetPolicyShared(ctx, req.Storage, name)
+	if lock != nil {
+		defer lock.RUnlock()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return logical.ErrorResponse("encryption key not found"), logical.ErrInvalidRequest
+	}
+
+	if err := checkOperation(p, keysutil.KeyOperationDerive); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	// The keys of derived policies are themselves derived with these KDFs,
+	// so that deriving from them could return their encryption keys
+	if !p.Type.Symmetric() || p.Derived {
+		return logical.ErrorResponse("key derivation requires a non-derived symmetric key"), logical.ErrInvalidRequest
+	}
+
+	ver, err := keyVersion(p, d.Get("key_version").(int))
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	key, err := p.DeriveKey(nil, ver)
+	if err != nil {
+		switch err.(type) {
+		case errutil.UserError:
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		default:
+			return nil, err
+		}
+	}
+
+	var derived []byte
+	if hf == nil {
+		derived, err = kdf.CounterMode(kdf.HMACSHA256PRF, kdf.HMACSHA256PRFLen, key, info, uint32(length*8))
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		derived = make([]byte, length)
+		if _, err := io.ReadFull(hkdf.New(hf, key, salt, info), derived); err != nil {
+			return nil, err
+		}
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"derived_key": base64.StdEncoding.EncodeToString(derived),
+			"key_version": ver,
+		},
+	}, nil
+}
+
+const pathDeriveHelpSyn = `Derive a key from the named key`
+
+const pathDeriveHelpDesc = `
+Derives a key of the given length from the named key with HKDF (RFC 5869) or
+with the counter mode KDF of NIST SP 800-108 using HMAC-SHA256, so that
+applications can derive sub-keys without exporting the named key. Only
+non-derived symmetric keys can be derived from.
+`
@@ -0,0 +1,102 @@
+package transit
+
+import (
+	"context"
+	"crypto/sha512"
+	"encoding/base64"
+	"io"
+	"strconv"
+	"testing"
+
+	"github.com/hashicorp/vault/helper/kdf"
+	"github.com/hashicorp/vault/logical"
+	"golang.org/x/crypto/hkdf"
+)
+
+func TestTransit_Derive(t *testing.T) {
+	b, storage := createBackendWithStorage(t)
+
+	request := func(path string, data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Data:      data,
+		})
+	}
+	doReq := func(path string, data map[string]interface{}) *logical.Response {
+		resp, err := request(path, data)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: path: %s, err: %v, resp: %#v", path, err, resp)
+		}
+		return resp
+	}
+	expectError := func(path string, data map[string]interface{}) {
+		resp, err := request(path, data)
+		if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() {
+			t.Fatalf("expected an error: path: %s, err: %v, resp: %#v", path, err, resp)
+		}
+	}
+
+	doReq("keys/foo", nil)
+	doReq("keys/derived", map[string]interface{}{"derived": true})
+	doReq("keys/ed", map[string]interface{}{"type": "ed25519"})
+
+	p, lock, err := b.lm.GetPolicyShared(context.Background(), storage, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lock.RUnlock()
+	key := p.Keys[strconv.Itoa(p.LatestVersion)].Key
+
+	salt, info := []byte("salt"), []byte("info")
+	expected := make([]byte, 48)
+	if _, err := io.ReadFull(hkdf.New(sha512.New, key, salt, info), expected); err != nil {
+		t.Fatal(err)
+	}
+	resp := doReq("derive/foo", map[string]interface{}{
+		"algorithm": "hkdf-sha512",
+		"salt":      base64.StdEncoding.EncodeToString(salt),
+		"info":      base64.StdEncoding.EncodeToString(info),
+		"length":    48,
+	})
+	if resp.Data["derived_key"] != base64.StdEncoding.EncodeToString(expected) || resp.Data["key_version"] != 1 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	expected, err = kdf.CounterMode(kdf.HMACSHA256PRF, kdf.HMACSHA256PRFLen, key, info, 128)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp = doReq("derive/foo", map[string]interface{}{
+		"algorithm": "hmac-sha256-counter",
+		"info":      base64.StdEncoding.EncodeToString(info),
+		"length":    16,
+	})
+	if resp.Data["derived_key"] != base64.StdEncoding.EncodeToString(expected) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// The default is a 32-byte HKDF-SHA256 key of the latest version
+	first := doReq("derive/foo", nil).Data["derived_key"].(string)
+	if derived, _ := base64.StdEncoding.DecodeString(first); len(derived) != 32 {
+		t.Fatalf("bad: %q", first)
+	}
+	doReq("keys/foo/rotate", nil)
+	resp = doReq("derive/foo", nil)
+	if resp.Data["derived_key"] == first || resp.Data["key_version"] != 2 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if resp := doReq("derive/foo", map[string]interface{}{"key_version": 1}); resp.Data["derived_key"] != first {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	expectError("derive/derived", nil)
+	expectError("derive/ed", nil)
+	expectError("derive/missing", nil)
+	expectError("derive/foo", map[string]interface{}{"algorithm": "sha2-256"})
+	expectError("derive/foo", map[string]interface{}{"algorithm": "hmac-sha256-counter", "salt": "c2FsdA=="})
+	expectError("derive/foo", map[string]interface{}{"length": 0})
+	expectError("derive/foo", map[string]interface{}{"length": 2048})
+	expectError("derive/foo", map[string]interface{}{"key_version": 3})
+}
@@ -0,0 +1,480 @@
+package transit
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/helper/errutil"
+	"github.com/hashicorp/vault/helper/fpe"
+	"github.com/hashicorp/vault/helper/keysutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// tokenAttempts is the number of random tokens generated for a value before
+// giving up on finding an unused one
+const tokenAttempts = 10
+
+// tokenEntry is the stored value of a token
+type tokenEntry struct {
+	Ciphertext   string            `json:"ciphertext"`
+	Metadata     map[string]string `json:"metadata"`
+	CreationTime time.Time         `json:"creation_time"`
+}
+
+func (b *backend) pathEncode() *framework.Path {
+	return &framework.Path{
+		Pattern: "encode/" + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the transformation",
+			},
+
+			"value": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "The value to encode",
+			},
+
+			"tweak": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `Base64 encoded 7-byte tweak for "fpe"
+transformations. The same tweak must be given to
+decode the value. Defaults to zero bytes.`,
+			},
+
+			"key_version": &framework.FieldSchema{
+				Type: framework.TypeInt,
+				Description: `The version of the key to use for "fpe"
+transformations. Must be 0 (for latest) or a value
+greater than or equal to the min_encryption_version
+configured on the key.`,
+			},
+
+			"metadata": &framework.FieldSchema{
+				Type: framework.TypeKVPairs,
+				Description: `Metadata stored with the token of
+"tokenization" transformations`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathEncodeWrite,
+		},
+
+		HelpSynopsis:    pathEncodeHelpSyn,
+		HelpDescription: pathEncodeHelpDesc,
+	}
+}
+
+func (b *backend) pathDecode() *framework.Path {
+	return &framework.Path{
+		Pattern: "decode/" + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the transformation",
+			},
+
+			"value": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "The value to decode",
+			},
+
+			"tweak": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `Base64 encoded 7-byte tweak the value was
+encoded with by an "fpe" transformation`,
+			},
+
+			"key_version": &framework.FieldSchema{
+				Type: framework.TypeInt,
+				Description: `The version of the key the value was encoded
+with by an "fpe" transformation. Defaults to the
+latest version.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathDecodeWrite,
+		},
+
+		HelpSynopsis:    pathDecodeHelpSyn,
+		HelpDescription: pathDecodeHelpDesc,
+	}
+}
+
+func (b *backend) pathTokenMetadata() *framework.Path {
+	return &framework.Path{
+		Pattern: "metadata/" + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the transformation",
+			},
+
+			"value": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "The token to look up",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathTokenMetadataWrite,
+		},
+
+		HelpSynopsis:    pathTokenMetadataHelpSyn,
+		HelpDescription: pathTokenMetadataHelpDesc,
+	}
+}
+
+// getTransformationPolicy returns the transformation and its key, which is
+// locked for reading unless a response or an error is returned. Encoding
+// values uses the key for encryption and decoding them for decryption.
+func (b *backend) getTransformationPolicy(ctx context.Context, req *logical.Request, name, operation string) (*transformation, *keysutil.Policy, *sync.RWMutex, *logical.Response, error) {
+	t, err := getTransformation(ctx, req.Storage, name)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	if t == nil {
+		return nil, nil, nil, logical.ErrorResponse(fmt.Sprintf("transformation %q not found", name)), logical.ErrInvalidRequest
+	}
+
+	p, lock, err := b.lm.GetPolicyShared(ctx, req.Storage, t.Key)
+	if err == nil && p == nil {
+		err = errutil.UserError{Err: fmt.Sprintf("key %q not found", t.Key)}
+	}
+	if err == nil {
+		if err = validateTransformationKey(p); err == nil {
+			err = checkOperation(p, operation)
+		}
+		if err != nil {
+			err = errutil.UserError{Err: err.Error()}
+		}
+	}
+	if err != nil {
+		if lock != nil {
+			lock.RUnlock()
+		}
+		resp, err := userErrorResponse(err)
+		return nil, nil, nil, resp, err
+	}
+
+	return t, p, lock, nil, nil
+}
+
+// fpeCipher returns the FF3-1 cipher of the transformation under the given
+// version of the key, which is only used to derive the FF3-1 key
+func fpeCipher(t *transformation, p *keysutil.Policy, ver int) (*fpe.Cipher, error) {
+	key, err := p.DeriveKey(nil, ver)
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("transit-fpe"))
+	return fpe.NewCipher(mac.Sum(nil), t.radix())
+}
+
+// parseTweak returns the base64 encoded tweak, or zero bytes if it is empty
+func parseTweak(tweakB64 string) ([]byte, error) {
+	if tweakB64 == "" {
+		return make([]byte, fpe.TweakSize), nil
+	}
+	tweak, err := base64.StdEncoding.DecodeString(tweakB64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to base64-decode tweak")
+	}
+	if len(tweak) != fpe.TweakSize {
+		return nil, fmt.Errorf("tweak must be %d bytes", fpe.TweakSize)
+	}
+	return tweak, nil
+}
+
+// tokenStorageKey returns the storage key of the token, which is hashed so
+// that any characters can be used in alphabets
+func tokenStorageKey(name, token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return "token/" + name + "/" + hex.EncodeToString(hash[:])
+}
+
+func getTokenEntry(ctx context.Context, s logical.Storage, name, token string) (*tokenEntry, error) {
+	entry, err := s.Get(ctx, tokenStorageKey(name, token))
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var result tokenEntry
+	if err := entry.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// userErrorResponse returns user errors as error responses
+func userErrorResponse(err error) (*logical.Response, error) {
+	switch err.(type) {
+	case errutil.UserError:
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	default:
+		return nil, err
+	}
+}
+
+func (b *backend) pathEncodeWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+	value := d.Get("value").(string)
+
+	t, p, lock, resp, err := b.getTransformationPolicy(ctx, req, name, keysutil.KeyOperationEncrypt)
+	if resp != nil || err != nil {
+		return resp, err
+	}
+	if lock != nil {
+		defer lock.RUnlock()
+	}
+
+	numerals := t.parse(value)
+
+	switch t.Type {
+	case transformationTypeFPE:
+		if _, ok := d.GetOk("metadata"); ok {
+			return logical.ErrorResponse("metadata is only supported by tokenization transformations"), logical.ErrInvalidRequest
+		}
+		tweak, err := parseTweak(d.Get("tweak").(string))
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+
+		ver := d.Get("key_version").(int)
+		switch {
+		case ver == 0:
+			ver = p.LatestVersion
+		case ver < 0 || ver > p.LatestVersion:
+			return logical.ErrorResponse("invalid key version"), logical.ErrInvalidRequest
+		case ver < p.MinEncryptionVersion:
+			return logical.ErrorResponse("requested version for encryption is less than the minimum encryption key version"), logical.ErrInvalidRequest
+		}
+
+		c, err := fpeCipher(t, p, ver)
+		if err != nil {
+			return userErrorResponse(err)
+		}
+		encoded, err := c.Encrypt(numerals, tweak)
+		if err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("failed to encode the value: %v", err)), logical.ErrInvalidRequest
+		}
+
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"encoded_value": t.format(value, encoded),
+				"key_version":   ver,
+			},
+		}, nil
+
+	default:
+		if _, ok := d.GetOk("tweak"); ok {
+			return logical.ErrorResponse("tweak is only supported by fpe transformations"), logical.ErrInvalidRequest
+		}
+
+		// Tokens must be hard to guess, as they are looked up as is; the
+		// minimum length of FF3-1 gives at least a million possible tokens
+		c, err := fpe.NewCipher(make([]byte, 32), t.radix())
+		if err != nil {
+			return nil, err
+		}
+		if len(numerals) < c.MinLength() {
+			return logical.ErrorResponse(fmt.Sprintf("the value must have at least %d characters of the alphabet", c.MinLength())), logical.ErrInvalidRequest
+		}
+
+		ciphertext, err := p.Encrypt(0, nil, nil, base64.StdEncoding.EncodeToString([]byte(value)))
+		if err != nil {
+			return userErrorResponse(err)
+		}
+
+		var token string
+		bigRadix := big.NewInt(int64(t.radix()))
+		for attempt := 0; token == "" && attempt < tokenAttempts; attempt++ {
+			random := make([]int, len(numerals))
+			for i := range random {
+				n, err := rand.Int(rand.Reader, bigRadix)
+				if err != nil {
+					return nil, err
+				}
+				random[i] = int(n.Int64())
+			}
+			candidate := t.format(value, random)
+			if candidate == value {
+				continue
+			}
+			existing, err := getTokenEntry(ctx, req.Storage, name, candidate)
+			if err != nil {
+				return nil, err
+			}
+			if existing == nil {
+				token = candidate
+			}
+		}
+		if token == "" {
+			return nil, fmt.Errorf("failed to generate an unused token")
+		}
+
+		metadata := map[string]string{}
+		if metadataRaw, ok := d.GetOk("metadata"); ok {
+			metadata = metadataRaw.(map[string]string)
+		}
+		entry, err := logical.StorageEntryJSON(tokenStorageKey(name, token), &tokenEntry{
+			Ciphertext:   ciphertext,
+			Metadata:     metadata,
+			CreationTime: time.Now(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := req.Storage.Put(ctx, entry); err != nil {
+			return nil, err
+		}
+
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"encoded_value": token,
+			},
+		}, nil
+	}
+}
+
+func (b *backend) pathDecodeWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+	value := d.Get("value").(string)
+
+	t, p, lock, resp, err := b.getTransformationPolicy(ctx, req, name, keysutil.KeyOperationDecrypt)
+	if resp != nil || err != nil {
+		return resp, err
+	}
+	if lock != nil {
+		defer lock.RUnlock()
+	}
+
+	switch t.Type {
+	case transformationTypeFPE:
+		tweak, err := parseTweak(d.Get("tweak").(string))
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+
+		ver := d.Get("key_version").(int)
+		switch {
+		case ver == 0:
+			ver = p.LatestVersion
+		case ver < 0 || ver > p.LatestVersion:
+			return logical.ErrorResponse("invalid key version"), logical.ErrInvalidRequest
+		case ver < p.MinDecryptionVersion:
+			return logical.ErrorResponse(keysutil.ErrTooOld), logical.ErrInvalidRequest
+		}
+
+		c, err := fpeCipher(t, p, ver)
+		if err != nil {
+			return userErrorResponse(err)
+		}
+		decoded, err := c.Decrypt(t.parse(value), tweak)
+		if err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("failed to decode the value: %v", err)), logical.ErrInvalidRequest
+		}
+
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"decoded_value": t.format(value, decoded),
+			},
+		}, nil
+
+	default:
+		entry, err := getTokenEntry(ctx, req.Storage, name, value)
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil {
+			return logical.ErrorResponse("token not found"), logical.ErrInvalidRequest
+		}
+
+		plaintext, err := p.Decrypt(nil, nil, entry.Ciphertext)
+		if err != nil {
+			return userErrorResponse(err)
+		}
+		decoded, err := base64.StdEncoding.DecodeString(plaintext)
+		if err != nil {
+			return nil, err
+		}
+
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"decoded_value": string(decoded),
+				"metadata":      entry.Metadata,
+			},
+		}, nil
+	}
+}
+
+func (b *backend) pathTokenMetadataWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	t, err := getTransformation(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if t == nil || t.Type != transformationTypeTokenization {
+		return logical.ErrorResponse(fmt.Sprintf("tokenization transformation %q not found", name)), logical.ErrInvalidRequest
+	}
+
+	entry, err := getTokenEntry(ctx, req.Storage, name, d.Get("value").(string))
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return logical.ErrorResponse("token not found"), logical.ErrInvalidRequest
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"metadata":      entry.Metadata,
+			"creation_time": entry.CreationTime,
+		},
+	}, nil
+}
+
+const pathEncodeHelpSyn = `Encode a value with a transformation`
+
+const pathEncodeHelpDesc = `
+This path encodes the value with the named transformation. The characters of
+the value which belong to the alphabet of the transformation are replaced,
+and the others kept in place, so that the encoded value has the same length
+and format.
+
+Transformations of type "fpe" encrypt the value with FF3-1 under the latest
+version of the key, which is returned along with the encoded value. The tweak
+and key version must be given to decode it. Transformations of type
+"tokenization" return a random token, and store the encrypted value with the
+given metadata.
+`
+
+const pathDecodeHelpSyn = `Decode a value encoded with a transformation`
+
+const pathDecodeHelpDesc = `
+This path decodes the value encoded by the named transformation. Tokens of
+"tokenization" transformations are returned with their metadata.
+`
+
+const pathTokenMetadataHelpSyn = `Look up the metadata of a token`
+
+const pathTokenMetadataHelpDesc = `
+This path returns the metadata stored with a token of the named "tokenization"
+transformation, without decoding it.
+`
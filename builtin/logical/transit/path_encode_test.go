@@ -0,0 +1,149 @@
+package transit
+
+import (
+	"context"
+	"encoding/base64"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestTransit_Transformations(t *testing.T) {
+	b, storage := createBackendWithStorage(t)
+
+	request := func(op logical.Operation, path string, data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Operation: op,
+			Path:      path,
+			Storage:   storage,
+			Data:      data,
+		})
+	}
+	doReq := func(op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		resp, err := request(op, path, data)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: path: %s, err: %v, resp: %#v", path, err, resp)
+		}
+		return resp
+	}
+	expectError := func(path string, data map[string]interface{}, message string) {
+		resp, err := request(logical.UpdateOperation, path, data)
+		if resp == nil || !resp.IsError() || !strings.Contains(resp.Error().Error(), message) {
+			t.Fatalf("expected an error: path: %s, err: %v, resp: %#v", path, err, resp)
+		}
+	}
+
+	doReq(logical.UpdateOperation, "keys/fpe", nil)
+	doReq(logical.UpdateOperation, "keys/derived", map[string]interface{}{"derived": true})
+	doReq(logical.UpdateOperation, "keys/ed", map[string]interface{}{"type": "ed25519"})
+
+	expectError("transformations/bad", map[string]interface{}{"key": "missing"}, "not found")
+	expectError("transformations/bad", map[string]interface{}{"key": "derived"}, "non-derived")
+	expectError("transformations/bad", map[string]interface{}{"key": "ed"}, "non-derived")
+	expectError("transformations/bad", map[string]interface{}{"key": "fpe", "type": "bad"}, "unknown transformation type")
+	expectError("transformations/bad", map[string]interface{}{"key": "fpe", "alphabet": "0"}, "invalid alphabet")
+	expectError("transformations/bad", map[string]interface{}{"key": "fpe", "alphabet": "00"}, "more than once")
+
+	doReq(logical.UpdateOperation, "transformations/ccn", map[string]interface{}{"key": "fpe"})
+	doReq(logical.UpdateOperation, "transformations/tokens", map[string]interface{}{
+		"key":      "fpe",
+		"type":     "tokenization",
+		"alphabet": "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ",
+	})
+	resp := doReq(logical.ReadOperation, "transformations/ccn", nil)
+	if resp.Data["type"] != "fpe" || resp.Data["key"] != "fpe" || resp.Data["alphabet"] != "0123456789" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	resp = doReq(logical.ListOperation, "transformations/", nil)
+	if !reflect.DeepEqual(resp.Data["keys"], []string{"ccn", "tokens"}) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	expectError("transformations/ccn", map[string]interface{}{"key": "fpe", "alphabet": "01234567"}, "cannot be changed")
+
+	// FPE keeps the length and the characters outside of the alphabet
+	ccn := "4111-1111-1111-1111"
+	resp = doReq(logical.UpdateOperation, "encode/ccn", map[string]interface{}{"value": ccn})
+	encoded := resp.Data["encoded_value"].(string)
+	if len(encoded) != len(ccn) || encoded == ccn || strings.Count(encoded, "-") != 3 || encoded[4] != '-' {
+		t.Fatalf("bad: %q", encoded)
+	}
+	if resp.Data["key_version"] != 1 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if resp := doReq(logical.UpdateOperation, "encode/ccn", map[string]interface{}{"value": ccn}); resp.Data["encoded_value"] != encoded {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	resp = doReq(logical.UpdateOperation, "decode/ccn", map[string]interface{}{"value": encoded})
+	if resp.Data["decoded_value"] != ccn {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// The tweak and key version change the encoded value
+	tweak := base64.StdEncoding.EncodeToString([]byte("1234567"))
+	resp = doReq(logical.UpdateOperation, "encode/ccn", map[string]interface{}{"value": ccn, "tweak": tweak})
+	tweaked := resp.Data["encoded_value"].(string)
+	if tweaked == encoded {
+		t.Fatal("the tweak was ignored")
+	}
+	if resp := doReq(logical.UpdateOperation, "decode/ccn", map[string]interface{}{"value": tweaked, "tweak": tweak}); resp.Data["decoded_value"] != ccn {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	doReq(logical.UpdateOperation, "keys/fpe/rotate", nil)
+	if resp := doReq(logical.UpdateOperation, "encode/ccn", map[string]interface{}{"value": ccn}); resp.Data["encoded_value"] == encoded || resp.Data["key_version"] != 2 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if resp := doReq(logical.UpdateOperation, "decode/ccn", map[string]interface{}{"value": encoded, "key_version": 1}); resp.Data["decoded_value"] != ccn {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	expectError("encode/ccn", map[string]interface{}{"value": "12345"}, "length")
+	expectError("encode/ccn", map[string]interface{}{"value": ccn, "tweak": "bad"}, "base64")
+	expectError("encode/ccn", map[string]interface{}{"value": ccn, "tweak": base64.StdEncoding.EncodeToString([]byte("short"))}, "7 bytes")
+	expectError("encode/ccn", map[string]interface{}{"value": ccn, "key_version": 3}, "invalid key version")
+	expectError("encode/ccn", map[string]interface{}{"value": ccn, "metadata": "a=b"}, "metadata")
+	expectError("encode/missing", map[string]interface{}{"value": ccn}, "not found")
+
+	// Tokens are random, and decode to the value with its metadata
+	ssn := "078-05-1120"
+	resp = doReq(logical.UpdateOperation, "encode/tokens", map[string]interface{}{
+		"value":    ssn,
+		"metadata": "customer=1234",
+	})
+	token := resp.Data["encoded_value"].(string)
+	if len(token) != len(ssn) || token == ssn || token[3] != '-' || token[6] != '-' {
+		t.Fatalf("bad: %q", token)
+	}
+	if resp := doReq(logical.UpdateOperation, "encode/tokens", map[string]interface{}{"value": ssn}); resp.Data["encoded_value"] == token {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	resp = doReq(logical.UpdateOperation, "decode/tokens", map[string]interface{}{"value": token})
+	if resp.Data["decoded_value"] != ssn || !reflect.DeepEqual(resp.Data["metadata"], map[string]string{"customer": "1234"}) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	resp = doReq(logical.UpdateOperation, "metadata/tokens", map[string]interface{}{"value": token})
+	if !reflect.DeepEqual(resp.Data["metadata"], map[string]string{"customer": "1234"}) || resp.Data["decoded_value"] != nil {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Tokens remain decodable after the key is rotated
+	doReq(logical.UpdateOperation, "keys/fpe/rotate", nil)
+	if resp := doReq(logical.UpdateOperation, "decode/tokens", map[string]interface{}{"value": token}); resp.Data["decoded_value"] != ssn {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	expectError("encode/tokens", map[string]interface{}{"value": "12-3"}, "at least")
+	expectError("encode/tokens", map[string]interface{}{"value": ssn, "tweak": tweak}, "tweak")
+	expectError("decode/tokens", map[string]interface{}{"value": "000-00-0000"}, "token not found")
+	expectError("metadata/ccn", map[string]interface{}{"value": token}, "not found")
+
+	// Deleting the transformation deletes its tokens
+	doReq(logical.DeleteOperation, "transformations/tokens", nil)
+	if tokens, err := storage.List(context.Background(), "token/tokens/"); err != nil || len(tokens) != 0 {
+		t.Fatalf("bad: tokens: %v, err: %v", tokens, err)
+	}
+	if resp := doReq(logical.ReadOperation, "transformations/tokens", nil); resp != nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+}
@@ -88,8 +88,8 @@ encryption key) this nonce value is **never reused**.
 				Description: `
 This parameter is required when encryption key is expected to be created.
 When performing an upsert operation, the type of key to create. Currently,
-"aes256-gcm96" (symmetric) is the only type supported. Defaults to
-"aes256-gcm96".`,
+"aes256-gcm96", "chacha20-poly1305" and "aes256-cbc-hmac-sha512" (symmetric)
+are supported. Defaults to "aes256-gcm96".`,
 			},
 
 			"convergent_encryption": &framework.FieldSchema{
@@ -225,12 +225,20 @@ func (b *backend) pathEncryptWrite(ctx context.Context, req *logical.Request, d
 		switch keyType {
 		case "aes256-gcm96":
 			polReq.KeyType = keysutil.KeyType_AES256_GCM96
+		case "chacha20-poly1305":
+			polReq.KeyType = keysutil.KeyType_ChaCha20_Poly1305
+		case "aes256-cbc-hmac-sha512":
+			polReq.KeyType = keysutil.KeyType_AES256_CBC_HMAC_SHA512
 		case "ecdsa-p256":
 			return logical.ErrorResponse(fmt.Sprintf("key type %v not supported for this operation", keyType)), logical.ErrInvalidRequest
 		default:
 			return logical.ErrorResponse(fmt.Sprintf("unknown key type %v", keyType)), logical.ErrInvalidRequest
 		}
+		if err := checkFIPSKeyType(polReq.KeyType); err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
 
+		polReq.RandReader = b.GetRandomReader()
 		p, lock, upserted, err = b.lm.GetPolicyUpsert(ctx, polReq)
 
 	} else {
@@ -246,6 +254,10 @@ func (b *backend) pathEncryptWrite(ctx context.Context, req *logical.Request, d
 		return logical.ErrorResponse("encryption key not found"), logical.ErrInvalidRequest
 	}
 
+	if err := checkOperation(p, keysutil.KeyOperationEncrypt); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
 	// Process batch request items. If encryption of any request
 	// item fails, respectively mark the error in the response
 	// collection and continue to process other items.
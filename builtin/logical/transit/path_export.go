@@ -2,20 +2,26 @@ package transit
 
 import (
 	"context"
+	"crypto"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/elliptic"
+	"crypto/rand"
 	"crypto/rsa"
+	"crypto/sha256"
 	"crypto/x509"
 	"encoding/base64"
 	"encoding/pem"
 	"errors"
 	"fmt"
+	"io"
 	"strconv"
 	"strings"
 
 	"github.com/hashicorp/vault/helper/keysutil"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
+	"golang.org/x/crypto/hkdf"
 )
 
 const (
@@ -40,10 +46,23 @@ func (b *backend) pathExportKeys() *framework.Path {
 				Type:        framework.TypeString,
 				Description: "Version of the key",
 			},
+			"public_key": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `PEM encoded RSA or P-256 ECDSA public key to wrap
+the exported keys under, when writing to this path`,
+			},
+			"hash_function": &framework.FieldSchema{
+				Type:    framework.TypeString,
+				Default: "SHA256",
+				Description: `The hash function used for RSA-OAEP. Valid
+values are "SHA1", "SHA256", "SHA384" and
+"SHA512". Defaults to "SHA256".`,
+			},
 		},
 
 		Callbacks: map[logical.Operation]framework.OperationFunc{
-			logical.ReadOperation: b.pathPolicyExportRead,
+			logical.ReadOperation:   b.pathPolicyExportRead,
+			logical.UpdateOperation: b.pathPolicyExportWrappedWrite,
 		},
 
 		HelpSynopsis:    pathExportHelpSyn,
@@ -51,7 +70,83 @@ func (b *backend) pathExportKeys() *framework.Path {
 	}
 }
 
+// exportFunc returns the exported form of a key entry of the policy
+type exportFunc func(policy *keysutil.Policy, key *keysutil.KeyEntry, exportType string) (string, error)
+
 func (b *backend) pathPolicyExportRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	return b.exportPolicy(ctx, req, d, false, getExportKey)
+}
+
+func (b *backend) pathPolicyExportWrappedWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	hash, ok := parseOAEPHash(d.Get("hash_function").(string))
+	if !ok {
+		return logical.ErrorResponse(fmt.Sprintf("unsupported hash function %v", d.Get("hash_function"))), logical.ErrInvalidRequest
+	}
+
+	block, _ := pem.Decode([]byte(d.Get("public_key").(string)))
+	if block == nil {
+		return logical.ErrorResponse("'public_key' must be a PEM encoded public key"), logical.ErrInvalidRequest
+	}
+	publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("failed to parse the public key: %v", err)), logical.ErrInvalidRequest
+	}
+
+	var wrap func(key []byte) ([]byte, error)
+	var ephemeralPublicKey string
+	switch publicKey := publicKey.(type) {
+	case *rsa.PublicKey:
+		if publicKey.N.BitLen() < 2048 {
+			return logical.ErrorResponse("RSA public keys must be at least 2048 bits"), logical.ErrInvalidRequest
+		}
+		wrap = func(key []byte) ([]byte, error) {
+			return rsaAESWrap(publicKey, hash, key)
+		}
+
+	case *ecdsa.PublicKey:
+		if publicKey.Curve != elliptic.P256() {
+			return logical.ErrorResponse("ECDSA public keys must use the P-256 curve"), logical.ErrInvalidRequest
+		}
+		kek, ephemeralKey, err := ecdhKEK(publicKey)
+		if err != nil {
+			return nil, err
+		}
+		wrap = func(key []byte) ([]byte, error) {
+			return kwpWrap(kek, key)
+		}
+		ephemeralPublicKey, err = encodePublicKey(&ephemeralKey.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+
+	default:
+		return logical.ErrorResponse("the public key must be an RSA or ECDSA key"), logical.ErrInvalidRequest
+	}
+
+	resp, err := b.exportPolicy(ctx, req, d, true, func(policy *keysutil.Policy, key *keysutil.KeyEntry, exportType string) (string, error) {
+		material, err := getExportKeyBytes(policy, key, exportType)
+		if err != nil {
+			return "", err
+		}
+		wrapped, err := wrap(material)
+		if err != nil {
+			return "", err
+		}
+		return base64.StdEncoding.EncodeToString(wrapped), nil
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		return resp, err
+	}
+
+	if ephemeralPublicKey != "" {
+		resp.Data["ephemeral_public_key"] = ephemeralPublicKey
+	}
+	return resp, nil
+}
+
+// exportPolicy returns the keys of the requested versions of the policy,
+// exported with the given function
+func (b *backend) exportPolicy(ctx context.Context, req *logical.Request, d *framework.FieldData, wrapped bool, export exportFunc) (*logical.Response, error) {
 	exportType := d.Get("type").(string)
 	name := d.Get("name").(string)
 	version := d.Get("version").(string)
@@ -79,6 +174,10 @@ func (b *backend) pathPolicyExportRead(ctx context.Context, req *logical.Request
 		return logical.ErrorResponse("key is not exportable"), nil
 	}
 
+	if p.WrappedExportOnly && !wrapped {
+		return logical.ErrorResponse("key can only be exported wrapped under a public key"), logical.ErrInvalidRequest
+	}
+
 	switch exportType {
 	case exportTypeEncryptionKey:
 		if !p.Type.EncryptionSupported() {
@@ -94,7 +193,7 @@ func (b *backend) pathPolicyExportRead(ctx context.Context, req *logical.Request
 	switch version {
 	case "":
 		for k, v := range p.Keys {
-			exportKey, err := getExportKey(p, &v, exportType)
+			exportKey, err := export(p, &v, exportType)
 			if err != nil {
 				return nil, err
 			}
@@ -121,7 +220,7 @@ func (b *backend) pathPolicyExportRead(ctx context.Context, req *logical.Request
 			return logical.ErrorResponse("version does not exist or cannot be found"), logical.ErrInvalidRequest
 		}
 
-		exportKey, err := getExportKey(p, &key, exportType)
+		exportKey, err := export(p, &key, exportType)
 		if err != nil {
 			return nil, err
 		}
@@ -151,7 +250,7 @@ func getExportKey(policy *keysutil.Policy, key *keysutil.KeyEntry, exportType st
 
 	case exportTypeEncryptionKey:
 		switch policy.Type {
-		case keysutil.KeyType_AES256_GCM96:
+		case keysutil.KeyType_AES256_GCM96, keysutil.KeyType_ChaCha20_Poly1305, keysutil.KeyType_AES256_CBC_HMAC_SHA512:
 			return strings.TrimSpace(base64.StdEncoding.EncodeToString(key.Key)), nil
 
 		case keysutil.KeyType_RSA2048, keysutil.KeyType_RSA4096:
@@ -178,6 +277,102 @@ func getExportKey(policy *keysutil.Policy, key *keysutil.KeyEntry, exportType st
 	return "", fmt.Errorf("unknown key type %v", policy.Type)
 }
 
+// getExportKeyBytes returns the key material of a key entry to be wrapped:
+// raw bytes for symmetric and HMAC keys, and PKCS#8 DER private keys for
+// asymmetric keys, as expected by the import endpoint
+func getExportKeyBytes(policy *keysutil.Policy, key *keysutil.KeyEntry, exportType string) ([]byte, error) {
+	if policy == nil {
+		return nil, errors.New("nil policy provided")
+	}
+
+	switch exportType {
+	case exportTypeHMACKey:
+		return key.HMACKey, nil
+
+	case exportTypeEncryptionKey:
+		switch policy.Type {
+		case keysutil.KeyType_AES256_GCM96, keysutil.KeyType_ChaCha20_Poly1305, keysutil.KeyType_AES256_CBC_HMAC_SHA512:
+			return key.Key, nil
+
+		case keysutil.KeyType_RSA2048, keysutil.KeyType_RSA4096:
+			return x509.MarshalPKCS8PrivateKey(key.RSAKey)
+		}
+
+	case exportTypeSigningKey:
+		switch policy.Type {
+		case keysutil.KeyType_ECDSA_P256:
+			return x509.MarshalPKCS8PrivateKey(&ecdsa.PrivateKey{
+				PublicKey: ecdsa.PublicKey{
+					Curve: elliptic.P256(),
+					X:     key.EC_X,
+					Y:     key.EC_Y,
+				},
+				D: key.EC_D,
+			})
+
+		case keysutil.KeyType_ED25519:
+			return x509.MarshalPKCS8PrivateKey(ed25519.PrivateKey(key.Key))
+
+		case keysutil.KeyType_RSA2048, keysutil.KeyType_RSA4096:
+			return x509.MarshalPKCS8PrivateKey(key.RSAKey)
+		}
+	}
+
+	return nil, fmt.Errorf("unknown key type %v", policy.Type)
+}
+
+// rsaAESWrap wraps the key in the format of the import endpoint: the RSA-OAEP
+// encryption of an ephemeral 256-bit AES key, followed by the AES key wrap
+// with padding of the key under the AES key
+func rsaAESWrap(publicKey *rsa.PublicKey, hash crypto.Hash, key []byte) ([]byte, error) {
+	ephemeralKey := make([]byte, 32)
+	if _, err := rand.Read(ephemeralKey); err != nil {
+		return nil, err
+	}
+	wrappedKey, err := rsa.EncryptOAEP(hash.New(), rand.Reader, publicKey, ephemeralKey, nil)
+	if err != nil {
+		return nil, err
+	}
+	wrapped, err := kwpWrap(ephemeralKey, key)
+	if err != nil {
+		return nil, err
+	}
+	return append(wrappedKey, wrapped...), nil
+}
+
+// ecdhKEK returns a 256-bit AES key encryption key agreed with the public key
+// by ephemeral ECDH, derived from the shared secret with HKDF-SHA256 without
+// salt or info, along with the ephemeral key
+func ecdhKEK(publicKey *ecdsa.PublicKey) ([]byte, *ecdsa.PrivateKey, error) {
+	ephemeralKey, err := ecdsa.GenerateKey(publicKey.Curve, rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	x, _ := publicKey.Curve.ScalarMult(publicKey.X, publicKey.Y, ephemeralKey.D.Bytes())
+	sharedSecret := make([]byte, (publicKey.Curve.Params().BitSize+7)/8)
+	xBytes := x.Bytes()
+	copy(sharedSecret[len(sharedSecret)-len(xBytes):], xBytes)
+
+	kek := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, sharedSecret, nil, nil), kek); err != nil {
+		return nil, nil, err
+	}
+	return kek, ephemeralKey, nil
+}
+
+// encodePublicKey returns the PEM encoded PKIX form of the public key
+func encodePublicKey(publicKey interface{}) (string, error) {
+	derBytes, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{
+		Type:  "PUBLIC KEY",
+		Bytes: derBytes,
+	})), nil
+}
+
 func encodeRSAPrivateKey(key *rsa.PrivateKey) string {
 	// When encoding PKCS1, the PEM header should be `RSA PRIVATE KEY`. When Go
 	// has PKCS8 encoding support, we may want to change this.
@@ -223,4 +418,13 @@ const pathExportHelpSyn = `Export named encryption or signing key`
 const pathExportHelpDesc = `
 This path is used to export the named keys that are configured as
 exportable.
+
+Writing a PEM encoded public key to this path exports the keys wrapped under
+it, which is the only way to export keys configured with "wrapped_export_only".
+Under an RSA public key, each key is wrapped in the format of the import
+endpoint. Under a P-256 ECDSA public key, a key encryption key is agreed by
+ephemeral ECDH and derived with HKDF-SHA256, the keys are wrapped with the AES
+key wrap with padding algorithm of RFC 5649 under it, and the ephemeral public
+key is returned. Wrapped asymmetric keys are PKCS#8 DER private keys; other
+keys are raw bytes.
 `
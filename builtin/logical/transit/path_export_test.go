@@ -1,13 +1,27 @@
 package transit
 
 import (
+	"bytes"
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
 	"fmt"
+	"io"
 	"reflect"
 	"strconv"
+	"strings"
 	"testing"
 
+	"github.com/hashicorp/vault/helper/keysutil"
 	"github.com/hashicorp/vault/logical"
+	"golang.org/x/crypto/hkdf"
 )
 
 func TestTransit_Export_KeyVersion_ExportsCorrectVersion(t *testing.T) {
@@ -413,3 +427,179 @@ func TestTransit_Export_EncryptionKey_DoesNotExportHMACKey(t *testing.T) {
 		t.Fatal("Encryption key data matched hmac key data")
 	}
 }
+
+func TestTransit_Export_Wrapped(t *testing.T) {
+	b, storage := createBackendWithStorage(t)
+
+	request := func(op logical.Operation, path string, data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Operation: op,
+			Path:      path,
+			Storage:   storage,
+			Data:      data,
+		})
+	}
+	doReq := func(op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		resp, err := request(op, path, data)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: path: %s, err: %v, resp: %#v", path, err, resp)
+		}
+		return resp
+	}
+	expectError := func(op logical.Operation, path string, data map[string]interface{}, message string) {
+		resp, err := request(op, path, data)
+		if resp == nil || !resp.IsError() || !strings.Contains(resp.Error().Error(), message) {
+			t.Fatalf("expected an error: path: %s, err: %v, resp: %#v", path, err, resp)
+		}
+	}
+	encodePublicKey := func(publicKey interface{}) string {
+		derBytes, err := x509.MarshalPKIXPublicKey(publicKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: derBytes}))
+	}
+	getKey := func(name string) keysutil.KeyEntry {
+		p, lock, err := b.lm.GetPolicyShared(context.Background(), storage, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lock.RUnlock()
+		return p.Keys["1"]
+	}
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectError(logical.UpdateOperation, "keys/bad", map[string]interface{}{"wrapped_export_only": true}, "exportable")
+	doReq(logical.UpdateOperation, "keys/aes", map[string]interface{}{
+		"exportable":             true,
+		"wrapped_export_only":    true,
+		"allow_plaintext_backup": true,
+	})
+	if resp := doReq(logical.ReadOperation, "keys/aes", nil); resp.Data["wrapped_export_only"] != true {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Keys can neither be exported nor backed up in plaintext
+	expectError(logical.ReadOperation, "export/encryption-key/aes", nil, "wrapped")
+	if resp, err := request(logical.ReadOperation, "backup/aes", nil); err == nil && !resp.IsError() {
+		t.Fatalf("expected an error: %#v", resp)
+	}
+
+	// Under RSA public keys, keys are wrapped as for imports
+	resp := doReq(logical.UpdateOperation, "export/encryption-key/aes/1", map[string]interface{}{
+		"public_key":    encodePublicKey(&rsaKey.PublicKey),
+		"hash_function": "SHA512",
+	})
+	wrapped, err := base64.StdEncoding.DecodeString(resp.Data["keys"].(map[string]string)["1"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	ephemeralKey, err := rsa.DecryptOAEP(sha512.New(), rand.Reader, rsaKey, wrapped[:rsaKey.Size()], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := kwpUnwrap(ephemeralKey, wrapped[rsaKey.Size():])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(key, getKey("aes").Key) {
+		t.Fatal("bad: wrong key")
+	}
+
+	// Under P-256 public keys, keys are wrapped under a key agreed by ECDH
+	resp = doReq(logical.UpdateOperation, "export/hmac-key/aes", map[string]interface{}{
+		"public_key": encodePublicKey(&ecKey.PublicKey),
+	})
+	block, _ := pem.Decode([]byte(resp.Data["ephemeral_public_key"].(string)))
+	if block == nil {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ephemeralPublicKey := parsed.(*ecdsa.PublicKey)
+	x, _ := elliptic.P256().ScalarMult(ephemeralPublicKey.X, ephemeralPublicKey.Y, ecKey.D.Bytes())
+	sharedSecret := make([]byte, 32)
+	copy(sharedSecret[32-len(x.Bytes()):], x.Bytes())
+	kek := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, sharedSecret, nil, nil), kek); err != nil {
+		t.Fatal(err)
+	}
+	wrapped, err = base64.StdEncoding.DecodeString(resp.Data["keys"].(map[string]string)["1"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err = kwpUnwrap(kek, wrapped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(key, getKey("aes").HMACKey) {
+		t.Fatal("bad: wrong key")
+	}
+
+	// Wrapped keys can be imported by another backend
+	b2, storage2 := createBackendWithStorage(t)
+	resp, err = b2.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "wrapping_key",
+		Storage:   storage2,
+	})
+	if err != nil || resp.IsError() {
+		t.Fatalf("bad: err: %v, resp: %#v", err, resp)
+	}
+	wrappingKey := resp.Data["public_key"].(string)
+	doReq(logical.UpdateOperation, "keys/ed", map[string]interface{}{"type": "ed25519", "exportable": true})
+	resp = doReq(logical.UpdateOperation, "export/signing-key/ed/latest", map[string]interface{}{"public_key": wrappingKey})
+	resp, err = b2.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "keys/ed/import",
+		Storage:   storage2,
+		Data: map[string]interface{}{
+			"ciphertext": resp.Data["keys"].(map[string]string)["1"],
+			"type":       "ed25519",
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: err: %v, resp: %#v", err, resp)
+	}
+	input := "dGhlIHF1aWNrIGJyb3duIGZveA=="
+	signature := doReq(logical.UpdateOperation, "sign/ed", map[string]interface{}{"input": input}).Data["signature"]
+	resp, err = b2.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "verify/ed",
+		Storage:   storage2,
+		Data:      map[string]interface{}{"input": input, "signature": signature},
+	})
+	if err != nil || resp.Data["valid"] != true {
+		t.Fatalf("bad: err: %v, resp: %#v", err, resp)
+	}
+
+	// The mode can be enabled on existing exportable keys
+	doReq(logical.UpdateOperation, "keys/plain", nil)
+	expectError(logical.UpdateOperation, "keys/plain/config", map[string]interface{}{"wrapped_export_only": true}, "exportable")
+	doReq(logical.UpdateOperation, "keys/plain/config", map[string]interface{}{"exportable": true, "wrapped_export_only": true})
+	expectError(logical.ReadOperation, "export/encryption-key/plain", nil, "wrapped")
+
+	smallKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p384Key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectError(logical.UpdateOperation, "export/encryption-key/aes", map[string]interface{}{"public_key": "bad"}, "PEM")
+	expectError(logical.UpdateOperation, "export/encryption-key/aes", map[string]interface{}{"public_key": encodePublicKey(&smallKey.PublicKey)}, "2048")
+	expectError(logical.UpdateOperation, "export/encryption-key/aes", map[string]interface{}{"public_key": encodePublicKey(&p384Key.PublicKey)}, "P-256")
+	expectError(logical.UpdateOperation, "export/encryption-key/aes", map[string]interface{}{"public_key": encodePublicKey(&rsaKey.PublicKey), "hash_function": "MD5"}, "hash function")
+	expectError(logical.UpdateOperation, "export/encryption-key/plain/3", map[string]interface{}{"public_key": encodePublicKey(&rsaKey.PublicKey)}, "version")
+}
@@ -11,8 +11,11 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/hashicorp/vault/helper/errutil"
+	"github.com/hashicorp/vault/helper/keysutil"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
+	"github.com/mitchellh/mapstructure"
 )
 
 func (b *backend) pathHMAC() *framework.Path {
@@ -64,18 +67,67 @@ to the min_encryption_version configured on the key.`,
 	}
 }
 
+// batchRequestHMACItem represents a request item for batch HMAC generation
+type batchRequestHMACItem struct {
+	// Input is the base64-encoded input data
+	Input string `json:"input" structs:"input" mapstructure:"input"`
+}
+
+// batchResponseHMACItem represents a response item for batch HMAC generation
+type batchResponseHMACItem struct {
+	// HMAC of the input present in the corresponding batch request item
+	HMAC string `json:"hmac,omitempty" structs:"hmac" mapstructure:"hmac"`
+
+	// Error, if set represents a failure encountered while generating the
+	// HMAC of the corresponding batch request item
+	Error string `json:"error,omitempty" structs:"error" mapstructure:"error"`
+}
+
+// hashFunction returns the hash function of the given algorithm, or nil if
+// the algorithm is not supported
+func hashFunction(algorithm string) func() hash.Hash {
+	switch algorithm {
+	case "sha2-224":
+		return sha256.New224
+	case "sha2-256":
+		return sha256.New
+	case "sha2-384":
+		return sha512.New384
+	case "sha2-512":
+		return sha512.New
+	default:
+		return nil
+	}
+}
+
 func (b *backend) pathHMACWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
 	name := d.Get("name").(string)
 	ver := d.Get("key_version").(int)
-	inputB64 := d.Get("input").(string)
 	algorithm := d.Get("urlalgorithm").(string)
 	if algorithm == "" {
 		algorithm = d.Get("algorithm").(string)
 	}
 
-	input, err := base64.StdEncoding.DecodeString(inputB64)
-	if err != nil {
-		return logical.ErrorResponse(fmt.Sprintf("unable to decode input as base64: %s", err)), logical.ErrInvalidRequest
+	batchInputRaw := d.Raw["batch_input"]
+	var batchInputItems []batchRequestHMACItem
+	if batchInputRaw != nil {
+		err := mapstructure.Decode(batchInputRaw, &batchInputItems)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse batch input: %v", err)
+		}
+
+		if len(batchInputItems) == 0 {
+			return logical.ErrorResponse("missing batch input to process"), logical.ErrInvalidRequest
+		}
+	} else {
+		batchInputItems = []batchRequestHMACItem{
+			{Input: d.Get("input").(string)},
+		}
+	}
+
+	hf := hashFunction(algorithm)
+	if hf == nil {
+		return logical.ErrorResponse(fmt.Sprintf("unsupported algorithm %s", algorithm)), nil
 	}
 
 	// Get the policy
@@ -90,6 +142,10 @@ func (b *backend) pathHMACWrite(ctx context.Context, req *logical.Request, d *fr
 		return logical.ErrorResponse("encryption key not found"), logical.ErrInvalidRequest
 	}
 
+	if err := checkOperation(p, keysutil.KeyOperationHMAC); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
 	switch {
 	case ver == 0:
 		// Allowed, will use latest; set explicitly here to ensure the string
@@ -109,120 +165,83 @@ func (b *backend) pathHMACWrite(ctx context.Context, req *logical.Request, d *fr
 		return nil, fmt.Errorf("HMAC key value could not be computed")
 	}
 
-	var hf hash.Hash
-	switch algorithm {
-	case "sha2-224":
-		hf = hmac.New(sha256.New224, key)
-	case "sha2-256":
-		hf = hmac.New(sha256.New, key)
-	case "sha2-384":
-		hf = hmac.New(sha512.New384, key)
-	case "sha2-512":
-		hf = hmac.New(sha512.New, key)
-	default:
-		return logical.ErrorResponse(fmt.Sprintf("unsupported algorithm %s", algorithm)), nil
-	}
-	hf.Write(input)
-	retBytes := hf.Sum(nil)
+	batchResponseItems := make([]batchResponseHMACItem, len(batchInputItems))
+	for i, item := range batchInputItems {
+		input, err := base64.StdEncoding.DecodeString(item.Input)
+		if err != nil {
+			batchResponseItems[i].Error = fmt.Sprintf("unable to decode input as base64: %s", err)
+			continue
+		}
 
-	retStr := base64.StdEncoding.EncodeToString(retBytes)
-	retStr = fmt.Sprintf("vault:v%s:%s", strconv.Itoa(ver), retStr)
+		mac := hmac.New(hf, key)
+		mac.Write(input)
+		retStr := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+		batchResponseItems[i].HMAC = fmt.Sprintf("vault:v%s:%s", strconv.Itoa(ver), retStr)
+	}
 
 	// Generate the response
-	resp := &logical.Response{
-		Data: map[string]interface{}{
-			"hmac": retStr,
-		},
+	resp := &logical.Response{}
+	if batchInputRaw != nil {
+		resp.Data = map[string]interface{}{
+			"batch_results": batchResponseItems,
+		}
+	} else {
+		if batchResponseItems[0].Error != "" {
+			return logical.ErrorResponse(batchResponseItems[0].Error), logical.ErrInvalidRequest
+		}
+		resp.Data = map[string]interface{}{
+			"hmac": batchResponseItems[0].HMAC,
+		}
 	}
 	return resp, nil
 }
 
-func (b *backend) pathHMACVerify(ctx context.Context, req *logical.Request, d *framework.FieldData, verificationHMAC string) (*logical.Response, error) {
-	name := d.Get("name").(string)
-	inputB64 := d.Get("input").(string)
-	algorithm := d.Get("urlalgorithm").(string)
-	if algorithm == "" {
-		algorithm = d.Get("algorithm").(string)
-	}
-
-	input, err := base64.StdEncoding.DecodeString(inputB64)
-	if err != nil {
-		return logical.ErrorResponse(fmt.Sprintf("unable to decode input as base64: %s", err)), logical.ErrInvalidRequest
-	}
-
+// verifyHMAC verifies the given HMAC of the input with the policy
+func verifyHMAC(p *keysutil.Policy, hf func() hash.Hash, input []byte, verificationHMAC string) (bool, error) {
 	// Verify the prefix
 	if !strings.HasPrefix(verificationHMAC, "vault:v") {
-		return logical.ErrorResponse("invalid HMAC to verify: no prefix"), logical.ErrInvalidRequest
+		return false, errutil.UserError{Err: "invalid HMAC to verify: no prefix"}
 	}
 
 	splitVerificationHMAC := strings.SplitN(strings.TrimPrefix(verificationHMAC, "vault:v"), ":", 2)
 	if len(splitVerificationHMAC) != 2 {
-		return logical.ErrorResponse("invalid HMAC: wrong number of fields"), logical.ErrInvalidRequest
+		return false, errutil.UserError{Err: "invalid HMAC: wrong number of fields"}
 	}
 
 	ver, err := strconv.Atoi(splitVerificationHMAC[0])
 	if err != nil {
-		return logical.ErrorResponse("invalid HMAC: version number could not be decoded"), logical.ErrInvalidRequest
+		return false, errutil.UserError{Err: "invalid HMAC: version number could not be decoded"}
 	}
 
 	verBytes, err := base64.StdEncoding.DecodeString(splitVerificationHMAC[1])
 	if err != nil {
-		return logical.ErrorResponse(fmt.Sprintf("unable to decode verification HMAC as base64: %s", err)), logical.ErrInvalidRequest
-	}
-
-	// Get the policy
-	p, lock, err := b.lm.GetPolicyShared(ctx, req.Storage, name)
-	if lock != nil {
-		defer lock.RUnlock()
-	}
-	if err != nil {
-		return nil, err
-	}
-	if p == nil {
-		return logical.ErrorResponse("encryption key not found"), logical.ErrInvalidRequest
+		return false, errutil.UserError{Err: fmt.Sprintf("unable to decode verification HMAC as base64: %s", err)}
 	}
 
 	if ver > p.LatestVersion {
-		return logical.ErrorResponse("invalid HMAC: version is too new"), logical.ErrInvalidRequest
+		return false, errutil.UserError{Err: "invalid HMAC: version is too new"}
 	}
 
 	if p.MinDecryptionVersion > 0 && ver < p.MinDecryptionVersion {
-		return logical.ErrorResponse("cannot verify HMAC: version is too old (disallowed by policy)"), logical.ErrInvalidRequest
+		return false, errutil.UserError{Err: "cannot verify HMAC: version is too old (disallowed by policy)"}
 	}
 
 	key, err := p.HMACKey(ver)
 	if err != nil {
-		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		return false, errutil.UserError{Err: err.Error()}
 	}
 	if key == nil {
-		return nil, fmt.Errorf("HMAC key value could not be computed")
-	}
-
-	var hf hash.Hash
-	switch algorithm {
-	case "sha2-224":
-		hf = hmac.New(sha256.New224, key)
-	case "sha2-256":
-		hf = hmac.New(sha256.New, key)
-	case "sha2-384":
-		hf = hmac.New(sha512.New384, key)
-	case "sha2-512":
-		hf = hmac.New(sha512.New, key)
-	default:
-		return logical.ErrorResponse(fmt.Sprintf("unsupported algorithm %s", algorithm)), nil
+		return false, fmt.Errorf("HMAC key value could not be computed")
 	}
-	hf.Write(input)
-	retBytes := hf.Sum(nil)
 
-	return &logical.Response{
-		Data: map[string]interface{}{
-			"valid": hmac.Equal(retBytes, verBytes),
-		},
-	}, nil
+	mac := hmac.New(hf, key)
+	mac.Write(input)
+	return hmac.Equal(mac.Sum(nil), verBytes), nil
 }
 
 const pathHMACHelpSyn = `Generate an HMAC for input data using the named key`
 
 const pathHMACHelpDesc = `
-Generates an HMAC sum of the given algorithm and key against the given input data.
+Generates an HMAC sum of the given algorithm and key against the given input
+data, or against each item of a batch of input data.
 `
@@ -2,6 +2,7 @@ package transit
 
 import (
 	"context"
+	"crypto/rand"
 	"fmt"
 	"strconv"
 	"strings"
@@ -127,7 +128,7 @@ func TestTransit_HMAC(t *testing.T) {
 	req.Data["input"] = "dGhlIHF1aWNrIGJyb3duIGZveA=="
 
 	// Rotate
-	err = p.Rotate(context.Background(), storage)
+	err = p.Rotate(context.Background(), storage, rand.Reader)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -184,3 +185,68 @@ func TestTransit_HMAC(t *testing.T) {
 		t.Fatalf("expected invalid request error, got %v", err)
 	}
 }
+
+func TestTransit_BatchHMAC(t *testing.T) {
+	b, storage := createBackendWithStorage(t)
+
+	doReq := func(path string, data map[string]interface{}) *logical.Response {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Data:      data,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: path: %s, err: %v, resp: %#v", path, err, resp)
+		}
+		return resp
+	}
+
+	doReq("keys/foo", nil)
+
+	inputs := []string{"dGhlIHF1aWNrIGJyb3duIGZveA==", "Zm9vYmFy"}
+	resp := doReq("hmac/foo/sha2-512", map[string]interface{}{
+		"batch_input": []interface{}{
+			map[string]interface{}{"input": inputs[0]},
+			map[string]interface{}{"input": inputs[1]},
+			map[string]interface{}{"input": "not base64"},
+		},
+	})
+	results := resp.Data["batch_results"].([]batchResponseHMACItem)
+	if len(results) != 3 || results[2].Error == "" || results[2].HMAC != "" {
+		t.Fatalf("bad: %#v", results)
+	}
+	for i, input := range inputs {
+		resp := doReq("hmac/foo/sha2-512", map[string]interface{}{"input": input})
+		if results[i].Error != "" || results[i].HMAC != resp.Data["hmac"] {
+			t.Fatalf("bad: item %d: %#v, expected: %#v", i, results[i], resp.Data)
+		}
+	}
+
+	resp = doReq("verify/foo/sha2-512", map[string]interface{}{
+		"batch_input": []interface{}{
+			map[string]interface{}{"input": inputs[0], "hmac": results[0].HMAC},
+			map[string]interface{}{"input": inputs[0], "hmac": results[1].HMAC},
+			map[string]interface{}{"input": inputs[1], "hmac": "vault:v2:" + strings.TrimPrefix(results[1].HMAC, "vault:v1:")},
+			map[string]interface{}{"input": inputs[1]},
+		},
+	})
+	verifyResults := resp.Data["batch_results"].([]batchResponseVerifyItem)
+	if len(verifyResults) != 4 ||
+		!verifyResults[0].Valid || verifyResults[0].Error != "" ||
+		verifyResults[1].Valid || verifyResults[1].Error != "" ||
+		verifyResults[2].Valid || !strings.Contains(verifyResults[2].Error, "too new") ||
+		verifyResults[3].Valid || verifyResults[3].Error == "" {
+		t.Fatalf("bad: %#v", verifyResults)
+	}
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "hmac/foo",
+		Data:      map[string]interface{}{"batch_input": []interface{}{}},
+	})
+	if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error: err: %v, resp: %#v", err, resp)
+	}
+}
@@ -0,0 +1,326 @@
+package transit
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/aes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/vault/helper/errutil"
+	"github.com/hashicorp/vault/helper/keysutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// kwpIV is the alternative initial value of RFC 5649
+var kwpIV = []byte{0xA6, 0x59, 0x59, 0xA6}
+
+func (b *backend) pathImport() *framework.Path {
+	return &framework.Path{
+		Pattern: "keys/" + framework.GenericNameRegex("name") + "/import",
+		Fields: map[string]*framework.FieldSchema{
+			"name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the key",
+			},
+
+			"ciphertext": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `Base64 encoded key material, wrapped as the
+RSA-OAEP encryption of an ephemeral 256-bit AES
+key under the key returned by "wrapping_key",
+followed by the AES key wrap with padding (RFC
+5649) of the key material under the AES key.`,
+			},
+
+			"hash_function": &framework.FieldSchema{
+				Type:    framework.TypeString,
+				Default: "SHA256",
+				Description: `The hash function used for RSA-OAEP. Valid
+values are "SHA1", "SHA256", "SHA384" and
+"SHA512". Defaults to "SHA256".`,
+			},
+
+			"type": &framework.FieldSchema{
+				Type:    framework.TypeString,
+				Default: "aes256-gcm96",
+				Description: `
+The type of the imported key. Currently, "aes256-gcm96" (symmetric),
+"chacha20-poly1305" (symmetric), "aes256-cbc-hmac-sha512" (symmetric),
+"ecdsa-p256" (asymmetric), 'ed25519' (asymmetric), 'rsa-2048' (asymmetric),
+'rsa-4096' (asymmetric) are supported.  Defaults to "aes256-gcm96".
+`,
+			},
+
+			"derived": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `Enables key derivation mode. This
+allows for per-transaction unique
+keys for encryption operations.`,
+			},
+
+			"convergent_encryption": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `Whether to support convergent encryption.
+This is only supported when using a key with
+key derivation enabled.`,
+			},
+
+			"convergent_version": &framework.FieldSchema{
+				Type: framework.TypeInt,
+				Description: `The version of convergent encryption, either 2
+or 3. Version 3 derives the nonce with a sub-key
+of the per-context key rather than with the
+context alone. Defaults to 2.`,
+			},
+
+			"exportable": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `Enables keys to be exportable.
+This allows for all the valid keys
+in the key ring to be exported.`,
+			},
+
+			"wrapped_export_only": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `Only allows exportable keys to be exported
+wrapped under a public key. Once set, this
+cannot be disabled.`,
+			},
+
+			"allow_plaintext_backup": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `Enables taking a backup of the named
+key in plaintext format. Once set,
+this cannot be disabled.`,
+			},
+
+			"allow_rotation": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `Allows the imported key to be rotated,
+with new versions generated by the backend.`,
+			},
+
+			"allowed_operations": &framework.FieldSchema{
+				Type: framework.TypeCommaStringSlice,
+				Description: `The operations the key can be used for, among
+encrypt, decrypt, sign, verify, hmac, datakey,
+cmac and derive. Defaults to all operations.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathImportWrite,
+		},
+
+		HelpSynopsis:    pathImportHelpSyn,
+		HelpDescription: pathImportHelpDesc,
+	}
+}
+
+func (b *backend) pathImportWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+	keyType := d.Get("type").(string)
+
+	polReq := keysutil.PolicyRequest{
+		Storage:                  req.Storage,
+		Name:                     name,
+		Derived:                  d.Get("derived").(bool),
+		Convergent:               d.Get("convergent_encryption").(bool),
+		ConvergentVersion:        d.Get("convergent_version").(int),
+		Exportable:               d.Get("exportable").(bool),
+		WrappedExportOnly:        d.Get("wrapped_export_only").(bool),
+		AllowPlaintextBackup:     d.Get("allow_plaintext_backup").(bool),
+		AllowImportedKeyRotation: d.Get("allow_rotation").(bool),
+		AllowedOperations:        d.Get("allowed_operations").([]string),
+	}
+	var ok bool
+	polReq.KeyType, ok = parseKeyType(keyType)
+	if !ok {
+		return logical.ErrorResponse(fmt.Sprintf("unknown key type %v", keyType)), logical.ErrInvalidRequest
+	}
+	if err := checkFIPSKeyType(polReq.KeyType); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	hash, ok := parseOAEPHash(d.Get("hash_function").(string))
+	if !ok {
+		return logical.ErrorResponse(fmt.Sprintf("unsupported hash function %v", d.Get("hash_function"))), logical.ErrInvalidRequest
+	}
+
+	ciphertextB64 := d.Get("ciphertext").(string)
+	if ciphertextB64 == "" {
+		return logical.ErrorResponse("'ciphertext' must be supplied"), logical.ErrInvalidRequest
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(ciphertextB64)
+	if err != nil {
+		return logical.ErrorResponse("failed to base64-decode ciphertext"), logical.ErrInvalidRequest
+	}
+
+	wrappingKey, err := b.getWrappingKey(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	// The ephemeral key is encrypted to the size of the wrapping key
+	wrappedKeySize := wrappingKey.Size()
+	if len(ciphertext) <= wrappedKeySize {
+		return logical.ErrorResponse("ciphertext is too short"), logical.ErrInvalidRequest
+	}
+	ephemeralKey, err := rsa.DecryptOAEP(hash.New(), rand.Reader, wrappingKey, ciphertext[:wrappedKeySize], nil)
+	if err != nil {
+		return logical.ErrorResponse("failed to decrypt the ephemeral key"), logical.ErrInvalidRequest
+	}
+	if len(ephemeralKey) != 32 {
+		return logical.ErrorResponse("the ephemeral key must be a 256-bit AES key"), logical.ErrInvalidRequest
+	}
+	key, err := kwpUnwrap(ephemeralKey, ciphertext[wrappedKeySize:])
+	if err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("failed to unwrap the key: %v", err)), logical.ErrInvalidRequest
+	}
+
+	err = b.lm.ImportPolicy(ctx, polReq, key)
+	if err != nil {
+		switch err.(type) {
+		case errutil.UserError:
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		default:
+			return nil, err
+		}
+	}
+
+	return nil, nil
+}
+
+// parseOAEPHash returns the RSA-OAEP hash function of the given name
+func parseOAEPHash(name string) (crypto.Hash, bool) {
+	switch strings.ToUpper(name) {
+	case "SHA1":
+		return crypto.SHA1, true
+	case "SHA256":
+		return crypto.SHA256, true
+	case "SHA384":
+		return crypto.SHA384, true
+	case "SHA512":
+		return crypto.SHA512, true
+	default:
+		return 0, false
+	}
+}
+
+// kwpWrap wraps the key with the AES key wrap with padding algorithm of RFC
+// 5649 under the given key encryption key
+func kwpWrap(kek, key []byte) ([]byte, error) {
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) == 0 {
+		return nil, fmt.Errorf("cannot wrap an empty key")
+	}
+
+	a := make([]byte, 8)
+	copy(a, kwpIV)
+	binary.BigEndian.PutUint32(a[4:], uint32(len(key)))
+	r := append([]byte{}, key...)
+	if len(r)%8 != 0 {
+		r = append(r, make([]byte, 8-len(r)%8)...)
+	}
+
+	n := len(r) / 8
+	buf := make([]byte, 16)
+	if n == 1 {
+		// A single block is encrypted directly
+		copy(buf, a)
+		copy(buf[8:], r)
+		block.Encrypt(buf, buf)
+		return buf, nil
+	}
+	for j := 0; j <= 5; j++ {
+		for i := 1; i <= n; i++ {
+			copy(buf, a)
+			copy(buf[8:], r[(i-1)*8:i*8])
+			block.Encrypt(buf, buf)
+			copy(a, buf[:8])
+			t := uint64(n*j + i)
+			for k := 0; k < 8; k++ {
+				a[7-k] ^= byte(t >> uint(8*k))
+			}
+			copy(r[(i-1)*8:i*8], buf[8:])
+		}
+	}
+	return append(a, r...), nil
+}
+
+// kwpUnwrap unwraps the key wrapped by the AES key wrap with padding
+// algorithm of RFC 5649 under the given key encryption key
+func kwpUnwrap(kek, wrapped []byte) ([]byte, error) {
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	if len(wrapped) < 16 || len(wrapped)%8 != 0 {
+		return nil, fmt.Errorf("invalid wrapped key length %d", len(wrapped))
+	}
+
+	n := len(wrapped)/8 - 1
+	a := make([]byte, 8)
+	buf := make([]byte, 16)
+	var r []byte
+	if n == 1 {
+		// A single block is encrypted directly
+		block.Decrypt(buf, wrapped)
+		copy(a, buf[:8])
+		r = append([]byte{}, buf[8:]...)
+	} else {
+		copy(a, wrapped[:8])
+		r = append([]byte{}, wrapped[8:]...)
+		for j := 5; j >= 0; j-- {
+			for i := n; i >= 1; i-- {
+				t := uint64(n*j + i)
+				for k := 0; k < 8; k++ {
+					a[7-k] ^= byte(t >> uint(8*k))
+				}
+				copy(buf[:8], a)
+				copy(buf[8:], r[(i-1)*8:i*8])
+				block.Decrypt(buf, buf)
+				copy(a, buf[:8])
+				copy(r[(i-1)*8:i*8], buf[8:])
+			}
+		}
+	}
+
+	// Check the integrity of the result
+	if subtle.ConstantTimeCompare(a[:4], kwpIV) != 1 {
+		return nil, fmt.Errorf("integrity check failed")
+	}
+	mli := int(binary.BigEndian.Uint32(a[4:]))
+	if mli <= 8*(n-1) || mli > 8*n {
+		return nil, fmt.Errorf("integrity check failed")
+	}
+	if !bytes.Equal(r[mli:], make([]byte, len(r)-mli)) {
+		return nil, fmt.Errorf("integrity check failed")
+	}
+
+	return r[:mli], nil
+}
+
+const pathImportHelpSyn = `Import externally generated key material`
+
+const pathImportHelpDesc = `
+This path creates the named key with externally generated key material as
+its first version. The key material must be wrapped under the public key
+returned by "wrapping_key": an ephemeral 256-bit AES key is encrypted with
+RSA-OAEP, and the key material is wrapped with it using the AES key wrap with
+padding algorithm of RFC 5649. The ciphertext is the concatenation of both.
+
+Symmetric keys are imported as raw bytes, 64 for aes256-cbc-hmac-sha512 and
+32 for the other types; asymmetric keys as PKCS#8 DER private keys. Imported keys cannot be rotated unless "allow_rotation" is set.
+`
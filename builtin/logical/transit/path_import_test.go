@@ -0,0 +1,236 @@
+package transit
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestTransit_KWP(t *testing.T) {
+	// The test vectors of RFC 5649
+	kek, _ := hex.DecodeString("5840df6e29b02af1ab493b705bf16ea1ae8338f4dcc176a8")
+	for _, tc := range []struct {
+		key     string
+		wrapped string
+	}{
+		{"c37b7e6492584340bed12207808941155068f738", "138bdeaa9b8fa7fc61f97742e72248ee5ae6ae5360d1ae6a5f54f373fa543b6a"},
+		{"466f7250617369", "afbeb0f07dfbf5419200f2ccb50bb24f"},
+	} {
+		key, _ := hex.DecodeString(tc.key)
+		wrapped, _ := hex.DecodeString(tc.wrapped)
+		result, err := kwpWrap(kek, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(result, wrapped) {
+			t.Fatalf("bad: wrapped: %x", result)
+		}
+		result, err = kwpUnwrap(kek, wrapped)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(result, key) {
+			t.Fatalf("bad: unwrapped: %x", result)
+		}
+
+		wrapped[len(wrapped)-1] ^= 1
+		if _, err := kwpUnwrap(kek, wrapped); err == nil {
+			t.Fatal("expected an error")
+		}
+	}
+}
+
+func TestTransit_Import(t *testing.T) {
+	b, storage := createBackendWithStorage(t)
+
+	request := func(op logical.Operation, path string, data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Operation: op,
+			Path:      path,
+			Storage:   storage,
+			Data:      data,
+		})
+	}
+	doReq := func(op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		resp, err := request(op, path, data)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: path: %s, err: %v, resp: %#v", path, err, resp)
+		}
+		return resp
+	}
+	expectError := func(path string, data map[string]interface{}, message string) {
+		resp, err := request(logical.UpdateOperation, path, data)
+		if err == nil || resp == nil || !resp.IsError() || !strings.Contains(resp.Error().Error(), message) {
+			t.Fatalf("expected an error: path: %s, err: %v, resp: %#v", path, err, resp)
+		}
+	}
+
+	resp := doReq(logical.ReadOperation, "wrapping_key", nil)
+	block, _ := pem.Decode([]byte(resp.Data["public_key"].(string)))
+	if block == nil {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrappingKey := parsed.(*rsa.PublicKey)
+	if wrappingKey.N.BitLen() != 4096 {
+		t.Fatalf("bad: %d", wrappingKey.N.BitLen())
+	}
+
+	// The wrapping key is stable
+	b.invalidate(context.Background(), "wrapping_key")
+	if resp := doReq(logical.ReadOperation, "wrapping_key", nil); resp.Data["public_key"] != string(pem.EncodeToMemory(block)) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	wrap := func(key []byte) string {
+		ephemeralKey := make([]byte, 32)
+		if _, err := rand.Read(ephemeralKey); err != nil {
+			t.Fatal(err)
+		}
+		wrappedKey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, wrappingKey, ephemeralKey, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wrappedMaterial, err := kwpWrap(ephemeralKey, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.StdEncoding.EncodeToString(append(wrappedKey, wrappedMaterial...))
+	}
+	marshalPKCS8 := func(key interface{}) []byte {
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return der
+	}
+
+	// AES keys
+	aesKey := make([]byte, 32)
+	if _, err := rand.Read(aesKey); err != nil {
+		t.Fatal(err)
+	}
+	doReq(logical.UpdateOperation, "keys/aes/import", map[string]interface{}{
+		"ciphertext": wrap(aesKey),
+		"exportable": true,
+	})
+	resp = doReq(logical.ReadOperation, "export/encryption-key/aes/1", nil)
+	if resp.Data["keys"].(map[string]string)["1"] != base64.StdEncoding.EncodeToString(aesKey) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	resp = doReq(logical.ReadOperation, "keys/aes", nil)
+	if resp.Data["imported_key"] != true || resp.Data["imported_key_allow_rotation"] != false || resp.Data["latest_version"] != 1 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	resp = doReq(logical.UpdateOperation, "encrypt/aes", map[string]interface{}{
+		"plaintext": base64.StdEncoding.EncodeToString([]byte(testPlaintext)),
+	})
+	resp = doReq(logical.UpdateOperation, "decrypt/aes", map[string]interface{}{
+		"ciphertext": resp.Data["ciphertext"],
+	})
+	if resp.Data["plaintext"] != base64.StdEncoding.EncodeToString([]byte(testPlaintext)) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	expectError("keys/aes/rotate", nil, "does not allow rotation")
+	expectError("keys/aes/import", map[string]interface{}{"ciphertext": wrap(aesKey)}, "already exists")
+
+	doReq(logical.UpdateOperation, "keys/rotatable/import", map[string]interface{}{
+		"ciphertext":     wrap(aesKey),
+		"allow_rotation": true,
+	})
+	doReq(logical.UpdateOperation, "keys/rotatable/rotate", nil)
+	if resp := doReq(logical.ReadOperation, "keys/rotatable", nil); resp.Data["latest_version"] != 2 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// ECDSA keys
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doReq(logical.UpdateOperation, "keys/ec/import", map[string]interface{}{
+		"ciphertext": wrap(marshalPKCS8(ecKey)),
+		"type":       "ecdsa-p256",
+	})
+	input := []byte(testPlaintext)
+	resp = doReq(logical.UpdateOperation, "sign/ec", map[string]interface{}{
+		"input": base64.StdEncoding.EncodeToString(input),
+	})
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(resp.Data["signature"].(string), "vault:v1:"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256(input)
+	if !ecdsa.VerifyASN1(&ecKey.PublicKey, digest[:], sig) {
+		t.Fatal("the signature does not verify with the imported key")
+	}
+
+	// Ed25519 keys
+	edPub, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doReq(logical.UpdateOperation, "keys/ed/import", map[string]interface{}{
+		"ciphertext": wrap(marshalPKCS8(edKey)),
+		"type":       "ed25519",
+	})
+	resp = doReq(logical.UpdateOperation, "sign/ed", map[string]interface{}{
+		"input": base64.StdEncoding.EncodeToString(input),
+	})
+	sig, err = base64.StdEncoding.DecodeString(strings.TrimPrefix(resp.Data["signature"].(string), "vault:v1:"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ed25519.Verify(edPub, input, sig) {
+		t.Fatal("the signature does not verify with the imported key")
+	}
+
+	// RSA keys
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doReq(logical.UpdateOperation, "keys/rsa/import", map[string]interface{}{
+		"ciphertext": wrap(marshalPKCS8(rsaKey)),
+		"type":       "rsa-2048",
+	})
+	resp = doReq(logical.UpdateOperation, "encrypt/rsa", map[string]interface{}{
+		"plaintext": base64.StdEncoding.EncodeToString(input),
+	})
+	ciphertext, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(resp.Data["ciphertext"].(string), "vault:v1:"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plaintext, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, rsaKey, ciphertext, nil); err != nil || !bytes.Equal(plaintext, input) {
+		t.Fatalf("bad: plaintext: %q, err: %v", plaintext, err)
+	}
+
+	// Invalid imports
+	expectError("keys/bad/import", map[string]interface{}{"ciphertext": wrap(aesKey[:16])}, "must be 32 bytes")
+	expectError("keys/bad/import", map[string]interface{}{"ciphertext": wrap(marshalPKCS8(ecKey)), "type": "rsa-2048"}, "not a rsa-2048 key")
+	expectError("keys/bad/import", map[string]interface{}{"ciphertext": wrap(marshalPKCS8(rsaKey)), "type": "rsa-4096"}, "not a rsa-4096 key")
+	expectError("keys/bad/import", map[string]interface{}{"ciphertext": wrap([]byte("bad")), "type": "ed25519"}, "PKCS#8")
+	expectError("keys/bad/import", map[string]interface{}{"ciphertext": wrap(marshalPKCS8(ecKey)), "type": "ecdsa-p256", "derived": true}, "not supported")
+	expectError("keys/bad/import", map[string]interface{}{"ciphertext": wrap(aesKey), "hash_function": "SHA384"}, "ephemeral key")
+	expectError("keys/bad/import", map[string]interface{}{"ciphertext": wrap(aesKey), "hash_function": "MD5"}, "unsupported hash function")
+	expectError("keys/bad/import", map[string]interface{}{"ciphertext": "bad"}, "base64")
+	if resp := doReq(logical.ReadOperation, "keys/bad", nil); resp != nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"golang.org/x/crypto/ed25519"
 
 	"github.com/fatih/structs"
+	"github.com/hashicorp/vault/helper/fips"
 	"github.com/hashicorp/vault/helper/keysutil"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
@@ -44,9 +45,10 @@ func (b *backend) pathKeys() *framework.Path {
 				Type:    framework.TypeString,
 				Default: "aes256-gcm96",
 				Description: `
-The type of key to create. Currently, "aes256-gcm96" (symmetric), "ecdsa-p256"
-(asymmetric), 'ed25519' (asymmetric), 'rsa-2048' (asymmetric), 'rsa-4096'
-(asymmetric) are supported.  Defaults to "aes256-gcm96".
+The type of key to create. Currently, "aes256-gcm96" (symmetric),
+"chacha20-poly1305" (symmetric), "aes256-cbc-hmac-sha512" (symmetric),
+"ecdsa-p256" (asymmetric), 'ed25519' (asymmetric), 'rsa-2048' (asymmetric),
+'rsa-4096' (asymmetric) are supported.  Defaults to "aes256-gcm96".
 `,
 			},
 
@@ -73,6 +75,14 @@ given context. Failing to do so will severely
 impact the ciphertext's security.`,
 			},
 
+			"convergent_version": &framework.FieldSchema{
+				Type: framework.TypeInt,
+				Description: `The version of convergent encryption, either 2
+or 3. Version 3 derives the nonce with a sub-key
+of the per-context key rather than with the
+context alone. Defaults to 2.`,
+			},
+
 			"exportable": &framework.FieldSchema{
 				Type: framework.TypeBool,
 				Description: `Enables keys to be exportable.
@@ -80,6 +90,13 @@ This allows for all the valid keys
 in the key ring to be exported.`,
 			},
 
+			"wrapped_export_only": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `Only allows exportable keys to be exported
+wrapped under a public key. Once set, this
+cannot be disabled.`,
+			},
+
 			"allow_plaintext_backup": &framework.FieldSchema{
 				Type: framework.TypeBool,
 				Description: `Enables taking a backup of the named
@@ -87,6 +104,20 @@ key in plaintext format. Once set,
 this cannot be disabled.`,
 			},
 
+			"auto_rotate_period": &framework.FieldSchema{
+				Type: framework.TypeDurationSecond,
+				Description: `The period after which the latest version is
+rotated automatically, at least one hour. Zero
+disables automatic rotation.`,
+			},
+
+			"allowed_operations": &framework.FieldSchema{
+				Type: framework.TypeCommaStringSlice,
+				Description: `The operations the key can be used for, among
+encrypt, decrypt, sign, verify, hmac, datakey,
+cmac and derive. Defaults to all operations.`,
+			},
+
 			"context": &framework.FieldSchema{
 				Type: framework.TypeString,
 				Description: `Base64 encoded context for key derivation.
@@ -120,36 +151,55 @@ func (b *backend) pathPolicyWrite(ctx context.Context, req *logical.Request, d *
 	name := d.Get("name").(string)
 	derived := d.Get("derived").(bool)
 	convergent := d.Get("convergent_encryption").(bool)
+	convergentVersion := d.Get("convergent_version").(int)
 	keyType := d.Get("type").(string)
 	exportable := d.Get("exportable").(bool)
+	wrappedExportOnly := d.Get("wrapped_export_only").(bool)
 	allowPlaintextBackup := d.Get("allow_plaintext_backup").(bool)
+	autoRotatePeriod := time.Duration(d.Get("auto_rotate_period").(int)) * time.Second
+	allowedOperations := d.Get("allowed_operations").([]string)
 
 	if !derived && convergent {
 		return logical.ErrorResponse("convergent encryption requires derivation to be enabled"), nil
 	}
+	switch {
+	case convergentVersion != 0 && convergentVersion != 2 && convergentVersion != 3:
+		return logical.ErrorResponse(fmt.Sprintf("unsupported convergent encryption version %d", convergentVersion)), logical.ErrInvalidRequest
+	case convergentVersion != 0 && !convergent:
+		return logical.ErrorResponse("a convergent encryption version requires convergent encryption to be enabled"), logical.ErrInvalidRequest
+	}
+	if wrappedExportOnly && !exportable {
+		return logical.ErrorResponse("wrapped-only export requires the key to be exportable"), logical.ErrInvalidRequest
+	}
+	if err := validateAutoRotatePeriod(autoRotatePeriod); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	if err := keysutil.ValidateKeyOperations(allowedOperations); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
 
 	polReq := keysutil.PolicyRequest{
 		Storage:              req.Storage,
 		Name:                 name,
 		Derived:              derived,
 		Convergent:           convergent,
+		ConvergentVersion:    convergentVersion,
 		Exportable:           exportable,
+		WrappedExportOnly:    wrappedExportOnly,
 		AllowPlaintextBackup: allowPlaintextBackup,
+		AutoRotatePeriod:     autoRotatePeriod,
+		AllowedOperations:    allowedOperations,
 	}
-	switch keyType {
-	case "aes256-gcm96":
-		polReq.KeyType = keysutil.KeyType_AES256_GCM96
-	case "ecdsa-p256":
-		polReq.KeyType = keysutil.KeyType_ECDSA_P256
-	case "ed25519":
-		polReq.KeyType = keysutil.KeyType_ED25519
-	case "rsa-2048":
-		polReq.KeyType = keysutil.KeyType_RSA2048
-	case "rsa-4096":
-		polReq.KeyType = keysutil.KeyType_RSA4096
-	default:
+	var ok bool
+	polReq.KeyType, ok = parseKeyType(keyType)
+	if !ok {
 		return logical.ErrorResponse(fmt.Sprintf("unknown key type %v", keyType)), logical.ErrInvalidRequest
 	}
+	if err := checkFIPSKeyType(polReq.KeyType); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	polReq.RandReader = b.GetRandomReader()
 
 	p, lock, upserted, err := b.lm.GetPolicyUpsert(ctx, polReq)
 	if lock != nil {
@@ -170,6 +220,50 @@ func (b *backend) pathPolicyWrite(ctx context.Context, req *logical.Request, d *
 	return nil, nil
 }
 
+// parseKeyType returns the key type of the given API name
+func parseKeyType(keyType string) (keysutil.KeyType, bool) {
+	switch keyType {
+	case "aes256-gcm96":
+		return keysutil.KeyType_AES256_GCM96, true
+	case "chacha20-poly1305":
+		return keysutil.KeyType_ChaCha20_Poly1305, true
+	case "aes256-cbc-hmac-sha512":
+		return keysutil.KeyType_AES256_CBC_HMAC_SHA512, true
+	case "ecdsa-p256":
+		return keysutil.KeyType_ECDSA_P256, true
+	case "ed25519":
+		return keysutil.KeyType_ED25519, true
+	case "rsa-2048":
+		return keysutil.KeyType_RSA2048, true
+	case "rsa-4096":
+		return keysutil.KeyType_RSA4096, true
+	default:
+		return 0, false
+	}
+}
+
+// checkFIPSKeyType returns an error if Vault runs in FIPS mode and the key
+// type is not FIPS approved
+func checkFIPSKeyType(keyType keysutil.KeyType) error {
+	switch keyType {
+	case keysutil.KeyType_ED25519, keysutil.KeyType_ChaCha20_Poly1305:
+		return fips.CheckApproved(fmt.Sprintf("key type %s", keyType), false)
+	}
+	return nil
+}
+
+// checkOperation returns an error if the key does not allow the operation,
+// or if its type cannot be used in FIPS mode
+func checkOperation(p *keysutil.Policy, operation string) error {
+	if err := checkFIPSKeyType(p.Type); err != nil {
+		return err
+	}
+	if !p.OperationAllowed(operation) {
+		return fmt.Errorf("key does not allow the %s operation", operation)
+	}
+	return nil
+}
+
 // Built-in helper type for returning asymmetric keys
 type asymKey struct {
 	Name         string    `json:"name" structs:"name" mapstructure:"name"`
@@ -202,7 +296,10 @@ func (b *backend) pathPolicyRead(ctx context.Context, req *logical.Request, d *f
 			"min_encryption_version": p.MinEncryptionVersion,
 			"latest_version":         p.LatestVersion,
 			"exportable":             p.Exportable,
+			"wrapped_export_only":    p.WrappedExportOnly,
 			"allow_plaintext_backup": p.AllowPlaintextBackup,
+			"imported_key":           p.Imported,
+			"auto_rotate_period":     int64(p.AutoRotatePeriod.Seconds()),
 			"supports_encryption":    p.Type.EncryptionSupported(),
 			"supports_decryption":    p.Type.DecryptionSupported(),
 			"supports_signing":       p.Type.SigningSupported(),
@@ -210,6 +307,14 @@ func (b *backend) pathPolicyRead(ctx context.Context, req *logical.Request, d *f
 		},
 	}
 
+	if len(p.AllowedOperations) != 0 {
+		resp.Data["allowed_operations"] = p.AllowedOperations
+	}
+
+	if p.Imported {
+		resp.Data["imported_key_allow_rotation"] = p.AllowImportedKeyRotation
+	}
+
 	if p.BackupInfo != nil {
 		resp.Data["backup_info"] = map[string]interface{}{
 			"time":    p.BackupInfo.Time,
@@ -247,7 +352,7 @@ func (b *backend) pathPolicyRead(ctx context.Context, req *logical.Request, d *f
 	}
 
 	switch p.Type {
-	case keysutil.KeyType_AES256_GCM96:
+	case keysutil.KeyType_AES256_GCM96, keysutil.KeyType_ChaCha20_Poly1305, keysutil.KeyType_AES256_CBC_HMAC_SHA512:
 		retKeys := map[string]int64{}
 		for k, v := range p.Keys {
 			retKeys[k] = v.DeprecatedCreationTime
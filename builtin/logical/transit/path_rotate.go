@@ -2,7 +2,10 @@ package transit
 
 import (
 	"context"
+	"fmt"
+	"time"
 
+	multierror "github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
 )
@@ -41,12 +44,73 @@ func (b *backend) pathRotateWrite(ctx context.Context, req *logical.Request, d *
 		return logical.ErrorResponse("key not found"), logical.ErrInvalidRequest
 	}
 
+	if p.Imported && !p.AllowImportedKeyRotation {
+		return logical.ErrorResponse("imported key does not allow rotation"), logical.ErrInvalidRequest
+	}
+
 	// Rotate the policy
-	err = p.Rotate(ctx, req.Storage)
+	err = p.Rotate(ctx, req.Storage, b.GetRandomReader())
 
 	return nil, err
 }
 
+// minAutoRotatePeriod is the shortest period keys can be rotated
+// automatically with
+const minAutoRotatePeriod = time.Hour
+
+func validateAutoRotatePeriod(period time.Duration) error {
+	if period != 0 && period < minAutoRotatePeriod {
+		return fmt.Errorf("auto_rotate_period must be zero or at least %s", minAutoRotatePeriod)
+	}
+	return nil
+}
+
+// autoRotateKeys rotates the keys whose latest version is older than their
+// automatic rotation period
+func (b *backend) autoRotateKeys(ctx context.Context, req *logical.Request) error {
+	names, err := req.Storage.List(ctx, "policy/")
+	if err != nil {
+		return err
+	}
+
+	var mErr *multierror.Error
+	for _, name := range names {
+		if err := b.autoRotateKey(ctx, req.Storage, name); err != nil {
+			mErr = multierror.Append(mErr, fmt.Errorf("failed to rotate key %q: %v", name, err))
+		}
+	}
+	return mErr.ErrorOrNil()
+}
+
+func (b *backend) autoRotateKey(ctx context.Context, s logical.Storage, name string) error {
+	p, lock, err := b.lm.GetPolicyExclusive(ctx, s, name)
+	if lock != nil {
+		defer lock.Unlock()
+	}
+	if err != nil {
+		return err
+	}
+	if p == nil || !p.AutoRotationDue(time.Now()) {
+		return nil
+	}
+
+	if err := p.Rotate(ctx, s, b.GetRandomReader()); err != nil {
+		return err
+	}
+	b.Logger().Info("transit: rotated key automatically", "name", name, "version", p.LatestVersion)
+
+	// The rotation is audited as a request to the rotate endpoint of the key
+	return b.System().AuditInternalRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "keys/" + name + "/rotate",
+	}, &logical.Response{
+		Data: map[string]interface{}{
+			"auto_rotated":   true,
+			"latest_version": p.LatestVersion,
+		},
+	})
+}
+
 const pathRotateHelpSyn = `Rotate named encryption key`
 
 const pathRotateHelpDesc = `
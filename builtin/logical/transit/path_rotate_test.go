@@ -0,0 +1,161 @@
+package transit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestTransit_AutoRotate(t *testing.T) {
+	b, storage := createBackendWithStorage(t)
+
+	request := func(op logical.Operation, path string, data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Operation: op,
+			Path:      path,
+			Storage:   storage,
+			Data:      data,
+		})
+	}
+	doReq := func(op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		resp, err := request(op, path, data)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: path: %s, err: %v, resp: %#v", path, err, resp)
+		}
+		return resp
+	}
+	latestVersion := func(name string) int {
+		return doReq(logical.ReadOperation, "keys/"+name, nil).Data["latest_version"].(int)
+	}
+	// age makes the latest version of the key older by the given duration
+	age := func(name string, d time.Duration) {
+		p, lock, err := b.lm.GetPolicyExclusive(context.Background(), storage, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer lock.Unlock()
+		for version, entry := range p.Keys {
+			entry.CreationTime = entry.CreationTime.Add(-d)
+			entry.DeprecatedCreationTime = entry.CreationTime.Unix()
+			p.Keys[version] = entry
+		}
+		if err := p.Persist(context.Background(), storage); err != nil {
+			t.Fatal(err)
+		}
+	}
+	periodic := func() {
+		if err := b.periodicFunc(context.Background(), &logical.Request{Storage: storage}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, data := range []map[string]interface{}{
+		{"auto_rotate_period": "10m"},
+		{"auto_rotate_period": -1},
+	} {
+		if resp, err := request(logical.UpdateOperation, "keys/bad", data); err == nil && (resp == nil || !resp.IsError()) {
+			t.Fatalf("expected an error: data: %v", data)
+		}
+	}
+
+	doReq(logical.UpdateOperation, "keys/rotated", map[string]interface{}{"auto_rotate_period": "24h"})
+	doReq(logical.UpdateOperation, "keys/manual", nil)
+	if resp := doReq(logical.ReadOperation, "keys/rotated", nil); resp.Data["auto_rotate_period"] != int64(86400) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Keys are rotated once their latest version is older than the period
+	periodic()
+	age("rotated", 23*time.Hour)
+	age("manual", 48*time.Hour)
+	periodic()
+	if latestVersion("rotated") != 1 || latestVersion("manual") != 1 {
+		t.Fatal("keys were rotated before their period")
+	}
+	age("rotated", 2*time.Hour)
+	periodic()
+	periodic()
+	if latestVersion("rotated") != 2 || latestVersion("manual") != 1 {
+		t.Fatalf("bad: rotated: %d, manual: %d", latestVersion("rotated"), latestVersion("manual"))
+	}
+
+	// The period can be changed and disabled through the key configuration
+	doReq(logical.UpdateOperation, "keys/manual/config", map[string]interface{}{"auto_rotate_period": "1h"})
+	doReq(logical.UpdateOperation, "keys/rotated/config", map[string]interface{}{"auto_rotate_period": 0})
+	age("rotated", 48*time.Hour)
+	periodic()
+	if latestVersion("rotated") != 2 || latestVersion("manual") != 2 {
+		t.Fatalf("bad: rotated: %d, manual: %d", latestVersion("rotated"), latestVersion("manual"))
+	}
+	if resp, err := request(logical.UpdateOperation, "keys/manual/config", map[string]interface{}{"auto_rotate_period": "30m"}); err == nil && (resp == nil || !resp.IsError()) {
+		t.Fatal("expected an error")
+	}
+}
+
+// auditSystemView records the internal requests audited by the backend
+type auditSystemView struct {
+	logical.StaticSystemView
+	requests  []*logical.Request
+	responses []*logical.Response
+}
+
+func (s *auditSystemView) AuditInternalRequest(_ context.Context, req *logical.Request, resp *logical.Response) error {
+	s.requests = append(s.requests, req)
+	s.responses = append(s.responses, resp)
+	return nil
+}
+
+func TestTransit_AutoRotateAudit(t *testing.T) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	sysView := &auditSystemView{StaticSystemView: *logical.TestSystemView()}
+	config.System = sysView
+	b := Backend(config)
+	if err := b.Backend.Setup(context.Background(), config); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	if _, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "keys/foo",
+		Storage:   config.StorageView,
+		Data:      map[string]interface{}{"auto_rotate_period": "1h"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	p, lock, err := b.lm.GetPolicyExclusive(ctx, config.StorageView, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := p.Keys["1"]
+	entry.CreationTime = entry.CreationTime.Add(-2 * time.Hour)
+	p.Keys["1"] = entry
+	if err := p.Persist(ctx, config.StorageView); err != nil {
+		t.Fatal(err)
+	}
+	lock.Unlock()
+
+	if err := b.periodicFunc(ctx, &logical.Request{Storage: config.StorageView}); err != nil {
+		t.Fatal(err)
+	}
+	if len(sysView.requests) != 1 {
+		t.Fatalf("expected one audited rotation, got %d", len(sysView.requests))
+	}
+	if req := sysView.requests[0]; req.Operation != logical.UpdateOperation || req.Path != "keys/foo/rotate" {
+		t.Fatalf("bad: %#v", req)
+	}
+	if resp := sysView.responses[0]; resp.Data["latest_version"] != 2 || resp.Data["auto_rotated"] != true {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Keys which aren't due aren't rotated nor audited
+	if err := b.periodicFunc(ctx, &logical.Request{Storage: config.StorageView}); err != nil {
+		t.Fatal(err)
+	}
+	if len(sysView.requests) != 1 {
+		t.Fatalf("bad: %d", len(sysView.requests))
+	}
+}
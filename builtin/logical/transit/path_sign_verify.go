@@ -2,15 +2,14 @@ package transit
 
 import (
 	"context"
-	"crypto/sha256"
-	"crypto/sha512"
 	"encoding/base64"
 	"fmt"
-	"hash"
 
 	"github.com/hashicorp/vault/helper/errutil"
+	"github.com/hashicorp/vault/helper/keysutil"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
+	"github.com/mitchellh/mapstructure"
 )
 
 func (b *backend) pathSign() *framework.Path {
@@ -137,19 +136,96 @@ Defaults to "sha2-256". Not valid for all key types.`,
 	}
 }
 
+// batchRequestSignItem represents a request item for batch signing and
+// verification
+type batchRequestSignItem struct {
+	// Input is the base64-encoded input data
+	Input string `json:"input" structs:"input" mapstructure:"input"`
+
+	// Context for key derivation. This is required for derived keys.
+	Context string `json:"context" structs:"context" mapstructure:"context"`
+
+	// Signature to verify
+	Signature string `json:"signature" structs:"signature" mapstructure:"signature"`
+
+	// HMAC to verify
+	HMAC string `json:"hmac" structs:"hmac" mapstructure:"hmac"`
+}
+
+// batchResponseSignItem represents a response item for batch signing
+type batchResponseSignItem struct {
+	// Signature of the input present in the corresponding batch request item
+	Signature string `json:"signature,omitempty" structs:"signature" mapstructure:"signature"`
+
+	// PublicKey is the public key of derived ed25519 keys
+	PublicKey []byte `json:"public_key,omitempty" structs:"public_key" mapstructure:"public_key"`
+
+	// Error, if set represents a failure encountered while signing a
+	// corresponding batch request item
+	Error string `json:"error,omitempty" structs:"error" mapstructure:"error"`
+}
+
+// batchResponseVerifyItem represents a response item for batch verification
+type batchResponseVerifyItem struct {
+	// Valid is set if the signature or HMAC of the corresponding batch
+	// request item is valid
+	Valid bool `json:"valid" structs:"valid" mapstructure:"valid"`
+
+	// Error, if set represents a failure encountered while verifying a
+	// corresponding batch request item
+	Error string `json:"error,omitempty" structs:"error" mapstructure:"error"`
+}
+
+// parseBatchSignInput returns the batch input items of the request, or the
+// given single item if no batch input was given
+func parseBatchSignInput(d *framework.FieldData, single batchRequestSignItem) ([]batchRequestSignItem, *logical.Response, error) {
+	batchInputRaw := d.Raw["batch_input"]
+	if batchInputRaw == nil {
+		return []batchRequestSignItem{single}, nil, nil
+	}
+
+	var batchInputItems []batchRequestSignItem
+	if err := mapstructure.Decode(batchInputRaw, &batchInputItems); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse batch input: %v", err)
+	}
+	if len(batchInputItems) == 0 {
+		return nil, logical.ErrorResponse("missing batch input to process"), logical.ErrInvalidRequest
+	}
+	return batchInputItems, nil, nil
+}
+
+// decode returns the base64-decoded input and context of the item
+func (item batchRequestSignItem) decode() ([]byte, []byte, error) {
+	input, err := base64.StdEncoding.DecodeString(item.Input)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to decode input as base64: %s", err)
+	}
+
+	var context []byte
+	if len(item.Context) != 0 {
+		context, err = base64.StdEncoding.DecodeString(item.Context)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to base64-decode context")
+		}
+	}
+	return input, context, nil
+}
+
 func (b *backend) pathSignWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
 	name := d.Get("name").(string)
 	ver := d.Get("key_version").(int)
-	inputB64 := d.Get("input").(string)
 	algorithm := d.Get("urlalgorithm").(string)
 	if algorithm == "" {
 		algorithm = d.Get("algorithm").(string)
 	}
 	prehashed := d.Get("prehashed").(bool)
 
-	input, err := base64.StdEncoding.DecodeString(inputB64)
-	if err != nil {
-		return logical.ErrorResponse(fmt.Sprintf("unable to decode input as base64: %s", err)), logical.ErrInvalidRequest
+	batchInputItems, resp, err := parseBatchSignInput(d, batchRequestSignItem{
+		Input:   d.Get("input").(string),
+		Context: d.Get("context").(string),
+	})
+	if resp != nil || err != nil {
+		return resp, err
 	}
 
 	// Get the policy
@@ -164,85 +240,90 @@ func (b *backend) pathSignWrite(ctx context.Context, req *logical.Request, d *fr
 		return logical.ErrorResponse("encryption key not found"), logical.ErrInvalidRequest
 	}
 
+	if err := checkOperation(p, keysutil.KeyOperationSign); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
 	if !p.Type.SigningSupported() {
 		return logical.ErrorResponse(fmt.Sprintf("key type %v does not support signing", p.Type)), logical.ErrInvalidRequest
 	}
 
-	contextRaw := d.Get("context").(string)
-	var context []byte
-	if len(contextRaw) != 0 {
-		context, err = base64.StdEncoding.DecodeString(contextRaw)
+	hashInput := p.Type.HashSignatureInput() && !prehashed
+	hf := hashFunction(algorithm)
+	if hashInput && hf == nil {
+		return logical.ErrorResponse(fmt.Sprintf("unsupported algorithm %s", algorithm)), nil
+	}
+
+	batchResponseItems := make([]batchResponseSignItem, len(batchInputItems))
+	for i, item := range batchInputItems {
+		input, context, err := item.decode()
 		if err != nil {
-			return logical.ErrorResponse("failed to base64-decode context"), logical.ErrInvalidRequest
+			batchResponseItems[i].Error = err.Error()
+			continue
 		}
-	}
 
-	if p.Type.HashSignatureInput() && !prehashed {
-		var hf hash.Hash
-		switch algorithm {
-		case "sha2-224":
-			hf = sha256.New224()
-		case "sha2-256":
-			hf = sha256.New()
-		case "sha2-384":
-			hf = sha512.New384()
-		case "sha2-512":
-			hf = sha512.New()
-		default:
-			return logical.ErrorResponse(fmt.Sprintf("unsupported algorithm %s", algorithm)), nil
+		if hashInput {
+			h := hf()
+			h.Write(input)
+			input = h.Sum(nil)
 		}
-		hf.Write(input)
-		input = hf.Sum(nil)
-	}
 
-	sig, err := p.Sign(ver, context, input, algorithm)
-	if err != nil {
-		return nil, err
-	}
-	if sig == nil {
-		return nil, fmt.Errorf("signature could not be computed")
+		sig, err := p.Sign(ver, context, input, algorithm)
+		if err != nil {
+			switch err.(type) {
+			case errutil.UserError:
+				batchResponseItems[i].Error = err.Error()
+				continue
+			default:
+				return nil, err
+			}
+		}
+		if sig == nil {
+			return nil, fmt.Errorf("signature could not be computed")
+		}
+
+		batchResponseItems[i].Signature = sig.Signature
+		batchResponseItems[i].PublicKey = sig.PublicKey
 	}
 
 	// Generate the response
-	resp := &logical.Response{
-		Data: map[string]interface{}{
-			"signature": sig.Signature,
-		},
+	resp = &logical.Response{}
+	if d.Raw["batch_input"] != nil {
+		resp.Data = map[string]interface{}{
+			"batch_results": batchResponseItems,
+		}
+		return resp, nil
 	}
 
-	if len(sig.PublicKey) > 0 {
-		resp.Data["public_key"] = sig.PublicKey
+	if batchResponseItems[0].Error != "" {
+		return logical.ErrorResponse(batchResponseItems[0].Error), logical.ErrInvalidRequest
+	}
+	resp.Data = map[string]interface{}{
+		"signature": batchResponseItems[0].Signature,
+	}
+	if len(batchResponseItems[0].PublicKey) > 0 {
+		resp.Data["public_key"] = batchResponseItems[0].PublicKey
 	}
 
 	return resp, nil
 }
 
 func (b *backend) pathVerifyWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
-
-	sig := d.Get("signature").(string)
-	hmac := d.Get("hmac").(string)
-	switch {
-	case sig != "" && hmac != "":
-		return logical.ErrorResponse("provide one of 'signature' or 'hmac'"), logical.ErrInvalidRequest
-
-	case sig == "" && hmac == "":
-		return logical.ErrorResponse("neither a 'signature' nor an 'hmac' were given to verify"), logical.ErrInvalidRequest
-
-	case hmac != "":
-		return b.pathHMACVerify(ctx, req, d, hmac)
-	}
-
 	name := d.Get("name").(string)
-	inputB64 := d.Get("input").(string)
 	algorithm := d.Get("urlalgorithm").(string)
 	if algorithm == "" {
 		algorithm = d.Get("algorithm").(string)
 	}
 	prehashed := d.Get("prehashed").(bool)
 
-	input, err := base64.StdEncoding.DecodeString(inputB64)
-	if err != nil {
-		return logical.ErrorResponse(fmt.Sprintf("unable to decode input as base64: %s", err)), logical.ErrInvalidRequest
+	batchInputItems, resp, err := parseBatchSignInput(d, batchRequestSignItem{
+		Input:     d.Get("input").(string),
+		Context:   d.Get("context").(string),
+		Signature: d.Get("signature").(string),
+		HMAC:      d.Get("hmac").(string),
+	})
+	if resp != nil || err != nil {
+		return resp, err
 	}
 
 	// Get the policy
@@ -257,54 +338,88 @@ func (b *backend) pathVerifyWrite(ctx context.Context, req *logical.Request, d *
 		return logical.ErrorResponse("encryption key not found"), logical.ErrInvalidRequest
 	}
 
-	if !p.Type.SigningSupported() {
-		return logical.ErrorResponse(fmt.Sprintf("key type %v does not support verification", p.Type)), logical.ErrInvalidRequest
-	}
+	hf := hashFunction(algorithm)
 
-	contextRaw := d.Get("context").(string)
-	var context []byte
-	if len(contextRaw) != 0 {
-		context, err = base64.StdEncoding.DecodeString(contextRaw)
+	batchResponseItems := make([]batchResponseVerifyItem, len(batchInputItems))
+	for i, item := range batchInputItems {
+		switch {
+		case item.Signature != "" && item.HMAC != "":
+			batchResponseItems[i].Error = "provide one of 'signature' or 'hmac'"
+			continue
+
+		case item.Signature == "" && item.HMAC == "":
+			batchResponseItems[i].Error = "neither a 'signature' nor an 'hmac' were given to verify"
+			continue
+
+		case item.Signature != "" && !p.Type.SigningSupported():
+			batchResponseItems[i].Error = fmt.Sprintf("key type %v does not support verification", p.Type)
+			continue
+		}
+
+		// Verifying HMACs is part of the hmac operation
+		operation := keysutil.KeyOperationVerify
+		if item.HMAC != "" {
+			operation = keysutil.KeyOperationHMAC
+		}
+		if err := checkOperation(p, operation); err != nil {
+			batchResponseItems[i].Error = err.Error()
+			continue
+		}
+
+		input, context, err := item.decode()
 		if err != nil {
-			return logical.ErrorResponse("failed to base64-decode context"), logical.ErrInvalidRequest
+			batchResponseItems[i].Error = err.Error()
+			continue
 		}
-	}
 
-	if p.Type.HashSignatureInput() && !prehashed {
-		var hf hash.Hash
-		switch algorithm {
-		case "sha2-224":
-			hf = sha256.New224()
-		case "sha2-256":
-			hf = sha256.New()
-		case "sha2-384":
-			hf = sha512.New384()
-		case "sha2-512":
-			hf = sha512.New()
+		var valid bool
+		switch {
+		case item.HMAC != "":
+			if hf == nil {
+				batchResponseItems[i].Error = fmt.Sprintf("unsupported algorithm %s", algorithm)
+				continue
+			}
+			valid, err = verifyHMAC(p, hf, input, item.HMAC)
+
 		default:
-			return logical.ErrorResponse(fmt.Sprintf("unsupported algorithm %s", algorithm)), nil
+			if p.Type.HashSignatureInput() && !prehashed {
+				if hf == nil {
+					batchResponseItems[i].Error = fmt.Sprintf("unsupported algorithm %s", algorithm)
+					continue
+				}
+				h := hf()
+				h.Write(input)
+				input = h.Sum(nil)
+			}
+			valid, err = p.VerifySignature(context, input, item.Signature, algorithm)
 		}
-		hf.Write(input)
-		input = hf.Sum(nil)
+		if err != nil {
+			switch err.(type) {
+			case errutil.UserError:
+				batchResponseItems[i].Error = err.Error()
+				continue
+			default:
+				return nil, err
+			}
+		}
+
+		batchResponseItems[i].Valid = valid
 	}
 
-	valid, err := p.VerifySignature(context, input, sig, algorithm)
-	if err != nil {
-		switch err.(type) {
-		case errutil.UserError:
-			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
-		case errutil.InternalError:
-			return nil, err
-		default:
-			return nil, err
+	// Generate the response
+	resp = &logical.Response{}
+	if d.Raw["batch_input"] != nil {
+		resp.Data = map[string]interface{}{
+			"batch_results": batchResponseItems,
 		}
+		return resp, nil
 	}
 
-	// Generate the response
-	resp := &logical.Response{
-		Data: map[string]interface{}{
-			"valid": valid,
-		},
+	if batchResponseItems[0].Error != "" {
+		return logical.ErrorResponse(batchResponseItems[0].Error), logical.ErrInvalidRequest
+	}
+	resp.Data = map[string]interface{}{
+		"valid": batchResponseItems[0].Valid,
 	}
 	return resp, nil
 }
@@ -312,10 +427,12 @@ func (b *backend) pathVerifyWrite(ctx context.Context, req *logical.Request, d *
 const pathSignHelpSyn = `Generate a signature for input data using the named key`
 
 const pathSignHelpDesc = `
-Generates a signature of the input data using the named key and the given hash algorithm.
+Generates a signature of the input data, or of each item of a batch of input
+data, using the named key and the given hash algorithm.
 `
 const pathVerifyHelpSyn = `Verify a signature or HMAC for input data created using the named key`
 
 const pathVerifyHelpDesc = `
-Verifies a signature or HMAC of the input data using the named key and the given hash algorithm.
+Verifies a signature or HMAC of the input data, or of each item of a batch of
+input data, using the named key and the given hash algorithm.
 `
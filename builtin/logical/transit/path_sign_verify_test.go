@@ -2,6 +2,7 @@ package transit
 
 import (
 	"context"
+	"crypto/rand"
 	"encoding/base64"
 	"strconv"
 	"strings"
@@ -189,11 +190,11 @@ func TestTransit_SignVerify_P256(t *testing.T) {
 	signRequest(req, true, "")
 
 	// Rotate and set min decryption version
-	err = p.Rotate(context.Background(), storage)
+	err = p.Rotate(context.Background(), storage, rand.Reader)
 	if err != nil {
 		t.Fatal(err)
 	}
-	err = p.Rotate(context.Background(), storage)
+	err = p.Rotate(context.Background(), storage, rand.Reader)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -383,11 +384,11 @@ func TestTransit_SignVerify_ED25519(t *testing.T) {
 	v1sig := sig
 
 	// Rotate and set min decryption version
-	err = fooP.Rotate(context.Background(), storage)
+	err = fooP.Rotate(context.Background(), storage, rand.Reader)
 	if err != nil {
 		t.Fatal(err)
 	}
-	err = fooP.Rotate(context.Background(), storage)
+	err = fooP.Rotate(context.Background(), storage, rand.Reader)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -395,11 +396,11 @@ func TestTransit_SignVerify_ED25519(t *testing.T) {
 	if err = fooP.Persist(context.Background(), storage); err != nil {
 		t.Fatal(err)
 	}
-	err = barP.Rotate(context.Background(), storage)
+	err = barP.Rotate(context.Background(), storage, rand.Reader)
 	if err != nil {
 		t.Fatal(err)
 	}
-	err = barP.Rotate(context.Background(), storage)
+	err = barP.Rotate(context.Background(), storage, rand.Reader)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -418,3 +419,91 @@ func TestTransit_SignVerify_ED25519(t *testing.T) {
 	verifyRequest(req, false, "bar", sig)
 	verifyRequest(req, true, "bar", v1sig)
 }
+
+func TestTransit_BatchSignVerify(t *testing.T) {
+	b, storage := createBackendWithStorage(t)
+
+	doReq := func(path string, data map[string]interface{}) *logical.Response {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Data:      data,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: path: %s, err: %v, resp: %#v", path, err, resp)
+		}
+		return resp
+	}
+
+	doReq("keys/ecdsa", map[string]interface{}{"type": "ecdsa-p256"})
+	doReq("keys/derived", map[string]interface{}{"type": "ed25519", "derived": true})
+
+	input := "dGhlIHF1aWNrIGJyb3duIGZveA=="
+	resp := doReq("sign/ecdsa/sha2-384", map[string]interface{}{
+		"batch_input": []interface{}{
+			map[string]interface{}{"input": input},
+			map[string]interface{}{"input": "Zm9vYmFy"},
+			map[string]interface{}{"input": "not base64"},
+		},
+	})
+	results := resp.Data["batch_results"].([]batchResponseSignItem)
+	if len(results) != 3 || results[0].Signature == "" || results[1].Signature == "" || results[2].Error == "" {
+		t.Fatalf("bad: %#v", results)
+	}
+	hmac := doReq("hmac/ecdsa/sha2-384", map[string]interface{}{"input": input}).Data["hmac"].(string)
+
+	// Signatures and HMACs can be verified in the same batch
+	resp = doReq("verify/ecdsa/sha2-384", map[string]interface{}{
+		"batch_input": []interface{}{
+			map[string]interface{}{"input": input, "signature": results[0].Signature},
+			map[string]interface{}{"input": input, "signature": results[1].Signature},
+			map[string]interface{}{"input": input, "hmac": hmac},
+			map[string]interface{}{"input": input, "signature": results[0].Signature, "hmac": hmac},
+		},
+	})
+	verifyResults := resp.Data["batch_results"].([]batchResponseVerifyItem)
+	if len(verifyResults) != 4 ||
+		!verifyResults[0].Valid || verifyResults[0].Error != "" ||
+		verifyResults[1].Valid || verifyResults[1].Error != "" ||
+		!verifyResults[2].Valid || verifyResults[2].Error != "" ||
+		verifyResults[3].Valid || verifyResults[3].Error == "" {
+		t.Fatalf("bad: %#v", verifyResults)
+	}
+	if resp := doReq("verify/ecdsa/sha2-384", map[string]interface{}{"input": input, "signature": results[0].Signature}); resp.Data["valid"] != true {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Each item of derived keys is signed with the key of its context
+	resp = doReq("sign/derived", map[string]interface{}{
+		"batch_input": []interface{}{
+			map[string]interface{}{"input": input, "context": "YWJjZA=="},
+			map[string]interface{}{"input": input, "context": "ZWZnaA=="},
+			map[string]interface{}{"input": input},
+		},
+	})
+	results = resp.Data["batch_results"].([]batchResponseSignItem)
+	if len(results) != 3 || results[2].Error == "" {
+		t.Fatalf("bad: %#v", results)
+	}
+	rawInput, _ := base64.StdEncoding.DecodeString(input)
+	for _, result := range results[:2] {
+		signature, _ := base64.StdEncoding.DecodeString(strings.Split(result.Signature, ":")[2])
+		if !ed25519.Verify(ed25519.PublicKey(result.PublicKey), rawInput, signature) {
+			t.Fatalf("bad: %#v", result)
+		}
+	}
+	if string(results[0].PublicKey) == string(results[1].PublicKey) {
+		t.Fatal("expected different public keys")
+	}
+	resp = doReq("verify/derived", map[string]interface{}{
+		"batch_input": []interface{}{
+			map[string]interface{}{"input": input, "context": "YWJjZA==", "signature": results[0].Signature},
+			map[string]interface{}{"input": input, "context": "YWJjZA==", "signature": results[1].Signature},
+		},
+	})
+	verifyResults = resp.Data["batch_results"].([]batchResponseVerifyItem)
+	if len(verifyResults) != 2 || !verifyResults[0].Valid || verifyResults[1].Valid {
+		t.Fatalf("bad: %#v", verifyResults)
+	}
+}
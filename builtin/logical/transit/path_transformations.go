@@ -0,0 +1,266 @@
+package transit
+
+import (
+	"context"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/hashicorp/vault/helper/fpe"
+	"github.com/hashicorp/vault/helper/keysutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+const (
+	transformationTypeFPE          = "fpe"
+	transformationTypeTokenization = "tokenization"
+)
+
+// transformation encodes values into values of the same format, either by
+// encrypting them with FF3-1 or by replacing them with random tokens
+type transformation struct {
+	Type     string `json:"type"`
+	Key      string `json:"key"`
+	Alphabet string `json:"alphabet"`
+}
+
+func (b *backend) pathListTransformations() *framework.Path {
+	return &framework.Path{
+		Pattern: "transformations/?$",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ListOperation: b.pathTransformationsList,
+		},
+
+		HelpSynopsis:    pathTransformationsHelpSyn,
+		HelpDescription: pathTransformationsHelpDesc,
+	}
+}
+
+func (b *backend) pathTransformations() *framework.Path {
+	return &framework.Path{
+		Pattern: "transformations/" + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the transformation",
+			},
+
+			"type": &framework.FieldSchema{
+				Type:    framework.TypeString,
+				Default: transformationTypeFPE,
+				Description: `The type of the transformation: "fpe" encrypts
+values with FF3-1, "tokenization" replaces them
+with random tokens stored by the backend.`,
+			},
+
+			"key": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `Name of the aes256-gcm96 key the transformation
+encrypts values with`,
+			},
+
+			"alphabet": &framework.FieldSchema{
+				Type:    framework.TypeString,
+				Default: "0123456789",
+				Description: `The characters of values which are transformed;
+other characters are kept in place. Defaults to
+the decimal digits.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathTransformationWrite,
+			logical.ReadOperation:   b.pathTransformationRead,
+			logical.DeleteOperation: b.pathTransformationDelete,
+		},
+
+		HelpSynopsis:    pathTransformationsHelpSyn,
+		HelpDescription: pathTransformationsHelpDesc,
+	}
+}
+
+func getTransformation(ctx context.Context, s logical.Storage, name string) (*transformation, error) {
+	entry, err := s.Get(ctx, "transformation/"+name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var result transformation
+	if err := entry.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// radix returns the radix of the numerals of the transformation
+func (t *transformation) radix() int {
+	return utf8.RuneCountInString(t.Alphabet)
+}
+
+// parse returns the numerals of the alphabet characters of the value
+func (t *transformation) parse(value string) []int {
+	indexes := make(map[rune]int, len(t.Alphabet))
+	for _, r := range t.Alphabet {
+		indexes[r] = len(indexes)
+	}
+
+	var numerals []int
+	for _, r := range value {
+		if index, ok := indexes[r]; ok {
+			numerals = append(numerals, index)
+		}
+	}
+	return numerals
+}
+
+// format returns the value with its alphabet characters replaced by the
+// characters of the numerals
+func (t *transformation) format(value string, numerals []int) string {
+	alphabet := []rune(t.Alphabet)
+	indexes := make(map[rune]bool, len(alphabet))
+	for _, r := range alphabet {
+		indexes[r] = true
+	}
+
+	result := make([]rune, 0, len(value))
+	for _, r := range value {
+		if indexes[r] {
+			r = alphabet[numerals[0]]
+			numerals = numerals[1:]
+		}
+		result = append(result, r)
+	}
+	return string(result)
+}
+
+// validateTransformationKey checks that the key can be used by transformations
+func validateTransformationKey(p *keysutil.Policy) error {
+	if p.Type != keysutil.KeyType_AES256_GCM96 || p.Derived {
+		return fmt.Errorf("transformations require a non-derived aes256-gcm96 key")
+	}
+	return nil
+}
+
+func (b *backend) pathTransformationsList(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	entries, err := req.Storage.List(ctx, "transformation/")
+	if err != nil {
+		return nil, err
+	}
+
+	return logical.ListResponse(entries), nil
+}
+
+func (b *backend) pathTransformationRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	t, err := getTransformation(ctx, req.Storage, d.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if t == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"type":     t.Type,
+			"key":      t.Key,
+			"alphabet": t.Alphabet,
+		},
+	}, nil
+}
+
+func (b *backend) pathTransformationWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+	t := &transformation{
+		Type:     d.Get("type").(string),
+		Key:      d.Get("key").(string),
+		Alphabet: d.Get("alphabet").(string),
+	}
+
+	switch t.Type {
+	case transformationTypeFPE, transformationTypeTokenization:
+	default:
+		return logical.ErrorResponse(fmt.Sprintf("unknown transformation type %q", t.Type)), nil
+	}
+
+	if !utf8.ValidString(t.Alphabet) {
+		return logical.ErrorResponse("alphabet must be valid UTF-8"), nil
+	}
+	seen := map[rune]bool{}
+	for _, r := range t.Alphabet {
+		if seen[r] {
+			return logical.ErrorResponse(fmt.Sprintf("alphabet contains %q more than once", r)), nil
+		}
+		seen[r] = true
+	}
+	if _, err := fpe.NewCipher(make([]byte, 32), t.radix()); err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("invalid alphabet: %v", err)), nil
+	}
+
+	if t.Key == "" {
+		return logical.ErrorResponse("key is required"), nil
+	}
+	p, lock, err := b.lm.GetPolicyShared(ctx, req.Storage, t.Key)
+	if lock != nil {
+		defer lock.RUnlock()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return logical.ErrorResponse(fmt.Sprintf("key %q not found", t.Key)), nil
+	}
+	if err := validateTransformationKey(p); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	// Existing values could not be decoded with other settings
+	existing, err := getTransformation(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil && *existing != *t {
+		return logical.ErrorResponse("the settings of an existing transformation cannot be changed"), nil
+	}
+
+	entry, err := logical.StorageEntryJSON("transformation/"+name, t)
+	if err != nil {
+		return nil, err
+	}
+	return nil, req.Storage.Put(ctx, entry)
+}
+
+func (b *backend) pathTransformationDelete(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	// Tokens cannot be decoded without their transformation
+	tokens, err := req.Storage.List(ctx, "token/"+name+"/")
+	if err != nil {
+		return nil, err
+	}
+	for _, token := range tokens {
+		if err := req.Storage.Delete(ctx, "token/"+name+"/"+token); err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, req.Storage.Delete(ctx, "transformation/"+name)
+}
+
+const pathTransformationsHelpSyn = `Manage format-preserving transformations`
+
+const pathTransformationsHelpDesc = `
+This path manages the transformations used by the "encode/<name>" and
+"decode/<name>" endpoints. A transformation encodes the characters of a value
+which belong to its alphabet, keeping the other characters in place, so that
+encoded values keep the length and format of the original ones, such as
+credit card or social security numbers.
+
+Transformations of type "fpe" encrypt values with the FF3-1 mode of NIST SP
+800-38G, under a key derived from the given transit key. Transformations of
+type "tokenization" replace values with random tokens, and store the
+encrypted values with optional metadata so that tokens can be decoded.
+`
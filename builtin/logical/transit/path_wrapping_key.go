@@ -0,0 +1,100 @@
+package transit
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// wrappingKeyBits is the size of the RSA key imported keys are wrapped under
+const wrappingKeyBits = 4096
+
+func (b *backend) pathWrappingKey() *framework.Path {
+	return &framework.Path{
+		Pattern: "wrapping_key",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathWrappingKeyRead,
+		},
+
+		HelpSynopsis:    pathWrappingKeyHelpSyn,
+		HelpDescription: pathWrappingKeyHelpDesc,
+	}
+}
+
+// getWrappingKey returns the RSA key used to wrap imported keys, generating
+// it on first use
+func (b *backend) getWrappingKey(ctx context.Context, s logical.Storage) (*rsa.PrivateKey, error) {
+	b.wrappingKeyLock.Lock()
+	defer b.wrappingKeyLock.Unlock()
+
+	if b.wrappingKey != nil {
+		return b.wrappingKey, nil
+	}
+
+	entry, err := s.Get(ctx, "wrapping_key")
+	if err != nil {
+		return nil, err
+	}
+	if entry != nil {
+		key, err := x509.ParsePKCS1PrivateKey(entry.Value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse the stored wrapping key: %v", err)
+		}
+		b.wrappingKey = key
+		return key, nil
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, wrappingKeyBits)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate the wrapping key: %v", err)
+	}
+	err = s.Put(ctx, &logical.StorageEntry{
+		Key:   "wrapping_key",
+		Value: x509.MarshalPKCS1PrivateKey(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+	b.wrappingKey = key
+	return key, nil
+}
+
+func (b *backend) pathWrappingKeyRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	key, err := b.getWrappingKey(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	derBytes, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling RSA public key: %v", err)
+	}
+	pemBytes := pem.EncodeToMemory(&pem.Block{
+		Type:  "PUBLIC KEY",
+		Bytes: derBytes,
+	})
+	if len(pemBytes) == 0 {
+		return nil, fmt.Errorf("failed to PEM-encode RSA public key")
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"public_key": string(pemBytes),
+		},
+	}, nil
+}
+
+const pathWrappingKeyHelpSyn = `Returns the public key to wrap imported keys with`
+
+const pathWrappingKeyHelpDesc = `
+This path returns the PEM-encoded public part of the 4096-bit RSA key of the
+backend. Key material imported through "keys/<name>/import" must be wrapped
+under it. The key is generated the first time it is read.
+`
@@ -178,6 +178,36 @@ func (b *backend) HandleRequest(ctx context.Context, req *logical.Request) (*log
 	return resp, err
 }
 
+// OperationProperties is a thin wrapper implementation of OperationProperties
+// that lazy-loads the plugin, since the properties are needed to route the
+// first request.
+func (b *backend) OperationProperties(path string, op logical.Operation) logical.OperationProperties {
+	b.RLock()
+
+	// Lazy-load backend
+	if !b.loaded {
+		// Upgrade lock
+		b.RUnlock()
+		b.Lock()
+		// Check once more after lock swap
+		if !b.loaded {
+			if err := b.startBackend(context.Background()); err != nil {
+				b.Unlock()
+				return logical.OperationProperties{}
+			}
+		}
+		b.Unlock()
+		b.RLock()
+	}
+	defer b.RUnlock()
+
+	propsBackend, ok := b.Backend.(logical.OperationPropertiesBackend)
+	if !ok {
+		return logical.OperationProperties{}
+	}
+	return propsBackend.OperationProperties(path, op)
+}
+
 // HandleExistenceCheck is a thin wrapper implementation of HandleRequest that includes automatic plugin reload.
 func (b *backend) HandleExistenceCheck(ctx context.Context, req *logical.Request) (bool, bool, error) {
 	b.RLock()
@@ -30,6 +30,22 @@ func TestBackend(t *testing.T) {
 	}
 }
 
+func TestBackend_OperationProperties(t *testing.T) {
+	config, cleanup := testConfig(t)
+	defer cleanup()
+
+	b, err := Factory(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The plugin is loaded to return the properties
+	props := b.(logical.OperationPropertiesBackend).OperationProperties("special", logical.ReadOperation)
+	if props != (logical.OperationProperties{Unauthenticated: true, ReadOnly: true}) {
+		t.Fatalf("bad: %#v", props)
+	}
+}
+
 func TestBackend_Factory(t *testing.T) {
 	config, cleanup := testConfig(t)
 	defer cleanup()
@@ -0,0 +1,419 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/command/agent/auth"
+	"github.com/hashicorp/vault/command/agent/auth/approle"
+	"github.com/hashicorp/vault/command/agent/auth/aws"
+	"github.com/hashicorp/vault/command/agent/auth/kubernetes"
+	"github.com/hashicorp/vault/command/agent/cache"
+	"github.com/hashicorp/vault/command/agent/config"
+	"github.com/hashicorp/vault/command/agent/sink"
+	"github.com/hashicorp/vault/command/agent/sink/file"
+	"github.com/hashicorp/vault/command/agent/sink/inmem"
+	"github.com/hashicorp/vault/command/agent/template"
+	"github.com/hashicorp/vault/command/server"
+	"github.com/hashicorp/vault/helper/logformat"
+	log "github.com/mgutz/logxi/v1"
+	"github.com/mitchellh/cli"
+	"github.com/posener/complete"
+)
+
+var _ cli.Command = (*AgentCommand)(nil)
+var _ cli.CommandAutocomplete = (*AgentCommand)(nil)
+
+type AgentCommand struct {
+	*BaseCommand
+
+	ShutdownCh chan struct{}
+
+	flagConfig   string
+	flagLogLevel string
+
+	logger log.Logger
+}
+
+func (c *AgentCommand) Synopsis() string {
+	return "Start a Vault agent"
+}
+
+func (c *AgentCommand) Help() string {
+	helpText := `
+Usage: vault agent [options]
+
+  This command starts a Vault agent that authenticates to Vault with an auth
+  method and keeps the token renewed, authenticating again when the token can
+  no longer be renewed. The token is written to the configured sinks, so that
+  applications can use it without authenticating themselves, and templates of
+  secrets are rendered to files that are kept up to date. The agent can also
+  proxy the requests of applications to Vault, caching the leased secrets and
+  tokens they create.
+
+  Start an agent with a configuration file:
+
+      $ vault agent -config=/etc/vault/agent.hcl
+
+  For a full list of examples, please see the documentation.
+
+` + c.Flags().Help()
+	return strings.TrimSpace(helpText)
+}
+
+func (c *AgentCommand) Flags() *FlagSets {
+	set := NewFlagSets(c.UI)
+
+	f := set.NewFlagSet("Command Options")
+
+	f.StringVar(&StringVar{
+		Name:    "config",
+		Target:  &c.flagConfig,
+		Default: "",
+		EnvVar:  "",
+		Completion: complete.PredictOr(
+			complete.PredictFiles("*.hcl"),
+			complete.PredictFiles("*.json"),
+		),
+		Usage: "Path to the configuration file of the agent.",
+	})
+
+	f.StringVar(&StringVar{
+		Name:       "log-level",
+		Target:     &c.flagLogLevel,
+		Default:    "info",
+		EnvVar:     "VAULT_LOG_LEVEL",
+		Completion: complete.PredictSet("trace", "debug", "info", "warn", "err"),
+		Usage: "Log verbosity level. Supported values (in order of detail) are " +
+			"\"trace\", \"debug\", \"info\", \"warn\", and \"err\".",
+	})
+
+	return set
+}
+
+func (c *AgentCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+func (c *AgentCommand) AutocompleteFlags() complete.Flags {
+	return c.Flags().Completions()
+}
+
+func (c *AgentCommand) Run(args []string) int {
+	f := c.Flags()
+
+	if err := f.Parse(args); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	args = f.Args()
+	if len(args) > 0 {
+		c.UI.Error(fmt.Sprintf("Too many arguments (expected 0, got %d)", len(args)))
+		return 1
+	}
+
+	if c.flagConfig == "" {
+		c.UI.Error("Must specify exactly one config path using -config")
+		return 1
+	}
+
+	var level int
+	switch strings.ToLower(strings.TrimSpace(c.flagLogLevel)) {
+	case "trace":
+		level = log.LevelTrace
+	case "debug":
+		level = log.LevelDebug
+	case "", "info", "notice":
+		level = log.LevelInfo
+	case "warn", "warning":
+		level = log.LevelWarn
+	case "err", "error":
+		level = log.LevelError
+	default:
+		c.UI.Error(fmt.Sprintf("Unknown log level: %s", c.flagLogLevel))
+		return 1
+	}
+	if c.logger == nil {
+		c.logger = logformat.NewVaultLoggerWithWriter(os.Stderr, level)
+	}
+
+	agentConfig, err := config.LoadConfig(c.flagConfig)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error loading configuration from %s: %s", c.flagConfig, err))
+		return 1
+	}
+
+	client, err := c.agentClient(agentConfig.Vault)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error creating Vault client: %s", err))
+		return 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	info := map[string]string{
+		"log level": c.flagLogLevel,
+		"vault":     client.Address(),
+	}
+
+	var method auth.AuthMethod
+	var authHandler *auth.AuthHandler
+	var sinks []sink.Sink
+	if agentConfig.AutoAuth != nil {
+		method, err = newAuthMethod(c.logger, agentConfig.AutoAuth.Method)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error creating %s auth method: %s", agentConfig.AutoAuth.Method.Type, err))
+			return 1
+		}
+
+		for _, sc := range agentConfig.AutoAuth.Sinks {
+			s, err := newSink(c.logger, sc)
+			if err != nil {
+				c.UI.Error(fmt.Sprintf("Error creating %s sink: %s", sc.Type, err))
+				return 1
+			}
+			sinks = append(sinks, s)
+		}
+
+		authHandler, err = auth.NewAuthHandler(&auth.AuthHandlerConfig{
+			Logger: c.logger,
+			Client: client,
+		})
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error creating auth handler: %s", err))
+			return 1
+		}
+
+		info["auth method"] = fmt.Sprintf("%s (mount path: %s)", agentConfig.AutoAuth.Method.Type, agentConfig.AutoAuth.Method.MountPath)
+		info["sinks"] = fmt.Sprintf("%d", len(sinks))
+	}
+
+	var templateServer *template.Server
+	if len(agentConfig.Templates) > 0 {
+		templateServer, err = template.NewServer(&template.ServerConfig{
+			Logger:    c.logger,
+			Client:    client,
+			Templates: agentConfig.Templates,
+		})
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error creating template server: %s", err))
+			return 1
+		}
+		info["templates"] = fmt.Sprintf("%d", len(agentConfig.Templates))
+	}
+
+	var servers []*http.Server
+	defer func() {
+		for _, srv := range servers {
+			srv.Close()
+		}
+	}()
+	if agentConfig.Cache != nil {
+		proxy, err := cache.NewAPIProxy(&cache.APIProxyConfig{
+			Client: client,
+			Logger: c.logger,
+		})
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error creating API proxy: %s", err))
+			return 1
+		}
+
+		leaseCache, err := cache.NewLeaseCache(&cache.LeaseCacheConfig{
+			Client:      client,
+			Proxier:     proxy,
+			Logger:      c.logger,
+			BaseContext: ctx,
+		})
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error creating lease cache: %s", err))
+			return 1
+		}
+
+		// The requests that have no token are made with the token of the
+		// agent, which the proxy gets from a sink
+		var autoAuthSink *inmem.Sink
+		if agentConfig.Cache.UseAutoAuthToken {
+			autoAuthSink = inmem.New()
+			sinks = append(sinks, autoAuthSink)
+		}
+
+		mux := http.NewServeMux()
+		mux.Handle(cache.AgentPathCacheClear, cache.HandleCacheClear(c.logger, leaseCache))
+		mux.Handle("/", cache.ProxyHandler(c.logger, leaseCache, autoAuthSink))
+
+		for i, lnConfig := range agentConfig.Listeners {
+			ln, props, _, err := server.NewListener(lnConfig.Type, lnConfig.Config, os.Stderr, c.UI)
+			if err != nil {
+				c.UI.Error(fmt.Sprintf("Error initializing listener of type %s: %s", lnConfig.Type, err))
+				return 1
+			}
+
+			propsList := make([]string, 0, len(props))
+			for k, v := range props {
+				propsList = append(propsList, fmt.Sprintf(
+					"%s: %q", k, v))
+			}
+			sort.Strings(propsList)
+			info[fmt.Sprintf("listener %d", i+1)] = fmt.Sprintf(
+				"%s (%s)", lnConfig.Type, strings.Join(propsList, ", "))
+
+			srv := &http.Server{
+				Handler: mux,
+			}
+			servers = append(servers, srv)
+			go srv.Serve(ln)
+		}
+	}
+
+	infoKeys := make([]string, 0, len(info))
+	for k := range info {
+		infoKeys = append(infoKeys, k)
+	}
+	sort.Strings(infoKeys)
+
+	padding := 24
+	c.UI.Output("==> Vault agent configuration:\n")
+	for _, k := range infoKeys {
+		c.UI.Output(fmt.Sprintf(
+			"%s%s: %s",
+			strings.Repeat(" ", padding-len(k)),
+			strings.Title(k),
+			info[k]))
+	}
+	c.UI.Output("")
+	c.UI.Output("==> Vault agent started! Log data will stream in below:\n")
+
+	var wg sync.WaitGroup
+	if authHandler != nil {
+		// Each token of the auth handler is sent to the sinks and to the
+		// template server
+		sinkCh := make(chan string)
+		templateCh := make(chan string)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			authHandler.Run(ctx, method)
+		}()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sink.NewSinkServer(c.logger).Run(ctx, sinkCh, sinks)
+		}()
+		if templateServer != nil {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				templateServer.Run(ctx, templateCh)
+			}()
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				var token string
+				select {
+				case <-ctx.Done():
+					return
+				case token = <-authHandler.OutputCh:
+				}
+
+				select {
+				case <-ctx.Done():
+					return
+				case sinkCh <- token:
+				}
+				if templateServer != nil {
+					select {
+					case <-ctx.Done():
+						return
+					case templateCh <- token:
+					}
+				}
+			}
+		}()
+	}
+
+	<-c.ShutdownCh
+	c.UI.Output("==> Vault agent shutdown triggered")
+	cancel()
+	wg.Wait()
+
+	return 0
+}
+
+// agentClient returns the client of the agent, configured with the vault
+// block of the configuration on top of the environment
+func (c *AgentCommand) agentClient(v *config.Vault) (*api.Client, error) {
+	clientConfig := api.DefaultConfig()
+	if clientConfig.Error != nil {
+		return nil, clientConfig.Error
+	}
+
+	if v != nil {
+		if v.Address != "" {
+			clientConfig.Address = v.Address
+		}
+		if v.CACert != "" || v.CAPath != "" || v.ClientCert != "" || v.ClientKey != "" || v.TLSSkipVerify {
+			if err := clientConfig.ConfigureTLS(&api.TLSConfig{
+				CACert:     v.CACert,
+				CAPath:     v.CAPath,
+				ClientCert: v.ClientCert,
+				ClientKey:  v.ClientKey,
+				Insecure:   v.TLSSkipVerify,
+			}); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	client, err := api.NewClient(clientConfig)
+	if err != nil {
+		return nil, err
+	}
+	// The token of the agent is the one it authenticates for
+	client.ClearToken()
+	return client, nil
+}
+
+// newAuthMethod returns the auth method of the configuration
+func newAuthMethod(logger log.Logger, m *config.Method) (auth.AuthMethod, error) {
+	authConfig := &auth.AuthConfig{
+		Logger:    logger,
+		MountPath: m.MountPath,
+		Config:    m.Config,
+	}
+
+	switch m.Type {
+	case "approle":
+		return approle.NewApproleAuthMethod(authConfig)
+	case "aws":
+		return aws.NewAWSAuthMethod(authConfig)
+	case "kubernetes":
+		return kubernetes.NewKubernetesAuthMethod(authConfig)
+	default:
+		return nil, fmt.Errorf("unknown auth method type %q", m.Type)
+	}
+}
+
+// newSink returns the sink of the configuration
+func newSink(logger log.Logger, s *config.Sink) (sink.Sink, error) {
+	sinkConfig := &sink.SinkConfig{
+		Logger: logger,
+		Config: s.Config,
+	}
+
+	switch s.Type {
+	case "file":
+		return file.NewFileSink(sinkConfig)
+	default:
+		return nil, fmt.Errorf("unknown sink type %q", s.Type)
+	}
+}
@@ -0,0 +1,91 @@
+package approle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/command/agent/auth"
+	log "github.com/mgutz/logxi/v1"
+)
+
+type approleMethod struct {
+	logger    log.Logger
+	mountPath string
+
+	roleIDFilePath   string
+	secretIDFilePath string
+}
+
+// NewApproleAuthMethod returns an auth method logging in with the role ID and
+// secret ID read from files. The files are read for each login, so that the
+// secret ID can be rotated by writing the new secret ID to its file.
+func NewApproleAuthMethod(conf *auth.AuthConfig) (auth.AuthMethod, error) {
+	if conf == nil {
+		return nil, errors.New("empty config")
+	}
+	if conf.Config == nil {
+		return nil, errors.New("empty config data")
+	}
+
+	a := &approleMethod{
+		logger:    conf.Logger,
+		mountPath: conf.MountPath,
+	}
+
+	roleIDFilePathRaw, ok := conf.Config["role_id_file_path"]
+	if !ok {
+		return nil, errors.New("missing 'role_id_file_path' value")
+	}
+	a.roleIDFilePath, ok = roleIDFilePathRaw.(string)
+	if !ok || a.roleIDFilePath == "" {
+		return nil, errors.New("could not convert 'role_id_file_path' config value to string")
+	}
+
+	// The secret ID is optional, since roles may not require one
+	if secretIDFilePathRaw, ok := conf.Config["secret_id_file_path"]; ok {
+		a.secretIDFilePath, ok = secretIDFilePathRaw.(string)
+		if !ok {
+			return nil, errors.New("could not convert 'secret_id_file_path' config value to string")
+		}
+	}
+
+	return a, nil
+}
+
+func (a *approleMethod) Authenticate(ctx context.Context, client *api.Client) (string, map[string]interface{}, error) {
+	roleID, err := readIDFile(a.roleIDFilePath)
+	if err != nil {
+		return "", nil, errwrap.Wrapf("error reading role ID: {{err}}", err)
+	}
+
+	data := map[string]interface{}{
+		"role_id": roleID,
+	}
+
+	if a.secretIDFilePath != "" {
+		secretID, err := readIDFile(a.secretIDFilePath)
+		if err != nil {
+			return "", nil, errwrap.Wrapf("error reading secret ID: {{err}}", err)
+		}
+		data["secret_id"] = secretID
+	}
+
+	return fmt.Sprintf("%s/login", a.mountPath), data, nil
+}
+
+func readIDFile(path string) (string, error) {
+	d, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	id := strings.TrimSpace(string(d))
+	if id == "" {
+		return "", fmt.Errorf("%s is empty", path)
+	}
+	return id, nil
+}
@@ -0,0 +1,179 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	log "github.com/mgutz/logxi/v1"
+)
+
+const (
+	// initialBackoff is the time to wait before authenticating again after
+	// a failed authentication, which doubles with each consecutive failure
+	// up to maxBackoff
+	initialBackoff = 1 * time.Second
+	maxBackoff     = 5 * time.Minute
+)
+
+// AuthMethod is an auth method the agent authenticates with
+type AuthMethod interface {
+	// Authenticate returns the path and the data of the login request. It is
+	// called for each authentication, so that credentials that change, e.g.
+	// rotated secret IDs, are read again.
+	Authenticate(ctx context.Context, client *api.Client) (string, map[string]interface{}, error)
+}
+
+// AuthConfig is the configuration of an auth method
+type AuthConfig struct {
+	Logger    log.Logger
+	MountPath string
+	Config    map[string]interface{}
+}
+
+// AuthHandlerConfig is the configuration of an AuthHandler
+type AuthHandlerConfig struct {
+	Logger log.Logger
+	Client *api.Client
+}
+
+// AuthHandler authenticates with an auth method and keeps the token renewed,
+// authenticating again when the token can no longer be renewed
+type AuthHandler struct {
+	// OutputCh receives each new token
+	OutputCh chan string
+
+	logger log.Logger
+	client *api.Client
+	random *rand.Rand
+}
+
+// NewAuthHandler returns an AuthHandler
+func NewAuthHandler(conf *AuthHandlerConfig) (*AuthHandler, error) {
+	// Logins are made without a token
+	client, err := conf.Client.Clone()
+	if err != nil {
+		return nil, err
+	}
+	client.ClearToken()
+
+	return &AuthHandler{
+		OutputCh: make(chan string),
+		logger:   conf.Logger,
+		client:   client,
+		random:   rand.New(rand.NewSource(time.Now().UnixNano())),
+	}, nil
+}
+
+// Run authenticates with the auth method and sends the token to OutputCh,
+// then renews it until it expires, and starts over. Run returns when ctx is
+// done.
+func (ah *AuthHandler) Run(ctx context.Context, am AuthMethod) {
+	ah.logger.Info("auth.handler: starting")
+	defer ah.logger.Info("auth.handler: stopped")
+
+	backoff := initialBackoff
+	for {
+		secret, err := ah.authenticate(ctx, am)
+		if err != nil {
+			ah.logger.Error("auth.handler: error authenticating", "error", err, "backoff", backoff)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(ah.jitter(backoff)):
+			}
+			backoff *= 2
+			if backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+			continue
+		}
+		backoff = initialBackoff
+
+		ah.logger.Info("auth.handler: authentication successful, sending token to sinks")
+		select {
+		case <-ctx.Done():
+			return
+		case ah.OutputCh <- secret.Auth.ClientToken:
+		}
+
+		if !ah.waitForExpiry(ctx, secret) {
+			return
+		}
+	}
+}
+
+// authenticate logs in with the auth method
+func (ah *AuthHandler) authenticate(ctx context.Context, am AuthMethod) (*api.Secret, error) {
+	path, data, err := am.Authenticate(ctx, ah.client)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := ah.client.Logical().Write(path, data)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil || secret.Auth == nil || secret.Auth.ClientToken == "" {
+		return nil, fmt.Errorf("no token returned by %s", path)
+	}
+	return secret, nil
+}
+
+// waitForExpiry renews the token of the secret for as long as it can be
+// renewed, then returns true, or false if ctx is done first. Tokens that
+// cannot be renewed are replaced once two thirds of their TTL have passed.
+func (ah *AuthHandler) waitForExpiry(ctx context.Context, secret *api.Secret) bool {
+	if !secret.Auth.Renewable {
+		ttl := time.Duration(secret.Auth.LeaseDuration) * time.Second
+		if ttl == 0 {
+			// The token never expires
+			<-ctx.Done()
+			return false
+		}
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(ttl * 2 / 3):
+			ah.logger.Info("auth.handler: token is expiring, authenticating again")
+			return true
+		}
+	}
+
+	renewer, err := ah.client.NewLifetimeWatcher(&api.LifetimeWatcherInput{
+		Secret: secret,
+	})
+	if err != nil {
+		ah.logger.Error("auth.handler: error creating renewer, authenticating again", "error", err)
+		return true
+	}
+	go renewer.Start()
+	defer renewer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+
+		case err := <-renewer.DoneCh():
+			if err != nil {
+				ah.logger.Error("auth.handler: error renewing token, authenticating again", "error", err)
+			} else {
+				ah.logger.Info("auth.handler: token can no longer be renewed, authenticating again")
+			}
+			return true
+
+		case <-renewer.RenewCh():
+			ah.logger.Info("auth.handler: renewed token")
+		}
+	}
+}
+
+// jitter returns a random duration between 3/4 and 5/4 of the duration, so
+// that agents failing at the same time do not retry at the same time
+func (ah *AuthHandler) jitter(d time.Duration) time.Duration {
+	return d*3/4 + time.Duration(ah.random.Int63n(int64(d/2)+1))
+}
@@ -0,0 +1,125 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/ec2metadata"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/hashicorp/errwrap"
+	uuid "github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/api"
+	awsauth "github.com/hashicorp/vault/builtin/credential/aws"
+	"github.com/hashicorp/vault/command/agent/auth"
+	log "github.com/mgutz/logxi/v1"
+)
+
+const (
+	typeEC2 = "ec2"
+	typeIAM = "iam"
+)
+
+type awsMethod struct {
+	logger    log.Logger
+	mountPath string
+
+	authType     string
+	role         string
+	accessKey    string
+	secretKey    string
+	sessionToken string
+	headerValue  string
+	nonce        string
+}
+
+// NewAWSAuthMethod returns an auth method logging in with the IAM credentials
+// of the agent, found in the config, the environment, the shared credentials
+// file or the instance metadata, or with the signed identity document of the
+// EC2 instance the agent runs on
+func NewAWSAuthMethod(conf *auth.AuthConfig) (auth.AuthMethod, error) {
+	if conf == nil {
+		return nil, errors.New("empty config")
+	}
+	if conf.Config == nil {
+		return nil, errors.New("empty config data")
+	}
+
+	a := &awsMethod{
+		logger:    conf.Logger,
+		mountPath: conf.MountPath,
+		authType:  typeIAM,
+	}
+
+	values := map[string]*string{
+		"type":          &a.authType,
+		"role":          &a.role,
+		"access_key":    &a.accessKey,
+		"secret_key":    &a.secretKey,
+		"session_token": &a.sessionToken,
+		"header_value":  &a.headerValue,
+		"nonce":         &a.nonce,
+	}
+	for key, value := range values {
+		raw, ok := conf.Config[key]
+		if !ok {
+			continue
+		}
+		if *value, ok = raw.(string); !ok {
+			return nil, fmt.Errorf("could not convert '%s' config value to string", key)
+		}
+	}
+
+	a.authType = strings.ToLower(a.authType)
+	switch a.authType {
+	case typeIAM:
+	case typeEC2:
+		// The nonce must be the same for all the logins of the instance, so
+		// one is generated for the lifetime of the agent if none is set
+		if a.nonce == "" {
+			nonce, err := uuid.GenerateUUID()
+			if err != nil {
+				return nil, errwrap.Wrapf("error generating nonce: {{err}}", err)
+			}
+			a.nonce = nonce
+		}
+	default:
+		return nil, fmt.Errorf("unknown type %q, must be %q or %q", a.authType, typeIAM, typeEC2)
+	}
+
+	return a, nil
+}
+
+func (a *awsMethod) Authenticate(ctx context.Context, client *api.Client) (string, map[string]interface{}, error) {
+	var data map[string]interface{}
+	switch a.authType {
+	case typeEC2:
+		sess, err := session.NewSession()
+		if err != nil {
+			return "", nil, errwrap.Wrapf("error creating AWS session: {{err}}", err)
+		}
+		pkcs7, err := ec2metadata.New(sess).GetDynamicData("instance-identity/pkcs7")
+		if err != nil {
+			return "", nil, errwrap.Wrapf("error reading instance identity document: {{err}}", err)
+		}
+
+		data = map[string]interface{}{
+			"pkcs7": strings.Replace(strings.TrimSpace(pkcs7), "\n", "", -1),
+			"nonce": a.nonce,
+		}
+
+	default:
+		var err error
+		data, err = awsauth.GenerateLoginData(a.accessKey, a.secretKey, a.sessionToken, a.headerValue)
+		if err != nil {
+			return "", nil, errwrap.Wrapf("error generating login data: {{err}}", err)
+		}
+	}
+
+	if a.role != "" {
+		data["role"] = a.role
+	}
+
+	return fmt.Sprintf("%s/login", a.mountPath), data, nil
+}
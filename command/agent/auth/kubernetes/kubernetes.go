@@ -0,0 +1,78 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/command/agent/auth"
+	log "github.com/mgutz/logxi/v1"
+)
+
+// serviceAccountTokenPath is the path of the token of the service account of
+// the pod, mounted by Kubernetes
+const serviceAccountTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+type kubernetesMethod struct {
+	logger    log.Logger
+	mountPath string
+
+	role      string
+	tokenPath string
+}
+
+// NewKubernetesAuthMethod returns an auth method logging in with the token of
+// the service account of the pod the agent runs in
+func NewKubernetesAuthMethod(conf *auth.AuthConfig) (auth.AuthMethod, error) {
+	if conf == nil {
+		return nil, errors.New("empty config")
+	}
+	if conf.Config == nil {
+		return nil, errors.New("empty config data")
+	}
+
+	k := &kubernetesMethod{
+		logger:    conf.Logger,
+		mountPath: conf.MountPath,
+		tokenPath: serviceAccountTokenPath,
+	}
+
+	roleRaw, ok := conf.Config["role"]
+	if !ok {
+		return nil, errors.New("missing 'role' value")
+	}
+	k.role, ok = roleRaw.(string)
+	if !ok || k.role == "" {
+		return nil, errors.New("could not convert 'role' config value to string")
+	}
+
+	if tokenPathRaw, ok := conf.Config["token_path"]; ok {
+		k.tokenPath, ok = tokenPathRaw.(string)
+		if !ok || k.tokenPath == "" {
+			return nil, errors.New("could not convert 'token_path' config value to string")
+		}
+	}
+
+	return k, nil
+}
+
+func (k *kubernetesMethod) Authenticate(ctx context.Context, client *api.Client) (string, map[string]interface{}, error) {
+	// The token is read for each login since Kubernetes may rotate it
+	d, err := ioutil.ReadFile(k.tokenPath)
+	if err != nil {
+		return "", nil, errwrap.Wrapf("error reading service account token: {{err}}", err)
+	}
+	jwt := strings.TrimSpace(string(d))
+	if jwt == "" {
+		return "", nil, fmt.Errorf("service account token %s is empty", k.tokenPath)
+	}
+
+	return fmt.Sprintf("%s/login", k.mountPath), map[string]interface{}{
+		"role": k.role,
+		"jwt":  jwt,
+	}, nil
+}
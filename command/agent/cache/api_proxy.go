@@ -0,0 +1,103 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/hashicorp/vault/api"
+	log "github.com/mgutz/logxi/v1"
+)
+
+// proxiedHeaders are the headers of the requests that are set on the
+// request to Vault by the client rather than copied
+var proxiedHeaders = map[string]bool{
+	"X-Vault-Token":           true,
+	"X-Vault-Wrap-Ttl":        true,
+	"X-Vault-Mfa":             true,
+	"X-Vault-Namespace":       true,
+	"X-Vault-Policy-Override": true,
+	"Content-Length":          true,
+	"Connection":              true,
+	"Host":                    true,
+}
+
+// APIProxyConfig is the configuration of an APIProxy
+type APIProxyConfig struct {
+	Client *api.Client
+	Logger log.Logger
+}
+
+// APIProxy sends the requests to Vault
+type APIProxy struct {
+	client *api.Client
+	logger log.Logger
+}
+
+var _ Proxier = (*APIProxy)(nil)
+
+// NewAPIProxy returns an APIProxy
+func NewAPIProxy(conf *APIProxyConfig) (*APIProxy, error) {
+	client, err := conf.Client.Clone()
+	if err != nil {
+		return nil, err
+	}
+	client.ClearToken()
+
+	return &APIProxy{
+		client: client,
+		logger: conf.Logger,
+	}, nil
+}
+
+func (ap *APIProxy) Send(ctx context.Context, req *SendRequest) (*SendResponse, error) {
+	r := ap.client.NewRequest(req.Request.Method, req.Request.URL.Path)
+	r.ClientToken = req.Token
+	r.Params = req.Request.URL.Query()
+	r.WrapTTL = req.Request.Header.Get("X-Vault-Wrap-TTL")
+	r.MFAHeaderVals = req.Request.Header["X-Vault-Mfa"]
+	r.PolicyOverride = req.Request.Header.Get("X-Vault-Policy-Override") == "true"
+	if namespace := req.Request.Header.Get("X-Vault-Namespace"); namespace != "" {
+		r.Namespace = namespace
+	}
+
+	r.Headers = make(http.Header)
+	for header, vals := range req.Request.Header {
+		if !proxiedHeaders[http.CanonicalHeaderKey(header)] {
+			r.Headers[header] = vals
+		}
+	}
+
+	if len(req.RequestBody) > 0 {
+		// JSON bodies are set as raw messages, so that they are sent again
+		// as is if the request is redirected
+		if json.Valid(req.RequestBody) {
+			if err := r.SetJSONBody(json.RawMessage(req.RequestBody)); err != nil {
+				return nil, err
+			}
+		} else {
+			r.Body = bytes.NewReader(req.RequestBody)
+			r.BodySize = int64(len(req.RequestBody))
+		}
+	}
+
+	// The error responses of Vault are proxied as they are
+	resp, err := ap.client.RawRequest(r)
+	if resp == nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SendResponse{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       body,
+	}, nil
+}
@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/hashicorp/vault/command/agent/sink/inmem"
+	"github.com/hashicorp/vault/helper/jsonutil"
+	vaulthttp "github.com/hashicorp/vault/http"
+	log "github.com/mgutz/logxi/v1"
+)
+
+// AgentPathCacheClear is the path of the endpoint of the agent evicting
+// cached responses
+const AgentPathCacheClear = "/agent/v1/cache-clear"
+
+// ProxyHandler proxies the requests of the applications to Vault. The
+// requests that have no token are made with the token of the agent, if
+// autoAuthSink is set.
+func ProxyHandler(logger log.Logger, proxier Proxier, autoAuthSink *inmem.Sink) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.Header.Get("X-Vault-Token")
+		if token == "" && autoAuthSink != nil {
+			token = autoAuthSink.Token()
+		}
+
+		body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, vaulthttp.MaxRequestSize))
+		if err != nil {
+			respondError(w, http.StatusBadRequest, fmt.Errorf("failed to read request body: %s", err))
+			return
+		}
+
+		resp, err := proxier.Send(r.Context(), &SendRequest{
+			Token:       token,
+			Request:     r,
+			RequestBody: body,
+		})
+		if err != nil {
+			logger.Error("cache.proxy: error proxying request", "path", r.URL.Path, "error", err)
+			respondError(w, http.StatusBadGateway, fmt.Errorf("failed to proxy request: %s", err))
+			return
+		}
+
+		for header, vals := range resp.Header {
+			if header == "Content-Length" {
+				continue
+			}
+			w.Header()[header] = vals
+		}
+		w.WriteHeader(resp.StatusCode)
+		w.Write(resp.Body)
+	})
+}
+
+// cacheClearRequest is the request of the cache clear endpoint
+type cacheClearRequest struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// HandleCacheClear evicts the cached responses selected by the request, see
+// LeaseCache.ClearCache. The endpoint is only served by the agent: it is not
+// proxied to Vault.
+func HandleCacheClear(logger log.Logger, leaseCache *LeaseCache) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "PUT" && r.Method != "POST" {
+			respondError(w, http.StatusMethodNotAllowed, nil)
+			return
+		}
+
+		var req cacheClearRequest
+		if err := jsonutil.DecodeJSONFromReader(http.MaxBytesReader(w, r.Body, vaulthttp.MaxRequestSize), &req); err != nil {
+			respondError(w, http.StatusBadRequest, fmt.Errorf("failed to parse JSON input: %s", err))
+			return
+		}
+		if req.Type != "all" && req.Value == "" {
+			respondError(w, http.StatusBadRequest, fmt.Errorf("missing value"))
+			return
+		}
+
+		evicted, err := leaseCache.ClearCache(req.Type, req.Value)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, err)
+			return
+		}
+		logger.Info("cache.proxy: cleared cache", "type", req.Type, "evicted", evicted)
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func respondError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	resp := struct {
+		Errors []string `json:"errors"`
+	}{
+		Errors: make([]string, 0, 1),
+	}
+	if err != nil {
+		resp.Errors = append(resp.Errors, err.Error())
+	}
+
+	enc := json.NewEncoder(w)
+	enc.Encode(resp)
+}
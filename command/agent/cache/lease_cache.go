@@ -0,0 +1,358 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	log "github.com/mgutz/logxi/v1"
+)
+
+// LeaseCacheConfig is the configuration of a LeaseCache
+type LeaseCacheConfig struct {
+	Client  *api.Client
+	Proxier Proxier
+	Logger  log.Logger
+
+	// BaseContext is the context of the renewals of the cached secrets,
+	// which are all evicted when it is done
+	BaseContext context.Context
+}
+
+// LeaseCache proxies the requests to Vault, and caches the responses that
+// have a lease or create a token. Identical requests, made with the same
+// token, get the cached response for as long as the agent can renew its
+// lease or token, so that the applications do not create a new lease or
+// token for each of their requests. The cached responses are evicted when
+// their lease or token expires, or is revoked through the agent, and with
+// the token they were created with.
+type LeaseCache struct {
+	client  *api.Client
+	proxier Proxier
+	logger  log.Logger
+	baseCtx context.Context
+
+	lock sync.RWMutex
+
+	// entries are the cached responses by request, and tokens the cached
+	// responses creating a token, by token
+	entries map[string]*cacheEntry
+	tokens  map[string]*cacheEntry
+}
+
+var _ Proxier = (*LeaseCache)(nil)
+
+type cacheEntry struct {
+	key         string
+	requestPath string
+
+	// token is the token of the request, leaseID the lease of the response
+	// and createdToken and accessor the token it created, if any
+	token        string
+	leaseID      string
+	createdToken string
+	accessor     string
+
+	response *SendResponse
+
+	// ctx is done when the entry is evicted. It derives from the context of
+	// the entry of the token of the request, if it is cached, so that the
+	// entries created with a token are evicted with it.
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewLeaseCache returns a LeaseCache
+func NewLeaseCache(conf *LeaseCacheConfig) (*LeaseCache, error) {
+	client, err := conf.Client.Clone()
+	if err != nil {
+		return nil, err
+	}
+	client.ClearToken()
+
+	return &LeaseCache{
+		client:  client,
+		proxier: conf.Proxier,
+		logger:  conf.Logger,
+		baseCtx: conf.BaseContext,
+		entries: make(map[string]*cacheEntry),
+		tokens:  make(map[string]*cacheEntry),
+	}, nil
+}
+
+func (c *LeaseCache) Send(ctx context.Context, req *SendRequest) (*SendResponse, error) {
+	key := cacheKey(req)
+
+	c.lock.RLock()
+	entry, ok := c.entries[key]
+	c.lock.RUnlock()
+	if ok {
+		if c.logger.IsTrace() {
+			c.logger.Trace("cache.leasecache: returning cached response", "path", req.Request.URL.Path)
+		}
+		return entry.response, nil
+	}
+
+	resp, err := c.proxier.Send(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp, nil
+	}
+
+	c.handleRevocation(req)
+
+	if resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+	secret, err := api.ParseSecret(bytes.NewReader(resp.Body))
+	if err != nil || secret == nil {
+		return resp, nil
+	}
+
+	entry = &cacheEntry{
+		key:         key,
+		requestPath: req.Request.URL.Path,
+		token:       req.Token,
+		response:    resp,
+	}
+	switch {
+	case secret.LeaseID != "":
+		entry.leaseID = secret.LeaseID
+	case secret.Auth != nil && secret.Auth.ClientToken != "":
+		entry.createdToken = secret.Auth.ClientToken
+		entry.accessor = secret.Auth.Accessor
+	default:
+		return resp, nil
+	}
+
+	c.lock.Lock()
+	if _, ok := c.entries[key]; ok {
+		// An identical request was cached concurrently
+		c.lock.Unlock()
+		return resp, nil
+	}
+	parentCtx := c.baseCtx
+	if parent, ok := c.tokens[req.Token]; ok {
+		parentCtx = parent.ctx
+	}
+	entry.ctx, entry.cancel = context.WithCancel(parentCtx)
+	c.entries[key] = entry
+	if entry.createdToken != "" {
+		c.tokens[entry.createdToken] = entry
+	}
+	c.lock.Unlock()
+
+	if c.logger.IsDebug() {
+		c.logger.Debug("cache.leasecache: cached response", "path", entry.requestPath)
+	}
+	go c.renew(entry, secret)
+
+	return resp, nil
+}
+
+// renew renews the lease or token of the entry for as long as it can be
+// renewed, then evicts the entry
+func (c *LeaseCache) renew(entry *cacheEntry, secret *api.Secret) {
+	defer c.evict(entry)
+
+	renewable, ttl := secret.Renewable, secret.LeaseDuration
+	if secret.Auth != nil && entry.leaseID == "" {
+		renewable, ttl = secret.Auth.Renewable, secret.Auth.LeaseDuration
+	}
+
+	if !renewable {
+		if ttl == 0 {
+			// The token never expires
+			<-entry.ctx.Done()
+			return
+		}
+		select {
+		case <-entry.ctx.Done():
+		case <-time.After(time.Duration(ttl) * time.Second):
+		}
+		return
+	}
+
+	client, err := c.client.Clone()
+	if err != nil {
+		c.logger.Error("cache.leasecache: error creating client for renewals", "error", err)
+		return
+	}
+	client.SetToken(entry.token)
+
+	renewer, err := client.NewLifetimeWatcher(&api.LifetimeWatcherInput{
+		Secret: secret,
+	})
+	if err != nil {
+		c.logger.Error("cache.leasecache: error creating renewer", "error", err)
+		return
+	}
+	go renewer.Start()
+	defer renewer.Stop()
+
+	for {
+		select {
+		case <-entry.ctx.Done():
+			return
+		case err := <-renewer.DoneCh():
+			if err != nil {
+				c.logger.Warn("cache.leasecache: error renewing, evicting cached response", "path", entry.requestPath, "error", err)
+			}
+			return
+		case <-renewer.RenewCh():
+			if c.logger.IsTrace() {
+				c.logger.Trace("cache.leasecache: renewed", "path", entry.requestPath)
+			}
+		}
+	}
+}
+
+// evict removes the entry from the cache, along with the entries created with
+// its token
+func (c *LeaseCache) evict(entry *cacheEntry) {
+	c.lock.Lock()
+	if c.entries[entry.key] == entry {
+		delete(c.entries, entry.key)
+	}
+	if entry.createdToken != "" && c.tokens[entry.createdToken] == entry {
+		delete(c.tokens, entry.createdToken)
+	}
+	c.lock.Unlock()
+
+	entry.cancel()
+}
+
+// evictMatching evicts the entries for which the function returns true
+func (c *LeaseCache) evictMatching(f func(*cacheEntry) bool) int {
+	c.lock.RLock()
+	var matching []*cacheEntry
+	for _, entry := range c.entries {
+		if f(entry) {
+			matching = append(matching, entry)
+		}
+	}
+	c.lock.RUnlock()
+
+	for _, entry := range matching {
+		c.evict(entry)
+	}
+	return len(matching)
+}
+
+// handleRevocation evicts the entries of the leases and tokens revoked by the
+// successful request
+func (c *LeaseCache) handleRevocation(req *SendRequest) {
+	if req.Request.Method != "PUT" && req.Request.Method != "POST" {
+		return
+	}
+
+	var body map[string]interface{}
+	if len(req.RequestBody) > 0 {
+		json.Unmarshal(req.RequestBody, &body)
+	}
+	bodyValue := func(key string) string {
+		v, _ := body[key].(string)
+		return v
+	}
+
+	path := strings.TrimPrefix(req.Request.URL.Path, "/v1/")
+	switch {
+	case path == "sys/leases/revoke" || path == "sys/revoke":
+		c.evictLease(bodyValue("lease_id"))
+	case strings.HasPrefix(path, "sys/leases/revoke/"):
+		c.evictLease(strings.TrimPrefix(path, "sys/leases/revoke/"))
+	case strings.HasPrefix(path, "sys/revoke/"):
+		c.evictLease(strings.TrimPrefix(path, "sys/revoke/"))
+
+	case strings.HasPrefix(path, "sys/leases/revoke-prefix/"),
+		strings.HasPrefix(path, "sys/leases/revoke-force/"),
+		strings.HasPrefix(path, "sys/revoke-prefix/"),
+		strings.HasPrefix(path, "sys/revoke-force/"):
+		prefix := path[strings.Index(path, "/revoke-")+1:]
+		prefix = prefix[strings.Index(prefix, "/")+1:]
+		c.evictMatching(func(entry *cacheEntry) bool {
+			return entry.leaseID != "" && strings.HasPrefix(entry.leaseID, prefix)
+		})
+
+	case path == "auth/token/revoke" || path == "auth/token/revoke-orphan":
+		c.evictToken(bodyValue("token"))
+	case path == "auth/token/revoke-self":
+		c.evictToken(req.Token)
+	case path == "auth/token/revoke-accessor":
+		accessor := bodyValue("accessor")
+		c.evictMatching(func(entry *cacheEntry) bool {
+			return accessor != "" && entry.accessor == accessor
+		})
+	}
+}
+
+func (c *LeaseCache) evictLease(leaseID string) int {
+	return c.evictMatching(func(entry *cacheEntry) bool {
+		return leaseID != "" && entry.leaseID == leaseID
+	})
+}
+
+// evictToken evicts the entry creating the token, which evicts the entries
+// created with it, and the entries requested with the token that are not
+// cached with it
+func (c *LeaseCache) evictToken(token string) int {
+	return c.evictMatching(func(entry *cacheEntry) bool {
+		return token != "" && (entry.createdToken == token || entry.token == token)
+	})
+}
+
+// ClearCache evicts cached responses. The type of the eviction is one of:
+//
+//   - all: evicts all the entries
+//   - request_path: evicts the entries of the requests to the path
+//   - lease: evicts the entry of the lease
+//   - token: evicts the entries of the requests made with the token, and the
+//     entry creating the token
+//
+// The leases and tokens of the evicted entries are no longer renewed, but
+// they are not revoked.
+func (c *LeaseCache) ClearCache(clearType, value string) (int, error) {
+	switch clearType {
+	case "all":
+		return c.evictMatching(func(*cacheEntry) bool { return true }), nil
+	case "request_path":
+		path := "/v1/" + strings.TrimPrefix(value, "/v1/")
+		return c.evictMatching(func(entry *cacheEntry) bool {
+			return entry.requestPath == path
+		}), nil
+	case "lease":
+		return c.evictLease(value), nil
+	case "token":
+		return c.evictToken(value), nil
+	default:
+		return 0, fmt.Errorf("invalid type %q, must be one of all, request_path, lease or token", clearType)
+	}
+}
+
+// cacheKey returns the key of the request in the cache
+func cacheKey(req *SendRequest) string {
+	h := sha256.New()
+	for _, v := range []string{
+		req.Request.Method,
+		req.Request.URL.Path,
+		req.Request.URL.Query().Encode(),
+		req.Request.Header.Get("X-Vault-Namespace"),
+		req.Request.Header.Get("X-Vault-Wrap-TTL"),
+		req.Token,
+	} {
+		h.Write([]byte(v))
+		h.Write([]byte{0})
+	}
+	h.Write(req.RequestBody)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,218 @@
+package cache
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/command/agent/sink/inmem"
+	"github.com/hashicorp/vault/helper/logformat"
+	vaulthttp "github.com/hashicorp/vault/http"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/vault"
+	log "github.com/mgutz/logxi/v1"
+)
+
+// testProxy returns a client of Vault with the root token, and a client of an
+// agent proxying requests to Vault
+func testProxy(t *testing.T, autoAuthSink *inmem.Sink) (*api.Client, *api.Client, *LeaseCache, func()) {
+	cluster := vault.NewTestCluster(t, &vault.CoreConfig{
+		LogicalBackends: map[string]logical.Factory{
+			"generic-leased": vault.LeasedPassthroughBackendFactory,
+		},
+	}, &vault.TestClusterOptions{
+		HandlerFunc: vaulthttp.Handler,
+		NumCores:    1,
+	})
+	cluster.Start()
+	vault.TestWaitActive(t, cluster.Cores[0].Core)
+
+	client := cluster.Cores[0].Client
+	client.SetToken(cluster.RootToken)
+
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+	proxy, err := NewAPIProxy(&APIProxyConfig{
+		Client: client,
+		Logger: logger,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	leaseCache, err := NewLeaseCache(&LeaseCacheConfig{
+		Client:      client,
+		Proxier:     proxy,
+		Logger:      logger,
+		BaseContext: ctx,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(AgentPathCacheClear, HandleCacheClear(logger, leaseCache))
+	mux.Handle("/", ProxyHandler(logger, leaseCache, autoAuthSink))
+	server := httptest.NewServer(mux)
+
+	config := api.DefaultConfig()
+	config.Address = server.URL
+	agentClient, err := api.NewClient(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	agentClient.SetToken(cluster.RootToken)
+
+	return client, agentClient, leaseCache, func() {
+		server.Close()
+		cancel()
+		cluster.Cleanup()
+	}
+}
+
+func TestLeaseCache(t *testing.T) {
+	client, agentClient, leaseCache, closer := testProxy(t, nil)
+	defer closer()
+
+	if err := client.Sys().Mount("leased", &api.MountInput{Type: "generic-leased"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Logical().Write("leased/db", map[string]interface{}{"username": "app", "ttl": "1h"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Logical().Write("secret/app", map[string]interface{}{"password": "foo"}); err != nil {
+		t.Fatal(err)
+	}
+
+	readLease := func(c *api.Client) string {
+		t.Helper()
+		secret, err := c.Logical().Read("leased/db")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if secret.LeaseID == "" {
+			t.Fatal("expected lease")
+		}
+		return secret.LeaseID
+	}
+
+	// Leased secrets are cached
+	leaseID := readLease(agentClient)
+	if readLease(agentClient) != leaseID {
+		t.Fatal("expected cached lease")
+	}
+
+	// Secrets with no lease are not cached
+	if _, err := client.Logical().Write("secret/app", map[string]interface{}{"password": "bar"}); err != nil {
+		t.Fatal(err)
+	}
+	secret, err := agentClient.Logical().Read("secret/app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret.Data["password"] != "bar" {
+		t.Fatalf("bad secret: %#v", secret.Data)
+	}
+
+	// Errors are proxied
+	if _, err := agentClient.Logical().Write("sys/mounts/secret", map[string]interface{}{"type": "kv"}); err == nil {
+		t.Fatal("expected error")
+	}
+
+	// Revoking the lease through the agent evicts it
+	if _, err := agentClient.Logical().Write("sys/leases/revoke", map[string]interface{}{"lease_id": leaseID}); err != nil {
+		t.Fatal(err)
+	}
+	newLeaseID := readLease(agentClient)
+	if newLeaseID == leaseID {
+		t.Fatal("expected new lease")
+	}
+
+	// Tokens are cached, along with the leases created with them
+	createToken := func() string {
+		t.Helper()
+		secret, err := agentClient.Auth().Token().Create(&api.TokenCreateRequest{
+			Policies: []string{"root"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return secret.Auth.ClientToken
+	}
+	token := createToken()
+	if createToken() != token {
+		t.Fatal("expected cached token")
+	}
+
+	tokenClient, err := agentClient.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokenClient.SetToken(token)
+	tokenLeaseID := readLease(tokenClient)
+	if readLease(tokenClient) != tokenLeaseID {
+		t.Fatal("expected cached lease")
+	}
+
+	// Revoking the token evicts it along with its leases
+	if err := tokenClient.Auth().Token().RevokeSelf(""); err != nil {
+		t.Fatal(err)
+	}
+	leaseCache.lock.RLock()
+	_, tokenCached := leaseCache.tokens[token]
+	numEntries := len(leaseCache.entries)
+	leaseCache.lock.RUnlock()
+	if tokenCached || numEntries != 1 {
+		t.Fatalf("expected only the lease of the root token to be cached, got %d entries", numEntries)
+	}
+	if createToken() == token {
+		t.Fatal("expected new token")
+	}
+
+	// The cache is cleared by the agent
+	r := agentClient.NewRequest("POST", AgentPathCacheClear)
+	if err := r.SetJSONBody(map[string]interface{}{"type": "all"}); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := agentClient.RawRequest(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if readLease(agentClient) == newLeaseID {
+		t.Fatal("expected new lease")
+	}
+
+	r = agentClient.NewRequest("POST", AgentPathCacheClear)
+	if err := r.SetJSONBody(map[string]interface{}{"type": "foo", "value": "bar"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := agentClient.RawRequest(r); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestLeaseCache_autoAuthToken(t *testing.T) {
+	autoAuthSink := inmem.New()
+	client, agentClient, _, closer := testProxy(t, autoAuthSink)
+	defer closer()
+
+	agentClient.ClearToken()
+	if _, err := agentClient.Logical().Read("secret/app"); err == nil {
+		t.Fatal("expected error without token")
+	}
+
+	autoAuthSink.WriteToken(client.Token())
+	if _, err := agentClient.Logical().Write("secret/app", map[string]interface{}{"password": "foo"}); err != nil {
+		t.Fatal(err)
+	}
+	secret, err := agentClient.Logical().Read("secret/app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret.Data["password"] != "foo" {
+		t.Fatalf("bad secret: %#v", secret.Data)
+	}
+}
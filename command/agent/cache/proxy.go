@@ -0,0 +1,29 @@
+package cache
+
+import (
+	"context"
+	"net/http"
+)
+
+// SendRequest is a request of an application proxied to Vault
+type SendRequest struct {
+	// Token is the token the request is made with, which is the token of
+	// the agent if the request has none and the agent is configured to use
+	// it
+	Token string
+
+	Request     *http.Request
+	RequestBody []byte
+}
+
+// SendResponse is the response of Vault to a proxied request
+type SendResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// Proxier sends the requests of the applications to Vault
+type Proxier interface {
+	Send(ctx context.Context, req *SendRequest) (*SendResponse, error)
+}
@@ -0,0 +1,464 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/hcl"
+	"github.com/hashicorp/hcl/hcl/ast"
+	"github.com/hashicorp/vault/helper/parseutil"
+)
+
+// Config is the configuration of the Vault agent
+type Config struct {
+	AutoAuth  *AutoAuth
+	Cache     *Cache
+	Listeners []*Listener
+	Vault     *Vault
+	Templates []*Template
+}
+
+// Vault is the configuration of the connection to the Vault server. Unset
+// values default to the standard VAULT_* environment variables.
+type Vault struct {
+	Address          string      `hcl:"address"`
+	CACert           string      `hcl:"ca_cert"`
+	CAPath           string      `hcl:"ca_path"`
+	ClientCert       string      `hcl:"client_cert"`
+	ClientKey        string      `hcl:"client_key"`
+	TLSSkipVerify    bool        `hcl:"-"`
+	TLSSkipVerifyRaw interface{} `hcl:"tls_skip_verify"`
+}
+
+// AutoAuth is the configuration of the authentication of the agent, and of
+// the sinks its token is written to
+type AutoAuth struct {
+	Method *Method
+	Sinks  []*Sink
+}
+
+// Method is the configuration of the auth method the agent authenticates
+// with. MountPath defaults to auth/<type>.
+type Method struct {
+	Type      string
+	MountPath string
+	Config    map[string]interface{}
+}
+
+// Sink is the configuration of a destination of the token of the agent
+type Sink struct {
+	Type   string
+	Config map[string]interface{}
+}
+
+// Cache is the configuration of the API proxy of the agent, which caches the
+// leased secrets and the tokens it proxies. UseAutoAuthToken makes the
+// requests that have no token use the token of the agent.
+type Cache struct {
+	UseAutoAuthToken    bool        `hcl:"-"`
+	UseAutoAuthTokenRaw interface{} `hcl:"use_auto_auth_token"`
+}
+
+// Listener is the configuration of a listener of the API proxy, which has the
+// format of the listeners of the server
+type Listener struct {
+	Type   string
+	Config map[string]interface{}
+}
+
+// Template is the configuration of a template rendered by the agent. The
+// template is read from Source, or is given inline as Contents, and rendered
+// to Destination. Command, if set, is run each time the rendered file changes.
+type Template struct {
+	Source      string      `hcl:"source"`
+	Contents    string      `hcl:"contents"`
+	Destination string      `hcl:"destination"`
+	Command     string      `hcl:"command"`
+	Perms       os.FileMode `hcl:"-"`
+	PermsRaw    interface{} `hcl:"perms"`
+}
+
+// LoadConfig loads the configuration of the agent from the given file
+func LoadConfig(path string) (*Config, error) {
+	d, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseConfig(string(d))
+}
+
+// ParseConfig parses the configuration of the agent
+func ParseConfig(d string) (*Config, error) {
+	obj, err := hcl.Parse(d)
+	if err != nil {
+		return nil, err
+	}
+
+	list, ok := obj.Node.(*ast.ObjectList)
+	if !ok {
+		return nil, fmt.Errorf("error parsing: file doesn't contain a root object")
+	}
+
+	valid := []string{
+		"auto_auth",
+		"cache",
+		"listener",
+		"vault",
+		"template",
+	}
+	if err := checkHCLKeys(list, valid); err != nil {
+		return nil, err
+	}
+
+	var result Config
+	if o := list.Filter("vault"); len(o.Items) > 0 {
+		if err := parseVault(&result, o); err != nil {
+			return nil, multierror.Prefix(err, "vault:")
+		}
+	}
+
+	if o := list.Filter("auto_auth"); len(o.Items) > 0 {
+		if err := parseAutoAuth(&result, o); err != nil {
+			return nil, multierror.Prefix(err, "auto_auth:")
+		}
+	}
+
+	if o := list.Filter("cache"); len(o.Items) > 0 {
+		if err := parseCache(&result, o); err != nil {
+			return nil, multierror.Prefix(err, "cache:")
+		}
+	}
+
+	if o := list.Filter("listener"); len(o.Items) > 0 {
+		if err := parseListeners(&result, o); err != nil {
+			return nil, multierror.Prefix(err, "listener:")
+		}
+	}
+
+	if o := list.Filter("template"); len(o.Items) > 0 {
+		if err := parseTemplates(&result, o); err != nil {
+			return nil, multierror.Prefix(err, "template:")
+		}
+	}
+
+	switch {
+	case result.AutoAuth == nil && result.Cache == nil:
+		return nil, fmt.Errorf("one of 'auto_auth' or 'cache' blocks must be set")
+	case result.AutoAuth == nil && len(result.Templates) > 0:
+		return nil, fmt.Errorf("'template' blocks require an 'auto_auth' block")
+	case result.AutoAuth == nil && result.Cache.UseAutoAuthToken:
+		return nil, fmt.Errorf("cache.use_auto_auth_token requires an 'auto_auth' block")
+	case result.Cache != nil && len(result.Listeners) == 0:
+		return nil, fmt.Errorf("the 'cache' block requires at least one 'listener' block")
+	case result.Cache == nil && len(result.Listeners) > 0:
+		return nil, fmt.Errorf("'listener' blocks require a 'cache' block")
+	}
+
+	return &result, nil
+}
+
+func parseVault(result *Config, list *ast.ObjectList) error {
+	if len(list.Items) > 1 {
+		return fmt.Errorf("only one 'vault' block is permitted")
+	}
+
+	item := list.Items[0]
+
+	valid := []string{
+		"address",
+		"ca_cert",
+		"ca_path",
+		"client_cert",
+		"client_key",
+		"tls_skip_verify",
+	}
+	if err := checkHCLKeys(item.Val, valid); err != nil {
+		return err
+	}
+
+	var v Vault
+	if err := hcl.DecodeObject(&v, item.Val); err != nil {
+		return err
+	}
+
+	if v.TLSSkipVerifyRaw != nil {
+		var err error
+		if v.TLSSkipVerify, err = parseutil.ParseBool(v.TLSSkipVerifyRaw); err != nil {
+			return multierror.Prefix(err, "tls_skip_verify:")
+		}
+	}
+
+	result.Vault = &v
+	return nil
+}
+
+func parseAutoAuth(result *Config, list *ast.ObjectList) error {
+	if len(list.Items) > 1 {
+		return fmt.Errorf("only one 'auto_auth' block is permitted")
+	}
+
+	item := list.Items[0]
+	if err := checkHCLKeys(item.Val, []string{"method", "sink"}); err != nil {
+		return err
+	}
+
+	subList, ok := item.Val.(*ast.ObjectType)
+	if !ok {
+		return fmt.Errorf("could not parse 'auto_auth' block")
+	}
+
+	var a AutoAuth
+
+	methods := subList.List.Filter("method")
+	switch len(methods.Items) {
+	case 0:
+		return fmt.Errorf("missing 'method' block")
+	case 1:
+	default:
+		return fmt.Errorf("only one 'method' block is permitted")
+	}
+	method, err := parseMethod(methods.Items[0])
+	if err != nil {
+		return multierror.Prefix(err, "method:")
+	}
+	a.Method = method
+
+	for _, item := range subList.List.Filter("sink").Items {
+		sink, err := parseSink(item)
+		if err != nil {
+			return multierror.Prefix(err, "sink:")
+		}
+		a.Sinks = append(a.Sinks, sink)
+	}
+
+	result.AutoAuth = &a
+	return nil
+}
+
+func parseMethod(item *ast.ObjectItem) (*Method, error) {
+	if len(item.Keys) == 0 {
+		return nil, fmt.Errorf("missing type of auth method")
+	}
+	methodType := strings.ToLower(item.Keys[0].Token.Value().(string))
+
+	if err := checkHCLKeys(item.Val, []string{"mount_path", "config"}); err != nil {
+		return nil, multierror.Prefix(err, methodType+":")
+	}
+
+	var m struct {
+		MountPath string `hcl:"mount_path"`
+	}
+	if err := hcl.DecodeObject(&m, item.Val); err != nil {
+		return nil, multierror.Prefix(err, methodType+":")
+	}
+
+	config, err := parseConfigBlock(item)
+	if err != nil {
+		return nil, multierror.Prefix(err, methodType+":")
+	}
+
+	mountPath := strings.Trim(m.MountPath, "/")
+	if mountPath == "" {
+		mountPath = "auth/" + methodType
+	}
+
+	return &Method{
+		Type:      methodType,
+		MountPath: mountPath,
+		Config:    config,
+	}, nil
+}
+
+func parseSink(item *ast.ObjectItem) (*Sink, error) {
+	if len(item.Keys) == 0 {
+		return nil, fmt.Errorf("missing type of sink")
+	}
+	sinkType := strings.ToLower(item.Keys[0].Token.Value().(string))
+
+	if err := checkHCLKeys(item.Val, []string{"config"}); err != nil {
+		return nil, multierror.Prefix(err, sinkType+":")
+	}
+
+	config, err := parseConfigBlock(item)
+	if err != nil {
+		return nil, multierror.Prefix(err, sinkType+":")
+	}
+
+	return &Sink{
+		Type:   sinkType,
+		Config: config,
+	}, nil
+}
+
+// parseConfigBlock returns the config block of a method or sink
+func parseConfigBlock(item *ast.ObjectItem) (map[string]interface{}, error) {
+	obj, ok := item.Val.(*ast.ObjectType)
+	if !ok {
+		return nil, fmt.Errorf("could not parse block")
+	}
+
+	configs := obj.List.Filter("config").Items
+	switch len(configs) {
+	case 0:
+		return map[string]interface{}{}, nil
+	case 1:
+	default:
+		return nil, fmt.Errorf("only one 'config' block is permitted")
+	}
+
+	var m map[string]interface{}
+	if err := hcl.DecodeObject(&m, configs[0].Val); err != nil {
+		return nil, multierror.Prefix(err, "config:")
+	}
+	return m, nil
+}
+
+func parseCache(result *Config, list *ast.ObjectList) error {
+	if len(list.Items) > 1 {
+		return fmt.Errorf("only one 'cache' block is permitted")
+	}
+
+	item := list.Items[0]
+	if err := checkHCLKeys(item.Val, []string{"use_auto_auth_token"}); err != nil {
+		return err
+	}
+
+	var c Cache
+	if err := hcl.DecodeObject(&c, item.Val); err != nil {
+		return err
+	}
+
+	if c.UseAutoAuthTokenRaw != nil {
+		var err error
+		if c.UseAutoAuthToken, err = parseutil.ParseBool(c.UseAutoAuthTokenRaw); err != nil {
+			return multierror.Prefix(err, "use_auto_auth_token:")
+		}
+	}
+
+	result.Cache = &c
+	return nil
+}
+
+func parseListeners(result *Config, list *ast.ObjectList) error {
+	for _, item := range list.Items {
+		if len(item.Keys) == 0 {
+			return fmt.Errorf("missing type of listener")
+		}
+		key := item.Keys[0].Token.Value().(string)
+
+		valid := []string{
+			"address",
+			"proxy_protocol_behavior",
+			"proxy_protocol_authorized_addrs",
+			"socket_mode",
+			"socket_user",
+			"socket_group",
+			"tls_disable",
+			"tls_cert_file",
+			"tls_key_file",
+			"tls_min_version",
+			"tls_cipher_suites",
+			"tls_prefer_server_cipher_suites",
+			"tls_require_and_verify_client_cert",
+			"tls_disable_client_certs",
+			"tls_client_ca_file",
+			"tls_client_crl_file",
+		}
+		if err := checkHCLKeys(item.Val, valid); err != nil {
+			return multierror.Prefix(err, key+":")
+		}
+
+		var m map[string]interface{}
+		if err := hcl.DecodeObject(&m, item.Val); err != nil {
+			return multierror.Prefix(err, key+":")
+		}
+
+		result.Listeners = append(result.Listeners, &Listener{
+			Type:   strings.ToLower(key),
+			Config: m,
+		})
+	}
+
+	return nil
+}
+
+func parseTemplates(result *Config, list *ast.ObjectList) error {
+	for _, item := range list.Items {
+		valid := []string{
+			"source",
+			"contents",
+			"destination",
+			"command",
+			"perms",
+		}
+		if err := checkHCLKeys(item.Val, valid); err != nil {
+			return err
+		}
+
+		var t Template
+		if err := hcl.DecodeObject(&t, item.Val); err != nil {
+			return err
+		}
+
+		switch {
+		case t.Source == "" && t.Contents == "":
+			return fmt.Errorf("one of 'source' or 'contents' must be set")
+		case t.Source != "" && t.Contents != "":
+			return fmt.Errorf("only one of 'source' or 'contents' can be set")
+		case t.Destination == "":
+			return fmt.Errorf("missing 'destination'")
+		}
+
+		t.Perms = 0644
+		switch perms := t.PermsRaw.(type) {
+		case nil:
+		case int:
+			// HCL parses numbers with a leading zero as octal
+			t.Perms = os.FileMode(perms)
+		case string:
+			p, err := strconv.ParseUint(perms, 8, 32)
+			if err != nil {
+				return multierror.Prefix(err, "perms:")
+			}
+			t.Perms = os.FileMode(p)
+		default:
+			return fmt.Errorf("perms: invalid file permissions %v", perms)
+		}
+
+		result.Templates = append(result.Templates, &t)
+	}
+
+	return nil
+}
+
+func checkHCLKeys(node ast.Node, valid []string) error {
+	var list *ast.ObjectList
+	switch n := node.(type) {
+	case *ast.ObjectList:
+		list = n
+	case *ast.ObjectType:
+		list = n.List
+	default:
+		return fmt.Errorf("cannot check HCL keys of type %T", n)
+	}
+
+	validMap := make(map[string]struct{}, len(valid))
+	for _, v := range valid {
+		validMap[v] = struct{}{}
+	}
+
+	var result error
+	for _, item := range list.Items {
+		key := item.Keys[0].Token.Value().(string)
+		if _, ok := validMap[key]; !ok {
+			result = multierror.Append(result, fmt.Errorf(
+				"invalid key '%s' on line %d", key, item.Assign.Line))
+		}
+	}
+
+	return result
+}
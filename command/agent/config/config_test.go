@@ -0,0 +1,192 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	config, err := LoadConfig("./test-fixtures/config.hcl")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := &Config{
+		Vault: &Vault{
+			Address:          "https://127.0.0.1:8200",
+			CACert:           "/etc/vault/ca.pem",
+			TLSSkipVerify:    true,
+			TLSSkipVerifyRaw: "true",
+		},
+		AutoAuth: &AutoAuth{
+			Method: &Method{
+				Type:      "approle",
+				MountPath: "auth/approle-agent",
+				Config: map[string]interface{}{
+					"role_id_file_path":   "/etc/vault/role_id",
+					"secret_id_file_path": "/etc/vault/secret_id",
+				},
+			},
+			Sinks: []*Sink{
+				&Sink{
+					Type: "file",
+					Config: map[string]interface{}{
+						"path": "/var/run/vault/token",
+					},
+				},
+				&Sink{
+					Type: "file",
+					Config: map[string]interface{}{
+						"path": "/var/run/app/token",
+						"mode": 0600,
+					},
+				},
+			},
+		},
+		Cache: &Cache{
+			UseAutoAuthToken:    true,
+			UseAutoAuthTokenRaw: true,
+		},
+		Listeners: []*Listener{
+			&Listener{
+				Type: "tcp",
+				Config: map[string]interface{}{
+					"address":     "127.0.0.1:8100",
+					"tls_disable": true,
+				},
+			},
+		},
+		Templates: []*Template{
+			&Template{
+				Source:      "/etc/vault/app.ctmpl",
+				Destination: "/etc/app/app.conf",
+				Command:     "systemctl reload app",
+				Perms:       0644,
+			},
+			&Template{
+				Contents:    `{{ with secret "secret/app" }}{{ .Data.password }}{{ end }}`,
+				Destination: "/etc/app/password",
+				Perms:       0600,
+				PermsRaw:    "0600",
+			},
+		},
+	}
+	if !reflect.DeepEqual(config, expected) {
+		t.Fatalf("bad config:\nexpected: %#v\nactual:   %#v", expected, config)
+	}
+}
+
+func TestParseConfig_defaults(t *testing.T) {
+	config, err := ParseConfig(`
+auto_auth {
+  method "kubernetes" {
+    config = {
+      role = "app"
+    }
+  }
+}
+`)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	method := config.AutoAuth.Method
+	if method.Type != "kubernetes" || method.MountPath != "auth/kubernetes" {
+		t.Fatalf("bad method: %#v", method)
+	}
+	if config.Vault != nil || len(config.AutoAuth.Sinks) != 0 || len(config.Templates) != 0 {
+		t.Fatalf("bad config: %#v", config)
+	}
+}
+
+func TestParseConfig_errors(t *testing.T) {
+	cases := map[string]struct {
+		config string
+		err    string
+	}{
+		"missing auto_auth": {
+			`vault { address = "http://127.0.0.1:8200" }`,
+			"one of 'auto_auth' or 'cache' blocks must be set",
+		},
+		"template without auto_auth": {
+			`
+cache {}
+listener "tcp" { address = "127.0.0.1:8100" }
+template {
+  contents    = "foo"
+  destination = "/tmp/out"
+}
+`,
+			"'template' blocks require an 'auto_auth' block",
+		},
+		"auto-auth token without auto_auth": {
+			`
+cache { use_auto_auth_token = true }
+listener "tcp" { address = "127.0.0.1:8100" }
+`,
+			"requires an 'auto_auth' block",
+		},
+		"cache without listener": {
+			`cache {}`,
+			"requires at least one 'listener' block",
+		},
+		"listener without cache": {
+			`
+auto_auth { method "approle" {} }
+listener "tcp" { address = "127.0.0.1:8100" }
+`,
+			"'listener' blocks require a 'cache' block",
+		},
+		"invalid listener key": {
+			`
+cache {}
+listener "tcp" { foo = "bar" }
+`,
+			"invalid key 'foo'",
+		},
+		"missing method": {
+			`auto_auth { sink "file" { config = { path = "/tmp/token" } } }`,
+			"missing 'method' block",
+		},
+		"invalid key": {
+			`auto_auth { method "approle" { foo = "bar" } }`,
+			"invalid key 'foo'",
+		},
+		"template without source": {
+			`
+auto_auth { method "approle" {} }
+template { destination = "/tmp/out" }
+`,
+			"one of 'source' or 'contents' must be set",
+		},
+		"template without destination": {
+			`
+auto_auth { method "approle" {} }
+template { contents = "foo" }
+`,
+			"missing 'destination'",
+		},
+		"invalid perms": {
+			`
+auto_auth { method "approle" {} }
+template {
+  contents    = "foo"
+  destination = "/tmp/out"
+  perms       = "rw"
+}
+`,
+			"perms:",
+		},
+	}
+
+	for name, tc := range cases {
+		_, err := ParseConfig(tc.config)
+		if err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+		if !strings.Contains(err.Error(), tc.err) {
+			t.Fatalf("%s: expected error containing %q, got %q", name, tc.err, err)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+vault {
+  address         = "https://127.0.0.1:8200"
+  ca_cert         = "/etc/vault/ca.pem"
+  tls_skip_verify = "true"
+}
+
+auto_auth {
+  method "approle" {
+    mount_path = "auth/approle-agent/"
+
+    config = {
+      role_id_file_path   = "/etc/vault/role_id"
+      secret_id_file_path = "/etc/vault/secret_id"
+    }
+  }
+
+  sink "file" {
+    config = {
+      path = "/var/run/vault/token"
+    }
+  }
+
+  sink "file" {
+    config = {
+      path = "/var/run/app/token"
+      mode = 0600
+    }
+  }
+}
+
+template {
+  source      = "/etc/vault/app.ctmpl"
+  destination = "/etc/app/app.conf"
+  command     = "systemctl reload app"
+}
+
+template {
+  contents    = "{{ with secret \"secret/app\" }}{{ .Data.password }}{{ end }}"
+  destination = "/etc/app/password"
+  perms       = "0600"
+}
+
+cache {
+  use_auto_auth_token = true
+}
+
+listener "tcp" {
+  address     = "127.0.0.1:8100"
+  tls_disable = true
+}
@@ -0,0 +1,85 @@
+package file
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/command/agent/sink"
+	log "github.com/mgutz/logxi/v1"
+)
+
+// defaultMode is the default mode of the token file, which must not be
+// readable by other users
+const defaultMode os.FileMode = 0640
+
+type fileSink struct {
+	logger log.Logger
+	path   string
+	mode   os.FileMode
+}
+
+// NewFileSink returns a sink writing the token to a file. The file is
+// replaced atomically, so that readers never see a partially written token.
+func NewFileSink(conf *sink.SinkConfig) (sink.Sink, error) {
+	if conf == nil {
+		return nil, errors.New("empty config")
+	}
+	if conf.Config == nil {
+		return nil, errors.New("empty config data")
+	}
+
+	f := &fileSink{
+		logger: conf.Logger,
+		mode:   defaultMode,
+	}
+
+	pathRaw, ok := conf.Config["path"]
+	if !ok {
+		return nil, errors.New("missing 'path' value")
+	}
+	f.path, ok = pathRaw.(string)
+	if !ok || f.path == "" {
+		return nil, errors.New("could not convert 'path' config value to string")
+	}
+
+	if modeRaw, ok := conf.Config["mode"]; ok {
+		mode, ok := modeRaw.(int)
+		if !ok {
+			return nil, errors.New("could not convert 'mode' config value to file mode")
+		}
+		f.mode = os.FileMode(mode)
+	}
+
+	return f, nil
+}
+
+func (f *fileSink) WriteToken(token string) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(f.path), "."+filepath.Base(f.path)+".tmp")
+	if err != nil {
+		return errwrap.Wrapf(fmt.Sprintf("error creating temporary file for %s: {{err}}", f.path), err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(f.mode); err != nil {
+		tmp.Close()
+		return errwrap.Wrapf(fmt.Sprintf("error setting mode of %s: {{err}}", tmp.Name()), err)
+	}
+	if _, err := tmp.WriteString(token); err != nil {
+		tmp.Close()
+		return errwrap.Wrapf(fmt.Sprintf("error writing %s: {{err}}", tmp.Name()), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return errwrap.Wrapf(fmt.Sprintf("error closing %s: {{err}}", tmp.Name()), err)
+	}
+
+	if err := os.Rename(tmp.Name(), f.path); err != nil {
+		return errwrap.Wrapf(fmt.Sprintf("error writing %s: {{err}}", f.path), err)
+	}
+
+	f.logger.Info("sink.file: token written", "path", f.path)
+	return nil
+}
@@ -0,0 +1,78 @@
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/vault/command/agent/sink"
+	"github.com/hashicorp/vault/helper/logformat"
+	log "github.com/mgutz/logxi/v1"
+)
+
+func TestFileSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vault-agent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "token")
+	s, err := NewFileSink(&sink.SinkConfig{
+		Logger: logformat.NewVaultLogger(log.LevelTrace),
+		Config: map[string]interface{}{
+			"path": path,
+			"mode": 0600,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, token := range []string{"token1", "token2"} {
+		if err := s.WriteToken(token); err != nil {
+			t.Fatal(err)
+		}
+
+		d, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(d) != token {
+			t.Fatalf("expected token %q, got %q", token, d)
+		}
+
+		fi, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.Mode() != 0600 {
+			t.Fatalf("bad mode: %v", fi.Mode())
+		}
+	}
+
+	// The temporary files are cleaned up
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("expected only the token file, got %d files", len(files))
+	}
+}
+
+func TestFileSink_config(t *testing.T) {
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+
+	for _, config := range []map[string]interface{}{
+		nil,
+		map[string]interface{}{},
+		map[string]interface{}{"path": ""},
+		map[string]interface{}{"path": "/tmp/token", "mode": "0600"},
+	} {
+		if _, err := NewFileSink(&sink.SinkConfig{Logger: logger, Config: config}); err == nil {
+			t.Fatalf("expected error for config %#v", config)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package inmem
+
+import (
+	"sync"
+
+	"github.com/hashicorp/vault/command/agent/sink"
+)
+
+// Sink keeps the token of the agent in memory, for the API proxy to use it
+// for the requests that have no token
+type Sink struct {
+	lock  sync.RWMutex
+	token string
+}
+
+var _ sink.Sink = (*Sink)(nil)
+
+// New returns an empty in-memory sink
+func New() *Sink {
+	return &Sink{}
+}
+
+func (s *Sink) WriteToken(token string) error {
+	s.lock.Lock()
+	s.token = token
+	s.lock.Unlock()
+	return nil
+}
+
+// Token returns the last token written to the sink, or the empty string if
+// the agent did not authenticate yet
+func (s *Sink) Token() string {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.token
+}
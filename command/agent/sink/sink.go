@@ -0,0 +1,75 @@
+package sink
+
+import (
+	"context"
+	"time"
+
+	log "github.com/mgutz/logxi/v1"
+)
+
+// retryInterval is the time to wait before writing a token again to the
+// sinks that failed to write it
+const retryInterval = 5 * time.Second
+
+// Sink is a destination of the token of the agent
+type Sink interface {
+	WriteToken(token string) error
+}
+
+// SinkConfig is the configuration of a sink
+type SinkConfig struct {
+	Logger log.Logger
+	Config map[string]interface{}
+}
+
+// SinkServer writes the tokens of the agent to its sinks
+type SinkServer struct {
+	logger log.Logger
+}
+
+// NewSinkServer returns a SinkServer
+func NewSinkServer(logger log.Logger) *SinkServer {
+	return &SinkServer{
+		logger: logger,
+	}
+}
+
+// Run writes each token received on incoming to all the sinks. The sinks that
+// fail to write a token are retried until they succeed or the next token is
+// received. Run returns when ctx is done.
+func (ss *SinkServer) Run(ctx context.Context, incoming <-chan string, sinks []Sink) {
+	ss.logger.Info("sink.server: starting")
+	defer ss.logger.Info("sink.server: stopped")
+
+	var token string
+	var pending []Sink
+	var retryCh <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case token = <-incoming:
+			pending = sinks
+		case <-retryCh:
+		}
+
+		pending = ss.writeToken(token, pending)
+		retryCh = nil
+		if len(pending) > 0 {
+			retryCh = time.After(retryInterval)
+		}
+	}
+}
+
+// writeToken writes the token to the sinks, and returns the sinks that failed
+// to write it
+func (ss *SinkServer) writeToken(token string, sinks []Sink) []Sink {
+	var failed []Sink
+	for _, s := range sinks {
+		if err := s.WriteToken(token); err != nil {
+			ss.logger.Error("sink.server: error writing token, retrying", "error", err, "retry_interval", retryInterval)
+			failed = append(failed, s)
+		}
+	}
+	return failed
+}
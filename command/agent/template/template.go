@@ -0,0 +1,345 @@
+package template
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/command/agent/config"
+	log "github.com/mgutz/logxi/v1"
+)
+
+const (
+	// staticSecretRefreshInterval is the interval at which the secrets that
+	// have no lease, e.g. key/value secrets, are read again
+	staticSecretRefreshInterval = 5 * time.Minute
+
+	// retryInterval is the time to wait before rendering the templates again
+	// after an error
+	retryInterval = 10 * time.Second
+
+	// commandTimeout is the timeout of the commands run when a rendered file
+	// changes
+	commandTimeout = 30 * time.Second
+)
+
+// ServerConfig is the configuration of a Server
+type ServerConfig struct {
+	Logger    log.Logger
+	Client    *api.Client
+	Templates []*config.Template
+}
+
+// Server renders templates with the secrets read from Vault with the token of
+// the agent. The templates use the text/template syntax, with a secret
+// function returning the secret at a path:
+//
+//	{{ with secret "secret/app" }}{{ .Data.password }}{{ end }}
+//
+// Extra key=value arguments of secret are written to the path, e.g. to issue
+// a certificate, instead of reading it. The leases of the secrets are renewed
+// while they can be, and the secrets are read again when they expire, so that
+// the rendered files stay up to date.
+type Server struct {
+	logger    log.Logger
+	client    *api.Client
+	templates []*serverTemplate
+
+	// secrets are the secrets used by the current rendering, by path and
+	// arguments, and previous the secrets used by the previous one, which
+	// are used again until they must be refreshed
+	secrets  map[string]*cachedSecret
+	previous map[string]*cachedSecret
+}
+
+type serverTemplate struct {
+	*config.Template
+	tmpl *template.Template
+}
+
+type cachedSecret struct {
+	secret *api.Secret
+
+	// refreshAt is when the lease of the secret is renewed or the secret is
+	// read again
+	refreshAt time.Time
+}
+
+// NewServer returns a Server rendering the templates, which are parsed first
+// so that errors in the templates are reported early
+func NewServer(conf *ServerConfig) (*Server, error) {
+	client, err := conf.Client.Clone()
+	if err != nil {
+		return nil, err
+	}
+
+	ts := &Server{
+		logger:  conf.Logger,
+		client:  client,
+		secrets: make(map[string]*cachedSecret),
+	}
+
+	for _, t := range conf.Templates {
+		contents := t.Contents
+		if t.Source != "" {
+			d, err := ioutil.ReadFile(t.Source)
+			if err != nil {
+				return nil, errwrap.Wrapf(fmt.Sprintf("error reading template %s: {{err}}", t.Source), err)
+			}
+			contents = string(d)
+		}
+
+		tmpl, err := template.New(t.Destination).Funcs(ts.funcs()).Option("missingkey=zero").Parse(contents)
+		if err != nil {
+			return nil, errwrap.Wrapf(fmt.Sprintf("error parsing template of %s: {{err}}", t.Destination), err)
+		}
+
+		ts.templates = append(ts.templates, &serverTemplate{
+			Template: t,
+			tmpl:     tmpl,
+		})
+	}
+
+	return ts, nil
+}
+
+// Run renders the templates with each token received on incoming, and again
+// each time a secret they use must be read again. Run returns when ctx is
+// done.
+func (ts *Server) Run(ctx context.Context, incoming <-chan string) {
+	ts.logger.Info("template.server: starting")
+	defer ts.logger.Info("template.server: stopped")
+
+	var renderCh <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case token := <-incoming:
+			ts.client.SetToken(token)
+			// The secrets are read again with the new token
+			ts.secrets = make(map[string]*cachedSecret)
+
+		case <-renderCh:
+		}
+
+		next, err := ts.render(ctx)
+		if err != nil {
+			ts.logger.Error("template.server: error rendering templates, retrying", "error", err, "retry_interval", retryInterval)
+			next = time.Now().Add(retryInterval)
+		}
+		renderCh = time.After(time.Until(next))
+	}
+}
+
+// render renders all the templates, writing the files that changed, and
+// returns when they must be rendered again
+func (ts *Server) render(ctx context.Context) (next time.Time, err error) {
+	ts.previous = ts.secrets
+	ts.secrets = make(map[string]*cachedSecret)
+	defer func() {
+		// On errors, the secrets of the previous rendering that were not
+		// used yet are kept, so that they are used by the next one
+		if err != nil {
+			for key, s := range ts.previous {
+				if _, ok := ts.secrets[key]; !ok {
+					ts.secrets[key] = s
+				}
+			}
+		}
+		ts.previous = nil
+	}()
+
+	for _, t := range ts.templates {
+		var buf bytes.Buffer
+		if err := t.tmpl.Execute(&buf, nil); err != nil {
+			return time.Time{}, errwrap.Wrapf(fmt.Sprintf("error rendering %s: {{err}}", t.Destination), err)
+		}
+
+		changed, err := writeFile(t.Destination, buf.Bytes(), t.Perms)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if !changed {
+			continue
+		}
+		ts.logger.Info("template.server: rendered template", "destination", t.Destination)
+
+		if t.Command != "" {
+			if err := runCommand(ctx, t.Command); err != nil {
+				// The file is rendered, so this is not retried
+				ts.logger.Error("template.server: error running command", "destination", t.Destination, "command", t.Command, "error", err)
+			}
+		}
+	}
+
+	next = time.Now().Add(staticSecretRefreshInterval)
+	for _, s := range ts.secrets {
+		if s.refreshAt.Before(next) {
+			next = s.refreshAt
+		}
+	}
+	return next, nil
+}
+
+// funcs returns the functions of the templates
+func (ts *Server) funcs() template.FuncMap {
+	return template.FuncMap{
+		"secret": ts.secretFunc,
+		"env":    os.Getenv,
+	}
+}
+
+// secretFunc returns the secret at the path. Extra key=value arguments are
+// written to the path instead of reading it.
+func (ts *Server) secretFunc(path string, args ...string) (*api.Secret, error) {
+	data := make(map[string]interface{}, len(args))
+	for _, arg := range args {
+		parts := strings.SplitN(arg, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid argument %q of secret %s, must be key=value", arg, path)
+		}
+		data[parts[0]] = parts[1]
+	}
+
+	keyArgs := append([]string(nil), args...)
+	sort.Strings(keyArgs)
+	key := strings.Join(append([]string{path}, keyArgs...), "\x00")
+
+	// The secret may already have been used by another template
+	if s, ok := ts.secrets[key]; ok {
+		return s.secret, nil
+	}
+
+	now := time.Now()
+	if prev, ok := ts.previous[key]; ok && prev.secret.LeaseID != "" {
+		if now.Before(prev.refreshAt) {
+			ts.secrets[key] = prev
+			return prev.secret, nil
+		}
+		if prev.secret.Renewable {
+			if s := ts.renew(prev); s != nil {
+				ts.secrets[key] = s
+				return s.secret, nil
+			}
+		}
+	}
+
+	var secret *api.Secret
+	var err error
+	if len(args) > 0 {
+		secret, err = ts.client.Logical().Write(path, data)
+	} else {
+		secret, err = ts.client.Logical().Read(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil {
+		return nil, fmt.Errorf("no secret at %s", path)
+	}
+
+	s := &cachedSecret{
+		secret:    secret,
+		refreshAt: now.Add(staticSecretRefreshInterval),
+	}
+	if secret.LeaseID != "" {
+		s.refreshAt = now.Add(refreshDuration(secret.LeaseDuration))
+	}
+	ts.secrets[key] = s
+	return secret, nil
+}
+
+// renew renews the lease of the secret, and returns nil if it cannot be
+// renewed so that the secret is read again
+func (ts *Server) renew(s *cachedSecret) *cachedSecret {
+	renewal, err := ts.client.Sys().Renew(s.secret.LeaseID, 0)
+	if err != nil || renewal == nil || renewal.LeaseDuration <= 0 {
+		if err != nil {
+			ts.logger.Warn("template.server: error renewing lease, reading secret again", "lease_id", s.secret.LeaseID, "error", err)
+		}
+		return nil
+	}
+
+	secret := *s.secret
+	secret.LeaseDuration = renewal.LeaseDuration
+	secret.Renewable = renewal.Renewable
+	return &cachedSecret{
+		secret:    &secret,
+		refreshAt: time.Now().Add(refreshDuration(renewal.LeaseDuration)),
+	}
+}
+
+// refreshDuration returns the time after which a lease of the given duration,
+// in seconds, is renewed, leaving a third of the lease to renew it or read the
+// secret again
+func refreshDuration(leaseDuration int) time.Duration {
+	d := time.Duration(leaseDuration) * time.Second * 2 / 3
+	if d < time.Second {
+		d = time.Second
+	}
+	return d
+}
+
+// writeFile writes the contents to the file unless they did not change, and
+// returns whether the file was written. The file is replaced atomically.
+func writeFile(path string, contents []byte, perms os.FileMode) (bool, error) {
+	existing, err := ioutil.ReadFile(path)
+	if err == nil && bytes.Equal(existing, contents) {
+		return false, nil
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return false, errwrap.Wrapf(fmt.Sprintf("error creating temporary file for %s: {{err}}", path), err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(perms); err != nil {
+		tmp.Close()
+		return false, errwrap.Wrapf(fmt.Sprintf("error setting permissions of %s: {{err}}", tmp.Name()), err)
+	}
+	if _, err := tmp.Write(contents); err != nil {
+		tmp.Close()
+		return false, errwrap.Wrapf(fmt.Sprintf("error writing %s: {{err}}", tmp.Name()), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return false, errwrap.Wrapf(fmt.Sprintf("error closing %s: {{err}}", tmp.Name()), err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return false, errwrap.Wrapf(fmt.Sprintf("error writing %s: {{err}}", path), err)
+	}
+	return true, nil
+}
+
+// runCommand runs the command with the shell
+func runCommand(ctx context.Context, command string) error {
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "/bin/sh", "-c", command)
+	}
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+package template
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/command/agent/config"
+	"github.com/hashicorp/vault/helper/logformat"
+	vaulthttp "github.com/hashicorp/vault/http"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/vault"
+	log "github.com/mgutz/logxi/v1"
+)
+
+func testClient(t *testing.T) (*api.Client, func()) {
+	cluster := vault.NewTestCluster(t, &vault.CoreConfig{
+		LogicalBackends: map[string]logical.Factory{
+			"generic-leased": vault.LeasedPassthroughBackendFactory,
+		},
+	}, &vault.TestClusterOptions{
+		HandlerFunc: vaulthttp.Handler,
+		NumCores:    1,
+	})
+	cluster.Start()
+	vault.TestWaitActive(t, cluster.Cores[0].Core)
+
+	client := cluster.Cores[0].Client
+	client.SetToken(cluster.RootToken)
+	return client, cluster.Cleanup
+}
+
+func TestServer_render(t *testing.T) {
+	client, closer := testClient(t)
+	defer closer()
+
+	if err := client.Sys().Mount("leased", &api.MountInput{Type: "generic-leased"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Logical().Write("secret/app", map[string]interface{}{"password": "foo"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Logical().Write("leased/db", map[string]interface{}{"username": "app", "ttl": "1h"}); err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "vault-agent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	appPath := filepath.Join(dir, "app.conf")
+	dbPath := filepath.Join(dir, "db.conf")
+	markerPath := filepath.Join(dir, "marker")
+	ts, err := NewServer(&ServerConfig{
+		Logger: logformat.NewVaultLogger(log.LevelTrace),
+		Client: client,
+		Templates: []*config.Template{
+			&config.Template{
+				Contents:    `password={{ with secret "secret/app" }}{{ .Data.password }}{{ end }}`,
+				Destination: appPath,
+				Command:     "touch " + markerPath,
+				Perms:       0600,
+			},
+			&config.Template{
+				Contents:    `{{ with secret "leased/db" }}username={{ .Data.username }} password={{ with secret "secret/app" }}{{ .Data.password }}{{ end }}{{ end }}`,
+				Destination: dbPath,
+				Perms:       0644,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts.client.SetToken(client.Token())
+
+	assertFile := func(path, expected string) {
+		t.Helper()
+		d, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(d) != expected {
+			t.Fatalf("expected %s to contain %q, got %q", path, expected, d)
+		}
+	}
+	assertMarker := func(expected bool) {
+		t.Helper()
+		_, err := os.Stat(markerPath)
+		if exists := err == nil; exists != expected {
+			t.Fatalf("expected command run: %t", expected)
+		}
+		os.Remove(markerPath)
+	}
+
+	ctx := context.Background()
+	next, err := ts.render(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertFile(appPath, "password=foo")
+	assertFile(dbPath, "username=app password=foo")
+	assertMarker(true)
+	if fi, err := os.Stat(appPath); err != nil || fi.Mode() != 0600 {
+		t.Fatalf("bad mode: %v", fi.Mode())
+	}
+	if until := time.Until(next); until > staticSecretRefreshInterval || until < staticSecretRefreshInterval-time.Minute {
+		t.Fatalf("bad next rendering in %s", until)
+	}
+
+	leaseID := ts.secrets["leased/db"].secret.LeaseID
+	if leaseID == "" {
+		t.Fatal("expected lease")
+	}
+
+	// Unchanged files are not written again
+	if _, err := ts.render(ctx); err != nil {
+		t.Fatal(err)
+	}
+	assertMarker(false)
+	if ts.secrets["leased/db"].secret.LeaseID != leaseID {
+		t.Fatal("expected lease to be used again")
+	}
+
+	// Leases are renewed once they must be refreshed
+	ts.secrets["leased/db"].refreshAt = time.Now().Add(-time.Second)
+	if _, err := ts.render(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if s := ts.secrets["leased/db"]; s.secret.LeaseID != leaseID || !s.refreshAt.After(time.Now()) {
+		t.Fatalf("expected lease to be renewed: %#v", s)
+	}
+
+	// Static secrets are read again
+	if _, err := client.Logical().Write("secret/app", map[string]interface{}{"password": "bar"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ts.render(ctx); err != nil {
+		t.Fatal(err)
+	}
+	assertFile(appPath, "password=bar")
+	assertFile(dbPath, "username=app password=bar")
+	assertMarker(true)
+
+	// Revoked leases are read again
+	if err := client.Sys().Revoke(leaseID); err != nil {
+		t.Fatal(err)
+	}
+	ts.secrets["leased/db"].refreshAt = time.Now().Add(-time.Second)
+	if _, err := ts.render(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if s := ts.secrets["leased/db"]; s.secret.LeaseID == "" || s.secret.LeaseID == leaseID {
+		t.Fatalf("expected new lease: %#v", s)
+	}
+}
+
+func TestServer_errors(t *testing.T) {
+	client, closer := testClient(t)
+	defer closer()
+
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+	if _, err := NewServer(&ServerConfig{
+		Logger: logger,
+		Client: client,
+		Templates: []*config.Template{
+			&config.Template{Contents: "{{ with secret }", Destination: "/tmp/out"},
+		},
+	}); err == nil {
+		t.Fatal("expected parse error")
+	}
+
+	dir, err := ioutil.TempDir("", "vault-agent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ts, err := NewServer(&ServerConfig{
+		Logger: logger,
+		Client: client,
+		Templates: []*config.Template{
+			&config.Template{
+				Contents:    `{{ with secret "secret/missing" }}{{ .Data.password }}{{ end }}`,
+				Destination: filepath.Join(dir, "out"),
+				Perms:       0644,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts.client.SetToken(client.Token())
+
+	if _, err := ts.render(context.Background()); err == nil {
+		t.Fatal("expected error rendering missing secret")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "out")); !os.IsNotExist(err) {
+		t.Fatal("expected file not to be rendered")
+	}
+}
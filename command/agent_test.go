@@ -0,0 +1,260 @@
+package command
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	credAppRole "github.com/hashicorp/vault/builtin/credential/approle"
+	vaulthttp "github.com/hashicorp/vault/http"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/vault"
+	"github.com/mitchellh/cli"
+)
+
+func testAgentCommand(tb testing.TB) (*cli.MockUi, *AgentCommand) {
+	tb.Helper()
+
+	ui := cli.NewMockUi()
+	return ui, &AgentCommand{
+		BaseCommand: &BaseCommand{
+			UI: ui,
+		},
+		ShutdownCh: make(chan struct{}),
+		logger:     defaultVaultLogger,
+	}
+}
+
+func TestAgentCommand_Run(t *testing.T) {
+	t.Parallel()
+
+	t.Run("validations", func(t *testing.T) {
+		t.Parallel()
+
+		cases := []struct {
+			name string
+			args []string
+			out  string
+		}{
+			{
+				"no_config",
+				nil,
+				"Must specify exactly one config path",
+			},
+			{
+				"too_many_args",
+				[]string{"-config", "agent.hcl", "foo"},
+				"Too many arguments",
+			},
+			{
+				"missing_config",
+				[]string{"-config", "/nonexistent/agent.hcl"},
+				"Error loading configuration",
+			},
+		}
+
+		for _, tc := range cases {
+			tc := tc
+
+			t.Run(tc.name, func(t *testing.T) {
+				t.Parallel()
+
+				ui, cmd := testAgentCommand(t)
+				code := cmd.Run(tc.args)
+				if code != 1 {
+					t.Errorf("expected %d to be %d", code, 1)
+				}
+
+				combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+				if !strings.Contains(combined, tc.out) {
+					t.Errorf("expected %q to contain %q", combined, tc.out)
+				}
+			})
+		}
+	})
+
+	t.Run("approle", func(t *testing.T) {
+		t.Parallel()
+
+		cluster := vault.NewTestCluster(t, &vault.CoreConfig{
+			DisableMlock: true,
+			DisableCache: true,
+			Logger:       defaultVaultLogger,
+			CredentialBackends: map[string]logical.Factory{
+				"approle": credAppRole.Factory,
+			},
+		}, &vault.TestClusterOptions{
+			HandlerFunc: vaulthttp.Handler,
+			NumCores:    1,
+		})
+		cluster.Start()
+		defer cluster.Cleanup()
+		vault.TestWaitActive(t, cluster.Cores[0].Core)
+
+		client := cluster.Cores[0].Client
+		client.SetToken(cluster.RootToken)
+
+		if err := client.Sys().PutPolicy("agent", `path "secret/*" { capabilities = ["read"] }`); err != nil {
+			t.Fatal(err)
+		}
+		if err := client.Sys().EnableAuthWithOptions("approle", &api.EnableAuthOptions{Type: "approle"}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := client.Logical().Write("auth/approle/role/agent", map[string]interface{}{
+			"policies": "agent",
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := client.Logical().Write("secret/app", map[string]interface{}{"password": "foo"}); err != nil {
+			t.Fatal(err)
+		}
+
+		secret, err := client.Logical().Read("auth/approle/role/agent/role-id")
+		if err != nil {
+			t.Fatal(err)
+		}
+		roleID := secret.Data["role_id"].(string)
+		secret, err = client.Logical().Write("auth/approle/role/agent/secret-id", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		secretID := secret.Data["secret_id"].(string)
+
+		dir, err := ioutil.TempDir("", "vault-agent")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		roleIDPath := filepath.Join(dir, "role_id")
+		secretIDPath := filepath.Join(dir, "secret_id")
+		tokenPath := filepath.Join(dir, "token")
+		renderedPath := filepath.Join(dir, "app.conf")
+		if err := ioutil.WriteFile(roleIDPath, []byte(roleID+"\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(secretIDPath, []byte(secretID+"\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		listenerAddr := ln.Addr().String()
+		ln.Close()
+
+		configPath := filepath.Join(dir, "agent.hcl")
+		if err := ioutil.WriteFile(configPath, []byte(fmt.Sprintf(`
+vault {
+  address = %q
+  ca_cert = %q
+}
+
+auto_auth {
+  method "approle" {
+    config = {
+      role_id_file_path   = %q
+      secret_id_file_path = %q
+    }
+  }
+
+  sink "file" {
+    config = {
+      path = %q
+    }
+  }
+}
+
+template {
+  contents    = "{{ with secret \"secret/app\" }}{{ .Data.password }}{{ end }}"
+  destination = %q
+}
+
+cache {
+  use_auto_auth_token = true
+}
+
+listener "tcp" {
+  address     = %q
+  tls_disable = true
+}
+`, client.Address(), cluster.CACertPEMFile, roleIDPath, secretIDPath, tokenPath, renderedPath, listenerAddr)), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		ui, cmd := testAgentCommand(t)
+		codeCh := make(chan int)
+		go func() {
+			codeCh <- cmd.Run([]string{"-config", configPath})
+		}()
+
+		waitForFile := func(path string) string {
+			t.Helper()
+			for i := 0; i < 100; i++ {
+				if d, err := ioutil.ReadFile(path); err == nil {
+					return string(d)
+				}
+				time.Sleep(100 * time.Millisecond)
+			}
+			t.Fatalf("timed out waiting for %s: %s", path, ui.ErrorWriter.String())
+			return ""
+		}
+
+		token := waitForFile(tokenPath)
+		lookup, err := client.Auth().Token().Lookup(token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		policies, err := lookup.TokenPolicies()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(strings.Join(policies, ","), "agent") {
+			t.Fatalf("bad policies: %v", policies)
+		}
+
+		if rendered := waitForFile(renderedPath); rendered != "foo" {
+			t.Fatalf("expected rendered template %q, got %q", "foo", rendered)
+		}
+
+		// Requests with no token are proxied with the token of the agent
+		agentConfig := api.DefaultConfig()
+		agentConfig.Address = "http://" + listenerAddr
+		agentClient, err := api.NewClient(agentConfig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		agentClient.ClearToken()
+		secret, err = agentClient.Logical().Read("secret/app")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if secret == nil || secret.Data["password"] != "foo" {
+			t.Fatalf("bad secret: %#v", secret)
+		}
+
+		close(cmd.ShutdownCh)
+		select {
+		case code := <-codeCh:
+			if code != 0 {
+				t.Fatalf("expected %d to be %d: %s", code, 0, ui.ErrorWriter.String())
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatal("timed out waiting for agent to stop")
+		}
+	})
+
+	t.Run("no_tabs", func(t *testing.T) {
+		t.Parallel()
+
+		_, cmd := testAgentCommand(t)
+		assertNoTabs(t, cmd)
+	})
+}
@@ -39,7 +39,7 @@ Usage: vault audit disable [options] PATH
 }
 
 func (c *AuditDisableCommand) Flags() *FlagSets {
-	return c.flagSet(FlagSetHTTP)
+	return c.flagSet(FlagSetHTTP | FlagSetOutputField | FlagSetOutputFormat)
 }
 
 func (c *AuditDisableCommand) AutocompleteArgs() complete.Predictor {
@@ -81,7 +81,9 @@ func (c *AuditDisableCommand) Run(args []string) int {
 		return 2
 	}
 
-	c.UI.Output(fmt.Sprintf("Success! Disabled audit device (if it was enabled) at: %s", path))
-
-	return 0
+	return OutputSuccess(c.UI, c.flagFormat, c.flagField,
+		fmt.Sprintf("Success! Disabled audit device (if it was enabled) at: %s", path),
+		map[string]interface{}{
+			"path": path,
+		})
 }
@@ -49,7 +49,7 @@ Usage: vault audit enable [options] TYPE [CONFIG K=V...]
 }
 
 func (c *AuditEnableCommand) Flags() *FlagSets {
-	set := c.flagSet(FlagSetHTTP)
+	set := c.flagSet(FlagSetHTTP | FlagSetOutputField | FlagSetOutputFormat)
 
 	f := set.NewFlagSet("Command Options")
 
@@ -89,6 +89,7 @@ func (c *AuditEnableCommand) Flags() *FlagSets {
 func (c *AuditEnableCommand) AutocompleteArgs() complete.Predictor {
 	return complete.PredictSet(
 		"file",
+		"http",
 		"syslog",
 		"socket",
 	)
@@ -149,6 +150,10 @@ func (c *AuditEnableCommand) Run(args []string) int {
 		return 2
 	}
 
-	c.UI.Output(fmt.Sprintf("Success! Enabled the %s audit device at: %s", auditType, auditPath))
-	return 0
+	return OutputSuccess(c.UI, c.flagFormat, c.flagField,
+		fmt.Sprintf("Success! Enabled the %s audit device at: %s", auditType, auditPath),
+		map[string]interface{}{
+			"path": auditPath,
+			"type": auditType,
+		})
 }
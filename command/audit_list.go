@@ -44,7 +44,7 @@ Usage: vault audit list [options]
 }
 
 func (c *AuditListCommand) Flags() *FlagSets {
-	set := c.flagSet(FlagSetHTTP)
+	set := c.flagSet(FlagSetHTTP | FlagSetOutputFormat)
 
 	f := set.NewFlagSet("Command Options")
 
@@ -94,6 +94,10 @@ func (c *AuditListCommand) Run(args []string) int {
 		return 2
 	}
 
+	if !isTableFormat(c.flagFormat) {
+		return OutputData(c.UI, c.flagFormat, "", audits)
+	}
+
 	if len(audits) == 0 {
 		c.UI.Output(fmt.Sprintf("No audit devices are enabled."))
 		return 0
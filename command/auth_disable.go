@@ -40,7 +40,7 @@ Usage: vault auth disable [options] PATH
 }
 
 func (c *AuthDisableCommand) Flags() *FlagSets {
-	return c.flagSet(FlagSetHTTP)
+	return c.flagSet(FlagSetHTTP | FlagSetOutputField | FlagSetOutputFormat)
 }
 
 func (c *AuthDisableCommand) AutocompleteArgs() complete.Predictor {
@@ -82,6 +82,9 @@ func (c *AuthDisableCommand) Run(args []string) int {
 		return 2
 	}
 
-	c.UI.Output(fmt.Sprintf("Success! Disabled the auth method (if it existed) at: %s", path))
-	return 0
+	return OutputSuccess(c.UI, c.flagFormat, c.flagField,
+		fmt.Sprintf("Success! Disabled the auth method (if it existed) at: %s", path),
+		map[string]interface{}{
+			"path": path,
+		})
 }
@@ -15,11 +15,15 @@ var _ cli.CommandAutocomplete = (*AuthEnableCommand)(nil)
 type AuthEnableCommand struct {
 	*BaseCommand
 
-	flagDescription string
-	flagPath        string
-	flagPluginName  string
-	flagLocal       bool
-	flagSealWrap    bool
+	flagDescription   string
+	flagPath          string
+	flagPluginName    string
+	flagPluginVersion string
+	flagLocal         bool
+	flagSealWrap      bool
+
+	flagSyncExternalGroups bool
+	flagTokenType          string
 }
 
 func (c *AuthEnableCommand) Synopsis() string {
@@ -52,7 +56,7 @@ Usage: vault auth enable [options] TYPE
 }
 
 func (c *AuthEnableCommand) Flags() *FlagSets {
-	set := c.flagSet(FlagSetHTTP)
+	set := c.flagSet(FlagSetHTTP | FlagSetOutputField | FlagSetOutputFormat)
 
 	f := set.NewFlagSet("Command Options")
 
@@ -83,6 +87,14 @@ func (c *AuthEnableCommand) Flags() *FlagSets {
 			"exist in the Vault server's plugin catalog.",
 	})
 
+	f.StringVar(&StringVar{
+		Name:       "plugin-version",
+		Target:     &c.flagPluginVersion,
+		Completion: complete.PredictAnything,
+		Usage: "Version of the plugin to run. This version of the plugin must " +
+			"already be registered in the Vault server's plugin catalog.",
+	})
+
 	f.BoolVar(&BoolVar{
 		Name:    "local",
 		Target:  &c.flagLocal,
@@ -98,6 +110,24 @@ func (c *AuthEnableCommand) Flags() *FlagSets {
 		Usage:   "Enable seal wrapping of critical values in the secrets engine.",
 	})
 
+	f.BoolVar(&BoolVar{
+		Name:    "sync-external-groups",
+		Target:  &c.flagSyncExternalGroups,
+		Default: false,
+		Usage: "Create or link external identity groups for the groups returned " +
+			"by the auth method on login, such as Okta or LDAP groups.",
+	})
+
+	f.StringVar(&StringVar{
+		Name:       "token-type",
+		Target:     &c.flagTokenType,
+		Default:    "",
+		Completion: complete.PredictSet("service", "batch"),
+		Usage: "Type of the tokens issued on login, either \"service\" or " +
+			"\"batch\". Batch tokens are not persisted and cannot be renewed " +
+			"or revoked. The default is \"service\".",
+	})
+
 	return set
 }
 
@@ -155,7 +185,10 @@ func (c *AuthEnableCommand) Run(args []string) int {
 		Local:       c.flagLocal,
 		SealWrap:    c.flagSealWrap,
 		Config: api.AuthConfigInput{
-			PluginName: c.flagPluginName,
+			PluginName:         c.flagPluginName,
+			PluginVersion:      c.flagPluginVersion,
+			SyncExternalGroups: c.flagSyncExternalGroups,
+			TokenType:          c.flagTokenType,
 		},
 	}); err != nil {
 		c.UI.Error(fmt.Sprintf("Error enabling %s auth: %s", authType, err))
@@ -167,6 +200,13 @@ func (c *AuthEnableCommand) Run(args []string) int {
 		authThing = c.flagPluginName + " plugin"
 	}
 
-	c.UI.Output(fmt.Sprintf("Success! Enabled %s at: %s", authThing, authPath))
-	return 0
+	data := map[string]interface{}{
+		"path": authPath,
+		"type": authType,
+	}
+	if authType == "plugin" {
+		data["plugin_name"] = c.flagPluginName
+	}
+	return OutputSuccess(c.UI, c.flagFormat, c.flagField,
+		fmt.Sprintf("Success! Enabled %s at: %s", authThing, authPath), data)
 }
@@ -5,6 +5,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/hashicorp/vault/helper/strutil"
 	"github.com/mitchellh/cli"
 )
 
@@ -159,6 +160,10 @@ func TestAuthEnableCommand_Run(t *testing.T) {
 			if f.IsDir() {
 				backends = append(backends, f.Name())
 			}
+			// The jwt auth method is also registered as oidc
+			if f.Name() == "jwt" {
+				backends = append(backends, "oidc")
+			}
 		}
 
 		plugins, err := ioutil.ReadDir("../vendor/github.com/hashicorp")
@@ -167,7 +172,11 @@ func TestAuthEnableCommand_Run(t *testing.T) {
 		}
 		for _, p := range plugins {
 			if p.IsDir() && strings.HasPrefix(p.Name(), "vault-plugin-auth-") {
-				backends = append(backends, strings.TrimPrefix(p.Name(), "vault-plugin-auth-"))
+				// In-tree forks replace the vendored plugins
+				name := strings.TrimPrefix(p.Name(), "vault-plugin-auth-")
+				if !strutil.StrListContains(backends, name) {
+					backends = append(backends, name)
+				}
 			}
 		}
 
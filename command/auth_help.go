@@ -50,7 +50,7 @@ Usage: vault auth help [options] TYPE | PATH
 }
 
 func (c *AuthHelpCommand) Flags() *FlagSets {
-	return c.flagSet(FlagSetHTTP)
+	return c.flagSet(FlagSetHTTP | FlagSetOutputField | FlagSetOutputFormat)
 }
 
 func (c *AuthHelpCommand) AutocompleteArgs() complete.Predictor {
@@ -120,6 +120,12 @@ func (c *AuthHelpCommand) Run(args []string) int {
 		}
 	}
 
+	if c.flagField != "" || !isTableFormat(c.flagFormat) {
+		return OutputData(c.UI, c.flagFormat, c.flagField, map[string]interface{}{
+			"help": authHandler.Help(),
+		})
+	}
+
 	c.UI.Output(authHandler.Help())
 	return 0
 }
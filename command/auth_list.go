@@ -46,7 +46,7 @@ Usage: vault auth list [options]
 }
 
 func (c *AuthListCommand) Flags() *FlagSets {
-	set := c.flagSet(FlagSetHTTP)
+	set := c.flagSet(FlagSetHTTP | FlagSetOutputFormat)
 
 	f := set.NewFlagSet("Command Options")
 
@@ -95,6 +95,10 @@ func (c *AuthListCommand) Run(args []string) int {
 		return 2
 	}
 
+	if !isTableFormat(c.flagFormat) {
+		return OutputData(c.UI, c.flagFormat, "", auths)
+	}
+
 	if c.flagDetailed {
 		c.UI.Output(tableOutput(c.detailedMounts(auths), nil))
 		return 0
@@ -42,7 +42,7 @@ Usage: vault auth tune [options] PATH
 }
 
 func (c *AuthTuneCommand) Flags() *FlagSets {
-	set := c.flagSet(FlagSetHTTP)
+	set := c.flagSet(FlagSetHTTP | FlagSetOutputField | FlagSetOutputFormat)
 
 	f := set.NewFlagSet("Command Options")
 
@@ -115,6 +115,9 @@ func (c *AuthTuneCommand) Run(args []string) int {
 		return 2
 	}
 
-	c.UI.Output(fmt.Sprintf("Success! Tuned the auth method at: %s", mountPath))
-	return 0
+	return OutputSuccess(c.UI, c.flagFormat, c.flagField,
+		fmt.Sprintf("Success! Tuned the auth method at: %s", mountPath),
+		map[string]interface{}{
+			"path": mountPath,
+		})
 }
@@ -39,6 +39,8 @@ type BaseCommand struct {
 	flagTLSServerName string
 	flagTLSSkipVerify bool
 	flagWrapTTL       time.Duration
+	flagMFA           []string
+	flagNamespace     string
 
 	flagFormat string
 	flagField  string
@@ -89,6 +91,16 @@ func (c *BaseCommand) Client() (*api.Client, error) {
 	// Set the wrapping function
 	client.SetWrappingLookupFunc(c.DefaultWrappingLookupFunc)
 
+	// Set the MFA credentials
+	if len(c.flagMFA) > 0 {
+		client.SetMFACreds(c.flagMFA)
+	}
+
+	// Set the namespace the requests are made in
+	if c.flagNamespace != "" {
+		client.SetNamespace(c.flagNamespace)
+	}
+
 	// Get the token if it came in from the environment
 	token := client.Token()
 
@@ -237,6 +249,27 @@ func (c *BaseCommand) flagSet(bit FlagSetBit) *FlagSets {
 					"The TTL is specified as a numeric string with suffix like \"30s\" " +
 					"or \"5m\".",
 			})
+
+			f.StringSliceVar(&StringSliceVar{
+				Name:       "mfa",
+				Target:     &c.flagMFA,
+				Default:    nil,
+				EnvVar:     api.EnvVaultMFA,
+				Completion: complete.PredictAnything,
+				Usage: "Supplies the credentials of a login MFA method, in the " +
+					"form \"<method name>[:<passcode>]\". This can be specified " +
+					"multiple times.",
+			})
+
+			f.StringVar(&StringVar{
+				Name:       "namespace",
+				Target:     &c.flagNamespace,
+				Default:    "",
+				EnvVar:     api.EnvVaultNamespace,
+				Completion: complete.PredictAnything,
+				Usage: "The namespace the request is made in, such as " +
+					"\"team-a/\". Paths are relative to this namespace.",
+			})
 		}
 
 		if bit&(FlagSetOutputField|FlagSetOutputFormat) != 0 {
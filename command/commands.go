@@ -13,9 +13,12 @@ import (
 	"github.com/mitchellh/cli"
 
 	"github.com/hashicorp/vault/builtin/logical/aws"
+	"github.com/hashicorp/vault/builtin/logical/azure"
 	"github.com/hashicorp/vault/builtin/logical/cassandra"
 	"github.com/hashicorp/vault/builtin/logical/consul"
 	"github.com/hashicorp/vault/builtin/logical/database"
+	"github.com/hashicorp/vault/builtin/logical/gcp"
+	"github.com/hashicorp/vault/builtin/logical/kv"
 	"github.com/hashicorp/vault/builtin/logical/mongodb"
 	"github.com/hashicorp/vault/builtin/logical/mssql"
 	"github.com/hashicorp/vault/builtin/logical/mysql"
@@ -29,17 +32,19 @@ import (
 	"github.com/hashicorp/vault/builtin/plugin"
 
 	auditFile "github.com/hashicorp/vault/builtin/audit/file"
+	auditHTTP "github.com/hashicorp/vault/builtin/audit/http"
 	auditSocket "github.com/hashicorp/vault/builtin/audit/socket"
 	auditSyslog "github.com/hashicorp/vault/builtin/audit/syslog"
 
 	credCentrify "github.com/hashicorp/vault-plugin-auth-centrify"
 	credGcp "github.com/hashicorp/vault-plugin-auth-gcp/plugin"
-	credKube "github.com/hashicorp/vault-plugin-auth-kubernetes"
 	credAppId "github.com/hashicorp/vault/builtin/credential/app-id"
 	credAppRole "github.com/hashicorp/vault/builtin/credential/approle"
 	credAws "github.com/hashicorp/vault/builtin/credential/aws"
 	credCert "github.com/hashicorp/vault/builtin/credential/cert"
 	credGitHub "github.com/hashicorp/vault/builtin/credential/github"
+	credJWT "github.com/hashicorp/vault/builtin/credential/jwt"
+	credKube "github.com/hashicorp/vault/builtin/credential/kubernetes"
 	credLdap "github.com/hashicorp/vault/builtin/credential/ldap"
 	credOkta "github.com/hashicorp/vault/builtin/credential/okta"
 	credRadius "github.com/hashicorp/vault/builtin/credential/radius"
@@ -67,6 +72,7 @@ import (
 var (
 	auditBackends = map[string]audit.Factory{
 		"file":   auditFile.Factory,
+		"http":   auditHTTP.Factory,
 		"socket": auditSocket.Factory,
 		"syslog": auditSyslog.Factory,
 	}
@@ -79,8 +85,10 @@ var (
 		"cert":       credCert.Factory,
 		"gcp":        credGcp.Factory,
 		"github":     credGitHub.Factory,
+		"jwt":        credJWT.Factory,
 		"kubernetes": credKube.Factory,
 		"ldap":       credLdap.Factory,
+		"oidc":       credJWT.Factory,
 		"okta":       credOkta.Factory,
 		"plugin":     plugin.Factory,
 		"radius":     credRadius.Factory,
@@ -89,9 +97,12 @@ var (
 
 	logicalBackends = map[string]logical.Factory{
 		"aws":        aws.Factory,
+		"azure":      azure.Factory,
 		"cassandra":  cassandra.Factory,
 		"consul":     consul.Factory,
 		"database":   database.Factory,
+		"gcp":        gcp.Factory,
+		"kv-v2":      kv.Factory,
 		"mongodb":    mongodb.Factory,
 		"mssql":      mssql.Factory,
 		"mysql":      mysql.Factory,
@@ -190,6 +201,7 @@ func init() {
 		"cert":     &credCert.CLIHandler{},
 		"github":   &credGitHub.CLIHandler{},
 		"ldap":     &credLdap.CLIHandler{},
+		"oidc":     &credJWT.CLIHandler{},
 		"okta":     &credOkta.CLIHandler{},
 		"radius": &credUserpass.CLIHandler{
 			DefaultMount: "radius",
@@ -201,6 +213,14 @@ func init() {
 	}
 
 	Commands = map[string]cli.CommandFactory{
+		"agent": func() (cli.Command, error) {
+			return &AgentCommand{
+				BaseCommand: &BaseCommand{
+					UI: serverCmdUi,
+				},
+				ShutdownCh: MakeShutdownCh(),
+			}, nil
+		},
 		"audit": func() (cli.Command, error) {
 			return &AuditCommand{
 				BaseCommand: &BaseCommand{
@@ -344,6 +364,23 @@ func init() {
 				},
 			}, nil
 		},
+		"operator debug": func() (cli.Command, error) {
+			return &OperatorDebugCommand{
+				BaseCommand: &BaseCommand{
+					UI: ui,
+				},
+				ShutdownCh: MakeShutdownCh(),
+			}, nil
+		},
+		"operator migrate": func() (cli.Command, error) {
+			return &OperatorMigrateCommand{
+				BaseCommand: &BaseCommand{
+					UI: ui,
+				},
+				PhysicalBackends: physicalBackends,
+				ShutdownCh:       MakeShutdownCh(),
+			}, nil
+		},
 		"operator rekey": func() (cli.Command, error) {
 			return &OperatorRekeyCommand{
 				BaseCommand: &BaseCommand{
@@ -497,6 +534,14 @@ func init() {
 				},
 			}, nil
 		},
+		"ssh-agent": func() (cli.Command, error) {
+			return &SSHAgentCommand{
+				BaseCommand: &BaseCommand{
+					UI: ui,
+				},
+				ShutdownCh: MakeShutdownCh(),
+			}, nil
+		},
 		"status": func() (cli.Command, error) {
 			return &StatusCommand{
 				BaseCommand: &BaseCommand{
@@ -47,7 +47,7 @@ Usage: vault delete [options] PATH
 }
 
 func (c *DeleteCommand) Flags() *FlagSets {
-	return c.flagSet(FlagSetHTTP)
+	return c.flagSet(FlagSetHTTP | FlagSetOutputField | FlagSetOutputFormat)
 }
 
 func (c *DeleteCommand) AutocompleteArgs() complete.Predictor {
@@ -89,6 +89,9 @@ func (c *DeleteCommand) Run(args []string) int {
 		return 2
 	}
 
-	c.UI.Info(fmt.Sprintf("Success! Data deleted (if it existed) at: %s", path))
-	return 0
+	return OutputSuccess(c.UI, c.flagFormat, c.flagField,
+		fmt.Sprintf("Success! Data deleted (if it existed) at: %s", path),
+		map[string]interface{}{
+			"path": path,
+		})
 }
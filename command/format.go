@@ -27,6 +27,33 @@ func OutputList(ui cli.Ui, format string, secret *api.Secret) int {
 	return outputWithFormat(ui, format, secret, secret.Data["keys"])
 }
 
+// OutputData prints data other than secrets, such as the lists and statuses
+// returned by the sys endpoints, in the given format, or only its field with
+// the given name if it is set. The commands print their own tables for the
+// table format, which the data does not support.
+func OutputData(ui cli.Ui, format, field string, data interface{}) int {
+	if field != "" {
+		return PrintRawDataField(ui, data, field)
+	}
+	return outputWithFormat(ui, format, nil, data)
+}
+
+// OutputSuccess prints the success message of a command that only performs
+// an operation for the table format. The other formats print the data
+// describing the operation instead, such as the path it was performed on.
+func OutputSuccess(ui cli.Ui, format, field, message string, data map[string]interface{}) int {
+	if field == "" && isTableFormat(format) {
+		ui.Output(message)
+		return 0
+	}
+	return OutputData(ui, format, field, data)
+}
+
+// isTableFormat checks whether the format is the human readable table format
+func isTableFormat(format string) bool {
+	return strings.ToLower(format) == "table"
+}
+
 func outputWithFormat(ui cli.Ui, format string, secret *api.Secret, data interface{}) int {
 	// If we had a colored UI, pull out the nested ui so we don't add escape
 	// sequences for outputting json, etc.
@@ -208,7 +235,39 @@ func (t TableFormatter) OutputSecret(ui cli.Ui, secret *api.Secret) error {
 	return nil
 }
 
-func OutputSealStatus(ui cli.Ui, client *api.Client, status *api.SealStatusResponse) int {
+// sealStatusOutput is the seal status of a server with its HA status, as
+// printed in the formats other than table
+type sealStatusOutput struct {
+	api.SealStatusResponse
+	HAEnabled            bool   `json:"ha_enabled"`
+	IsSelf               bool   `json:"is_self,omitempty"`
+	LeaderAddress        string `json:"leader_address,omitempty"`
+	LeaderClusterAddress string `json:"leader_cluster_address,omitempty"`
+}
+
+// OutputSealStatus prints the seal status and the HA status of the server in
+// the given format, or only the field with the given name if it is set.
+func OutputSealStatus(ui cli.Ui, format, field string, client *api.Client, status *api.SealStatusResponse) int {
+	// Mask the 'Vault is sealed' error, since this means HA is enabled, but that
+	// we cannot query for the leader since we are sealed.
+	leaderStatus, err := client.Sys().Leader()
+	if err != nil && strings.Contains(err.Error(), "Vault is sealed") {
+		leaderStatus = &api.LeaderResponse{HAEnabled: true}
+	}
+
+	if field != "" || !isTableFormat(format) {
+		output := &sealStatusOutput{
+			SealStatusResponse: *status,
+		}
+		if leaderStatus != nil {
+			output.HAEnabled = leaderStatus.HAEnabled
+			output.IsSelf = leaderStatus.IsSelf
+			output.LeaderAddress = leaderStatus.LeaderAddress
+			output.LeaderClusterAddress = leaderStatus.LeaderClusterAddress
+		}
+		return OutputData(ui, format, field, output)
+	}
+
 	var sealPrefix string
 	if status.RecoverySeal {
 		sealPrefix = "Recovery "
@@ -226,6 +285,10 @@ func OutputSealStatus(ui cli.Ui, client *api.Client, status *api.SealStatusRespo
 		out = append(out, fmt.Sprintf("Unseal Nonce | %s", status.Nonce))
 	}
 
+	if status.Migration {
+		out = append(out, "Seal Migration in Progress | true")
+	}
+
 	out = append(out, fmt.Sprintf("Version | %s", status.Version))
 
 	if status.ClusterName != "" && status.ClusterID != "" {
@@ -233,13 +296,6 @@ func OutputSealStatus(ui cli.Ui, client *api.Client, status *api.SealStatusRespo
 		out = append(out, fmt.Sprintf("Cluster ID | %s", status.ClusterID))
 	}
 
-	// Mask the 'Vault is sealed' error, since this means HA is enabled, but that
-	// we cannot query for the leader since we are sealed.
-	leaderStatus, err := client.Sys().Leader()
-	if err != nil && strings.Contains(err.Error(), "Vault is sealed") {
-		leaderStatus = &api.LeaderResponse{HAEnabled: true}
-	}
-
 	// Output if HA is enabled
 	out = append(out, fmt.Sprintf("HA Enabled | %t", leaderStatus.HAEnabled))
 	if leaderStatus.HAEnabled {
@@ -7,6 +7,7 @@ import (
 	"github.com/ghodss/yaml"
 	"github.com/hashicorp/vault/api"
 	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/mitchellh/cli"
 )
 
 var output string
@@ -72,3 +73,56 @@ func TestTableFormatter(t *testing.T) {
 		t.Fatal("did not find 'something'")
 	}
 }
+
+func TestOutputData(t *testing.T) {
+	data := &api.KeyStatus{Term: 1234567}
+
+	ui := cli.NewMockUi()
+	if code := OutputData(ui, "json", "", data); code != 0 {
+		t.Fatal(ui.ErrorWriter.String())
+	}
+	if !strings.Contains(ui.OutputWriter.String(), `"term": 1234567`) {
+		t.Fatalf("bad: %s", ui.OutputWriter.String())
+	}
+
+	ui = cli.NewMockUi()
+	if code := OutputData(ui, "table", "term", data); code != 0 {
+		t.Fatal(ui.ErrorWriter.String())
+	}
+	if actual := ui.OutputWriter.String(); actual != "1234567" {
+		t.Fatalf("bad: %q", actual)
+	}
+
+	ui = cli.NewMockUi()
+	if code := OutputData(ui, "table", "foo", data); code != 1 {
+		t.Fatalf("expected %d to be %d", code, 1)
+	}
+}
+
+func TestOutputSuccess(t *testing.T) {
+	data := map[string]interface{}{"path": "foo/"}
+
+	ui := cli.NewMockUi()
+	if code := OutputSuccess(ui, "table", "", "Success! Did it at: foo/", data); code != 0 {
+		t.Fatal(ui.ErrorWriter.String())
+	}
+	if actual := ui.OutputWriter.String(); actual != "Success! Did it at: foo/\n" {
+		t.Fatalf("bad: %q", actual)
+	}
+
+	ui = cli.NewMockUi()
+	if code := OutputSuccess(ui, "json", "", "Success! Did it at: foo/", data); code != 0 {
+		t.Fatal(ui.ErrorWriter.String())
+	}
+	if actual := ui.OutputWriter.String(); strings.Contains(actual, "Success!") || !strings.Contains(actual, `"path": "foo/"`) {
+		t.Fatalf("bad: %q", actual)
+	}
+
+	ui = cli.NewMockUi()
+	if code := OutputSuccess(ui, "table", "path", "Success! Did it at: foo/", data); code != 0 {
+		t.Fatal(ui.ErrorWriter.String())
+	}
+	if actual := ui.OutputWriter.String(); actual != "foo/" {
+		t.Fatalf("bad: %q", actual)
+	}
+}
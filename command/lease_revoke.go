@@ -50,7 +50,7 @@ Usage: vault lease revoke [options] ID
 }
 
 func (c *LeaseRevokeCommand) Flags() *FlagSets {
-	set := c.flagSet(FlagSetHTTP)
+	set := c.flagSet(FlagSetHTTP | FlagSetOutputField | FlagSetOutputFormat)
 	f := set.NewFlagSet("Command Options")
 
 	f.BoolVar(&BoolVar{
@@ -122,21 +122,31 @@ func (c *LeaseRevokeCommand) Run(args []string) int {
 			c.UI.Error(fmt.Sprintf("Error force revoking leases with prefix %s: %s", leaseID, err))
 			return 2
 		}
-		c.UI.Output(fmt.Sprintf("Success! Force revoked any leases with prefix: %s", leaseID))
-		return 0
+		return OutputSuccess(c.UI, c.flagFormat, c.flagField,
+			fmt.Sprintf("Success! Force revoked any leases with prefix: %s", leaseID),
+			map[string]interface{}{
+				"prefix": leaseID,
+				"force":  true,
+			})
 	case c.flagPrefix:
 		if err := client.Sys().RevokePrefix(leaseID); err != nil {
 			c.UI.Error(fmt.Sprintf("Error revoking leases with prefix %s: %s", leaseID, err))
 			return 2
 		}
-		c.UI.Output(fmt.Sprintf("Success! Revoked any leases with prefix: %s", leaseID))
-		return 0
+		return OutputSuccess(c.UI, c.flagFormat, c.flagField,
+			fmt.Sprintf("Success! Revoked any leases with prefix: %s", leaseID),
+			map[string]interface{}{
+				"prefix": leaseID,
+			})
 	default:
 		if err := client.Sys().Revoke(leaseID); err != nil {
 			c.UI.Error(fmt.Sprintf("Error revoking lease %s: %s", leaseID, err))
 			return 2
 		}
-		c.UI.Output(fmt.Sprintf("Success! Revoked lease: %s", leaseID))
-		return 0
+		return OutputSuccess(c.UI, c.flagFormat, c.flagField,
+			fmt.Sprintf("Success! Revoked lease: %s", leaseID),
+			map[string]interface{}{
+				"lease_id": leaseID,
+			})
 	}
 }
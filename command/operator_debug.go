@@ -0,0 +1,506 @@
+package command
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/version"
+	"github.com/mitchellh/cli"
+	"github.com/posener/complete"
+)
+
+var _ cli.Command = (*OperatorDebugCommand)(nil)
+var _ cli.CommandAutocomplete = (*OperatorDebugCommand)(nil)
+
+const (
+	// debugMaxCPUProfileDuration caps the duration of the CPU profile, which
+	// is captured in a single request
+	debugMaxCPUProfileDuration = 30 * time.Second
+
+	// debugRedacted replaces the secret material in the captured data
+	debugRedacted = "[redacted]"
+)
+
+// debugTargets are the data which can be captured, in the order of the help
+var debugTargets = []string{"metrics", "pprof", "replication-status", "ha-status", "logs"}
+
+// debugSensitiveKeys are the keys whose values are redacted from the captured
+// JSON documents and log lines
+var debugSensitiveKeys = []string{
+	"accessor",
+	"client_token",
+	"hmac_key",
+	"keys",
+	"keys_base64",
+	"password",
+	"private_key",
+	"recovery_keys",
+	"root_token",
+	"secret_id",
+	"token",
+	"unseal_keys",
+}
+
+// debugSensitiveLogRe matches the values of the sensitive keys in log lines,
+// such as token=abcd or "password":"abcd"
+var debugSensitiveLogRe = regexp.MustCompile(`(?i)\b(` + strings.Join(debugSensitiveKeys, "|") + `)(["']?\s*[=:]\s*["']?)([^\s"',}\]]+)`)
+
+type OperatorDebugCommand struct {
+	*BaseCommand
+
+	ShutdownCh chan struct{}
+
+	flagDuration time.Duration
+	flagInterval time.Duration
+	flagOutput   string
+	flagTargets  []string
+}
+
+// debugSnapshot is a capture of a polled target
+type debugSnapshot struct {
+	Timestamp time.Time   `json:"timestamp"`
+	Data      interface{} `json:"data,omitempty"`
+	Error     string      `json:"error,omitempty"`
+}
+
+// debugIndex describes the content of the archive
+type debugIndex struct {
+	VaultAddress  string            `json:"vault_address"`
+	ClientVersion string            `json:"client_version"`
+	ServerVersion string            `json:"server_version,omitempty"`
+	StartTime     time.Time         `json:"start_time"`
+	EndTime       time.Time         `json:"end_time"`
+	Duration      string            `json:"duration"`
+	Interval      string            `json:"interval"`
+	Targets       []string          `json:"targets"`
+	Errors        map[string]string `json:"errors,omitempty"`
+}
+
+func (c *OperatorDebugCommand) Synopsis() string {
+	return "Captures debugging information from a Vault server"
+}
+
+func (c *OperatorDebugCommand) Help() string {
+	helpText := `
+Usage: vault operator debug [options]
+
+  Captures the metrics, runtime profiles, replication and HA status, and
+  recent log lines of the Vault server at the given address during the given
+  duration, and writes them to a gzipped tarball to share with support. The
+  metrics and status are polled at each interval. The values of the keys
+  holding secret material, such as tokens, keys and passwords, are redacted.
+
+  This command requires a root token, since it reads the sys/debug endpoints.
+
+  Capture the default targets for two minutes:
+
+      $ vault operator debug
+
+  Capture the metrics and the logs every 10 seconds for a minute:
+
+      $ vault operator debug -duration=1m -interval=10s \
+          -target=metrics -target=logs
+
+` + c.Flags().Help()
+
+	return strings.TrimSpace(helpText)
+}
+
+func (c *OperatorDebugCommand) Flags() *FlagSets {
+	set := c.flagSet(FlagSetHTTP)
+
+	f := set.NewFlagSet("Command Options")
+
+	f.DurationVar(&DurationVar{
+		Name:       "duration",
+		Target:     &c.flagDuration,
+		Default:    2 * time.Minute,
+		EnvVar:     "",
+		Completion: complete.PredictAnything,
+		Usage: "Duration of the capture. The CPU profile samples the first " +
+			"30 seconds of the capture at most.",
+	})
+
+	f.DurationVar(&DurationVar{
+		Name:       "interval",
+		Target:     &c.flagInterval,
+		Default:    30 * time.Second,
+		EnvVar:     "",
+		Completion: complete.PredictAnything,
+		Usage: "Interval at which the metrics, replication status and HA " +
+			"status are polled. It must not be longer than the duration.",
+	})
+
+	f.StringVar(&StringVar{
+		Name:       "output",
+		Target:     &c.flagOutput,
+		Default:    "",
+		EnvVar:     "",
+		Completion: complete.PredictFiles("*.tar.gz"),
+		Usage: "Path of the archive to write, which must not exist. Defaults " +
+			"to vault-debug-<timestamp>.tar.gz in the current directory.",
+	})
+
+	f.StringSliceVar(&StringSliceVar{
+		Name:       "target",
+		Target:     &c.flagTargets,
+		Completion: complete.PredictSet(debugTargets...),
+		Usage: "Data to capture, among \"metrics\", \"pprof\", " +
+			"\"replication-status\", \"ha-status\" and \"logs\". This can be " +
+			"specified multiple times. Defaults to all of them.",
+	})
+
+	return set
+}
+
+func (c *OperatorDebugCommand) AutocompleteArgs() complete.Predictor {
+	return nil
+}
+
+func (c *OperatorDebugCommand) AutocompleteFlags() complete.Flags {
+	return c.Flags().Completions()
+}
+
+func (c *OperatorDebugCommand) Run(args []string) int {
+	f := c.Flags()
+
+	if err := f.Parse(args); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	args = f.Args()
+	if len(args) > 0 {
+		c.UI.Error(fmt.Sprintf("Too many arguments (expected 0, got %d)", len(args)))
+		return 1
+	}
+
+	if c.flagDuration < time.Second {
+		c.UI.Error("The duration must be at least 1s")
+		return 1
+	}
+	if c.flagInterval <= 0 || c.flagInterval > c.flagDuration {
+		c.UI.Error("The interval must be positive and not longer than the duration")
+		return 1
+	}
+
+	targets := strutil.RemoveDuplicatesStable(c.flagTargets, true)
+	if len(targets) == 0 {
+		targets = debugTargets
+	}
+	for _, target := range targets {
+		if !strutil.StrListContains(debugTargets, target) {
+			c.UI.Error(fmt.Sprintf("Unknown target %q, expected one of %s", target, strings.Join(debugTargets, ", ")))
+			return 1
+		}
+	}
+
+	start := time.Now()
+	output := c.flagOutput
+	if output == "" {
+		output = fmt.Sprintf("vault-debug-%s.tar.gz", start.UTC().Format("2006-01-02T15-04-05Z"))
+	}
+	// Fail before the capture rather than after it
+	if _, err := os.Stat(output); err == nil {
+		c.UI.Error(fmt.Sprintf("Output file %q already exists", output))
+		return 1
+	}
+
+	client, err := c.Client()
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 2
+	}
+
+	index := &debugIndex{
+		VaultAddress:  client.Address(),
+		ClientVersion: version.GetVersion().VersionNumber(),
+		StartTime:     start.UTC(),
+		Duration:      c.flagDuration.String(),
+		Interval:      c.flagInterval.String(),
+		Targets:       targets,
+		Errors:        make(map[string]string),
+	}
+	if status, err := client.Sys().SealStatus(); err == nil {
+		index.ServerVersion = status.Version
+	}
+
+	c.UI.Output(fmt.Sprintf("Capturing %s of debugging information from %s (%s)...",
+		c.flagDuration, client.Address(), strings.Join(targets, ", ")))
+
+	files := make(map[string][]byte)
+	var filesLock sync.Mutex
+	addFile := func(name string, data []byte) {
+		filesLock.Lock()
+		files[name] = data
+		filesLock.Unlock()
+	}
+	addError := func(target string, err error) {
+		filesLock.Lock()
+		index.Errors[target] = err.Error()
+		filesLock.Unlock()
+		c.UI.Warn(fmt.Sprintf("Error capturing %s: %s", target, err))
+	}
+
+	// The CPU profile is captured in the background during the polls
+	var wg sync.WaitGroup
+	if strutil.StrListContains(targets, "pprof") {
+		seconds := c.flagDuration
+		if seconds > debugMaxCPUProfileDuration {
+			seconds = debugMaxCPUProfileDuration
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			profile, err := client.Sys().Pprof("profile", int(seconds/time.Second))
+			if err != nil {
+				addError("pprof", err)
+				return
+			}
+			addFile("profile.prof", profile)
+		}()
+	}
+
+	snapshots := make(map[string][]*debugSnapshot)
+	poll := func() {
+		for _, target := range targets {
+			var data interface{}
+			var err error
+			switch target {
+			case "metrics":
+				data, err = c.captureMetrics(client)
+			case "replication-status":
+				data, err = c.captureReplicationStatus(client)
+			case "ha-status":
+				data, err = c.captureHAStatus(client)
+			default:
+				continue
+			}
+
+			snapshot := &debugSnapshot{
+				Timestamp: time.Now().UTC(),
+			}
+			if err != nil {
+				snapshot.Error = err.Error()
+			} else {
+				snapshot.Data = redactDebugData(data)
+			}
+			snapshots[target] = append(snapshots[target], snapshot)
+		}
+	}
+
+	ticker := time.NewTicker(c.flagInterval)
+	defer ticker.Stop()
+	deadline := time.NewTimer(c.flagDuration)
+	defer deadline.Stop()
+
+	poll()
+POLL:
+	for {
+		select {
+		case <-ticker.C:
+			poll()
+		case <-deadline.C:
+			break POLL
+		case <-c.ShutdownCh:
+			c.UI.Warn("Interrupted, writing the information captured so far")
+			break POLL
+		}
+	}
+
+	if strutil.StrListContains(targets, "pprof") {
+		for _, profile := range []string{"heap", "goroutine"} {
+			data, err := client.Sys().Pprof(profile, 0)
+			if err != nil {
+				addError("pprof", err)
+				continue
+			}
+			addFile(profile+".prof", data)
+		}
+	}
+
+	if strutil.StrListContains(targets, "logs") {
+		lines, err := client.Sys().RecentLogs()
+		if err != nil {
+			addError("logs", err)
+		} else {
+			var log strings.Builder
+			for _, line := range lines {
+				log.WriteString(redactDebugLogLine(line))
+				log.WriteString("\n")
+			}
+			addFile("vault.log", []byte(log.String()))
+		}
+	}
+
+	wg.Wait()
+
+	for target, targetSnapshots := range snapshots {
+		data, err := json.MarshalIndent(targetSnapshots, "", "  ")
+		if err != nil {
+			addError(target, err)
+			continue
+		}
+		addFile(strings.Replace(target, "-", "_", -1)+".json", data)
+	}
+
+	index.EndTime = time.Now().UTC()
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error encoding the index: %s", err))
+		return 2
+	}
+	files["index.json"] = data
+
+	if err := writeDebugArchive(output, files); err != nil {
+		c.UI.Error(fmt.Sprintf("Error writing the archive: %s", err))
+		return 2
+	}
+
+	if len(index.Errors) > 0 {
+		c.UI.Warn("Some targets could not be captured, see index.json in the archive")
+	}
+	c.UI.Output(fmt.Sprintf("Success! Debugging information written to: %s", output))
+	return 0
+}
+
+// captureMetrics returns the JSON summary of the metrics of the server
+func (c *OperatorDebugCommand) captureMetrics(client *api.Client) (interface{}, error) {
+	body, err := client.Sys().Metrics("json")
+	if err != nil {
+		return nil, err
+	}
+
+	var metrics interface{}
+	if err := json.Unmarshal(body, &metrics); err != nil {
+		return nil, err
+	}
+	return metrics, nil
+}
+
+// captureReplicationStatus returns the replication status of the server
+func (c *OperatorDebugCommand) captureReplicationStatus(client *api.Client) (interface{}, error) {
+	secret, err := client.Logical().Read("sys/replication/status")
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil {
+		return nil, fmt.Errorf("no replication status returned")
+	}
+	return secret.Data, nil
+}
+
+// captureHAStatus returns the leader and the HA nodes known to the server
+func (c *OperatorDebugCommand) captureHAStatus(client *api.Client) (interface{}, error) {
+	leader, err := client.Sys().Leader()
+	if err != nil {
+		return nil, err
+	}
+	status := map[string]interface{}{
+		"leader": leader,
+	}
+
+	if leader.HAEnabled {
+		haStatus, err := client.Sys().HAStatus()
+		if err != nil {
+			return nil, err
+		}
+		status["ha_status"] = haStatus
+	}
+
+	return status, nil
+}
+
+// redactDebugData returns the generic JSON form of the data with the values
+// of the sensitive keys redacted
+func redactDebugData(data interface{}) interface{} {
+	// Go through JSON so that structs are redacted as maps
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil
+	}
+	var generic interface{}
+	if err := json.Unmarshal(raw, &generic); err != nil {
+		return nil
+	}
+	return redactDebugValue(generic)
+}
+
+func redactDebugValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if item != nil && strutil.StrListContains(debugSensitiveKeys, strings.ToLower(key)) {
+				v[key] = debugRedacted
+				continue
+			}
+			v[key] = redactDebugValue(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redactDebugValue(item)
+		}
+	}
+	return value
+}
+
+// redactDebugLogLine redacts the values of the sensitive keys of the line
+func redactDebugLogLine(line string) string {
+	return debugSensitiveLogRe.ReplaceAllString(line, "${1}${2}"+debugRedacted)
+}
+
+// writeDebugArchive writes the files to a new gzipped tarball, in a directory
+// named after the archive
+func writeDebugArchive(path string, files map[string][]byte) (retErr error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := file.Close(); err != nil && retErr == nil {
+			retErr = err
+		}
+	}()
+
+	gz := gzip.NewWriter(file)
+	tw := tar.NewWriter(gz)
+
+	dir := strings.TrimSuffix(filepath.Base(path), ".tar.gz")
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	now := time.Now()
+	for _, name := range names {
+		data := files[name]
+		if err := tw.WriteHeader(&tar.Header{
+			Name:    dir + "/" + name,
+			Mode:    0600,
+			Size:    int64(len(data)),
+			ModTime: now,
+		}); err != nil {
+			return err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
@@ -0,0 +1,247 @@
+package command
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+	"github.com/hashicorp/vault/helper/logbuffer"
+	"github.com/hashicorp/vault/helper/metricsutil"
+	"github.com/hashicorp/vault/vault"
+	"github.com/mitchellh/cli"
+)
+
+func testOperatorDebugCommand(tb testing.TB) (*cli.MockUi, *OperatorDebugCommand) {
+	tb.Helper()
+
+	ui := cli.NewMockUi()
+	return ui, &OperatorDebugCommand{
+		BaseCommand: &BaseCommand{
+			UI: ui,
+		},
+	}
+}
+
+// testReadDebugArchive returns the files of the archive by name, without the
+// directory of the archive
+func testReadDebugArchive(tb testing.TB, path string) map[string][]byte {
+	tb.Helper()
+
+	f, err := os.Open(path)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+
+	files := make(map[string][]byte)
+	for {
+		header, err := tr.Next()
+		if err != nil {
+			break
+		}
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		files[filepath.Base(header.Name)] = data
+	}
+	return files
+}
+
+func TestOperatorDebugCommand_Run(t *testing.T) {
+	t.Parallel()
+
+	t.Run("validations", func(t *testing.T) {
+		t.Parallel()
+
+		cases := []struct {
+			name string
+			args []string
+			out  string
+			code int
+		}{
+			{
+				"too_many_args",
+				[]string{"foo"},
+				"Too many arguments",
+				1,
+			},
+			{
+				"short_duration",
+				[]string{"-duration=100ms"},
+				"at least 1s",
+				1,
+			},
+			{
+				"long_interval",
+				[]string{"-duration=10s", "-interval=20s"},
+				"not longer than the duration",
+				1,
+			},
+			{
+				"unknown_target",
+				[]string{"-target=foo"},
+				"Unknown target",
+				1,
+			},
+		}
+
+		for _, tc := range cases {
+			tc := tc
+
+			t.Run(tc.name, func(t *testing.T) {
+				t.Parallel()
+
+				ui, cmd := testOperatorDebugCommand(t)
+
+				code := cmd.Run(tc.args)
+				if code != tc.code {
+					t.Errorf("expected %d to be %d", code, tc.code)
+				}
+
+				combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+				if !strings.Contains(combined, tc.out) {
+					t.Errorf("expected %q to contain %q", combined, tc.out)
+				}
+			})
+		}
+	})
+
+	t.Run("integration", func(t *testing.T) {
+		t.Parallel()
+
+		recentLogs := logbuffer.New(10)
+		recentLogs.Write([]byte("core: renewed token=s.abcd\n"))
+
+		client, _, closer := testVaultServerCoreConfig(t, &vault.CoreConfig{
+			DisableMlock:       true,
+			DisableCache:       true,
+			Logger:             defaultVaultLogger,
+			CredentialBackends: defaultVaultCredentialBackends,
+			AuditBackends:      defaultVaultAuditBackends,
+			LogicalBackends:    defaultVaultLogicalBackends,
+			MetricsHelper:      metricsutil.NewMetricsHelper(metrics.NewInmemSink(time.Second, time.Minute), nil),
+			RecentLogs:         recentLogs,
+		})
+		defer closer()
+
+		dir, err := ioutil.TempDir("", "vault-debug")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		output := filepath.Join(dir, "debug.tar.gz")
+
+		ui, cmd := testOperatorDebugCommand(t)
+		cmd.client = client
+
+		code := cmd.Run([]string{
+			"-duration=2s",
+			"-interval=1s",
+			"-output=" + output,
+		})
+		if exp := 0; code != exp {
+			t.Fatalf("expected %d to be %d: %s", code, exp, ui.ErrorWriter.String())
+		}
+
+		files := testReadDebugArchive(t, output)
+		for _, name := range []string{
+			"index.json",
+			"metrics.json",
+			"replication_status.json",
+			"ha_status.json",
+			"profile.prof",
+			"heap.prof",
+			"goroutine.prof",
+			"vault.log",
+		} {
+			if len(files[name]) == 0 {
+				t.Errorf("missing %s in the archive", name)
+			}
+		}
+
+		var index debugIndex
+		if err := json.Unmarshal(files["index.json"], &index); err != nil {
+			t.Fatal(err)
+		}
+		if len(index.Errors) > 0 {
+			t.Errorf("unexpected errors: %v", index.Errors)
+		}
+
+		var snapshots []*debugSnapshot
+		if err := json.Unmarshal(files["metrics.json"], &snapshots); err != nil {
+			t.Fatal(err)
+		}
+		if len(snapshots) < 2 {
+			t.Errorf("expected at least 2 snapshots, got %d", len(snapshots))
+		}
+
+		if log := string(files["vault.log"]); log != "core: renewed token=[redacted]\n" {
+			t.Errorf("bad log: %q", log)
+		}
+
+		// The archive is not overwritten
+		ui, cmd = testOperatorDebugCommand(t)
+		cmd.client = client
+		if code := cmd.Run([]string{"-output=" + output}); code != 1 {
+			t.Errorf("expected %d to be %d", code, 1)
+		}
+		if combined := ui.OutputWriter.String() + ui.ErrorWriter.String(); !strings.Contains(combined, "already exists") {
+			t.Errorf("expected %q to contain %q", combined, "already exists")
+		}
+	})
+}
+
+func TestRedactDebugData(t *testing.T) {
+	data := map[string]interface{}{
+		"token": "s.abcd",
+		"nested": []interface{}{
+			map[string]interface{}{
+				"Password": "hunter2",
+				"name":     "foo",
+			},
+		},
+		"keys": nil,
+	}
+	expected := map[string]interface{}{
+		"token": debugRedacted,
+		"nested": []interface{}{
+			map[string]interface{}{
+				"Password": debugRedacted,
+				"name":     "foo",
+			},
+		},
+		"keys": nil,
+	}
+	if actual := redactDebugData(data); !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestRedactDebugLogLine(t *testing.T) {
+	cases := map[string]string{
+		`login: client_token=s.abcd path=auth/foo`: `login: client_token=[redacted] path=auth/foo`,
+		`{"secret_id":"abcd","role":"foo"}`:        `{"secret_id":"[redacted]","role":"foo"}`,
+		`request: password: hunter2`:               `request: password: [redacted]`,
+		`core: tokens restored`:                    `core: tokens restored`,
+	}
+	for line, expected := range cases {
+		if actual := redactDebugLogLine(line); actual != expected {
+			t.Errorf("expected %q to be %q", actual, expected)
+		}
+	}
+}
@@ -82,7 +82,7 @@ Usage: vault operator generate-root [options] [KEY]
 }
 
 func (c *OperatorGenerateRootCommand) Flags() *FlagSets {
-	set := c.flagSet(FlagSetHTTP)
+	set := c.flagSet(FlagSetHTTP | FlagSetOutputField | FlagSetOutputFormat)
 
 	f := set.NewFlagSet("Command Options")
 
@@ -441,8 +441,11 @@ func (c *OperatorGenerateRootCommand) cancel(client *api.Client, drToken bool) i
 		c.UI.Error(fmt.Sprintf("Error canceling root token generation: %s", err))
 		return 2
 	}
-	c.UI.Output("Success! Root token generation canceled (if it was started)")
-	return 0
+	return OutputSuccess(c.UI, c.flagFormat, c.flagField,
+		"Success! Root token generation canceled (if it was started)",
+		map[string]interface{}{
+			"canceled": true,
+		})
 }
 
 // status is used just to fetch and dump the status
@@ -461,6 +464,10 @@ func (c *OperatorGenerateRootCommand) status(client *api.Client, drToken bool) i
 
 // printStatus dumps the status to output
 func (c *OperatorGenerateRootCommand) printStatus(status *api.GenerateRootStatusResponse) int {
+	if c.flagField != "" || !isTableFormat(c.flagFormat) {
+		return OutputData(c.UI, c.flagFormat, c.flagField, status)
+	}
+
 	out := []string{}
 	out = append(out, fmt.Sprintf("Nonce | %s", status.Nonce))
 	out = append(out, fmt.Sprintf("Started | %t", status.Started))
@@ -80,6 +80,27 @@ func TestOperatorKeyStatusCommand_Run(t *testing.T) {
 		}
 	})
 
+	t.Run("field", func(t *testing.T) {
+		t.Parallel()
+
+		client, closer := testVaultServer(t)
+		defer closer()
+
+		ui, cmd := testOperatorKeyStatusCommand(t)
+		cmd.client = client
+
+		code := cmd.Run([]string{
+			"-field", "term",
+		})
+		if exp := 0; code != exp {
+			t.Errorf("expected %d to be %d", code, exp)
+		}
+
+		if exp, actual := "1", ui.OutputWriter.String(); actual != exp {
+			t.Errorf("expected %q to be %q", actual, exp)
+		}
+	})
+
 	t.Run("communication_failure", func(t *testing.T) {
 		t.Parallel()
 
@@ -0,0 +1,391 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/hcl"
+	"github.com/hashicorp/hcl/hcl/ast"
+	"github.com/hashicorp/vault/command/server"
+	"github.com/hashicorp/vault/helper/logformat"
+	"github.com/hashicorp/vault/physical"
+	log "github.com/mgutz/logxi/v1"
+	"github.com/mitchellh/cli"
+	"github.com/posener/complete"
+)
+
+var _ cli.Command = (*OperatorMigrateCommand)(nil)
+var _ cli.CommandAutocomplete = (*OperatorMigrateCommand)(nil)
+
+const (
+	// migrationLockPath is the lock of the source storage held during the
+	// migration, which is the lock of the active Vault node so that no node
+	// becomes active while the data is copied
+	migrationLockPath = "core/lock"
+
+	// migrationLockTimeout is how long to wait for the lock of the source
+	// storage before assuming that Vault is running
+	migrationLockTimeout = 10 * time.Second
+
+	// migrationProgressInterval is the number of keys copied between outputs
+	// of the progress
+	migrationProgressInterval = 1000
+)
+
+type OperatorMigrateCommand struct {
+	*BaseCommand
+
+	PhysicalBackends map[string]physical.Factory
+	ShutdownCh       chan struct{}
+
+	flagConfig   string
+	flagStart    string
+	flagLogLevel string
+
+	logger log.Logger
+}
+
+// migratorConfig is the configuration of the migration, which has the same
+// format as the storage stanza of the server configuration
+type migratorConfig struct {
+	StorageSource      *server.Storage
+	StorageDestination *server.Storage
+}
+
+func (c *OperatorMigrateCommand) Synopsis() string {
+	return "Migrates Vault data between storage backends"
+}
+
+func (c *OperatorMigrateCommand) Help() string {
+	helpText := `
+Usage: vault operator migrate [options]
+
+  Copies all the data of Vault from one storage backend to another. The
+  migration is offline: Vault must not be running on the source storage
+  while the data is copied. Neither storage is unsealed, so the data is
+  copied encrypted as is.
+
+  The storage backends are set in a configuration file with the format of the
+  storage stanza of the server configuration:
+
+      storage_source "consul" {
+        path = "vault/"
+      }
+
+      storage_destination "file" {
+        path = "/var/lib/vault"
+      }
+
+  Migrate the data:
+
+      $ vault operator migrate -config=migrate.hcl
+
+  Keys are copied in lexical order. Resume an interrupted migration from the
+  key reported when it stopped:
+
+      $ vault operator migrate -config=migrate.hcl -start=sys/token/id/abc
+
+` + c.Flags().Help()
+
+	return strings.TrimSpace(helpText)
+}
+
+func (c *OperatorMigrateCommand) Flags() *FlagSets {
+	set := NewFlagSets(c.UI)
+
+	f := set.NewFlagSet("Command Options")
+
+	f.StringVar(&StringVar{
+		Name:    "config",
+		Target:  &c.flagConfig,
+		Default: "",
+		EnvVar:  "",
+		Completion: complete.PredictOr(
+			complete.PredictFiles("*.hcl"),
+			complete.PredictFiles("*.json"),
+		),
+		Usage: "Path to the configuration file of the migration, with a " +
+			"storage_source and a storage_destination stanza.",
+	})
+
+	f.StringVar(&StringVar{
+		Name:       "start",
+		Target:     &c.flagStart,
+		Default:    "",
+		EnvVar:     "",
+		Completion: complete.PredictAnything,
+		Usage: "Key to start the migration from, to resume an interrupted " +
+			"migration. Keys before it in lexical order are not copied, and " +
+			"the destination storage may already contain data.",
+	})
+
+	f.StringVar(&StringVar{
+		Name:       "log-level",
+		Target:     &c.flagLogLevel,
+		Default:    "info",
+		EnvVar:     "VAULT_LOG_LEVEL",
+		Completion: complete.PredictSet("trace", "debug", "info", "warn", "err"),
+		Usage: "Log verbosity level. Supported values (in order of detail) are " +
+			"\"trace\", \"debug\", \"info\", \"warn\", and \"err\".",
+	})
+
+	return set
+}
+
+func (c *OperatorMigrateCommand) AutocompleteArgs() complete.Predictor {
+	return nil
+}
+
+func (c *OperatorMigrateCommand) AutocompleteFlags() complete.Flags {
+	return c.Flags().Completions()
+}
+
+func (c *OperatorMigrateCommand) Run(args []string) int {
+	f := c.Flags()
+
+	if err := f.Parse(args); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	args = f.Args()
+	if len(args) > 0 {
+		c.UI.Error(fmt.Sprintf("Too many arguments (expected 0, got %d)", len(args)))
+		return 1
+	}
+
+	if c.flagConfig == "" {
+		c.UI.Error("Must specify exactly one config path using -config")
+		return 1
+	}
+
+	var level int
+	switch strings.ToLower(strings.TrimSpace(c.flagLogLevel)) {
+	case "trace":
+		level = log.LevelTrace
+	case "debug":
+		level = log.LevelDebug
+	case "", "info", "notice":
+		level = log.LevelInfo
+	case "warn", "warning":
+		level = log.LevelWarn
+	case "err", "error":
+		level = log.LevelError
+	default:
+		c.UI.Error(fmt.Sprintf("Unknown log level: %s", c.flagLogLevel))
+		return 1
+	}
+	c.logger = logformat.NewVaultLoggerWithWriter(os.Stderr, level)
+
+	config, err := loadMigratorConfig(c.flagConfig)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error loading configuration from %s: %s", c.flagConfig, err))
+		return 1
+	}
+
+	if err := c.migrate(config); err != nil {
+		c.UI.Error(err.Error())
+		return 2
+	}
+
+	return 0
+}
+
+// migrate copies the keys of the source storage to the destination storage
+func (c *OperatorMigrateCommand) migrate(config *migratorConfig) error {
+	from, err := c.newBackend(config.StorageSource)
+	if err != nil {
+		return fmt.Errorf("Error initializing source storage: %s", err)
+	}
+	to, err := c.newBackend(config.StorageDestination)
+	if err != nil {
+		return fmt.Errorf("Error initializing destination storage: %s", err)
+	}
+
+	ctx := context.Background()
+
+	// A new migration must not overwrite data of the destination
+	if c.flagStart == "" {
+		keys, err := to.List(ctx, "")
+		if err != nil {
+			return fmt.Errorf("Error checking destination storage: %s", err)
+		}
+		if len(keys) > 0 {
+			return fmt.Errorf("Destination storage already contains data; " +
+				"use -start to resume a previous migration")
+		}
+	}
+
+	// Hold the lock of the active node, if the source storage has one, so that
+	// Vault cannot become active on the source storage during the migration
+	if ha, ok := from.(physical.HABackend); ok && ha.HAEnabled() {
+		lock, err := ha.LockWith(migrationLockPath, "migration")
+		if err != nil {
+			return fmt.Errorf("Error creating lock of source storage: %s", err)
+		}
+
+		stopCh := make(chan struct{})
+		timer := time.AfterFunc(migrationLockTimeout, func() { close(stopCh) })
+		leaderCh, err := lock.Lock(stopCh)
+		timer.Stop()
+		if err != nil {
+			return fmt.Errorf("Error acquiring lock of source storage: %s", err)
+		}
+		if leaderCh == nil {
+			return fmt.Errorf("Timed out acquiring lock of source storage; " +
+				"Vault must not be running during the migration")
+		}
+		defer lock.Unlock()
+	}
+
+	c.UI.Output("Listing keys of the source storage...")
+	keys, err := listKeys(ctx, from, "")
+	if err != nil {
+		return fmt.Errorf("Error listing keys of source storage: %s", err)
+	}
+	sort.Strings(keys)
+
+	copied := 0
+	for _, key := range keys {
+		if key < c.flagStart || key == migrationLockPath {
+			continue
+		}
+
+		select {
+		case <-c.ShutdownCh:
+			return fmt.Errorf("Migration interrupted before copying %q; "+
+				"resume it with -start=%q", key, key)
+		default:
+		}
+
+		entry, err := from.Get(ctx, key)
+		if err != nil {
+			return fmt.Errorf("Error reading %q from source storage: %s; "+
+				"resume the migration with -start=%q", key, err, key)
+		}
+		if entry == nil {
+			// Deleted since the listing
+			continue
+		}
+		if err := to.Put(ctx, entry); err != nil {
+			return fmt.Errorf("Error writing %q to destination storage: %s; "+
+				"resume the migration with -start=%q", key, err, key)
+		}
+
+		copied++
+		if c.logger.IsTrace() {
+			c.logger.Trace("migrate: copied key", "key", key)
+		}
+		if copied%migrationProgressInterval == 0 {
+			c.UI.Output(fmt.Sprintf("Copied %d keys, up to %q", copied, key))
+		}
+	}
+
+	c.UI.Output(fmt.Sprintf("Success! Migrated %d keys from %s to %s storage.",
+		copied, config.StorageSource.Type, config.StorageDestination.Type))
+	return nil
+}
+
+// newBackend returns the storage backend of the configuration
+func (c *OperatorMigrateCommand) newBackend(storage *server.Storage) (physical.Backend, error) {
+	factory, ok := c.PhysicalBackends[storage.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown storage type %s", storage.Type)
+	}
+	return factory(storage.Config, c.logger)
+}
+
+// listKeys returns all the keys of the storage under the prefix
+func listKeys(ctx context.Context, b physical.Backend, prefix string) ([]string, error) {
+	children, err := b.List(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for _, child := range children {
+		if strings.HasSuffix(child, "/") {
+			subKeys, err := listKeys(ctx, b, prefix+child)
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, subKeys...)
+			continue
+		}
+		keys = append(keys, prefix+child)
+	}
+	return keys, nil
+}
+
+// loadMigratorConfig loads the configuration of the migration from the file
+func loadMigratorConfig(path string) (*migratorConfig, error) {
+	d, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseMigratorConfig(string(d))
+}
+
+func parseMigratorConfig(d string) (*migratorConfig, error) {
+	obj, err := hcl.Parse(d)
+	if err != nil {
+		return nil, err
+	}
+
+	list, ok := obj.Node.(*ast.ObjectList)
+	if !ok {
+		return nil, fmt.Errorf("error parsing: file doesn't contain a root object")
+	}
+
+	if err := checkHCLKeys(list, []string{"storage_source", "storage_destination"}); err != nil {
+		return nil, err
+	}
+
+	var config migratorConfig
+	if config.StorageSource, err = parseMigratorStorage(list, "storage_source"); err != nil {
+		return nil, err
+	}
+	if config.StorageDestination, err = parseMigratorStorage(list, "storage_destination"); err != nil {
+		return nil, err
+	}
+
+	if config.StorageSource.Type == config.StorageDestination.Type &&
+		fmt.Sprint(config.StorageSource.Config) == fmt.Sprint(config.StorageDestination.Config) {
+		return nil, fmt.Errorf("source and destination storage are the same")
+	}
+
+	return &config, nil
+}
+
+func parseMigratorStorage(list *ast.ObjectList, name string) (*server.Storage, error) {
+	items := list.Filter(name).Items
+	switch len(items) {
+	case 0:
+		return nil, fmt.Errorf("missing %q block", name)
+	case 1:
+	default:
+		return nil, fmt.Errorf("only one %q block is permitted", name)
+	}
+
+	item := items[0]
+	if len(item.Keys) == 0 {
+		return nil, fmt.Errorf("missing storage type of %q block", name)
+	}
+	key := item.Keys[0].Token.Value().(string)
+
+	var m map[string]string
+	if err := hcl.DecodeObject(&m, item.Val); err != nil {
+		return nil, multierror.Prefix(err, fmt.Sprintf("%s.%s:", name, key))
+	}
+
+	return &server.Storage{
+		Type:   strings.ToLower(key),
+		Config: m,
+	}, nil
+}
@@ -0,0 +1,222 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/helper/logformat"
+	"github.com/hashicorp/vault/physical"
+	physFile "github.com/hashicorp/vault/physical/file"
+	log "github.com/mgutz/logxi/v1"
+	"github.com/mitchellh/cli"
+)
+
+func testOperatorMigrateCommand(tb testing.TB) (*cli.MockUi, *OperatorMigrateCommand) {
+	tb.Helper()
+
+	ui := cli.NewMockUi()
+	return ui, &OperatorMigrateCommand{
+		BaseCommand: &BaseCommand{
+			UI: ui,
+		},
+		PhysicalBackends: map[string]physical.Factory{
+			"file": physFile.NewFileBackend,
+		},
+	}
+}
+
+// testMigrateStorage returns the directories of a source file storage with
+// the given keys and of an empty destination file storage, along with the
+// path of the configuration of the migration between them
+func testMigrateStorage(t *testing.T, keys []string) (string, string, string) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "vault-migrate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := filepath.Join(dir, "source")
+	dest := filepath.Join(dir, "dest")
+
+	b, err := physFile.NewFileBackend(map[string]string{"path": source}, logformat.NewVaultLogger(log.LevelTrace))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range keys {
+		if err := b.Put(context.Background(), &physical.Entry{Key: key, Value: []byte("value of " + key)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	config := filepath.Join(dir, "migrate.hcl")
+	if err := ioutil.WriteFile(config, []byte(fmt.Sprintf(`
+storage_source "file" {
+  path = %q
+}
+
+storage_destination "file" {
+  path = %q
+}
+`, source, dest)), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return source, dest, config
+}
+
+func testMigrateKeys(t *testing.T, path string) []string {
+	t.Helper()
+
+	b, err := physFile.NewFileBackend(map[string]string{"path": path}, logformat.NewVaultLogger(log.LevelTrace))
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := listKeys(context.Background(), b, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		entry, err := b.Get(context.Background(), key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(entry.Value) != "value of "+key {
+			t.Fatalf("bad value of %s: %q", key, entry.Value)
+		}
+	}
+	return keys
+}
+
+func TestOperatorMigrateCommand_Run(t *testing.T) {
+	t.Parallel()
+
+	keys := []string{"core/keyring", "core/master", "logical/abc/foo", "logical/abc/nested/bar", "sys/token/id/xyz"}
+
+	t.Run("no_config", func(t *testing.T) {
+		t.Parallel()
+
+		ui, cmd := testOperatorMigrateCommand(t)
+		code := cmd.Run(nil)
+		if code != 1 {
+			t.Errorf("expected %d to be %d", code, 1)
+		}
+		expected := "Must specify exactly one config path"
+		combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+		if !strings.Contains(combined, expected) {
+			t.Errorf("expected %q to contain %q", combined, expected)
+		}
+	})
+
+	t.Run("migrate", func(t *testing.T) {
+		t.Parallel()
+
+		source, dest, config := testMigrateStorage(t, keys)
+		defer os.RemoveAll(filepath.Dir(source))
+
+		ui, cmd := testOperatorMigrateCommand(t)
+		code := cmd.Run([]string{"-config", config})
+		if code != 0 {
+			t.Fatalf("expected %d to be %d: %s", code, 0, ui.ErrorWriter.String())
+		}
+		expected := "Success! Migrated 5 keys from file to file storage."
+		combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+		if !strings.Contains(combined, expected) {
+			t.Errorf("expected %q to contain %q", combined, expected)
+		}
+
+		if actual := testMigrateKeys(t, dest); !reflect.DeepEqual(actual, keys) {
+			t.Errorf("expected %v to be %v", actual, keys)
+		}
+
+		// The destination now has data, so only a resumed migration runs
+		ui, cmd = testOperatorMigrateCommand(t)
+		code = cmd.Run([]string{"-config", config})
+		if code != 2 {
+			t.Errorf("expected %d to be %d", code, 2)
+		}
+		expected = "Destination storage already contains data"
+		combined = ui.OutputWriter.String() + ui.ErrorWriter.String()
+		if !strings.Contains(combined, expected) {
+			t.Errorf("expected %q to contain %q", combined, expected)
+		}
+	})
+
+	t.Run("start", func(t *testing.T) {
+		t.Parallel()
+
+		source, dest, config := testMigrateStorage(t, keys)
+		defer os.RemoveAll(filepath.Dir(source))
+
+		ui, cmd := testOperatorMigrateCommand(t)
+		code := cmd.Run([]string{"-config", config, "-start", "logical/abc/nested/bar"})
+		if code != 0 {
+			t.Fatalf("expected %d to be %d: %s", code, 0, ui.ErrorWriter.String())
+		}
+
+		if actual := testMigrateKeys(t, dest); !reflect.DeepEqual(actual, keys[3:]) {
+			t.Errorf("expected %v to be %v", actual, keys[3:])
+		}
+	})
+}
+
+func TestParseMigratorConfig(t *testing.T) {
+	t.Parallel()
+
+	config, err := parseMigratorConfig(`
+storage_source "consul" {
+  path = "vault/"
+}
+
+storage_destination "File" {
+  path = "/var/lib/vault"
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.StorageSource.Type != "consul" || config.StorageSource.Config["path"] != "vault/" {
+		t.Errorf("bad source storage: %#v", config.StorageSource)
+	}
+	if config.StorageDestination.Type != "file" || config.StorageDestination.Config["path"] != "/var/lib/vault" {
+		t.Errorf("bad destination storage: %#v", config.StorageDestination)
+	}
+
+	cases := []struct {
+		name     string
+		config   string
+		expected string
+	}{
+		{
+			"missing_destination",
+			`storage_source "file" { path = "/a" }`,
+			`missing "storage_destination" block`,
+		},
+		{
+			"same_storage",
+			`storage_source "file" { path = "/a" }
+			storage_destination "file" { path = "/a" }`,
+			"source and destination storage are the same",
+		},
+		{
+			"invalid_key",
+			`storage "file" { path = "/a" }`,
+			"invalid key 'storage'",
+		},
+	}
+
+	for _, tc := range cases {
+		_, err := parseMigratorConfig(tc.config)
+		if err == nil || !strings.Contains(err.Error(), tc.expected) {
+			t.Errorf("%s: expected error containing %q, got %v", tc.name, tc.expected, err)
+		}
+	}
+}
@@ -29,6 +29,7 @@ type OperatorRekeyCommand struct {
 	flagPGPKeys      []string
 	flagStatus       bool
 	flagTarget       string
+	flagVerify       bool
 
 	// Backup options
 	flagBackup         bool
@@ -76,6 +77,15 @@ Usage: vault rekey [options] [KEY]
           -key-threshold=2 \
           -pgp-keys="keybase:hashicorp,keybase:jefferai,keybase:sethvargo"
 
+  Rekey and require the new unseal keys to be provided before the new master
+  key is installed:
+
+      $ vault operator rekey -init -verify
+
+  Provide a new unseal key to verify the rekey:
+
+      $ vault operator rekey -verify
+
   Store encrypted PGP keys in Vault's core:
 
       $ vault operator rekey \
@@ -96,7 +106,7 @@ Usage: vault rekey [options] [KEY]
 }
 
 func (c *OperatorRekeyCommand) Flags() *FlagSets {
-	set := c.flagSet(FlagSetHTTP)
+	set := c.flagSet(FlagSetHTTP | FlagSetOutputFormat)
 
 	f := set.NewFlagSet("Common Options")
 
@@ -177,6 +187,16 @@ func (c *OperatorRekeyCommand) Flags() *FlagSets {
 			"specified in this list.",
 	})
 
+	f.BoolVar(&BoolVar{
+		Name:    "verify",
+		Target:  &c.flagVerify,
+		Default: false,
+		Usage: "With -init, require a quorum of the new unseal keys to be " +
+			"provided before the new master key is installed. Otherwise, " +
+			"indicates that the action (-status, -cancel, or providing an " +
+			"unseal key) affects the verification of the current rekey.",
+	})
+
 	f = set.NewFlagSet("Backup Options")
 
 	f.BoolVar(&BoolVar{
@@ -289,10 +309,14 @@ func (c *OperatorRekeyCommand) Run(args []string) int {
 		return c.backupDelete(client)
 	case c.flagBackupRetrieve:
 		return c.backupRetrieve(client)
-	case c.flagCancel:
-		return c.cancel(client)
 	case c.flagInit:
 		return c.init(client)
+	case c.flagCancel && c.flagVerify:
+		return c.cancelVerification(client)
+	case c.flagCancel:
+		return c.cancel(client)
+	case c.flagStatus && c.flagVerify:
+		return c.verificationStatus(client)
 	case c.flagStatus:
 		return c.status(client)
 	default:
@@ -301,6 +325,9 @@ func (c *OperatorRekeyCommand) Run(args []string) int {
 		if len(args) > 0 {
 			key = strings.TrimSpace(args[0])
 		}
+		if c.flagVerify {
+			return c.provideVerification(client, key)
+		}
 		return c.provide(client, key)
 	}
 }
@@ -325,6 +352,8 @@ func (c *OperatorRekeyCommand) init(client *api.Client) int {
 		SecretThreshold: c.flagKeyThreshold,
 		PGPKeys:         c.flagPGPKeys,
 		Backup:          c.flagBackup,
+
+		RequireVerification: c.flagVerify,
 	})
 	if err != nil {
 		c.UI.Error(fmt.Sprintf("Error initializing rekey: %s", err))
@@ -378,8 +407,12 @@ func (c *OperatorRekeyCommand) cancel(client *api.Client) int {
 		return 2
 	}
 
-	c.UI.Output("Success! Canceled rekeying (if it was started)")
-	return 0
+	return OutputSuccess(c.UI, c.flagFormat, "",
+		"Success! Canceled rekeying (if it was started)",
+		map[string]interface{}{
+			"target":   c.flagTarget,
+			"canceled": true,
+		})
 }
 
 // provide prompts the user for the seal key and posts it to the update root
@@ -415,7 +448,89 @@ func (c *OperatorRekeyCommand) provide(client *api.Client, key string) int {
 		return 1
 	}
 
+	if status.VerificationRequired && status.VerificationNonce != "" {
+		c.UI.Error(wrapAtLength(
+			"The rekey is awaiting the verification of the new unseal keys. " +
+				"Provide them by running \"vault operator rekey -verify\"."))
+		return 1
+	}
+
+	key, nonce, ret := c.readKey(key, status.Nonce, "Rekey operation nonce")
+	if ret != 0 {
+		return ret
+	}
+
+	// Provide the key, this may potentially complete the update
+	resp, err := updateFn(key, nonce)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error posting unseal key: %s", err))
+		return 2
+	}
+
+	if !resp.Complete {
+		return c.status(client)
+	}
+
+	return c.printUnsealKeys(status, resp)
+}
+
+// provideVerification prompts the user for a new unseal key and posts it to
+// the verify endpoint. If this completes the verification, the new master key
+// is installed.
+func (c *OperatorRekeyCommand) provideVerification(client *api.Client, key string) int {
+	var statusFn func() (*api.RekeyVerificationStatusResponse, error)
+	var updateFn func(string, string) (*api.RekeyVerificationUpdateResponse, error)
+
+	switch strings.ToLower(strings.TrimSpace(c.flagTarget)) {
+	case "barrier":
+		statusFn = client.Sys().RekeyVerificationStatus
+		updateFn = client.Sys().RekeyVerificationUpdate
+	case "recovery", "hsm":
+		statusFn = client.Sys().RekeyRecoveryKeyVerificationStatus
+		updateFn = client.Sys().RekeyRecoveryKeyVerificationUpdate
+	default:
+		c.UI.Error(fmt.Sprintf("Unknown target: %s", c.flagTarget))
+		return 1
+	}
+
+	status, err := statusFn()
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error getting rekey verification status: %s", err))
+		return 2
+	}
+
+	key, nonce, ret := c.readKey(key, status.Nonce, "Rekey verification nonce")
+	if ret != 0 {
+		return ret
+	}
+
+	// Provide the key, this may potentially complete the verification
+	resp, err := updateFn(key, nonce)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error posting unseal key: %s", err))
+		return 2
+	}
+
+	if !resp.Complete {
+		return c.verificationStatus(client)
+	}
+
+	if !isTableFormat(c.flagFormat) {
+		return OutputData(c.UI, c.flagFormat, "", resp)
+	}
+
+	c.UI.Output("")
+	c.UI.Output(wrapAtLength(fmt.Sprintf(
+		"Rekey verification successful. The rekey operation is complete and the "+
+			"new keys are now active. Operation nonce: %s", resp.Nonce)))
+	return 0
+}
+
+// readKey reads the key share from stdin or the tty, or returns the one given
+// as an argument, along with the nonce to submit it with.
+func (c *OperatorRekeyCommand) readKey(key, statusNonce, nonceLabel string) (string, string, int) {
 	var nonce string
+	var err error
 
 	switch key {
 	case "-": // Read from stdin
@@ -430,23 +545,23 @@ func (c *OperatorRekeyCommand) provide(client *api.Client, key string) int {
 		var buf bytes.Buffer
 		if _, err := io.Copy(&buf, stdin); err != nil {
 			c.UI.Error(fmt.Sprintf("Failed to read from stdin: %s", err))
-			return 1
+			return "", "", 1
 		}
 
 		key = buf.String()
 	case "": // Prompt using the tty
 		// Nonce value is not required if we are prompting via the terminal
-		nonce = status.Nonce
+		nonce = statusNonce
 
 		w := getWriterFromUI(c.UI)
-		fmt.Fprintf(w, "Rekey operation nonce: %s\n", nonce)
+		fmt.Fprintf(w, "%s: %s\n", nonceLabel, nonce)
 		fmt.Fprintf(w, "Unseal Key (will be hidden): ")
 		key, err = password.Read(os.Stdin)
 		fmt.Fprintf(w, "\n")
 		if err != nil {
 			if err == password.ErrInterrupted {
 				c.UI.Error("user canceled")
-				return 1
+				return "", "", 1
 			}
 
 			c.UI.Error(wrapAtLength(fmt.Sprintf("An error occurred attempting to "+
@@ -455,7 +570,7 @@ func (c *OperatorRekeyCommand) provide(client *api.Client, key string) int {
 				"command or you are executing outside of a terminal (tty). If you "+
 				"want to pipe the value, pass \"-\" as the argument to read from "+
 				"stdin. The raw error was: %s", err)))
-			return 1
+			return "", "", 1
 		}
 	default: // Supplied directly as an arg
 		nonce = c.flagNonce
@@ -468,21 +583,10 @@ func (c *OperatorRekeyCommand) provide(client *api.Client, key string) int {
 	// Verify we have a nonce value
 	if nonce == "" {
 		c.UI.Error("Missing nonce value: specify it via the -nonce flag")
-		return 1
+		return "", "", 1
 	}
 
-	// Provide the key, this may potentially complete the update
-	resp, err := updateFn(key, nonce)
-	if err != nil {
-		c.UI.Error(fmt.Sprintf("Error posting unseal key: %s", err))
-		return 2
-	}
-
-	if !resp.Complete {
-		return c.status(client)
-	}
-
-	return c.printUnsealKeys(status, resp)
+	return key, nonce, 0
 }
 
 // status is used just to fetch and dump the status.
@@ -509,6 +613,72 @@ func (c *OperatorRekeyCommand) status(client *api.Client) int {
 	return c.printStatus(status)
 }
 
+// verificationStatus is used to fetch and dump the status of the
+// verification.
+func (c *OperatorRekeyCommand) verificationStatus(client *api.Client) int {
+	// Handle the different API requests
+	var fn func() (*api.RekeyVerificationStatusResponse, error)
+	switch strings.ToLower(strings.TrimSpace(c.flagTarget)) {
+	case "barrier":
+		fn = client.Sys().RekeyVerificationStatus
+	case "recovery", "hsm":
+		fn = client.Sys().RekeyRecoveryKeyVerificationStatus
+	default:
+		c.UI.Error(fmt.Sprintf("Unknown target: %s", c.flagTarget))
+		return 1
+	}
+
+	// Make the request
+	status, err := fn()
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error reading rekey verification status: %s", err))
+		return 2
+	}
+
+	if !isTableFormat(c.flagFormat) {
+		return OutputData(c.UI, c.flagFormat, "", status)
+	}
+
+	out := []string{}
+	out = append(out, "Key | Value")
+	out = append(out, fmt.Sprintf("Verification Nonce | %s", status.Nonce))
+	out = append(out, fmt.Sprintf("Started | %t", status.Started))
+	out = append(out, fmt.Sprintf("Verification Progress | %d/%d", status.Progress, status.T))
+	out = append(out, fmt.Sprintf("New Shares | %d", status.N))
+	out = append(out, fmt.Sprintf("New Threshold | %d", status.T))
+
+	c.UI.Output(tableOutput(out, nil))
+	return 0
+}
+
+// cancelVerification is used to restart the verification of the rekey.
+func (c *OperatorRekeyCommand) cancelVerification(client *api.Client) int {
+	// Handle the different API requests
+	var fn func() error
+	switch strings.ToLower(strings.TrimSpace(c.flagTarget)) {
+	case "barrier":
+		fn = client.Sys().RekeyVerificationCancel
+	case "recovery", "hsm":
+		fn = client.Sys().RekeyRecoveryKeyVerificationCancel
+	default:
+		c.UI.Error(fmt.Sprintf("Unknown target: %s", c.flagTarget))
+		return 1
+	}
+
+	// Make the request
+	if err := fn(); err != nil {
+		c.UI.Error(fmt.Sprintf("Error restarting rekey verification: %s", err))
+		return 2
+	}
+
+	return OutputSuccess(c.UI, c.flagFormat, "",
+		"Success! Restarted rekey verification",
+		map[string]interface{}{
+			"target":    c.flagTarget,
+			"restarted": true,
+		})
+}
+
 // backupRetrieve retrieves the stored backup keys.
 func (c *OperatorRekeyCommand) backupRetrieve(client *api.Client) int {
 	// Handle the different API requests
@@ -534,7 +704,7 @@ func (c *OperatorRekeyCommand) backupRetrieve(client *api.Client) int {
 		Data: structs.New(storedKeys).Map(),
 	}
 
-	return OutputSecret(c.UI, "table", secret)
+	return OutputSecret(c.UI, c.flagFormat, secret)
 }
 
 // backupDelete deletes the stored backup keys.
@@ -557,12 +727,20 @@ func (c *OperatorRekeyCommand) backupDelete(client *api.Client) int {
 		return 2
 	}
 
-	c.UI.Output("Success! Delete stored keys (if they existed)")
-	return 0
+	return OutputSuccess(c.UI, c.flagFormat, "",
+		"Success! Delete stored keys (if they existed)",
+		map[string]interface{}{
+			"target":  c.flagTarget,
+			"deleted": true,
+		})
 }
 
 // printStatus dumps the status to output
 func (c *OperatorRekeyCommand) printStatus(status *api.RekeyStatusResponse) int {
+	if !isTableFormat(c.flagFormat) {
+		return OutputData(c.UI, c.flagFormat, "", status)
+	}
+
 	out := []string{}
 	out = append(out, "Key | Value")
 	out = append(out, fmt.Sprintf("Nonce | %s", status.Nonce))
@@ -572,6 +750,10 @@ func (c *OperatorRekeyCommand) printStatus(status *api.RekeyStatusResponse) int
 		out = append(out, fmt.Sprintf("Rekey Progress | %d/%d", status.Progress, status.Required))
 		out = append(out, fmt.Sprintf("New Shares | %d", status.N))
 		out = append(out, fmt.Sprintf("New Threshold | %d", status.T))
+		out = append(out, fmt.Sprintf("Verification Required | %t", status.VerificationRequired))
+		if status.VerificationNonce != "" {
+			out = append(out, fmt.Sprintf("Verification Nonce | %s", status.VerificationNonce))
+		}
 	}
 
 	if len(status.PGPFingerprints) > 0 {
@@ -584,6 +766,10 @@ func (c *OperatorRekeyCommand) printStatus(status *api.RekeyStatusResponse) int
 }
 
 func (c *OperatorRekeyCommand) printUnsealKeys(status *api.RekeyStatusResponse, resp *api.RekeyUpdateResponse) int {
+	if !isTableFormat(c.flagFormat) {
+		return OutputData(c.UI, c.flagFormat, "", resp)
+	}
+
 	// Space between the key prompt, if any, and the output
 	c.UI.Output("")
 
@@ -612,15 +798,38 @@ func (c *OperatorRekeyCommand) printUnsealKeys(status *api.RekeyStatusResponse,
 	c.UI.Output(fmt.Sprintf("Operation nonce: %s", resp.Nonce))
 
 	if len(resp.PGPFingerprints) > 0 && resp.Backup {
+		backupPath := "core/unseal-keys-backup"
+		if t := strings.ToLower(strings.TrimSpace(c.flagTarget)); t == "recovery" || t == "hsm" {
+			backupPath = "core/recovery-keys-backup"
+		}
+
 		c.UI.Output("")
 		c.UI.Output(wrapAtLength(fmt.Sprintf(
-			"The encrypted unseal keys are backed up to \"core/unseal-keys-backup\"" +
-				"in the storage backend. Remove these keys at any time using " +
-				"\"vault rekey -delete-backup\". Vault does not automatically remove " +
-				"these keys.",
+			"The encrypted unseal keys are backed up to \"%s\" "+
+				"in the storage backend. Remove these keys at any time using "+
+				"\"vault operator rekey -backup-delete\". Vault does not automatically "+
+				"remove these keys.", backupPath,
 		)))
 	}
 
+	if resp.VerificationRequired {
+		c.UI.Output("")
+		c.UI.Output(wrapAtLength(fmt.Sprintf(
+			"Vault has created a new key, split into %d key shares and a key threshold "+
+				"of %d. These will not be active until after verification is complete. "+
+				"Please securely distribute the key shares printed above. When Vault "+
+				"is re-sealed, restarted, or stopped, you must still provide at least "+
+				"%d of the old key shares to unseal it, until the verification is "+
+				"complete. Provide %d of the new key shares by running \"vault "+
+				"operator rekey -verify\" with the verification nonce %s.",
+			status.N,
+			status.T,
+			status.Required,
+			status.T,
+			resp.VerificationNonce)))
+		return 0
+	}
+
 	c.UI.Output("")
 	c.UI.Output(wrapAtLength(fmt.Sprintf(
 		"Vault rekeyed with %d key shares an a key threshold of %d. Please "+
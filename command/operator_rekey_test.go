@@ -316,6 +316,86 @@ func TestOperatorRekeyCommand_Run(t *testing.T) {
 		}
 	})
 
+	t.Run("provide_verify", func(t *testing.T) {
+		t.Parallel()
+
+		client, keys, closer := testVaultServerUnseal(t)
+		defer closer()
+
+		// Initialize a rekey requiring verification
+		status, err := client.Sys().RekeyInit(&api.RekeyInitRequest{
+			SecretShares:        1,
+			SecretThreshold:     1,
+			RequireVerification: true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		nonce := status.Nonce
+
+		var ui *cli.MockUi
+		for _, key := range keys {
+			var cmd *OperatorRekeyCommand
+			ui, cmd = testOperatorRekeyCommand(t)
+			cmd.client = client
+
+			code := cmd.Run([]string{
+				"-nonce", nonce,
+				key,
+			})
+			if exp := 0; code != exp {
+				t.Errorf("expected %d to be %d: %s", code, exp, ui.ErrorWriter.String())
+			}
+		}
+
+		output := ui.OutputWriter.String()
+		if !strings.Contains(output, "will not be active until after verification") {
+			t.Errorf("expected verification notice in %q", output)
+		}
+
+		re := regexp.MustCompile(`Key 1: (.+)`)
+		match := re.FindAllStringSubmatch(output, -1)
+		if len(match) < 1 || len(match[0]) < 2 {
+			t.Fatalf("bad match: %#v", match)
+		}
+		newKey := match[0][1]
+
+		status, err = client.Sys().RekeyStatus()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ui, cmd := testOperatorRekeyCommand(t)
+		cmd.client = client
+
+		code := cmd.Run([]string{
+			"-verify",
+			"-nonce", status.VerificationNonce,
+			newKey,
+		})
+		if exp := 0; code != exp {
+			t.Errorf("expected %d to be %d: %s", code, exp, ui.ErrorWriter.String())
+		}
+
+		expected := "Rekey verification successful"
+		combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+		if !strings.Contains(combined, expected) {
+			t.Errorf("expected %q to contain %q", combined, expected)
+		}
+
+		// Unseal with the new key
+		if err := client.Sys().Seal(); err != nil {
+			t.Fatal(err)
+		}
+		sealStatus, err := client.Sys().Unseal(newKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sealStatus.Sealed {
+			t.Errorf("expected vault to be unsealed: %#v", sealStatus)
+		}
+	})
+
 	t.Run("provide_stdin", func(t *testing.T) {
 		t.Parallel()
 
@@ -43,7 +43,7 @@ Usage: vault seal [options]
 }
 
 func (c *OperatorSealCommand) Flags() *FlagSets {
-	return c.flagSet(FlagSetHTTP)
+	return c.flagSet(FlagSetHTTP | FlagSetOutputField | FlagSetOutputFormat)
 }
 
 func (c *OperatorSealCommand) AutocompleteArgs() complete.Predictor {
@@ -79,6 +79,9 @@ func (c *OperatorSealCommand) Run(args []string) int {
 		return 2
 	}
 
-	c.UI.Output("Success! Vault is sealed.")
-	return 0
+	return OutputSuccess(c.UI, c.flagFormat, c.flagField,
+		"Success! Vault is sealed.",
+		map[string]interface{}{
+			"sealed": true,
+		})
 }
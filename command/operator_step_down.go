@@ -39,7 +39,7 @@ Usage: vault operator step-down [options]
 }
 
 func (c *OperatorStepDownCommand) Flags() *FlagSets {
-	return c.flagSet(FlagSetHTTP)
+	return c.flagSet(FlagSetHTTP | FlagSetOutputField | FlagSetOutputFormat)
 }
 
 func (c *OperatorStepDownCommand) AutocompleteArgs() complete.Predictor {
@@ -75,6 +75,9 @@ func (c *OperatorStepDownCommand) Run(args []string) int {
 		return 2
 	}
 
-	c.UI.Output(fmt.Sprintf("Success! Stepped down: %s", client.Address()))
-	return 0
+	return OutputSuccess(c.UI, c.flagFormat, c.flagField,
+		fmt.Sprintf("Success! Stepped down: %s", client.Address()),
+		map[string]interface{}{
+			"address": client.Address(),
+		})
 }
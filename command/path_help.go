@@ -50,7 +50,7 @@ Usage: vault path-help [options] PATH
 }
 
 func (c *PathHelpCommand) Flags() *FlagSets {
-	return c.flagSet(FlagSetHTTP)
+	return c.flagSet(FlagSetHTTP | FlagSetOutputField | FlagSetOutputFormat)
 }
 
 func (c *PathHelpCommand) AutocompleteArgs() complete.Predictor {
@@ -97,6 +97,10 @@ func (c *PathHelpCommand) Run(args []string) int {
 		return 2
 	}
 
+	if c.flagField != "" || !isTableFormat(c.flagFormat) {
+		return OutputData(c.UI, c.flagFormat, c.flagField, help)
+	}
+
 	c.UI.Output(help.Help)
 	return 0
 }
@@ -57,6 +57,12 @@ func TestPathHelpCommand_Run(t *testing.T) {
 			"currently mounted backends",
 			0,
 		},
+		{
+			"json",
+			[]string{"-format=json", "sys/mounts"},
+			`"help": "`,
+			0,
+		},
 	}
 
 	for _, tc := range cases {
@@ -39,7 +39,7 @@ Usage: vault policy delete [options] NAME
 }
 
 func (c *PolicyDeleteCommand) Flags() *FlagSets {
-	return c.flagSet(FlagSetHTTP)
+	return c.flagSet(FlagSetHTTP | FlagSetOutputField | FlagSetOutputFormat)
 }
 
 func (c *PolicyDeleteCommand) AutocompleteArgs() complete.Predictor {
@@ -80,6 +80,9 @@ func (c *PolicyDeleteCommand) Run(args []string) int {
 		return 2
 	}
 
-	c.UI.Output(fmt.Sprintf("Success! Deleted policy: %s", name))
-	return 0
+	return OutputSuccess(c.UI, c.flagFormat, c.flagField,
+		fmt.Sprintf("Success! Deleted policy: %s", name),
+		map[string]interface{}{
+			"name": name,
+		})
 }
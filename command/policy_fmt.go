@@ -41,7 +41,7 @@ Usage: vault policy fmt [options] PATH
 }
 
 func (c *PolicyFmtCommand) Flags() *FlagSets {
-	return c.flagSet(FlagSetNone)
+	return c.flagSet(FlagSetOutputField | FlagSetOutputFormat)
 }
 
 func (c *PolicyFmtCommand) AutocompleteArgs() complete.Predictor {
@@ -104,6 +104,9 @@ func (c *PolicyFmtCommand) Run(args []string) int {
 		return 1
 	}
 
-	c.UI.Output(fmt.Sprintf("Success! Formatted policy: %s", path))
-	return 0
+	return OutputSuccess(c.UI, c.flagFormat, c.flagField,
+		fmt.Sprintf("Success! Formatted policy: %s", path),
+		map[string]interface{}{
+			"path": path,
+		})
 }
@@ -31,7 +31,7 @@ Usage: vault policy list [options]
 }
 
 func (c *PolicyListCommand) Flags() *FlagSets {
-	return c.flagSet(FlagSetHTTP)
+	return c.flagSet(FlagSetHTTP | FlagSetOutputFormat)
 }
 
 func (c *PolicyListCommand) AutocompleteArgs() complete.Predictor {
@@ -68,6 +68,11 @@ func (c *PolicyListCommand) Run(args []string) int {
 		c.UI.Error(fmt.Sprintf("Error listing policies: %s", err))
 		return 2
 	}
+
+	if !isTableFormat(c.flagFormat) {
+		return OutputData(c.UI, c.flagFormat, "", policies)
+	}
+
 	for _, p := range policies {
 		c.UI.Output(p)
 	}
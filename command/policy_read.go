@@ -36,7 +36,7 @@ Usage: vault policy read [options] [NAME]
 }
 
 func (c *PolicyReadCommand) Flags() *FlagSets {
-	return c.flagSet(FlagSetHTTP)
+	return c.flagSet(FlagSetHTTP | FlagSetOutputField | FlagSetOutputFormat)
 }
 
 func (c *PolicyReadCommand) AutocompleteArgs() complete.Predictor {
@@ -81,6 +81,14 @@ func (c *PolicyReadCommand) Run(args []string) int {
 		c.UI.Error(fmt.Sprintf("No policy named: %s", name))
 		return 2
 	}
+
+	if c.flagField != "" || !isTableFormat(c.flagFormat) {
+		return OutputData(c.UI, c.flagFormat, c.flagField, map[string]interface{}{
+			"name":  name,
+			"rules": rules,
+		})
+	}
+
 	c.UI.Output(strings.TrimSpace(rules))
 
 	return 0
@@ -96,6 +96,33 @@ func TestPolicyReadCommand_Run(t *testing.T) {
 		}
 	})
 
+	t.Run("field", func(t *testing.T) {
+		t.Parallel()
+
+		client, closer := testVaultServer(t)
+		defer closer()
+
+		policy := `path "secret/" {}`
+		if err := client.Sys().PutPolicy("my-policy", policy); err != nil {
+			t.Fatal(err)
+		}
+
+		ui, cmd := testPolicyReadCommand(t)
+		cmd.client = client
+
+		code := cmd.Run([]string{
+			"-field", "rules",
+			"my-policy",
+		})
+		if exp := 0; code != exp {
+			t.Errorf("expected %d to be %d", code, exp)
+		}
+
+		if actual := ui.OutputWriter.String(); actual != policy {
+			t.Errorf("expected %q to be %q", actual, policy)
+		}
+	})
+
 	t.Run("communication_failure", func(t *testing.T) {
 		t.Parallel()
 
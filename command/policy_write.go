@@ -46,7 +46,7 @@ Usage: vault policy write [options] NAME PATH
 }
 
 func (c *PolicyWriteCommand) Flags() *FlagSets {
-	return c.flagSet(FlagSetHTTP)
+	return c.flagSet(FlagSetHTTP | FlagSetOutputField | FlagSetOutputFormat)
 }
 
 func (c *PolicyWriteCommand) AutocompleteArgs() complete.Predictor {
@@ -122,6 +122,9 @@ func (c *PolicyWriteCommand) Run(args []string) int {
 		return 2
 	}
 
-	c.UI.Output(fmt.Sprintf("Success! Uploaded policy: %s", name))
-	return 0
+	return OutputSuccess(c.UI, c.flagFormat, c.flagField,
+		fmt.Sprintf("Success! Uploaded policy: %s", name),
+		map[string]interface{}{
+			"name": name,
+		})
 }
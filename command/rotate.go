@@ -44,7 +44,7 @@ Usage: vault rotate [options]
 }
 
 func (c *OperatorRotateCommand) Flags() *FlagSets {
-	return c.flagSet(FlagSetHTTP)
+	return c.flagSet(FlagSetHTTP | FlagSetOutputField | FlagSetOutputFormat)
 }
 
 func (c *OperatorRotateCommand) AutocompleteArgs() complete.Predictor {
@@ -89,6 +89,10 @@ func (c *OperatorRotateCommand) Run(args []string) int {
 		return 2
 	}
 
+	if c.flagField != "" || !isTableFormat(c.flagFormat) {
+		return OutputData(c.UI, c.flagFormat, c.flagField, status)
+	}
+
 	c.UI.Output("Success! Rotated key")
 	c.UI.Output("")
 	c.UI.Output(printKeyStatus(status))
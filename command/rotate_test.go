@@ -1,6 +1,7 @@
 package command
 
 import (
+	"strconv"
 	"strings"
 	"testing"
 
@@ -88,6 +89,31 @@ func TestOperatorRotateCommand_Run(t *testing.T) {
 		}
 	})
 
+	t.Run("field", func(t *testing.T) {
+		t.Parallel()
+
+		client, closer := testVaultServer(t)
+		defer closer()
+
+		ui, cmd := testOperatorRotateCommand(t)
+		cmd.client = client
+
+		code := cmd.Run([]string{
+			"-field", "term",
+		})
+		if exp := 0; code != exp {
+			t.Errorf("expected %d to be %d", code, exp)
+		}
+
+		status, err := client.Sys().KeyStatus()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exp := strconv.Itoa(status.Term); ui.OutputWriter.String() != exp {
+			t.Errorf("expected %q to be %q", ui.OutputWriter.String(), exp)
+		}
+	})
+
 	t.Run("communication_failure", func(t *testing.T) {
 		t.Parallel()
 
@@ -3,7 +3,9 @@ package command
 import (
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/hashicorp/vault/api"
 	"github.com/mitchellh/cli"
 	"github.com/posener/complete"
 )
@@ -13,6 +15,9 @@ var _ cli.CommandAutocomplete = (*SecretsDisableCommand)(nil)
 
 type SecretsDisableCommand struct {
 	*BaseCommand
+
+	flagRecoveryWindow time.Duration
+	flagForce          bool
 }
 
 func (c *SecretsDisableCommand) Synopsis() string {
@@ -31,13 +36,40 @@ Usage: vault secrets disable [options] PATH
 
       $ vault secrets disable aws/
 
+  Disable the secrets engine enabled at aws/, keeping it for recovery for a
+  day:
+
+      $ vault secrets disable -recovery-window=24h aws/
+
 ` + c.Flags().Help()
 
 	return strings.TrimSpace(helpText)
 }
 
 func (c *SecretsDisableCommand) Flags() *FlagSets {
-	return c.flagSet(FlagSetHTTP)
+	set := c.flagSet(FlagSetHTTP | FlagSetOutputField | FlagSetOutputFormat)
+
+	f := set.NewFlagSet("Command Options")
+
+	f.DurationVar(&DurationVar{
+		Name:       "recovery-window",
+		Target:     &c.flagRecoveryWindow,
+		Completion: complete.PredictAnything,
+		Usage: "Mark the secrets engine for deletion instead of removing it, " +
+			"and purge it once this duration elapses. Until then the engine " +
+			"can be restored with its data and leases.",
+	})
+
+	f.BoolVar(&BoolVar{
+		Name:    "force",
+		Target:  &c.flagForce,
+		Default: false,
+		Usage: "Remove the secrets engine even if revoking its leases fails. " +
+			"This is a DANGEROUS operation as it removes Vault's oversight of " +
+			"the external secrets of the engine.",
+	})
+
+	return set
 }
 
 func (c *SecretsDisableCommand) AutocompleteArgs() complete.Predictor {
@@ -74,11 +106,27 @@ func (c *SecretsDisableCommand) Run(args []string) int {
 
 	path := ensureTrailingSlash(sanitizePath(args[0]))
 
-	if err := client.Sys().Unmount(path); err != nil {
+	out, err := client.Sys().UnmountWithOptions(path, &api.UnmountOptions{
+		RecoveryWindow: c.flagRecoveryWindow,
+		Force:          c.flagForce,
+	})
+	if err != nil {
 		c.UI.Error(fmt.Sprintf("Error disabling secrets engine at %s: %s", path, err))
 		return 2
 	}
 
-	c.UI.Output(fmt.Sprintf("Success! Disabled the secrets engine (if it existed) at: %s", path))
-	return 0
+	if out.PurgeTime != "" {
+		return OutputSuccess(c.UI, c.flagFormat, c.flagField,
+			fmt.Sprintf("Success! Marked the secrets engine at %s for deletion, it is purged at: %s", path, out.PurgeTime),
+			map[string]interface{}{
+				"path":       path,
+				"purge_time": out.PurgeTime,
+			})
+	}
+
+	return OutputSuccess(c.UI, c.flagFormat, c.flagField,
+		fmt.Sprintf("Success! Disabled the secrets engine (if it existed) at: %s", path),
+		map[string]interface{}{
+			"path": path,
+		})
 }
@@ -22,6 +22,7 @@ type SecretsEnableCommand struct {
 	flagMaxLeaseTTL     time.Duration
 	flagForceNoCache    bool
 	flagPluginName      string
+	flagPluginVersion   string
 	flagLocal           bool
 	flagSealWrap        bool
 }
@@ -65,7 +66,7 @@ Usage: vault secrets enable [options] TYPE
 }
 
 func (c *SecretsEnableCommand) Flags() *FlagSets {
-	set := c.flagSet(FlagSetHTTP)
+	set := c.flagSet(FlagSetHTTP | FlagSetOutputField | FlagSetOutputFormat)
 
 	f := set.NewFlagSet("Command Options")
 
@@ -121,6 +122,14 @@ func (c *SecretsEnableCommand) Flags() *FlagSets {
 			"exist in Vault's plugin catalog.",
 	})
 
+	f.StringVar(&StringVar{
+		Name:       "plugin-version",
+		Target:     &c.flagPluginVersion,
+		Completion: complete.PredictAnything,
+		Usage: "Version of the plugin to run. This version of the plugin must " +
+			"already be registered in the Vault server's plugin catalog.",
+	})
+
 	f.BoolVar(&BoolVar{
 		Name:    "local",
 		Target:  &c.flagLocal,
@@ -199,6 +208,7 @@ func (c *SecretsEnableCommand) Run(args []string) int {
 			MaxLeaseTTL:     c.flagMaxLeaseTTL.String(),
 			ForceNoCache:    c.flagForceNoCache,
 			PluginName:      c.flagPluginName,
+			PluginVersion:   c.flagPluginVersion,
 		},
 	}
 
@@ -212,6 +222,13 @@ func (c *SecretsEnableCommand) Run(args []string) int {
 		thing = c.flagPluginName + " plugin"
 	}
 
-	c.UI.Output(fmt.Sprintf("Success! Enabled the %s at: %s", thing, mountPath))
-	return 0
+	data := map[string]interface{}{
+		"path": mountPath,
+		"type": engineType,
+	}
+	if engineType == "plugin" {
+		data["plugin_name"] = c.flagPluginName
+	}
+	return OutputSuccess(c.UI, c.flagFormat, c.flagField,
+		fmt.Sprintf("Success! Enabled the %s at: %s", thing, mountPath), data)
 }
@@ -1,6 +1,7 @@
 package command
 
 import (
+	"encoding/json"
 	"io/ioutil"
 	"strings"
 	"testing"
@@ -140,6 +141,33 @@ func TestSecretsEnableCommand_Run(t *testing.T) {
 		}
 	})
 
+	t.Run("json", func(t *testing.T) {
+		t.Parallel()
+
+		client, closer := testVaultServer(t)
+		defer closer()
+
+		ui, cmd := testSecretsEnableCommand(t)
+		cmd.client = client
+
+		code := cmd.Run([]string{
+			"-format", "json",
+			"-path", "mount_json/",
+			"pki",
+		})
+		if exp := 0; code != exp {
+			t.Errorf("expected %d to be %d", code, exp)
+		}
+
+		var data map[string]interface{}
+		if err := json.Unmarshal(ui.OutputWriter.Bytes(), &data); err != nil {
+			t.Fatalf("expected JSON output: %s", err)
+		}
+		if data["path"] != "mount_json/" || data["type"] != "pki" {
+			t.Errorf("bad: %#v", data)
+		}
+	})
+
 	t.Run("communication_failure", func(t *testing.T) {
 		t.Parallel()
 
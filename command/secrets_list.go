@@ -47,7 +47,7 @@ Usage: vault secrets list [options]
 }
 
 func (c *SecretsListCommand) Flags() *FlagSets {
-	set := c.flagSet(FlagSetHTTP)
+	set := c.flagSet(FlagSetHTTP | FlagSetOutputFormat)
 
 	f := set.NewFlagSet("Command Options")
 
@@ -96,6 +96,10 @@ func (c *SecretsListCommand) Run(args []string) int {
 		return 2
 	}
 
+	if !isTableFormat(c.flagFormat) {
+		return OutputData(c.UI, c.flagFormat, "", mounts)
+	}
+
 	if c.flagDetailed {
 		c.UI.Output(tableOutput(c.detailedMounts(mounts), nil))
 		return 0
@@ -45,6 +45,12 @@ func TestSecretsListCommand_Run(t *testing.T) {
 			"Default TTL",
 			0,
 		},
+		{
+			"json",
+			[]string{"-format=json"},
+			`"secret/": {`,
+			0,
+		},
 	}
 
 	t.Run("validations", func(t *testing.T) {
@@ -40,7 +40,7 @@ Usage: vault secrets move [options] SOURCE DESTINATION
 }
 
 func (c *SecretsMoveCommand) Flags() *FlagSets {
-	return c.flagSet(FlagSetHTTP)
+	return c.flagSet(FlagSetHTTP | FlagSetOutputField | FlagSetOutputFormat)
 }
 
 func (c *SecretsMoveCommand) AutocompleteArgs() complete.Predictor {
@@ -84,6 +84,10 @@ func (c *SecretsMoveCommand) Run(args []string) int {
 		return 2
 	}
 
-	c.UI.Output(fmt.Sprintf("Success! Moved secrets engine %s to: %s", source, destination))
-	return 0
+	return OutputSuccess(c.UI, c.flagFormat, c.flagField,
+		fmt.Sprintf("Success! Moved secrets engine %s to: %s", source, destination),
+		map[string]interface{}{
+			"source":      source,
+			"destination": destination,
+		})
 }
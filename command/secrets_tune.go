@@ -42,7 +42,7 @@ Usage: vault secrets tune [options] PATH
 }
 
 func (c *SecretsTuneCommand) Flags() *FlagSets {
-	set := c.flagSet(FlagSetHTTP)
+	set := c.flagSet(FlagSetHTTP | FlagSetOutputField | FlagSetOutputFormat)
 
 	f := set.NewFlagSet("Command Options")
 
@@ -114,6 +114,9 @@ func (c *SecretsTuneCommand) Run(args []string) int {
 		return 2
 	}
 
-	c.UI.Output(fmt.Sprintf("Success! Tuned the secrets engine at: %s", mountPath))
-	return 0
+	return OutputSuccess(c.UI, c.flagFormat, c.flagField,
+		fmt.Sprintf("Success! Tuned the secrets engine at: %s", mountPath),
+		map[string]interface{}{
+			"path": mountPath,
+		})
 }
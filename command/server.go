@@ -4,12 +4,14 @@ import (
 	"context"
 	"encoding/base64"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
+	"reflect"
 	"runtime"
 	"sort"
 	"strconv"
@@ -33,9 +35,12 @@ import (
 	"github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/vault/audit"
 	"github.com/hashicorp/vault/command/server"
+	"github.com/hashicorp/vault/helper/fips"
 	"github.com/hashicorp/vault/helper/gated-writer"
 	"github.com/hashicorp/vault/helper/logbridge"
+	"github.com/hashicorp/vault/helper/logbuffer"
 	"github.com/hashicorp/vault/helper/logformat"
+	"github.com/hashicorp/vault/helper/metricsutil"
 	"github.com/hashicorp/vault/helper/mlock"
 	"github.com/hashicorp/vault/helper/parseutil"
 	"github.com/hashicorp/vault/helper/reload"
@@ -62,8 +67,9 @@ type ServerCommand struct {
 
 	WaitGroup *sync.WaitGroup
 
-	logGate *gatedwriter.Writer
-	logger  log.Logger
+	logGate    *gatedwriter.Writer
+	logger     log.Logger
+	recentLogs *logbuffer.Buffer
 
 	cleanupGuard sync.Once
 
@@ -72,6 +78,19 @@ type ServerCommand struct {
 	startedCh       chan (struct{}) // for tests
 	reloadedCh      chan (struct{}) // for tests
 
+	// listeners are the listeners of the server, which are reconciled with
+	// the configuration on reload, and handler the handler they serve
+	listenersLock sync.Mutex
+	listeners     []*serverListener
+	handler       http.Handler
+
+	// telemetry is the telemetry configuration the metrics sinks were
+	// configured with, which are configured again on reload if it changes
+	telemetry      *server.Telemetry
+	inmemSink      *metrics.InmemSink
+	prometheusSink *metricsutil.PrometheusSink
+	metricsSinks   metrics.FanoutSink
+
 	// new stuff
 	flagConfigs        []string
 	flagLogLevel       string
@@ -140,11 +159,13 @@ func (c *ServerCommand) Flags() *FlagSets {
 	f.StringVar(&StringVar{
 		Name:       "log-level",
 		Target:     &c.flagLogLevel,
-		Default:    "info",
+		Default:    "",
 		EnvVar:     "VAULT_LOG_LEVEL",
 		Completion: complete.PredictSet("trace", "debug", "info", "warn", "err"),
 		Usage: "Log verbosity level. Supported values (in order of detail) are " +
-			"\"trace\", \"debug\", \"info\", \"warn\", and \"err\".",
+			"\"trace\", \"debug\", \"info\", \"warn\", and \"err\". This " +
+			"overrides the log_level of the configuration, which defaults to " +
+			"\"info\".",
 	})
 
 	f = set.NewFlagSet("Dev Options")
@@ -267,25 +288,13 @@ func (c *ServerCommand) Run(args []string) int {
 	}
 
 	// Create a logger. We wrap it in a gated writer so that it doesn't
-	// start logging too early.
-	c.logGate = &gatedwriter.Writer{Writer: colorable.NewColorable(os.Stderr)}
-	var level int
+	// start logging too early. The recent lines are kept for sys/debug/logs.
+	c.recentLogs = logbuffer.New(logbuffer.DefaultSize)
+	c.logGate = &gatedwriter.Writer{Writer: io.MultiWriter(colorable.NewColorable(os.Stderr), c.recentLogs)}
 	c.flagLogLevel = strings.ToLower(strings.TrimSpace(c.flagLogLevel))
-	switch c.flagLogLevel {
-	case "trace":
-		level = log.LevelTrace
-	case "debug":
-		level = log.LevelDebug
-	case "info", "":
-		level = log.LevelInfo
-	case "notice":
-		level = log.LevelNotice
-	case "warn", "warning":
-		level = log.LevelWarn
-	case "err", "error":
-		level = log.LevelError
-	default:
-		c.UI.Error(fmt.Sprintf("Unknown log level: %s", c.flagLogLevel))
+	level, err := parseLogLevel(c.flagLogLevel)
+	if err != nil {
+		c.UI.Error(err.Error())
 		return 1
 	}
 
@@ -332,25 +341,10 @@ func (c *ServerCommand) Run(args []string) int {
 	}
 
 	// Load the configuration
-	var config *server.Config
-	if c.flagDev {
-		config = server.DevConfig(c.flagDevHA, c.flagDevTransactional)
-		if c.flagDevListenAddr != "" {
-			config.Listeners[0].Config["address"] = c.flagDevListenAddr
-		}
-	}
-	for _, path := range c.flagConfigs {
-		current, err := server.LoadConfig(path, c.logger)
-		if err != nil {
-			c.UI.Error(fmt.Sprintf("Error loading configuration from %s: %s", path, err))
-			return 1
-		}
-
-		if config == nil {
-			config = current
-		} else {
-			config = config.Merge(current)
-		}
+	config, err := c.loadConfig(c.flagConfigs)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
 	}
 
 	// Ensure at least one config was found.
@@ -369,6 +363,21 @@ func (c *ServerCommand) Run(args []string) int {
 		return 1
 	}
 
+	// The log level of the configuration applies unless the flag is set
+	logLevel := c.flagLogLevel
+	if logLevel == "" {
+		logLevel = strings.ToLower(strings.TrimSpace(config.LogLevel))
+		level, err := parseLogLevel(logLevel)
+		if err != nil {
+			c.UI.Error(err.Error())
+			return 1
+		}
+		c.logger.SetLevel(level)
+	}
+	if logLevel == "" {
+		logLevel = "info"
+	}
+
 	// If mlockall(2) isn't supported, show a warning.  We disable this
 	// in dev because it is quite scary to see when first using Vault.
 	if !c.flagDev && !mlock.Supported() {
@@ -380,7 +389,8 @@ func (c *ServerCommand) Run(args []string) int {
 				"in a Docker container, provide the IPC_LOCK cap to the container."))
 	}
 
-	if err := c.setupTelemetry(config); err != nil {
+	metricsHelper, err := c.setupTelemetry(config)
+	if err != nil {
 		c.UI.Error(fmt.Sprintf("Error initializing telemetry: %s", err))
 		return 1
 	}
@@ -399,10 +409,18 @@ func (c *ServerCommand) Run(args []string) int {
 
 	infoKeys := make([]string, 0, 10)
 	info := make(map[string]string)
-	info["log level"] = c.flagLogLevel
+	info["log level"] = logLevel
 	infoKeys = append(infoKeys, "log level")
 
-	var seal vault.Seal = &vault.DefaultSeal{}
+	seal, err := configureSeal(config, &infoKeys, info, c.logger)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error configuring seal: %v", err))
+		return 1
+	}
+	if err := seal.Init(context.Background()); err != nil {
+		c.UI.Error(fmt.Sprintf("Error initializing seal: %v", err))
+		return 1
+	}
 
 	// Ensure that the seal finalizer is called, even if using verify-only
 	defer func() {
@@ -419,11 +437,42 @@ func (c *ServerCommand) Run(args []string) int {
 		return 1
 	}
 
+	// The disabled seal is the previous seal of a seal migration
+	unwrapSeal, err := configureDisabledSeal(config, &infoKeys, info, c.logger)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error configuring disabled seal: %v", err))
+		return 1
+	}
+	if unwrapSeal != nil {
+		if err := unwrapSeal.Init(context.Background()); err != nil {
+			c.UI.Error(fmt.Sprintf("Error initializing disabled seal: %v", err))
+			return 1
+		}
+		defer func() {
+			if err := unwrapSeal.Finalize(context.Background()); err != nil {
+				c.UI.Error(fmt.Sprintf("Error finalizing disabled seal: %v", err))
+			}
+		}()
+	}
+
+	entropySource, err := configureEntropy(config, seal, &infoKeys, info)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error configuring entropy source: %v", err))
+		return 1
+	}
+
+	// The algorithms which are not FIPS approved are rejected from now on;
+	// FIPS builds always reject them
+	if config.FIPSMode {
+		fips.SetEnabled(true)
+	}
+
 	coreConfig := &vault.CoreConfig{
 		Physical:           backend,
 		RedirectAddr:       config.Storage.RedirectAddr,
 		HAPhysical:         nil,
 		Seal:               seal,
+		UnwrapSeal:         unwrapSeal,
 		AuditBackends:      c.AuditBackends,
 		CredentialBackends: c.CredentialBackends,
 		LogicalBackends:    c.LogicalBackends,
@@ -436,6 +485,12 @@ func (c *ServerCommand) Run(args []string) int {
 		CacheSize:          config.CacheSize,
 		PluginDirectory:    config.PluginDirectory,
 		EnableRaw:          config.EnableRawEndpoint,
+		PerformanceStandby: config.PerformanceStandby,
+		MetricsHelper:      metricsHelper,
+		RecentLogs:         c.recentLogs,
+		EntropySource:      entropySource,
+
+		DefaultMaxRequestDuration: config.DefaultMaxRequestDuration,
 	}
 	if c.flagDev {
 		coreConfig.DevToken = c.flagDevRootTokenID
@@ -605,12 +660,20 @@ CLUSTER_SYNTHESIS_COMPLETE:
 
 	// Compile server information for output later
 	info["storage"] = config.Storage.Type
-	info["log level"] = c.flagLogLevel
+	info["log level"] = logLevel
 	info["mlock"] = fmt.Sprintf(
 		"supported: %v, enabled: %v",
 		mlock.Supported(), !config.DisableMlock && mlock.Supported())
 	infoKeys = append(infoKeys, "mlock", "storage")
 
+	if fips.Enabled() {
+		info["fips mode"] = "enabled"
+		if fips.BuildEnabled() {
+			info["fips mode"] = "enabled (build)"
+		}
+		infoKeys = append(infoKeys, "fips mode")
+	}
+
 	if coreConfig.ClusterAddr != "" {
 		info["cluster address"] = coreConfig.ClusterAddr
 		infoKeys = append(infoKeys, "cluster address")
@@ -636,23 +699,31 @@ CLUSTER_SYNTHESIS_COMPLETE:
 
 	clusterAddrs := []*net.TCPAddr{}
 
+	// Make sure we close all listeners from this point on
+	listenerCloseFunc := func() {
+		c.listenersLock.Lock()
+		defer c.listenersLock.Unlock()
+
+		for _, sl := range c.listeners {
+			sl.ln.Close()
+		}
+		c.listeners = nil
+	}
+
+	defer c.cleanupGuard.Do(listenerCloseFunc)
+
 	// Initialize the listeners
-	c.reloadFuncsLock.Lock()
-	lns := make([]net.Listener, 0, len(config.Listeners))
+	c.listenersLock.Lock()
 	for i, lnConfig := range config.Listeners {
-		ln, props, reloadFunc, err := server.NewListener(lnConfig.Type, lnConfig.Config, c.logGate, c.UI)
+		sl, props, err := c.newServerListener(lnConfig)
 		if err != nil {
+			c.listenersLock.Unlock()
 			c.UI.Error(fmt.Sprintf("Error initializing listener of type %s: %s", lnConfig.Type, err))
 			return 1
 		}
+		ln := sl.ln
 
-		lns = append(lns, ln)
-
-		if reloadFunc != nil {
-			relSlice := (*c.reloadFuncs)["listener|"+lnConfig.Type]
-			relSlice = append(relSlice, reloadFunc)
-			(*c.reloadFuncs)["listener|"+lnConfig.Type] = relSlice
-		}
+		c.listeners = append(c.listeners, sl)
 
 		if !disableClustering && lnConfig.Type == "tcp" {
 			var addrRaw interface{}
@@ -662,6 +733,7 @@ CLUSTER_SYNTHESIS_COMPLETE:
 				addr = addrRaw.(string)
 				tcpAddr, err := net.ResolveTCPAddr("tcp", addr)
 				if err != nil {
+					c.listenersLock.Unlock()
 					c.UI.Error(fmt.Sprintf("Error resolving cluster_address: %s", err))
 					return 1
 				}
@@ -669,6 +741,7 @@ CLUSTER_SYNTHESIS_COMPLETE:
 			} else {
 				tcpAddr, ok := ln.Addr().(*net.TCPAddr)
 				if !ok {
+					c.listenersLock.Unlock()
 					c.UI.Error("Failed to parse tcp listener")
 					return 1
 				}
@@ -695,22 +768,13 @@ CLUSTER_SYNTHESIS_COMPLETE:
 			"%s (%s)", lnConfig.Type, strings.Join(propsList, ", "))
 
 	}
-	c.reloadFuncsLock.Unlock()
+	c.listenersLock.Unlock()
 	if !disableClustering {
 		if c.logger.IsTrace() {
 			c.logger.Trace("cluster listener addresses synthesized", "cluster_addresses", clusterAddrs)
 		}
 	}
 
-	// Make sure we close all listeners from this point on
-	listenerCloseFunc := func() {
-		for _, ln := range lns {
-			ln.Close()
-		}
-	}
-
-	defer c.cleanupGuard.Do(listenerCloseFunc)
-
 	infoKeys = append(infoKeys, "version")
 	verInfo := version.GetVersion()
 	info["version"] = verInfo.FullVersionNumber(false)
@@ -844,12 +908,12 @@ CLUSTER_SYNTHESIS_COMPLETE:
 	}
 
 	// Initialize the HTTP servers
-	for _, ln := range lns {
-		server := &http.Server{
-			Handler: handler,
-		}
-		go server.Serve(ln)
+	c.listenersLock.Lock()
+	c.handler = handler
+	for _, sl := range c.listeners {
+		c.serve(sl)
 	}
+	c.listenersLock.Unlock()
 
 	if newCoreError != nil {
 		c.UI.Warn(wrapAtLength(
@@ -1287,7 +1351,7 @@ func (c *ServerCommand) detectRedirect(detect physical.RedirectDetect,
 }
 
 // setupTelemetry is used to setup the telemetry sub-systems
-func (c *ServerCommand) setupTelemetry(config *server.Config) error {
+func (c *ServerCommand) setupTelemetry(config *server.Config) (*metricsutil.MetricsHelper, error) {
 	/* Setup telemetry
 	Aggregate on 10 second intervals for 1 minute. Expose the
 	metrics over stderr when there is a SIGUSR1 received.
@@ -1302,6 +1366,20 @@ func (c *ServerCommand) setupTelemetry(config *server.Config) error {
 		telConfig = config.Telemetry
 	}
 
+	// The Prometheus sink backs the Prometheus format of sys/metrics
+	c.inmemSink = inm
+	c.prometheusSink = metricsutil.NewPrometheusSink(telConfig.PrometheusRetentionTime)
+
+	if err := c.configureMetricsSinks(telConfig); err != nil {
+		return nil, err
+	}
+	return metricsutil.NewMetricsHelper(c.inmemSink, c.prometheusSink), nil
+}
+
+// configureMetricsSinks configures the global metrics with the sinks of the
+// telemetry configuration, along with the in-memory and Prometheus sinks of
+// the server. The sinks of the previous configuration are shut down.
+func (c *ServerCommand) configureMetricsSinks(telConfig *server.Telemetry) error {
 	metricsConf := metrics.DefaultConfig("vault")
 	metricsConf.EnableHostname = !telConfig.DisableHostname
 
@@ -1377,16 +1455,31 @@ func (c *ServerCommand) setupTelemetry(config *server.Config) error {
 	}
 
 	// Initialize the global sink
+	previous := c.metricsSinks
+	c.metricsSinks = fanout
+	c.telemetry = telConfig
 	if len(fanout) > 0 {
-		fanout = append(fanout, inm)
-		metrics.NewGlobal(metricsConf, fanout)
+		metrics.NewGlobal(metricsConf, append(fanout, c.inmemSink, c.prometheusSink))
 	} else {
 		metricsConf.EnableHostname = false
-		metrics.NewGlobal(metricsConf, inm)
+		metrics.NewGlobal(metricsConf, metrics.FanoutSink{c.inmemSink, c.prometheusSink})
+	}
+
+	for _, sink := range previous {
+		switch sink := sink.(type) {
+		case interface{ Shutdown() }:
+			sink.Shutdown()
+		case interface{ Flush() }:
+			sink.Flush()
+		}
 	}
 	return nil
 }
 
+// Reload runs the reload funcs, e.g. to reload the TLS certificates of the
+// listeners and reopen the files of the file audit devices. If configPath is
+// set, the configuration is loaded again and the listeners, log level and
+// telemetry of the server are updated with it.
 func (c *ServerCommand) Reload(lock *sync.RWMutex, reloadFuncs *map[string][]reload.ReloadFunc, configPath []string) error {
 	lock.RLock()
 	defer lock.RUnlock()
@@ -1415,6 +1508,41 @@ func (c *ServerCommand) Reload(lock *sync.RWMutex, reloadFuncs *map[string][]rel
 		}
 	}
 
+	var config *server.Config
+	if len(configPath) > 0 {
+		var err error
+		config, err = c.loadConfig(configPath)
+		if err != nil {
+			reloadErrors = multierror.Append(reloadErrors, err)
+		}
+	}
+
+	if config == nil {
+		// Only reload the TLS certificates of the current listeners
+		reloadErrors = multierror.Append(reloadErrors, c.reloadListeners(nil))
+	} else {
+		reloadErrors = multierror.Append(reloadErrors, c.reloadListeners(config.Listeners))
+
+		if c.flagLogLevel == "" {
+			level, err := parseLogLevel(config.LogLevel)
+			if err != nil {
+				reloadErrors = multierror.Append(reloadErrors, err)
+			} else {
+				c.logger.SetLevel(level)
+			}
+		}
+
+		telConfig := config.Telemetry
+		if telConfig == nil {
+			telConfig = &server.Telemetry{}
+		}
+		if c.inmemSink != nil && !reflect.DeepEqual(telConfig, c.telemetry) {
+			if err := c.configureMetricsSinks(telConfig); err != nil {
+				reloadErrors = multierror.Append(reloadErrors, fmt.Errorf("Error encountered reloading telemetry: %v", err))
+			}
+		}
+	}
+
 	// Send a message that we reloaded. This prevents "guessing" sleep times
 	// in tests.
 	select {
@@ -1425,6 +1553,190 @@ func (c *ServerCommand) Reload(lock *sync.RWMutex, reloadFuncs *map[string][]rel
 	return reloadErrors.ErrorOrNil()
 }
 
+// loadConfig loads and merges the configurations at the paths, on top of the
+// configuration of the dev mode if it is enabled. It returns nil if no
+// configuration was found.
+func (c *ServerCommand) loadConfig(paths []string) (*server.Config, error) {
+	var config *server.Config
+	if c.flagDev {
+		config = server.DevConfig(c.flagDevHA, c.flagDevTransactional)
+		if c.flagDevListenAddr != "" {
+			config.Listeners[0].Config["address"] = c.flagDevListenAddr
+		}
+	}
+	for _, path := range paths {
+		current, err := server.LoadConfig(path, c.logger)
+		if err != nil {
+			return nil, fmt.Errorf("Error loading configuration from %s: %s", path, err)
+		}
+
+		if config == nil {
+			config = current
+		} else {
+			config = config.Merge(current)
+		}
+	}
+
+	return config, nil
+}
+
+// serverListener is a listener of the server along with the configuration it
+// was created with
+type serverListener struct {
+	config       *server.Listener
+	ln           net.Listener
+	reloadFunc   reload.ReloadFunc
+	forwardedFor *server.ForwardedForConfig
+
+	// maxInFlightRequests is the maximum number of requests handled at once
+	// by the listener, zero for no limit
+	maxInFlightRequests int
+}
+
+// newServerListener creates the listener of the configuration. It returns
+// the properties of the listener for the output of its configuration.
+func (c *ServerCommand) newServerListener(lnConfig *server.Listener) (*serverListener, map[string]string, error) {
+	forwardedFor, err := server.ParseForwardedFor(lnConfig.Config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	maxInFlight, err := server.ParseMaxInFlightRequests(lnConfig.Config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ln, props, reloadFunc, err := server.NewListener(lnConfig.Type, lnConfig.Config, c.logGate, c.UI)
+	if err != nil {
+		return nil, nil, err
+	}
+	if forwardedFor != nil {
+		props["x-forwarded-for"] = "enabled"
+	}
+	if maxInFlight > 0 {
+		props["max in-flight requests"] = strconv.Itoa(maxInFlight)
+	}
+
+	return &serverListener{
+		config:              lnConfig,
+		ln:                  ln,
+		reloadFunc:          reloadFunc,
+		forwardedFor:        forwardedFor,
+		maxInFlightRequests: maxInFlight,
+	}, props, nil
+}
+
+// serve serves the handler of the server on the listener. The listener lock
+// must be held.
+func (c *ServerCommand) serve(sl *serverListener) {
+	handler := c.handler
+	if ff := sl.forwardedFor; ff != nil {
+		handler = vaulthttp.WrapForwardedForHandler(handler, ff.AuthorizedAddrs, ff.RejectNotPresent, ff.RejectNotAuthorized, ff.HopSkips)
+	}
+	if sl.maxInFlightRequests > 0 {
+		handler = vaulthttp.WrapMaxInFlightHandler(handler, sl.maxInFlightRequests)
+	}
+
+	server := &http.Server{
+		Handler: handler,
+	}
+	go server.Serve(sl.ln)
+}
+
+// reloadListeners reconciles the listeners of the server with the listener
+// blocks of the configuration. The listeners whose block is unchanged reload
+// their TLS certificates and client CAs, the listeners whose block changed
+// are created again, the listeners of new blocks are created and served, and
+// the listeners with no block are closed. If configs is nil, the listeners
+// are only reloaded.
+func (c *ServerCommand) reloadListeners(configs []*server.Listener) error {
+	c.listenersLock.Lock()
+	defer c.listenersLock.Unlock()
+
+	var reloadErrors *multierror.Error
+
+	if configs == nil {
+		for _, sl := range c.listeners {
+			if sl.reloadFunc == nil {
+				continue
+			}
+			if err := sl.reloadFunc(sl.config.Config); err != nil {
+				reloadErrors = multierror.Append(reloadErrors, fmt.Errorf("Error encountered reloading listener: %v", err))
+			}
+		}
+		return reloadErrors.ErrorOrNil()
+	}
+
+	// The listeners are identified by their type and address
+	listenerID := func(lnConfig *server.Listener) string {
+		return fmt.Sprintf("%s|%v", lnConfig.Type, lnConfig.Config["address"])
+	}
+
+	current := make(map[string]*serverListener, len(c.listeners))
+	for _, sl := range c.listeners {
+		current[listenerID(sl.config)] = sl
+	}
+
+	listeners := make([]*serverListener, 0, len(configs))
+	for _, lnConfig := range configs {
+		id := listenerID(lnConfig)
+		if sl, ok := current[id]; ok {
+			delete(current, id)
+			if reflect.DeepEqual(sl.config, lnConfig) {
+				if sl.reloadFunc != nil {
+					if err := sl.reloadFunc(lnConfig.Config); err != nil {
+						reloadErrors = multierror.Append(reloadErrors, fmt.Errorf("Error encountered reloading listener: %v", err))
+					}
+				}
+				listeners = append(listeners, sl)
+				continue
+			}
+
+			// The listener is closed first to free its address
+			sl.ln.Close()
+			c.logger.Info("closed listener to reconfigure it", "type", sl.config.Type, "address", sl.ln.Addr().String())
+		}
+
+		sl, _, err := c.newServerListener(lnConfig)
+		if err != nil {
+			reloadErrors = multierror.Append(reloadErrors, fmt.Errorf("Error initializing listener of type %s: %v", lnConfig.Type, err))
+			continue
+		}
+		c.serve(sl)
+		listeners = append(listeners, sl)
+		c.logger.Info("started listener", "type", lnConfig.Type, "address", sl.ln.Addr().String())
+	}
+
+	for _, sl := range current {
+		sl.ln.Close()
+		c.logger.Info("closed listener", "type", sl.config.Type, "address", sl.ln.Addr().String())
+	}
+
+	c.listeners = listeners
+	return reloadErrors.ErrorOrNil()
+}
+
+// parseLogLevel returns the level of the name of a log level, which defaults
+// to info
+func parseLogLevel(name string) (int, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "trace":
+		return log.LevelTrace, nil
+	case "debug":
+		return log.LevelDebug, nil
+	case "info", "":
+		return log.LevelInfo, nil
+	case "notice":
+		return log.LevelNotice, nil
+	case "warn", "warning":
+		return log.LevelWarn, nil
+	case "err", "error":
+		return log.LevelError, nil
+	default:
+		return 0, fmt.Errorf("Unknown log level: %s", name)
+	}
+}
+
 // storePidFile is used to write out our PID to a file if necessary
 func (c *ServerCommand) storePidFile(pidPath string) error {
 	// Quit fast if no pidfile
@@ -26,6 +26,12 @@ type Config struct {
 
 	Seal *Seal `hcl:"-"`
 
+	// DisabledSeal is the previous seal of a seal migration, configured
+	// with disabled set
+	DisabledSeal *Seal `hcl:"-"`
+
+	Entropy *Entropy `hcl:"-"`
+
 	CacheSize       int         `hcl:"cache_size"`
 	DisableCache    bool        `hcl:"-"`
 	DisableCacheRaw interface{} `hcl:"disable_cache"`
@@ -42,12 +48,16 @@ type Config struct {
 	DefaultLeaseTTL    time.Duration `hcl:"-"`
 	DefaultLeaseTTLRaw interface{}   `hcl:"default_lease_ttl"`
 
+	DefaultMaxRequestDuration    time.Duration `hcl:"-"`
+	DefaultMaxRequestDurationRaw interface{}   `hcl:"default_max_request_duration"`
+
 	ClusterName         string `hcl:"cluster_name"`
 	ClusterCipherSuites string `hcl:"cluster_cipher_suites"`
 
 	PluginDirectory string `hcl:"plugin_directory"`
 
 	PidFile              string      `hcl:"pid_file"`
+	LogLevel             string      `hcl:"log_level"`
 	EnableRawEndpoint    bool        `hcl:"-"`
 	EnableRawEndpointRaw interface{} `hcl:"raw_storage_endpoint"`
 
@@ -55,6 +65,12 @@ type Config struct {
 	ClusterAddr          string      `hcl:"cluster_addr"`
 	DisableClustering    bool        `hcl:"-"`
 	DisableClusteringRaw interface{} `hcl:"disable_clustering"`
+
+	PerformanceStandby    bool        `hcl:"-"`
+	PerformanceStandbyRaw interface{} `hcl:"performance_standby"`
+
+	FIPSMode    bool        `hcl:"-"`
+	FIPSModeRaw interface{} `hcl:"fips_mode"`
 }
 
 // DevConfig is a Config that is used for dev mode of Vault.
@@ -130,6 +146,21 @@ func (h *Seal) GoString() string {
 	return fmt.Sprintf("*%#v", *h)
 }
 
+// EntropyModeAugmentation is the mode of the entropy sources which are mixed
+// with the random data of the system for the generation of keys
+const EntropyModeAugmentation = "augmentation"
+
+// Entropy contains the configuration of the external source of entropy
+type Entropy struct {
+	Type   string
+	Mode   string
+	Config map[string]string
+}
+
+func (e *Entropy) GoString() string {
+	return fmt.Sprintf("*%#v", *e)
+}
+
 // Telemetry is the telemetry configuration for the server
 type Telemetry struct {
 	StatsiteAddr string `hcl:"statsite_address"`
@@ -216,6 +247,13 @@ type Telemetry struct {
 	// DogStatsdTags are the global tags that should be sent with each packet to dogstatsd
 	// It is a list of strings, where each string looks like "my_tag_name:my_tag_value"
 	DogStatsDTags []string `hcl:"dogstatsd_tags"`
+
+	// Prometheus:
+	// PrometheusRetentionTime is how long the metrics exposed in the
+	// Prometheus format of sys/metrics are kept after their last update.
+	// Default: 24h
+	PrometheusRetentionTime    time.Duration `hcl:"-"`
+	PrometheusRetentionTimeRaw interface{}   `hcl:"prometheus_retention_time"`
 }
 
 func (s *Telemetry) GoString() string {
@@ -251,6 +289,16 @@ func (c *Config) Merge(c2 *Config) *Config {
 		result.Seal = c2.Seal
 	}
 
+	result.DisabledSeal = c.DisabledSeal
+	if c2.DisabledSeal != nil {
+		result.DisabledSeal = c2.DisabledSeal
+	}
+
+	result.Entropy = c.Entropy
+	if c2.Entropy != nil {
+		result.Entropy = c2.Entropy
+	}
+
 	result.Telemetry = c.Telemetry
 	if c2.Telemetry != nil {
 		result.Telemetry = c2.Telemetry
@@ -261,6 +309,11 @@ func (c *Config) Merge(c2 *Config) *Config {
 		result.CacheSize = c2.CacheSize
 	}
 
+	result.DefaultMaxRequestDuration = c.DefaultMaxRequestDuration
+	if c2.DefaultMaxRequestDuration != 0 {
+		result.DefaultMaxRequestDuration = c2.DefaultMaxRequestDuration
+	}
+
 	// merging these booleans via an OR operation
 	result.DisableCache = c.DisableCache
 	if c2.DisableCache {
@@ -303,6 +356,16 @@ func (c *Config) Merge(c2 *Config) *Config {
 		result.EnableRawEndpoint = c2.EnableRawEndpoint
 	}
 
+	result.PerformanceStandby = c.PerformanceStandby
+	if c2.PerformanceStandby {
+		result.PerformanceStandby = c2.PerformanceStandby
+	}
+
+	result.FIPSMode = c.FIPSMode
+	if c2.FIPSMode {
+		result.FIPSMode = c2.FIPSMode
+	}
+
 	result.PluginDirectory = c.PluginDirectory
 	if c2.PluginDirectory != "" {
 		result.PluginDirectory = c2.PluginDirectory
@@ -313,6 +376,11 @@ func (c *Config) Merge(c2 *Config) *Config {
 		result.PidFile = c2.PidFile
 	}
 
+	result.LogLevel = c.LogLevel
+	if c2.LogLevel != "" {
+		result.LogLevel = c2.LogLevel
+	}
+
 	return result
 }
 
@@ -363,6 +431,11 @@ func ParseConfig(d string, logger log.Logger) (*Config, error) {
 			return nil, err
 		}
 	}
+	if result.DefaultMaxRequestDurationRaw != nil {
+		if result.DefaultMaxRequestDuration, err = parseutil.ParseDurationSecond(result.DefaultMaxRequestDurationRaw); err != nil {
+			return nil, err
+		}
+	}
 
 	if result.EnableUIRaw != nil {
 		if result.EnableUI, err = parseutil.ParseBool(result.EnableUIRaw); err != nil {
@@ -394,6 +467,18 @@ func ParseConfig(d string, logger log.Logger) (*Config, error) {
 		}
 	}
 
+	if result.PerformanceStandbyRaw != nil {
+		if result.PerformanceStandby, err = parseutil.ParseBool(result.PerformanceStandbyRaw); err != nil {
+			return nil, err
+		}
+	}
+
+	if result.FIPSModeRaw != nil {
+		if result.FIPSMode, err = parseutil.ParseBool(result.FIPSModeRaw); err != nil {
+			return nil, err
+		}
+	}
+
 	list, ok := obj.Node.(*ast.ObjectList)
 	if !ok {
 		return nil, fmt.Errorf("error parsing: file doesn't contain a root object")
@@ -406,6 +491,7 @@ func ParseConfig(d string, logger log.Logger) (*Config, error) {
 		"ha_backend",
 		"hsm",
 		"seal",
+		"entropy",
 		"listener",
 		"cache_size",
 		"disable_cache",
@@ -414,14 +500,18 @@ func ParseConfig(d string, logger log.Logger) (*Config, error) {
 		"telemetry",
 		"default_lease_ttl",
 		"max_lease_ttl",
+		"default_max_request_duration",
 		"cluster_name",
 		"cluster_cipher_suites",
 		"plugin_directory",
 		"pid_file",
+		"log_level",
 		"raw_storage_endpoint",
 		"api_addr",
 		"cluster_addr",
 		"disable_clustering",
+		"performance_standby",
+		"fips_mode",
 	}
 	if err := checkHCLKeys(list, valid); err != nil {
 		return nil, err
@@ -464,6 +554,12 @@ func ParseConfig(d string, logger log.Logger) (*Config, error) {
 		}
 	}
 
+	if o := list.Filter("entropy"); len(o.Items) > 0 {
+		if err := parseEntropy(&result, o); err != nil {
+			return nil, fmt.Errorf("error parsing 'entropy': %s", err)
+		}
+	}
+
 	if o := list.Filter("listener"); len(o.Items) > 0 {
 		if err := parseListeners(&result, o); err != nil {
 			return nil, fmt.Errorf("error parsing 'listener': %s", err)
@@ -693,60 +789,169 @@ func parseHAStorage(result *Config, list *ast.ObjectList, name string) error {
 }
 
 func parseSeal(result *Config, list *ast.ObjectList, blockName string) error {
+	// A second seal is only permitted to migrate from a disabled seal
+	if len(list.Items) > 2 {
+		return fmt.Errorf("only two %q blocks are permitted", blockName)
+	}
+
+	for _, item := range list.Items {
+		key := blockName
+		if len(item.Keys) > 0 {
+			key = item.Keys[0].Token.Value().(string)
+		}
+
+		var valid []string
+		// Valid parameter for the Seal types
+		switch key {
+		case "pkcs11":
+			valid = []string{
+				"lib",
+				"slot",
+				"pin",
+				"mechanism",
+				"hmac_mechanism",
+				"key_label",
+				"hmac_key_label",
+				"generate_key",
+				"regenerate_key",
+				"max_parallel",
+			}
+		case "awskms":
+			valid = []string{
+				"region",
+				"access_key",
+				"secret_key",
+				"session_token",
+				"kms_key_id",
+				"endpoint",
+				"max_parallel",
+			}
+		case "gcpckms":
+			valid = []string{
+				"credentials",
+				"project",
+				"region",
+				"key_ring",
+				"crypto_key",
+			}
+		case "azurekeyvault":
+			valid = []string{
+				"tenant_id",
+				"client_id",
+				"client_secret",
+				"environment",
+				"vault_name",
+				"key_name",
+			}
+		case "transit":
+			valid = []string{
+				"address",
+				"token",
+				"namespace",
+				"mount_path",
+				"key_name",
+				"disable_renewal",
+				"tls_ca_cert",
+				"tls_client_cert",
+				"tls_client_key",
+				"tls_server_name",
+				"tls_skip_verify",
+			}
+		default:
+			return fmt.Errorf("invalid seal type %q", key)
+		}
+		valid = append(valid, "disabled")
+
+		if err := checkHCLKeys(item.Val, valid); err != nil {
+			return multierror.Prefix(err, fmt.Sprintf("%s.%s:", blockName, key))
+		}
+
+		var m map[string]string
+		if err := hcl.DecodeObject(&m, item.Val); err != nil {
+			return multierror.Prefix(err, fmt.Sprintf("%s.%s:", blockName, key))
+		}
+
+		seal := &Seal{
+			Type:   strings.ToLower(key),
+			Config: m,
+		}
+
+		var disabled bool
+		if v, ok := m["disabled"]; ok {
+			var err error
+			disabled, err = strconv.ParseBool(v)
+			if err != nil {
+				return multierror.Prefix(fmt.Errorf("invalid value for 'disabled': %v", err), fmt.Sprintf("%s.%s:", blockName, key))
+			}
+			delete(m, "disabled")
+		}
+
+		switch {
+		case disabled && result.DisabledSeal != nil:
+			return fmt.Errorf("only one disabled %q block is permitted", blockName)
+		case disabled:
+			result.DisabledSeal = seal
+		case result.Seal != nil:
+			return fmt.Errorf("only one %q block is permitted unless the other is disabled", blockName)
+		default:
+			result.Seal = seal
+		}
+	}
+
+	return nil
+}
+
+func parseEntropy(result *Config, list *ast.ObjectList) error {
 	if len(list.Items) > 1 {
-		return fmt.Errorf("only one %q block is permitted", blockName)
+		return fmt.Errorf("only one 'entropy' block is permitted")
 	}
 
-	// Get our item
+	// Get our one item
 	item := list.Items[0]
 
-	key := blockName
+	key := "entropy"
 	if len(item.Keys) > 0 {
 		key = item.Keys[0].Token.Value().(string)
 	}
 
-	var valid []string
-	// Valid parameter for the Seal types
+	// Valid parameter for the entropy source types
+	valid := []string{"mode"}
 	switch key {
-	case "pkcs11":
-		valid = []string{
-			"lib",
-			"slot",
-			"pin",
-			"mechanism",
-			"hmac_mechanism",
-			"key_label",
-			"hmac_key_label",
-			"generate_key",
-			"regenerate_key",
-			"max_parallel",
-		}
-	case "awskms":
-		valid = []string{
-			"region",
-			"access_key",
-			"secret_key",
-			"kms_key_id",
-			"max_parallel",
-		}
+	case "seal":
+	case "device":
+		valid = append(valid, "path")
 	default:
-		return fmt.Errorf("invalid seal type %q", key)
+		return fmt.Errorf("invalid entropy source type %q", key)
 	}
 
 	if err := checkHCLKeys(item.Val, valid); err != nil {
-		return multierror.Prefix(err, fmt.Sprintf("%s.%s:", blockName, key))
+		return multierror.Prefix(err, fmt.Sprintf("entropy.%s:", key))
 	}
 
 	var m map[string]string
 	if err := hcl.DecodeObject(&m, item.Val); err != nil {
-		return multierror.Prefix(err, fmt.Sprintf("%s.%s:", blockName, key))
+		return multierror.Prefix(err, fmt.Sprintf("entropy.%s:", key))
+	}
+
+	mode := m["mode"]
+	delete(m, "mode")
+	switch mode {
+	case EntropyModeAugmentation:
+	case "":
+		return fmt.Errorf("entropy.%s: 'mode' is required", key)
+	default:
+		return fmt.Errorf("entropy.%s: invalid mode %q", key, mode)
+	}
+
+	if key == "device" && m["path"] == "" {
+		return fmt.Errorf("entropy.%s: 'path' is required", key)
 	}
 
-	result.Seal = &Seal{
+	result.Entropy = &Entropy{
 		Type:   strings.ToLower(key),
+		Mode:   mode,
 		Config: m,
 	}
-
 	return nil
 }
 
@@ -766,6 +971,9 @@ func parseListeners(result *Config, list *ast.ObjectList) error {
 			"node_id",
 			"proxy_protocol_behavior",
 			"proxy_protocol_authorized_addrs",
+			"socket_mode",
+			"socket_user",
+			"socket_group",
 			"tls_disable",
 			"tls_cert_file",
 			"tls_key_file",
@@ -775,7 +983,13 @@ func parseListeners(result *Config, list *ast.ObjectList) error {
 			"tls_require_and_verify_client_cert",
 			"tls_disable_client_certs",
 			"tls_client_ca_file",
+			"tls_client_crl_file",
 			"token",
+			"max_in_flight_requests",
+			"x_forwarded_for_authorized_addrs",
+			"x_forwarded_for_hop_skips",
+			"x_forwarded_for_reject_not_authorized",
+			"x_forwarded_for_reject_not_present",
 		}
 		if err := checkHCLKeys(item.Val, valid); err != nil {
 			return multierror.Prefix(err, fmt.Sprintf("listeners.%s:", key))
@@ -824,6 +1038,7 @@ func parseTelemetry(result *Config, list *ast.ObjectList) error {
 		"disable_hostname",
 		"dogstatsd_addr",
 		"dogstatsd_tags",
+		"prometheus_retention_time",
 		"statsd_address",
 		"statsite_address",
 	}
@@ -843,6 +1058,13 @@ func parseTelemetry(result *Config, list *ast.ObjectList) error {
 	if err := hcl.DecodeObject(&result.Telemetry, item.Val); err != nil {
 		return multierror.Prefix(err, "telemetry:")
 	}
+
+	if result.Telemetry.PrometheusRetentionTimeRaw != nil {
+		var err error
+		if result.Telemetry.PrometheusRetentionTime, err = parseutil.ParseDurationSecond(result.Telemetry.PrometheusRetentionTimeRaw); err != nil {
+			return multierror.Prefix(err, "telemetry.prometheus_retention_time:")
+		}
+	}
 	return nil
 }
 
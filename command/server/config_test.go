@@ -71,7 +71,8 @@ func TestLoadConfigFile(t *testing.T) {
 		DefaultLeaseTTLRaw: "10h",
 		ClusterName:        "testcluster",
 
-		PidFile: "./pidfile",
+		PidFile:  "./pidfile",
+		LogLevel: "debug",
 	}
 	if !reflect.DeepEqual(config, expected) {
 		t.Fatalf("expected \n\n%#v\n\n to be \n\n%#v\n\n", config, expected)
@@ -139,6 +140,9 @@ func TestLoadConfigFile_topLevel(t *testing.T) {
 		DefaultLeaseTTLRaw: "10h",
 		ClusterName:        "testcluster",
 
+		DefaultMaxRequestDuration:    90 * time.Second,
+		DefaultMaxRequestDurationRaw: "90s",
+
 		PidFile: "./pidfile",
 
 		APIAddr:     "top_level_api_addr",
@@ -330,6 +334,18 @@ func TestLoadConfigDir(t *testing.T) {
 	}
 }
 
+func TestConfig_Merge_defaultMaxRequestDuration(t *testing.T) {
+	c := &Config{DefaultMaxRequestDuration: 90 * time.Second}
+
+	// A later configuration overrides the duration, unless it leaves it unset
+	if merged := c.Merge(&Config{DefaultMaxRequestDuration: 30 * time.Second}); merged.DefaultMaxRequestDuration != 30*time.Second {
+		t.Fatalf("bad: %s", merged.DefaultMaxRequestDuration)
+	}
+	if merged := c.Merge(&Config{}); merged.DefaultMaxRequestDuration != 90*time.Second {
+		t.Fatalf("bad: %s", merged.DefaultMaxRequestDuration)
+	}
+}
+
 func TestParseListeners(t *testing.T) {
 	obj, _ := hcl.Parse(strings.TrimSpace(`
 listener "tcp" {
@@ -342,6 +358,14 @@ listener "tcp" {
 	tls_min_version = "tls12"
 	tls_require_and_verify_client_cert = true
 	tls_disable_client_certs = true
+}
+
+listener "unix" {
+	address = "/var/run/vault.sock"
+	socket_mode = "0660"
+	socket_user = "vault"
+	socket_group = "vault"
+	tls_disable = true
 }`))
 
 	var config Config
@@ -373,6 +397,16 @@ listener "tcp" {
 					"tls_disable_client_certs":           true,
 				},
 			},
+			&Listener{
+				Type: "unix",
+				Config: map[string]interface{}{
+					"address":      "/var/run/vault.sock",
+					"socket_mode":  "0660",
+					"socket_user":  "vault",
+					"socket_group": "vault",
+					"tls_disable":  true,
+				},
+			},
 		},
 	}
 
@@ -451,3 +485,162 @@ telemetry {
 		t.Errorf("bad error: %q", err)
 	}
 }
+
+func TestParseConfig_seal(t *testing.T) {
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+
+	config, err := ParseConfig(strings.TrimSpace(`
+seal "gcpckms" {
+	project    = "vault-project"
+	key_ring   = "vault-keyring"
+	crypto_key = "vault-key"
+}
+`), logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &Seal{
+		Type: "gcpckms",
+		Config: map[string]string{
+			"project":    "vault-project",
+			"key_ring":   "vault-keyring",
+			"crypto_key": "vault-key",
+		},
+	}
+	if !reflect.DeepEqual(config.Seal, expected) {
+		t.Fatalf("expected \n\n%#v\n\n to be \n\n%#v\n\n", config.Seal, expected)
+	}
+
+	_, err = ParseConfig(strings.TrimSpace(`
+seal "azurekeyvault" {
+	vault_name = "vault"
+	bad        = "one"
+}
+`), logger)
+	if err == nil || !strings.Contains(err.Error(), "seal.azurekeyvault: invalid key 'bad' on line 3") {
+		t.Errorf("bad error: %q", err)
+	}
+}
+
+func TestParseConfig_sealDisabled(t *testing.T) {
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+
+	config, err := ParseConfig(strings.TrimSpace(`
+seal "awskms" {
+	kms_key_id = "alias/vault"
+	disabled   = "true"
+}
+
+seal "transit" {
+	address  = "https://vault.example.com:8200"
+	key_name = "autounseal"
+}
+`), logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &Seal{
+		Type: "transit",
+		Config: map[string]string{
+			"address":  "https://vault.example.com:8200",
+			"key_name": "autounseal",
+		},
+	}
+	if !reflect.DeepEqual(config.Seal, expected) {
+		t.Fatalf("expected \n\n%#v\n\n to be \n\n%#v\n\n", config.Seal, expected)
+	}
+
+	expected = &Seal{
+		Type: "awskms",
+		Config: map[string]string{
+			"kms_key_id": "alias/vault",
+		},
+	}
+	if !reflect.DeepEqual(config.DisabledSeal, expected) {
+		t.Fatalf("expected \n\n%#v\n\n to be \n\n%#v\n\n", config.DisabledSeal, expected)
+	}
+
+	_, err = ParseConfig(strings.TrimSpace(`
+seal "awskms" {
+	kms_key_id = "alias/vault"
+}
+
+seal "transit" {
+	key_name = "autounseal"
+}
+`), logger)
+	if err == nil || !strings.Contains(err.Error(), "unless the other is disabled") {
+		t.Errorf("bad error: %q", err)
+	}
+}
+
+func TestParseConfig_entropy(t *testing.T) {
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+
+	config, err := ParseConfig(strings.TrimSpace(`
+entropy "device" {
+	mode = "augmentation"
+	path = "/dev/hwrng"
+}
+`), logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &Entropy{
+		Type: "device",
+		Mode: EntropyModeAugmentation,
+		Config: map[string]string{
+			"path": "/dev/hwrng",
+		},
+	}
+	if !reflect.DeepEqual(config.Entropy, expected) {
+		t.Fatalf("expected \n\n%#v\n\n to be \n\n%#v\n\n", config.Entropy, expected)
+	}
+
+	for _, tc := range []struct {
+		config string
+		err    string
+	}{
+		{`entropy "seal" {}`, "'mode' is required"},
+		{`entropy "seal" { mode = "replacement" }`, "invalid mode"},
+		{`entropy "device" { mode = "augmentation" }`, "'path' is required"},
+		{`entropy "seal" {
+	mode = "augmentation"
+	path = "/dev/hwrng"
+}`, "entropy.seal: invalid key 'path' on line 3"},
+		{`entropy "tpm" { mode = "augmentation" }`, "invalid entropy source type"},
+	} {
+		_, err := ParseConfig(tc.config, logger)
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%s: bad error: %q", tc.config, err)
+		}
+	}
+}
+
+func TestParseConfig_prometheusRetentionTime(t *testing.T) {
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+
+	config, err := ParseConfig(strings.TrimSpace(`
+telemetry {
+	prometheus_retention_time = "30m"
+}
+`), logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Telemetry.PrometheusRetentionTime != 30*time.Minute {
+		t.Fatalf("bad: %s", config.Telemetry.PrometheusRetentionTime)
+	}
+
+	_, err = ParseConfig(strings.TrimSpace(`
+telemetry {
+	prometheus_retention_time = "forever"
+}
+`), logger)
+	if err == nil || !strings.Contains(err.Error(), "prometheus_retention_time") {
+		t.Fatalf("expected error, got %v", err)
+	}
+}
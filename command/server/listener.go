@@ -7,11 +7,14 @@ import (
 	_ "crypto/sha512"
 	"crypto/tls"
 	"crypto/x509"
+	"crypto/x509/pkix"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net"
+	"sync"
 
+	sockaddr "github.com/hashicorp/go-sockaddr"
 	"github.com/hashicorp/vault/helper/parseutil"
 	"github.com/hashicorp/vault/helper/proxyutil"
 	"github.com/hashicorp/vault/helper/reload"
@@ -24,7 +27,8 @@ type ListenerFactory func(map[string]interface{}, io.Writer, cli.Ui) (net.Listen
 
 // BuiltinListeners is the list of built-in listener types.
 var BuiltinListeners = map[string]ListenerFactory{
-	"tcp": tcpListenerFactory,
+	"tcp":  tcpListenerFactory,
+	"unix": unixListenerFactory,
 }
 
 // NewListener creates a new listener of the given type with the given
@@ -69,6 +73,80 @@ func listenerWrapProxy(ln net.Listener, config map[string]interface{}) (net.List
 	return newLn, nil
 }
 
+// ForwardedForConfig is the configuration of the client addresses taken from
+// the X-Forwarded-For header of the requests, see
+// vaulthttp.WrapForwardedForHandler
+type ForwardedForConfig struct {
+	AuthorizedAddrs     []*sockaddr.SockAddrMarshaler
+	HopSkips            int
+	RejectNotAuthorized bool
+	RejectNotPresent    bool
+}
+
+// ParseForwardedFor parses the X-Forwarded-For configuration of the listener.
+// It returns nil if x_forwarded_for_authorized_addrs is not set.
+func ParseForwardedFor(config map[string]interface{}) (*ForwardedForConfig, error) {
+	authorizedAddrsRaw, ok := config["x_forwarded_for_authorized_addrs"]
+	if !ok {
+		return nil, nil
+	}
+
+	authorizedAddrs, err := proxyutil.ParseAuthorizedAddrs(authorizedAddrsRaw)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing x_forwarded_for_authorized_addrs: %v", err)
+	}
+
+	forwardedFor := &ForwardedForConfig{
+		AuthorizedAddrs:     authorizedAddrs,
+		RejectNotAuthorized: true,
+		RejectNotPresent:    true,
+	}
+
+	if v, ok := config["x_forwarded_for_hop_skips"]; ok {
+		hopSkips, err := parseutil.ParseInt(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for 'x_forwarded_for_hop_skips': %v", err)
+		}
+		if hopSkips < 0 {
+			return nil, fmt.Errorf("invalid value for 'x_forwarded_for_hop_skips': %d, must not be negative", hopSkips)
+		}
+		forwardedFor.HopSkips = int(hopSkips)
+	}
+
+	if v, ok := config["x_forwarded_for_reject_not_authorized"]; ok {
+		if forwardedFor.RejectNotAuthorized, err = parseutil.ParseBool(v); err != nil {
+			return nil, fmt.Errorf("invalid value for 'x_forwarded_for_reject_not_authorized': %v", err)
+		}
+	}
+
+	if v, ok := config["x_forwarded_for_reject_not_present"]; ok {
+		if forwardedFor.RejectNotPresent, err = parseutil.ParseBool(v); err != nil {
+			return nil, fmt.Errorf("invalid value for 'x_forwarded_for_reject_not_present': %v", err)
+		}
+	}
+
+	return forwardedFor, nil
+}
+
+// ParseMaxInFlightRequests parses the maximum number of requests the
+// listener handles at once. It returns 0, for no limit, if
+// max_in_flight_requests is not set.
+func ParseMaxInFlightRequests(config map[string]interface{}) (int, error) {
+	v, ok := config["max_in_flight_requests"]
+	if !ok {
+		return 0, nil
+	}
+
+	maxInFlight, err := parseutil.ParseInt(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value for 'max_in_flight_requests': %v", err)
+	}
+	if maxInFlight < 0 {
+		return 0, fmt.Errorf("invalid value for 'max_in_flight_requests': %d, must not be negative", maxInFlight)
+	}
+	return int(maxInFlight), nil
+}
+
 func listenerWrapTLS(
 	ln net.Listener,
 	props map[string]string,
@@ -146,6 +224,8 @@ PASSPHRASECORRECT:
 		}
 		tlsConf.PreferServerCipherSuites = preferServer
 	}
+	reloadFuncs := []reload.ReloadFunc{cg.Reload}
+	var cag *clientCAGetter
 	var requireVerifyCerts bool
 	var err error
 	if v, ok := config["tls_require_and_verify_client_cert"]; ok {
@@ -157,16 +237,11 @@ PASSPHRASECORRECT:
 			tlsConf.ClientAuth = tls.RequireAndVerifyClientCert
 		}
 		if tlsClientCaFile, ok := config["tls_client_ca_file"]; ok {
-			caPool := x509.NewCertPool()
-			data, err := ioutil.ReadFile(tlsClientCaFile.(string))
-			if err != nil {
-				return nil, nil, nil, fmt.Errorf("failed to read tls_client_ca_file: %v", err)
-			}
-
-			if !caPool.AppendCertsFromPEM(data) {
-				return nil, nil, nil, fmt.Errorf("failed to parse CA certificate in tls_client_ca_file")
+			cag = &clientCAGetter{caFile: tlsClientCaFile.(string)}
+			if err := cag.Reload(config); err != nil {
+				return nil, nil, nil, err
 			}
-			tlsConf.ClientCAs = caPool
+			reloadFuncs = append(reloadFuncs, cag.Reload)
 		}
 	}
 	if v, ok := config["tls_disable_client_certs"]; ok {
@@ -179,8 +254,125 @@ PASSPHRASECORRECT:
 		}
 		tlsConf.ClientAuth = tls.NoClientCert
 	}
+	if v, ok := config["tls_client_crl_file"]; ok {
+		if !requireVerifyCerts {
+			return nil, nil, nil, fmt.Errorf("'tls_client_crl_file' requires 'tls_require_and_verify_client_cert'")
+		}
+		crlg := &clientCRLGetter{crlFile: v.(string)}
+		if err := crlg.Reload(config); err != nil {
+			return nil, nil, nil, err
+		}
+		tlsConf.VerifyPeerCertificate = crlg.VerifyPeerCertificate
+		reloadFuncs = append(reloadFuncs, crlg.Reload)
+	}
+
+	if cag != nil {
+		// The client CAs are set on the configuration of each connection,
+		// so that they can be reloaded
+		baseConf := tlsConf.Clone()
+		tlsConf.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			connConf := baseConf.Clone()
+			connConf.ClientCAs = cag.ClientCAs()
+			return connConf, nil
+		}
+	}
 
 	ln = tls.NewListener(ln, tlsConf)
 	props["tls"] = "enabled"
-	return ln, props, cg.Reload, nil
+	return ln, props, func(config map[string]interface{}) error {
+		for _, reloadFunc := range reloadFuncs {
+			if err := reloadFunc(config); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, nil
+}
+
+// clientCAGetter holds the CA certificates used to verify the client
+// certificates, which are read again from the CA file on reload.
+type clientCAGetter struct {
+	sync.RWMutex
+
+	caFile string
+	caPool *x509.CertPool
+}
+
+func (cag *clientCAGetter) Reload(_ map[string]interface{}) error {
+	data, err := ioutil.ReadFile(cag.caFile)
+	if err != nil {
+		return fmt.Errorf("failed to read tls_client_ca_file: %v", err)
+	}
+
+	caPool := x509.NewCertPool()
+	if !caPool.AppendCertsFromPEM(data) {
+		return fmt.Errorf("failed to parse CA certificate in tls_client_ca_file")
+	}
+
+	cag.Lock()
+	defer cag.Unlock()
+
+	cag.caPool = caPool
+
+	return nil
+}
+
+func (cag *clientCAGetter) ClientCAs() *x509.CertPool {
+	cag.RLock()
+	defer cag.RUnlock()
+
+	return cag.caPool
+}
+
+// clientCRLGetter holds the CRL the client certificates are checked against,
+// which is read again from the CRL file on reload.
+type clientCRLGetter struct {
+	sync.RWMutex
+
+	crlFile string
+	crl     *pkix.CertificateList
+}
+
+func (crlg *clientCRLGetter) Reload(_ map[string]interface{}) error {
+	data, err := ioutil.ReadFile(crlg.crlFile)
+	if err != nil {
+		return fmt.Errorf("failed to read tls_client_crl_file: %v", err)
+	}
+
+	crl, err := x509.ParseCRL(data)
+	if err != nil {
+		return fmt.Errorf("failed to parse CRL in tls_client_crl_file: %v", err)
+	}
+
+	crlg.Lock()
+	defer crlg.Unlock()
+
+	crlg.crl = crl
+
+	return nil
+}
+
+// VerifyPeerCertificate rejects the client certificates revoked by the CRL.
+// The CRL applies to the certificates of the verified chains issued by the
+// issuer of the CRL.
+func (crlg *clientCRLGetter) VerifyPeerCertificate(_ [][]byte, verifiedChains [][]*x509.Certificate) error {
+	crlg.RLock()
+	crl := crlg.crl
+	crlg.RUnlock()
+
+	for _, chain := range verifiedChains {
+		for i := 0; i < len(chain)-1; i++ {
+			cert, issuer := chain[i], chain[i+1]
+			if issuer.CheckCRLSignature(crl) != nil {
+				continue
+			}
+			for _, revoked := range crl.TBSCertList.RevokedCertificates {
+				if revoked.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+					return fmt.Errorf("certificate %q is revoked", cert.Subject.CommonName)
+				}
+			}
+		}
+	}
+
+	return nil
 }
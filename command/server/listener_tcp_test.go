@@ -1,13 +1,20 @@
 package server
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	crand "crypto/rand"
 	"crypto/tls"
 	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
 	"fmt"
 	"io/ioutil"
+	"math/big"
 	"math/rand"
 	"net"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -113,3 +120,234 @@ func TestTCPListener_tls(t *testing.T) {
 
 	testListenerImpl(t, ln, connFn(false), "foo.example.com")
 }
+
+func TestTCPListener_reloadClientCA(t *testing.T) {
+	wd, _ := os.Getwd()
+	wd += "/test-fixtures/reload/"
+
+	td, err := ioutil.TempDir("", "vault-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(td)
+
+	caBytes, _ := ioutil.ReadFile(wd + "reload_ca.pem")
+	certPool := x509.NewCertPool()
+	if !certPool.AppendCertsFromPEM(caBytes) {
+		t.Fatal("not ok when appending CA cert")
+	}
+	caFile := td + "/ca.pem"
+	if err := ioutil.WriteFile(caFile, caBytes, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ln, _, reloadFunc, err := tcpListenerFactory(map[string]interface{}{
+		"address":                            "127.0.0.1:0",
+		"tls_cert_file":                      wd + "reload_foo.pem",
+		"tls_key_file":                       wd + "reload_foo.key",
+		"tls_require_and_verify_client_cert": "true",
+		"tls_client_ca_file":                 caFile,
+	}, nil, cli.NewMockUi())
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer ln.Close()
+
+	clientCert, err := tls.LoadX509KeyPair(wd+"reload_foo.pem", wd+"reload_foo.key")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// handshake returns the error of the server verifying the client
+	// certificate
+	handshake := func() error {
+		errCh := make(chan error, 1)
+		go func() {
+			conn, err := ln.Accept()
+			if err != nil {
+				errCh <- err
+				return
+			}
+			defer conn.Close()
+			errCh <- conn.(*tls.Conn).Handshake()
+		}()
+
+		conn, err := tls.Dial("tcp", ln.Addr().String(), &tls.Config{
+			RootCAs:      certPool,
+			Certificates: []tls.Certificate{clientCert},
+		})
+		if err == nil {
+			conn.Close()
+		}
+		return <-errCh
+	}
+
+	if err := handshake(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The client certificate is not signed by the new client CA
+	leafBytes, _ := ioutil.ReadFile(wd + "reload_bar.pem")
+	if err := ioutil.WriteFile(caFile, leafBytes, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := reloadFunc(nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := handshake(); err == nil {
+		t.Fatal("expected client certificate to be rejected")
+	}
+
+	if err := ioutil.WriteFile(caFile, caBytes, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := reloadFunc(nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := handshake(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestTCPListener_clientCRL(t *testing.T) {
+	td, err := ioutil.TempDir("", "vault-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(td)
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), crand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ca"},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(crand.Reader, caTemplate, caTemplate, caKey.Public(), caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caPool := x509.NewCertPool()
+	caPool.AddCert(ca)
+
+	// issue returns a certificate and its key signed by the CA, written to
+	// files if name is set
+	issue := func(serial int64, name string, extKeyUsage x509.ExtKeyUsage) tls.Certificate {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), crand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		der, err := x509.CreateCertificate(crand.Reader, &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: fmt.Sprintf("cert-%d", serial)},
+			NotBefore:    time.Now().Add(-time.Minute),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{extKeyUsage},
+			IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		}, ca, key.Public(), caKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if name != "" {
+			keyDER, err := x509.MarshalECPrivateKey(key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ioutil.WriteFile(td+"/"+name+".pem", pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+			ioutil.WriteFile(td+"/"+name+".key", pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+		}
+		return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+	}
+	issue(2, "server", x509.ExtKeyUsageServerAuth)
+	validCert := issue(3, "", x509.ExtKeyUsageClientAuth)
+	revokedCert := issue(4, "", x509.ExtKeyUsageClientAuth)
+
+	writeCRL := func(revoked ...int64) {
+		var revokedCerts []pkix.RevokedCertificate
+		for _, serial := range revoked {
+			revokedCerts = append(revokedCerts, pkix.RevokedCertificate{
+				SerialNumber:   big.NewInt(serial),
+				RevocationTime: time.Now(),
+			})
+		}
+		crl, err := ca.CreateCRL(crand.Reader, caKey, revokedCerts, time.Now(), time.Now().Add(time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(td+"/crl.pem", pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: crl}), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeCRL(4)
+	ioutil.WriteFile(td+"/ca.pem", pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}), 0600)
+
+	config := map[string]interface{}{
+		"address":                            "127.0.0.1:0",
+		"tls_cert_file":                      td + "/server.pem",
+		"tls_key_file":                       td + "/server.key",
+		"tls_require_and_verify_client_cert": "true",
+		"tls_client_ca_file":                 td + "/ca.pem",
+		"tls_client_crl_file":                td + "/crl.pem",
+	}
+	ln, _, reloadFunc, err := tcpListenerFactory(config, nil, cli.NewMockUi())
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer ln.Close()
+
+	// handshake returns the error of the server verifying the client
+	// certificate
+	handshake := func(clientCert tls.Certificate) error {
+		errCh := make(chan error, 1)
+		go func() {
+			conn, err := ln.Accept()
+			if err != nil {
+				errCh <- err
+				return
+			}
+			defer conn.Close()
+			errCh <- conn.(*tls.Conn).Handshake()
+		}()
+
+		conn, err := tls.Dial("tcp", ln.Addr().String(), &tls.Config{
+			RootCAs:      caPool,
+			Certificates: []tls.Certificate{clientCert},
+		})
+		if err == nil {
+			conn.Close()
+		}
+		return <-errCh
+	}
+
+	if err := handshake(validCert); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := handshake(revokedCert); err == nil || !strings.Contains(err.Error(), "revoked") {
+		t.Fatalf("expected revoked certificate to be rejected, got: %v", err)
+	}
+
+	// The CRL is read again on reload
+	writeCRL()
+	if err := reloadFunc(nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := handshake(revokedCert); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	delete(config, "tls_require_and_verify_client_cert")
+	if _, _, _, err := tcpListenerFactory(config, nil, cli.NewMockUi()); err == nil {
+		t.Fatal("expected error without client certificate verification")
+	}
+}
@@ -62,3 +62,67 @@ func testListenerImpl(t *testing.T, ln net.Listener, connFn testListenerConnFn,
 		t.Fatalf("bad: %v", buf.String())
 	}
 }
+
+func TestParseForwardedFor(t *testing.T) {
+	forwardedFor, err := ParseForwardedFor(map[string]interface{}{
+		"address": "127.0.0.1:8200",
+	})
+	if err != nil || forwardedFor != nil {
+		t.Fatalf("expected no configuration, got %#v, %v", forwardedFor, err)
+	}
+
+	forwardedFor, err = ParseForwardedFor(map[string]interface{}{
+		"x_forwarded_for_authorized_addrs": "127.0.0.1/32,10.0.0.0/8",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(forwardedFor.AuthorizedAddrs) != 2 || forwardedFor.HopSkips != 0 || !forwardedFor.RejectNotAuthorized || !forwardedFor.RejectNotPresent {
+		t.Fatalf("bad configuration: %#v", forwardedFor)
+	}
+
+	forwardedFor, err = ParseForwardedFor(map[string]interface{}{
+		"x_forwarded_for_authorized_addrs":      []interface{}{"127.0.0.1/32"},
+		"x_forwarded_for_hop_skips":             "2",
+		"x_forwarded_for_reject_not_authorized": false,
+		"x_forwarded_for_reject_not_present":    "false",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(forwardedFor.AuthorizedAddrs) != 1 || forwardedFor.HopSkips != 2 || forwardedFor.RejectNotAuthorized || forwardedFor.RejectNotPresent {
+		t.Fatalf("bad configuration: %#v", forwardedFor)
+	}
+
+	for _, config := range []map[string]interface{}{
+		{"x_forwarded_for_authorized_addrs": "foo"},
+		{"x_forwarded_for_authorized_addrs": "127.0.0.1/32", "x_forwarded_for_hop_skips": -1},
+		{"x_forwarded_for_authorized_addrs": "127.0.0.1/32", "x_forwarded_for_reject_not_present": "foo"},
+	} {
+		if _, err := ParseForwardedFor(config); err == nil {
+			t.Fatalf("expected error for %#v", config)
+		}
+	}
+}
+
+func TestParseMaxInFlightRequests(t *testing.T) {
+	max, err := ParseMaxInFlightRequests(map[string]interface{}{
+		"address": "127.0.0.1:8200",
+	})
+	if err != nil || max != 0 {
+		t.Fatalf("expected no limit, got %d, %v", max, err)
+	}
+
+	max, err = ParseMaxInFlightRequests(map[string]interface{}{
+		"max_in_flight_requests": "100",
+	})
+	if err != nil || max != 100 {
+		t.Fatalf("expected a limit of 100, got %d, %v", max, err)
+	}
+
+	for _, v := range []interface{}{"foo", -1} {
+		if _, err := ParseMaxInFlightRequests(map[string]interface{}{"max_in_flight_requests": v}); err == nil {
+			t.Fatalf("expected error for %#v", v)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/user"
+	"strconv"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/helper/reload"
+	"github.com/mitchellh/cli"
+)
+
+func unixListenerFactory(config map[string]interface{}, _ io.Writer, ui cli.Ui) (net.Listener, map[string]string, reload.ReloadFunc, error) {
+	addrRaw, ok := config["address"]
+	if !ok {
+		return nil, nil, nil, fmt.Errorf("'address' must be set to the path of the socket")
+	}
+	addr, ok := addrRaw.(string)
+	if !ok || addr == "" {
+		return nil, nil, nil, fmt.Errorf("invalid value for 'address': must be the path of the socket")
+	}
+
+	// Remove the socket left by a previous run, but never a regular file
+	if fi, err := os.Lstat(addr); err == nil {
+		if fi.Mode()&os.ModeSocket == 0 {
+			return nil, nil, nil, fmt.Errorf("%s exists and is not a socket", addr)
+		}
+		if err := os.Remove(addr); err != nil {
+			return nil, nil, nil, errwrap.Wrapf("failed to remove existing socket: {{err}}", err)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, nil, nil, err
+	}
+
+	ln, err := net.Listen("unix", addr)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	props := map[string]string{"addr": addr}
+	if err := setSocketPermissions(addr, config, props); err != nil {
+		ln.Close()
+		return nil, nil, nil, err
+	}
+
+	tlsLn, props, reloadFunc, err := listenerWrapTLS(ln, props, config, ui)
+	if err != nil {
+		ln.Close()
+		return nil, nil, nil, err
+	}
+	return tlsLn, props, reloadFunc, nil
+}
+
+// setSocketPermissions sets the mode, owner and group of the socket from the
+// socket_mode, socket_user and socket_group of the listener
+func setSocketPermissions(path string, config map[string]interface{}, props map[string]string) error {
+	if v, ok := config["socket_mode"]; ok {
+		modeStr := fmt.Sprintf("%v", v)
+		mode, err := strconv.ParseUint(modeStr, 8, 32)
+		if err != nil {
+			return fmt.Errorf("invalid value for 'socket_mode': %v", err)
+		}
+		if err := os.Chmod(path, os.FileMode(mode)); err != nil {
+			return errwrap.Wrapf("failed to set the mode of the socket: {{err}}", err)
+		}
+		props["socket mode"] = modeStr
+	}
+
+	uid, gid := -1, -1
+	if v, ok := config["socket_user"]; ok {
+		name := fmt.Sprintf("%v", v)
+		var err error
+		if uid, err = strconv.Atoi(name); err != nil {
+			u, err := user.Lookup(name)
+			if err != nil {
+				return fmt.Errorf("invalid value for 'socket_user': %v", err)
+			}
+			if uid, err = strconv.Atoi(u.Uid); err != nil {
+				return fmt.Errorf("invalid value for 'socket_user': user ID %q is not a number", u.Uid)
+			}
+		}
+		props["socket user"] = name
+	}
+	if v, ok := config["socket_group"]; ok {
+		name := fmt.Sprintf("%v", v)
+		var err error
+		if gid, err = strconv.Atoi(name); err != nil {
+			g, err := user.LookupGroup(name)
+			if err != nil {
+				return fmt.Errorf("invalid value for 'socket_group': %v", err)
+			}
+			if gid, err = strconv.Atoi(g.Gid); err != nil {
+				return fmt.Errorf("invalid value for 'socket_group': group ID %q is not a number", g.Gid)
+			}
+		}
+		props["socket group"] = name
+	}
+	if uid != -1 || gid != -1 {
+		if err := os.Chown(path, uid, gid); err != nil {
+			return errwrap.Wrapf("failed to set the owner of the socket: {{err}}", err)
+		}
+	}
+
+	return nil
+}
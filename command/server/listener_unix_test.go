@@ -0,0 +1,87 @@
+package server
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+func TestUnixListener(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vault-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	socket := filepath.Join(dir, "vault.sock")
+	config := map[string]interface{}{
+		"address":     socket,
+		"socket_mode": "0600",
+		"tls_disable": "1",
+	}
+
+	ln, props, _, err := unixListenerFactory(config, nil, cli.NewMockUi())
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if props["socket mode"] != "0600" {
+		t.Fatalf("bad props: %#v", props)
+	}
+
+	fi, err := os.Stat(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0600 {
+		t.Fatalf("bad mode: %v", fi.Mode())
+	}
+
+	connFn := func(lnReal net.Listener) (net.Conn, error) {
+		return net.Dial("unix", socket)
+	}
+
+	testListenerImpl(t, ln, connFn, "")
+	ln.Close()
+
+	// A socket left by a previous run is replaced
+	stale, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	ln, _, _, err = unixListenerFactory(config, nil, cli.NewMockUi())
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	testListenerImpl(t, ln, connFn, "")
+	ln.Close()
+
+	// Other files are never removed
+	file := filepath.Join(dir, "file")
+	if err := ioutil.WriteFile(file, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err := unixListenerFactory(map[string]interface{}{
+		"address":     file,
+		"tls_disable": "1",
+	}, nil, cli.NewMockUi()); err == nil {
+		t.Fatal("expected error")
+	}
+
+	if _, _, _, err := unixListenerFactory(map[string]interface{}{
+		"address":     socket,
+		"socket_mode": "rwx",
+		"tls_disable": "1",
+	}, nil, cli.NewMockUi()); err == nil {
+		t.Fatal("expected error for bad socket_mode")
+	}
+	if _, err := os.Stat(socket); !os.IsNotExist(err) {
+		t.Fatalf("expected socket to be removed on error: %v", err)
+	}
+}
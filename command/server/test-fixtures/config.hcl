@@ -29,4 +29,5 @@ max_lease_ttl = "10h"
 default_lease_ttl = "10h"
 cluster_name = "testcluster"
 pid_file = "./pidfile"
+log_level = "debug"
 raw_storage_endpoint = true
@@ -30,6 +30,7 @@ telemetry {
 
 max_lease_ttl = "10h"
 default_lease_ttl = "10h"
+default_max_request_duration = "90s"
 cluster_name = "testcluster"
 pid_file = "./pidfile"
 raw_storage_endpoint = true
@@ -0,0 +1,47 @@
+package command
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/vault/command/server"
+	"github.com/hashicorp/vault/helper/entropy"
+	"github.com/hashicorp/vault/vault"
+)
+
+// configureEntropy returns the external source of entropy of the server
+// configuration, adding the information about the source to the server
+// output, or nil if the configuration has no entropy stanza. The "seal" source
+// is the key management service of the seal, such as a PKCS#11 HSM, which
+// must be able to generate random data.
+func configureEntropy(config *server.Config, seal vault.Seal, infoKeys *[]string, info map[string]string) (entropy.Sourcer, error) {
+	if config.Entropy == nil {
+		return nil, nil
+	}
+
+	var sourcer entropy.Sourcer
+	switch config.Entropy.Type {
+	case "seal":
+		access := vault.AutoSealAccess(seal)
+		if access == nil {
+			return nil, fmt.Errorf("the seal must be an auto seal to be used as entropy source")
+		}
+		var ok bool
+		sourcer, ok = access.(entropy.Sourcer)
+		if !ok {
+			return nil, fmt.Errorf("seal of type %q does not support entropy augmentation", access.SealType())
+		}
+	case "device":
+		var err error
+		sourcer, err = entropy.NewDeviceSource(config.Entropy.Config["path"])
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported entropy source type %q", config.Entropy.Type)
+	}
+
+	*infoKeys = append(*infoKeys, "entropy source")
+	info["entropy source"] = fmt.Sprintf("%s (%s)", config.Entropy.Type, config.Entropy.Mode)
+
+	return sourcer, nil
+}
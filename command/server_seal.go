@@ -0,0 +1,109 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/vault/command/server"
+	"github.com/hashicorp/vault/vault"
+	"github.com/hashicorp/vault/vault/seal/awskms"
+	"github.com/hashicorp/vault/vault/seal/azurekeyvault"
+	"github.com/hashicorp/vault/vault/seal/gcpckms"
+	"github.com/hashicorp/vault/vault/seal/transit"
+	log "github.com/mgutz/logxi/v1"
+)
+
+// EnvVaultSealType is the environment variable of the type of the seal, which
+// configures the seal from the environment when the configuration has no
+// seal stanza
+const EnvVaultSealType = "VAULT_SEAL_TYPE"
+
+// configurableSealAccess is implemented by the accesses to key management
+// services configured from the seal stanza of the server configuration
+type configurableSealAccess interface {
+	SetConfig(map[string]string) (map[string]string, error)
+}
+
+// configureSeal returns the seal of the server configuration, adding the
+// information about the seal to the server output. Without seal stanza or
+// VAULT_SEAL_TYPE, the seal is the default Shamir seal.
+func configureSeal(config *server.Config, infoKeys *[]string, info map[string]string, logger log.Logger) (vault.Seal, error) {
+	sealType := vault.SealTypeShamir
+	var sealConfig map[string]string
+	if config.Seal != nil {
+		sealType = config.Seal.Type
+		sealConfig = config.Seal.Config
+	}
+	if envType := os.Getenv(EnvVaultSealType); envType != "" && config.Seal == nil {
+		sealType = strings.ToLower(envType)
+	}
+
+	seal, sealInfo, err := newSeal(sealType, sealConfig, logger)
+	if err != nil {
+		return nil, err
+	}
+	if sealType == vault.SealTypeShamir {
+		return seal, nil
+	}
+
+	*infoKeys = append(*infoKeys, "seal type")
+	info["seal type"] = sealType
+	for k, v := range sealInfo {
+		*infoKeys = append(*infoKeys, k)
+		info[k] = v
+	}
+
+	return seal, nil
+}
+
+// configureDisabledSeal returns the disabled seal of the server
+// configuration, which is the previous seal of a seal migration, or nil if
+// there is none
+func configureDisabledSeal(config *server.Config, infoKeys *[]string, info map[string]string, logger log.Logger) (vault.Seal, error) {
+	if config.DisabledSeal == nil {
+		return nil, nil
+	}
+
+	seal, _, err := newSeal(config.DisabledSeal.Type, config.DisabledSeal.Config, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	*infoKeys = append(*infoKeys, "disabled seal type")
+	info["disabled seal type"] = config.DisabledSeal.Type
+
+	return seal, nil
+}
+
+// newSeal returns the seal of the given type configured from the parameters
+// of its stanza, along with the information about the seal
+func newSeal(sealType string, sealConfig map[string]string, logger log.Logger) (vault.Seal, map[string]string, error) {
+	var access configurableSealAccess
+	var seal vault.Seal
+	switch sealType {
+	case vault.SealTypeShamir:
+		return &vault.DefaultSeal{}, nil, nil
+	case vault.SealTypeAWSKMS:
+		a := awskms.NewSeal()
+		access, seal = a, vault.NewAutoSeal(a)
+	case vault.SealTypeGCPCKMS:
+		a := gcpckms.NewSeal()
+		access, seal = a, vault.NewAutoSeal(a)
+	case vault.SealTypeAzureKeyVault:
+		a := azurekeyvault.NewSeal()
+		access, seal = a, vault.NewAutoSeal(a)
+	case vault.SealTypeTransit:
+		a := transit.NewSeal(logger)
+		access, seal = a, vault.NewAutoSeal(a)
+	default:
+		return nil, nil, fmt.Errorf("unsupported seal type %q", sealType)
+	}
+
+	sealInfo, err := access.SetConfig(sealConfig)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error configuring seal of type %s: %v", sealType, err)
+	}
+
+	return seal, sealInfo, nil
+}
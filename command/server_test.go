@@ -206,6 +206,105 @@ func TestServer_ReloadListener(t *testing.T) {
 	wg.Wait()
 }
 
+func TestServer_ReloadConfig(t *testing.T) {
+	t.Parallel()
+
+	td, err := ioutil.TempDir("", "vault-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(td)
+
+	port1, port2 := testRandomPort(t), testRandomPort(t)
+	configFile := td + "/reload.hcl"
+	writeConfig := func(hcl string) {
+		t.Helper()
+		if err := ioutil.WriteFile(configFile, []byte(hcl), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	listenerHCL := func(port int) string {
+		return fmt.Sprintf(`
+listener "tcp" {
+  address     = "127.0.0.1:%d"
+  tls_disable = "true"
+}
+`, port)
+	}
+	baseHCL := fmt.Sprintf(`
+backend "file" {
+  path = %q
+}
+disable_mlock = true
+`, td+"/storage")
+	writeConfig(baseHCL + listenerHCL(port1))
+
+	ui, cmd := testServerCommand(t)
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if code := cmd.Run([]string{"-config", configFile}); code != 0 {
+			t.Errorf("got a non-zero exit status: %s", ui.ErrorWriter.String())
+		}
+	}()
+
+	select {
+	case <-cmd.startedCh:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timeout")
+	}
+
+	testListening := func(port int) error {
+		conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+		if err != nil {
+			return err
+		}
+		conn.Close()
+		return nil
+	}
+	if err := testListening(port1); err != nil {
+		t.Fatal(err)
+	}
+	if cmd.logger.IsDebug() {
+		t.Fatal("expected info log level")
+	}
+
+	// The listener is replaced, and the log level and telemetry change
+	writeConfig(baseHCL + listenerHCL(port2) + `
+log_level = "debug"
+
+telemetry {
+  statsd_address = "127.0.0.1:8125"
+}
+`)
+
+	cmd.SighupCh <- struct{}{}
+	select {
+	case <-cmd.reloadedCh:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timeout")
+	}
+
+	if err := testListening(port2); err != nil {
+		t.Fatal(err)
+	}
+	if err := testListening(port1); err == nil {
+		t.Fatal("expected removed listener to be closed")
+	}
+	if !cmd.logger.IsDebug() {
+		t.Fatal("expected debug log level")
+	}
+	if cmd.telemetry.StatsdAddr != "127.0.0.1:8125" {
+		t.Fatalf("bad telemetry: %#v", cmd.telemetry)
+	}
+
+	cmd.ShutdownCh <- struct{}{}
+
+	wg.Wait()
+}
+
 func TestServer(t *testing.T) {
 	t.Parallel()
 
@@ -174,10 +174,11 @@ func (c *SSHCommand) Flags() *FlagSets {
 		Usage: "Mount point to the SSH secrets engine where host keys are signed. " +
 			"When given a value, Vault will generate a custom \"known_hosts\" file " +
 			"with delegation to the CA at the provided mount point to verify the " +
-			"SSH connection's host keys against the provided CA. By default, host " +
-			"keys are validated against the user's local \"known_hosts\" file. " +
-			"This flag forces strict key host checking and ignores a custom user " +
-			"known hosts file.",
+			"SSH connection's host keys against the provided CA. This flag forces " +
+			"strict key host checking and ignores a custom user known hosts file. " +
+			"By default, host keys are validated against the user's local " +
+			"\"known_hosts\" file, and the host certificates signed by the CA of " +
+			"-mount-point are trusted as well.",
 	})
 
 	f.StringVar(&StringVar{
@@ -370,7 +371,7 @@ func (c *SSHCommand) handleTypeCA(username, ip string, sshArgs []string) int {
 
 	// Handle no-exec
 	if c.flagNoExec {
-		if c.flagFormat != "" {
+		if c.flagField != "" {
 			return PrintRawField(c.UI, secret, c.flagField)
 		}
 		return OutputSecret(c.UI, c.flagFormat, secret)
@@ -388,38 +389,32 @@ func (c *SSHCommand) handleTypeCA(username, ip string, sshArgs []string) int {
 	userKnownHostsFile := c.flagUserKnownHostsFile
 	strictHostKeyChecking := c.flagStrictHostKeyChecking
 
-	// Handle host key signing verification. If the user specified a mount point,
-	// download the public key, trust it with the given domains, and use that
-	// instead of the user's regular known_hosts file.
-	if c.flagHostKeyMountPoint != "" {
-		secret, err := c.client.Logical().Read(c.flagHostKeyMountPoint + "/config/ca")
-		if err != nil {
-			c.UI.Error(fmt.Sprintf("failed to get host signing key: %s", err))
-			return 2
-		}
-		if secret == nil || secret.Data == nil {
-			c.UI.Error("missing host signing key")
-			return 2
-		}
-		publicKey, ok := secret.Data["public_key"].(string)
-		if !ok || publicKey == "" {
-			c.UI.Error("host signing key is empty")
-			return 2
-		}
-
-		// Write the known_hosts file
-		name := fmt.Sprintf("vault_ssh_ca_known_hosts_%s_%s", username, ip)
-		data := fmt.Sprintf("@cert-authority %s %s", c.flagHostKeyHostnames, publicKey)
-		knownHosts, err, closer := c.writeTemporaryFile(name, []byte(data), 0644)
-		defer closer()
-		if err != nil {
-			c.UI.Error(fmt.Sprintf("failed to write host public key: %s", err))
-			return 1
-		}
-
-		// Update the variables
+	// Handle host key signing verification. The CA public keys of the mount
+	// signing the host keys are trusted with the given domains in a temporary
+	// known_hosts file. If the user specified a host key mount point, the file
+	// is used instead of the user's regular known_hosts file. Otherwise the
+	// hosts presenting a certificate signed by the CA of the mount signing the
+	// user key are trusted, in addition to the user's known hosts.
+	hostKeyMountPoint := c.flagHostKeyMountPoint
+	strict := hostKeyMountPoint != ""
+	if !strict {
+		hostKeyMountPoint = c.flagMountPoint
+	}
+	knownHosts, err, knownHostsCloser := c.writeCAKnownHosts(hostKeyMountPoint, username, ip)
+	defer knownHostsCloser()
+	switch {
+	case err != nil && strict:
+		c.UI.Error(fmt.Sprintf("failed to write host CA public keys: %s", err))
+		return 2
+	case err != nil:
+		c.UI.Warn(fmt.Sprintf("Host keys are only verified against %s: %s",
+			userKnownHostsFile, err))
+	case strict:
 		userKnownHostsFile = knownHosts
 		strictHostKeyChecking = "yes"
+	default:
+		// New host keys accepted by the user are added to the first file
+		userKnownHostsFile = userKnownHostsFile + " " + knownHosts
 	}
 
 	// Write the signed public key to disk
@@ -474,7 +469,7 @@ func (c *SSHCommand) handleTypeOTP(username, ip string, sshArgs []string) int {
 
 	// Handle no-exec
 	if c.flagNoExec {
-		if c.flagFormat != "" {
+		if c.flagField != "" {
 			return PrintRawField(c.UI, secret, c.flagField)
 		}
 		return OutputSecret(c.UI, c.flagFormat, secret)
@@ -556,7 +551,7 @@ func (c *SSHCommand) handleTypeDynamic(username, ip string, sshArgs []string) in
 
 	// Handle no-exec
 	if c.flagNoExec {
-		if c.flagFormat != "" {
+		if c.flagField != "" {
 			return PrintRawField(c.UI, secret, c.flagField)
 		}
 		return OutputSecret(c.UI, c.flagFormat, secret)
@@ -666,6 +661,35 @@ func (c *SSHCommand) writeTemporaryFile(name string, data []byte, perms os.FileM
 	return f.Name(), nil, closer
 }
 
+// writeCAKnownHosts writes a temporary known_hosts file trusting the CA public
+// keys of the mount to sign the host keys of the host key hostnames, and
+// returns its path. The caller should defer the closer to cleanup the file.
+func (c *SSHCommand) writeCAKnownHosts(mountPoint, username, ip string) (string, error, func() error) {
+	// default closer to prevent panic
+	closer := func() error { return nil }
+
+	keys, err := c.client.SSHWithMountPoint(mountPoint).CAPublicKeys()
+	if err != nil {
+		return "", errors.Wrap(err, "fetching host CA public keys"), closer
+	}
+	if len(keys) == 0 {
+		return "", errors.Errorf("no CA configured at %s", mountPoint), closer
+	}
+
+	name := fmt.Sprintf("vault_ssh_ca_known_hosts_%s_%s", username, ip)
+	return c.writeTemporaryFile(name, []byte(knownHostsCertAuthorities(c.flagHostKeyHostnames, keys)), 0644)
+}
+
+// knownHostsCertAuthorities returns the known_hosts lines trusting each key as
+// a certificate authority for the hostnames
+func knownHostsCertAuthorities(hostnames string, keys []string) string {
+	var lines string
+	for _, key := range keys {
+		lines += fmt.Sprintf("@cert-authority %s %s\n", hostnames, strings.TrimSpace(key))
+	}
+	return lines
+}
+
 // writeTemporaryKey writes the key to a temporary file and returns the path.
 // The caller should defer the closer to cleanup the key.
 func (c *SSHCommand) writeTemporaryKey(name string, data []byte) (string, error, func() error) {
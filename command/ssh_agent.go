@@ -0,0 +1,354 @@
+package command
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/mitchellh/cli"
+	"github.com/posener/complete"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+var _ cli.Command = (*SSHAgentCommand)(nil)
+var _ cli.CommandAutocomplete = (*SSHAgentCommand)(nil)
+
+// sshAgentRetryInterval is how long to wait before trying again to sign the
+// key after a failure. It's var so that tests can lower it.
+var sshAgentRetryInterval = 30 * time.Second
+
+type SSHAgentCommand struct {
+	*BaseCommand
+
+	ShutdownCh chan struct{}
+
+	flagMountPoint      string
+	flagRole            string
+	flagPublicKeyPath   string
+	flagPrivateKeyPath  string
+	flagIssue           bool
+	flagValidPrincipals string
+	flagTTL             time.Duration
+	flagRenewBefore     time.Duration
+	flagAgentSocket     string
+
+	// agent is used instead of the agent of the socket by the tests
+	agent agent.Agent
+}
+
+// sshAgentCert is a certificate loaded into the agent
+type sshAgentCert struct {
+	cert        *ssh.Certificate
+	validBefore time.Time
+}
+
+func (c *SSHAgentCommand) Synopsis() string {
+	return "Keep a signed SSH certificate loaded in the ssh-agent"
+}
+
+func (c *SSHAgentCommand) Help() string {
+	helpText := `
+Usage: vault ssh-agent [options]
+
+  Signs an SSH key with a role of the SSH secrets engine in CA mode and keeps
+  the signed certificate loaded in the local ssh-agent, signing the key again
+  before the certificate expires. The command runs until it is interrupted;
+  the last certificate is left in the ssh-agent until it expires.
+
+  Keep a certificate of ~/.ssh/id_rsa.pub loaded in the agent:
+
+      $ vault ssh-agent -role=my-role
+
+  Have Vault generate a new key pair each time the certificate is renewed,
+  so that the private key never touches the disk:
+
+      $ vault ssh-agent -role=my-role -issue
+
+  For the full list of options and arguments, please see the documentation.
+
+` + c.Flags().Help()
+
+	return strings.TrimSpace(helpText)
+}
+
+func (c *SSHAgentCommand) Flags() *FlagSets {
+	set := c.flagSet(FlagSetHTTP)
+
+	f := set.NewFlagSet("Command Options")
+
+	f.StringVar(&StringVar{
+		Name:       "mount-point",
+		Target:     &c.flagMountPoint,
+		Default:    "ssh/",
+		EnvVar:     "",
+		Completion: complete.PredictAnything,
+		Usage:      "Mount point to the SSH secrets engine.",
+	})
+
+	f.StringVar(&StringVar{
+		Name:       "role",
+		Target:     &c.flagRole,
+		Default:    "",
+		EnvVar:     "",
+		Completion: complete.PredictAnything,
+		Usage:      "Name of the role to sign the key with. This is required.",
+	})
+
+	f.StringVar(&StringVar{
+		Name:       "public-key-path",
+		Target:     &c.flagPublicKeyPath,
+		Default:    "~/.ssh/id_rsa.pub",
+		EnvVar:     "",
+		Completion: complete.PredictFiles("*"),
+		Usage:      "Path to the SSH public key to send to Vault for signing.",
+	})
+
+	f.StringVar(&StringVar{
+		Name:       "private-key-path",
+		Target:     &c.flagPrivateKeyPath,
+		Default:    "~/.ssh/id_rsa",
+		EnvVar:     "",
+		Completion: complete.PredictFiles("*"),
+		Usage: "Path to the SSH private key loaded into the agent along with " +
+			"the certificate. This must be the corresponding private key to " +
+			"-public-key-path, and must not be encrypted.",
+	})
+
+	f.BoolVar(&BoolVar{
+		Name:       "issue",
+		Target:     &c.flagIssue,
+		Default:    false,
+		EnvVar:     "",
+		Completion: complete.PredictNothing,
+		Usage: "Have Vault generate a new key pair of the type configured by " +
+			"the role each time the certificate is renewed, through the issue " +
+			"endpoint, instead of signing the key of -public-key-path.",
+	})
+
+	f.StringVar(&StringVar{
+		Name:       "valid-principals",
+		Target:     &c.flagValidPrincipals,
+		Default:    "",
+		EnvVar:     "",
+		Completion: complete.PredictAnything,
+		Usage: "Comma-separated list of the usernames the certificate is " +
+			"signed for. Defaults to the default user of the role.",
+	})
+
+	f.DurationVar(&DurationVar{
+		Name:       "ttl",
+		Target:     &c.flagTTL,
+		Default:    0,
+		EnvVar:     "",
+		Completion: complete.PredictAnything,
+		Usage:      "TTL of the certificates. Defaults to the TTL of the role.",
+	})
+
+	f.DurationVar(&DurationVar{
+		Name:       "renew-before",
+		Target:     &c.flagRenewBefore,
+		Default:    0,
+		EnvVar:     "",
+		Completion: complete.PredictAnything,
+		Usage: "How long before the expiry of the certificate to sign the " +
+			"key again. Defaults to a third of the lifetime of the certificate.",
+	})
+
+	f.StringVar(&StringVar{
+		Name:       "agent-socket",
+		Target:     &c.flagAgentSocket,
+		Default:    "",
+		EnvVar:     "SSH_AUTH_SOCK",
+		Completion: complete.PredictFiles("*"),
+		Usage:      "Path to the socket of the ssh-agent.",
+	})
+
+	return set
+}
+
+func (c *SSHAgentCommand) AutocompleteArgs() complete.Predictor {
+	return nil
+}
+
+func (c *SSHAgentCommand) AutocompleteFlags() complete.Flags {
+	return c.Flags().Completions()
+}
+
+func (c *SSHAgentCommand) Run(args []string) int {
+	f := c.Flags()
+
+	if err := f.Parse(args); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	args = f.Args()
+	if len(args) > 0 {
+		c.UI.Error(fmt.Sprintf("Too many arguments (expected 0, got %d)", len(args)))
+		return 1
+	}
+
+	if c.flagRole == "" {
+		c.UI.Error("Missing -role")
+		return 1
+	}
+	if c.flagRenewBefore < 0 {
+		c.UI.Error("The renew-before duration must not be negative")
+		return 1
+	}
+	if c.agent == nil && c.flagAgentSocket == "" {
+		c.UI.Error("Missing -agent-socket, and SSH_AUTH_SOCK is not set")
+		return 1
+	}
+
+	c.flagPublicKeyPath = expandPath(c.flagPublicKeyPath)
+	c.flagPrivateKeyPath = expandPath(c.flagPrivateKeyPath)
+
+	client, err := c.Client()
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 2
+	}
+	sshClient := client.SSHWithMountPoint(strings.Trim(c.flagMountPoint, "/"))
+
+	var current *sshAgentCert
+	for {
+		wait := sshAgentRetryInterval
+		cert, err := c.renew(sshClient, current)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error renewing the certificate: %s", err))
+		} else {
+			current = cert
+			renewAt := current.validBefore.Add(-c.renewBefore(current))
+			c.UI.Output(fmt.Sprintf("Loaded certificate %d into the agent, valid until %s, renewing at %s",
+				current.cert.Serial, current.validBefore.Format(time.RFC3339), renewAt.Format(time.RFC3339)))
+			wait = time.Until(renewAt)
+		}
+
+		// Retry sooner if the certificate expires before the retry
+		if err != nil && current != nil {
+			if remaining := time.Until(current.validBefore); remaining > 0 && remaining < wait {
+				wait = remaining / 2
+			}
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-c.ShutdownCh:
+			timer.Stop()
+			c.UI.Output("Interrupted, the certificate is left in the agent until it expires")
+			return 0
+		}
+	}
+}
+
+// renewBefore returns how long before the expiry of the certificate it's
+// renewed
+func (c *SSHAgentCommand) renewBefore(cert *sshAgentCert) time.Duration {
+	lifetime := time.Duration(cert.cert.ValidBefore-cert.cert.ValidAfter) * time.Second
+	if c.flagRenewBefore > 0 && c.flagRenewBefore < lifetime {
+		return c.flagRenewBefore
+	}
+	return lifetime / 3
+}
+
+// renew signs the key, or issues a new key pair, with the role and loads the
+// certificate into the agent in place of the previous one
+func (c *SSHAgentCommand) renew(client *api.SSH, previous *sshAgentCert) (*sshAgentCert, error) {
+	input := &api.SSHSignInput{
+		CertType: "user",
+		TTL:      c.flagTTL,
+	}
+	if c.flagValidPrincipals != "" {
+		input.ValidPrincipals = strings.Split(c.flagValidPrincipals, ",")
+	}
+
+	var signedKey *api.SSHSignedKey
+	var privateKeyPEM []byte
+	if c.flagIssue {
+		issued, err := client.IssueKeyPair(c.flagRole, input)
+		if err != nil {
+			return nil, err
+		}
+		signedKey = issued.SSHSignedKey
+		privateKeyPEM = []byte(issued.PrivateKey)
+	} else {
+		publicKey, err := ioutil.ReadFile(c.flagPublicKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read public key: %v", err)
+		}
+		if privateKeyPEM, err = ioutil.ReadFile(c.flagPrivateKeyPath); err != nil {
+			return nil, fmt.Errorf("failed to read private key: %v", err)
+		}
+		input.PublicKey = string(publicKey)
+		if signedKey, err = client.SignPublicKey(c.flagRole, input); err != nil {
+			return nil, err
+		}
+	}
+
+	privateKey, err := ssh.ParseRawPrivateKey(privateKeyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %v", err)
+	}
+	parsed, _, _, _, err := ssh.ParseAuthorizedKey([]byte(signedKey.SignedKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse signed key: %v", err)
+	}
+	cert, ok := parsed.(*ssh.Certificate)
+	if !ok {
+		return nil, fmt.Errorf("signed key is not a certificate")
+	}
+	validBefore := time.Unix(int64(cert.ValidBefore), 0)
+
+	keyring, closer, err := c.connectAgent()
+	if err != nil {
+		return nil, err
+	}
+	defer closer()
+
+	// The agent forgets the certificate once it expires
+	lifetime := time.Until(validBefore)
+	if lifetime <= 0 {
+		return nil, fmt.Errorf("certificate %d already expired", cert.Serial)
+	}
+	if err := keyring.Add(agent.AddedKey{
+		PrivateKey:   privateKey,
+		Certificate:  cert,
+		Comment:      fmt.Sprintf("vault-ssh-agent %s %d", c.flagRole, cert.Serial),
+		LifetimeSecs: uint32(lifetime / time.Second),
+	}); err != nil {
+		return nil, fmt.Errorf("failed to add the certificate to the agent: %v", err)
+	}
+
+	// The previous certificate is superseded. Issued key pairs are only
+	// used along with their certificate, so they go with it.
+	if previous != nil {
+		if err := keyring.Remove(previous.cert); err != nil {
+			c.UI.Warn(fmt.Sprintf("Failed to remove the previous certificate from the agent: %s", err))
+		}
+	}
+
+	return &sshAgentCert{
+		cert:        cert,
+		validBefore: validBefore,
+	}, nil
+}
+
+// connectAgent connects to the agent of the socket. The connection is made
+// for each renewal so that a restart of the agent is survived.
+func (c *SSHAgentCommand) connectAgent() (agent.Agent, func(), error) {
+	if c.agent != nil {
+		return c.agent, func() {}, nil
+	}
+
+	conn, err := net.Dial("unix", c.flagAgentSocket)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to the agent: %v", err)
+	}
+	return agent.NewClient(conn), func() { conn.Close() }, nil
+}
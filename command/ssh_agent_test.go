@@ -0,0 +1,139 @@
+package command
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/mitchellh/cli"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+func testSSHAgentCommand(tb testing.TB) (*cli.MockUi, *SSHAgentCommand) {
+	tb.Helper()
+
+	ui := cli.NewMockUi()
+	return ui, &SSHAgentCommand{
+		BaseCommand: &BaseCommand{
+			UI: ui,
+		},
+		ShutdownCh: make(chan struct{}),
+		agent:      agent.NewKeyring(),
+	}
+}
+
+func TestSSHAgentCommand_Run(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		args []string
+		out  string
+		code int
+	}{
+		{
+			"too_many_args",
+			[]string{"-role=users", "foo"},
+			"Too many arguments",
+			1,
+		},
+		{
+			"missing_role",
+			[]string{},
+			"Missing -role",
+			1,
+		},
+		{
+			"negative_renew_before",
+			[]string{"-role=users", "-renew-before=-1s"},
+			"must not be negative",
+			1,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ui, cmd := testSSHAgentCommand(t)
+
+			code := cmd.Run(tc.args)
+			if code != tc.code {
+				t.Errorf("expected %d to be %d", code, tc.code)
+			}
+
+			combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+			if !strings.Contains(combined, tc.out) {
+				t.Errorf("expected %q to contain %q", combined, tc.out)
+			}
+		})
+	}
+}
+
+func TestSSHAgentCommand_renew(t *testing.T) {
+	t.Parallel()
+
+	client, closer := testVaultServer(t)
+	defer closer()
+
+	if err := client.Sys().Mount("ssh", &api.MountInput{
+		Type: "ssh",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Logical().Write("ssh/config/ca", map[string]interface{}{
+		"generate_signing_key": true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Logical().Write("ssh/roles/users", map[string]interface{}{
+		"key_type":                "ca",
+		"allow_user_certificates": true,
+		"allowed_users":           "*",
+		"issued_key_type":         "ed25519",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, cmd := testSSHAgentCommand(t)
+	cmd.flagRole = "users"
+	cmd.flagIssue = true
+	cmd.flagValidPrincipals = "alice"
+	cmd.flagTTL = time.Hour
+	sshClient := client.SSHWithMountPoint("ssh")
+
+	first, err := cmd.renew(sshClient, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lifetime := time.Until(first.validBefore); lifetime < 59*time.Minute || lifetime > time.Hour+time.Minute {
+		t.Fatalf("bad lifetime: %s", lifetime)
+	}
+	if renewBefore := cmd.renewBefore(first); renewBefore < 20*time.Minute || renewBefore > 21*time.Minute {
+		t.Fatalf("bad renew before: %s", renewBefore)
+	}
+
+	// The renewal issues a new key pair and replaces the previous certificate
+	second, err := cmd.renew(sshClient, first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := cmd.agent.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 || keys[0].Format != "ssh-ed25519-cert-v01@openssh.com" {
+		t.Fatalf("bad: %#v", keys)
+	}
+	if string(keys[0].Blob) != string(second.cert.Marshal()) || second.cert.Serial == first.cert.Serial {
+		t.Fatalf("expected the agent to hold the second certificate")
+	}
+
+	cmd.flagRenewBefore = 5 * time.Minute
+	if renewBefore := cmd.renewBefore(second); renewBefore != 5*time.Minute {
+		t.Fatalf("bad renew before: %s", renewBefore)
+	}
+}
@@ -1,8 +1,12 @@
 package command
 
 import (
+	"io/ioutil"
+	"os"
+	"strings"
 	"testing"
 
+	"github.com/hashicorp/vault/api"
 	"github.com/mitchellh/cli"
 )
 
@@ -21,3 +25,63 @@ func TestSSHCommand_Run(t *testing.T) {
 	t.Parallel()
 	t.Skip("Need a way to setup target infrastructure")
 }
+
+func TestSSHCommand_writeCAKnownHosts(t *testing.T) {
+	t.Parallel()
+
+	client, closer := testVaultServer(t)
+	defer closer()
+
+	if err := client.Sys().Mount("host-signer", &api.MountInput{
+		Type: "ssh",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, cmd := testSSHCommand(t)
+	cmd.client = client
+	cmd.flagHostKeyHostnames = "*.example.com"
+
+	// The CA of the mount is not configured yet
+	if _, err, cleanup := cmd.writeCAKnownHosts("host-signer", "user", "1.2.3.4"); err == nil {
+		cleanup()
+		t.Fatal("expected an error")
+	}
+
+	secret, err := client.Logical().Write("host-signer/config/ca", map[string]interface{}{
+		"generate_signing_key": true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey := strings.TrimSpace(secret.Data["public_key"].(string))
+
+	// The CA keys are fetched without a token
+	client.ClearToken()
+	path, err, cleanup := cmd.writeCAKnownHosts("host-signer", "user", "1.2.3.4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "@cert-authority *.example.com " + publicKey + "\n"; string(contents) != expected {
+		t.Fatalf("expected %q, got %q", expected, contents)
+	}
+	cleanup()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected the file to be removed, got %v", err)
+	}
+}
+
+func TestKnownHostsCertAuthorities(t *testing.T) {
+	t.Parallel()
+
+	lines := knownHostsCertAuthorities("example.com,*.example.com", []string{"ssh-rsa AAAA\n", "ssh-ed25519 BBBB"})
+	expected := "@cert-authority example.com,*.example.com ssh-rsa AAAA\n" +
+		"@cert-authority example.com,*.example.com ssh-ed25519 BBBB\n"
+	if lines != expected {
+		t.Fatalf("expected %q, got %q", expected, lines)
+	}
+}
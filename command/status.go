@@ -39,7 +39,7 @@ Usage: vault status [options]
 }
 
 func (c *StatusCommand) Flags() *FlagSets {
-	return c.flagSet(FlagSetHTTP)
+	return c.flagSet(FlagSetHTTP | FlagSetOutputField | FlagSetOutputFormat)
 }
 
 func (c *StatusCommand) AutocompleteArgs() complete.Predictor {
@@ -79,7 +79,7 @@ func (c *StatusCommand) Run(args []string) int {
 
 	// Do not return the int here, since we want to return a custom error code
 	// depending on the seal status.
-	OutputSealStatus(c.UI, client, status)
+	OutputSealStatus(c.UI, c.flagFormat, c.flagField, client, status)
 
 	if status.Sealed {
 		return 2
@@ -49,6 +49,13 @@ func TestStatusCommand_Run(t *testing.T) {
 			"Too many arguments",
 			1,
 		},
+		{
+			"json",
+			[]string{"-format=json"},
+			false,
+			`"sealed": false`,
+			0,
+		},
 	}
 
 	t.Run("validations", func(t *testing.T) {
@@ -45,7 +45,7 @@ Usage: vault token capabilities [options] [TOKEN] PATH
 }
 
 func (c *TokenCapabilitiesCommand) Flags() *FlagSets {
-	return c.flagSet(FlagSetHTTP)
+	return c.flagSet(FlagSetHTTP | FlagSetOutputFormat)
 }
 
 func (c *TokenCapabilitiesCommand) AutocompleteArgs() complete.Predictor {
@@ -95,6 +95,11 @@ func (c *TokenCapabilitiesCommand) Run(args []string) int {
 	}
 
 	sort.Strings(capabilities)
+
+	if !isTableFormat(c.flagFormat) {
+		return OutputData(c.UI, c.flagFormat, "", capabilities)
+	}
+
 	c.UI.Output(strings.Join(capabilities, ", "))
 	return 0
 }
@@ -28,6 +28,7 @@ type TokenCreateCommand struct {
 	flagRole            string
 	flagMetadata        map[string]string
 	flagPolicies        []string
+	flagType            string
 
 	// Deprecated flags
 	flagLease time.Duration
@@ -153,6 +154,16 @@ func (c *TokenCreateCommand) Flags() *FlagSets {
 			"must have permission for \"auth/token/create/<role>\".",
 	})
 
+	f.StringVar(&StringVar{
+		Name:       "type",
+		Target:     &c.flagType,
+		Default:    "",
+		Completion: complete.PredictSet("service", "batch"),
+		Usage: "Type of the token, either \"service\" or \"batch\". Batch " +
+			"tokens are not persisted and cannot be renewed or revoked. The " +
+			"default is \"service\", or the type of the role given with -role.",
+	})
+
 	f.StringMapVar(&StringMapVar{
 		Name:       "metadata",
 		Target:     &c.flagMetadata,
@@ -229,6 +240,7 @@ func (c *TokenCreateCommand) Run(args []string) int {
 		Renewable:       &c.flagRenewable,
 		ExplicitMaxTTL:  c.flagExplicitMaxTTL.String(),
 		Period:          c.flagPeriod.String(),
+		Type:            c.flagType,
 	}
 
 	var secret *api.Secret
@@ -52,7 +52,7 @@ Usage: vault token revoke [options] [TOKEN | ACCESSOR]
 }
 
 func (c *TokenRevokeCommand) Flags() *FlagSets {
-	set := c.flagSet(FlagSetHTTP)
+	set := c.flagSet(FlagSetHTTP | FlagSetOutputField | FlagSetOutputFormat)
 
 	f := set.NewFlagSet("Command Options")
 
@@ -169,6 +169,10 @@ func (c *TokenRevokeCommand) Run(args []string) int {
 		return 2
 	}
 
-	c.UI.Output("Success! Revoked token (if it existed)")
-	return 0
+	// The token is not included in the data, since it is sensitive
+	return OutputSuccess(c.UI, c.flagFormat, c.flagField,
+		"Success! Revoked token (if it existed)",
+		map[string]interface{}{
+			"revoked": true,
+		})
 }
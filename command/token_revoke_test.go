@@ -131,6 +131,34 @@ func TestTokenRevokeCommand_Run(t *testing.T) {
 		}
 	})
 
+	t.Run("json", func(t *testing.T) {
+		t.Parallel()
+
+		client, closer := testVaultServer(t)
+		defer closer()
+
+		token, _ := testTokenAndAccessor(t, client)
+
+		ui, cmd := testTokenRevokeCommand(t)
+		cmd.client = client
+
+		code := cmd.Run([]string{
+			"-format", "json",
+			token,
+		})
+		if exp := 0; code != exp {
+			t.Errorf("expected %d to be %d", code, exp)
+		}
+
+		combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+		if expected := `"revoked": true`; !strings.Contains(combined, expected) {
+			t.Errorf("expected %q to contain %q", combined, expected)
+		}
+		if strings.Contains(combined, token) {
+			t.Errorf("expected %q to not contain the token", combined)
+		}
+	})
+
 	t.Run("self", func(t *testing.T) {
 		t.Parallel()
 
@@ -1,6 +1,8 @@
 package command
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
@@ -95,6 +97,26 @@ func PrintRawField(ui cli.Ui, secret *api.Secret, field string) int {
 	return PrintRaw(ui, str)
 }
 
+// PrintRawDataField prints the raw field of the data, with the name of the field
+// in the JSON output of the data.
+func PrintRawDataField(ui cli.Ui, data interface{}, field string) int {
+	b, err := json.Marshal(data)
+	if err != nil {
+		ui.Error(fmt.Sprintf("Could not output field: %s", err))
+		return 1
+	}
+
+	var fields map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&fields); err != nil || fields[field] == nil {
+		ui.Error(fmt.Sprintf("Field %q not present in output", field))
+		return 1
+	}
+
+	return PrintRaw(ui, fmt.Sprintf("%v", fields[field]))
+}
+
 // PrintRaw prints a raw value to the terminal. If the process is being "piped"
 // to something else, the "raw" value is printed without a newline character.
 // Otherwise the value is printed as normal.
@@ -24,7 +24,7 @@ func (c *VersionCommand) Synopsis() string {
 
 func (c *VersionCommand) Help() string {
 	helpText := `
-Usage: vault version
+Usage: vault version [options]
 
   Prints the version of this Vault CLI. This does not print the target Vault
   server version.
@@ -33,14 +33,20 @@ Usage: vault version
 
       $ vault version
 
-  There are no arguments or flags to this command. Any additional arguments or
-  flags are ignored.
-`
+  Print the version in JSON format:
+
+      $ vault version -format=json
+
+  There are no arguments to this command. Any additional arguments are
+  ignored.
+
+` + c.Flags().Help()
+
 	return strings.TrimSpace(helpText)
 }
 
 func (c *VersionCommand) Flags() *FlagSets {
-	return nil
+	return c.flagSet(FlagSetOutputField | FlagSetOutputFormat)
 }
 
 func (c *VersionCommand) AutocompleteArgs() complete.Predictor {
@@ -48,10 +54,25 @@ func (c *VersionCommand) AutocompleteArgs() complete.Predictor {
 }
 
 func (c *VersionCommand) AutocompleteFlags() complete.Flags {
-	return nil
+	return c.Flags().Completions()
 }
 
-func (c *VersionCommand) Run(_ []string) int {
+func (c *VersionCommand) Run(args []string) int {
+	f := c.Flags()
+
+	if err := f.Parse(args); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	if c.flagField != "" || !isTableFormat(c.flagFormat) {
+		return OutputData(c.UI, c.flagFormat, c.flagField, map[string]interface{}{
+			"version":     c.VersionInfo.VersionNumber(),
+			"revision":    c.VersionInfo.Revision,
+			"cgo_enabled": version.CgoEnabled,
+		})
+	}
+
 	out := c.VersionInfo.FullVersionNumber(true)
 	if version.CgoEnabled {
 		out += " (cgo)"
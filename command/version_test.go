@@ -39,6 +39,23 @@ func TestVersionCommand_Run(t *testing.T) {
 		}
 	})
 
+	t.Run("json", func(t *testing.T) {
+		t.Parallel()
+
+		ui, cmd := testVersionCommand(t)
+		cmd.VersionInfo.Revision = "abcd1234"
+		code := cmd.Run([]string{"-format=json"})
+		if exp := 0; code != exp {
+			t.Errorf("expected %d to be %d", code, exp)
+		}
+
+		expected := `"revision": "abcd1234"`
+		combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+		if !strings.Contains(combined, expected) {
+			t.Errorf("expected %q to contain %q", combined, expected)
+		}
+	})
+
 	t.Run("no_tabs", func(t *testing.T) {
 		t.Parallel()
 
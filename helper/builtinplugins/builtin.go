@@ -2,8 +2,10 @@ package builtinplugins
 
 import (
 	"github.com/hashicorp/vault/plugins/database/cassandra"
+	"github.com/hashicorp/vault/plugins/database/elasticsearch"
 	"github.com/hashicorp/vault/plugins/database/hana"
 	"github.com/hashicorp/vault/plugins/database/mongodb"
+	"github.com/hashicorp/vault/plugins/database/mongodbatlas"
 	"github.com/hashicorp/vault/plugins/database/mssql"
 	"github.com/hashicorp/vault/plugins/database/mysql"
 	"github.com/hashicorp/vault/plugins/database/postgresql"
@@ -22,11 +24,13 @@ var plugins = map[string]BuiltinFactory{
 	"mysql-rds-database-plugin":    mysql.New(credsutil.NoneLength, mysql.LegacyMetadataLen, mysql.LegacyUsernameLen),
 	"mysql-legacy-database-plugin": mysql.New(credsutil.NoneLength, mysql.LegacyMetadataLen, mysql.LegacyUsernameLen),
 
-	"postgresql-database-plugin": postgresql.New,
-	"mssql-database-plugin":      mssql.New,
-	"cassandra-database-plugin":  cassandra.New,
-	"mongodb-database-plugin":    mongodb.New,
-	"hana-database-plugin":       hana.New,
+	"postgresql-database-plugin":    postgresql.New,
+	"mssql-database-plugin":         mssql.New,
+	"cassandra-database-plugin":     cassandra.New,
+	"mongodb-database-plugin":       mongodb.New,
+	"mongodbatlas-database-plugin":  mongodbatlas.New,
+	"hana-database-plugin":          hana.New,
+	"elasticsearch-database-plugin": elasticsearch.New,
 }
 
 // Get returns the BuiltinFactory func for a particular backend plugin
@@ -0,0 +1,76 @@
+// This package implements the CMAC message authentication code of NIST SP
+// 800-38B, which with AES is the AES-CMAC algorithm of RFC 4493. CMAC is
+// used by payment (EMV) and other protocols to authenticate messages and
+// derive keys with block cipher keys.
+package cmac
+
+import (
+	"crypto/cipher"
+	"fmt"
+)
+
+// Size is the size in bytes of CMACs of 128-bit block ciphers
+const Size = 16
+
+// rb is the constant of the subkey generation of 128-bit block ciphers
+const rb = 0x87
+
+// shift returns the block shifted left by one bit, xored with the constant
+// if the most significant bit of the block was set
+func shift(block []byte) []byte {
+	result := make([]byte, len(block))
+	var carry byte
+	for i := len(block) - 1; i >= 0; i-- {
+		result[i] = block[i]<<1 | carry
+		carry = block[i] >> 7
+	}
+	if carry != 0 {
+		result[len(result)-1] ^= rb
+	}
+	return result
+}
+
+// xorBlock xors the block with the other block in place
+func xorBlock(block, other []byte) {
+	for i := range block {
+		block[i] ^= other[i]
+	}
+}
+
+// Sum returns the CMAC of the message with the block cipher
+func Sum(block cipher.Block, message []byte) ([]byte, error) {
+	if block.BlockSize() != Size {
+		return nil, fmt.Errorf("CMAC requires a block size of %d bytes", Size)
+	}
+
+	// Generate the subkeys
+	l := make([]byte, Size)
+	block.Encrypt(l, l)
+	k1 := shift(l)
+	k2 := shift(k1)
+
+	// The last block is xored with the first subkey if it is complete, or
+	// padded and xored with the second subkey otherwise
+	n := (len(message) + Size - 1) / Size
+	last := make([]byte, Size)
+	if n > 0 && len(message)%Size == 0 {
+		copy(last, message[(n-1)*Size:])
+		xorBlock(last, k1)
+	} else {
+		if n == 0 {
+			n = 1
+		}
+		rest := copy(last, message[(n-1)*Size:])
+		last[rest] = 0x80
+		xorBlock(last, k2)
+	}
+
+	x := make([]byte, Size)
+	for i := 0; i < n-1; i++ {
+		xorBlock(x, message[i*Size:(i+1)*Size])
+		block.Encrypt(x, x)
+	}
+	xorBlock(x, last)
+	block.Encrypt(x, x)
+	return x, nil
+}
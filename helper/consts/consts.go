@@ -4,4 +4,8 @@ const (
 	// ExpirationRestoreWorkerCount specifies the numer of workers to use while
 	// restoring leases into the expiration manager
 	ExpirationRestoreWorkerCount = 64
+
+	// ExpirationRevokeWorkerCount specifies the number of workers revoking
+	// expired leases in the expiration manager
+	ExpirationRevokeWorkerCount = 64
 )
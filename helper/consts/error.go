@@ -11,6 +11,26 @@ var (
 	// No operation is expected to succeed until active.
 	ErrStandby = errors.New("Vault is in standby mode")
 
+	// ErrPerformanceStandbyForward is returned if an operation which cannot
+	// be performed on a performance standby is performed on one. The request
+	// is expected to be forwarded to the active node.
+	ErrPerformanceStandbyForward = errors.New("request must be forwarded to the active node")
+
+	// ErrDRSecondary is returned if a request is made to a DR replication
+	// secondary, which only serves the replication endpoints until it's
+	// promoted
+	ErrDRSecondary = errors.New("request is not supported on a DR replication secondary")
+
+	// ErrIndexStateNotReached is returned if the storage backend does not
+	// reflect the writes of the index state required by a request in time.
+	// The request can be retried.
+	ErrIndexStateNotReached = errors.New("storage has not reached the required index state")
+
+	// ErrUnknownIndexState is returned if the index state required by a
+	// request was not issued by the active node, for instance before a
+	// leadership change
+	ErrUnknownIndexState = errors.New("unknown index state")
+
 	// Used when .. is used in a path
 	ErrPathContainsParentReferences = errors.New("path cannot contain parent references")
 )
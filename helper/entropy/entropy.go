@@ -0,0 +1,98 @@
+// Package entropy augments the random data used for the generation of keys
+// with the entropy of an external source, such as an HSM or a hardware random
+// number generator, as required by some compliance regimes.
+package entropy
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// Sourcer is implemented by the external sources of entropy
+type Sourcer interface {
+	// GetRandom returns the given number of random bytes
+	GetRandom(bytes int) ([]byte, error)
+}
+
+// Reader is an io.Reader returning the random data of crypto/rand XORed with
+// the data of an external source, so that the result is at least as random as
+// the most random of both. Reads fail if the external source does, rather
+// than falling back to crypto/rand alone.
+type Reader struct {
+	source Sourcer
+}
+
+var _ io.Reader = (*Reader)(nil)
+
+// NewReader returns a Reader augmenting crypto/rand with the source
+func NewReader(source Sourcer) *Reader {
+	return &Reader{
+		source: source,
+	}
+}
+
+func (r *Reader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	external, err := r.source.GetRandom(len(p))
+	if err != nil {
+		return 0, fmt.Errorf("error reading from entropy source: %v", err)
+	}
+	if len(external) != len(p) {
+		return 0, fmt.Errorf("entropy source returned %d bytes, expected %d", len(external), len(p))
+	}
+
+	if _, err := io.ReadFull(rand.Reader, p); err != nil {
+		return 0, err
+	}
+	for i := range p {
+		p[i] ^= external[i]
+	}
+	return len(p), nil
+}
+
+// DeviceSource is a Sourcer reading from a device, such as the /dev/hwrng
+// device of a hardware random number generator
+type DeviceSource struct {
+	path string
+	file *os.File
+	lock sync.Mutex
+}
+
+var _ Sourcer = (*DeviceSource)(nil)
+
+// NewDeviceSource opens the device at the path
+func NewDeviceSource(path string) (*DeviceSource, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening entropy device: %v", err)
+	}
+	return &DeviceSource{
+		path: path,
+		file: file,
+	}, nil
+}
+
+func (d *DeviceSource) GetRandom(bytes int) ([]byte, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	buf := make([]byte, bytes)
+	if _, err := io.ReadFull(d.file, buf); err != nil {
+		return nil, fmt.Errorf("error reading entropy device %q: %v", d.path, err)
+	}
+	return buf, nil
+}
+
+// Close closes the device
+func (d *DeviceSource) Close() error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return d.file.Close()
+}
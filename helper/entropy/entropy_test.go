@@ -0,0 +1,80 @@
+package entropy
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+type testSource struct {
+	data []byte
+	err  error
+}
+
+func (s *testSource) GetRandom(n int) ([]byte, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	if len(s.data) < n {
+		return s.data, nil
+	}
+	return s.data[:n], nil
+}
+
+func TestReader(t *testing.T) {
+	// XORing with zeros leaves the data of crypto/rand, so two reads differ
+	r := NewReader(&testSource{data: make([]byte, 32)})
+	a, b := make([]byte, 32), make([]byte, 32)
+	if _, err := r.Read(a); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(a, b) {
+		t.Fatal("expected random data")
+	}
+
+	// Reads fail closed
+	r = NewReader(&testSource{err: errors.New("unavailable")})
+	if _, err := r.Read(a); err == nil {
+		t.Fatal("expected error")
+	}
+	r = NewReader(&testSource{data: make([]byte, 16)})
+	if _, err := r.Read(a); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestDeviceSource(t *testing.T) {
+	f, err := ioutil.TempFile("", "vault-entropy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.Write([]byte("foobar"))
+	f.Close()
+
+	if _, err := NewDeviceSource(f.Name() + "-missing"); err == nil {
+		t.Fatal("expected error")
+	}
+
+	d, err := NewDeviceSource(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	data, err := d.GetRandom(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "foob" {
+		t.Fatalf("bad: %q", data)
+	}
+	if _, err := d.GetRandom(4); err == nil {
+		t.Fatal("expected error")
+	}
+}
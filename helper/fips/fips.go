@@ -0,0 +1,47 @@
+// Package fips holds the FIPS mode of Vault, in which the algorithms which are
+// not FIPS approved are rejected. The mode is always enabled in the binaries
+// built with the fips build tag, and can be enabled at runtime by the server
+// configuration.
+package fips
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// This is set by the build-specific files to tell whether the binary was
+// built for FIPS mode
+var buildEnabled bool
+
+var runtimeEnabled uint32
+
+// Enabled returns true if the algorithms which are not FIPS approved must be
+// rejected.
+func Enabled() bool {
+	return buildEnabled || atomic.LoadUint32(&runtimeEnabled) == 1
+}
+
+// BuildEnabled returns true if the binary was built for FIPS mode, in which
+// case the mode cannot be disabled.
+func BuildEnabled() bool {
+	return buildEnabled
+}
+
+// SetEnabled enables or disables FIPS mode at runtime. Disabling it has no
+// effect on the binaries built for FIPS mode.
+func SetEnabled(enabled bool) {
+	var v uint32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreUint32(&runtimeEnabled, v)
+}
+
+// CheckApproved returns an error naming the algorithm if FIPS mode is enabled
+// and the algorithm is not approved.
+func CheckApproved(algorithm string, approved bool) error {
+	if approved || !Enabled() {
+		return nil
+	}
+	return fmt.Errorf("%s is not FIPS approved", algorithm)
+}
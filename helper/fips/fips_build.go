@@ -0,0 +1,14 @@
+// +build fips
+
+package fips
+
+import (
+	// Restricts the TLS configurations to the FIPS approved settings. This
+	// requires the BoringCrypto toolchain, which replaces the standard
+	// crypto packages with the FIPS validated BoringSSL module.
+	_ "crypto/tls/fipsonly"
+)
+
+func init() {
+	buildEnabled = true
+}
@@ -0,0 +1,7 @@
+// +build !fips
+
+package fips
+
+func init() {
+	buildEnabled = false
+}
@@ -0,0 +1,27 @@
+package fips
+
+import "testing"
+
+func TestCheckApproved(t *testing.T) {
+	defer SetEnabled(false)
+
+	if err := CheckApproved("ed25519", false); err != nil && !BuildEnabled() {
+		t.Fatalf("expected no error outside of FIPS mode, got %v", err)
+	}
+
+	SetEnabled(true)
+	if !Enabled() {
+		t.Fatal("expected FIPS mode to be enabled")
+	}
+	if err := CheckApproved("ed25519", false); err == nil || err.Error() != "ed25519 is not FIPS approved" {
+		t.Fatalf("bad: %v", err)
+	}
+	if err := CheckApproved("aes256-gcm96", true); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	SetEnabled(false)
+	if Enabled() != BuildEnabled() {
+		t.Fatal("expected FIPS mode to follow the build once disabled at runtime")
+	}
+}
@@ -0,0 +1,220 @@
+// This package implements the FF3-1 format-preserving encryption mode of
+// NIST SP 800-38G Revision 1. FF3-1 encrypts strings of numerals in a given
+// radix into strings of the same length and radix, such as credit card
+// numbers into other valid-looking credit card numbers.
+package fpe
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+)
+
+const (
+	// TweakSize is the size in bytes of FF3-1 tweaks
+	TweakSize = 7
+
+	// maxRadix is the largest radix supported by FF3-1
+	maxRadix = 1 << 16
+
+	// minDomainSize is the minimum number of possible inputs, radix^minLen,
+	// required by NIST SP 800-38G Revision 1
+	minDomainSize = 1000000
+
+	// rounds is the number of Feistel rounds of FF3-1
+	rounds = 8
+)
+
+// Cipher encrypts numeral strings of a given radix with FF3-1
+type Cipher struct {
+	block  cipher.Block
+	radix  int
+	minLen int
+	maxLen int
+}
+
+// NewCipher returns a cipher for numeral strings of the given radix under
+// the given AES key, which must be 16, 24 or 32 bytes long
+func NewCipher(key []byte, radix int) (*Cipher, error) {
+	if radix < 2 || radix > maxRadix {
+		return nil, fmt.Errorf("radix must be between 2 and %d", maxRadix)
+	}
+
+	// The key is used in reverse byte order
+	block, err := aes.NewCipher(reverseBytes(key))
+	if err != nil {
+		return nil, err
+	}
+
+	bigRadix := big.NewInt(int64(radix))
+
+	// The shortest length with at least a million possible strings
+	minLen := 2
+	domain := new(big.Int).Exp(bigRadix, big.NewInt(int64(minLen)), nil)
+	for domain.Cmp(big.NewInt(minDomainSize)) < 0 {
+		domain.Mul(domain, bigRadix)
+		minLen++
+	}
+
+	// Each half must fit in the 96 bits of a round input
+	limit := new(big.Int).Lsh(big.NewInt(1), 96)
+	halfLen := 0
+	power := big.NewInt(1)
+	for {
+		power.Mul(power, bigRadix)
+		if power.Cmp(limit) > 0 {
+			break
+		}
+		halfLen++
+	}
+
+	return &Cipher{
+		block:  block,
+		radix:  radix,
+		minLen: minLen,
+		maxLen: 2 * halfLen,
+	}, nil
+}
+
+// MinLength returns the length of the shortest numeral string the cipher
+// accepts
+func (c *Cipher) MinLength() int {
+	return c.minLen
+}
+
+// MaxLength returns the length of the longest numeral string the cipher
+// accepts
+func (c *Cipher) MaxLength() int {
+	return c.maxLen
+}
+
+// Encrypt encrypts the numeral string with the 7-byte tweak
+func (c *Cipher) Encrypt(numerals []int, tweak []byte) ([]int, error) {
+	tl, tr, err := splitTweak(tweak)
+	if err != nil {
+		return nil, err
+	}
+	return c.crypt(numerals, tl, tr, true)
+}
+
+// Decrypt decrypts the numeral string with the 7-byte tweak
+func (c *Cipher) Decrypt(numerals []int, tweak []byte) ([]int, error) {
+	tl, tr, err := splitTweak(tweak)
+	if err != nil {
+		return nil, err
+	}
+	return c.crypt(numerals, tl, tr, false)
+}
+
+// splitTweak returns the left and right halves of the 56-bit tweak, as
+// defined by FF3-1
+func splitTweak(tweak []byte) ([]byte, []byte, error) {
+	if len(tweak) != TweakSize {
+		return nil, nil, fmt.Errorf("tweak must be %d bytes", TweakSize)
+	}
+	tl := []byte{tweak[0], tweak[1], tweak[2], tweak[3] & 0xf0}
+	tr := []byte{tweak[4], tweak[5], tweak[6], tweak[3] << 4}
+	return tl, tr, nil
+}
+
+// crypt runs the Feistel network of FF3 with the given 32-bit tweak halves
+func (c *Cipher) crypt(numerals []int, tl, tr []byte, encrypt bool) ([]int, error) {
+	n := len(numerals)
+	if n < c.minLen || n > c.maxLen {
+		return nil, fmt.Errorf("length must be between %d and %d, not %d", c.minLen, c.maxLen, n)
+	}
+	for _, numeral := range numerals {
+		if numeral < 0 || numeral >= c.radix {
+			return nil, fmt.Errorf("numeral %d is out of radix %d", numeral, c.radix)
+		}
+	}
+
+	u := (n + 1) / 2
+	v := n - u
+	a := append([]int{}, numerals[:u]...)
+	b := append([]int{}, numerals[u:]...)
+
+	bigRadix := big.NewInt(int64(c.radix))
+	modU := new(big.Int).Exp(bigRadix, big.NewInt(int64(u)), nil)
+	modV := new(big.Int).Exp(bigRadix, big.NewInt(int64(v)), nil)
+
+	p := make([]byte, aes.BlockSize)
+	s := make([]byte, aes.BlockSize)
+	for j := 0; j < rounds; j++ {
+		i := j
+		if !encrypt {
+			i = rounds - 1 - j
+		}
+
+		m, mod, w := u, modU, tr
+		if i%2 == 1 {
+			m, mod, w = v, modV, tl
+		}
+
+		// The round function reads the half which is kept
+		half := b
+		if !encrypt {
+			half = a
+		}
+		binary.BigEndian.PutUint32(p[:4], binary.BigEndian.Uint32(w)^uint32(i))
+		for k := range p[4:] {
+			p[4+k] = 0
+		}
+		num := numRev(half, bigRadix).Bytes()
+		copy(p[aes.BlockSize-len(num):], num)
+
+		c.block.Encrypt(s, reverseBytes(p))
+		y := new(big.Int).SetBytes(reverseBytes(s))
+
+		// The other half is combined with the round output
+		if encrypt {
+			y.Add(numRev(a, bigRadix), y)
+		} else {
+			y.Sub(numRev(b, bigRadix), y)
+		}
+		y.Mod(y, mod)
+		result := strRev(y, bigRadix, m)
+
+		if encrypt {
+			a, b = b, result
+		} else {
+			b, a = a, result
+		}
+	}
+
+	return append(a, b...), nil
+}
+
+// numRev returns NUM_radix(REV(x)), the number whose base radix digits are
+// the numerals of x, least significant first
+func numRev(x []int, radix *big.Int) *big.Int {
+	result := new(big.Int)
+	for i := len(x) - 1; i >= 0; i-- {
+		result.Mul(result, radix)
+		result.Add(result, big.NewInt(int64(x[i])))
+	}
+	return result
+}
+
+// strRev returns REV(STR^m_radix(x)), the m base radix digits of x, least
+// significant first
+func strRev(x *big.Int, radix *big.Int, m int) []int {
+	result := make([]int, m)
+	x = new(big.Int).Set(x)
+	digit := new(big.Int)
+	for i := 0; i < m; i++ {
+		x.DivMod(x, radix, digit)
+		result[i] = int(digit.Int64())
+	}
+	return result
+}
+
+func reverseBytes(b []byte) []byte {
+	result := make([]byte, len(b))
+	for i := range b {
+		result[len(b)-1-i] = b[i]
+	}
+	return result
+}
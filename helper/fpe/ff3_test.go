@@ -0,0 +1,127 @@
+package fpe
+
+import (
+	"encoding/hex"
+	"reflect"
+	"testing"
+)
+
+func parseNumerals(s string) []int {
+	result := make([]int, len(s))
+	for i, r := range s {
+		result[i] = int(r - '0')
+	}
+	return result
+}
+
+func TestFF3_Vectors(t *testing.T) {
+	// The first sample of FF3, whose 64-bit tweak sets both halves directly
+	key, _ := hex.DecodeString("EF4359D8D580AA4F7F036D6F04FC6A94")
+	tweak, _ := hex.DecodeString("D8E7920AFA330A73")
+	c, err := NewCipher(key, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext := parseNumerals("890121234567890000")
+	ciphertext := parseNumerals("750918814058654607")
+	result, err := c.crypt(plaintext, tweak[:4], tweak[4:], true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result, ciphertext) {
+		t.Fatalf("bad: %v", result)
+	}
+	result, err = c.crypt(ciphertext, tweak[:4], tweak[4:], false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result, plaintext) {
+		t.Fatalf("bad: %v", result)
+	}
+}
+
+func TestFF3_1(t *testing.T) {
+	key, _ := hex.DecodeString("2DE79D232DF5585D68CE47882AE256D6")
+	tweak, _ := hex.DecodeString("CBD09280979564")
+	c, err := NewCipher(key, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.MinLength() != 6 || c.MaxLength() != 56 {
+		t.Fatalf("bad: min: %d, max: %d", c.MinLength(), c.MaxLength())
+	}
+
+	plaintext := parseNumerals("3992520240")
+	ciphertext, err := c.Encrypt(plaintext, tweak)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ciphertext, parseNumerals("8901801106")) {
+		t.Fatalf("bad: %v", ciphertext)
+	}
+	result, err := c.Decrypt(ciphertext, tweak)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result, plaintext) {
+		t.Fatalf("bad: %v", result)
+	}
+
+	// Other tweaks give other ciphertexts
+	otherTweak := append([]byte{}, tweak...)
+	otherTweak[3] ^= 1
+	if result, _ := c.Encrypt(plaintext, otherTweak); reflect.DeepEqual(result, ciphertext) {
+		t.Fatal("the tweak was ignored")
+	}
+
+	for _, tc := range []struct {
+		numerals []int
+		tweak    []byte
+	}{
+		{parseNumerals("12345"), tweak},
+		{make([]int, 57), tweak},
+		{[]int{1, 2, 3, 4, 5, 10}, tweak},
+		{plaintext, tweak[:6]},
+	} {
+		if _, err := c.Encrypt(tc.numerals, tc.tweak); err == nil {
+			t.Fatalf("expected an error: numerals: %v, tweak: %x", tc.numerals, tc.tweak)
+		}
+	}
+}
+
+func TestFF3_1_Radix(t *testing.T) {
+	key := make([]byte, 32)
+	tweak := make([]byte, TweakSize)
+	for _, radix := range []int{2, 26, 36, 62, 1 << 16} {
+		c, err := NewCipher(key, radix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, n := range []int{c.MinLength(), c.MinLength() + 1, c.MaxLength()} {
+			plaintext := make([]int, n)
+			for i := range plaintext {
+				plaintext[i] = (i * 7) % radix
+			}
+			ciphertext, err := c.Encrypt(plaintext, tweak)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(ciphertext) != n || reflect.DeepEqual(ciphertext, plaintext) {
+				t.Fatalf("bad: radix: %d, ciphertext: %v", radix, ciphertext)
+			}
+			result, err := c.Decrypt(ciphertext, tweak)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(result, plaintext) {
+				t.Fatalf("bad: radix: %d, result: %v", radix, result)
+			}
+		}
+	}
+
+	for _, radix := range []int{1, 1<<16 + 1} {
+		if _, err := NewCipher(key, radix); err == nil {
+			t.Fatalf("expected an error: radix: %d", radix)
+		}
+	}
+}
@@ -0,0 +1,276 @@
+package identity
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrUnbalancedTemplatingCharacter = errors.New("unbalanced templating characters")
+	ErrNoEntityAttachedToToken       = errors.New("string contains entity template directives but no entity was provided")
+	ErrNoGroupsAttachedToToken       = errors.New("string contains groups template directives but no groups were provided")
+	ErrTemplateValueNotFound         = errors.New("no value could be found for one of the template directives")
+)
+
+const (
+	// ACLTemplating substitutes the directives with their raw values and
+	// fails if a value is not found
+	ACLTemplating = iota
+
+	// JSONTemplating substitutes the directives with their JSON encoded
+	// values, rendering values that are not found as empty strings. List and
+	// map values, such as the group names of the entity, are only available
+	// in this mode.
+	JSONTemplating
+)
+
+// PopulateStringInput is the input of PopulateString
+type PopulateStringInput struct {
+	// Mode is either ACLTemplating or JSONTemplating
+	Mode int
+
+	// String is the input string containing the template directives
+	String string
+
+	// Entity is the entity the directives are populated from
+	Entity *Entity
+
+	// Groups are the groups of the entity, including the inherited groups
+	Groups []*Group
+
+	// ValidityCheckOnly only checks that the templating characters are
+	// balanced, without populating the directives
+	ValidityCheckOnly bool
+}
+
+// PopulateString replaces the {{identity.*}} template directives of the
+// input string with the values of the entity and groups. It returns whether
+// any directive was found.
+//
+// The supported directives are:
+//
+//	identity.entity.id
+//	identity.entity.name
+//	identity.entity.metadata                            (JSON only)
+//	identity.entity.metadata.<key>
+//	identity.entity.aliases.<mount accessor>.id
+//	identity.entity.aliases.<mount accessor>.name
+//	identity.entity.aliases.<mount accessor>.metadata.<key>
+//	identity.entity.groups.ids                          (JSON only)
+//	identity.entity.groups.names                        (JSON only)
+//	identity.groups.ids.<group id>.name
+//	identity.groups.ids.<group id>.metadata.<key>
+//	identity.groups.names.<group name>.id
+//	identity.groups.names.<group name>.metadata.<key>
+func PopulateString(p *PopulateStringInput) (bool, string, error) {
+	if p == nil {
+		return false, "", errors.New("nil input")
+	}
+
+	if p.String == "" {
+		return false, "", nil
+	}
+
+	var subst bool
+	splitStr := strings.Split(p.String, "{{")
+
+	if len(splitStr) == 1 {
+		if strings.Contains(p.String, "}}") {
+			return false, "", ErrUnbalancedTemplatingCharacter
+		}
+		return false, p.String, nil
+	}
+
+	var b strings.Builder
+	b.Grow(2 * len(p.String))
+
+	for i, str := range splitStr {
+		if i == 0 {
+			if strings.Contains(str, "}}") {
+				return false, "", ErrUnbalancedTemplatingCharacter
+			}
+			b.WriteString(str)
+			continue
+		}
+		splitPiece := strings.Split(str, "}}")
+		switch len(splitPiece) {
+		case 2:
+			subst = true
+			if p.ValidityCheckOnly {
+				continue
+			}
+			tmplStr, err := performTemplating(p, strings.TrimSpace(splitPiece[0]))
+			if err != nil {
+				return false, "", err
+			}
+			b.WriteString(tmplStr)
+			b.WriteString(splitPiece[1])
+		default:
+			return false, "", ErrUnbalancedTemplatingCharacter
+		}
+	}
+
+	return subst, b.String(), nil
+}
+
+func performTemplating(p *PopulateStringInput, input string) (string, error) {
+	// format returns the value in the output format of the mode
+	format := func(value interface{}, found bool) (string, error) {
+		if p.Mode == JSONTemplating {
+			if !found {
+				value = ""
+			}
+			out, err := json.Marshal(value)
+			if err != nil {
+				return "", err
+			}
+			return string(out), nil
+		}
+
+		if !found {
+			return "", ErrTemplateValueNotFound
+		}
+		str, ok := value.(string)
+		if !ok {
+			return "", fmt.Errorf("template directive %q is only supported by JSON templates", input)
+		}
+		return str, nil
+	}
+
+	metadataValue := func(metadata map[string]string, key string) (string, error) {
+		value, ok := metadata[key]
+		return format(value, ok)
+	}
+
+	switch {
+	case strings.HasPrefix(input, "identity.entity."):
+		if p.Entity == nil {
+			return "", ErrNoEntityAttachedToToken
+		}
+		return performEntityTemplating(p.Entity, p.Groups, strings.TrimPrefix(input, "identity.entity."), format, metadataValue)
+
+	case strings.HasPrefix(input, "identity.groups."):
+		if len(p.Groups) == 0 && p.Mode != JSONTemplating {
+			return "", ErrNoGroupsAttachedToToken
+		}
+		return performGroupsTemplating(p.Groups, strings.TrimPrefix(input, "identity.groups."), format, metadataValue)
+	}
+
+	return "", ErrTemplateValueNotFound
+}
+
+func performEntityTemplating(entity *Entity, groups []*Group, trimmed string, format func(interface{}, bool) (string, error), metadataValue func(map[string]string, string) (string, error)) (string, error) {
+	switch {
+	case trimmed == "id":
+		return format(entity.ID, true)
+
+	case trimmed == "name":
+		return format(entity.Name, entity.Name != "")
+
+	case trimmed == "metadata":
+		metadata := entity.Metadata
+		if metadata == nil {
+			metadata = map[string]string{}
+		}
+		return format(metadata, true)
+
+	case strings.HasPrefix(trimmed, "metadata."):
+		return metadataValue(entity.Metadata, strings.TrimPrefix(trimmed, "metadata."))
+
+	case trimmed == "groups.ids", trimmed == "groups.names":
+		values := make([]string, 0, len(groups))
+		for _, group := range groups {
+			if trimmed == "groups.ids" {
+				values = append(values, group.ID)
+			} else {
+				values = append(values, group.Name)
+			}
+		}
+		sort.Strings(values)
+		return format(values, true)
+
+	case strings.HasPrefix(trimmed, "aliases."):
+		split := strings.SplitN(strings.TrimPrefix(trimmed, "aliases."), ".", 2)
+		if len(split) != 2 {
+			return "", errors.New("invalid alias selector")
+		}
+		var found *Alias
+		for _, alias := range entity.Aliases {
+			if split[0] == alias.MountAccessor {
+				found = alias
+				break
+			}
+		}
+		switch {
+		case split[1] == "id":
+			if found == nil {
+				return format("", false)
+			}
+			return format(found.ID, true)
+		case split[1] == "name":
+			if found == nil {
+				return format("", false)
+			}
+			return format(found.Name, true)
+		case strings.HasPrefix(split[1], "metadata."):
+			var metadata map[string]string
+			if found != nil {
+				metadata = found.Metadata
+			}
+			return metadataValue(metadata, strings.TrimPrefix(split[1], "metadata."))
+		}
+		return "", errors.New("invalid alias selector")
+	}
+
+	return "", ErrTemplateValueNotFound
+}
+
+func performGroupsTemplating(groups []*Group, trimmed string, format func(interface{}, bool) (string, error), metadataValue func(map[string]string, string) (string, error)) (string, error) {
+	var ids bool
+	selectorSplit := strings.SplitN(trimmed, ".", 2)
+	switch {
+	case len(selectorSplit) != 2:
+		return "", errors.New("invalid groups selector")
+	case selectorSplit[0] == "ids":
+		ids = true
+	case selectorSplit[0] == "names":
+	default:
+		return "", errors.New("invalid groups selector")
+	}
+	trimmed = selectorSplit[1]
+
+	accessorSplit := strings.SplitN(trimmed, ".", 2)
+	if len(accessorSplit) != 2 {
+		return "", errors.New("invalid groups accessor")
+	}
+
+	var found *Group
+	for _, group := range groups {
+		compare := group.Name
+		if ids {
+			compare = group.ID
+		}
+		if compare == accessorSplit[0] {
+			found = group
+			break
+		}
+	}
+	if found == nil {
+		return format("", false)
+	}
+
+	trimmed = accessorSplit[1]
+	switch {
+	case ids && trimmed == "name":
+		return format(found.Name, true)
+	case !ids && trimmed == "id":
+		return format(found.ID, true)
+	case strings.HasPrefix(trimmed, "metadata."):
+		return metadataValue(found.Metadata, strings.TrimPrefix(trimmed, "metadata."))
+	}
+
+	return "", ErrTemplateValueNotFound
+}
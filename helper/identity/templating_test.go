@@ -0,0 +1,145 @@
+package identity
+
+import (
+	"errors"
+	"testing"
+)
+
+// errAny matches any error
+var errAny = errors.New("any error")
+
+func TestPopulate_Basic(t *testing.T) {
+	entity := &Entity{
+		ID:   "entityID",
+		Name: "entityName",
+		Metadata: map[string]string{
+			"color": "green",
+		},
+		Aliases: []*Alias{
+			{
+				ID:            "aliasID",
+				MountAccessor: "auth_userpass_1234",
+				Name:          "aliasName",
+				Metadata: map[string]string{
+					"team": "infra",
+				},
+			},
+		},
+	}
+	groups := []*Group{
+		{
+			ID:   "groupID2",
+			Name: "groupName2",
+			Metadata: map[string]string{
+				"region": "west",
+			},
+		},
+		{
+			ID:   "groupID1",
+			Name: "groupName1",
+		},
+	}
+
+	tests := []struct {
+		name   string
+		mode   int
+		input  string
+		output string
+		subst  bool
+		err    error
+	}{
+		{name: "no_templating", input: "path foobar {", output: "path foobar {"},
+		{name: "only_closing", input: "path foobar}} {", err: ErrUnbalancedTemplatingCharacter},
+		{name: "closing_in_front", input: "path }} {{foobar}} {", err: ErrUnbalancedTemplatingCharacter},
+		{name: "closing_in_back", input: "path {{foobar}} }}", err: ErrUnbalancedTemplatingCharacter},
+		{name: "entity_id", input: "path {{identity.entity.id}} {", output: "path entityID {", subst: true},
+		{name: "entity_name", input: "path {{ identity.entity.name }} {", output: "path entityName {", subst: true},
+		{name: "entity_metadata", input: "{{identity.entity.metadata.color}}", output: "green", subst: true},
+		{name: "entity_metadata_missing", input: "{{identity.entity.metadata.size}}", err: ErrTemplateValueNotFound},
+		{name: "alias_name", input: "{{identity.entity.aliases.auth_userpass_1234.name}}", output: "aliasName", subst: true},
+		{name: "alias_id", input: "{{identity.entity.aliases.auth_userpass_1234.id}}", output: "aliasID", subst: true},
+		{name: "alias_metadata", input: "{{identity.entity.aliases.auth_userpass_1234.metadata.team}}", output: "infra", subst: true},
+		{name: "alias_missing", input: "{{identity.entity.aliases.auth_userpass_5678.name}}", err: ErrTemplateValueNotFound},
+		{name: "group_by_id", input: "{{identity.groups.ids.groupID1.name}}", output: "groupName1", subst: true},
+		{name: "group_by_name", input: "{{identity.groups.names.groupName2.id}}", output: "groupID2", subst: true},
+		{name: "group_metadata", input: "{{identity.groups.names.groupName2.metadata.region}}", output: "west", subst: true},
+		{name: "entity_groups_acl", input: "{{identity.entity.groups.names}}", err: errAny},
+		{name: "unknown", input: "{{identity.foo}}", err: ErrTemplateValueNotFound},
+		{name: "json_string", mode: JSONTemplating, input: `{"color": {{identity.entity.metadata.color}}}`, output: `{"color": "green"}`, subst: true},
+		{name: "json_missing", mode: JSONTemplating, input: `{"size": {{identity.entity.metadata.size}}}`, output: `{"size": ""}`, subst: true},
+		{name: "json_metadata", mode: JSONTemplating, input: `{{identity.entity.metadata}}`, output: `{"color":"green"}`, subst: true},
+		{name: "json_group_names", mode: JSONTemplating, input: `{{identity.entity.groups.names}}`, output: `["groupName1","groupName2"]`, subst: true},
+		{name: "json_group_ids", mode: JSONTemplating, input: `{{identity.entity.groups.ids}}`, output: `["groupID1","groupID2"]`, subst: true},
+	}
+
+	for _, test := range tests {
+		subst, out, err := PopulateString(&PopulateStringInput{
+			Mode:   test.mode,
+			String: test.input,
+			Entity: entity,
+			Groups: groups,
+		})
+		switch {
+		case test.err == errAny:
+			if err == nil {
+				t.Fatalf("%s: expected an error", test.name)
+			}
+			continue
+		case err != test.err:
+			t.Fatalf("%s: expected error %v, got %v", test.name, test.err, err)
+		}
+		if subst != test.subst {
+			t.Fatalf("%s: expected substitution %t, got %t", test.name, test.subst, subst)
+		}
+		if out != test.output {
+			t.Fatalf("%s: expected %q, got %q", test.name, test.output, out)
+		}
+	}
+}
+
+func TestPopulate_NoEntityOrGroups(t *testing.T) {
+	if _, _, err := PopulateString(&PopulateStringInput{String: "{{identity.entity.id}}"}); err != ErrNoEntityAttachedToToken {
+		t.Fatalf("expected ErrNoEntityAttachedToToken, got %v", err)
+	}
+
+	if _, _, err := PopulateString(&PopulateStringInput{
+		String: "{{identity.groups.ids.groupID.name}}",
+		Entity: &Entity{},
+	}); err != ErrNoGroupsAttachedToToken {
+		t.Fatalf("expected ErrNoGroupsAttachedToToken, got %v", err)
+	}
+
+	_, out, err := PopulateString(&PopulateStringInput{
+		Mode:   JSONTemplating,
+		String: "{{identity.groups.ids.groupID.name}}",
+		Entity: &Entity{},
+	})
+	if err != nil || out != `""` {
+		t.Fatalf("bad: %q, %v", out, err)
+	}
+}
+
+func TestPopulate_ValidityCheckOnly(t *testing.T) {
+	subst, _, err := PopulateString(&PopulateStringInput{
+		ValidityCheckOnly: true,
+		String:            "secret/{{identity.entity.name}}/*",
+	})
+	if err != nil || !subst {
+		t.Fatalf("bad: %v, %v", subst, err)
+	}
+
+	subst, _, err = PopulateString(&PopulateStringInput{
+		ValidityCheckOnly: true,
+		String:            "secret/*",
+	})
+	if err != nil || subst {
+		t.Fatalf("bad: %v, %v", subst, err)
+	}
+
+	if _, _, err := PopulateString(&PopulateStringInput{
+		ValidityCheckOnly: true,
+		String:            "secret/{{identity.entity.name/*",
+	}); err != ErrUnbalancedTemplatingCharacter {
+		t.Fatalf("expected ErrUnbalancedTemplatingCharacter, got %v", err)
+	}
+}
@@ -0,0 +1,245 @@
+package keysutil
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/bits"
+
+	"golang.org/x/crypto/poly1305"
+)
+
+var errOpen = errors.New("message authentication failed")
+
+// newAEAD returns the AEAD of the symmetric key type with the given key
+func (kt KeyType) newAEAD(key []byte) (cipher.AEAD, error) {
+	switch kt {
+	case KeyType_AES256_GCM96:
+		aesCipher, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		return cipher.NewGCM(aesCipher)
+	case KeyType_ChaCha20_Poly1305:
+		return newChaCha20Poly1305(key)
+	case KeyType_AES256_CBC_HMAC_SHA512:
+		return newAESCBCHMAC(key)
+	default:
+		return nil, fmt.Errorf("unsupported key type %v", kt)
+	}
+}
+
+// chacha20Poly1305 is the ChaCha20-Poly1305 AEAD of RFC 8439
+type chacha20Poly1305 struct {
+	key [32]byte
+}
+
+func newChaCha20Poly1305(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, errors.New("chacha20-poly1305 keys must be 32 bytes")
+	}
+	c := &chacha20Poly1305{}
+	copy(c.key[:], key)
+	return c, nil
+}
+
+func (c *chacha20Poly1305) NonceSize() int {
+	return 12
+}
+
+func (c *chacha20Poly1305) Overhead() int {
+	return poly1305.TagSize
+}
+
+// chacha20Block returns the keystream block of the counter
+func (c *chacha20Poly1305) chacha20Block(counter uint32, nonce []byte) [64]byte {
+	var state [16]uint32
+	state[0], state[1], state[2], state[3] = 0x61707865, 0x3320646e, 0x79622d32, 0x6b206574
+	for i := 0; i < 8; i++ {
+		state[4+i] = binary.LittleEndian.Uint32(c.key[4*i:])
+	}
+	state[12] = counter
+	for i := 0; i < 3; i++ {
+		state[13+i] = binary.LittleEndian.Uint32(nonce[4*i:])
+	}
+
+	x := state
+	quarterRound := func(a, b, c, d int) {
+		x[a] += x[b]
+		x[d] = bits.RotateLeft32(x[d]^x[a], 16)
+		x[c] += x[d]
+		x[b] = bits.RotateLeft32(x[b]^x[c], 12)
+		x[a] += x[b]
+		x[d] = bits.RotateLeft32(x[d]^x[a], 8)
+		x[c] += x[d]
+		x[b] = bits.RotateLeft32(x[b]^x[c], 7)
+	}
+	for i := 0; i < 10; i++ {
+		quarterRound(0, 4, 8, 12)
+		quarterRound(1, 5, 9, 13)
+		quarterRound(2, 6, 10, 14)
+		quarterRound(3, 7, 11, 15)
+		quarterRound(0, 5, 10, 15)
+		quarterRound(1, 6, 11, 12)
+		quarterRound(2, 7, 8, 13)
+		quarterRound(3, 4, 9, 14)
+	}
+
+	var block [64]byte
+	for i := range x {
+		binary.LittleEndian.PutUint32(block[4*i:], x[i]+state[i])
+	}
+	return block
+}
+
+// xorKeyStream encrypts src into dst with the keystream starting at block 1
+func (c *chacha20Poly1305) xorKeyStream(dst, src, nonce []byte) {
+	for i, counter := 0, uint32(1); i < len(src); i, counter = i+64, counter+1 {
+		block := c.chacha20Block(counter, nonce)
+		for j := 0; j < 64 && i+j < len(src); j++ {
+			dst[i+j] = src[i+j] ^ block[j]
+		}
+	}
+}
+
+// tag returns the Poly1305 tag of the additional data and ciphertext
+func (c *chacha20Poly1305) tag(nonce, ciphertext, additionalData []byte) [16]byte {
+	block := c.chacha20Block(0, nonce)
+	var polyKey [32]byte
+	copy(polyKey[:], block[:32])
+
+	pad := func(b []byte) []byte {
+		if len(b)%16 == 0 {
+			return b
+		}
+		return append(b, make([]byte, 16-len(b)%16)...)
+	}
+	macData := pad(append([]byte{}, additionalData...))
+	macData = pad(append(macData, ciphertext...))
+	var lengths [16]byte
+	binary.LittleEndian.PutUint64(lengths[:8], uint64(len(additionalData)))
+	binary.LittleEndian.PutUint64(lengths[8:], uint64(len(ciphertext)))
+	macData = append(macData, lengths[:]...)
+
+	var tag [16]byte
+	poly1305.Sum(&tag, macData, &polyKey)
+	return tag
+}
+
+func (c *chacha20Poly1305) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if len(nonce) != c.NonceSize() {
+		panic("chacha20-poly1305: bad nonce length")
+	}
+	ciphertext := make([]byte, len(plaintext))
+	c.xorKeyStream(ciphertext, plaintext, nonce)
+	tag := c.tag(nonce, ciphertext, additionalData)
+	return append(append(dst, ciphertext...), tag[:]...)
+}
+
+func (c *chacha20Poly1305) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(nonce) != c.NonceSize() {
+		return nil, errors.New("chacha20-poly1305: bad nonce length")
+	}
+	if len(ciphertext) < poly1305.TagSize {
+		return nil, errOpen
+	}
+	tag := ciphertext[len(ciphertext)-poly1305.TagSize:]
+	ciphertext = ciphertext[:len(ciphertext)-poly1305.TagSize]
+	expected := c.tag(nonce, ciphertext, additionalData)
+	if subtle.ConstantTimeCompare(tag, expected[:]) != 1 {
+		return nil, errOpen
+	}
+	plaintext := make([]byte, len(ciphertext))
+	c.xorKeyStream(plaintext, ciphertext, nonce)
+	return append(dst, plaintext...), nil
+}
+
+// aesCBCHMAC is the AEAD_AES_256_CBC_HMAC_SHA_512 construction of
+// draft-mcgrew-aead-aes-cbc-hmac-sha2, also used by A256CBC-HS512 of
+// RFC 7518. The nonce is the CBC IV, which is not part of the ciphertext.
+type aesCBCHMAC struct {
+	macKey []byte
+	block  cipher.Block
+}
+
+func newAESCBCHMAC(key []byte) (cipher.AEAD, error) {
+	if len(key) != 64 {
+		return nil, errors.New("aes256-cbc-hmac-sha512 keys must be 64 bytes")
+	}
+	block, err := aes.NewCipher(key[32:])
+	if err != nil {
+		return nil, err
+	}
+	return &aesCBCHMAC{
+		macKey: append([]byte{}, key[:32]...),
+		block:  block,
+	}, nil
+}
+
+func (c *aesCBCHMAC) NonceSize() int {
+	return aes.BlockSize
+}
+
+func (c *aesCBCHMAC) Overhead() int {
+	return aes.BlockSize + 32
+}
+
+// tag returns the truncated HMAC of the additional data, IV and ciphertext
+func (c *aesCBCHMAC) tag(nonce, ciphertext, additionalData []byte) []byte {
+	mac := hmac.New(sha512.New, c.macKey)
+	mac.Write(additionalData)
+	mac.Write(nonce)
+	mac.Write(ciphertext)
+	var al [8]byte
+	binary.BigEndian.PutUint64(al[:], uint64(len(additionalData))*8)
+	mac.Write(al[:])
+	return mac.Sum(nil)[:32]
+}
+
+func (c *aesCBCHMAC) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if len(nonce) != c.NonceSize() {
+		panic("aes256-cbc-hmac-sha512: bad nonce length")
+	}
+
+	// PKCS#7 padding
+	padding := aes.BlockSize - len(plaintext)%aes.BlockSize
+	ciphertext := append([]byte{}, plaintext...)
+	for i := 0; i < padding; i++ {
+		ciphertext = append(ciphertext, byte(padding))
+	}
+	cipher.NewCBCEncrypter(c.block, nonce).CryptBlocks(ciphertext, ciphertext)
+
+	return append(append(dst, ciphertext...), c.tag(nonce, ciphertext, additionalData)...)
+}
+
+func (c *aesCBCHMAC) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(nonce) != c.NonceSize() {
+		return nil, errors.New("aes256-cbc-hmac-sha512: bad nonce length")
+	}
+	if len(ciphertext) < c.Overhead() || (len(ciphertext)-32)%aes.BlockSize != 0 {
+		return nil, errOpen
+	}
+	tag := ciphertext[len(ciphertext)-32:]
+	ciphertext = ciphertext[:len(ciphertext)-32]
+	if subtle.ConstantTimeCompare(tag, c.tag(nonce, ciphertext, additionalData)) != 1 {
+		return nil, errOpen
+	}
+
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(c.block, nonce).CryptBlocks(plaintext, ciphertext)
+	padding := int(plaintext[len(plaintext)-1])
+	if padding == 0 || padding > aes.BlockSize {
+		return nil, errOpen
+	}
+	for _, b := range plaintext[len(plaintext)-padding:] {
+		if int(b) != padding {
+			return nil, errOpen
+		}
+	}
+	return append(dst, plaintext[:len(plaintext)-padding]...), nil
+}
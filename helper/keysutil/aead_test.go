@@ -0,0 +1,91 @@
+package keysutil
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func decodeHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(strings.Replace(s, " ", "", -1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestChaCha20Poly1305(t *testing.T) {
+	// The AEAD test vector of RFC 8439, section 2.8.2
+	key := decodeHex(t, "808182838485868788898a8b8c8d8e8f909192939495969798999a9b9c9d9e9f")
+	nonce := decodeHex(t, "070000004041424344454647")
+	aad := decodeHex(t, "50515253c0c1c2c3c4c5c6c7")
+	plaintext := []byte("Ladies and Gentlemen of the class of '99: If I could offer you only one tip for the future, sunscreen would be it.")
+
+	aead, err := newChaCha20Poly1305(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed := aead.Seal(nil, nonce, plaintext, aad)
+	if len(sealed) != len(plaintext)+aead.Overhead() {
+		t.Fatalf("bad: length: %d", len(sealed))
+	}
+	if !bytes.HasPrefix(sealed, decodeHex(t, "d31a8d34648e60db7b86afbc53ef7ec2")) {
+		t.Fatalf("bad: ciphertext: %x", sealed)
+	}
+	if tag := sealed[len(plaintext):]; !bytes.Equal(tag, decodeHex(t, "1ae10b594f09e26a7e902ecbd0600691")) {
+		t.Fatalf("bad: tag: %x", tag)
+	}
+
+	opened, err := aead.Open(nil, nonce, sealed, aad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(opened, plaintext) {
+		t.Fatalf("bad: %q", opened)
+	}
+
+	sealed[0] ^= 1
+	if _, err := aead.Open(nil, nonce, sealed, aad); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestAESCBCHMAC(t *testing.T) {
+	// The A256CBC-HS512 test case of RFC 7518, appendix B.3
+	key := decodeHex(t, "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"+
+		"202122232425262728292a2b2c2d2e2f303132333435363738393a3b3c3d3e3f")
+	nonce := decodeHex(t, "1af38c2dc2b96ffdd86694092341bc04")
+	aad := []byte("The second principle of Auguste Kerckhoffs")
+	plaintext := []byte("A cipher system must not be required to be secret, and it must be able to fall into the hands of the enemy without inconvenience")
+
+	aead, err := newAESCBCHMAC(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed := aead.Seal(nil, nonce, plaintext, aad)
+	if tag := sealed[len(sealed)-32:]; !bytes.Equal(tag, decodeHex(t, "4dd3b4c088a7f45c216839645b2012bf2e6269a8c56a816dbc1b267761955bc5")) {
+		t.Fatalf("bad: tag: %x", tag)
+	}
+
+	opened, err := aead.Open(nil, nonce, sealed, aad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(opened, plaintext) {
+		t.Fatalf("bad: %q", opened)
+	}
+
+	for _, tampered := range [][]byte{
+		append([]byte{sealed[0] ^ 1}, sealed[1:]...),
+		sealed[:len(sealed)-1],
+		sealed[16:],
+	} {
+		if _, err := aead.Open(nil, nonce, tampered, aad); err == nil {
+			t.Fatal("expected an error")
+		}
+	}
+	if _, err := aead.Open(nil, nonce, sealed, nil); err == nil {
+		t.Fatal("expected an error")
+	}
+}
@@ -0,0 +1,87 @@
+package keysutil
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/golang-lru"
+)
+
+// MinCacheSize is the smallest size of a bounded policy cache
+const MinCacheSize = 10
+
+// Cache is the in-memory cache of the decoded policies of a LockManager
+type Cache interface {
+	Get(name string) *Policy
+	Set(name string, p *Policy)
+	Delete(name string)
+
+	// Size returns the maximum number of policies of the cache, zero if it
+	// is unbounded
+	Size() int
+}
+
+// mapCache is the unbounded policy cache
+type mapCache map[string]*Policy
+
+func (c mapCache) Get(name string) *Policy {
+	return c[name]
+}
+
+func (c mapCache) Set(name string, p *Policy) {
+	c[name] = p
+}
+
+func (c mapCache) Delete(name string) {
+	delete(c, name)
+}
+
+func (c mapCache) Size() int {
+	return 0
+}
+
+// lruCache is the policy cache evicting the least recently used policies
+// past its size
+type lruCache struct {
+	lru  *lru.Cache
+	size int
+}
+
+func (c *lruCache) Get(name string) *Policy {
+	p, ok := c.lru.Get(name)
+	if !ok {
+		return nil
+	}
+	return p.(*Policy)
+}
+
+func (c *lruCache) Set(name string, p *Policy) {
+	c.lru.Add(name, p)
+}
+
+func (c *lruCache) Delete(name string) {
+	c.lru.Remove(name)
+}
+
+func (c *lruCache) Size() int {
+	return c.size
+}
+
+// NewCache returns a policy cache holding at most size policies, or any
+// number of them if size is zero
+func NewCache(size int) (Cache, error) {
+	switch {
+	case size == 0:
+		return mapCache{}, nil
+	case size < MinCacheSize:
+		return nil, fmt.Errorf("cache size must be zero or at least %d", MinCacheSize)
+	}
+
+	cache, err := lru.New(size)
+	if err != nil {
+		return nil, err
+	}
+	return &lruCache{
+		lru:  cache,
+		size: size,
+	}, nil
+}
@@ -0,0 +1,107 @@
+package keysutil
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestNewCache(t *testing.T) {
+	for _, size := range []int{-1, 1, MinCacheSize - 1} {
+		if _, err := NewCache(size); err == nil {
+			t.Fatalf("expected error for size %d", size)
+		}
+	}
+
+	cache, err := NewCache(MinCacheSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cache.Size() != MinCacheSize {
+		t.Fatalf("bad: %d", cache.Size())
+	}
+
+	// The least recently used policies are evicted past the size
+	for i := 0; i < MinCacheSize; i++ {
+		name := fmt.Sprintf("test%d", i)
+		cache.Set(name, &Policy{Name: name})
+	}
+	if cache.Get("test0") == nil {
+		t.Fatal("expected cached policy")
+	}
+	cache.Set("new", &Policy{Name: "new"})
+	if cache.Get("test1") != nil {
+		t.Fatal("expected least recently used policy to be evicted")
+	}
+	if cache.Get("test0") == nil || cache.Get("new") == nil {
+		t.Fatal("expected cached policies")
+	}
+	cache.Delete("new")
+	if cache.Get("new") != nil {
+		t.Fatal("expected deleted policy")
+	}
+}
+
+func TestLockManager_ConfigureCache(t *testing.T) {
+	ctx := context.Background()
+	storage := &logical.InmemStorage{}
+	lm := NewLockManager(false)
+
+	getPolicy := func(name string) *Policy {
+		p, lock, _, err := lm.GetPolicyUpsert(ctx, PolicyRequest{
+			Storage: storage,
+			KeyType: KeyType_AES256_GCM96,
+			Name:    name,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		lock.RUnlock()
+		return p
+	}
+
+	p := getPolicy("test")
+	if getPolicy("test") != p {
+		t.Fatal("expected cached policy")
+	}
+
+	// Configuring the cache empties it
+	if err := lm.ConfigureCache(false, MinCacheSize); err != nil {
+		t.Fatal(err)
+	}
+	if lm.CacheSize() != MinCacheSize {
+		t.Fatalf("bad: %d", lm.CacheSize())
+	}
+	if lm.cachedPolicy("test") != nil {
+		t.Fatal("expected empty cache")
+	}
+	p = getPolicy("test")
+	if getPolicy("test") != p {
+		t.Fatal("expected cached policy")
+	}
+
+	if err := lm.ConfigureCache(false, 1); err == nil {
+		t.Fatal("expected error")
+	}
+
+	if err := lm.ConfigureCache(true, 0); err != nil {
+		t.Fatal(err)
+	}
+	if lm.CacheActive() {
+		t.Fatal("expected disabled cache")
+	}
+	if getPolicy("test") == getPolicy("test") {
+		t.Fatal("expected policies to be read from storage")
+	}
+
+	// The cache cannot be enabled if caching is disabled for the system
+	lm = NewLockManager(true)
+	if err := lm.ConfigureCache(false, 0); err != nil {
+		t.Fatal(err)
+	}
+	if lm.CacheActive() {
+		t.Fatal("expected disabled cache")
+	}
+}
@@ -2,13 +2,17 @@ package keysutil
 
 import (
 	"context"
+	"crypto/rand"
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"io"
 	"sync"
 	"time"
 
+	"github.com/hashicorp/vault/helper/errutil"
 	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/helper/strutil"
 	"github.com/hashicorp/vault/logical"
 )
 
@@ -39,14 +43,104 @@ type PolicyRequest struct {
 	// Whether to enable convergent encryption
 	Convergent bool
 
+	// The version of convergent encryption, defaulting to 2
+	ConvergentVersion int
+
 	// Whether to allow export
 	Exportable bool
 
+	// Whether to only allow export wrapped under a public key
+	WrappedExportOnly bool
+
 	// Whether to upsert
 	Upsert bool
 
 	// Whether to allow plaintext backup
 	AllowPlaintextBackup bool
+
+	// Whether to allow rotating an imported key
+	AllowImportedKeyRotation bool
+
+	// The period after which the key is rotated automatically
+	AutoRotatePeriod time.Duration
+
+	// The operations the key can be used for, or all of them if empty
+	AllowedOperations []string
+
+	// The source of the random data of the generated key, crypto/rand if nil
+	RandReader io.Reader
+}
+
+// validate checks that the options of the request are supported by its key
+// type
+func (req PolicyRequest) validate() error {
+	switch req.KeyType {
+	case KeyType_AES256_GCM96, KeyType_ChaCha20_Poly1305, KeyType_AES256_CBC_HMAC_SHA512:
+		if req.Convergent && !req.Derived {
+			return fmt.Errorf("convergent encryption requires derivation to be enabled")
+		}
+
+	case KeyType_ECDSA_P256:
+		if req.Derived || req.Convergent {
+			return fmt.Errorf("key derivation and convergent encryption not supported for keys of type %v", req.KeyType)
+		}
+
+	case KeyType_ED25519:
+		if req.Convergent {
+			return fmt.Errorf("convergent encryption not supported for keys of type %v", req.KeyType)
+		}
+
+	case KeyType_RSA2048, KeyType_RSA4096:
+		if req.Derived || req.Convergent {
+			return fmt.Errorf("key derivation and convergent encryption not supported for keys of type %v", req.KeyType)
+		}
+
+	default:
+		return fmt.Errorf("unsupported key type %v", req.KeyType)
+	}
+
+	switch req.ConvergentVersion {
+	case 0:
+	case 2, 3:
+		if !req.Convergent {
+			return fmt.Errorf("a convergent encryption version requires convergent encryption to be enabled")
+		}
+	default:
+		return fmt.Errorf("unsupported convergent encryption version %d", req.ConvergentVersion)
+	}
+
+	if req.WrappedExportOnly && !req.Exportable {
+		return fmt.Errorf("wrapped-only export requires the key to be exportable")
+	}
+
+	if err := ValidateKeyOperations(req.AllowedOperations); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// newPolicy returns a policy with the options of the request, without keys
+func (req PolicyRequest) newPolicy() *Policy {
+	p := &Policy{
+		Name:                 req.Name,
+		Type:                 req.KeyType,
+		Derived:              req.Derived,
+		Exportable:           req.Exportable,
+		WrappedExportOnly:    req.WrappedExportOnly,
+		AllowPlaintextBackup: req.AllowPlaintextBackup,
+		AutoRotatePeriod:     req.AutoRotatePeriod,
+		AllowedOperations:    strutil.RemoveDuplicates(req.AllowedOperations, false),
+	}
+	if req.Derived {
+		p.KDF = Kdf_hkdf_sha256
+		p.ConvergentEncryption = req.Convergent
+		p.ConvergentVersion = 2
+		if req.ConvergentVersion != 0 {
+			p.ConvergentVersion = req.ConvergentVersion
+		}
+	}
+	return p
 }
 
 type LockManager struct {
@@ -56,36 +150,100 @@ type LockManager struct {
 	// A mutex for the map itself
 	locksMutex sync.RWMutex
 
-	// If caching is enabled, the map of name to in-memory policy cache
-	cache map[string]*Policy
+	// If caching is enabled, the in-memory policy cache
+	cache Cache
 
-	// Used for global locking, and as the cache map mutex
+	// Whether caching is disabled for the whole system, in which case the
+	// cache cannot be enabled by its configuration
+	cacheDisabled bool
+
+	// Used for global locking, and as the cache mutex
 	cacheMutex sync.RWMutex
 }
 
 func NewLockManager(cacheDisabled bool) *LockManager {
 	lm := &LockManager{
-		locks: map[string]*sync.RWMutex{},
+		locks:         map[string]*sync.RWMutex{},
+		cacheDisabled: cacheDisabled,
 	}
 	if !cacheDisabled {
-		lm.cache = map[string]*Policy{}
+		lm.cache = mapCache{}
 	}
 	return lm
 }
 
 func (lm *LockManager) CacheActive() bool {
+	lm.cacheMutex.RLock()
+	defer lm.cacheMutex.RUnlock()
 	return lm.cache != nil
 }
 
+// CacheSize returns the maximum number of policies of the cache, zero if it
+// is unbounded
+func (lm *LockManager) CacheSize() int {
+	lm.cacheMutex.RLock()
+	defer lm.cacheMutex.RUnlock()
+	if lm.cache == nil {
+		return 0
+	}
+	return lm.cache.Size()
+}
+
+// ConfigureCache replaces the policy cache by an empty one holding at most
+// size policies, or any number of them if size is zero, or removes it if
+// disabled. The cache stays disabled if caching is disabled for the system.
+func (lm *LockManager) ConfigureCache(disabled bool, size int) error {
+	var cache Cache
+	if !disabled && !lm.cacheDisabled {
+		var err error
+		cache, err = NewCache(size)
+		if err != nil {
+			return err
+		}
+	}
+
+	lm.cacheMutex.Lock()
+	defer lm.cacheMutex.Unlock()
+	if lm.cache != nil && cache != nil && lm.cache.Size() == cache.Size() {
+		return nil
+	}
+	lm.cache = cache
+	return nil
+}
+
 func (lm *LockManager) InvalidatePolicy(name string) {
-	// Check if it's in our cache. If so, return right away.
-	if lm.CacheActive() {
-		lm.cacheMutex.Lock()
-		defer lm.cacheMutex.Unlock()
-		delete(lm.cache, name)
+	lm.cacheMutex.Lock()
+	defer lm.cacheMutex.Unlock()
+	if lm.cache != nil {
+		lm.cache.Delete(name)
 	}
 }
 
+// cachedPolicy returns the policy from the cache, or nil
+func (lm *LockManager) cachedPolicy(name string) *Policy {
+	lm.cacheMutex.RLock()
+	defer lm.cacheMutex.RUnlock()
+	if lm.cache == nil {
+		return nil
+	}
+	return lm.cache.Get(name)
+}
+
+// cachePolicy stores the policy in the cache unless one appeared meanwhile,
+// in which case that one is returned
+func (lm *LockManager) cachePolicy(name string, p *Policy) *Policy {
+	lm.cacheMutex.Lock()
+	defer lm.cacheMutex.Unlock()
+	if lm.cache == nil {
+		return p
+	}
+	if exp := lm.cache.Get(name); exp != nil {
+		return exp
+	}
+	lm.cache.Set(name, p)
+	return p
+}
+
 func (lm *LockManager) policyLock(name string, lockType bool) *sync.RWMutex {
 	lm.locksMutex.RLock()
 	lock := lm.locks[name]
@@ -142,10 +300,10 @@ func (lm *LockManager) UnlockPolicy(lock *sync.RWMutex, lockType bool) {
 }
 
 func (lm *LockManager) UpdateCache(name string, policy *Policy) {
-	if lm.CacheActive() {
-		lm.cacheMutex.Lock()
-		defer lm.cacheMutex.Unlock()
-		lm.cache[name] = policy
+	lm.cacheMutex.Lock()
+	defer lm.cacheMutex.Unlock()
+	if lm.cache != nil {
+		lm.cache.Set(name, policy)
 	}
 }
 
@@ -244,14 +402,8 @@ func (lm *LockManager) RestorePolicy(ctx context.Context, storage logical.Storag
 	defer lm.UnlockPolicy(lock, lockType)
 
 	// If the policy is in cache, error out
-	if lm.CacheActive() {
-		lm.cacheMutex.RLock()
-		p = lm.cache[name]
-		if p != nil {
-			lm.cacheMutex.RUnlock()
-			return fmt.Errorf(fmt.Sprintf("policy %q already exists", name))
-		}
-		lm.cacheMutex.RUnlock()
+	if p = lm.cachedPolicy(name); p != nil {
+		return fmt.Errorf(fmt.Sprintf("policy %q already exists", name))
 	}
 
 	// If the policy exists in storage, error out
@@ -289,6 +441,45 @@ func (lm *LockManager) RestorePolicy(ctx context.Context, storage logical.Storag
 	return nil
 }
 
+// ImportPolicy creates the policy of the request with the given key material
+// as its first version. The key must be raw bytes for AES keys, and a PKCS#8
+// DER private key for the other types.
+func (lm *LockManager) ImportPolicy(ctx context.Context, req PolicyRequest, key []byte) error {
+	if err := req.validate(); err != nil {
+		return errutil.UserError{Err: err.Error()}
+	}
+
+	lockType := exclusive
+	lock := lm.policyLock(req.Name, lockType)
+	defer lm.UnlockPolicy(lock, lockType)
+
+	// If the policy is in cache, error out
+	if p := lm.cachedPolicy(req.Name); p != nil {
+		return errutil.UserError{Err: fmt.Sprintf("policy %q already exists", req.Name)}
+	}
+
+	// If the policy exists in storage, error out
+	p, err := lm.getStoredPolicy(ctx, req.Storage, req.Name)
+	if err != nil {
+		return err
+	}
+	if p != nil {
+		return errutil.UserError{Err: fmt.Sprintf("policy %q already exists", req.Name)}
+	}
+
+	p = req.newPolicy()
+	p.Imported = true
+	p.AllowImportedKeyRotation = req.AllowImportedKeyRotation
+
+	if err := p.ImportKey(ctx, req.Storage, key); err != nil {
+		return err
+	}
+
+	lm.UpdateCache(req.Name, p)
+
+	return nil
+}
+
 func (lm *LockManager) BackupPolicy(ctx context.Context, storage logical.Storage, name string) (string, error) {
 	p, lock, err := lm.GetPolicyExclusive(ctx, storage, name)
 	if lock != nil {
@@ -321,14 +512,8 @@ func (lm *LockManager) getPolicyCommon(ctx context.Context, req PolicyRequest, l
 	var err error
 
 	// Check if it's in our cache. If so, return right away.
-	if lm.CacheActive() {
-		lm.cacheMutex.RLock()
-		p = lm.cache[req.Name]
-		if p != nil {
-			lm.cacheMutex.RUnlock()
-			return p, lock, false, nil
-		}
-		lm.cacheMutex.RUnlock()
+	if p = lm.cachedPolicy(req.Name); p != nil {
+		return p, lock, false, nil
 	}
 
 	// Load it from storage
@@ -351,69 +536,27 @@ func (lm *LockManager) getPolicyCommon(ctx context.Context, req PolicyRequest, l
 			return nil, nil, false, errNeedExclusiveLock
 		}
 
-		switch req.KeyType {
-		case KeyType_AES256_GCM96:
-			if req.Convergent && !req.Derived {
-				lm.UnlockPolicy(lock, lockType)
-				return nil, nil, false, fmt.Errorf("convergent encryption requires derivation to be enabled")
-			}
-
-		case KeyType_ECDSA_P256:
-			if req.Derived || req.Convergent {
-				lm.UnlockPolicy(lock, lockType)
-				return nil, nil, false, fmt.Errorf("key derivation and convergent encryption not supported for keys of type %v", req.KeyType)
-			}
-
-		case KeyType_ED25519:
-			if req.Convergent {
-				lm.UnlockPolicy(lock, lockType)
-				return nil, nil, false, fmt.Errorf("convergent encryption not supported for keys of type %v", req.KeyType)
-			}
-
-		case KeyType_RSA2048, KeyType_RSA4096:
-			if req.Derived || req.Convergent {
-				lm.UnlockPolicy(lock, lockType)
-				return nil, nil, false, fmt.Errorf("key derivation and convergent encryption not supported for keys of type %v", req.KeyType)
-			}
-
-		default:
+		if err := req.validate(); err != nil {
 			lm.UnlockPolicy(lock, lockType)
-			return nil, nil, false, fmt.Errorf("unsupported key type %v", req.KeyType)
+			return nil, nil, false, err
 		}
 
-		p = &Policy{
-			Name:                 req.Name,
-			Type:                 req.KeyType,
-			Derived:              req.Derived,
-			Exportable:           req.Exportable,
-			AllowPlaintextBackup: req.AllowPlaintextBackup,
-		}
-		if req.Derived {
-			p.KDF = Kdf_hkdf_sha256
-			p.ConvergentEncryption = req.Convergent
-			p.ConvergentVersion = 2
-		}
+		p = req.newPolicy()
 
-		err = p.Rotate(ctx, req.Storage)
+		randReader := req.RandReader
+		if randReader == nil {
+			randReader = rand.Reader
+		}
+		err = p.Rotate(ctx, req.Storage, randReader)
 		if err != nil {
 			lm.UnlockPolicy(lock, lockType)
 			return nil, nil, false, err
 		}
 
-		if lm.CacheActive() {
-			// Since we didn't have the policy in the cache, if there was no
-			// error, write the value in.
-			lm.cacheMutex.Lock()
-			defer lm.cacheMutex.Unlock()
-			// Make sure a policy didn't appear. If so, it will only be set if
-			// there was no error, so assume it's good and return that
-			exp := lm.cache[req.Name]
-			if exp != nil {
-				return exp, lock, false, nil
-			}
-			if err == nil {
-				lm.cache[req.Name] = p
-			}
+		// Since we didn't have the policy in the cache, write the value in.
+		// If a policy appeared meanwhile, assume it's good and return that.
+		if exp := lm.cachePolicy(req.Name, p); exp != p {
+			return exp, lock, false, nil
 		}
 
 		// We don't need to worry about upgrading since it will be a new policy
@@ -433,23 +576,9 @@ func (lm *LockManager) getPolicyCommon(ctx context.Context, req PolicyRequest, l
 		}
 	}
 
-	if lm.CacheActive() {
-		// Since we didn't have the policy in the cache, if there was no
-		// error, write the value in.
-		lm.cacheMutex.Lock()
-		defer lm.cacheMutex.Unlock()
-		// Make sure a policy didn't appear. If so, it will only be set if
-		// there was no error, so assume it's good and return that
-		exp := lm.cache[req.Name]
-		if exp != nil {
-			return exp, lock, false, nil
-		}
-		if err == nil {
-			lm.cache[req.Name] = p
-		}
-	}
-
-	return p, lock, false, nil
+	// Since we didn't have the policy in the cache, write the value in. If a
+	// policy appeared meanwhile, assume it's good and return that.
+	return lm.cachePolicy(req.Name, p), lock, false, nil
 }
 
 func (lm *LockManager) DeletePolicy(ctx context.Context, storage logical.Storage, name string) error {
@@ -461,8 +590,8 @@ func (lm *LockManager) DeletePolicy(ctx context.Context, storage logical.Storage
 	var p *Policy
 	var err error
 
-	if lm.CacheActive() {
-		p = lm.cache[name]
+	if lm.cache != nil {
+		p = lm.cache.Get(name)
 	}
 	if p == nil {
 		p, err = lm.getStoredPolicy(ctx, storage, name)
@@ -488,8 +617,8 @@ func (lm *LockManager) DeletePolicy(ctx context.Context, storage logical.Storage
 		return fmt.Errorf("error deleting archive %s: %s", name, err)
 	}
 
-	if lm.CacheActive() {
-		delete(lm.cache, name)
+	if lm.cache != nil {
+		lm.cache.Delete(name)
 	}
 
 	return nil
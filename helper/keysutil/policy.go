@@ -4,9 +4,8 @@ import (
 	"bytes"
 	"context"
 	"crypto"
-	"crypto/aes"
-	"crypto/cipher"
 	"crypto/ecdsa"
+	stded25519 "crypto/ed25519"
 	"crypto/elliptic"
 	"crypto/hmac"
 	"crypto/rand"
@@ -48,10 +47,37 @@ const (
 	KeyType_ED25519
 	KeyType_RSA2048
 	KeyType_RSA4096
+	KeyType_ChaCha20_Poly1305
+	KeyType_AES256_CBC_HMAC_SHA512
 )
 
 const ErrTooOld = "ciphertext or signature version is disallowed by policy (too old)"
 
+// The operations which the use of a policy can be restricted to
+const (
+	KeyOperationEncrypt = "encrypt"
+	KeyOperationDecrypt = "decrypt"
+	KeyOperationSign    = "sign"
+	KeyOperationVerify  = "verify"
+	KeyOperationHMAC    = "hmac"
+	KeyOperationDatakey = "datakey"
+	KeyOperationCMAC    = "cmac"
+	KeyOperationDerive  = "derive"
+)
+
+// ValidateKeyOperations checks that all the operations are known
+func ValidateKeyOperations(operations []string) error {
+	for _, operation := range operations {
+		switch operation {
+		case KeyOperationEncrypt, KeyOperationDecrypt, KeyOperationSign, KeyOperationVerify,
+			KeyOperationHMAC, KeyOperationDatakey, KeyOperationCMAC, KeyOperationDerive:
+		default:
+			return fmt.Errorf("unknown key operation %q", operation)
+		}
+	}
+	return nil
+}
+
 type RestoreInfo struct {
 	Time    time.Time `json:"time"`
 	Version int       `json:"version"`
@@ -75,7 +101,7 @@ type KeyType int
 
 func (kt KeyType) EncryptionSupported() bool {
 	switch kt {
-	case KeyType_AES256_GCM96, KeyType_RSA2048, KeyType_RSA4096:
+	case KeyType_AES256_GCM96, KeyType_ChaCha20_Poly1305, KeyType_AES256_CBC_HMAC_SHA512, KeyType_RSA2048, KeyType_RSA4096:
 		return true
 	}
 	return false
@@ -83,7 +109,7 @@ func (kt KeyType) EncryptionSupported() bool {
 
 func (kt KeyType) DecryptionSupported() bool {
 	switch kt {
-	case KeyType_AES256_GCM96, KeyType_RSA2048, KeyType_RSA4096:
+	case KeyType_AES256_GCM96, KeyType_ChaCha20_Poly1305, KeyType_AES256_CBC_HMAC_SHA512, KeyType_RSA2048, KeyType_RSA4096:
 		return true
 	}
 	return false
@@ -107,12 +133,32 @@ func (kt KeyType) HashSignatureInput() bool {
 
 func (kt KeyType) DerivationSupported() bool {
 	switch kt {
-	case KeyType_AES256_GCM96, KeyType_ED25519:
+	case KeyType_AES256_GCM96, KeyType_ChaCha20_Poly1305, KeyType_AES256_CBC_HMAC_SHA512, KeyType_ED25519:
+		return true
+	}
+	return false
+}
+
+// Symmetric returns whether the key type is a symmetric encryption key, which
+// is stored as raw bytes
+func (kt KeyType) Symmetric() bool {
+	switch kt {
+	case KeyType_AES256_GCM96, KeyType_ChaCha20_Poly1305, KeyType_AES256_CBC_HMAC_SHA512:
 		return true
 	}
 	return false
 }
 
+// KeySize returns the size in bytes of symmetric keys of the type
+func (kt KeyType) KeySize() int {
+	switch kt {
+	case KeyType_AES256_CBC_HMAC_SHA512:
+		return 64
+	default:
+		return 32
+	}
+}
+
 func (kt KeyType) String() string {
 	switch kt {
 	case KeyType_AES256_GCM96:
@@ -125,6 +171,10 @@ func (kt KeyType) String() string {
 		return "rsa-2048"
 	case KeyType_RSA4096:
 		return "rsa-4096"
+	case KeyType_ChaCha20_Poly1305:
+		return "chacha20-poly1305"
+	case KeyType_AES256_CBC_HMAC_SHA512:
+		return "aes256-cbc-hmac-sha512"
 	}
 
 	return "[unknown]"
@@ -208,6 +258,9 @@ type Policy struct {
 	// Whether the key is exportable
 	Exportable bool `json:"exportable"`
 
+	// Whether the key can only be exported wrapped under a public key
+	WrappedExportOnly bool `json:"wrapped_export_only"`
+
 	// The minimum version of the key allowed to be used for decryption
 	MinDecryptionVersion int `json:"min_decryption_version"`
 
@@ -240,6 +293,35 @@ type Policy struct {
 
 	// AllowPlaintextBackup allows taking backup of the policy in plaintext
 	AllowPlaintextBackup bool `json:"allow_plaintext_backup"`
+
+	// Imported indicates that the key material was imported rather than
+	// generated
+	Imported bool `json:"imported"`
+
+	// AllowImportedKeyRotation allows rotating an imported key, with the new
+	// versions generated
+	AllowImportedKeyRotation bool `json:"allow_imported_key_rotation"`
+
+	// AutoRotatePeriod is the age of the latest version after which the key
+	// is rotated automatically; zero disables automatic rotation
+	AutoRotatePeriod time.Duration `json:"auto_rotate_period"`
+
+	// AllowedOperations restricts the operations the key can be used for;
+	// all operations are allowed if it is empty
+	AllowedOperations []string `json:"allowed_operations"`
+}
+
+// OperationAllowed returns whether the key can be used for the operation
+func (p *Policy) OperationAllowed(operation string) bool {
+	if len(p.AllowedOperations) == 0 {
+		return true
+	}
+	for _, allowed := range p.AllowedOperations {
+		if allowed == operation {
+			return true
+		}
+	}
+	return false
 }
 
 // ArchivedKeys stores old keys. This is used to keep the key loading time sane
@@ -513,25 +595,26 @@ func (p *Policy) DeriveKey(context []byte, ver int) ([]byte, error) {
 	case Kdf_hmac_sha256_counter:
 		prf := kdf.HMACSHA256PRF
 		prfLen := kdf.HMACSHA256PRFLen
-		return kdf.CounterMode(prf, prfLen, p.Keys[strconv.Itoa(ver)].Key, context, 256)
+		return kdf.CounterMode(prf, prfLen, p.Keys[strconv.Itoa(ver)].Key, context, uint32(p.Type.KeySize()*8))
 
 	case Kdf_hkdf_sha256:
 		reader := hkdf.New(sha256.New, p.Keys[strconv.Itoa(ver)].Key, nil, context)
+		keySize := p.Type.KeySize()
 		derBytes := bytes.NewBuffer(nil)
-		derBytes.Grow(32)
+		derBytes.Grow(keySize)
 		limReader := &io.LimitedReader{
 			R: reader,
-			N: 32,
+			N: int64(keySize),
 		}
 
 		switch p.Type {
-		case KeyType_AES256_GCM96:
+		case KeyType_AES256_GCM96, KeyType_ChaCha20_Poly1305, KeyType_AES256_CBC_HMAC_SHA512:
 			n, err := derBytes.ReadFrom(limReader)
 			if err != nil {
 				return nil, errutil.InternalError{Err: fmt.Sprintf("error reading returned derived bytes: %v", err)}
 			}
-			if n != 32 {
-				return nil, errutil.InternalError{Err: fmt.Sprintf("unable to read enough derived bytes, needed 32, got %d", n)}
+			if n != int64(keySize) {
+				return nil, errutil.InternalError{Err: fmt.Sprintf("unable to read enough derived bytes, needed %d, got %d", keySize, n)}
 			}
 			return derBytes.Bytes(), nil
 
@@ -553,6 +636,16 @@ func (p *Policy) DeriveKey(context []byte, ver int) ([]byte, error) {
 	}
 }
 
+// convergentNonceKey derives the key of the nonces of version 3 convergent
+// encryption from the encryption key of the context
+func convergentNonceKey(key []byte) ([]byte, error) {
+	nonceKey := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, key, nil, []byte("convergent-nonce")), nonceKey); err != nil {
+		return nil, err
+	}
+	return nonceKey, nil
+}
+
 func (p *Policy) Encrypt(ver int, context, nonce []byte, value string) (string, error) {
 	if !p.Type.EncryptionSupported() {
 		return "", errutil.UserError{Err: fmt.Sprintf("message encryption not supported for key type %v", p.Type)}
@@ -578,21 +671,15 @@ func (p *Policy) Encrypt(ver int, context, nonce []byte, value string) (string,
 	var ciphertext []byte
 
 	switch p.Type {
-	case KeyType_AES256_GCM96:
+	case KeyType_AES256_GCM96, KeyType_ChaCha20_Poly1305, KeyType_AES256_CBC_HMAC_SHA512:
 		// Derive the key that should be used
 		key, err := p.DeriveKey(context, ver)
 		if err != nil {
 			return "", err
 		}
 
-		// Setup the cipher
-		aesCipher, err := aes.NewCipher(key)
-		if err != nil {
-			return "", errutil.InternalError{Err: err.Error()}
-		}
-
-		// Setup the GCM AEAD
-		gcm, err := cipher.NewGCM(aesCipher)
+		// Setup the AEAD
+		gcm, err := p.Type.newAEAD(key)
 		if err != nil {
 			return "", errutil.InternalError{Err: err.Error()}
 		}
@@ -603,6 +690,18 @@ func (p *Policy) Encrypt(ver int, context, nonce []byte, value string) (string,
 				if len(nonce) != gcm.NonceSize() {
 					return "", errutil.UserError{Err: fmt.Sprintf("base64-decoded nonce must be %d bytes long when using convergent encryption with this key", gcm.NonceSize())}
 				}
+			case 3:
+				// The nonce is computed under a sub-key of the per-context
+				// key rather than under the context alone, so that it
+				// cannot be computed without the key
+				nonceKey, err := convergentNonceKey(key)
+				if err != nil {
+					return "", errutil.InternalError{Err: err.Error()}
+				}
+				nonceHmac := hmac.New(sha256.New, nonceKey)
+				nonceHmac.Write(plaintext)
+				nonceSum := nonceHmac.Sum(nil)
+				nonce = nonceSum[:gcm.NonceSize()]
 			default:
 				nonceHmac := hmac.New(sha256.New, context)
 				nonceHmac.Write(plaintext)
@@ -692,20 +791,14 @@ func (p *Policy) Decrypt(context, nonce []byte, value string) (string, error) {
 	var plain []byte
 
 	switch p.Type {
-	case KeyType_AES256_GCM96:
+	case KeyType_AES256_GCM96, KeyType_ChaCha20_Poly1305, KeyType_AES256_CBC_HMAC_SHA512:
 		key, err := p.DeriveKey(context, ver)
 		if err != nil {
 			return "", err
 		}
 
-		// Setup the cipher
-		aesCipher, err := aes.NewCipher(key)
-		if err != nil {
-			return "", errutil.InternalError{Err: err.Error()}
-		}
-
-		// Setup the GCM AEAD
-		gcm, err := cipher.NewGCM(aesCipher)
+		// Setup the AEAD
+		gcm, err := p.Type.newAEAD(key)
 		if err != nil {
 			return "", errutil.InternalError{Err: err.Error()}
 		}
@@ -808,6 +901,9 @@ func (p *Policy) Sign(ver int, context, input []byte, algorithm string) (*Signin
 			// Derive the key that should be used
 			var err error
 			key, err = p.DeriveKey(context, ver)
+			if _, ok := err.(errutil.UserError); ok {
+				return nil, err
+			}
 			if err != nil {
 				return nil, errutil.InternalError{Err: fmt.Sprintf("error deriving key: %v", err)}
 			}
@@ -920,6 +1016,9 @@ func (p *Policy) VerifySignature(context, input []byte, sig, algorithm string) (
 			// Derive the key that should be used
 			var err error
 			key, err = p.DeriveKey(context, ver)
+			if _, ok := err.(errutil.UserError); ok {
+				return false, err
+			}
 			if err != nil {
 				return false, errutil.InternalError{Err: fmt.Sprintf("error deriving key: %v", err)}
 			}
@@ -955,7 +1054,9 @@ func (p *Policy) VerifySignature(context, input []byte, sig, algorithm string) (
 	}
 }
 
-func (p *Policy) Rotate(ctx context.Context, storage logical.Storage) error {
+// Rotate adds a new version of the key to the policy, generated from the
+// random data of randReader
+func (p *Policy) Rotate(ctx context.Context, storage logical.Storage, randReader io.Reader) error {
 	if p.Keys == nil {
 		// This is an initial key rotation when generating a new policy. We
 		// don't need to call migrate here because if we've called getPolicy to
@@ -970,45 +1071,32 @@ func (p *Policy) Rotate(ctx context.Context, storage logical.Storage) error {
 		DeprecatedCreationTime: now.Unix(),
 	}
 
-	hmacKey, err := uuid.GenerateRandomBytes(32)
-	if err != nil {
+	hmacKey := make([]byte, 32)
+	if _, err := io.ReadFull(randReader, hmacKey); err != nil {
 		return err
 	}
 	entry.HMACKey = hmacKey
 
 	switch p.Type {
-	case KeyType_AES256_GCM96:
-		// Generate a 256bit key
-		newKey, err := uuid.GenerateRandomBytes(32)
-		if err != nil {
+	case KeyType_AES256_GCM96, KeyType_ChaCha20_Poly1305, KeyType_AES256_CBC_HMAC_SHA512:
+		// Generate a 256bit key, or two for CBC and HMAC
+		newKey := make([]byte, p.Type.KeySize())
+		if _, err := io.ReadFull(randReader, newKey); err != nil {
 			return err
 		}
 		entry.Key = newKey
 
 	case KeyType_ECDSA_P256:
-		privKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		privKey, err := ecdsa.GenerateKey(elliptic.P256(), randReader)
 		if err != nil {
 			return err
 		}
-		entry.EC_D = privKey.D
-		entry.EC_X = privKey.X
-		entry.EC_Y = privKey.Y
-		derBytes, err := x509.MarshalPKIXPublicKey(privKey.Public())
-		if err != nil {
-			return fmt.Errorf("error marshaling public key: %s", err)
-		}
-		pemBlock := &pem.Block{
-			Type:  "PUBLIC KEY",
-			Bytes: derBytes,
-		}
-		pemBytes := pem.EncodeToMemory(pemBlock)
-		if pemBytes == nil || len(pemBytes) == 0 {
-			return fmt.Errorf("error PEM-encoding public key")
+		if err := entry.setECDSAKey(privKey); err != nil {
+			return err
 		}
-		entry.FormattedPublicKey = string(pemBytes)
 
 	case KeyType_ED25519:
-		pub, pri, err := ed25519.GenerateKey(rand.Reader)
+		pub, pri, err := ed25519.GenerateKey(randReader)
 		if err != nil {
 			return err
 		}
@@ -1021,7 +1109,8 @@ func (p *Policy) Rotate(ctx context.Context, storage logical.Storage) error {
 			bitSize = 4096
 		}
 
-		entry.RSAKey, err = rsa.GenerateKey(rand.Reader, bitSize)
+		var err error
+		entry.RSAKey, err = rsa.GenerateKey(randReader, bitSize)
 		if err != nil {
 			return err
 		}
@@ -1039,6 +1128,113 @@ func (p *Policy) Rotate(ctx context.Context, storage logical.Storage) error {
 	return p.Persist(ctx, storage)
 }
 
+// AutoRotationDue returns whether the latest version of the policy is older
+// than its automatic rotation period at the given time
+func (p *Policy) AutoRotationDue(now time.Time) bool {
+	if p.AutoRotatePeriod <= 0 || (p.Imported && !p.AllowImportedKeyRotation) {
+		return false
+	}
+
+	latest := p.Keys[strconv.Itoa(p.LatestVersion)]
+	creationTime := latest.CreationTime
+	if creationTime.IsZero() {
+		creationTime = time.Unix(latest.DeprecatedCreationTime, 0)
+	}
+	return !now.Before(creationTime.Add(p.AutoRotatePeriod))
+}
+
+// ImportKey adds a version to the policy with the given key material, which
+// must be raw bytes for AES keys, and a PKCS#8 DER private key for the other
+// types
+func (p *Policy) ImportKey(ctx context.Context, storage logical.Storage, key []byte) error {
+	now := time.Now()
+	entry := KeyEntry{
+		CreationTime:           now,
+		DeprecatedCreationTime: now.Unix(),
+	}
+
+	hmacKey, err := uuid.GenerateRandomBytes(32)
+	if err != nil {
+		return err
+	}
+	entry.HMACKey = hmacKey
+
+	if p.Type.Symmetric() {
+		if len(key) != p.Type.KeySize() {
+			return errutil.UserError{Err: fmt.Sprintf("%v keys must be %d bytes, not %d", p.Type, p.Type.KeySize(), len(key))}
+		}
+		entry.Key = key
+	} else {
+		parsedKey, err := x509.ParsePKCS8PrivateKey(key)
+		if err != nil {
+			return errutil.UserError{Err: fmt.Sprintf("failed to parse the PKCS#8 private key: %v", err)}
+		}
+
+		switch parsedKey := parsedKey.(type) {
+		case *ecdsa.PrivateKey:
+			if p.Type != KeyType_ECDSA_P256 || parsedKey.Curve != elliptic.P256() {
+				return errutil.UserError{Err: fmt.Sprintf("the key is not a %v key", p.Type)}
+			}
+			if err := entry.setECDSAKey(parsedKey); err != nil {
+				return err
+			}
+
+		case stded25519.PrivateKey:
+			if p.Type != KeyType_ED25519 {
+				return errutil.UserError{Err: fmt.Sprintf("the key is not a %v key", p.Type)}
+			}
+			entry.Key = ed25519.PrivateKey(parsedKey)
+			entry.FormattedPublicKey = base64.StdEncoding.EncodeToString(parsedKey.Public().(stded25519.PublicKey))
+
+		case *rsa.PrivateKey:
+			bitSize := 2048
+			if p.Type == KeyType_RSA4096 {
+				bitSize = 4096
+			}
+			if (p.Type != KeyType_RSA2048 && p.Type != KeyType_RSA4096) || parsedKey.N.BitLen() != bitSize {
+				return errutil.UserError{Err: fmt.Sprintf("the key is not a %v key", p.Type)}
+			}
+			entry.RSAKey = parsedKey
+
+		default:
+			return errutil.UserError{Err: fmt.Sprintf("the key is not a %v key", p.Type)}
+		}
+	}
+
+	if p.Keys == nil {
+		p.Keys = keyEntryMap{}
+	}
+	p.LatestVersion += 1
+	p.Keys[strconv.Itoa(p.LatestVersion)] = entry
+	if p.MinDecryptionVersion == 0 {
+		p.MinDecryptionVersion = 1
+	}
+
+	return p.Persist(ctx, storage)
+}
+
+// setECDSAKey sets the ECDSA private key of the entry, and its formatted
+// public key
+func (ke *KeyEntry) setECDSAKey(privKey *ecdsa.PrivateKey) error {
+	ke.EC_D = privKey.D
+	ke.EC_X = privKey.X
+	ke.EC_Y = privKey.Y
+	derBytes, err := x509.MarshalPKIXPublicKey(privKey.Public())
+	if err != nil {
+		return fmt.Errorf("error marshaling public key: %s", err)
+	}
+	pemBlock := &pem.Block{
+		Type:  "PUBLIC KEY",
+		Bytes: derBytes,
+	}
+	pemBytes := pem.EncodeToMemory(pemBlock)
+	if pemBytes == nil || len(pemBytes) == 0 {
+		return fmt.Errorf("error PEM-encoding public key")
+	}
+	ke.FormattedPublicKey = string(pemBytes)
+	return nil
+}
+
 func (p *Policy) MigrateKeyToKeysMap() {
 	now := time.Now()
 	p.Keys = keyEntryMap{
@@ -1061,6 +1257,10 @@ func (p *Policy) Backup(ctx context.Context, storage logical.Storage) (string, e
 		return "", fmt.Errorf("plaintext backup is disallowed on the policy")
 	}
 
+	if p.WrappedExportOnly {
+		return "", fmt.Errorf("plaintext backup is disallowed on policies which can only be exported wrapped")
+	}
+
 	// Create a record of this backup operation in the policy
 	p.BackupInfo = &BackupInfo{
 		Time:    time.Now(),
@@ -2,6 +2,7 @@ package keysutil
 
 import (
 	"context"
+	"crypto/rand"
 	"reflect"
 	"strconv"
 	"testing"
@@ -135,7 +136,7 @@ func testArchivingUpgradeCommon(t *testing.T, lm *LockManager) {
 	checkKeys(t, ctx, p, storage, "initial", 1, 1, 1)
 
 	for i := 2; i <= 10; i++ {
-		err = p.Rotate(ctx, storage)
+		err = p.Rotate(ctx, storage, rand.Reader)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -169,7 +170,7 @@ func testArchivingUpgradeCommon(t *testing.T, lm *LockManager) {
 	// If we're caching, expire from the cache since we modified it
 	// under-the-hood
 	if lm.CacheActive() {
-		delete(lm.cache, "test")
+		lm.cache.Delete("test")
 	}
 
 	// Now get the policy again; the upgrade should happen automatically
@@ -187,7 +188,7 @@ func testArchivingUpgradeCommon(t *testing.T, lm *LockManager) {
 	// Let's check some deletion logic while we're at it
 
 	// The policy should be in there
-	if lm.CacheActive() && lm.cache["test"] == nil {
+	if lm.CacheActive() && lm.cache.Get("test") == nil {
 		t.Fatal("nil policy in cache")
 	}
 
@@ -198,7 +199,7 @@ func testArchivingUpgradeCommon(t *testing.T, lm *LockManager) {
 	}
 
 	// The policy should still be in there
-	if lm.CacheActive() && lm.cache["test"] == nil {
+	if lm.CacheActive() && lm.cache.Get("test") == nil {
 		t.Fatal("nil policy in cache")
 	}
 
@@ -223,7 +224,7 @@ func testArchivingUpgradeCommon(t *testing.T, lm *LockManager) {
 	}
 
 	// The policy should *not* be in there
-	if lm.CacheActive() && lm.cache["test"] != nil {
+	if lm.CacheActive() && lm.cache.Get("test") != nil {
 		t.Fatal("non-nil policy in cache")
 	}
 
@@ -272,7 +273,7 @@ func testArchivingCommon(t *testing.T, lm *LockManager) {
 	checkKeys(t, ctx, p, storage, "initial", 1, 1, 1)
 
 	for i := 2; i <= 10; i++ {
-		err = p.Rotate(ctx, storage)
+		err = p.Rotate(ctx, storage, rand.Reader)
 		if err != nil {
 			t.Fatal(err)
 		}
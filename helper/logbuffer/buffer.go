@@ -0,0 +1,73 @@
+package logbuffer
+
+import (
+	"bytes"
+	"sync"
+)
+
+// DefaultSize is the default number of lines kept by a Buffer
+const DefaultSize = 1000
+
+// Buffer is an io.Writer implementation that keeps the last lines written to
+// it, so that the recent log lines of a server can be retrieved.
+type Buffer struct {
+	lines   []string
+	next    int
+	full    bool
+	partial []byte
+	lock    sync.Mutex
+}
+
+// New returns a Buffer keeping the given number of lines, or DefaultSize if
+// the size is not positive.
+func New(size int) *Buffer {
+	if size <= 0 {
+		size = DefaultSize
+	}
+	return &Buffer{
+		lines: make([]string, size),
+	}
+}
+
+func (b *Buffer) Write(p []byte) (n int, err error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	data := p
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		b.add(string(append(b.partial, data[:i]...)))
+		b.partial = nil
+		data = data[i+1:]
+	}
+	if len(data) > 0 {
+		b.partial = append(b.partial, data...)
+	}
+	return len(p), nil
+}
+
+// add records the line, overwriting the oldest line once the buffer is full.
+// The lock must be held.
+func (b *Buffer) add(line string) {
+	b.lines[b.next] = line
+	b.next = (b.next + 1) % len(b.lines)
+	if b.next == 0 {
+		b.full = true
+	}
+}
+
+// Lines returns the complete lines kept by the buffer, oldest first.
+func (b *Buffer) Lines() []string {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if !b.full {
+		return append([]string{}, b.lines[:b.next]...)
+	}
+	lines := make([]string, 0, len(b.lines))
+	lines = append(lines, b.lines[b.next:]...)
+	return append(lines, b.lines[:b.next]...)
+}
@@ -0,0 +1,27 @@
+package logbuffer
+
+import (
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestBuffer_impl(t *testing.T) {
+	var _ io.Writer = New(0)
+}
+
+func TestBuffer(t *testing.T) {
+	b := New(3)
+	b.Write([]byte("foo\nba"))
+	b.Write([]byte("r\n"))
+
+	if lines := b.Lines(); !reflect.DeepEqual(lines, []string{"foo", "bar"}) {
+		t.Fatalf("bad: %#v", lines)
+	}
+
+	b.Write([]byte("baz\nqux\nquux"))
+
+	if lines := b.Lines(); !reflect.DeepEqual(lines, []string{"bar", "baz", "qux"}) {
+		t.Fatalf("bad: %#v", lines)
+	}
+}
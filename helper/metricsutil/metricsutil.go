@@ -0,0 +1,96 @@
+package metricsutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	metrics "github.com/armon/go-metrics"
+	"github.com/hashicorp/vault/logical"
+)
+
+const (
+	// JSONMetricsFormat is the format of the summary of the metrics of the
+	// last interval of the in-memory sink
+	JSONMetricsFormat = "json"
+
+	// PrometheusMetricsFormat is the Prometheus text exposition format
+	PrometheusMetricsFormat = "prometheus"
+
+	// PrometheusContentType is the content type of PrometheusMetricsFormat
+	PrometheusContentType = "text/plain; version=0.0.4"
+)
+
+// MetricsHelper exposes the metrics collected by the in-memory and
+// Prometheus sinks
+type MetricsHelper struct {
+	inmemSink      *metrics.InmemSink
+	prometheusSink *PrometheusSink
+}
+
+// NewMetricsHelper returns a helper exposing the metrics of the sinks
+func NewMetricsHelper(inmem *metrics.InmemSink, prometheus *PrometheusSink) *MetricsHelper {
+	return &MetricsHelper{
+		inmemSink:      inmem,
+		prometheusSink: prometheus,
+	}
+}
+
+// ResponseForFormat returns a response with the metrics in the given format,
+// defaulting to JSON
+func (m *MetricsHelper) ResponseForFormat(format string) (*logical.Response, error) {
+	switch format {
+	case PrometheusMetricsFormat:
+		return m.PrometheusResponse()
+	case JSONMetricsFormat, "":
+		return m.GenericResponse()
+	default:
+		return logical.ErrorResponse(fmt.Sprintf("unsupported metrics format %q", format)), logical.ErrInvalidRequest
+	}
+}
+
+// PrometheusResponse returns a response with the metrics in the Prometheus
+// text exposition format
+func (m *MetricsHelper) PrometheusResponse() (*logical.Response, error) {
+	if m.prometheusSink == nil {
+		return logical.ErrorResponse("prometheus metrics are not enabled"), logical.ErrInvalidRequest
+	}
+
+	var buf bytes.Buffer
+	if _, err := m.prometheusSink.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPContentType: PrometheusContentType,
+			logical.HTTPRawBody:     buf.Bytes(),
+			logical.HTTPStatusCode:  200,
+		},
+	}, nil
+}
+
+// GenericResponse returns a response with the JSON summary of the metrics of
+// the last finished interval of the in-memory sink
+func (m *MetricsHelper) GenericResponse() (*logical.Response, error) {
+	if m.inmemSink == nil {
+		return logical.ErrorResponse("metrics are not enabled"), logical.ErrInvalidRequest
+	}
+
+	summary, err := m.inmemSink.DisplayMetrics(nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	body, err := json.Marshal(summary)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPContentType: "application/json",
+			logical.HTTPRawBody:     body,
+			logical.HTTPStatusCode:  200,
+		},
+	}, nil
+}
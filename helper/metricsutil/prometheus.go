@@ -0,0 +1,206 @@
+package metricsutil
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+)
+
+// DefaultPrometheusRetentionTime is how long a metric which is no longer
+// updated is kept by the Prometheus sink
+const DefaultPrometheusRetentionTime = 24 * time.Hour
+
+var _ metrics.MetricSink = (*PrometheusSink)(nil)
+
+// PrometheusSink is a metrics sink which keeps the metrics in memory to
+// expose them in the Prometheus text format. Unlike the in-memory sink,
+// counters and samples are cumulative since the sink was created, as
+// Prometheus expects.
+type PrometheusSink struct {
+	retention time.Duration
+
+	l        sync.Mutex
+	gauges   map[string]*prometheusMetric
+	counters map[string]*prometheusMetric
+	samples  map[string]*prometheusMetric
+}
+
+// prometheusMetric is the state of a metric with a given name and labels
+type prometheusMetric struct {
+	name    string
+	labels  []metrics.Label
+	value   float64
+	sum     float64
+	count   uint64
+	updated time.Time
+}
+
+// NewPrometheusSink returns a Prometheus sink which drops the metrics not
+// updated within the retention time
+func NewPrometheusSink(retention time.Duration) *PrometheusSink {
+	if retention <= 0 {
+		retention = DefaultPrometheusRetentionTime
+	}
+	return &PrometheusSink{
+		retention: retention,
+		gauges:    make(map[string]*prometheusMetric),
+		counters:  make(map[string]*prometheusMetric),
+		samples:   make(map[string]*prometheusMetric),
+	}
+}
+
+func (p *PrometheusSink) SetGauge(key []string, val float32) {
+	p.SetGaugeWithLabels(key, val, nil)
+}
+
+func (p *PrometheusSink) SetGaugeWithLabels(key []string, val float32, labels []metrics.Label) {
+	p.l.Lock()
+	defer p.l.Unlock()
+	m := p.metric(p.gauges, key, labels)
+	m.value = float64(val)
+}
+
+// EmitKey is not supported, since Prometheus has no equivalent of key/value
+// points
+func (p *PrometheusSink) EmitKey(key []string, val float32) {
+}
+
+func (p *PrometheusSink) IncrCounter(key []string, val float32) {
+	p.IncrCounterWithLabels(key, val, nil)
+}
+
+func (p *PrometheusSink) IncrCounterWithLabels(key []string, val float32, labels []metrics.Label) {
+	p.l.Lock()
+	defer p.l.Unlock()
+	m := p.metric(p.counters, key, labels)
+	m.value += float64(val)
+}
+
+func (p *PrometheusSink) AddSample(key []string, val float32) {
+	p.AddSampleWithLabels(key, val, nil)
+}
+
+func (p *PrometheusSink) AddSampleWithLabels(key []string, val float32, labels []metrics.Label) {
+	p.l.Lock()
+	defer p.l.Unlock()
+	m := p.metric(p.samples, key, labels)
+	m.sum += float64(val)
+	m.count++
+}
+
+// metric returns the metric of the key and labels, creating it if needed.
+// N.B.: This must be called with the lock held.
+func (p *PrometheusSink) metric(set map[string]*prometheusMetric, key []string, labels []metrics.Label) *prometheusMetric {
+	name := prometheusName(strings.Join(key, "_"))
+	id := name + prometheusLabels(labels)
+	m, ok := set[id]
+	if !ok {
+		m = &prometheusMetric{
+			name:   name,
+			labels: labels,
+		}
+		set[id] = m
+	}
+	m.updated = time.Now()
+	return m
+}
+
+// WriteTo writes the metrics in the Prometheus text exposition format
+func (p *PrometheusSink) WriteTo(w io.Writer) (int64, error) {
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	var buf bytes.Buffer
+	expired := time.Now().Add(-p.retention)
+	for _, set := range []struct {
+		typ     string
+		metrics map[string]*prometheusMetric
+	}{
+		{"gauge", p.gauges},
+		{"counter", p.counters},
+		{"summary", p.samples},
+	} {
+		ids := make([]string, 0, len(set.metrics))
+		for id, m := range set.metrics {
+			if m.updated.Before(expired) {
+				delete(set.metrics, id)
+				continue
+			}
+			ids = append(ids, id)
+		}
+		// Sort by name first, so that the metrics of a name are adjacent
+		sort.Slice(ids, func(i, j int) bool {
+			mi, mj := set.metrics[ids[i]], set.metrics[ids[j]]
+			if mi.name != mj.name {
+				return mi.name < mj.name
+			}
+			return ids[i] < ids[j]
+		})
+
+		var lastName string
+		for _, id := range ids {
+			m := set.metrics[id]
+			if m.name != lastName {
+				fmt.Fprintf(&buf, "# TYPE %s %s\n", m.name, set.typ)
+				lastName = m.name
+			}
+
+			labels := prometheusLabels(m.labels)
+			switch set.typ {
+			case "summary":
+				fmt.Fprintf(&buf, "%s_sum%s %s\n", m.name, labels, prometheusValue(m.sum))
+				fmt.Fprintf(&buf, "%s_count%s %d\n", m.name, labels, m.count)
+			default:
+				fmt.Fprintf(&buf, "%s%s %s\n", m.name, labels, prometheusValue(m.value))
+			}
+		}
+	}
+
+	return buf.WriteTo(w)
+}
+
+// prometheusName replaces the characters not allowed in Prometheus metric
+// and label names
+func prometheusName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == ':':
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// prometheusLabels formats the labels of a metric, sorted by name
+func prometheusLabels(labels []metrics.Label) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	pairs := make([]string, 0, len(labels))
+	for _, label := range labels {
+		value := strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`).Replace(label.Value)
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, prometheusName(label.Name), value))
+	}
+	sort.Strings(pairs)
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func prometheusValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return fmt.Sprintf("%g", v)
+}
@@ -0,0 +1,69 @@
+package metricsutil
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+)
+
+func TestPrometheusSink_WriteTo(t *testing.T) {
+	sink := NewPrometheusSink(0)
+
+	sink.SetGauge([]string{"vault", "expire", "num_leases"}, 5)
+	sink.SetGauge([]string{"vault", "expire", "num_leases"}, 3)
+	sink.IncrCounter([]string{"vault", "audit", "log_request_failure"}, 1)
+	sink.IncrCounter([]string{"vault", "audit", "log_request_failure"}, 2)
+	sink.AddSampleWithLabels([]string{"vault", "route", "request"}, 1.5, []metrics.Label{
+		{Name: "operation", Value: "read"},
+		{Name: "mount", Value: "secret/"},
+	})
+	sink.AddSampleWithLabels([]string{"vault", "route", "request"}, 2.5, []metrics.Label{
+		{Name: "mount", Value: "secret/"},
+		{Name: "operation", Value: "read"},
+	})
+	sink.AddSample([]string{"vault", "barrier", "get-entry"}, 1)
+	sink.EmitKey([]string{"vault", "ignored"}, 1)
+
+	var buf bytes.Buffer
+	if _, err := sink.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := strings.Join([]string{
+		"# TYPE vault_expire_num_leases gauge",
+		"vault_expire_num_leases 3",
+		"# TYPE vault_audit_log_request_failure counter",
+		"vault_audit_log_request_failure 3",
+		"# TYPE vault_barrier_get_entry summary",
+		"vault_barrier_get_entry_sum 1",
+		"vault_barrier_get_entry_count 1",
+		"# TYPE vault_route_request summary",
+		`vault_route_request_sum{mount="secret/",operation="read"} 4`,
+		`vault_route_request_count{mount="secret/",operation="read"} 2`,
+		"",
+	}, "\n")
+	if buf.String() != expected {
+		t.Fatalf("expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
+
+func TestPrometheusSink_retention(t *testing.T) {
+	sink := NewPrometheusSink(time.Minute)
+	sink.SetGauge([]string{"stale"}, 1)
+	sink.SetGauge([]string{"fresh"}, 1)
+	sink.gauges["stale"].updated = time.Now().Add(-2 * time.Minute)
+
+	var buf bytes.Buffer
+	if _, err := sink.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "stale") || !strings.Contains(buf.String(), "fresh 1") {
+		t.Fatalf("bad: %s", buf.String())
+	}
+	if _, ok := sink.gauges["stale"]; ok {
+		t.Fatal("expected stale gauge to be dropped")
+	}
+}
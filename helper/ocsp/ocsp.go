@@ -0,0 +1,413 @@
+// Package ocsp implements the parts of the OCSP protocol (RFC 6960) used by
+// the cert auth method and the pki secrets engine.
+//
+// golang.org/x/crypto/ocsp is not used since it supports neither Ed25519
+// signatures, nor nonces, nor requests for several certificates.
+package ocsp
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/hashicorp/vault/helper/certutil"
+)
+
+// Response statuses
+const (
+	StatusSuccessful       = 0
+	StatusMalformedRequest = 1
+	StatusInternalError    = 2
+	StatusTryLater         = 3
+	StatusSigRequired      = 5
+	StatusUnauthorized     = 6
+)
+
+var (
+	OIDBasicResponse = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1, 1}
+	OIDNonce         = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1, 2}
+
+	oidSHA1 = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+)
+
+// hashes are the hash algorithms supported in the CertIDs
+var hashes = []struct {
+	oid  asn1.ObjectIdentifier
+	hash crypto.Hash
+}{
+	{oidSHA1, crypto.SHA1},
+	{asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}, crypto.SHA256},
+	{asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 2}, crypto.SHA384},
+	{asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}, crypto.SHA512},
+}
+
+// signatureAlgorithms maps the signature algorithm identifiers of the
+// responses to their x509 counterparts
+var signatureAlgorithms = []struct {
+	oid  asn1.ObjectIdentifier
+	algo x509.SignatureAlgorithm
+}{
+	{asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 5}, x509.SHA1WithRSA},
+	{asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 11}, x509.SHA256WithRSA},
+	{asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 12}, x509.SHA384WithRSA},
+	{asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 13}, x509.SHA512WithRSA},
+	{asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 1}, x509.ECDSAWithSHA1},
+	{asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}, x509.ECDSAWithSHA256},
+	{asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 3}, x509.ECDSAWithSHA384},
+	{asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 4}, x509.ECDSAWithSHA512},
+	{asn1.ObjectIdentifier{1, 3, 101, 112}, x509.PureEd25519},
+}
+
+// The structures below are the ASN.1 encoding of the requests and responses
+
+type CertID struct {
+	HashAlgorithm pkix.AlgorithmIdentifier
+	NameHash      []byte
+	IssuerKeyHash []byte
+	SerialNumber  *big.Int
+}
+
+type Request struct {
+	TBSRequest TBSRequest
+}
+
+type TBSRequest struct {
+	Version           int           `asn1:"explicit,tag:0,default:0,optional"`
+	RequestorName     asn1.RawValue `asn1:"explicit,tag:1,optional"`
+	RequestList       []SingleRequest
+	RequestExtensions []pkix.Extension `asn1:"explicit,tag:2,optional"`
+}
+
+type SingleRequest struct {
+	CertID CertID
+}
+
+type Response struct {
+	Status        asn1.Enumerated
+	ResponseBytes ResponseBytes `asn1:"explicit,tag:0,optional"`
+}
+
+type ResponseBytes struct {
+	ResponseType asn1.ObjectIdentifier
+	Response     []byte
+}
+
+type BasicResponse struct {
+	TBSResponseData    ResponseData
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          asn1.BitString
+	Certificates       []asn1.RawValue `asn1:"explicit,tag:0,optional"`
+}
+
+// ResponseData is the signed part of the basic responses. Raw is set when
+// parsing, and is marshaled as is if set.
+type ResponseData struct {
+	Raw                asn1.RawContent
+	Version            int `asn1:"explicit,tag:0,default:0,optional"`
+	ResponderID        asn1.RawValue
+	ProducedAt         time.Time `asn1:"generalized"`
+	Responses          []SingleResponse
+	ResponseExtensions []pkix.Extension `asn1:"explicit,tag:1,optional"`
+}
+
+type SingleResponse struct {
+	CertID           CertID
+	Good             asn1.Flag        `asn1:"tag:0,optional"`
+	Revoked          RevokedInfo      `asn1:"tag:1,optional"`
+	Unknown          asn1.Flag        `asn1:"tag:2,optional"`
+	ThisUpdate       time.Time        `asn1:"generalized"`
+	NextUpdate       time.Time        `asn1:"generalized,explicit,tag:0,optional"`
+	SingleExtensions []pkix.Extension `asn1:"explicit,tag:1,optional"`
+}
+
+type RevokedInfo struct {
+	RevocationTime time.Time       `asn1:"generalized"`
+	Reason         asn1.Enumerated `asn1:"explicit,tag:0,optional"`
+}
+
+// NewCertID returns the identifier of the certificate with the given serial
+// number issued by issuer, hashed with SHA-1 as expected by most responders
+func NewCertID(serial *big.Int, issuer *x509.Certificate) (CertID, error) {
+	publicKey, err := SubjectPublicKeyBytes(issuer)
+	if err != nil {
+		return CertID{}, fmt.Errorf("failed to parse the public key of the issuer: %v", err)
+	}
+
+	nameHash := sha1.Sum(issuer.RawSubject)
+	keyHash := sha1.Sum(publicKey)
+
+	return CertID{
+		HashAlgorithm: pkix.AlgorithmIdentifier{
+			Algorithm:  oidSHA1,
+			Parameters: asn1.NullRawValue,
+		},
+		NameHash:      nameHash[:],
+		IssuerKeyHash: keyHash[:],
+		SerialNumber:  serial,
+	}, nil
+}
+
+// MatchesIssuer returns whether the CertID identifies a certificate issued
+// by the given CA
+func (id *CertID) MatchesIssuer(issuer *x509.Certificate) (bool, error) {
+	var hash crypto.Hash
+	for _, h := range hashes {
+		if h.oid.Equal(id.HashAlgorithm.Algorithm) {
+			hash = h.hash
+			break
+		}
+	}
+	if hash == 0 || !hash.Available() {
+		return false, fmt.Errorf("unsupported hash algorithm %v", id.HashAlgorithm.Algorithm)
+	}
+
+	publicKey, err := SubjectPublicKeyBytes(issuer)
+	if err != nil {
+		return false, err
+	}
+
+	h := hash.New()
+	h.Write(issuer.RawSubject)
+	nameHash := h.Sum(nil)
+	h.Reset()
+	h.Write(publicKey)
+	keyHash := h.Sum(nil)
+
+	return bytes.Equal(nameHash, id.NameHash) && bytes.Equal(keyHash, id.IssuerKeyHash), nil
+}
+
+// SubjectPublicKeyBytes returns the contents of the subjectPublicKey bit
+// string of a certificate, which OCSP hashes to identify keys
+func SubjectPublicKeyBytes(cert *x509.Certificate) ([]byte, error) {
+	var spki struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+	if _, err := asn1.Unmarshal(cert.RawSubjectPublicKeyInfo, &spki); err != nil {
+		return nil, err
+	}
+	return spki.PublicKey.RightAlign(), nil
+}
+
+// CreateRequest returns a DER encoded request for the status of the given
+// certificates
+func CreateRequest(ids ...CertID) ([]byte, error) {
+	req := Request{}
+	for _, id := range ids {
+		req.TBSRequest.RequestList = append(req.TBSRequest.RequestList, SingleRequest{CertID: id})
+	}
+	return asn1.Marshal(req)
+}
+
+// ParseResponse parses a DER encoded response and returns its signed data.
+// The response must be signed either by the issuer, or by a responder that
+// the issuer delegated the OCSP signing to and that is valid at the given
+// time.
+func ParseResponse(der []byte, issuer *x509.Certificate, now time.Time) (*ResponseData, error) {
+	var resp Response
+	rest, err := asn1.Unmarshal(der, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the OCSP response: %v", err)
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("trailing data in the OCSP response")
+	}
+	if resp.Status != StatusSuccessful {
+		return nil, fmt.Errorf("OCSP responder returned the error status %d", resp.Status)
+	}
+	if !resp.ResponseBytes.ResponseType.Equal(OIDBasicResponse) {
+		return nil, errors.New("unsupported OCSP response type")
+	}
+
+	var basic BasicResponse
+	rest, err = asn1.Unmarshal(resp.ResponseBytes.Response, &basic)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the basic OCSP response: %v", err)
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("trailing data in the basic OCSP response")
+	}
+
+	responder := issuer
+	if len(basic.Certificates) > 0 {
+		delegate, err := x509.ParseCertificate(basic.Certificates[0].FullBytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse the OCSP responder certificate: %v", err)
+		}
+		if !delegate.Equal(issuer) {
+			if err := checkDelegate(delegate, issuer, now); err != nil {
+				return nil, err
+			}
+			responder = delegate
+		}
+	}
+
+	signatureAlgorithm := x509.UnknownSignatureAlgorithm
+	for _, details := range signatureAlgorithms {
+		if details.oid.Equal(basic.SignatureAlgorithm.Algorithm) {
+			signatureAlgorithm = details.algo
+			break
+		}
+	}
+	if err := responder.CheckSignature(signatureAlgorithm, basic.TBSResponseData.Raw, basic.Signature.RightAlign()); err != nil {
+		return nil, fmt.Errorf("invalid OCSP response signature: %v", err)
+	}
+
+	return &basic.TBSResponseData, nil
+}
+
+// checkDelegate verifies that the responder certificate is issued by the
+// issuer for OCSP signing, and valid at the given time
+func checkDelegate(delegate, issuer *x509.Certificate, now time.Time) error {
+	if err := delegate.CheckSignatureFrom(issuer); err != nil {
+		return fmt.Errorf("OCSP responder certificate is not signed by the issuer: %v", err)
+	}
+
+	var ocspSigning bool
+	for _, usage := range delegate.ExtKeyUsage {
+		if usage == x509.ExtKeyUsageOCSPSigning {
+			ocspSigning = true
+			break
+		}
+	}
+	if !ocspSigning {
+		return errors.New("OCSP responder certificate is not authorized for OCSP signing")
+	}
+
+	if now.Before(delegate.NotBefore) {
+		return errors.New("OCSP responder certificate is not yet valid")
+	}
+	if now.After(delegate.NotAfter) {
+		return errors.New("OCSP responder certificate has expired")
+	}
+	return nil
+}
+
+// FindResponse returns the response for the certificate with the given serial
+// number issued by issuer, or nil if there is none
+func (d *ResponseData) FindResponse(serial *big.Int, issuer *x509.Certificate) *SingleResponse {
+	for i, single := range d.Responses {
+		if single.CertID.SerialNumber == nil || single.CertID.SerialNumber.Cmp(serial) != 0 {
+			continue
+		}
+		if matches, err := single.CertID.MatchesIssuer(issuer); err != nil || !matches {
+			continue
+		}
+		return &d.Responses[i]
+	}
+	return nil
+}
+
+// ErrorResponse returns an unsuccessful response, which is not signed
+func ErrorResponse(status int) []byte {
+	resp, _ := asn1.Marshal(Response{Status: asn1.Enumerated(status)})
+	return resp
+}
+
+// ParseRequest parses a DER encoded request
+func ParseRequest(der []byte) (*Request, error) {
+	var req Request
+	rest, err := asn1.Unmarshal(der, &req)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("trailing data after the OCSP request")
+	}
+	if len(req.TBSRequest.RequestList) == 0 {
+		return nil, errors.New("the OCSP request has no certificate")
+	}
+	return &req, nil
+}
+
+// Nonce returns the nonce extension of the request, if any, which must be
+// echoed in the response
+func (r *Request) Nonce() *pkix.Extension {
+	for _, ext := range r.TBSRequest.RequestExtensions {
+		if ext.Id.Equal(OIDNonce) {
+			return &pkix.Extension{Id: OIDNonce, Value: ext.Value}
+		}
+	}
+	return nil
+}
+
+// Signer signs responses: either the CA itself, or a delegated responder
+// certificate issued by it
+type Signer struct {
+	Certificate *x509.Certificate
+	PrivateKey  crypto.Signer
+	Delegated   bool
+}
+
+// Sign returns a successful response with the given single responses
+func (s *Signer) Sign(responses []SingleResponse, extensions []pkix.Extension) ([]byte, error) {
+	publicKey, err := SubjectPublicKeyBytes(s.Certificate)
+	if err != nil {
+		return nil, err
+	}
+	keyHash := sha1.Sum(publicKey)
+	keyHashBytes, err := asn1.Marshal(keyHash[:])
+	if err != nil {
+		return nil, err
+	}
+
+	tbs := ResponseData{
+		// byKey [2] KeyHash
+		ResponderID: asn1.RawValue{
+			Class:      asn1.ClassContextSpecific,
+			Tag:        2,
+			IsCompound: true,
+			Bytes:      keyHashBytes,
+		},
+		ProducedAt:         time.Now().UTC().Truncate(time.Second),
+		Responses:          responses,
+		ResponseExtensions: extensions,
+	}
+	tbsBytes, err := asn1.Marshal(tbs)
+	if err != nil {
+		return nil, err
+	}
+
+	algorithm, hash, err := certutil.SignatureAlgorithm(s.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := certutil.SignData(s.PrivateKey, hash, tbsBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	basic := BasicResponse{
+		TBSResponseData:    ResponseData{Raw: tbsBytes},
+		SignatureAlgorithm: algorithm,
+		Signature: asn1.BitString{
+			Bytes:     signature,
+			BitLength: 8 * len(signature),
+		},
+	}
+	// Clients need the delegated responder certificate to verify the
+	// response
+	if s.Delegated {
+		basic.Certificates = []asn1.RawValue{{FullBytes: s.Certificate.Raw}}
+	}
+	basicBytes, err := asn1.Marshal(basic)
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(Response{
+		Status: StatusSuccessful,
+		ResponseBytes: ResponseBytes{
+			ResponseType: OIDBasicResponse,
+			Response:     basicBytes,
+		},
+	})
+}
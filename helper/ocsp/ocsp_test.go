@@ -0,0 +1,158 @@
+package ocsp
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCert(t *testing.T, template *x509.Certificate, issuer *testCert) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parent, signer := template, key
+	if issuer != nil {
+		parent, signer = issuer.cert, issuer.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCert{cert: cert, key: key}
+}
+
+// response returns a response signed by the signer, including its
+// certificate if it is not the issuer
+func (signer *testCert) response(t *testing.T, issuer *testCert, serial *big.Int) []byte {
+	certID, err := NewCertID(serial, issuer.cert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	tbsBytes, err := asn1.Marshal(ResponseData{
+		ResponderID: asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 1, IsCompound: true, Bytes: signer.cert.RawSubject},
+		ProducedAt:  now,
+		Responses: []SingleResponse{{
+			CertID:     certID,
+			Good:       true,
+			ThisUpdate: now,
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256(tbsBytes)
+	signature, err := signer.key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	basic := BasicResponse{
+		TBSResponseData:    ResponseData{Raw: tbsBytes},
+		SignatureAlgorithm: pkix.AlgorithmIdentifier{Algorithm: asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}},
+		Signature:          asn1.BitString{Bytes: signature, BitLength: 8 * len(signature)},
+	}
+	if signer != issuer {
+		basic.Certificates = []asn1.RawValue{{FullBytes: signer.cert.Raw}}
+	}
+	basicBytes, err := asn1.Marshal(basic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := asn1.Marshal(Response{
+		ResponseBytes: ResponseBytes{
+			ResponseType: OIDBasicResponse,
+			Response:     basicBytes,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+func TestParseResponse(t *testing.T) {
+	now := time.Now()
+	ca := newTestCert(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "CA"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(time.Hour),
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil)
+	delegate := func(notBefore, notAfter time.Time, usages ...x509.ExtKeyUsage) *testCert {
+		return newTestCert(t, &x509.Certificate{
+			SerialNumber: big.NewInt(2),
+			Subject:      pkix.Name{CommonName: "Responder"},
+			NotBefore:    notBefore,
+			NotAfter:     notAfter,
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  usages,
+		}, ca)
+	}
+	other := newTestCert(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(3),
+		Subject:               pkix.Name{CommonName: "Other CA"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(time.Hour),
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil)
+
+	serial := big.NewInt(42)
+	cases := []struct {
+		name   string
+		signer *testCert
+		err    string
+	}{
+		{"issuer", ca, ""},
+		{"delegate", delegate(now.Add(-time.Hour), now.Add(time.Hour), x509.ExtKeyUsageOCSPSigning), ""},
+		{"expired delegate", delegate(now.Add(-2*time.Hour), now.Add(-time.Hour), x509.ExtKeyUsageOCSPSigning), "has expired"},
+		{"future delegate", delegate(now.Add(time.Hour), now.Add(2*time.Hour), x509.ExtKeyUsageOCSPSigning), "not yet valid"},
+		{"delegate without OCSP signing", delegate(now.Add(-time.Hour), now.Add(time.Hour), x509.ExtKeyUsageClientAuth), "not authorized"},
+		{"other issuer", other, "not signed by the issuer"},
+	}
+	for _, tc := range cases {
+		data, err := ParseResponse(tc.signer.response(t, ca, serial), ca.cert, now)
+		if tc.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("%s: expected an error containing %q, got: %v", tc.name, tc.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if single := data.FindResponse(serial, ca.cert); single == nil || !single.Good {
+			t.Fatalf("%s: bad: %#v", tc.name, data.Responses)
+		}
+		if single := data.FindResponse(big.NewInt(43), ca.cert); single != nil {
+			t.Fatalf("%s: expected no response for another serial", tc.name)
+		}
+		if single := data.FindResponse(serial, other.cert); single != nil {
+			t.Fatalf("%s: expected no response for another issuer", tc.name)
+		}
+	}
+}
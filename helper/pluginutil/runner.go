@@ -41,6 +41,7 @@ type LookRunnerUtil interface {
 // go-plugin.
 type PluginRunner struct {
 	Name           string                      `json:"name" structs:"name"`
+	Version        string                      `json:"version,omitempty" structs:"version"`
 	Command        string                      `json:"command" structs:"command"`
 	Args           []string                    `json:"args" structs:"args"`
 	Sha256         []byte                      `json:"sha256" structs:"sha256"`
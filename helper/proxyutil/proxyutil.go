@@ -19,14 +19,26 @@ type ProxyProtoConfig struct {
 }
 
 func (p *ProxyProtoConfig) SetAuthorizedAddrs(addrs interface{}) error {
-	p.AuthorizedAddrs = make([]*sockaddr.SockAddrMarshaler, 0)
+	authorizedAddrs, err := ParseAuthorizedAddrs(addrs)
+	if err != nil {
+		return err
+	}
+	p.AuthorizedAddrs = authorizedAddrs
+
+	return nil
+}
+
+// ParseAuthorizedAddrs parses a list of addresses and CIDR blocks, given as a
+// comma-separated string or as a list of strings
+func ParseAuthorizedAddrs(addrs interface{}) ([]*sockaddr.SockAddrMarshaler, error) {
+	authorizedAddrs := make([]*sockaddr.SockAddrMarshaler, 0)
 	stringAddrs := make([]string, 0)
 
 	switch addrs.(type) {
 	case string:
 		stringAddrs = strutil.ParseArbitraryStringSlice(addrs.(string), ",")
 		if len(stringAddrs) == 0 {
-			return fmt.Errorf("unable to parse addresses from %v", addrs)
+			return nil, fmt.Errorf("unable to parse addresses from %v", addrs)
 		}
 
 	case []string:
@@ -36,26 +48,26 @@ func (p *ProxyProtoConfig) SetAuthorizedAddrs(addrs interface{}) error {
 		for _, v := range addrs.([]interface{}) {
 			stringAddr, ok := v.(string)
 			if !ok {
-				return fmt.Errorf("error parsing %v as string", v)
+				return nil, fmt.Errorf("error parsing %v as string", v)
 			}
 			stringAddrs = append(stringAddrs, stringAddr)
 		}
 
 	default:
-		return fmt.Errorf("unknown address input type %T", addrs)
+		return nil, fmt.Errorf("unknown address input type %T", addrs)
 	}
 
 	for _, addr := range stringAddrs {
 		sa, err := sockaddr.NewSockAddr(addr)
 		if err != nil {
-			return errwrap.Wrapf("error parsing authorized address: {{err}}", err)
+			return nil, errwrap.Wrapf("error parsing authorized address: {{err}}", err)
 		}
-		p.AuthorizedAddrs = append(p.AuthorizedAddrs, &sockaddr.SockAddrMarshaler{
+		authorizedAddrs = append(authorizedAddrs, &sockaddr.SockAddrMarshaler{
 			SockAddr: sa,
 		})
 	}
 
-	return nil
+	return authorizedAddrs, nil
 }
 
 // WrapInProxyProto wraps the given listener in the PROXY protocol. If behavior
@@ -0,0 +1,125 @@
+package random
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/hashicorp/hcl"
+)
+
+const (
+	// MaxPasswordLength is the maximum length of a password of a policy
+	MaxPasswordLength = 1024
+
+	// passwordGenerationAttempts is the number of passwords generated before
+	// giving up on satisfying the rules of the policy
+	passwordGenerationAttempts = 100
+)
+
+// PasswordPolicy describes the length and the character sets of passwords,
+// in the HCL form:
+//
+//	length = 20
+//	rule "charset" {
+//	  charset = "abcdefghijklmnopqrstuvwxyz"
+//	  min_chars = 1
+//	}
+//
+// Passwords are made of the characters of all the rules.
+type PasswordPolicy struct {
+	Length int            `hcl:"length"`
+	Rules  []*CharsetRule `hcl:"rule"`
+
+	// charset is the set of characters of the passwords
+	charset []rune
+}
+
+// CharsetRule requires a password to contain at least MinChars of the
+// characters of Charset
+type CharsetRule struct {
+	Type     string `hcl:",key"`
+	Charset  string `hcl:"charset"`
+	MinChars int    `hcl:"min_chars"`
+}
+
+// ParsePasswordPolicy parses and validates the HCL definition of a password
+// policy
+func ParsePasswordPolicy(raw string) (*PasswordPolicy, error) {
+	var p PasswordPolicy
+	if err := hcl.Decode(&p, raw); err != nil {
+		return nil, fmt.Errorf("failed to parse password policy: %s", err)
+	}
+
+	if p.Length <= 0 || p.Length > MaxPasswordLength {
+		return nil, fmt.Errorf("password policy length must be between 1 and %d", MaxPasswordLength)
+	}
+	if len(p.Rules) == 0 {
+		return nil, fmt.Errorf("password policy must have at least one charset rule")
+	}
+
+	seen := make(map[rune]bool)
+	minChars := 0
+	for _, rule := range p.Rules {
+		switch {
+		case rule.Type != "charset":
+			return nil, fmt.Errorf("unsupported password policy rule %q", rule.Type)
+		case rule.Charset == "":
+			return nil, fmt.Errorf("charset of password policy rule cannot be empty")
+		case rule.MinChars < 0:
+			return nil, fmt.Errorf("min_chars of password policy rule cannot be negative")
+		}
+		minChars += rule.MinChars
+
+		for _, r := range rule.Charset {
+			if !seen[r] {
+				seen[r] = true
+				p.charset = append(p.charset, r)
+			}
+		}
+	}
+	if minChars > p.Length {
+		return nil, fmt.Errorf("password policy requires %d characters but has a length of %d", minChars, p.Length)
+	}
+
+	return &p, nil
+}
+
+// Generate returns a random password satisfying the rules of the policy
+func (p *PasswordPolicy) Generate() (string, error) {
+	max := big.NewInt(int64(len(p.charset)))
+	password := make([]rune, p.Length)
+	for attempt := 0; attempt < passwordGenerationAttempts; attempt++ {
+		for i := range password {
+			n, err := rand.Int(rand.Reader, max)
+			if err != nil {
+				return "", err
+			}
+			password[i] = p.charset[n.Int64()]
+		}
+
+		if p.satisfiedBy(password) {
+			return string(password), nil
+		}
+	}
+
+	return "", fmt.Errorf("failed to generate a password satisfying the password policy after %d attempts", passwordGenerationAttempts)
+}
+
+// satisfiedBy returns whether the password contains the minimum number of
+// characters of each rule
+func (p *PasswordPolicy) satisfiedBy(password []rune) bool {
+	for _, rule := range p.Rules {
+		count := 0
+		for _, r := range password {
+			if strings.ContainsRune(rule.Charset, r) {
+				count++
+			}
+		}
+		if count < rule.MinChars {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,63 @@
+package random
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPasswordPolicy(t *testing.T) {
+	policy, err := ParsePasswordPolicy(`
+length = 16
+rule "charset" {
+	charset = "abcdefghijklmnopqrstuvwxyz"
+	min_chars = 1
+}
+rule "charset" {
+	charset = "0123456789"
+	min_chars = 4
+}
+rule "charset" {
+	charset = "-_"
+	min_chars = 1
+}
+`)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	for i := 0; i < 100; i++ {
+		password, err := policy.Generate()
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if len(password) != 16 {
+			t.Fatalf("bad: length of %q", password)
+		}
+		if strings.Trim(password, "abcdefghijklmnopqrstuvwxyz0123456789-_") != "" {
+			t.Fatalf("bad: characters of %q", password)
+		}
+		if !strings.ContainsAny(password, "-_") {
+			t.Fatalf("bad: %q does not satisfy the rules", password)
+		}
+	}
+
+	for _, raw := range []string{
+		`length = 20`,
+		`length = 0
+rule "charset" { charset = "abc" }`,
+		`length = 2
+rule "charset" {
+	charset = "abc"
+	min_chars = 3
+}`,
+		`length = 10
+rule "charset" { charset = "" }`,
+		`length = 10
+rule "words" { charset = "abc" }`,
+		`length = `,
+	} {
+		if _, err := ParsePasswordPolicy(raw); err == nil {
+			t.Fatalf("%q: expected an error", raw)
+		}
+	}
+}
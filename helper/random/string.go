@@ -0,0 +1,25 @@
+package random
+
+import (
+	"crypto/rand"
+	"math/big"
+)
+
+// base62Charset is the charset of Base62
+const base62Charset = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+
+// Base62 returns a random string of [A-Za-z0-9] of the length, e.g. to be
+// used as an identifier in URLs
+func Base62(length int) (string, error) {
+	max := big.NewInt(int64(len(base62Charset)))
+	out := make([]byte, length)
+	for i := range out {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		out[i] = base62Charset[n.Int64()]
+	}
+
+	return string(out), nil
+}
@@ -0,0 +1,25 @@
+package random
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestBase62(t *testing.T) {
+	matcher := regexp.MustCompile(`^[A-Za-z0-9]{24}$`)
+
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		out, err := Base62(24)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !matcher.MatchString(out) {
+			t.Fatalf("bad: %q", out)
+		}
+		if seen[out] {
+			t.Fatalf("duplicate: %q", out)
+		}
+		seen[out] = true
+	}
+}
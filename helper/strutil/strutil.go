@@ -236,6 +236,26 @@ func RemoveDuplicates(items []string, lowercase bool) []string {
 	return items
 }
 
+// RemoveDuplicatesStable removes duplicate and empty elements from a slice of
+// strings, preserving the order of the first occurrence of each element.
+// Elements are trimmed of whitespace and optionally lowercased.
+func RemoveDuplicatesStable(items []string, lowercase bool) []string {
+	seen := make(map[string]bool, len(items))
+	result := make([]string, 0, len(items))
+	for _, item := range items {
+		item = strings.TrimSpace(item)
+		if lowercase {
+			item = strings.ToLower(item)
+		}
+		if item == "" || seen[item] {
+			continue
+		}
+		seen[item] = true
+		result = append(result, item)
+	}
+	return result
+}
+
 // EquivalentSlices checks whether the given string sets are equivalent, as in,
 // they contain the same values.
 func EquivalentSlices(a, b []string) bool {
@@ -324,3 +344,21 @@ func AppendIfMissing(slice []string, i string) []string {
 	}
 	return append(slice, i)
 }
+
+// StrListPage returns the page of the strings of the list, in lexicographic
+// order, which are after the given string, with at most limit strings. There
+// is no limit if limit is not positive. The list is not modified.
+func StrListPage(items []string, after string, limit int) []string {
+	sorted := make([]string, len(items))
+	copy(sorted, items)
+	sort.Strings(sorted)
+
+	start := sort.Search(len(sorted), func(i int) bool {
+		return sorted[i] > after
+	})
+	page := sorted[start:]
+	if limit > 0 && len(page) > limit {
+		page = page[:limit]
+	}
+	return page
+}
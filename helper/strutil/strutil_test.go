@@ -423,3 +423,53 @@ func TestStrUtil_RemoveDuplicates(t *testing.T) {
 		}
 	}
 }
+
+func TestStrUtil_RemoveDuplicatesStable(t *testing.T) {
+	type tCase struct {
+		input     []string
+		expect    []string
+		lowercase bool
+	}
+
+	tCases := []tCase{
+		tCase{[]string{}, []string{}, false},
+		tCase{[]string{}, []string{}, true},
+		tCase{[]string{"b", "a", "b"}, []string{"b", "a"}, false},
+		tCase{[]string{"b", " ", "A", "a"}, []string{"b", "A", "a"}, false},
+		tCase{[]string{"b", "A", "a"}, []string{"b", "a"}, true},
+	}
+
+	for _, tc := range tCases {
+		actual := RemoveDuplicatesStable(tc.input, tc.lowercase)
+
+		if !reflect.DeepEqual(actual, tc.expect) {
+			t.Fatalf("Bad testcase %#v, expected %v, got %v", tc, tc.expect, actual)
+		}
+	}
+}
+
+func TestStrUtil_StrListPage(t *testing.T) {
+	type tCase struct {
+		input  []string
+		after  string
+		limit  int
+		expect []string
+	}
+
+	tCases := []tCase{
+		tCase{[]string{}, "", 0, []string{}},
+		tCase{[]string{"c", "a", "b/"}, "", 0, []string{"a", "b/", "c"}},
+		tCase{[]string{"c", "a", "b/"}, "", 2, []string{"a", "b/"}},
+		tCase{[]string{"c", "a", "b/"}, "a", 0, []string{"b/", "c"}},
+		tCase{[]string{"c", "a", "b/"}, "aa", 1, []string{"b/"}},
+		tCase{[]string{"c", "a", "b/"}, "c", 0, []string{}},
+	}
+
+	for _, tc := range tCases {
+		actual := StrListPage(tc.input, tc.after, tc.limit)
+
+		if !reflect.DeepEqual(actual, tc.expect) {
+			t.Fatalf("Bad testcase %#v, expected %v, got %v", tc, tc.expect, actual)
+		}
+	}
+}
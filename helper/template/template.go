@@ -0,0 +1,109 @@
+// This package renders Go templates used to generate names, such as the
+// usernames of dynamic database credentials. Besides the standard template
+// functions, templates can use:
+//
+//	random <length>           - a random string of [A-Za-z0-9] of the length
+//	truncate <length> <str>   - the string truncated to at most the length
+//	unix_time                 - the current Unix time, in seconds
+//	uppercase <str>           - the string in upper case
+//	lowercase <str>           - the string in lower case
+//	replace <old> <new> <str> - the string with every old replaced by new
+package template
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const alphanumeric = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+
+// maxRandomLength is the longest random string a template can request
+const maxRandomLength = 128
+
+// Template is a parsed template
+type Template struct {
+	tmpl *template.Template
+}
+
+// Parse parses the template text, failing on unknown functions
+func Parse(text string) (*Template, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, fmt.Errorf("template is empty")
+	}
+
+	tmpl, err := template.New("template").
+		Option("missingkey=error").
+		Funcs(template.FuncMap{
+			"random":    random,
+			"truncate":  truncate,
+			"unix_time": unixTime,
+			"uppercase": strings.ToUpper,
+			"lowercase": strings.ToLower,
+			"replace":   replace,
+		}).
+		Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Template{tmpl: tmpl}, nil
+}
+
+// Generate renders the template with the data, whose fields and keys the
+// template can reference. Rendering to an empty string is an error.
+func (t *Template) Generate(data interface{}) (string, error) {
+	var buf bytes.Buffer
+	if err := t.tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	result := strings.TrimSpace(buf.String())
+	if result == "" {
+		return "", fmt.Errorf("template rendered an empty string")
+	}
+	return result, nil
+}
+
+func random(length int) (string, error) {
+	if length <= 0 || length > maxRandomLength {
+		return "", fmt.Errorf("random length must be between 1 and %d", maxRandomLength)
+	}
+
+	result := make([]byte, 0, length)
+	buf := make([]byte, length)
+	for len(result) < length {
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+		for _, b := range buf {
+			// 248 is the largest multiple of 62 below 256, rejecting the
+			// bytes above it keeps the characters uniformly distributed
+			if b < 248 && len(result) < length {
+				result = append(result, alphanumeric[int(b)%len(alphanumeric)])
+			}
+		}
+	}
+	return string(result), nil
+}
+
+func truncate(length int, s string) (string, error) {
+	if length <= 0 {
+		return "", fmt.Errorf("truncate length must be positive")
+	}
+	if len(s) > length {
+		s = s[:length]
+	}
+	return s, nil
+}
+
+func unixTime() string {
+	return fmt.Sprint(time.Now().Unix())
+}
+
+func replace(old, new, s string) string {
+	return strings.Replace(s, old, new, -1)
+}
@@ -0,0 +1,66 @@
+package template
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestTemplate_Generate(t *testing.T) {
+	data := map[string]string{
+		"DisplayName": "token-with-a-long-name",
+		"RoleName":    "Readonly_Role",
+	}
+
+	cases := map[string]string{
+		`v_{{.RoleName}}`:                             `^v_Readonly_Role$`,
+		`v_{{.DisplayName | truncate 5}}`:             `^v_token$`,
+		`{{.RoleName | lowercase}}_{{random 8}}`:      `^readonly_role_[A-Za-z0-9]{8}$`,
+		`{{.RoleName | uppercase}}`:                   `^READONLY_ROLE$`,
+		`{{.DisplayName | replace "-" "_"}}`:          `^token_with_a_long_name$`,
+		`{{.RoleName | truncate 4}}-{{unix_time}}`:    `^Read-[0-9]+$`,
+		`{{printf "%s-%s" .RoleName (random 20)}}`:    `^Readonly_Role-[A-Za-z0-9]{20}$`,
+		`  {{.RoleName | truncate 100 | lowercase}} `: `^readonly_role$`,
+	}
+
+	for text, expected := range cases {
+		tmpl, err := Parse(text)
+		if err != nil {
+			t.Fatalf("%q: %s", text, err)
+		}
+		result, err := tmpl.Generate(data)
+		if err != nil {
+			t.Fatalf("%q: %s", text, err)
+		}
+		if !regexp.MustCompile(expected).MatchString(result) {
+			t.Fatalf("%q: bad: %q", text, result)
+		}
+	}
+}
+
+func TestTemplate_Errors(t *testing.T) {
+	for _, text := range []string{
+		``,
+		`{{.RoleName`,
+		`{{unknown .RoleName}}`,
+	} {
+		if _, err := Parse(text); err == nil {
+			t.Fatalf("%q: expected a parse error", text)
+		}
+	}
+
+	for _, text := range []string{
+		`{{.Missing}}`,
+		`{{random 0}}`,
+		`{{random 1000}}`,
+		`{{.RoleName | truncate 0}}`,
+		`{{if false}}x{{end}}`,
+	} {
+		tmpl, err := Parse(text)
+		if err != nil {
+			t.Fatalf("%q: %s", text, err)
+		}
+		if _, err := tmpl.Generate(map[string]string{"RoleName": "role"}); err == nil {
+			t.Fatalf("%q: expected an error", text)
+		}
+	}
+}
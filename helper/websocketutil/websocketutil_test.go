@@ -0,0 +1,178 @@
+package websocketutil
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testEchoServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Test") != "yes" {
+			http.Error(w, "missing header", http.StatusForbidden)
+			return
+		}
+		conn, err := Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		for {
+			opcode, message, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if opcode == OpText {
+				err = conn.WriteText(message)
+			} else {
+				err = conn.WriteBinary(message)
+			}
+			if err != nil {
+				return
+			}
+		}
+	}))
+}
+
+func testDial(t *testing.T, url string, header http.Header) (*Conn, *http.Response, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return Dial(ctx, req, nil)
+}
+
+func TestConn(t *testing.T) {
+	server := testEchoServer(t)
+	defer server.Close()
+
+	conn, _, err := testDial(t, server.URL, http.Header{"X-Test": []string{"yes"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// Messages of every length encoding are echoed
+	for _, size := range []int{0, 10, 125, 126, 1000, 65535, 65536, 200000} {
+		message := bytes.Repeat([]byte("a"), size)
+		if err := conn.WriteText(message); err != nil {
+			t.Fatal(err)
+		}
+		opcode, echo, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if opcode != OpText || !bytes.Equal(echo, message) {
+			t.Fatalf("bad echo of %d bytes: opcode %d, %d bytes", size, opcode, len(echo))
+		}
+	}
+
+	if err := conn.WriteBinary([]byte{0, 1, 2}); err != nil {
+		t.Fatal(err)
+	}
+	opcode, echo, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opcode != OpBinary || !bytes.Equal(echo, []byte{0, 1, 2}) {
+		t.Fatalf("bad: %d %v", opcode, echo)
+	}
+
+	// Pings are answered by the peer while it reads
+	if err := conn.WritePing([]byte("ping")); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.WriteText([]byte("after ping")); err != nil {
+		t.Fatal(err)
+	}
+	if _, echo, err := conn.ReadMessage(); err != nil || string(echo) != "after ping" {
+		t.Fatalf("bad: %q %v", echo, err)
+	}
+
+	// The closing handshake is answered by the peer
+	if err := conn.WriteClose(CloseNormal, "bye"); err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = conn.ReadMessage()
+	closeErr, ok := err.(*CloseError)
+	if !ok || closeErr.Code != CloseNormal {
+		t.Fatalf("bad: %v", err)
+	}
+	if err := conn.WriteText([]byte("closed")); err == nil {
+		t.Fatal("expected an error writing to a closing websocket")
+	}
+}
+
+func TestConn_maxMessageSize(t *testing.T) {
+	server := testEchoServer(t)
+	defer server.Close()
+
+	conn, _, err := testDial(t, server.URL, http.Header{"X-Test": []string{"yes"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if err := conn.WriteText(bytes.Repeat([]byte("a"), DefaultMaxMessageSize+1)); err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = conn.ReadMessage()
+	closeErr, ok := err.(*CloseError)
+	if !ok || closeErr.Code != CloseMessageTooBig {
+		t.Fatalf("bad: %v", err)
+	}
+}
+
+func TestDial_handshakeFailure(t *testing.T) {
+	server := testEchoServer(t)
+	defer server.Close()
+
+	conn, resp, err := testDial(t, server.URL, nil)
+	if err == nil || conn != nil {
+		t.Fatal("expected the handshake to fail")
+	}
+	if resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("bad: %#v", resp)
+	}
+	buf := new(bytes.Buffer)
+	buf.ReadFrom(resp.Body)
+	if !strings.Contains(buf.String(), "missing header") {
+		t.Fatalf("bad: %q", buf.String())
+	}
+}
+
+func TestUpgrade_notUpgradeRequest(t *testing.T) {
+	server := testEchoServer(t)
+	defer server.Close()
+
+	req, err := http.NewRequest("GET", server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("X-Test", "yes")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("bad: %d", resp.StatusCode)
+	}
+}
+
+func TestAcceptKey(t *testing.T) {
+	// The example of RFC 6455
+	if key := acceptKey("dGhlIHNhbXBsZSBub25jZQ=="); key != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("bad: %s", key)
+	}
+}
@@ -577,3 +577,86 @@ func TestHTTP_Forwarding_HelpOperation(t *testing.T) {
 	testHelp(cores[0].Client)
 	testHelp(cores[1].Client)
 }
+
+func TestHTTP_Forwarding_PerformanceStandby(t *testing.T) {
+	cluster := vault.NewTestCluster(t, &vault.CoreConfig{
+		PerformanceStandby: true,
+	}, &vault.TestClusterOptions{
+		HandlerFunc: Handler,
+	})
+	cluster.Start()
+	defer cluster.Cleanup()
+	cores := cluster.Cores
+
+	vault.TestWaitActive(t, cores[0].Core)
+	start := time.Now()
+	for {
+		if perfStandby, _ := cores[1].Core.PerformanceStandby(); perfStandby {
+			break
+		}
+		if time.Since(start) > 5*time.Second {
+			t.Fatal("standby did not become a performance standby")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	if _, err := cores[0].Client.Logical().Write("secret/foo", map[string]interface{}{"bar": "baz"}); err != nil {
+		t.Fatal(err)
+	}
+
+	transport := cleanhttp.DefaultTransport()
+	transport.TLSClientConfig = cores[0].TLSConfig
+	if err := http2.ConfigureTransport(transport); err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	addr := fmt.Sprintf("https://127.0.0.1:%d/v1/secret/foo", cores[1].Listeners[0].Address.Port)
+
+	do := func(method, body string, noForward bool) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(method, addr, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set(AuthHeaderName, cluster.RootToken)
+		if noForward {
+			req.Header.Set(NoRequestForwardingHeaderName, "true")
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	// Reads are served by the standby, even when forwarding is disabled
+	resp := do("GET", "", true)
+	var actual map[string]interface{}
+	testResponseStatus(t, resp, 200)
+	testResponseBody(t, resp, &actual)
+	if actual["data"].(map[string]interface{})["bar"] != "baz" {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	// Writes are forwarded, or redirected when forwarding is disabled
+	resp = do("PUT", `{"bar": "qux"}`, true)
+	resp.Body.Close()
+	testResponseStatus(t, resp, 307)
+
+	resp = do("PUT", `{"bar": "qux"}`, false)
+	resp.Body.Close()
+	testResponseStatus(t, resp, 204)
+
+	secret, err := cores[0].Client.Logical().Read("secret/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret.Data["bar"] != "qux" {
+		t.Fatalf("bad: %#v", secret.Data)
+	}
+}
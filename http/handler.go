@@ -4,13 +4,18 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
+	"net"
 	"net/http"
+	"net/textproto"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/hashicorp/errwrap"
 	cleanhttp "github.com/hashicorp/go-cleanhttp"
+	sockaddr "github.com/hashicorp/go-sockaddr"
 	"github.com/hashicorp/vault/helper/consts"
 	"github.com/hashicorp/vault/helper/jsonutil"
 	"github.com/hashicorp/vault/helper/parseutil"
@@ -46,6 +51,19 @@ const (
 	// soft-mandatory Sentinel policies.
 	PolicyOverrideHeaderName = "X-Vault-Policy-Override"
 
+	// CacheBypassHeaderName is the header set to have the reads of the
+	// request bypass the physical cache
+	CacheBypassHeaderName = "X-Vault-Cache-Bypass"
+
+	// IndexHeaderName is the header carrying the index state of the storage
+	// writes. It is returned on writes, and the reads of a request setting
+	// it wait for the storage backend to reflect the writes up to it.
+	IndexHeaderName = "X-Vault-Index"
+
+	// NamespaceHeaderName is the header carrying the path of the namespace
+	// of the request, which is prepended to the path of the request
+	NamespaceHeaderName = "X-Vault-Namespace"
+
 	// MaxRequestSize is the maximum accepted request size. This is to prevent
 	// a denial of service attack where no Content-Length is provided and the server
 	// is fed ever more data until it exhausts memory.
@@ -70,13 +88,23 @@ func Handler(core *vault.Core) http.Handler {
 	mux.Handle("/v1/sys/health", handleSysHealth(core))
 	mux.Handle("/v1/sys/generate-root/attempt", handleRequestForwarding(core, handleSysGenerateRootAttempt(core, vault.GenerateStandardRootTokenStrategy)))
 	mux.Handle("/v1/sys/generate-root/update", handleRequestForwarding(core, handleSysGenerateRootUpdate(core, vault.GenerateStandardRootTokenStrategy)))
+	mux.Handle("/v1/sys/replication/status", handleRequestForwarding(core, handleSysReplicationStatus(core)))
+	mux.Handle("/v1/sys/replication/dr/status", handleRequestForwarding(core, handleSysDRReplicationStatus(core)))
+	mux.Handle("/v1/sys/replication/dr/secondary/promote", handleRequestForwarding(core, handleSysDRSecondaryPromote(core)))
+	mux.Handle("/v1/sys/replication/dr/secondary/update-primary", handleRequestForwarding(core, handleSysDRSecondaryUpdatePrimary(core)))
+	mux.Handle("/v1/sys/replication/dr/secondary/operation-token/delete", handleRequestForwarding(core, handleSysDROperationTokenDelete(core)))
+	mux.Handle("/v1/sys/replication/dr/secondary/generate-operation-token/attempt", handleRequestForwarding(core, handleSysGenerateRootAttempt(core, vault.GenerateDROperationTokenStrategy)))
+	mux.Handle("/v1/sys/replication/dr/secondary/generate-operation-token/update", handleRequestForwarding(core, handleSysGenerateRootUpdate(core, vault.GenerateDROperationTokenStrategy)))
 	mux.Handle("/v1/sys/rekey/init", handleRequestForwarding(core, handleSysRekeyInit(core, false)))
 	mux.Handle("/v1/sys/rekey/update", handleRequestForwarding(core, handleSysRekeyUpdate(core, false)))
+	mux.Handle("/v1/sys/rekey/verify", handleRequestForwarding(core, handleSysRekeyVerify(core, false)))
 	mux.Handle("/v1/sys/rekey-recovery-key/init", handleRequestForwarding(core, handleSysRekeyInit(core, true)))
 	mux.Handle("/v1/sys/rekey-recovery-key/update", handleRequestForwarding(core, handleSysRekeyUpdate(core, true)))
+	mux.Handle("/v1/sys/rekey-recovery-key/verify", handleRequestForwarding(core, handleSysRekeyVerify(core, true)))
 	mux.Handle("/v1/sys/wrapping/lookup", handleRequestForwarding(core, handleLogical(core, false, wrappingVerificationFunc)))
 	mux.Handle("/v1/sys/wrapping/rewrap", handleRequestForwarding(core, handleLogical(core, false, wrappingVerificationFunc)))
 	mux.Handle("/v1/sys/wrapping/unwrap", handleRequestForwarding(core, handleLogical(core, false, wrappingVerificationFunc)))
+	mux.Handle("/v1/sys/events/subscribe", handleSysEventsSubscribe(core))
 	for _, path := range injectDataIntoTopRoutes {
 		mux.Handle(path, handleRequestForwarding(core, handleLogical(core, true, nil)))
 	}
@@ -111,6 +139,96 @@ func wrapGenericHandler(h http.Handler) http.Handler {
 	})
 }
 
+// WrapForwardedForHandler wraps the handler so that the remote address of
+// the requests coming from the authorized addresses is taken from their
+// X-Forwarded-For header. hopSkips is the number of addresses to skip from
+// the end of the header, for requests going through multiple proxies. The
+// requests without the header, or coming from an address that is not
+// authorized, are rejected or passed through unchanged.
+func WrapForwardedForHandler(h http.Handler, authorizedAddrs []*sockaddr.SockAddrMarshaler, rejectNotPresent, rejectNonAuthz bool, hopSkips int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers, headersOK := r.Header[textproto.CanonicalMIMEHeaderKey("X-Forwarded-For")]
+		if !headersOK || len(headers) == 0 {
+			if !rejectNotPresent {
+				h.ServeHTTP(w, r)
+				return
+			}
+			respondError(w, http.StatusBadRequest, fmt.Errorf("missing x-forwarded-for header and configured to reject when not present"))
+			return
+		}
+
+		host, port, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, errwrap.Wrapf("error parsing client hostport: {{err}}", err))
+			return
+		}
+
+		addr, err := sockaddr.NewIPAddr(host)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, errwrap.Wrapf("error parsing client address: {{err}}", err))
+			return
+		}
+
+		var found bool
+		for _, authz := range authorizedAddrs {
+			if authz.Contains(addr) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			if !rejectNonAuthz {
+				h.ServeHTTP(w, r)
+				return
+			}
+			respondError(w, http.StatusBadRequest, fmt.Errorf("client address not authorized for x-forwarded-for and configured to reject connection"))
+			return
+		}
+
+		// The header can be repeated, and each value can hold a
+		// comma-separated list of addresses: the last address is the one
+		// added by the closest proxy
+		var acc []string
+		for _, header := range headers {
+			for _, v := range strings.Split(header, ",") {
+				acc = append(acc, strings.TrimSpace(v))
+			}
+		}
+
+		indexToUse := len(acc) - 1 - hopSkips
+		if indexToUse < 0 {
+			respondError(w, http.StatusBadRequest, fmt.Errorf("malformed x-forwarded-for configuration or request, hops to skip (%d) would skip before earliest chain link (chain length %d)", hopSkips, len(acc)))
+			return
+		}
+
+		clientAddr := acc[indexToUse]
+		if net.ParseIP(clientAddr) == nil {
+			respondError(w, http.StatusBadRequest, fmt.Errorf("invalid address %q in x-forwarded-for header", clientAddr))
+			return
+		}
+		r.RemoteAddr = net.JoinHostPort(clientAddr, port)
+		h.ServeHTTP(w, r)
+	})
+}
+
+// WrapMaxInFlightHandler wraps the handler so that at most max requests are
+// handled at once. The requests beyond it are rejected with a 503 rather than
+// queued, so that an overloaded node sheds load instead of piling it up.
+func WrapMaxInFlightHandler(h http.Handler, max int) http.Handler {
+	sem := make(chan struct{}, max)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case sem <- struct{}{}:
+		default:
+			respondError(w, http.StatusServiceUnavailable, logical.ErrInFlightRequestsExceeded)
+			return
+		}
+		defer func() { <-sem }()
+
+		h.ServeHTTP(w, r)
+	})
+}
+
 // A lookup on a token that is about to expire returns nil, which means by the
 // time we can validate a wrapping token lookup will return nil since it will
 // be revoked after the call. So we have to do the validation here.
@@ -119,6 +237,11 @@ func wrappingVerificationFunc(core *vault.Core, req *logical.Request) error {
 		return fmt.Errorf("invalid request")
 	}
 
+	// The batch rewraps validate each of the wrapping tokens they are given
+	if strings.HasSuffix(req.Path, "sys/wrapping/rewrap") && req.Data != nil && req.Data["tokens"] != nil {
+		return nil
+	}
+
 	valid, err := core.ValidateWrappingToken(req)
 	if err != nil {
 		return fmt.Errorf("error validating wrapping token: %v", err)
@@ -196,42 +319,80 @@ func handleRequestForwarding(core *vault.Core, handler http.Handler) http.Handle
 			return
 		}
 
-		// Attempt forwarding the request. If we cannot forward -- perhaps it's
-		// been disabled on the active node -- this will return with an
-		// ErrCannotForward and we simply fall back
-		statusCode, header, retBytes, err := core.ForwardRequest(r)
-		if err != nil {
-			if err == vault.ErrCannotForward {
-				core.Logger().Trace("http/handleRequestForwarding: cannot forward (possibly disabled on active node), falling back")
-			} else {
-				core.Logger().Error("http/handleRequestForwarding: error forwarding request", "error", err)
-			}
-
-			// Fall back to redirection
+		// Performance standbys attempt to serve the reads of secrets, and
+		// forward the requests they cannot serve
+		if perfStandby, _ := core.PerformanceStandby(); perfStandby && perfStandbyReadRequest(r) {
 			handler.ServeHTTP(w, r)
 			return
 		}
 
-		if header != nil {
-			for k, v := range header {
-				w.Header()[k] = v
-			}
+		if !forwardRequest(core, w, r) {
+			// Fall back to redirection
+			handler.ServeHTTP(w, r)
 		}
-
-		w.WriteHeader(statusCode)
-		w.Write(retBytes)
 		return
 	})
 }
 
+// perfStandbyReadRequest checks if the request is a read which performance
+// standbys may be able to serve
+func perfStandbyReadRequest(r *http.Request) bool {
+	if r.Method != "GET" && r.Method != "LIST" {
+		return false
+	}
+	if r.URL.Query().Get("help") != "" {
+		return false
+	}
+	// The index states are the ones of the writes on the active node
+	if r.Header.Get(IndexHeaderName) != "" {
+		return false
+	}
+	return !strings.HasPrefix(r.URL.Path, "/v1/sys/")
+}
+
+// forwardRequest forwards the request to the active node and writes its
+// response. If we cannot forward -- perhaps it's been disabled on the active
+// node -- it returns false and nothing is written.
+func forwardRequest(core *vault.Core, w http.ResponseWriter, r *http.Request) bool {
+	statusCode, header, retBytes, err := core.ForwardRequest(r)
+	if err != nil {
+		if err == vault.ErrCannotForward {
+			core.Logger().Trace("http/handleRequestForwarding: cannot forward (possibly disabled on active node), falling back")
+		} else {
+			core.Logger().Error("http/handleRequestForwarding: error forwarding request", "error", err)
+		}
+		return false
+	}
+
+	if header != nil {
+		for k, v := range header {
+			w.Header()[k] = v
+		}
+	}
+
+	w.WriteHeader(statusCode)
+	w.Write(retBytes)
+	return true
+}
+
 // request is a helper to perform a request and properly exit in the
 // case of an error.
 func request(core *vault.Core, w http.ResponseWriter, rawReq *http.Request, r *logical.Request) (*logical.Response, bool) {
+	// Cancel the handling of the request when the client goes away
+	r.SetContext(rawReq.Context())
 	resp, err := core.HandleRequest(r)
 	if errwrap.Contains(err, consts.ErrStandby.Error()) {
 		respondStandby(core, w, rawReq.URL)
 		return resp, false
 	}
+	if errwrap.Contains(err, consts.ErrPerformanceStandbyForward.Error()) {
+		// The performance standby cannot serve the request, forward it
+		// unless forwarding is disabled by the client
+		if rawReq.Header.Get(NoRequestForwardingHeaderName) != "" || !forwardRequest(core, w, rawReq) {
+			respondStandby(core, w, rawReq.URL)
+		}
+		return resp, false
+	}
 	if respondErrorCommon(w, r, resp, err) {
 		return resp, false
 	}
@@ -284,8 +445,15 @@ func respondStandby(core *vault.Core, w http.ResponseWriter, reqURL *url.URL) {
 
 // requestAuth adds the token to the logical.Request if it exists.
 func requestAuth(core *vault.Core, r *http.Request, req *logical.Request) *logical.Request {
-	// Attach the header value if we have it
-	if v := r.Header.Get(AuthHeaderName); v != "" {
+	// Attach the header value if we have it. A bearer token is accepted for
+	// clients which cannot set custom headers, such as Prometheus.
+	v := r.Header.Get(AuthHeaderName)
+	if v == "" {
+		if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+			v = strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+		}
+	}
+	if v != "" {
 		req.ClientToken = v
 
 		// Also attach the accessor if we have it. This doesn't fail if it
@@ -302,6 +470,16 @@ func requestAuth(core *vault.Core, r *http.Request, req *logical.Request) *logic
 	return req
 }
 
+// requestNamespacePath prepends the namespace of the X-Vault-Namespace header,
+// if any, to the path of the request
+func requestNamespacePath(r *http.Request, path string) string {
+	ns := strings.Trim(r.Header.Get(NamespaceHeaderName), "/")
+	if ns == "" {
+		return path
+	}
+	return ns + "/" + path
+}
+
 // requestWrapInfo adds the WrapInfo value to the logical.Request if wrap info exists
 func requestWrapInfo(r *http.Request, req *logical.Request) (*logical.Request, error) {
 	// First try for the header value
@@ -332,6 +510,57 @@ func requestWrapInfo(r *http.Request, req *logical.Request) (*logical.Request, e
 	return req, nil
 }
 
+// requestMFACreds adds the MFA credentials of the X-Vault-MFA headers to the
+// logical.Request. The value of each header is the name of the MFA method,
+// optionally followed by a colon and the credential, e.g. a TOTP passcode.
+func requestMFACreds(r *http.Request, req *logical.Request) (*logical.Request, error) {
+	values := r.Header[canonicalMFAHeaderName]
+	if len(values) == 0 {
+		return req, nil
+	}
+
+	creds := logical.MFACreds{}
+	for _, value := range values {
+		split := strings.SplitN(value, ":", 2)
+		name := strings.TrimSpace(split[0])
+		if name == "" {
+			return req, fmt.Errorf("missing MFA method name")
+		}
+		if len(split) == 1 {
+			creds[name] = append(creds[name], "")
+			continue
+		}
+		creds[name] = append(creds[name], split[1])
+	}
+	req.MFACreds = creds
+
+	return req, nil
+}
+
+// requestCacheBypass marks the logical.Request to bypass the physical cache
+// if the X-Vault-Cache-Bypass header is set to true
+func requestCacheBypass(r *http.Request, req *logical.Request) (*logical.Request, error) {
+	value := r.Header.Get(CacheBypassHeaderName)
+	if value == "" {
+		return req, nil
+	}
+
+	bypass, err := strconv.ParseBool(value)
+	if err != nil {
+		return req, err
+	}
+	req.CacheBypass = bypass
+
+	return req, nil
+}
+
+// requestIndexState sets the index state the reads of the logical.Request
+// wait for from the X-Vault-Index header
+func requestIndexState(r *http.Request, req *logical.Request) *logical.Request {
+	req.IndexState = r.Header.Get(IndexHeaderName)
+	return req
+}
+
 func respondError(w http.ResponseWriter, status int, err error) {
 	logical.AdjustErrorStatusCode(&status, err)
 
@@ -353,6 +582,11 @@ func respondErrorCommon(w http.ResponseWriter, req *logical.Request, resp *logic
 		return false
 	}
 
+	// Tell the clients rejected by a rate limit quota when to retry
+	if quotaErr, ok := errwrap.GetType(err, new(logical.RateLimitQuotaError)).(*logical.RateLimitQuotaError); ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(quotaErr.RetryAfter.Seconds()))))
+	}
+
 	respondError(w, statusCode, newErr)
 	return true
 }
@@ -12,6 +12,7 @@ import (
 
 	"github.com/hashicorp/go-cleanhttp"
 	"github.com/hashicorp/vault/helper/consts"
+	"github.com/hashicorp/vault/helper/proxyutil"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/vault"
 )
@@ -130,6 +131,25 @@ func TestHandler_CacheControlNoStore(t *testing.T) {
 	}
 }
 
+func TestHandler_bearerAuth(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+
+	req, err := http.NewRequest("GET", addr+"/v1/sys/mounts", nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	client := cleanhttp.DefaultClient()
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	testResponseStatus(t, resp, 200)
+}
+
 // We use this test to verify header auth
 func TestSysMounts_headerAuth(t *testing.T) {
 	core, _, token := vault.TestCoreUnsealed(t)
@@ -400,3 +420,176 @@ func TestHandler_nonPrintableChars(t *testing.T) {
 
 	testResponseStatus(t, resp, 400)
 }
+
+func TestHandler_requestMFACreds(t *testing.T) {
+	r, err := http.NewRequest("POST", "/v1/auth/userpass/login/foo", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Add(MFAHeaderName, "my_totp:123456")
+	r.Header.Add(MFAHeaderName, "my_duo")
+	r.Header.Add(MFAHeaderName, "my_webhook:a:b")
+
+	req, err := requestMFACreds(r, &logical.Request{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := logical.MFACreds{
+		"my_totp":    []string{"123456"},
+		"my_duo":     []string{""},
+		"my_webhook": []string{"a:b"},
+	}
+	if !reflect.DeepEqual(req.MFACreds, expected) {
+		t.Fatalf("bad: %#v", req.MFACreds)
+	}
+
+	r.Header.Set(MFAHeaderName, ":123456")
+	if _, err := requestMFACreds(r, &logical.Request{}); err == nil {
+		t.Fatal("expected an error for a missing method name")
+	}
+}
+
+func TestHandler_requestNamespacePath(t *testing.T) {
+	r, err := http.NewRequest("GET", "/v1/secret/foo", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path := requestNamespacePath(r, "secret/foo"); path != "secret/foo" {
+		t.Fatalf("bad: %s", path)
+	}
+
+	r.Header.Set(NamespaceHeaderName, "/team-a/dev/")
+	if path := requestNamespacePath(r, "secret/foo"); path != "team-a/dev/secret/foo" {
+		t.Fatalf("bad: %s", path)
+	}
+}
+
+func TestHandler_forwardedFor(t *testing.T) {
+	authorizedAddrs, err := proxyutil.ParseAuthorizedAddrs("127.0.0.1/32")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var remoteAddr string
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		remoteAddr = r.RemoteAddr
+	})
+
+	cases := map[string]struct {
+		remoteAddr       string
+		headers          []string
+		rejectNotPresent bool
+		rejectNonAuthz   bool
+		hopSkips         int
+		status           int
+		expected         string
+	}{
+		"single": {
+			remoteAddr: "127.0.0.1:8200",
+			headers:    []string{"5.6.7.8"},
+			status:     http.StatusOK,
+			expected:   "5.6.7.8:8200",
+		},
+		"chain": {
+			remoteAddr: "127.0.0.1:8200",
+			headers:    []string{"1.2.3.4, 5.6.7.8"},
+			status:     http.StatusOK,
+			expected:   "5.6.7.8:8200",
+		},
+		"repeated header with hop skips": {
+			remoteAddr: "127.0.0.1:8200",
+			headers:    []string{"1.2.3.4", "5.6.7.8, 9.10.11.12"},
+			hopSkips:   2,
+			status:     http.StatusOK,
+			expected:   "1.2.3.4:8200",
+		},
+		"too many hop skips": {
+			remoteAddr: "127.0.0.1:8200",
+			headers:    []string{"1.2.3.4"},
+			hopSkips:   1,
+			status:     http.StatusBadRequest,
+		},
+		"invalid address": {
+			remoteAddr: "127.0.0.1:8200",
+			headers:    []string{"foo"},
+			status:     http.StatusBadRequest,
+		},
+		"not present": {
+			remoteAddr: "127.0.0.1:8200",
+			status:     http.StatusOK,
+			expected:   "127.0.0.1:8200",
+		},
+		"not present rejected": {
+			remoteAddr:       "127.0.0.1:8200",
+			rejectNotPresent: true,
+			status:           http.StatusBadRequest,
+		},
+		"not authorized": {
+			remoteAddr: "10.0.0.1:8200",
+			headers:    []string{"5.6.7.8"},
+			status:     http.StatusOK,
+			expected:   "10.0.0.1:8200",
+		},
+		"not authorized rejected": {
+			remoteAddr:     "10.0.0.1:8200",
+			headers:        []string{"5.6.7.8"},
+			rejectNonAuthz: true,
+			status:         http.StatusBadRequest,
+		},
+	}
+
+	for name, tc := range cases {
+		remoteAddr = ""
+		req := httptest.NewRequest("GET", "/v1/sys/health", nil)
+		req.RemoteAddr = tc.remoteAddr
+		for _, v := range tc.headers {
+			req.Header.Add("X-Forwarded-For", v)
+		}
+		w := httptest.NewRecorder()
+		WrapForwardedForHandler(h, authorizedAddrs, tc.rejectNotPresent, tc.rejectNonAuthz, tc.hopSkips).ServeHTTP(w, req)
+
+		if w.Code != tc.status {
+			t.Fatalf("%s: bad status: expected %d, got %d", name, tc.status, w.Code)
+		}
+		if remoteAddr != tc.expected {
+			t.Fatalf("%s: bad remote address: expected %q, got %q", name, tc.expected, remoteAddr)
+		}
+	}
+}
+
+func TestHandler_maxInFlight(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	h := WrapMaxInFlightHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+	}), 1)
+
+	done := make(chan int)
+	go func() {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/v1/sys/health", nil))
+		done <- w.Code
+	}()
+	<-started
+
+	// The second request is rejected while the first one is in flight
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/v1/sys/health", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("bad status: expected %d, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+
+	close(release)
+	if code := <-done; code != http.StatusOK {
+		t.Fatalf("bad status: expected %d, got %d", http.StatusOK, code)
+	}
+
+	// The slot of the first request is released once it's handled
+	go func() { <-started }()
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/v1/sys/health", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("bad status: expected %d, got %d", http.StatusOK, w.Code)
+	}
+}
@@ -4,8 +4,11 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"io"
+	"io/ioutil"
+	"mime"
 	"net"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
@@ -27,13 +30,17 @@ func buildLogicalRequest(core *vault.Core, w http.ResponseWriter, r *http.Reques
 	if path == "" {
 		return nil, http.StatusNotFound, nil
 	}
+	path = requestNamespacePath(r, path)
+
+	var data map[string]interface{}
 
 	// Determine the operation
 	var op logical.Operation
 	switch r.Method {
 	case "DELETE":
 		op = logical.DeleteOperation
-	case "GET":
+		data = parseQuery(r.URL.Query())
+	case "GET", "HEAD":
 		op = logical.ReadOperation
 		// Need to call ParseForm to get query params loaded
 		queryVals := r.URL.Query()
@@ -46,11 +53,22 @@ func buildLogicalRequest(core *vault.Core, w http.ResponseWriter, r *http.Reques
 			if list {
 				op = logical.ListOperation
 			}
+			queryVals.Del("list")
 		}
+
+		data = parseQuery(queryVals)
 	case "POST", "PUT":
 		op = logical.UpdateOperation
+	case "PATCH":
+		// Patches must be JSON merge patches (RFC 7386), so that clients
+		// do not mistake them for other patch formats
+		if requestMediaType(r) != mergePatchContentType {
+			return nil, http.StatusUnsupportedMediaType, nil
+		}
+		op = logical.PatchOperation
 	case "LIST":
 		op = logical.ListOperation
+		data = parseQuery(r.URL.Query())
 	case "OPTIONS":
 	default:
 		return nil, http.StatusMethodNotAllowed, nil
@@ -63,8 +81,18 @@ func buildLogicalRequest(core *vault.Core, w http.ResponseWriter, r *http.Reques
 	}
 
 	// Parse the request if we can
-	var data map[string]interface{}
-	if op == logical.UpdateOperation {
+	switch {
+	case op == logical.UpdateOperation && rawBodyContentTypes[requestMediaType(r)]:
+		// OCSP and EST requests are not JSON and are passed to the backend
+		// as is
+		body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, MaxRequestSize))
+		if err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+		data = map[string]interface{}{
+			logical.HTTPRawBody: body,
+		}
+	case op == logical.UpdateOperation, op == logical.PatchOperation:
 		err := parseRequest(r, w, &data)
 		if err == io.EOF {
 			data = nil
@@ -95,9 +123,68 @@ func buildLogicalRequest(core *vault.Core, w http.ResponseWriter, r *http.Reques
 		return nil, http.StatusBadRequest, errwrap.Wrapf("error parsing X-Vault-Wrap-TTL header: {{err}}", err)
 	}
 
+	req, err = requestMFACreds(r, req)
+	if err != nil {
+		return nil, http.StatusBadRequest, errwrap.Wrapf("error parsing X-Vault-MFA header: {{err}}", err)
+	}
+
+	req, err = requestCacheBypass(r, req)
+	if err != nil {
+		return nil, http.StatusBadRequest, errwrap.Wrapf("error parsing X-Vault-Cache-Bypass header: {{err}}", err)
+	}
+
+	req = requestIndexState(r, req)
+
 	return req, 0, nil
 }
 
+// mergePatchContentType is the content type of the bodies of PATCH requests
+const mergePatchContentType = "application/merge-patch+json"
+
+// rawBodyContentTypes are the content types of the request bodies passed to
+// backends in logical.HTTPRawBody
+var rawBodyContentTypes = map[string]bool{
+	"application/ocsp-request": true,
+	"application/pkcs10":       true,
+}
+
+// requestMediaType returns the media type of the Content-Type header of the
+// request without its parameters, such as the charset, or an empty string if
+// it cannot be parsed
+func requestMediaType(r *http.Request) string {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return ""
+	}
+	return mediaType
+}
+
+// parseQuery converts the query parameters of a read or list request into
+// request data. Parameters given once are passed as strings, repeated parameters as
+// a slice of strings.
+func parseQuery(values url.Values) map[string]interface{} {
+	data := map[string]interface{}{}
+	for k, v := range values {
+		// Skip the help key as this is a reserved parameter
+		if k == "help" {
+			continue
+		}
+
+		switch {
+		case len(v) == 0:
+		case len(v) == 1:
+			data[k] = v[0]
+		default:
+			data[k] = v
+		}
+	}
+
+	if len(data) > 0 {
+		return data
+	}
+	return nil
+}
+
 func handleLogical(core *vault.Core, injectDataIntoTopLevel bool, prepareRequestCallback PrepareRequestFunc) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		req, statusCode, err := buildLogicalRequest(core, w, r)
@@ -126,6 +213,14 @@ func handleLogical(core *vault.Core, injectDataIntoTopLevel bool, prepareRequest
 			return
 		}
 
+		// Return the index state of the storage writes after a write, so
+		// that the client can require reading it
+		switch req.Operation {
+		case logical.ReadOperation, logical.ListOperation, logical.HelpOperation:
+		default:
+			w.Header().Set(IndexHeaderName, core.IndexState())
+		}
+
 		// Build the proper response
 		respondLogical(w, r, req, injectDataIntoTopLevel, resp)
 	})
@@ -136,6 +231,13 @@ func respondLogical(w http.ResponseWriter, r *http.Request, req *logical.Request
 	var ret interface{}
 
 	if resp != nil {
+		// Set the response headers the mount allows the backend to send
+		for key, values := range resp.Headers {
+			for _, value := range values {
+				w.Header().Add(key, value)
+			}
+		}
+
 		if resp.Redirect != "" {
 			// If we have a redirect, redirect! We use a 307 code
 			// because we don't actually know if its permanent.
@@ -269,6 +371,28 @@ func respondRaw(w http.ResponseWriter, r *http.Request, resp *logical.Response)
 		w.Header().Set("Content-Type", contentType)
 	}
 
+	if contentDispositionRaw, ok := resp.Data[logical.HTTPContentDisposition]; ok {
+		contentDisposition, ok := contentDispositionRaw.(string)
+		if !ok {
+			retErr(w, "cannot decode content disposition")
+			return
+		}
+		w.Header().Set("Content-Disposition", contentDisposition)
+	}
+
+	if headersRaw, ok := resp.Data[logical.HTTPRawHeaders]; ok {
+		headers, ok := headersRaw.(map[string][]string)
+		if !ok {
+			retErr(w, "cannot decode headers")
+			return
+		}
+		for name, values := range headers {
+			for _, value := range values {
+				w.Header().Add(name, value)
+			}
+		}
+	}
+
 	w.WriteHeader(status)
 	w.Write(body)
 }
@@ -5,15 +5,18 @@ import (
 	"encoding/json"
 	"io"
 	"net/http"
+	"net/url"
 	"reflect"
 	"strconv"
 	"strings"
 	"testing"
 	"time"
 
+	cleanhttp "github.com/hashicorp/go-cleanhttp"
 	log "github.com/mgutz/logxi/v1"
 
 	"github.com/hashicorp/vault/helper/logformat"
+	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/physical"
 	"github.com/hashicorp/vault/physical/inmem"
 	"github.com/hashicorp/vault/vault"
@@ -156,6 +159,7 @@ func TestLogical_StandbyRedirect(t *testing.T) {
 			"ttl":              json.Number("0"),
 			"creation_ttl":     json.Number("0"),
 			"explicit_max_ttl": json.Number("0"),
+			"type":             "service",
 			"expire_time":      nil,
 			"entity_id":        "",
 		},
@@ -240,6 +244,9 @@ func TestLogical_RawHTTP(t *testing.T) {
 	if resp.Header.Get("Content-Type") != "plain/text" {
 		t.Fatalf("Bad: %#v", resp.Header)
 	}
+	if !reflect.DeepEqual(resp.Header["X-Raw-Header"], []string{"a", "b"}) {
+		t.Fatalf("Bad: %#v", resp.Header)
+	}
 
 	// Get the body
 	body := new(bytes.Buffer)
@@ -247,6 +254,112 @@ func TestLogical_RawHTTP(t *testing.T) {
 	if string(body.Bytes()) != "hello world" {
 		t.Fatalf("Bad: %s", body.Bytes())
 	}
+
+	// HEAD requests are reads, answered without the body
+	resp = testHttpData(t, "HEAD", token, addr+"/v1/foo/raw", nil, false)
+	testResponseStatus(t, resp, 200)
+	if resp.Header.Get("X-Raw-Header") != "a" {
+		t.Fatalf("Bad: %#v", resp.Header)
+	}
+	body.Reset()
+	io.Copy(body, resp.Body)
+	if body.Len() != 0 {
+		t.Fatalf("Bad: %s", body.Bytes())
+	}
+
+	// OCSP and EST request bodies are passed to the backend as is
+	for _, contentType := range []string{"application/ocsp-request", "application/pkcs10"} {
+		req, err := http.NewRequest("POST", addr+"/v1/foo/raw", bytes.NewReader([]byte{0x30, 0x00}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", contentType)
+		resp, err = http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		testResponseStatus(t, resp, 200)
+		body.Reset()
+		io.Copy(body, resp.Body)
+		if !bytes.Equal(body.Bytes(), []byte{0x30, 0x00}) {
+			t.Fatalf("Bad: %s: %v", contentType, body.Bytes())
+		}
+	}
+}
+
+func TestLogical_Patch(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+	TestServerAuth(t, addr, token)
+
+	// Patches must be JSON merge patches
+	resp := testHttpData(t, "PATCH", token, addr+"/v1/secret/foo", map[string]interface{}{
+		"data": "bar",
+	}, false)
+	testResponseStatus(t, resp, http.StatusUnsupportedMediaType)
+
+	// Only the media type of the content type is compared, and the generic
+	// secret backend does not support patches
+	for contentType, status := range map[string]int{
+		"application/merge-patch+json":                http.StatusMethodNotAllowed,
+		"application/merge-patch+json; charset=utf-8": http.StatusMethodNotAllowed,
+		"Application/Merge-Patch+JSON":                http.StatusMethodNotAllowed,
+		"application/merge-patch+json; charset":       http.StatusUnsupportedMediaType,
+		"application/json; charset=utf-8":             http.StatusUnsupportedMediaType,
+	} {
+		req, err := http.NewRequest("PATCH", addr+"/v1/secret/foo", strings.NewReader(`{"data": "bar"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set(AuthHeaderName, token)
+		resp, err = http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != status {
+			t.Fatalf("%s: expected status %d, got %d", contentType, status, resp.StatusCode)
+		}
+	}
+}
+
+func TestLogical_parseQuery(t *testing.T) {
+	values := url.Values{
+		"help":   []string{"1"},
+		"single": []string{"value"},
+		"multi":  []string{"one", "two"},
+	}
+
+	expected := map[string]interface{}{
+		"single": "value",
+		"multi":  []string{"one", "two"},
+	}
+	if actual := parseQuery(values); !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: expected %#v, got %#v", expected, actual)
+	}
+
+	if actual := parseQuery(url.Values{"help": []string{"1"}}); actual != nil {
+		t.Fatalf("bad: expected nil data, got %#v", actual)
+	}
+}
+
+func TestLogical_ListQuery(t *testing.T) {
+	core, _, _ := vault.TestCoreUnsealed(t)
+	req, _ := http.NewRequest("LIST", "http://127.0.0.1:8200/v1/secret/foo?detailed=true", nil)
+	lreq, status, err := buildLogicalRequest(core, nil, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != 0 {
+		t.Fatalf("got status %d", status)
+	}
+	if lreq.Operation != logical.ListOperation {
+		t.Fatalf("bad operation: %v", lreq.Operation)
+	}
+	if !reflect.DeepEqual(lreq.Data, map[string]interface{}{"detailed": "true"}) {
+		t.Fatalf("bad data: %#v", lreq.Data)
+	}
 }
 
 func TestLogical_RequestSizeLimit(t *testing.T) {
@@ -300,3 +413,47 @@ func TestLogical_ListSuffix(t *testing.T) {
 		t.Fatal("trailing slash not found on path")
 	}
 }
+
+func TestLogical_IndexState(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+	TestServerAuth(t, addr, token)
+
+	// Writes return the index state
+	resp := testHttpPut(t, token, addr+"/v1/secret/foo", map[string]interface{}{
+		"data": "bar",
+	})
+	testResponseStatus(t, resp, 204)
+	state := resp.Header.Get(IndexHeaderName)
+	if state == "" {
+		t.Fatal("expected index state header")
+	}
+
+	read := func(state string) *http.Response {
+		req, err := http.NewRequest("GET", addr+"/v1/secret/foo", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set(AuthHeaderName, token)
+		req.Header.Set(IndexHeaderName, state)
+		resp, err := cleanhttp.DefaultClient().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	// Reads do not
+	resp = read(state)
+	testResponseStatus(t, resp, 200)
+	if value := resp.Header.Get(IndexHeaderName); value != "" {
+		t.Fatalf("unexpected index state header: %q", value)
+	}
+
+	// States not issued by the node cannot be read
+	resp = read("foo:1")
+	testResponseStatus(t, resp, 412)
+	resp = read("foo")
+	testResponseStatus(t, resp, 400)
+}
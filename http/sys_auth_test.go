@@ -29,8 +29,10 @@ func TestSysAuth(t *testing.T) {
 				"description": "token based credentials",
 				"type":        "token",
 				"config": map[string]interface{}{
-					"default_lease_ttl": json.Number("0"),
-					"max_lease_ttl":     json.Number("0"),
+					"default_lease_ttl":    json.Number("0"),
+					"max_lease_ttl":        json.Number("0"),
+					"sync_external_groups": false,
+					"token_type":           "service",
 				},
 				"local":     false,
 				"seal_wrap": false,
@@ -40,8 +42,10 @@ func TestSysAuth(t *testing.T) {
 			"description": "token based credentials",
 			"type":        "token",
 			"config": map[string]interface{}{
-				"default_lease_ttl": json.Number("0"),
-				"max_lease_ttl":     json.Number("0"),
+				"default_lease_ttl":    json.Number("0"),
+				"max_lease_ttl":        json.Number("0"),
+				"sync_external_groups": false,
+				"token_type":           "service",
 			},
 			"local":     false,
 			"seal_wrap": false,
@@ -91,8 +95,10 @@ func TestSysEnableAuth(t *testing.T) {
 				"description": "foo",
 				"type":        "noop",
 				"config": map[string]interface{}{
-					"default_lease_ttl": json.Number("0"),
-					"max_lease_ttl":     json.Number("0"),
+					"default_lease_ttl":    json.Number("0"),
+					"max_lease_ttl":        json.Number("0"),
+					"sync_external_groups": false,
+					"token_type":           "service",
 				},
 				"local":     false,
 				"seal_wrap": false,
@@ -101,8 +107,10 @@ func TestSysEnableAuth(t *testing.T) {
 				"description": "token based credentials",
 				"type":        "token",
 				"config": map[string]interface{}{
-					"default_lease_ttl": json.Number("0"),
-					"max_lease_ttl":     json.Number("0"),
+					"default_lease_ttl":    json.Number("0"),
+					"max_lease_ttl":        json.Number("0"),
+					"sync_external_groups": false,
+					"token_type":           "service",
 				},
 				"local":     false,
 				"seal_wrap": false,
@@ -112,8 +120,10 @@ func TestSysEnableAuth(t *testing.T) {
 			"description": "foo",
 			"type":        "noop",
 			"config": map[string]interface{}{
-				"default_lease_ttl": json.Number("0"),
-				"max_lease_ttl":     json.Number("0"),
+				"default_lease_ttl":    json.Number("0"),
+				"max_lease_ttl":        json.Number("0"),
+				"sync_external_groups": false,
+				"token_type":           "service",
 			},
 			"local":     false,
 			"seal_wrap": false,
@@ -122,8 +132,10 @@ func TestSysEnableAuth(t *testing.T) {
 			"description": "token based credentials",
 			"type":        "token",
 			"config": map[string]interface{}{
-				"default_lease_ttl": json.Number("0"),
-				"max_lease_ttl":     json.Number("0"),
+				"default_lease_ttl":    json.Number("0"),
+				"max_lease_ttl":        json.Number("0"),
+				"sync_external_groups": false,
+				"token_type":           "service",
 			},
 			"local":     false,
 			"seal_wrap": false,
@@ -174,8 +186,10 @@ func TestSysDisableAuth(t *testing.T) {
 		"data": map[string]interface{}{
 			"token/": map[string]interface{}{
 				"config": map[string]interface{}{
-					"default_lease_ttl": json.Number("0"),
-					"max_lease_ttl":     json.Number("0"),
+					"default_lease_ttl":    json.Number("0"),
+					"max_lease_ttl":        json.Number("0"),
+					"sync_external_groups": false,
+					"token_type":           "service",
 				},
 				"description": "token based credentials",
 				"type":        "token",
@@ -185,8 +199,10 @@ func TestSysDisableAuth(t *testing.T) {
 		},
 		"token/": map[string]interface{}{
 			"config": map[string]interface{}{
-				"default_lease_ttl": json.Number("0"),
-				"max_lease_ttl":     json.Number("0"),
+				"default_lease_ttl":    json.Number("0"),
+				"max_lease_ttl":        json.Number("0"),
+				"sync_external_groups": false,
+				"token_type":           "service",
 			},
 			"description": "token based credentials",
 			"type":        "token",
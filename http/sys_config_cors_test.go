@@ -75,4 +75,36 @@ func TestSysConfigCors(t *testing.T) {
 		t.Fatalf("bad: expected: %#v\nactual: %#v", expected, actual)
 	}
 
+	// Updating the configuration replaces the allowed headers
+	resp = testHttpPut(t, token, addr+"/v1/sys/config/cors", map[string]interface{}{
+		"allowed_origins": addr,
+		"allowed_headers": "X-Other-Header",
+	})
+	testResponseStatus(t, resp, 204)
+
+	expectedHeaders = make([]interface{}, 0, len(vault.StdAllowedHeaders)+1)
+	for _, header := range vault.StdAllowedHeaders {
+		expectedHeaders = append(expectedHeaders, header)
+	}
+	expectedHeaders = append(expectedHeaders, "X-Other-Header")
+
+	resp = testHttpGet(t, token, addr+"/v1/sys/config/cors")
+	testResponseStatus(t, resp, 200)
+	actual = nil
+	testResponseBody(t, resp, &actual)
+	if !reflect.DeepEqual(actual["allowed_headers"], expectedHeaders) {
+		t.Fatalf("bad: expected: %#v\nactual: %#v", expectedHeaders, actual["allowed_headers"])
+	}
+
+	// Disabling CORS clears the configuration
+	resp = testHttpDelete(t, token, addr+"/v1/sys/config/cors")
+	testResponseStatus(t, resp, 204)
+
+	resp = testHttpGet(t, token, addr+"/v1/sys/config/cors")
+	testResponseStatus(t, resp, 200)
+	actual = nil
+	testResponseBody(t, resp, &actual)
+	if actual["enabled"] != false || actual["allowed_origins"] != nil {
+		t.Fatalf("bad: %#v", actual)
+	}
 }
@@ -0,0 +1,132 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/hashicorp/vault/helper/websocketutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/vault"
+)
+
+const (
+	// eventsPingInterval is the interval of the pings sent to the
+	// subscribers, which detect the connections that are gone
+	eventsPingInterval = 30 * time.Second
+
+	// eventsWriteTimeout is the timeout of the writes to the subscribers
+	eventsWriteTimeout = 10 * time.Second
+)
+
+// handleSysEventsSubscribe streams the events of a subscription over a
+// websocket. The request is handled as a read of sys/events/subscribe first,
+// so that it is authorized and audited like any other request, and the
+// subscription it returns is then claimed. The request is not forwarded, since
+// events are only published on the active node.
+func handleSysEventsSubscribe(core *vault.Core) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !websocketutil.IsUpgradeRequest(r) {
+			respondError(w, http.StatusBadRequest, fmt.Errorf("events are streamed over a websocket, which the request must upgrade to"))
+			return
+		}
+
+		req, statusCode, err := buildLogicalRequest(core, w, r)
+		if err != nil || statusCode != 0 {
+			respondError(w, statusCode, err)
+			return
+		}
+
+		switch req.Operation {
+		case logical.ReadOperation:
+		default:
+			respondError(w, http.StatusMethodNotAllowed, nil)
+			return
+		}
+		if req.WrapInfo != nil {
+			respondError(w, http.StatusBadRequest, fmt.Errorf("event subscriptions cannot be response-wrapped"))
+			return
+		}
+
+		resp, ok := request(core, w, r, req)
+		if !ok {
+			return
+		}
+
+		var sub *vault.EventSubscription
+		if resp != nil {
+			if id, ok := resp.Data["subscription_id"].(string); ok {
+				sub = core.ClaimEventSubscription(id)
+			}
+		}
+		if sub == nil {
+			respondError(w, http.StatusInternalServerError, fmt.Errorf("event subscription not found"))
+			return
+		}
+		defer sub.Close()
+
+		conn, err := websocketutil.Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		streamEvents(conn, sub)
+	})
+}
+
+// streamEvents writes the events of the subscription to the websocket as JSON
+// text messages, until the client closes the websocket or the subscription
+// ends, e.g. because Vault is sealed
+func streamEvents(conn *websocketutil.Conn, sub *vault.EventSubscription) {
+	// The messages of the client are only read to answer its pings and to
+	// detect that it closed the websocket
+	closedCh := make(chan struct{})
+	go func() {
+		defer close(closedCh)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(eventsPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case event, ok := <-sub.Events():
+			if !ok {
+				conn.SetWriteDeadline(time.Now().Add(eventsWriteTimeout))
+				if err := conn.WriteClose(websocketutil.CloseGoingAway, "subscription ended"); err == nil {
+					// Give the client a chance to answer the closing handshake
+					select {
+					case <-closedCh:
+					case <-time.After(time.Second):
+					}
+				}
+				return
+			}
+
+			buf, err := json.Marshal(event)
+			if err != nil {
+				return
+			}
+			conn.SetWriteDeadline(time.Now().Add(eventsWriteTimeout))
+			if err := conn.WriteText(buf); err != nil {
+				return
+			}
+
+		case <-ticker.C:
+			conn.SetWriteDeadline(time.Now().Add(eventsWriteTimeout))
+			if err := conn.WritePing(nil); err != nil {
+				return
+			}
+
+		case <-closedCh:
+			return
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/vault"
+)
+
+func TestSysEventsSubscribe(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+
+	config := api.DefaultConfig()
+	config.Address = addr
+	client, err := api.NewClient(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetToken(token)
+
+	sub, err := client.Sys().SubscribeEvents([]string{"kv/*"}, "secret/foo*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+
+	// Only the write of secret/foo matches the subscription
+	if _, err := client.Logical().Write("secret/bar", map[string]interface{}{"value": "bar"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Logical().Write("secret/foo", map[string]interface{}{"value": "foo"}); err != nil {
+		t.Fatal(err)
+	}
+
+	eventCh := make(chan *api.Event, 1)
+	errCh := make(chan error, 1)
+	go func() {
+		event, err := sub.Next()
+		if err != nil {
+			errCh <- err
+			return
+		}
+		eventCh <- event
+	}()
+
+	select {
+	case event := <-eventCh:
+		if event.EventType != logical.EventTypeKVWrite || event.Path != "secret/foo" || event.Namespace != "" {
+			t.Fatalf("bad: %#v", event)
+		}
+		if event.ID == "" || event.Timestamp.IsZero() {
+			t.Fatalf("bad: %#v", event)
+		}
+	case err := <-errCh:
+		t.Fatal(err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the event")
+	}
+}
+
+func TestSysEventsSubscribe_errors(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+
+	// The events are only streamed over websockets
+	resp := testHttpGet(t, token, addr+"/v1/sys/events/subscribe")
+	testResponseStatus(t, resp, http.StatusBadRequest)
+
+	config := api.DefaultConfig()
+	config.Address = addr
+	client, err := api.NewClient(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Subscriptions are authorized like any other request
+	client.SetToken("invalid-token")
+	if _, err := client.Sys().SubscribeEvents(nil, ""); err == nil {
+		t.Fatal("expected permission denied")
+	} else if !strings.Contains(err.Error(), "Code: 403") {
+		t.Fatalf("bad: %v", err)
+	}
+
+	client.SetToken(token)
+	if _, err := client.Sys().SubscribeEvents([]string{"kv/*/x"}, ""); err == nil {
+		t.Fatal("expected invalid pattern error")
+	}
+}
@@ -322,6 +322,7 @@ func TestSysGenerateRoot_Update_OTP(t *testing.T) {
 		"ttl":              json.Number("0"),
 		"path":             "auth/token/root",
 		"explicit_max_ttl": json.Number("0"),
+		"type":             "service",
 		"expire_time":      nil,
 		"entity_id":        "",
 	}
@@ -417,6 +418,7 @@ func TestSysGenerateRoot_Update_PGP(t *testing.T) {
 		"ttl":              json.Number("0"),
 		"path":             "auth/token/root",
 		"explicit_max_ttl": json.Number("0"),
+		"type":             "service",
 		"expire_time":      nil,
 		"entity_id":        "",
 	}
@@ -0,0 +1,66 @@
+package http
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/vault"
+)
+
+func TestSysHAStatus(t *testing.T) {
+	cluster := vault.NewTestCluster(t, nil, &vault.TestClusterOptions{
+		HandlerFunc: Handler,
+	})
+	cluster.Start()
+	defer cluster.Cleanup()
+	cores := cluster.Cores
+
+	vault.TestWaitActive(t, cores[0].Core)
+
+	// Wait for the standbys to send their heartbeat
+	start := time.Now()
+	for len(cores[0].Core.GetHAPeerNodesCached()) != 2 {
+		if time.Since(start) > 10*time.Second {
+			t.Fatal("standbys did not send a heartbeat")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	status, err := cores[1].Client.Sys().HAStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(status.Nodes) != 3 {
+		t.Fatalf("expected 3 nodes, got %#v", status.Nodes)
+	}
+	for i, node := range status.Nodes {
+		if node.ActiveNode != (i == 0) {
+			t.Fatalf("bad active node: %#v", status.Nodes)
+		}
+		if node.ClusterAddress == "" || node.APIAddress == "" {
+			t.Fatalf("expected addresses: %#v", node)
+		}
+		if (node.LastEcho == nil) != node.ActiveNode {
+			t.Fatalf("bad last echo: %#v", node)
+		}
+	}
+
+	leader, err := cores[1].Client.Sys().Leader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if leader.IsSelf || leader.LastContact == nil || leader.LastContact.IsZero() {
+		t.Fatalf("bad: %#v", leader)
+	}
+	if leader.LeaderHostname != status.Nodes[0].Hostname {
+		t.Fatalf("expected hostname %q, got %q", status.Nodes[0].Hostname, leader.LeaderHostname)
+	}
+
+	leader, err = cores[0].Client.Sys().Leader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !leader.IsSelf || leader.LastContact != nil {
+		t.Fatalf("bad: %#v", leader)
+	}
+}
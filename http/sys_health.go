@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/hashicorp/vault/helper/consts"
+	"github.com/hashicorp/vault/helper/fips"
 	"github.com/hashicorp/vault/vault"
 	"github.com/hashicorp/vault/version"
 )
@@ -75,6 +76,7 @@ func handleSysHealthHead(core *vault.Core, w http.ResponseWriter, r *http.Reques
 func getSysHealth(core *vault.Core, r *http.Request) (int, *HealthResponse, error) {
 	// Check if being a standby is allowed for the purpose of a 200 OK
 	_, standbyOK := r.URL.Query()["standbyok"]
+	_, perfStandbyOK := r.URL.Query()["perfstandbyok"]
 
 	uninitCode := http.StatusNotImplemented
 	if code, found, ok := fetchStatusCode(r, "uninitcode"); !ok {
@@ -111,11 +113,19 @@ func getSysHealth(core *vault.Core, r *http.Request) (int, *HealthResponse, erro
 		drSecondaryCode = code
 	}
 
+	perfStandbyCode := 473 // unofficial 4xx status code
+	if code, found, ok := fetchStatusCode(r, "performancestandbycode"); !ok {
+		return http.StatusBadRequest, nil, nil
+	} else if found {
+		perfStandbyCode = code
+	}
+
 	ctx := context.Background()
 
 	// Check system status
 	sealed, _ := core.Sealed()
 	standby, _ := core.Standby()
+	perfStandby, _ := core.PerformanceStandby()
 	var replicationState consts.ReplicationState
 	if standby {
 		replicationState = core.ActiveNodeReplicationState()
@@ -137,6 +147,12 @@ func getSysHealth(core *vault.Core, r *http.Request) (int, *HealthResponse, erro
 		code = sealedCode
 	case replicationState.HasState(consts.ReplicationDRSecondary):
 		code = drSecondaryCode
+	case perfStandby:
+		// Performance standbys are standbys as well, so either parameter
+		// allows them
+		if !perfStandbyOK && !standbyOK {
+			code = perfStandbyCode
+		}
 	case !standbyOK && standby:
 		code = standbyCode
 	}
@@ -160,12 +176,14 @@ func getSysHealth(core *vault.Core, r *http.Request) (int, *HealthResponse, erro
 		Initialized:                init,
 		Sealed:                     sealed,
 		Standby:                    standby,
+		PerformanceStandby:         perfStandby,
 		ReplicationPerformanceMode: replicationState.GetPerformanceString(),
 		ReplicationDRMode:          replicationState.GetDRString(),
 		ServerTimeUTC:              time.Now().UTC().Unix(),
 		Version:                    version.GetVersion().VersionNumber(),
 		ClusterName:                clusterName,
 		ClusterID:                  clusterID,
+		FIPSEnabled:                fips.Enabled(),
 	}
 	return code, body, nil
 }
@@ -174,10 +192,12 @@ type HealthResponse struct {
 	Initialized                bool   `json:"initialized"`
 	Sealed                     bool   `json:"sealed"`
 	Standby                    bool   `json:"standby"`
+	PerformanceStandby         bool   `json:"performance_standby"`
 	ReplicationPerformanceMode string `json:"replication_performance_mode"`
 	ReplicationDRMode          string `json:"replication_dr_mode"`
 	ServerTimeUTC              int64  `json:"server_time_utc"`
 	Version                    string `json:"version"`
 	ClusterName                string `json:"cluster_name,omitempty"`
 	ClusterID                  string `json:"cluster_id,omitempty"`
+	FIPSEnabled                bool   `json:"fips_enabled"`
 }
@@ -4,9 +4,11 @@ import (
 	"io/ioutil"
 
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/vault/helper/consts"
 	"github.com/hashicorp/vault/vault"
@@ -26,9 +28,11 @@ func TestSysHealth_get(t *testing.T) {
 	expected := map[string]interface{}{
 		"replication_performance_mode": consts.ReplicationUnknown.GetPerformanceString(),
 		"replication_dr_mode":          consts.ReplicationUnknown.GetDRString(),
+		"fips_enabled":                 false,
 		"initialized":                  false,
 		"sealed":                       true,
 		"standby":                      true,
+		"performance_standby":          false,
 	}
 	testResponseStatus(t, resp, 501)
 	testResponseBody(t, resp, &actual)
@@ -58,9 +62,11 @@ func TestSysHealth_get(t *testing.T) {
 	expected = map[string]interface{}{
 		"replication_performance_mode": consts.ReplicationUnknown.GetPerformanceString(),
 		"replication_dr_mode":          consts.ReplicationUnknown.GetDRString(),
+		"fips_enabled":                 false,
 		"initialized":                  true,
 		"sealed":                       true,
 		"standby":                      true,
+		"performance_standby":          false,
 	}
 	testResponseStatus(t, resp, 503)
 	testResponseBody(t, resp, &actual)
@@ -94,9 +100,11 @@ func TestSysHealth_get(t *testing.T) {
 	expected = map[string]interface{}{
 		"replication_performance_mode": consts.ReplicationPerformanceDisabled.GetPerformanceString(),
 		"replication_dr_mode":          consts.ReplicationDRDisabled.GetDRString(),
+		"fips_enabled":                 false,
 		"initialized":                  true,
 		"sealed":                       false,
 		"standby":                      false,
+		"performance_standby":          false,
 	}
 	testResponseStatus(t, resp, 200)
 	testResponseBody(t, resp, &actual)
@@ -136,9 +144,11 @@ func TestSysHealth_customcodes(t *testing.T) {
 	expected := map[string]interface{}{
 		"replication_performance_mode": consts.ReplicationUnknown.GetPerformanceString(),
 		"replication_dr_mode":          consts.ReplicationUnknown.GetDRString(),
+		"fips_enabled":                 false,
 		"initialized":                  false,
 		"sealed":                       true,
 		"standby":                      true,
+		"performance_standby":          false,
 	}
 	testResponseStatus(t, resp, 581)
 	testResponseBody(t, resp, &actual)
@@ -169,9 +179,11 @@ func TestSysHealth_customcodes(t *testing.T) {
 	expected = map[string]interface{}{
 		"replication_performance_mode": consts.ReplicationUnknown.GetPerformanceString(),
 		"replication_dr_mode":          consts.ReplicationUnknown.GetDRString(),
+		"fips_enabled":                 false,
 		"initialized":                  true,
 		"sealed":                       true,
 		"standby":                      true,
+		"performance_standby":          false,
 	}
 	testResponseStatus(t, resp, 523)
 	testResponseBody(t, resp, &actual)
@@ -206,9 +218,11 @@ func TestSysHealth_customcodes(t *testing.T) {
 	expected = map[string]interface{}{
 		"replication_performance_mode": consts.ReplicationPerformanceDisabled.GetPerformanceString(),
 		"replication_dr_mode":          consts.ReplicationDRDisabled.GetDRString(),
+		"fips_enabled":                 false,
 		"initialized":                  true,
 		"sealed":                       false,
 		"standby":                      false,
+		"performance_standby":          false,
 	}
 	testResponseStatus(t, resp, 202)
 	testResponseBody(t, resp, &actual)
@@ -266,3 +280,45 @@ func TestSysHealth_head(t *testing.T) {
 		}
 	}
 }
+
+func TestSysHealth_performanceStandby(t *testing.T) {
+	cluster := vault.NewTestCluster(t, &vault.CoreConfig{
+		PerformanceStandby: true,
+	}, &vault.TestClusterOptions{
+		HandlerFunc: Handler,
+	})
+	cluster.Start()
+	defer cluster.Cleanup()
+	cores := cluster.Cores
+
+	vault.TestWaitActive(t, cores[0].Core)
+	start := time.Now()
+	for {
+		if perfStandby, _ := cores[1].Core.PerformanceStandby(); perfStandby {
+			break
+		}
+		if time.Since(start) > 5*time.Second {
+			t.Fatal("standby did not become a performance standby")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	// Either standbyok or perfstandbyok allows a performance standby
+	for query, expected := range map[string]int{
+		"":                           473,
+		"performancestandbycode=299": 299,
+		"standbycode=298":            473,
+		"standbyok=true":             http.StatusOK,
+		"perfstandbyok=true":         http.StatusOK,
+		"standbyok=true&activecode=204&performancestandbycode=299": 204,
+	} {
+		req := httptest.NewRequest("GET", "/v1/sys/health?"+query, nil)
+		code, body, err := getSysHealth(cores[1].Core, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != expected || !body.PerformanceStandby || !body.Standby {
+			t.Fatalf("%q: expected %d, got %d: %#v", query, expected, code, body)
+		}
+	}
+}
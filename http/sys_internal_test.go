@@ -0,0 +1,34 @@
+package http
+
+import (
+	"testing"
+
+	"github.com/hashicorp/vault/vault"
+)
+
+func TestSysInternal_OpenAPI(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+	TestServerAuth(t, addr, token)
+
+	resp := testHttpGet(t, token, addr+"/v1/sys/internal/specs/openapi")
+	testResponseStatus(t, resp, 200)
+	if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("bad content type: %q", ct)
+	}
+
+	var actual map[string]interface{}
+	testResponseBody(t, resp, &actual)
+	if actual["openapi"] != "3.0.2" {
+		t.Fatalf("bad: %#v", actual)
+	}
+	paths, ok := actual["paths"].(map[string]interface{})
+	if !ok || paths["/sys/mounts"] == nil || paths["/secret/{path}"] == nil {
+		t.Fatalf("bad paths: %#v", actual["paths"])
+	}
+
+	// The document is only returned to authenticated requests
+	resp = testHttpGet(t, "", addr+"/v1/sys/internal/specs/openapi")
+	testResponseStatus(t, resp, 400)
+}
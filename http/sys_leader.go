@@ -2,6 +2,8 @@ package http
 
 import (
 	"net/http"
+	"os"
+	"time"
 
 	"github.com/hashicorp/errwrap"
 	"github.com/hashicorp/vault/vault"
@@ -30,17 +32,27 @@ func handleSysLeaderGet(core *vault.Core, w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	respondOk(w, &LeaderResponse{
+	resp := &LeaderResponse{
 		HAEnabled:            haEnabled,
 		IsSelf:               isLeader,
 		LeaderAddress:        address,
 		LeaderClusterAddress: clusterAddr,
-	})
+	}
+	if isLeader {
+		resp.LeaderHostname, _ = os.Hostname()
+	} else if hostname, lastEcho := core.ActiveNodeHeartbeat(); !lastEcho.IsZero() {
+		resp.LeaderHostname = hostname
+		resp.LastContact = &lastEcho
+	}
+
+	respondOk(w, resp)
 }
 
 type LeaderResponse struct {
-	HAEnabled            bool   `json:"ha_enabled"`
-	IsSelf               bool   `json:"is_self"`
-	LeaderAddress        string `json:"leader_address"`
-	LeaderClusterAddress string `json:"leader_cluster_address"`
+	HAEnabled            bool       `json:"ha_enabled"`
+	IsSelf               bool       `json:"is_self"`
+	LeaderAddress        string     `json:"leader_address"`
+	LeaderClusterAddress string     `json:"leader_cluster_address"`
+	LeaderHostname       string     `json:"leader_hostname,omitempty"`
+	LastContact          *time.Time `json:"last_contact,omitempty"`
 }
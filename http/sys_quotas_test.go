@@ -0,0 +1,51 @@
+package http
+
+import (
+	"testing"
+
+	"github.com/hashicorp/vault/vault"
+)
+
+func TestSysQuotas(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+	TestServerAuth(t, addr, token)
+
+	resp := testHttpPut(t, token, addr+"/v1/secret/foo", map[string]interface{}{
+		"data": "bar",
+		"ttl":  "1h",
+	})
+	testResponseStatus(t, resp, 204)
+
+	resp = testHttpPut(t, token, addr+"/v1/sys/quotas/lease-count/secret", map[string]interface{}{
+		"path":       "secret",
+		"max_leases": 1,
+	})
+	testResponseStatus(t, resp, 204)
+
+	resp = testHttpGet(t, token, addr+"/v1/secret/foo")
+	testResponseStatus(t, resp, 200)
+	resp = testHttpGet(t, token, addr+"/v1/secret/foo")
+	testResponseStatus(t, resp, 403)
+
+	resp = testHttpPut(t, token, addr+"/v1/sys/quotas/rate-limit/secret", map[string]interface{}{
+		"path":  "secret",
+		"rate":  0.001,
+		"burst": 1,
+	})
+	testResponseStatus(t, resp, 204)
+
+	resp = testHttpPut(t, token, addr+"/v1/secret/bar", map[string]interface{}{
+		"data": "baz",
+	})
+	testResponseStatus(t, resp, 204)
+	resp = testHttpGet(t, token, addr+"/v1/secret/bar")
+	testResponseStatus(t, resp, 429)
+	if retryAfter := resp.Header.Get("Retry-After"); retryAfter == "" || retryAfter == "0" {
+		t.Fatalf("bad retry after: %q", retryAfter)
+	}
+
+	resp = testHttpGet(t, token, addr+"/v1/sys/quotas/rate-limit/secret")
+	testResponseStatus(t, resp, 200)
+}
@@ -100,6 +100,8 @@ func handleSysRekeyInitGet(ctx context.Context, core *vault.Core, recovery bool,
 			status.PGPFingerprints = pgpFingerprints
 			status.Backup = rekeyConf.Backup
 		}
+		status.VerificationRequired = rekeyConf.VerificationRequired
+		status.VerificationNonce = rekeyConf.VerificationNonce
 	}
 	respondOk(w, status)
 }
@@ -131,6 +133,11 @@ func handleSysRekeyInitPut(ctx context.Context, core *vault.Core, recovery bool,
 		return
 	}
 
+	if req.RequireVerification && req.StoredShares > 0 {
+		respondError(w, http.StatusBadRequest, fmt.Errorf("requiring verification not supported when storing shares"))
+		return
+	}
+
 	// Initialize the rekey
 	err := core.RekeyInit(&vault.SealConfig{
 		SecretShares:    req.SecretShares,
@@ -138,6 +145,8 @@ func handleSysRekeyInitPut(ctx context.Context, core *vault.Core, recovery bool,
 		StoredShares:    req.StoredShares,
 		PGPKeys:         req.PGPKeys,
 		Backup:          req.Backup,
+
+		VerificationRequired: req.RequireVerification,
 	}, recovery)
 	if err != nil {
 		respondError(w, http.StatusBadRequest, err)
@@ -177,20 +186,10 @@ func handleSysRekeyUpdate(core *vault.Core, recovery bool) http.Handler {
 			return
 		}
 
-		// Decode the key, which is base64 or hex encoded
-		min, max := core.BarrierKeyLength()
-		key, err := hex.DecodeString(req.Key)
-		// We check min and max here to ensure that a string that is base64
-		// encoded but also valid hex will not be valid and we instead base64
-		// decode it
-		if err != nil || len(key) < min || len(key) > max {
-			key, err = base64.StdEncoding.DecodeString(req.Key)
-			if err != nil {
-				respondError(
-					w, http.StatusBadRequest,
-					errors.New("'key' must be a valid hex or base64 string"))
-				return
-			}
+		key, err := decodeRekeyKey(core, req.Key)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, err)
+			return
 		}
 
 		ctx, cancel := core.GetContext()
@@ -210,6 +209,8 @@ func handleSysRekeyUpdate(core *vault.Core, recovery bool) http.Handler {
 			resp.Nonce = req.Nonce
 			resp.Backup = result.Backup
 			resp.PGPFingerprints = result.PGPFingerprints
+			resp.VerificationRequired = result.VerificationRequired
+			resp.VerificationNonce = result.VerificationNonce
 
 			// Encode the keys
 			keys := make([]string, 0, len(result.SecretShares))
@@ -227,23 +228,144 @@ func handleSysRekeyUpdate(core *vault.Core, recovery bool) http.Handler {
 	})
 }
 
+func handleSysRekeyVerify(core *vault.Core, recovery bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		standby, _ := core.Standby()
+		if standby {
+			respondStandby(core, w, r.URL)
+			return
+		}
+
+		ctx, cancel := core.GetContext()
+		defer cancel()
+
+		switch {
+		case recovery && !core.SealAccess().RecoveryKeySupported():
+			respondError(w, http.StatusBadRequest, fmt.Errorf("recovery rekeying not supported"))
+		case r.Method == "GET":
+			handleSysRekeyVerifyGet(core, recovery, w, r)
+		case r.Method == "POST" || r.Method == "PUT":
+			handleSysRekeyVerifyPut(ctx, core, recovery, w, r)
+		case r.Method == "DELETE":
+			handleSysRekeyVerifyDelete(core, recovery, w, r)
+		default:
+			respondError(w, http.StatusMethodNotAllowed, nil)
+		}
+	})
+}
+
+func handleSysRekeyVerifyGet(core *vault.Core, recovery bool, w http.ResponseWriter, r *http.Request) {
+	// Get the rekey configuration
+	rekeyConf, err := core.RekeyConfig(recovery)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if rekeyConf == nil || rekeyConf.VerificationNonce == "" {
+		respondError(w, http.StatusBadRequest, errors.New("no rekey verification in progress"))
+		return
+	}
+
+	// Get the progress
+	progress, err := core.RekeyVerifyProgress(recovery)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	respondOk(w, &RekeyVerificationStatusResponse{
+		Nonce:    rekeyConf.VerificationNonce,
+		Started:  true,
+		T:        rekeyConf.SecretThreshold,
+		N:        rekeyConf.SecretShares,
+		Progress: progress,
+	})
+}
+
+func handleSysRekeyVerifyPut(ctx context.Context, core *vault.Core, recovery bool, w http.ResponseWriter, r *http.Request) {
+	// Parse the request
+	var req RekeyVerificationUpdateRequest
+	if err := parseRequest(r, w, &req); err != nil {
+		respondError(w, http.StatusBadRequest, err)
+		return
+	}
+	if req.Key == "" {
+		respondError(
+			w, http.StatusBadRequest,
+			errors.New("'key' must be specified in request body as JSON"))
+		return
+	}
+
+	key, err := decodeRekeyKey(core, req.Key)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// Use the key to make progress on the verification
+	result, err := core.RekeyVerify(ctx, key, req.Nonce, recovery)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if result == nil {
+		handleSysRekeyVerifyGet(core, recovery, w, r)
+		return
+	}
+
+	respondOk(w, &RekeyVerificationUpdateResponse{
+		Nonce:    result.Nonce,
+		Complete: true,
+	})
+}
+
+func handleSysRekeyVerifyDelete(core *vault.Core, recovery bool, w http.ResponseWriter, r *http.Request) {
+	if err := core.RekeyVerifyRestart(recovery); err != nil {
+		respondError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	handleSysRekeyVerifyGet(core, recovery, w, r)
+}
+
+// decodeRekeyKey decodes a key share, which is base64 or hex encoded
+func decodeRekeyKey(core *vault.Core, encoded string) ([]byte, error) {
+	min, max := core.BarrierKeyLength()
+	key, err := hex.DecodeString(encoded)
+	// We check min and max here to ensure that a string that is base64
+	// encoded but also valid hex will not be valid and we instead base64
+	// decode it
+	if err != nil || len(key) < min || len(key) > max {
+		key, err = base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, errors.New("'key' must be a valid hex or base64 string")
+		}
+	}
+	return key, nil
+}
+
 type RekeyRequest struct {
 	SecretShares    int      `json:"secret_shares"`
 	SecretThreshold int      `json:"secret_threshold"`
 	StoredShares    int      `json:"stored_shares"`
 	PGPKeys         []string `json:"pgp_keys"`
 	Backup          bool     `json:"backup"`
+
+	RequireVerification bool `json:"require_verification"`
 }
 
 type RekeyStatusResponse struct {
-	Nonce           string   `json:"nonce"`
-	Started         bool     `json:"started"`
-	T               int      `json:"t"`
-	N               int      `json:"n"`
-	Progress        int      `json:"progress"`
-	Required        int      `json:"required"`
-	PGPFingerprints []string `json:"pgp_fingerprints"`
-	Backup          bool     `json:"backup"`
+	Nonce                string   `json:"nonce"`
+	Started              bool     `json:"started"`
+	T                    int      `json:"t"`
+	N                    int      `json:"n"`
+	Progress             int      `json:"progress"`
+	Required             int      `json:"required"`
+	PGPFingerprints      []string `json:"pgp_fingerprints"`
+	Backup               bool     `json:"backup"`
+	VerificationRequired bool     `json:"verification_required"`
+	VerificationNonce    string   `json:"verification_nonce,omitempty"`
 }
 
 type RekeyUpdateRequest struct {
@@ -252,10 +374,30 @@ type RekeyUpdateRequest struct {
 }
 
 type RekeyUpdateResponse struct {
-	Nonce           string   `json:"nonce"`
-	Complete        bool     `json:"complete"`
-	Keys            []string `json:"keys"`
-	KeysB64         []string `json:"keys_base64"`
-	PGPFingerprints []string `json:"pgp_fingerprints"`
-	Backup          bool     `json:"backup"`
+	Nonce                string   `json:"nonce"`
+	Complete             bool     `json:"complete"`
+	Keys                 []string `json:"keys"`
+	KeysB64              []string `json:"keys_base64"`
+	PGPFingerprints      []string `json:"pgp_fingerprints"`
+	Backup               bool     `json:"backup"`
+	VerificationRequired bool     `json:"verification_required"`
+	VerificationNonce    string   `json:"verification_nonce,omitempty"`
+}
+
+type RekeyVerificationUpdateRequest struct {
+	Nonce string `json:"nonce"`
+	Key   string `json:"key"`
+}
+
+type RekeyVerificationStatusResponse struct {
+	Nonce    string `json:"nonce"`
+	Started  bool   `json:"started"`
+	T        int    `json:"t"`
+	N        int    `json:"n"`
+	Progress int    `json:"progress"`
+}
+
+type RekeyVerificationUpdateResponse struct {
+	Nonce    string `json:"nonce"`
+	Complete bool   `json:"complete"`
 }
@@ -40,14 +40,15 @@ func TestSysRekey_Init_Status(t *testing.T) {
 
 	var actual map[string]interface{}
 	expected := map[string]interface{}{
-		"started":          false,
-		"t":                json.Number("0"),
-		"n":                json.Number("0"),
-		"progress":         json.Number("0"),
-		"required":         json.Number("3"),
-		"pgp_fingerprints": interface{}(nil),
-		"backup":           false,
-		"nonce":            "",
+		"started":               false,
+		"t":                     json.Number("0"),
+		"n":                     json.Number("0"),
+		"progress":              json.Number("0"),
+		"required":              json.Number("3"),
+		"pgp_fingerprints":      interface{}(nil),
+		"backup":                false,
+		"verification_required": false,
+		"nonce":                 "",
 	}
 	testResponseStatus(t, resp, 200)
 	testResponseBody(t, resp, &actual)
@@ -71,13 +72,14 @@ func TestSysRekey_Init_Setup(t *testing.T) {
 
 	var actual map[string]interface{}
 	expected := map[string]interface{}{
-		"started":          true,
-		"t":                json.Number("3"),
-		"n":                json.Number("5"),
-		"progress":         json.Number("0"),
-		"required":         json.Number("3"),
-		"pgp_fingerprints": interface{}(nil),
-		"backup":           false,
+		"started":               true,
+		"t":                     json.Number("3"),
+		"n":                     json.Number("5"),
+		"progress":              json.Number("0"),
+		"required":              json.Number("3"),
+		"pgp_fingerprints":      interface{}(nil),
+		"backup":                false,
+		"verification_required": false,
 	}
 	testResponseStatus(t, resp, 200)
 	testResponseBody(t, resp, &actual)
@@ -94,13 +96,14 @@ func TestSysRekey_Init_Setup(t *testing.T) {
 
 	actual = map[string]interface{}{}
 	expected = map[string]interface{}{
-		"started":          true,
-		"t":                json.Number("3"),
-		"n":                json.Number("5"),
-		"progress":         json.Number("0"),
-		"required":         json.Number("3"),
-		"pgp_fingerprints": interface{}(nil),
-		"backup":           false,
+		"started":               true,
+		"t":                     json.Number("3"),
+		"n":                     json.Number("5"),
+		"progress":              json.Number("0"),
+		"required":              json.Number("3"),
+		"pgp_fingerprints":      interface{}(nil),
+		"backup":                false,
+		"verification_required": false,
 	}
 	testResponseStatus(t, resp, 200)
 	testResponseBody(t, resp, &actual)
@@ -138,14 +141,15 @@ func TestSysRekey_Init_Cancel(t *testing.T) {
 
 	var actual map[string]interface{}
 	expected := map[string]interface{}{
-		"started":          false,
-		"t":                json.Number("0"),
-		"n":                json.Number("0"),
-		"progress":         json.Number("0"),
-		"required":         json.Number("3"),
-		"pgp_fingerprints": interface{}(nil),
-		"backup":           false,
-		"nonce":            "",
+		"started":               false,
+		"t":                     json.Number("0"),
+		"n":                     json.Number("0"),
+		"progress":              json.Number("0"),
+		"required":              json.Number("3"),
+		"pgp_fingerprints":      interface{}(nil),
+		"backup":                false,
+		"verification_required": false,
+		"nonce":                 "",
 	}
 	testResponseStatus(t, resp, 200)
 	testResponseBody(t, resp, &actual)
@@ -191,14 +195,15 @@ func TestSysRekey_Update(t *testing.T) {
 
 		actual = map[string]interface{}{}
 		expected = map[string]interface{}{
-			"started":          true,
-			"nonce":            rekeyStatus["nonce"].(string),
-			"backup":           false,
-			"pgp_fingerprints": interface{}(nil),
-			"required":         json.Number("3"),
-			"t":                json.Number("3"),
-			"n":                json.Number("5"),
-			"progress":         json.Number(fmt.Sprintf("%d", i+1)),
+			"started":               true,
+			"nonce":                 rekeyStatus["nonce"].(string),
+			"backup":                false,
+			"verification_required": false,
+			"pgp_fingerprints":      interface{}(nil),
+			"required":              json.Number("3"),
+			"t":                     json.Number("3"),
+			"n":                     json.Number("5"),
+			"progress":              json.Number(fmt.Sprintf("%d", i+1)),
 		}
 		testResponseStatus(t, resp, 200)
 		testResponseBody(t, resp, &actual)
@@ -260,3 +265,74 @@ func TestSysRekey_ReInitUpdate(t *testing.T) {
 
 	testResponseStatus(t, resp, 400)
 }
+
+func TestSysRekey_Verify(t *testing.T) {
+	core, keys, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+	TestServerAuth(t, addr, token)
+
+	resp := testHttpPut(t, token, addr+"/v1/sys/rekey/init", map[string]interface{}{
+		"secret_shares":        5,
+		"secret_threshold":     3,
+		"require_verification": true,
+	})
+	var rekeyStatus map[string]interface{}
+	testResponseStatus(t, resp, 200)
+	testResponseBody(t, resp, &rekeyStatus)
+	if rekeyStatus["verification_required"] != true {
+		t.Fatalf("bad: %#v", rekeyStatus)
+	}
+
+	var actual map[string]interface{}
+	for _, key := range keys {
+		resp = testHttpPut(t, token, addr+"/v1/sys/rekey/update", map[string]interface{}{
+			"nonce": rekeyStatus["nonce"].(string),
+			"key":   hex.EncodeToString(key),
+		})
+		actual = map[string]interface{}{}
+		testResponseStatus(t, resp, 200)
+		testResponseBody(t, resp, &actual)
+	}
+	if actual["complete"] != true || actual["verification_required"] != true {
+		t.Fatalf("bad: %#v", actual)
+	}
+	verificationNonce := actual["verification_nonce"].(string)
+	newKeys := actual["keys"].([]interface{})
+
+	resp = testHttpGet(t, token, addr+"/v1/sys/rekey/verify")
+	var verifyStatus map[string]interface{}
+	testResponseStatus(t, resp, 200)
+	testResponseBody(t, resp, &verifyStatus)
+	expected := map[string]interface{}{
+		"nonce":    verificationNonce,
+		"started":  true,
+		"t":        json.Number("3"),
+		"n":        json.Number("5"),
+		"progress": json.Number("0"),
+	}
+	if !reflect.DeepEqual(verifyStatus, expected) {
+		t.Fatalf("\nexpected: %#v\nactual: %#v", expected, verifyStatus)
+	}
+
+	for i := 0; i < 3; i++ {
+		resp = testHttpPut(t, token, addr+"/v1/sys/rekey/verify", map[string]interface{}{
+			"nonce": verificationNonce,
+			"key":   newKeys[i].(string),
+		})
+		actual = map[string]interface{}{}
+		testResponseStatus(t, resp, 200)
+		testResponseBody(t, resp, &actual)
+	}
+	expected = map[string]interface{}{
+		"nonce":    rekeyStatus["nonce"].(string),
+		"complete": true,
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("\nexpected: %#v\nactual: %#v", expected, actual)
+	}
+
+	// The verification is over
+	resp = testHttpGet(t, token, addr+"/v1/sys/rekey/verify")
+	testResponseStatus(t, resp, 400)
+}
@@ -0,0 +1,148 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/vault"
+)
+
+// The replication status and the operations of DR secondaries are handled
+// here rather than by the system backend, as DR secondaries don't serve
+// logical requests.
+
+func handleSysReplicationStatus(core *vault.Core) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			respondError(w, http.StatusMethodNotAllowed, nil)
+			return
+		}
+
+		respondOk(w, &logical.HTTPResponse{
+			Data: map[string]interface{}{
+				"dr": core.DRReplicationStatus(),
+				"performance": map[string]interface{}{
+					"mode": "disabled",
+				},
+			},
+		})
+	})
+}
+
+func handleSysDRReplicationStatus(core *vault.Core) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			respondError(w, http.StatusMethodNotAllowed, nil)
+			return
+		}
+
+		respondOk(w, &logical.HTTPResponse{
+			Data: core.DRReplicationStatus(),
+		})
+	})
+}
+
+func handleSysDRSecondaryPromote(core *vault.Core) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "PUT", "POST":
+		default:
+			respondError(w, http.StatusMethodNotAllowed, nil)
+			return
+		}
+
+		var req DRSecondaryPromoteRequest
+		if err := parseRequest(r, w, &req); err != nil {
+			respondError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		ctx, cancel := core.GetContext()
+		defer cancel()
+
+		if err := core.PromoteDRSecondary(ctx, req.DROperationToken, req.PrimaryClusterAddr); err != nil {
+			respondDRError(w, err)
+			return
+		}
+
+		respondOk(w, nil)
+	})
+}
+
+func handleSysDRSecondaryUpdatePrimary(core *vault.Core) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "PUT", "POST":
+		default:
+			respondError(w, http.StatusMethodNotAllowed, nil)
+			return
+		}
+
+		var req DRSecondaryUpdatePrimaryRequest
+		if err := parseRequest(r, w, &req); err != nil {
+			respondError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		ctx, cancel := core.GetContext()
+		defer cancel()
+
+		if err := core.UpdateDRSecondaryPrimary(ctx, req.DROperationToken, req.Token, req.PrimaryAPIAddr, req.CAFile, req.CAPath); err != nil {
+			respondDRError(w, err)
+			return
+		}
+
+		respondOk(w, nil)
+	})
+}
+
+func handleSysDROperationTokenDelete(core *vault.Core) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "PUT", "POST":
+		default:
+			respondError(w, http.StatusMethodNotAllowed, nil)
+			return
+		}
+
+		var req DROperationTokenRequest
+		if err := parseRequest(r, w, &req); err != nil {
+			respondError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		ctx, cancel := core.GetContext()
+		defer cancel()
+
+		if err := core.DeleteDROperationToken(ctx, req.DROperationToken); err != nil {
+			respondDRError(w, err)
+			return
+		}
+
+		respondOk(w, nil)
+	})
+}
+
+// respondDRError responds with the status code of the error of an operation
+// of a DR secondary
+func respondDRError(w http.ResponseWriter, err error) {
+	statusCode, _ := logical.RespondErrorCommon(&logical.Request{}, nil, err)
+	respondError(w, statusCode, err)
+}
+
+type DROperationTokenRequest struct {
+	DROperationToken string `json:"dr_operation_token"`
+}
+
+type DRSecondaryPromoteRequest struct {
+	DROperationToken   string `json:"dr_operation_token"`
+	PrimaryClusterAddr string `json:"primary_cluster_addr"`
+}
+
+type DRSecondaryUpdatePrimaryRequest struct {
+	DROperationToken string `json:"dr_operation_token"`
+	Token            string `json:"token"`
+	PrimaryAPIAddr   string `json:"primary_api_addr"`
+	CAFile           string `json:"ca_file"`
+	CAPath           string `json:"ca_path"`
+}
@@ -0,0 +1,194 @@
+package http
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/helper/xor"
+	"github.com/hashicorp/vault/vault"
+)
+
+func testDRStatus(t *testing.T, client *api.Client) map[string]interface{} {
+	t.Helper()
+
+	secret, err := client.Logical().Read("sys/replication/dr/status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret == nil || secret.Data == nil {
+		t.Fatal("expected a status")
+	}
+	return secret.Data
+}
+
+func testWaitDRStatus(t *testing.T, client *api.Client, check func(map[string]interface{}) bool) map[string]interface{} {
+	t.Helper()
+
+	start := time.Now()
+	for {
+		status := testDRStatus(t, client)
+		if check(status) {
+			return status
+		}
+		if time.Since(start) > 30*time.Second {
+			t.Fatalf("timed out waiting for the DR status, last: %#v", status)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func TestSysReplication_DR(t *testing.T) {
+	primary := vault.NewTestCluster(t, &vault.CoreConfig{}, &vault.TestClusterOptions{
+		HandlerFunc: Handler,
+		NumCores:    1,
+	})
+	primary.Start()
+	defer primary.Cleanup()
+	secondary := vault.NewTestCluster(t, &vault.CoreConfig{}, &vault.TestClusterOptions{
+		HandlerFunc: Handler,
+		NumCores:    1,
+	})
+	secondary.Start()
+	defer secondary.Cleanup()
+
+	vault.TestWaitActive(t, primary.Cores[0].Core)
+	vault.TestWaitActive(t, secondary.Cores[0].Core)
+	pc := primary.Cores[0].Client
+	sc := secondary.Cores[0].Client
+
+	if status := testDRStatus(t, pc); status["mode"] != "disabled" {
+		t.Fatalf("bad: %#v", status)
+	}
+
+	if _, err := pc.Logical().Write("sys/replication/dr/primary/enable", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pc.Logical().Write("secret/foo", map[string]interface{}{
+		"value": "bar",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	secret, err := pc.Logical().Write("sys/replication/dr/primary/secondary-token", map[string]interface{}{
+		"id": "sec1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret == nil || secret.WrapInfo == nil || secret.WrapInfo.Token == "" {
+		t.Fatalf("expected a wrapped activation token: %#v", secret)
+	}
+	if _, err := pc.Logical().Write("sys/replication/dr/primary/secondary-token", map[string]interface{}{
+		"id": "sec1",
+	}); err == nil {
+		t.Fatal("expected an error for a duplicate id")
+	}
+
+	if _, err := sc.Logical().Write("sys/replication/dr/secondary/enable", map[string]interface{}{
+		"token":   secret.WrapInfo.Token,
+		"ca_file": primary.CACertPEMFile,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	testWaitDRStatus(t, sc, func(status map[string]interface{}) bool {
+		return status["mode"] == "secondary" && status["state"] == "stream-wals"
+	})
+
+	// Changes made after the bootstrap are streamed from the WAL
+	if _, err := pc.Logical().Write("secret/bar", map[string]interface{}{
+		"value": "baz",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pc.Logical().Delete("secret/foo"); err != nil {
+		t.Fatal(err)
+	}
+	testWaitDRStatus(t, pc, func(status map[string]interface{}) bool {
+		info, ok := status["connected_secondaries"].(map[string]interface{})["sec1"].(map[string]interface{})
+		return ok && info["lag"] != nil && info["lag"].(interface{ String() string }).String() == "0"
+	})
+	status := testWaitDRStatus(t, sc, func(status map[string]interface{}) bool {
+		return status["lag"] != nil && status["lag"].(interface{ String() string }).String() == "0"
+	})
+	if status["secondary_id"] != "sec1" || status["cluster_id"] == "" {
+		t.Fatalf("bad: %#v", status)
+	}
+
+	// The secondary doesn't serve requests until it's promoted
+	sc.SetToken(primary.RootToken)
+	if _, err := sc.Logical().Read("secret/bar"); err == nil || !strings.Contains(err.Error(), "DR replication secondary") {
+		t.Fatalf("expected the request to be rejected, got %v", err)
+	}
+	replication, err := sc.Logical().Read("sys/replication/status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replication.Data["dr"].(map[string]interface{})["mode"] != "secondary" {
+		t.Fatalf("bad: %#v", replication.Data)
+	}
+
+	// Generate a DR operation token with the unseal keys of the secondary
+	otpBytes, err := vault.GenerateRandBytes(16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otp := base64.StdEncoding.EncodeToString(otpBytes)
+	if _, err := sc.Sys().GenerateDROperationTokenInit(otp, ""); err != nil {
+		t.Fatal(err)
+	}
+	generation, err := sc.Sys().GenerateDROperationTokenStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range secondary.BarrierKeys {
+		if generation, err = sc.Sys().GenerateDROperationTokenUpdate(hex.EncodeToString(key), generation.Nonce); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !generation.Complete {
+		t.Fatalf("bad: %#v", generation)
+	}
+	decoded, err := xor.XORBase64(otp, generation.EncodedToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	operationToken, err := uuid.FormatUUID(decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := sc.Logical().Write("sys/replication/dr/secondary/promote", map[string]interface{}{
+		"dr_operation_token": "bogus",
+	}); err == nil {
+		t.Fatal("expected an error for a bad operation token")
+	}
+	if _, err := sc.Logical().Write("sys/replication/dr/secondary/promote", map[string]interface{}{
+		"dr_operation_token": operationToken,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The promoted secondary serves the replicated data and tokens
+	if status := testDRStatus(t, sc); status["mode"] != "primary" {
+		t.Fatalf("bad: %#v", status)
+	}
+	secret, err = sc.Logical().Read("secret/bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret == nil || secret.Data["value"] != "baz" {
+		t.Fatalf("bad: %#v", secret)
+	}
+	secret, err = sc.Logical().Read("secret/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret != nil {
+		t.Fatalf("expected secret/foo to be deleted: %#v", secret)
+	}
+}
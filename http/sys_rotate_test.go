@@ -43,6 +43,19 @@ func TestSysRotate(t *testing.T) {
 	expected["data"].(map[string]interface{})["install_time"] = actualInstallTime
 	expected["install_time"] = actualInstallTime
 
+	// The encryption counts depend on the writes of the setup
+	for _, field := range []string{"encryptions", "keys"} {
+		value, ok := actual["data"].(map[string]interface{})[field]
+		if !ok {
+			t.Fatalf("%s missing in data", field)
+		}
+		expected["data"].(map[string]interface{})[field] = value
+		expected[field] = value
+	}
+	if keys := actual["keys"].([]interface{}); len(keys) != 2 {
+		t.Fatalf("bad: %#v", keys)
+	}
+
 	expected["request_id"] = actual["request_id"]
 
 	if !reflect.DeepEqual(actual, expected) {
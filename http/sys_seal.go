@@ -124,9 +124,12 @@ func handleSysUnseal(core *vault.Core) http.Handler {
 
 			// Attempt the unseal
 			ctx := context.Background()
-			if core.SealAccess().RecoveryKeySupported() {
+			switch {
+			case req.Migrate:
+				_, err = core.UnsealMigrate(key)
+			case core.SealAccess().RecoveryKeySupported():
 				_, err = core.UnsealWithRecoveryKeys(ctx, key)
-			} else {
+			default:
 				_, err = core.Unseal(key)
 			}
 			if err != nil {
@@ -136,6 +139,7 @@ func handleSysUnseal(core *vault.Core) http.Handler {
 				case errwrap.Contains(err, vault.ErrBarrierNotInit.Error()):
 				case errwrap.Contains(err, vault.ErrBarrierSealed.Error()):
 				case errwrap.Contains(err, consts.ErrStandby.Error()):
+				case errwrap.Contains(err, vault.ErrSealMigrationRequired.Error()):
 				default:
 					respondError(w, http.StatusInternalServerError, err)
 					return
@@ -170,6 +174,11 @@ func handleSysSealStatusRaw(core *vault.Core, w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	if err := core.CheckSealMigration(ctx); err != nil {
+		respondError(w, http.StatusInternalServerError, err)
+		return
+	}
+
 	var sealConfig *vault.SealConfig
 	if core.SealAccess().RecoveryKeySupported() {
 		sealConfig, err = core.SealAccess().RecoveryConfig(ctx)
@@ -215,6 +224,7 @@ func handleSysSealStatusRaw(core *vault.Core, w http.ResponseWriter, r *http.Req
 		Version:     version.GetVersion().VersionNumber(),
 		ClusterName: clusterName,
 		ClusterID:   clusterID,
+		Migration:   core.SealMigrationPending(),
 	})
 }
 
@@ -228,9 +238,11 @@ type SealStatusResponse struct {
 	Version     string `json:"version"`
 	ClusterName string `json:"cluster_name,omitempty"`
 	ClusterID   string `json:"cluster_id,omitempty"`
+	Migration   bool   `json:"migration,omitempty"`
 }
 
 type UnsealRequest struct {
-	Key   string
-	Reset bool
+	Key     string
+	Reset   bool
+	Migrate bool
 }
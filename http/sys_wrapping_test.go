@@ -352,3 +352,109 @@ func TestHTTP_Wrapping(t *testing.T) {
 		t.Fatalf("secret data did not match expected: %#v", secret.Data)
 	}
 }
+
+func TestHTTP_Wrapping_Validate(t *testing.T) {
+	cluster := vault.NewTestCluster(t, &vault.CoreConfig{}, &vault.TestClusterOptions{
+		HandlerFunc: Handler,
+	})
+	cluster.Start()
+	defer cluster.Cleanup()
+
+	core := cluster.Cores[0].Core
+	vault.TestWaitActive(t, core)
+
+	client := cluster.Cores[0].Client
+	client.SetToken(cluster.RootToken)
+
+	for _, key := range []string{"foo", "bar"} {
+		_, err := client.Logical().Write("secret/"+key, map[string]interface{}{
+			"zip": key,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	client.SetWrappingLookupFunc(func(operation, path string) string {
+		if operation == "GET" && path != "sys/wrapping/lookup" {
+			return "5m"
+		}
+		return api.DefaultWrappingLookupFunc(operation, path)
+	})
+	var tokens []string
+	for _, key := range []string{"foo", "bar"} {
+		secret, err := client.Logical().Read("secret/" + key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if secret == nil || secret.WrapInfo == nil {
+			t.Fatal("secret or wrap info is nil")
+		}
+		tokens = append(tokens, secret.WrapInfo.Token)
+	}
+	client.SetWrappingLookupFunc(api.DefaultWrappingLookupFunc)
+
+	// Validate the creation paths
+	lookup, err := client.Sys().ValidateWrapping(tokens[0], "secret/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lookup.CreationPath != "secret/foo" || lookup.CreationTTL != 300 || lookup.CreationTime.IsZero() {
+		t.Fatalf("bad: %#v", lookup)
+	}
+	if _, err := client.Sys().ValidateWrapping(tokens[1], "secret/f*"); err == nil {
+		t.Fatal("expected an error")
+	}
+	if _, err := client.Sys().ValidateWrapping(tokens[1], "secret/f*", "secret/b*"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A batch with an invalid token does not rewrap any of them
+	if _, err := client.Sys().RewrapBatch([]string{tokens[0], "invalid"}); err == nil {
+		t.Fatal("expected an error")
+	}
+	if _, err := client.Sys().WrapLookup(tokens[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	wrapInfos, err := client.Sys().RewrapBatch(tokens)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, key := range []string{"foo", "bar"} {
+		if wrapInfos[i].CreationPath != "secret/"+key || wrapInfos[i].TTL != 300 || wrapInfos[i].Token == tokens[i] {
+			t.Fatalf("bad: %#v", wrapInfos[i])
+		}
+
+		// The original tokens are revoked
+		if _, err := client.Sys().ValidateWrapping(tokens[i], "secret/*"); err == nil {
+			t.Fatal("expected an error")
+		}
+
+		secret, err := client.Logical().Unwrap(wrapInfos[i].Token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if secret.Data["zip"] != key {
+			t.Fatalf("bad: %#v", secret.Data)
+		}
+
+		// The new tokens can only be unwrapped once
+		if _, err := client.Sys().ValidateWrapping(wrapInfos[i].Token, "secret/*"); err == nil {
+			t.Fatal("expected an error")
+		}
+	}
+
+	// Single rewraps
+	secret, err := client.Logical().Write("sys/wrapping/wrap", map[string]interface{}{"zip": "zap"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrapInfo, err := client.Sys().Rewrap(secret.WrapInfo.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wrapInfo.CreationPath != "sys/wrapping/wrap" || wrapInfo.Token == secret.WrapInfo.Token {
+		t.Fatalf("bad: %#v", wrapInfo)
+	}
+}
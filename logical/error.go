@@ -1,5 +1,7 @@
 package logical
 
+import "time"
+
 type HTTPCodedError interface {
 	Error() string
 	Code() int
@@ -48,3 +50,13 @@ type ReplicationCodedError struct {
 func (r *ReplicationCodedError) Error() string {
 	return r.Msg
 }
+
+// RateLimitQuotaError is returned if the request is rejected by a rate limit
+// quota. RetryAfter is the time until the client is allowed another request.
+type RateLimitQuotaError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitQuotaError) Error() string {
+	return ErrRateLimitQuotaExceeded.Error()
+}
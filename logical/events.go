@@ -0,0 +1,25 @@
+package logical
+
+// The types of the events published by the builtin backends and by Vault. The
+// event types are prefixed by the kind of backend publishing them.
+const (
+	// EventTypeKVWrite is published when a secret is written to a key/value
+	// backend
+	EventTypeKVWrite = "kv/write"
+
+	// EventTypeKVDelete is published when a secret is deleted from a
+	// key/value backend
+	EventTypeKVDelete = "kv/delete"
+
+	// EventTypePKICertIssued is published when a PKI backend issues or signs
+	// a certificate
+	EventTypePKICertIssued = "pki/cert-issued"
+
+	// EventTypePKICARotated is published when the default issuer of a PKI
+	// backend changes
+	EventTypePKICARotated = "pki/ca-rotated"
+
+	// EventTypeLeaseRevoked is published when a lease is revoked, including
+	// when it expires
+	EventTypeLeaseRevoked = "lease/revoked"
+)
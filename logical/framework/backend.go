@@ -2,8 +2,10 @@ package framework
 
 import (
 	"context"
+	"crypto/rand"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"regexp"
 	"sort"
@@ -14,6 +16,7 @@ import (
 	log "github.com/mgutz/logxi/v1"
 
 	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/vault/helper/entropy"
 	"github.com/hashicorp/vault/helper/errutil"
 	"github.com/hashicorp/vault/helper/logformat"
 	"github.com/hashicorp/vault/helper/parseutil"
@@ -86,6 +89,11 @@ type Backend struct {
 	system  logical.SystemView
 	once    sync.Once
 	pathsRe []*regexp.Regexp
+
+	// fieldPatterns are the compiled patterns of the fields of the paths, by
+	// pattern, and patternsErr the error of an invalid pattern
+	fieldPatterns map[string]*regexp.Regexp
+	patternsErr   error
 }
 
 // periodicFunc is the callback called when the RollbackManager's timer ticks.
@@ -210,16 +218,30 @@ func (b *Backend) HandleRequest(ctx context.Context, req *logical.Request) (*log
 	fd := FieldData{
 		Raw:    raw,
 		Schema: path.Fields}
+	if req.Operation == logical.ListOperation {
+		fd.Schema = listPageSchema(path.Fields)
+	}
 
 	if req.Operation != logical.HelpOperation {
 		err := fd.Validate()
 		if err != nil {
 			return nil, err
 		}
+
+		// The required fields are only checked on writes, as the reads and
+		// deletes only get the fields captured from the path
+		write := req.Operation == logical.CreateOperation || req.Operation == logical.UpdateOperation
+		if err := fd.validateConstraints(write, b.fieldPatterns); err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
 	}
 
 	// Call the callback with the request and the data
-	return callback(ctx, req, &fd)
+	resp, err := callback(ctx, req, &fd)
+	if err != nil || req.Operation != logical.ListOperation {
+		return resp, err
+	}
+	return paginateListResponse(resp, &fd), nil
 }
 
 // SpecialPaths is the logical.Backend implementation.
@@ -227,6 +249,25 @@ func (b *Backend) SpecialPaths() *logical.Paths {
 	return b.PathsSpecial
 }
 
+// OperationProperties is the logical.OperationPropertiesBackend
+// implementation.
+func (b *Backend) OperationProperties(path string, op logical.Operation) logical.OperationProperties {
+	p, _ := b.route(path)
+	if p == nil {
+		return logical.OperationProperties{}
+	}
+	if props, ok := p.Properties[op]; ok {
+		return props
+	}
+
+	// The core only knows if an update creates the entry once it has checked
+	// its existence
+	if op == logical.UpdateOperation {
+		return p.Properties[logical.CreateOperation]
+	}
+	return logical.OperationProperties{}
+}
+
 // Cleanup is used to release resources and prepare to stop the backend
 func (b *Backend) Cleanup(ctx context.Context) {
 	if b.Clean != nil {
@@ -245,7 +286,11 @@ func (b *Backend) InvalidateKey(ctx context.Context, key string) {
 func (b *Backend) Setup(ctx context.Context, config *logical.BackendConfig) error {
 	b.logger = config.Logger
 	b.system = config.System
-	return nil
+
+	// The field patterns are compiled along with the paths, so that invalid
+	// patterns fail the setup rather than the requests
+	b.once.Do(b.init)
+	return b.patternsErr
 }
 
 // Logger can be used to get the logger. If no logger has been set,
@@ -263,6 +308,16 @@ func (b *Backend) System() logical.SystemView {
 	return b.system
 }
 
+// GetRandomReader returns the source of the random data of the keys generated
+// by the backend, which mixes in the entropy of the external source of Vault
+// when one is configured.
+func (b *Backend) GetRandomReader() io.Reader {
+	if sourcer, ok := b.System().(entropy.Sourcer); ok {
+		return entropy.NewReader(sourcer)
+	}
+	return rand.Reader
+}
+
 // Type returns the backend type
 func (b *Backend) Type() logical.BackendType {
 	return b.BackendType
@@ -344,6 +399,24 @@ func (b *Backend) init() {
 		}
 		b.pathsRe[i] = regexp.MustCompile(p.Pattern)
 	}
+
+	b.fieldPatterns = make(map[string]*regexp.Regexp)
+	for _, p := range b.Paths {
+		for field, schema := range p.Fields {
+			if schema.Pattern == "" {
+				continue
+			}
+			if _, ok := b.fieldPatterns[schema.Pattern]; ok {
+				continue
+			}
+			re, err := regexp.Compile("^(?:" + schema.Pattern + ")$")
+			if err != nil {
+				b.patternsErr = fmt.Errorf("invalid pattern for field %s of path %s: %v", field, p.Pattern, err)
+				continue
+			}
+			b.fieldPatterns[schema.Pattern] = re
+		}
+	}
 }
 
 func (b *Backend) route(path string) (*Path, map[string]string) {
@@ -403,7 +476,13 @@ func (b *Backend) handleRootHelp() (*logical.Response, error) {
 		return nil, err
 	}
 
-	return logical.HelpResponse(help, nil), nil
+	// Along with the help, return the OpenAPI document of the paths
+	doc := NewOASDocument()
+	documentPaths(b, doc)
+
+	resp := logical.HelpResponse(help, nil)
+	resp.Data["openapi"] = doc
+	return resp, nil
 }
 
 func (b *Backend) handleRevokeRenew(ctx context.Context, req *logical.Request) (*logical.Response, error) {
@@ -527,6 +606,23 @@ type FieldSchema struct {
 	Type        FieldType
 	Default     interface{}
 	Description string
+
+	// Required fields must be set by the create and update requests, to a
+	// non-empty value for the string fields.
+	Required bool
+
+	// AllowedValues are the values the field can be set to. Each element of
+	// the slice fields must be one of them. No restriction if empty.
+	AllowedValues []interface{}
+
+	// Pattern is a regular expression the values of the string fields, and
+	// the elements of the slice of strings fields, must match entirely.
+	Pattern string
+
+	// Min and Max bound the values of the TypeInt fields, and the number of
+	// seconds of the TypeDurationSecond fields. No bound if nil.
+	Min *int
+	Max *int
 }
 
 // DefaultOrZero returns the default value if it is set, or otherwise
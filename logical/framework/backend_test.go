@@ -3,6 +3,7 @@ package framework
 import (
 	"context"
 	"reflect"
+	"strings"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -40,6 +41,7 @@ func BenchmarkBackendRoute(b *testing.B) {
 
 func TestBackend_impl(t *testing.T) {
 	var _ logical.Backend = new(Backend)
+	var _ logical.OperationPropertiesBackend = new(Backend)
 }
 
 func TestBackendHandleRequest(t *testing.T) {
@@ -113,6 +115,120 @@ func TestBackendHandleRequest_badwrite(t *testing.T) {
 
 }
 
+func TestBackendHandleRequest_constraints(t *testing.T) {
+	callback := func(ctx context.Context, req *logical.Request, data *FieldData) (*logical.Response, error) {
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"ok": true,
+			},
+		}, nil
+	}
+
+	min, max := 1, 10
+	b := &Backend{
+		Paths: []*Path{
+			&Path{
+				Pattern: "foo/bar",
+				Fields: map[string]*FieldSchema{
+					"name": &FieldSchema{
+						Type:     TypeString,
+						Required: true,
+						Pattern:  "[a-z]+",
+					},
+					"type": &FieldSchema{
+						Type:          TypeString,
+						AllowedValues: []interface{}{"rsa", "ec"},
+					},
+					"bits": &FieldSchema{
+						Type:          TypeInt,
+						AllowedValues: []interface{}{2048, 4096},
+					},
+					"count": &FieldSchema{
+						Type: TypeInt,
+						Min:  &min,
+						Max:  &max,
+					},
+					"ttl": &FieldSchema{
+						Type: TypeDurationSecond,
+						Max:  &max,
+					},
+					"tags": &FieldSchema{
+						Type:          TypeCommaStringSlice,
+						AllowedValues: []interface{}{"a", "b"},
+					},
+				},
+				Callbacks: map[logical.Operation]OperationFunc{
+					logical.ReadOperation:   callback,
+					logical.UpdateOperation: callback,
+				},
+			},
+		},
+	}
+
+	cases := []struct {
+		op   logical.Operation
+		data map[string]interface{}
+		err  string
+	}{
+		{logical.UpdateOperation, map[string]interface{}{"name": "foo"}, ""},
+		{logical.UpdateOperation, map[string]interface{}{}, "missing name"},
+		{logical.UpdateOperation, map[string]interface{}{"name": ""}, "missing name"},
+		{logical.ReadOperation, map[string]interface{}{}, ""},
+		{logical.UpdateOperation, map[string]interface{}{"name": "foo1"}, `invalid name "foo1": must match "[a-z]+"`},
+		{logical.UpdateOperation, map[string]interface{}{"name": "foo", "type": "ec"}, ""},
+		{logical.UpdateOperation, map[string]interface{}{"name": "foo", "type": "dsa"}, `invalid type "dsa": must be one of "rsa" or "ec"`},
+		{logical.ReadOperation, map[string]interface{}{"type": "dsa"}, `invalid type "dsa": must be one of "rsa" or "ec"`},
+		{logical.UpdateOperation, map[string]interface{}{"name": "foo", "bits": "4096"}, ""},
+		{logical.UpdateOperation, map[string]interface{}{"name": "foo", "bits": 1024}, "invalid bits 1024: must be one of 2048 or 4096"},
+		{logical.UpdateOperation, map[string]interface{}{"name": "foo", "count": 10}, ""},
+		{logical.UpdateOperation, map[string]interface{}{"name": "foo", "count": 0}, "invalid count 0: must be at least 1"},
+		{logical.UpdateOperation, map[string]interface{}{"name": "foo", "count": 11}, "invalid count 11: must be at most 10"},
+		{logical.UpdateOperation, map[string]interface{}{"name": "foo", "ttl": "11s"}, "invalid ttl 11: must be at most 10"},
+		{logical.UpdateOperation, map[string]interface{}{"name": "foo", "tags": "a,b"}, ""},
+		{logical.UpdateOperation, map[string]interface{}{"name": "foo", "tags": "a,c"}, `invalid tags "c": must be one of "a" or "b"`},
+	}
+	for i, tc := range cases {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: tc.op,
+			Path:      "foo/bar",
+			Data:      tc.data,
+		})
+		if err != nil {
+			t.Fatalf("%d: err: %s", i, err)
+		}
+		if tc.err == "" {
+			if resp.IsError() {
+				t.Fatalf("%d: bad: %#v", i, resp)
+			}
+			continue
+		}
+		if !resp.IsError() || resp.Error().Error() != tc.err {
+			t.Fatalf("%d: expected error %q, got %#v", i, tc.err, resp)
+		}
+	}
+}
+
+func TestBackendSetup_invalidPattern(t *testing.T) {
+	b := &Backend{
+		Paths: []*Path{
+			&Path{
+				Pattern: "foo/bar",
+				Fields: map[string]*FieldSchema{
+					"name": &FieldSchema{
+						Type:    TypeString,
+						Pattern: "[a-z",
+					},
+				},
+			},
+		},
+	}
+
+	err := b.Setup(context.Background(), &logical.BackendConfig{})
+	if err == nil || !strings.Contains(err.Error(), "invalid pattern for field name of path ^foo/bar$") {
+		t.Fatalf("expected an invalid pattern error, got %v", err)
+	}
+}
+
 func TestBackendHandleRequest_404(t *testing.T) {
 	callback := func(ctx context.Context, req *logical.Request, data *FieldData) (*logical.Response, error) {
 		return &logical.Response{
@@ -498,6 +614,45 @@ func TestBackendRoute(t *testing.T) {
 	}
 }
 
+func TestBackendOperationProperties(t *testing.T) {
+	b := &Backend{
+		Paths: []*Path{
+			&Path{
+				Pattern: "public_key",
+				Properties: map[logical.Operation]logical.OperationProperties{
+					logical.ReadOperation: {Unauthenticated: true, ReadOnly: true},
+				},
+			},
+			&Path{
+				Pattern: "keys/" + GenericNameRegex("name"),
+				Properties: map[logical.Operation]logical.OperationProperties{
+					logical.CreateOperation: {ForwardToActive: true},
+					logical.ListOperation:   {LocalOnly: true},
+				},
+			},
+		},
+	}
+
+	cases := []struct {
+		path  string
+		op    logical.Operation
+		props logical.OperationProperties
+	}{
+		{"public_key", logical.ReadOperation, logical.OperationProperties{Unauthenticated: true, ReadOnly: true}},
+		{"public_key", logical.UpdateOperation, logical.OperationProperties{}},
+		{"keys/foo", logical.CreateOperation, logical.OperationProperties{ForwardToActive: true}},
+		{"keys/foo", logical.UpdateOperation, logical.OperationProperties{ForwardToActive: true}},
+		{"keys/foo", logical.ListOperation, logical.OperationProperties{LocalOnly: true}},
+		{"keys/foo", logical.ReadOperation, logical.OperationProperties{}},
+		{"missing", logical.ReadOperation, logical.OperationProperties{}},
+	}
+	for _, tc := range cases {
+		if props := b.OperationProperties(tc.path, tc.op); props != tc.props {
+			t.Fatalf("%s %s: expected %#v, got %#v", tc.op, tc.path, tc.props, props)
+		}
+	}
+}
+
 func TestBackendSecret(t *testing.T) {
 	cases := map[string]struct {
 		Secrets []*Secret
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/hashicorp/vault/helper/parseutil"
@@ -50,6 +51,110 @@ func (d *FieldData) Validate() error {
 	return nil
 }
 
+// validateConstraints checks the values set in the raw data against the
+// allowed values, pattern and bounds of their schema and, if required is
+// true, that the required fields are set. Empty strings are treated as
+// unset. The values must have been checked with Validate first. The patterns
+// are the compiled patterns of the schema, by pattern.
+func (d *FieldData) validateConstraints(required bool, patterns map[string]*regexp.Regexp) error {
+	fields := make([]string, 0, len(d.Schema))
+	for field := range d.Schema {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	for _, field := range fields {
+		schema := d.Schema[field]
+		value, ok, err := d.GetOkErr(field)
+		if err != nil {
+			return err
+		}
+		if !ok || value == "" {
+			if required && schema.Required {
+				return fmt.Errorf("missing %s", field)
+			}
+			continue
+		}
+
+		var values []interface{}
+		switch v := value.(type) {
+		case []string:
+			for _, s := range v {
+				values = append(values, s)
+			}
+		case []interface{}:
+			values = v
+		default:
+			values = []interface{}{v}
+		}
+		for _, v := range values {
+			if err := schema.validateValue(field, v, patterns[schema.Pattern]); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateValue checks a value of the field, or one of its elements for the
+// slice fields, against the constraints of the schema. The pattern is the
+// compiled pattern of the schema, nil if it is invalid.
+func (s *FieldSchema) validateValue(field string, value interface{}, pattern *regexp.Regexp) error {
+	if len(s.AllowedValues) > 0 {
+		allowed := false
+		for _, a := range s.AllowedValues {
+			if fmt.Sprint(a) == fmt.Sprint(value) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("invalid %s %s: must be %s", field, formatFieldValue(value), formatAllowedValues(s.AllowedValues))
+		}
+	}
+
+	if str, ok := value.(string); ok && s.Pattern != "" {
+		if pattern == nil {
+			return fmt.Errorf("invalid pattern for field %s", field)
+		}
+		if !pattern.MatchString(str) {
+			return fmt.Errorf("invalid %s %q: must match %q", field, str, s.Pattern)
+		}
+	}
+
+	if n, ok := value.(int); ok {
+		if s.Min != nil && n < *s.Min {
+			return fmt.Errorf("invalid %s %d: must be at least %d", field, n, *s.Min)
+		}
+		if s.Max != nil && n > *s.Max {
+			return fmt.Errorf("invalid %s %d: must be at most %d", field, n, *s.Max)
+		}
+	}
+
+	return nil
+}
+
+func formatFieldValue(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return fmt.Sprintf("%q", s)
+	}
+	return fmt.Sprint(value)
+}
+
+// formatAllowedValues formats the allowed values of a field for the error
+// messages, e.g. one of "a", "b" or "c"
+func formatAllowedValues(allowed []interface{}) string {
+	formatted := make([]string, len(allowed))
+	for i, a := range allowed {
+		formatted[i] = formatFieldValue(a)
+	}
+	if len(formatted) == 1 {
+		return formatted[0]
+	}
+	return "one of " + strings.Join(formatted[:len(formatted)-1], ", ") + " or " + formatted[len(formatted)-1]
+}
+
 // Get gets the value for the given field. If the key is an invalid field,
 // FieldData will panic. If you want a safer version of this method, use
 // GetOk. If the field k is not set, the default value (if set) will be
@@ -0,0 +1,80 @@
+package framework
+
+import (
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/logical"
+)
+
+// listPageFields are the fields paginating the list operations. The list
+// callbacks can get them to list a single page of the storage with
+// logical.ListPage; the keys of their response are paginated otherwise.
+var listPageFields = map[string]*FieldSchema{
+	"after": &FieldSchema{
+		Type:        TypeString,
+		Description: "Optional entry to list after, to list the entries after the page ending with it.",
+	},
+	"limit": &FieldSchema{
+		Type:        TypeInt,
+		Description: "Optional maximum number of entries to list. All the entries are listed if not set.",
+		Min:         new(int),
+	},
+}
+
+// listPageSchema returns the schema of the fields of a list operation, with
+// the fields paginating it unless the path has fields of the same name
+func listPageSchema(fields map[string]*FieldSchema) map[string]*FieldSchema {
+	schema := make(map[string]*FieldSchema, len(fields)+len(listPageFields))
+	for name, field := range listPageFields {
+		schema[name] = field
+	}
+	for name, field := range fields {
+		schema[name] = field
+	}
+	return schema
+}
+
+// paginateListResponse keeps the page of the keys of a list response that was
+// requested, in lexicographic order, along with their key information. The
+// keys of a response paginated by the callback are kept as they are.
+func paginateListResponse(resp *logical.Response, d *FieldData) *logical.Response {
+	if resp == nil || resp.IsError() || resp.Data == nil {
+		return resp
+	}
+	if d.Schema["after"] != listPageFields["after"] || d.Schema["limit"] != listPageFields["limit"] {
+		return resp
+	}
+	after := d.Get("after").(string)
+	limit := d.Get("limit").(int)
+	if after == "" && limit <= 0 {
+		return resp
+	}
+
+	var keys []string
+	switch v := resp.Data["keys"].(type) {
+	case []string:
+		keys = v
+	case []interface{}:
+		for _, key := range v {
+			s, ok := key.(string)
+			if !ok {
+				return resp
+			}
+			keys = append(keys, s)
+		}
+	default:
+		return resp
+	}
+
+	page := strutil.StrListPage(keys, after, limit)
+	resp.Data["keys"] = page
+	if keyInfo, ok := resp.Data["key_info"].(map[string]interface{}); ok {
+		pageInfo := make(map[string]interface{}, len(page))
+		for _, key := range page {
+			if info, ok := keyInfo[key]; ok {
+				pageInfo[key] = info
+			}
+		}
+		resp.Data["key_info"] = pageInfo
+	}
+	return resp
+}
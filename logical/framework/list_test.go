@@ -0,0 +1,96 @@
+package framework
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestBackendHandleRequest_listPage(t *testing.T) {
+	callback := func(ctx context.Context, req *logical.Request, data *FieldData) (*logical.Response, error) {
+		return logical.ListResponseWithInfo([]string{"c", "a", "d", "b"}, map[string]interface{}{
+			"a": 1,
+			"b": 2,
+			"c": 3,
+			"d": 4,
+		}), nil
+	}
+	paginated := func(ctx context.Context, req *logical.Request, data *FieldData) (*logical.Response, error) {
+		keys, err := logical.ListPage(ctx, req.Storage, "", data.Get("after").(string), data.Get("limit").(int))
+		if err != nil {
+			return nil, err
+		}
+		return logical.ListResponse(keys), nil
+	}
+
+	b := &Backend{
+		Paths: []*Path{
+			&Path{
+				Pattern: "keys/?$",
+				Callbacks: map[logical.Operation]OperationFunc{
+					logical.ListOperation: callback,
+				},
+			},
+			&Path{
+				Pattern: "storage/?$",
+				Callbacks: map[logical.Operation]OperationFunc{
+					logical.ListOperation: paginated,
+				},
+			},
+		},
+	}
+
+	storage := &logical.InmemStorage{}
+	for _, key := range []string{"a", "b/1", "b/2", "c", "d"} {
+		if err := storage.Put(context.Background(), &logical.StorageEntry{Key: key}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		path    string
+		data    map[string]interface{}
+		keys    []string
+		keyInfo map[string]interface{}
+	}{
+		{"keys", nil, []string{"c", "a", "d", "b"}, map[string]interface{}{"a": 1, "b": 2, "c": 3, "d": 4}},
+		{"keys", map[string]interface{}{"limit": 2}, []string{"a", "b"}, map[string]interface{}{"a": 1, "b": 2}},
+		{"keys", map[string]interface{}{"after": "b"}, []string{"c", "d"}, map[string]interface{}{"c": 3, "d": 4}},
+		{"keys", map[string]interface{}{"after": "a", "limit": "1"}, []string{"b"}, map[string]interface{}{"b": 2}},
+		{"keys", map[string]interface{}{"after": "d"}, []string{}, map[string]interface{}{}},
+		{"storage", nil, []string{"a", "b/", "c", "d"}, nil},
+		{"storage", map[string]interface{}{"after": "a", "limit": 2}, []string{"b/", "c"}, nil},
+		{"storage", map[string]interface{}{"after": "b/"}, []string{"c", "d"}, nil},
+	}
+	for _, tc := range cases {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.ListOperation,
+			Path:      tc.path,
+			Data:      tc.data,
+			Storage:   storage,
+		})
+		if err != nil {
+			t.Fatalf("%s %v: %v", tc.path, tc.data, err)
+		}
+		if keys := resp.Data["keys"]; !reflect.DeepEqual(keys, tc.keys) {
+			t.Fatalf("%s %v: expected keys %#v, got %#v", tc.path, tc.data, tc.keys, keys)
+		}
+		if tc.keyInfo != nil && !reflect.DeepEqual(resp.Data["key_info"], tc.keyInfo) {
+			t.Fatalf("%s %v: expected key info %#v, got %#v", tc.path, tc.data, tc.keyInfo, resp.Data["key_info"])
+		}
+	}
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ListOperation,
+		Path:      "keys",
+		Data:      map[string]interface{}{"limit": -1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.IsError() {
+		t.Fatalf("expected an error response, got %#v", resp)
+	}
+}
@@ -0,0 +1,452 @@
+package framework
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp/syntax"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/version"
+)
+
+// OpenAPIVersion is the version of the OpenAPI specification the documents
+// of the backends follow
+const OpenAPIVersion = "3.0.2"
+
+// maxPatternExpansions limits the number of paths generated from a single
+// pattern, as each optional part of a pattern doubles them
+const maxPatternExpansions = 128
+
+// OASDocument is an OpenAPI document describing the paths of one or more
+// backends
+type OASDocument struct {
+	Version string                  `json:"openapi"`
+	Info    OASInfo                 `json:"info"`
+	Paths   map[string]*OASPathItem `json:"paths"`
+}
+
+type OASInfo struct {
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Version     string     `json:"version"`
+	License     OASLicense `json:"license"`
+}
+
+type OASLicense struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// OASPathItem describes the operations of a path. The x-vault extensions
+// tell whether the path requires a root token, or none.
+type OASPathItem struct {
+	Description     string         `json:"description,omitempty"`
+	Parameters      []OASParameter `json:"parameters,omitempty"`
+	Sudo            bool           `json:"x-vault-sudo,omitempty"`
+	Unauthenticated bool           `json:"x-vault-unauthenticated,omitempty"`
+
+	Get    *OASOperation `json:"get,omitempty"`
+	Post   *OASOperation `json:"post,omitempty"`
+	Delete *OASOperation `json:"delete,omitempty"`
+}
+
+type OASOperation struct {
+	Summary     string               `json:"summary,omitempty"`
+	Description string               `json:"description,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	Parameters  []OASParameter       `json:"parameters,omitempty"`
+	RequestBody *OASRequestBody      `json:"requestBody,omitempty"`
+	Responses   map[int]*OASResponse `json:"responses"`
+}
+
+type OASParameter struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	In          string     `json:"in"`
+	Schema      *OASSchema `json:"schema,omitempty"`
+	Required    bool       `json:"required,omitempty"`
+}
+
+type OASRequestBody struct {
+	Description string                         `json:"description,omitempty"`
+	Content     map[string]*OASMediaTypeObject `json:"content"`
+}
+
+type OASMediaTypeObject struct {
+	Schema *OASSchema `json:"schema,omitempty"`
+}
+
+type OASSchema struct {
+	Type        string                `json:"type,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Properties  map[string]*OASSchema `json:"properties,omitempty"`
+	Items       *OASSchema            `json:"items,omitempty"`
+	Format      string                `json:"format,omitempty"`
+	Pattern     string                `json:"pattern,omitempty"`
+	Enum        []interface{}         `json:"enum,omitempty"`
+	Minimum     *int                  `json:"minimum,omitempty"`
+	Maximum     *int                  `json:"maximum,omitempty"`
+	Required    []string              `json:"required,omitempty"`
+	Default     interface{}           `json:"default,omitempty"`
+}
+
+type OASResponse struct {
+	Description string `json:"description"`
+}
+
+// NewOASDocument returns an empty OpenAPI document
+func NewOASDocument() *OASDocument {
+	return &OASDocument{
+		Version: OpenAPIVersion,
+		Info: OASInfo{
+			Title:       "HashiCorp Vault API",
+			Description: "HTTP API that gives you full access to Vault. All API routes are prefixed with `/v1/`.",
+			Version:     version.GetVersion().Version,
+			License: OASLicense{
+				Name: "Mozilla Public License 2.0",
+				URL:  "https://www.mozilla.org/en-US/MPL/2.0",
+			},
+		},
+		Paths: make(map[string]*OASPathItem),
+	}
+}
+
+// NewOASDocumentFromResponse returns the OpenAPI document of the root help
+// response of a backend, which is a map instead of an OASDocument when it
+// comes from an external plugin. It returns nil if the response has no
+// document.
+func NewOASDocumentFromResponse(resp *logical.Response) (*OASDocument, error) {
+	if resp == nil || resp.Data == nil {
+		return nil, nil
+	}
+	switch v := resp.Data["openapi"].(type) {
+	case nil:
+		return nil, nil
+	case *OASDocument:
+		return v, nil
+	default:
+		buf, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		doc := new(OASDocument)
+		if err := json.Unmarshal(buf, doc); err != nil {
+			return nil, fmt.Errorf("invalid OpenAPI document: %v", err)
+		}
+		return doc, nil
+	}
+}
+
+// documentPaths adds the paths of the backend to the document. The paths
+// that cannot be expressed as OpenAPI paths, because their pattern matches
+// arbitrary text outside of a named capture, are left out.
+func documentPaths(b *Backend, doc *OASDocument) {
+	b.once.Do(b.init)
+
+	for _, p := range b.Paths {
+		paths, err := expandPattern(p.Pattern)
+		if err != nil {
+			continue
+		}
+		for _, path := range paths {
+			doc.Paths["/"+path] = documentPath(b, p, path)
+		}
+	}
+}
+
+// documentPath returns the description of one of the paths expanded from the
+// pattern of the Path
+func documentPath(b *Backend, p *Path, path string) *OASPathItem {
+	item := &OASPathItem{
+		Description: strings.TrimSpace(p.HelpSynopsis),
+	}
+	if b.PathsSpecial != nil {
+		item.Sudo = specialPathMatch(path, b.PathsSpecial.Root)
+		item.Unauthenticated = specialPathMatch(path, b.PathsSpecial.Unauthenticated)
+	}
+	for _, props := range p.Properties {
+		item.Unauthenticated = item.Unauthenticated || props.Unauthenticated
+	}
+
+	// The captures of the path are path parameters, the other fields are
+	// sent in the body of the writes
+	captures := make(map[string]bool)
+	for _, name := range pathParameters(path) {
+		captures[name] = true
+		param := OASParameter{
+			Name:     name,
+			In:       "path",
+			Required: true,
+			Schema:   &OASSchema{Type: "string"},
+		}
+		if schema, ok := p.Fields[name]; ok {
+			param.Description = strings.TrimSpace(schema.Description)
+			param.Schema = convertSchema(schema)
+		}
+		item.Parameters = append(item.Parameters, param)
+	}
+
+	var tags []string
+	switch b.BackendType {
+	case logical.TypeLogical:
+		tags = []string{"secrets"}
+	case logical.TypeCredential:
+		tags = []string{"auth"}
+	}
+
+	newOperation := func() *OASOperation {
+		return &OASOperation{
+			Summary:     strings.TrimSpace(p.HelpSynopsis),
+			Description: strings.TrimSpace(p.HelpDescription),
+			Tags:        tags,
+			Responses: map[int]*OASResponse{
+				200: {Description: "OK"},
+			},
+		}
+	}
+
+	_, hasRead := p.Callbacks[logical.ReadOperation]
+	_, hasList := p.Callbacks[logical.ListOperation]
+	if hasRead || hasList {
+		item.Get = newOperation()
+		if hasList {
+			item.Get.Parameters = append(item.Get.Parameters, OASParameter{
+				Name:        "list",
+				Description: "Return a list if `true`",
+				In:          "query",
+				Schema:      &OASSchema{Type: "string"},
+				Required:    !hasRead,
+			})
+			for _, name := range []string{"after", "limit"} {
+				if _, ok := p.Fields[name]; ok {
+					continue
+				}
+				item.Get.Parameters = append(item.Get.Parameters, OASParameter{
+					Name:        name,
+					Description: listPageFields[name].Description,
+					In:          "query",
+					Schema:      convertSchema(listPageFields[name]),
+				})
+			}
+		}
+	}
+
+	_, hasCreate := p.Callbacks[logical.CreateOperation]
+	_, hasUpdate := p.Callbacks[logical.UpdateOperation]
+	if hasCreate || hasUpdate {
+		item.Post = newOperation()
+
+		properties := make(map[string]*OASSchema)
+		var required []string
+		for name, schema := range p.Fields {
+			if captures[name] {
+				continue
+			}
+			s := convertSchema(schema)
+			s.Description = strings.TrimSpace(schema.Description)
+			s.Default = schema.Default
+			properties[name] = s
+			if schema.Required {
+				required = append(required, name)
+			}
+		}
+		sort.Strings(required)
+		if len(properties) > 0 {
+			item.Post.RequestBody = &OASRequestBody{
+				Content: map[string]*OASMediaTypeObject{
+					"application/json": {
+						Schema: &OASSchema{
+							Type:       "object",
+							Properties: properties,
+							Required:   required,
+						},
+					},
+				},
+			}
+		}
+	}
+
+	if _, ok := p.Callbacks[logical.DeleteOperation]; ok {
+		item.Delete = newOperation()
+		item.Delete.Responses = map[int]*OASResponse{
+			204: {Description: "empty body"},
+		}
+	}
+
+	return item
+}
+
+// convertSchema returns the schema of the values of a field, with their
+// constraints
+func convertSchema(schema *FieldSchema) *OASSchema {
+	s := convertType(schema.Type)
+
+	// The constraints of the slice fields apply to their elements
+	target := s
+	if s.Items != nil {
+		target = s.Items
+	}
+	if len(schema.AllowedValues) > 0 {
+		target.Enum = schema.AllowedValues
+	}
+	if schema.Pattern != "" {
+		target.Pattern = "^(?:" + schema.Pattern + ")$"
+	}
+	target.Minimum = schema.Min
+	target.Maximum = schema.Max
+
+	return s
+}
+
+// convertType returns the schema of the values of a field type
+func convertType(t FieldType) *OASSchema {
+	switch t {
+	case TypeString:
+		return &OASSchema{Type: "string"}
+	case TypeNameString:
+		return &OASSchema{Type: "string", Pattern: `^\w(([\w-.]+)?\w)?$`}
+	case TypeInt:
+		return &OASSchema{Type: "integer"}
+	case TypeBool:
+		return &OASSchema{Type: "boolean"}
+	case TypeMap, TypeKVPairs:
+		return &OASSchema{Type: "object"}
+	case TypeDurationSecond:
+		return &OASSchema{Type: "integer", Format: "seconds"}
+	case TypeSlice:
+		return &OASSchema{Type: "array", Items: &OASSchema{Type: "object"}}
+	case TypeStringSlice, TypeCommaStringSlice:
+		return &OASSchema{Type: "array", Items: &OASSchema{Type: "string"}}
+	default:
+		return &OASSchema{Type: "string"}
+	}
+}
+
+// pathParameters returns the names of the parameters of an expanded path
+func pathParameters(path string) []string {
+	var names []string
+	for {
+		start := strings.Index(path, "{")
+		if start == -1 {
+			return names
+		}
+		end := strings.Index(path[start:], "}")
+		if end == -1 {
+			return names
+		}
+		names = append(names, path[start+1:start+end])
+		path = path[start+end+1:]
+	}
+}
+
+// specialPathMatch checks if the path matches one of the special paths of a
+// backend, which are exact matches or, with a '*' suffix, prefix matches
+func specialPathMatch(path string, specialPaths []string) bool {
+	for _, sp := range specialPaths {
+		if strings.HasSuffix(sp, "*") {
+			if strings.HasPrefix(path, strings.TrimSuffix(sp, "*")) {
+				return true
+			}
+		} else if path == sp {
+			return true
+		}
+	}
+	return false
+}
+
+// expandPattern returns the OpenAPI paths matched by the pattern of a Path.
+// The named captures of the pattern become path parameters, and a path is
+// generated for each combination of its alternations and optional parts. The
+// paths which only differ by a trailing slash are merged into the path
+// without it.
+func expandPattern(pattern string) ([]string, error) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return nil, err
+	}
+	expanded, err := expandRegexp(re)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var paths []string
+	for _, path := range expanded {
+		path = strings.TrimSuffix(path, "/")
+		if path == "" || seen[path] {
+			continue
+		}
+		seen[path] = true
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+func expandRegexp(re *syntax.Regexp) ([]string, error) {
+	switch re.Op {
+	case syntax.OpLiteral:
+		return []string{string(re.Rune)}, nil
+
+	case syntax.OpEmptyMatch, syntax.OpBeginLine, syntax.OpEndLine,
+		syntax.OpBeginText, syntax.OpEndText:
+		return []string{""}, nil
+
+	case syntax.OpCapture:
+		if re.Name != "" {
+			return []string{"{" + re.Name + "}"}, nil
+		}
+		return expandRegexp(re.Sub[0])
+
+	case syntax.OpQuest:
+		sub, err := expandRegexp(re.Sub[0])
+		if err != nil {
+			return nil, err
+		}
+		return append([]string{""}, sub...), nil
+
+	case syntax.OpAlternate:
+		var result []string
+		for _, sub := range re.Sub {
+			paths, err := expandRegexp(sub)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, paths...)
+		}
+		if len(result) > maxPatternExpansions {
+			return nil, fmt.Errorf("pattern expands to too many paths")
+		}
+		return result, nil
+
+	case syntax.OpConcat:
+		result := []string{""}
+		for _, sub := range re.Sub {
+			paths, err := expandRegexp(sub)
+			if err != nil {
+				return nil, err
+			}
+			next := make([]string, 0, len(result)*len(paths))
+			for _, prefix := range result {
+				for _, path := range paths {
+					next = append(next, prefix+path)
+				}
+			}
+			if len(next) > maxPatternExpansions {
+				return nil, fmt.Errorf("pattern expands to too many paths")
+			}
+			result = next
+		}
+		return result, nil
+
+	case syntax.OpCharClass:
+		// A class of a single character, e.g. an escaped character
+		if len(re.Rune) == 2 && re.Rune[0] == re.Rune[1] {
+			return []string{string(re.Rune[0])}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unsupported pattern %q", re.String())
+}
@@ -0,0 +1,184 @@
+package framework
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestOpenAPI_ExpandPattern(t *testing.T) {
+	cases := []struct {
+		pattern string
+		paths   []string
+	}{
+		{"^rotate$", []string{"rotate"}},
+		{"roles/?$", []string{"roles"}},
+		{"roles/" + GenericNameRegex("name"), []string{"roles/{name}"}},
+		{"creds/(?P<name>.+)", []string{"creds/{name}"}},
+		{"config/(lease|connection)$", []string{"config/connection", "config/lease"}},
+		{"keys" + OptionalParamRegex("key"), []string{"keys", "keys/{key}"}},
+		{`tools/hash(/(?P<format>.+))?`, []string{"tools/hash", "tools/hash/{format}"}},
+		{`foo\.bar$`, []string{"foo.bar"}},
+	}
+	for _, tc := range cases {
+		paths, err := expandPattern(tc.pattern)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.pattern, err)
+		}
+		if !reflect.DeepEqual(paths, tc.paths) {
+			t.Fatalf("%s: expected %v, got %v", tc.pattern, tc.paths, paths)
+		}
+	}
+
+	for _, pattern := range []string{"raw/.*", "foo/[a-z]+"} {
+		if _, err := expandPattern(pattern); err == nil {
+			t.Fatalf("%s: expected an error", pattern)
+		}
+	}
+}
+
+func TestOpenAPI_Paths(t *testing.T) {
+	callback := func(context.Context, *logical.Request, *FieldData) (*logical.Response, error) {
+		return nil, nil
+	}
+
+	b := &Backend{
+		BackendType: logical.TypeLogical,
+		PathsSpecial: &logical.Paths{
+			Root:            []string{"roles/*"},
+			Unauthenticated: []string{"login"},
+		},
+		Paths: []*Path{
+			&Path{
+				Pattern: "roles/?$",
+				Callbacks: map[logical.Operation]OperationFunc{
+					logical.ListOperation: callback,
+				},
+				HelpSynopsis: "List the roles.",
+			},
+			&Path{
+				Pattern: "roles/" + GenericNameRegex("name"),
+				Fields: map[string]*FieldSchema{
+					"name": &FieldSchema{
+						Type:        TypeString,
+						Description: "Name of the role.",
+					},
+					"ttl": &FieldSchema{
+						Type:        TypeDurationSecond,
+						Description: "TTL of the role.",
+						Default:     60,
+					},
+					"policies": &FieldSchema{
+						Type:          TypeCommaStringSlice,
+						AllowedValues: []interface{}{"default", "admin"},
+					},
+					"type": &FieldSchema{
+						Type:     TypeString,
+						Required: true,
+						Pattern:  "[a-z]+",
+					},
+				},
+				Callbacks: map[logical.Operation]OperationFunc{
+					logical.ReadOperation:   callback,
+					logical.UpdateOperation: callback,
+					logical.DeleteOperation: callback,
+				},
+				HelpSynopsis:    "Manage the roles.",
+				HelpDescription: "Creates, reads and deletes the roles.",
+			},
+			&Path{
+				Pattern: "login",
+				Callbacks: map[logical.Operation]OperationFunc{
+					logical.UpdateOperation: callback,
+				},
+			},
+			&Path{
+				Pattern: "raw/.*",
+				Callbacks: map[logical.Operation]OperationFunc{
+					logical.ReadOperation: callback,
+				},
+			},
+		},
+	}
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.HelpOperation,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := NewOASDocumentFromResponse(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Version != OpenAPIVersion {
+		t.Fatalf("bad version: %q", doc.Version)
+	}
+	if len(doc.Paths) != 3 {
+		t.Fatalf("expected 3 paths, got %#v", doc.Paths)
+	}
+
+	list := doc.Paths["/roles"]
+	if list == nil || list.Get == nil || list.Post != nil || list.Sudo {
+		t.Fatalf("bad: %#v", list)
+	}
+	if len(list.Get.Parameters) != 3 || list.Get.Parameters[0].Name != "list" || !list.Get.Parameters[0].Required || list.Get.Parameters[2].Name != "limit" {
+		t.Fatalf("bad list parameters: %#v", list.Get.Parameters)
+	}
+
+	role := doc.Paths["/roles/{name}"]
+	if role == nil || role.Get == nil || role.Post == nil || role.Delete == nil || !role.Sudo {
+		t.Fatalf("bad: %#v", role)
+	}
+	if len(role.Parameters) != 1 || role.Parameters[0].Name != "name" || role.Parameters[0].In != "path" {
+		t.Fatalf("bad path parameters: %#v", role.Parameters)
+	}
+	if len(role.Get.Parameters) != 0 {
+		t.Fatalf("bad read parameters: %#v", role.Get.Parameters)
+	}
+	if !reflect.DeepEqual(role.Post.Tags, []string{"secrets"}) || role.Post.Description != "Creates, reads and deletes the roles." {
+		t.Fatalf("bad: %#v", role.Post)
+	}
+	body := role.Post.RequestBody.Content["application/json"].Schema
+	if !reflect.DeepEqual(body.Required, []string{"type"}) {
+		t.Fatalf("bad required fields: %#v", body.Required)
+	}
+	props := body.Properties
+	if len(props) != 3 {
+		t.Fatalf("bad properties: %#v", props)
+	}
+	if typ := props["type"]; typ.Pattern != "^(?:[a-z]+)$" {
+		t.Fatalf("bad type: %#v", typ)
+	}
+	if ttl := props["ttl"]; ttl.Type != "integer" || ttl.Format != "seconds" || ttl.Default != 60 {
+		t.Fatalf("bad ttl: %#v", ttl)
+	}
+	if policies := props["policies"]; policies.Type != "array" || policies.Items.Type != "string" || len(policies.Items.Enum) != 2 {
+		t.Fatalf("bad policies: %#v", policies)
+	}
+
+	login := doc.Paths["/login"]
+	if login == nil || !login.Unauthenticated || login.Post.RequestBody != nil {
+		t.Fatalf("bad: %#v", login)
+	}
+
+	// The documents of the external plugins are decoded from JSON
+	buf, err := json.Marshal(resp.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal(buf, &data); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := NewOASDocumentFromResponse(&logical.Response{Data: data})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Paths) != 3 || decoded.Paths["/roles/{name}"].Delete == nil {
+		t.Fatalf("bad: %#v", decoded)
+	}
+}
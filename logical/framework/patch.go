@@ -0,0 +1,29 @@
+package framework
+
+// MergePatch applies the JSON merge patch (RFC 7386) to the resource and
+// returns the result, leaving the resource unmodified. Keys of the patch with
+// nil values are removed from the resource, objects are merged recursively
+// and any other values replace those of the resource.
+func MergePatch(resource, patch map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(resource))
+	for k, v := range resource {
+		result[k] = v
+	}
+
+	for k, v := range patch {
+		if v == nil {
+			delete(result, k)
+			continue
+		}
+
+		patchMap, ok := v.(map[string]interface{})
+		if !ok {
+			result[k] = v
+			continue
+		}
+		target, _ := result[k].(map[string]interface{})
+		result[k] = MergePatch(target, patchMap)
+	}
+
+	return result
+}
@@ -0,0 +1,90 @@
+package framework
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergePatch(t *testing.T) {
+	cases := map[string]struct {
+		Resource map[string]interface{}
+		Patch    map[string]interface{}
+		Result   map[string]interface{}
+	}{
+		"add": {
+			map[string]interface{}{"a": "b"},
+			map[string]interface{}{"c": "d"},
+			map[string]interface{}{"a": "b", "c": "d"},
+		},
+
+		"replace": {
+			map[string]interface{}{"a": "b"},
+			map[string]interface{}{"a": "c"},
+			map[string]interface{}{"a": "c"},
+		},
+
+		"remove": {
+			map[string]interface{}{"a": "b", "c": "d"},
+			map[string]interface{}{"a": nil},
+			map[string]interface{}{"c": "d"},
+		},
+
+		"remove missing": {
+			map[string]interface{}{"a": "b"},
+			map[string]interface{}{"c": nil},
+			map[string]interface{}{"a": "b"},
+		},
+
+		"nested": {
+			map[string]interface{}{
+				"a": map[string]interface{}{"b": "c", "d": "e"},
+			},
+			map[string]interface{}{
+				"a": map[string]interface{}{"b": "f", "d": nil},
+			},
+			map[string]interface{}{
+				"a": map[string]interface{}{"b": "f"},
+			},
+		},
+
+		"replace with object": {
+			map[string]interface{}{"a": "b"},
+			map[string]interface{}{
+				"a": map[string]interface{}{"c": "d", "e": nil},
+			},
+			map[string]interface{}{
+				"a": map[string]interface{}{"c": "d"},
+			},
+		},
+
+		"replace array": {
+			map[string]interface{}{"a": []interface{}{"b", "c"}},
+			map[string]interface{}{"a": []interface{}{"d"}},
+			map[string]interface{}{"a": []interface{}{"d"}},
+		},
+
+		"nil resource": {
+			nil,
+			map[string]interface{}{"a": "b"},
+			map[string]interface{}{"a": "b"},
+		},
+	}
+
+	for name, tc := range cases {
+		var original map[string]interface{}
+		if tc.Resource != nil {
+			original = map[string]interface{}{}
+			for k, v := range tc.Resource {
+				original[k] = v
+			}
+		}
+
+		result := MergePatch(tc.Resource, tc.Patch)
+		if !reflect.DeepEqual(result, tc.Result) {
+			t.Fatalf("%s: bad: %#v", name, result)
+		}
+		if !reflect.DeepEqual(tc.Resource, original) {
+			t.Fatalf("%s: resource modified: %#v", name, tc.Resource)
+		}
+	}
+}
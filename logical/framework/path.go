@@ -60,6 +60,13 @@ type Path struct {
 	// callback will be called.
 	Callbacks map[logical.Operation]OperationFunc
 
+	// Properties are the properties of the operations of the path that are
+	// honored by the router of the core, such as the operations that can be
+	// requested without auth or served by performance standbys. The
+	// properties of the create operation also apply to the update operation
+	// if it has none.
+	Properties map[logical.Operation]logical.OperationProperties
+
 	// ExistenceCheck, if implemented, is used to query whether a given
 	// resource exists or not. This is used for ACL purposes: if an Update
 	// action is specified, and the existence check returns false, the action
@@ -14,3 +14,20 @@ type Alias struct {
 	// Name is the identifier of this identity in its authentication source
 	Name string `json:"name" structs:"name" mapstructure:"name"`
 }
+
+// Entity represents the information about the entity of a client that is
+// exposed to backends, e.g. to template values with.
+type Entity struct {
+	// ID is the unique identifier of the entity
+	ID string `json:"id" structs:"id" mapstructure:"id"`
+
+	// Name is the human-friendly unique identifier of the entity
+	Name string `json:"name" structs:"name" mapstructure:"name"`
+
+	// Aliases are the identities in authentication sources the entity is
+	// made of
+	Aliases []*Alias `json:"aliases" structs:"aliases" mapstructure:"aliases"`
+
+	// Metadata is the explicit metadata set on the entity
+	Metadata map[string]string `json:"metadata" structs:"metadata" mapstructure:"metadata"`
+}
@@ -120,3 +120,34 @@ type Paths struct {
 	// unless it ends with '/' in which case it will be treated as a prefix.
 	SealWrapStorage []string
 }
+
+// OperationProperties are the properties of an operation on a path that are
+// honored by the router of the core.
+type OperationProperties struct {
+	// Unauthenticated operations can be requested without any auth, like the
+	// Unauthenticated special paths.
+	Unauthenticated bool
+
+	// ForwardToActive operations are always forwarded to the active node by
+	// the performance standbys.
+	ForwardToActive bool
+
+	// ReadOnly operations only read from storage, and are served by the
+	// performance standbys from their read-only view of the storage. They are
+	// forwarded to the active node if they try to write to storage.
+	ReadOnly bool
+
+	// LocalOnly operations are served by the node receiving them, including
+	// the performance standbys, and are never forwarded to the active node.
+	LocalOnly bool
+}
+
+// OperationPropertiesBackend is an optional interface that a Backend can
+// implement to declare the properties of its operations. The operations of
+// the other backends have no properties: on performance standbys, they are
+// forwarded to the active node.
+type OperationPropertiesBackend interface {
+	// OperationProperties returns the properties of the operation on the
+	// path, relative to the mount point of the backend.
+	OperationProperties(path string, op Operation) OperationProperties
+}
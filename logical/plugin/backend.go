@@ -8,6 +8,7 @@ import (
 
 	hclog "github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/go-plugin"
+	uuid "github.com/hashicorp/go-uuid"
 	"github.com/hashicorp/vault/helper/logbridge"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/plugin/pb"
@@ -18,6 +19,10 @@ type BackendPlugin struct {
 	Factory      logical.Factory
 	metadataMode bool
 	Logger       hclog.Logger
+
+	// Multiplexing makes the gRPC server serve a backend for each mount
+	// using the plugin, instead of a single one
+	Multiplexing bool
 }
 
 // Server gets called when on plugin.Serve()
@@ -32,8 +37,9 @@ func (b BackendPlugin) Client(broker *plugin.MuxBroker, c *rpc.Client) (interfac
 
 func (b BackendPlugin) GRPCServer(broker *plugin.GRPCBroker, s *grpc.Server) error {
 	pb.RegisterBackendServer(s, &backendGRPCPluginServer{
-		broker:  broker,
-		factory: b.Factory,
+		broker:       broker,
+		factory:      b.Factory,
+		multiplexing: b.Multiplexing,
 		// We pass the logger down into the backend so go-plugin will forward
 		// logs for us.
 		logger: logbridge.NewLogger(b.Logger).LogxiLogger(),
@@ -42,10 +48,18 @@ func (b BackendPlugin) GRPCServer(broker *plugin.GRPCBroker, s *grpc.Server) err
 }
 
 func (p *BackendPlugin) GRPCClient(ctx context.Context, broker *plugin.GRPCBroker, c *grpc.ClientConn) (interface{}, error) {
+	// Each backend dispensed from the plugin is a mount of its own, which
+	// plugins serving several mounts tell apart by this ID
+	multiplexID, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+
 	return &backendGRPCPluginClient{
-		client:     pb.NewBackendClient(c),
-		clientConn: c,
-		broker:     broker,
-		doneCtx:    ctx,
+		client:      pb.NewBackendClient(c),
+		clientConn:  c,
+		broker:      broker,
+		multiplexID: multiplexID,
+		doneCtx:     ctx,
 	}, nil
 }
@@ -42,6 +42,17 @@ type SpecialPathsReply struct {
 	Paths *logical.Paths
 }
 
+// OperationPropertiesArgs is the args for OperationProperties method.
+type OperationPropertiesArgs struct {
+	Path      string
+	Operation logical.Operation
+}
+
+// OperationPropertiesReply is the reply for OperationProperties method.
+type OperationPropertiesReply struct {
+	Properties logical.OperationProperties
+}
+
 // SystemReply is the reply for System method.
 type SystemReply struct {
 	SystemView logical.SystemView
@@ -125,6 +136,22 @@ func (b *backendPluginClient) SpecialPaths() *logical.Paths {
 	return reply.Paths
 }
 
+// OperationProperties returns the properties declared by the plugin. Plugins
+// built before operation properties were supported declare none.
+func (b *backendPluginClient) OperationProperties(path string, op logical.Operation) logical.OperationProperties {
+	args := &OperationPropertiesArgs{
+		Path:      path,
+		Operation: op,
+	}
+	var reply OperationPropertiesReply
+	err := b.client.Call("Plugin.OperationProperties", args, &reply)
+	if err != nil {
+		return logical.OperationProperties{}
+	}
+
+	return reply.Properties
+}
+
 // System returns vault's system view. The backend client stores the view during
 // Setup, so there is no need to shim the system just to get it back.
 func (b *backendPluginClient) System() logical.SystemView {
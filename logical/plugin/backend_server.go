@@ -55,6 +55,19 @@ func (b *backendPluginServer) SpecialPaths(_ interface{}, reply *SpecialPathsRep
 	return nil
 }
 
+func (b *backendPluginServer) OperationProperties(args *OperationPropertiesArgs, reply *OperationPropertiesReply) error {
+	backend, ok := b.backend.(logical.OperationPropertiesBackend)
+	if !ok {
+		*reply = OperationPropertiesReply{}
+		return nil
+	}
+
+	*reply = OperationPropertiesReply{
+		Properties: backend.OperationProperties(args.Path, args.Operation),
+	}
+	return nil
+}
+
 func (b *backendPluginServer) HandleExistenceCheck(args *HandleExistenceCheckArgs, reply *HandleExistenceCheckReply) error {
 	if inMetadataMode() {
 		return ErrServerInMetadataMode
@@ -46,6 +46,24 @@ func TestBackendPlugin_SpecialPaths(t *testing.T) {
 	}
 }
 
+func TestBackendPlugin_OperationProperties(t *testing.T) {
+	b, cleanup := testBackend(t)
+	defer cleanup()
+
+	propsBackend, ok := b.(logical.OperationPropertiesBackend)
+	if !ok {
+		t.Fatal("the backend does not implement OperationProperties")
+	}
+	props := propsBackend.OperationProperties("special", logical.ReadOperation)
+	if props != (logical.OperationProperties{Unauthenticated: true, ReadOnly: true}) {
+		t.Fatalf("bad: %#v", props)
+	}
+	props = propsBackend.OperationProperties("kv/foo", logical.ReadOperation)
+	if props != (logical.OperationProperties{}) {
+		t.Fatalf("bad: %#v", props)
+	}
+}
+
 func TestBackendPlugin_System(t *testing.T) {
 	b, cleanup := testBackend(t)
 	defer cleanup()
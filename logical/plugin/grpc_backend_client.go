@@ -5,6 +5,7 @@ import (
 	"errors"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 
 	"github.com/hashicorp/go-plugin"
 	"github.com/hashicorp/vault/helper/pluginutil"
@@ -17,6 +18,7 @@ var ErrPluginShutdown = errors.New("plugin is shut down")
 
 // Validate backendGRPCPluginClient satisfies the logical.Backend interface
 var _ logical.Backend = &backendGRPCPluginClient{}
+var _ logical.OperationPropertiesBackend = &backendGRPCPluginClient{}
 
 // backendPluginClient implements logical.Backend and is the
 // go-plugin client.
@@ -28,6 +30,12 @@ type backendGRPCPluginClient struct {
 	system logical.SystemView
 	logger log.Logger
 
+	// multiplexID identifies the mount of the backend to plugins serving
+	// several mounts from one process, which is the case if multiplexed is
+	// set by Setup
+	multiplexID string
+	multiplexed bool
+
 	// server is the grpc server used for serving storage and sysview requests.
 	server *grpc.Server
 	// clientConn is the underlying grpc connection to the server, we store it
@@ -51,7 +59,7 @@ func (b *backendGRPCPluginClient) HandleRequest(ctx context.Context, req *logica
 		return nil, err
 	}
 
-	reply, err := b.client.HandleRequest(ctx, &pb.HandleRequestArgs{
+	reply, err := b.client.HandleRequest(b.withMultiplexID(ctx), &pb.HandleRequestArgs{
 		Request: protoReq,
 	}, largeMsgGRPCCallOpts...)
 	if err != nil {
@@ -73,7 +81,7 @@ func (b *backendGRPCPluginClient) HandleRequest(ctx context.Context, req *logica
 }
 
 func (b *backendGRPCPluginClient) SpecialPaths() *logical.Paths {
-	reply, err := b.client.SpecialPaths(b.doneCtx, &pb.Empty{})
+	reply, err := b.client.SpecialPaths(b.withMultiplexID(b.doneCtx), &pb.Empty{})
 	if err != nil {
 		return nil
 	}
@@ -90,6 +98,25 @@ func (b *backendGRPCPluginClient) SpecialPaths() *logical.Paths {
 	}
 }
 
+// OperationProperties returns the properties declared by the plugin. Plugins
+// built before operation properties were supported declare none.
+func (b *backendGRPCPluginClient) OperationProperties(path string, op logical.Operation) logical.OperationProperties {
+	reply, err := b.client.OperationProperties(b.withMultiplexID(b.doneCtx), &pb.OperationPropertiesArgs{
+		Path:      path,
+		Operation: string(op),
+	})
+	if err != nil {
+		return logical.OperationProperties{}
+	}
+
+	return logical.OperationProperties{
+		Unauthenticated: reply.Unauthenticated,
+		ForwardToActive: reply.ForwardToActive,
+		ReadOnly:        reply.ReadOnly,
+		LocalOnly:       reply.LocalOnly,
+	}
+}
+
 // System returns vault's system view. The backend client stores the view during
 // Setup, so there is no need to shim the system just to get it back.
 func (b *backendGRPCPluginClient) System() logical.SystemView {
@@ -116,7 +143,7 @@ func (b *backendGRPCPluginClient) HandleExistenceCheck(ctx context.Context, req
 	quitCh := pluginutil.CtxCancelIfCanceled(cancel, b.doneCtx)
 	defer close(quitCh)
 	defer cancel()
-	reply, err := b.client.HandleExistenceCheck(ctx, &pb.HandleExistenceCheckArgs{
+	reply, err := b.client.HandleExistenceCheck(b.withMultiplexID(ctx), &pb.HandleExistenceCheckArgs{
 		Request: protoReq,
 	}, largeMsgGRPCCallOpts...)
 	if err != nil {
@@ -138,11 +165,16 @@ func (b *backendGRPCPluginClient) Cleanup(ctx context.Context) {
 	defer close(quitCh)
 	defer cancel()
 
-	b.client.Cleanup(ctx, &pb.Empty{})
+	b.client.Cleanup(b.withMultiplexID(ctx), &pb.Empty{})
 	if b.server != nil {
 		b.server.GracefulStop()
 	}
-	b.clientConn.Close()
+
+	// The connection of a multiplexed plugin is shared with the other mounts
+	// and closed with the plugin process
+	if !b.multiplexed {
+		b.clientConn.Close()
+	}
 }
 
 func (b *backendGRPCPluginClient) InvalidateKey(ctx context.Context, key string) {
@@ -155,7 +187,7 @@ func (b *backendGRPCPluginClient) InvalidateKey(ctx context.Context, key string)
 	defer close(quitCh)
 	defer cancel()
 
-	b.client.InvalidateKey(ctx, &pb.InvalidateKeyArgs{
+	b.client.InvalidateKey(b.withMultiplexID(ctx), &pb.InvalidateKeyArgs{
 		Key: key,
 	})
 }
@@ -200,13 +232,15 @@ func (b *backendGRPCPluginClient) Setup(ctx context.Context, config *logical.Bac
 	defer close(quitCh)
 	defer cancel()
 
-	reply, err := b.client.Setup(ctx, args)
+	var header metadata.MD
+	reply, err := b.client.Setup(b.withMultiplexID(ctx), args, grpc.Header(&header))
 	if err != nil {
 		return err
 	}
 	if reply.Err != "" {
 		return errors.New(reply.Err)
 	}
+	b.multiplexed = len(header[multiplexingHeaderKey]) > 0
 
 	// Set system and logger for getter methods
 	b.system = config.System
@@ -216,10 +250,17 @@ func (b *backendGRPCPluginClient) Setup(ctx context.Context, config *logical.Bac
 }
 
 func (b *backendGRPCPluginClient) Type() logical.BackendType {
-	reply, err := b.client.Type(b.doneCtx, &pb.Empty{})
+	reply, err := b.client.Type(b.withMultiplexID(b.doneCtx), &pb.Empty{})
 	if err != nil {
 		return logical.TypeUnknown
 	}
 
 	return logical.BackendType(reply.Type)
 }
+
+// withMultiplexID returns the context of a call to the plugin, carrying the
+// multiplex ID of the backend
+func (b *backendGRPCPluginClient) withMultiplexID(ctx context.Context) context.Context {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	return metadata.NewOutgoingContext(ctx, metadata.Join(md, metadata.Pairs(multiplexIDMetadataKey, b.multiplexID)))
+}
@@ -2,35 +2,90 @@ package plugin
 
 import (
 	"context"
+	"errors"
+	"sync"
 
 	plugin "github.com/hashicorp/go-plugin"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/plugin/pb"
 	log "github.com/mgutz/logxi/v1"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 )
 
+const (
+	// multiplexIDMetadataKey is the gRPC metadata key of the ID identifying
+	// the mount a call is made for
+	multiplexIDMetadataKey = "vault-multiplex-id"
+
+	// multiplexingHeaderKey is set in the header of the reply to Setup by
+	// the plugins which serve several mounts from one process
+	multiplexingHeaderKey = "vault-multiplexing"
+)
+
+var ErrNoBackendInstance = errors.New("no backend is set up for the mount")
+
 type backendGRPCPluginServer struct {
-	broker  *plugin.GRPCBroker
-	backend logical.Backend
+	broker *plugin.GRPCBroker
 
 	factory logical.Factory
 
-	brokeredClient *grpc.ClientConn
+	// multiplexing is set if the plugin serves a backend for each mount
+	// which is set up, identified by the multiplex ID of the calls. Otherwise
+	// it serves a single backend.
+	multiplexing bool
+
+	l         sync.RWMutex
+	instances map[string]*backendInstance
 
 	logger log.Logger
 }
 
+// backendInstance is a backend served by the plugin, with the connection to
+// the storage and system view of its mount
+type backendInstance struct {
+	backend        logical.Backend
+	brokeredClient *grpc.ClientConn
+}
+
+// instanceID returns the ID of the backend instance the call is made for
+func (b *backendGRPCPluginServer) instanceID(ctx context.Context) string {
+	if !b.multiplexing {
+		return ""
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	if ids := md[multiplexIDMetadataKey]; len(ids) > 0 {
+		return ids[0]
+	}
+	return ""
+}
+
+// instance returns the backend instance the call is made for
+func (b *backendGRPCPluginServer) instance(ctx context.Context) (*backendInstance, error) {
+	b.l.RLock()
+	defer b.l.RUnlock()
+	instance, ok := b.instances[b.instanceID(ctx)]
+	if !ok {
+		return nil, ErrNoBackendInstance
+	}
+	return instance, nil
+}
+
 // Setup dials into the plugin's broker to get a shimmed storage, logger, and
 // system view of the backend. This method also instantiates the underlying
 // backend through its factory func for the server side of the plugin.
 func (b *backendGRPCPluginServer) Setup(ctx context.Context, args *pb.SetupArgs) (*pb.SetupReply, error) {
+	if b.multiplexing {
+		if err := grpc.SetHeader(ctx, metadata.Pairs(multiplexingHeaderKey, "true")); err != nil {
+			return &pb.SetupReply{}, err
+		}
+	}
+
 	// Dial for storage
 	brokeredClient, err := b.broker.Dial(args.BrokerID)
 	if err != nil {
 		return &pb.SetupReply{}, err
 	}
-	b.brokeredClient = brokeredClient
 	storage := newGRPCStorageClient(brokeredClient)
 	sysView := newGRPCSystemView(brokeredClient)
 
@@ -42,14 +97,24 @@ func (b *backendGRPCPluginServer) Setup(ctx context.Context, args *pb.SetupArgs)
 	}
 
 	// Call the underlying backend factory after shims have been created
-	// to set b.backend
+	// to set up the instance
 	backend, err := b.factory(ctx, config)
 	if err != nil {
+		brokeredClient.Close()
 		return &pb.SetupReply{
 			Err: pb.ErrToString(err),
 		}, nil
 	}
-	b.backend = backend
+
+	b.l.Lock()
+	if b.instances == nil {
+		b.instances = make(map[string]*backendInstance)
+	}
+	b.instances[b.instanceID(ctx)] = &backendInstance{
+		backend:        backend,
+		brokeredClient: brokeredClient,
+	}
+	b.l.Unlock()
 
 	return &pb.SetupReply{}, nil
 }
@@ -59,14 +124,19 @@ func (b *backendGRPCPluginServer) HandleRequest(ctx context.Context, args *pb.Ha
 		return &pb.HandleRequestReply{}, ErrServerInMetadataMode
 	}
 
+	instance, err := b.instance(ctx)
+	if err != nil {
+		return &pb.HandleRequestReply{}, err
+	}
+
 	logicalReq, err := pb.ProtoRequestToLogicalRequest(args.Request)
 	if err != nil {
 		return &pb.HandleRequestReply{}, err
 	}
 
-	logicalReq.Storage = newGRPCStorageClient(b.brokeredClient)
+	logicalReq.Storage = newGRPCStorageClient(instance.brokeredClient)
 
-	resp, respErr := b.backend.HandleRequest(ctx, logicalReq)
+	resp, respErr := instance.backend.HandleRequest(ctx, logicalReq)
 
 	pbResp, err := pb.LogicalResponseToProtoResponse(resp)
 	if err != nil {
@@ -80,7 +150,12 @@ func (b *backendGRPCPluginServer) HandleRequest(ctx context.Context, args *pb.Ha
 }
 
 func (b *backendGRPCPluginServer) SpecialPaths(ctx context.Context, args *pb.Empty) (*pb.SpecialPathsReply, error) {
-	paths := b.backend.SpecialPaths()
+	instance, err := b.instance(ctx)
+	if err != nil {
+		return &pb.SpecialPathsReply{}, err
+	}
+
+	paths := instance.backend.SpecialPaths()
 	if paths == nil {
 		return &pb.SpecialPathsReply{
 			Paths: nil,
@@ -97,18 +172,43 @@ func (b *backendGRPCPluginServer) SpecialPaths(ctx context.Context, args *pb.Emp
 	}, nil
 }
 
+func (b *backendGRPCPluginServer) OperationProperties(ctx context.Context, args *pb.OperationPropertiesArgs) (*pb.OperationPropertiesReply, error) {
+	instance, err := b.instance(ctx)
+	if err != nil {
+		return &pb.OperationPropertiesReply{}, err
+	}
+
+	backend, ok := instance.backend.(logical.OperationPropertiesBackend)
+	if !ok {
+		return &pb.OperationPropertiesReply{}, nil
+	}
+
+	props := backend.OperationProperties(args.Path, logical.Operation(args.Operation))
+	return &pb.OperationPropertiesReply{
+		Unauthenticated: props.Unauthenticated,
+		ForwardToActive: props.ForwardToActive,
+		ReadOnly:        props.ReadOnly,
+		LocalOnly:       props.LocalOnly,
+	}, nil
+}
+
 func (b *backendGRPCPluginServer) HandleExistenceCheck(ctx context.Context, args *pb.HandleExistenceCheckArgs) (*pb.HandleExistenceCheckReply, error) {
 	if inMetadataMode() {
 		return &pb.HandleExistenceCheckReply{}, ErrServerInMetadataMode
 	}
 
+	instance, err := b.instance(ctx)
+	if err != nil {
+		return &pb.HandleExistenceCheckReply{}, err
+	}
+
 	logicalReq, err := pb.ProtoRequestToLogicalRequest(args.Request)
 	if err != nil {
 		return &pb.HandleExistenceCheckReply{}, err
 	}
-	logicalReq.Storage = newGRPCStorageClient(b.brokeredClient)
+	logicalReq.Storage = newGRPCStorageClient(instance.brokeredClient)
 
-	checkFound, exists, err := b.backend.HandleExistenceCheck(ctx, logicalReq)
+	checkFound, exists, err := instance.backend.HandleExistenceCheck(ctx, logicalReq)
 	return &pb.HandleExistenceCheckReply{
 		CheckFound: checkFound,
 		Exists:     exists,
@@ -117,10 +217,19 @@ func (b *backendGRPCPluginServer) HandleExistenceCheck(ctx context.Context, args
 }
 
 func (b *backendGRPCPluginServer) Cleanup(ctx context.Context, _ *pb.Empty) (*pb.Empty, error) {
-	b.backend.Cleanup(ctx)
+	id := b.instanceID(ctx)
+	b.l.Lock()
+	instance, ok := b.instances[id]
+	delete(b.instances, id)
+	b.l.Unlock()
+	if !ok {
+		return &pb.Empty{}, ErrNoBackendInstance
+	}
+
+	instance.backend.Cleanup(ctx)
 
 	// Close rpc clients
-	b.brokeredClient.Close()
+	instance.brokeredClient.Close()
 	return &pb.Empty{}, nil
 }
 
@@ -129,12 +238,22 @@ func (b *backendGRPCPluginServer) InvalidateKey(ctx context.Context, args *pb.In
 		return &pb.Empty{}, ErrServerInMetadataMode
 	}
 
-	b.backend.InvalidateKey(ctx, args.Key)
+	instance, err := b.instance(ctx)
+	if err != nil {
+		return &pb.Empty{}, err
+	}
+
+	instance.backend.InvalidateKey(ctx, args.Key)
 	return &pb.Empty{}, nil
 }
 
 func (b *backendGRPCPluginServer) Type(ctx context.Context, _ *pb.Empty) (*pb.TypeReply, error) {
+	instance, err := b.instance(ctx)
+	if err != nil {
+		return &pb.TypeReply{}, err
+	}
+
 	return &pb.TypeReply{
-		Type: uint32(b.backend.Type()),
+		Type: uint32(instance.backend.Type()),
 	}, nil
 }
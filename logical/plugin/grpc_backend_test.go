@@ -2,7 +2,9 @@ package plugin
 
 import (
 	"context"
+	"fmt"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -48,6 +50,24 @@ func TestGRPCBackendPlugin_SpecialPaths(t *testing.T) {
 	}
 }
 
+func TestGRPCBackendPlugin_OperationProperties(t *testing.T) {
+	b, cleanup := testGRPCBackend(t)
+	defer cleanup()
+
+	propsBackend, ok := b.(logical.OperationPropertiesBackend)
+	if !ok {
+		t.Fatal("the backend does not implement OperationProperties")
+	}
+	props := propsBackend.OperationProperties("special", logical.ReadOperation)
+	if props != (logical.OperationProperties{Unauthenticated: true, ReadOnly: true}) {
+		t.Fatalf("bad: %#v", props)
+	}
+	props = propsBackend.OperationProperties("kv/foo", logical.ReadOperation)
+	if props != (logical.OperationProperties{}) {
+		t.Fatalf("bad: %#v", props)
+	}
+}
+
 func TestGRPCBackendPlugin_System(t *testing.T) {
 	b, cleanup := testGRPCBackend(t)
 	defer cleanup()
@@ -138,6 +158,80 @@ func TestGRPCBackendPlugin_Setup(t *testing.T) {
 	defer cleanup()
 }
 
+func TestGRPCBackendPlugin_Multiplexing(t *testing.T) {
+	for _, multiplexing := range []bool{true, false} {
+		pluginMap := map[string]gplugin.Plugin{
+			"backend": &BackendPlugin{
+				Factory: mock.Factory,
+				Logger: hclog.New(&hclog.LoggerOptions{
+					Level:      hclog.Trace,
+					Output:     os.Stderr,
+					JSONFormat: true,
+				}),
+				Multiplexing: multiplexing,
+			},
+		}
+		client, _ := gplugin.TestPluginGRPCConn(t, pluginMap)
+		defer client.Close()
+
+		ctx := context.Background()
+		backends := make([]*backendGRPCPluginClient, 2)
+		for i := range backends {
+			b := testGRPCBackendSetup(t, client)
+			backends[i] = b.(*backendGRPCPluginClient)
+			if backends[i].multiplexed != multiplexing {
+				t.Fatalf("expected multiplexed to be %t", multiplexing)
+			}
+
+			if _, err := b.HandleRequest(ctx, &logical.Request{
+				Operation: logical.UpdateOperation,
+				Path:      "internal",
+				Data:      map[string]interface{}{"value": fmt.Sprintf("value%d", i)},
+			}); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		// Without multiplexing the plugin only serves the last backend set up
+		if !multiplexing {
+			continue
+		}
+
+		// The backends don't share their state
+		for i, b := range backends {
+			resp, err := b.HandleRequest(ctx, &logical.Request{
+				Operation: logical.ReadOperation,
+				Path:      "internal",
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.Data["value"] != fmt.Sprintf("value%d", i) {
+				t.Fatalf("bad: %#v", resp)
+			}
+		}
+
+		// Cleaning a backend up leaves the other one served
+		backends[0].Cleanup(ctx)
+		if _, err := backends[0].HandleRequest(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "internal",
+		}); err == nil || !strings.Contains(err.Error(), ErrNoBackendInstance.Error()) {
+			t.Fatalf("expected an error for the cleaned up backend, got %v", err)
+		}
+		resp, err := backends[1].HandleRequest(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "internal",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Data["value"] != "value1" {
+			t.Fatalf("bad: %#v", resp)
+		}
+	}
+}
+
 func testGRPCBackend(t *testing.T) (logical.Backend, func()) {
 	// Create a mock provider
 	pluginMap := map[string]gplugin.Plugin{
@@ -155,6 +249,11 @@ func testGRPCBackend(t *testing.T) (logical.Backend, func()) {
 		client.Close()
 	}
 
+	return testGRPCBackendSetup(t, client), cleanup
+}
+
+// testGRPCBackendSetup requests a backend from the plugin and sets it up
+func testGRPCBackendSetup(t *testing.T, client *gplugin.GRPCClient) logical.Backend {
 	// Request the backend
 	raw, err := client.Dispense(BackendPluginName)
 	if err != nil {
@@ -174,5 +273,5 @@ func testGRPCBackend(t *testing.T) (logical.Backend, func()) {
 		t.Fatal(err)
 	}
 
-	return b, cleanup
+	return b
 }
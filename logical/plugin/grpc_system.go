@@ -114,6 +114,22 @@ func (s *gRPCSystemViewClient) LookupPlugin(ctx context.Context, name string) (*
 	return nil, fmt.Errorf("cannot call LookupPlugin from a plugin backend")
 }
 
+func (s *gRPCSystemViewClient) EntityInfo(entityID string) (*logical.Entity, error) {
+	return nil, fmt.Errorf("cannot call EntityInfo from a plugin backend")
+}
+
+func (s *gRPCSystemViewClient) GeneratePasswordFromPolicy(ctx context.Context, policyName string) (string, error) {
+	return "", fmt.Errorf("cannot call GeneratePasswordFromPolicy from a plugin backend")
+}
+
+func (s *gRPCSystemViewClient) SendEvent(ctx context.Context, eventType, path string, metadata map[string]string) error {
+	return fmt.Errorf("cannot call SendEvent from a plugin backend")
+}
+
+func (s *gRPCSystemViewClient) AuditInternalRequest(ctx context.Context, req *logical.Request, resp *logical.Response) error {
+	return fmt.Errorf("cannot call AuditInternalRequest from a plugin backend")
+}
+
 func (s *gRPCSystemViewClient) MlockEnabled() bool {
 	reply, err := s.client.MlockEnabled(context.Background(), &pb.Empty{})
 	if err != nil {
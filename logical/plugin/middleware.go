@@ -20,6 +20,7 @@ type backendTracingMiddleware struct {
 
 // Validate the backendTracingMiddle object satisfies the backend interface
 var _ logical.Backend = &backendTracingMiddleware{}
+var _ logical.OperationPropertiesBackend = &backendTracingMiddleware{}
 
 func (b *backendTracingMiddleware) HandleRequest(ctx context.Context, req *logical.Request) (resp *logical.Response, err error) {
 	defer func(then time.Time) {
@@ -39,6 +40,19 @@ func (b *backendTracingMiddleware) SpecialPaths() *logical.Paths {
 	return b.next.SpecialPaths()
 }
 
+func (b *backendTracingMiddleware) OperationProperties(path string, op logical.Operation) logical.OperationProperties {
+	defer func(then time.Time) {
+		b.logger.Trace("plugin.OperationProperties", "path", path, "status", "finished", "type", b.typeStr, "transport", b.transport, "took", time.Since(then))
+	}(time.Now())
+
+	b.logger.Trace("plugin.OperationProperties", "path", path, "status", "started", "type", b.typeStr, "transport", b.transport)
+	next, ok := b.next.(logical.OperationPropertiesBackend)
+	if !ok {
+		return logical.OperationProperties{}
+	}
+	return next.OperationProperties(path, op)
+}
+
 func (b *backendTracingMiddleware) System() logical.SystemView {
 	return b.next.System()
 }
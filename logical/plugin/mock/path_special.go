@@ -14,6 +14,9 @@ func pathSpecial(b *backend) *framework.Path {
 		Callbacks: map[logical.Operation]framework.OperationFunc{
 			logical.ReadOperation: b.pathSpecialRead,
 		},
+		Properties: map[logical.Operation]logical.OperationProperties{
+			logical.ReadOperation: {Unauthenticated: true, ReadOnly: true},
+		},
 	}
 }
 
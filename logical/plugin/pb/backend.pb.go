@@ -29,6 +29,8 @@ It has these top-level messages:
 	SetupReply
 	TypeReply
 	InvalidateKeyArgs
+	OperationPropertiesArgs
+	OperationPropertiesReply
 	StorageEntry
 	StorageListArgs
 	StorageListReply
@@ -1033,6 +1035,72 @@ func (m *InvalidateKeyArgs) GetKey() string {
 	return ""
 }
 
+// OperationPropertiesArgs is the args for the OperationProperties method.
+type OperationPropertiesArgs struct {
+	Path      string `sentinel:"" protobuf:"bytes,1,opt,name=path" json:"path,omitempty"`
+	Operation string `sentinel:"" protobuf:"bytes,2,opt,name=operation" json:"operation,omitempty"`
+}
+
+func (m *OperationPropertiesArgs) Reset()                    { *m = OperationPropertiesArgs{} }
+func (m *OperationPropertiesArgs) String() string            { return proto.CompactTextString(m) }
+func (*OperationPropertiesArgs) ProtoMessage()               {}
+func (*OperationPropertiesArgs) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{21} }
+
+func (m *OperationPropertiesArgs) GetPath() string {
+	if m != nil {
+		return m.Path
+	}
+	return ""
+}
+
+func (m *OperationPropertiesArgs) GetOperation() string {
+	if m != nil {
+		return m.Operation
+	}
+	return ""
+}
+
+// OperationPropertiesReply is the reply for the OperationProperties method.
+type OperationPropertiesReply struct {
+	Unauthenticated bool `sentinel:"" protobuf:"varint,1,opt,name=unauthenticated" json:"unauthenticated,omitempty"`
+	ForwardToActive bool `sentinel:"" protobuf:"varint,2,opt,name=forward_to_active,json=forwardToActive" json:"forward_to_active,omitempty"`
+	ReadOnly        bool `sentinel:"" protobuf:"varint,3,opt,name=read_only,json=readOnly" json:"read_only,omitempty"`
+	LocalOnly       bool `sentinel:"" protobuf:"varint,4,opt,name=local_only,json=localOnly" json:"local_only,omitempty"`
+}
+
+func (m *OperationPropertiesReply) Reset()                    { *m = OperationPropertiesReply{} }
+func (m *OperationPropertiesReply) String() string            { return proto.CompactTextString(m) }
+func (*OperationPropertiesReply) ProtoMessage()               {}
+func (*OperationPropertiesReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{22} }
+
+func (m *OperationPropertiesReply) GetUnauthenticated() bool {
+	if m != nil {
+		return m.Unauthenticated
+	}
+	return false
+}
+
+func (m *OperationPropertiesReply) GetForwardToActive() bool {
+	if m != nil {
+		return m.ForwardToActive
+	}
+	return false
+}
+
+func (m *OperationPropertiesReply) GetReadOnly() bool {
+	if m != nil {
+		return m.ReadOnly
+	}
+	return false
+}
+
+func (m *OperationPropertiesReply) GetLocalOnly() bool {
+	if m != nil {
+		return m.LocalOnly
+	}
+	return false
+}
+
 type StorageEntry struct {
 	Key      string `sentinel:"" protobuf:"bytes,1,opt,name=key" json:"key,omitempty"`
 	Value    []byte `sentinel:"" protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
@@ -1042,7 +1110,7 @@ type StorageEntry struct {
 func (m *StorageEntry) Reset()                    { *m = StorageEntry{} }
 func (m *StorageEntry) String() string            { return proto.CompactTextString(m) }
 func (*StorageEntry) ProtoMessage()               {}
-func (*StorageEntry) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{21} }
+func (*StorageEntry) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{23} }
 
 func (m *StorageEntry) GetKey() string {
 	if m != nil {
@@ -1072,7 +1140,7 @@ type StorageListArgs struct {
 func (m *StorageListArgs) Reset()                    { *m = StorageListArgs{} }
 func (m *StorageListArgs) String() string            { return proto.CompactTextString(m) }
 func (*StorageListArgs) ProtoMessage()               {}
-func (*StorageListArgs) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{22} }
+func (*StorageListArgs) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{24} }
 
 func (m *StorageListArgs) GetPrefix() string {
 	if m != nil {
@@ -1089,7 +1157,7 @@ type StorageListReply struct {
 func (m *StorageListReply) Reset()                    { *m = StorageListReply{} }
 func (m *StorageListReply) String() string            { return proto.CompactTextString(m) }
 func (*StorageListReply) ProtoMessage()               {}
-func (*StorageListReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{23} }
+func (*StorageListReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{25} }
 
 func (m *StorageListReply) GetKeys() []string {
 	if m != nil {
@@ -1112,7 +1180,7 @@ type StorageGetArgs struct {
 func (m *StorageGetArgs) Reset()                    { *m = StorageGetArgs{} }
 func (m *StorageGetArgs) String() string            { return proto.CompactTextString(m) }
 func (*StorageGetArgs) ProtoMessage()               {}
-func (*StorageGetArgs) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{24} }
+func (*StorageGetArgs) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{26} }
 
 func (m *StorageGetArgs) GetKey() string {
 	if m != nil {
@@ -1129,7 +1197,7 @@ type StorageGetReply struct {
 func (m *StorageGetReply) Reset()                    { *m = StorageGetReply{} }
 func (m *StorageGetReply) String() string            { return proto.CompactTextString(m) }
 func (*StorageGetReply) ProtoMessage()               {}
-func (*StorageGetReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{25} }
+func (*StorageGetReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{27} }
 
 func (m *StorageGetReply) GetEntry() *StorageEntry {
 	if m != nil {
@@ -1152,7 +1220,7 @@ type StoragePutArgs struct {
 func (m *StoragePutArgs) Reset()                    { *m = StoragePutArgs{} }
 func (m *StoragePutArgs) String() string            { return proto.CompactTextString(m) }
 func (*StoragePutArgs) ProtoMessage()               {}
-func (*StoragePutArgs) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{26} }
+func (*StoragePutArgs) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{28} }
 
 func (m *StoragePutArgs) GetEntry() *StorageEntry {
 	if m != nil {
@@ -1168,7 +1236,7 @@ type StoragePutReply struct {
 func (m *StoragePutReply) Reset()                    { *m = StoragePutReply{} }
 func (m *StoragePutReply) String() string            { return proto.CompactTextString(m) }
 func (*StoragePutReply) ProtoMessage()               {}
-func (*StoragePutReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{27} }
+func (*StoragePutReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{29} }
 
 func (m *StoragePutReply) GetErr() string {
 	if m != nil {
@@ -1184,7 +1252,7 @@ type StorageDeleteArgs struct {
 func (m *StorageDeleteArgs) Reset()                    { *m = StorageDeleteArgs{} }
 func (m *StorageDeleteArgs) String() string            { return proto.CompactTextString(m) }
 func (*StorageDeleteArgs) ProtoMessage()               {}
-func (*StorageDeleteArgs) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{28} }
+func (*StorageDeleteArgs) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{30} }
 
 func (m *StorageDeleteArgs) GetKey() string {
 	if m != nil {
@@ -1200,7 +1268,7 @@ type StorageDeleteReply struct {
 func (m *StorageDeleteReply) Reset()                    { *m = StorageDeleteReply{} }
 func (m *StorageDeleteReply) String() string            { return proto.CompactTextString(m) }
 func (*StorageDeleteReply) ProtoMessage()               {}
-func (*StorageDeleteReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{29} }
+func (*StorageDeleteReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{31} }
 
 func (m *StorageDeleteReply) GetErr() string {
 	if m != nil {
@@ -1216,7 +1284,7 @@ type TTLReply struct {
 func (m *TTLReply) Reset()                    { *m = TTLReply{} }
 func (m *TTLReply) String() string            { return proto.CompactTextString(m) }
 func (*TTLReply) ProtoMessage()               {}
-func (*TTLReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{30} }
+func (*TTLReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{32} }
 
 func (m *TTLReply) GetTTL() int64 {
 	if m != nil {
@@ -1233,7 +1301,7 @@ type SudoPrivilegeArgs struct {
 func (m *SudoPrivilegeArgs) Reset()                    { *m = SudoPrivilegeArgs{} }
 func (m *SudoPrivilegeArgs) String() string            { return proto.CompactTextString(m) }
 func (*SudoPrivilegeArgs) ProtoMessage()               {}
-func (*SudoPrivilegeArgs) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{31} }
+func (*SudoPrivilegeArgs) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{33} }
 
 func (m *SudoPrivilegeArgs) GetPath() string {
 	if m != nil {
@@ -1256,7 +1324,7 @@ type SudoPrivilegeReply struct {
 func (m *SudoPrivilegeReply) Reset()                    { *m = SudoPrivilegeReply{} }
 func (m *SudoPrivilegeReply) String() string            { return proto.CompactTextString(m) }
 func (*SudoPrivilegeReply) ProtoMessage()               {}
-func (*SudoPrivilegeReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{32} }
+func (*SudoPrivilegeReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{34} }
 
 func (m *SudoPrivilegeReply) GetSudo() bool {
 	if m != nil {
@@ -1272,7 +1340,7 @@ type TaintedReply struct {
 func (m *TaintedReply) Reset()                    { *m = TaintedReply{} }
 func (m *TaintedReply) String() string            { return proto.CompactTextString(m) }
 func (*TaintedReply) ProtoMessage()               {}
-func (*TaintedReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{33} }
+func (*TaintedReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{35} }
 
 func (m *TaintedReply) GetTainted() bool {
 	if m != nil {
@@ -1288,7 +1356,7 @@ type CachingDisabledReply struct {
 func (m *CachingDisabledReply) Reset()                    { *m = CachingDisabledReply{} }
 func (m *CachingDisabledReply) String() string            { return proto.CompactTextString(m) }
 func (*CachingDisabledReply) ProtoMessage()               {}
-func (*CachingDisabledReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{34} }
+func (*CachingDisabledReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{36} }
 
 func (m *CachingDisabledReply) GetDisabled() bool {
 	if m != nil {
@@ -1304,7 +1372,7 @@ type ReplicationStateReply struct {
 func (m *ReplicationStateReply) Reset()                    { *m = ReplicationStateReply{} }
 func (m *ReplicationStateReply) String() string            { return proto.CompactTextString(m) }
 func (*ReplicationStateReply) ProtoMessage()               {}
-func (*ReplicationStateReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{35} }
+func (*ReplicationStateReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{37} }
 
 func (m *ReplicationStateReply) GetState() int32 {
 	if m != nil {
@@ -1322,7 +1390,7 @@ type ResponseWrapDataArgs struct {
 func (m *ResponseWrapDataArgs) Reset()                    { *m = ResponseWrapDataArgs{} }
 func (m *ResponseWrapDataArgs) String() string            { return proto.CompactTextString(m) }
 func (*ResponseWrapDataArgs) ProtoMessage()               {}
-func (*ResponseWrapDataArgs) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{36} }
+func (*ResponseWrapDataArgs) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{38} }
 
 func (m *ResponseWrapDataArgs) GetData() string {
 	if m != nil {
@@ -1353,7 +1421,7 @@ type ResponseWrapDataReply struct {
 func (m *ResponseWrapDataReply) Reset()                    { *m = ResponseWrapDataReply{} }
 func (m *ResponseWrapDataReply) String() string            { return proto.CompactTextString(m) }
 func (*ResponseWrapDataReply) ProtoMessage()               {}
-func (*ResponseWrapDataReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{37} }
+func (*ResponseWrapDataReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{39} }
 
 func (m *ResponseWrapDataReply) GetWrapInfo() *ResponseWrapInfo {
 	if m != nil {
@@ -1376,7 +1444,7 @@ type MlockEnabledReply struct {
 func (m *MlockEnabledReply) Reset()                    { *m = MlockEnabledReply{} }
 func (m *MlockEnabledReply) String() string            { return proto.CompactTextString(m) }
 func (*MlockEnabledReply) ProtoMessage()               {}
-func (*MlockEnabledReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{38} }
+func (*MlockEnabledReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{40} }
 
 func (m *MlockEnabledReply) GetEnabled() bool {
 	if m != nil {
@@ -1392,7 +1460,7 @@ type LocalMountReply struct {
 func (m *LocalMountReply) Reset()                    { *m = LocalMountReply{} }
 func (m *LocalMountReply) String() string            { return proto.CompactTextString(m) }
 func (*LocalMountReply) ProtoMessage()               {}
-func (*LocalMountReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{39} }
+func (*LocalMountReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{41} }
 
 func (m *LocalMountReply) GetLocal() bool {
 	if m != nil {
@@ -1423,6 +1491,8 @@ func init() {
 	proto.RegisterType((*SetupReply)(nil), "pb.SetupReply")
 	proto.RegisterType((*TypeReply)(nil), "pb.TypeReply")
 	proto.RegisterType((*InvalidateKeyArgs)(nil), "pb.InvalidateKeyArgs")
+	proto.RegisterType((*OperationPropertiesArgs)(nil), "pb.OperationPropertiesArgs")
+	proto.RegisterType((*OperationPropertiesReply)(nil), "pb.OperationPropertiesReply")
 	proto.RegisterType((*StorageEntry)(nil), "pb.StorageEntry")
 	proto.RegisterType((*StorageListArgs)(nil), "pb.StorageListArgs")
 	proto.RegisterType((*StorageListReply)(nil), "pb.StorageListReply")
@@ -1462,6 +1532,7 @@ type BackendClient interface {
 	InvalidateKey(ctx context.Context, in *InvalidateKeyArgs, opts ...grpc.CallOption) (*Empty, error)
 	Setup(ctx context.Context, in *SetupArgs, opts ...grpc.CallOption) (*SetupReply, error)
 	Type(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*TypeReply, error)
+	OperationProperties(ctx context.Context, in *OperationPropertiesArgs, opts ...grpc.CallOption) (*OperationPropertiesReply, error)
 }
 
 type backendClient struct {
@@ -1535,6 +1606,15 @@ func (c *backendClient) Type(ctx context.Context, in *Empty, opts ...grpc.CallOp
 	return out, nil
 }
 
+func (c *backendClient) OperationProperties(ctx context.Context, in *OperationPropertiesArgs, opts ...grpc.CallOption) (*OperationPropertiesReply, error) {
+	out := new(OperationPropertiesReply)
+	err := grpc.Invoke(ctx, "/pb.Backend/OperationProperties", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for Backend service
 
 type BackendServer interface {
@@ -1545,6 +1625,7 @@ type BackendServer interface {
 	InvalidateKey(context.Context, *InvalidateKeyArgs) (*Empty, error)
 	Setup(context.Context, *SetupArgs) (*SetupReply, error)
 	Type(context.Context, *Empty) (*TypeReply, error)
+	OperationProperties(context.Context, *OperationPropertiesArgs) (*OperationPropertiesReply, error)
 }
 
 func RegisterBackendServer(s *grpc.Server, srv BackendServer) {
@@ -1677,6 +1758,24 @@ func _Backend_Type_Handler(srv interface{}, ctx context.Context, dec func(interf
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Backend_OperationProperties_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(OperationPropertiesArgs)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BackendServer).OperationProperties(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.Backend/OperationProperties",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BackendServer).OperationProperties(ctx, req.(*OperationPropertiesArgs))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _Backend_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "pb.Backend",
 	HandlerType: (*BackendServer)(nil),
@@ -1709,6 +1808,10 @@ var _Backend_serviceDesc = grpc.ServiceDesc{
 			MethodName: "Type",
 			Handler:    _Backend_Type_Handler,
 		},
+		{
+			MethodName: "OperationProperties",
+			Handler:    _Backend_OperationProperties_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "logical/plugin/pb/backend.proto",
@@ -2208,133 +2311,140 @@ var _SystemView_serviceDesc = grpc.ServiceDesc{
 func init() { proto.RegisterFile("logical/plugin/pb/backend.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 2042 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb4, 0x58, 0x5f, 0x73, 0xdb, 0xc6,
-	0x11, 0x1f, 0x92, 0x22, 0x09, 0x2e, 0x49, 0xfd, 0x39, 0xcb, 0x2e, 0x44, 0x3b, 0x15, 0x8b, 0x8c,
-	0x15, 0xc6, 0xd3, 0x50, 0x36, 0xfb, 0xcf, 0x69, 0x27, 0xe9, 0xa8, 0xb2, 0xe2, 0xa8, 0x91, 0x12,
-	0x0d, 0xa4, 0x36, 0xed, 0xb4, 0x33, 0xcc, 0x09, 0x58, 0x51, 0x18, 0x81, 0x00, 0x7a, 0x38, 0x48,
-	0xe6, 0x53, 0x3f, 0x42, 0xdf, 0xd2, 0xaf, 0xd4, 0x99, 0x3e, 0xf7, 0x13, 0xf4, 0xbd, 0x0f, 0xfd,
-	0x04, 0x9d, 0xfb, 0x03, 0xf0, 0x40, 0x52, 0xb5, 0x3b, 0xd3, 0xbc, 0xdd, 0xfe, 0x76, 0xef, 0xf6,
-	0x6e, 0xb1, 0xfb, 0xdb, 0x3b, 0xc0, 0x6e, 0x18, 0x4f, 0x02, 0x8f, 0x86, 0xfb, 0x49, 0x98, 0x4d,
-	0x82, 0x68, 0x3f, 0xb9, 0xdc, 0xbf, 0xa4, 0xde, 0x0d, 0x46, 0xfe, 0x30, 0x61, 0x31, 0x8f, 0x49,
-	0x35, 0xb9, 0xec, 0xed, 0x4e, 0xe2, 0x78, 0x12, 0xe2, 0xbe, 0x44, 0x2e, 0xb3, 0xab, 0x7d, 0x1e,
-	0x4c, 0x31, 0xe5, 0x74, 0x9a, 0x28, 0x23, 0xa7, 0x09, 0xf5, 0xa3, 0x69, 0xc2, 0x67, 0x4e, 0x1f,
-	0x1a, 0x9f, 0x23, 0xf5, 0x91, 0x91, 0x47, 0xd0, 0xb8, 0x96, 0x23, 0xbb, 0xd2, 0xaf, 0x0d, 0x5a,
-	0xae, 0x96, 0x9c, 0x3f, 0x00, 0x9c, 0x89, 0x39, 0x47, 0x8c, 0xc5, 0x8c, 0xec, 0x80, 0x85, 0x8c,
-	0x8d, 0xf9, 0x2c, 0x41, 0xbb, 0xd2, 0xaf, 0x0c, 0xba, 0x6e, 0x13, 0x19, 0xbb, 0x98, 0x25, 0x48,
-	0xbe, 0x07, 0x62, 0x38, 0x9e, 0xa6, 0x13, 0xbb, 0xda, 0xaf, 0x88, 0x15, 0x90, 0xb1, 0xd3, 0x74,
-	0x92, 0xcf, 0xf1, 0x62, 0x1f, 0xed, 0x5a, 0xbf, 0x32, 0xa8, 0xc9, 0x39, 0x87, 0xb1, 0x8f, 0xce,
-	0xb7, 0x15, 0xa8, 0x9f, 0x51, 0x7e, 0x9d, 0x12, 0x02, 0x6b, 0x2c, 0x8e, 0xb9, 0x76, 0x2e, 0xc7,
-	0x64, 0x00, 0x1b, 0x59, 0x44, 0x33, 0x7e, 0x8d, 0x11, 0x0f, 0x3c, 0xca, 0xd1, 0xb7, 0xab, 0x52,
-	0xbd, 0x08, 0x93, 0xf7, 0xa1, 0x1b, 0xc6, 0x1e, 0x0d, 0xc7, 0x29, 0x8f, 0x19, 0x9d, 0x08, 0x3f,
-	0xc2, 0xae, 0x23, 0xc1, 0x73, 0x85, 0x91, 0x67, 0xb0, 0x95, 0x22, 0x0d, 0xc7, 0x77, 0x8c, 0x26,
-	0x85, 0xe1, 0x9a, 0x5a, 0x50, 0x28, 0xbe, 0x66, 0x34, 0xd1, 0xb6, 0xce, 0x5f, 0x1a, 0xd0, 0x74,
-	0xf1, 0x4f, 0x19, 0xa6, 0x9c, 0xac, 0x43, 0x35, 0xf0, 0xe5, 0x69, 0x5b, 0x6e, 0x35, 0xf0, 0xc9,
-	0x10, 0x88, 0x8b, 0x49, 0x28, 0x5c, 0x07, 0x71, 0x74, 0x18, 0x66, 0x29, 0x47, 0xa6, 0xcf, 0xbc,
-	0x42, 0x43, 0x9e, 0x40, 0x2b, 0x4e, 0x90, 0x49, 0x4c, 0x06, 0xa0, 0xe5, 0xce, 0x01, 0x71, 0xf0,
-	0x84, 0xf2, 0x6b, 0x7b, 0x4d, 0x2a, 0xe4, 0x58, 0x60, 0x3e, 0xe5, 0xd4, 0xae, 0x2b, 0x4c, 0x8c,
-	0x89, 0x03, 0x8d, 0x14, 0x3d, 0x86, 0xdc, 0x6e, 0xf4, 0x2b, 0x83, 0xf6, 0x08, 0x86, 0xc9, 0xe5,
-	0xf0, 0x5c, 0x22, 0xae, 0xd6, 0x90, 0x27, 0xb0, 0x26, 0xe2, 0x62, 0x37, 0xa5, 0x85, 0x25, 0x2c,
-	0x0e, 0x32, 0x7e, 0xed, 0x4a, 0x94, 0x8c, 0xa0, 0xa9, 0xbe, 0x69, 0x6a, 0x5b, 0xfd, 0xda, 0xa0,
-	0x3d, 0xb2, 0x85, 0x81, 0x3e, 0xe5, 0x50, 0xa5, 0x41, 0x7a, 0x14, 0x71, 0x36, 0x73, 0x73, 0x43,
-	0xf2, 0x03, 0xe8, 0x78, 0x61, 0x80, 0x11, 0x1f, 0xf3, 0xf8, 0x06, 0x23, 0xbb, 0x25, 0x77, 0xd4,
-	0x56, 0xd8, 0x85, 0x80, 0xc8, 0x08, 0x1e, 0x9a, 0x26, 0x63, 0xea, 0x79, 0x98, 0xa6, 0x31, 0xb3,
-	0x41, 0xda, 0x3e, 0x30, 0x6c, 0x0f, 0xb4, 0x4a, 0x2c, 0xeb, 0x07, 0x69, 0x12, 0xd2, 0xd9, 0x38,
-	0xa2, 0x53, 0xb4, 0xdb, 0x6a, 0x59, 0x8d, 0x7d, 0x49, 0xa7, 0x48, 0x76, 0xa1, 0x3d, 0x8d, 0xb3,
-	0x88, 0x8f, 0x93, 0x38, 0x88, 0xb8, 0xdd, 0x91, 0x16, 0x20, 0xa1, 0x33, 0x81, 0x90, 0xf7, 0x40,
-	0x49, 0x2a, 0x19, 0xbb, 0x2a, 0xae, 0x12, 0x91, 0xe9, 0xf8, 0x14, 0xd6, 0x95, 0xba, 0xd8, 0xcf,
-	0xba, 0x34, 0xe9, 0x4a, 0xb4, 0xd8, 0xc9, 0x73, 0x68, 0xc9, 0x7c, 0x08, 0xa2, 0xab, 0xd8, 0xde,
-	0x90, 0x71, 0x7b, 0x60, 0x84, 0x45, 0xe4, 0xc4, 0x71, 0x74, 0x15, 0xbb, 0xd6, 0x9d, 0x1e, 0x91,
-	0x4f, 0xe0, 0x71, 0xe9, 0xbc, 0x0c, 0xa7, 0x34, 0x88, 0x82, 0x68, 0x32, 0xce, 0x52, 0x4c, 0xed,
-	0x4d, 0x99, 0xe1, 0xb6, 0x71, 0x6a, 0x37, 0x37, 0xf8, 0x4d, 0x8a, 0x29, 0x79, 0x0c, 0x2d, 0x91,
-	0xb7, 0x7c, 0x36, 0x0e, 0x7c, 0x7b, 0x4b, 0x6e, 0xc9, 0x52, 0xc0, 0xb1, 0x4f, 0x3e, 0x80, 0x8d,
-	0x24, 0x0e, 0x03, 0x6f, 0x36, 0x8e, 0x6f, 0x91, 0xb1, 0xc0, 0x47, 0x9b, 0xf4, 0x2b, 0x03, 0xcb,
-	0x5d, 0x57, 0xf0, 0x57, 0x1a, 0x5d, 0x55, 0x1a, 0x0f, 0xa4, 0xe1, 0x22, 0xdc, 0xfb, 0x0c, 0x3a,
-	0xe6, 0xa7, 0x25, 0x9b, 0x50, 0xbb, 0xc1, 0x99, 0x4e, 0x67, 0x31, 0x24, 0x7d, 0xa8, 0xdf, 0xd2,
-	0x30, 0x43, 0x99, 0xc2, 0x3a, 0xb1, 0xd4, 0x14, 0x57, 0x29, 0x7e, 0x5e, 0x7d, 0x59, 0x71, 0x28,
-	0xd4, 0x0f, 0xc2, 0x80, 0xa6, 0x0b, 0x71, 0xaf, 0xbc, 0x3d, 0xee, 0xd5, 0x55, 0x71, 0x27, 0xb0,
-	0x26, 0xbf, 0xbc, 0xaa, 0x07, 0x39, 0x76, 0xfe, 0x5d, 0x83, 0x35, 0x91, 0xaf, 0xe4, 0x27, 0xd0,
-	0x0d, 0x91, 0xa6, 0x38, 0x8e, 0x13, 0x51, 0x23, 0xa9, 0xf4, 0xd2, 0x1e, 0x6d, 0x8a, 0x9d, 0x9d,
-	0x08, 0xc5, 0x57, 0x0a, 0x77, 0x3b, 0xa1, 0x21, 0x09, 0x16, 0x08, 0x22, 0x8e, 0x2c, 0xa2, 0xe1,
-	0x58, 0xd6, 0x8f, 0xf2, 0xdc, 0xc9, 0xc1, 0x57, 0xa2, 0x8e, 0x16, 0x53, 0xaf, 0xb6, 0x9c, 0x7a,
-	0x3d, 0xb0, 0x64, 0xb8, 0x03, 0x4c, 0x35, 0x3f, 0x14, 0x32, 0x19, 0x81, 0x35, 0x45, 0x4e, 0x75,
-	0x79, 0x8a, 0x2a, 0x7a, 0x94, 0x97, 0xd9, 0xf0, 0x54, 0x2b, 0x54, 0x0d, 0x15, 0x76, 0x4b, 0x45,
-	0xd4, 0x58, 0x2e, 0xa2, 0x1e, 0x58, 0x45, 0xbc, 0x9a, 0x2a, 0x29, 0x72, 0x59, 0x30, 0x73, 0x82,
-	0x2c, 0x88, 0x7d, 0xdb, 0x92, 0xb9, 0xa5, 0x25, 0xc1, 0xab, 0x51, 0x36, 0x55, 0x59, 0xd7, 0x52,
-	0xbc, 0x1a, 0x65, 0xd3, 0xe5, 0x24, 0x83, 0x85, 0x24, 0xdb, 0x85, 0x3a, 0x15, 0x5f, 0x52, 0x56,
-	0x5d, 0x7b, 0xd4, 0x92, 0xfb, 0x17, 0x80, 0xab, 0x70, 0x32, 0x84, 0xee, 0x84, 0xc5, 0x59, 0x32,
-	0x96, 0x22, 0xa6, 0x76, 0x47, 0x1e, 0xd4, 0x30, 0xec, 0x48, 0xfd, 0x81, 0x52, 0xf7, 0x7e, 0x01,
-	0xdd, 0xd2, 0xd1, 0x57, 0xe4, 0xd8, 0xb6, 0x99, 0x63, 0x2d, 0x33, 0xaf, 0xfe, 0x5a, 0x81, 0x8e,
-	0xf9, 0x4d, 0xc5, 0xe4, 0x8b, 0x8b, 0x13, 0x39, 0xb9, 0xe6, 0x8a, 0xa1, 0x20, 0x50, 0x86, 0x11,
-	0xde, 0xd1, 0xcb, 0x50, 0x2d, 0x60, 0xb9, 0x73, 0x40, 0x68, 0x83, 0xc8, 0x63, 0x38, 0xc5, 0x88,
-	0xeb, 0xfe, 0x32, 0x07, 0xc8, 0xc7, 0x00, 0x41, 0x9a, 0x66, 0x38, 0x16, 0x2d, 0x50, 0x92, 0x6c,
-	0x7b, 0xd4, 0x1b, 0xaa, 0xfe, 0x38, 0xcc, 0xfb, 0xe3, 0xf0, 0x22, 0xef, 0x8f, 0x6e, 0x4b, 0x5a,
-	0x0b, 0xd9, 0xf9, 0x33, 0x34, 0x14, 0xbf, 0x7e, 0xa7, 0xf9, 0xb8, 0x03, 0x96, 0x5a, 0x3b, 0xf0,
-	0x75, 0x2e, 0x36, 0xa5, 0x7c, 0xec, 0x3b, 0x7f, 0xaf, 0x80, 0xe5, 0x62, 0x9a, 0xc4, 0x51, 0x8a,
-	0x06, 0xff, 0x57, 0xde, 0xca, 0xff, 0xd5, 0x95, 0xfc, 0x9f, 0x77, 0x95, 0x9a, 0xd1, 0x55, 0x7a,
-	0x60, 0x31, 0xf4, 0x03, 0x86, 0x1e, 0xd7, 0x1d, 0xa8, 0x90, 0x85, 0xee, 0x8e, 0x32, 0x41, 0x5c,
-	0xa9, 0x4c, 0xf5, 0x96, 0x5b, 0xc8, 0xe4, 0x85, 0x49, 0x9b, 0xaa, 0x21, 0x6d, 0x2b, 0xda, 0x54,
-	0xdb, 0x5d, 0xe6, 0x4d, 0xe7, 0x6f, 0x55, 0xd8, 0x5c, 0x54, 0xaf, 0xf8, 0xd8, 0xdb, 0x50, 0x57,
-	0x55, 0xa2, 0x33, 0x85, 0x2f, 0xd5, 0x47, 0x6d, 0xa1, 0x3e, 0x7e, 0x09, 0x5d, 0x8f, 0xa1, 0xec,
-	0xa6, 0xef, 0xfa, 0x95, 0x3b, 0xf9, 0x04, 0x01, 0x91, 0x0f, 0x61, 0x53, 0xec, 0x32, 0x41, 0x7f,
-	0x4e, 0x5a, 0xaa, 0xf5, 0x6e, 0x68, 0xbc, 0xa0, 0xad, 0x67, 0xb0, 0x95, 0x9b, 0xce, 0x0b, 0xac,
-	0x51, 0xb2, 0x3d, 0xca, 0xeb, 0xec, 0x11, 0x34, 0xae, 0x62, 0x36, 0xa5, 0x5c, 0x57, 0xb4, 0x96,
-	0x44, 0x5a, 0x14, 0xfb, 0x95, 0xad, 0xdf, 0x52, 0x69, 0x91, 0x83, 0xe2, 0x42, 0x24, 0x2a, 0xb8,
-	0xb8, 0xac, 0xc8, 0xea, 0xb6, 0x5c, 0x2b, 0xbf, 0xa4, 0x38, 0xbf, 0x83, 0x8d, 0x85, 0xfe, 0xb4,
-	0x22, 0x90, 0x73, 0xf7, 0xd5, 0x92, 0xfb, 0xd2, 0xca, 0xb5, 0x85, 0x95, 0x7f, 0x0f, 0x5b, 0x9f,
-	0xd3, 0xc8, 0x0f, 0x51, 0xaf, 0x7f, 0xc0, 0x26, 0x92, 0xf1, 0xf5, 0x75, 0x69, 0xac, 0x2f, 0x42,
-	0x5d, 0xb7, 0xa5, 0x91, 0x63, 0x9f, 0x3c, 0x85, 0x26, 0x53, 0xd6, 0x3a, 0xf1, 0xda, 0x46, 0x03,
-	0x75, 0x73, 0x9d, 0xf3, 0x0d, 0x90, 0xd2, 0xd2, 0xe2, 0xa6, 0x34, 0x23, 0x03, 0x91, 0x80, 0x2a,
-	0x29, 0x74, 0x62, 0x77, 0xcc, 0x3c, 0x72, 0x0b, 0x2d, 0xe9, 0x43, 0x0d, 0x19, 0xd3, 0x2e, 0xd6,
-	0x85, 0xd1, 0xfc, 0x5e, 0xea, 0x0a, 0x95, 0xf3, 0x63, 0xd8, 0x3a, 0x4f, 0xd0, 0x0b, 0x68, 0x28,
-	0xef, 0x94, 0xca, 0xc1, 0x2e, 0xd4, 0x45, 0x90, 0xf3, 0x9a, 0x95, 0x24, 0xa6, 0xd4, 0x0a, 0x77,
-	0xbe, 0x01, 0x5b, 0xed, 0xeb, 0xe8, 0x4d, 0x90, 0x72, 0x8c, 0x3c, 0x3c, 0xbc, 0x46, 0xef, 0xe6,
-	0xff, 0x78, 0xf2, 0x5b, 0xd8, 0x59, 0xe5, 0x21, 0xdf, 0x5f, 0xdb, 0x13, 0xd2, 0xf8, 0x2a, 0xce,
-	0x22, 0xe5, 0xc3, 0x72, 0x41, 0x42, 0x9f, 0x09, 0x44, 0x7c, 0x47, 0x14, 0xf3, 0x52, 0x4d, 0x7d,
-	0x5a, 0xca, 0xe3, 0x51, 0xbb, 0x3f, 0x1e, 0xdf, 0x56, 0xa0, 0x75, 0x8e, 0x3c, 0x4b, 0xe4, 0x59,
-	0x1e, 0x43, 0xeb, 0x92, 0xc5, 0x37, 0xc8, 0xe6, 0x47, 0xb1, 0x14, 0x70, 0xec, 0x93, 0x17, 0xd0,
-	0x38, 0x8c, 0xa3, 0xab, 0x60, 0x22, 0x6f, 0xd8, 0xed, 0xd1, 0x8e, 0x62, 0x17, 0x3d, 0x77, 0xa8,
-	0x74, 0xaa, 0xaf, 0x69, 0xc3, 0xde, 0xc7, 0xd0, 0x36, 0xe0, 0xff, 0x89, 0xf3, 0xbf, 0x0f, 0x20,
-	0xd7, 0x56, 0x11, 0xd8, 0x54, 0x07, 0xd1, 0x33, 0xc5, 0xc6, 0x77, 0xa1, 0x25, 0xee, 0x12, 0x4a,
-	0x4d, 0x60, 0xcd, 0x78, 0x6e, 0xc8, 0xb1, 0xf3, 0x14, 0xb6, 0x8e, 0xa3, 0x5b, 0x1a, 0x06, 0x3e,
-	0xe5, 0xf8, 0x05, 0xce, 0xe4, 0x01, 0x97, 0x76, 0xe0, 0x9c, 0x43, 0x47, 0x5f, 0xe8, 0xdf, 0x69,
-	0x8f, 0x1d, 0xbd, 0xc7, 0xff, 0x5e, 0x22, 0x1f, 0xc2, 0x86, 0x5e, 0xf4, 0x24, 0xd0, 0x05, 0x22,
-	0x3a, 0x34, 0xc3, 0xab, 0xe0, 0x8d, 0x5e, 0x5a, 0x4b, 0xce, 0x4b, 0xd8, 0x34, 0x4c, 0x8b, 0xe3,
-	0xdc, 0xe0, 0x2c, 0xcd, 0x1f, 0x3a, 0x62, 0x9c, 0x47, 0xa0, 0x3a, 0x8f, 0x80, 0x03, 0xeb, 0x7a,
-	0xe6, 0x6b, 0xe4, 0xf7, 0x9c, 0xee, 0x8b, 0x62, 0x23, 0xaf, 0x51, 0x2f, 0xbe, 0x07, 0x75, 0x14,
-	0x27, 0x35, 0x1b, 0x94, 0x19, 0x01, 0x57, 0xa9, 0x57, 0x38, 0x7c, 0x59, 0x38, 0x3c, 0xcb, 0x94,
-	0xc3, 0x77, 0x5c, 0xcb, 0x79, 0xbf, 0xd8, 0xc6, 0x59, 0xc6, 0xef, 0xfb, 0xa2, 0x4f, 0x61, 0x4b,
-	0x1b, 0xbd, 0xc2, 0x10, 0x39, 0xde, 0x73, 0xa4, 0x3d, 0x20, 0x25, 0xb3, 0xfb, 0x96, 0x7b, 0x02,
-	0xd6, 0xc5, 0xc5, 0x49, 0xa1, 0x2d, 0x33, 0x9f, 0xf3, 0x09, 0x6c, 0x9d, 0x67, 0x7e, 0x7c, 0xc6,
-	0x82, 0xdb, 0x20, 0xc4, 0x89, 0x72, 0x96, 0xbf, 0xb3, 0x2a, 0xc6, 0x3b, 0x6b, 0x65, 0xaf, 0x71,
-	0x06, 0x40, 0x4a, 0xd3, 0x8b, 0xef, 0x96, 0x66, 0x7e, 0xac, 0x0b, 0x54, 0x8e, 0x9d, 0x01, 0x74,
-	0x2e, 0xa8, 0xe8, 0xe6, 0xbe, 0xb2, 0xb1, 0xa1, 0xc9, 0x95, 0xac, 0xcd, 0x72, 0xd1, 0x19, 0xc1,
-	0xf6, 0x21, 0xf5, 0xae, 0x83, 0x68, 0xf2, 0x2a, 0x48, 0xc5, 0xb5, 0x45, 0xcf, 0xe8, 0x81, 0xe5,
-	0x6b, 0x40, 0x4f, 0x29, 0x64, 0xe7, 0x23, 0x78, 0x68, 0xbc, 0x26, 0xcf, 0x39, 0xcd, 0xe3, 0xb1,
-	0x0d, 0xf5, 0x54, 0x48, 0x72, 0x46, 0xdd, 0x55, 0x82, 0xf3, 0x25, 0x6c, 0x9b, 0xed, 0x55, 0x5c,
-	0x2e, 0xf2, 0x83, 0xcb, 0xb6, 0x5f, 0x31, 0xda, 0xbe, 0x8e, 0x59, 0x75, 0xde, 0x2d, 0x36, 0xa1,
-	0xf6, 0xeb, 0xaf, 0x2f, 0x74, 0xb2, 0x8b, 0xa1, 0xf3, 0x47, 0xe1, 0xbe, 0xbc, 0x9e, 0x72, 0x5f,
-	0xea, 0xfd, 0x95, 0x77, 0xe9, 0xfd, 0x2b, 0xf2, 0xed, 0x23, 0xd8, 0x3a, 0x0d, 0x63, 0xef, 0xe6,
-	0x28, 0x32, 0xa2, 0x61, 0x43, 0x13, 0x23, 0x33, 0x18, 0xb9, 0xe8, 0x7c, 0x00, 0x1b, 0x27, 0xe2,
-	0x2d, 0x7f, 0x2a, 0x1e, 0x11, 0x45, 0x14, 0xe4, 0xf3, 0x5e, 0x9b, 0x2a, 0x61, 0xf4, 0xaf, 0x2a,
-	0x34, 0x7f, 0xa5, 0x7e, 0x88, 0x90, 0x4f, 0xa1, 0x5b, 0xea, 0x38, 0xe4, 0xa1, 0x7c, 0xda, 0x2c,
-	0xf6, 0xb7, 0xde, 0xa3, 0x25, 0x58, 0x79, 0x78, 0x0e, 0x1d, 0xb3, 0x9f, 0x10, 0xd9, 0x3b, 0xe4,
-	0x7f, 0x93, 0x9e, 0x5c, 0x69, 0xb9, 0xd9, 0x9c, 0xc3, 0xf6, 0x2a, 0xa6, 0x27, 0x4f, 0xe6, 0x1e,
-	0x96, 0xbb, 0x4c, 0xef, 0xbd, 0xfb, 0xb4, 0x79, 0x87, 0x68, 0x1e, 0x86, 0x48, 0xa3, 0x2c, 0x31,
-	0x77, 0x30, 0x1f, 0x92, 0x17, 0xd0, 0x2d, 0xb1, 0xa1, 0x3a, 0xe7, 0x12, 0x41, 0x9a, 0x53, 0xf6,
-	0xa0, 0x2e, 0x19, 0x98, 0x74, 0x4b, 0x44, 0xdf, 0x5b, 0x2f, 0x44, 0xe5, 0xbb, 0x0f, 0x6b, 0xf2,
-	0x55, 0x67, 0x38, 0x96, 0x33, 0x0a, 0x7a, 0x1e, 0xfd, 0xa3, 0x02, 0xcd, 0xfc, 0x0f, 0xcb, 0x0b,
-	0x58, 0x13, 0x44, 0x47, 0x1e, 0x18, 0x5c, 0x91, 0x93, 0x64, 0x6f, 0x7b, 0x01, 0x54, 0x0e, 0x86,
-	0x50, 0x7b, 0x8d, 0x9c, 0x10, 0x43, 0xa9, 0x19, 0xaf, 0xf7, 0xa0, 0x8c, 0x15, 0xf6, 0x67, 0x59,
-	0xd9, 0x5e, 0x13, 0x56, 0xc9, 0xbe, 0xa0, 0xa2, 0x9f, 0x41, 0x43, 0x51, 0x89, 0x0a, 0xca, 0x12,
-	0x09, 0xa9, 0x8f, 0xbf, 0x4c, 0x3a, 0xa3, 0x7f, 0xd6, 0x00, 0xce, 0x67, 0x29, 0xc7, 0xe9, 0x6f,
-	0x03, 0xbc, 0x23, 0xcf, 0x60, 0xe3, 0x15, 0x5e, 0xd1, 0x2c, 0xe4, 0xf2, 0xc2, 0x2f, 0x4a, 0xc6,
-	0x88, 0x89, 0xbc, 0xb3, 0x14, 0x8c, 0xb4, 0x07, 0xed, 0x53, 0xfa, 0xe6, 0xed, 0x76, 0x9f, 0x42,
-	0xb7, 0x44, 0x34, 0x7a, 0x8b, 0x8b, 0xd4, 0xa5, 0xb7, 0xb8, 0x4c, 0x49, 0x7b, 0xd0, 0xd4, 0xf4,
-	0x63, 0xfa, 0x90, 0x44, 0x5d, 0xa2, 0xa5, 0x9f, 0xc2, 0xc6, 0x02, 0xf9, 0x98, 0xf6, 0xf2, 0x2f,
-	0xd0, 0x4a, 0x72, 0x7a, 0x29, 0x2e, 0xec, 0x65, 0x02, 0x32, 0x27, 0xee, 0xa8, 0xa2, 0x5f, 0xc5,
-	0x50, 0xaf, 0xcb, 0x57, 0x7d, 0xf9, 0xd0, 0xb1, 0x17, 0x39, 0x22, 0x67, 0xa8, 0x7c, 0xa1, 0x55,
-	0x5c, 0xf3, 0x1c, 0x3a, 0x26, 0x4d, 0x2c, 0x95, 0xe0, 0x32, 0x87, 0xfc, 0x10, 0x60, 0xce, 0x14,
-	0xa6, 0xbd, 0x4c, 0x8f, 0x05, 0x12, 0xb9, 0x6c, 0xc8, 0xc7, 0xc1, 0x8f, 0xfe, 0x13, 0x00, 0x00,
-	0xff, 0xff, 0x54, 0xbe, 0x6c, 0x5a, 0x57, 0x15, 0x00, 0x00,
+	// 2147 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb4, 0x58, 0xdd, 0x72, 0xdb, 0xd6,
+	0xf1, 0x1f, 0x90, 0x22, 0x09, 0x2e, 0x49, 0x51, 0x3a, 0x92, 0x15, 0x88, 0x76, 0xfe, 0xe2, 0x1f,
+	0x19, 0x3b, 0x8c, 0xa7, 0xa1, 0x63, 0xf6, 0xcb, 0x69, 0x27, 0xe9, 0xa8, 0xb2, 0xe2, 0xa8, 0xb1,
+	0x63, 0x0e, 0xc4, 0x36, 0xed, 0xb4, 0x33, 0xc8, 0x11, 0xb0, 0xa2, 0x30, 0x02, 0x01, 0xf4, 0xe0,
+	0x40, 0x32, 0xaf, 0xfa, 0x08, 0xbd, 0x4b, 0x5f, 0xa2, 0x0f, 0xd1, 0xcb, 0xce, 0xf4, 0xba, 0x4f,
+	0xd0, 0x37, 0xe8, 0x13, 0x74, 0xce, 0x07, 0x40, 0xf0, 0x43, 0xb5, 0x3b, 0xd3, 0xde, 0x9d, 0xfd,
+	0xed, 0x9e, 0xaf, 0x3d, 0xbb, 0xbf, 0x5d, 0x00, 0x8e, 0xc2, 0x78, 0x1a, 0x78, 0x34, 0x7c, 0x92,
+	0x84, 0xd9, 0x34, 0x88, 0x9e, 0x24, 0x17, 0x4f, 0x2e, 0xa8, 0x77, 0x8d, 0x91, 0x3f, 0x4c, 0x58,
+	0xcc, 0x63, 0x52, 0x49, 0x2e, 0x7a, 0x47, 0xd3, 0x38, 0x9e, 0x86, 0xf8, 0x44, 0x22, 0x17, 0xd9,
+	0xe5, 0x13, 0x1e, 0xcc, 0x30, 0xe5, 0x74, 0x96, 0x28, 0x23, 0xbb, 0x01, 0xb5, 0xd3, 0x59, 0xc2,
+	0xe7, 0x76, 0x1f, 0xea, 0x5f, 0x22, 0xf5, 0x91, 0x91, 0x03, 0xa8, 0x5f, 0xc9, 0x91, 0x65, 0xf4,
+	0xab, 0x83, 0xa6, 0xa3, 0x25, 0xfb, 0xb7, 0x00, 0x63, 0x31, 0xe7, 0x94, 0xb1, 0x98, 0x91, 0x43,
+	0x30, 0x91, 0x31, 0x97, 0xcf, 0x13, 0xb4, 0x8c, 0xbe, 0x31, 0xe8, 0x38, 0x0d, 0x64, 0x6c, 0x32,
+	0x4f, 0x90, 0xbc, 0x07, 0x62, 0xe8, 0xce, 0xd2, 0xa9, 0x55, 0xe9, 0x1b, 0x62, 0x05, 0x64, 0xec,
+	0x55, 0x3a, 0xcd, 0xe7, 0x78, 0xb1, 0x8f, 0x56, 0xb5, 0x6f, 0x0c, 0xaa, 0x72, 0xce, 0x49, 0xec,
+	0xa3, 0xfd, 0x9d, 0x01, 0xb5, 0x31, 0xe5, 0x57, 0x29, 0x21, 0xb0, 0xc5, 0xe2, 0x98, 0xeb, 0xcd,
+	0xe5, 0x98, 0x0c, 0xa0, 0x9b, 0x45, 0x34, 0xe3, 0x57, 0x18, 0xf1, 0xc0, 0xa3, 0x1c, 0x7d, 0xab,
+	0x22, 0xd5, 0xab, 0x30, 0xf9, 0x00, 0x3a, 0x61, 0xec, 0xd1, 0xd0, 0x4d, 0x79, 0xcc, 0xe8, 0x54,
+	0xec, 0x23, 0xec, 0xda, 0x12, 0x3c, 0x57, 0x18, 0x79, 0x0c, 0xbb, 0x29, 0xd2, 0xd0, 0xbd, 0x65,
+	0x34, 0x29, 0x0c, 0xb7, 0xd4, 0x82, 0x42, 0xf1, 0x0d, 0xa3, 0x89, 0xb6, 0xb5, 0xff, 0x58, 0x87,
+	0x86, 0x83, 0xbf, 0xcf, 0x30, 0xe5, 0x64, 0x1b, 0x2a, 0x81, 0x2f, 0x6f, 0xdb, 0x74, 0x2a, 0x81,
+	0x4f, 0x86, 0x40, 0x1c, 0x4c, 0x42, 0xb1, 0x75, 0x10, 0x47, 0x27, 0x61, 0x96, 0x72, 0x64, 0xfa,
+	0xce, 0x1b, 0x34, 0xe4, 0x01, 0x34, 0xe3, 0x04, 0x99, 0xc4, 0xa4, 0x03, 0x9a, 0xce, 0x02, 0x10,
+	0x17, 0x4f, 0x28, 0xbf, 0xb2, 0xb6, 0xa4, 0x42, 0x8e, 0x05, 0xe6, 0x53, 0x4e, 0xad, 0x9a, 0xc2,
+	0xc4, 0x98, 0xd8, 0x50, 0x4f, 0xd1, 0x63, 0xc8, 0xad, 0x7a, 0xdf, 0x18, 0xb4, 0x46, 0x30, 0x4c,
+	0x2e, 0x86, 0xe7, 0x12, 0x71, 0xb4, 0x86, 0x3c, 0x80, 0x2d, 0xe1, 0x17, 0xab, 0x21, 0x2d, 0x4c,
+	0x61, 0x71, 0x9c, 0xf1, 0x2b, 0x47, 0xa2, 0x64, 0x04, 0x0d, 0xf5, 0xa6, 0xa9, 0x65, 0xf6, 0xab,
+	0x83, 0xd6, 0xc8, 0x12, 0x06, 0xfa, 0x96, 0x43, 0x15, 0x06, 0xe9, 0x69, 0xc4, 0xd9, 0xdc, 0xc9,
+	0x0d, 0xc9, 0xff, 0x43, 0xdb, 0x0b, 0x03, 0x8c, 0xb8, 0xcb, 0xe3, 0x6b, 0x8c, 0xac, 0xa6, 0x3c,
+	0x51, 0x4b, 0x61, 0x13, 0x01, 0x91, 0x11, 0xdc, 0x2b, 0x9b, 0xb8, 0xd4, 0xf3, 0x30, 0x4d, 0x63,
+	0x66, 0x81, 0xb4, 0xdd, 0x2b, 0xd9, 0x1e, 0x6b, 0x95, 0x58, 0xd6, 0x0f, 0xd2, 0x24, 0xa4, 0x73,
+	0x37, 0xa2, 0x33, 0xb4, 0x5a, 0x6a, 0x59, 0x8d, 0x7d, 0x4d, 0x67, 0x48, 0x8e, 0xa0, 0x35, 0x8b,
+	0xb3, 0x88, 0xbb, 0x49, 0x1c, 0x44, 0xdc, 0x6a, 0x4b, 0x0b, 0x90, 0xd0, 0x58, 0x20, 0xe4, 0x7d,
+	0x50, 0x92, 0x0a, 0xc6, 0x8e, 0xf2, 0xab, 0x44, 0x64, 0x38, 0x3e, 0x84, 0x6d, 0xa5, 0x2e, 0xce,
+	0xb3, 0x2d, 0x4d, 0x3a, 0x12, 0x2d, 0x4e, 0xf2, 0x09, 0x34, 0x65, 0x3c, 0x04, 0xd1, 0x65, 0x6c,
+	0x75, 0xa5, 0xdf, 0xf6, 0x4a, 0x6e, 0x11, 0x31, 0x71, 0x16, 0x5d, 0xc6, 0x8e, 0x79, 0xab, 0x47,
+	0xe4, 0x33, 0xb8, 0xbf, 0x74, 0x5f, 0x86, 0x33, 0x1a, 0x44, 0x41, 0x34, 0x75, 0xb3, 0x14, 0x53,
+	0x6b, 0x47, 0x46, 0xb8, 0x55, 0xba, 0xb5, 0x93, 0x1b, 0xfc, 0x32, 0xc5, 0x94, 0xdc, 0x87, 0xa6,
+	0x88, 0x5b, 0x3e, 0x77, 0x03, 0xdf, 0xda, 0x95, 0x47, 0x32, 0x15, 0x70, 0xe6, 0x93, 0x0f, 0xa1,
+	0x9b, 0xc4, 0x61, 0xe0, 0xcd, 0xdd, 0xf8, 0x06, 0x19, 0x0b, 0x7c, 0xb4, 0x48, 0xdf, 0x18, 0x98,
+	0xce, 0xb6, 0x82, 0x5f, 0x6b, 0x74, 0x53, 0x6a, 0xec, 0x49, 0xc3, 0x55, 0xb8, 0xf7, 0x05, 0xb4,
+	0xcb, 0x4f, 0x4b, 0x76, 0xa0, 0x7a, 0x8d, 0x73, 0x1d, 0xce, 0x62, 0x48, 0xfa, 0x50, 0xbb, 0xa1,
+	0x61, 0x86, 0x56, 0x65, 0x11, 0x58, 0x6a, 0x8a, 0xa3, 0x14, 0x3f, 0xa9, 0x3c, 0x33, 0x6c, 0x0a,
+	0xb5, 0xe3, 0x30, 0xa0, 0xe9, 0x8a, 0xdf, 0x8d, 0xb7, 0xfb, 0xbd, 0xb2, 0xc9, 0xef, 0x04, 0xb6,
+	0xe4, 0xcb, 0xab, 0x7c, 0x90, 0x63, 0xfb, 0x9f, 0x55, 0xd8, 0x12, 0xf1, 0x4a, 0x7e, 0x08, 0x9d,
+	0x10, 0x69, 0x8a, 0x6e, 0x9c, 0x88, 0x1c, 0x49, 0xe5, 0x2e, 0xad, 0xd1, 0x8e, 0x38, 0xd9, 0x4b,
+	0xa1, 0x78, 0xad, 0x70, 0xa7, 0x1d, 0x96, 0x24, 0xc1, 0x02, 0x41, 0xc4, 0x91, 0x45, 0x34, 0x74,
+	0x65, 0xfe, 0xa8, 0x9d, 0xdb, 0x39, 0xf8, 0x5c, 0xe4, 0xd1, 0x6a, 0xe8, 0x55, 0xd7, 0x43, 0xaf,
+	0x07, 0xa6, 0x74, 0x77, 0x80, 0xa9, 0xe6, 0x87, 0x42, 0x26, 0x23, 0x30, 0x67, 0xc8, 0xa9, 0x4e,
+	0x4f, 0x91, 0x45, 0x07, 0x79, 0x9a, 0x0d, 0x5f, 0x69, 0x85, 0xca, 0xa1, 0xc2, 0x6e, 0x2d, 0x89,
+	0xea, 0xeb, 0x49, 0xd4, 0x03, 0xb3, 0xf0, 0x57, 0x43, 0x05, 0x45, 0x2e, 0x0b, 0x66, 0x4e, 0x90,
+	0x05, 0xb1, 0x6f, 0x99, 0x32, 0xb6, 0xb4, 0x24, 0x78, 0x35, 0xca, 0x66, 0x2a, 0xea, 0x9a, 0x8a,
+	0x57, 0xa3, 0x6c, 0xb6, 0x1e, 0x64, 0xb0, 0x12, 0x64, 0x47, 0x50, 0xa3, 0xe2, 0x25, 0x65, 0xd6,
+	0xb5, 0x46, 0x4d, 0x79, 0x7e, 0x01, 0x38, 0x0a, 0x27, 0x43, 0xe8, 0x4c, 0x59, 0x9c, 0x25, 0xae,
+	0x14, 0x31, 0xb5, 0xda, 0xfd, 0xea, 0xb2, 0x61, 0x5b, 0xea, 0x8f, 0x95, 0xba, 0xf7, 0x53, 0xe8,
+	0x2c, 0x5d, 0x7d, 0x43, 0x8c, 0xed, 0x97, 0x63, 0xac, 0x59, 0x8e, 0xab, 0x3f, 0x19, 0xd0, 0x2e,
+	0xbf, 0xa9, 0x98, 0x3c, 0x99, 0xbc, 0x94, 0x93, 0xab, 0x8e, 0x18, 0x0a, 0x02, 0x65, 0x18, 0xe1,
+	0x2d, 0xbd, 0x08, 0xd5, 0x02, 0xa6, 0xb3, 0x00, 0x84, 0x36, 0x88, 0x3c, 0x86, 0x33, 0x8c, 0xb8,
+	0xae, 0x2f, 0x0b, 0x80, 0x7c, 0x0a, 0x10, 0xa4, 0x69, 0x86, 0xae, 0x28, 0x81, 0x92, 0x64, 0x5b,
+	0xa3, 0xde, 0x50, 0xd5, 0xc7, 0x61, 0x5e, 0x1f, 0x87, 0x93, 0xbc, 0x3e, 0x3a, 0x4d, 0x69, 0x2d,
+	0x64, 0xfb, 0x0f, 0x50, 0x57, 0xfc, 0xfa, 0x3f, 0x8d, 0xc7, 0x43, 0x30, 0xd5, 0xda, 0x81, 0xaf,
+	0x63, 0xb1, 0x21, 0xe5, 0x33, 0xdf, 0xfe, 0x9b, 0x01, 0xa6, 0x83, 0x69, 0x12, 0x47, 0x29, 0x96,
+	0xf8, 0xdf, 0x78, 0x2b, 0xff, 0x57, 0x36, 0xf2, 0x7f, 0x5e, 0x55, 0xaa, 0xa5, 0xaa, 0xd2, 0x03,
+	0x93, 0xa1, 0x1f, 0x30, 0xf4, 0xb8, 0xae, 0x40, 0x85, 0x2c, 0x74, 0xb7, 0x94, 0x09, 0xe2, 0x4a,
+	0x65, 0xa8, 0x37, 0x9d, 0x42, 0x26, 0x4f, 0xcb, 0xb4, 0xa9, 0x0a, 0xd2, 0xbe, 0xa2, 0x4d, 0x75,
+	0xdc, 0x75, 0xde, 0xb4, 0xff, 0x5a, 0x81, 0x9d, 0x55, 0xf5, 0x86, 0xc7, 0xde, 0x87, 0x9a, 0xca,
+	0x12, 0x1d, 0x29, 0x7c, 0x2d, 0x3f, 0xaa, 0x2b, 0xf9, 0xf1, 0x33, 0xe8, 0x78, 0x0c, 0x65, 0x35,
+	0x7d, 0xd7, 0x57, 0x6e, 0xe7, 0x13, 0x04, 0x44, 0x3e, 0x82, 0x1d, 0x71, 0xca, 0x04, 0xfd, 0x05,
+	0x69, 0xa9, 0xd2, 0xdb, 0xd5, 0x78, 0x41, 0x5b, 0x8f, 0x61, 0x37, 0x37, 0x5d, 0x24, 0x58, 0x7d,
+	0xc9, 0xf6, 0x34, 0xcf, 0xb3, 0x03, 0xa8, 0x5f, 0xc6, 0x6c, 0x46, 0xb9, 0xce, 0x68, 0x2d, 0x89,
+	0xb0, 0x28, 0xce, 0x2b, 0x4b, 0xbf, 0xa9, 0xc2, 0x22, 0x07, 0x45, 0x43, 0x24, 0x32, 0xb8, 0x68,
+	0x56, 0x64, 0x76, 0x9b, 0x8e, 0x99, 0x37, 0x29, 0xf6, 0xaf, 0xa1, 0xbb, 0x52, 0x9f, 0x36, 0x38,
+	0x72, 0xb1, 0x7d, 0x65, 0x69, 0xfb, 0xa5, 0x95, 0xab, 0x2b, 0x2b, 0xff, 0x06, 0x76, 0xbf, 0xa4,
+	0x91, 0x1f, 0xa2, 0x5e, 0xff, 0x98, 0x4d, 0x25, 0xe3, 0xeb, 0x76, 0xc9, 0xd5, 0x8d, 0x50, 0xc7,
+	0x69, 0x6a, 0xe4, 0xcc, 0x27, 0x0f, 0xa1, 0xc1, 0x94, 0xb5, 0x0e, 0xbc, 0x56, 0xa9, 0x80, 0x3a,
+	0xb9, 0xce, 0xfe, 0x16, 0xc8, 0xd2, 0xd2, 0xa2, 0x53, 0x9a, 0x93, 0x81, 0x08, 0x40, 0x15, 0x14,
+	0x3a, 0xb0, 0xdb, 0xe5, 0x38, 0x72, 0x0a, 0x2d, 0xe9, 0x43, 0x15, 0x19, 0xd3, 0x5b, 0x6c, 0x0b,
+	0xa3, 0x45, 0x5f, 0xea, 0x08, 0x95, 0xfd, 0x03, 0xd8, 0x3d, 0x4f, 0xd0, 0x0b, 0x68, 0x28, 0x7b,
+	0x4a, 0xb5, 0xc1, 0x11, 0xd4, 0x84, 0x93, 0xf3, 0x9c, 0x95, 0x24, 0xa6, 0xd4, 0x0a, 0xb7, 0xbf,
+	0x05, 0x4b, 0x9d, 0xeb, 0xf4, 0x4d, 0x90, 0x72, 0x8c, 0x3c, 0x3c, 0xb9, 0x42, 0xef, 0xfa, 0xbf,
+	0x78, 0xf3, 0x1b, 0x38, 0xdc, 0xb4, 0x43, 0x7e, 0xbe, 0x96, 0x27, 0x24, 0xf7, 0x32, 0xce, 0x22,
+	0xb5, 0x87, 0xe9, 0x80, 0x84, 0xbe, 0x10, 0x88, 0x78, 0x47, 0x14, 0xf3, 0x52, 0x4d, 0x7d, 0x5a,
+	0xca, 0xfd, 0x51, 0xbd, 0xdb, 0x1f, 0xdf, 0x19, 0xd0, 0x3c, 0x47, 0x9e, 0x25, 0xf2, 0x2e, 0xf7,
+	0xa1, 0x79, 0xc1, 0xe2, 0x6b, 0x64, 0x8b, 0xab, 0x98, 0x0a, 0x38, 0xf3, 0xc9, 0x53, 0xa8, 0x9f,
+	0xc4, 0xd1, 0x65, 0x30, 0x95, 0x1d, 0x76, 0x6b, 0x74, 0xa8, 0xd8, 0x45, 0xcf, 0x1d, 0x2a, 0x9d,
+	0xaa, 0x6b, 0xda, 0xb0, 0xf7, 0x29, 0xb4, 0x4a, 0xf0, 0x7f, 0xc4, 0xf9, 0xff, 0x07, 0x20, 0xd7,
+	0x56, 0x1e, 0xd8, 0x51, 0x17, 0xd1, 0x33, 0xc5, 0xc1, 0x8f, 0xa0, 0x29, 0x7a, 0x09, 0xa5, 0x26,
+	0xb0, 0x55, 0xfa, 0xdc, 0x90, 0x63, 0xfb, 0x21, 0xec, 0x9e, 0x45, 0x37, 0x34, 0x0c, 0x7c, 0xca,
+	0xf1, 0x2b, 0x9c, 0xcb, 0x0b, 0xae, 0x9d, 0xc0, 0xfe, 0x0a, 0xde, 0x7b, 0x9d, 0x37, 0xda, 0x63,
+	0x26, 0x7a, 0x6e, 0x1e, 0x60, 0x2a, 0x8d, 0xf3, 0xb6, 0xdb, 0x28, 0xb5, 0xdd, 0x4b, 0x8d, 0x7a,
+	0x65, 0xa5, 0x51, 0xb7, 0xff, 0x6c, 0x80, 0xb5, 0x61, 0xb5, 0x3c, 0x8c, 0xd7, 0xfa, 0x31, 0x63,
+	0x63, 0x3f, 0x26, 0x18, 0xe4, 0x32, 0x66, 0xb7, 0x94, 0xf9, 0x2e, 0x8f, 0x5d, 0xea, 0xf1, 0xe0,
+	0x26, 0x2f, 0x6a, 0x5d, 0xad, 0x98, 0xc4, 0xc7, 0x12, 0x16, 0x4f, 0xc6, 0x90, 0xfa, 0x6e, 0x1c,
+	0x85, 0xf3, 0x3c, 0x55, 0x05, 0xf0, 0x3a, 0x0a, 0xe7, 0x22, 0x36, 0xd5, 0x37, 0x8f, 0xd4, 0x6e,
+	0x49, 0x6d, 0x53, 0x22, 0x42, 0x6d, 0x9f, 0x43, 0x5b, 0x7f, 0xcc, 0xbc, 0xd3, 0xfb, 0xb4, 0xf5,
+	0xfb, 0xfc, 0x7b, 0x7a, 0xf8, 0x08, 0xba, 0x7a, 0xd1, 0x97, 0x81, 0x26, 0x07, 0xd1, 0x9d, 0x30,
+	0xbc, 0x0c, 0xde, 0xe8, 0xa5, 0xb5, 0x64, 0x3f, 0x83, 0x9d, 0x92, 0x69, 0xf1, 0x94, 0xd7, 0x38,
+	0x4f, 0xf3, 0x8f, 0x3c, 0x31, 0xce, 0x5f, 0xbf, 0xb2, 0x78, 0x7d, 0x1b, 0xb6, 0xf5, 0xcc, 0x17,
+	0xc8, 0xef, 0x7c, 0xd9, 0xee, 0xc2, 0x46, 0x2d, 0xfe, 0x08, 0x6a, 0x28, 0x6e, 0x5a, 0x2e, 0xce,
+	0x65, 0x0f, 0x38, 0x4a, 0xbd, 0x61, 0xc3, 0x67, 0xc5, 0x86, 0xe3, 0x4c, 0x6d, 0xf8, 0x8e, 0x6b,
+	0xd9, 0x1f, 0x14, 0xc7, 0x18, 0x67, 0xfc, 0xae, 0x68, 0x7e, 0x08, 0xbb, 0xda, 0xe8, 0x39, 0x86,
+	0xc8, 0xf1, 0x8e, 0x2b, 0x3d, 0x02, 0xb2, 0x64, 0x76, 0xd7, 0x72, 0x0f, 0xc0, 0x9c, 0x4c, 0x5e,
+	0x16, 0xda, 0x65, 0xd6, 0xb7, 0x3f, 0x83, 0xdd, 0xf3, 0xcc, 0x8f, 0xc7, 0x2c, 0xb8, 0x09, 0x42,
+	0x9c, 0xe2, 0x9d, 0xc1, 0xbe, 0xb1, 0xce, 0xda, 0x03, 0x20, 0x4b, 0xd3, 0x8b, 0x77, 0x4b, 0x33,
+	0x3f, 0xd6, 0x21, 0x2d, 0xc7, 0xf6, 0x00, 0xda, 0x13, 0x2a, 0x3a, 0x19, 0x5f, 0xd9, 0x58, 0xd0,
+	0xe0, 0x4a, 0xd6, 0x66, 0xb9, 0x68, 0x8f, 0x60, 0xff, 0x84, 0x7a, 0x57, 0x41, 0x34, 0x7d, 0x1e,
+	0xa4, 0xa2, 0x65, 0xd3, 0x33, 0x7a, 0x60, 0xfa, 0x1a, 0xd0, 0x53, 0x0a, 0xd9, 0xfe, 0x18, 0xee,
+	0x95, 0xbe, 0xa4, 0xcf, 0x39, 0xcd, 0xfd, 0xb1, 0x0f, 0xb5, 0x54, 0x48, 0x72, 0x46, 0xcd, 0x51,
+	0x82, 0xfd, 0x35, 0xec, 0x97, 0x5b, 0x0b, 0xd1, 0x58, 0xe5, 0x17, 0x97, 0x2d, 0x8f, 0x51, 0x6a,
+	0x79, 0xb4, 0xcf, 0x2a, 0x8b, 0x4a, 0xb9, 0x03, 0xd5, 0x5f, 0x7c, 0x33, 0xd1, 0xc1, 0x2e, 0x86,
+	0xf6, 0xef, 0xe0, 0xde, 0xea, 0x7a, 0x6a, 0xfb, 0xa5, 0xbe, 0xc7, 0x78, 0x97, 0xbe, 0x67, 0x43,
+	0xbc, 0x7d, 0x0c, 0xbb, 0xaf, 0xc2, 0xd8, 0xbb, 0x3e, 0x8d, 0x4a, 0xde, 0xb0, 0xa0, 0x81, 0x51,
+	0xd9, 0x19, 0xb9, 0x68, 0x7f, 0x08, 0xdd, 0x97, 0x22, 0xad, 0x5f, 0x89, 0x0f, 0xa8, 0xc2, 0x0b,
+	0x32, 0xd3, 0xb5, 0xa9, 0x12, 0x46, 0x7f, 0xa9, 0x42, 0xe3, 0xe7, 0xea, 0x67, 0x10, 0xf9, 0x1c,
+	0x3a, 0x4b, 0xd5, 0x96, 0xdc, 0x93, 0x9f, 0x75, 0xab, 0xb5, 0xbd, 0x77, 0xb0, 0x06, 0xab, 0x1d,
+	0x3e, 0x81, 0x76, 0xb9, 0x96, 0x12, 0x59, 0x37, 0xe5, 0x3f, 0xa3, 0x9e, 0x5c, 0x69, 0xbd, 0xd0,
+	0x9e, 0xc3, 0xfe, 0xa6, 0x2a, 0x47, 0x1e, 0x2c, 0x76, 0x58, 0xaf, 0xb0, 0xbd, 0xf7, 0xef, 0xd2,
+	0xe6, 0xd5, 0xb1, 0x71, 0x12, 0x22, 0x8d, 0xb2, 0xa4, 0x7c, 0x82, 0xc5, 0x90, 0x3c, 0x85, 0xce,
+	0x52, 0x25, 0x50, 0xf7, 0x5c, 0x2b, 0x0e, 0xe5, 0x29, 0x8f, 0xa0, 0x26, 0xab, 0x0f, 0xe9, 0x2c,
+	0x15, 0xb9, 0xde, 0x76, 0x21, 0xaa, 0xbd, 0xfb, 0xb0, 0x25, 0xbf, 0x68, 0x4b, 0x1b, 0xcb, 0x19,
+	0x8b, 0xd2, 0x34, 0x86, 0xbd, 0x0d, 0x15, 0x81, 0xdc, 0x17, 0x56, 0x77, 0x14, 0x9e, 0xde, 0x83,
+	0x3b, 0x94, 0x72, 0xc5, 0xd1, 0xdf, 0x0d, 0x68, 0xe4, 0xff, 0xab, 0x9e, 0xc2, 0x96, 0xa0, 0x4e,
+	0xb2, 0x57, 0x62, 0x9f, 0x9c, 0x76, 0x7b, 0xfb, 0x2b, 0xa0, 0x3a, 0xd0, 0x10, 0xaa, 0x2f, 0x90,
+	0x13, 0x52, 0x52, 0x6a, 0x0e, 0xed, 0xed, 0x2d, 0x63, 0x85, 0xfd, 0x38, 0x5b, 0xb6, 0x1f, 0x67,
+	0xeb, 0xf6, 0x05, 0xb9, 0xfd, 0x18, 0xea, 0x8a, 0x9c, 0xc8, 0xbd, 0x92, 0x7a, 0x41, 0x6b, 0xbd,
+	0x83, 0x35, 0x58, 0xdd, 0xeb, 0x1f, 0x55, 0x80, 0xf3, 0x79, 0xca, 0x71, 0xf6, 0xab, 0x00, 0x6f,
+	0xc9, 0x63, 0xe8, 0x3e, 0xc7, 0x4b, 0x9a, 0x85, 0x5c, 0x7e, 0x3e, 0x89, 0x24, 0x2c, 0x79, 0x59,
+	0x76, 0x80, 0x05, 0xc7, 0x3d, 0x82, 0xd6, 0x2b, 0xfa, 0xe6, 0xed, 0x76, 0x9f, 0x43, 0x67, 0x89,
+	0xba, 0xf4, 0x11, 0x57, 0xc9, 0xb0, 0x77, 0xb0, 0x06, 0xe7, 0xfb, 0x34, 0x34, 0xa1, 0x95, 0xf7,
+	0x90, 0xd4, 0xbf, 0x44, 0x74, 0x3f, 0x82, 0xee, 0x0a, 0x9d, 0x95, 0xed, 0xe5, 0x3f, 0xb5, 0x8d,
+	0x74, 0xf7, 0x0c, 0x76, 0x56, 0x29, 0xad, 0x3c, 0xf1, 0x50, 0xd1, 0xc8, 0x26, 0xce, 0x7b, 0x01,
+	0x3b, 0xab, 0x6c, 0x44, 0xac, 0x55, 0xd6, 0xc9, 0x39, 0xaf, 0x77, 0xb8, 0x49, 0x53, 0x24, 0x75,
+	0x99, 0x78, 0xd6, 0x92, 0x7a, 0x9d, 0x95, 0xbe, 0x07, 0xb0, 0xe0, 0x9e, 0xb2, 0xbd, 0x0c, 0x8f,
+	0x15, 0x5a, 0xba, 0xa8, 0xcb, 0x4f, 0xad, 0xef, 0xff, 0x6b, 0x00, 0x4b, 0x8d, 0xf0, 0x92, 0xa5,
+	0x16, 0x00, 0x00,
 }
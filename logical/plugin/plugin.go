@@ -7,6 +7,7 @@ import (
 	"encoding/gob"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"sync"
@@ -39,6 +40,42 @@ func init() {
 	gob.Register(&logical.StatusBadRequest{})
 }
 
+// multiplexedClient is the process of a plugin serving several mounts
+type multiplexedClient struct {
+	client *plugin.Client
+	name   string
+
+	// refs is the number of mounts using the process
+	refs int
+}
+
+var (
+	multiplexedClientsLock sync.Mutex
+
+	// multiplexedClients holds the processes of the plugins which serve
+	// several mounts, by their runner key
+	multiplexedClients = make(map[string]*multiplexedClient)
+)
+
+// runnerKey identifies the plugin processes started by runners that can be
+// shared by their mounts
+func runnerKey(r *pluginutil.PluginRunner) string {
+	return fmt.Sprintf("%s\x00%s\x00%s\x00%s\x00%x", r.Name, r.Version, r.Command, strings.Join(r.Args, "\x00"), r.Sha256)
+}
+
+// ForgetMultiplexedClients makes the mounts of the plugin with the given name
+// set up from now on start a new process, instead of sharing the one serving
+// its current mounts. That process is killed once none of them uses it.
+func ForgetMultiplexedClients(name string) {
+	multiplexedClientsLock.Lock()
+	defer multiplexedClientsLock.Unlock()
+	for key, mc := range multiplexedClients {
+		if mc.name == name {
+			delete(multiplexedClients, key)
+		}
+	}
+}
+
 // BackendPluginClient is a wrapper around backendPluginClient
 // that also contains its plugin.Client instance. It's primarily
 // used to cleanly kill the client on Cleanup()
@@ -47,12 +84,85 @@ type BackendPluginClient struct {
 	sync.Mutex
 
 	logical.Backend
+
+	// grpcBackend is the gRPC client of the backend, nil over net/rpc
+	grpcBackend *backendGRPCPluginClient
+
+	// runnerKey is the key the process is shared with in
+	// multiplexedClients, if the plugin serves several mounts. The process
+	// is shared through multiplexed once it is set.
+	runnerKey   string
+	runnerName  string
+	multiplexed *multiplexedClient
+	released    bool
+}
+
+// Setup sets the backend up and, if the plugin serves several mounts from
+// one process, makes the process available to the other mounts of the
+// plugin
+func (b *BackendPluginClient) Setup(ctx context.Context, config *logical.BackendConfig) error {
+	if err := b.Backend.Setup(ctx, config); err != nil {
+		b.release()
+		return err
+	}
+
+	if b.multiplexed != nil || b.runnerKey == "" || b.grpcBackend == nil || !b.grpcBackend.multiplexed {
+		return nil
+	}
+
+	multiplexedClientsLock.Lock()
+	defer multiplexedClientsLock.Unlock()
+	b.multiplexed = &multiplexedClient{
+		client: b.client,
+		name:   b.runnerName,
+		refs:   1,
+	}
+	if _, ok := multiplexedClients[b.runnerKey]; !ok {
+		multiplexedClients[b.runnerKey] = b.multiplexed
+	}
+	return nil
+}
+
+// OperationProperties returns the properties declared by the plugin
+func (b *BackendPluginClient) OperationProperties(path string, op logical.Operation) logical.OperationProperties {
+	propsBackend, ok := b.Backend.(logical.OperationPropertiesBackend)
+	if !ok {
+		return logical.OperationProperties{}
+	}
+	return propsBackend.OperationProperties(path, op)
 }
 
 // Cleanup calls the RPC client's Cleanup() func and also calls
-// the go-plugin's client Kill() func
+// the go-plugin's client Kill() func, once no other mount uses
+// the plugin process
 func (b *BackendPluginClient) Cleanup(ctx context.Context) {
 	b.Backend.Cleanup(ctx)
+	b.release()
+}
+
+// release drops the reference of the backend to its plugin process, killing
+// the process if no other mount uses it
+func (b *BackendPluginClient) release() {
+	b.Lock()
+	defer b.Unlock()
+	if b.released {
+		return
+	}
+	b.released = true
+
+	if mc := b.multiplexed; mc != nil {
+		multiplexedClientsLock.Lock()
+		mc.refs--
+		if mc.refs > 0 {
+			multiplexedClientsLock.Unlock()
+			return
+		}
+		if multiplexedClients[b.runnerKey] == mc {
+			delete(multiplexedClients, b.runnerKey)
+		}
+		multiplexedClientsLock.Unlock()
+	}
+
 	b.client.Kill()
 }
 
@@ -94,65 +204,92 @@ func NewBackend(ctx context.Context, pluginName string, sys pluginutil.LookRunne
 }
 
 func newPluginClient(ctx context.Context, sys pluginutil.RunnerUtil, pluginRunner *pluginutil.PluginRunner, logger log.Logger, isMetadataMode bool) (logical.Backend, error) {
-	// pluginMap is the map of plugins we can dispense.
-	pluginMap := map[string]plugin.Plugin{
-		"backend": &BackendPlugin{
-			metadataMode: isMetadataMode,
-		},
+	// Mounts share the process of a plugin serving several mounts, which is
+	// never the case in metadata mode
+	b := &BackendPluginClient{}
+	if !isMetadataMode {
+		b.runnerKey = runnerKey(pluginRunner)
+		b.runnerName = pluginRunner.Name
+
+		multiplexedClientsLock.Lock()
+		mc, ok := multiplexedClients[b.runnerKey]
+		if ok && mc.client.Exited() {
+			delete(multiplexedClients, b.runnerKey)
+			ok = false
+		}
+		if ok {
+			mc.refs++
+			b.client = mc.client
+			b.multiplexed = mc
+		}
+		multiplexedClientsLock.Unlock()
 	}
 
-	var client *plugin.Client
-	var err error
-	if isMetadataMode {
-		client, err = pluginRunner.RunMetadataMode(ctx, sys, pluginMap, handshakeConfig, []string{}, logger)
-	} else {
-		client, err = pluginRunner.Run(ctx, sys, pluginMap, handshakeConfig, []string{}, logger)
+	if b.client == nil {
+		// pluginMap is the map of plugins we can dispense.
+		pluginMap := map[string]plugin.Plugin{
+			"backend": &BackendPlugin{
+				metadataMode: isMetadataMode,
+			},
+		}
+
+		var err error
+		if isMetadataMode {
+			b.client, err = pluginRunner.RunMetadataMode(ctx, sys, pluginMap, handshakeConfig, []string{}, logger)
+		} else {
+			b.client, err = pluginRunner.Run(ctx, sys, pluginMap, handshakeConfig, []string{}, logger)
+		}
+		if err != nil {
+			return nil, err
+		}
 	}
+
+	backend, transport, err := b.dispense()
 	if err != nil {
+		b.release()
 		return nil, err
 	}
 
+	// Wrap the backend in a tracing middleware
+	if logger.IsTrace() {
+		backend = &backendTracingMiddleware{
+			logger:    logger,
+			transport: transport,
+			typeStr:   pluginRunner.Name,
+			next:      backend,
+		}
+	}
+
+	b.Backend = backend
+	return b, nil
+}
+
+// dispense requests the backend from the plugin process, returning it with
+// the name of its transport
+func (b *BackendPluginClient) dispense() (logical.Backend, string, error) {
 	// Connect via RPC
-	rpcClient, err := client.Client()
+	rpcClient, err := b.client.Client()
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	// Request the plugin
 	raw, err := rpcClient.Dispense("backend")
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
-	var backend logical.Backend
-	var transport string
 	// We should have a logical backend type now. This feels like a normal interface
 	// implementation but is in fact over an RPC connection.
 	switch raw.(type) {
 	case *backendPluginClient:
-		backend = raw.(*backendPluginClient)
-		transport = "netRPC"
+		return raw.(*backendPluginClient), "netRPC", nil
 	case *backendGRPCPluginClient:
-		backend = raw.(*backendGRPCPluginClient)
-		transport = "gRPC"
+		b.grpcBackend = raw.(*backendGRPCPluginClient)
+		return b.grpcBackend, "gRPC", nil
 	default:
-		return nil, errors.New("Unsupported plugin client type")
-	}
-
-	// Wrap the backend in a tracing middleware
-	if logger.IsTrace() {
-		backend = &backendTracingMiddleware{
-			logger:    logger,
-			transport: transport,
-			typeStr:   pluginRunner.Name,
-			next:      backend,
-		}
+		return nil, "", errors.New("Unsupported plugin client type")
 	}
-
-	return &BackendPluginClient{
-		client:  client,
-		Backend: backend,
-	}, nil
 }
 
 // wrapError takes a generic error type and makes it usable with the plugin
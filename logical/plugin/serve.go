@@ -20,6 +20,12 @@ type ServeOpts struct {
 	BackendFactoryFunc logical.Factory
 	TLSProviderFunc    TLSProdiverFunc
 	Logger             hclog.Logger
+
+	// Multiplexing makes the plugin process serve all the mounts using the
+	// plugin, calling BackendFactoryFunc once for each of them, instead of
+	// Vault starting a process for each mount. The backends must not share
+	// any state for this. It is only supported over gRPC.
+	Multiplexing bool
 }
 
 // Serve is a helper function used to serve a backend plugin. This
@@ -37,8 +43,9 @@ func Serve(opts *ServeOpts) error {
 	// pluginMap is the map of plugins we can dispense.
 	var pluginMap = map[string]plugin.Plugin{
 		"backend": &BackendPlugin{
-			Factory: opts.BackendFactoryFunc,
-			Logger:  logger,
+			Factory:      opts.BackendFactoryFunc,
+			Logger:       logger,
+			Multiplexing: opts.Multiplexing,
 		},
 	}
 
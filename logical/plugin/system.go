@@ -109,6 +109,22 @@ func (s *SystemViewClient) LookupPlugin(ctx context.Context, name string) (*plug
 	return nil, fmt.Errorf("cannot call LookupPlugin from a plugin backend")
 }
 
+func (s *SystemViewClient) EntityInfo(entityID string) (*logical.Entity, error) {
+	return nil, fmt.Errorf("cannot call EntityInfo from a plugin backend")
+}
+
+func (s *SystemViewClient) GeneratePasswordFromPolicy(ctx context.Context, policyName string) (string, error) {
+	return "", fmt.Errorf("cannot call GeneratePasswordFromPolicy from a plugin backend")
+}
+
+func (s *SystemViewClient) SendEvent(ctx context.Context, eventType, path string, metadata map[string]string) error {
+	return fmt.Errorf("cannot call SendEvent from a plugin backend")
+}
+
+func (s *SystemViewClient) AuditInternalRequest(ctx context.Context, req *logical.Request, resp *logical.Response) error {
+	return fmt.Errorf("cannot call AuditInternalRequest from a plugin backend")
+}
+
 func (s *SystemViewClient) MlockEnabled() bool {
 	var reply MlockEnabledReply
 	err := s.client.Call("Plugin.MlockEnabled", new(interface{}), &reply)
@@ -1,6 +1,7 @@
 package logical
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strings"
@@ -44,6 +45,10 @@ func (r *RequestWrapInfo) SentinelKeys() []string {
 	}
 }
 
+// MFACreds maps the names of MFA methods to the credentials supplied for them,
+// such as TOTP passcodes
+type MFACreds map[string][]string
+
 // Request is a struct that stores the parameters and context of a request
 // being made to Vault. It is used to abstract the details of the higher level
 // request protocol from the handlers.
@@ -136,14 +141,36 @@ type Request struct {
 	// soft-mandatory Sentinel policies
 	PolicyOverride bool `json:"policy_override" structs:"policy_override" mapstructure:"policy_override"`
 
+	// MFACreds holds the credentials of the login MFA methods supplied with
+	// the request, keyed by the name of the method
+	MFACreds MFACreds `json:"mfa_creds" structs:"mfa_creds" mapstructure:"mfa_creds" sentinel:""`
+
 	// Whether the request is unauthenticated, as in, had no client token
 	// attached. Useful in some situations where the client token is not made
 	// accessible.
 	Unauthenticated bool `json:"unauthenticated" structs:"unauthenticated" mapstructure:"unauthenticated"`
 
+	// CacheBypass indicates that the reads of the request should bypass the
+	// physical cache and go to the storage backend
+	CacheBypass bool `json:"cache_bypass" structs:"cache_bypass" mapstructure:"cache_bypass"`
+
+	// IndexState is the index state returned by an earlier write. The reads
+	// of the request wait for the storage backend to reflect the writes made
+	// up to it.
+	IndexState string `json:"index_state" structs:"index_state" mapstructure:"index_state" sentinel:""`
+
 	// For replication, contains the last WAL on the remote side after handling
 	// the request, used for best-effort avoidance of stale read-after-write
 	lastRemoteWAL uint64 `sentinel:""`
+
+	// The keys of the request and response data which the audit devices do
+	// not HMAC for the mount of the request, in addition to their own
+	auditNonHMACRequestKeys  []string `sentinel:""`
+	auditNonHMACResponseKeys []string `sentinel:""`
+
+	// ctx is the context of the client making the request, cancelled when
+	// the client goes away
+	ctx context.Context
 }
 
 // Get returns a data field and guards for nil Data
@@ -161,6 +188,21 @@ func (r *Request) GetString(key string) string {
 	return s
 }
 
+// Context returns the context of the client making the request. It is never
+// nil: it defaults to the background context.
+func (r *Request) Context() context.Context {
+	if r.ctx != nil {
+		return r.ctx
+	}
+	return context.Background()
+}
+
+// SetContext sets the context of the client making the request. The core
+// cancels the handling of the request once it is done.
+func (r *Request) SetContext(ctx context.Context) {
+	r.ctx = ctx
+}
+
 func (r *Request) GoString() string {
 	return fmt.Sprintf("*%#v", *r)
 }
@@ -199,6 +241,17 @@ func (r *Request) SetLastRemoteWAL(last uint64) {
 	r.lastRemoteWAL = last
 }
 
+// AuditNonHMACKeys returns the keys of the request and response data which
+// the audit devices do not HMAC for the mount of the request
+func (r *Request) AuditNonHMACKeys() (request, response []string) {
+	return r.auditNonHMACRequestKeys, r.auditNonHMACResponseKeys
+}
+
+func (r *Request) SetAuditNonHMACKeys(request, response []string) {
+	r.auditNonHMACRequestKeys = request
+	r.auditNonHMACResponseKeys = response
+}
+
 // RenewRequest creates the structure of the renew request.
 func RenewRequest(path string, secret *Secret, data map[string]interface{}) *Request {
 	return &Request{
@@ -249,6 +302,7 @@ const (
 	UpdateOperation                   = "update"
 	DeleteOperation                   = "delete"
 	ListOperation                     = "list"
+	PatchOperation                    = "patch"
 	HelpOperation                     = "help"
 	AliasLookaheadOperation           = "alias-lookahead"
 
@@ -276,4 +330,16 @@ var (
 	// ErrMultiAuthzPending is returned if the the request needs more
 	// authorizations
 	ErrMultiAuthzPending = errors.New("request needs further approval")
+
+	// ErrRateLimitQuotaExceeded is returned if the request is rejected by a
+	// rate limit quota
+	ErrRateLimitQuotaExceeded = errors.New("rate limit quota exceeded")
+
+	// ErrLeaseCountQuotaExceeded is returned if the request would create a
+	// lease beyond the maximum of a lease count quota
+	ErrLeaseCountQuotaExceeded = errors.New("lease count quota exceeded")
+
+	// ErrInFlightRequestsExceeded is returned if the request is rejected
+	// because too many requests are already being handled
+	ErrInFlightRequestsExceeded = errors.New("too many in-flight requests")
 )
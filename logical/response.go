@@ -23,6 +23,16 @@ const (
 	// This can only be specified for non-secrets, and should should be similarly
 	// avoided like the HTTPContentType. The value must be an integer.
 	HTTPStatusCode = "http_status_code"
+
+	// HTTPContentDisposition is the value of the Content-Disposition header
+	// sent along with the HTTPRawBody, e.g. to have browsers download the
+	// body as a file. This is optional. The value must be a string.
+	HTTPContentDisposition = "http_content_disposition"
+
+	// HTTPRawHeaders are additional headers sent along with the HTTPRawBody,
+	// for specifications that carry data in headers. This is optional. The
+	// value must be a map[string][]string.
+	HTTPRawHeaders = "http_raw_headers"
 )
 
 // Response is a struct that stores the response of a request.
@@ -53,6 +63,10 @@ type Response struct {
 
 	// Information for wrapping the response in a cubbyhole
 	WrapInfo *wrapping.ResponseWrapInfo `json:"wrap_info" structs:"wrap_info" mapstructure:"wrap_info"`
+
+	// Headers are the HTTP headers of the response. Only the ones allowed by
+	// the allowed_response_headers of the mount are sent to the client.
+	Headers map[string][]string `json:"headers" structs:"headers" mapstructure:"headers"`
 }
 
 // AddWarning adds a warning into the response's warning list
@@ -95,6 +95,19 @@ func RespondErrorCommon(req *Request, resp *Response, err error) (int, error) {
 			statusCode = http.StatusNotFound
 		case errwrap.Contains(err, ErrInvalidRequest.Error()):
 			statusCode = http.StatusBadRequest
+		case errwrap.Contains(err, ErrRateLimitQuotaExceeded.Error()):
+			statusCode = http.StatusTooManyRequests
+		case errwrap.Contains(err, ErrLeaseCountQuotaExceeded.Error()):
+			statusCode = http.StatusForbidden
+		case errwrap.Contains(err, ErrInFlightRequestsExceeded.Error()):
+			statusCode = http.StatusServiceUnavailable
+		case errwrap.Contains(err, ErrMultiAuthzPending.Error()):
+			statusCode = http.StatusForbidden
+		case errwrap.Contains(err, consts.ErrDRSecondary.Error()):
+			statusCode = http.StatusBadRequest
+		case errwrap.Contains(err, consts.ErrIndexStateNotReached.Error()),
+			errwrap.Contains(err, consts.ErrUnknownIndexState.Error()):
+			statusCode = http.StatusPreconditionFailed
 		}
 	}
 
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/helper/strutil"
 )
 
 // ErrReadOnly is returned when a backend does not support
@@ -26,6 +27,29 @@ type Storage interface {
 	Delete(context.Context, string) error
 }
 
+// PaginatedStorage is an optional interface that a Storage can implement to
+// list a page of the keys under a prefix without listing all of them.
+type PaginatedStorage interface {
+	// ListPage lists the keys under the prefix like List, in lexicographic
+	// order, starting after the given key and returning at most limit keys,
+	// or all of them if limit is not positive.
+	ListPage(ctx context.Context, prefix, after string, limit int) ([]string, error)
+}
+
+// ListPage lists a page of the keys under the prefix, natively if the storage
+// is a PaginatedStorage, and by sorting the keys listed otherwise
+func ListPage(ctx context.Context, s Storage, prefix, after string, limit int) ([]string, error) {
+	if p, ok := s.(PaginatedStorage); ok {
+		return p.ListPage(ctx, prefix, after, limit)
+	}
+
+	keys, err := s.List(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	return strutil.StrListPage(keys, after, limit), nil
+}
+
 // StorageEntry is the entry for an item in a Storage implementation.
 type StorageEntry struct {
 	Key      string
@@ -86,6 +86,31 @@ func (s *InmemStorage) List(ctx context.Context, prefix string) ([]string, error
 
 }
 
+func (s *InmemStorage) ListPage(ctx context.Context, prefix, after string, limit int) ([]string, error) {
+	s.once.Do(s.init)
+
+	s.RLock()
+	defer s.RUnlock()
+
+	// The keys are walked in lexicographic order, so the keys of a
+	// sub-prefix are contiguous and listed in order
+	var out []string
+	walkFn := func(s string, v interface{}) bool {
+		trimmed := strings.TrimPrefix(s, prefix)
+		if sep := strings.Index(trimmed, "/"); sep != -1 {
+			trimmed = trimmed[:sep+1]
+		}
+		if trimmed <= after || (len(out) > 0 && out[len(out)-1] == trimmed) {
+			return false
+		}
+		out = append(out, trimmed)
+		return limit > 0 && len(out) >= limit
+	}
+	s.root.WalkPrefix(prefix, walkFn)
+
+	return out, nil
+}
+
 func (s *InmemStorage) init() {
 	s.root = radix.New()
 }
@@ -3,10 +3,12 @@ package logical
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/hashicorp/vault/helper/consts"
 	"github.com/hashicorp/vault/helper/pluginutil"
+	"github.com/hashicorp/vault/helper/random"
 	"github.com/hashicorp/vault/helper/wrapping"
 )
 
@@ -57,6 +59,24 @@ type SystemView interface {
 	// MlockEnabled returns the configuration setting for enabling mlock on
 	// plugins.
 	MlockEnabled() bool
+
+	// EntityInfo returns the entity with the given ID, or nil if there is no
+	// such entity.
+	EntityInfo(entityID string) (*Entity, error)
+
+	// GeneratePasswordFromPolicy generates a password from the password
+	// policy with the given name, or returns an error if there is no such
+	// policy.
+	GeneratePasswordFromPolicy(ctx context.Context, policyName string) (string, error)
+
+	// SendEvent publishes an event of the given type about the path, relative
+	// to the mount, to the subscribers of the events of Vault.
+	SendEvent(ctx context.Context, eventType, path string, metadata map[string]string) error
+
+	// AuditInternalRequest logs an operation the backend performed on its
+	// own, such as from its periodic function, to the audit devices as the
+	// request and the response to the path, relative to the mount.
+	AuditInternalRequest(ctx context.Context, req *Request, resp *Response) error
 }
 
 type StaticSystemView struct {
@@ -69,6 +89,8 @@ type StaticSystemView struct {
 	EnableMlock         bool
 	LocalMountVal       bool
 	ReplicationStateVal consts.ReplicationState
+	EntityVal           *Entity
+	PasswordPolicies    map[string]string
 }
 
 func (d StaticSystemView) DefaultLeaseTTL() time.Duration {
@@ -110,3 +132,30 @@ func (d StaticSystemView) LookupPlugin(_ context.Context, name string) (*pluginu
 func (d StaticSystemView) MlockEnabled() bool {
 	return d.EnableMlock
 }
+
+func (d StaticSystemView) EntityInfo(entityID string) (*Entity, error) {
+	if d.EntityVal == nil || d.EntityVal.ID != entityID {
+		return nil, nil
+	}
+	return d.EntityVal, nil
+}
+
+func (d StaticSystemView) GeneratePasswordFromPolicy(_ context.Context, policyName string) (string, error) {
+	raw, ok := d.PasswordPolicies[policyName]
+	if !ok {
+		return "", fmt.Errorf("password policy %q not found", policyName)
+	}
+	policy, err := random.ParsePasswordPolicy(raw)
+	if err != nil {
+		return "", err
+	}
+	return policy.Generate()
+}
+
+func (d StaticSystemView) SendEvent(_ context.Context, eventType, path string, metadata map[string]string) error {
+	return nil
+}
+
+func (d StaticSystemView) AuditInternalRequest(_ context.Context, req *Request, resp *Response) error {
+	return nil
+}
@@ -21,9 +21,40 @@ const (
 type Cache struct {
 	backend Backend
 	lru     *lru.TwoQueueCache
+	size    int
 	locks   []*locksutil.LockEntry
 	logger  log.Logger
 	enabled *uint32
+	hits    *uint64
+	misses  *uint64
+}
+
+// CacheStats is the usage of the cache since it was created
+type CacheStats struct {
+	Enabled bool
+	Size    int
+	Entries int
+	Hits    uint64
+	Misses  uint64
+}
+
+// cacheBypassContextKey is the key of the context value set by
+// ContextWithCacheBypass
+type cacheBypassContextKey struct{}
+
+// ContextWithCacheBypass returns a context whose reads bypass the cache and
+// go to the underlying backend. Writes still go through the cache so that it
+// stays consistent.
+func ContextWithCacheBypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheBypassContextKey{}, true)
+}
+
+func cacheBypassFromContext(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	bypass, _ := ctx.Value(cacheBypassContextKey{}).(bool)
+	return bypass
 }
 
 // TransactionalCache is a Cache that wraps the physical that is transactional
@@ -52,10 +83,13 @@ func NewCache(b Backend, size int, logger log.Logger) *Cache {
 	c := &Cache{
 		backend: b,
 		lru:     cache,
+		size:    size,
 		locks:   locksutil.CreateLocks(),
 		logger:  logger,
 		// This fails safe.
 		enabled: new(uint32),
+		hits:    new(uint64),
+		misses:  new(uint64),
 	}
 	return c
 }
@@ -78,6 +112,17 @@ func (c *Cache) SetEnabled(enabled bool) {
 	atomic.StoreUint32(c.enabled, 0)
 }
 
+// Stats returns the usage of the cache
+func (c *Cache) Stats() *CacheStats {
+	return &CacheStats{
+		Enabled: atomic.LoadUint32(c.enabled) == 1,
+		Size:    c.size,
+		Entries: c.lru.Len(),
+		Hits:    atomic.LoadUint64(c.hits),
+		Misses:  atomic.LoadUint64(c.misses),
+	}
+}
+
 // Purge is used to clear the cache
 func (c *Cache) Purge(ctx context.Context) {
 	// Lock the world
@@ -106,7 +151,7 @@ func (c *Cache) Put(ctx context.Context, entry *Entry) error {
 }
 
 func (c *Cache) Get(ctx context.Context, key string) (*Entry, error) {
-	if atomic.LoadUint32(c.enabled) == 0 {
+	if atomic.LoadUint32(c.enabled) == 0 || cacheBypassFromContext(ctx) {
 		return c.backend.Get(ctx, key)
 	}
 
@@ -116,11 +161,13 @@ func (c *Cache) Get(ctx context.Context, key string) (*Entry, error) {
 
 	// Check the LRU first
 	if raw, ok := c.lru.Get(key); ok {
+		atomic.AddUint64(c.hits, 1)
 		if raw == nil {
 			return nil, nil
 		}
 		return raw.(*Entry), nil
 	}
+	atomic.AddUint64(c.misses, 1)
 
 	// Read from the underlying backend
 	ent, err := c.backend.Get(ctx, key)
@@ -159,6 +206,10 @@ func (c *Cache) List(ctx context.Context, prefix string) ([]string, error) {
 	return c.backend.List(ctx, prefix)
 }
 
+func (c *Cache) ListPage(ctx context.Context, prefix, after string, limit int) ([]string, error) {
+	return ListPage(ctx, c.backend, prefix, after, limit)
+}
+
 func (c *TransactionalCache) Transaction(ctx context.Context, txns []*TxnEntry) error {
 	// Collect keys that need to be locked
 	var keys []string
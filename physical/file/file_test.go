@@ -236,4 +236,5 @@ func TestFileBackend(t *testing.T) {
 	}
 
 	physical.ExerciseBackend_ListPrefix(t, b)
+	physical.ExerciseBackend_ListPage(t, b)
 }
@@ -0,0 +1,382 @@
+package physical
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/helper/consts"
+	log "github.com/mgutz/logxi/v1"
+)
+
+var (
+	// IndexWriteRetention is how long the writes are tracked. Eventually
+	// consistent backends are expected to have reflected a write by then.
+	IndexWriteRetention = 15 * time.Second
+
+	// IndexWaitTimeout is the maximum time a read waits for the backend to
+	// reflect the writes of its required index state
+	IndexWaitTimeout = 2 * time.Second
+
+	indexWaitMinBackoff = 10 * time.Millisecond
+	indexWaitMaxBackoff = 250 * time.Millisecond
+)
+
+// IndexState identifies the writes made through an IndexTracker up to an
+// index. The epoch identifies the tracker, since the indexes of different
+// trackers do not compare.
+type IndexState struct {
+	Epoch string
+	Index uint64
+}
+
+// String returns the encoded state, as given to clients
+func (s *IndexState) String() string {
+	return fmt.Sprintf("%s:%d", s.Epoch, s.Index)
+}
+
+// ParseIndexState parses an encoded index state
+func ParseIndexState(value string) (*IndexState, error) {
+	idx := strings.LastIndex(value, ":")
+	if idx <= 0 {
+		return nil, fmt.Errorf("invalid index state %q", value)
+	}
+	index, err := strconv.ParseUint(value[idx+1:], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid index state %q", value)
+	}
+	return &IndexState{
+		Epoch: value[:idx],
+		Index: index,
+	}, nil
+}
+
+// indexStateContextKey is the key of the context value set by
+// ContextWithIndexState
+type indexStateContextKey struct{}
+
+// ContextWithIndexState returns a context whose reads wait for the backend to
+// reflect the writes made up to the given state
+func ContextWithIndexState(ctx context.Context, state *IndexState) context.Context {
+	return context.WithValue(ctx, indexStateContextKey{}, state)
+}
+
+func indexStateFromContext(ctx context.Context) *IndexState {
+	if ctx == nil {
+		return nil
+	}
+	state, _ := ctx.Value(indexStateContextKey{}).(*IndexState)
+	return state
+}
+
+// trackedWrite is a write of a key made through an IndexTracker
+type trackedWrite struct {
+	key   string
+	index uint64
+	time  time.Time
+
+	// hash is the SHA256 of the value written, nil if the key was deleted
+	hash []byte
+}
+
+// IndexTracker is used to wrap an eventually consistent physical backend
+// and give read-your-writes consistency to the requests which ask for it.
+// Each write increments the index and is remembered for a while; the reads
+// of a request carrying an index state retry until the backend reflects the
+// writes made up to that state, or any write of the same keys made after it.
+type IndexTracker struct {
+	backend Backend
+	logger  log.Logger
+	epoch   string
+
+	l     sync.Mutex
+	index uint64
+
+	// writes holds the tracked writes of each key, oldest first
+	writes map[string][]*trackedWrite
+
+	// order holds the writes in the order they were made, for pruning
+	order []*trackedWrite
+}
+
+// TransactionalIndexTracker is an IndexTracker that wraps the physical that
+// is transactional
+type TransactionalIndexTracker struct {
+	*IndexTracker
+	transactional Transactional
+}
+
+// Verify IndexTracker satisfies the correct interfaces
+var _ Backend = (*IndexTracker)(nil)
+var _ Transactional = (*TransactionalIndexTracker)(nil)
+
+// NewIndexTracker returns a wrapped physical backend tracking its writes
+func NewIndexTracker(b Backend, logger log.Logger) *IndexTracker {
+	return &IndexTracker{
+		backend: b,
+		logger:  logger,
+		epoch:   strconv.FormatInt(time.Now().UnixNano(), 36),
+		writes:  make(map[string][]*trackedWrite),
+	}
+}
+
+// NewTransactionalIndexTracker creates a new transactional IndexTracker
+func NewTransactionalIndexTracker(b Backend, logger log.Logger) *TransactionalIndexTracker {
+	return &TransactionalIndexTracker{
+		IndexTracker:  NewIndexTracker(b, logger),
+		transactional: b.(Transactional),
+	}
+}
+
+// State returns the index state of the writes made so far
+func (t *IndexTracker) State() *IndexState {
+	t.l.Lock()
+	defer t.l.Unlock()
+	return &IndexState{
+		Epoch: t.epoch,
+		Index: t.index,
+	}
+}
+
+// CheckState returns an error if the state was not issued by this tracker
+func (t *IndexTracker) CheckState(state *IndexState) error {
+	t.l.Lock()
+	defer t.l.Unlock()
+	if state.Epoch != t.epoch || state.Index > t.index {
+		return consts.ErrUnknownIndexState
+	}
+	return nil
+}
+
+func (t *IndexTracker) Put(ctx context.Context, entry *Entry) error {
+	if err := t.backend.Put(ctx, entry); err != nil {
+		return err
+	}
+	t.record(entry.Key, entry.Value, false)
+	return nil
+}
+
+func (t *IndexTracker) Delete(ctx context.Context, key string) error {
+	if err := t.backend.Delete(ctx, key); err != nil {
+		return err
+	}
+	t.record(key, nil, true)
+	return nil
+}
+
+func (t *IndexTracker) Get(ctx context.Context, key string) (*Entry, error) {
+	state := indexStateFromContext(ctx)
+	if state == nil {
+		return t.backend.Get(ctx, key)
+	}
+
+	var entry *Entry
+	err := t.wait(ctx, state, func() (bool, error) {
+		var err error
+		entry, err = t.backend.Get(ctx, key)
+		if err != nil {
+			return false, err
+		}
+
+		t.l.Lock()
+		writes := t.required(key, state)
+		t.l.Unlock()
+		if len(writes) == 0 {
+			return true, nil
+		}
+
+		var hash []byte
+		if entry != nil {
+			sum := sha256.Sum256(entry.Value)
+			hash = sum[:]
+		}
+		for _, write := range writes {
+			switch {
+			case write.hash == nil && entry == nil:
+				return true, nil
+			case write.hash != nil && entry != nil && bytes.Equal(hash, write.hash):
+				return true, nil
+			}
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func (t *IndexTracker) List(ctx context.Context, prefix string) ([]string, error) {
+	state := indexStateFromContext(ctx)
+	if state == nil {
+		return t.backend.List(ctx, prefix)
+	}
+
+	var keys []string
+	err := t.wait(ctx, state, func() (bool, error) {
+		var err error
+		keys, err = t.backend.List(ctx, prefix)
+		if err != nil {
+			return false, err
+		}
+
+		listed := make(map[string]struct{}, len(keys))
+		for _, key := range keys {
+			listed[key] = struct{}{}
+		}
+
+		t.l.Lock()
+		defer t.l.Unlock()
+		for key := range t.writes {
+			if !strings.HasPrefix(key, prefix) {
+				continue
+			}
+
+			var put, deleted bool
+			for _, write := range t.required(key, state) {
+				if write.hash == nil {
+					deleted = true
+				} else {
+					put = true
+				}
+			}
+
+			// A written key lists itself or its top-level folder, while a
+			// deleted key only ensures that it is not listed itself since
+			// its folder may hold other keys
+			child := strings.TrimPrefix(key, prefix)
+			idx := strings.Index(child, "/")
+			_, ok := listed[child]
+			switch {
+			case put == deleted:
+				// The key was not written up to the state, or both written
+				// and deleted from it on, either of which may be listed
+			case deleted:
+				if idx == -1 && ok {
+					return false, nil
+				}
+			case idx != -1:
+				if _, ok := listed[child[:idx+1]]; !ok {
+					return false, nil
+				}
+			case !ok:
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (t *TransactionalIndexTracker) Transaction(ctx context.Context, txns []*TxnEntry) error {
+	if err := t.transactional.Transaction(ctx, txns); err != nil {
+		return err
+	}
+	for _, txn := range txns {
+		switch txn.Operation {
+		case PutOperation:
+			t.record(txn.Entry.Key, txn.Entry.Value, false)
+		case DeleteOperation:
+			t.record(txn.Entry.Key, nil, true)
+		}
+	}
+	return nil
+}
+
+// record tracks a write, pruning the writes past their retention
+func (t *IndexTracker) record(key string, value []byte, deleted bool) {
+	now := time.Now()
+	write := &trackedWrite{
+		key:  key,
+		time: now,
+	}
+	if !deleted {
+		hash := sha256.Sum256(value)
+		write.hash = hash[:]
+	}
+
+	t.l.Lock()
+	defer t.l.Unlock()
+
+	t.index++
+	write.index = t.index
+	t.writes[key] = append(t.writes[key], write)
+	t.order = append(t.order, write)
+
+	// The writes are pruned in order, so a pruned write is the oldest of its
+	// key
+	var pruned int
+	for _, old := range t.order {
+		if now.Sub(old.time) < IndexWriteRetention {
+			break
+		}
+		if writes := t.writes[old.key]; len(writes) > 1 {
+			t.writes[old.key] = writes[1:]
+		} else {
+			delete(t.writes, old.key)
+		}
+		pruned++
+	}
+	if pruned > 0 {
+		t.order = append(t.order[:0:0], t.order[pruned:]...)
+	}
+}
+
+// required returns the writes of the key the backend has to reflect one of
+// for the state: the last write made up to the state, and the writes made
+// after it since they overwrite it. It returns nil if no write of the key up
+// to the state is tracked. The lock must be held.
+func (t *IndexTracker) required(key string, state *IndexState) []*trackedWrite {
+	writes := t.writes[key]
+	for i := len(writes) - 1; i >= 0; i-- {
+		if writes[i].index <= state.Index {
+			return writes[i:]
+		}
+	}
+	return nil
+}
+
+// wait calls check with a backoff until it reports that the backend reflects
+// the tracked writes, or fails if it does not within IndexWaitTimeout
+func (t *IndexTracker) wait(ctx context.Context, state *IndexState, check func() (bool, error)) error {
+	if err := t.CheckState(state); err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(IndexWaitTimeout)
+	backoff := indexWaitMinBackoff
+	for {
+		ok, err := check()
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+		if time.Now().Add(backoff).After(deadline) {
+			if t.logger.IsDebug() {
+				t.logger.Debug("physical/index: backend did not reach the required index state", "state", state.String())
+			}
+			return consts.ErrIndexStateNotReached
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > indexWaitMaxBackoff {
+			backoff = indexWaitMaxBackoff
+		}
+	}
+}
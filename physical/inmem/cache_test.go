@@ -19,6 +19,7 @@ func TestCache(t *testing.T) {
 	cache := physical.NewCache(inm, 0, logger)
 	physical.ExerciseBackend(t, cache)
 	physical.ExerciseBackend_ListPrefix(t, cache)
+	physical.ExerciseBackend_ListPage(t, cache)
 }
 
 func TestCache_Purge(t *testing.T) {
@@ -267,3 +268,72 @@ func TestCache_Disable(t *testing.T) {
 	cache.SetEnabled(false)
 	disabledTests()
 }
+
+func TestCache_Stats(t *testing.T) {
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+
+	inm, err := NewInmem(nil, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache := physical.NewCache(inm, 0, logger)
+	cache.SetEnabled(true)
+
+	if err := cache.Put(context.Background(), &physical.Entry{Key: "foo", Value: []byte("bar")}); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"foo", "foo", "missing"} {
+		if _, err := cache.Get(context.Background(), key); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats := cache.Stats()
+	if !stats.Enabled || stats.Size != physical.DefaultCacheSize {
+		t.Fatalf("bad stats: %#v", stats)
+	}
+	if stats.Entries != 1 || stats.Hits != 2 || stats.Misses != 1 {
+		t.Fatalf("bad stats: %#v", stats)
+	}
+}
+
+func TestCache_Bypass(t *testing.T) {
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+
+	inm, err := NewInmem(nil, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache := physical.NewCache(inm, 0, logger)
+	cache.SetEnabled(true)
+
+	if err := cache.Put(context.Background(), &physical.Entry{Key: "foo", Value: []byte("bar")}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Change the value from under the cache
+	if err := inm.Put(context.Background(), &physical.Entry{Key: "foo", Value: []byte("baz")}); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := cache.Get(context.Background(), "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out.Value) != "bar" {
+		t.Fatalf("expected cached value, got %q", out.Value)
+	}
+
+	out, err = cache.Get(physical.ContextWithCacheBypass(context.Background()), "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out.Value) != "baz" {
+		t.Fatalf("expected value of the backend, got %q", out.Value)
+	}
+
+	// Bypassed reads are not counted
+	if stats := cache.Stats(); stats.Hits != 1 || stats.Misses != 0 {
+		t.Fatalf("bad stats: %#v", stats)
+	}
+}
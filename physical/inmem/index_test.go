@@ -0,0 +1,241 @@
+package inmem
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/helper/consts"
+	"github.com/hashicorp/vault/helper/logformat"
+	"github.com/hashicorp/vault/physical"
+	log "github.com/mgutz/logxi/v1"
+)
+
+// laggingBackend only applies the writes to the wrapped backend once
+// released, simulating an eventually consistent backend
+type laggingBackend struct {
+	physical.Backend
+
+	l       sync.Mutex
+	pending []func()
+}
+
+func (b *laggingBackend) Put(ctx context.Context, entry *physical.Entry) error {
+	b.l.Lock()
+	defer b.l.Unlock()
+	b.pending = append(b.pending, func() { b.Backend.Put(ctx, entry) })
+	return nil
+}
+
+func (b *laggingBackend) Delete(ctx context.Context, key string) error {
+	b.l.Lock()
+	defer b.l.Unlock()
+	b.pending = append(b.pending, func() { b.Backend.Delete(ctx, key) })
+	return nil
+}
+
+func (b *laggingBackend) release() {
+	b.l.Lock()
+	defer b.l.Unlock()
+	for _, f := range b.pending {
+		f()
+	}
+	b.pending = nil
+}
+
+func TestIndexTracker(t *testing.T) {
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+
+	inm, err := NewInmem(nil, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracker := physical.NewIndexTracker(inm, logger)
+	physical.ExerciseBackend(t, tracker)
+	physical.ExerciseBackend_ListPrefix(t, tracker)
+
+	// The same holds when the reads require the state of the writes
+	if err := tracker.Put(context.Background(), &physical.Entry{Key: "foo", Value: []byte("bar")}); err != nil {
+		t.Fatal(err)
+	}
+	ctx := physical.ContextWithIndexState(context.Background(), tracker.State())
+	if entry, err := tracker.Get(ctx, "foo"); err != nil || entry == nil || string(entry.Value) != "bar" {
+		t.Fatalf("bad: %#v %v", entry, err)
+	}
+	if _, err := tracker.List(ctx, ""); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIndexTracker_State(t *testing.T) {
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+
+	inm, err := NewInmem(nil, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracker := physical.NewIndexTracker(inm, logger)
+	if err := tracker.Put(context.Background(), &physical.Entry{Key: "foo", Value: []byte("bar")}); err != nil {
+		t.Fatal(err)
+	}
+
+	state, err := physical.ParseIndexState(tracker.State().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *state != *tracker.State() || state.Index != 1 {
+		t.Fatalf("bad: %#v", state)
+	}
+	if err := tracker.CheckState(state); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, value := range []string{"", "foo", ":1", "foo:bar"} {
+		if _, err := physical.ParseIndexState(value); err == nil {
+			t.Fatalf("expected error parsing %q", value)
+		}
+	}
+
+	// The states of other trackers, or which the tracker has not reached,
+	// are unknown
+	for _, state := range []*physical.IndexState{
+		{Epoch: "foo", Index: 1},
+		{Epoch: state.Epoch, Index: 2},
+	} {
+		if err := tracker.CheckState(state); err != consts.ErrUnknownIndexState {
+			t.Fatalf("expected unknown state error for %#v: %v", state, err)
+		}
+	}
+}
+
+func TestIndexTracker_Wait(t *testing.T) {
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+
+	inm, err := NewInmem(nil, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lagging := &laggingBackend{Backend: inm}
+	tracker := physical.NewIndexTracker(lagging, logger)
+
+	if err := tracker.Put(context.Background(), &physical.Entry{Key: "foo/bar", Value: []byte("baz")}); err != nil {
+		t.Fatal(err)
+	}
+	ctx := physical.ContextWithIndexState(context.Background(), tracker.State())
+
+	// Without the state, the reads do not see the write
+	if entry, err := tracker.Get(context.Background(), "foo/bar"); err != nil || entry != nil {
+		t.Fatalf("bad: %#v %v", entry, err)
+	}
+
+	// With it, they wait for the write, or fail if it is not reflected in time
+	timeout := physical.IndexWaitTimeout
+	physical.IndexWaitTimeout = 100 * time.Millisecond
+	defer func() {
+		physical.IndexWaitTimeout = timeout
+	}()
+	if _, err := tracker.Get(ctx, "foo/bar"); err != consts.ErrIndexStateNotReached {
+		t.Fatalf("expected state not reached error: %v", err)
+	}
+	if _, err := tracker.List(ctx, "foo/"); err != consts.ErrIndexStateNotReached {
+		t.Fatalf("expected state not reached error: %v", err)
+	}
+
+	physical.IndexWaitTimeout = 2 * time.Second
+	time.AfterFunc(50*time.Millisecond, lagging.release)
+	entry, err := tracker.Get(ctx, "foo/bar")
+	if err != nil || entry == nil || string(entry.Value) != "baz" {
+		t.Fatalf("bad: %#v %v", entry, err)
+	}
+	keys, err := tracker.List(ctx, "")
+	if err != nil || len(keys) != 1 || keys[0] != "foo/" {
+		t.Fatalf("bad: %#v %v", keys, err)
+	}
+
+	// Deletes are waited for as well
+	if err := tracker.Delete(context.Background(), "foo/bar"); err != nil {
+		t.Fatal(err)
+	}
+	ctx = physical.ContextWithIndexState(context.Background(), tracker.State())
+	time.AfterFunc(50*time.Millisecond, lagging.release)
+	if entry, err := tracker.Get(ctx, "foo/bar"); err != nil || entry != nil {
+		t.Fatalf("bad: %#v %v", entry, err)
+	}
+	keys, err = tracker.List(ctx, "foo/")
+	if err != nil || len(keys) != 0 {
+		t.Fatalf("bad: %#v %v", keys, err)
+	}
+}
+
+func TestIndexTracker_WaitOverwritten(t *testing.T) {
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+
+	inm, err := NewInmem(nil, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lagging := &laggingBackend{Backend: inm}
+	tracker := physical.NewIndexTracker(lagging, logger)
+
+	timeout := physical.IndexWaitTimeout
+	physical.IndexWaitTimeout = 100 * time.Millisecond
+	defer func() {
+		physical.IndexWaitTimeout = timeout
+	}()
+
+	if err := tracker.Put(context.Background(), &physical.Entry{Key: "foo/bar", Value: []byte("baz")}); err != nil {
+		t.Fatal(err)
+	}
+	lagging.release()
+	ctx := physical.ContextWithIndexState(context.Background(), tracker.State())
+
+	// A write made after the state was issued is not required, so the reads
+	// do not wait for the backend to reflect it
+	if err := tracker.Put(context.Background(), &physical.Entry{Key: "foo/bar", Value: []byte("qux")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tracker.Put(context.Background(), &physical.Entry{Key: "foo/other", Value: []byte("qux")}); err != nil {
+		t.Fatal(err)
+	}
+	entry, err := tracker.Get(ctx, "foo/bar")
+	if err != nil || entry == nil || string(entry.Value) != "baz" {
+		t.Fatalf("bad: %#v %v", entry, err)
+	}
+	if _, err := tracker.Get(ctx, "foo/other"); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := tracker.List(ctx, "foo/")
+	if err != nil || len(keys) != 1 || keys[0] != "bar" {
+		t.Fatalf("bad: %#v %v", keys, err)
+	}
+
+	// Once reflected, the overwrite is accepted as well, being at least as
+	// new as the required write
+	lagging.release()
+	entry, err = tracker.Get(ctx, "foo/bar")
+	if err != nil || entry == nil || string(entry.Value) != "qux" {
+		t.Fatalf("bad: %#v %v", entry, err)
+	}
+
+	// A delete made after the state may be reflected too, while the required
+	// write still has to be
+	if err := tracker.Put(context.Background(), &physical.Entry{Key: "foo/baz", Value: []byte("baz")}); err != nil {
+		t.Fatal(err)
+	}
+	ctx = physical.ContextWithIndexState(context.Background(), tracker.State())
+	if err := tracker.Delete(context.Background(), "foo/bar"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tracker.Get(ctx, "foo/baz"); err != consts.ErrIndexStateNotReached {
+		t.Fatalf("expected state not reached error: %v", err)
+	}
+	lagging.release()
+	if entry, err := tracker.Get(ctx, "foo/bar"); err != nil || entry != nil {
+		t.Fatalf("bad: %#v %v", entry, err)
+	}
+	keys, err = tracker.List(ctx, "foo/")
+	if err != nil || len(keys) != 2 {
+		t.Fatalf("bad: %#v %v", keys, err)
+	}
+}
@@ -143,6 +143,34 @@ func (i *InmemBackend) ListInternal(prefix string) ([]string, error) {
 	return out, nil
 }
 
+// ListPage lists a page of the keys under a given prefix, walking the keys
+// in order up to the end of the page only
+func (i *InmemBackend) ListPage(ctx context.Context, prefix, after string, limit int) ([]string, error) {
+	i.permitPool.Acquire()
+	defer i.permitPool.Release()
+
+	i.RLock()
+	defer i.RUnlock()
+
+	// The keys are walked in lexicographic order, so the keys of a
+	// sub-prefix are contiguous and listed in order
+	var out []string
+	walkFn := func(s string, v interface{}) bool {
+		trimmed := strings.TrimPrefix(s, prefix)
+		if sep := strings.Index(trimmed, "/"); sep != -1 {
+			trimmed = trimmed[:sep+1]
+		}
+		if trimmed <= after || (len(out) > 0 && out[len(out)-1] == trimmed) {
+			return false
+		}
+		out = append(out, trimmed)
+		return limit > 0 && len(out) >= limit
+	}
+	i.root.WalkPrefix(prefix, walkFn)
+
+	return out, nil
+}
+
 // Implements the transaction interface
 func (t *TransactionalInmemBackend) Transaction(ctx context.Context, txns []*physical.TxnEntry) error {
 	t.permitPool.Acquire()
@@ -5,6 +5,7 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/hashicorp/vault/helper/strutil"
 	log "github.com/mgutz/logxi/v1"
 )
 
@@ -44,6 +45,29 @@ type Backend interface {
 	List(ctx context.Context, prefix string) ([]string, error)
 }
 
+// Paginated is an optional interface that a Backend can implement to list
+// a page of the keys under a prefix without listing all of them.
+type Paginated interface {
+	// ListPage lists the keys under the prefix like List, in lexicographic
+	// order, starting after the given key and returning at most limit keys,
+	// or all of them if limit is not positive.
+	ListPage(ctx context.Context, prefix, after string, limit int) ([]string, error)
+}
+
+// ListPage lists a page of the keys under the prefix, natively if the backend
+// is Paginated, and by sorting the keys listed otherwise
+func ListPage(ctx context.Context, b Backend, prefix, after string, limit int) ([]string, error) {
+	if p, ok := b.(Paginated); ok {
+		return p.ListPage(ctx, prefix, after, limit)
+	}
+
+	keys, err := b.List(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	return strutil.StrListPage(keys, after, limit), nil
+}
+
 // HABackend is an extensions to the standard physical
 // backend to support high-availability. Vault only expects to
 // use mutual exclusion to allow multiple instances to act as a
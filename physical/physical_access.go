@@ -31,6 +31,10 @@ func (p *PhysicalAccess) List(ctx context.Context, prefix string) ([]string, err
 	return p.physical.List(ctx, prefix)
 }
 
+func (p *PhysicalAccess) ListPage(ctx context.Context, prefix, after string, limit int) ([]string, error) {
+	return ListPage(ctx, p.physical, prefix, after, limit)
+}
+
 func (p *PhysicalAccess) Purge(ctx context.Context) {
 	if purgeable, ok := p.physical.(ToggleablePurgemonster); ok {
 		purgeable.Purge(ctx)
@@ -6,9 +6,11 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/go-uuid"
 	"github.com/hashicorp/vault/physical"
 	log "github.com/mgutz/logxi/v1"
 
@@ -16,8 +18,23 @@ import (
 	"github.com/lib/pq"
 )
 
+const (
+	// PostgreSQLLockTTL is the maximum length of time of a lock without
+	// renewal
+	PostgreSQLLockTTL = 15 * time.Second
+
+	// PostgreSQLLockRenewInterval is the time to wait between lock renewals
+	PostgreSQLLockRenewInterval = 5 * time.Second
+
+	// PostgreSQLLockRetryInterval is the amount of time to wait
+	// if a lock fails before trying again.
+	PostgreSQLLockRetryInterval = time.Second
+)
+
 // Verify PostgreSQLBackend satisfies the correct interfaces
 var _ physical.Backend = (*PostgreSQLBackend)(nil)
+var _ physical.HABackend = (*PostgreSQLBackend)(nil)
+var _ physical.Lock = (*PostgreSQLLock)(nil)
 
 // PostgreSQL Backend is a physical backend that stores data
 // within a PostgreSQL database.
@@ -30,6 +47,27 @@ type PostgreSQLBackend struct {
 	list_query   string
 	logger       log.Logger
 	permitPool   *physical.PermitPool
+
+	haEnabled        bool
+	haGetLockQuery   string
+	haUpsertLockExec string
+	haDeleteLockExec string
+}
+
+// PostgreSQLLock implements a lock using a row of the HA table. The row
+// expires at valid_until, computed by the database so that the lock does
+// not depend on the clocks of the Vault nodes.
+type PostgreSQLLock struct {
+	backend    *PostgreSQLBackend
+	value, key string
+	identity   string
+	held       bool
+	lock       sync.Mutex
+	stopRenew  chan struct{}
+	// Allow modifying the Lock durations for ease of unit testing.
+	renewInterval time.Duration
+	ttl           time.Duration
+	retryInterval time.Duration
 }
 
 // NewPostgreSQLBackend constructs a PostgreSQL backend using the given
@@ -47,6 +85,21 @@ func NewPostgreSQLBackend(conf map[string]string, logger log.Logger) (physical.B
 	}
 	quoted_table := pq.QuoteIdentifier(unquoted_table)
 
+	haEnabledBool := false
+	if haEnabled, ok := conf["ha_enabled"]; ok {
+		var err error
+		haEnabledBool, err = strconv.ParseBool(haEnabled)
+		if err != nil {
+			return nil, errwrap.Wrapf("failed parsing ha_enabled parameter: {{err}}", err)
+		}
+	}
+
+	unquoted_ha_table, ok := conf["ha_table"]
+	if !ok {
+		unquoted_ha_table = "vault_ha_locks"
+	}
+	quoted_ha_table := pq.QuoteIdentifier(unquoted_ha_table)
+
 	maxParStr, ok := conf["max_parallel"]
 	var maxParInt int
 	var err error
@@ -76,6 +129,11 @@ func NewPostgreSQLBackend(conf map[string]string, logger log.Logger) (physical.B
 		return nil, fmt.Errorf("failed to check for native upsert: %v", err)
 	}
 
+	// The lock is acquired with a native upsert
+	if haEnabledBool && upsert_required {
+		return nil, fmt.Errorf("ha_enabled requires PostgreSQL 9.5 or later")
+	}
+
 	// Setup our put strategy based on the presence or absence of a native
 	// upsert.
 	var put_query string
@@ -99,6 +157,16 @@ func NewPostgreSQLBackend(conf map[string]string, logger log.Logger) (physical.B
 			quoted_table + " WHERE parent_path LIKE $1 || '%'",
 		logger:     logger,
 		permitPool: physical.NewPermitPool(maxParInt),
+
+		haEnabled:      haEnabledBool,
+		haGetLockQuery: "SELECT ha_value FROM " + quoted_ha_table + " WHERE NOW() <= valid_until AND ha_key = $1",
+		// The row is only taken over once expired, or renewed by its holder
+		haUpsertLockExec: "INSERT INTO " + quoted_ha_table + " AS t (ha_identity, ha_key, ha_value, valid_until)" +
+			" VALUES ($1, $2, $3, NOW() + $4 * INTERVAL '1 seconds')" +
+			" ON CONFLICT (ha_key) DO" +
+			" UPDATE SET (ha_identity, ha_key, ha_value, valid_until) = ($1, $2, $3, NOW() + $4 * INTERVAL '1 seconds')" +
+			" WHERE (t.valid_until < NOW() AND t.ha_key = $2) OR (t.ha_identity = $1 AND t.ha_key = $2)",
+		haDeleteLockExec: "DELETE FROM " + quoted_ha_table + " WHERE ha_identity = $1 AND ha_key = $2",
 	}
 
 	return m, nil
@@ -212,3 +280,149 @@ func (m *PostgreSQLBackend) List(ctx context.Context, prefix string) ([]string,
 
 	return keys, nil
 }
+
+// LockWith is used for mutual exclusion based on the given key.
+func (m *PostgreSQLBackend) LockWith(key, value string) (physical.Lock, error) {
+	identity, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+	return &PostgreSQLLock{
+		backend:       m,
+		key:           key,
+		value:         value,
+		identity:      identity,
+		renewInterval: PostgreSQLLockRenewInterval,
+		ttl:           PostgreSQLLockTTL,
+		retryInterval: PostgreSQLLockRetryInterval,
+	}, nil
+}
+
+func (m *PostgreSQLBackend) HAEnabled() bool {
+	return m.haEnabled
+}
+
+// Lock tries to acquire the lock by repeatedly trying to insert or take
+// over the row of the key in the HA table. It will block until either the
+// stop channel is closed or the lock could be acquired successfully. The
+// returned channel will be closed once the lock could not be renewed.
+func (l *PostgreSQLLock) Lock(stopCh <-chan struct{}) (<-chan struct{}, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.held {
+		return nil, fmt.Errorf("lock already held")
+	}
+
+	ticker := time.NewTicker(l.retryInterval)
+	defer ticker.Stop()
+	for {
+		acquired, err := l.writeItem()
+		if err != nil {
+			return nil, err
+		}
+		if acquired {
+			break
+		}
+
+		select {
+		case <-ticker.C:
+		case <-stopCh:
+			return nil, nil
+		}
+	}
+
+	l.held = true
+	l.stopRenew = make(chan struct{})
+	leader := make(chan struct{})
+	go l.periodicallyRenewLock(l.stopRenew, leader)
+
+	return leader, nil
+}
+
+// Unlock releases the lock by deleting the row of the lock from the HA
+// table.
+func (l *PostgreSQLLock) Unlock() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if !l.held {
+		return nil
+	}
+
+	l.held = false
+	close(l.stopRenew)
+
+	l.backend.permitPool.Acquire()
+	defer l.backend.permitPool.Release()
+
+	if _, err := l.backend.client.Exec(l.backend.haDeleteLockExec, l.identity, l.key); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Value checks whether or not the lock is held by any instance of
+// PostgreSQLLock, including this one, and returns the current value.
+func (l *PostgreSQLLock) Value() (bool, string, error) {
+	l.backend.permitPool.Acquire()
+	defer l.backend.permitPool.Release()
+
+	var result string
+	err := l.backend.client.QueryRow(l.backend.haGetLockQuery, l.key).Scan(&result)
+	if err == sql.ErrNoRows {
+		return false, "", nil
+	}
+	if err != nil {
+		return false, "", err
+	}
+
+	return true, result, nil
+}
+
+// periodicallyRenewLock renews the lock every renewInterval until stopped,
+// closing the leader channel once the lock is taken over or could not be
+// renewed before its TTL elapsed.
+func (l *PostgreSQLLock) periodicallyRenewLock(stop, leader chan struct{}) {
+	defer close(leader)
+
+	ticker := time.NewTicker(l.renewInterval)
+	defer ticker.Stop()
+
+	lastRenewal := time.Now()
+	for {
+		select {
+		case <-ticker.C:
+			acquired, err := l.writeItem()
+			switch {
+			case err != nil:
+				l.backend.logger.Error("postgres: failed to renew lock", "error", err)
+				if time.Since(lastRenewal) >= l.ttl {
+					return
+				}
+			case !acquired:
+				l.backend.logger.Warn("postgres: lock taken over by another instance")
+				return
+			default:
+				lastRenewal = time.Now()
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// writeItem inserts the row of the lock, or updates it if it is expired or
+// held by this lock, returning whether the row is now held by this lock.
+func (l *PostgreSQLLock) writeItem() (bool, error) {
+	l.backend.permitPool.Acquire()
+	defer l.backend.permitPool.Release()
+
+	res, err := l.backend.client.Exec(l.backend.haUpsertLockExec, l.identity, l.key, l.value, l.ttl.Seconds())
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
@@ -8,7 +8,7 @@ import (
 	"github.com/hashicorp/vault/physical"
 	log "github.com/mgutz/logxi/v1"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 func TestPostgreSQLBackend(t *testing.T) {
@@ -44,3 +44,41 @@ func TestPostgreSQLBackend(t *testing.T) {
 	physical.ExerciseBackend(t, b)
 	physical.ExerciseBackend_ListPrefix(t, b)
 }
+
+func TestPostgreSQLBackend_HA(t *testing.T) {
+	connURL := os.Getenv("PGURL")
+	if connURL == "" {
+		t.SkipNow()
+	}
+
+	haTable := os.Getenv("PGHATABLE")
+	if haTable == "" {
+		haTable = "vault_ha_locks"
+	}
+
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+	conf := map[string]string{
+		"connection_url": connURL,
+		"ha_enabled":     "true",
+		"ha_table":       haTable,
+	}
+
+	b, err := NewPostgreSQLBackend(conf, logger)
+	if err != nil {
+		t.Fatalf("Failed to create new backend: %v", err)
+	}
+	b2, err := NewPostgreSQLBackend(conf, logger)
+	if err != nil {
+		t.Fatalf("Failed to create new backend: %v", err)
+	}
+
+	defer func() {
+		pg := b.(*PostgreSQLBackend)
+		_, err := pg.client.Exec("TRUNCATE TABLE " + pq.QuoteIdentifier(haTable))
+		if err != nil {
+			t.Fatalf("Failed to drop table: %v", err)
+		}
+	}()
+
+	physical.ExerciseHABackend(t, b.(physical.HABackend), b2.(physical.HABackend))
+}
@@ -268,6 +268,49 @@ func ExerciseBackend_ListPrefix(t *testing.T, b Backend) {
 	}
 }
 
+func ExerciseBackend_ListPage(t *testing.T, b Backend) {
+	t.Helper()
+	keys := []string{"page/c", "page/a", "page/b/1", "page/b/2", "page/d"}
+
+	defer func() {
+		for _, key := range keys {
+			b.Delete(context.Background(), key)
+		}
+	}()
+
+	for _, key := range keys {
+		err := b.Put(context.Background(), &Entry{Key: key, Value: []byte("test")})
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	cases := []struct {
+		after    string
+		limit    int
+		expected []string
+	}{
+		{"", 0, []string{"a", "b/", "c", "d"}},
+		{"", 2, []string{"a", "b/"}},
+		{"a", 2, []string{"b/", "c"}},
+		{"b/", 0, []string{"c", "d"}},
+		{"bb", 1, []string{"c"}},
+		{"d", 0, nil},
+	}
+	for _, tc := range cases {
+		page, err := ListPage(context.Background(), b, "page/", tc.after, tc.limit)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if len(page) == 0 && len(tc.expected) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(page, tc.expected) {
+			t.Fatalf("after %q, limit %d: expected %v, got %v", tc.after, tc.limit, tc.expected, page)
+		}
+	}
+}
+
 func ExerciseHABackend(t *testing.T, b HABackend, b2 HABackend) {
 	t.Helper()
 	// Get the lock
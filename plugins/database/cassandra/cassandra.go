@@ -2,6 +2,7 @@ package cassandra
 
 import (
 	"context"
+	"errors"
 	"strings"
 	"time"
 
@@ -11,7 +12,6 @@ import (
 	"github.com/hashicorp/vault/builtin/logical/database/dbplugin"
 	"github.com/hashicorp/vault/helper/strutil"
 	"github.com/hashicorp/vault/plugins"
-	"github.com/hashicorp/vault/plugins/helper/database/connutil"
 	"github.com/hashicorp/vault/plugins/helper/database/credsutil"
 	"github.com/hashicorp/vault/plugins/helper/database/dbutil"
 )
@@ -19,6 +19,7 @@ import (
 const (
 	defaultUserCreationCQL = `CREATE USER '{{username}}' WITH PASSWORD '{{password}}' NOSUPERUSER;`
 	defaultUserDeletionCQL = `DROP USER '{{username}}';`
+	defaultUserRotationCQL = `ALTER USER '{{username}}' WITH PASSWORD '{{password}}';`
 	cassandraTypeName      = "cassandra"
 )
 
@@ -26,7 +27,7 @@ var _ dbplugin.Database = &Cassandra{}
 
 // Cassandra is an implementation of Database interface
 type Cassandra struct {
-	connutil.ConnectionProducer
+	*cassandraConnectionProducer
 	credsutil.CredentialsProducer
 }
 
@@ -43,8 +44,8 @@ func New() (interface{}, error) {
 	}
 
 	dbType := &Cassandra{
-		ConnectionProducer:  connProducer,
-		CredentialsProducer: credsProducer,
+		cassandraConnectionProducer: connProducer,
+		CredentialsProducer:         credsProducer,
 	}
 
 	return dbType, nil
@@ -140,6 +141,103 @@ func (c *Cassandra) CreateUser(ctx context.Context, statements dbplugin.Statemen
 	return username, password, nil
 }
 
+// SetCredentials sets the password of the static user as instructed by the
+// RotationStatements provided, or with ALTER USER by default.
+func (c *Cassandra) SetCredentials(ctx context.Context, statements dbplugin.Statements, staticConfig dbplugin.StaticUserConfig) (username string, password string, err error) {
+	if staticConfig.Username == "" {
+		return "", "", dbutil.ErrEmptyUsername
+	}
+
+	rotationCQL := statements.RotationStatements
+	if rotationCQL == "" {
+		rotationCQL = defaultUserRotationCQL
+	}
+
+	// Grab the lock
+	c.Lock()
+	defer c.Unlock()
+
+	username = staticConfig.Username
+	password = staticConfig.Password
+	if password == "" {
+		password, err = c.GeneratePassword()
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	// Get the connection
+	session, err := c.getConnection(ctx)
+	if err != nil {
+		return "", "", err
+	}
+
+	// Execute each query
+	for _, query := range strutil.ParseArbitraryStringSlice(rotationCQL, ";") {
+		query = strings.TrimSpace(query)
+		if len(query) == 0 {
+			continue
+		}
+
+		err = session.Query(dbutil.QueryHelper(query, map[string]string{
+			"username": username,
+			"password": password,
+		})).Exec()
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	return username, password, nil
+}
+
+// RotateRootCredentials rotates the password of the user of the connection.
+func (c *Cassandra) RotateRootCredentials(ctx context.Context, statements []string) (map[string]interface{}, error) {
+	// Grab the lock
+	c.Lock()
+	defer c.Unlock()
+
+	if len(c.Username) == 0 || len(c.Password) == 0 {
+		return nil, errors.New("username and password are required to rotate the root credentials")
+	}
+
+	rotateStatements := statements
+	if len(rotateStatements) == 0 {
+		rotateStatements = []string{defaultUserRotationCQL}
+	}
+
+	password, err := c.GeneratePassword()
+	if err != nil {
+		return nil, err
+	}
+
+	// Get the connection
+	session, err := c.getConnection(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Execute each query
+	for _, statement := range rotateStatements {
+		for _, query := range strutil.ParseArbitraryStringSlice(statement, ";") {
+			query = strings.TrimSpace(query)
+			if len(query) == 0 {
+				continue
+			}
+
+			err = session.Query(dbutil.QueryHelper(query, map[string]string{
+				"username": c.Username,
+				"password": password,
+			})).Exec()
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return c.setPassword(password), nil
+}
+
 // RenewUser is not supported on Cassandra, so this is a no-op.
 func (c *Cassandra) RenewUser(ctx context.Context, statements dbplugin.Statements, username string, expiration time.Time) error {
 	// NOOP
@@ -88,7 +88,7 @@ func TestCassandra_Initialize(t *testing.T) {
 
 	dbRaw, _ := New()
 	db := dbRaw.(*Cassandra)
-	connProducer := db.ConnectionProducer.(*cassandraConnectionProducer)
+	connProducer := db.cassandraConnectionProducer
 
 	err := db.Initialize(context.Background(), connectionDetails, true)
 	if err != nil {
@@ -33,6 +33,7 @@ type cassandraConnectionProducer struct {
 	PemBundle         string      `json:"pem_bundle" structs:"pem_bundle" mapstructure:"pem_bundle"`
 	PemJSON           string      `json:"pem_json" structs:"pem_json" mapstructure:"pem_json"`
 
+	rawConfig      map[string]interface{}
 	connectTimeout time.Duration
 	certificate    string
 	privateKey     string
@@ -48,6 +49,11 @@ func (c *cassandraConnectionProducer) Initialize(ctx context.Context, conf map[s
 	c.Lock()
 	defer c.Unlock()
 
+	c.rawConfig = make(map[string]interface{}, len(conf))
+	for k, v := range conf {
+		c.rawConfig[k] = v
+	}
+
 	err := mapstructure.WeakDecode(conf, c)
 	if err != nil {
 		return err
@@ -150,6 +156,22 @@ func (c *cassandraConnectionProducer) Close() error {
 	return nil
 }
 
+// setPassword updates the password of the connection after it has been
+// rotated in the database and returns the updated connection configuration.
+// The current session is closed so that the next one is established with the
+// new password. The caller of this function needs to hold the producer's lock.
+func (c *cassandraConnectionProducer) setPassword(password string) map[string]interface{} {
+	c.Password = password
+	c.rawConfig["password"] = password
+
+	if c.session != nil {
+		c.session.Close()
+	}
+	c.session = nil
+
+	return c.rawConfig
+}
+
 func (c *cassandraConnectionProducer) createSession() (*gocql.Session, error) {
 	hosts := strings.Split(c.Hosts, ",")
 	clusterConfig := gocql.NewCluster(hosts...)
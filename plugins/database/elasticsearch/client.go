@@ -0,0 +1,101 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/helper/jsonutil"
+)
+
+// securityPath is the path of the security API of the native realm
+const securityPath = "/_xpack/security"
+
+// esClient is a client of the Elasticsearch security API, authenticated with
+// basic authentication
+type esClient struct {
+	baseURL    string
+	username   string
+	password   string
+	httpClient *http.Client
+}
+
+// esError is an error response of the Elasticsearch API
+type esError struct {
+	StatusCode int
+	Type       string
+	Reason     string
+}
+
+func (e *esError) Error() string {
+	if e.Type != "" {
+		return fmt.Sprintf("elasticsearch API error %d (%s): %s", e.StatusCode, e.Type, e.Reason)
+	}
+	return fmt.Sprintf("elasticsearch API error %d: %s", e.StatusCode, e.Reason)
+}
+
+// isNotFound returns whether the error is an Elasticsearch API error for a
+// missing resource
+func isNotFound(err error) bool {
+	apiErr, ok := err.(*esError)
+	return ok && apiErr.StatusCode == http.StatusNotFound
+}
+
+// do sends the request to the Elasticsearch API and decodes the JSON response
+// into out if it is not nil
+func (c *esClient) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body []byte
+	if in != nil {
+		var err error
+		body, err = json.Marshal(in)
+		if err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.SetBasicAuth(c.username, c.password)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var errResp struct {
+			Error struct {
+				Type   string `json:"type"`
+				Reason string `json:"reason"`
+			} `json:"error"`
+		}
+		apiErr := &esError{StatusCode: resp.StatusCode, Reason: http.StatusText(resp.StatusCode)}
+		if err := jsonutil.DecodeJSONFromReader(resp.Body, &errResp); err == nil && errResp.Error.Reason != "" {
+			apiErr.Type = errResp.Error.Type
+			apiErr.Reason = errResp.Error.Reason
+		}
+		return apiErr
+	}
+
+	if out == nil {
+		io.Copy(ioutil.Discard, resp.Body)
+		return nil
+	}
+	if err := jsonutil.DecodeJSONFromReader(resp.Body, out); err != nil {
+		return errwrap.Wrapf("error decoding elasticsearch API response: {{err}}", err)
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package elasticsearch
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/vault/plugins/helper/database/connutil"
+	"github.com/mitchellh/mapstructure"
+)
+
+// esConnectionProducer implements ConnectionProducer and provides a client of
+// the security API of the Elasticsearch cluster.
+type esConnectionProducer struct {
+	URL      string `json:"url" structs:"url" mapstructure:"url"`
+	Username string `json:"username" structs:"username" mapstructure:"username"`
+	Password string `json:"password" structs:"password" mapstructure:"password"`
+	CACert   string `json:"ca_cert" structs:"ca_cert" mapstructure:"ca_cert"`
+	Insecure bool   `json:"insecure" structs:"insecure" mapstructure:"insecure"`
+
+	Initialized bool
+	Type        string
+	rawConfig   map[string]interface{}
+	client      *esClient
+	sync.Mutex
+}
+
+// Initialize parses connection configuration.
+func (c *esConnectionProducer) Initialize(ctx context.Context, conf map[string]interface{}, verifyConnection bool) error {
+	c.Lock()
+	defer c.Unlock()
+
+	c.rawConfig = make(map[string]interface{}, len(conf))
+	for k, v := range conf {
+		c.rawConfig[k] = v
+	}
+
+	err := mapstructure.WeakDecode(conf, c)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case len(c.URL) == 0:
+		return fmt.Errorf("url cannot be empty")
+	case len(c.Username) == 0:
+		return fmt.Errorf("username cannot be empty")
+	case len(c.Password) == 0:
+		return fmt.Errorf("password cannot be empty")
+	}
+
+	if _, err := url.Parse(c.URL); err != nil {
+		return fmt.Errorf("invalid url: %s", err)
+	}
+
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: c.Insecure,
+	}
+	if len(c.CACert) != 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(c.CACert)) {
+			return fmt.Errorf("failed to parse ca_cert")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	transport := cleanhttp.DefaultPooledTransport()
+	transport.TLSClientConfig = tlsConfig
+
+	c.client = &esClient{
+		baseURL:  strings.TrimSuffix(c.URL, "/"),
+		username: c.Username,
+		password: c.Password,
+		httpClient: &http.Client{
+			Transport: transport,
+			Timeout:   1 * time.Minute,
+		},
+	}
+
+	// Set initialized to true at this point since all fields are set,
+	// and the connection can be established at a later time.
+	c.Initialized = true
+
+	if verifyConnection {
+		if err := c.client.do(ctx, http.MethodGet, securityPath+"/_authenticate", nil, nil); err != nil {
+			return fmt.Errorf("error verifying connection: %s", err)
+		}
+	}
+
+	return nil
+}
+
+// Connection returns the client of the Elasticsearch API.
+func (c *esConnectionProducer) Connection(_ context.Context) (interface{}, error) {
+	if !c.Initialized {
+		return nil, connutil.ErrNotInitialized
+	}
+
+	return c.client, nil
+}
+
+// setPassword updates the password of the connection after it has been
+// rotated in Elasticsearch and returns the updated connection configuration.
+// The caller of this function needs to hold the producer's lock.
+func (c *esConnectionProducer) setPassword(password string) map[string]interface{} {
+	c.Password = password
+	c.rawConfig["password"] = password
+	c.client.password = password
+
+	return c.rawConfig
+}
+
+// Close releases the idle connections of the client.
+func (c *esConnectionProducer) Close() error {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.client != nil {
+		if transport, ok := c.client.httpClient.Transport.(*http.Transport); ok {
+			transport.CloseIdleConnections()
+		}
+	}
+
+	c.client = nil
+	c.Initialized = false
+
+	return nil
+}
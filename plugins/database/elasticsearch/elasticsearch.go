@@ -0,0 +1,238 @@
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/builtin/logical/database/dbplugin"
+	"github.com/hashicorp/vault/plugins"
+	"github.com/hashicorp/vault/plugins/helper/database/credsutil"
+	"github.com/hashicorp/vault/plugins/helper/database/dbutil"
+)
+
+const elasticsearchTypeName = "elasticsearch"
+
+// Elasticsearch is an implementation of Database interface which manages the
+// users and roles of the native realm of an Elasticsearch cluster
+type Elasticsearch struct {
+	*esConnectionProducer
+	credsutil.CredentialsProducer
+}
+
+var _ dbplugin.Database = &Elasticsearch{}
+
+// New returns a new Elasticsearch instance
+func New() (interface{}, error) {
+	connProducer := &esConnectionProducer{}
+	connProducer.Type = elasticsearchTypeName
+
+	credsProducer := &credsutil.SQLCredentialsProducer{
+		DisplayNameLen: 15,
+		RoleNameLen:    15,
+		UsernameLen:    100,
+		Separator:      "-",
+	}
+
+	dbType := &Elasticsearch{
+		esConnectionProducer: connProducer,
+		CredentialsProducer:  credsProducer,
+	}
+	return dbType, nil
+}
+
+// Run instantiates an Elasticsearch object, and runs the RPC server for the plugin
+func Run(apiTLSConfig *api.TLSConfig) error {
+	dbType, err := New()
+	if err != nil {
+		return err
+	}
+
+	plugins.Serve(dbType.(*Elasticsearch), apiTLSConfig)
+
+	return nil
+}
+
+// Type returns the TypeName for this backend
+func (e *Elasticsearch) Type() (string, error) {
+	return elasticsearchTypeName, nil
+}
+
+func (e *Elasticsearch) getConnection(ctx context.Context) (*esClient, error) {
+	client, err := e.Connection(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.(*esClient), nil
+}
+
+func userPath(username string) string {
+	return securityPath + "/user/" + url.PathEscape(username)
+}
+
+func rolePath(name string) string {
+	return securityPath + "/role/" + url.PathEscape(name)
+}
+
+// CreateUser creates a user of the native realm with the roles of the
+// creation statement. The creation statement is a JSON blob with either an
+// array of existing roles to assign to the user, or the definition of a role
+// which is created with the name of the user and removed on revocation.
+//
+// JSON Examples:
+//  { "elasticsearch_roles": ["monitoring_user", "logs-reader"] }
+//  { "elasticsearch_role_definition": { "indices": [{ "names": ["logs-*"], "privileges": ["read"] }] } }
+func (e *Elasticsearch) CreateUser(ctx context.Context, statements dbplugin.Statements, usernameConfig dbplugin.UsernameConfig, expiration time.Time) (username string, password string, err error) {
+	// Grab the lock
+	e.Lock()
+	defer e.Unlock()
+
+	if statements.CreationStatements == "" {
+		return "", "", dbutil.ErrEmptyCreationStatement
+	}
+
+	var esCS esStatement
+	err = json.Unmarshal([]byte(statements.CreationStatements), &esCS)
+	if err != nil {
+		return "", "", err
+	}
+	switch {
+	case len(esCS.Roles) == 0 && len(esCS.RoleDefinition) == 0:
+		return "", "", fmt.Errorf("elasticsearch_roles or elasticsearch_role_definition is required in creation statement")
+	case len(esCS.Roles) != 0 && len(esCS.RoleDefinition) != 0:
+		return "", "", fmt.Errorf("only one of elasticsearch_roles and elasticsearch_role_definition can be set in creation statement")
+	}
+
+	client, err := e.getConnection(ctx)
+	if err != nil {
+		return "", "", err
+	}
+
+	username, err = e.GenerateUsername(usernameConfig)
+	if err != nil {
+		return "", "", err
+	}
+
+	password, err = e.GeneratePassword()
+	if err != nil {
+		return "", "", err
+	}
+
+	roles := esCS.Roles
+	if len(esCS.RoleDefinition) != 0 {
+		if err := client.do(ctx, http.MethodPut, rolePath(username), esCS.RoleDefinition, nil); err != nil {
+			return "", "", err
+		}
+		roles = []string{username}
+	}
+
+	user := &esUser{
+		Password: password,
+		Roles:    roles,
+	}
+	if err := client.do(ctx, http.MethodPost, userPath(username), user, nil); err != nil {
+		// Remove the role created for the user
+		if len(esCS.RoleDefinition) != 0 {
+			client.do(ctx, http.MethodDelete, rolePath(username), nil, nil)
+		}
+		return "", "", err
+	}
+
+	return username, password, nil
+}
+
+// SetCredentials sets the password of the user of a static role.
+func (e *Elasticsearch) SetCredentials(ctx context.Context, statements dbplugin.Statements, staticConfig dbplugin.StaticUserConfig) (username string, password string, err error) {
+	if staticConfig.Username == "" {
+		return "", "", dbutil.ErrEmptyUsername
+	}
+
+	// Grab the lock
+	e.Lock()
+	defer e.Unlock()
+
+	username = staticConfig.Username
+	password = staticConfig.Password
+	if password == "" {
+		password, err = e.GeneratePassword()
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	client, err := e.getConnection(ctx)
+	if err != nil {
+		return "", "", err
+	}
+
+	if err := client.do(ctx, http.MethodPost, userPath(username)+"/_password", &esUser{Password: password}, nil); err != nil {
+		return "", "", err
+	}
+
+	return username, password, nil
+}
+
+// RotateRootCredentials rotates the password of the user of the connection.
+// Rotation statements are not used.
+func (e *Elasticsearch) RotateRootCredentials(ctx context.Context, statements []string) (map[string]interface{}, error) {
+	// Grab the lock
+	e.Lock()
+	defer e.Unlock()
+
+	if len(e.Username) == 0 || len(e.Password) == 0 {
+		return nil, errors.New("username and password are required to rotate the root credentials")
+	}
+
+	password, err := e.GeneratePassword()
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := e.getConnection(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := client.do(ctx, http.MethodPost, userPath(e.Username)+"/_password", &esUser{Password: password}, nil); err != nil {
+		return nil, err
+	}
+
+	return e.setPassword(password), nil
+}
+
+// RenewUser is not supported on Elasticsearch, so this is a no-op.
+func (e *Elasticsearch) RenewUser(ctx context.Context, statements dbplugin.Statements, username string, expiration time.Time) error {
+	// NOOP
+	return nil
+}
+
+// RevokeUser deletes the user and the role which may have been created for
+// it. Users and roles which no longer exist are considered revoked.
+func (e *Elasticsearch) RevokeUser(ctx context.Context, statements dbplugin.Statements, username string) error {
+	// Grab the lock
+	e.Lock()
+	defer e.Unlock()
+
+	client, err := e.getConnection(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = client.do(ctx, http.MethodDelete, userPath(username), nil, nil)
+	if err != nil && !isNotFound(err) {
+		return err
+	}
+
+	err = client.do(ctx, http.MethodDelete, rolePath(username), nil, nil)
+	if err != nil && !isNotFound(err) {
+		return err
+	}
+
+	return nil
+}
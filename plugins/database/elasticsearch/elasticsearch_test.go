@@ -0,0 +1,339 @@
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/builtin/logical/database/dbplugin"
+)
+
+const testElasticsearchRoles = `{ "elasticsearch_roles": ["monitoring_user"] }`
+
+const testElasticsearchRoleDefinition = `{ "elasticsearch_role_definition": { "indices": [{ "names": ["logs-*"], "privileges": ["read"] }] } }`
+
+// fakeElasticsearch is a fake of the security API of the native realm
+type fakeElasticsearch struct {
+	sync.Mutex
+	users map[string]*esUser
+	roles map[string]json.RawMessage
+}
+
+func (f *fakeElasticsearch) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	writeError := func(code int, reason string) {
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error": map[string]interface{}{
+				"type":   "security_exception",
+				"reason": reason,
+			},
+			"status": code,
+		})
+	}
+
+	username, password, ok := r.BasicAuth()
+	if user, exists := f.users[username]; !ok || !exists || user.Password != password {
+		writeError(http.StatusUnauthorized, "unable to authenticate user")
+		return
+	}
+
+	switch {
+	case r.URL.Path == securityPath+"/_authenticate" && r.Method == http.MethodGet:
+		json.NewEncoder(w).Encode(map[string]interface{}{"username": username})
+
+	case strings.HasPrefix(r.URL.Path, securityPath+"/role/"):
+		name := strings.TrimPrefix(r.URL.Path, securityPath+"/role/")
+		switch r.Method {
+		case http.MethodPut:
+			var definition json.RawMessage
+			if err := json.NewDecoder(r.Body).Decode(&definition); err != nil {
+				writeError(http.StatusBadRequest, "invalid role definition")
+				return
+			}
+			f.roles[name] = definition
+			json.NewEncoder(w).Encode(map[string]interface{}{"role": map[string]bool{"created": true}})
+		case http.MethodDelete:
+			if _, ok := f.roles[name]; !ok {
+				w.WriteHeader(http.StatusNotFound)
+				json.NewEncoder(w).Encode(map[string]bool{"found": false})
+				return
+			}
+			delete(f.roles, name)
+			json.NewEncoder(w).Encode(map[string]bool{"found": true})
+		}
+
+	case strings.HasSuffix(r.URL.Path, "/_password") && r.Method == http.MethodPost:
+		name := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, securityPath+"/user/"), "/_password")
+		user, ok := f.users[name]
+		if !ok {
+			writeError(http.StatusNotFound, "user not found")
+			return
+		}
+		update := &esUser{}
+		if err := json.NewDecoder(r.Body).Decode(update); err != nil {
+			writeError(http.StatusBadRequest, "invalid password")
+			return
+		}
+		user.Password = update.Password
+		json.NewEncoder(w).Encode(map[string]interface{}{})
+
+	case strings.HasPrefix(r.URL.Path, securityPath+"/user/"):
+		name := strings.TrimPrefix(r.URL.Path, securityPath+"/user/")
+		switch r.Method {
+		case http.MethodPost:
+			user := &esUser{}
+			if err := json.NewDecoder(r.Body).Decode(user); err != nil {
+				writeError(http.StatusBadRequest, "invalid user")
+				return
+			}
+			for _, role := range user.Roles {
+				if _, ok := f.roles[role]; !ok && role != "monitoring_user" {
+					writeError(http.StatusBadRequest, "unknown role "+role)
+					return
+				}
+			}
+			f.users[name] = user
+			json.NewEncoder(w).Encode(map[string]interface{}{"created": true})
+		case http.MethodDelete:
+			if _, ok := f.users[name]; !ok {
+				w.WriteHeader(http.StatusNotFound)
+				json.NewEncoder(w).Encode(map[string]bool{"found": false})
+				return
+			}
+			delete(f.users, name)
+			json.NewEncoder(w).Encode(map[string]bool{"found": true})
+		}
+
+	default:
+		writeError(http.StatusNotFound, "no handler found")
+	}
+}
+
+func prepareFakeElasticsearch(t *testing.T) (*fakeElasticsearch, map[string]interface{}, func()) {
+	es := &fakeElasticsearch{
+		users: map[string]*esUser{
+			"vault": {Password: "bootstrap", Roles: []string{"superuser"}},
+		},
+		roles: make(map[string]json.RawMessage),
+	}
+	server := httptest.NewServer(es)
+
+	connectionDetails := map[string]interface{}{
+		"url":      server.URL,
+		"username": "vault",
+		"password": "bootstrap",
+	}
+	return es, connectionDetails, server.Close
+}
+
+func TestElasticsearch_Initialize(t *testing.T) {
+	_, connectionDetails, cleanup := prepareFakeElasticsearch(t)
+	defer cleanup()
+
+	dbRaw, err := New()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	db := dbRaw.(*Elasticsearch)
+
+	err = db.Initialize(context.Background(), connectionDetails, true)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !db.Initialized {
+		t.Fatal("Database should be initialized")
+	}
+
+	err = db.Close()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// A wrong password fails the verification of the connection
+	connectionDetails["password"] = "wrong"
+	err = db.Initialize(context.Background(), connectionDetails, true)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	connectionDetails["ca_cert"] = "not a certificate"
+	err = db.Initialize(context.Background(), connectionDetails, false)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestElasticsearch_CreateUser(t *testing.T) {
+	es, connectionDetails, cleanup := prepareFakeElasticsearch(t)
+	defer cleanup()
+
+	dbRaw, err := New()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	db := dbRaw.(*Elasticsearch)
+	err = db.Initialize(context.Background(), connectionDetails, true)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	usernameConfig := dbplugin.UsernameConfig{
+		DisplayName: "test",
+		RoleName:    "test",
+	}
+
+	// Existing roles are assigned to the user
+	statements := dbplugin.Statements{
+		CreationStatements: testElasticsearchRoles,
+	}
+	username, password, err := db.CreateUser(context.Background(), statements, usernameConfig, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	user, ok := es.users[username]
+	if !ok || user.Password != password || !reflect.DeepEqual(user.Roles, []string{"monitoring_user"}) {
+		t.Fatalf("bad: %#v", user)
+	}
+
+	// A role is created for the user from the role definition
+	statements.CreationStatements = testElasticsearchRoleDefinition
+	username, password, err = db.CreateUser(context.Background(), statements, usernameConfig, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	user, ok = es.users[username]
+	if !ok || user.Password != password || !reflect.DeepEqual(user.Roles, []string{username}) {
+		t.Fatalf("bad: %#v", user)
+	}
+	if _, ok := es.roles[username]; !ok {
+		t.Fatalf("role %q was not created", username)
+	}
+
+	for _, cs := range []string{`{}`, `{ "elasticsearch_roles": ["a"], "elasticsearch_role_definition": {} }`} {
+		statements.CreationStatements = cs
+		_, _, err = db.CreateUser(context.Background(), statements, usernameConfig, time.Now().Add(time.Minute))
+		if err == nil {
+			t.Fatalf("%s: expected an error", cs)
+		}
+	}
+}
+
+func TestElasticsearch_RevokeUser(t *testing.T) {
+	es, connectionDetails, cleanup := prepareFakeElasticsearch(t)
+	defer cleanup()
+
+	dbRaw, err := New()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	db := dbRaw.(*Elasticsearch)
+	err = db.Initialize(context.Background(), connectionDetails, true)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	statements := dbplugin.Statements{
+		CreationStatements: testElasticsearchRoleDefinition,
+	}
+
+	usernameConfig := dbplugin.UsernameConfig{
+		DisplayName: "test",
+		RoleName:    "test",
+	}
+
+	username, _, err := db.CreateUser(context.Background(), statements, usernameConfig, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	err = db.RevokeUser(context.Background(), statements, username)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, ok := es.users[username]; ok {
+		t.Fatalf("user %q was not revoked", username)
+	}
+	if _, ok := es.roles[username]; ok {
+		t.Fatalf("role %q was not removed", username)
+	}
+
+	// Revoking a missing user succeeds
+	err = db.RevokeUser(context.Background(), statements, username)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestElasticsearch_SetCredentials(t *testing.T) {
+	es, connectionDetails, cleanup := prepareFakeElasticsearch(t)
+	defer cleanup()
+
+	es.users["static"] = &esUser{Password: "initial"}
+
+	dbRaw, err := New()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	db := dbRaw.(*Elasticsearch)
+	err = db.Initialize(context.Background(), connectionDetails, true)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	username, password, err := db.SetCredentials(context.Background(), dbplugin.Statements{}, dbplugin.StaticUserConfig{
+		Username: "static",
+		Password: "new-password",
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if username != "static" || password != "new-password" || es.users["static"].Password != "new-password" {
+		t.Fatalf("bad: username: %q, password: %q", username, password)
+	}
+}
+
+func TestElasticsearch_RotateRootCredentials(t *testing.T) {
+	es, connectionDetails, cleanup := prepareFakeElasticsearch(t)
+	defer cleanup()
+
+	dbRaw, err := New()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	db := dbRaw.(*Elasticsearch)
+	err = db.Initialize(context.Background(), connectionDetails, true)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	config, err := db.RotateRootCredentials(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	password := config["password"].(string)
+	if password == "bootstrap" || es.users["vault"].Password != password || config["url"] != connectionDetails["url"] {
+		t.Fatalf("bad: %#v", config)
+	}
+
+	// The connection uses the new password
+	err = db.Initialize(context.Background(), config, true)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	statements := dbplugin.Statements{
+		CreationStatements: testElasticsearchRoles,
+	}
+	_, _, err = db.CreateUser(context.Background(), statements, dbplugin.UsernameConfig{DisplayName: "test", RoleName: "test"}, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
@@ -0,0 +1,14 @@
+package elasticsearch
+
+import "encoding/json"
+
+// esUser is a user of the native realm
+type esUser struct {
+	Password string   `json:"password"`
+	Roles    []string `json:"roles,omitempty"`
+}
+
+type esStatement struct {
+	Roles          []string        `json:"elasticsearch_roles"`
+	RoleDefinition json.RawMessage `json:"elasticsearch_role_definition"`
+}
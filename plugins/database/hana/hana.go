@@ -3,6 +3,7 @@ package hana
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -18,12 +19,18 @@ import (
 )
 
 const (
-	hanaTypeName = "hdb"
+	hanaTypeName            = "hdb"
+	defaultHANARotationStmt = `
+ALTER USER {{name}} PASSWORD {{password}} NO FORCE_FIRST_PASSWORD_CHANGE;
+`
+	defaultHANARootRotationStmt = `
+ALTER USER {{username}} PASSWORD {{password}};
+`
 )
 
 // HANA is an implementation of Database interface
 type HANA struct {
-	connutil.ConnectionProducer
+	*connutil.SQLConnectionProducer
 	credsutil.CredentialsProducer
 }
 
@@ -42,8 +49,8 @@ func New() (interface{}, error) {
 	}
 
 	dbType := &HANA{
-		ConnectionProducer:  connProducer,
-		CredentialsProducer: credsProducer,
+		SQLConnectionProducer: connProducer,
+		CredentialsProducer:   credsProducer,
 	}
 
 	return dbType, nil
@@ -155,6 +162,142 @@ func (h *HANA) CreateUser(ctx context.Context, statements dbplugin.Statements, u
 	return username, password, nil
 }
 
+// SetCredentials sets the password of the static user as instructed by the
+// RotationStatements provided, or with ALTER USER by default
+func (h *HANA) SetCredentials(ctx context.Context, statements dbplugin.Statements, staticConfig dbplugin.StaticUserConfig) (username string, password string, err error) {
+	if staticConfig.Username == "" {
+		return "", "", dbutil.ErrEmptyUsername
+	}
+
+	rotationStmts := statements.RotationStatements
+	if rotationStmts == "" {
+		rotationStmts = defaultHANARotationStmt
+	}
+
+	// Grab the lock
+	h.Lock()
+	defer h.Unlock()
+
+	username = staticConfig.Username
+	password = staticConfig.Password
+	if password == "" {
+		password, err = h.GeneratePassword()
+		if err != nil {
+			return "", "", err
+		}
+		// Satisfy the password constraints as for created users
+		password = strings.Replace(password, "-", "_", -1)
+		password = "A1a" + password
+	}
+
+	// Get the connection
+	db, err := h.getConnection(ctx)
+	if err != nil {
+		return "", "", err
+	}
+
+	// Start a transaction
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", "", err
+	}
+	defer tx.Rollback()
+
+	// Execute each query
+	for _, query := range strutil.ParseArbitraryStringSlice(rotationStmts, ";") {
+		query = strings.TrimSpace(query)
+		if len(query) == 0 {
+			continue
+		}
+
+		stmt, err := tx.PrepareContext(ctx, dbutil.QueryHelper(query, map[string]string{
+			"name":     username,
+			"password": password,
+		}))
+		if err != nil {
+			return "", "", err
+		}
+		defer stmt.Close()
+		if _, err := stmt.ExecContext(ctx); err != nil {
+			return "", "", err
+		}
+	}
+
+	// Commit the transaction
+	if err := tx.Commit(); err != nil {
+		return "", "", err
+	}
+
+	return username, password, nil
+}
+
+// RotateRootCredentials rotates the password of the user of the connection,
+// which must be configured with the "username" and "password" values.
+func (h *HANA) RotateRootCredentials(ctx context.Context, statements []string) (map[string]interface{}, error) {
+	// Grab the lock
+	h.Lock()
+	defer h.Unlock()
+
+	if len(h.Username) == 0 || len(h.Password) == 0 {
+		return nil, errors.New("username and password are required to rotate the root credentials")
+	}
+
+	rotateStatements := statements
+	if len(rotateStatements) == 0 {
+		rotateStatements = []string{defaultHANARootRotationStmt}
+	}
+
+	password, err := h.GeneratePassword()
+	if err != nil {
+		return nil, err
+	}
+	// Satisfy the password constraints as for created users
+	password = strings.Replace(password, "-", "_", -1)
+	password = "A1a" + password
+
+	// Get the connection
+	db, err := h.getConnection(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Start a transaction
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Execute each query
+	for _, statement := range rotateStatements {
+		for _, query := range strutil.ParseArbitraryStringSlice(statement, ";") {
+			query = strings.TrimSpace(query)
+			if len(query) == 0 {
+				continue
+			}
+
+			stmt, err := tx.PrepareContext(ctx, dbutil.QueryHelper(query, map[string]string{
+				"username": h.Username,
+				"password": password,
+			}))
+			if err != nil {
+				return nil, err
+			}
+			defer stmt.Close()
+			if _, err := stmt.ExecContext(ctx); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Commit the transaction
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return h.SetPassword(password), nil
+}
+
 // Renewing hana user just means altering user's valid until property
 func (h *HANA) RenewUser(ctx context.Context, statements dbplugin.Statements, username string, expiration time.Time) error {
 	// Get connection
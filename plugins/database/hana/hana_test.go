@@ -10,7 +10,6 @@ import (
 	"time"
 
 	"github.com/hashicorp/vault/builtin/logical/database/dbplugin"
-	"github.com/hashicorp/vault/plugins/helper/database/connutil"
 )
 
 func TestHANA_Initialize(t *testing.T) {
@@ -31,7 +30,7 @@ func TestHANA_Initialize(t *testing.T) {
 		t.Fatalf("err: %s", err)
 	}
 
-	connProducer := db.ConnectionProducer.(*connutil.SQLConnectionProducer)
+	connProducer := db.SQLConnectionProducer
 	if !connProducer.Initialized {
 		t.Fatal("Database should be initialized")
 	}
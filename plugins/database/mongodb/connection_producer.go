@@ -15,6 +15,7 @@ import (
 	"time"
 
 	"github.com/hashicorp/vault/plugins/helper/database/connutil"
+	"github.com/hashicorp/vault/plugins/helper/database/dbutil"
 	"github.com/mitchellh/mapstructure"
 
 	"gopkg.in/mgo.v2"
@@ -25,11 +26,15 @@ import (
 type mongoDBConnectionProducer struct {
 	ConnectionURL string `json:"connection_url" structs:"connection_url" mapstructure:"connection_url"`
 	WriteConcern  string `json:"write_concern" structs:"write_concern" mapstructure:"write_concern"`
-
-	Initialized bool
-	Type        string
-	session     *mgo.Session
-	safe        *mgo.Safe
+	Username      string `json:"username" structs:"username" mapstructure:"username"`
+	Password      string `json:"password" structs:"password" mapstructure:"password"`
+
+	Initialized   bool
+	Type          string
+	rawConfig     map[string]interface{}
+	connectionURL string
+	session       *mgo.Session
+	safe          *mgo.Safe
 	sync.Mutex
 }
 
@@ -38,6 +43,11 @@ func (c *mongoDBConnectionProducer) Initialize(ctx context.Context, conf map[str
 	c.Lock()
 	defer c.Unlock()
 
+	c.rawConfig = make(map[string]interface{}, len(conf))
+	for k, v := range conf {
+		c.rawConfig[k] = v
+	}
+
 	err := mapstructure.WeakDecode(conf, c)
 	if err != nil {
 		return err
@@ -47,6 +57,8 @@ func (c *mongoDBConnectionProducer) Initialize(ctx context.Context, conf map[str
 		return fmt.Errorf("connection_url cannot be empty")
 	}
 
+	c.connectionURL = c.templatedConnectionURL()
+
 	if c.WriteConcern != "" {
 		input := c.WriteConcern
 
@@ -102,7 +114,7 @@ func (c *mongoDBConnectionProducer) Connection(_ context.Context) (interface{},
 		c.session.Close()
 	}
 
-	dialInfo, err := parseMongoURL(c.ConnectionURL)
+	dialInfo, err := parseMongoURL(c.connectionURL)
 	if err != nil {
 		return nil, err
 	}
@@ -122,6 +134,32 @@ func (c *mongoDBConnectionProducer) Connection(_ context.Context) (interface{},
 	return c.session, nil
 }
 
+// templatedConnectionURL returns the connection URL with the username and
+// password substituted for the "{{username}}" and "{{password}}" values
+func (c *mongoDBConnectionProducer) templatedConnectionURL() string {
+	return dbutil.QueryHelper(c.ConnectionURL, map[string]string{
+		"username": c.Username,
+		"password": c.Password,
+	})
+}
+
+// setPassword updates the password of the connection after it has been
+// rotated in the database and returns the updated connection configuration.
+// The current session is closed so that the next one is established with the
+// new password. The caller of this function needs to hold the producer's lock.
+func (c *mongoDBConnectionProducer) setPassword(password string) map[string]interface{} {
+	c.Password = password
+	c.rawConfig["password"] = password
+	c.connectionURL = c.templatedConnectionURL()
+
+	if c.session != nil {
+		c.session.Close()
+	}
+	c.session = nil
+
+	return c.rawConfig
+}
+
 // Close terminates the database connection.
 func (c *mongoDBConnectionProducer) Close() error {
 	c.Lock()
@@ -2,6 +2,7 @@ package mongodb
 
 import (
 	"context"
+	"errors"
 	"io"
 	"strings"
 	"time"
@@ -14,7 +15,6 @@ import (
 	"github.com/hashicorp/vault/api"
 	"github.com/hashicorp/vault/builtin/logical/database/dbplugin"
 	"github.com/hashicorp/vault/plugins"
-	"github.com/hashicorp/vault/plugins/helper/database/connutil"
 	"github.com/hashicorp/vault/plugins/helper/database/credsutil"
 	"github.com/hashicorp/vault/plugins/helper/database/dbutil"
 	"gopkg.in/mgo.v2"
@@ -24,7 +24,7 @@ const mongoDBTypeName = "mongodb"
 
 // MongoDB is an implementation of Database interface
 type MongoDB struct {
-	connutil.ConnectionProducer
+	*mongoDBConnectionProducer
 	credsutil.CredentialsProducer
 }
 
@@ -43,8 +43,8 @@ func New() (interface{}, error) {
 	}
 
 	dbType := &MongoDB{
-		ConnectionProducer:  connProducer,
-		CredentialsProducer: credsProducer,
+		mongoDBConnectionProducer: connProducer,
+		CredentialsProducer:       credsProducer,
 	}
 	return dbType, nil
 }
@@ -149,6 +149,140 @@ func (m *MongoDB) CreateUser(ctx context.Context, statements dbplugin.Statements
 	return username, password, nil
 }
 
+// SetCredentials sets the password of the static user with the updateUser
+// command, in the authentication database of the rotation statement. If none
+// is provided, the default "admin" authentication database will be assumed.
+//
+// JSON Example:
+//  { "db": "admin" }
+func (m *MongoDB) SetCredentials(ctx context.Context, statements dbplugin.Statements, staticConfig dbplugin.StaticUserConfig) (username string, password string, err error) {
+	if staticConfig.Username == "" {
+		return "", "", dbutil.ErrEmptyUsername
+	}
+
+	// Grab the lock
+	m.Lock()
+	defer m.Unlock()
+
+	// If no rotation statements provided, pass in empty JSON
+	rotationStatement := statements.RotationStatements
+	if rotationStatement == "" {
+		rotationStatement = `{}`
+	}
+
+	var mongoCS mongoDBStatement
+	err = json.Unmarshal([]byte(rotationStatement), &mongoCS)
+	if err != nil {
+		return "", "", err
+	}
+
+	// Default to "admin" if no db provided
+	if mongoCS.DB == "" {
+		mongoCS.DB = "admin"
+	}
+
+	username = staticConfig.Username
+	password = staticConfig.Password
+	if password == "" {
+		password, err = m.GeneratePassword()
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	session, err := m.getConnection(ctx)
+	if err != nil {
+		return "", "", err
+	}
+
+	updateUserCmd := updateUserCommand{
+		Username: username,
+		Password: password,
+	}
+
+	err = session.DB(mongoCS.DB).Run(updateUserCmd, nil)
+	switch {
+	case err == nil:
+	case err == io.EOF, strings.Contains(err.Error(), "EOF"):
+		// Call getConnection to reset and retry query if we get an EOF error on first attempt.
+		session, err := m.getConnection(ctx)
+		if err != nil {
+			return "", "", err
+		}
+		err = session.DB(mongoCS.DB).Run(updateUserCmd, nil)
+		if err != nil {
+			return "", "", err
+		}
+	default:
+		return "", "", err
+	}
+
+	return username, password, nil
+}
+
+// RotateRootCredentials rotates the password of the user of the connection
+// with the updateUser command, in the authentication database of the first
+// rotation statement. If none is provided, the default "admin"
+// authentication database will be assumed.
+//
+// JSON Example:
+//  { "db": "admin" }
+func (m *MongoDB) RotateRootCredentials(ctx context.Context, statements []string) (map[string]interface{}, error) {
+	// Grab the lock
+	m.Lock()
+	defer m.Unlock()
+
+	if len(m.Username) == 0 || len(m.Password) == 0 {
+		return nil, errors.New("username and password are required to rotate the root credentials")
+	}
+
+	var mongoCS mongoDBStatement
+	if len(statements) > 0 {
+		if err := json.Unmarshal([]byte(statements[0]), &mongoCS); err != nil {
+			return nil, err
+		}
+	}
+
+	// Default to "admin" if no db provided
+	if mongoCS.DB == "" {
+		mongoCS.DB = "admin"
+	}
+
+	password, err := m.GeneratePassword()
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := m.getConnection(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	updateUserCmd := updateUserCommand{
+		Username: m.Username,
+		Password: password,
+	}
+
+	err = session.DB(mongoCS.DB).Run(updateUserCmd, nil)
+	switch {
+	case err == nil:
+	case err == io.EOF, strings.Contains(err.Error(), "EOF"):
+		// Call getConnection to reset and retry query if we get an EOF error on first attempt.
+		session, err := m.getConnection(ctx)
+		if err != nil {
+			return nil, err
+		}
+		err = session.DB(mongoCS.DB).Run(updateUserCmd, nil)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	return m.setPassword(password), nil
+}
+
 // RenewUser is not supported on MongoDB, so this is a no-op.
 func (m *MongoDB) RenewUser(ctx context.Context, statements dbplugin.Statements, username string, expiration time.Time) error {
 	// NOOP
@@ -186,7 +320,7 @@ func (m *MongoDB) RevokeUser(ctx context.Context, statements dbplugin.Statements
 	switch {
 	case err == nil, err == mgo.ErrNotFound:
 	case err == io.EOF, strings.Contains(err.Error(), "EOF"):
-		if err := m.ConnectionProducer.Close(); err != nil {
+		if err := m.mongoDBConnectionProducer.Close(); err != nil {
 			return errwrap.Wrapf("error closing EOF'd mongo connection: {{err}}", err)
 		}
 		session, err := m.getConnection(ctx)
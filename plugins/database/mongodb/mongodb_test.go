@@ -78,7 +78,7 @@ func TestMongoDB_Initialize(t *testing.T) {
 		t.Fatalf("err: %s", err)
 	}
 	db := dbRaw.(*MongoDB)
-	connProducer := db.ConnectionProducer.(*mongoDBConnectionProducer)
+	connProducer := db.mongoDBConnectionProducer
 
 	err = db.Initialize(context.Background(), connectionDetails, true)
 	if err != nil {
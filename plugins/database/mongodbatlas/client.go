@@ -0,0 +1,198 @@
+package mongodbatlas
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/helper/jsonutil"
+)
+
+// atlasClient is a client of the MongoDB Atlas API, which is authenticated
+// with the public and private key of a programmatic API key using HTTP digest
+// authentication.
+type atlasClient struct {
+	baseURL    string
+	publicKey  string
+	privateKey string
+	httpClient *http.Client
+}
+
+// atlasError is an error response of the Atlas API
+type atlasError struct {
+	StatusCode int    `json:"error"`
+	ErrorCode  string `json:"errorCode"`
+	Detail     string `json:"detail"`
+	Reason     string `json:"reason"`
+}
+
+func (e *atlasError) Error() string {
+	if e.Detail != "" {
+		return fmt.Sprintf("atlas API error %d (%s): %s", e.StatusCode, e.ErrorCode, e.Detail)
+	}
+	return fmt.Sprintf("atlas API error %d: %s", e.StatusCode, e.Reason)
+}
+
+// isNotFound returns whether the error is an Atlas API error for a missing
+// resource
+func isNotFound(err error) bool {
+	apiErr, ok := err.(*atlasError)
+	return ok && apiErr.StatusCode == http.StatusNotFound
+}
+
+// do sends the request to the Atlas API, answering its digest challenge, and
+// decodes the JSON response into out if it is not nil
+func (c *atlasClient) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body []byte
+	if in != nil {
+		var err error
+		body, err = json.Marshal(in)
+		if err != nil {
+			return err
+		}
+	}
+
+	url := c.baseURL + path
+	resp, err := c.send(ctx, method, url, body, "")
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+
+		authorization, err := c.digestAuthorization(method, resp.Request.URL.RequestURI(), challenge)
+		if err != nil {
+			return err
+		}
+		resp, err = c.send(ctx, method, url, body, authorization)
+		if err != nil {
+			return err
+		}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		apiErr := &atlasError{}
+		if err := jsonutil.DecodeJSONFromReader(resp.Body, apiErr); err != nil || apiErr.StatusCode == 0 {
+			apiErr = &atlasError{StatusCode: resp.StatusCode, Reason: http.StatusText(resp.StatusCode)}
+		}
+		return apiErr
+	}
+
+	if out == nil {
+		io.Copy(ioutil.Discard, resp.Body)
+		return nil
+	}
+	if err := jsonutil.DecodeJSONFromReader(resp.Body, out); err != nil {
+		return errwrap.Wrapf("error decoding atlas API response: {{err}}", err)
+	}
+	return nil
+}
+
+func (c *atlasClient) send(ctx context.Context, method, url string, body []byte, authorization string) (*http.Response, error) {
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	return c.httpClient.Do(req)
+}
+
+// digestAuthorization returns the Authorization header answering the digest
+// challenge of RFC 2617 with the API key, as used by the Atlas API
+func (c *atlasClient) digestAuthorization(method, uri, challenge string) (string, error) {
+	if !strings.HasPrefix(challenge, "Digest ") {
+		return "", fmt.Errorf("unsupported authentication challenge %q", challenge)
+	}
+	params := parseChallenge(strings.TrimPrefix(challenge, "Digest "))
+	if algorithm, ok := params["algorithm"]; ok && !strings.EqualFold(algorithm, "MD5") {
+		return "", fmt.Errorf("unsupported digest algorithm %q", algorithm)
+	}
+
+	cnonceBytes := make([]byte, 16)
+	if _, err := rand.Read(cnonceBytes); err != nil {
+		return "", err
+	}
+	cnonce := hex.EncodeToString(cnonceBytes)
+	nc := "00000001"
+
+	ha1 := md5Hex(fmt.Sprintf("%s:%s:%s", c.publicKey, params["realm"], c.privateKey))
+	ha2 := md5Hex(fmt.Sprintf("%s:%s", method, uri))
+
+	var response string
+	qop := params["qop"]
+	if qop == "" {
+		response = md5Hex(fmt.Sprintf("%s:%s:%s", ha1, params["nonce"], ha2))
+	} else {
+		// Only the auth quality of protection is supported
+		qop = "auth"
+		response = md5Hex(fmt.Sprintf("%s:%s:%s:%s:%s:%s", ha1, params["nonce"], nc, cnonce, qop, ha2))
+	}
+
+	authorization := fmt.Sprintf(`Digest username="%s", realm="%s", nonce="%s", uri="%s", response="%s", algorithm=MD5`,
+		c.publicKey, params["realm"], params["nonce"], uri, response)
+	if qop != "" {
+		authorization += fmt.Sprintf(`, qop=%s, nc=%s, cnonce="%s"`, qop, nc, cnonce)
+	}
+	if opaque, ok := params["opaque"]; ok {
+		authorization += fmt.Sprintf(`, opaque="%s"`, opaque)
+	}
+	return authorization, nil
+}
+
+// parseChallenge parses the comma separated key=value parameters of a digest
+// challenge, whose values can be quoted
+func parseChallenge(s string) map[string]string {
+	params := make(map[string]string)
+	for len(s) > 0 {
+		s = strings.TrimLeft(s, " ,")
+		eq := strings.IndexByte(s, '=')
+		if eq < 0 {
+			break
+		}
+		key := strings.ToLower(strings.TrimSpace(s[:eq]))
+		s = s[eq+1:]
+
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			end := strings.IndexByte(s[1:], '"')
+			if end < 0 {
+				value, s = s[1:], ""
+			} else {
+				value, s = s[1:end+1], s[end+2:]
+			}
+		} else {
+			end := strings.IndexByte(s, ',')
+			if end < 0 {
+				end = len(s)
+			}
+			value = strings.TrimSpace(s[:end])
+			s = s[end:]
+		}
+		params[key] = value
+	}
+	return params
+}
+
+func md5Hex(s string) string {
+	sum := md5.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,108 @@
+package mongodbatlas
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/vault/plugins/helper/database/connutil"
+	"github.com/mitchellh/mapstructure"
+)
+
+// defaultAPIURL is the base URL of the public MongoDB Atlas API
+const defaultAPIURL = "https://cloud.mongodb.com/api/atlas/v1.0"
+
+// atlasConnectionProducer implements ConnectionProducer and provides a client
+// of the Atlas API for the project of the configuration.
+type atlasConnectionProducer struct {
+	PublicKey  string `json:"public_key" structs:"public_key" mapstructure:"public_key"`
+	PrivateKey string `json:"private_key" structs:"private_key" mapstructure:"private_key"`
+	ProjectID  string `json:"project_id" structs:"project_id" mapstructure:"project_id"`
+	APIURL     string `json:"api_url" structs:"api_url" mapstructure:"api_url"`
+
+	Initialized bool
+	Type        string
+	client      *atlasClient
+	sync.Mutex
+}
+
+// Initialize parses connection configuration.
+func (c *atlasConnectionProducer) Initialize(ctx context.Context, conf map[string]interface{}, verifyConnection bool) error {
+	c.Lock()
+	defer c.Unlock()
+
+	err := mapstructure.WeakDecode(conf, c)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case len(c.PublicKey) == 0:
+		return fmt.Errorf("public_key cannot be empty")
+	case len(c.PrivateKey) == 0:
+		return fmt.Errorf("private_key cannot be empty")
+	case len(c.ProjectID) == 0:
+		return fmt.Errorf("project_id cannot be empty")
+	}
+
+	if len(c.APIURL) == 0 {
+		c.APIURL = defaultAPIURL
+	}
+	if _, err := url.Parse(c.APIURL); err != nil {
+		return fmt.Errorf("invalid api_url: %s", err)
+	}
+
+	c.client = &atlasClient{
+		baseURL:    strings.TrimSuffix(c.APIURL, "/"),
+		publicKey:  c.PublicKey,
+		privateKey: c.PrivateKey,
+		httpClient: cleanhttp.DefaultClient(),
+	}
+	c.client.httpClient.Timeout = 1 * time.Minute
+
+	// Set initialized to true at this point since all fields are set,
+	// and the connection can be established at a later time.
+	c.Initialized = true
+
+	if verifyConnection {
+		// Reading the project verifies both the API key and its access to
+		// the project
+		if err := c.client.do(ctx, http.MethodGet, "/groups/"+url.PathEscape(c.ProjectID), nil, nil); err != nil {
+			return fmt.Errorf("error verifying connection: %s", err)
+		}
+	}
+
+	return nil
+}
+
+// Connection returns the client of the Atlas API. The API is stateless, so
+// there is no connection to establish.
+func (c *atlasConnectionProducer) Connection(_ context.Context) (interface{}, error) {
+	if !c.Initialized {
+		return nil, connutil.ErrNotInitialized
+	}
+
+	return c.client, nil
+}
+
+// Close releases the idle connections of the client.
+func (c *atlasConnectionProducer) Close() error {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.client != nil {
+		if transport, ok := c.client.httpClient.Transport.(*http.Transport); ok {
+			transport.CloseIdleConnections()
+		}
+	}
+
+	c.client = nil
+	c.Initialized = false
+
+	return nil
+}
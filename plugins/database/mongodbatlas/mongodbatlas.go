@@ -0,0 +1,207 @@
+package mongodbatlas
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/builtin/logical/database/dbplugin"
+	"github.com/hashicorp/vault/plugins"
+	"github.com/hashicorp/vault/plugins/helper/database/credsutil"
+	"github.com/hashicorp/vault/plugins/helper/database/dbutil"
+)
+
+const mongoDBAtlasTypeName = "mongodbatlas"
+
+// Database users of Atlas are always authenticated by the admin database
+const authDatabase = "admin"
+
+// MongoDBAtlas is an implementation of Database interface which manages the
+// database users of a MongoDB Atlas project with the Atlas API
+type MongoDBAtlas struct {
+	*atlasConnectionProducer
+	credsutil.CredentialsProducer
+}
+
+var _ dbplugin.Database = &MongoDBAtlas{}
+
+// New returns a new MongoDBAtlas instance
+func New() (interface{}, error) {
+	connProducer := &atlasConnectionProducer{}
+	connProducer.Type = mongoDBAtlasTypeName
+
+	credsProducer := &credsutil.SQLCredentialsProducer{
+		DisplayNameLen: 15,
+		RoleNameLen:    15,
+		UsernameLen:    100,
+		Separator:      "-",
+	}
+
+	dbType := &MongoDBAtlas{
+		atlasConnectionProducer: connProducer,
+		CredentialsProducer:     credsProducer,
+	}
+	return dbType, nil
+}
+
+// Run instantiates a MongoDBAtlas object, and runs the RPC server for the plugin
+func Run(apiTLSConfig *api.TLSConfig) error {
+	dbType, err := New()
+	if err != nil {
+		return err
+	}
+
+	plugins.Serve(dbType.(*MongoDBAtlas), apiTLSConfig)
+
+	return nil
+}
+
+// Type returns the TypeName for this backend
+func (m *MongoDBAtlas) Type() (string, error) {
+	return mongoDBAtlasTypeName, nil
+}
+
+func (m *MongoDBAtlas) getConnection(ctx context.Context) (*atlasClient, error) {
+	client, err := m.Connection(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.(*atlasClient), nil
+}
+
+// usersPath returns the API path of the database users of the project, or of
+// the given user
+func (m *MongoDBAtlas) usersPath(username string) string {
+	path := "/groups/" + url.PathEscape(m.ProjectID) + "/databaseUsers"
+	if username != "" {
+		path += "/" + authDatabase + "/" + url.PathEscape(username)
+	}
+	return path
+}
+
+// CreateUser creates a database user of the project with the roles of the
+// creation statement. The creation statement is a JSON blob with an array of
+// roles, each of which has a role and an optional db value, defaulting to the
+// "admin" database, and an optional collection value.
+//
+// JSON Example:
+//  { "roles": [{ "role": "readWrite", "db": "foo" }, { "role": "read", "db": "bar", "collection": "baz" }] }
+func (m *MongoDBAtlas) CreateUser(ctx context.Context, statements dbplugin.Statements, usernameConfig dbplugin.UsernameConfig, expiration time.Time) (username string, password string, err error) {
+	// Grab the lock
+	m.Lock()
+	defer m.Unlock()
+
+	if statements.CreationStatements == "" {
+		return "", "", dbutil.ErrEmptyCreationStatement
+	}
+
+	var atlasCS atlasStatement
+	err = json.Unmarshal([]byte(statements.CreationStatements), &atlasCS)
+	if err != nil {
+		return "", "", err
+	}
+	if len(atlasCS.Roles) == 0 {
+		return "", "", fmt.Errorf("roles array is required in creation statement")
+	}
+
+	client, err := m.getConnection(ctx)
+	if err != nil {
+		return "", "", err
+	}
+
+	username, err = m.GenerateUsername(usernameConfig)
+	if err != nil {
+		return "", "", err
+	}
+
+	password, err = m.GeneratePassword()
+	if err != nil {
+		return "", "", err
+	}
+
+	user := &databaseUser{
+		DatabaseName: authDatabase,
+		GroupID:      m.ProjectID,
+		Username:     username,
+		Password:     password,
+		Roles:        atlasCS.Roles.toAtlasRoles(),
+	}
+	if err := client.do(ctx, http.MethodPost, m.usersPath(""), user, nil); err != nil {
+		return "", "", err
+	}
+
+	return username, password, nil
+}
+
+// SetCredentials sets the password of the database user of a static role.
+func (m *MongoDBAtlas) SetCredentials(ctx context.Context, statements dbplugin.Statements, staticConfig dbplugin.StaticUserConfig) (username string, password string, err error) {
+	if staticConfig.Username == "" {
+		return "", "", dbutil.ErrEmptyUsername
+	}
+
+	// Grab the lock
+	m.Lock()
+	defer m.Unlock()
+
+	username = staticConfig.Username
+	password = staticConfig.Password
+	if password == "" {
+		password, err = m.GeneratePassword()
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	client, err := m.getConnection(ctx)
+	if err != nil {
+		return "", "", err
+	}
+
+	user := &databaseUser{
+		Password: password,
+	}
+	if err := client.do(ctx, http.MethodPatch, m.usersPath(username), user, nil); err != nil {
+		return "", "", err
+	}
+
+	return username, password, nil
+}
+
+// RotateRootCredentials is not supported, since the programmatic API key the
+// plugin authenticates with is managed in Atlas rather than through the
+// database users API.
+func (m *MongoDBAtlas) RotateRootCredentials(ctx context.Context, statements []string) (map[string]interface{}, error) {
+	return nil, errors.New("root credential rotation is not supported by the MongoDB Atlas plugin")
+}
+
+// RenewUser is not supported on MongoDB Atlas, so this is a no-op.
+func (m *MongoDBAtlas) RenewUser(ctx context.Context, statements dbplugin.Statements, username string, expiration time.Time) error {
+	// NOOP
+	return nil
+}
+
+// RevokeUser deletes the database user from the project. Users which no
+// longer exist are considered revoked.
+func (m *MongoDBAtlas) RevokeUser(ctx context.Context, statements dbplugin.Statements, username string) error {
+	// Grab the lock
+	m.Lock()
+	defer m.Unlock()
+
+	client, err := m.getConnection(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = client.do(ctx, http.MethodDelete, m.usersPath(username), nil, nil)
+	if err != nil && !isNotFound(err) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,310 @@
+package mongodbatlas
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/builtin/logical/database/dbplugin"
+)
+
+const testAtlasRole = `{ "roles": [ { "role": "readWrite", "db": "app" }, { "role": "read", "db": "logs", "collection": "events" } ] }`
+
+const (
+	testPublicKey  = "public-key"
+	testPrivateKey = "private-key"
+	testProjectID  = "5b1f6e7a"
+)
+
+// fakeAtlas is a fake Atlas API managing the database users of a project and
+// authenticating requests with HTTP digest authentication
+type fakeAtlas struct {
+	sync.Mutex
+	users map[string]*databaseUser
+}
+
+func (f *fakeAtlas) authenticated(r *http.Request) bool {
+	authorization := r.Header.Get("Authorization")
+	if !strings.HasPrefix(authorization, "Digest ") {
+		return false
+	}
+	params := parseChallenge(strings.TrimPrefix(authorization, "Digest "))
+	if params["username"] != testPublicKey || params["nonce"] != "test-nonce" || params["uri"] != r.URL.RequestURI() {
+		return false
+	}
+	ha1 := md5Hex(fmt.Sprintf("%s:%s:%s", testPublicKey, "MMS Public API", testPrivateKey))
+	ha2 := md5Hex(fmt.Sprintf("%s:%s", r.Method, params["uri"]))
+	expected := md5Hex(fmt.Sprintf("%s:%s:%s:%s:%s:%s", ha1, params["nonce"], params["nc"], params["cnonce"], params["qop"], ha2))
+	return params["response"] == expected
+}
+
+func (f *fakeAtlas) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !f.authenticated(r) {
+		w.Header().Set("WWW-Authenticate", `Digest realm="MMS Public API", domain="", nonce="test-nonce", algorithm=MD5, qop="auth", stale=false`)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	f.Lock()
+	defer f.Unlock()
+
+	writeError := func(code int, errorCode string) {
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":     code,
+			"errorCode": errorCode,
+			"detail":    errorCode,
+		})
+	}
+
+	projectPath := "/api/atlas/v1.0/groups/" + testProjectID
+	usersPath := projectPath + "/databaseUsers"
+	switch {
+	case r.URL.Path == projectPath && r.Method == http.MethodGet:
+		json.NewEncoder(w).Encode(map[string]interface{}{"id": testProjectID})
+	case r.URL.Path == usersPath && r.Method == http.MethodPost:
+		user := &databaseUser{}
+		if err := json.NewDecoder(r.Body).Decode(user); err != nil {
+			writeError(http.StatusBadRequest, "INVALID_JSON")
+			return
+		}
+		if _, ok := f.users[user.Username]; ok {
+			writeError(http.StatusConflict, "USER_ALREADY_EXISTS")
+			return
+		}
+		f.users[user.Username] = user
+		json.NewEncoder(w).Encode(user)
+	case strings.HasPrefix(r.URL.Path, usersPath+"/admin/"):
+		username := strings.TrimPrefix(r.URL.Path, usersPath+"/admin/")
+		user, ok := f.users[username]
+		if !ok {
+			writeError(http.StatusNotFound, "USER_NOT_FOUND")
+			return
+		}
+		switch r.Method {
+		case http.MethodPatch:
+			update := &databaseUser{}
+			if err := json.NewDecoder(r.Body).Decode(update); err != nil {
+				writeError(http.StatusBadRequest, "INVALID_JSON")
+				return
+			}
+			user.Password = update.Password
+			json.NewEncoder(w).Encode(user)
+		case http.MethodDelete:
+			delete(f.users, username)
+		default:
+			writeError(http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED")
+		}
+	default:
+		writeError(http.StatusNotFound, "RESOURCE_NOT_FOUND")
+	}
+}
+
+func prepareFakeAtlas(t *testing.T) (*fakeAtlas, map[string]interface{}, func()) {
+	atlas := &fakeAtlas{users: make(map[string]*databaseUser)}
+	server := httptest.NewServer(atlas)
+
+	connectionDetails := map[string]interface{}{
+		"public_key":  testPublicKey,
+		"private_key": testPrivateKey,
+		"project_id":  testProjectID,
+		"api_url":     server.URL + "/api/atlas/v1.0",
+	}
+	return atlas, connectionDetails, server.Close
+}
+
+func TestMongoDBAtlas_Initialize(t *testing.T) {
+	_, connectionDetails, cleanup := prepareFakeAtlas(t)
+	defer cleanup()
+
+	dbRaw, err := New()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	db := dbRaw.(*MongoDBAtlas)
+
+	err = db.Initialize(context.Background(), connectionDetails, true)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !db.Initialized {
+		t.Fatal("Database should be initialized")
+	}
+
+	err = db.Close()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// A wrong private key fails the verification of the connection
+	connectionDetails["private_key"] = "wrong"
+	err = db.Initialize(context.Background(), connectionDetails, true)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	dbRaw, err = New()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	db = dbRaw.(*MongoDBAtlas)
+	delete(connectionDetails, "project_id")
+	err = db.Initialize(context.Background(), connectionDetails, false)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestMongoDBAtlas_CreateUser(t *testing.T) {
+	atlas, connectionDetails, cleanup := prepareFakeAtlas(t)
+	defer cleanup()
+
+	dbRaw, err := New()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	db := dbRaw.(*MongoDBAtlas)
+	err = db.Initialize(context.Background(), connectionDetails, true)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	statements := dbplugin.Statements{
+		CreationStatements: testAtlasRole,
+	}
+
+	usernameConfig := dbplugin.UsernameConfig{
+		DisplayName: "test",
+		RoleName:    "test",
+	}
+
+	username, password, err := db.CreateUser(context.Background(), statements, usernameConfig, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	user, ok := atlas.users[username]
+	if !ok {
+		t.Fatalf("user %q was not created", username)
+	}
+	if user.Password != password || user.DatabaseName != "admin" || user.GroupID != testProjectID {
+		t.Fatalf("bad: %#v", user)
+	}
+	expectedRoles := []atlasRole{
+		{DatabaseName: "app", RoleName: "readWrite"},
+		{DatabaseName: "logs", RoleName: "read", CollectionName: "events"},
+	}
+	if !reflect.DeepEqual(user.Roles, expectedRoles) {
+		t.Fatalf("bad: roles: %#v", user.Roles)
+	}
+
+	// Roles are required
+	statements.CreationStatements = `{ "roles": [] }`
+	_, _, err = db.CreateUser(context.Background(), statements, usernameConfig, time.Now().Add(time.Minute))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestMongoDBAtlas_RevokeUser(t *testing.T) {
+	atlas, connectionDetails, cleanup := prepareFakeAtlas(t)
+	defer cleanup()
+
+	dbRaw, err := New()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	db := dbRaw.(*MongoDBAtlas)
+	err = db.Initialize(context.Background(), connectionDetails, true)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	statements := dbplugin.Statements{
+		CreationStatements: testAtlasRole,
+	}
+
+	usernameConfig := dbplugin.UsernameConfig{
+		DisplayName: "test",
+		RoleName:    "test",
+	}
+
+	username, _, err := db.CreateUser(context.Background(), statements, usernameConfig, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	err = db.RevokeUser(context.Background(), statements, username)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, ok := atlas.users[username]; ok {
+		t.Fatalf("user %q was not revoked", username)
+	}
+
+	// Revoking a missing user succeeds
+	err = db.RevokeUser(context.Background(), statements, username)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestMongoDBAtlas_SetCredentials(t *testing.T) {
+	atlas, connectionDetails, cleanup := prepareFakeAtlas(t)
+	defer cleanup()
+
+	atlas.users["static"] = &databaseUser{
+		DatabaseName: "admin",
+		Username:     "static",
+		Password:     "initial",
+	}
+
+	dbRaw, err := New()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	db := dbRaw.(*MongoDBAtlas)
+	err = db.Initialize(context.Background(), connectionDetails, true)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	username, password, err := db.SetCredentials(context.Background(), dbplugin.Statements{}, dbplugin.StaticUserConfig{
+		Username: "static",
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if username != "static" || password == "" || atlas.users["static"].Password != password {
+		t.Fatalf("bad: username: %q, password: %q, user: %#v", username, password, atlas.users["static"])
+	}
+
+	_, _, err = db.SetCredentials(context.Background(), dbplugin.Statements{}, dbplugin.StaticUserConfig{
+		Username: "missing",
+	})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestParseChallenge(t *testing.T) {
+	params := parseChallenge(`realm="MMS Public API", domain="", nonce="abc,def", algorithm=MD5, qop="auth", stale=false`)
+	expected := map[string]string{
+		"realm":     "MMS Public API",
+		"domain":    "",
+		"nonce":     "abc,def",
+		"algorithm": "MD5",
+		"qop":       "auth",
+		"stale":     "false",
+	}
+	if !reflect.DeepEqual(params, expected) {
+		t.Fatalf("bad: %#v", params)
+	}
+}
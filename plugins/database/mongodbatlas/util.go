@@ -0,0 +1,51 @@
+package mongodbatlas
+
+// databaseUser is a database user of the Atlas API
+type databaseUser struct {
+	DatabaseName string      `json:"databaseName,omitempty"`
+	GroupID      string      `json:"groupId,omitempty"`
+	Username     string      `json:"username,omitempty"`
+	Password     string      `json:"password,omitempty"`
+	Roles        []atlasRole `json:"roles,omitempty"`
+}
+
+type atlasRole struct {
+	DatabaseName   string `json:"databaseName"`
+	RoleName       string `json:"roleName"`
+	CollectionName string `json:"collectionName,omitempty"`
+}
+
+type mongodbRole struct {
+	Role       string `json:"role"`
+	DB         string `json:"db"`
+	Collection string `json:"collection"`
+}
+
+type mongodbRoles []mongodbRole
+
+type atlasStatement struct {
+	Roles mongodbRoles `json:"roles"`
+}
+
+// Convert array of role documents like:
+//
+// [ { "role": "readWrite" }, { "role": "read", "db": "test", "collection": "logs" } ]
+//
+// into the roles of the Atlas API, whose database defaults to "admin":
+//
+// [ { "databaseName": "admin", "roleName": "readWrite" }, { "databaseName": "test", "roleName": "read", "collectionName": "logs" } ]
+func (roles mongodbRoles) toAtlasRoles() []atlasRole {
+	atlasRoles := make([]atlasRole, 0, len(roles))
+	for _, role := range roles {
+		db := role.DB
+		if db == "" {
+			db = authDatabase
+		}
+		atlasRoles = append(atlasRoles, atlasRole{
+			DatabaseName:   db,
+			RoleName:       role.Role,
+			CollectionName: role.Collection,
+		})
+	}
+	return atlasRoles
+}
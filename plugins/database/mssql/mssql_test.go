@@ -11,7 +11,6 @@ import (
 	"time"
 
 	"github.com/hashicorp/vault/builtin/logical/database/dbplugin"
-	"github.com/hashicorp/vault/plugins/helper/database/connutil"
 )
 
 var (
@@ -36,7 +35,7 @@ func TestMSSQL_Initialize(t *testing.T) {
 		t.Fatalf("err: %s", err)
 	}
 
-	connProducer := db.ConnectionProducer.(*connutil.SQLConnectionProducer)
+	connProducer := db.SQLConnectionProducer
 	if !connProducer.Initialized {
 		t.Fatal("Database should be initalized")
 	}
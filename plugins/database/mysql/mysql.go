@@ -3,6 +3,7 @@ package mysql
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"strings"
 	"time"
 
@@ -21,6 +22,12 @@ const (
 		REVOKE ALL PRIVILEGES, GRANT OPTION FROM '{{name}}'@'%'; 
 		DROP USER '{{name}}'@'%'
 	`
+	defaultMysqlRotationStmts = `
+		ALTER USER '{{name}}'@'%' IDENTIFIED BY '{{password}}';
+	`
+	defaultMysqlRootRotationStmts = `
+		ALTER USER '{{username}}'@'%' IDENTIFIED BY '{{password}}';
+	`
 	mySQLTypeName = "mysql"
 )
 
@@ -34,7 +41,7 @@ var (
 var _ dbplugin.Database = &MySQL{}
 
 type MySQL struct {
-	connutil.ConnectionProducer
+	*connutil.SQLConnectionProducer
 	credsutil.CredentialsProducer
 }
 
@@ -52,8 +59,8 @@ func New(displayNameLen, roleNameLen, usernameLen int) func() (interface{}, erro
 		}
 
 		dbType := &MySQL{
-			ConnectionProducer:  connProducer,
-			CredentialsProducer: credsProducer,
+			SQLConnectionProducer: connProducer,
+			CredentialsProducer:   credsProducer,
 		}
 
 		return dbType, nil
@@ -181,6 +188,157 @@ func (m *MySQL) CreateUser(ctx context.Context, statements dbplugin.Statements,
 	return username, password, nil
 }
 
+func (m *MySQL) SetCredentials(ctx context.Context, statements dbplugin.Statements, staticConfig dbplugin.StaticUserConfig) (username string, password string, err error) {
+	if staticConfig.Username == "" {
+		return "", "", dbutil.ErrEmptyUsername
+	}
+
+	rotationStmts := statements.RotationStatements
+	if rotationStmts == "" {
+		rotationStmts = defaultMysqlRotationStmts
+	}
+
+	// Grab the lock
+	m.Lock()
+	defer m.Unlock()
+
+	username = staticConfig.Username
+	password = staticConfig.Password
+	if password == "" {
+		password, err = m.GeneratePassword()
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	// Get the connection
+	db, err := m.getConnection(ctx)
+	if err != nil {
+		return "", "", err
+	}
+
+	// Start a transaction
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", "", err
+	}
+	defer tx.Rollback()
+
+	// Execute each query
+	for _, query := range strutil.ParseArbitraryStringSlice(rotationStmts, ";") {
+		query = strings.TrimSpace(query)
+		if len(query) == 0 {
+			continue
+		}
+		query = dbutil.QueryHelper(query, map[string]string{
+			"name":     username,
+			"password": password,
+		})
+
+		stmt, err := tx.PrepareContext(ctx, query)
+		if err != nil {
+			// Statements which are not supported by the prepared statement
+			// protocol are executed without preparing them, as for creation
+			// statements
+			if e, ok := err.(*stdmysql.MySQLError); ok && e.Number == 1295 {
+				_, err = tx.ExecContext(ctx, query)
+				if err != nil {
+					return "", "", err
+				}
+				continue
+			}
+
+			return "", "", err
+		}
+		defer stmt.Close()
+		if _, err := stmt.ExecContext(ctx); err != nil {
+			return "", "", err
+		}
+	}
+
+	// Commit the transaction
+	if err := tx.Commit(); err != nil {
+		return "", "", err
+	}
+
+	return username, password, nil
+}
+
+// RotateRootCredentials rotates the password of the user of the connection,
+// which must be configured with the "username" and "password" values.
+func (m *MySQL) RotateRootCredentials(ctx context.Context, statements []string) (map[string]interface{}, error) {
+	// Grab the lock
+	m.Lock()
+	defer m.Unlock()
+
+	if len(m.Username) == 0 || len(m.Password) == 0 {
+		return nil, errors.New("username and password are required to rotate the root credentials")
+	}
+
+	rotateStatements := statements
+	if len(rotateStatements) == 0 {
+		rotateStatements = []string{defaultMysqlRootRotationStmts}
+	}
+
+	password, err := m.GeneratePassword()
+	if err != nil {
+		return nil, err
+	}
+
+	// Get the connection
+	db, err := m.getConnection(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Start a transaction
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Execute each query
+	for _, statement := range rotateStatements {
+		for _, query := range strutil.ParseArbitraryStringSlice(statement, ";") {
+			query = strings.TrimSpace(query)
+			if len(query) == 0 {
+				continue
+			}
+			query = dbutil.QueryHelper(query, map[string]string{
+				"username": m.Username,
+				"password": password,
+			})
+
+			stmt, err := tx.PrepareContext(ctx, query)
+			if err != nil {
+				// Statements which are not supported by the prepared statement
+				// protocol are executed without preparing them
+				if e, ok := err.(*stdmysql.MySQLError); ok && e.Number == 1295 {
+					_, err = tx.ExecContext(ctx, query)
+					if err != nil {
+						return nil, err
+					}
+					continue
+				}
+
+				return nil, err
+			}
+			defer stmt.Close()
+			if _, err := stmt.ExecContext(ctx); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Commit the transaction
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return m.SetPassword(password), nil
+}
+
 // NOOP
 func (m *MySQL) RenewUser(ctx context.Context, statements dbplugin.Statements, username string, expiration time.Time) error {
 	return nil
@@ -10,7 +10,6 @@ import (
 	"time"
 
 	"github.com/hashicorp/vault/builtin/logical/database/dbplugin"
-	"github.com/hashicorp/vault/plugins/helper/database/connutil"
 	"github.com/hashicorp/vault/plugins/helper/database/credsutil"
 	dockertest "gopkg.in/ory-am/dockertest.v3"
 )
@@ -107,7 +106,7 @@ func TestMySQL_Initialize(t *testing.T) {
 	f := New(MetadataLen, MetadataLen, UsernameLen)
 	dbRaw, _ := f()
 	db := dbRaw.(*MySQL)
-	connProducer := db.ConnectionProducer.(*connutil.SQLConnectionProducer)
+	connProducer := db.SQLConnectionProducer
 
 	err := db.Initialize(context.Background(), connectionDetails, true)
 	if err != nil {
@@ -319,6 +318,60 @@ func TestMySQL_RevokeUser(t *testing.T) {
 	}
 }
 
+func TestMySQL_SetCredentials(t *testing.T) {
+	cleanup, connURL := prepareMySQLTestContainer(t)
+	defer cleanup()
+
+	connectionDetails := map[string]interface{}{
+		"connection_url": connURL,
+	}
+
+	f := New(MetadataLen, MetadataLen, UsernameLen)
+	dbRaw, _ := f()
+	db := dbRaw.(*MySQL)
+
+	err := db.Initialize(context.Background(), connectionDetails, true)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	statements := dbplugin.Statements{
+		CreationStatements: testMySQLRoleWildCard,
+	}
+
+	usernameConfig := dbplugin.UsernameConfig{
+		DisplayName: "test",
+		RoleName:    "test",
+	}
+
+	username, password, err := db.CreateUser(context.Background(), statements, usernameConfig, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Test with no username
+	_, _, err = db.SetCredentials(context.Background(), dbplugin.Statements{}, dbplugin.StaticUserConfig{})
+	if err == nil {
+		t.Fatal("Expected error when no username is provided")
+	}
+
+	// Test default rotation statements with a generated password
+	_, newPassword, err := db.SetCredentials(context.Background(), dbplugin.Statements{}, dbplugin.StaticUserConfig{Username: username})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if newPassword == "" || newPassword == password {
+		t.Fatalf("bad: password was not rotated: %q", newPassword)
+	}
+
+	if err := testCredsExist(t, connURL, username, newPassword); err != nil {
+		t.Fatalf("Could not connect with new credentials: %s", err)
+	}
+	if err := testCredsExist(t, connURL, username, password); err == nil {
+		t.Fatal("Credentials were not rotated")
+	}
+}
+
 func testCredsExist(t testing.TB, connURL, username, password string) error {
 	// Log in with the new creds
 	connURL = strings.Replace(connURL, "root:secret", fmt.Sprintf("%s:%s", username, password), 1)
@@ -11,7 +11,6 @@ import (
 	"time"
 
 	"github.com/hashicorp/vault/builtin/logical/database/dbplugin"
-	"github.com/hashicorp/vault/plugins/helper/database/connutil"
 	dockertest "gopkg.in/ory-am/dockertest.v3"
 )
 
@@ -71,7 +70,7 @@ func TestPostgreSQL_Initialize(t *testing.T) {
 	dbRaw, _ := New()
 	db := dbRaw.(*PostgreSQL)
 
-	connProducer := db.ConnectionProducer.(*connutil.SQLConnectionProducer)
+	connProducer := db.SQLConnectionProducer
 
 	err := db.Initialize(context.Background(), connectionDetails, true)
 	if err != nil {
@@ -285,6 +284,150 @@ func TestPostgreSQL_RevokeUser(t *testing.T) {
 	}
 }
 
+func TestPostgreSQL_SetCredentials(t *testing.T) {
+	cleanup, connURL := preparePostgresTestContainer(t)
+	defer cleanup()
+
+	connectionDetails := map[string]interface{}{
+		"connection_url": connURL,
+	}
+
+	dbRaw, _ := New()
+	db := dbRaw.(*PostgreSQL)
+	err := db.Initialize(context.Background(), connectionDetails, true)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	statements := dbplugin.Statements{
+		CreationStatements: testPostgresRole,
+	}
+
+	usernameConfig := dbplugin.UsernameConfig{
+		DisplayName: "test",
+		RoleName:    "test",
+	}
+
+	username, password, err := db.CreateUser(context.Background(), statements, usernameConfig, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Test with no username
+	_, _, err = db.SetCredentials(context.Background(), dbplugin.Statements{}, dbplugin.StaticUserConfig{})
+	if err == nil {
+		t.Fatal("Expected error when no username is provided")
+	}
+
+	// Test default rotation statements with a generated password
+	_, newPassword, err := db.SetCredentials(context.Background(), dbplugin.Statements{}, dbplugin.StaticUserConfig{Username: username})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if newPassword == "" || newPassword == password {
+		t.Fatalf("bad: password was not rotated: %q", newPassword)
+	}
+
+	if err := testCredsExist(t, connURL, username, newPassword); err != nil {
+		t.Fatalf("Could not connect with new credentials: %s", err)
+	}
+	if err := testCredsExist(t, connURL, username, password); err == nil {
+		t.Fatal("Credentials were not rotated")
+	}
+
+	// Test custom rotation statements with a given password
+	statements.RotationStatements = defaultPostgresRotationSQL
+	_, newPassword, err = db.SetCredentials(context.Background(), statements, dbplugin.StaticUserConfig{Username: username, Password: "test-password"})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if newPassword != "test-password" {
+		t.Fatalf("bad: password: %q", newPassword)
+	}
+
+	if err := testCredsExist(t, connURL, username, newPassword); err != nil {
+		t.Fatalf("Could not connect with new credentials: %s", err)
+	}
+}
+
+func TestPostgreSQL_RotateRootCredentials(t *testing.T) {
+	cleanup, connURL := preparePostgresTestContainer(t)
+	defer cleanup()
+
+	connectionDetails := map[string]interface{}{
+		"connection_url": connURL,
+	}
+
+	dbRaw, _ := New()
+	db := dbRaw.(*PostgreSQL)
+	err := db.Initialize(context.Background(), connectionDetails, true)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	statements := dbplugin.Statements{
+		CreationStatements: testPostgresRole,
+	}
+
+	usernameConfig := dbplugin.UsernameConfig{
+		DisplayName: "test",
+		RoleName:    "test",
+	}
+
+	// Use a created user as the root user, which can change its own password
+	username, password, err := db.CreateUser(context.Background(), statements, usernameConfig, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	rootConnectionDetails := map[string]interface{}{
+		"connection_url": strings.Replace(connURL, "postgres:secret", "{{username}}:{{password}}", 1),
+		"username":       username,
+		"password":       password,
+	}
+
+	rootRaw, _ := New()
+	root := rootRaw.(*PostgreSQL)
+	err = root.Initialize(context.Background(), rootConnectionDetails, true)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer root.Close()
+
+	// Test without a password
+	dbRaw, _ = New()
+	noPassword := dbRaw.(*PostgreSQL)
+	err = noPassword.Initialize(context.Background(), connectionDetails, false)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := noPassword.RotateRootCredentials(context.Background(), nil); err == nil {
+		t.Fatal("Expected error when no username and password are configured")
+	}
+
+	config, err := root.RotateRootCredentials(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	newPassword := config["password"].(string)
+	if newPassword == "" || newPassword == password || config["username"] != username {
+		t.Fatalf("bad: %#v", config)
+	}
+
+	if err := testCredsExist(t, connURL, username, newPassword); err != nil {
+		t.Fatalf("Could not connect with new credentials: %s", err)
+	}
+	if err := testCredsExist(t, connURL, username, password); err == nil {
+		t.Fatal("Credentials were not rotated")
+	}
+
+	// The connection is established again with the new password
+	if _, err := root.getConnection(context.Background()); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
 func testCredsExist(t testing.TB, connURL, username, password string) error {
 	// Log in with the new creds
 	connURL = strings.Replace(connURL, "postgres:secret", fmt.Sprintf("%s:%s", username, password), 1)
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/hashicorp/vault/helper/parseutil"
+	"github.com/hashicorp/vault/plugins/helper/database/dbutil"
 	"github.com/mitchellh/mapstructure"
 )
 
@@ -18,8 +19,15 @@ type SQLConnectionProducer struct {
 	MaxOpenConnections       int         `json:"max_open_connections" structs:"max_open_connections" mapstructure:"max_open_connections"`
 	MaxIdleConnections       int         `json:"max_idle_connections" structs:"max_idle_connections" mapstructure:"max_idle_connections"`
 	MaxConnectionLifetimeRaw interface{} `json:"max_connection_lifetime" structs:"max_connection_lifetime" mapstructure:"max_connection_lifetime"`
+	Username                 string      `json:"username" structs:"username" mapstructure:"username"`
+	Password                 string      `json:"password" structs:"password" mapstructure:"password"`
+
+	// RawConfig is the connection configuration the producer was
+	// initialized with, which is updated when the password is rotated
+	RawConfig map[string]interface{}
 
 	Type                  string
+	connectionURL         string
 	maxConnectionLifetime time.Duration
 	Initialized           bool
 	db                    *sql.DB
@@ -30,6 +38,11 @@ func (c *SQLConnectionProducer) Initialize(ctx context.Context, conf map[string]
 	c.Lock()
 	defer c.Unlock()
 
+	c.RawConfig = make(map[string]interface{}, len(conf))
+	for k, v := range conf {
+		c.RawConfig[k] = v
+	}
+
 	err := mapstructure.WeakDecode(conf, c)
 	if err != nil {
 		return err
@@ -39,6 +52,8 @@ func (c *SQLConnectionProducer) Initialize(ctx context.Context, conf map[string]
 		return fmt.Errorf("connection_url cannot be empty")
 	}
 
+	c.connectionURL = c.templatedConnectionURL()
+
 	if c.MaxOpenConnections == 0 {
 		c.MaxOpenConnections = 2
 	}
@@ -97,7 +112,7 @@ func (c *SQLConnectionProducer) Connection(ctx context.Context) (interface{}, er
 	}
 
 	// Otherwise, attempt to make connection
-	conn := c.ConnectionURL
+	conn := c.connectionURL
 
 	// Ensure timezone is set to UTC for all the conenctions
 	if strings.HasPrefix(conn, "postgres://") || strings.HasPrefix(conn, "postgresql://") {
@@ -123,6 +138,33 @@ func (c *SQLConnectionProducer) Connection(ctx context.Context) (interface{}, er
 	return c.db, nil
 }
 
+// templatedConnectionURL returns the connection URL with the username and
+// password substituted for the "{{username}}" and "{{password}}" values
+func (c *SQLConnectionProducer) templatedConnectionURL() string {
+	return dbutil.QueryHelper(c.ConnectionURL, map[string]string{
+		"username": c.Username,
+		"password": c.Password,
+	})
+}
+
+// SetPassword updates the password of the connection after it has been
+// rotated in the database and returns the updated connection configuration.
+// The current connection is closed so that the next one is established with
+// the new password. The caller of this function needs to hold the producer's
+// lock.
+func (c *SQLConnectionProducer) SetPassword(password string) map[string]interface{} {
+	c.Password = password
+	c.RawConfig["password"] = password
+	c.connectionURL = c.templatedConnectionURL()
+
+	if c.db != nil {
+		c.db.Close()
+		c.db = nil
+	}
+
+	return c.RawConfig
+}
+
 // Close attempts to close the connection
 func (c *SQLConnectionProducer) Close() error {
 	// Grab the write lock
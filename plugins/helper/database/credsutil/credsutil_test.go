@@ -1,8 +1,11 @@
 package credsutil
 
 import (
+	"regexp"
 	"strings"
 	"testing"
+
+	"github.com/hashicorp/vault/builtin/logical/database/dbplugin"
 )
 
 func TestRandomAlphaNumeric(t *testing.T) {
@@ -38,3 +41,44 @@ func TestRandomAlphaNumeric(t *testing.T) {
 		t.Fatalf("Expected %s not to contain %s", s, reqStr)
 	}
 }
+
+func TestSQLCredentialsProducer_GenerateUsername(t *testing.T) {
+	scp := &SQLCredentialsProducer{
+		DisplayNameLen: 8,
+		RoleNameLen:    8,
+		UsernameLen:    30,
+		Separator:      "-",
+	}
+
+	username, err := scp.GenerateUsername(dbplugin.UsernameConfig{
+		DisplayName: "token",
+		RoleName:    "readonly-role",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(username, "v-token-readonly-") || len(username) != 30 {
+		t.Fatalf("bad: %s", username)
+	}
+
+	username, err = scp.GenerateUsername(dbplugin.UsernameConfig{
+		DisplayName: "token",
+		RoleName:    "readonly-role",
+		Template:    `{{.RoleName | replace "-" "_"}}_{{random 8 | lowercase}}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !regexp.MustCompile(`^readonly_role_[a-z0-9]{8}$`).MatchString(username) {
+		t.Fatalf("bad: %s", username)
+	}
+
+	// Templated usernames are not truncated
+	_, err = scp.GenerateUsername(dbplugin.UsernameConfig{
+		RoleName: "readonly-role",
+		Template: `{{.RoleName}}-{{random 20}}`,
+	})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
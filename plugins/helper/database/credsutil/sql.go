@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/hashicorp/vault/builtin/logical/database/dbplugin"
+	"github.com/hashicorp/vault/helper/template"
 )
 
 const (
@@ -20,6 +21,10 @@ type SQLCredentialsProducer struct {
 }
 
 func (scp *SQLCredentialsProducer) GenerateUsername(config dbplugin.UsernameConfig) (string, error) {
+	if config.Template != "" {
+		return scp.generateTemplatedUsername(config)
+	}
+
 	username := "v"
 
 	displayName := config.DisplayName
@@ -58,6 +63,26 @@ func (scp *SQLCredentialsProducer) GenerateUsername(config dbplugin.UsernameConf
 	return username, nil
 }
 
+// generateTemplatedUsername renders the username template of the role. The
+// username is not truncated, since that could remove the random part of it, so
+// templates generating usernames which are too long are an error.
+func (scp *SQLCredentialsProducer) generateTemplatedUsername(config dbplugin.UsernameConfig) (string, error) {
+	tmpl, err := template.Parse(config.Template)
+	if err != nil {
+		return "", fmt.Errorf("invalid username template: %s", err)
+	}
+
+	username, err := tmpl.Generate(config)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate username from template: %s", err)
+	}
+	if scp.UsernameLen > 0 && len(username) > scp.UsernameLen {
+		return "", fmt.Errorf("generated username %q is longer than the maximum of %d characters", username, scp.UsernameLen)
+	}
+
+	return username, nil
+}
+
 func (scp *SQLCredentialsProducer) GeneratePassword() (string, error) {
 	password, err := RandomAlphaNumeric(20, true)
 	if err != nil {
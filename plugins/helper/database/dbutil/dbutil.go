@@ -8,6 +8,8 @@ import (
 
 var (
 	ErrEmptyCreationStatement = errors.New("empty creation statements")
+	ErrEmptyRotationStatement = errors.New("empty rotation statements")
+	ErrEmptyUsername          = errors.New("empty username")
 )
 
 // Query templates a query for us.
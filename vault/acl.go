@@ -44,6 +44,9 @@ type ACLResults struct {
 	RootPrivs  bool
 	IsRoot     bool
 	MFAMethods []string
+
+	// ControlGroup is the control group of the matching path, if any
+	ControlGroup *ControlGroup
 }
 
 // New is used to construct a policy based ACL from a set of policies.
@@ -68,8 +71,9 @@ func NewACL(policies []*Policy) (*ACL, error) {
 			return nil, fmt.Errorf("unable to parse policy (wrong type)")
 		}
 
-		// Check if this is root
-		if policy.Name == "root" {
+		// Check if this is root. Only the root policy of the root namespace
+		// grants root privileges.
+		if policy.Name == "root" && (policy.namespace == nil || policy.namespace.isRoot()) {
 			a.root = true
 		}
 		for _, pc := range policy.Paths {
@@ -79,14 +83,21 @@ func NewACL(policies []*Policy) (*ACL, error) {
 				tree = a.globRules
 			}
 
+			// The paths of the policies of child namespaces are relative to
+			// the namespace
+			prefix := pc.Prefix
+			if policy.namespace != nil {
+				prefix = policy.namespace.Path + prefix
+			}
+
 			// Check for an existing policy
-			raw, ok := tree.Get(pc.Prefix)
+			raw, ok := tree.Get(prefix)
 			if !ok {
 				clonedPerms, err := pc.Permissions.Clone()
 				if err != nil {
 					return nil, errwrap.Wrapf("error cloning ACL permissions: {{err}}", err)
 				}
-				tree.Insert(pc.Prefix, clonedPerms)
+				tree.Insert(prefix, clonedPerms)
 				continue
 			}
 
@@ -104,6 +115,7 @@ func NewACL(policies []*Policy) (*ACL, error) {
 				existingPerms.CapabilitiesBitmap = DenyCapabilityInt
 				existingPerms.AllowedParameters = nil
 				existingPerms.DeniedParameters = nil
+				existingPerms.ControlGroup = nil
 				goto INSERT
 
 			default:
@@ -182,8 +194,12 @@ func NewACL(policies []*Policy) (*ACL, error) {
 				}
 			}
 
+			// The factors of all the control groups of the path must be
+			// satisfied
+			existingPerms.ControlGroup = existingPerms.ControlGroup.merge(pc.Permissions.ControlGroup)
+
 		INSERT:
-			tree.Insert(pc.Prefix, existingPerms)
+			tree.Insert(prefix, existingPerms)
 		}
 	}
 	return a, nil
@@ -233,6 +249,9 @@ CHECK:
 	if capabilities&CreateCapabilityInt > 0 {
 		pathCapabilities = append(pathCapabilities, CreateCapability)
 	}
+	if capabilities&PatchCapabilityInt > 0 {
+		pathCapabilities = append(pathCapabilities, PatchCapability)
+	}
 
 	// If "deny" is explicitly set or if the path has no capabilities at all,
 	// set the path capabilities to "deny"
@@ -300,6 +319,8 @@ CHECK:
 		operationAllowed = capabilities&DeleteCapabilityInt > 0
 	case logical.CreateOperation:
 		operationAllowed = capabilities&CreateCapabilityInt > 0
+	case logical.PatchOperation:
+		operationAllowed = capabilities&PatchCapabilityInt > 0
 
 	// These three re-use UpdateCapabilityInt since that's the most appropriate
 	// capability/operation mapping
@@ -314,6 +335,8 @@ CHECK:
 		return
 	}
 
+	ret.ControlGroup = permissions.ControlGroup
+
 	if permissions.MaxWrappingTTL > 0 {
 		if req.WrapInfo == nil || req.WrapInfo.TTL > permissions.MaxWrappingTTL {
 			return
@@ -334,7 +357,7 @@ CHECK:
 
 	// Only check parameter permissions for operations that can modify
 	// parameters.
-	if op == logical.UpdateOperation || op == logical.CreateOperation {
+	if op == logical.UpdateOperation || op == logical.CreateOperation || op == logical.PatchOperation {
 		for _, parameter := range permissions.RequiredParameters {
 			if _, ok := req.Data[strings.ToLower(parameter)]; !ok {
 				return
@@ -423,6 +423,67 @@ func TestACL_ValuePermissions(t *testing.T) {
 }
 
 // NOTE: this test doesn't catch any races ATM
+func TestACL_Patch(t *testing.T) {
+	policy, err := ParseACLPolicy(patchPolicy)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	acl, err := NewACL([]*Policy{policy})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	actual := acl.Capabilities("secret/patch")
+	expected := []string{"read", "patch"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: got\n%#v\nexpected\n%#v\n", actual, expected)
+	}
+
+	type tcase struct {
+		op         logical.Operation
+		path       string
+		parameters []string
+		allowed    bool
+	}
+	tcases := []tcase{
+		{logical.PatchOperation, "secret/patch", []string{"data"}, true},
+		{logical.UpdateOperation, "secret/patch", []string{"data"}, false},
+		{logical.PatchOperation, "secret/update", []string{"data"}, false},
+		{logical.UpdateOperation, "secret/update", []string{"data"}, true},
+		{logical.PatchOperation, "secret/params", []string{"data"}, true},
+		{logical.PatchOperation, "secret/params", []string{"options"}, false},
+	}
+	for _, tc := range tcases {
+		request := &logical.Request{
+			Operation: tc.op,
+			Path:      tc.path,
+			Data:      make(map[string]interface{}),
+		}
+		for _, parameter := range tc.parameters {
+			request.Data[parameter] = ""
+		}
+		if authResults := acl.AllowOperation(request); authResults.Allowed != tc.allowed {
+			t.Fatalf("bad: case %#v: %v", tc, authResults.Allowed)
+		}
+	}
+}
+
+var patchPolicy = `
+name = "patch"
+path "secret/patch" {
+	capabilities = ["read", "patch"]
+}
+path "secret/update" {
+	capabilities = ["update"]
+}
+path "secret/params" {
+	capabilities = ["patch"]
+	allowed_parameters = {
+		"data" = []
+	}
+}
+`
+
 func TestACL_CreationRace(t *testing.T) {
 	policy, err := ParseACLPolicy(valuePermissionsPolicy)
 	if err != nil {
@@ -0,0 +1,748 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/helper/consts"
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+const (
+	// coreActivityLogConfigPath is the path of the configuration of the
+	// activity log
+	coreActivityLogConfigPath = "core/activity-log-config"
+
+	// coreActivityLogPath is the prefix of the barrier entries of the
+	// activity log, which are stored by month
+	coreActivityLogPath = "core/activity/"
+
+	// activityMonthFormat is the format of the months of the activity log,
+	// which sort chronologically
+	activityMonthFormat = "2006-01"
+
+	// defaultActivityRetentionMonths is the number of months of activity
+	// kept by default
+	defaultActivityRetentionMonths = 24
+
+	// activityClientEntity and activityClientNonEntityToken are the types of
+	// the clients of the activity log
+	activityClientEntity         = "entity"
+	activityClientNonEntityToken = "non-entity-token"
+)
+
+// activityLogWriteInterval is how often the active node persists the
+// activity of the current month. It's var so that tests can lower it.
+var activityLogWriteInterval = time.Minute
+
+// ActivityLogConfig is the configuration of the activity log, which records
+// the distinct clients of each mount by month
+type ActivityLogConfig struct {
+	Disabled        bool `json:"disabled"`
+	RetentionMonths int  `json:"retention_months"`
+}
+
+// defaultActivityLogConfig returns the configuration of the cores which were
+// never configured
+func defaultActivityLogConfig() *ActivityLogConfig {
+	return &ActivityLogConfig{
+		RetentionMonths: defaultActivityRetentionMonths,
+	}
+}
+
+// activityMonth is the activity of the clients in a month, by mount accessor
+type activityMonth struct {
+	Month  string                    `json:"month"`
+	Mounts map[string]*mountActivity `json:"mounts"`
+}
+
+// mountActivity is the activity of the clients of a mount, with the time
+// each client was first seen in the month. Tokens without an entity are
+// identified by the hash of their ID.
+type mountActivity struct {
+	NamespaceID     string               `json:"namespace_id,omitempty"`
+	Path            string               `json:"path"`
+	Entities        map[string]time.Time `json:"entities,omitempty"`
+	NonEntityTokens map[string]time.Time `json:"non_entity_tokens,omitempty"`
+}
+
+func newActivityMonth(month string) *activityMonth {
+	return &activityMonth{
+		Month:  month,
+		Mounts: make(map[string]*mountActivity),
+	}
+}
+
+// activityLog holds the activity of the current month until it's persisted
+// by the active node
+type activityLog struct {
+	lock    sync.Mutex
+	config  *ActivityLogConfig
+	current *activityMonth
+	dirty   bool
+
+	// pending are the previous months not persisted yet
+	pending []*activityMonth
+
+	stopCh chan struct{}
+}
+
+// activityClientID returns the type and the ID of the client of a token
+func activityClientID(te *TokenEntry) (string, string) {
+	if te.EntityID != "" {
+		return activityClientEntity, te.EntityID
+	}
+	sum := sha256.Sum256([]byte(te.ID))
+	return activityClientNonEntityToken, hex.EncodeToString(sum[:])
+}
+
+// recordToken records the client of the token as active on the mount
+func (a *activityLog) recordToken(te *TokenEntry, entry *MountEntry) {
+	if a == nil || te == nil || entry == nil {
+		return
+	}
+	a.record(te, entry, time.Now().UTC())
+}
+
+func (a *activityLog) record(te *TokenEntry, entry *MountEntry, now time.Time) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.config.Disabled {
+		return
+	}
+
+	if month := now.Format(activityMonthFormat); a.current.Month != month {
+		if a.dirty {
+			a.pending = append(a.pending, a.current)
+		}
+		a.current = newActivityMonth(month)
+		a.dirty = false
+	}
+
+	mount, ok := a.current.Mounts[entry.Accessor]
+	if !ok {
+		path := entry.Path
+		if entry.Table == credentialTableType {
+			path = credentialRoutePrefix + path
+		}
+		mount = &mountActivity{
+			NamespaceID:     entry.NamespaceID,
+			Path:            path,
+			Entities:        make(map[string]time.Time),
+			NonEntityTokens: make(map[string]time.Time),
+		}
+		a.current.Mounts[entry.Accessor] = mount
+	}
+
+	clients := mount.NonEntityTokens
+	clientType, clientID := activityClientID(te)
+	if clientType == activityClientEntity {
+		clients = mount.Entities
+	}
+	if _, ok := clients[clientID]; !ok {
+		clients[clientID] = now
+		a.dirty = true
+	}
+}
+
+// activityMonthKey returns the barrier key of the activity of a month
+func activityMonthKey(month string) string {
+	return coreActivityLogPath + month
+}
+
+// setupActivityLog is invoked as part of postUnseal to load the configuration
+// and the activity of the current month of the activity log, and to start
+// persisting it periodically
+func (c *Core) setupActivityLog(ctx context.Context) error {
+	config := defaultActivityLogConfig()
+	raw, err := c.barrier.Get(ctx, coreActivityLogConfigPath)
+	if err != nil {
+		return fmt.Errorf("failed to read activity log config: %v", err)
+	}
+	if raw != nil {
+		if err := jsonutil.DecodeJSON(raw.Value, config); err != nil {
+			return fmt.Errorf("failed to decode activity log config: %v", err)
+		}
+	}
+
+	month := time.Now().UTC().Format(activityMonthFormat)
+	current, err := c.loadActivityMonth(ctx, month)
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		current = newActivityMonth(month)
+	}
+
+	c.activityLog = &activityLog{
+		config:  config,
+		current: current,
+		stopCh:  make(chan struct{}),
+	}
+	if err := c.tidyActivityLog(ctx, config.RetentionMonths); err != nil {
+		c.logger.Error("core: failed to remove expired activity", "error", err)
+	}
+
+	go c.activityLogLoop(ctx, c.activityLog.stopCh)
+	return nil
+}
+
+// teardownActivityLog stops the periodic writes of the activity log, after
+// persisting the activity not written yet
+func (c *Core) teardownActivityLog() {
+	if c.activityLog == nil {
+		return
+	}
+	close(c.activityLog.stopCh)
+
+	if err := c.persistActivityLog(context.Background()); err != nil && err != ErrBarrierSealed {
+		c.logger.Error("core: failed to persist the activity log", "error", err)
+	}
+	c.activityLog = nil
+}
+
+func (c *Core) activityLogLoop(ctx context.Context, stopCh chan struct{}) {
+	ticker := time.NewTicker(activityLogWriteInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.persistActivityLog(ctx); err != nil {
+				c.logger.Error("core: failed to persist the activity log", "error", err)
+			}
+		case <-stopCh:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// persistActivityLog writes the months of the activity log which changed
+// since they were last written. Expired months are removed once a month is
+// over.
+func (c *Core) persistActivityLog(ctx context.Context) error {
+	a := c.activityLog
+	if a == nil {
+		return nil
+	}
+
+	a.lock.Lock()
+	pending := a.pending
+	months := append([]*activityMonth{}, pending...)
+	if a.dirty {
+		months = append(months, a.current)
+	}
+	entries := make([]*Entry, 0, len(months))
+	for _, month := range months {
+		raw, err := jsonutil.EncodeJSON(month)
+		if err != nil {
+			a.lock.Unlock()
+			return err
+		}
+		entries = append(entries, &Entry{
+			Key:   activityMonthKey(month.Month),
+			Value: raw,
+		})
+	}
+	retention := a.config.RetentionMonths
+	a.pending = nil
+	a.dirty = false
+	a.lock.Unlock()
+
+	for _, entry := range entries {
+		if err := c.barrier.Put(ctx, entry); err != nil {
+			// The months are written again with the next write
+			a.lock.Lock()
+			a.pending = append(pending, a.pending...)
+			a.dirty = true
+			a.lock.Unlock()
+			return err
+		}
+	}
+
+	if len(pending) > 0 {
+		return c.tidyActivityLog(ctx, retention)
+	}
+	return nil
+}
+
+// loadActivityMonth reads the activity of a month, which is nil if none was
+// persisted
+func (c *Core) loadActivityMonth(ctx context.Context, month string) (*activityMonth, error) {
+	raw, err := c.barrier.Get(ctx, activityMonthKey(month))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read activity of %s: %v", month, err)
+	}
+	if raw == nil {
+		return nil, nil
+	}
+
+	activity := newActivityMonth(month)
+	if err := jsonutil.DecodeJSON(raw.Value, activity); err != nil {
+		return nil, fmt.Errorf("failed to decode activity of %s: %v", month, err)
+	}
+	return activity, nil
+}
+
+// activityMonthsBetween reads the persisted activity of the months between
+// the given months, inclusive, in chronological order
+func (c *Core) activityMonthsBetween(ctx context.Context, start, end string) ([]*activityMonth, error) {
+	keys, err := c.barrier.List(ctx, coreActivityLogPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list activity: %v", err)
+	}
+	sort.Strings(keys)
+
+	var months []*activityMonth
+	for _, month := range keys {
+		if month < start || month > end {
+			continue
+		}
+		activity, err := c.loadActivityMonth(ctx, month)
+		if err != nil {
+			return nil, err
+		}
+		if activity != nil {
+			months = append(months, activity)
+		}
+	}
+	return months, nil
+}
+
+// tidyActivityLog removes the activity of the months older than the
+// retention
+func (c *Core) tidyActivityLog(ctx context.Context, retentionMonths int) error {
+	cutoff := time.Now().UTC().AddDate(0, -retentionMonths, 0).Format(activityMonthFormat)
+
+	keys, err := c.barrier.List(ctx, coreActivityLogPath)
+	if err != nil {
+		return err
+	}
+	for _, month := range keys {
+		if month >= cutoff {
+			continue
+		}
+		if err := c.barrier.Delete(ctx, activityMonthKey(month)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setActivityLogConfig persists the configuration of the activity log and
+// applies it
+func (c *Core) setActivityLogConfig(ctx context.Context, config *ActivityLogConfig) error {
+	entry, err := logical.StorageEntryJSON(coreActivityLogConfigPath, config)
+	if err != nil {
+		return fmt.Errorf("failed to create activity log config entry: %v", err)
+	}
+	if err := c.barrier.Put(ctx, &Entry{
+		Key:   entry.Key,
+		Value: entry.Value,
+	}); err != nil {
+		return fmt.Errorf("failed to save activity log config: %v", err)
+	}
+
+	c.activityLog.lock.Lock()
+	c.activityLog.config = config
+	c.activityLog.lock.Unlock()
+
+	return c.tidyActivityLog(ctx, config.RetentionMonths)
+}
+
+// activityRecord is a client active on a mount in a month, as exported
+type activityRecord struct {
+	Month         string `json:"month"`
+	NamespaceID   string `json:"namespace_id"`
+	NamespacePath string `json:"namespace_path"`
+	MountAccessor string `json:"mount_accessor"`
+	MountPath     string `json:"mount_path"`
+	ClientType    string `json:"client_type"`
+	ClientID      string `json:"client_id"`
+	FirstSeen     string `json:"first_seen"`
+}
+
+// activityRecords returns the clients of the months active on the mounts of
+// the namespace or of its descendants, sorted by month, mount and client
+func (c *Core) activityRecords(ns *Namespace, months []*activityMonth) []*activityRecord {
+	var records []*activityRecord
+	for _, month := range months {
+		accessors := make([]string, 0, len(month.Mounts))
+		for accessor := range month.Mounts {
+			accessors = append(accessors, accessor)
+		}
+		sort.Strings(accessors)
+
+		for _, accessor := range accessors {
+			mount := month.Mounts[accessor]
+
+			// The activity of deleted namespaces is only reported to the
+			// root namespace
+			mountNS := c.namespaceByID(mount.NamespaceID)
+			nsPath := ""
+			switch {
+			case mountNS != nil && mountNS.isWithin(ns):
+				nsPath = mountNS.Path
+			case mountNS == nil && ns.isRoot():
+			default:
+				continue
+			}
+
+			add := func(clientType string, clients map[string]time.Time) {
+				ids := make([]string, 0, len(clients))
+				for id := range clients {
+					ids = append(ids, id)
+				}
+				sort.Strings(ids)
+				for _, id := range ids {
+					records = append(records, &activityRecord{
+						Month:         month.Month,
+						NamespaceID:   mount.NamespaceID,
+						NamespacePath: nsPath,
+						MountAccessor: accessor,
+						MountPath:     mount.Path,
+						ClientType:    clientType,
+						ClientID:      id,
+						FirstSeen:     clients[id].Format(time.RFC3339),
+					})
+				}
+			}
+			add(activityClientEntity, mount.Entities)
+			add(activityClientNonEntityToken, mount.NonEntityTokens)
+		}
+	}
+	return records
+}
+
+// activityCounts counts the distinct clients of activity records
+type activityCounts struct {
+	entities  map[string]struct{}
+	nonEntity map[string]struct{}
+}
+
+func newActivityCounts() *activityCounts {
+	return &activityCounts{
+		entities:  make(map[string]struct{}),
+		nonEntity: make(map[string]struct{}),
+	}
+}
+
+func (a *activityCounts) add(record *activityRecord) {
+	if record.ClientType == activityClientEntity {
+		a.entities[record.ClientID] = struct{}{}
+		return
+	}
+	a.nonEntity[record.ClientID] = struct{}{}
+}
+
+func (a *activityCounts) data() map[string]interface{} {
+	return map[string]interface{}{
+		"entity_clients":     len(a.entities),
+		"non_entity_clients": len(a.nonEntity),
+		"clients":            len(a.entities) + len(a.nonEntity),
+	}
+}
+
+func activityLogPaths(b *SystemBackend) []*framework.Path {
+	timeFields := map[string]*framework.FieldSchema{
+		"start_time": &framework.FieldSchema{
+			Type:        framework.TypeString,
+			Description: strings.TrimSpace(sysHelp["activity-start-time"][0]),
+		},
+		"end_time": &framework.FieldSchema{
+			Type:        framework.TypeString,
+			Description: strings.TrimSpace(sysHelp["activity-end-time"][0]),
+		},
+	}
+
+	return []*framework.Path{
+		{
+			Pattern: "internal/counters/config$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"enabled": &framework.FieldSchema{
+					Type:        framework.TypeBool,
+					Description: strings.TrimSpace(sysHelp["activity-enabled"][0]),
+				},
+				"retention_months": &framework.FieldSchema{
+					Type:        framework.TypeInt,
+					Description: strings.TrimSpace(sysHelp["activity-retention-months"][0]),
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation:   b.handleActivityConfigRead,
+				logical.UpdateOperation: b.handleActivityConfigUpdate,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["activity-config"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["activity-config"][1]),
+		},
+		{
+			Pattern: "internal/counters/activity$",
+
+			Fields: timeFields,
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation: b.handleActivityQuery,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["activity-query"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["activity-query"][1]),
+		},
+		{
+			Pattern: "internal/counters/activity/export$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"start_time": timeFields["start_time"],
+				"end_time":   timeFields["end_time"],
+				"format": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Default:     "json",
+					Description: strings.TrimSpace(sysHelp["activity-export-format"][0]),
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation: b.handleActivityExport,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["activity-export"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["activity-export"][1]),
+		},
+	}
+}
+
+func (b *SystemBackend) handleActivityConfigRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	// Only the active node records the activity
+	if b.Core.activityLog == nil {
+		return nil, consts.ErrPerformanceStandbyForward
+	}
+
+	b.Core.activityLog.lock.Lock()
+	config := b.Core.activityLog.config
+	b.Core.activityLog.lock.Unlock()
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"enabled":          !config.Disabled,
+			"retention_months": config.RetentionMonths,
+		},
+	}, nil
+}
+
+func (b *SystemBackend) handleActivityConfigUpdate(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	if b.Core.activityLog == nil {
+		return nil, consts.ErrPerformanceStandbyForward
+	}
+
+	b.Core.activityLog.lock.Lock()
+	config := *b.Core.activityLog.config
+	b.Core.activityLog.lock.Unlock()
+
+	if enabled, ok := d.GetOk("enabled"); ok {
+		config.Disabled = !enabled.(bool)
+	}
+	if retention, ok := d.GetOk("retention_months"); ok {
+		config.RetentionMonths = retention.(int)
+	}
+	if config.RetentionMonths < 1 {
+		return logical.ErrorResponse("retention_months must be at least 1"), logical.ErrInvalidRequest
+	}
+
+	if err := b.Core.setActivityLogConfig(ctx, &config); err != nil {
+		b.Backend.Logger().Error("sys: failed to set activity log config", "error", err)
+		return handleError(err)
+	}
+	return nil, nil
+}
+
+// activityForRequest returns the activity records of the namespace of the
+// request in the time range of the request
+func (b *SystemBackend) activityForRequest(ctx context.Context, d *framework.FieldData) ([]*activityRecord, time.Time, time.Time, error) {
+	if b.Core.activityLog == nil {
+		return nil, time.Time{}, time.Time{}, consts.ErrPerformanceStandbyForward
+	}
+
+	var start, end time.Time
+	for name, t := range map[string]*time.Time{"start_time": &start, "end_time": &end} {
+		raw := d.Get(name).(string)
+		if raw == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return nil, time.Time{}, time.Time{}, logical.CodedError(400, fmt.Sprintf("invalid %s: %v", name, err))
+		}
+		*t = parsed.UTC()
+	}
+	if end.IsZero() {
+		end = time.Now().UTC()
+	}
+	if start.After(end) {
+		return nil, time.Time{}, time.Time{}, logical.CodedError(400, "start_time is after end_time")
+	}
+
+	// The current month is written before it's read back
+	if err := b.Core.persistActivityLog(ctx); err != nil {
+		return nil, time.Time{}, time.Time{}, err
+	}
+
+	startMonth := ""
+	if !start.IsZero() {
+		startMonth = start.Format(activityMonthFormat)
+	}
+	months, err := b.Core.activityMonthsBetween(ctx, startMonth, end.Format(activityMonthFormat))
+	if err != nil {
+		return nil, time.Time{}, time.Time{}, err
+	}
+	return b.Core.activityRecords(namespaceFromContext(ctx), months), start, end, nil
+}
+
+// handleActivityQuery returns the distinct clients active in the time range,
+// in total, by namespace and mount, and by month
+func (b *SystemBackend) handleActivityQuery(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	records, start, end, err := b.activityForRequest(ctx, d)
+	if err != nil {
+		return handleError(err)
+	}
+
+	total := newActivityCounts()
+	byMonth := make(map[string]*activityCounts)
+	byNamespace := make(map[string]*activityCounts)
+	byMount := make(map[string]*activityCounts)
+	var months, namespaces, mounts []*activityRecord
+	for _, record := range records {
+		total.add(record)
+
+		if _, ok := byMonth[record.Month]; !ok {
+			byMonth[record.Month] = newActivityCounts()
+			months = append(months, record)
+		}
+		byMonth[record.Month].add(record)
+
+		if _, ok := byNamespace[record.NamespaceID]; !ok {
+			byNamespace[record.NamespaceID] = newActivityCounts()
+			namespaces = append(namespaces, record)
+		}
+		byNamespace[record.NamespaceID].add(record)
+
+		if _, ok := byMount[record.MountAccessor]; !ok {
+			byMount[record.MountAccessor] = newActivityCounts()
+			mounts = append(mounts, record)
+		}
+		byMount[record.MountAccessor].add(record)
+	}
+
+	monthsData := make([]map[string]interface{}, 0, len(months))
+	for _, record := range months {
+		monthsData = append(monthsData, map[string]interface{}{
+			"month":  record.Month,
+			"counts": byMonth[record.Month].data(),
+		})
+	}
+
+	sort.SliceStable(namespaces, func(i, j int) bool {
+		return namespaces[i].NamespacePath < namespaces[j].NamespacePath
+	})
+	sort.SliceStable(mounts, func(i, j int) bool {
+		return mounts[i].MountPath < mounts[j].MountPath
+	})
+	namespacesData := make([]map[string]interface{}, 0, len(namespaces))
+	for _, ns := range namespaces {
+		mountsData := []map[string]interface{}{}
+		for _, mount := range mounts {
+			if mount.NamespaceID != ns.NamespaceID {
+				continue
+			}
+			mountsData = append(mountsData, map[string]interface{}{
+				"mount_accessor": mount.MountAccessor,
+				"mount_path":     mount.MountPath,
+				"counts":         byMount[mount.MountAccessor].data(),
+			})
+		}
+		namespacesData = append(namespacesData, map[string]interface{}{
+			"namespace_id":   ns.NamespaceID,
+			"namespace_path": ns.NamespacePath,
+			"counts":         byNamespace[ns.NamespaceID].data(),
+			"mounts":         mountsData,
+		})
+	}
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"end_time":     end.Format(time.RFC3339),
+			"total":        total.data(),
+			"by_namespace": namespacesData,
+			"months":       monthsData,
+		},
+	}
+	if !start.IsZero() {
+		resp.Data["start_time"] = start.Format(time.RFC3339)
+	}
+	return resp, nil
+}
+
+// handleActivityExport returns the clients active on each mount in each month
+// of the time range, as JSON lines or CSV
+func (b *SystemBackend) handleActivityExport(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	format := d.Get("format").(string)
+	if format != "json" && format != "csv" {
+		return logical.ErrorResponse(fmt.Sprintf("unsupported format %q", format)), logical.ErrInvalidRequest
+	}
+
+	records, _, _, err := b.activityForRequest(ctx, d)
+	if err != nil {
+		return handleError(err)
+	}
+
+	body, contentType, err := encodeActivityRecords(records, format)
+	if err != nil {
+		return nil, err
+	}
+	return &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPContentType: contentType,
+			logical.HTTPRawBody:     body,
+			logical.HTTPStatusCode:  200,
+		},
+	}, nil
+}
+
+// encodeActivityRecords encodes the records as JSON lines or CSV and returns
+// the content type of the encoding
+func encodeActivityRecords(records []*activityRecord, format string) ([]byte, string, error) {
+	var buf bytes.Buffer
+	switch format {
+	case "csv":
+		w := csv.NewWriter(&buf)
+		w.Write([]string{"month", "namespace_id", "namespace_path", "mount_accessor", "mount_path", "client_type", "client_id", "first_seen"})
+		for _, r := range records {
+			w.Write([]string{r.Month, r.NamespaceID, r.NamespacePath, r.MountAccessor, r.MountPath, r.ClientType, r.ClientID, r.FirstSeen})
+		}
+		w.Flush()
+		return buf.Bytes(), "text/csv", w.Error()
+	default:
+		enc := json.NewEncoder(&buf)
+		for _, r := range records {
+			if err := enc.Encode(r); err != nil {
+				return nil, "", err
+			}
+		}
+		return buf.Bytes(), "application/x-ndjson", nil
+	}
+}
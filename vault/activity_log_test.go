@@ -0,0 +1,97 @@
+package vault
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestActivityLog_record(t *testing.T) {
+	now := time.Date(2018, time.March, 31, 12, 0, 0, 0, time.UTC)
+	a := &activityLog{
+		config:  defaultActivityLogConfig(),
+		current: newActivityMonth(now.Format(activityMonthFormat)),
+	}
+	secret := &MountEntry{Table: mountTableType, Path: "secret/", Accessor: "kv_1234"}
+	userpass := &MountEntry{Table: credentialTableType, Path: "userpass/", Accessor: "auth_userpass_1234"}
+
+	a.record(&TokenEntry{ID: "token1", EntityID: "entity1"}, secret, now)
+	a.record(&TokenEntry{ID: "token2", EntityID: "entity1"}, secret, now.Add(time.Minute))
+	a.record(&TokenEntry{ID: "token3"}, secret, now)
+	a.record(&TokenEntry{ID: "token1", EntityID: "entity1"}, userpass, now)
+
+	mount := a.current.Mounts["kv_1234"]
+	if len(mount.Entities) != 1 || len(mount.NonEntityTokens) != 1 {
+		t.Fatalf("bad: %#v", mount)
+	}
+	if !mount.Entities["entity1"].Equal(now) {
+		t.Fatalf("expected the entity to be first seen at %s, got %s", now, mount.Entities["entity1"])
+	}
+	if path := a.current.Mounts["auth_userpass_1234"].Path; path != "auth/userpass/" {
+		t.Fatalf("bad: %q", path)
+	}
+	if !a.dirty {
+		t.Fatal("expected the month to be dirty")
+	}
+
+	// The activity of the previous month is kept until it's persisted
+	a.record(&TokenEntry{ID: "token1", EntityID: "entity1"}, secret, now.Add(24*time.Hour))
+	if len(a.pending) != 1 || a.pending[0].Month != "2018-03" || a.current.Month != "2018-04" {
+		t.Fatalf("bad: %#v %#v", a.pending, a.current)
+	}
+
+	a.config.Disabled = true
+	a.record(&TokenEntry{ID: "token4"}, secret, now.Add(24*time.Hour))
+	if len(a.current.Mounts["kv_1234"].NonEntityTokens) != 0 {
+		t.Fatal("expected no activity to be recorded when disabled")
+	}
+}
+
+func TestCore_ActivityLog(t *testing.T) {
+	c, b, root := testCoreSystemBackend(t)
+
+	req := &logical.Request{
+		Operation:   logical.UpdateOperation,
+		Path:        "secret/foo",
+		Data:        map[string]interface{}{"value": "bar"},
+		ClientToken: root,
+	}
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	resp, err := b.HandleRequest(context.Background(), logical.TestRequest(t, logical.ReadOperation, "internal/counters/activity"))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	total := resp.Data["total"].(map[string]interface{})
+	if total["non_entity_clients"] != 1 || total["entity_clients"] != 0 {
+		t.Fatalf("bad: %#v", total)
+	}
+
+	// The activity survives the teardown of the activity log
+	c.teardownActivityLog()
+	if err := c.setupActivityLog(context.Background()); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "internal/counters/activity/export")
+	req.Data["format"] = "csv"
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(resp.Data[logical.HTTPRawBody].([]byte))), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[1], ",secret/,non-entity-token,") {
+		t.Fatalf("bad: %q", lines)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "internal/counters/config")
+	req.Data["retention_months"] = 0
+	if _, err := b.HandleRequest(context.Background(), req); err != logical.ErrInvalidRequest {
+		t.Fatalf("expected an invalid request, got %v", err)
+	}
+}
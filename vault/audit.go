@@ -5,6 +5,7 @@ import (
 	"crypto/sha256"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -96,6 +97,11 @@ func (c *Core) enableAudit(ctx context.Context, entry *MountEntry) error {
 	view.setReadOnlyErr(logical.ErrSetupReadOnly)
 	defer view.setReadOnlyErr(nil)
 
+	filter, err := auditFilter(entry)
+	if err != nil {
+		return err
+	}
+
 	// Lookup the new backend
 	backend, err := c.newAuditBackend(ctx, entry, view, entry.Options)
 	if err != nil {
@@ -114,7 +120,7 @@ func (c *Core) enableAudit(ctx context.Context, entry *MountEntry) error {
 	c.audit = newTable
 
 	// Register the backend
-	c.auditBroker.Register(entry.Path, backend, view)
+	c.auditBroker.RegisterWithFilter(entry.Path, backend, view, filter)
 	if c.logger.IsInfo() {
 		c.logger.Info("core: enabled audit backend", "path", entry.Path, "type", entry.Type)
 	}
@@ -161,6 +167,13 @@ func (c *Core) disableAudit(ctx context.Context, path string) (bool, error) {
 		c.logger.Info("core: disabled audit backend", "path", path)
 	}
 
+	// The captured entries can't be exported anymore
+	if auditCaptureEnabled(entry) {
+		if err := logical.ClearView(ctx, c.auditCaptureView(entry)); err != nil {
+			c.logger.Error("core: failed to clear the captured audit entries", "path", path, "error", err)
+		}
+	}
+
 	return true, nil
 }
 
@@ -230,7 +243,8 @@ func (c *Core) loadAudits(ctx context.Context) error {
 		}
 	}
 
-	if !needPersist {
+	// Performance standbys leave persisting to the active node
+	if !needPersist || c.perfStandby {
 		return nil
 	}
 
@@ -315,6 +329,7 @@ func (c *Core) persistAudit(ctx context.Context, table *MountTable, localOnly bo
 // initialize the audit backends
 func (c *Core) setupAudits(ctx context.Context) error {
 	broker := NewAuditBroker(c.logger)
+	broker.router = c.router
 
 	c.auditLock.Lock()
 	defer c.auditLock.Unlock()
@@ -332,6 +347,12 @@ func (c *Core) setupAudits(ctx context.Context) error {
 		view.setReadOnlyErr(logical.ErrSetupReadOnly)
 		defer view.setReadOnlyErr(nil)
 
+		filter, err := auditFilter(entry)
+		if err != nil {
+			c.logger.Error("core: failed to create audit entry", "path", entry.Path, "error", err)
+			continue
+		}
+
 		// Initialize the backend
 		backend, err := c.newAuditBackend(ctx, entry, view, entry.Options)
 		if err != nil {
@@ -344,7 +365,7 @@ func (c *Core) setupAudits(ctx context.Context) error {
 		}
 
 		// Mount the backend
-		broker.Register(entry.Path, backend, view)
+		broker.RegisterWithFilter(entry.Path, backend, view, filter)
 
 		successCount += 1
 	}
@@ -369,6 +390,10 @@ func (c *Core) teardownAudits() error {
 		}
 	}
 
+	if c.auditBroker != nil {
+		c.auditBroker.closeAll()
+	}
+
 	c.audit = nil
 	c.auditBroker = nil
 	return nil
@@ -416,6 +441,21 @@ func (c *Core) newAuditBackend(ctx context.Context, entry *MountEntry, view logi
 		return nil, fmt.Errorf("nil backend returned from %q factory function", entry.Type)
 	}
 
+	if raw, ok := entry.Options["capture"]; ok {
+		if _, err := strconv.ParseBool(raw); err != nil {
+			return nil, fmt.Errorf("invalid capture: %v", err)
+		}
+	}
+
+	// Performance standbys don't write to storage, the active node captures
+	// the entries of the requests it forwards
+	if auditCaptureEnabled(entry) && !c.perfStandby {
+		be, err = c.newAuditCaptureBackend(entry, be, view, saltConfig)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	switch entry.Type {
 	case "file":
 		key := "audit_file|" + entry.Path
@@ -443,6 +483,12 @@ func (c *Core) newAuditBackend(ctx context.Context, entry *MountEntry, view logi
 				c.logger.Debug("audit: socket backend options", "path", entry.Path, "address", entry.Options["address"], "socket type", entry.Options["socket_type"])
 			}
 		}
+	case "http":
+		if c.logger.IsDebug() {
+			if entry.Options != nil {
+				c.logger.Debug("audit: http backend options", "path", entry.Path, "address", entry.Options["address"], "buffer path", entry.Options["buffer_path"])
+			}
+		}
 	case "syslog":
 		if c.logger.IsDebug() {
 			if entry.Options != nil {
@@ -454,6 +500,21 @@ func (c *Core) newAuditBackend(ctx context.Context, entry *MountEntry, view logi
 	return be, err
 }
 
+// auditFilter parses the filter option of the audit entry, returning nil if
+// the backend logs all the requests
+func auditFilter(entry *MountEntry) (*audit.Filter, error) {
+	expr := strings.TrimSpace(entry.Options["filter"])
+	if expr == "" {
+		return nil, nil
+	}
+
+	filter, err := audit.ParseFilter(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter: %v", err)
+	}
+	return filter, nil
+}
+
 // defaultAuditTable creates a default audit table
 func defaultAuditTable() *MountTable {
 	table := &MountTable{
@@ -465,6 +526,7 @@ func defaultAuditTable() *MountTable {
 type backendEntry struct {
 	backend audit.Backend
 	view    *BarrierView
+	filter  *audit.Filter
 }
 
 // AuditBroker is used to provide a single ingest interface to auditable
@@ -473,6 +535,10 @@ type AuditBroker struct {
 	sync.RWMutex
 	backends map[string]backendEntry
 	logger   log.Logger
+
+	// router resolves the mounts of the requests matched by the filters of
+	// the backends
+	router *Router
 }
 
 // NewAuditBroker creates a new audit broker
@@ -486,11 +552,19 @@ func NewAuditBroker(log log.Logger) *AuditBroker {
 
 // Register is used to add new audit backend to the broker
 func (a *AuditBroker) Register(name string, b audit.Backend, v *BarrierView) {
+	a.RegisterWithFilter(name, b, v, nil)
+}
+
+// RegisterWithFilter is used to add a new audit backend to the broker which
+// only logs the requests matching the filter. A nil filter matches all the
+// requests.
+func (a *AuditBroker) RegisterWithFilter(name string, b audit.Backend, v *BarrierView, filter *audit.Filter) {
 	a.Lock()
 	defer a.Unlock()
 	a.backends[name] = backendEntry{
 		backend: b,
 		view:    v,
+		filter:  filter,
 	}
 }
 
@@ -498,9 +572,34 @@ func (a *AuditBroker) Register(name string, b audit.Backend, v *BarrierView) {
 func (a *AuditBroker) Deregister(name string) {
 	a.Lock()
 	defer a.Unlock()
+	if be, ok := a.backends[name]; ok {
+		a.closeBackend(name, be)
+	}
 	delete(a.backends, name)
 }
 
+// closeAll releases the resources of all the audit backends, which are no
+// longer used once the broker is torn down
+func (a *AuditBroker) closeAll() {
+	a.Lock()
+	defer a.Unlock()
+	for name, be := range a.backends {
+		a.closeBackend(name, be)
+	}
+}
+
+// closeBackend releases the resources of the backend, if it holds any.
+// N.B.: This must be called with the lock held.
+func (a *AuditBroker) closeBackend(name string, be backendEntry) {
+	closer, ok := be.backend.(audit.Closer)
+	if !ok {
+		return
+	}
+	if err := closer.Close(); err != nil {
+		a.logger.Error("audit: failed to close backend", "backend", name, "error", err)
+	}
+}
+
 // IsRegistered is used to check if a given audit backend is registered
 func (a *AuditBroker) IsRegistered(name string) bool {
 	a.RLock()
@@ -521,6 +620,25 @@ func (a *AuditBroker) GetHash(name string, input string) (string, error) {
 	return be.backend.GetHash(input)
 }
 
+// filterValues returns the values of the fields of the request matched by the
+// filters of the backends. The mount point is relative to the namespace of
+// the request.
+func (a *AuditBroker) filterValues(ctx context.Context, req *logical.Request) map[string]string {
+	ns := namespaceFromContext(ctx)
+	values := map[string]string{
+		"namespace": ns.Path,
+		"operation": string(req.Operation),
+	}
+	if a.router != nil {
+		if entry := a.router.MatchingMountEntry(req.Path); entry != nil {
+			mount := a.router.MatchingMount(req.Path)
+			values["mount_point"] = strings.TrimPrefix(namespaceRequestPath(ns, mount), ns.Path)
+			values["mount_type"] = entry.Type
+		}
+	}
+	return values
+}
+
 // LogRequest is used to ensure all the audit backends have an opportunity to
 // log the given request and that *at least one* succeeds.
 func (a *AuditBroker) LogRequest(ctx context.Context, auth *logical.Auth, req *logical.Request, headersConfig *AuditedHeadersConfig, outerErr error) (ret error) {
@@ -556,9 +674,22 @@ func (a *AuditBroker) LogRequest(ctx context.Context, auth *logical.Auth, req *l
 		req.Headers = headers
 	}()
 
-	// Ensure at least one backend logs
+	// Ensure at least one backend logs, among the ones whose filter matches
+	// the request
 	anyLogged := false
+	anyMatched := false
+	var values map[string]string
 	for name, be := range a.backends {
+		if be.filter != nil {
+			if values == nil {
+				values = a.filterValues(ctx, req)
+			}
+			if !be.filter.Match(values) {
+				continue
+			}
+		}
+		anyMatched = true
+
 		req.Headers = nil
 		transHeaders, thErr := headersConfig.ApplyConfig(headers, be.backend.GetHash)
 		if thErr != nil {
@@ -576,7 +707,7 @@ func (a *AuditBroker) LogRequest(ctx context.Context, auth *logical.Auth, req *l
 			anyLogged = true
 		}
 	}
-	if !anyLogged && len(a.backends) > 0 {
+	if !anyLogged && anyMatched {
 		retErr = multierror.Append(retErr, fmt.Errorf("no audit backend succeeded in logging the request"))
 	}
 
@@ -613,9 +744,22 @@ func (a *AuditBroker) LogResponse(ctx context.Context, auth *logical.Auth, req *
 		req.Headers = headers
 	}()
 
-	// Ensure at least one backend logs
+	// Ensure at least one backend logs, among the ones whose filter matches
+	// the request
 	anyLogged := false
+	anyMatched := false
+	var values map[string]string
 	for name, be := range a.backends {
+		if be.filter != nil {
+			if values == nil {
+				values = a.filterValues(ctx, req)
+			}
+			if !be.filter.Match(values) {
+				continue
+			}
+		}
+		anyMatched = true
+
 		req.Headers = nil
 		transHeaders, thErr := headersConfig.ApplyConfig(headers, be.backend.GetHash)
 		if thErr != nil {
@@ -633,7 +777,7 @@ func (a *AuditBroker) LogResponse(ctx context.Context, auth *logical.Auth, req *
 			anyLogged = true
 		}
 	}
-	if !anyLogged && len(a.backends) > 0 {
+	if !anyLogged && anyMatched {
 		retErr = multierror.Append(retErr, fmt.Errorf("no audit backend succeeded in logging the response"))
 	}
 
@@ -0,0 +1,425 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hashicorp/vault/audit"
+	"github.com/hashicorp/vault/helper/salt"
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+	log "github.com/mgutz/logxi/v1"
+)
+
+const (
+	// auditCapturePrefix is the prefix of the captured entries in the view
+	// of an audit device, under which the entries are grouped by day
+	auditCapturePrefix = "capture/"
+
+	// auditCaptureDayFormat is the format of the days of the captured entries
+	auditCaptureDayFormat = "2006-01-02"
+
+	// auditCaptureDefaultRetention is how long the captured entries are kept
+	// when the device doesn't set capture_retention
+	auditCaptureDefaultRetention = 30 * 24 * time.Hour
+)
+
+// auditCaptureEnabled returns whether the audit device captures its entries
+// for sys/audit/export
+func auditCaptureEnabled(entry *MountEntry) bool {
+	capture, _ := strconv.ParseBool(entry.Options["capture"])
+	return capture
+}
+
+// auditCaptureBackend wraps an audit backend to store the entries it logs
+// successfully in the view of the device, so that they can be exported.
+// The entries are formatted as the JSON lines of the file device, with the
+// salt and the hashing options of the device.
+type auditCaptureBackend struct {
+	audit.Backend
+
+	view      logical.Storage
+	retention time.Duration
+	logger    log.Logger
+
+	formatter    audit.AuditFormatter
+	formatConfig audit.FormatterConfig
+	saltConfig   *salt.Config
+	salt         *salt.Salt
+	saltLock     sync.RWMutex
+
+	seq      uint64
+	lastDay  string
+	pruneMux sync.Mutex
+}
+
+// newAuditCaptureBackend wraps the backend of the audit entry to capture its
+// entries in the view
+func (c *Core) newAuditCaptureBackend(entry *MountEntry, be audit.Backend, view logical.Storage, saltConfig *salt.Config) (*auditCaptureBackend, error) {
+	retention := auditCaptureDefaultRetention
+	if raw := entry.Options["capture_retention"]; raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid capture_retention: %v", err)
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("capture_retention must be positive")
+		}
+		retention = d
+	}
+
+	formatConfig := audit.FormatterConfig{
+		HMACAccessor: true,
+	}
+	if raw, ok := entry.Options["hmac_accessor"]; ok {
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, err
+		}
+		formatConfig.HMACAccessor = value
+	}
+	if raw, ok := entry.Options["log_raw"]; ok {
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, err
+		}
+		formatConfig.Raw = value
+	}
+	if keys, ok := entry.Options["non_hmac_request_keys"]; ok {
+		formatConfig.NonHMACRequestDataKeys = strutil.ParseDedupAndSortStrings(keys, ",")
+	}
+	if keys, ok := entry.Options["non_hmac_response_keys"]; ok {
+		formatConfig.NonHMACResponseDataKeys = strutil.ParseDedupAndSortStrings(keys, ",")
+	}
+
+	b := &auditCaptureBackend{
+		Backend:      be,
+		view:         view,
+		retention:    retention,
+		logger:       c.logger,
+		formatConfig: formatConfig,
+		saltConfig:   saltConfig,
+	}
+	b.formatter.AuditFormatWriter = &audit.JSONFormatWriter{
+		SaltFunc: b.Salt,
+	}
+	return b, nil
+}
+
+// Salt returns the salt of the device, which the wrapped backend shares
+func (b *auditCaptureBackend) Salt() (*salt.Salt, error) {
+	b.saltLock.RLock()
+	if b.salt != nil {
+		defer b.saltLock.RUnlock()
+		return b.salt, nil
+	}
+	b.saltLock.RUnlock()
+	b.saltLock.Lock()
+	defer b.saltLock.Unlock()
+	if b.salt != nil {
+		return b.salt, nil
+	}
+	salt, err := salt.NewSalt(b.view, b.saltConfig)
+	if err != nil {
+		return nil, err
+	}
+	b.salt = salt
+	return salt, nil
+}
+
+func (b *auditCaptureBackend) LogRequest(ctx context.Context, auth *logical.Auth, req *logical.Request, outerErr error) error {
+	if err := b.Backend.LogRequest(ctx, auth, req, outerErr); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := b.formatter.FormatRequest(&buf, b.formatConfig, auth, req, outerErr); err != nil {
+		return err
+	}
+	return b.store(ctx, buf.Bytes())
+}
+
+func (b *auditCaptureBackend) LogResponse(ctx context.Context, auth *logical.Auth, req *logical.Request, resp *logical.Response, outerErr error) error {
+	if err := b.Backend.LogResponse(ctx, auth, req, resp, outerErr); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := b.formatter.FormatResponse(&buf, b.formatConfig, auth, req, resp, outerErr); err != nil {
+		return err
+	}
+	return b.store(ctx, buf.Bytes())
+}
+
+func (b *auditCaptureBackend) Invalidate(ctx context.Context) {
+	b.saltLock.Lock()
+	b.salt = nil
+	b.saltLock.Unlock()
+
+	b.Backend.Invalidate(ctx)
+}
+
+// Close releases the resources of the wrapped backend
+func (b *auditCaptureBackend) Close() error {
+	if closer, ok := b.Backend.(audit.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// store writes the formatted entry under the day it's logged, keyed by its
+// time so that the keys of a day list in order
+func (b *auditCaptureBackend) store(ctx context.Context, line []byte) error {
+	now := time.Now().UTC()
+	day := now.Format(auditCaptureDayFormat)
+	key := fmt.Sprintf("%s%s/%019d-%010d", auditCapturePrefix, day, now.UnixNano(), atomic.AddUint64(&b.seq, 1))
+
+	if err := b.view.Put(ctx, &logical.StorageEntry{
+		Key:   key,
+		Value: bytes.TrimSpace(line),
+	}); err != nil {
+		return fmt.Errorf("failed to capture the audit entry: %v", err)
+	}
+
+	// The days past the retention are pruned once a day
+	b.pruneMux.Lock()
+	prune := b.lastDay != day
+	b.lastDay = day
+	b.pruneMux.Unlock()
+	if prune {
+		go b.prune(now.Add(-b.retention))
+	}
+	return nil
+}
+
+// prune deletes the entries captured on the days before the day of the
+// cutoff
+func (b *auditCaptureBackend) prune(cutoff time.Time) {
+	ctx := context.Background()
+	days, err := b.view.List(ctx, auditCapturePrefix)
+	if err != nil {
+		b.logger.Error("audit: failed to list the captured audit entries", "error", err)
+		return
+	}
+
+	oldest := cutoff.Format(auditCaptureDayFormat)
+	for _, day := range days {
+		if strings.TrimSuffix(day, "/") >= oldest {
+			continue
+		}
+		keys, err := b.view.List(ctx, auditCapturePrefix+day)
+		if err != nil {
+			b.logger.Error("audit: failed to list the captured audit entries", "day", day, "error", err)
+			continue
+		}
+		for _, key := range keys {
+			if err := b.view.Delete(ctx, auditCapturePrefix+day+key); err != nil {
+				b.logger.Error("audit: failed to prune the captured audit entries", "day", day, "error", err)
+				break
+			}
+		}
+	}
+}
+
+// auditCaptureView returns the view of the captured entries of the audit
+// entry
+func (c *Core) auditCaptureView(entry *MountEntry) *BarrierView {
+	return NewBarrierView(c.barrier, auditBarrierPrefix+entry.UUID+"/"+auditCapturePrefix)
+}
+
+// readAuditCapture returns the entries captured by the audit device in the
+// time range, in the order they were logged
+func (c *Core) readAuditCapture(ctx context.Context, entry *MountEntry, start, end time.Time) ([]json.RawMessage, error) {
+	view := c.auditCaptureView(entry)
+	days, err := view.List(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(days)
+
+	first := start.Format(auditCaptureDayFormat)
+	last := end.Format(auditCaptureDayFormat)
+	var entries []json.RawMessage
+	for _, day := range days {
+		day = strings.TrimSuffix(day, "/")
+		if day < first || day > last {
+			continue
+		}
+
+		keys, err := view.List(ctx, day+"/")
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			nanos, err := strconv.ParseInt(strings.SplitN(key, "-", 2)[0], 10, 64)
+			if err != nil {
+				continue
+			}
+			if t := time.Unix(0, nanos); t.Before(start) || t.After(end) {
+				continue
+			}
+
+			raw, err := view.Get(ctx, day+"/"+key)
+			if err != nil {
+				return nil, err
+			}
+			if raw == nil {
+				// Pruned while reading
+				continue
+			}
+			entries = append(entries, json.RawMessage(raw.Value))
+		}
+	}
+	return entries, nil
+}
+
+// handleAuditExport exports the entries captured by an audit device or the
+// activity records in the time range of the request
+func (b *SystemBackend) handleAuditExport(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	format := d.Get("format").(string)
+	if format != "json" && format != "csv" {
+		return logical.ErrorResponse(fmt.Sprintf("unsupported format %q", format)), logical.ErrInvalidRequest
+	}
+
+	var body []byte
+	var contentType string
+	switch source := d.Get("source").(string); source {
+	case "activity":
+		records, _, _, err := b.activityForRequest(ctx, d)
+		if err != nil {
+			return handleError(err)
+		}
+		body, contentType, err = encodeActivityRecords(records, format)
+		if err != nil {
+			return nil, err
+		}
+
+	case "audit":
+		entry, err := b.auditExportDevice(d.Get("device").(string))
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+
+		var start, end time.Time
+		for name, t := range map[string]*time.Time{"start_time": &start, "end_time": &end} {
+			raw := d.Get(name).(string)
+			if raw == "" {
+				continue
+			}
+			parsed, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				return logical.ErrorResponse(fmt.Sprintf("invalid %s: %v", name, err)), logical.ErrInvalidRequest
+			}
+			*t = parsed.UTC()
+		}
+		if end.IsZero() {
+			end = time.Now().UTC()
+		}
+		if start.After(end) {
+			return logical.ErrorResponse("start_time is after end_time"), logical.ErrInvalidRequest
+		}
+
+		entries, err := b.Core.readAuditCapture(ctx, entry, start, end)
+		if err != nil {
+			b.Backend.Logger().Error("sys: failed to read the captured audit entries", "path", entry.Path, "error", err)
+			return handleError(err)
+		}
+		body, contentType, err = encodeAuditEntries(entries, format)
+		if err != nil {
+			return nil, err
+		}
+
+	default:
+		return logical.ErrorResponse(fmt.Sprintf("unsupported source %q", source)), logical.ErrInvalidRequest
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPContentType: contentType,
+			logical.HTTPRawBody:     body,
+			logical.HTTPStatusCode:  200,
+		},
+	}, nil
+}
+
+// auditExportDevice returns the capturing audit device to export at the
+// path, which can be omitted when a single device captures its entries
+func (b *SystemBackend) auditExportDevice(path string) (*MountEntry, error) {
+	b.Core.auditLock.RLock()
+	defer b.Core.auditLock.RUnlock()
+
+	var capturing []*MountEntry
+	for _, entry := range b.Core.audit.Entries {
+		if auditCaptureEnabled(entry) {
+			capturing = append(capturing, entry)
+		}
+	}
+
+	if path != "" {
+		path = sanitizeMountPath(path)
+		for _, entry := range capturing {
+			if entry.Path == path {
+				return entry, nil
+			}
+		}
+		return nil, fmt.Errorf("no capturing audit device at %q", path)
+	}
+
+	switch len(capturing) {
+	case 0:
+		return nil, fmt.Errorf("no audit device captures its entries")
+	case 1:
+		return capturing[0], nil
+	default:
+		return nil, fmt.Errorf("several audit devices capture their entries, the device must be set")
+	}
+}
+
+// encodeAuditEntries encodes the audit entries as JSON lines or CSV and
+// returns the content type of the encoding
+func encodeAuditEntries(entries []json.RawMessage, format string) ([]byte, string, error) {
+	var buf bytes.Buffer
+	switch format {
+	case "csv":
+		w := csv.NewWriter(&buf)
+		w.Write([]string{"time", "type", "request_id", "operation", "path", "remote_address", "accessor", "display_name", "entity_id", "policies", "error"})
+		for _, raw := range entries {
+			var entry audit.AuditResponseEntry
+			if err := json.Unmarshal(raw, &entry); err != nil {
+				return nil, "", err
+			}
+			w.Write([]string{
+				entry.Time,
+				entry.Type,
+				entry.Request.ID,
+				string(entry.Request.Operation),
+				entry.Request.Path,
+				entry.Request.RemoteAddr,
+				entry.Auth.Accessor,
+				entry.Auth.DisplayName,
+				entry.Auth.EntityID,
+				strings.Join(entry.Auth.Policies, ","),
+				entry.Error,
+			})
+		}
+		w.Flush()
+		return buf.Bytes(), "text/csv", w.Error()
+	default:
+		for _, raw := range entries {
+			buf.Write(raw)
+			buf.WriteByte('\n')
+		}
+		return buf.Bytes(), "application/x-ndjson", nil
+	}
+}
@@ -0,0 +1,193 @@
+package vault
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/audit"
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestAuditCapture_Export(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	c.auditBackends["noop"] = func(ctx context.Context, config *audit.BackendConfig) (audit.Backend, error) {
+		return &NoopAudit{
+			Config: config,
+		}, nil
+	}
+	ctx := context.Background()
+
+	// Nothing can be exported without a capturing device
+	req := logical.TestRequest(t, logical.ReadOperation, "sys/audit/export")
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err == nil {
+		t.Fatal("expected an error without a capturing device")
+	}
+
+	for path, options := range map[string]map[string]string{
+		"plain":   nil,
+		"capture": {"capture": "true"},
+	} {
+		if err := c.enableAudit(ctx, &MountEntry{
+			Table:   auditTableType,
+			Path:    path,
+			Type:    "noop",
+			Options: options,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.enableAudit(ctx, &MountEntry{
+		Table:   auditTableType,
+		Path:    "bad",
+		Type:    "noop",
+		Options: map[string]string{"capture": "true", "capture_retention": "-1h"},
+	}); err == nil {
+		t.Fatal("expected an error for a negative retention")
+	}
+
+	start := time.Now().UTC().Add(-time.Second)
+	req = logical.TestRequest(t, logical.UpdateOperation, "secret/foo")
+	req.Data["value"] = "bar"
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatal(err)
+	}
+
+	export := func(data map[string]interface{}) (string, string) {
+		t.Helper()
+
+		req := logical.TestRequest(t, logical.ReadOperation, "sys/audit/export")
+		req.Data = data
+		req.ClientToken = root
+		resp, err := c.HandleRequest(req)
+		if err != nil {
+			t.Fatalf("err: %v, resp: %#v", err, resp)
+		}
+		return resp.Data[logical.HTTPContentType].(string), string(resp.Data[logical.HTTPRawBody].([]byte))
+	}
+
+	// The request and the response to secret/foo are captured, the export
+	// logged after them
+	contentType, body := export(map[string]interface{}{
+		"start_time": start.Format(time.RFC3339),
+	})
+	if contentType != "application/x-ndjson" {
+		t.Fatalf("bad: %s", contentType)
+	}
+	lines := strings.Split(strings.TrimSpace(body), "\n")
+	if len(lines) < 2 {
+		t.Fatalf("bad: %s", body)
+	}
+	var entry audit.AuditResponseEntry
+	if err := json.Unmarshal([]byte(lines[1]), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry.Type != "response" || entry.Request.Path != "secret/foo" || !strings.HasPrefix(entry.Auth.ClientToken, "hmac-sha256:") {
+		t.Fatalf("bad: %#v", entry)
+	}
+	if !strings.HasPrefix(entry.Request.Data["value"].(string), "hmac-sha256:") {
+		t.Fatalf("expected the data to be hashed: %#v", entry.Request.Data)
+	}
+
+	contentType, body = export(map[string]interface{}{
+		"device":     "capture",
+		"format":     "csv",
+		"start_time": start.Format(time.RFC3339),
+	})
+	if contentType != "text/csv" {
+		t.Fatalf("bad: %s", contentType)
+	}
+	records, err := csv.NewReader(strings.NewReader(body)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) < 3 || records[0][0] != "time" || records[1][1] != "request" || records[1][4] != "secret/foo" {
+		t.Fatalf("bad: %#v", records)
+	}
+
+	// Entries out of the time range aren't exported
+	_, body = export(map[string]interface{}{
+		"end_time": start.Format(time.RFC3339),
+	})
+	if body != "" {
+		t.Fatalf("bad: %s", body)
+	}
+
+	// The activity records are exported in the same formats
+	contentType, _ = export(map[string]interface{}{
+		"source": "activity",
+		"format": "csv",
+	})
+	if contentType != "text/csv" {
+		t.Fatalf("bad: %s", contentType)
+	}
+
+	for _, data := range []map[string]interface{}{
+		{"device": "plain"},
+		{"source": "bogus"},
+		{"format": "xml"},
+	} {
+		req := logical.TestRequest(t, logical.ReadOperation, "sys/audit/export")
+		req.Data = data
+		req.ClientToken = root
+		resp, err := c.HandleRequest(req)
+		if err == nil || resp == nil || !resp.IsError() {
+			t.Fatalf("%v: expected an error, got %#v", data, resp)
+		}
+	}
+
+	// Disabling the device clears its captured entries
+	entries := c.audit.Entries
+	var captureEntry *MountEntry
+	for _, e := range entries {
+		if e.Path == "capture/" {
+			captureEntry = e
+		}
+	}
+	if _, err := c.disableAudit(ctx, "capture"); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := logical.CollectKeys(ctx, c.auditCaptureView(captureEntry))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 0 {
+		t.Fatalf("bad: %v", keys)
+	}
+}
+
+func TestAuditCapture_Prune(t *testing.T) {
+	c, _, _ := TestCoreUnsealed(t)
+	ctx := context.Background()
+	entry := &MountEntry{
+		Path: "foo/",
+		UUID: "abc",
+	}
+	view := NewBarrierView(c.barrier, auditBarrierPrefix+entry.UUID+"/")
+	b, err := c.newAuditCaptureBackend(entry, &NoopAudit{}, view, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now().UTC()
+	old := now.Add(-2 * auditCaptureDefaultRetention).Format(auditCaptureDayFormat)
+	for _, key := range []string{old + "/1-1", now.Format(auditCaptureDayFormat) + "/2-2"} {
+		if err := view.Put(ctx, &logical.StorageEntry{Key: auditCapturePrefix + key, Value: []byte("{}")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	b.prune(now.Add(-b.retention))
+	keys, err := logical.CollectKeys(ctx, c.auditCaptureView(entry))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 || !strings.HasPrefix(keys[0], now.Format(auditCaptureDayFormat)) {
+		t.Fatalf("bad: %v", keys)
+	}
+}
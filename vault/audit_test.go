@@ -175,6 +175,39 @@ func TestCore_EnableAudit(t *testing.T) {
 	}
 }
 
+func TestCore_EnableAudit_Filter(t *testing.T) {
+	c, _, _ := TestCoreUnsealed(t)
+	c.auditBackends["noop"] = func(ctx context.Context, config *audit.BackendConfig) (audit.Backend, error) {
+		return &NoopAudit{
+			Config: config,
+		}, nil
+	}
+
+	me := &MountEntry{
+		Table: auditTableType,
+		Path:  "foo",
+		Type:  "noop",
+		Options: map[string]string{
+			"filter": `mount_type == "kv" and`,
+		},
+	}
+	err := c.enableAudit(context.Background(), me)
+	if err == nil || !strings.Contains(err.Error(), "invalid filter") {
+		t.Fatalf("expected invalid filter error, got %v", err)
+	}
+	if c.auditBroker.IsRegistered("foo/") {
+		t.Fatalf("audit backend should not be registered")
+	}
+
+	me.Options["filter"] = `not (mount_type == "kv" and operation == "read")`
+	if err := c.enableAudit(context.Background(), me); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !c.auditBroker.IsRegistered("foo/") {
+		t.Fatalf("missing audit backend")
+	}
+}
+
 func TestCore_EnableAudit_MixedFailures(t *testing.T) {
 	c, _, _ := TestCoreUnsealed(t)
 	c.auditBackends["noop"] = func(ctx context.Context, config *audit.BackendConfig) (audit.Backend, error) {
@@ -590,6 +623,69 @@ func TestAuditBroker_LogResponse(t *testing.T) {
 	}
 }
 
+func TestAuditBroker_Filter(t *testing.T) {
+	c, _, _ := TestCoreUnsealed(t)
+	b := NewAuditBroker(c.logger)
+	b.router = c.router
+
+	filter, err := audit.ParseFilter(`not (mount_type == "kv" and operation == "read")`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a1 := &NoopAudit{}
+	a2 := &NoopAudit{}
+	b.RegisterWithFilter("foo", a1, nil, filter)
+	b.Register("bar", a2, nil)
+
+	headersConf := &AuditedHeadersConfig{
+		Headers: make(map[string]*auditedHeaderSettings),
+	}
+	req := &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "secret/foo",
+	}
+
+	// The read of the kv mount is only logged by the unfiltered backend
+	if err := b.LogRequest(context.Background(), nil, req, headersConf, nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := b.LogResponse(context.Background(), nil, req, nil, headersConf, nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(a1.Req) != 0 || len(a1.Resp) != 0 {
+		t.Fatalf("filtered request logged: %#v", a1.Req)
+	}
+	if len(a2.Req) != 1 || len(a2.RespReq) != 1 {
+		t.Fatalf("request not logged: %#v", a2.Req)
+	}
+
+	// Other requests are logged by both
+	req.Operation = logical.UpdateOperation
+	if err := b.LogRequest(context.Background(), nil, req, headersConf, nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(a1.Req) != 1 || len(a2.Req) != 2 {
+		t.Fatalf("request not logged: %#v %#v", a1.Req, a2.Req)
+	}
+
+	// Filtered backends do not count when checking that a backend logged the
+	// request
+	req.Operation = logical.ReadOperation
+	a2.ReqErr = fmt.Errorf("failed")
+	if err := b.LogRequest(context.Background(), nil, req, headersConf, nil); !errwrap.Contains(err, "no audit backend succeeded in logging the request") {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Requests filtered out by all the backends are not an error
+	b.Deregister("bar")
+	if err := b.LogRequest(context.Background(), nil, req, headersConf, nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(a1.Req) != 1 {
+		t.Fatalf("filtered request logged: %#v", a1.Req)
+	}
+}
+
 func TestAuditBroker_AuditHeaders(t *testing.T) {
 	logger := logformat.NewVaultLogger(log.LevelTrace)
 	b := NewAuditBroker(logger)
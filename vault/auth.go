@@ -55,6 +55,22 @@ func (c *Core) enableCredential(ctx context.Context, entry *MountEntry) error {
 		return fmt.Errorf("backend path must be specified")
 	}
 
+	ns := c.namespaceByID(entry.NamespaceID)
+	if ns == nil {
+		return logical.CodedError(404, "namespace of the auth method does not exist")
+	}
+
+	// The token store is shared by all namespaces
+	if !ns.isRoot() && entry.Path == ns.Path+"token/" {
+		return logical.CodedError(409, "path is already in use")
+	}
+
+	// Auth methods cannot overlap the paths of other namespaces, which are
+	// routed under the auth prefix as well
+	if match := c.namespaceConflict(ns, entry.Path); match != "" {
+		return logical.CodedError(409, fmt.Sprintf("existing namespace at %s", match))
+	}
+
 	c.authLock.Lock()
 	defer c.authLock.Unlock()
 
@@ -349,7 +365,8 @@ func (c *Core) loadCredentials(ctx context.Context) error {
 		}
 	}
 
-	if !needPersist {
+	// Performance standbys leave persisting to the active node
+	if !needPersist || c.perfStandby {
 		return nil
 	}
 
@@ -457,7 +474,7 @@ func (c *Core) setupCredentials(ctx context.Context) error {
 		// ensure that it is reset after. This ensures that there will be no
 		// writes during the construction of the backend.
 		view.setReadOnlyErr(logical.ErrSetupReadOnly)
-		defer view.setReadOnlyErr(nil)
+		defer view.setReadOnlyErr(c.mountReadOnlyErr())
 
 		// Initialize the backend
 		sysView := c.mountEntrySysView(entry)
@@ -512,7 +529,7 @@ func (c *Core) setupCredentials(ctx context.Context) error {
 		}
 	}
 
-	if persistNeeded {
+	if persistNeeded && !c.perfStandby {
 		return c.persistAuth(ctx, c.auth, false)
 	}
 
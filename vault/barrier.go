@@ -123,6 +123,10 @@ type SecurityBarrier interface {
 	// ActiveKeyInfo is used to inform details about the active key
 	ActiveKeyInfo() (*KeyInfo, error)
 
+	// PersistEncryptionCount adds the encryptions with the active key since
+	// the last call to the count of the keyring, and persists it
+	PersistEncryptionCount(ctx context.Context) error
+
 	// Rekey is used to change the master key used to protect the keyring
 	Rekey(context.Context, []byte) error
 
@@ -152,6 +156,12 @@ type BarrierStorage interface {
 	List(ctx context.Context, prefix string) ([]string, error)
 }
 
+// paginatedBarrierStorage is implemented by the barriers which can list a
+// page of the keys under a prefix, like physical.Paginated
+type paginatedBarrierStorage interface {
+	ListPage(ctx context.Context, prefix, after string, limit int) ([]string, error)
+}
+
 // BarrierEncryptor is the in memory only interface that does not actually
 // use the underlying barrier. It is used for lower level modules like the
 // Write-Ahead-Log and Merkle index to allow them to use the barrier.
@@ -180,4 +190,7 @@ func (e *Entry) Logical() *logical.StorageEntry {
 type KeyInfo struct {
 	Term        int
 	InstallTime time.Time
+
+	// Encryptions is the estimated number of encryptions with the key
+	Encryptions uint64
 }
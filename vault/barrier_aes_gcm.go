@@ -8,8 +8,10 @@ import (
 	"crypto/subtle"
 	"encoding/binary"
 	"fmt"
+	"io"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/armon/go-metrics"
@@ -48,6 +50,11 @@ var _ SecurityBarrier = &AESGCMBarrier{}
 // bit. AES-GCM is high performance, and provides both confidentiality
 // and integrity.
 type AESGCMBarrier struct {
+	// unaccountedEncryptions is the number of encryptions with the active key
+	// not yet added to the count of the keyring. It is first to be aligned
+	// for the atomic operations.
+	unaccountedEncryptions uint64
+
 	backend physical.Backend
 
 	l      sync.RWMutex
@@ -66,6 +73,9 @@ type AESGCMBarrier struct {
 	// future versioning of barrier implementations. It's var instead
 	// of const to allow for testing
 	currentAESGCMVersionByte byte
+
+	// rand is the source of the random data of the generated keys
+	rand io.Reader
 }
 
 // NewAESGCMBarrier is used to construct a new barrier that uses
@@ -76,10 +86,17 @@ func NewAESGCMBarrier(physical physical.Backend) (*AESGCMBarrier, error) {
 		sealed:  true,
 		cache:   make(map[uint32]cipher.AEAD),
 		currentAESGCMVersionByte: byte(AESGCMVersion2),
+		rand:                     rand.Reader,
 	}
 	return b, nil
 }
 
+// SetReader sets the source of the random data of the keys generated by the
+// barrier, which defaults to crypto/rand
+func (b *AESGCMBarrier) SetReader(r io.Reader) {
+	b.rand = r
+}
+
 // Initialized checks if the barrier has been initialized
 // and has a master key set.
 func (b *AESGCMBarrier) Initialized(ctx context.Context) (bool, error) {
@@ -199,7 +216,7 @@ func (b *AESGCMBarrier) persistKeyring(ctx context.Context, keyring *Keyring) er
 func (b *AESGCMBarrier) GenerateKey() ([]byte, error) {
 	// Generate a 256bit key
 	buf := make([]byte, 2*aes.BlockSize)
-	_, err := rand.Read(buf)
+	_, err := io.ReadFull(b.rand, buf)
 	return buf, err
 }
 
@@ -426,6 +443,7 @@ func (b *AESGCMBarrier) Seal() error {
 	b.keyring.Zeroize(true)
 	b.keyring = nil
 	b.sealed = true
+	atomic.StoreUint64(&b.unaccountedEncryptions, 0)
 	return nil
 }
 
@@ -448,18 +466,23 @@ func (b *AESGCMBarrier) Rotate(ctx context.Context) (uint32, error) {
 	term := b.keyring.ActiveTerm()
 	newTerm := term + 1
 
+	// Account for the encryptions with the previous key
+	unaccounted := atomic.SwapUint64(&b.unaccountedEncryptions, 0)
+
 	// Add a new encryption key
-	newKeyring, err := b.keyring.AddKey(&Key{
+	newKeyring, err := b.keyring.AddEncryptions(unaccounted).AddKey(&Key{
 		Term:    newTerm,
 		Version: 1,
 		Value:   encrypt,
 	})
 	if err != nil {
+		atomic.AddUint64(&b.unaccountedEncryptions, unaccounted)
 		return 0, fmt.Errorf("failed to add new encryption key: %v", err)
 	}
 
 	// Persist the new keyring
 	if err := b.persistKeyring(ctx, newKeyring); err != nil {
+		atomic.AddUint64(&b.unaccountedEncryptions, unaccounted)
 		return 0, err
 	}
 
@@ -571,10 +594,34 @@ func (b *AESGCMBarrier) ActiveKeyInfo() (*KeyInfo, error) {
 	info := &KeyInfo{
 		Term:        int(term),
 		InstallTime: key.InstallTime,
+		Encryptions: key.Encryptions + atomic.LoadUint64(&b.unaccountedEncryptions),
 	}
 	return info, nil
 }
 
+// PersistEncryptionCount adds the encryptions with the active key since the
+// last call to the count of the keyring, and persists it
+func (b *AESGCMBarrier) PersistEncryptionCount(ctx context.Context) error {
+	b.l.Lock()
+	defer b.l.Unlock()
+	if b.sealed {
+		return ErrBarrierSealed
+	}
+
+	unaccounted := atomic.SwapUint64(&b.unaccountedEncryptions, 0)
+	if unaccounted == 0 {
+		return nil
+	}
+
+	newKeyring := b.keyring.AddEncryptions(unaccounted)
+	if err := b.persistKeyring(ctx, newKeyring); err != nil {
+		atomic.AddUint64(&b.unaccountedEncryptions, unaccounted)
+		return err
+	}
+	b.keyring = newKeyring
+	return nil
+}
+
 // Rekey is used to change the master key used to protect the keyring
 func (b *AESGCMBarrier) Rekey(ctx context.Context, key []byte) error {
 	b.l.Lock()
@@ -651,6 +698,7 @@ func (b *AESGCMBarrier) Put(ctx context.Context, entry *Entry) error {
 		Value:    b.encrypt(entry.Key, term, primary, entry.Value),
 		SealWrap: entry.SealWrap,
 	}
+	atomic.AddUint64(&b.unaccountedEncryptions, 1)
 	return b.backend.Put(ctx, pe)
 }
 
@@ -711,6 +759,18 @@ func (b *AESGCMBarrier) List(ctx context.Context, prefix string) ([]string, erro
 	return b.backend.List(ctx, prefix)
 }
 
+// ListPage is used to list a page of the keys under a given prefix
+func (b *AESGCMBarrier) ListPage(ctx context.Context, prefix, after string, limit int) ([]string, error) {
+	defer metrics.MeasureSince([]string{"barrier", "list"}, time.Now())
+	b.l.RLock()
+	defer b.l.RUnlock()
+	if b.sealed {
+		return nil, ErrBarrierSealed
+	}
+
+	return physical.ListPage(ctx, b.backend, prefix, after, limit)
+}
+
 // aeadForTerm returns the AES-GCM AEAD for the given term
 func (b *AESGCMBarrier) aeadForTerm(term uint32) (cipher.AEAD, error) {
 	// Check for the keyring
@@ -819,6 +879,12 @@ func (b *AESGCMBarrier) decrypt(path string, gcm cipher.AEAD, cipher []byte) ([]
 
 // decryptKeyring is used to decrypt a value using the keyring
 func (b *AESGCMBarrier) decryptKeyring(path string, cipher []byte) ([]byte, error) {
+	// The ciphertext may come from clients through the BarrierEncryptor
+	// interface, so its length must be checked before slicing it
+	if len(cipher) < 5 {
+		return nil, fmt.Errorf("invalid ciphertext length")
+	}
+
 	// Verify the term
 	term := binary.BigEndian.Uint32(cipher[:4])
 
@@ -832,6 +898,9 @@ func (b *AESGCMBarrier) decryptKeyring(path string, cipher []byte) ([]byte, erro
 	if gcm == nil {
 		return nil, fmt.Errorf("no decryption key available for term %d", term)
 	}
+	if len(cipher) < 5+gcm.NonceSize()+gcm.Overhead() {
+		return nil, fmt.Errorf("invalid ciphertext length")
+	}
 
 	nonce := cipher[5 : 5+gcm.NonceSize()]
 	raw := cipher[5+gcm.NonceSize():]
@@ -863,6 +932,7 @@ func (b *AESGCMBarrier) Encrypt(ctx context.Context, key string, plaintext []byt
 	}
 
 	ciphertext := b.encrypt(key, term, primary, plaintext)
+	atomic.AddUint64(&b.unaccountedEncryptions, 1)
 	return ciphertext, nil
 }
 
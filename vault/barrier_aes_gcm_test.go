@@ -502,3 +502,75 @@ func TestEncrypt_BarrierEncryptor(t *testing.T) {
 		t.Fatalf("bad: %s", plain)
 	}
 }
+
+func TestAESGCMBarrier_EncryptionCount(t *testing.T) {
+	inm, err := inmem.NewInmem(nil, logger)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	b, err := NewAESGCMBarrier(inm)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Initialize and unseal
+	key, _ := b.GenerateKey()
+	b.Initialize(context.Background(), key)
+	b.Unseal(context.Background(), key)
+
+	info, err := b.ActiveKeyInfo()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	start := info.Encryptions
+
+	for i := 0; i < 3; i++ {
+		if err := b.Put(context.Background(), &Entry{Key: "test", Value: []byte("test")}); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+	if err := b.PersistEncryptionCount(context.Background()); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := b.Encrypt(context.Background(), "foo", []byte("quick brown fox")); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	info, err = b.ActiveKeyInfo()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if info.Encryptions != start+4 {
+		t.Fatalf("bad: %d", info.Encryptions)
+	}
+
+	// The persisted count survives a seal, the unaccounted one does not
+	b.Seal()
+	b.Unseal(context.Background(), key)
+	info, err = b.ActiveKeyInfo()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if info.Encryptions < start+3 {
+		t.Fatalf("bad: %d", info.Encryptions)
+	}
+
+	// Rotation starts a new count and keeps the one of the previous key
+	if _, err := b.Rotate(context.Background()); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	info, err = b.ActiveKeyInfo()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if info.Term != 2 || info.Encryptions != 0 {
+		t.Fatalf("bad: %#v", info)
+	}
+	keyring, err := b.Keyring()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if keyring.TermKey(1).Encryptions < start+3 {
+		t.Fatalf("bad: %d", keyring.TermKey(1).Encryptions)
+	}
+}
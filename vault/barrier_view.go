@@ -5,6 +5,7 @@ import (
 	"errors"
 	"strings"
 
+	"github.com/hashicorp/vault/helper/strutil"
 	"github.com/hashicorp/vault/logical"
 )
 
@@ -54,6 +55,22 @@ func (v *BarrierView) List(ctx context.Context, prefix string) ([]string, error)
 	return v.barrier.List(ctx, v.expandKey(prefix))
 }
 
+// logical.PaginatedStorage impl.
+func (v *BarrierView) ListPage(ctx context.Context, prefix, after string, limit int) ([]string, error) {
+	if err := v.sanityCheck(prefix); err != nil {
+		return nil, err
+	}
+	if p, ok := v.barrier.(paginatedBarrierStorage); ok {
+		return p.ListPage(ctx, v.expandKey(prefix), after, limit)
+	}
+
+	keys, err := v.barrier.List(ctx, v.expandKey(prefix))
+	if err != nil {
+		return nil, err
+	}
+	return strutil.StrListPage(keys, after, limit), nil
+}
+
 // logical.Storage impl.
 func (v *BarrierView) Get(ctx context.Context, key string) (*logical.StorageEntry, error) {
 	if err := v.sanityCheck(key); err != nil {
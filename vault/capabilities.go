@@ -29,38 +29,31 @@ func (c *Core) Capabilities(ctx context.Context, token, path string) ([]string,
 		return []string{DenyCapability}, nil
 	}
 
-	var policies []*Policy
-	for _, tePolicy := range te.Policies {
-		policy, err := c.policyStore.GetPolicy(ctx, tePolicy, PolicyTypeToken)
-		if err != nil {
-			return nil, err
-		}
-		policies = append(policies, policy)
+	// The path is relative to the namespace of the request, while the
+	// policies of the token are resolved in the namespace of the token
+	ns := namespaceFromContext(ctx)
+	tokenNS := c.namespaceByID(te.NamespaceID)
+	if tokenNS == nil || !ns.isWithin(tokenNS) {
+		return []string{DenyCapability}, nil
 	}
+	tokenCtx := contextWithNamespace(ctx, tokenNS)
 
-	_, derivedPolicies, err := c.fetchEntityAndDerivedPolicies(te.EntityID)
+	entity, derivedPolicies, err := c.fetchEntityAndDerivedPolicies(tokenCtx, te.EntityID)
 	if err != nil {
 		return nil, err
 	}
 
-	for _, item := range derivedPolicies {
-		policy, err := c.policyStore.GetPolicy(ctx, item, PolicyTypeToken)
-		if err != nil {
-			return nil, err
-		}
-		policies = append(policies, policy)
-	}
-
+	policies := append(te.Policies, derivedPolicies...)
 	if len(policies) == 0 {
 		return []string{DenyCapability}, nil
 	}
 
-	acl, err := NewACL(policies)
+	acl, err := c.policyStore.ACL(tokenCtx, entity, policies...)
 	if err != nil {
 		return nil, err
 	}
 
-	capabilities := acl.Capabilities(path)
+	capabilities := acl.Capabilities(ns.Path + path)
 	sort.Strings(capabilities)
 	return capabilities, nil
 }
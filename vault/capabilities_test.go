@@ -115,6 +115,75 @@ path "secret/sample" {
 	}
 }
 
+func TestCapabilities_TemplatedPolicies(t *testing.T) {
+	i, _, c := testIdentityStoreWithGithubAuth(t)
+
+	policy, err := ParseACLPolicy(`
+name = "templated"
+path "secret/{{identity.entity.name}}/*" {
+	capabilities = ["read"]
+}
+path "secret/groups/{{identity.groups.names.eng.id}}/*" {
+	capabilities = ["list"]
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.policyStore.SetPolicy(context.Background(), policy); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := i.HandleRequest(context.Background(), &logical.Request{
+		Path:      "entity",
+		Operation: logical.UpdateOperation,
+		Data: map[string]interface{}{
+			"name": "alice",
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+	entityID := resp.Data["id"].(string)
+
+	resp, err = i.HandleRequest(context.Background(), &logical.Request{
+		Path:      "group",
+		Operation: logical.UpdateOperation,
+		Data: map[string]interface{}{
+			"name":              "eng",
+			"member_entity_ids": []string{entityID},
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+	groupID := resp.Data["id"].(string)
+
+	ent := &TokenEntry{
+		ID:       "templatedtoken",
+		Path:     "auth/token/create",
+		Policies: []string{"templated"},
+		EntityID: entityID,
+	}
+	if err := c.tokenStore.create(context.Background(), ent); err != nil {
+		t.Fatal(err)
+	}
+
+	for path, expected := range map[string][]string{
+		"secret/alice/foo":                  {"read"},
+		"secret/bob/foo":                    {"deny"},
+		"secret/groups/" + groupID + "/foo": {"list"},
+	} {
+		actual, err := c.Capabilities(context.Background(), "templatedtoken", path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Fatalf("%s: bad: got %#v, expected %#v", path, actual, expected)
+		}
+	}
+}
+
 func TestCapabilities(t *testing.T) {
 	c, _, token := TestCoreUnsealed(t)
 
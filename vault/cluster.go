@@ -397,6 +397,10 @@ func (c *Core) ClusterTLSConfig(ctx context.Context) (*tls.Config, error) {
 		for _, v := range clientHello.SupportedProtos {
 			switch v {
 			case "h2", requestForwardingALPN:
+			case drReplicationALPN:
+				// DR secondaries are authenticated with the CA of the DR
+				// primary rather than the local cluster certificate
+				return c.drPrimaryTLSConfig()
 			default:
 				return nil, fmt.Errorf("unknown ALPN proto %s", v)
 			}
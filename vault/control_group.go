@@ -0,0 +1,476 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/helper/locksutil"
+	"github.com/hashicorp/vault/helper/parseutil"
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/helper/wrapping"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+const (
+	// defaultControlGroupTTL is the TTL of the control group tokens of paths
+	// whose control group doesn't set one
+	defaultControlGroupTTL = 24 * time.Hour
+
+	// controlGroupCubbyholePath is the path in the cubbyhole of a control
+	// group token where the state of the request is stored
+	controlGroupCubbyholePath = "cubbyhole/controlgroup"
+)
+
+// ControlGroupHCL is the HCL form of the control group of a path
+type ControlGroupHCL struct {
+	TTL     interface{}                       `hcl:"ttl"`
+	Factors map[string]*ControlGroupFactorHCL `hcl:"factor"`
+}
+
+// ControlGroupFactorHCL is the HCL form of a factor of a control group
+type ControlGroupFactorHCL struct {
+	Identity *IdentityFactorHCL `hcl:"identity"`
+}
+
+// IdentityFactorHCL is the HCL form of a factor that is satisfied by the
+// members of identity groups
+type IdentityFactorHCL struct {
+	GroupNames []string `hcl:"group_names"`
+	Approvals  int      `hcl:"approvals"`
+}
+
+// ControlGroup gates the reads of a path behind the authorization of
+// approvers. The response of a read is wrapped in a control group token,
+// which can only be unwrapped once every factor is satisfied.
+type ControlGroup struct {
+	TTL     time.Duration
+	Factors []*ControlGroupFactor
+}
+
+// ControlGroupFactor is satisfied once enough members of any of its identity
+// groups have authorized the request
+type ControlGroupFactor struct {
+	Name       string   `json:"name"`
+	GroupNames []string `json:"group_names"`
+	Approvals  int      `json:"approvals"`
+}
+
+// parseControlGroup validates the HCL form of a control group
+func parseControlGroup(raw *ControlGroupHCL) (*ControlGroup, error) {
+	cg := &ControlGroup{
+		TTL: defaultControlGroupTTL,
+	}
+	if raw.TTL != nil {
+		dur, err := parseutil.ParseDurationSecond(raw.TTL)
+		if err != nil {
+			return nil, errwrap.Wrapf("error parsing control group ttl: {{err}}", err)
+		}
+		if dur <= 0 {
+			return nil, errors.New("control group ttl must be positive")
+		}
+		cg.TTL = dur
+	}
+
+	if len(raw.Factors) == 0 {
+		return nil, errors.New("control group must have at least one factor")
+	}
+	for name, factor := range raw.Factors {
+		if factor == nil || factor.Identity == nil || len(factor.Identity.GroupNames) == 0 {
+			return nil, fmt.Errorf("control group factor %q must have identity group names", name)
+		}
+		approvals := factor.Identity.Approvals
+		switch {
+		case approvals < 0:
+			return nil, fmt.Errorf("control group factor %q cannot have negative approvals", name)
+		case approvals == 0:
+			approvals = 1
+		}
+		cg.Factors = append(cg.Factors, &ControlGroupFactor{
+			Name:       name,
+			GroupNames: factor.Identity.GroupNames,
+			Approvals:  approvals,
+		})
+	}
+
+	// Keep the factors in a stable order
+	sortControlGroupFactors(cg.Factors)
+
+	return cg, nil
+}
+
+// merge returns the control group requiring the factors of both control
+// groups, with the lesser of the TTLs
+func (cg *ControlGroup) merge(other *ControlGroup) *ControlGroup {
+	switch {
+	case cg == nil:
+		return other
+	case other == nil:
+		return cg
+	}
+
+	ret := &ControlGroup{
+		TTL:     cg.TTL,
+		Factors: append(append([]*ControlGroupFactor{}, cg.Factors...), other.Factors...),
+	}
+	if other.TTL < ret.TTL {
+		ret.TTL = other.TTL
+	}
+	sortControlGroupFactors(ret.Factors)
+	return ret
+}
+
+func sortControlGroupFactors(factors []*ControlGroupFactor) {
+	sort.Slice(factors, func(i, j int) bool {
+		return factors[i].Name < factors[j].Name
+	})
+}
+
+// controlGroupRequest is the state of a request gated by a control group,
+// which is stored in the cubbyhole of its control group token
+type controlGroupRequest struct {
+	RequestPath     string                       `json:"request_path"`
+	RequestEntityID string                       `json:"request_entity_id"`
+	NamespaceID     string                       `json:"namespace_id"`
+	Factors         []*ControlGroupFactor        `json:"factors"`
+	Authorizations  []*controlGroupAuthorization `json:"authorizations"`
+}
+
+// controlGroupAuthorization records the factors an approver satisfied
+type controlGroupAuthorization struct {
+	EntityID string    `json:"entity_id"`
+	Factors  []string  `json:"factors"`
+	Time     time.Time `json:"time"`
+}
+
+// approved checks whether every factor has enough authorizations
+func (r *controlGroupRequest) approved() bool {
+	for _, factor := range r.Factors {
+		var count int
+		for _, authz := range r.Authorizations {
+			if strutil.StrListContains(authz.Factors, factor.Name) {
+				count++
+			}
+		}
+		if count < factor.Approvals {
+			return false
+		}
+	}
+	return true
+}
+
+// wrapInControlGroup wraps the response of a read gated by a control group in
+// a control group token. Only the wrapping information is returned.
+func (c *Core) wrapInControlGroup(ctx context.Context, req *logical.Request, resp *logical.Response, auth *logical.Auth, cg *ControlGroup) (*logical.Response, error) {
+	resp.WrapInfo = &wrapping.ResponseWrapInfo{
+		TTL: cg.TTL,
+	}
+	if req.WrapInfo != nil {
+		resp.WrapInfo.Format = req.WrapInfo.Format
+	}
+
+	cgReq := &controlGroupRequest{
+		RequestPath: req.Path,
+		NamespaceID: namespaceFromContext(ctx).ID,
+		Factors:     cg.Factors,
+	}
+	if auth != nil {
+		cgReq.RequestEntityID = auth.EntityID
+	}
+
+	cubbyResp, err := c.wrapInCubbyhole(ctx, req, resp, auth, cgReq)
+	if cubbyResp != nil || err != nil {
+		return cubbyResp, err
+	}
+
+	return &logical.Response{
+		WrapInfo: resp.WrapInfo,
+		Warnings: resp.Warnings,
+	}, nil
+}
+
+// storeControlGroupRequest stores the state of the request in the cubbyhole
+// of its control group token
+func (c *Core) storeControlGroupRequest(ctx context.Context, token string, cgReq *controlGroupRequest) (*logical.Response, error) {
+	raw, err := json.Marshal(cgReq)
+	if err != nil {
+		return nil, errwrap.Wrapf("failed to encode control group request: {{err}}", err)
+	}
+
+	return c.router.Route(ctx, &logical.Request{
+		Operation:   logical.CreateOperation,
+		Path:        controlGroupCubbyholePath,
+		ClientToken: token,
+		Data: map[string]interface{}{
+			"request": string(raw),
+		},
+	})
+}
+
+// fetchControlGroupRequest reads the state of the request of a control group
+// token
+func (c *Core) fetchControlGroupRequest(ctx context.Context, token string) (*controlGroupRequest, error) {
+	cubbyResp, err := c.router.Route(ctx, &logical.Request{
+		Operation:   logical.ReadOperation,
+		Path:        controlGroupCubbyholePath,
+		ClientToken: token,
+	})
+	if err != nil {
+		return nil, errwrap.Wrapf("error looking up control group request: {{err}}", err)
+	}
+	if cubbyResp == nil || cubbyResp.Data == nil {
+		return nil, errors.New("no control group request found")
+	}
+	if cubbyResp.IsError() {
+		return nil, cubbyResp.Error()
+	}
+
+	raw, ok := cubbyResp.Data["request"].(string)
+	if !ok {
+		return nil, errors.New("could not decode control group request")
+	}
+	var cgReq controlGroupRequest
+	if err := json.Unmarshal([]byte(raw), &cgReq); err != nil {
+		return nil, errwrap.Wrapf("failed to decode control group request: {{err}}", err)
+	}
+	return &cgReq, nil
+}
+
+// controlGroupUnwrap returns the wrapped response of a control group token
+// once its request is authorized, revoking the token
+func (b *SystemBackend) controlGroupUnwrap(ctx context.Context, token string) (string, error) {
+	cgReq, err := b.Core.fetchControlGroupRequest(ctx, token)
+	if err != nil {
+		return "", err
+	}
+	if !cgReq.approved() {
+		return "", logical.ErrMultiAuthzPending
+	}
+
+	// Control group tokens are not used up by handleRequest, so the token is
+	// revoked here
+	defer b.Core.tokenStore.Revoke(ctx, token)
+
+	return b.responseWrappingUnwrap(ctx, token, false)
+}
+
+func controlGroupPaths(b *SystemBackend) []*framework.Path {
+	accessorField := &framework.FieldSchema{
+		Type:        framework.TypeString,
+		Description: strings.TrimSpace(sysHelp["control-group-accessor"][0]),
+	}
+
+	return []*framework.Path{
+		{
+			Pattern: "control-group/authorize$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"accessor": accessorField,
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: b.handleControlGroupAuthorize,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["control-group-authorize"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["control-group-authorize"][1]),
+		},
+		{
+			Pattern: "control-group/request$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"accessor": accessorField,
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: b.handleControlGroupRequest,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["control-group-request"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["control-group-request"][1]),
+		},
+	}
+}
+
+// controlGroupTokenByAccessor looks up the control group token of the
+// accessor
+func (b *SystemBackend) controlGroupTokenByAccessor(ctx context.Context, accessor string) (*TokenEntry, *logical.Response, error) {
+	if accessor == "" {
+		return nil, logical.ErrorResponse("missing accessor"), logical.ErrInvalidRequest
+	}
+
+	aEntry, err := b.Core.tokenStore.lookupByAccessor(ctx, accessor, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	if aEntry.TokenID == "" {
+		return nil, logical.ErrorResponse("invalid accessor"), logical.ErrInvalidRequest
+	}
+
+	te, err := b.Core.tokenStore.Lookup(ctx, aEntry.TokenID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if te == nil || len(te.Policies) != 1 || te.Policies[0] != controlGroupPolicyName {
+		return nil, logical.ErrorResponse("accessor is not the accessor of a control group token"), logical.ErrInvalidRequest
+	}
+
+	return te, nil, nil
+}
+
+// controlGroupIdentityStore returns the identity store of the namespace of
+// the request of a control group token
+func (b *SystemBackend) controlGroupIdentityStore(cgReq *controlGroupRequest) (*IdentityStore, *logical.Response, error) {
+	ns := b.Core.namespaceByID(cgReq.NamespaceID)
+	if ns == nil {
+		return nil, logical.ErrorResponse("namespace of the request no longer exists"), logical.ErrInvalidRequest
+	}
+	identityStore := b.Core.identityStoreForNamespace(ns)
+	if identityStore == nil {
+		return nil, nil, errors.New("identity store of the namespace of the request is unavailable")
+	}
+	return identityStore, nil, nil
+}
+
+// handleControlGroupAuthorize records the authorization of the request of a
+// control group token by the entity of the caller
+func (b *SystemBackend) handleControlGroupAuthorize(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	te, resp, err := b.controlGroupTokenByAccessor(ctx, d.Get("accessor").(string))
+	if te == nil {
+		return resp, err
+	}
+
+	if req.EntityID == "" {
+		return logical.ErrorResponse("authorizing control group requests requires an entity"), logical.ErrInvalidRequest
+	}
+
+	lock := locksutil.LockForKey(b.Core.tokenStore.tokenLocks, te.ID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	cgReq, err := b.Core.fetchControlGroupRequest(ctx, te.ID)
+	if err != nil {
+		return nil, err
+	}
+	if cgReq.RequestEntityID == req.EntityID {
+		return logical.ErrorResponse("requesters cannot authorize their own requests"), logical.ErrPermissionDenied
+	}
+
+	for _, authz := range cgReq.Authorizations {
+		if authz.EntityID == req.EntityID {
+			return &logical.Response{
+				Data: map[string]interface{}{
+					"approved": cgReq.approved(),
+				},
+			}, nil
+		}
+	}
+
+	// The groups of the approvers are looked up in the namespace of the
+	// request
+	identityStore, resp, err := b.controlGroupIdentityStore(cgReq)
+	if identityStore == nil {
+		return resp, err
+	}
+	directGroups, inheritedGroups, err := identityStore.groupsByEntityID(req.EntityID)
+	if err != nil {
+		return nil, err
+	}
+	var groupNames []string
+	for _, group := range append(directGroups, inheritedGroups...) {
+		groupNames = append(groupNames, group.Name)
+	}
+
+	authz := &controlGroupAuthorization{
+		EntityID: req.EntityID,
+		Time:     time.Now(),
+	}
+	for _, factor := range cgReq.Factors {
+		for _, name := range factor.GroupNames {
+			if strutil.StrListContains(groupNames, name) {
+				authz.Factors = append(authz.Factors, factor.Name)
+				break
+			}
+		}
+	}
+	if len(authz.Factors) == 0 {
+		return logical.ErrorResponse("entity is not an approver of the request"), logical.ErrPermissionDenied
+	}
+
+	cgReq.Authorizations = append(cgReq.Authorizations, authz)
+	if cubbyResp, err := b.Core.storeControlGroupRequest(ctx, te.ID, cgReq); err != nil || (cubbyResp != nil && cubbyResp.IsError()) {
+		if err == nil {
+			err = cubbyResp.Error()
+		}
+		return nil, errwrap.Wrapf("failed to store control group request: {{err}}", err)
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"approved": cgReq.approved(),
+		},
+	}, nil
+}
+
+// handleControlGroupRequest returns the status of the request of a control
+// group token
+func (b *SystemBackend) handleControlGroupRequest(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	te, resp, err := b.controlGroupTokenByAccessor(ctx, d.Get("accessor").(string))
+	if te == nil {
+		return resp, err
+	}
+
+	cgReq, err := b.Core.fetchControlGroupRequest(ctx, te.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	identityStore, resp, err := b.controlGroupIdentityStore(cgReq)
+	if identityStore == nil {
+		return resp, err
+	}
+	entityName := func(id string) (string, error) {
+		entity, err := identityStore.MemDBEntityByID(id, false)
+		if err != nil || entity == nil {
+			return "", err
+		}
+		return entity.Name, nil
+	}
+
+	authorizations := make([]map[string]interface{}, 0, len(cgReq.Authorizations))
+	for _, authz := range cgReq.Authorizations {
+		name, err := entityName(authz.EntityID)
+		if err != nil {
+			return nil, err
+		}
+		authorizations = append(authorizations, map[string]interface{}{
+			"entity_id":   authz.EntityID,
+			"entity_name": name,
+		})
+	}
+
+	resp = &logical.Response{
+		Data: map[string]interface{}{
+			"approved":       cgReq.approved(),
+			"request_path":   cgReq.RequestPath,
+			"authorizations": authorizations,
+		},
+	}
+	if cgReq.RequestEntityID != "" {
+		name, err := entityName(cgReq.RequestEntityID)
+		if err != nil {
+			return nil, err
+		}
+		resp.Data["request_entity"] = map[string]interface{}{
+			"id":   cgReq.RequestEntityID,
+			"name": name,
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,256 @@
+package vault
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/logical"
+)
+
+const controlGroupTestPolicy = `
+name = "breakglass"
+path "secret/breakglass" {
+	capabilities = ["read"]
+	control_group = {
+		ttl = "1h"
+		factor "managers" {
+			identity {
+				group_names = ["managers"]
+				approvals = 2
+			}
+		}
+	}
+}
+path "sys/control-group/*" {
+	capabilities = ["update"]
+}
+`
+
+func TestControlGroup_Parse(t *testing.T) {
+	policy, err := ParseACLPolicy(controlGroupTestPolicy)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	cg := policy.Paths[0].Permissions.ControlGroup
+	if cg == nil || cg.TTL != time.Hour || len(cg.Factors) != 1 {
+		t.Fatalf("bad: %#v", cg)
+	}
+	if f := cg.Factors[0]; f.Name != "managers" || len(f.GroupNames) != 1 || f.GroupNames[0] != "managers" || f.Approvals != 2 {
+		t.Fatalf("bad: %#v", f)
+	}
+
+	for name, rules := range map[string]string{
+		"no factors": `path "secret/foo" {
+	capabilities = ["read"]
+	control_group = {
+		ttl = "1h"
+	}
+}`,
+		"no groups": `path "secret/foo" {
+	capabilities = ["read"]
+	control_group = {
+		factor "managers" {
+			identity {
+				approvals = 1
+			}
+		}
+	}
+}`,
+		"invalid ttl": `path "secret/foo" {
+	capabilities = ["read"]
+	control_group = {
+		ttl = "soon"
+		factor "managers" {
+			identity {
+				group_names = ["managers"]
+			}
+		}
+	}
+}`,
+	} {
+		if _, err := ParseACLPolicy(rules); err == nil {
+			t.Fatalf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestControlGroup_Merge(t *testing.T) {
+	a := &ControlGroup{
+		TTL:     time.Hour,
+		Factors: []*ControlGroupFactor{{Name: "b", GroupNames: []string{"b"}, Approvals: 1}},
+	}
+	b := &ControlGroup{
+		TTL:     time.Minute,
+		Factors: []*ControlGroupFactor{{Name: "a", GroupNames: []string{"a"}, Approvals: 1}},
+	}
+
+	merged := a.merge(b)
+	if merged.TTL != time.Minute || len(merged.Factors) != 2 || merged.Factors[0].Name != "a" || merged.Factors[1].Name != "b" {
+		t.Fatalf("bad: %#v", merged)
+	}
+	if len(a.Factors) != 1 || len(b.Factors) != 1 {
+		t.Fatal("expected the merged control groups to be unmodified")
+	}
+	if (*ControlGroup)(nil).merge(a) != a || a.merge(nil) != a {
+		t.Fatal("expected merging with nil to return the control group")
+	}
+}
+
+// testControlGroupEntityToken creates an entity with a token of the given
+// policies
+func testControlGroupEntityToken(t *testing.T, c *Core, name string, policies ...string) (string, string) {
+	resp, err := c.identityStore.HandleRequest(context.Background(), &logical.Request{
+		Path:      "entity",
+		Operation: logical.UpdateOperation,
+		Data: map[string]interface{}{
+			"name": name,
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+	entityID := resp.Data["id"].(string)
+
+	te := &TokenEntry{
+		Path:     "auth/token/create",
+		Policies: policies,
+		EntityID: entityID,
+	}
+	if err := c.tokenStore.create(context.Background(), te); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	return entityID, te.ID
+}
+
+func TestControlGroup_Authorize(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	policy, err := ParseACLPolicy(controlGroupTestPolicy)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := c.policyStore.SetPolicy(context.Background(), policy); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "secret/breakglass")
+	req.ClientToken = root
+	req.Data["password"] = "hunter2"
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	_, requester := testControlGroupEntityToken(t, c, "requester", "breakglass")
+	var managerIDs, managers []string
+	for _, name := range []string{"alice", "bob"} {
+		entityID, token := testControlGroupEntityToken(t, c, name, "breakglass")
+		managerIDs = append(managerIDs, entityID)
+		managers = append(managers, token)
+	}
+	_, outsider := testControlGroupEntityToken(t, c, "outsider", "breakglass")
+	resp, err := c.identityStore.HandleRequest(context.Background(), &logical.Request{
+		Path:      "group",
+		Operation: logical.UpdateOperation,
+		Data: map[string]interface{}{
+			"name":              "managers",
+			"member_entity_ids": managerIDs,
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+
+	// The read returns a control group token instead of the secret
+	req = logical.TestRequest(t, logical.ReadOperation, "secret/breakglass")
+	req.ClientToken = requester
+	resp, err = c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp == nil || resp.WrapInfo == nil || resp.WrapInfo.Token == "" || resp.Data != nil {
+		t.Fatalf("expected a control group token, got %#v", resp)
+	}
+	if resp.WrapInfo.TTL != time.Hour || resp.WrapInfo.CreationPath != "secret/breakglass" {
+		t.Fatalf("bad: %#v", resp.WrapInfo)
+	}
+	cgToken := resp.WrapInfo.Token
+	accessor := resp.WrapInfo.Accessor
+
+	unwrap := func() (*logical.Response, error) {
+		req := logical.TestRequest(t, logical.UpdateOperation, "sys/wrapping/unwrap")
+		req.ClientToken = cgToken
+		return c.HandleRequest(req)
+	}
+	authorize := func(token string) (*logical.Response, error) {
+		req := logical.TestRequest(t, logical.UpdateOperation, "sys/control-group/authorize")
+		req.ClientToken = token
+		req.Data["accessor"] = accessor
+		return c.HandleRequest(req)
+	}
+
+	// The token cannot be unwrapped until the request is approved, and
+	// failing to unwrap doesn't use the token up
+	for i := 0; i < 2; i++ {
+		if _, err := unwrap(); err == nil || !errwrap.Contains(err, logical.ErrMultiAuthzPending.Error()) {
+			t.Fatalf("expected the request to be pending, got %v", err)
+		}
+	}
+
+	// The requester and entities outside of the groups cannot authorize
+	for _, token := range []string{requester, outsider} {
+		if _, err := authorize(token); err == nil || !errwrap.Contains(err, logical.ErrPermissionDenied.Error()) {
+			t.Fatalf("expected permission denied, got %v", err)
+		}
+	}
+
+	resp, err = authorize(managers[0])
+	if err != nil || resp == nil || resp.Data["approved"] != false {
+		t.Fatalf("err: %v resp: %#v", err, resp)
+	}
+	// Authorizing twice doesn't count twice
+	resp, err = authorize(managers[0])
+	if err != nil || resp == nil || resp.Data["approved"] != false {
+		t.Fatalf("err: %v resp: %#v", err, resp)
+	}
+	if _, err := unwrap(); err == nil {
+		t.Fatal("expected the request to be pending")
+	}
+
+	resp, err = authorize(managers[1])
+	if err != nil || resp == nil || resp.Data["approved"] != true {
+		t.Fatalf("err: %v resp: %#v", err, resp)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/control-group/request")
+	req.ClientToken = requester
+	req.Data["accessor"] = accessor
+	resp, err = c.HandleRequest(req)
+	if err != nil || resp == nil {
+		t.Fatalf("err: %v resp: %#v", err, resp)
+	}
+	if resp.Data["approved"] != true || resp.Data["request_path"] != "secret/breakglass" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if entity := resp.Data["request_entity"].(map[string]interface{}); entity["name"] != "requester" {
+		t.Fatalf("bad: %#v", entity)
+	}
+	if authzs := resp.Data["authorizations"].([]map[string]interface{}); len(authzs) != 2 || authzs[0]["entity_name"] != "alice" || authzs[1]["entity_name"] != "bob" {
+		t.Fatalf("bad: %#v", authzs)
+	}
+
+	resp, err = unwrap()
+	if err != nil || resp == nil {
+		t.Fatalf("err: %v resp: %#v", err, resp)
+	}
+	if body := string(resp.Data[logical.HTTPRawBody].([]byte)); !strings.Contains(body, "hunter2") {
+		t.Fatalf("bad: %s", body)
+	}
+
+	// The token is revoked once unwrapped
+	if te, err := c.tokenStore.Lookup(context.Background(), cgToken); err != nil || te != nil {
+		t.Fatalf("expected the control group token to be revoked, got %#v %v", te, err)
+	}
+}
@@ -25,11 +25,15 @@ import (
 	"github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/go-uuid"
 	"github.com/hashicorp/vault/audit"
+	"github.com/hashicorp/vault/helper/cidrutil"
 	"github.com/hashicorp/vault/helper/consts"
+	"github.com/hashicorp/vault/helper/entropy"
 	"github.com/hashicorp/vault/helper/errutil"
 	"github.com/hashicorp/vault/helper/identity"
 	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/helper/logbuffer"
 	"github.com/hashicorp/vault/helper/logformat"
+	"github.com/hashicorp/vault/helper/metricsutil"
 	"github.com/hashicorp/vault/helper/mlock"
 	"github.com/hashicorp/vault/helper/reload"
 	"github.com/hashicorp/vault/helper/tlsutil"
@@ -60,6 +64,10 @@ const (
 	// HA lock if an error is encountered
 	lockRetryInterval = 10 * time.Second
 
+	// gaugeMetricsInterval is the interval at which the gauges requiring a
+	// scan of the storage are emitted
+	gaugeMetricsInterval = time.Minute
+
 	// leaderCheckInterval is how often a standby checks for a new leader
 	leaderCheckInterval = 2500 * time.Millisecond
 
@@ -172,6 +180,17 @@ type Core struct {
 	// Our Seal, for seal configuration information
 	seal Seal
 
+	// migrationSeal is the configured seal while a seal migration is
+	// pending, in which case seal is the seal Vault was initialized with
+	migrationSeal Seal
+
+	// unwrapSeal is the previous seal when migrating away from an auto seal,
+	// and sealMigrationChecked is set once the seal Vault was initialized
+	// with has been checked against the configured seal
+	unwrapSeal           Seal
+	sealMigrationChecked bool
+	sealMigrationLock    sync.Mutex
+
 	// barrier is the security barrier wrapping the physical backend
 	barrier SecurityBarrier
 
@@ -196,6 +215,13 @@ type Core struct {
 	standbyStopCh    chan struct{}
 	manualStepDownCh chan struct{}
 
+	// performanceStandby is set if the standby serves the requests it can
+	// serve without writing to storage. perfStandby is set while the state
+	// needed for it is set up, and perfStandbyStopCh stops its refresh.
+	performanceStandby bool
+	perfStandby        bool
+	perfStandbyStopCh  chan struct{}
+
 	// unlockInfo has the keys provided to Unseal until the threshold number of parts is available, as well as the operation nonce
 	unlockInfo *unlockInformation
 
@@ -214,6 +240,11 @@ type Core struct {
 	recoveryRekeyProgress [][]byte
 	rekeyLock             sync.RWMutex
 
+	// barrierRekeyVerifyProgress and recoveryRekeyVerifyProgress hold the new
+	// shares provided to verify a rekey before the new key is installed
+	barrierRekeyVerifyProgress  [][]byte
+	recoveryRekeyVerifyProgress [][]byte
+
 	// mounts is loaded after unseal since it is a protected
 	// configuration
 	mounts *MountTable
@@ -268,6 +299,30 @@ type Core struct {
 	// identityStore is used to manage client entities
 	identityStore *IdentityStore
 
+	// loginMFALock protects the login MFA methods and enforcements
+	loginMFALock sync.RWMutex
+
+	// totpMFAUsage tracks the passcodes accepted and the failed validations
+	// of the TOTP MFA methods, by method ID and entity ID
+	totpMFAUsageLock sync.Mutex
+	totpMFAUsage     map[string]*totpMFAUsage
+
+	// namespaces holds the child namespaces by ID
+	namespaces     map[string]*Namespace
+	namespacesLock sync.RWMutex
+
+	// namespaceIdentityStores holds the identity stores of the child
+	// namespaces by namespace ID
+	namespaceIdentityStores     map[string]*IdentityStore
+	namespaceIdentityStoresLock sync.RWMutex
+
+	// quotas holds the rate limit and lease count quotas
+	quotas *quotaManager
+
+	// events delivers the events published by the backends to the
+	// subscribers
+	events *eventBus
+
 	// metricsCh is used to stop the metrics streaming
 	metricsCh chan struct{}
 
@@ -278,13 +333,47 @@ type Core struct {
 	defaultLeaseTTL time.Duration
 	maxLeaseTTL     time.Duration
 
+	// defaultMaxRequestDuration is the maximum duration of the handling of
+	// the requests, unlimited if zero
+	defaultMaxRequestDuration time.Duration
+
 	logger log.Logger
 
 	// cachingDisabled indicates whether caches are disabled
 	cachingDisabled bool
 	// Cache stores the actual cache; we always have this but may bypass it if
 	// disabled
-	physicalCache physical.ToggleablePurgemonster
+	physicalCache *physical.Cache
+
+	// indexTracker tracks the writes to the physical backend to give
+	// read-your-writes consistency to the requests asking for it
+	indexTracker *physical.IndexTracker
+
+	// metricsHelper exposes the collected metrics
+	metricsHelper *metricsutil.MetricsHelper
+
+	// recentLogs keeps the recent log lines served by sys/debug/logs
+	recentLogs *logbuffer.Buffer
+
+	// keyRotation is the automatic rotation of the barrier encryption key,
+	// set up on the active node
+	keyRotation *keyRotation
+
+	// activityLog records the distinct clients of the mounts by month, set up
+	// on the active node
+	activityLog *activityLog
+
+	// mountPurgeStopCh stops the periodic purge of the mounts marked for
+	// deletion on the active node
+	mountPurgeStopCh chan struct{}
+
+	// inFlightRequests tracks the requests being handled and enforces the
+	// maximum of in-flight requests of the mounts
+	inFlightRequests *inFlightRequests
+
+	// entropySource is the external source of entropy mixed into the random
+	// data of the generation of keys, if any
+	entropySource entropy.Sourcer
 
 	// reloadFuncs is a map containing reload functions
 	reloadFuncs map[string][]reload.ReloadFunc
@@ -362,6 +451,19 @@ type Core struct {
 	replicationState           *uint32
 	activeNodeReplicationState *uint32
 
+	// drBarrier records the writes to the barrier in the WAL of the DR
+	// primary. drPrimary and drSecondary hold the DR replication state of
+	// the active node, whichever is set up; they're guarded by the
+	// drReplicationLock.
+	drBarrier         *drWALBarrier
+	drReplicationLock sync.RWMutex
+	drPrimary         *drPrimary
+	drSecondary       *drSecondary
+
+	// activeNodeHeartbeat stores the hostname of the active node and the time
+	// of the last successful heartbeat to it on standbys
+	activeNodeHeartbeat *atomic.Value
+
 	// uiEnabled indicates whether Vault Web UI is enabled or not
 	uiEnabled bool
 
@@ -402,6 +504,22 @@ type CoreConfig struct {
 
 	Seal Seal `json:"seal" structs:"seal" mapstructure:"seal"`
 
+	// UnwrapSeal is the previous seal when migrating away from an auto seal
+	UnwrapSeal Seal `json:"unwrap_seal" structs:"unwrap_seal" mapstructure:"unwrap_seal"`
+
+	// MetricsHelper exposes the collected metrics in sys/metrics. May be nil,
+	// which disables the endpoint.
+	MetricsHelper *metricsutil.MetricsHelper `json:"metrics_helper" structs:"metrics_helper" mapstructure:"metrics_helper"`
+
+	// RecentLogs keeps the recent log lines of the server, served by
+	// sys/debug/logs. May be nil, which disables the endpoint.
+	RecentLogs *logbuffer.Buffer `json:"recent_logs" structs:"recent_logs" mapstructure:"recent_logs"`
+
+	// EntropySource is an external source of entropy, such as an HSM, mixed
+	// into the random data used to generate the barrier keys and the keys of
+	// the backends. May be nil, in which case only the system's is used.
+	EntropySource entropy.Sourcer `json:"entropy_source" structs:"entropy_source" mapstructure:"entropy_source"`
+
 	Logger log.Logger `json:"logger" structs:"logger" mapstructure:"logger"`
 
 	// Disables the LRU cache on the physical backend
@@ -423,6 +541,10 @@ type CoreConfig struct {
 
 	MaxLeaseTTL time.Duration `json:"max_lease_ttl" structs:"max_lease_ttl" mapstructure:"max_lease_ttl"`
 
+	// The maximum duration of the handling of the requests, after which
+	// they are cancelled; unlimited if zero
+	DefaultMaxRequestDuration time.Duration `json:"default_max_request_duration" structs:"default_max_request_duration" mapstructure:"default_max_request_duration"`
+
 	ClusterName string `json:"cluster_name" structs:"cluster_name" mapstructure:"cluster_name"`
 
 	ClusterCipherSuites string `json:"cluster_cipher_suites" structs:"cluster_cipher_suites" mapstructure:"cluster_cipher_suites"`
@@ -432,6 +554,9 @@ type CoreConfig struct {
 	// Enable the raw endpoint
 	EnableRaw bool `json:"enable_raw" structs:"enable_raw" mapstructure:"enable_raw"`
 
+	// Serve the read-only requests on standbys rather than forwarding them
+	PerformanceStandby bool `json:"performance_standby" structs:"performance_standby" mapstructure:"performance_standby"`
+
 	PluginDirectory string `json:"plugin_directory" structs:"plugin_directory" mapstructure:"plugin_directory"`
 
 	ReloadFuncs     *map[string][]reload.ReloadFunc
@@ -486,6 +611,7 @@ func NewCore(conf *CoreConfig) (*Core, error) {
 		logger:                           conf.Logger,
 		defaultLeaseTTL:                  conf.DefaultLeaseTTL,
 		maxLeaseTTL:                      conf.MaxLeaseTTL,
+		defaultMaxRequestDuration:        conf.DefaultMaxRequestDuration,
 		cachingDisabled:                  conf.DisableCache,
 		clusterName:                      conf.ClusterName,
 		clusterListenerShutdownCh:        make(chan struct{}),
@@ -493,11 +619,17 @@ func NewCore(conf *CoreConfig) (*Core, error) {
 		clusterPeerClusterAddrsCache:     cache.New(3*HeartbeatInterval, time.Second),
 		enableMlock:                      !conf.DisableMlock,
 		rawEnabled:                       conf.EnableRaw,
+		performanceStandby:               conf.PerformanceStandby,
 		replicationState:                 new(uint32),
 		rpcServerActive:                  new(uint32),
 		atomicPrimaryClusterAddrs:        new(atomic.Value),
 		atomicPrimaryFailoverAddrs:       new(atomic.Value),
 		activeNodeReplicationState:       new(uint32),
+		activeNodeHeartbeat:              new(atomic.Value),
+		metricsHelper:                    conf.MetricsHelper,
+		recentLogs:                       conf.RecentLogs,
+		entropySource:                    conf.EntropySource,
+		inFlightRequests:                 newInFlightRequests(),
 	}
 
 	atomic.StoreUint32(c.replicationState, uint32(consts.ReplicationDRDisabled|consts.ReplicationPerformanceDisabled))
@@ -513,8 +645,20 @@ func NewCore(conf *CoreConfig) (*Core, error) {
 	// Load CORS config and provide a value for the core field.
 	c.corsConfig = &CORSConfig{core: c}
 
+	c.events = newEventBus(c.logger, c.resolveNamespace)
+
 	phys := conf.Physical
 	_, txnOK := conf.Physical.(physical.Transactional)
+
+	// Track the writes to the backend so that the reads of a request can wait
+	// for an eventually consistent backend to reflect them
+	if txnOK {
+		tracker := physical.NewTransactionalIndexTracker(phys, conf.Logger)
+		phys, c.indexTracker = tracker, tracker.IndexTracker
+	} else {
+		tracker := physical.NewIndexTracker(phys, conf.Logger)
+		phys, c.indexTracker = tracker, tracker
+	}
 	if c.seal == nil {
 		c.seal = &DefaultSeal{}
 	}
@@ -526,11 +670,12 @@ func NewCore(conf *CoreConfig) (*Core, error) {
 
 	// Wrap the physical backend in a cache layer if enabled
 	if txnOK {
-		c.physical = physical.NewTransactionalCache(c.sealUnwrapper, conf.CacheSize, conf.Logger)
+		cache := physical.NewTransactionalCache(c.sealUnwrapper, conf.CacheSize, conf.Logger)
+		c.physical, c.physicalCache = cache, cache.Cache
 	} else {
-		c.physical = physical.NewCache(c.sealUnwrapper, conf.CacheSize, conf.Logger)
+		cache := physical.NewCache(c.sealUnwrapper, conf.CacheSize, conf.Logger)
+		c.physical, c.physicalCache = cache, cache
 	}
-	c.physicalCache = c.physical.(physical.ToggleablePurgemonster)
 
 	if !conf.DisableMlock {
 		// Ensure our memory usage is locked into physical RAM
@@ -557,10 +702,15 @@ func NewCore(conf *CoreConfig) (*Core, error) {
 	}
 
 	// Construct a new AES-GCM barrier
-	c.barrier, err = NewAESGCMBarrier(c.physical)
+	barrier, err := NewAESGCMBarrier(c.physical)
 	if err != nil {
 		return nil, fmt.Errorf("barrier setup failed: %v", err)
 	}
+	if c.entropySource != nil {
+		barrier.SetReader(entropy.NewReader(c.entropySource))
+	}
+	c.drBarrier = &drWALBarrier{SecurityBarrier: barrier}
+	c.barrier = c.drBarrier
 
 	if conf.HAPhysical != nil && conf.HAPhysical.HAEnabled() {
 		c.ha = conf.HAPhysical
@@ -613,6 +763,16 @@ func NewCore(conf *CoreConfig) (*Core, error) {
 	}
 	c.auditBackends = auditBackends
 
+	// The storage may not be reachable yet, in which case the check for a
+	// pending seal migration is done again before unsealing
+	c.unwrapSeal = conf.UnwrapSeal
+	pe, err := c.physical.Get(context.Background(), barrierSealConfigPath)
+	if err != nil {
+		c.logger.Warn("core: failed to check for a pending seal migration, checking again before unsealing", "error", err)
+	} else if err := c.adjustForSealMigration(pe, c.unwrapSeal); err != nil {
+		return nil, err
+	}
+
 	return c, nil
 }
 
@@ -682,16 +842,22 @@ func (c *Core) LookupToken(token string) (*TokenEntry, error) {
 // which the given entity ID is merged into will be returned. This function
 // also returns the cumulative list of policies that the entity is entitled to.
 // This list includes the policies from the entity itself and from all the
-// groups in which the given entity ID is a member of.
-func (c *Core) fetchEntityAndDerivedPolicies(entityID string) (*identity.Entity, []string, error) {
+// groups in which the given entity ID is a member of. The entity is looked up
+// in the identity store of the namespace carried by the context.
+func (c *Core) fetchEntityAndDerivedPolicies(ctx context.Context, entityID string) (*identity.Entity, []string, error) {
 	if entityID == "" {
 		return nil, nil, nil
 	}
 
+	identityStore := c.identityStoreForNamespace(namespaceFromContext(ctx))
+	if identityStore == nil {
+		return nil, nil, nil
+	}
+
 	//c.logger.Debug("core: entity set on the token", "entity_id", te.EntityID)
 
 	// Fetch the entity
-	entity, err := c.identityStore.MemDBEntityByID(entityID, false)
+	entity, err := identityStore.MemDBEntityByID(entityID, false)
 	if err != nil {
 		c.logger.Error("core: failed to lookup entity using its ID", "error", err)
 		return nil, nil, err
@@ -701,7 +867,7 @@ func (c *Core) fetchEntityAndDerivedPolicies(entityID string) (*identity.Entity,
 		// If there was no corresponding entity object found, it is
 		// possible that the entity got merged into another entity. Try
 		// finding entity based on the merged entity index.
-		entity, err = c.identityStore.MemDBEntityByMergedEntityID(entityID, false)
+		entity, err = identityStore.MemDBEntityByMergedEntityID(entityID, false)
 		if err != nil {
 			c.logger.Error("core: failed to lookup entity in merged entity ID index", "error", err)
 			return nil, nil, err
@@ -715,7 +881,7 @@ func (c *Core) fetchEntityAndDerivedPolicies(entityID string) (*identity.Entity,
 		// Attach the policies on the entity
 		policies = append(policies, entity.Policies...)
 
-		groupPolicies, err := c.identityStore.groupPoliciesByEntityID(entity.ID)
+		groupPolicies, err := identityStore.groupPoliciesByEntityID(entity.ID)
 		if err != nil {
 			c.logger.Error("core: failed to fetch group policies", "error", err)
 			return nil, nil, err
@@ -744,6 +910,9 @@ func (c *Core) fetchACLTokenEntryAndEntity(clientToken string) (*ACL, *TokenEntr
 	// Resolve the token policy
 	te, err := c.tokenStore.Lookup(c.activeContext, clientToken)
 	if err != nil {
+		if c.standby && isReadOnlyErr(err) {
+			return nil, nil, nil, consts.ErrPerformanceStandbyForward
+		}
 		c.logger.Error("core: failed to lookup token", "error", err)
 		return nil, nil, nil, ErrInternalError
 	}
@@ -753,9 +922,23 @@ func (c *Core) fetchACLTokenEntryAndEntity(clientToken string) (*ACL, *TokenEntr
 		return nil, nil, nil, logical.ErrPermissionDenied
 	}
 
+	// Performance standbys cannot decrement the uses of tokens, and have no
+	// identity store to fetch the policies of entities from
+	if c.standby && (te.NumUses != 0 || te.EntityID != "") {
+		return nil, nil, nil, consts.ErrPerformanceStandbyForward
+	}
+
+	// The policies and entity of the token are resolved in its namespace,
+	// which can no longer be used once it is deleted
+	ns := c.namespaceByID(te.NamespaceID)
+	if ns == nil {
+		return nil, nil, nil, logical.ErrPermissionDenied
+	}
+	ctx := contextWithNamespace(c.activeContext, ns)
+
 	tokenPolicies := te.Policies
 
-	entity, derivedPolicies, err := c.fetchEntityAndDerivedPolicies(te.EntityID)
+	entity, derivedPolicies, err := c.fetchEntityAndDerivedPolicies(ctx, te.EntityID)
 	if err != nil {
 		return nil, nil, nil, ErrInternalError
 	}
@@ -763,7 +946,7 @@ func (c *Core) fetchACLTokenEntryAndEntity(clientToken string) (*ACL, *TokenEntr
 	tokenPolicies = append(tokenPolicies, derivedPolicies...)
 
 	// Construct the corresponding ACL object
-	acl, err := c.policyStore.ACL(c.activeContext, tokenPolicies...)
+	acl, err := c.policyStore.ACL(ctx, entity, tokenPolicies...)
 	if err != nil {
 		c.logger.Error("core: failed to construct ACL", "error", err)
 		return nil, nil, nil, ErrInternalError
@@ -772,7 +955,9 @@ func (c *Core) fetchACLTokenEntryAndEntity(clientToken string) (*ACL, *TokenEntr
 	return acl, te, entity, nil
 }
 
-func (c *Core) checkToken(ctx context.Context, req *logical.Request, unauth bool) (*logical.Auth, *TokenEntry, error) {
+// checkToken validates the token of the request and checks its policies. The
+// control group of the path is returned if the request is gated by one.
+func (c *Core) checkToken(ctx context.Context, req *logical.Request, unauth bool) (*logical.Auth, *TokenEntry, *ControlGroup, error) {
 	defer metrics.MeasureSince([]string{"core", "check_token"}, time.Now())
 
 	var acl *ACL
@@ -789,7 +974,26 @@ func (c *Core) checkToken(ctx context.Context, req *logical.Request, unauth bool
 		// unauth, we just have no information to attach to the request, so
 		// ignore errors...this was best-effort anyways
 		if err != nil && !unauth {
-			return nil, te, err
+			return nil, te, nil, err
+		}
+	}
+
+	// Tokens can only be used in their namespace and its descendants
+	ns := namespaceFromContext(ctx)
+	if te != nil && !unauth {
+		if tokenNS := c.namespaceByID(te.NamespaceID); tokenNS == nil || !ns.isWithin(tokenNS) {
+			return nil, te, nil, logical.ErrPermissionDenied
+		}
+	}
+
+	// Tokens with bound CIDRs can only be used from within those blocks
+	if te != nil && !unauth && len(te.BoundCIDRs) > 0 {
+		if req.Connection == nil || req.Connection.RemoteAddr == "" {
+			return nil, te, nil, logical.ErrPermissionDenied
+		}
+		belongs, err := cidrutil.IPBelongsToCIDRBlocksSlice(req.Connection.RemoteAddr, te.BoundCIDRs)
+		if err != nil || !belongs {
+			return nil, te, nil, logical.ErrPermissionDenied
 		}
 	}
 
@@ -797,7 +1001,7 @@ func (c *Core) checkToken(ctx context.Context, req *logical.Request, unauth bool
 	rootPath := c.router.RootPath(req.Path)
 
 	if rootPath && unauth {
-		return nil, nil, errors.New("cannot access root path in unauthenticated request")
+		return nil, nil, nil, errors.New("cannot access root path in unauthenticated request")
 	}
 
 	// When we receive a write of either type, rather than require clients to
@@ -816,9 +1020,9 @@ func (c *Core) checkToken(ctx context.Context, req *logical.Request, unauth bool
 		default:
 			c.logger.Error("core: failed to run existence check", "error", err)
 			if _, ok := err.(errutil.UserError); ok {
-				return nil, nil, err
+				return nil, nil, nil, err
 			} else {
-				return nil, nil, ErrInternalError
+				return nil, nil, nil, ErrInternalError
 			}
 		}
 
@@ -852,20 +1056,37 @@ func (c *Core) checkToken(ctx context.Context, req *logical.Request, unauth bool
 	}
 
 	// Check the standard non-root ACLs. Return the token entry if it's not
-	// allowed so we can decrement the use count.
-	authResults := c.performPolicyChecks(ctx, acl, te, req, entity, &PolicyCheckOpts{
+	// allowed so we can decrement the use count. The policies apply to the
+	// full path of the request within its namespace.
+	policyReq := req
+	if !ns.isRoot() {
+		nsReq := *req
+		nsReq.Path = namespaceRequestPath(ns, req.Path)
+		policyReq = &nsReq
+	}
+	authResults := c.performPolicyChecks(ctx, acl, te, policyReq, entity, &PolicyCheckOpts{
 		Unauth:            unauth,
 		RootPrivsRequired: rootPath,
 	})
 	if authResults.Error.ErrorOrNil() != nil {
-		return auth, te, authResults.Error
+		return auth, te, nil, authResults.Error
 	}
 	if !authResults.Allowed {
 		// Return auth for audit logging even if not allowed
-		return auth, te, logical.ErrPermissionDenied
+		return auth, te, nil, logical.ErrPermissionDenied
+	}
+
+	// Bypassing the cache is reserved to tokens with sudo on the path
+	if req.CacheBypass && !authResults.RootPrivs {
+		return auth, te, nil, logical.ErrPermissionDenied
 	}
 
-	return auth, te, nil
+	var controlGroup *ControlGroup
+	if authResults.ACLResults != nil {
+		controlGroup = authResults.ACLResults.ControlGroup
+	}
+
+	return auth, te, controlGroup, nil
 }
 
 // Sealed checks if the Vault is current sealed
@@ -1033,6 +1254,9 @@ func (c *Core) Unseal(key []byte) (bool, error) {
 	if !init {
 		return false, ErrNotInit
 	}
+	if c.migrationSeal != nil {
+		return false, ErrSealMigrationRequired
+	}
 
 	// Verify the key length
 	min, max := c.barrier.KeyLength()
@@ -1082,6 +1306,9 @@ func (c *Core) UnsealWithRecoveryKeys(ctx context.Context, key []byte) (bool, er
 	if !init {
 		return false, ErrNotInit
 	}
+	if c.migrationSeal != nil {
+		return false, ErrSealMigrationRequired
+	}
 
 	var config *SealConfig
 	// If recovery keys are supported then use recovery seal config to unseal
@@ -1533,6 +1760,8 @@ func (c *Core) sealInternal() error {
 		c.stateLock.Unlock()
 		<-c.standbyDoneCh
 		c.stateLock.Lock()
+
+		c.teardownPerformanceStandby()
 	}
 
 	c.logger.Debug("core: sealing barrier")
@@ -1601,12 +1830,30 @@ func (c *Core) postUnseal() (retErr error) {
 	if err := enterprisePostUnseal(c); err != nil {
 		return err
 	}
+	secondary, err := c.setupDRReplication(c.activeContext)
+	if err != nil {
+		return err
+	}
+	if secondary {
+		// DR secondaries only apply the changes streamed by their primary,
+		// the mounts and the rest are set up on promotion
+		if c.ha != nil {
+			if err := c.startClusterListener(c.activeContext); err != nil {
+				return err
+			}
+		}
+		c.logger.Info("core: post-unseal setup complete as a DR secondary")
+		return nil
+	}
 	if err := c.ensureWrappingKey(c.activeContext); err != nil {
 		return err
 	}
 	if err := c.setupPluginCatalog(); err != nil {
 		return err
 	}
+	if err := c.loadNamespaces(c.activeContext); err != nil {
+		return err
+	}
 	if err := c.loadMounts(c.activeContext); err != nil {
 		return err
 	}
@@ -1628,6 +1875,9 @@ func (c *Core) postUnseal() (retErr error) {
 	if err := c.startRollback(); err != nil {
 		return err
 	}
+	if err := c.setupQuotas(c.activeContext); err != nil {
+		return err
+	}
 	if err := c.setupExpiration(); err != nil {
 		return err
 	}
@@ -1643,6 +1893,13 @@ func (c *Core) postUnseal() (retErr error) {
 	if err := c.setupAuditedHeadersConfig(c.activeContext); err != nil {
 		return err
 	}
+	if err := c.setupKeyRotation(c.activeContext); err != nil {
+		return err
+	}
+	if err := c.setupActivityLog(c.activeContext); err != nil {
+		return err
+	}
+	c.startMountPurge(c.activeContext)
 
 	if c.ha != nil {
 		if err := c.startClusterListener(c.activeContext); err != nil {
@@ -1666,6 +1923,8 @@ func (c *Core) preSeal() error {
 	c.barrierRekeyProgress = nil
 	c.recoveryRekeyConfig = nil
 	c.recoveryRekeyProgress = nil
+	c.barrierRekeyVerifyProgress = nil
+	c.recoveryRekeyVerifyProgress = nil
 
 	if c.metricsCh != nil {
 		close(c.metricsCh)
@@ -1674,6 +1933,10 @@ func (c *Core) preSeal() error {
 	var result error
 
 	c.stopClusterListener()
+	c.teardownDRReplication()
+	c.teardownKeyRotation()
+	c.stopMountPurge()
+	c.teardownActivityLog()
 
 	if err := c.teardownAudits(); err != nil {
 		result = multierror.Append(result, errwrap.Wrapf("error tearing down audits: {{err}}", err))
@@ -1681,6 +1944,8 @@ func (c *Core) preSeal() error {
 	if err := c.stopExpiration(); err != nil {
 		result = multierror.Append(result, errwrap.Wrapf("error stopping expiration: {{err}}", err))
 	}
+	c.teardownQuotas()
+	c.events.closeAll()
 	if err := c.teardownCredentials(c.activeContext); err != nil {
 		result = multierror.Append(result, errwrap.Wrapf("error tearing down credentials: {{err}}", err))
 	}
@@ -1759,6 +2024,14 @@ func (c *Core) runStandby(doneCh, stopCh, manualStepDownCh chan struct{}) {
 		default:
 		}
 
+		if c.performanceStandby {
+			c.stateLock.Lock()
+			if err := c.setupPerformanceStandby(); err != nil {
+				c.logger.Error("core: performance standby setup failed, forwarding all requests", "error", err)
+			}
+			c.stateLock.Unlock()
+		}
+
 		// Create a lock
 		uuid, err := uuid.GenerateUUID()
 		if err != nil {
@@ -1788,6 +2061,10 @@ func (c *Core) runStandby(doneCh, stopCh, manualStepDownCh chan struct{}) {
 		// before advertising;
 		c.stateLock.Lock()
 
+		// Stop serving requests as a standby before setting up active
+		// operation
+		c.teardownPerformanceStandby()
+
 		// We haven't run postUnseal yet so we have nothing meaningful to use here
 		ctx := context.Background()
 
@@ -2133,6 +2410,10 @@ func (c *Core) clearLeader(uuid string) error {
 
 // emitMetrics is used to periodically expose metrics while runnig
 func (c *Core) emitMetrics(stopCh chan struct{}) {
+	// The gauges which require scanning storage are emitted less often
+	gaugeTicker := time.NewTicker(gaugeMetricsInterval)
+	defer gaugeTicker.Stop()
+
 	for {
 		select {
 		case <-time.After(time.Second):
@@ -2141,12 +2422,36 @@ func (c *Core) emitMetrics(stopCh chan struct{}) {
 				c.expiration.emitMetrics()
 			}
 			c.metricsMutex.Unlock()
+		case <-gaugeTicker.C:
+			c.emitGaugeMetrics()
 		case <-stopCh:
 			return
 		}
 	}
 }
 
+// emitGaugeMetrics emits the number of tokens and of leases per mount
+func (c *Core) emitGaugeMetrics() {
+	c.metricsMutex.Lock()
+	defer c.metricsMutex.Unlock()
+
+	if c.tokenStore != nil {
+		count, err := c.tokenStore.countTokens(c.activeContext)
+		if err != nil {
+			c.logger.Error("core: failed to count tokens", "error", err)
+		} else {
+			metrics.SetGauge([]string{"token", "count"}, float32(count))
+		}
+	}
+
+	if c.expiration != nil {
+		for mount, count := range c.expiration.leaseCountsByMount() {
+			metrics.SetGaugeWithLabels([]string{"expire", "leases", "by_mount"}, float32(count),
+				[]metrics.Label{{Name: "mount", Value: mount}})
+		}
+	}
+}
+
 func (c *Core) ReplicationState() consts.ReplicationState {
 	return consts.ReplicationState(atomic.LoadUint32(c.replicationState))
 }
@@ -2177,6 +2482,12 @@ func lastRemoteWALImpl(c *Core) uint64 {
 	return 0
 }
 
+// IndexState returns the index state of the writes made to storage so far,
+// which the requests can require to read their writes
+func (c *Core) IndexState() string {
+	return c.indexTracker.State().String()
+}
+
 func (c *Core) BarrierEncryptorAccess() *BarrierEncryptorAccess {
 	return NewBarrierEncryptorAccess(c.barrier)
 }
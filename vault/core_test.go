@@ -3,6 +3,7 @@ package vault
 import (
 	"context"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -10,8 +11,10 @@ import (
 	"github.com/hashicorp/go-uuid"
 	"github.com/hashicorp/vault/audit"
 	"github.com/hashicorp/vault/helper/consts"
+	"github.com/hashicorp/vault/helper/entropy"
 	"github.com/hashicorp/vault/helper/logformat"
 	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
 	"github.com/hashicorp/vault/physical"
 	"github.com/hashicorp/vault/physical/inmem"
 	log "github.com/mgutz/logxi/v1"
@@ -41,6 +44,68 @@ func TestNewCore_badRedirectAddr(t *testing.T) {
 	}
 }
 
+// testEntropySource is an entropy source returning zeros, counting its calls
+type testEntropySource struct {
+	calls int
+}
+
+func (s *testEntropySource) GetRandom(bytes int) ([]byte, error) {
+	s.calls++
+	return make([]byte, bytes), nil
+}
+
+func TestNewCore_entropySource(t *testing.T) {
+	logger = logformat.NewVaultLogger(log.LevelTrace)
+
+	inm, err := inmem.NewInmem(nil, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	source := &testEntropySource{}
+	core, err := NewCore(&CoreConfig{
+		Physical:      inm,
+		DisableMlock:  true,
+		EntropySource: source,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The barrier keys mix in the entropy of the source
+	if _, err := core.barrier.GenerateKey(); err != nil {
+		t.Fatal(err)
+	}
+	if source.calls != 1 {
+		t.Fatalf("expected the source to be used, got %d calls", source.calls)
+	}
+
+	// So do the keys of the backends, through their system view
+	sysView := core.mountEntrySysView(&MountEntry{})
+	sourcer, ok := sysView.(entropy.Sourcer)
+	if !ok {
+		t.Fatalf("expected the system view to be an entropy source, got %T", sysView)
+	}
+	if _, err := sourcer.GetRandom(32); err != nil {
+		t.Fatal(err)
+	}
+	if source.calls != 2 {
+		t.Fatalf("expected the source to be used, got %d calls", source.calls)
+	}
+
+	// Without source the system view is not one
+	core, err = NewCore(&CoreConfig{
+		Physical:     inm,
+		DisableMlock: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := core.mountEntrySysView(&MountEntry{}).(entropy.Sourcer); ok {
+		t.Fatal("expected the system view not to be an entropy source")
+	}
+}
+
 func TestSealConfig_Invalid(t *testing.T) {
 	s := &SealConfig{
 		SecretShares:    2,
@@ -605,6 +670,61 @@ func TestCore_HandleRequest_PermissionAllowed(t *testing.T) {
 	}
 }
 
+func TestCore_HandleRequest_Cancel(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	c.logicalBackends["slow"] = func(ctx context.Context, conf *logical.BackendConfig) (logical.Backend, error) {
+		b := &framework.Backend{
+			BackendType: logical.TypeLogical,
+			Paths: []*framework.Path{
+				&framework.Path{
+					Pattern: "wait",
+					Callbacks: map[logical.Operation]framework.OperationFunc{
+						logical.ReadOperation: func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+							<-ctx.Done()
+							return nil, ctx.Err()
+						},
+					},
+				},
+			},
+		}
+		if err := b.Setup(ctx, conf); err != nil {
+			return nil, err
+		}
+		return b, nil
+	}
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "sys/mounts/slow")
+	req.Data["type"] = "slow"
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The request is cancelled when the client goes away
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	req = &logical.Request{
+		Operation:   logical.ReadOperation,
+		Path:        "slow/wait",
+		ClientToken: root,
+	}
+	req.SetContext(ctx)
+	if _, err := c.HandleRequest(req); err == nil || !strings.Contains(err.Error(), context.Canceled.Error()) {
+		t.Fatalf("expected the request to be cancelled, got %v", err)
+	}
+
+	// And once the maximum duration of the requests is over
+	c.defaultMaxRequestDuration = 100 * time.Millisecond
+	req = &logical.Request{
+		Operation:   logical.ReadOperation,
+		Path:        "slow/wait",
+		ClientToken: root,
+	}
+	if _, err := c.HandleRequest(req); err == nil || !strings.Contains(err.Error(), context.DeadlineExceeded.Error()) {
+		t.Fatalf("expected the request to time out, got %v", err)
+	}
+}
+
 func TestCore_HandleRequest_NoClientToken(t *testing.T) {
 	noop := &NoopBackend{
 		Response: &logical.Response{},
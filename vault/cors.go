@@ -20,7 +20,9 @@ var StdAllowedHeaders = []string{
 	"Content-Type",
 	"X-Requested-With",
 	"X-Vault-AWS-IAM-Server-ID",
+	"X-Vault-Cache-Bypass",
 	"X-Vault-MFA",
+	"X-Vault-Namespace",
 	"X-Vault-No-Request-Forwarding",
 	"X-Vault-Token",
 	"X-Vault-Wrap-Format",
@@ -99,8 +101,9 @@ func (c *CORSConfig) Enable(ctx context.Context, urls []string, headers []string
 	c.Lock()
 	c.AllowedOrigins = urls
 
-	// Start with the standard headers to Vault accepts.
-	c.AllowedHeaders = append(c.AllowedHeaders, StdAllowedHeaders...)
+	// Start with the standard headers to Vault accepts. The headers of a
+	// previous configuration are replaced.
+	c.AllowedHeaders = append([]string(nil), StdAllowedHeaders...)
 
 	// Allow the user to add additional headers to the list of
 	// headers allowed on cross-origin requests.
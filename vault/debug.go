@@ -0,0 +1,128 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"runtime/pprof"
+	"runtime/trace"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// defaultPprofDuration is the default duration of the CPU profiles and
+// execution traces of sys/debug/pprof
+const defaultPprofDuration = 30 * time.Second
+
+func debugPaths(b *SystemBackend) []*framework.Path {
+	return []*framework.Path{
+		&framework.Path{
+			Pattern: "debug/pprof/" + framework.GenericNameRegex("profile"),
+
+			Fields: map[string]*framework.FieldSchema{
+				"profile": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: strings.TrimSpace(sysHelp["debug-pprof-profile"][0]),
+				},
+				"seconds": &framework.FieldSchema{
+					Type:        framework.TypeDurationSecond,
+					Description: strings.TrimSpace(sysHelp["debug-pprof-seconds"][0]),
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation: b.handleDebugPprof,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["debug-pprof"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["debug-pprof"][1]),
+		},
+
+		&framework.Path{
+			Pattern: "debug/logs$",
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation: b.handleDebugLogs,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["debug-logs"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["debug-logs"][1]),
+		},
+	}
+}
+
+// handleDebugPprof returns the requested runtime profile of the process
+func (b *SystemBackend) handleDebugPprof(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("profile").(string)
+	duration := time.Duration(d.Get("seconds").(int)) * time.Second
+	if duration <= 0 {
+		duration = defaultPprofDuration
+	}
+
+	var buf bytes.Buffer
+	switch name {
+	case "profile":
+		if err := pprof.StartCPUProfile(&buf); err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("could not start the CPU profile: %v", err)), logical.ErrInvalidRequest
+		}
+		err := sleepContext(ctx, duration)
+		pprof.StopCPUProfile()
+		if err != nil {
+			return nil, err
+		}
+	case "trace":
+		if err := trace.Start(&buf); err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("could not start the execution trace: %v", err)), logical.ErrInvalidRequest
+		}
+		err := sleepContext(ctx, duration)
+		trace.Stop()
+		if err != nil {
+			return nil, err
+		}
+	default:
+		profile := pprof.Lookup(name)
+		if profile == nil {
+			return logical.ErrorResponse(fmt.Sprintf("unknown profile %q", name)), logical.ErrInvalidRequest
+		}
+		if err := profile.WriteTo(&buf, 0); err != nil {
+			return nil, err
+		}
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPContentType: "application/octet-stream",
+			logical.HTTPRawBody:     buf.Bytes(),
+			logical.HTTPStatusCode:  200,
+		},
+	}, nil
+}
+
+// handleDebugLogs returns the recent log lines of the server
+func (b *SystemBackend) handleDebugLogs(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	if b.Core.recentLogs == nil {
+		return logical.ErrorResponse("recent logs are not kept by this server"), logical.ErrInvalidRequest
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"lines": b.Core.recentLogs.Lines(),
+		},
+	}, nil
+}
+
+// sleepContext waits for the duration, or until the context is done
+func sleepContext(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,59 @@
+package vault
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/vault/helper/logbuffer"
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestSystemBackend_debugPprof(t *testing.T) {
+	_, b, _ := testCoreSystemBackend(t)
+
+	req := logical.TestRequest(t, logical.ReadOperation, "debug/pprof/heap")
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Data[logical.HTTPContentType] != "application/octet-stream" || len(resp.Data[logical.HTTPRawBody].([]byte)) == 0 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "debug/pprof/profile")
+	req.Data["seconds"] = 1
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(resp.Data[logical.HTTPRawBody].([]byte)) == 0 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "debug/pprof/foo")
+	if _, err := b.HandleRequest(context.Background(), req); err != logical.ErrInvalidRequest {
+		t.Fatalf("expected invalid request for an unknown profile, got %v", err)
+	}
+}
+
+func TestSystemBackend_debugLogs(t *testing.T) {
+	c, b, _ := testCoreSystemBackend(t)
+
+	req := logical.TestRequest(t, logical.ReadOperation, "debug/logs")
+	if _, err := b.HandleRequest(context.Background(), req); err != logical.ErrInvalidRequest {
+		t.Fatalf("expected invalid request without a log buffer, got %v", err)
+	}
+
+	c.recentLogs = logbuffer.New(10)
+	c.recentLogs.Write([]byte("foo\nbar\n"))
+
+	req = logical.TestRequest(t, logical.ReadOperation, "debug/logs")
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(resp.Data["lines"], []string{"foo", "bar"}) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+}
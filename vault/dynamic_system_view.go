@@ -6,8 +6,10 @@ import (
 	"time"
 
 	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/go-uuid"
 
 	"github.com/hashicorp/vault/helper/consts"
+	"github.com/hashicorp/vault/helper/entropy"
 	"github.com/hashicorp/vault/helper/pluginutil"
 	"github.com/hashicorp/vault/helper/wrapping"
 	"github.com/hashicorp/vault/logical"
@@ -42,8 +44,22 @@ func (d dynamicSystemView) SudoPrivilege(ctx context.Context, path string, token
 		return false
 	}
 
+	// The policies of the token are resolved in its namespace
+	tokenNS := d.core.namespaceByID(te.NamespaceID)
+	if tokenNS == nil {
+		return false
+	}
+	tokenCtx := contextWithNamespace(ctx, tokenNS)
+
+	entity, derivedPolicies, err := d.core.fetchEntityAndDerivedPolicies(tokenCtx, te.EntityID)
+	if err != nil {
+		return false
+	}
+
+	tokenPolicies := append(te.Policies, derivedPolicies...)
+
 	// Construct the corresponding ACL object
-	acl, err := d.core.policyStore.ACL(ctx, te.Policies...)
+	acl, err := d.core.policyStore.ACL(tokenCtx, entity, tokenPolicies...)
 	if err != nil {
 		d.core.logger.Error("failed to retrieve ACL for token's policies", "token_policies", te.Policies, "error", err)
 		return false
@@ -54,7 +70,7 @@ func (d dynamicSystemView) SudoPrivilege(ctx context.Context, path string, token
 	// have sudo
 	req := new(logical.Request)
 	req.Operation = logical.ReadOperation
-	req.Path = path
+	req.Path = namespaceRequestPath(namespaceFromContext(ctx), path)
 	authResults := acl.AllowOperation(req)
 	return authResults.RootPrivs
 }
@@ -114,7 +130,7 @@ func (d dynamicSystemView) ResponseWrapData(ctx context.Context, data map[string
 		resp.WrapInfo.Format = "jwt"
 	}
 
-	_, err := d.core.wrapInCubbyhole(ctx, req, resp, nil)
+	_, err := d.core.wrapInCubbyhole(ctx, req, resp, nil, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -123,7 +139,8 @@ func (d dynamicSystemView) ResponseWrapData(ctx context.Context, data map[string
 }
 
 // LookupPlugin looks for a plugin with the given name in the plugin catalog. It
-// returns a PluginRunner or an error if no plugin was found.
+// returns a PluginRunner or an error if no plugin was found. The plugin of a
+// plugin mount is looked up at the version the mount is pinned to, if any.
 func (d dynamicSystemView) LookupPlugin(ctx context.Context, name string) (*pluginutil.PluginRunner, error) {
 	if d.core == nil {
 		return nil, fmt.Errorf("system view core is nil")
@@ -131,11 +148,18 @@ func (d dynamicSystemView) LookupPlugin(ctx context.Context, name string) (*plug
 	if d.core.pluginCatalog == nil {
 		return nil, fmt.Errorf("system view core plugin catalog is nil")
 	}
-	r, err := d.core.pluginCatalog.Get(ctx, name)
+	var version string
+	if d.mountEntry != nil && d.mountEntry.Config.PluginName == name {
+		version = d.mountEntry.Config.PluginVersion
+	}
+	r, err := d.core.pluginCatalog.Get(ctx, name, version)
 	if err != nil {
 		return nil, err
 	}
 	if r == nil {
+		if version != "" {
+			return nil, errwrap.Wrapf(fmt.Sprintf("{{err}}: %s version %s", name, version), ErrPluginNotFound)
+		}
 		return nil, errwrap.Wrapf(fmt.Sprintf("{{err}}: %s", name), ErrPluginNotFound)
 	}
 
@@ -146,3 +170,133 @@ func (d dynamicSystemView) LookupPlugin(ctx context.Context, name string) (*plug
 func (d dynamicSystemView) MlockEnabled() bool {
 	return d.core.enableMlock
 }
+
+// EntityInfo returns the entity with the given ID from the identity store of
+// the namespace of the mount.
+func (d dynamicSystemView) EntityInfo(entityID string) (*logical.Entity, error) {
+	if entityID == "" {
+		return nil, nil
+	}
+	if d.core == nil {
+		return nil, fmt.Errorf("identity store is not available")
+	}
+
+	ns := rootNamespace
+	if d.mountEntry != nil {
+		ns = d.core.namespaceByID(d.mountEntry.NamespaceID)
+	}
+	var identityStore *IdentityStore
+	if ns != nil {
+		identityStore = d.core.identityStoreForNamespace(ns)
+	}
+	if identityStore == nil {
+		return nil, fmt.Errorf("identity store is not available")
+	}
+
+	entity, err := identityStore.MemDBEntityByID(entityID, false)
+	if err != nil {
+		return nil, err
+	}
+	if entity == nil {
+		return nil, nil
+	}
+
+	result := &logical.Entity{
+		ID:       entity.ID,
+		Name:     entity.Name,
+		Metadata: make(map[string]string, len(entity.Metadata)),
+	}
+	for k, v := range entity.Metadata {
+		result.Metadata[k] = v
+	}
+	for _, alias := range entity.Aliases {
+		result.Aliases = append(result.Aliases, &logical.Alias{
+			MountType:     alias.MountType,
+			MountAccessor: alias.MountAccessor,
+			Name:          alias.Name,
+		})
+	}
+
+	return result, nil
+}
+
+// GeneratePasswordFromPolicy generates a password from the password policy
+// with the given name in sys/policies/password.
+func (d dynamicSystemView) GeneratePasswordFromPolicy(ctx context.Context, policyName string) (string, error) {
+	if d.core == nil || d.core.systemBarrierView == nil {
+		return "", fmt.Errorf("password policies are not available")
+	}
+
+	return d.core.generatePasswordFromPolicy(ctx, policyName)
+}
+
+// SendEvent publishes an event about the path, relative to the mount of the
+// system view, to the subscribers of the namespace of the mount.
+func (d dynamicSystemView) SendEvent(ctx context.Context, eventType, path string, metadata map[string]string) error {
+	if d.core == nil || d.mountEntry == nil {
+		return fmt.Errorf("events are not available")
+	}
+
+	routePath := d.mountEntry.Path + path
+	if d.mountEntry.Table == credentialTableType {
+		routePath = credentialRoutePrefix + routePath
+	}
+	return d.core.events.publish(eventType, routePath, metadata)
+}
+
+// AuditInternalRequest logs an operation the backend of the mount performed
+// on its own to the audit devices. The request has no client token, and its
+// path is relative to the mount of the system view.
+func (d dynamicSystemView) AuditInternalRequest(ctx context.Context, req *logical.Request, resp *logical.Response) error {
+	if d.core == nil || d.mountEntry == nil || d.core.auditBroker == nil {
+		return fmt.Errorf("audit is not available")
+	}
+
+	ns := d.core.namespaceByID(d.mountEntry.NamespaceID)
+	if ns == nil {
+		return fmt.Errorf("namespace of the mount does not exist")
+	}
+	ctx = contextWithNamespace(ctx, ns)
+
+	routePath := d.mountEntry.Path + req.Path
+	if d.mountEntry.Table == credentialTableType {
+		routePath = credentialRoutePrefix + routePath
+	}
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		return err
+	}
+	auditReq := &logical.Request{
+		ID:            id,
+		Operation:     req.Operation,
+		Path:          routePath,
+		Data:          req.Data,
+		MountPoint:    d.mountEntry.Path,
+		MountType:     d.mountEntry.Type,
+		MountAccessor: d.mountEntry.Accessor,
+	}
+	auth := &logical.Auth{
+		DisplayName: "internal",
+	}
+
+	if err := d.core.auditBroker.LogRequest(ctx, auth, auditReq, d.core.auditedHeaders, nil); err != nil {
+		return errwrap.Wrapf("failed to audit the internal request: {{err}}", err)
+	}
+	if err := d.core.auditBroker.LogResponse(ctx, auth, auditReq, resp, d.core.auditedHeaders, nil); err != nil {
+		return errwrap.Wrapf("failed to audit the internal response: {{err}}", err)
+	}
+	return nil
+}
+
+// entropySystemView is the system view of the mounts of a core configured
+// with an external source of entropy, which the backends use to augment the
+// random data of the keys they generate
+type entropySystemView struct {
+	dynamicSystemView
+}
+
+var _ entropy.Sourcer = entropySystemView{}
+
+func (e entropySystemView) GetRandom(bytes int) ([]byte, error) {
+	return e.core.entropySource.GetRandom(bytes)
+}
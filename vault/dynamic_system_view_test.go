@@ -0,0 +1,58 @@
+package vault
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/vault/audit"
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestDynamicSystemView_AuditInternalRequest(t *testing.T) {
+	c, _, _ := TestCoreUnsealed(t)
+	noop := &NoopAudit{}
+	c.auditBackends["noop"] = func(ctx context.Context, config *audit.BackendConfig) (audit.Backend, error) {
+		noop.Config = config
+		return noop, nil
+	}
+	if err := c.enableAudit(context.Background(), &MountEntry{
+		Table: auditTableType,
+		Path:  "noop",
+		Type:  "noop",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	me := c.router.MatchingMountEntry("secret/")
+	if me == nil {
+		t.Fatal("missing mount entry")
+	}
+	sysView := c.mountEntrySysView(me)
+	if err := sysView.AuditInternalRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "keys/foo/rotate",
+	}, &logical.Response{
+		Data: map[string]interface{}{
+			"latest_version": 2,
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(noop.Req) != 1 || len(noop.Resp) != 1 {
+		t.Fatalf("expected the request and the response to be audited: %d, %d", len(noop.Req), len(noop.Resp))
+	}
+	req := noop.Req[0]
+	if req.Path != "secret/keys/foo/rotate" || req.Operation != logical.UpdateOperation || req.ID == "" || req.ClientToken != "" {
+		t.Fatalf("bad: %#v", req)
+	}
+	if req.MountType != me.Type || req.MountAccessor != me.Accessor {
+		t.Fatalf("bad: %#v", req)
+	}
+	if noop.ReqAuth[0].DisplayName != "internal" {
+		t.Fatalf("bad: %#v", noop.ReqAuth[0])
+	}
+	if noop.Resp[0].Data["latest_version"] != 2 {
+		t.Fatalf("bad: %#v", noop.Resp[0])
+	}
+}
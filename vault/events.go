@@ -0,0 +1,283 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/armon/go-metrics"
+	uuid "github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+	log "github.com/mgutz/logxi/v1"
+)
+
+const (
+	// eventSubscriptionBufferSize is the number of events queued for a
+	// subscriber. The events published while the queue of a subscriber is
+	// full are dropped for that subscriber.
+	eventSubscriptionBufferSize = 256
+
+	// eventSubscriptionClaimTimeout is the time after which a subscription
+	// that was not claimed by a connection streaming its events is closed
+	eventSubscriptionClaimTimeout = 30 * time.Second
+)
+
+// Event is a notification of a change in Vault, e.g. a secret being written,
+// published by a backend or by Vault itself
+type Event struct {
+	ID   string `json:"id"`
+	Type string `json:"event_type"`
+
+	// Namespace is the path of the namespace of the event, empty for the
+	// root namespace, and Path the full path the event is about
+	Namespace string `json:"namespace"`
+	Path      string `json:"path"`
+
+	Timestamp time.Time         `json:"timestamp"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+}
+
+// EventSubscription receives the events of the namespace of the subscription
+// and of its descendants matching the event type and path patterns of the
+// subscription. The patterns may start or end with a '*' wildcard.
+type EventSubscription struct {
+	ID string
+
+	namespace  *Namespace
+	eventTypes []string
+	path       string
+
+	bus     *eventBus
+	ch      chan *Event
+	claimed bool
+	timer   *time.Timer
+}
+
+// Events returns the channel of the events of the subscription, which is
+// closed when the subscription is closed
+func (s *EventSubscription) Events() <-chan *Event {
+	return s.ch
+}
+
+// Close ends the subscription
+func (s *EventSubscription) Close() {
+	s.bus.unsubscribe(s.ID)
+}
+
+// matches checks whether the event is delivered to the subscription. The path
+// pattern applies to the path of the event relative to the namespace of the
+// subscription.
+func (s *EventSubscription) matches(event *Event) bool {
+	if !strings.HasPrefix(event.Namespace, s.namespace.Path) {
+		return false
+	}
+	if len(s.eventTypes) > 0 && !globbedStringsContain(s.eventTypes, event.Type) {
+		return false
+	}
+	if s.path != "" && !strutil.GlobbedStringsMatch(s.path, strings.TrimPrefix(event.Path, s.namespace.Path)) {
+		return false
+	}
+	return true
+}
+
+func globbedStringsContain(patterns []string, val string) bool {
+	for _, pattern := range patterns {
+		if strutil.GlobbedStringsMatch(pattern, val) {
+			return true
+		}
+	}
+	return false
+}
+
+// eventBus delivers the published events to the subscriptions. Publishing
+// never blocks: slow subscribers miss the events that do not fit in their
+// queue.
+type eventBus struct {
+	logger log.Logger
+
+	// resolveNamespace returns the namespace of a router path, and the path
+	// relative to it
+	resolveNamespace func(string) (*Namespace, string)
+
+	lock          sync.RWMutex
+	subscriptions map[string]*EventSubscription
+}
+
+func newEventBus(logger log.Logger, resolveNamespace func(string) (*Namespace, string)) *eventBus {
+	return &eventBus{
+		logger:           logger,
+		resolveNamespace: resolveNamespace,
+		subscriptions:    make(map[string]*EventSubscription),
+	}
+}
+
+// subscribe registers a subscription to the events of the namespace. The
+// subscription is closed unless it is claimed before the claim timeout.
+func (b *eventBus) subscribe(ns *Namespace, eventTypes []string, path string) (*EventSubscription, error) {
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+
+	sub := &EventSubscription{
+		ID:         id,
+		namespace:  ns,
+		eventTypes: eventTypes,
+		path:       path,
+		bus:        b,
+		ch:         make(chan *Event, eventSubscriptionBufferSize),
+	}
+
+	b.lock.Lock()
+	b.subscriptions[id] = sub
+	sub.timer = time.AfterFunc(eventSubscriptionClaimTimeout, func() {
+		b.lock.Lock()
+		claimed := sub.claimed
+		b.lock.Unlock()
+		if !claimed {
+			b.unsubscribe(id)
+		}
+	})
+	b.lock.Unlock()
+
+	return sub, nil
+}
+
+// claim returns the unclaimed subscription with the given ID, or nil if there
+// is none. A subscription can only be claimed once.
+func (b *eventBus) claim(id string) *EventSubscription {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	sub, ok := b.subscriptions[id]
+	if !ok || sub.claimed {
+		return nil
+	}
+	sub.claimed = true
+	sub.timer.Stop()
+	return sub
+}
+
+func (b *eventBus) unsubscribe(id string) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if sub, ok := b.subscriptions[id]; ok {
+		sub.timer.Stop()
+		close(sub.ch)
+		delete(b.subscriptions, id)
+	}
+}
+
+// closeAll ends all the subscriptions
+func (b *eventBus) closeAll() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	for id, sub := range b.subscriptions {
+		sub.timer.Stop()
+		close(sub.ch)
+		delete(b.subscriptions, id)
+	}
+}
+
+// publish delivers an event of the given type about the router path to the
+// matching subscriptions
+func (b *eventBus) publish(eventType, routePath string, metadata map[string]string) error {
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		return err
+	}
+
+	ns, path := b.resolveNamespace(routePath)
+	event := &Event{
+		ID:        id,
+		Type:      eventType,
+		Namespace: ns.Path,
+		Path:      ns.Path + path,
+		Timestamp: time.Now().UTC(),
+		Metadata:  metadata,
+	}
+	metrics.IncrCounter([]string{"core", "events", "published"}, 1)
+
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+
+	for _, sub := range b.subscriptions {
+		if !sub.matches(event) {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+			metrics.IncrCounter([]string{"core", "events", "dropped"}, 1)
+			if b.logger.IsTrace() {
+				b.logger.Trace("core: dropped event of slow subscriber", "subscription_id", sub.ID, "event_type", eventType)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ClaimEventSubscription returns the subscription to the events with the given
+// ID, as returned by sys/events/subscribe, or nil if there is no such
+// subscription or it was already claimed. The caller streams the events of
+// the subscription and closes it when done.
+func (c *Core) ClaimEventSubscription(id string) *EventSubscription {
+	return c.events.claim(id)
+}
+
+func eventsPaths(b *SystemBackend) []*framework.Path {
+	return []*framework.Path{
+		&framework.Path{
+			Pattern: "events/subscribe$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"event_type": &framework.FieldSchema{
+					Type:        framework.TypeCommaStringSlice,
+					Description: strings.TrimSpace(sysHelp["events-event-type"][0]),
+				},
+				"path": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: strings.TrimSpace(sysHelp["events-path"][0]),
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation: b.handleEventsSubscribe,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["events-subscribe"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["events-subscribe"][1]),
+		},
+	}
+}
+
+// handleEventsSubscribe registers a subscription to the events of the
+// namespace of the request, which the HTTP layer claims to stream its events
+// over a websocket
+func (b *SystemBackend) handleEventsSubscribe(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	eventTypes := d.Get("event_type").([]string)
+	path := d.Get("path").(string)
+	for _, pattern := range append([]string{path}, eventTypes...) {
+		if strings.Contains(strings.Trim(pattern, "*"), "*") {
+			return logical.ErrorResponse(fmt.Sprintf("invalid pattern %q: wildcards are only supported at the start or end of patterns", pattern)), logical.ErrInvalidRequest
+		}
+	}
+
+	sub, err := b.Core.events.subscribe(namespaceFromContext(ctx), eventTypes, path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"subscription_id": sub.ID,
+		},
+	}, nil
+}
@@ -0,0 +1,212 @@
+package vault
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/helper/logformat"
+	"github.com/hashicorp/vault/logical"
+	log "github.com/mgutz/logxi/v1"
+)
+
+// testNextEvent returns the next event of the subscription, failing the test
+// if there is none
+func testNextEvent(t *testing.T, sub *EventSubscription) *Event {
+	t.Helper()
+	select {
+	case event, ok := <-sub.Events():
+		if !ok {
+			t.Fatal("subscription closed")
+		}
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for an event")
+	}
+	return nil
+}
+
+// testNoEvent fails the test if the subscription has a pending event
+func testNoEvent(t *testing.T, sub *EventSubscription) {
+	t.Helper()
+	select {
+	case event := <-sub.Events():
+		t.Fatalf("unexpected event: %#v", event)
+	default:
+	}
+}
+
+func TestEventBus(t *testing.T) {
+	teamA := &Namespace{ID: "team-a-id", Path: "team-a/"}
+	bus := newEventBus(logformat.NewVaultLogger(log.LevelTrace), func(path string) (*Namespace, string) {
+		if strings.HasPrefix(path, teamA.Path) {
+			return teamA, strings.TrimPrefix(path, teamA.Path)
+		}
+		return rootNamespace, path
+	})
+
+	all, err := bus.subscribe(rootNamespace, nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	kv, err := bus.subscribe(rootNamespace, []string{"kv/*"}, "secret/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ns, err := bus.subscribe(teamA, []string{logical.EventTypeKVWrite}, "kv/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := bus.publish(logical.EventTypeKVWrite, "secret/foo", map[string]string{"version": "1"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, sub := range []*EventSubscription{all, kv} {
+		event := testNextEvent(t, sub)
+		if event.Type != logical.EventTypeKVWrite || event.Path != "secret/foo" || event.Namespace != "" || event.Metadata["version"] != "1" {
+			t.Fatalf("bad: %#v", event)
+		}
+	}
+	testNoEvent(t, ns)
+
+	// The paths of the events of child namespaces are full paths, and the
+	// path patterns are relative to the namespace of the subscription
+	if err := bus.publish(logical.EventTypeKVWrite, "team-a/kv/foo", nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, sub := range []*EventSubscription{all, ns} {
+		event := testNextEvent(t, sub)
+		if event.Path != "team-a/kv/foo" || event.Namespace != "team-a/" {
+			t.Fatalf("bad: %#v", event)
+		}
+	}
+	testNoEvent(t, kv)
+
+	if err := bus.publish(logical.EventTypePKICertIssued, "pki/issue/web", nil); err != nil {
+		t.Fatal(err)
+	}
+	if event := testNextEvent(t, all); event.Type != logical.EventTypePKICertIssued {
+		t.Fatalf("bad: %#v", event)
+	}
+	testNoEvent(t, kv)
+	testNoEvent(t, ns)
+
+	// The events that do not fit in the queue of a subscriber are dropped
+	for i := 0; i < eventSubscriptionBufferSize+10; i++ {
+		if err := bus.publish(logical.EventTypeKVDelete, "secret/foo", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(kv.Events()) != eventSubscriptionBufferSize {
+		t.Fatalf("bad: %d", len(kv.Events()))
+	}
+
+	kv.Close()
+	bus.closeAll()
+	for _, sub := range []*EventSubscription{all, kv, ns} {
+		for range sub.Events() {
+		}
+	}
+	if len(bus.subscriptions) != 0 {
+		t.Fatalf("bad: %#v", bus.subscriptions)
+	}
+}
+
+func TestEventBus_claim(t *testing.T) {
+	bus := newEventBus(logformat.NewVaultLogger(log.LevelTrace), func(path string) (*Namespace, string) {
+		return rootNamespace, path
+	})
+
+	sub, err := bus.subscribe(rootNamespace, nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bus.claim("unknown") != nil {
+		t.Fatal("expected no subscription")
+	}
+	if bus.claim(sub.ID) != sub {
+		t.Fatal("expected the subscription")
+	}
+	if bus.claim(sub.ID) != nil {
+		t.Fatal("subscriptions can only be claimed once")
+	}
+
+	sub.Close()
+	if _, ok := <-sub.Events(); ok {
+		t.Fatal("expected the subscription to be closed")
+	}
+}
+
+func TestCore_Events(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	testNamespaceRequest(t, c, root, logical.UpdateOperation, "sys/namespaces/team-a", nil)
+	testNamespaceRequest(t, c, root, logical.UpdateOperation, "team-a/sys/mounts/kv", map[string]interface{}{
+		"type": "kv",
+	})
+
+	resp := testNamespaceRequest(t, c, root, logical.ReadOperation, "sys/events/subscribe", nil)
+	all := c.ClaimEventSubscription(resp.Data["subscription_id"].(string))
+	if all == nil {
+		t.Fatal("expected the subscription")
+	}
+	defer all.Close()
+
+	resp = testNamespaceRequest(t, c, root, logical.ReadOperation, "team-a/sys/events/subscribe", map[string]interface{}{
+		"event_type": "kv/*",
+	})
+	ns := c.ClaimEventSubscription(resp.Data["subscription_id"].(string))
+	if ns == nil {
+		t.Fatal("expected the subscription")
+	}
+	defer ns.Close()
+
+	testNamespaceRequest(t, c, root, logical.UpdateOperation, "secret/foo", map[string]interface{}{
+		"value": "bar",
+		"ttl":   "1h",
+	})
+	event := testNextEvent(t, all)
+	if event.Type != logical.EventTypeKVWrite || event.Path != "secret/foo" || event.Namespace != "" {
+		t.Fatalf("bad: %#v", event)
+	}
+	testNoEvent(t, ns)
+
+	testNamespaceRequest(t, c, root, logical.UpdateOperation, "team-a/kv/foo", map[string]interface{}{
+		"value": "bar",
+	})
+	for _, sub := range []*EventSubscription{all, ns} {
+		event := testNextEvent(t, sub)
+		if event.Type != logical.EventTypeKVWrite || event.Path != "team-a/kv/foo" || event.Namespace != "team-a/" {
+			t.Fatalf("bad: %#v", event)
+		}
+	}
+
+	// Revoking a lease publishes an event too
+	resp = testNamespaceRequest(t, c, root, logical.ReadOperation, "secret/foo", nil)
+	if resp.Secret == nil || resp.Secret.LeaseID == "" {
+		t.Fatalf("expected a lease: %#v", resp)
+	}
+	testNamespaceRequest(t, c, root, logical.UpdateOperation, "sys/leases/revoke", map[string]interface{}{
+		"lease_id": resp.Secret.LeaseID,
+	})
+	event = testNextEvent(t, all)
+	if event.Type != logical.EventTypeLeaseRevoked || event.Metadata["lease_id"] != resp.Secret.LeaseID {
+		t.Fatalf("bad: %#v", event)
+	}
+
+	// The patterns only support wildcards at their start or end
+	req := logical.TestRequest(t, logical.ReadOperation, "sys/events/subscribe")
+	req.ClientToken = root
+	req.Data["path"] = "secret/*/foo"
+	resp, err := c.HandleRequest(req)
+	if err == nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected invalid request, got %v %#v", err, resp)
+	}
+
+	// Sealing ends the subscriptions
+	if err := c.Seal(root); err != nil {
+		t.Fatal(err)
+	}
+	for range all.Events() {
+	}
+}
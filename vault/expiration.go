@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"path"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -56,11 +57,21 @@ type ExpirationManager struct {
 	idView     *BarrierView
 	tokenView  *BarrierView
 	tokenStore *TokenStore
+	quotas     *quotaManager
+	events     *eventBus
 	logger     log.Logger
 
 	pending     map[string]*time.Timer
 	pendingLock sync.RWMutex
 
+	// irrevocable holds the leases whose revocation permanently failed,
+	// guarded by the pending lock
+	irrevocable map[string]*leaseEntry
+
+	// leaseCounts is the number of pending leases by mount, guarded by the
+	// pending lock
+	leaseCounts map[string]int
+
 	tidyLock int32
 
 	restoreMode        int32
@@ -70,6 +81,9 @@ type ExpirationManager struct {
 	restoreLoaded      sync.Map
 	quitCh             chan struct{}
 
+	// expireCh carries the expired leases to the revocation workers
+	expireCh chan *expiredLease
+
 	coreStateLock *sync.RWMutex
 	quitContext   context.Context
 }
@@ -78,18 +92,23 @@ type ExpirationManager struct {
 // using a given view, and uses the provided router for revocation.
 func NewExpirationManager(c *Core, view *BarrierView) *ExpirationManager {
 	exp := &ExpirationManager{
-		router:     c.router,
-		idView:     view.SubView(leaseViewPrefix),
-		tokenView:  view.SubView(tokenViewPrefix),
-		tokenStore: c.tokenStore,
-		logger:     c.logger,
-		pending:    make(map[string]*time.Timer),
+		router:      c.router,
+		idView:      view.SubView(leaseViewPrefix),
+		tokenView:   view.SubView(tokenViewPrefix),
+		tokenStore:  c.tokenStore,
+		quotas:      c.quotas,
+		events:      c.events,
+		logger:      c.logger,
+		pending:     make(map[string]*time.Timer),
+		irrevocable: make(map[string]*leaseEntry),
+		leaseCounts: make(map[string]int),
 
 		// new instances of the expiration manager will go immediately into
 		// restore mode
 		restoreMode:  1,
 		restoreLocks: locksutil.CreateLocks(),
 		quitCh:       make(chan struct{}),
+		expireCh:     make(chan *expiredLease),
 
 		coreStateLock: &c.stateLock,
 		quitContext:   c.activeContext,
@@ -99,9 +118,22 @@ func NewExpirationManager(c *Core, view *BarrierView) *ExpirationManager {
 		exp.logger = log.New("expiration_manager")
 	}
 
+	// Expired leases are revoked by a bounded number of workers, so that
+	// many leases expiring together do not revoke all at once
+	for i := 0; i < consts.ExpirationRevokeWorkerCount; i++ {
+		go exp.revokeWorker()
+	}
+
 	return exp
 }
 
+// expiredLease is an expired lease to revoke, along with the number of
+// revocations attempted so far
+type expiredLease struct {
+	leaseID string
+	attempt uint
+}
+
 // setupExpiration is invoked after we've loaded the mount table to
 // initialize the expiration manager
 func (c *Core) setupExpiration() error {
@@ -214,15 +246,22 @@ func (m *ExpirationManager) Tidy() error {
 
 		isValid, ok = tokenCache[le.ClientToken]
 		if !ok {
-			saltedID, err := m.tokenStore.SaltID(le.ClientToken)
-			if err != nil {
-				tidyErrors = multierror.Append(tidyErrors, fmt.Errorf("failed to lookup salt id: %v", err))
-				return
+			// Batch tokens are not stored, their entry is in the token
+			var te *TokenEntry
+			if isBatchTokenID(le.ClientToken) {
+				te, err = m.tokenStore.Lookup(m.quitContext, le.ClientToken)
+			} else {
+				var saltedID string
+				saltedID, err = m.tokenStore.SaltID(le.ClientToken)
+				if err != nil {
+					tidyErrors = multierror.Append(tidyErrors, fmt.Errorf("failed to lookup salt id: %v", err))
+					return
+				}
+				lock := locksutil.LockForKey(m.tokenStore.tokenLocks, le.ClientToken)
+				lock.RLock()
+				te, err = m.tokenStore.lookupSalted(m.quitContext, saltedID, true)
+				lock.RUnlock()
 			}
-			lock := locksutil.LockForKey(m.tokenStore.tokenLocks, le.ClientToken)
-			lock.RLock()
-			te, err := m.tokenStore.lookupSalted(m.quitContext, saltedID, true)
-			lock.RUnlock()
 
 			if err != nil {
 				tidyErrors = multierror.Append(tidyErrors, fmt.Errorf("failed to lookup token: %v", err))
@@ -447,6 +486,8 @@ func (m *ExpirationManager) Stop() error {
 		timer.Stop()
 	}
 	m.pending = make(map[string]*time.Timer)
+	m.irrevocable = make(map[string]*leaseEntry)
+	m.leaseCounts = make(map[string]int)
 	m.pendingLock.Unlock()
 
 	if m.inRestoreMode() {
@@ -514,8 +555,16 @@ func (m *ExpirationManager) revokeCommon(leaseID string, force, skipToken bool)
 	if timer, ok := m.pending[leaseID]; ok {
 		timer.Stop()
 		delete(m.pending, leaseID)
+		m.pendingChanged(leaseID, -1)
 	}
+	delete(m.irrevocable, leaseID)
 	m.pendingLock.Unlock()
+
+	if err := m.events.publish(logical.EventTypeLeaseRevoked, le.Path, map[string]string{
+		"lease_id": leaseID,
+	}); err != nil {
+		m.logger.Warn("expiration: failed to publish lease revocation event", "lease_id", leaseID, "error", err)
+	}
 	return nil
 }
 
@@ -970,6 +1019,12 @@ func (m *ExpirationManager) updatePending(le *leaseEntry, leaseTotal time.Durati
 	m.pendingLock.Lock()
 	defer m.pendingLock.Unlock()
 
+	// Irrevocable leases are not revoked again on expiry
+	if le.RevokeErr != "" {
+		m.irrevocable[le.LeaseID] = le.irrevocableEntry()
+		return
+	}
+
 	// Check for an existing timer
 	timer, ok := m.pending[le.LeaseID]
 
@@ -980,6 +1035,7 @@ func (m *ExpirationManager) updatePending(le *leaseEntry, leaseTotal time.Durati
 		if ok {
 			timer.Stop()
 			delete(m.pending, le.LeaseID)
+			m.pendingChanged(le.LeaseID, -1)
 		}
 		return
 	}
@@ -990,6 +1046,7 @@ func (m *ExpirationManager) updatePending(le *leaseEntry, leaseTotal time.Durati
 			m.expireID(le.LeaseID)
 		})
 		m.pending[le.LeaseID] = timer
+		m.pendingChanged(le.LeaseID, 1)
 		return
 	}
 
@@ -997,42 +1054,203 @@ func (m *ExpirationManager) updatePending(le *leaseEntry, leaseTotal time.Durati
 	timer.Reset(leaseTotal)
 }
 
+// pendingChanged updates the lease count quotas and the lease counts of the
+// mounts when a lease is added to or removed from the pending leases. The
+// pending lock must be held.
+func (m *ExpirationManager) pendingChanged(leaseID string, delta int) {
+	m.quotas.leaseChanged(leaseID, delta)
+	m.leaseCounts[m.router.MatchingMount(leaseID)] += delta
+}
+
+// leaseCountsByMount returns the number of pending leases by mount. The
+// mounts whose leases were all removed are returned once with no leases, so
+// that their gauges are reset.
+func (m *ExpirationManager) leaseCountsByMount() map[string]int {
+	m.pendingLock.Lock()
+	defer m.pendingLock.Unlock()
+
+	counts := make(map[string]int, len(m.leaseCounts))
+	for mount, count := range m.leaseCounts {
+		counts[mount] = count
+		if count == 0 {
+			delete(m.leaseCounts, mount)
+		}
+	}
+	return counts
+}
+
 // expireID is invoked when a given ID is expired
 func (m *ExpirationManager) expireID(leaseID string) {
 	// Clear from the pending expiration
 	m.pendingLock.Lock()
-	delete(m.pending, leaseID)
+	if _, ok := m.pending[leaseID]; ok {
+		delete(m.pending, leaseID)
+		m.pendingChanged(leaseID, -1)
+	}
 	m.pendingLock.Unlock()
 
-	for attempt := uint(0); attempt < maxRevokeAttempts; attempt++ {
+	m.queueRevocation(&expiredLease{leaseID: leaseID})
+}
+
+// queueRevocation hands the expired lease to the revocation workers,
+// blocking until one is available
+func (m *ExpirationManager) queueRevocation(lease *expiredLease) {
+	select {
+	case m.expireCh <- lease:
+	case <-m.quitCh:
+		m.logger.Error("expiration: shutting down, not attempting further revocation of lease", "lease_id", lease.leaseID)
+	}
+}
+
+// revokeWorker revokes the expired leases until the expiration manager is
+// stopped
+func (m *ExpirationManager) revokeWorker() {
+	for {
 		select {
+		case lease := <-m.expireCh:
+			m.revokeExpired(lease)
 		case <-m.quitCh:
-			m.logger.Error("expiration: shutting down, not attempting further revocation of lease", "lease_id", leaseID)
 			return
-		default:
 		}
+	}
+}
 
-		m.coreStateLock.RLock()
-		if m.quitContext.Err() == context.Canceled {
-			m.logger.Error("expiration: core context canceled, not attempting further revocation of lease", "lease_id", leaseID)
-			m.coreStateLock.RUnlock()
-			return
+// revokeExpired attempts to revoke an expired lease. Failed attempts are
+// retried with an exponential backoff, without holding up the worker in the
+// meantime.
+func (m *ExpirationManager) revokeExpired(lease *expiredLease) {
+	m.coreStateLock.RLock()
+	if m.quitContext.Err() == context.Canceled {
+		m.logger.Error("expiration: core context canceled, not attempting further revocation of lease", "lease_id", lease.leaseID)
+		m.coreStateLock.RUnlock()
+		return
+	}
+
+	err := m.Revoke(lease.leaseID)
+	m.coreStateLock.RUnlock()
+	if err == nil {
+		if m.logger.IsInfo() {
+			m.logger.Info("expiration: revoked lease", "lease_id", lease.leaseID)
+		}
+		return
+	}
+
+	m.logger.Error("expiration: failed to revoke lease", "lease_id", lease.leaseID, "error", err)
+	if lease.attempt+1 >= maxRevokeAttempts {
+		m.logger.Error("expiration: maximum revoke attempts reached, marking lease irrevocable", "lease_id", lease.leaseID)
+		if err := m.markIrrevocable(lease.leaseID, err); err != nil {
+			m.logger.Error("expiration: failed to mark lease irrevocable", "lease_id", lease.leaseID, "error", err)
+		}
+		return
+	}
+
+	retry := &expiredLease{leaseID: lease.leaseID, attempt: lease.attempt + 1}
+	time.AfterFunc((1<<lease.attempt)*revokeRetryBase, func() {
+		m.queueRevocation(retry)
+	})
+}
+
+// markIrrevocable records the error of the last revocation attempt of the
+// lease, so that it is no longer revoked on expiry, including after a restore
+func (m *ExpirationManager) markIrrevocable(leaseID string, revokeErr error) error {
+	m.coreStateLock.RLock()
+	defer m.coreStateLock.RUnlock()
+	if m.quitContext.Err() == context.Canceled {
+		return m.quitContext.Err()
+	}
+
+	le, err := m.loadEntry(leaseID)
+	if err != nil {
+		return err
+	}
+	if le == nil {
+		// Revoked in the meantime
+		return nil
+	}
+
+	le.RevokeErr = revokeErr.Error()
+	if err := m.persistEntry(le); err != nil {
+		return err
+	}
+
+	m.pendingLock.Lock()
+	if timer, ok := m.pending[leaseID]; ok {
+		timer.Stop()
+		delete(m.pending, leaseID)
+		m.pendingChanged(leaseID, -1)
+	}
+	m.irrevocable[leaseID] = le.irrevocableEntry()
+	m.pendingLock.Unlock()
+	return nil
+}
+
+// IrrevocableLeases returns the irrevocable leases under the prefix, sorted
+// by lease ID. Only the lease ID, path, expiration time and revocation error
+// of the entries are set.
+func (m *ExpirationManager) IrrevocableLeases(prefix string) []*leaseEntry {
+	m.pendingLock.RLock()
+	leases := make([]*leaseEntry, 0, len(m.irrevocable))
+	for leaseID, le := range m.irrevocable {
+		if strings.HasPrefix(leaseID, prefix) {
+			leases = append(leases, le)
 		}
+	}
+	m.pendingLock.RUnlock()
 
-		err := m.Revoke(leaseID)
-		if err == nil {
-			if m.logger.IsInfo() {
-				m.logger.Info("expiration: revoked lease", "lease_id", leaseID)
+	sort.Slice(leases, func(i, j int) bool {
+		return leases[i].LeaseID < leases[j].LeaseID
+	})
+	return leases
+}
+
+// LeaseIDs returns the IDs of the leases under the prefix which are pending
+// expiration or irrevocable
+func (m *ExpirationManager) LeaseIDs(prefix string, irrevocable bool) []string {
+	m.pendingLock.RLock()
+	defer m.pendingLock.RUnlock()
+
+	var leaseIDs []string
+	for leaseID := range m.irrevocable {
+		if strings.HasPrefix(leaseID, prefix) {
+			leaseIDs = append(leaseIDs, leaseID)
+		}
+	}
+	if !irrevocable {
+		for leaseID := range m.pending {
+			if strings.HasPrefix(leaseID, prefix) {
+				leaseIDs = append(leaseIDs, leaseID)
 			}
-			m.coreStateLock.RUnlock()
-			return
 		}
+	}
+	return leaseIDs
+}
 
-		m.coreStateLock.RUnlock()
-		m.logger.Error("expiration: failed to revoke lease", "lease_id", leaseID, "error", err)
-		time.Sleep((1 << attempt) * revokeRetryBase)
+// TidyIrrevocable deletes the given irrevocable leases, as returned by
+// IrrevocableLeases, without attempting to revoke them again. It returns the
+// number of leases deleted. The secrets of the leases must be cleaned up out
+// of band.
+func (m *ExpirationManager) TidyIrrevocable(leases []*leaseEntry) (int, error) {
+	var deleted int
+	for _, le := range leases {
+		if err := m.deleteEntry(le.LeaseID); err != nil {
+			return deleted, err
+		}
+		if le.ClientToken != "" {
+			if err := m.removeIndexByToken(le.ClientToken, le.LeaseID); err != nil {
+				return deleted, err
+			}
+		}
+
+		m.pendingLock.Lock()
+		delete(m.irrevocable, le.LeaseID)
+		m.pendingLock.Unlock()
+		deleted++
+
+		if m.logger.IsTrace() {
+			m.logger.Trace("expiration: deleted irrevocable lease", "lease_id", le.LeaseID)
+		}
 	}
-	m.logger.Error("expiration: maximum revoke attempts reached", "lease_id", leaseID)
+	return deleted, nil
 }
 
 // revokeEntry is used to attempt revocation of an internal entry
@@ -1175,6 +1393,11 @@ func (m *ExpirationManager) deleteEntry(leaseID string) error {
 
 // createIndexByToken creates a secondary index from the token to a lease entry
 func (m *ExpirationManager) createIndexByToken(token, leaseID string) error {
+	// Batch tokens cannot be revoked, so their leases are not indexed
+	if isBatchTokenID(token) {
+		return nil
+	}
+
 	saltedID, err := m.tokenStore.SaltID(token)
 	if err != nil {
 		return err
@@ -1217,6 +1440,10 @@ func (m *ExpirationManager) indexByToken(token, leaseID string) (*logical.Storag
 
 // removeIndexByToken removes the secondary index from the token to a lease entry
 func (m *ExpirationManager) removeIndexByToken(token, leaseID string) error {
+	if isBatchTokenID(token) {
+		return nil
+	}
+
 	saltedID, err := m.tokenStore.SaltID(token)
 	if err != nil {
 		return err
@@ -1267,8 +1494,10 @@ func (m *ExpirationManager) lookupByToken(token string) ([]string, error) {
 func (m *ExpirationManager) emitMetrics() {
 	m.pendingLock.RLock()
 	num := len(m.pending)
+	numIrrevocable := len(m.irrevocable)
 	m.pendingLock.RUnlock()
 	metrics.SetGauge([]string{"expire", "num_leases"}, float32(num))
+	metrics.SetGauge([]string{"expire", "num_irrevocable_leases"}, float32(numIrrevocable))
 }
 
 // leaseEntry is used to structure the values the expiration
@@ -1283,6 +1512,25 @@ type leaseEntry struct {
 	IssueTime       time.Time              `json:"issue_time"`
 	ExpireTime      time.Time              `json:"expire_time"`
 	LastRenewalTime time.Time              `json:"last_renewal_time"`
+
+	// RevokeErr is the error of the last revocation attempt of an
+	// irrevocable lease
+	RevokeErr string `json:"revoke_err,omitempty"`
+}
+
+// irrevocableEntry returns the fields of the lease kept in memory while it
+// is irrevocable
+func (le *leaseEntry) irrevocableEntry() *leaseEntry {
+	ret := &leaseEntry{
+		LeaseID:    le.LeaseID,
+		Path:       le.Path,
+		ExpireTime: le.ExpireTime,
+		RevokeErr:  le.RevokeErr,
+	}
+	if le.Secret != nil {
+		ret.ClientToken = le.ClientToken
+	}
+	return ret
 }
 
 // encode is used to JSON encode the lease entry
@@ -2,6 +2,7 @@ package vault
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"reflect"
 	"sort"
@@ -11,6 +12,7 @@ import (
 	"time"
 
 	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/helper/consts"
 	"github.com/hashicorp/vault/helper/logformat"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
@@ -567,6 +569,59 @@ func TestExpiration_Revoke(t *testing.T) {
 	}
 }
 
+func TestExpiration_LeaseCountsByMount(t *testing.T) {
+	exp := mockExpiration(t)
+	noop := &NoopBackend{}
+	_, barrier, _ := mockBarrier(t)
+	view := NewBarrierView(barrier, "logical/")
+	meUUID, err := uuid.GenerateUUID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = exp.router.Mount(noop, "prod/aws/", &MountEntry{Path: "prod/aws/", Type: "noop", UUID: meUUID, Accessor: "noop-accessor"}, view)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := &logical.Request{
+		Operation:   logical.ReadOperation,
+		Path:        "prod/aws/foo",
+		ClientToken: "foobar",
+	}
+	resp := &logical.Response{
+		Secret: &logical.Secret{
+			LeaseOptions: logical.LeaseOptions{
+				TTL: time.Hour,
+			},
+		},
+	}
+
+	var ids []string
+	for i := 0; i < 2; i++ {
+		id, err := exp.Register(req, resp)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	if counts := exp.leaseCountsByMount(); counts["prod/aws/"] != 2 {
+		t.Fatalf("bad: %#v", counts)
+	}
+
+	// Once its leases are revoked, the mount is reported once with no leases
+	for _, id := range ids {
+		if err := exp.Revoke(id); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+	if counts := exp.leaseCountsByMount(); len(counts) != 1 || counts["prod/aws/"] != 0 {
+		t.Fatalf("bad: %#v", counts)
+	}
+	if counts := exp.leaseCountsByMount(); len(counts) != 0 {
+		t.Fatalf("bad: %#v", counts)
+	}
+}
+
 func TestExpiration_RevokeOnExpire(t *testing.T) {
 	exp := mockExpiration(t)
 	noop := &NoopBackend{}
@@ -624,6 +679,160 @@ func TestExpiration_RevokeOnExpire(t *testing.T) {
 	}
 }
 
+func TestExpiration_RevokeOnExpire_Many(t *testing.T) {
+	exp := mockExpiration(t)
+	noop := &NoopBackend{}
+	_, barrier, _ := mockBarrier(t)
+	view := NewBarrierView(barrier, "logical/")
+	meUUID, err := uuid.GenerateUUID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = exp.router.Mount(noop, "prod/aws/", &MountEntry{Path: "prod/aws/", Type: "noop", UUID: meUUID, Accessor: "noop-accessor"}, view)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// More leases than revocation workers expire at the same time
+	count := 4 * consts.ExpirationRevokeWorkerCount
+	for i := 0; i < count; i++ {
+		req := &logical.Request{
+			Operation:   logical.ReadOperation,
+			Path:        fmt.Sprintf("prod/aws/%d", i),
+			ClientToken: "foobar",
+		}
+		resp := &logical.Response{
+			Secret: &logical.Secret{
+				LeaseOptions: logical.LeaseOptions{
+					TTL: 20 * time.Millisecond,
+				},
+			},
+			Data: map[string]interface{}{
+				"access_key": "xyz",
+				"secret_key": "abcd",
+			},
+		}
+		if _, err := exp.Register(req, resp); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	var revoked int
+	start := time.Now()
+	for time.Now().Sub(start) < 5*time.Second {
+		noop.Lock()
+		revoked = len(noop.Requests)
+		noop.Unlock()
+		if revoked == count {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if revoked != count {
+		t.Fatalf("expected %d revocations, got %d", count, revoked)
+	}
+
+	exp.pendingLock.RLock()
+	pending := len(exp.pending)
+	exp.pendingLock.RUnlock()
+	if pending != 0 {
+		t.Fatalf("expected no pending leases, got %d", pending)
+	}
+}
+
+func TestExpiration_Irrevocable(t *testing.T) {
+	c, ts, _, _ := TestCoreWithTokenStore(t)
+	exp := ts.expiration
+	noop := &NoopBackend{}
+	_, barrier, _ := mockBarrier(t)
+	view := NewBarrierView(barrier, "logical/")
+	meUUID, err := uuid.GenerateUUID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = exp.router.Mount(noop, "prod/aws/", &MountEntry{Path: "prod/aws/", Type: "noop", UUID: meUUID, Accessor: "noop-accessor"}, view)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var leaseIDs []string
+	for _, path := range []string{"prod/aws/foo", "prod/aws/bar"} {
+		req := &logical.Request{
+			Operation:   logical.ReadOperation,
+			Path:        path,
+			ClientToken: "foobar",
+		}
+		resp := &logical.Response{
+			Secret: &logical.Secret{
+				LeaseOptions: logical.LeaseOptions{
+					TTL: time.Hour,
+				},
+			},
+		}
+		leaseID, err := exp.Register(req, resp)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		leaseIDs = append(leaseIDs, leaseID)
+	}
+
+	if err := exp.markIrrevocable(leaseIDs[0], errors.New("database is gone")); err != nil {
+		t.Fatal(err)
+	}
+
+	if actual := exp.LeaseIDs("prod/aws/", false); len(actual) != 2 {
+		t.Fatalf("bad: %v", actual)
+	}
+	if actual := exp.LeaseIDs("prod/aws/", true); !reflect.DeepEqual(actual, leaseIDs[:1]) {
+		t.Fatalf("bad: %v", actual)
+	}
+	exp.pendingLock.RLock()
+	_, pending := exp.pending[leaseIDs[0]]
+	exp.pendingLock.RUnlock()
+	if pending {
+		t.Fatal("irrevocable lease should not be pending expiration")
+	}
+
+	// The lease stays irrevocable after a restore
+	restored := NewExpirationManager(c, c.systemBarrierView.SubView(expirationSubPath))
+	if err := restored.Restore(nil); err != nil {
+		t.Fatal(err)
+	}
+	leases := restored.IrrevocableLeases("")
+	if len(leases) != 1 || leases[0].LeaseID != leaseIDs[0] || leases[0].RevokeErr != "database is gone" {
+		t.Fatalf("bad: %#v", leases)
+	}
+	if err := restored.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	leases = exp.IrrevocableLeases("prod/aws/")
+	deleted, err := exp.TidyIrrevocable(leases)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 1 {
+		t.Fatalf("expected 1 deleted lease, got %d", deleted)
+	}
+	if actual := exp.LeaseIDs("", true); len(actual) != 0 {
+		t.Fatalf("bad: %v", actual)
+	}
+	le, err := exp.loadEntry(leaseIDs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if le != nil {
+		t.Fatalf("expected lease to be deleted: %#v", le)
+	}
+
+	// The backend is not asked to revoke the tidied lease
+	noop.Lock()
+	defer noop.Unlock()
+	if len(noop.Requests) != 0 {
+		t.Fatalf("bad: %#v", noop.Requests)
+	}
+}
+
 func TestExpiration_RevokePrefix(t *testing.T) {
 	exp := mockExpiration(t)
 	noop := &NoopBackend{}
@@ -3,6 +3,7 @@ package vault
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"encoding/base64"
 	"fmt"
 
@@ -19,6 +20,10 @@ var (
 	// GenerateStandardRootTokenStrategy is the strategy used to generate a
 	// typical root token
 	GenerateStandardRootTokenStrategy GenerateRootStrategy = generateStandardRootToken{}
+
+	// GenerateDROperationTokenStrategy is the strategy used to generate the
+	// DR operation token authorizing the operations on a DR secondary
+	GenerateDROperationTokenStrategy GenerateRootStrategy = generateDROperationToken{}
 )
 
 // GenerateRootStrategy allows us to swap out the strategy we want to use to
@@ -49,6 +54,32 @@ func (g generateStandardRootToken) generate(ctx context.Context, c *Core) (strin
 	return te.ID, cleanupFunc, nil
 }
 
+// generateDROperationToken implements the GenerateRootStrategy and is in
+// charge of creating DR operation tokens. Only the hash of the token is
+// stored, the token replaces any previous one.
+type generateDROperationToken struct{}
+
+func (g generateDROperationToken) generate(ctx context.Context, c *Core) (string, func(), error) {
+	token, err := uuid.GenerateUUID()
+	if err != nil {
+		return "", nil, err
+	}
+	hash := sha256.Sum256([]byte(token))
+	if err := c.barrier.Put(ctx, &Entry{
+		Key:   coreDROperationTokenPath,
+		Value: hash[:],
+	}); err != nil {
+		c.logger.Error("core: DR operation token generation failed", "error", err)
+		return "", nil, err
+	}
+
+	cleanupFunc := func() {
+		c.barrier.Delete(ctx, coreDROperationTokenPath)
+	}
+
+	return token, cleanupFunc, nil
+}
+
 // GenerateRootConfig holds the configuration for a root generation
 // command.
 type GenerateRootConfig struct {
@@ -147,6 +178,14 @@ func (c *Core) GenerateRootInit(otp, pgpKey string, strategy GenerateRootStrateg
 		return consts.ErrStandby
 	}
 
+	// DR secondaries have no token store, only DR operation tokens
+	switch {
+	case strategy == GenerateDROperationTokenStrategy && !c.IsDRSecondary():
+		return fmt.Errorf("DR operation tokens can only be generated on a DR secondary")
+	case strategy != GenerateDROperationTokenStrategy && c.IsDRSecondary():
+		return consts.ErrDRSecondary
+	}
+
 	c.generateRootLock.Lock()
 	defer c.generateRootLock.Unlock()
 
@@ -38,6 +38,7 @@ func NewIdentityStore(ctx context.Context, core *Core, config *logical.BackendCo
 		entityLocks: locksutil.CreateLocks(),
 		logger:      core.logger,
 		validateMountAccessorFunc: core.router.validateMountByAccessor,
+		redirectAddr:              core.redirectAddr,
 	}
 
 	iStore.entityPacker, err = storagepacker.NewStoragePacker(iStore.view, iStore.logger, "")
@@ -59,8 +60,15 @@ func NewIdentityStore(ctx context.Context, core *Core, config *logical.BackendCo
 			groupPaths(iStore),
 			lookupPaths(iStore),
 			upgradePaths(iStore),
+			oidcPaths(iStore),
 		),
-		Invalidate: iStore.Invalidate,
+		PathsSpecial: &logical.Paths{
+			Unauthenticated: []string{
+				"oidc/.well-known/*",
+			},
+		},
+		Invalidate:   iStore.Invalidate,
+		PeriodicFunc: iStore.oidcPeriodicFunc,
 	}
 
 	err = iStore.Setup(ctx, config)
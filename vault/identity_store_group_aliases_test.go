@@ -214,3 +214,156 @@ func TestIdentityStore_GroupAliases_AliasOnInternalGroup(t *testing.T) {
 		t.Fatalf("expected an error")
 	}
 }
+
+func TestIdentityStore_GroupAliases_SyncExternalGroups(t *testing.T) {
+	var resp *logical.Response
+	var err error
+
+	i, accessor, c, root := testIdentityStoreWithGithubAuthRoot(t)
+
+	entity, err := i.CreateOrFetchEntity(&logical.Alias{
+		MountType:     "github",
+		MountAccessor: accessor,
+		Name:          "testuser",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	groupAliases := func(names ...string) []*logical.Alias {
+		var aliases []*logical.Alias
+		for _, name := range names {
+			aliases = append(aliases, &logical.Alias{
+				MountAccessor: accessor,
+				Name:          name,
+			})
+		}
+		return aliases
+	}
+
+	// Unknown group aliases are ignored unless the mount syncs external groups
+	err = i.refreshExternalGroupMembershipsByEntityID(entity.ID, groupAliases("devs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	group, err := i.MemDBGroupByName("devs", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if group != nil {
+		t.Fatalf("expected no group to be created, got %#v", group)
+	}
+
+	resp, err = c.HandleRequest(&logical.Request{
+		Path:        "sys/auth/github/tune",
+		Operation:   logical.UpdateOperation,
+		ClientToken: root,
+		Data: map[string]interface{}{
+			"sync_external_groups": true,
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+
+	resp, err = c.HandleRequest(&logical.Request{
+		Path:        "sys/auth/github/tune",
+		Operation:   logical.ReadOperation,
+		ClientToken: root,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+	if !resp.Data["sync_external_groups"].(bool) {
+		t.Fatalf("expected sync_external_groups to be set, got %#v", resp.Data)
+	}
+
+	// An external group created ahead of the first login is linked by name,
+	// so that policies can be assigned to it beforehand
+	resp, err = i.HandleRequest(context.Background(), &logical.Request{
+		Path:      "group",
+		Operation: logical.UpdateOperation,
+		Data: map[string]interface{}{
+			"name":     "admins",
+			"type":     "external",
+			"policies": []string{"admin"},
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+	adminsID := resp.Data["id"].(string)
+
+	// Internal groups are never tied to group aliases
+	resp, err = i.HandleRequest(context.Background(), &logical.Request{
+		Path:      "group",
+		Operation: logical.UpdateOperation,
+		Data: map[string]interface{}{
+			"name": "ops",
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+
+	err = i.refreshExternalGroupMembershipsByEntityID(entity.ID, groupAliases("admins", "devs", "ops"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"admins", "devs"} {
+		group, err = i.MemDBGroupByName(name, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if group == nil {
+			t.Fatalf("expected group %q to exist", name)
+		}
+		if group.Type != groupTypeExternal {
+			t.Fatalf("expected group %q to be external, got %q", name, group.Type)
+		}
+		if group.Alias == nil || group.Alias.Name != name || group.Alias.MountAccessor != accessor || group.Alias.MountType != "github" {
+			t.Fatalf("bad alias of group %q: %#v", name, group.Alias)
+		}
+		if len(group.MemberEntityIDs) != 1 || group.MemberEntityIDs[0] != entity.ID {
+			t.Fatalf("bad members of group %q: %#v", name, group.MemberEntityIDs)
+		}
+
+		alias, err := i.MemDBAliasByFactors(accessor, name, false, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if alias == nil || alias.CanonicalID != group.ID {
+			t.Fatalf("bad group alias %q: %#v", name, alias)
+		}
+	}
+
+	group, err = i.MemDBGroupByName("admins", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if group.ID != adminsID {
+		t.Fatalf("expected the existing admins group to be linked, got %q", group.ID)
+	}
+
+	group, err = i.MemDBGroupByName("ops", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if group.Alias != nil || len(group.MemberEntityIDs) != 0 {
+		t.Fatalf("expected the internal ops group to be left untouched, got %#v", group)
+	}
+
+	// Memberships follow the groups returned on subsequent logins
+	err = i.refreshExternalGroupMembershipsByEntityID(entity.ID, groupAliases("devs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	group, err = i.MemDBGroupByName("admins", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(group.MemberEntityIDs) != 0 {
+		t.Fatalf("expected the entity to be removed from admins, got %#v", group.MemberEntityIDs)
+	}
+}
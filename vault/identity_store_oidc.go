@@ -0,0 +1,1229 @@
+package vault
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	uuid "github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/helper/identity"
+	"github.com/hashicorp/vault/helper/random"
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+const (
+	// Storage paths of the OIDC provider
+	oidcConfigStorageKey = "oidc_config"
+	oidcTokensPrefix     = "oidc_tokens/"
+	namedKeyConfigPath   = oidcTokensPrefix + "named_keys/"
+	publicKeysConfigPath = oidcTokensPrefix + "public_keys/"
+	roleConfigPath       = oidcTokensPrefix + "roles/"
+
+	// issuerPath is appended to the issuer URL to form the issuer of the
+	// tokens, which is where the discovery document is served
+	issuerPath = "/v1/identity/oidc"
+
+	// minRotationPeriod is the shortest rotation period of a named key
+	minRotationPeriod = time.Minute
+)
+
+// reservedClaims are the claims set by Vault which templates can not
+// overwrite
+var reservedClaims = []string{"iat", "aud", "exp", "iss", "nbf", "sub"}
+
+// supportedAlgs are the supported signing algorithms of the named keys
+var supportedAlgs = []string{
+	string(jose.RS256),
+	string(jose.RS384),
+	string(jose.RS512),
+	string(jose.ES256),
+	string(jose.ES384),
+	string(jose.ES512),
+}
+
+// oidcConfig is the configuration of the OIDC provider
+type oidcConfig struct {
+	Issuer string `json:"issuer"`
+}
+
+// expireableKey is a key of a key ring. It expires once ExpireAt is set and
+// passed, which happens after the key was rotated out.
+type expireableKey struct {
+	KeyID    string    `json:"key_id"`
+	ExpireAt time.Time `json:"expire_at"`
+}
+
+// namedKey is a named set of signing keys of which the latest signs new
+// tokens, and the previous ones still verify the tokens they signed until
+// they expire
+type namedKey struct {
+	Algorithm        string           `json:"signing_algorithm"`
+	VerificationTTL  time.Duration    `json:"verification_ttl"`
+	RotationPeriod   time.Duration    `json:"rotation_period"`
+	KeyRing          []*expireableKey `json:"key_ring"`
+	SigningKey       *jose.JSONWebKey `json:"signing_key"`
+	NextRotation     time.Time        `json:"next_rotation"`
+	AllowedClientIDs []string         `json:"allowed_client_ids"`
+}
+
+// oidcRole is a role of the OIDC provider, which describes the tokens issued
+// for it
+type oidcRole struct {
+	TokenTTL time.Duration `json:"token_ttl"`
+	Key      string        `json:"key"`
+	Template string        `json:"template"`
+	ClientID string        `json:"client_id"`
+}
+
+// discovery is the OIDC discovery document of the provider
+type discovery struct {
+	Issuer        string   `json:"issuer"`
+	Keys          string   `json:"jwks_uri"`
+	ResponseTypes []string `json:"response_types_supported"`
+	Subjects      []string `json:"subject_types_supported"`
+	IDTokenAlgs   []string `json:"id_token_signing_alg_values_supported"`
+}
+
+// idToken is the set of the claims Vault sets on the issued tokens
+type idToken struct {
+	Issuer   string `json:"iss"`
+	Subject  string `json:"sub"`
+	Audience string `json:"aud"`
+	Expiry   int64  `json:"exp"`
+	IssuedAt int64  `json:"iat"`
+}
+
+func oidcPaths(i *IdentityStore) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "oidc/config/?$",
+			Fields: map[string]*framework.FieldSchema{
+				"issuer": {
+					Type:        framework.TypeString,
+					Description: "Issuer URL to be used in the iss claim of the token. If not set, Vault's api_addr will be used. The issuer is a case sensitive URL using the https scheme that contains scheme, host, and optionally, port number and path components, but no query or fragment components.",
+				},
+			},
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation:   i.pathOIDCReadConfig,
+				logical.UpdateOperation: i.pathOIDCUpdateConfig,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(oidcHelp["oidc-config"][0]),
+			HelpDescription: strings.TrimSpace(oidcHelp["oidc-config"][1]),
+		},
+		{
+			Pattern: "oidc/key/" + framework.GenericNameRegex("name"),
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the key",
+				},
+				"rotation_period": {
+					Type:        framework.TypeDurationSecond,
+					Description: "How often to generate a new keypair.",
+					Default:     "24h",
+				},
+				"verification_ttl": {
+					Type:        framework.TypeDurationSecond,
+					Description: "Controls how long the public portion of a key will be available for verification after being rotated.",
+					Default:     "24h",
+				},
+				"algorithm": {
+					Type:        framework.TypeString,
+					Description: "Signing algorithm to use. One of RS256, RS384, RS512, ES256, ES384 or ES512. Defaults to RS256.",
+					Default:     string(jose.RS256),
+				},
+				"allowed_client_ids": {
+					Type:        framework.TypeCommaStringSlice,
+					Description: "Comma separated string or array of role client ids allowed to use this key for signing. If empty no roles are allowed. If \"*\" all roles are allowed.",
+					Default:     []string{"*"},
+				},
+			},
+			ExistenceCheck: i.pathOIDCKeyExistenceCheck,
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.CreateOperation: i.pathOIDCCreateUpdateKey,
+				logical.UpdateOperation: i.pathOIDCCreateUpdateKey,
+				logical.ReadOperation:   i.pathOIDCReadKey,
+				logical.DeleteOperation: i.pathOIDCDeleteKey,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(oidcHelp["oidc-key"][0]),
+			HelpDescription: strings.TrimSpace(oidcHelp["oidc-key"][1]),
+		},
+		{
+			Pattern: "oidc/key/" + framework.GenericNameRegex("name") + "/rotate/?$",
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the key",
+				},
+				"verification_ttl": {
+					Type:        framework.TypeDurationSecond,
+					Description: "Controls how long the public portion of a key will be available for verification after being rotated. Setting verification_ttl here will override the verification_ttl set on the key.",
+				},
+			},
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: i.pathOIDCRotateKey,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(oidcHelp["oidc-rotate-key"][0]),
+			HelpDescription: strings.TrimSpace(oidcHelp["oidc-rotate-key"][1]),
+		},
+		{
+			Pattern: "oidc/key/?$",
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ListOperation: i.pathOIDCListKey,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(oidcHelp["oidc-key-list"][0]),
+			HelpDescription: strings.TrimSpace(oidcHelp["oidc-key-list"][1]),
+		},
+		{
+			Pattern: "oidc/role/" + framework.GenericNameRegex("name"),
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the role",
+				},
+				"key": {
+					Type:        framework.TypeString,
+					Description: "The OIDC key to use for generating tokens. The specified key must already exist.",
+				},
+				"template": {
+					Type:        framework.TypeString,
+					Description: "The template string to use for generating tokens. This may be in string-ified JSON or base64 format.",
+				},
+				"ttl": {
+					Type:        framework.TypeDurationSecond,
+					Description: "TTL of the tokens generated against the role.",
+					Default:     "24h",
+				},
+			},
+			ExistenceCheck: i.pathOIDCRoleExistenceCheck,
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.CreateOperation: i.pathOIDCCreateUpdateRole,
+				logical.UpdateOperation: i.pathOIDCCreateUpdateRole,
+				logical.ReadOperation:   i.pathOIDCReadRole,
+				logical.DeleteOperation: i.pathOIDCDeleteRole,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(oidcHelp["oidc-role"][0]),
+			HelpDescription: strings.TrimSpace(oidcHelp["oidc-role"][1]),
+		},
+		{
+			Pattern: "oidc/role/?$",
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ListOperation: i.pathOIDCListRole,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(oidcHelp["oidc-role-list"][0]),
+			HelpDescription: strings.TrimSpace(oidcHelp["oidc-role-list"][1]),
+		},
+		{
+			Pattern: "oidc/token/" + framework.GenericNameRegex("name"),
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the role",
+				},
+			},
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation: i.pathOIDCGenerateToken,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(oidcHelp["oidc-token"][0]),
+			HelpDescription: strings.TrimSpace(oidcHelp["oidc-token"][1]),
+		},
+		{
+			Pattern: "oidc/introspect/?$",
+			Fields: map[string]*framework.FieldSchema{
+				"token": {
+					Type:        framework.TypeString,
+					Description: "Token to verify",
+				},
+				"client_id": {
+					Type:        framework.TypeString,
+					Description: "Optional client_id to verify",
+				},
+			},
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: i.pathOIDCIntrospect,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(oidcHelp["oidc-introspect"][0]),
+			HelpDescription: strings.TrimSpace(oidcHelp["oidc-introspect"][1]),
+		},
+		{
+			Pattern: "oidc/.well-known/openid-configuration/?$",
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation: i.pathOIDCDiscovery,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(oidcHelp["oidc-discovery"][0]),
+			HelpDescription: strings.TrimSpace(oidcHelp["oidc-discovery"][1]),
+		},
+		{
+			Pattern: "oidc/.well-known/keys/?$",
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation: i.pathOIDCReadPublicKeys,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(oidcHelp["oidc-keys"][0]),
+			HelpDescription: strings.TrimSpace(oidcHelp["oidc-keys"][1]),
+		},
+	}
+}
+
+func (i *IdentityStore) pathOIDCReadConfig(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	i.oidcLock.RLock()
+	defer i.oidcLock.RUnlock()
+
+	config, err := i.getOIDCConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"issuer": config.Issuer,
+		},
+	}, nil
+}
+
+func (i *IdentityStore) pathOIDCUpdateConfig(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	i.oidcLock.Lock()
+	defer i.oidcLock.Unlock()
+
+	config, err := i.getOIDCConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp *logical.Response
+	if issuerRaw, ok := d.GetOk("issuer"); ok {
+		issuer := issuerRaw.(string)
+		if issuer != "" {
+			// verify that issuer is the correct format:
+			//   - http or https
+			//   - host name
+			//   - optional port
+			//   - optional path
+			//   - no query or fragment
+			u, err := url.Parse(issuer)
+			if err != nil {
+				return logical.ErrorResponse(fmt.Sprintf("invalid issuer, which must include only a scheme, host, optional port, and optional path: %v", err)), nil
+			}
+			if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.RawQuery != "" || u.Fragment != "" {
+				return logical.ErrorResponse("invalid issuer, which must include only a scheme, host, optional port, and optional path"), nil
+			}
+			if u.Scheme == "http" {
+				resp = &logical.Response{}
+				resp.AddWarning(`If "issuer" is set explicitly, all tokens must be validated against that address, including those issued by secondary clusters. Setting issuer to "" will restore the default behavior of using the cluster's api_addr as the issuer.`)
+			}
+		}
+		config.Issuer = strings.TrimSuffix(issuer, "/")
+	}
+
+	entry, err := logical.StorageEntryJSON(oidcConfigStorageKey, config)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// getOIDCConfig returns the configuration of the OIDC provider, which is
+// empty if it was never written
+func (i *IdentityStore) getOIDCConfig(ctx context.Context, s logical.Storage) (*oidcConfig, error) {
+	entry, err := s.Get(ctx, oidcConfigStorageKey)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &oidcConfig{}
+	if entry != nil {
+		if err := entry.DecodeJSON(config); err != nil {
+			return nil, err
+		}
+	}
+
+	return config, nil
+}
+
+// issuer returns the issuer of the tokens, which is the configured issuer or
+// the API address of the cluster followed by the path of the provider
+func (i *IdentityStore) issuer(ctx context.Context, s logical.Storage) (string, error) {
+	config, err := i.getOIDCConfig(ctx, s)
+	if err != nil {
+		return "", err
+	}
+
+	base := config.Issuer
+	if base == "" {
+		base = strings.TrimSuffix(i.redirectAddr, "/")
+	}
+
+	return base + issuerPath, nil
+}
+
+func (i *IdentityStore) pathOIDCKeyExistenceCheck(ctx context.Context, req *logical.Request, d *framework.FieldData) (bool, error) {
+	i.oidcLock.RLock()
+	defer i.oidcLock.RUnlock()
+
+	key, err := i.getNamedKey(ctx, req.Storage, d.Get("name").(string))
+	if err != nil {
+		return false, err
+	}
+
+	return key != nil, nil
+}
+
+func (i *IdentityStore) pathOIDCCreateUpdateKey(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	i.oidcLock.Lock()
+	defer i.oidcLock.Unlock()
+
+	key, err := i.getNamedKey(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		if req.Operation == logical.UpdateOperation {
+			return logical.ErrorResponse(fmt.Sprintf("no named key found with name %q", name)), nil
+		}
+		key = &namedKey{}
+	}
+
+	if rotationPeriodRaw, ok := d.GetOk("rotation_period"); ok {
+		key.RotationPeriod = time.Duration(rotationPeriodRaw.(int)) * time.Second
+	} else if req.Operation == logical.CreateOperation {
+		key.RotationPeriod = time.Duration(d.Get("rotation_period").(int)) * time.Second
+	}
+	if key.RotationPeriod < minRotationPeriod {
+		return logical.ErrorResponse("rotation_period must be at least one minute"), nil
+	}
+
+	if verificationTTLRaw, ok := d.GetOk("verification_ttl"); ok {
+		key.VerificationTTL = time.Duration(verificationTTLRaw.(int)) * time.Second
+	} else if req.Operation == logical.CreateOperation {
+		key.VerificationTTL = time.Duration(d.Get("verification_ttl").(int)) * time.Second
+	}
+
+	if allowedClientIDsRaw, ok := d.GetOk("allowed_client_ids"); ok {
+		key.AllowedClientIDs = allowedClientIDsRaw.([]string)
+	} else if req.Operation == logical.CreateOperation {
+		key.AllowedClientIDs = d.Get("allowed_client_ids").([]string)
+	}
+
+	prevAlgorithm := key.Algorithm
+	if algorithmRaw, ok := d.GetOk("algorithm"); ok {
+		key.Algorithm = algorithmRaw.(string)
+	} else if req.Operation == logical.CreateOperation {
+		key.Algorithm = d.Get("algorithm").(string)
+	}
+	if !strutil.StrListContains(supportedAlgs, key.Algorithm) {
+		return logical.ErrorResponse(fmt.Sprintf("unknown signing algorithm %q", key.Algorithm)), nil
+	}
+
+	// A new key, or a key with a changed algorithm, gets a new signing key.
+	// Otherwise only the schedule of the next rotation is updated.
+	if key.SigningKey == nil || key.Algorithm != prevAlgorithm {
+		if err := key.rotate(ctx, req.Storage, key.VerificationTTL); err != nil {
+			return nil, err
+		}
+	} else {
+		key.NextRotation = time.Now().Add(key.RotationPeriod)
+	}
+
+	if err := i.putNamedKey(ctx, req.Storage, name, key); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (i *IdentityStore) pathOIDCReadKey(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	i.oidcLock.RLock()
+	defer i.oidcLock.RUnlock()
+
+	key, err := i.getNamedKey(ctx, req.Storage, d.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"rotation_period":    int64(key.RotationPeriod.Seconds()),
+			"verification_ttl":   int64(key.VerificationTTL.Seconds()),
+			"algorithm":          key.Algorithm,
+			"allowed_client_ids": key.AllowedClientIDs,
+		},
+	}, nil
+}
+
+func (i *IdentityStore) pathOIDCDeleteKey(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	i.oidcLock.Lock()
+	defer i.oidcLock.Unlock()
+
+	// Keys referenced by roles can not be deleted
+	roleNames, err := req.Storage.List(ctx, roleConfigPath)
+	if err != nil {
+		return nil, err
+	}
+	var referencing []string
+	for _, roleName := range roleNames {
+		role, err := i.getOIDCRole(ctx, req.Storage, roleName)
+		if err != nil {
+			return nil, err
+		}
+		if role != nil && role.Key == name {
+			referencing = append(referencing, roleName)
+		}
+	}
+	if len(referencing) > 0 {
+		return logical.ErrorResponse(fmt.Sprintf("unable to delete key %q because it is currently referenced by these roles: %s", name, strings.Join(referencing, ", "))), logical.ErrInvalidRequest
+	}
+
+	key, err := i.getNamedKey(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, nil
+	}
+
+	for _, k := range key.KeyRing {
+		if err := req.Storage.Delete(ctx, publicKeysConfigPath+k.KeyID); err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, req.Storage.Delete(ctx, namedKeyConfigPath+name)
+}
+
+func (i *IdentityStore) pathOIDCRotateKey(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	i.oidcLock.Lock()
+	defer i.oidcLock.Unlock()
+
+	key, err := i.getNamedKey(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return logical.ErrorResponse(fmt.Sprintf("no named key found with name %q", name)), logical.ErrInvalidRequest
+	}
+
+	verificationTTL := key.VerificationTTL
+	if verificationTTLRaw, ok := d.GetOk("verification_ttl"); ok {
+		verificationTTL = time.Duration(verificationTTLRaw.(int)) * time.Second
+	}
+
+	if err := key.rotate(ctx, req.Storage, verificationTTL); err != nil {
+		return nil, err
+	}
+
+	if err := i.putNamedKey(ctx, req.Storage, name, key); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (i *IdentityStore) pathOIDCListKey(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	i.oidcLock.RLock()
+	defer i.oidcLock.RUnlock()
+
+	keys, err := req.Storage.List(ctx, namedKeyConfigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return logical.ListResponse(keys), nil
+}
+
+func (i *IdentityStore) getNamedKey(ctx context.Context, s logical.Storage, name string) (*namedKey, error) {
+	entry, err := s.Get(ctx, namedKeyConfigPath+name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var key namedKey
+	if err := entry.DecodeJSON(&key); err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+func (i *IdentityStore) putNamedKey(ctx context.Context, s logical.Storage, name string, key *namedKey) error {
+	entry, err := logical.StorageEntryJSON(namedKeyConfigPath+name, key)
+	if err != nil {
+		return err
+	}
+
+	return s.Put(ctx, entry)
+}
+
+// rotate generates a new signing key and stores its public key. The previous
+// signing key expires after the verification TTL. The caller persists the
+// named key.
+func (k *namedKey) rotate(ctx context.Context, s logical.Storage, verificationTTL time.Duration) error {
+	signingKey, err := generateSigningKey(k.Algorithm)
+	if err != nil {
+		return err
+	}
+
+	if err := savePublicKey(ctx, s, publicJWK(signingKey)); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if k.SigningKey != nil {
+		for _, ringKey := range k.KeyRing {
+			if ringKey.KeyID == k.SigningKey.KeyID {
+				ringKey.ExpireAt = now.Add(verificationTTL)
+			}
+		}
+	}
+
+	k.SigningKey = signingKey
+	k.KeyRing = append(k.KeyRing, &expireableKey{KeyID: signingKey.KeyID})
+	k.NextRotation = now.Add(k.RotationPeriod)
+
+	return nil
+}
+
+// signPayload signs the payload with the current signing key
+func (k *namedKey) signPayload(payload []byte) (string, error) {
+	signingKey := jose.SigningKey{
+		Algorithm: jose.SignatureAlgorithm(k.Algorithm),
+		Key:       k.SigningKey,
+	}
+	signer, err := jose.NewSigner(signingKey, (&jose.SignerOptions{}).WithType("JWT"))
+	if err != nil {
+		return "", err
+	}
+
+	signature, err := signer.Sign(payload)
+	if err != nil {
+		return "", err
+	}
+
+	return signature.CompactSerialize()
+}
+
+// generateSigningKey generates a private key for the algorithm
+func generateSigningKey(algorithm string) (*jose.JSONWebKey, error) {
+	var key crypto.PrivateKey
+	var err error
+
+	switch algorithm {
+	case string(jose.RS256), string(jose.RS384), string(jose.RS512):
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+	case string(jose.ES256):
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case string(jose.ES384):
+		key, err = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case string(jose.ES512):
+		key, err = ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	default:
+		return nil, fmt.Errorf("unknown signing algorithm %q", algorithm)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	keyID, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+
+	return &jose.JSONWebKey{
+		Key:       key,
+		KeyID:     keyID,
+		Algorithm: algorithm,
+		Use:       "sig",
+	}, nil
+}
+
+// publicJWK returns the public key of the private key
+func publicJWK(key *jose.JSONWebKey) jose.JSONWebKey {
+	public := *key
+	switch k := key.Key.(type) {
+	case *rsa.PrivateKey:
+		public.Key = &k.PublicKey
+	case *ecdsa.PrivateKey:
+		public.Key = &k.PublicKey
+	}
+
+	return public
+}
+
+func savePublicKey(ctx context.Context, s logical.Storage, key jose.JSONWebKey) error {
+	entry, err := logical.StorageEntryJSON(publicKeysConfigPath+key.KeyID, key)
+	if err != nil {
+		return err
+	}
+
+	return s.Put(ctx, entry)
+}
+
+func loadPublicKey(ctx context.Context, s logical.Storage, keyID string) (*jose.JSONWebKey, error) {
+	entry, err := s.Get(ctx, publicKeysConfigPath+keyID)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var key jose.JSONWebKey
+	if err := entry.DecodeJSON(&key); err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+func (i *IdentityStore) pathOIDCRoleExistenceCheck(ctx context.Context, req *logical.Request, d *framework.FieldData) (bool, error) {
+	i.oidcLock.RLock()
+	defer i.oidcLock.RUnlock()
+
+	role, err := i.getOIDCRole(ctx, req.Storage, d.Get("name").(string))
+	if err != nil {
+		return false, err
+	}
+
+	return role != nil, nil
+}
+
+func (i *IdentityStore) pathOIDCCreateUpdateRole(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	i.oidcLock.Lock()
+	defer i.oidcLock.Unlock()
+
+	role, err := i.getOIDCRole(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		if req.Operation == logical.UpdateOperation {
+			return logical.ErrorResponse(fmt.Sprintf("no role found with name %q", name)), nil
+		}
+		role = &oidcRole{}
+	}
+
+	if keyRaw, ok := d.GetOk("key"); ok {
+		role.Key = keyRaw.(string)
+	}
+	if role.Key == "" {
+		return logical.ErrorResponse("the key parameter is required"), nil
+	}
+
+	key, err := i.getNamedKey(ctx, req.Storage, role.Key)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return logical.ErrorResponse(fmt.Sprintf("key %q does not exist", role.Key)), nil
+	}
+
+	if templateRaw, ok := d.GetOk("template"); ok {
+		role.Template = templateRaw.(string)
+	}
+
+	// Templates may be base64 encoded
+	if decoded, err := base64.StdEncoding.DecodeString(role.Template); err == nil {
+		role.Template = string(decoded)
+	}
+
+	if role.Template != "" {
+		if err := validateOIDCTemplate(role.Template); err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+	}
+
+	if ttlRaw, ok := d.GetOk("ttl"); ok {
+		role.TokenTTL = time.Duration(ttlRaw.(int)) * time.Second
+	} else if req.Operation == logical.CreateOperation {
+		role.TokenTTL = time.Duration(d.Get("ttl").(int)) * time.Second
+	}
+
+	// The client ID is the audience of the tokens and never changes
+	if role.ClientID == "" {
+		clientID, err := random.Base62(24)
+		if err != nil {
+			return nil, err
+		}
+		role.ClientID = clientID
+	}
+
+	entry, err := logical.StorageEntryJSON(roleConfigPath+name, role)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// validateOIDCTemplate checks that the template renders to a JSON object
+// which does not set any of the reserved claims
+func validateOIDCTemplate(template string) error {
+	_, populated, err := identity.PopulateString(&identity.PopulateStringInput{
+		Mode:   identity.JSONTemplating,
+		String: template,
+		Entity: &identity.Entity{},
+	})
+	if err != nil {
+		return fmt.Errorf("error parsing template: %v", err)
+	}
+
+	var claims map[string]interface{}
+	if err := json.Unmarshal([]byte(populated), &claims); err != nil {
+		return fmt.Errorf("error parsing template JSON: %v", err)
+	}
+
+	for claim := range claims {
+		if strutil.StrListContains(reservedClaims, claim) {
+			return fmt.Errorf("top level key %q not allowed. Restricted keys: %s", claim, strings.Join(reservedClaims, ", "))
+		}
+	}
+
+	return nil
+}
+
+func (i *IdentityStore) pathOIDCReadRole(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	i.oidcLock.RLock()
+	defer i.oidcLock.RUnlock()
+
+	role, err := i.getOIDCRole(ctx, req.Storage, d.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"client_id": role.ClientID,
+			"key":       role.Key,
+			"template":  role.Template,
+			"ttl":       int64(role.TokenTTL.Seconds()),
+		},
+	}, nil
+}
+
+func (i *IdentityStore) pathOIDCDeleteRole(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	i.oidcLock.Lock()
+	defer i.oidcLock.Unlock()
+
+	return nil, req.Storage.Delete(ctx, roleConfigPath+d.Get("name").(string))
+}
+
+func (i *IdentityStore) pathOIDCListRole(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	i.oidcLock.RLock()
+	defer i.oidcLock.RUnlock()
+
+	roles, err := req.Storage.List(ctx, roleConfigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return logical.ListResponse(roles), nil
+}
+
+func (i *IdentityStore) getOIDCRole(ctx context.Context, s logical.Storage, name string) (*oidcRole, error) {
+	entry, err := s.Get(ctx, roleConfigPath+name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var role oidcRole
+	if err := entry.DecodeJSON(&role); err != nil {
+		return nil, err
+	}
+
+	return &role, nil
+}
+
+func (i *IdentityStore) pathOIDCGenerateToken(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	if req.EntityID == "" {
+		return logical.ErrorResponse("no entity associated with the request's token"), logical.ErrInvalidRequest
+	}
+
+	i.oidcLock.RLock()
+	defer i.oidcLock.RUnlock()
+
+	role, err := i.getOIDCRole(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return logical.ErrorResponse(fmt.Sprintf("role %q not found", name)), logical.ErrInvalidRequest
+	}
+
+	key, err := i.getNamedKey(ctx, req.Storage, role.Key)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return logical.ErrorResponse(fmt.Sprintf("key %q not found", role.Key)), logical.ErrInvalidRequest
+	}
+	if !strutil.StrListContains(key.AllowedClientIDs, "*") && !strutil.StrListContains(key.AllowedClientIDs, role.ClientID) {
+		return logical.ErrorResponse("the key specified by the role does not allow the role's client ID"), logical.ErrInvalidRequest
+	}
+
+	entity, err := i.MemDBEntityByID(req.EntityID, true)
+	if err != nil {
+		return nil, err
+	}
+	if entity == nil {
+		return logical.ErrorResponse("could not find the entity associated with the request's token"), logical.ErrInvalidRequest
+	}
+
+	issuer, err := i.issuer(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	payload, err := json.Marshal(&idToken{
+		Issuer:   issuer,
+		Subject:  entity.ID,
+		Audience: role.ClientID,
+		Expiry:   now.Add(role.TokenTTL).Unix(),
+		IssuedAt: now.Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if role.Template != "" {
+		directGroups, inheritedGroups, err := i.groupsByEntityID(entity.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		payload, err = mergeJSONTemplate(payload, role.Template, entity, append(directGroups, inheritedGroups...))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	token, err := key.signPayload(payload)
+	if err != nil {
+		return nil, fmt.Errorf("error signing token: %v", err)
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"token":     token,
+			"client_id": role.ClientID,
+			"ttl":       int64(role.TokenTTL.Seconds()),
+		},
+	}, nil
+}
+
+// mergeJSONTemplate populates the template with the entity and its groups and
+// merges the resulting claims into the payload. Reserved claims of the
+// payload are never overwritten.
+func mergeJSONTemplate(payload []byte, template string, entity *identity.Entity, groups []*identity.Group) ([]byte, error) {
+	_, populated, err := identity.PopulateString(&identity.PopulateStringInput{
+		Mode:   identity.JSONTemplating,
+		String: template,
+		Entity: entity,
+		Groups: groups,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error populating template: %v", err)
+	}
+
+	var claims, templateClaims map[string]interface{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(populated), &templateClaims); err != nil {
+		return nil, fmt.Errorf("error parsing populated template: %v", err)
+	}
+
+	for claim, value := range templateClaims {
+		if strutil.StrListContains(reservedClaims, claim) {
+			continue
+		}
+		claims[claim] = value
+	}
+
+	return json.Marshal(claims)
+}
+
+func (i *IdentityStore) pathOIDCIntrospect(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	rawToken := d.Get("token").(string)
+	clientID := d.Get("client_id").(string)
+
+	if rawToken == "" {
+		return logical.ErrorResponse("missing token"), logical.ErrInvalidRequest
+	}
+
+	// introspectionResp returns the response of an invalid token
+	introspectionResp := func(errorMsg string) *logical.Response {
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"active": false,
+				"error":  errorMsg,
+			},
+		}
+	}
+
+	i.oidcLock.RLock()
+	defer i.oidcLock.RUnlock()
+
+	token, err := jwt.ParseSigned(rawToken)
+	if err != nil {
+		return introspectionResp(fmt.Sprintf("error parsing token: %s", err)), nil
+	}
+	if len(token.Headers) != 1 {
+		return introspectionResp("token must have exactly one signature"), nil
+	}
+
+	publicKey, err := loadPublicKey(ctx, req.Storage, token.Headers[0].KeyID)
+	if err != nil {
+		return nil, err
+	}
+	if publicKey == nil {
+		return introspectionResp("unable to find the key which signed the token"), nil
+	}
+
+	var claims jwt.Claims
+	if err := token.Claims(publicKey, &claims); err != nil {
+		return introspectionResp(fmt.Sprintf("error verifying token: %s", err)), nil
+	}
+
+	issuer, err := i.issuer(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	expected := jwt.Expected{
+		Issuer: issuer,
+		Time:   time.Now(),
+	}
+	if clientID != "" {
+		expected.Audience = []string{clientID}
+	}
+	if err := claims.Validate(expected); err != nil {
+		return introspectionResp(fmt.Sprintf("error validating claims: %s", err)), nil
+	}
+
+	entity, err := i.MemDBEntityByID(claims.Subject, false)
+	if err != nil {
+		return nil, err
+	}
+	if entity == nil {
+		return introspectionResp("entity was not found"), nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"active": true,
+		},
+	}, nil
+}
+
+func (i *IdentityStore) pathOIDCDiscovery(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	issuer, err := i.issuer(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(&discovery{
+		Issuer:        issuer,
+		Keys:          issuer + "/.well-known/keys",
+		ResponseTypes: []string{"id_token"},
+		Subjects:      []string{"public"},
+		IDTokenAlgs:   supportedAlgs,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPStatusCode:  http.StatusOK,
+			logical.HTTPRawBody:     data,
+			logical.HTTPContentType: "application/json",
+		},
+	}, nil
+}
+
+func (i *IdentityStore) pathOIDCReadPublicKeys(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	i.oidcLock.RLock()
+	defer i.oidcLock.RUnlock()
+
+	keyIDs, err := req.Storage.List(ctx, publicKeysConfigPath)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(keyIDs)
+
+	jwks := &jose.JSONWebKeySet{
+		Keys: make([]jose.JSONWebKey, 0, len(keyIDs)),
+	}
+	for _, keyID := range keyIDs {
+		key, err := loadPublicKey(ctx, req.Storage, keyID)
+		if err != nil {
+			return nil, err
+		}
+		if key != nil {
+			jwks.Keys = append(jwks.Keys, *key)
+		}
+	}
+
+	data, err := json.Marshal(jwks)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPStatusCode:  http.StatusOK,
+			logical.HTTPRawBody:     data,
+			logical.HTTPContentType: "application/json",
+		},
+	}, nil
+}
+
+// oidcPeriodicFunc rotates the named keys which are due and removes the
+// public keys which expired
+func (i *IdentityStore) oidcPeriodicFunc(ctx context.Context, req *logical.Request) error {
+	s := req.Storage
+
+	i.oidcLock.Lock()
+	defer i.oidcLock.Unlock()
+
+	names, err := s.List(ctx, namedKeyConfigPath)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, name := range names {
+		key, err := i.getNamedKey(ctx, s, name)
+		if err != nil {
+			return err
+		}
+		if key == nil {
+			continue
+		}
+
+		modified := false
+		if now.After(key.NextRotation) {
+			if err := key.rotate(ctx, s, key.VerificationTTL); err != nil {
+				return err
+			}
+			modified = true
+		}
+
+		keyRing := make([]*expireableKey, 0, len(key.KeyRing))
+		for _, ringKey := range key.KeyRing {
+			if !ringKey.ExpireAt.IsZero() && now.After(ringKey.ExpireAt) {
+				if err := s.Delete(ctx, publicKeysConfigPath+ringKey.KeyID); err != nil {
+					return err
+				}
+				modified = true
+				continue
+			}
+			keyRing = append(keyRing, ringKey)
+		}
+		key.KeyRing = keyRing
+
+		if modified {
+			if err := i.putNamedKey(ctx, s, name, key); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+var oidcHelp = map[string][2]string{
+	"oidc-config": {
+		"OIDC configuration",
+		"Update OIDC configuration for the identity backend",
+	},
+	"oidc-key": {
+		"CRUD operations for OIDC keys.",
+		"Create, Read, Update, and Delete OIDC named keys.",
+	},
+	"oidc-rotate-key": {
+		"Rotate a named OIDC key.",
+		"Rotate a named OIDC key. The previous signing key's public key will remain available for verification until its verification_ttl elapses.",
+	},
+	"oidc-key-list": {
+		"List OIDC keys",
+		"List all named OIDC keys",
+	},
+	"oidc-role": {
+		"CRUD operations on OIDC Roles",
+		"Create a role to issue OIDC tokens for the entity of the calling token. The template of the role sets additional claims of the tokens.",
+	},
+	"oidc-role-list": {
+		"List configured OIDC roles",
+		"List all configured OIDC roles in the identity backend.",
+	},
+	"oidc-token": {
+		"Generate an OIDC token",
+		"Generate an OIDC token against a configured role. The vault token used to call this path must have a corresponding entity.",
+	},
+	"oidc-introspect": {
+		"Verify the authenticity of an OIDC token",
+		"Use this path to verify the authenticity of an OIDC token and whether the associated entity is active and enabled.",
+	},
+	"oidc-discovery": {
+		"Query OIDC configurations",
+		"Query this path to retrieve the configured OIDC Issuer and Keys endpoints, response types, subject types, and signing algorithms used by the OIDC backend.",
+	},
+	"oidc-keys": {
+		"Retrieve public keys",
+		"Query this path to retrieve the public portion of keys used to sign OIDC tokens. Clients can use this to validate the authenticity of an OIDC token.",
+	},
+}
@@ -0,0 +1,440 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+func testOIDCRequest(i *IdentityStore, op logical.Operation, path string, data map[string]interface{}) *logical.Request {
+	return &logical.Request{
+		Operation: op,
+		Path:      path,
+		Data:      data,
+		Storage:   i.view,
+	}
+}
+
+func testOIDCPublicKeys(t *testing.T, i *IdentityStore) *jose.JSONWebKeySet {
+	resp, err := i.HandleRequest(context.Background(), testOIDCRequest(i, logical.ReadOperation, "oidc/.well-known/keys", nil))
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+
+	var jwks jose.JSONWebKeySet
+	if err := json.Unmarshal(resp.Data[logical.HTTPRawBody].([]byte), &jwks); err != nil {
+		t.Fatal(err)
+	}
+	return &jwks
+}
+
+func TestOIDC_Key(t *testing.T) {
+	i, _, _ := testIdentityStoreWithGithubAuth(t)
+	ctx := context.Background()
+
+	resp, err := i.HandleRequest(ctx, testOIDCRequest(i, logical.CreateOperation, "oidc/key/test-key", map[string]interface{}{
+		"algorithm": "HS256",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.IsError() {
+		t.Fatalf("expected an error for an unsupported algorithm")
+	}
+
+	resp, err = i.HandleRequest(ctx, testOIDCRequest(i, logical.CreateOperation, "oidc/key/test-key", map[string]interface{}{
+		"rotation_period": "30s",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.IsError() {
+		t.Fatalf("expected an error for a rotation period below the minimum")
+	}
+
+	resp, err = i.HandleRequest(ctx, testOIDCRequest(i, logical.CreateOperation, "oidc/key/test-key", map[string]interface{}{
+		"algorithm": "ES256",
+	}))
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+
+	resp, err = i.HandleRequest(ctx, testOIDCRequest(i, logical.ReadOperation, "oidc/key/test-key", nil))
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+	expected := map[string]interface{}{
+		"rotation_period":    int64(24 * 60 * 60),
+		"verification_ttl":   int64(24 * 60 * 60),
+		"algorithm":          "ES256",
+		"allowed_client_ids": []string{"*"},
+	}
+	for k, v := range expected {
+		if got := resp.Data[k]; jsonString(t, got) != jsonString(t, v) {
+			t.Fatalf("bad: %s: expected %v, got %v", k, v, got)
+		}
+	}
+
+	if keys := testOIDCPublicKeys(t, i).Keys; len(keys) != 1 {
+		t.Fatalf("expected 1 public key, got %d", len(keys))
+	}
+
+	// Rotating keeps the previous public key available for verification
+	resp, err = i.HandleRequest(ctx, testOIDCRequest(i, logical.UpdateOperation, "oidc/key/test-key/rotate", nil))
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+	keys := testOIDCPublicKeys(t, i).Keys
+	if len(keys) != 2 {
+		t.Fatalf("expected 2 public keys, got %d", len(keys))
+	}
+	for _, key := range keys {
+		if !key.Valid() || !key.IsPublic() {
+			t.Fatalf("bad public key: %#v", key)
+		}
+	}
+
+	resp, err = i.HandleRequest(ctx, testOIDCRequest(i, logical.ListOperation, "oidc/key/", nil))
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+	if keys := resp.Data["keys"].([]string); len(keys) != 1 || keys[0] != "test-key" {
+		t.Fatalf("bad: %#v", keys)
+	}
+
+	// Keys referenced by roles can not be deleted
+	resp, err = i.HandleRequest(ctx, testOIDCRequest(i, logical.CreateOperation, "oidc/role/test-role", map[string]interface{}{
+		"key": "test-key",
+	}))
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+	resp, err = i.HandleRequest(ctx, testOIDCRequest(i, logical.DeleteOperation, "oidc/key/test-key", nil))
+	if err == nil || !resp.IsError() {
+		t.Fatalf("expected an error deleting a referenced key")
+	}
+
+	resp, err = i.HandleRequest(ctx, testOIDCRequest(i, logical.DeleteOperation, "oidc/role/test-role", nil))
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+	resp, err = i.HandleRequest(ctx, testOIDCRequest(i, logical.DeleteOperation, "oidc/key/test-key", nil))
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+	if keys := testOIDCPublicKeys(t, i).Keys; len(keys) != 0 {
+		t.Fatalf("expected the public keys to be deleted, got %d", len(keys))
+	}
+}
+
+func TestOIDC_Role(t *testing.T) {
+	i, _, _ := testIdentityStoreWithGithubAuth(t)
+	ctx := context.Background()
+
+	resp, err := i.HandleRequest(ctx, testOIDCRequest(i, logical.CreateOperation, "oidc/role/test-role", map[string]interface{}{
+		"key": "test-key",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.IsError() {
+		t.Fatalf("expected an error for a missing key")
+	}
+
+	resp, err = i.HandleRequest(ctx, testOIDCRequest(i, logical.CreateOperation, "oidc/key/test-key", nil))
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+
+	for _, template := range []string{
+		`{"sub": "override"}`,
+		`{"color": {{identity.entity.metadata.color}}`,
+		`{"color": {{identity.entity.unknown}}}`,
+		`{"color": {{identity.entity.metadata.color}`,
+	} {
+		resp, err = i.HandleRequest(ctx, testOIDCRequest(i, logical.CreateOperation, "oidc/role/test-role", map[string]interface{}{
+			"key":      "test-key",
+			"template": template,
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !resp.IsError() {
+			t.Fatalf("expected an error for template %q", template)
+		}
+	}
+
+	resp, err = i.HandleRequest(ctx, testOIDCRequest(i, logical.CreateOperation, "oidc/role/test-role", map[string]interface{}{
+		"key":      "test-key",
+		"template": `{"color": {{identity.entity.metadata.color}}}`,
+		"ttl":      "1h",
+	}))
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+
+	resp, err = i.HandleRequest(ctx, testOIDCRequest(i, logical.ReadOperation, "oidc/role/test-role", nil))
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+	clientID := resp.Data["client_id"].(string)
+	if len(clientID) != 24 {
+		t.Fatalf("bad client ID: %q", clientID)
+	}
+	if resp.Data["ttl"].(int64) != 3600 || resp.Data["key"].(string) != "test-key" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// The client ID does not change on updates
+	resp, err = i.HandleRequest(ctx, testOIDCRequest(i, logical.UpdateOperation, "oidc/role/test-role", map[string]interface{}{
+		"ttl": "2h",
+	}))
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+	resp, err = i.HandleRequest(ctx, testOIDCRequest(i, logical.ReadOperation, "oidc/role/test-role", nil))
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+	if resp.Data["client_id"].(string) != clientID || resp.Data["ttl"].(int64) != 7200 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if resp.Data["template"].(string) != `{"color": {{identity.entity.metadata.color}}}` {
+		t.Fatalf("bad template: %q", resp.Data["template"])
+	}
+}
+
+func TestOIDC_GenerateAndIntrospectToken(t *testing.T) {
+	i, _, _ := testIdentityStoreWithGithubAuth(t)
+	ctx := context.Background()
+
+	resp, err := i.HandleRequest(ctx, testOIDCRequest(i, logical.UpdateOperation, "oidc/config", map[string]interface{}{
+		"issuer": "https://vault.example.com:8200/",
+	}))
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+
+	resp, err = i.HandleRequest(ctx, testOIDCRequest(i, logical.UpdateOperation, "entity", map[string]interface{}{
+		"name": "testentity",
+		"metadata": []string{
+			"color=green",
+		},
+	}))
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+	entityID := resp.Data["id"].(string)
+
+	resp, err = i.HandleRequest(ctx, testOIDCRequest(i, logical.UpdateOperation, "group", map[string]interface{}{
+		"name":              "testgroup",
+		"member_entity_ids": entityID,
+	}))
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+
+	resp, err = i.HandleRequest(ctx, testOIDCRequest(i, logical.CreateOperation, "oidc/key/test-key", nil))
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+	resp, err = i.HandleRequest(ctx, testOIDCRequest(i, logical.CreateOperation, "oidc/role/test-role", map[string]interface{}{
+		"key":      "test-key",
+		"template": `{"name": {{identity.entity.name}}, "color": {{identity.entity.metadata.color}}, "groups": {{identity.entity.groups.names}}, "missing": {{identity.entity.metadata.missing}}}`,
+	}))
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+
+	// Tokens are only issued for entities
+	tokenReq := testOIDCRequest(i, logical.ReadOperation, "oidc/token/test-role", nil)
+	resp, err = i.HandleRequest(ctx, tokenReq)
+	if err == nil || !resp.IsError() {
+		t.Fatalf("expected an error without an entity")
+	}
+
+	tokenReq.EntityID = entityID
+	resp, err = i.HandleRequest(ctx, tokenReq)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+	rawToken := resp.Data["token"].(string)
+	clientID := resp.Data["client_id"].(string)
+
+	// The token is verifiable with the published keys
+	token, err := jwt.ParseSigned(rawToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwks := testOIDCPublicKeys(t, i)
+	keys := jwks.Key(token.Headers[0].KeyID)
+	if len(keys) != 1 {
+		t.Fatalf("expected the signing key to be published")
+	}
+
+	var claims jwt.Claims
+	var allClaims map[string]interface{}
+	if err := token.Claims(keys[0], &claims, &allClaims); err != nil {
+		t.Fatal(err)
+	}
+	if err := claims.Validate(jwt.Expected{
+		Issuer:   "https://vault.example.com:8200/v1/identity/oidc",
+		Subject:  entityID,
+		Audience: []string{clientID},
+		Time:     time.Now(),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if allClaims["name"] != "testentity" || allClaims["color"] != "green" || allClaims["missing"] != "" {
+		t.Fatalf("bad claims: %#v", allClaims)
+	}
+	if groups, ok := allClaims["groups"].([]interface{}); !ok || len(groups) != 1 || groups[0] != "testgroup" {
+		t.Fatalf("bad groups claim: %#v", allClaims["groups"])
+	}
+
+	// The discovery document points to the published keys
+	resp, err = i.HandleRequest(ctx, testOIDCRequest(i, logical.ReadOperation, "oidc/.well-known/openid-configuration", nil))
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+	var doc discovery
+	if err := json.Unmarshal(resp.Data[logical.HTTPRawBody].([]byte), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Issuer != "https://vault.example.com:8200/v1/identity/oidc" || doc.Keys != doc.Issuer+"/.well-known/keys" {
+		t.Fatalf("bad discovery document: %#v", doc)
+	}
+
+	introspect := func(data map[string]interface{}) map[string]interface{} {
+		resp, err := i.HandleRequest(ctx, testOIDCRequest(i, logical.UpdateOperation, "oidc/introspect", data))
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+		}
+		return resp.Data
+	}
+
+	if data := introspect(map[string]interface{}{"token": rawToken, "client_id": clientID}); data["active"] != true {
+		t.Fatalf("expected the token to be active: %#v", data)
+	}
+	if data := introspect(map[string]interface{}{"token": rawToken, "client_id": "other"}); data["active"] != false {
+		t.Fatalf("expected the token to be inactive for another client: %#v", data)
+	}
+	if data := introspect(map[string]interface{}{"token": rawToken[:len(rawToken)-4]}); data["active"] != false {
+		t.Fatalf("expected a tampered token to be inactive: %#v", data)
+	}
+
+	// Keys which do not allow the client ID of the role can not sign its
+	// tokens
+	resp, err = i.HandleRequest(ctx, testOIDCRequest(i, logical.UpdateOperation, "oidc/key/test-key", map[string]interface{}{
+		"allowed_client_ids": "other",
+	}))
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+	resp, err = i.HandleRequest(ctx, tokenReq)
+	if err == nil || !resp.IsError() {
+		t.Fatalf("expected an error for a client ID not allowed by the key")
+	}
+}
+
+func TestOIDC_PeriodicFunc(t *testing.T) {
+	i, _, _ := testIdentityStoreWithGithubAuth(t)
+	ctx := context.Background()
+
+	resp, err := i.HandleRequest(ctx, testOIDCRequest(i, logical.CreateOperation, "oidc/key/test-key", map[string]interface{}{
+		"rotation_period":  "1m",
+		"verification_ttl": "1m",
+	}))
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+
+	periodic := func() *namedKey {
+		if err := i.oidcPeriodicFunc(ctx, &logical.Request{Storage: i.view}); err != nil {
+			t.Fatal(err)
+		}
+		key, err := i.getNamedKey(ctx, i.view, "test-key")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return key
+	}
+
+	// Nothing is due yet
+	key := periodic()
+	if len(key.KeyRing) != 1 {
+		t.Fatalf("expected 1 key, got %d", len(key.KeyRing))
+	}
+	signingKeyID := key.SigningKey.KeyID
+
+	// A due rotation creates a new signing key
+	key.NextRotation = time.Now().Add(-time.Second)
+	if err := i.putNamedKey(ctx, i.view, "test-key", key); err != nil {
+		t.Fatal(err)
+	}
+	key = periodic()
+	if len(key.KeyRing) != 2 || key.SigningKey.KeyID == signingKeyID {
+		t.Fatalf("expected the key to be rotated: %#v", key.KeyRing)
+	}
+	if len(testOIDCPublicKeys(t, i).Keys) != 2 {
+		t.Fatalf("expected 2 public keys")
+	}
+
+	// The rotated out key is removed once it expires
+	for _, ringKey := range key.KeyRing {
+		if ringKey.KeyID == signingKeyID {
+			if ringKey.ExpireAt.IsZero() {
+				t.Fatalf("expected the rotated out key to expire")
+			}
+			ringKey.ExpireAt = time.Now().Add(-time.Second)
+		}
+	}
+	if err := i.putNamedKey(ctx, i.view, "test-key", key); err != nil {
+		t.Fatal(err)
+	}
+	key = periodic()
+	if len(key.KeyRing) != 1 || key.KeyRing[0].KeyID != key.SigningKey.KeyID {
+		t.Fatalf("expected the expired key to be removed: %#v", key.KeyRing)
+	}
+	if keys := testOIDCPublicKeys(t, i).Keys; len(keys) != 1 || keys[0].KeyID != key.SigningKey.KeyID {
+		t.Fatalf("expected only the signing key to be published")
+	}
+}
+
+func jsonString(t *testing.T, v interface{}) string {
+	out, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
+
+func TestOIDC_UnauthenticatedPaths(t *testing.T) {
+	c, _, _ := TestCoreUnsealed(t)
+
+	for _, path := range []string{
+		"identity/oidc/.well-known/openid-configuration",
+		"identity/oidc/.well-known/keys",
+	} {
+		resp, err := c.HandleRequest(&logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      path,
+		})
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("bad: %s: resp: %#v\nerr: %v", path, resp, err)
+		}
+	}
+
+	resp, err := c.HandleRequest(&logical.Request{
+		Operation: logical.ListOperation,
+		Path:      "identity/oidc/key",
+	})
+	if err == nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error without a client token, got resp: %#v\nerr: %v", resp, err)
+	}
+}
@@ -73,6 +73,14 @@ type IdentityStore struct {
 	// groupPacker is used to pack multiple group storage entries into 256
 	// buckets
 	groupPacker *storagepacker.StoragePacker
+
+	// oidcLock is used to protect modifications to the OIDC provider's keys
+	// and roles
+	oidcLock sync.RWMutex
+
+	// redirectAddr is the API address of the cluster, which is the default
+	// issuer of the OIDC tokens
+	redirectAddr string
 }
 
 type groupDiff struct {
@@ -33,6 +33,18 @@ func (c *Core) loadIdentityStoreArtifacts(ctx context.Context) error {
 		return err
 	}
 
+	// Load the identity stores of the child namespaces
+	c.namespaceIdentityStoresLock.RLock()
+	defer c.namespaceIdentityStoresLock.RUnlock()
+	for _, identityStore := range c.namespaceIdentityStores {
+		if err := identityStore.loadEntities(ctx); err != nil {
+			return err
+		}
+		if err := identityStore.loadGroups(ctx); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -2315,6 +2327,13 @@ func (i *IdentityStore) refreshExternalGroupMembershipsByEntityID(entityID strin
 			return err
 		}
 		if aliasByFactors == nil {
+			syncedGroup, err := i.syncExternalGroupInTxn(txn, alias)
+			if err != nil {
+				return err
+			}
+			if syncedGroup != nil {
+				newGroups = append(newGroups, syncedGroup)
+			}
 			continue
 		}
 		mappingGroup, err := i.MemDBGroupByAliasID(aliasByFactors.ID, true)
@@ -2366,6 +2385,74 @@ func (i *IdentityStore) refreshExternalGroupMembershipsByEntityID(entityID strin
 	return nil
 }
 
+// syncExternalGroupInTxn ties a group alias returned by an auth mount which
+// syncs external groups to the external group of the same name, creating the
+// group if it does not exist. It returns nil if the mount does not sync
+// external groups or if the name is taken by a group which can't be tied to
+// the alias.
+func (i *IdentityStore) syncExternalGroupInTxn(txn *memdb.Txn, groupAlias *logical.Alias) (*identity.Group, error) {
+	mountValidationResp := i.validateMountAccessorFunc(groupAlias.MountAccessor)
+	if mountValidationResp == nil || !mountValidationResp.SyncExternalGroups || groupAlias.Name == "" {
+		return nil, nil
+	}
+
+	group, err := i.MemDBGroupByNameInTxn(txn, groupAlias.Name, true)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case group == nil:
+		group = &identity.Group{
+			Name: groupAlias.Name,
+			Type: groupTypeExternal,
+		}
+
+		group.ID, err = uuid.GenerateUUID()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate group id")
+		}
+		group.BucketKeyHash = i.groupPacker.BucketKeyHashByItemID(group.ID)
+		group.CreationTime = ptypes.TimestampNow()
+		group.LastUpdateTime = group.CreationTime
+
+		i.logger.Debug("creating external group for group alias", "name", groupAlias.Name, "mount_accessor", groupAlias.MountAccessor, "group_id", group.ID)
+
+	case group.Type != groupTypeExternal || group.Alias != nil:
+		i.logger.Warn("not syncing group alias, group name already in use", "name", groupAlias.Name, "mount_accessor", groupAlias.MountAccessor, "group_id", group.ID)
+		return nil, nil
+
+	default:
+		group.LastUpdateTime = ptypes.TimestampNow()
+
+		i.logger.Debug("linking group alias to existing external group", "name", groupAlias.Name, "mount_accessor", groupAlias.MountAccessor, "group_id", group.ID)
+	}
+
+	group.Alias = &identity.Alias{
+		CanonicalID:   group.ID,
+		Name:          groupAlias.Name,
+		MountType:     mountValidationResp.MountType,
+		MountAccessor: mountValidationResp.MountAccessor,
+	}
+
+	err = i.sanitizeAlias(group.Alias)
+	if err != nil {
+		return nil, err
+	}
+
+	err = i.MemDBUpsertAliasInTxn(txn, group.Alias, true)
+	if err != nil {
+		return nil, err
+	}
+
+	err = i.upsertGroupInTxn(txn, group, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return group, nil
+}
+
 // diffGroups is used to diff two sets of groups
 func diffGroups(old, new []*identity.Group) *groupDiff {
 	diff := &groupDiff{}
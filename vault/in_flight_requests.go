@@ -0,0 +1,137 @@
+package vault
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// InFlightRequest describes a request being handled by the core
+type InFlightRequest struct {
+	ID               string
+	Path             string
+	ClientRemoteAddr string
+	MountAccessor    string
+	StartTime        time.Time
+}
+
+// inFlightRequests tracks the requests being handled by the core, and counts
+// them by mount to enforce the maximum of in-flight requests of the mounts
+type inFlightRequests struct {
+	l        sync.Mutex
+	requests map[*InFlightRequest]struct{}
+	byMount  map[string]int
+}
+
+func newInFlightRequests() *inFlightRequests {
+	return &inFlightRequests{
+		requests: make(map[*InFlightRequest]struct{}),
+		byMount:  make(map[string]int),
+	}
+}
+
+// add registers the request to the mount of the entry, which may be nil. The
+// returned function must be called once the request is handled. An error is
+// returned if the mount already handles its maximum of in-flight requests.
+func (r *inFlightRequests) add(req *logical.Request, entry *MountEntry) (func(), error) {
+	ifr := &InFlightRequest{
+		ID:        req.ID,
+		Path:      req.Path,
+		StartTime: time.Now(),
+	}
+	if ifr.ID == "" {
+		ifr.ID, _ = uuid.GenerateUUID()
+	}
+	if req.Connection != nil {
+		ifr.ClientRemoteAddr = req.Connection.RemoteAddr
+	}
+
+	r.l.Lock()
+	defer r.l.Unlock()
+
+	if entry != nil {
+		ifr.MountAccessor = entry.Accessor
+		if max := entry.Config.MaxInFlightRequests; max > 0 && r.byMount[entry.Accessor] >= max {
+			return nil, logical.ErrInFlightRequestsExceeded
+		}
+		r.byMount[entry.Accessor]++
+	}
+	r.requests[ifr] = struct{}{}
+
+	return func() {
+		r.l.Lock()
+		defer r.l.Unlock()
+
+		delete(r.requests, ifr)
+		if ifr.MountAccessor == "" {
+			return
+		}
+		r.byMount[ifr.MountAccessor]--
+		if r.byMount[ifr.MountAccessor] <= 0 {
+			delete(r.byMount, ifr.MountAccessor)
+		}
+	}, nil
+}
+
+// list returns copies of the requests being handled
+func (r *inFlightRequests) list() []InFlightRequest {
+	r.l.Lock()
+	defer r.l.Unlock()
+
+	requests := make([]InFlightRequest, 0, len(r.requests))
+	for ifr := range r.requests {
+		requests = append(requests, *ifr)
+	}
+	return requests
+}
+
+// count returns the number of requests being handled by the mount of the
+// accessor
+func (r *inFlightRequests) count(accessor string) int {
+	r.l.Lock()
+	defer r.l.Unlock()
+
+	return r.byMount[accessor]
+}
+
+func inFlightRequestPaths(b *SystemBackend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "in-flight-req$",
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation: b.handleInFlightRequests,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["in-flight-req"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["in-flight-req"][1]),
+		},
+	}
+}
+
+// handleInFlightRequests lists the requests being handled, keyed by request ID
+func (b *SystemBackend) handleInFlightRequests(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	now := time.Now()
+	resp := &logical.Response{
+		Data: map[string]interface{}{},
+	}
+	for _, ifr := range b.Core.inFlightRequests.list() {
+		// The listing request is in flight itself
+		if ifr.ID == req.ID {
+			continue
+		}
+		resp.Data[ifr.ID] = map[string]interface{}{
+			"request_path":          ifr.Path,
+			"client_remote_address": ifr.ClientRemoteAddr,
+			"mount_accessor":        ifr.MountAccessor,
+			"start_time":            ifr.StartTime.Format(time.RFC3339Nano),
+			"duration":              now.Sub(ifr.StartTime).String(),
+		}
+	}
+	return resp, nil
+}
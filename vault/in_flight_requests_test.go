@@ -0,0 +1,65 @@
+package vault
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestCore_MaxInFlightRequests(t *testing.T) {
+	c, b, root := testCoreSystemBackend(t)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "mounts/secret/tune")
+	req.Data["max_in_flight_requests"] = 1
+	if _, err := b.HandleRequest(context.Background(), req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Hold the only slot of the mount with a request in flight
+	entry := c.router.MatchingMountEntry("secret/foo")
+	if entry == nil || entry.Config.MaxInFlightRequests != 1 {
+		t.Fatalf("bad: %#v", entry)
+	}
+	inFlight := &logical.Request{
+		ID:         "in-flight",
+		Path:       "secret/foo",
+		Connection: &logical.Connection{RemoteAddr: "127.0.0.1"},
+	}
+	done, err := c.inFlightRequests.add(inFlight, entry)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req = &logical.Request{
+		Operation:   logical.ReadOperation,
+		Path:        "secret/foo",
+		ClientToken: root,
+	}
+	if _, err := c.HandleRequest(req); err != logical.ErrInFlightRequestsExceeded {
+		t.Fatalf("expected the request to be rejected, got %v", err)
+	}
+
+	resp, err := b.HandleRequest(context.Background(), logical.TestRequest(t, logical.ReadOperation, "in-flight-req"))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	info, ok := resp.Data["in-flight"].(map[string]interface{})
+	if !ok || info["request_path"] != "secret/foo" || info["client_remote_address"] != "127.0.0.1" || info["mount_accessor"] != entry.Accessor {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	done()
+	if n := c.inFlightRequests.count(entry.Accessor); n != 0 {
+		t.Fatalf("expected no request in flight, got %d", n)
+	}
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "mounts/secret/tune")
+	req.Data["max_in_flight_requests"] = -1
+	if _, err := b.HandleRequest(context.Background(), req); err != logical.ErrInvalidRequest {
+		t.Fatalf("expected an invalid request, got %v", err)
+	}
+}
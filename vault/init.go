@@ -39,6 +39,12 @@ func (c *Core) Initialized(ctx context.Context) (bool, error) {
 		return false, nil
 	}
 
+	// The seal configuration is stored by the seal Vault was initialized
+	// with, which may still have to be checked against the configured seal
+	if err := c.CheckSealMigration(ctx); err != nil {
+		return false, err
+	}
+
 	// Verify the seal configuration
 	sealConf, err := c.seal.BarrierConfig(ctx)
 	if err != nil {
@@ -258,6 +264,18 @@ func (c *Core) UnsealWithStoredKeys(ctx context.Context) error {
 		return nil
 	}
 
+	if err := c.CheckSealMigration(ctx); err != nil {
+		c.logger.Error("core: checking for a pending seal migration failed", "error", err)
+		return &NonFatalError{Err: err}
+	}
+
+	// The stored keys of the previous seal are not used while a seal
+	// migration is pending, since unsealing must migrate
+	if c.SealMigrationPending() {
+		c.logger.Warn("core: seal migration pending, not unsealing with stored keys")
+		return nil
+	}
+
 	sealed, err := c.Sealed()
 	if err != nil {
 		c.logger.Error("core: error checking sealed status in auto-unseal", "error", err)
@@ -0,0 +1,302 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/helper/consts"
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+const (
+	// coreKeyRotationConfigPath is the path of the configuration of the
+	// automatic rotation of the barrier encryption key
+	coreKeyRotationConfigPath = "core/key-rotation-config"
+
+	// maxKeyRotationOperations is the largest number of encryptions allowed
+	// with a key, below the 2^32 limit of AES-GCM with random nonces
+	maxKeyRotationOperations = 3865470566
+
+	// minKeyRotationOperations and minKeyRotationInterval are the smallest
+	// thresholds of the automatic rotation
+	minKeyRotationOperations = 1000000
+	minKeyRotationInterval   = 24 * time.Hour
+)
+
+// keyRotationCheckInterval is how often the active node accounts for the
+// encryptions and checks whether the key is due for rotation. It's var so
+// that tests can lower it.
+var keyRotationCheckInterval = 10 * time.Second
+
+// KeyRotationConfig is the configuration of the automatic rotation of the
+// barrier encryption key, which is rotated once it was used for MaxOperations
+// encryptions or, if set, is older than Interval
+type KeyRotationConfig struct {
+	Disabled      bool          `json:"disabled"`
+	MaxOperations uint64        `json:"max_operations"`
+	Interval      time.Duration `json:"interval"`
+}
+
+// defaultKeyRotationConfig returns the configuration of the cores which were
+// never configured
+func defaultKeyRotationConfig() *KeyRotationConfig {
+	return &KeyRotationConfig{
+		MaxOperations: maxKeyRotationOperations,
+	}
+}
+
+// keyRotation holds the configuration of the automatic key rotation of the
+// active node and stops its check loop
+type keyRotation struct {
+	lock   sync.RWMutex
+	config *KeyRotationConfig
+	stopCh chan struct{}
+}
+
+// rotationDue returns the reason the key described by the info is due for
+// rotation, or an empty string if it is not
+func (r *KeyRotationConfig) rotationDue(info *KeyInfo, now time.Time) string {
+	switch {
+	case r.Disabled:
+		return ""
+	case r.MaxOperations > 0 && info.Encryptions >= r.MaxOperations:
+		return "max operations reached"
+	case r.Interval > 0 && !now.Before(info.InstallTime.Add(r.Interval)):
+		return "interval elapsed"
+	}
+	return ""
+}
+
+// setupKeyRotation loads the configuration of the automatic key rotation and
+// starts checking the active key periodically
+func (c *Core) setupKeyRotation(ctx context.Context) error {
+	config := defaultKeyRotationConfig()
+	raw, err := c.barrier.Get(ctx, coreKeyRotationConfigPath)
+	if err != nil {
+		return fmt.Errorf("failed to read key rotation config: %v", err)
+	}
+	if raw != nil {
+		if err := jsonutil.DecodeJSON(raw.Value, config); err != nil {
+			return fmt.Errorf("failed to decode key rotation config: %v", err)
+		}
+	}
+
+	c.keyRotation = &keyRotation{
+		config: config,
+		stopCh: make(chan struct{}),
+	}
+	go c.keyRotationLoop(ctx, c.keyRotation.stopCh)
+	return nil
+}
+
+// teardownKeyRotation stops the checks of the active key, after accounting
+// for the last encryptions
+func (c *Core) teardownKeyRotation() {
+	if c.keyRotation == nil {
+		return
+	}
+	close(c.keyRotation.stopCh)
+	c.keyRotation = nil
+
+	if err := c.barrier.PersistEncryptionCount(context.Background()); err != nil && err != ErrBarrierSealed {
+		c.logger.Error("core: failed to persist the encryption count", "error", err)
+	}
+}
+
+func (c *Core) keyRotationLoop(ctx context.Context, stopCh chan struct{}) {
+	ticker := time.NewTicker(keyRotationCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.checkKeyRotation(ctx)
+		case <-stopCh:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// checkKeyRotation accounts for the encryptions with the active key and
+// rotates it if it is due for rotation
+func (c *Core) checkKeyRotation(ctx context.Context) {
+	if err := c.barrier.PersistEncryptionCount(ctx); err != nil {
+		c.logger.Error("core: failed to persist the encryption count", "error", err)
+		return
+	}
+	if c.ReplicationState().HasState(consts.ReplicationPerformanceSecondary) {
+		return
+	}
+
+	c.stateLock.RLock()
+	defer c.stateLock.RUnlock()
+	if c.sealed || c.standby || c.keyRotation == nil {
+		return
+	}
+
+	info, err := c.barrier.ActiveKeyInfo()
+	if err != nil {
+		c.logger.Error("core: failed to read the active key info", "error", err)
+		return
+	}
+
+	c.keyRotation.lock.RLock()
+	reason := c.keyRotation.config.rotationDue(info, time.Now())
+	c.keyRotation.lock.RUnlock()
+	if reason == "" {
+		return
+	}
+
+	c.logger.Info("core: rotating encryption key automatically", "term", info.Term, "reason", reason)
+	if err := c.rotateBarrierKey(ctx); err != nil {
+		c.logger.Error("core: failed to rotate encryption key automatically", "error", err)
+	}
+}
+
+// rotateBarrierKey installs a new barrier encryption key
+func (c *Core) rotateBarrierKey(ctx context.Context) error {
+	// Rotate to the new term
+	newTerm, err := c.barrier.Rotate(ctx)
+	if err != nil {
+		c.logger.Error("core: failed to create new encryption key", "error", err)
+		return err
+	}
+	c.logger.Info("core: installed new encryption key", "term", newTerm)
+
+	// In HA mode, we need to an upgrade path for the standby instances
+	if c.ha != nil {
+		// Create the upgrade path to the new term
+		if err := c.barrier.CreateUpgrade(ctx, newTerm); err != nil {
+			c.logger.Error("core: failed to create new upgrade", "term", newTerm, "error", err)
+		}
+
+		// Schedule the destroy of the upgrade path
+		time.AfterFunc(keyRotateGracePeriod, func() {
+			if err := c.barrier.DestroyUpgrade(ctx, newTerm); err != nil {
+				c.logger.Error("core: failed to destroy upgrade", "term", newTerm, "error", err)
+			}
+		})
+	}
+
+	// Write to the canary path, which will force a synchronous truing during
+	// replication
+	if err := c.barrier.Put(ctx, &Entry{
+		Key:   coreKeyringCanaryPath,
+		Value: []byte(fmt.Sprintf("new-rotation-term-%d", newTerm)),
+	}); err != nil {
+		c.logger.Error("core: error saving keyring canary", "error", err)
+		return fmt.Errorf("failed to save keyring canary: %v", err)
+	}
+
+	return nil
+}
+
+// setKeyRotationConfig persists the configuration of the automatic key
+// rotation and applies it
+func (c *Core) setKeyRotationConfig(ctx context.Context, config *KeyRotationConfig) error {
+	entry, err := logical.StorageEntryJSON(coreKeyRotationConfigPath, config)
+	if err != nil {
+		return fmt.Errorf("failed to create key rotation config entry: %v", err)
+	}
+	if err := c.barrier.Put(ctx, &Entry{
+		Key:   entry.Key,
+		Value: entry.Value,
+	}); err != nil {
+		return fmt.Errorf("failed to save key rotation config: %v", err)
+	}
+
+	c.keyRotation.lock.Lock()
+	c.keyRotation.config = config
+	c.keyRotation.lock.Unlock()
+	return nil
+}
+
+func keyRotationPaths(b *SystemBackend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "rotate/config$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"enabled": &framework.FieldSchema{
+					Type:        framework.TypeBool,
+					Description: strings.TrimSpace(sysHelp["rotation-enabled"][0]),
+				},
+				"max_operations": &framework.FieldSchema{
+					Type:        framework.TypeInt,
+					Description: strings.TrimSpace(sysHelp["rotation-max-operations"][0]),
+				},
+				"interval": &framework.FieldSchema{
+					Type:        framework.TypeDurationSecond,
+					Description: strings.TrimSpace(sysHelp["rotation-interval"][0]),
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation:   b.handleKeyRotationConfigRead,
+				logical.UpdateOperation: b.handleKeyRotationConfigUpdate,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["rotation-config"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["rotation-config"][1]),
+		},
+	}
+}
+
+func (b *SystemBackend) handleKeyRotationConfigRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	// Only the active node rotates the key
+	if b.Core.keyRotation == nil {
+		return nil, consts.ErrPerformanceStandbyForward
+	}
+
+	b.Core.keyRotation.lock.RLock()
+	config := b.Core.keyRotation.config
+	b.Core.keyRotation.lock.RUnlock()
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"enabled":        !config.Disabled,
+			"max_operations": config.MaxOperations,
+			"interval":       int64(config.Interval.Seconds()),
+		},
+	}, nil
+}
+
+func (b *SystemBackend) handleKeyRotationConfigUpdate(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	if b.Core.keyRotation == nil {
+		return nil, consts.ErrPerformanceStandbyForward
+	}
+
+	b.Core.keyRotation.lock.RLock()
+	config := *b.Core.keyRotation.config
+	b.Core.keyRotation.lock.RUnlock()
+
+	if enabled, ok := d.GetOk("enabled"); ok {
+		config.Disabled = !enabled.(bool)
+	}
+	if maxOperations, ok := d.GetOk("max_operations"); ok {
+		ops := maxOperations.(int)
+		if ops < minKeyRotationOperations || ops > maxKeyRotationOperations {
+			return logical.ErrorResponse(fmt.Sprintf("max_operations must be between %d and %d", minKeyRotationOperations, maxKeyRotationOperations)), logical.ErrInvalidRequest
+		}
+		config.MaxOperations = uint64(ops)
+	}
+	if interval, ok := d.GetOk("interval"); ok {
+		config.Interval = time.Duration(interval.(int)) * time.Second
+		if config.Interval != 0 && config.Interval < minKeyRotationInterval {
+			return logical.ErrorResponse(fmt.Sprintf("interval must be zero or at least %s", minKeyRotationInterval)), logical.ErrInvalidRequest
+		}
+	}
+
+	if err := b.Core.setKeyRotationConfig(ctx, &config); err != nil {
+		b.Backend.Logger().Error("sys: failed to set key rotation config", "error", err)
+		return handleError(err)
+	}
+	return nil, nil
+}
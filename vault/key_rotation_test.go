@@ -0,0 +1,27 @@
+package vault
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKeyRotationConfig_rotationDue(t *testing.T) {
+	now := time.Now()
+	cases := []struct {
+		config *KeyRotationConfig
+		info   *KeyInfo
+		due    bool
+	}{
+		{defaultKeyRotationConfig(), &KeyInfo{Encryptions: 10, InstallTime: now.Add(-1000 * time.Hour)}, false},
+		{defaultKeyRotationConfig(), &KeyInfo{Encryptions: maxKeyRotationOperations, InstallTime: now}, true},
+		{&KeyRotationConfig{Disabled: true, MaxOperations: 10}, &KeyInfo{Encryptions: 20, InstallTime: now}, false},
+		{&KeyRotationConfig{MaxOperations: 10, Interval: 24 * time.Hour}, &KeyInfo{Encryptions: 5, InstallTime: now.Add(-time.Hour)}, false},
+		{&KeyRotationConfig{MaxOperations: 10, Interval: 24 * time.Hour}, &KeyInfo{Encryptions: 5, InstallTime: now.Add(-25 * time.Hour)}, true},
+	}
+
+	for i, tc := range cases {
+		if due := tc.config.rotationDue(tc.info, now) != ""; due != tc.due {
+			t.Fatalf("%d: expected due %t, got %t", i, tc.due, due)
+		}
+	}
+}
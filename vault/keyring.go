@@ -35,6 +35,10 @@ type Key struct {
 	Version     int
 	Value       []byte
 	InstallTime time.Time
+
+	// Encryptions is the estimated number of encryptions with the key, as of
+	// the last time the barrier accounted for them
+	Encryptions uint64
 }
 
 // Serialize is used to create a byte encoded key
@@ -119,6 +123,18 @@ func (k *Keyring) RemoveKey(term uint32) (*Keyring, error) {
 	return clone, nil
 }
 
+// AddEncryptions returns a copy of the keyring accounting for the given
+// number of additional encryptions with the active key
+func (k *Keyring) AddEncryptions(n uint64) *Keyring {
+	clone := k.Clone()
+	if active, ok := clone.keys[clone.activeTerm]; ok {
+		key := *active
+		key.Encryptions += n
+		clone.keys[clone.activeTerm] = &key
+	}
+	return clone
+}
+
 // ActiveTerm returns the currently active term
 func (k *Keyring) ActiveTerm() uint32 {
 	return k.activeTerm
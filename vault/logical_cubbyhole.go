@@ -19,7 +19,14 @@ func CubbyholeBackendFactory(ctx context.Context, conf *logical.BackendConfig) (
 
 		Paths: []*framework.Path{
 			&framework.Path{
-				Pattern: ".*",
+				Pattern: "(?P<path>.*)",
+
+				Fields: map[string]*framework.FieldSchema{
+					"path": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: "Specifies the path of the secret.",
+					},
+				},
 
 				Callbacks: map[logical.Operation]framework.OperationFunc{
 					logical.ReadOperation:   b.handleRead,
@@ -29,6 +36,11 @@ func CubbyholeBackendFactory(ctx context.Context, conf *logical.BackendConfig) (
 					logical.ListOperation:   b.handleList,
 				},
 
+				Properties: map[logical.Operation]logical.OperationProperties{
+					logical.ReadOperation: {ReadOnly: true},
+					logical.ListOperation: {ReadOnly: true},
+				},
+
 				ExistenceCheck: b.handleExistenceCheck,
 
 				HelpSynopsis:    strings.TrimSpace(cubbyholeHelpSynopsis),
@@ -162,8 +174,8 @@ func (b *CubbyholeBackend) handleList(ctx context.Context, req *logical.Request,
 		path = path + "/"
 	}
 
-	// List the keys at the prefix given by the request
-	keys, err := req.Storage.List(ctx, req.ClientToken+"/"+path)
+	// List the page of the keys at the prefix given by the request
+	keys, err := logical.ListPage(ctx, req.Storage, req.ClientToken+"/"+path, data.Get("after").(string), data.Get("limit").(int))
 	if err != nil {
 		return nil, err
 	}
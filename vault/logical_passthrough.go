@@ -41,7 +41,14 @@ func LeaseSwitchedPassthroughBackend(ctx context.Context, conf *logical.BackendC
 
 		Paths: []*framework.Path{
 			&framework.Path{
-				Pattern: ".*",
+				Pattern: "(?P<path>.*)",
+
+				Fields: map[string]*framework.FieldSchema{
+					"path": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: "Specifies the path of the secret.",
+					},
+				},
 
 				Callbacks: map[logical.Operation]framework.OperationFunc{
 					logical.ReadOperation:   b.handleRead,
@@ -51,6 +58,11 @@ func LeaseSwitchedPassthroughBackend(ctx context.Context, conf *logical.BackendC
 					logical.ListOperation:   b.handleList,
 				},
 
+				Properties: map[logical.Operation]logical.OperationProperties{
+					logical.ReadOperation: {ReadOnly: true},
+					logical.ListOperation: {ReadOnly: true},
+				},
+
 				ExistenceCheck: b.handleExistenceCheck,
 
 				HelpSynopsis:    strings.TrimSpace(passthroughHelpSynopsis),
@@ -186,6 +198,8 @@ func (b *PassthroughBackend) handleWrite(ctx context.Context, req *logical.Reque
 		return nil, fmt.Errorf("failed to write: %v", err)
 	}
 
+	b.sendEvent(ctx, logical.EventTypeKVWrite, req.Path)
+
 	return nil, nil
 }
 
@@ -195,9 +209,19 @@ func (b *PassthroughBackend) handleDelete(ctx context.Context, req *logical.Requ
 		return nil, err
 	}
 
+	b.sendEvent(ctx, logical.EventTypeKVDelete, req.Path)
+
 	return nil, nil
 }
 
+// sendEvent notifies the subscribers of the change of the secret. The change
+// is made even if the event cannot be published.
+func (b *PassthroughBackend) sendEvent(ctx context.Context, eventType, path string) {
+	if err := b.System().SendEvent(ctx, eventType, path, nil); err != nil {
+		b.Logger().Warn("kv: failed to publish event", "event_type", eventType, "error", err)
+	}
+}
+
 func (b *PassthroughBackend) handleList(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	// Right now we only handle directories, so ensure it ends with /; however,
 	// some physical backends may not handle the "/" case properly, so only add
@@ -207,8 +231,8 @@ func (b *PassthroughBackend) handleList(ctx context.Context, req *logical.Reques
 		path = path + "/"
 	}
 
-	// List the keys at the prefix given by the request
-	keys, err := req.Storage.List(ctx, path)
+	// List the page of the keys at the prefix given by the request
+	keys, err := logical.ListPage(ctx, req.Storage, path, data.Get("after").(string), data.Get("limit").(int))
 	if err != nil {
 		return nil, err
 	}
@@ -189,6 +189,27 @@ func TestPassthroughBackend_List(t *testing.T) {
 		if !reflect.DeepEqual(resp, expected) {
 			t.Fatalf("bad response.\n\nexpected: %#v\n\nGot: %#v", expected, resp)
 		}
+
+		for _, key := range []string{"bar", "baz/qux", "zip"} {
+			req = logical.TestRequest(t, logical.UpdateOperation, key)
+			req.Data["raw"] = "test"
+			req.Storage = storage
+			if _, err := b.HandleRequest(context.Background(), req); err != nil {
+				t.Fatalf("err: %v", err)
+			}
+		}
+
+		req = logical.TestRequest(t, logical.ListOperation, "")
+		req.Data["after"] = "bar"
+		req.Data["limit"] = 2
+		req.Storage = storage
+		resp, err = b.HandleRequest(context.Background(), req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if keys := resp.Data["keys"]; !reflect.DeepEqual(keys, []string{"baz/", "foo"}) {
+			t.Fatalf("bad keys: %#v", keys)
+		}
 	}
 	b := testPassthroughBackend()
 	test(b)
@@ -10,16 +10,21 @@ import (
 	"errors"
 	"fmt"
 	"hash"
+	"net/textproto"
+	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	uuid "github.com/hashicorp/go-uuid"
+	goversion "github.com/hashicorp/go-version"
 	"github.com/hashicorp/vault/helper/compressutil"
 	"github.com/hashicorp/vault/helper/consts"
 	"github.com/hashicorp/vault/helper/parseutil"
+	"github.com/hashicorp/vault/helper/strutil"
 	"github.com/hashicorp/vault/helper/wrapping"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
@@ -36,21 +41,17 @@ var (
 	}
 
 	replicationPaths = func(b *SystemBackend) []*framework.Path {
-		return []*framework.Path{
+		return append([]*framework.Path{
 			&framework.Path{
 				Pattern: "replication/status",
 				Callbacks: map[logical.Operation]framework.OperationFunc{
-					logical.ReadOperation: func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-						resp := &logical.Response{
-							Data: map[string]interface{}{
-								"mode": "disabled",
-							},
-						}
-						return resp, nil
-					},
+					logical.ReadOperation: b.handleReplicationStatus,
 				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["replication-status"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["replication-status"][1]),
 			},
-		}
+		}, drReplicationPaths(b)...)
 	}
 )
 
@@ -73,21 +74,31 @@ func NewSystemBackend(core *Core) *SystemBackend {
 				"raw/*",
 				"replication/primary/secondary-token",
 				"replication/reindex",
+				"replication/dr/primary/*",
+				"replication/dr/secondary/*",
 				"rotate",
+				"rotate/config",
 				"config/cors",
 				"config/auditing/*",
+				"config/cache",
 				"plugins/catalog/*",
 				"revoke-prefix/*",
 				"revoke-force/*",
 				"leases/revoke-prefix/*",
 				"leases/revoke-force/*",
 				"leases/lookup/*",
+				"leases/irrevocable",
+				"leases/tidy-irrevocable",
+				"debug/*",
+				"in-flight-req",
 			},
 
 			Unauthenticated: []string{
 				"wrapping/lookup",
 				"wrapping/pubkey",
 				"replication/status",
+				"replication/dr/status",
+				"internal/ui/mounts",
 			},
 		},
 
@@ -245,6 +256,38 @@ func NewSystemBackend(core *Core) *SystemBackend {
 						Type:        framework.TypeString,
 						Description: strings.TrimSpace(sysHelp["auth_desc"][0]),
 					},
+					"sync_external_groups": &framework.FieldSchema{
+						Type:        framework.TypeBool,
+						Description: strings.TrimSpace(sysHelp["sync_external_groups"][0]),
+					},
+					"token_type": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["token_type"][0]),
+					},
+					"audit_non_hmac_request_keys": &framework.FieldSchema{
+						Type:        framework.TypeCommaStringSlice,
+						Description: strings.TrimSpace(sysHelp["tune_audit_non_hmac_request_keys"][0]),
+					},
+					"audit_non_hmac_response_keys": &framework.FieldSchema{
+						Type:        framework.TypeCommaStringSlice,
+						Description: strings.TrimSpace(sysHelp["tune_audit_non_hmac_response_keys"][0]),
+					},
+					"listing_visibility": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["listing_visibility"][0]),
+					},
+					"passthrough_request_headers": &framework.FieldSchema{
+						Type:        framework.TypeCommaStringSlice,
+						Description: strings.TrimSpace(sysHelp["passthrough_request_headers"][0]),
+					},
+					"allowed_response_headers": &framework.FieldSchema{
+						Type:        framework.TypeCommaStringSlice,
+						Description: strings.TrimSpace(sysHelp["allowed_response_headers"][0]),
+					},
+					"max_in_flight_requests": &framework.FieldSchema{
+						Type:        framework.TypeInt,
+						Description: strings.TrimSpace(sysHelp["max_in_flight_requests"][0]),
+					},
 				},
 				Callbacks: map[logical.Operation]framework.OperationFunc{
 					logical.ReadOperation:   b.handleAuthTuneRead,
@@ -274,6 +317,38 @@ func NewSystemBackend(core *Core) *SystemBackend {
 						Type:        framework.TypeString,
 						Description: strings.TrimSpace(sysHelp["auth_desc"][0]),
 					},
+					"sync_external_groups": &framework.FieldSchema{
+						Type:        framework.TypeBool,
+						Description: strings.TrimSpace(sysHelp["sync_external_groups"][0]),
+					},
+					"token_type": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["token_type"][0]),
+					},
+					"audit_non_hmac_request_keys": &framework.FieldSchema{
+						Type:        framework.TypeCommaStringSlice,
+						Description: strings.TrimSpace(sysHelp["tune_audit_non_hmac_request_keys"][0]),
+					},
+					"audit_non_hmac_response_keys": &framework.FieldSchema{
+						Type:        framework.TypeCommaStringSlice,
+						Description: strings.TrimSpace(sysHelp["tune_audit_non_hmac_response_keys"][0]),
+					},
+					"listing_visibility": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["listing_visibility"][0]),
+					},
+					"passthrough_request_headers": &framework.FieldSchema{
+						Type:        framework.TypeCommaStringSlice,
+						Description: strings.TrimSpace(sysHelp["passthrough_request_headers"][0]),
+					},
+					"allowed_response_headers": &framework.FieldSchema{
+						Type:        framework.TypeCommaStringSlice,
+						Description: strings.TrimSpace(sysHelp["allowed_response_headers"][0]),
+					},
+					"max_in_flight_requests": &framework.FieldSchema{
+						Type:        framework.TypeInt,
+						Description: strings.TrimSpace(sysHelp["max_in_flight_requests"][0]),
+					},
 				},
 
 				Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -285,6 +360,46 @@ func NewSystemBackend(core *Core) *SystemBackend {
 				HelpDescription: strings.TrimSpace(sysHelp["mount_tune"][1]),
 			},
 
+			&framework.Path{
+				Pattern: "mounts/(?P<path>.+?)/restore$",
+
+				Fields: map[string]*framework.FieldSchema{
+					"path": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["mount_path"][0]),
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.UpdateOperation: b.handleMountRestore,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["mount_restore"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["mount_restore"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "mounts/(?P<path>.+?)/purge$",
+
+				Fields: map[string]*framework.FieldSchema{
+					"path": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["mount_path"][0]),
+					},
+					"force": &framework.FieldSchema{
+						Type:        framework.TypeBool,
+						Description: strings.TrimSpace(sysHelp["unmount_force"][0]),
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.UpdateOperation: b.handleMountPurge,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["mount_purge"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["mount_purge"][1]),
+			},
+
 			&framework.Path{
 				Pattern: "mounts/(?P<path>.+?)",
 
@@ -319,6 +434,14 @@ func NewSystemBackend(core *Core) *SystemBackend {
 						Type:        framework.TypeString,
 						Description: strings.TrimSpace(sysHelp["mount_plugin_name"][0]),
 					},
+					"recovery_window": &framework.FieldSchema{
+						Type:        framework.TypeDurationSecond,
+						Description: strings.TrimSpace(sysHelp["unmount_recovery_window"][0]),
+					},
+					"force": &framework.FieldSchema{
+						Type:        framework.TypeBool,
+						Description: strings.TrimSpace(sysHelp["unmount_force"][0]),
+					},
 				},
 
 				Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -341,6 +464,17 @@ func NewSystemBackend(core *Core) *SystemBackend {
 				HelpDescription: strings.TrimSpace(sysHelp["mounts"][1]),
 			},
 
+			&framework.Path{
+				Pattern: "internal/ui/mounts$",
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation: b.handleInternalUIMounts,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["internal-ui-mounts"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["internal-ui-mounts"][1]),
+			},
+
 			&framework.Path{
 				Pattern: "remount",
 
@@ -494,6 +628,68 @@ func NewSystemBackend(core *Core) *SystemBackend {
 				HelpDescription: strings.TrimSpace(sysHelp["tidy_leases"][1]),
 			},
 
+			&framework.Path{
+				Pattern: "leases/count$",
+
+				Fields: map[string]*framework.FieldSchema{
+					"type": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["leases-count-type"][0]),
+					},
+					"mount": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["leases-mount"][0]),
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation: b.handleLeaseCount,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["leases-count"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["leases-count"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "leases/irrevocable$",
+
+				Fields: map[string]*framework.FieldSchema{
+					"mount": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["leases-mount"][0]),
+					},
+					"limit": &framework.FieldSchema{
+						Type:        framework.TypeInt,
+						Description: strings.TrimSpace(sysHelp["leases-irrevocable-limit"][0]),
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation: b.handleIrrevocableLeases,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["leases-irrevocable"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["leases-irrevocable"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "leases/tidy-irrevocable$",
+
+				Fields: map[string]*framework.FieldSchema{
+					"mount": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["leases-mount"][0]),
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.UpdateOperation: b.handleTidyIrrevocableLeases,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["tidy-irrevocable"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["tidy-irrevocable"][1]),
+			},
+
 			&framework.Path{
 				Pattern: "auth$",
 
@@ -622,6 +818,59 @@ func NewSystemBackend(core *Core) *SystemBackend {
 				HelpDescription: strings.TrimSpace(sysHelp["policy"][1]),
 			},
 
+			&framework.Path{
+				Pattern: "policies/password/?$",
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ListOperation: b.handlePasswordPoliciesList,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["password-policy-list"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["password-policy-list"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "policies/password/(?P<name>.+)/generate$",
+
+				Fields: map[string]*framework.FieldSchema{
+					"name": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["password-policy-name"][0]),
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation: b.handlePasswordPoliciesGenerate,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["password-policy-generate"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["password-policy-generate"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "policies/password/(?P<name>.+)",
+
+				Fields: map[string]*framework.FieldSchema{
+					"name": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["password-policy-name"][0]),
+					},
+					"policy": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["password-policy-rules"][0]),
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation:   b.handlePasswordPoliciesRead,
+					logical.UpdateOperation: b.handlePasswordPoliciesSet,
+					logical.DeleteOperation: b.handlePasswordPoliciesDelete,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["password-policy"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["password-policy"][1]),
+			},
+
 			&framework.Path{
 				Pattern:         "seal-status$",
 				HelpSynopsis:    strings.TrimSpace(sysHelp["seal-status"][0]),
@@ -673,6 +922,42 @@ func NewSystemBackend(core *Core) *SystemBackend {
 				HelpDescription: strings.TrimSpace(sysHelp["audit-table"][1]),
 			},
 
+			&framework.Path{
+				Pattern: "audit/export$",
+
+				Fields: map[string]*framework.FieldSchema{
+					"source": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Default:     "audit",
+						Description: strings.TrimSpace(sysHelp["audit-export-source"][0]),
+					},
+					"device": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["audit-export-device"][0]),
+					},
+					"format": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Default:     "json",
+						Description: strings.TrimSpace(sysHelp["activity-export-format"][0]),
+					},
+					"start_time": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["audit-export-start-time"][0]),
+					},
+					"end_time": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["audit-export-end-time"][0]),
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation: b.handleAuditExport,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["audit-export"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["audit-export"][1]),
+			},
+
 			&framework.Path{
 				Pattern: "audit/(?P<path>.+)",
 
@@ -720,6 +1005,28 @@ func NewSystemBackend(core *Core) *SystemBackend {
 				HelpDescription: strings.TrimSpace(sysHelp["key-status"][1]),
 			},
 
+			&framework.Path{
+				Pattern: "ha-status$",
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation: b.handleHAStatus,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["ha-status"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["ha-status"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "internal/specs/openapi$",
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation: b.handleInternalOpenAPI,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["internal-specs-openapi"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["internal-specs-openapi"][1]),
+			},
+
 			&framework.Path{
 				Pattern: "rotate$",
 
@@ -784,6 +1091,10 @@ func NewSystemBackend(core *Core) *SystemBackend {
 					"token": &framework.FieldSchema{
 						Type: framework.TypeString,
 					},
+					"tokens": &framework.FieldSchema{
+						Type:        framework.TypeCommaStringSlice,
+						Description: "Wrapping tokens to rewrap in a batch, each into a new wrapping token.",
+					},
 				},
 
 				Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -827,6 +1138,35 @@ func NewSystemBackend(core *Core) *SystemBackend {
 				HelpDescription: strings.TrimSpace(sysHelp["audited-headers"][1]),
 			},
 
+			&framework.Path{
+				Pattern: "config/cache$",
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation: b.handleCacheStatsRead,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["config/cache"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["config/cache"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "metrics$",
+
+				Fields: map[string]*framework.FieldSchema{
+					"format": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["metrics-format"][0]),
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation: b.handleMetrics,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["metrics"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["metrics"][1]),
+			},
+
 			&framework.Path{
 				Pattern: "plugins/catalog/?$",
 
@@ -864,6 +1204,10 @@ func NewSystemBackend(core *Core) *SystemBackend {
 						Type:        framework.TypeStringSlice,
 						Description: strings.TrimSpace(sysHelp["plugin-catalog_args"][0]),
 					},
+					"version": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["plugin-catalog_version"][0]),
+					},
 				},
 
 				Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -968,6 +1312,15 @@ func NewSystemBackend(core *Core) *SystemBackend {
 		},
 	}
 
+	b.Backend.Paths = append(b.Backend.Paths, loginMFAPaths(b)...)
+	b.Backend.Paths = append(b.Backend.Paths, namespacePaths(b)...)
+	b.Backend.Paths = append(b.Backend.Paths, quotaPaths(b)...)
+	b.Backend.Paths = append(b.Backend.Paths, controlGroupPaths(b)...)
+	b.Backend.Paths = append(b.Backend.Paths, eventsPaths(b)...)
+	b.Backend.Paths = append(b.Backend.Paths, debugPaths(b)...)
+	b.Backend.Paths = append(b.Backend.Paths, keyRotationPaths(b)...)
+	b.Backend.Paths = append(b.Backend.Paths, activityLogPaths(b)...)
+	b.Backend.Paths = append(b.Backend.Paths, inFlightRequestPaths(b)...)
 	b.Backend.Paths = append(b.Backend.Paths, replicationPaths(b)...)
 
 	if core.rawEnabled {
@@ -1052,54 +1405,176 @@ func (b *SystemBackend) handleTidyLeases(ctx context.Context, req *logical.Reque
 	return nil, err
 }
 
-func (b *SystemBackend) invalidate(ctx context.Context, key string) {
-	/*
-		if b.Core.logger.IsTrace() {
-			b.Core.logger.Trace("sys: invalidating key", "key", key)
-		}
-	*/
-	switch {
-	case strings.HasPrefix(key, policyACLSubPath):
-		b.Core.stateLock.RLock()
-		defer b.Core.stateLock.RUnlock()
-		if b.Core.policyStore != nil {
-			b.Core.policyStore.invalidate(ctx, strings.TrimPrefix(key, policyACLSubPath), PolicyTypeACL)
-		}
-	case strings.HasPrefix(key, tokenSubPath):
-		b.Core.stateLock.RLock()
-		defer b.Core.stateLock.RUnlock()
-		if b.Core.tokenStore != nil {
-			b.Core.tokenStore.Invalidate(ctx, key)
-		}
+// leasesMountPrefix returns the lease ID prefix of the mount of the request,
+// relative to its namespace
+func leasesMountPrefix(ctx context.Context, data *framework.FieldData) (string, error) {
+	mount := data.Get("mount").(string)
+	if mount != "" && !strings.HasSuffix(mount, "/") {
+		mount = mount + "/"
 	}
-}
 
-func (b *SystemBackend) handlePluginCatalogList(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
-	plugins, err := b.Core.pluginCatalog.List(ctx)
-	if err != nil {
-		return nil, err
+	// Without a mount, the leases are filtered by namespace afterwards
+	ns := namespaceFromContext(ctx)
+	if ns.isRoot() || mount == "" {
+		return mount, nil
 	}
-
-	return logical.ListResponse(plugins), nil
+	if isNamespaceSharedPath(mount) {
+		return "", fmt.Errorf("cannot access the leases of shared paths in a namespace")
+	}
+	return namespaceRoutePath(ns, mount), nil
 }
 
-func (b *SystemBackend) handlePluginCatalogUpdate(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
-	pluginName := d.Get("name").(string)
-	if pluginName == "" {
-		return logical.ErrorResponse("missing plugin name"), nil
+// handleLeaseCount returns the number of leases, in total and per mount
+func (b *SystemBackend) handleLeaseCount(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	var irrevocable bool
+	switch leaseType := data.Get("type").(string); leaseType {
+	case "", "all":
+	case "irrevocable":
+		irrevocable = true
+	default:
+		return logical.ErrorResponse(fmt.Sprintf("invalid lease type %q", leaseType)), logical.ErrInvalidRequest
 	}
 
-	sha256 := d.Get("sha256").(string)
-	if sha256 == "" {
-		sha256 = d.Get("sha_256").(string)
-		if sha256 == "" {
-			return logical.ErrorResponse("missing SHA-256 value"), nil
-		}
+	prefix, err := leasesMountPrefix(ctx, data)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
 	}
 
-	command := d.Get("command").(string)
-	if command == "" {
-		return logical.ErrorResponse("missing command value"), nil
+	var total int
+	counts := make(map[string]interface{})
+	for _, leaseID := range b.Core.expiration.LeaseIDs(prefix, irrevocable) {
+		if !leaseInNamespace(ctx, leaseID) {
+			continue
+		}
+		mount := b.Core.router.MatchingMount(leaseID)
+		count, _ := counts[mount].(int)
+		counts[mount] = count + 1
+		total++
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"lease_count": total,
+			"counts":      counts,
+		},
+	}, nil
+}
+
+// handleIrrevocableLeases lists the irrevocable leases with the error of
+// their last revocation attempt
+func (b *SystemBackend) handleIrrevocableLeases(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	limit := data.Get("limit").(int)
+	if limit < 0 {
+		return logical.ErrorResponse("limit must not be negative"), logical.ErrInvalidRequest
+	}
+
+	prefix, err := leasesMountPrefix(ctx, data)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	var total int
+	leases := make([]interface{}, 0)
+	for _, le := range b.Core.expiration.IrrevocableLeases(prefix) {
+		if !leaseInNamespace(ctx, le.LeaseID) {
+			continue
+		}
+		total++
+		if limit > 0 && len(leases) == limit {
+			continue
+		}
+		leases = append(leases, map[string]interface{}{
+			"lease_id":    le.LeaseID,
+			"mount":       b.Core.router.MatchingMount(le.LeaseID),
+			"expire_time": le.ExpireTime,
+			"error":       le.RevokeErr,
+		})
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"lease_count": total,
+			"leases":      leases,
+		},
+	}, nil
+}
+
+// handleTidyIrrevocableLeases deletes the irrevocable leases without
+// attempting to revoke them again
+func (b *SystemBackend) handleTidyIrrevocableLeases(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	prefix, err := leasesMountPrefix(ctx, data)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	var leases []*leaseEntry
+	for _, le := range b.Core.expiration.IrrevocableLeases(prefix) {
+		if leaseInNamespace(ctx, le.LeaseID) {
+			leases = append(leases, le)
+		}
+	}
+
+	deleted, err := b.Core.expiration.TidyIrrevocable(leases)
+	if err != nil {
+		b.Backend.Logger().Error("sys: failed to tidy irrevocable leases", "prefix", prefix, "error", err)
+		return handleError(err)
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"lease_count": deleted,
+		},
+	}, nil
+}
+
+func (b *SystemBackend) invalidate(ctx context.Context, key string) {
+	/*
+		if b.Core.logger.IsTrace() {
+			b.Core.logger.Trace("sys: invalidating key", "key", key)
+		}
+	*/
+	switch {
+	case strings.HasPrefix(key, policyACLSubPath):
+		b.Core.stateLock.RLock()
+		defer b.Core.stateLock.RUnlock()
+		if b.Core.policyStore != nil {
+			b.Core.policyStore.invalidate(ctx, strings.TrimPrefix(key, policyACLSubPath), PolicyTypeACL)
+		}
+	case strings.HasPrefix(key, tokenSubPath):
+		b.Core.stateLock.RLock()
+		defer b.Core.stateLock.RUnlock()
+		if b.Core.tokenStore != nil {
+			b.Core.tokenStore.Invalidate(ctx, key)
+		}
+	}
+}
+
+func (b *SystemBackend) handlePluginCatalogList(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	plugins, err := b.Core.pluginCatalog.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return logical.ListResponse(plugins), nil
+}
+
+func (b *SystemBackend) handlePluginCatalogUpdate(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	pluginName := d.Get("name").(string)
+	if pluginName == "" {
+		return logical.ErrorResponse("missing plugin name"), nil
+	}
+
+	sha256 := d.Get("sha256").(string)
+	if sha256 == "" {
+		sha256 = d.Get("sha_256").(string)
+		if sha256 == "" {
+			return logical.ErrorResponse("missing SHA-256 value"), nil
+		}
+	}
+
+	command := d.Get("command").(string)
+	if command == "" {
+		return logical.ErrorResponse("missing command value"), nil
 	}
 
 	// For backwards compatibility, also accept args as part of command.  Don't
@@ -1119,7 +1594,12 @@ func (b *SystemBackend) handlePluginCatalogUpdate(ctx context.Context, req *logi
 		return logical.ErrorResponse("Could not decode SHA-256 value from Hex"), err
 	}
 
-	err = b.Core.pluginCatalog.Set(ctx, pluginName, parts[0], args, sha256Bytes)
+	pluginVersion := d.Get("version").(string)
+	if _, err := goversion.NewVersion(pluginVersion); pluginVersion != "" && err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("version must be a semantic version: %v", err)), nil
+	}
+
+	err = b.Core.pluginCatalog.Set(ctx, pluginName, pluginVersion, parts[0], args, sha256Bytes)
 	if err != nil {
 		return nil, err
 	}
@@ -1132,13 +1612,21 @@ func (b *SystemBackend) handlePluginCatalogRead(ctx context.Context, req *logica
 	if pluginName == "" {
 		return logical.ErrorResponse("missing plugin name"), nil
 	}
-	plugin, err := b.Core.pluginCatalog.Get(ctx, pluginName)
+	pluginVersion := d.Get("version").(string)
+	if _, err := goversion.NewVersion(pluginVersion); pluginVersion != "" && err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("version must be a semantic version: %v", err)), nil
+	}
+	plugin, err := b.Core.pluginCatalog.Get(ctx, pluginName, pluginVersion)
 	if err != nil {
 		return nil, err
 	}
 	if plugin == nil {
 		return nil, nil
 	}
+	versions, err := b.Core.pluginCatalog.ListVersions(ctx, pluginName)
+	if err != nil {
+		return nil, err
+	}
 
 	command := ""
 	if !plugin.Builtin {
@@ -1155,6 +1643,12 @@ func (b *SystemBackend) handlePluginCatalogRead(ctx context.Context, req *logica
 		"sha256":  hex.EncodeToString(plugin.Sha256),
 		"builtin": plugin.Builtin,
 	}
+	if plugin.Version != "" {
+		data["version"] = plugin.Version
+	}
+	if len(versions) > 0 {
+		data["versions"] = versions
+	}
 
 	return &logical.Response{
 		Data: data,
@@ -1166,7 +1660,11 @@ func (b *SystemBackend) handlePluginCatalogDelete(ctx context.Context, req *logi
 	if pluginName == "" {
 		return logical.ErrorResponse("missing plugin name"), nil
 	}
-	err := b.Core.pluginCatalog.Delete(ctx, pluginName)
+	pluginVersion := d.Get("version").(string)
+	if _, err := goversion.NewVersion(pluginVersion); pluginVersion != "" && err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("version must be a semantic version: %v", err)), nil
+	}
+	err := b.Core.pluginCatalog.Delete(ctx, pluginName, pluginVersion)
 	if err != nil {
 		return nil, err
 	}
@@ -1264,6 +1762,35 @@ func (b *SystemBackend) handleAuditedHeadersRead(ctx context.Context, req *logic
 	}, nil
 }
 
+// handleCacheStatsRead returns the usage of the physical cache
+func (b *SystemBackend) handleCacheStatsRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	stats := b.Core.physicalCache.Stats()
+
+	var hitRate float64
+	if total := stats.Hits + stats.Misses; total > 0 {
+		hitRate = float64(stats.Hits) / float64(total)
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"enabled":  stats.Enabled,
+			"size":     stats.Size,
+			"entries":  stats.Entries,
+			"hits":     stats.Hits,
+			"misses":   stats.Misses,
+			"hit_rate": hitRate,
+		},
+	}, nil
+}
+
+// handleMetrics returns the collected metrics in the requested format
+func (b *SystemBackend) handleMetrics(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	if b.Core.metricsHelper == nil {
+		return logical.ErrorResponse("metrics are not enabled"), logical.ErrInvalidRequest
+	}
+	return b.Core.metricsHelper.ResponseForFormat(d.Get("format").(string))
+}
+
 // handleCapabilities returns the ACL capabilities of the token for a given path
 func (b *SystemBackend) handleCapabilities(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
 	token := d.Get("token").(string)
@@ -1383,6 +1910,8 @@ func (b *SystemBackend) handleRekeyDeleteRecovery(ctx context.Context, req *logi
 
 // handleMountTable handles the "mounts" endpoint to provide the mount table
 func (b *SystemBackend) handleMountTable(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	ns := namespaceFromContext(ctx)
+
 	b.Core.mountsLock.RLock()
 	defer b.Core.mountsLock.RUnlock()
 
@@ -1391,6 +1920,11 @@ func (b *SystemBackend) handleMountTable(ctx context.Context, req *logical.Reque
 	}
 
 	for _, entry := range b.Core.mounts.Entries {
+		// Only the mounts of the namespace of the request are listed
+		if entry.NamespaceID != ns.ID {
+			continue
+		}
+
 		// Populate mount info
 		info := map[string]interface{}{
 			"type":        entry.Type,
@@ -1405,12 +1939,54 @@ func (b *SystemBackend) handleMountTable(ctx context.Context, req *logical.Reque
 			"local":     entry.Local,
 			"seal_wrap": entry.SealWrap,
 		}
-		resp.Data[entry.Path] = info
+		if entry.Config.PluginVersion != "" {
+			info["config"].(map[string]interface{})["plugin_version"] = entry.Config.PluginVersion
+		}
+		addMountTunables(info["config"].(map[string]interface{}), entry)
+		if entry.markedForDeletion() {
+			info["purge_time"] = entry.PurgeTime.Format(time.RFC3339)
+		}
+		resp.Data[namespaceRelativePath(ns, entry.Path)] = info
 	}
 
 	return resp, nil
 }
 
+// handleInternalUIMounts lists the secrets engines and auth methods of the
+// namespace of the request tuned to be listed to unauthenticated clients
+func (b *SystemBackend) handleInternalUIMounts(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	ns := namespaceFromContext(ctx)
+
+	listed := func(entries []*MountEntry) map[string]interface{} {
+		ret := make(map[string]interface{})
+		for _, entry := range entries {
+			if entry.NamespaceID != ns.ID || entry.Config.ListingVisibility != ListingVisibilityUnauth || entry.markedForDeletion() {
+				continue
+			}
+			ret[namespaceRelativePath(ns, entry.Path)] = map[string]interface{}{
+				"type":        entry.Type,
+				"description": entry.Description,
+			}
+		}
+		return ret
+	}
+
+	b.Core.mountsLock.RLock()
+	secretMounts := listed(b.Core.mounts.Entries)
+	b.Core.mountsLock.RUnlock()
+
+	b.Core.authLock.RLock()
+	authMounts := listed(b.Core.auth.Entries)
+	b.Core.authLock.RUnlock()
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"secret": secretMounts,
+			"auth":   authMounts,
+		},
+	}, nil
+}
+
 // handleMount is used to mount a new path
 func (b *SystemBackend) handleMount(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	repState := b.Core.ReplicationState()
@@ -1499,6 +2075,14 @@ func (b *SystemBackend) handleMount(ctx context.Context, req *logical.Request, d
 					"plugin_name must be provided for plugin backend"),
 				logical.ErrInvalidRequest
 		}
+		config.PluginVersion = apiConfig.PluginVersion
+
+	default:
+		if apiConfig.PluginVersion != "" {
+			return logical.ErrorResponse(
+					"plugin_version can only be set for plugin backend"),
+				logical.ErrInvalidRequest
+		}
 	}
 
 	// Copy over the force no cache if set
@@ -1506,15 +2090,21 @@ func (b *SystemBackend) handleMount(ctx context.Context, req *logical.Request, d
 		config.ForceNoCache = true
 	}
 
+	if err := setMountTunables(&config, &apiConfig); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
 	// Create the mount entry
+	ns := namespaceFromContext(ctx)
 	me := &MountEntry{
 		Table:       mountTableType,
-		Path:        path,
+		Path:        ns.Path + path,
 		Type:        logicalType,
 		Description: description,
 		Config:      config,
 		Local:       local,
 		SealWrap:    sealWrap,
+		NamespaceID: ns.ID,
 	}
 
 	// Attempt mount
@@ -1540,7 +2130,8 @@ func handleError(
 // handleUnmount is used to unmount a path
 func (b *SystemBackend) handleUnmount(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	path := data.Get("path").(string)
-	path = sanitizeMountPath(path)
+	ns := namespaceFromContext(ctx)
+	path = ns.Path + sanitizeMountPath(path)
 
 	repState := b.Core.ReplicationState()
 	entry := b.Core.router.MatchingMountEntry(path)
@@ -1551,12 +2142,26 @@ func (b *SystemBackend) handleUnmount(ctx context.Context, req *logical.Request,
 	// We return success when the mount does not exists to not expose if the
 	// mount existed or not
 	match := b.Core.router.MatchingMount(path)
-	if match == "" || path != match {
+	if match == "" || path != match || entry == nil || entry.NamespaceID != ns.ID {
 		return nil, nil
 	}
 
+	// Mark the mount for deletion if a recovery window is requested
+	if window := data.Get("recovery_window").(int); window > 0 {
+		purgeTime, err := b.Core.markMountForDeletion(ctx, path, time.Duration(window)*time.Second)
+		if err != nil {
+			b.Backend.Logger().Error("sys: marking mount for deletion failed", "path", path, "error", err)
+			return handleError(err)
+		}
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"purge_time": purgeTime.Format(time.RFC3339),
+			},
+		}, nil
+	}
+
 	// Attempt unmount
-	if err := b.Core.unmount(ctx, path); err != nil {
+	if err := b.Core.unmount(ctx, path, data.Get("force").(bool)); err != nil {
 		b.Backend.Logger().Error("sys: unmount failed", "path", path, "error", err)
 		return handleError(err)
 	}
@@ -1577,13 +2182,17 @@ func (b *SystemBackend) handleRemount(ctx context.Context, req *logical.Request,
 			logical.ErrInvalidRequest
 	}
 
-	fromPath = sanitizeMountPath(fromPath)
-	toPath = sanitizeMountPath(toPath)
+	ns := namespaceFromContext(ctx)
+	fromPath = ns.Path + sanitizeMountPath(fromPath)
+	toPath = ns.Path + sanitizeMountPath(toPath)
 
 	entry := b.Core.router.MatchingMountEntry(fromPath)
 	if entry != nil && !entry.Local && repState.HasState(consts.ReplicationPerformanceSecondary) {
 		return logical.ErrorResponse("cannot remount a non-local mount on a replication secondary"), nil
 	}
+	if entry != nil && entry.NamespaceID != ns.ID {
+		return logical.ErrorResponse(fmt.Sprintf("no matching mount at '%s'", namespaceRelativePath(ns, fromPath))), logical.ErrInvalidRequest
+	}
 
 	// Attempt remount
 	if err := b.Core.remount(ctx, fromPath, toPath); err != nil {
@@ -1602,7 +2211,7 @@ func (b *SystemBackend) handleAuthTuneRead(ctx context.Context, req *logical.Req
 				"path must be specified as a string"),
 			logical.ErrInvalidRequest
 	}
-	return b.handleTuneReadCommon("auth/" + path)
+	return b.handleTuneReadCommon(ctx, "auth/"+path)
 }
 
 // handleMountTuneRead is used to get config settings on a backend
@@ -1617,12 +2226,13 @@ func (b *SystemBackend) handleMountTuneRead(ctx context.Context, req *logical.Re
 	// This call will read both logical backend's configuration as well as auth methods'.
 	// Retaining this behavior for backward compatibility. If this behavior is not desired,
 	// an error can be returned if path has a prefix of "auth/".
-	return b.handleTuneReadCommon(path)
+	return b.handleTuneReadCommon(ctx, path)
 }
 
 // handleTuneReadCommon returns the config settings of a path
-func (b *SystemBackend) handleTuneReadCommon(path string) (*logical.Response, error) {
-	path = sanitizeMountPath(path)
+func (b *SystemBackend) handleTuneReadCommon(ctx context.Context, path string) (*logical.Response, error) {
+	ns := namespaceFromContext(ctx)
+	path = namespaceRoutePath(ns, sanitizeMountPath(path))
 
 	sysView := b.Core.router.MatchingSystemView(path)
 	if sysView == nil {
@@ -1631,7 +2241,7 @@ func (b *SystemBackend) handleTuneReadCommon(path string) (*logical.Response, er
 	}
 
 	mountEntry := b.Core.router.MatchingMountEntry(path)
-	if mountEntry == nil {
+	if mountEntry == nil || mountEntry.NamespaceID != ns.ID {
 		b.Backend.Logger().Error("sys: cannot fetch mount entry", "path", path)
 		return handleError(fmt.Errorf("sys: cannot fetch mount entry for path %s", path))
 	}
@@ -1644,6 +2254,12 @@ func (b *SystemBackend) handleTuneReadCommon(path string) (*logical.Response, er
 		},
 	}
 
+	if mountEntry.Table == credentialTableType {
+		resp.Data["sync_external_groups"] = mountEntry.Config.SyncExternalGroups
+		resp.Data["token_type"] = mountTokenType(mountEntry)
+	}
+	addMountTunables(resp.Data, mountEntry)
+
 	return resp, nil
 }
 
@@ -1684,8 +2300,11 @@ func (b *SystemBackend) handleTuneWriteCommon(ctx context.Context, path string,
 		}
 	}
 
+	ns := namespaceFromContext(ctx)
+	path = namespaceRoutePath(ns, path)
+
 	mountEntry := b.Core.router.MatchingMountEntry(path)
-	if mountEntry == nil {
+	if mountEntry == nil || mountEntry.NamespaceID != ns.ID {
 		b.Backend.Logger().Error("sys: tune failed: no mount entry found", "path", path)
 		return handleError(fmt.Errorf("sys: tune of path '%s' failed: no mount entry found", path))
 	}
@@ -1777,9 +2396,256 @@ func (b *SystemBackend) handleTuneWriteCommon(ctx context.Context, path string,
 		}
 	}
 
+	if syncExternalGroupsRaw, ok := data.GetOk("sync_external_groups"); ok {
+		syncExternalGroups := syncExternalGroupsRaw.(bool)
+		if syncExternalGroups && mountEntry.Table != credentialTableType {
+			return logical.ErrorResponse("sync_external_groups can only be set on auth mounts"), logical.ErrInvalidRequest
+		}
+
+		if syncExternalGroups != mountEntry.Config.SyncExternalGroups {
+			oldSyncExternalGroups := mountEntry.Config.SyncExternalGroups
+			mountEntry.Config.SyncExternalGroups = syncExternalGroups
+
+			// Update the mount table
+			if err := b.Core.persistAuth(ctx, b.Core.auth, mountEntry.Local); err != nil {
+				mountEntry.Config.SyncExternalGroups = oldSyncExternalGroups
+				return handleError(err)
+			}
+			if b.Core.logger.IsInfo() {
+				b.Core.logger.Info("core: mount tuning of sync_external_groups successful", "path", path)
+			}
+		}
+	}
+
+	if tokenTypeRaw, ok := data.GetOk("token_type"); ok {
+		if mountEntry.Table != credentialTableType {
+			return logical.ErrorResponse("token_type can only be set on auth mounts"), logical.ErrInvalidRequest
+		}
+		tokenType, err := parseMountTokenType(tokenTypeRaw.(string))
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+
+		if tokenType != mountEntry.Config.TokenType {
+			oldTokenType := mountEntry.Config.TokenType
+			mountEntry.Config.TokenType = tokenType
+
+			// Update the mount table
+			if err := b.Core.persistAuth(ctx, b.Core.auth, mountEntry.Local); err != nil {
+				mountEntry.Config.TokenType = oldTokenType
+				return handleError(err)
+			}
+			if b.Core.logger.IsInfo() {
+				b.Core.logger.Info("core: mount tuning of token_type successful", "path", path)
+			}
+		}
+	}
+
+	if rawVal, ok := data.GetOk("audit_non_hmac_request_keys"); ok {
+		keys := parseAuditNonHMACKeys(rawVal.([]string))
+		if err := b.tuneMountConfig(ctx, path, mountEntry, "audit_non_hmac_request_keys", func(config *MountConfig) {
+			config.AuditNonHMACRequestKeys = keys
+		}); err != nil {
+			return handleError(err)
+		}
+	}
+
+	if rawVal, ok := data.GetOk("audit_non_hmac_response_keys"); ok {
+		keys := parseAuditNonHMACKeys(rawVal.([]string))
+		if err := b.tuneMountConfig(ctx, path, mountEntry, "audit_non_hmac_response_keys", func(config *MountConfig) {
+			config.AuditNonHMACResponseKeys = keys
+		}); err != nil {
+			return handleError(err)
+		}
+	}
+
+	if rawVal, ok := data.GetOk("listing_visibility"); ok {
+		visibility, err := parseListingVisibility(rawVal.(string))
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		if err := b.tuneMountConfig(ctx, path, mountEntry, "listing_visibility", func(config *MountConfig) {
+			config.ListingVisibility = visibility
+		}); err != nil {
+			return handleError(err)
+		}
+	}
+
+	if rawVal, ok := data.GetOk("passthrough_request_headers"); ok {
+		headers, err := parsePassthroughRequestHeaders(rawVal.([]string))
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		if err := b.tuneMountConfig(ctx, path, mountEntry, "passthrough_request_headers", func(config *MountConfig) {
+			config.PassthroughRequestHeaders = headers
+		}); err != nil {
+			return handleError(err)
+		}
+	}
+
+	if rawVal, ok := data.GetOk("allowed_response_headers"); ok {
+		headers := parseMountHeaders(rawVal.([]string))
+		if err := b.tuneMountConfig(ctx, path, mountEntry, "allowed_response_headers", func(config *MountConfig) {
+			config.AllowedResponseHeaders = headers
+		}); err != nil {
+			return handleError(err)
+		}
+	}
+
+	if rawVal, ok := data.GetOk("max_in_flight_requests"); ok {
+		maxInFlight := rawVal.(int)
+		if maxInFlight < 0 {
+			return logical.ErrorResponse("max_in_flight_requests cannot be negative"), logical.ErrInvalidRequest
+		}
+		if err := b.tuneMountConfig(ctx, path, mountEntry, "max_in_flight_requests", func(config *MountConfig) {
+			config.MaxInFlightRequests = maxInFlight
+		}); err != nil {
+			return handleError(err)
+		}
+	}
+
 	return nil, nil
 }
 
+// tuneMountConfig applies a change to the configuration of the mount entry
+// and persists its mount table, reverting the change if that fails
+func (b *SystemBackend) tuneMountConfig(ctx context.Context, path string, entry *MountEntry, option string, apply func(*MountConfig)) error {
+	oldConfig := entry.Config
+	apply(&entry.Config)
+
+	var err error
+	switch entry.Table {
+	case credentialTableType:
+		err = b.Core.persistAuth(ctx, b.Core.auth, entry.Local)
+	default:
+		err = b.Core.persistMounts(ctx, b.Core.mounts, entry.Local)
+	}
+	if err != nil {
+		entry.Config = oldConfig
+		return err
+	}
+	if b.Core.logger.IsInfo() {
+		b.Core.logger.Info("core: mount tuning of "+option+" successful", "path", path)
+	}
+	return nil
+}
+
+// unpassableRequestHeaders are the request headers carrying the client
+// token, which are never passed to backends
+var unpassableRequestHeaders = []string{
+	"Authorization",
+	"X-Vault-Token",
+}
+
+// parseMountHeaders canonicalizes the names of the headers passed to or from
+// the backend of a mount, removing the duplicates
+func parseMountHeaders(headers []string) []string {
+	var ret []string
+	for _, header := range strutil.RemoveDuplicatesStable(headers, false) {
+		ret = strutil.AppendIfMissing(ret, textproto.CanonicalMIMEHeaderKey(header))
+	}
+	return ret
+}
+
+// parsePassthroughRequestHeaders validates the request headers passed to the
+// backend of a mount
+func parsePassthroughRequestHeaders(headers []string) ([]string, error) {
+	ret := parseMountHeaders(headers)
+	for _, header := range ret {
+		if strutil.StrListContains(unpassableRequestHeaders, header) {
+			return nil, fmt.Errorf("header %q cannot be passed through to backends", header)
+		}
+	}
+	return ret, nil
+}
+
+// parseAuditNonHMACKeys removes the duplicate keys of the data of a mount
+// which the audit devices do not HMAC
+func parseAuditNonHMACKeys(keys []string) []string {
+	ret := strutil.RemoveDuplicatesStable(keys, false)
+	if len(ret) == 0 {
+		return nil
+	}
+	return ret
+}
+
+// parseListingVisibility validates the listing visibility of a mount
+func parseListingVisibility(visibility string) (string, error) {
+	switch visibility {
+	case ListingVisibilityDefault, ListingVisibilityUnauth:
+		return visibility, nil
+	}
+	return "", fmt.Errorf("invalid listing visibility %q", visibility)
+}
+
+// setMountTunables sets the tunable options of the configuration of a new
+// mount from the configuration given to mount it
+func setMountTunables(config *MountConfig, apiConfig *APIMountConfig) error {
+	visibility, err := parseListingVisibility(apiConfig.ListingVisibility)
+	if err != nil {
+		return err
+	}
+	passthroughHeaders, err := parsePassthroughRequestHeaders(apiConfig.PassthroughRequestHeaders)
+	if err != nil {
+		return err
+	}
+
+	config.AuditNonHMACRequestKeys = parseAuditNonHMACKeys(apiConfig.AuditNonHMACRequestKeys)
+	config.AuditNonHMACResponseKeys = parseAuditNonHMACKeys(apiConfig.AuditNonHMACResponseKeys)
+	config.ListingVisibility = visibility
+	config.PassthroughRequestHeaders = passthroughHeaders
+	config.AllowedResponseHeaders = parseMountHeaders(apiConfig.AllowedResponseHeaders)
+
+	if apiConfig.MaxInFlightRequests < 0 {
+		return fmt.Errorf("max_in_flight_requests cannot be negative")
+	}
+	config.MaxInFlightRequests = apiConfig.MaxInFlightRequests
+	return nil
+}
+
+// addMountTunables adds the tunable options set on the mount to its
+// configuration as returned by the API
+func addMountTunables(config map[string]interface{}, entry *MountEntry) {
+	if len(entry.Config.AuditNonHMACRequestKeys) != 0 {
+		config["audit_non_hmac_request_keys"] = entry.Config.AuditNonHMACRequestKeys
+	}
+	if len(entry.Config.AuditNonHMACResponseKeys) != 0 {
+		config["audit_non_hmac_response_keys"] = entry.Config.AuditNonHMACResponseKeys
+	}
+	if entry.Config.ListingVisibility != ListingVisibilityDefault {
+		config["listing_visibility"] = entry.Config.ListingVisibility
+	}
+	if len(entry.Config.PassthroughRequestHeaders) != 0 {
+		config["passthrough_request_headers"] = entry.Config.PassthroughRequestHeaders
+	}
+	if len(entry.Config.AllowedResponseHeaders) != 0 {
+		config["allowed_response_headers"] = entry.Config.AllowedResponseHeaders
+	}
+	if entry.Config.MaxInFlightRequests != 0 {
+		config["max_in_flight_requests"] = entry.Config.MaxInFlightRequests
+	}
+}
+
+// parseMountTokenType validates the token type of an auth mount, returning
+// the empty string for service tokens
+func parseMountTokenType(tokenType string) (string, error) {
+	switch tokenType {
+	case "", TokenTypeService:
+		return "", nil
+	case TokenTypeBatch:
+		return TokenTypeBatch, nil
+	}
+	return "", fmt.Errorf("invalid token type %q", tokenType)
+}
+
+// mountTokenType returns the type of the tokens issued by the auth mount
+func mountTokenType(entry *MountEntry) string {
+	if entry.Config.TokenType == "" {
+		return TokenTypeService
+	}
+	return entry.Config.TokenType
+}
+
 // handleLease is use to view the metadata for a given LeaseID
 func (b *SystemBackend) handleLeaseLookup(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	leaseID := data.Get("lease_id").(string)
@@ -1787,6 +2653,9 @@ func (b *SystemBackend) handleLeaseLookup(ctx context.Context, req *logical.Requ
 		return logical.ErrorResponse("lease_id must be specified"),
 			logical.ErrInvalidRequest
 	}
+	if !leaseInNamespace(ctx, leaseID) {
+		return logical.ErrorResponse("invalid lease"), logical.ErrInvalidRequest
+	}
 
 	leaseTimes, err := b.Core.expiration.FetchLeaseTimes(leaseID)
 	if err != nil {
@@ -1844,7 +2713,10 @@ func (b *SystemBackend) handleRenew(ctx context.Context, req *logical.Request, d
 		return logical.ErrorResponse("lease_id must be specified"),
 			logical.ErrInvalidRequest
 	}
-	incrementRaw := data.Get("increment").(int)
+	if !leaseInNamespace(ctx, leaseID) {
+		return logical.ErrorResponse("invalid lease"), logical.ErrInvalidRequest
+	}
+	incrementRaw := data.Get("increment").(int)
 
 	// Convert the increment
 	increment := time.Duration(incrementRaw) * time.Second
@@ -1870,6 +2742,10 @@ func (b *SystemBackend) handleRevoke(ctx context.Context, req *logical.Request,
 			logical.ErrInvalidRequest
 	}
 
+	if !leaseInNamespace(ctx, leaseID) {
+		return logical.ErrorResponse("invalid lease"), logical.ErrInvalidRequest
+	}
+
 	// Invoke the expiration manager directly
 	if err := b.Core.expiration.Revoke(leaseID); err != nil {
 		b.Backend.Logger().Error("sys: lease revocation failed", "lease_id", leaseID, "error", err)
@@ -1880,20 +2756,29 @@ func (b *SystemBackend) handleRevoke(ctx context.Context, req *logical.Request,
 
 // handleRevokePrefix is used to revoke a prefix with many LeaseIDs
 func (b *SystemBackend) handleRevokePrefix(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	return b.handleRevokePrefixCommon(req, data, false)
+	return b.handleRevokePrefixCommon(ctx, req, data, false)
 }
 
 // handleRevokeForce is used to revoke a prefix with many LeaseIDs, ignoring errors
 func (b *SystemBackend) handleRevokeForce(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	return b.handleRevokePrefixCommon(req, data, true)
+	return b.handleRevokePrefixCommon(ctx, req, data, true)
 }
 
 // handleRevokePrefixCommon is used to revoke a prefix with many LeaseIDs
-func (b *SystemBackend) handleRevokePrefixCommon(
+func (b *SystemBackend) handleRevokePrefixCommon(ctx context.Context,
 	req *logical.Request, data *framework.FieldData, force bool) (*logical.Response, error) {
 	// Get all the options
 	prefix := data.Get("prefix").(string)
 
+	// The prefix is relative to the namespace of the request, whose leases
+	// are issued under the router paths of its mounts
+	if ns := namespaceFromContext(ctx); !ns.isRoot() {
+		if isNamespaceSharedPath(prefix) {
+			return logical.ErrorResponse("cannot revoke the leases of shared paths in a namespace"), logical.ErrInvalidRequest
+		}
+		prefix = namespaceRoutePath(ns, prefix)
+	}
+
 	// Invoke the expiration manager directly
 	var err error
 	if force {
@@ -1910,6 +2795,8 @@ func (b *SystemBackend) handleRevokePrefixCommon(
 
 // handleAuthTable handles the "auth" endpoint to provide the auth table
 func (b *SystemBackend) handleAuthTable(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	ns := namespaceFromContext(ctx)
+
 	b.Core.authLock.RLock()
 	defer b.Core.authLock.RUnlock()
 
@@ -1917,18 +2804,32 @@ func (b *SystemBackend) handleAuthTable(ctx context.Context, req *logical.Reques
 		Data: make(map[string]interface{}),
 	}
 	for _, entry := range b.Core.auth.Entries {
+		// Only the auth methods of the namespace of the request are listed
+		if entry.NamespaceID != ns.ID {
+			continue
+		}
+
 		info := map[string]interface{}{
 			"type":        entry.Type,
 			"description": entry.Description,
 			"accessor":    entry.Accessor,
 			"config": map[string]interface{}{
-				"default_lease_ttl": int64(entry.Config.DefaultLeaseTTL.Seconds()),
-				"max_lease_ttl":     int64(entry.Config.MaxLeaseTTL.Seconds()),
+				"default_lease_ttl":    int64(entry.Config.DefaultLeaseTTL.Seconds()),
+				"max_lease_ttl":        int64(entry.Config.MaxLeaseTTL.Seconds()),
+				"sync_external_groups": entry.Config.SyncExternalGroups,
+				"token_type":           mountTokenType(entry),
 			},
 			"local":     entry.Local,
 			"seal_wrap": entry.SealWrap,
 		}
-		resp.Data[entry.Path] = info
+		if entry.Config.PluginName != "" {
+			info["config"].(map[string]interface{})["plugin_name"] = entry.Config.PluginName
+		}
+		if entry.Config.PluginVersion != "" {
+			info["config"].(map[string]interface{})["plugin_version"] = entry.Config.PluginVersion
+		}
+		addMountTunables(info["config"].(map[string]interface{}), entry)
+		resp.Data[namespaceRelativePath(ns, entry.Path)] = info
 	}
 	return resp, nil
 }
@@ -1974,6 +2875,11 @@ func (b *SystemBackend) handleEnableAuth(ctx context.Context, req *logical.Reque
 					"plugin_name must be provided for plugin backend"),
 				logical.ErrInvalidRequest
 		}
+		config.PluginVersion = apiConfig.PluginVersion
+	} else if apiConfig.PluginVersion != "" {
+		return logical.ErrorResponse(
+				"plugin_version can only be set for plugin backend"),
+			logical.ErrInvalidRequest
 	}
 
 	if logicalType == "" {
@@ -1982,17 +2888,31 @@ func (b *SystemBackend) handleEnableAuth(ctx context.Context, req *logical.Reque
 			logical.ErrInvalidRequest
 	}
 
+	config.SyncExternalGroups = apiConfig.SyncExternalGroups
+
+	tokenType, err := parseMountTokenType(apiConfig.TokenType)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	config.TokenType = tokenType
+
+	if err := setMountTunables(&config, &apiConfig); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
 	path = sanitizeMountPath(path)
 
 	// Create the mount entry
+	ns := namespaceFromContext(ctx)
 	me := &MountEntry{
 		Table:       credentialTableType,
-		Path:        path,
+		Path:        ns.Path + path,
 		Type:        logicalType,
 		Description: description,
 		Config:      config,
 		Local:       local,
 		SealWrap:    sealWrap,
+		NamespaceID: ns.ID,
 	}
 
 	// Attempt enabling
@@ -2006,7 +2926,8 @@ func (b *SystemBackend) handleEnableAuth(ctx context.Context, req *logical.Reque
 // handleDisableAuth is used to disable a credential backend
 func (b *SystemBackend) handleDisableAuth(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	path := data.Get("path").(string)
-	path = sanitizeMountPath(path)
+	ns := namespaceFromContext(ctx)
+	path = ns.Path + sanitizeMountPath(path)
 
 	fullPath := credentialRoutePrefix + path
 
@@ -2019,7 +2940,7 @@ func (b *SystemBackend) handleDisableAuth(ctx context.Context, req *logical.Requ
 	// We return success when the mount does not exists to not expose if the
 	// mount existed or not
 	match := b.Core.router.MatchingMount(fullPath)
-	if match == "" || fullPath != match {
+	if match == "" || fullPath != match || entry == nil || entry.NamespaceID != ns.ID {
 		return nil, nil
 	}
 
@@ -2036,8 +2957,10 @@ func (b *SystemBackend) handlePolicyList(ctx context.Context, req *logical.Reque
 	// Get all the configured policies
 	policies, err := b.Core.policyStore.ListPolicies(ctx, PolicyTypeACL)
 
-	// Add the special "root" policy
-	policies = append(policies, "root")
+	// Add the special "root" policy, which only exists in the root namespace
+	if namespaceFromContext(ctx).isRoot() {
+		policies = append(policies, "root")
+	}
 	resp := logical.ListResponse(policies)
 
 	// Backwords compatibility
@@ -2055,8 +2978,11 @@ func (b *SystemBackend) handlePoliciesList(policyType PolicyType) framework.Oper
 
 		switch policyType {
 		case PolicyTypeACL:
-			// Add the special "root" policy if not egp
-			policies = append(policies, "root")
+			// Add the special "root" policy if not egp, which only exists in
+			// the root namespace
+			if namespaceFromContext(ctx).isRoot() {
+				policies = append(policies, "root")
+			}
 			return logical.ListResponse(policies), nil
 
 		}
@@ -2210,6 +3136,97 @@ func (b *SystemBackend) handlePolicyDelete(ctx context.Context, req *logical.Req
 	return nil, nil
 }
 
+// handlePasswordPoliciesList handles the "policies/password" endpoint to list
+// the password policies
+func (b *SystemBackend) handlePasswordPoliciesList(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	policies, err := b.Core.passwordPolicyView().List(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return logical.ListResponse(policies), nil
+}
+
+// handlePasswordPoliciesRead handles the "policies/password/<name>" endpoint
+// to read a password policy
+func (b *SystemBackend) handlePasswordPoliciesRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+
+	policy, err := b.Core.getPasswordPolicyEntry(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if policy == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"name":   name,
+			"policy": policy.Policy,
+		},
+	}, nil
+}
+
+// handlePasswordPoliciesSet handles the "policies/password/<name>" endpoint
+// to create or update a password policy
+func (b *SystemBackend) handlePasswordPoliciesSet(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+	if name == "" {
+		return logical.ErrorResponse("policy name must be provided in the URL"), nil
+	}
+
+	raw := data.Get("policy").(string)
+	if raw == "" {
+		return logical.ErrorResponse("'policy' parameter not supplied or empty"), nil
+	}
+
+	if polBytes, err := base64.StdEncoding.DecodeString(raw); err == nil {
+		raw = string(polBytes)
+	}
+
+	if err := b.Core.setPasswordPolicyEntry(ctx, name, &passwordPolicyEntry{Policy: raw}); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+	return nil, nil
+}
+
+// handlePasswordPoliciesDelete handles the "policies/password/<name>"
+// endpoint to delete a password policy
+func (b *SystemBackend) handlePasswordPoliciesDelete(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+
+	if err := b.Core.passwordPolicyView().Delete(ctx, name); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// handlePasswordPoliciesGenerate handles the "policies/password/<name>/generate"
+// endpoint to generate a password from a password policy
+func (b *SystemBackend) handlePasswordPoliciesGenerate(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+
+	policy, err := b.Core.getPasswordPolicyEntry(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if policy == nil {
+		return logical.ErrorResponse(fmt.Sprintf("password policy %q not found", name)), nil
+	}
+
+	password, err := b.Core.generatePasswordFromPolicy(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"password": password,
+		},
+	}, nil
+}
+
 // handleAuditTable handles the "audit" endpoint to provide the audit table
 func (b *SystemBackend) handleAuditTable(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	b.Core.auditLock.RLock()
@@ -2422,55 +3439,182 @@ func (b *SystemBackend) handleKeyStatus(ctx context.Context, req *logical.Reques
 		return nil, err
 	}
 
+	keyring, err := b.Core.barrier.Keyring()
+	if err != nil {
+		return nil, err
+	}
+	terms := make([]int, 0, len(keyring.keys))
+	for term := range keyring.keys {
+		terms = append(terms, int(term))
+	}
+	sort.Ints(terms)
+	keys := make([]map[string]interface{}, 0, len(terms))
+	for _, term := range terms {
+		key := keyring.keys[uint32(term)]
+		encryptions := key.Encryptions
+		if term == info.Term {
+			encryptions = info.Encryptions
+		}
+		keys = append(keys, map[string]interface{}{
+			"term":         term,
+			"install_time": key.InstallTime.Format(time.RFC3339Nano),
+			"encryptions":  encryptions,
+		})
+	}
+
 	resp := &logical.Response{
 		Data: map[string]interface{}{
 			"term":         info.Term,
 			"install_time": info.InstallTime.Format(time.RFC3339Nano),
+			"encryptions":  info.Encryptions,
+			"keys":         keys,
 		},
 	}
 	return resp, nil
 }
 
-// handleRotate is used to trigger a key rotation
-func (b *SystemBackend) handleRotate(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	repState := b.Core.ReplicationState()
-	if repState.HasState(consts.ReplicationPerformanceSecondary) {
-		return logical.ErrorResponse("cannot rotate on a replication secondary"), nil
+// handleHAStatus returns the active node and the standby nodes which sent a
+// heartbeat to it recently
+// handleInternalOpenAPI returns the OpenAPI document of the paths of the
+// mounts of the namespace of the request, generated from the help of their
+// backends
+func (b *SystemBackend) handleInternalOpenAPI(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	ns := namespaceFromContext(ctx)
+
+	type mountedBackend struct {
+		path    string
+		tag     string
+		backend logical.Backend
 	}
+	var backends []mountedBackend
 
-	// Rotate to the new term
-	newTerm, err := b.Core.barrier.Rotate(ctx)
-	if err != nil {
-		b.Backend.Logger().Error("sys: failed to create new encryption key", "error", err)
-		return handleError(err)
+	b.Core.mountsLock.RLock()
+	for _, entry := range b.Core.mounts.Entries {
+		if entry.NamespaceID != ns.ID {
+			continue
+		}
+		tag := "secrets"
+		switch entry.Type {
+		case "system":
+			tag = "system"
+		case "identity":
+			tag = "identity"
+		}
+		backends = append(backends, mountedBackend{
+			path:    b.Core.mountNamespaceRelativePath(entry, entry.Path),
+			tag:     tag,
+			backend: b.Core.router.MatchingBackend(entry.Path),
+		})
 	}
-	b.Backend.Logger().Info("sys: installed new encryption key")
+	b.Core.mountsLock.RUnlock()
 
-	// In HA mode, we need to an upgrade path for the standby instances
-	if b.Core.ha != nil {
-		// Create the upgrade path to the new term
-		if err := b.Core.barrier.CreateUpgrade(ctx, newTerm); err != nil {
-			b.Backend.Logger().Error("sys: failed to create new upgrade", "term", newTerm, "error", err)
+	b.Core.authLock.RLock()
+	for _, entry := range b.Core.auth.Entries {
+		if entry.NamespaceID != ns.ID {
+			continue
+		}
+		backends = append(backends, mountedBackend{
+			path:    credentialRoutePrefix + b.Core.mountNamespaceRelativePath(entry, entry.Path),
+			tag:     "auth",
+			backend: b.Core.router.MatchingBackend(credentialRoutePrefix + entry.Path),
+		})
+	}
+	b.Core.authLock.RUnlock()
+
+	doc := framework.NewOASDocument()
+	for _, mounted := range backends {
+		if mounted.backend == nil {
+			continue
+		}
+		resp, err := mounted.backend.HandleRequest(ctx, &logical.Request{
+			Operation: logical.HelpOperation,
+			Storage:   req.Storage,
+		})
+		if err != nil {
+			b.Core.logger.Warn("core: failed to get the help of a mount", "path", mounted.path, "error", err)
+			continue
+		}
+		backendDoc, err := framework.NewOASDocumentFromResponse(resp)
+		if err != nil {
+			b.Core.logger.Warn("core: invalid OpenAPI document of a mount", "path", mounted.path, "error", err)
+			continue
+		}
+		if backendDoc == nil {
+			continue
 		}
 
-		// Schedule the destroy of the upgrade path
-		time.AfterFunc(keyRotateGracePeriod, func() {
-			if err := b.Core.barrier.DestroyUpgrade(ctx, newTerm); err != nil {
-				b.Backend.Logger().Error("sys: failed to destroy upgrade", "term", newTerm, "error", err)
+		// The operations are tagged with the table of the mount, or its type
+		// for the system and identity mounts
+		for path, item := range backendDoc.Paths {
+			for _, op := range []*framework.OASOperation{item.Get, item.Post, item.Delete} {
+				if op != nil {
+					op.Tags = []string{mounted.tag}
+				}
 			}
+			doc.Paths["/"+mounted.path+strings.TrimPrefix(path, "/")] = item
+		}
+	}
+
+	buf, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPStatusCode:  200,
+			logical.HTTPContentType: "application/json",
+			logical.HTTPRawBody:     buf,
+		},
+	}, nil
+}
+
+func (b *SystemBackend) handleHAStatus(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	_, apiAddr, clusterAddr, err := b.Core.Leader()
+	if err == ErrHANotEnabled {
+		return logical.ErrorResponse("high availability is not enabled"), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	hostname, _ := os.Hostname()
+	nodes := []map[string]interface{}{
+		{
+			"hostname":        hostname,
+			"api_address":     apiAddr,
+			"cluster_address": clusterAddr,
+			"active_node":     true,
+			"last_echo":       nil,
+		},
+	}
+	for _, peer := range b.Core.GetHAPeerNodesCached() {
+		nodes = append(nodes, map[string]interface{}{
+			"hostname":        peer.Hostname,
+			"api_address":     peer.APIAddress,
+			"cluster_address": peer.ClusterAddress,
+			"active_node":     false,
+			"last_echo":       peer.LastEcho.Format(time.RFC3339Nano),
 		})
 	}
 
-	// Write to the canary path, which will force a synchronous truing during
-	// replication
-	if err := b.Core.barrier.Put(ctx, &Entry{
-		Key:   coreKeyringCanaryPath,
-		Value: []byte(fmt.Sprintf("new-rotation-term-%d", newTerm)),
-	}); err != nil {
-		b.Core.logger.Error("core: error saving keyring canary", "error", err)
-		return nil, fmt.Errorf("failed to save keyring canary: %v", err)
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"nodes": nodes,
+		},
+	}, nil
+}
+
+// handleRotate is used to trigger a key rotation
+func (b *SystemBackend) handleRotate(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	repState := b.Core.ReplicationState()
+	if repState.HasState(consts.ReplicationPerformanceSecondary) {
+		return logical.ErrorResponse("cannot rotate on a replication secondary"), nil
 	}
 
+	if err := b.Core.rotateBarrierKey(ctx); err != nil {
+		return handleError(err)
+	}
 	return nil, nil
 }
 
@@ -2538,6 +3682,8 @@ func (b *SystemBackend) handleWrappingUnwrap(ctx context.Context, req *logical.R
 	switch te.Policies[0] {
 	case responseWrappingPolicyName:
 		response, err = b.responseWrappingUnwrap(ctx, token, thirdParty)
+	case controlGroupPolicyName:
+		response, err = b.controlGroupUnwrap(ctx, token)
 	}
 	if err != nil {
 		var respErr *logical.Response
@@ -2662,6 +3808,10 @@ func (b *SystemBackend) handleWrappingLookup(ctx context.Context, req *logical.R
 }
 
 func (b *SystemBackend) handleWrappingRewrap(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	if tokens := data.Get("tokens").([]string); len(tokens) > 0 {
+		return b.handleWrappingRewrapBatch(ctx, req, tokens)
+	}
+
 	// If a third party is rewrapping (rather than the calling token being the
 	// wrapping token) we detect this so that we can revoke the original
 	// wrapping token after reading it. Right now wrapped tokens can't unwrap
@@ -2676,6 +3826,80 @@ func (b *SystemBackend) handleWrappingRewrap(ctx context.Context, req *logical.R
 		token = req.ClientToken
 	}
 
+	return b.rewrapResponse(ctx, token, thirdParty)
+}
+
+// handleWrappingRewrapBatch rewraps each of the wrapping tokens into a new
+// wrapping token, with the same TTL and creation path, and returns the
+// wrapping information of the new tokens in the order of the tokens. All the
+// tokens are validated before any of them is rewrapped.
+func (b *SystemBackend) handleWrappingRewrapBatch(ctx context.Context, req *logical.Request, tokens []string) (*logical.Response, error) {
+	// The new wrapping tokens replace the response
+	if req.WrapInfo != nil && req.WrapInfo.TTL != 0 {
+		return logical.ErrorResponse("the response of a batch rewrap cannot be wrapped"), logical.ErrInvalidRequest
+	}
+
+	ids := make([]string, len(tokens))
+	for i, token := range tokens {
+		id, err := b.Core.wrappingTokenID(token)
+		if err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("error validating wrapping token %d: %v", i, err)), logical.ErrInvalidRequest
+		}
+		valid, err := b.Core.checkWrappingToken(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("error validating wrapping token %d: %v", i, err)
+		}
+		if !valid {
+			return logical.ErrorResponse(fmt.Sprintf("wrapping token %d is not valid or does not exist", i)), logical.ErrInvalidRequest
+		}
+		ids[i] = id
+	}
+
+	// The wrapping code stores the responses of the rewraps as they are
+	rewrapReq := &logical.Request{
+		ID:        req.ID,
+		Operation: logical.UpdateOperation,
+		Path:      "sys/wrapping/rewrap",
+	}
+
+	wrapInfos := make([]map[string]interface{}, 0, len(ids))
+	for i, id := range ids {
+		resp, err := b.rewrapResponse(ctx, id, true)
+		if err != nil {
+			return nil, fmt.Errorf("error rewrapping wrapping token %d: %v", i, err)
+		}
+		if resp.IsError() {
+			return resp, nil
+		}
+
+		cubbyResp, err := b.Core.wrapInCubbyhole(ctx, rewrapReq, resp, nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("error rewrapping wrapping token %d: %v", i, err)
+		}
+		if cubbyResp != nil {
+			return cubbyResp, nil
+		}
+
+		wrapInfos = append(wrapInfos, map[string]interface{}{
+			"token":         resp.WrapInfo.Token,
+			"accessor":      resp.WrapInfo.Accessor,
+			"ttl":           int64(resp.WrapInfo.TTL.Seconds()),
+			"creation_time": resp.WrapInfo.CreationTime.Format(time.RFC3339Nano),
+			"creation_path": resp.WrapInfo.CreationPath,
+		})
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"wrap_infos": wrapInfos,
+		},
+	}, nil
+}
+
+// rewrapResponse reads the wrapped response of a wrapping token and returns
+// it with the wrapping information needed to wrap it again. The wrapping
+// token is used, and revoked if a third party is rewrapping it.
+func (b *SystemBackend) rewrapResponse(ctx context.Context, token string, thirdParty bool) (*logical.Response, error) {
 	if thirdParty {
 		// Use the token to decrement the use count to avoid a second operation on the token.
 		_, err := b.Core.tokenStore.UseTokenByID(ctx, token)
@@ -3030,6 +4254,40 @@ in the plugin catalog.`,
 		`The max lease TTL for this mount.`,
 	},
 
+	"unmount_recovery_window": {
+		`The duration, in seconds, the mount is kept for recovery before it is
+purged when unmounting it. The mount is purged immediately if unset.`,
+	},
+
+	"unmount_force": {
+		`Whether to ignore the errors revoking the leases of the mount when
+unmounting it. This is a DANGEROUS operation as it removes Vault's oversight
+of the external secrets of the mount.`,
+	},
+
+	"mount_restore": {
+		"Restore a mount marked for deletion.",
+		`
+This path responds to the following HTTP methods.
+
+    POST /sys/mounts/<path>/restore
+        Puts back into service the mount at the path, marked for deletion
+        by an unmount with a recovery window. Its data and its leases
+        which did not expire are left intact.
+		`,
+	},
+
+	"mount_purge": {
+		"Purge a mount marked for deletion.",
+		`
+This path responds to the following HTTP methods.
+
+    POST /sys/mounts/<path>/purge
+        Unmounts the mount at the path, marked for deletion by an unmount
+        with a recovery window, without waiting for the window to elapse.
+		`,
+	},
+
 	"remount": {
 		"Move the mount point of an already-mounted backend.",
 		`
@@ -3158,63 +4416,251 @@ Example: you might have an OAuth backend for GitHub, and one for Google Apps.
 		"",
 	},
 
+	"sync_external_groups": {
+		`Whether logins through this auth mount create or link the external groups
+of the group aliases returned by the auth method.`,
+	},
+
+	"token_type": {
+		`The type of the tokens issued by logins through this auth mount, either
+"service" or "batch". Defaults to "service".`,
+	},
+
 	"auth_config": {
 		`Configuration for this mount, such as plugin_name.`,
 	},
 
-	"auth_plugin": {
-		`Name of the auth plugin to use based from the name in the plugin catalog.`,
-		"",
+	"internal-ui-mounts": {
+		"Information about the mounts listed to unauthenticated clients.",
+		`
+This path lists the secrets engines and auth methods of the namespace which
+were tuned with a listing visibility of "unauth", so that unauthenticated
+clients such as the UI can present them. Only their type and description are
+returned.
+		`,
 	},
 
-	"policy-list": {
-		`List the configured access control policies.`,
-		`
-This path responds to the following HTTP methods.
+	"tune_audit_non_hmac_request_keys": {
+		`The list of keys of the request data of this mount which the audit
+devices log without HMAC'ing their values.`,
+	},
 
-    GET /
-        List the names of the configured access control policies.
+	"tune_audit_non_hmac_response_keys": {
+		`The list of keys of the response data of this mount which the audit
+devices log without HMAC'ing their values.`,
+	},
 
-    GET /<name>
-        Retrieve the rules for the named policy.
+	"listing_visibility": {
+		`Whether this mount is listed by the unauthenticated sys/internal/ui/mounts
+endpoint, "unauth" to list it. Defaults to hidden.`,
+	},
 
-    PUT /<name>
-        Add or update a policy.
+	"passthrough_request_headers": {
+		`The list of headers of the requests passed to the backend of this mount.
+Other headers are hidden from it.`,
+	},
 
-    DELETE /<name>
-        Delete the policy with the given name.
+	"allowed_response_headers": {
+		`The list of headers of the responses of the backend of this mount which
+are sent to the clients.`,
+	},
+
+	"in-flight-req": {
+		"Lists the requests being handled.",
+		`
+Lists the requests being handled by the node, keyed by request ID, with their
+path, the remote address of their client, the accessor of their mount, and
+when their handling started.
 		`,
 	},
 
-	"policy": {
-		`Read, Modify, or Delete an access control policy.`,
+	"replication-status": {
+		"Returns the status of the replication.",
 		`
-Read the rules of an existing policy, create or update the rules of a policy,
-or delete a policy.
+Returns the status of the replication of the cluster. Only DR replication is
+available, performance replication is always disabled.
 		`,
 	},
 
-	"policy-name": {
-		`The name of the policy. Example: "ops"`,
-		"",
+	"replication-dr-status": {
+		"Returns the status of the DR replication.",
+		`
+Returns the mode of the cluster in the DR replication set. Primaries report the
+index of their WAL and their secondaries; secondaries report the state of the
+stream from their primary and how far they lag behind it.
+		`,
 	},
 
-	"policy-rules": {
-		`The rules of the policy. Either given in HCL or JSON format.`,
-		"",
+	"replication-dr-primary-enable": {
+		"Makes the cluster a DR primary.",
+		`
+Makes the cluster a DR primary, which streams the changes of its storage to the
+secondaries activated with its secondary tokens over the cluster port.
+		`,
 	},
 
-	"audit-hash": {
-		"The hash of the given string via the given audit backend",
-		"",
+	"replication-dr-primary-cluster-addr": {
+		`The cluster address the secondaries connect to. Defaults to the cluster
+address of the active node.`,
 	},
 
-	"audit-table": {
-		"List the currently enabled audit backends.",
+	"replication-dr-primary-demote": {
+		"Demotes the DR primary to a DR secondary.",
 		`
-This path responds to the following HTTP methods.
+Demotes the DR primary to a DR secondary without a primary, keeping its
+storage, so that another cluster can be promoted in its place. The secondaries
+of the primary are disconnected.
+		`,
+	},
 
-    GET /
+	"replication-dr-primary-disable": {
+		"Disables the DR replication of the primary.",
+		`
+Disables the DR replication of the primary. Its secondaries are disconnected
+and forgotten; they must be activated again with new secondary tokens if the
+replication is enabled again.
+		`,
+	},
+
+	"replication-dr-secondary-id": {
+		`The identifier of the secondary.`,
+	},
+
+	"replication-dr-primary-secondary-token": {
+		"Generates the activation token of a new DR secondary.",
+		`
+Issues the credentials of a new DR secondary, returned wrapped in the
+activation token given to the secondary when it is enabled. The identifier of
+the secondary must be unique.
+		`,
+	},
+
+	"replication-dr-primary-revoke-secondary": {
+		"Revokes the credentials of a DR secondary.",
+		`
+Revokes the credentials of the DR secondary and closes its stream, so that it
+can't connect to the primary anymore.
+		`,
+	},
+
+	"replication-dr-secondary-enable": {
+		"Makes the cluster a DR secondary.",
+		`
+Makes the cluster a DR secondary of the primary which generated the activation
+token. The storage of the cluster is replaced by the one of the primary, and
+the cluster stops serving requests until it is promoted.
+		`,
+	},
+
+	"max_in_flight_requests": {
+		`The maximum number of requests to this mount handled at once, beyond
+which requests are rejected. Zero, the default, sets no limit.`,
+	},
+
+	"auth_plugin": {
+		`Name of the auth plugin to use based from the name in the plugin catalog.`,
+		"",
+	},
+
+	"policy-list": {
+		`List the configured access control policies.`,
+		`
+This path responds to the following HTTP methods.
+
+    GET /
+        List the names of the configured access control policies.
+
+    GET /<name>
+        Retrieve the rules for the named policy.
+
+    PUT /<name>
+        Add or update a policy.
+
+    DELETE /<name>
+        Delete the policy with the given name.
+		`,
+	},
+
+	"policy": {
+		`Read, Modify, or Delete an access control policy.`,
+		`
+Read the rules of an existing policy, create or update the rules of a policy,
+or delete a policy.
+		`,
+	},
+
+	"policy-name": {
+		`The name of the policy. Example: "ops"`,
+		"",
+	},
+
+	"policy-rules": {
+		`The rules of the policy. Either given in HCL or JSON format.`,
+		"",
+	},
+
+	"password-policy-list": {
+		`List the configured password policies.`,
+		`
+This path responds to the following HTTP methods.
+
+    LIST /
+        List the names of the configured password policies.
+
+    GET /<name>
+        Retrieve the definition of the named password policy.
+
+    PUT /<name>
+        Add or update a password policy.
+
+    DELETE /<name>
+        Delete the password policy with the given name.
+
+    GET /<name>/generate
+        Generate a password from the named password policy.
+		`,
+	},
+
+	"password-policy": {
+		`Read, Modify, or Delete a password policy.`,
+		`
+Read the definition of an existing password policy, create or update a password
+policy, or delete a password policy. Secrets engines reference password policies
+by name to generate the passwords of their credentials.
+		`,
+	},
+
+	"password-policy-name": {
+		`The name of the password policy. Example: "alphanumeric"`,
+		"",
+	},
+
+	"password-policy-rules": {
+		`The HCL definition of the password policy, made of a length and of
+charset rules requiring a minimum number of characters of a character set.`,
+		"",
+	},
+
+	"password-policy-generate": {
+		`Generate a password from a password policy.`,
+		`
+Generate a password of the length of the password policy made of the characters
+of its charset rules, containing at least the minimum number of characters of
+each rule.
+		`,
+	},
+
+	"audit-hash": {
+		"The hash of the given string via the given audit backend",
+		"",
+	},
+
+	"audit-table": {
+		"List the currently enabled audit backends.",
+		`
+This path responds to the following HTTP methods.
+
+    GET /
         List the currently enabled audit backends.
 
     PUT /<path>
@@ -3225,6 +4671,39 @@ This path responds to the following HTTP methods.
 		`,
 	},
 
+	"audit-export": {
+		"Export the captured audit entries or the activity records.",
+		`
+Export the entries captured by an audit device enabled with the "capture"
+option, or the activity records with source=activity, logged between
+start_time and end_time. The export is made of JSON lines, or of CSV with
+format=csv.
+		`,
+	},
+
+	"audit-export-source": {
+		`The source of the export, "audit" for the captured audit entries or
+"activity" for the activity records. Defaults to "audit".`,
+		"",
+	},
+
+	"audit-export-device": {
+		`The path of the capturing audit device to export, which can be omitted
+when a single device captures its entries.`,
+		"",
+	},
+
+	"audit-export-start-time": {
+		`The RFC3339 time from which the entries are exported. Defaults to the
+oldest entry kept.`,
+		"",
+	},
+
+	"audit-export-end-time": {
+		"The RFC3339 time until which the entries are exported. Defaults to now.",
+		"",
+	},
+
 	"audit_path": {
 		`The name of the backend. Cannot be delimited. Example: "mysql"`,
 		"",
@@ -3255,7 +4734,28 @@ Enable a new audit backend or disable an existing backend.
 	"key-status": {
 		"Provides information about the backend encryption key.",
 		`
-		Provides the current backend encryption key term and installation time.
+		Provides the current backend encryption key term, installation time
+		and estimated number of encryptions, along with the installation time
+		and estimated number of encryptions of every key of the keyring.
+		`,
+	},
+
+	"ha-status": {
+		"Provides information about the nodes of the cluster.",
+		`
+		Provides the hostname and the addresses of the active node and of the
+		standby nodes which sent a heartbeat to it recently, along with the
+		time of their last heartbeat.
+		`,
+	},
+
+	"internal-specs-openapi": {
+		"Generates an OpenAPI document of the paths of the mounts.",
+		`
+		Generates an OpenAPI v3 document describing the paths of the secrets
+		engines and auth methods mounted in the namespace of the request, their
+		parameters and their operations, from the definitions of the paths of
+		their backends.
 		`,
 	},
 
@@ -3268,6 +4768,85 @@ Enable a new audit backend or disable an existing backend.
 		`,
 	},
 
+	"rotation-config": {
+		"Configures the automatic rotation of the backend encryption key.",
+		`
+		The backend encryption key is rotated automatically once it was used
+		for the maximum number of encryptions or, if an interval is set, once it
+		is older than the interval. The number of encryptions is an estimate,
+		accounted for periodically by the active node.
+		`,
+	},
+
+	"rotation-enabled": {
+		"Whether the key is rotated automatically. Defaults to true.",
+		"",
+	},
+
+	"rotation-max-operations": {
+		"The number of encryptions after which the key is rotated, between 1000000 and 3865470566, the default.",
+		"",
+	},
+
+	"rotation-interval": {
+		"The age after which the key is rotated, at least 24 hours. Zero, the default, disables the rotation by age.",
+		"",
+	},
+
+	"activity-config": {
+		"Configures the activity log of the clients of the mounts.",
+		`
+		The activity log records, for each month, the distinct entities and
+		the distinct tokens without an entity which made requests to each
+		mount. The active node writes it periodically, and removes the months
+		older than the retention.
+		`,
+	},
+
+	"activity-enabled": {
+		"Whether the activity of the clients is recorded. Defaults to true.",
+		"",
+	},
+
+	"activity-retention-months": {
+		"The number of months of activity kept, at least 1. Defaults to 24.",
+		"",
+	},
+
+	"activity-query": {
+		"Query the distinct clients active in a time range.",
+		`
+		Returns the number of distinct entities and of distinct tokens without
+		an entity which made requests in the months of the time range, in
+		total, by namespace and mount, and by month. Only the namespace of the
+		request and its descendants are reported.
+		`,
+	},
+
+	"activity-start-time": {
+		"The RFC3339 time from whose month the activity is reported. Defaults to the oldest month kept.",
+		"",
+	},
+
+	"activity-end-time": {
+		"The RFC3339 time up to whose month the activity is reported. Defaults to now.",
+		"",
+	},
+
+	"activity-export": {
+		"Export the clients active in a time range.",
+		`
+		Returns a record for each client active on each mount in each month of
+		the time range, with the time the client was first seen in the month,
+		as JSON lines or CSV.
+		`,
+	},
+
+	"activity-export-format": {
+		`The format of the export, "json" for JSON lines, the default, or "csv".`,
+		"",
+	},
+
 	"rekey_backup": {
 		"Allows fetching or deleting the backup of the rotated unseal keys.",
 		"",
@@ -3325,7 +4904,9 @@ it.`,
 	"rewrap": {
 		"Rotates a response-wrapped token.",
 		`Rotates a response-wrapped token; the output is a new token with the same
-		response wrapped inside and the same creation TTL. The original token is revoked.`,
+		response wrapped inside and the same creation TTL. The original token is revoked.
+		A batch of tokens given in "tokens" is rotated at once, returning the wrapping
+		information of each new token.`,
 	},
 	"audited-headers-name": {
 		"Configures the headers sent to the audit logs.",
@@ -3346,6 +4927,30 @@ This path responds to the following HTTP methods.
 		"Lists the headers configured to be audited.",
 		`Returns a list of headers that have been configured to be audited.`,
 	},
+	"metrics": {
+		"Export the metrics of Vault.",
+		`
+Returns the telemetry collected by Vault. By default, this is the JSON summary
+of the metrics of the last finished aggregation interval. With the format set
+to "prometheus", the metrics are returned in the Prometheus text exposition
+format, with counters and samples accumulated since Vault started.
+		`,
+	},
+
+	"metrics-format": {
+		`The format of the metrics, "json" or "prometheus". Defaults to "json".`,
+		"",
+	},
+
+	"config/cache": {
+		"Reports the usage of the physical cache.",
+		`
+Returns whether the cache of the storage backend is enabled, its size, the
+number of entries it holds, and the number of reads served from the cache
+(hits) or from the storage backend (misses) since Vault started. Reads of
+requests with the X-Vault-Cache-Bypass header are not counted.
+		`,
+	},
 	"plugin-catalog": {
 		"Configures the plugins known to vault",
 		`
@@ -3382,6 +4987,11 @@ plugin directory.`,
 		`The args passed to plugin command.`,
 		"",
 	},
+	"plugin-catalog_version": {
+		`The semantic version of the plugin. Plugin mounts can be pinned
+to a version of the plugin with the plugin_version of their config.`,
+		"",
+	},
 	"leases": {
 		`View or list lease metadata.`,
 		`
@@ -3399,6 +5009,57 @@ This path responds to the following HTTP methods.
 		`The path to list leases under. Example: "aws/creds/deploy"`,
 		"",
 	},
+
+	"leases-count": {
+		`Count the leases.`,
+		`
+This path responds to the following HTTP methods.
+
+    GET /
+        Returns the number of leases, in total and per mount. Set the type
+        to "irrevocable" to only count the irrevocable leases.
+		`,
+	},
+
+	"leases-count-type": {
+		`The type of leases to count, "all" or "irrevocable". Defaults to "all".`,
+		"",
+	},
+
+	"leases-mount": {
+		`The mount path to restrict the leases to. Example: "database/"`,
+		"",
+	},
+
+	"leases-irrevocable": {
+		`List the irrevocable leases.`,
+		`
+This path responds to the following HTTP methods.
+
+    GET /
+        Lists the leases whose revocation permanently failed, with the error
+        of their last revocation attempt. Irrevocable leases are no longer
+        revoked on expiry; they can be revoked with revoke-force, or deleted
+        with tidy-irrevocable once their secrets have been cleaned up.
+		`,
+	},
+
+	"leases-irrevocable-limit": {
+		`The maximum number of leases to return. Defaults to no limit.`,
+		"",
+	},
+
+	"tidy-irrevocable": {
+		`Delete the irrevocable leases.`,
+		`
+This path responds to the following HTTP methods.
+
+    PUT /
+        Deletes the irrevocable leases, optionally of a mount, without
+        attempting to revoke them again. The secrets of the leases must be
+        cleaned up out of band.
+		`,
+	},
 	"plugin-reload": {
 		"Reload mounts that use a particular backend plugin.",
 		`Reload mounts that use a particular backend plugin. Either the plugin name
@@ -3422,4 +5083,261 @@ This path responds to the following HTTP methods.
 		"Generate random bytes",
 		"This function can be used to generate high-entropy random bytes.",
 	},
+
+	"mfa-method-name": {
+		`The name of the MFA method.`,
+		"",
+	},
+
+	"mfa-method-list": {
+		`List the configured login MFA methods.`,
+		`
+This path responds to the following HTTP methods.
+
+    LIST /
+        List the names and types of the configured MFA methods.
+
+    GET /<type>/<name>
+        Retrieve the configuration of the named MFA method.
+
+    PUT /<type>/<name>
+        Add or update an MFA method of type "totp", "duo" or "webhook".
+
+    DELETE /<type>/<name>
+        Delete the MFA method with the given name.
+		`,
+	},
+
+	"mfa-method-totp": {
+		`Read, Modify, or Delete a TOTP MFA method.`,
+		`
+TOTP MFA methods validate the passcodes generated from a TOTP secret of the
+entity logging in. The secrets are generated on the "generate" and
+"admin-generate" endpoints of the method. The settings of a TOTP method cannot
+be changed once it is created.
+		`,
+	},
+
+	"mfa-method-duo": {
+		`Read, Modify, or Delete a Duo MFA method.`,
+		`
+Duo MFA methods validate logins with the Duo Auth API, either with a passcode
+or with a push notification if no passcode is given.
+		`,
+	},
+
+	"mfa-method-webhook": {
+		`Read, Modify, or Delete a webhook MFA method.`,
+		`
+Webhook MFA methods send the details of the login to a URL, which approves the
+login by responding with a 2xx status code and the JSON body
+{"approved": true}.
+		`,
+	},
+
+	"mfa-totp-generate": {
+		`Generate a TOTP secret for the entity of the token.`,
+		`
+Generates the TOTP secret of the entity of the calling token and returns its
+URL and QR code. A secret that was already generated is not replaced.
+		`,
+	},
+
+	"mfa-totp-admin-generate": {
+		`Generate a TOTP secret for an entity.`,
+		`
+Generates the TOTP secret of the given entity and returns its URL and QR code.
+A secret that was already generated is not replaced; it has to be destroyed
+first.
+		`,
+	},
+
+	"mfa-totp-admin-destroy": {
+		`Delete the TOTP secret of an entity.`,
+		"",
+	},
+
+	"mfa-login-enforcement-list": {
+		`List the configured login enforcements.`,
+		"",
+	},
+
+	"mfa-login-enforcement": {
+		`Read, Modify, or Delete a login enforcement.`,
+		`
+Login enforcements require all of their MFA methods on the logins matching any
+of their auth mount accessors, auth method types, entities or groups. The
+credentials of the methods are supplied in X-Vault-MFA headers of the form
+"<method name>[:<passcode>]".
+		`,
+	},
+
+	"namespaces-list": {
+		`List the child namespaces of the namespace.`,
+		`
+This path responds to the following HTTP methods.
+
+    LIST /
+        List the direct child namespaces of the namespace of the request.
+		`,
+	},
+
+	"namespaces": {
+		`Read, Create, or Delete a namespace.`,
+		`
+Namespaces isolate their mounts, auth methods, policies, tokens and identity
+store from the other namespaces. Requests are made in a namespace by prefixing
+their paths with the path of the namespace or by sending the path in the
+X-Vault-Namespace header. A namespace can only be deleted once its child
+namespaces are deleted; its mounts, auth methods and policies are deleted with
+it.
+		`,
+	},
+
+	"namespace-path": {
+		`The path of the namespace, relative to the namespace of the request.`,
+		"",
+	},
+
+	"rate-limit-quotas": {
+		`List the rate limit quotas.`,
+		`
+This path responds to the following HTTP methods.
+
+    LIST /
+        List the names of the rate limit quotas.
+		`,
+	},
+
+	"rate-limit-quota": {
+		`Read, Create, Update, or Delete a rate limit quota.`,
+		`
+Rate limit quotas limit the rate of the requests of each client to the paths
+below the path of the quota, which is the path of a namespace or of a mount, or
+empty to apply to all requests. Only the quota with the longest path matching a
+request applies. Requests exceeding the quota are rejected with a 429 status
+code. The requests to sys/quotas are exempt from the rate limit quotas.
+		`,
+	},
+
+	"lease-count-quotas": {
+		`List the lease count quotas.`,
+		`
+This path responds to the following HTTP methods.
+
+    LIST /
+        List the names of the lease count quotas.
+		`,
+	},
+
+	"lease-count-quota": {
+		`Read, Create, Update, or Delete a lease count quota.`,
+		`
+Lease count quotas limit the number of leases of the paths below the path of
+the quota, which is the path of a namespace or of a mount, or empty to apply to
+all leases, including the leases of tokens. Only the quota with the longest
+path matching a lease applies. Requests that would create leases beyond the
+maximum are rejected with a 403 status code, and the secrets they generated are
+revoked.
+		`,
+	},
+
+	"quota-name": {
+		`The name of the quota.`,
+		"",
+	},
+
+	"quota-path": {
+		`The path of the namespace or mount the quota applies to, or empty for a global quota.`,
+		"",
+	},
+
+	"quota-rate": {
+		`The number of requests per second allowed for each client.`,
+		"",
+	},
+
+	"quota-burst": {
+		`The number of requests each client can make at once. Defaults to the rate.`,
+		"",
+	},
+
+	"quota-max-leases": {
+		`The maximum number of leases.`,
+		"",
+	},
+
+	"control-group-authorize": {
+		"Authorize the request of a control group token.",
+		`
+The entity of the caller must be a member of an identity group of a factor of
+the control group of the request, and cannot be the entity of the requester.
+Once every factor has the required number of authorizations, the control group
+token can be unwrapped.
+		`,
+	},
+
+	"control-group-request": {
+		"Check the status of the request of a control group token.",
+		`
+Returns the path and requester of the request, its authorizations and whether
+it is approved.
+		`,
+	},
+
+	"control-group-accessor": {
+		`The accessor of the control group token.`,
+		"",
+	},
+
+	"events-subscribe": {
+		"Subscribe to the events of Vault over a websocket.",
+		`
+Streams the events of the namespace of the request and of its descendants,
+such as secrets being written or certificates being issued, as JSON text
+messages over a websocket until the client closes it. The request must be a
+websocket upgrade request. Only the events matching the event type and path
+patterns are streamed; patterns may start or end with a '*' wildcard.
+		`,
+	},
+
+	"events-event-type": {
+		`Comma-separated patterns of the types of the events to stream, e.g. "kv/*". All the events are streamed by default.`,
+		"",
+	},
+
+	"events-path": {
+		`The pattern of the paths of the events to stream, relative to the namespace of the request, e.g. "secret/*". All the paths are streamed by default.`,
+		"",
+	},
+
+	"debug-pprof": {
+		"Capture a runtime profile of Vault.",
+		`
+Returns the profile in the format read by "go tool pprof". The "profile"
+profile samples the CPU usage of Vault during the given number of seconds, and
+"trace" records an execution trace, read by "go tool trace", during that time.
+The other profiles, "heap", "goroutine", "allocs", "block", "mutex" and
+"threadcreate", are snapshots.
+		`,
+	},
+
+	"debug-pprof-profile": {
+		`The name of the profile.`,
+		"",
+	},
+
+	"debug-pprof-seconds": {
+		`The duration of the CPU profiles and execution traces. Defaults to 30 seconds.`,
+		"",
+	},
+
+	"debug-logs": {
+		"Read the recent log lines of Vault.",
+		`
+Returns the last log lines written by the server, oldest first. The lines are
+kept in memory, from the level of the server, and are not available when Vault
+is embedded without a log buffer.
+		`,
+	},
 }
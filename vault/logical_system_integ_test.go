@@ -1,10 +1,12 @@
 package vault_test
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strconv"
 	"testing"
 	"time"
 
@@ -123,6 +125,70 @@ func TestSystemBackend_Plugin_MismatchType(t *testing.T) {
 	time.Sleep(1 * time.Second)
 }
 
+func TestSystemBackend_Plugin_version(t *testing.T) {
+	cluster := testSystemBackendMock(t, 1, 1, logical.TypeLogical)
+	defer cluster.Cleanup()
+
+	core := cluster.Cores[0]
+	client := core.Client
+
+	// Register the logical plugin as version 1.0.0
+	resp, err := client.Logical().Read("sys/plugins/catalog/mock-plugin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Data["version"] = "1.0.0"
+	if _, err := client.Logical().Write("sys/plugins/catalog/mock-plugin", resp.Data); err != nil {
+		t.Fatal(err)
+	}
+
+	// Replace the unversioned plugin with a credential backend
+	vault.TestAddTestPlugin(t, core.Core, "mock-plugin", "TestBackend_PluginMainCredentials")
+
+	resp, err = client.Logical().Read("sys/plugins/catalog/mock-plugin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if versions, ok := resp.Data["versions"].([]interface{}); !ok || len(versions) != 1 || versions[0] != "1.0.0" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	mount := func(path, version string) error {
+		_, err := client.Logical().Write("sys/mounts/"+path, map[string]interface{}{
+			"type": "plugin",
+			"config": map[string]interface{}{
+				"plugin_name":    "mock-plugin",
+				"plugin_version": version,
+			},
+		})
+		return err
+	}
+
+	// The mount pinned to 1.0.0 gets the logical plugin
+	if err := mount("pinned", "1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	req := logical.TestRequest(t, logical.ReadOperation, "pinned/internal")
+	req.ClientToken = client.Token()
+	if _, err := core.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	mounts, err := client.Sys().ListMounts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mounts["pinned/"].Config.PluginVersion != "1.0.0" {
+		t.Fatalf("bad: %#v", mounts["pinned/"].Config)
+	}
+
+	if err := mount("unpinned", ""); err == nil {
+		t.Fatal("expected error mounting a credential plugin as a secrets engine")
+	}
+	if err := mount("missing", "2.0.0"); err == nil {
+		t.Fatal("expected error mounting a missing version")
+	}
+}
+
 func TestSystemBackend_Plugin_CatalogRemoved(t *testing.T) {
 	t.Run("secret", func(t *testing.T) {
 		testPlugin_CatalogRemoved(t, logical.TypeLogical, false)
@@ -457,6 +523,104 @@ func testSystemBackend_PluginReload(t *testing.T, reqData map[string]interface{}
 	}
 }
 
+func TestSystemBackend_Plugin_multiplexed(t *testing.T) {
+	coreConfig := &vault.CoreConfig{
+		LogicalBackends: map[string]logical.Factory{
+			"plugin": plugin.Factory,
+		},
+	}
+
+	// Create a tempdir, cluster.Cleanup will clean up this directory
+	tempDir, err := ioutil.TempDir("", "vault-test-cluster")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cluster := vault.NewTestCluster(t, coreConfig, &vault.TestClusterOptions{
+		HandlerFunc: vaulthttp.Handler,
+		NumCores:    1,
+		TempDir:     tempDir,
+	})
+	cluster.Start()
+	defer cluster.Cleanup()
+
+	core := cluster.Cores[0]
+	vault.TestWaitActive(t, core.Core)
+	client := core.Client
+
+	os.Setenv(pluginutil.PluginCACertPEMEnv, cluster.CACertPEMFile)
+	vault.TestAddTestPluginTempDir(t, core.Core, "mock-plugin", "TestBackend_PluginMainMultiplexed", tempDir)
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.Logical().Write(fmt.Sprintf("sys/mounts/mock-%d", i), map[string]interface{}{
+			"type":        "plugin",
+			"plugin_name": "mock-plugin",
+		})
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if resp != nil {
+			t.Fatalf("bad: %v", resp)
+		}
+
+		resp, err = client.Logical().Write(fmt.Sprintf("mock-%d/internal", i), map[string]interface{}{
+			"value": fmt.Sprintf("value%d", i),
+		})
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	// pids returns the processes serving the mounts
+	pids := func(mounts ...string) []string {
+		t.Helper()
+
+		var pids []string
+		for _, mount := range mounts {
+			resp, err := client.Logical().Read(mount + "/pid")
+			if err != nil {
+				t.Fatalf("err: %v", err)
+			}
+			pids = append(pids, resp.Data["pid"].(string))
+		}
+		return pids
+	}
+
+	// Both mounts are served by the same process, keeping their own state
+	before := pids("mock-0", "mock-1")
+	if before[0] != before[1] {
+		t.Fatalf("expected the mounts to share the plugin process: %v", before)
+	}
+	for i := 0; i < 2; i++ {
+		resp, err := client.Logical().Read(fmt.Sprintf("mock-%d/internal", i))
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if resp.Data["value"] != fmt.Sprintf("value%d", i) {
+			t.Fatalf("bad: %#v", resp)
+		}
+	}
+
+	// Reloading the plugin starts a new process for its mounts
+	if _, err := client.Logical().Write("sys/plugins/reload/backend", map[string]interface{}{
+		"plugin": "mock-plugin",
+	}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	after := pids("mock-0", "mock-1")
+	if after[0] != after[1] || after[0] == before[0] {
+		t.Fatalf("expected the mounts to share a new plugin process: %v, %v", before, after)
+	}
+
+	// Unmounting one of the mounts leaves the process serving the other one
+	if _, err := client.Logical().Delete("sys/mounts/mock-0"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if pid := pids("mock-1")[0]; pid != after[1] {
+		t.Fatalf("bad: %s, expected %s", pid, after[1])
+	}
+}
+
 // testSystemBackendMock returns a systemBackend with the desired number
 // of mounted mock plugin backends. numMounts alternates between different
 // ways of providing the plugin_name.
@@ -601,3 +765,61 @@ func TestBackend_PluginMainCredentials(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+// multiplexedMockBackend is the mock backend returning the pid of the
+// process serving it at the pid path
+type multiplexedMockBackend struct {
+	logical.Backend
+}
+
+func (b *multiplexedMockBackend) HandleRequest(ctx context.Context, req *logical.Request) (*logical.Response, error) {
+	if req.Path == "pid" {
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"pid": strconv.Itoa(os.Getpid()),
+			},
+		}, nil
+	}
+	return b.Backend.HandleRequest(ctx, req)
+}
+
+func TestBackend_PluginMainMultiplexed(t *testing.T) {
+	args := []string{}
+	if os.Getenv(pluginutil.PluginUnwrapTokenEnv) == "" && os.Getenv(pluginutil.PluginMetadaModeEnv) != "true" {
+		return
+	}
+
+	caPEM := os.Getenv(pluginutil.PluginCACertPEMEnv)
+	if caPEM == "" {
+		t.Fatal("CA cert not passed in")
+	}
+	args = append(args, fmt.Sprintf("--ca-cert=%s", caPEM))
+
+	apiClientMeta := &pluginutil.APIClientMeta{}
+	flags := apiClientMeta.FlagSet()
+	flags.Parse(args)
+	tlsConfig := apiClientMeta.GetTLSConfig()
+	tlsProviderFunc := pluginutil.VaultPluginTLSProvider(tlsConfig)
+
+	factory := mock.FactoryType(logical.TypeLogical)
+	factoryFunc := func(ctx context.Context, conf *logical.BackendConfig) (logical.Backend, error) {
+		b, err := factory(ctx, conf)
+		if err != nil {
+			return nil, err
+		}
+		return &multiplexedMockBackend{Backend: b}, nil
+	}
+
+	// Multiplexing is only supported over gRPC, which the version of the
+	// test binary predates
+	os.Setenv(pluginutil.PluginVaultVersionEnv, "unknown")
+
+	err := lplugin.Serve(&lplugin.ServeOpts{
+		BackendFactoryFunc: factoryFunc,
+		TLSProviderFunc:    tlsProviderFunc,
+		Multiplexing:       true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -14,11 +16,15 @@ import (
 	"testing"
 	"time"
 
+	metrics "github.com/armon/go-metrics"
 	"github.com/fatih/structs"
 	"github.com/hashicorp/vault/audit"
 	"github.com/hashicorp/vault/helper/builtinplugins"
+	"github.com/hashicorp/vault/helper/metricsutil"
 	"github.com/hashicorp/vault/helper/salt"
 	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+	"github.com/hashicorp/vault/physical"
 	"github.com/mitchellh/mapstructure"
 )
 
@@ -32,15 +38,23 @@ func TestSystemBackend_RootPaths(t *testing.T) {
 		"raw/*",
 		"replication/primary/secondary-token",
 		"replication/reindex",
+		"replication/dr/primary/*",
+		"replication/dr/secondary/*",
 		"rotate",
+		"rotate/config",
 		"config/cors",
 		"config/auditing/*",
+		"config/cache",
 		"plugins/catalog/*",
 		"revoke-prefix/*",
 		"revoke-force/*",
 		"leases/revoke-prefix/*",
 		"leases/revoke-force/*",
 		"leases/lookup/*",
+		"leases/irrevocable",
+		"leases/tidy-irrevocable",
+		"debug/*",
+		"in-flight-req",
 	}
 
 	b := testSystemBackend(t)
@@ -50,6 +64,27 @@ func TestSystemBackend_RootPaths(t *testing.T) {
 	}
 }
 
+func TestSystemBackend_configCache(t *testing.T) {
+	c, b, _ := testCoreSystemBackend(t)
+
+	req := logical.TestRequest(t, logical.ReadOperation, "config/cache")
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stats := c.physicalCache.Stats()
+	if resp.Data["enabled"] != true || resp.Data["size"] != physical.DefaultCacheSize {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if resp.Data["hits"].(uint64) > stats.Hits || resp.Data["misses"].(uint64) > stats.Misses {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if rate := resp.Data["hit_rate"].(float64); rate < 0 || rate > 1 {
+		t.Fatalf("bad hit rate: %v", rate)
+	}
+}
+
 func TestSystemConfigCORS(t *testing.T) {
 	b := testSystemBackend(t)
 	_, barrier, _ := mockBarrier(t)
@@ -312,6 +347,122 @@ func TestSystemBackend_mount_invalid(t *testing.T) {
 	}
 }
 
+func TestSystemBackend_tuneMountTunables(t *testing.T) {
+	core, b, _ := testCoreSystemBackend(t)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "mounts/secret/tune")
+	req.Data["audit_non_hmac_request_keys"] = "role,role, name"
+	req.Data["audit_non_hmac_response_keys"] = []string{"version"}
+	req.Data["listing_visibility"] = "unauth"
+	req.Data["passthrough_request_headers"] = "x-custom,X-Custom"
+	req.Data["allowed_response_headers"] = []string{"x-custom-response"}
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v %#v", err, resp)
+	}
+
+	expected := MountConfig{
+		AuditNonHMACRequestKeys:   []string{"role", "name"},
+		AuditNonHMACResponseKeys:  []string{"version"},
+		ListingVisibility:         ListingVisibilityUnauth,
+		PassthroughRequestHeaders: []string{"X-Custom"},
+		AllowedResponseHeaders:    []string{"X-Custom-Response"},
+	}
+	config := core.router.MatchingMountEntry("secret/").Config
+	config.DefaultLeaseTTL, config.MaxLeaseTTL = 0, 0
+	if !reflect.DeepEqual(config, expected) {
+		t.Fatalf("bad config: %#v", config)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "mounts/secret/tune")
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(resp.Data["passthrough_request_headers"], expected.PassthroughRequestHeaders) ||
+		resp.Data["listing_visibility"] != ListingVisibilityUnauth {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Clearing the options resets them
+	req = logical.TestRequest(t, logical.UpdateOperation, "mounts/secret/tune")
+	req.Data["listing_visibility"] = ""
+	req.Data["allowed_response_headers"] = ""
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v %#v", err, resp)
+	}
+	config = core.router.MatchingMountEntry("secret/").Config
+	if config.ListingVisibility != ListingVisibilityDefault || config.AllowedResponseHeaders != nil {
+		t.Fatalf("bad config: %#v", config)
+	}
+
+	// Invalid options are rejected
+	for field, value := range map[string]interface{}{
+		"listing_visibility":          "nope",
+		"passthrough_request_headers": "x-vault-token",
+	} {
+		req = logical.TestRequest(t, logical.UpdateOperation, "mounts/secret/tune")
+		req.Data[field] = value
+		resp, err = b.HandleRequest(context.Background(), req)
+		if err != logical.ErrInvalidRequest || !resp.IsError() {
+			t.Fatalf("expected invalid request tuning %s: %v %#v", field, err, resp)
+		}
+	}
+}
+
+func TestSystemBackend_internalUIMounts(t *testing.T) {
+	_, b, _ := testCoreSystemBackend(t)
+
+	req := logical.TestRequest(t, logical.ReadOperation, "internal/ui/mounts")
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	expected := map[string]interface{}{
+		"secret": map[string]interface{}{},
+		"auth":   map[string]interface{}{},
+	}
+	if !reflect.DeepEqual(resp.Data, expected) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// The mounts tuned to be listed to unauthenticated clients are listed
+	req = logical.TestRequest(t, logical.UpdateOperation, "mounts/secret/tune")
+	req.Data["listing_visibility"] = "unauth"
+	if resp, err := b.HandleRequest(context.Background(), req); err != nil {
+		t.Fatalf("err: %v %#v", err, resp)
+	}
+	req = logical.TestRequest(t, logical.UpdateOperation, "mounts/auth/token/tune")
+	req.Data["listing_visibility"] = "unauth"
+	if resp, err := b.HandleRequest(context.Background(), req); err != nil {
+		t.Fatalf("err: %v %#v", err, resp)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "internal/ui/mounts")
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	expected = map[string]interface{}{
+		"secret": map[string]interface{}{
+			"secret/": map[string]interface{}{
+				"type":        "kv",
+				"description": "key/value secret storage",
+			},
+		},
+		"auth": map[string]interface{}{
+			"token/": map[string]interface{}{
+				"type":        "token",
+				"description": "token based credentials",
+			},
+		},
+	}
+	if !reflect.DeepEqual(resp.Data, expected) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+}
+
 func TestSystemBackend_unmount(t *testing.T) {
 	b := testSystemBackend(t)
 
@@ -986,6 +1137,137 @@ func TestSystemBackend_revoke_invalidID_origUrl(t *testing.T) {
 	}
 }
 
+func TestSystemBackend_metrics(t *testing.T) {
+	c, b, _ := testCoreSystemBackend(t)
+
+	req := logical.TestRequest(t, logical.ReadOperation, "metrics")
+	if _, err := b.HandleRequest(context.Background(), req); err != logical.ErrInvalidRequest {
+		t.Fatalf("expected invalid request without metrics, got %v", err)
+	}
+
+	inm := metrics.NewInmemSink(10*time.Second, time.Minute)
+	prometheusSink := metricsutil.NewPrometheusSink(0)
+	c.metricsHelper = metricsutil.NewMetricsHelper(inm, prometheusSink)
+	for _, sink := range []metrics.MetricSink{inm, prometheusSink} {
+		sink.SetGauge([]string{"vault", "expire", "num_leases"}, 2)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "metrics")
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Data[logical.HTTPContentType] != "application/json" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	var summary metrics.MetricsSummary
+	if err := json.Unmarshal(resp.Data[logical.HTTPRawBody].([]byte), &summary); err != nil {
+		t.Fatal(err)
+	}
+	if len(summary.Gauges) != 1 || summary.Gauges[0].Name != "vault.expire.num_leases" || summary.Gauges[0].Value != 2 {
+		t.Fatalf("bad: %#v", summary)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "metrics")
+	req.Data["format"] = "prometheus"
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Data[logical.HTTPContentType] != metricsutil.PrometheusContentType {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if body := string(resp.Data[logical.HTTPRawBody].([]byte)); !strings.Contains(body, "vault_expire_num_leases 2\n") {
+		t.Fatalf("bad: %s", body)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "metrics")
+	req.Data["format"] = "xml"
+	if _, err := b.HandleRequest(context.Background(), req); err != logical.ErrInvalidRequest {
+		t.Fatalf("expected invalid request, got %v", err)
+	}
+}
+
+func TestSystemBackend_irrevocableLeases(t *testing.T) {
+	core, b, root := testCoreSystemBackend(t)
+
+	var leaseIDs []string
+	for _, path := range []string{"secret/foo", "secret/bar"} {
+		req := logical.TestRequest(t, logical.UpdateOperation, path)
+		req.Data["foo"] = "bar"
+		req.Data["lease"] = "1h"
+		req.ClientToken = root
+		if _, err := core.HandleRequest(req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		req = logical.TestRequest(t, logical.ReadOperation, path)
+		req.ClientToken = root
+		resp, err := core.HandleRequest(req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		leaseIDs = append(leaseIDs, resp.Secret.LeaseID)
+	}
+	if err := core.expiration.markIrrevocable(leaseIDs[0], errors.New("revocation failed")); err != nil {
+		t.Fatal(err)
+	}
+
+	req := logical.TestRequest(t, logical.ReadOperation, "leases/count")
+	req.Data["mount"] = "secret"
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Data["lease_count"] != 2 || !reflect.DeepEqual(resp.Data["counts"], map[string]interface{}{"secret/": 2}) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "leases/count")
+	req.Data["type"] = "irrevocable"
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Data["lease_count"] != 1 || !reflect.DeepEqual(resp.Data["counts"], map[string]interface{}{"secret/": 1}) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "leases/count")
+	req.Data["type"] = "bogus"
+	if _, err := b.HandleRequest(context.Background(), req); err != logical.ErrInvalidRequest {
+		t.Fatalf("expected invalid request, got %v", err)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "leases/irrevocable")
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	leases := resp.Data["leases"].([]interface{})
+	if resp.Data["lease_count"] != 1 || len(leases) != 1 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	lease := leases[0].(map[string]interface{})
+	if lease["lease_id"] != leaseIDs[0] || lease["mount"] != "secret/" || lease["error"] != "revocation failed" {
+		t.Fatalf("bad: %#v", lease)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "leases/tidy-irrevocable")
+	req.Data["mount"] = "secret/"
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Data["lease_count"] != 1 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	if actual := core.expiration.LeaseIDs("", false); !reflect.DeepEqual(actual, leaseIDs[1:]) {
+		t.Fatalf("bad: %v", actual)
+	}
+}
+
 func TestSystemBackend_revokePrefix(t *testing.T) {
 	core, b, root := testCoreSystemBackend(t)
 
@@ -1224,8 +1506,10 @@ func TestSystemBackend_authTable(t *testing.T) {
 			"description": "token based credentials",
 			"accessor":    resp.Data["token/"].(map[string]interface{})["accessor"],
 			"config": map[string]interface{}{
-				"default_lease_ttl": int64(0),
-				"max_lease_ttl":     int64(0),
+				"default_lease_ttl":    int64(0),
+				"max_lease_ttl":        int64(0),
+				"sync_external_groups": false,
+				"token_type":           "service",
 			},
 			"local":     false,
 			"seal_wrap": false,
@@ -1270,8 +1554,10 @@ func TestSystemBackend_enableAuth(t *testing.T) {
 			"description": "",
 			"accessor":    resp.Data["foo/"].(map[string]interface{})["accessor"],
 			"config": map[string]interface{}{
-				"default_lease_ttl": int64(0),
-				"max_lease_ttl":     int64(0),
+				"default_lease_ttl":    int64(0),
+				"max_lease_ttl":        int64(0),
+				"sync_external_groups": false,
+				"token_type":           "service",
 			},
 			"local":     true,
 			"seal_wrap": true,
@@ -1281,8 +1567,10 @@ func TestSystemBackend_enableAuth(t *testing.T) {
 			"description": "token based credentials",
 			"accessor":    resp.Data["token/"].(map[string]interface{})["accessor"],
 			"config": map[string]interface{}{
-				"default_lease_ttl": int64(0),
-				"max_lease_ttl":     int64(0),
+				"default_lease_ttl":    int64(0),
+				"max_lease_ttl":        int64(0),
+				"sync_external_groups": false,
+				"token_type":           "service",
 			},
 			"local":     false,
 			"seal_wrap": false,
@@ -1701,10 +1989,16 @@ func TestSystemBackend_keyStatus(t *testing.T) {
 		t.Fatalf("err: %v", err)
 	}
 
+	if keys := resp.Data["keys"].([]map[string]interface{}); len(keys) != 1 || keys[0]["term"] != 1 {
+		t.Fatalf("bad: %#v", resp.Data["keys"])
+	}
+
 	exp := map[string]interface{}{
 		"term": 1,
 	}
 	delete(resp.Data, "install_time")
+	delete(resp.Data, "encryptions")
+	delete(resp.Data, "keys")
 	if !reflect.DeepEqual(resp.Data, exp) {
 		t.Fatalf("got: %#v expect: %#v", resp.Data, exp)
 	}
@@ -1728,13 +2022,159 @@ func TestSystemBackend_rotate(t *testing.T) {
 		t.Fatalf("err: %v", err)
 	}
 
+	if keys := resp.Data["keys"].([]map[string]interface{}); len(keys) != 2 || keys[1]["term"] != 2 {
+		t.Fatalf("bad: %#v", resp.Data["keys"])
+	}
+
 	exp := map[string]interface{}{
 		"term": 2,
 	}
 	delete(resp.Data, "install_time")
+	delete(resp.Data, "encryptions")
+	delete(resp.Data, "keys")
+	if !reflect.DeepEqual(resp.Data, exp) {
+		t.Fatalf("got: %#v expect: %#v", resp.Data, exp)
+	}
+}
+
+func TestSystemBackend_rotateConfig(t *testing.T) {
+	b := testSystemBackend(t)
+
+	req := logical.TestRequest(t, logical.ReadOperation, "rotate/config")
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	exp := map[string]interface{}{
+		"enabled":        true,
+		"max_operations": uint64(maxKeyRotationOperations),
+		"interval":       int64(0),
+	}
+	if !reflect.DeepEqual(resp.Data, exp) {
+		t.Fatalf("got: %#v expect: %#v", resp.Data, exp)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "rotate/config")
+	req.Data["max_operations"] = 100
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != logical.ErrInvalidRequest {
+		t.Fatalf("expected invalid request, got: %v %#v", err, resp)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "rotate/config")
+	req.Data["interval"] = "1h"
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != logical.ErrInvalidRequest {
+		t.Fatalf("expected invalid request, got: %v %#v", err, resp)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "rotate/config")
+	req.Data["enabled"] = false
+	req.Data["max_operations"] = 2000000
+	req.Data["interval"] = "48h"
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v %#v", err, resp)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "rotate/config")
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	exp = map[string]interface{}{
+		"enabled":        false,
+		"max_operations": uint64(2000000),
+		"interval":       int64(48 * 60 * 60),
+	}
+	if !reflect.DeepEqual(resp.Data, exp) {
+		t.Fatalf("got: %#v expect: %#v", resp.Data, exp)
+	}
+}
+
+func TestSystemBackend_passwordPolicyCRUD(t *testing.T) {
+	c, b, _ := testCoreSystemBackend(t)
+
+	// Invalid policies are rejected
+	req := logical.TestRequest(t, logical.UpdateOperation, "policies/password/digits")
+	req.Data["policy"] = `length = 0`
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error response, got resp: %#v, err: %v", resp, err)
+	}
+
+	// Create the policy
+	policy := `
+length = 12
+rule "charset" {
+  charset = "0123456789"
+  min_chars = 1
+}`
+	req.Data["policy"] = policy
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err: %v %#v", err, resp)
+	}
+
+	// Read the policy
+	req = logical.TestRequest(t, logical.ReadOperation, "policies/password/digits")
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	exp := map[string]interface{}{
+		"name":   "digits",
+		"policy": policy,
+	}
 	if !reflect.DeepEqual(resp.Data, exp) {
 		t.Fatalf("got: %#v expect: %#v", resp.Data, exp)
 	}
+
+	// List the policies
+	req = logical.TestRequest(t, logical.ListOperation, "policies/password")
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(resp.Data["keys"], []string{"digits"}) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Generate passwords from the endpoint and from the system view
+	req = logical.TestRequest(t, logical.ReadOperation, "policies/password/digits/generate")
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("err: %v %#v", err, resp)
+	}
+	password := resp.Data["password"].(string)
+	if len(password) != 12 || strings.Trim(password, "0123456789") != "" {
+		t.Fatalf("bad: password: %q", password)
+	}
+
+	sysView := dynamicSystemView{core: c}
+	password, err = sysView.GeneratePasswordFromPolicy(context.Background(), "digits")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(password) != 12 || strings.Trim(password, "0123456789") != "" {
+		t.Fatalf("bad: password: %q", password)
+	}
+
+	// Delete the policy
+	req = logical.TestRequest(t, logical.DeleteOperation, "policies/password/digits")
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err: %v %#v", err, resp)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "policies/password/digits/generate")
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error response, got resp: %#v, err: %v", resp, err)
+	}
+	if _, err := sysView.GeneratePasswordFromPolicy(context.Background(), "digits"); err == nil {
+		t.Fatal("expected an error")
+	}
 }
 
 func testSystemBackend(t *testing.T) logical.Backend {
@@ -2024,3 +2464,51 @@ func TestSystemBackend_ToolsRandom(t *testing.T) {
 	req.Data["bytes"] = -1
 	doRequest(req, true, "", 0)
 }
+
+func TestSystemBackend_InternalOpenAPI(t *testing.T) {
+	b := testSystemBackend(t)
+
+	req := logical.TestRequest(t, logical.ReadOperation, "internal/specs/openapi")
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Data[logical.HTTPContentType] != "application/json" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	var doc framework.OASDocument
+	if err := json.Unmarshal(resp.Data[logical.HTTPRawBody].([]byte), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Version != framework.OpenAPIVersion {
+		t.Fatalf("bad version: %q", doc.Version)
+	}
+
+	expected := map[string]string{
+		"/sys/internal/specs/openapi": "system",
+		"/sys/mounts/{path}":          "system",
+		"/auth/token/lookup":          "auth",
+		"/cubbyhole/{path}":           "secrets",
+		"/identity/entity":            "identity",
+	}
+	for path, tag := range expected {
+		item, ok := doc.Paths[path]
+		if !ok {
+			t.Fatalf("missing path %q", path)
+		}
+		var op *framework.OASOperation
+		for _, o := range []*framework.OASOperation{item.Get, item.Post, item.Delete} {
+			if o != nil {
+				op = o
+				break
+			}
+		}
+		if op == nil || !reflect.DeepEqual(op.Tags, []string{tag}) {
+			t.Fatalf("bad operations of %q: %#v", path, item)
+		}
+	}
+	if item := doc.Paths["/sys/mounts/{path}"]; len(item.Parameters) != 1 || item.Parameters[0].Name != "path" {
+		t.Fatalf("bad: %#v", item)
+	}
+}
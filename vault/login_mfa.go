@@ -0,0 +1,1400 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image/png"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/duosecurity/duo_api_golang"
+	"github.com/duosecurity/duo_api_golang/authapi"
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/helper/identity"
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/helper/mfa/duo"
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+	otplib "github.com/pquerna/otp"
+	totplib "github.com/pquerna/otp/totp"
+)
+
+const (
+	// loginMFASubPath is the sub-path used for the login MFA methods,
+	// enforcements and TOTP secrets within the system barrier view
+	loginMFASubPath = "login_mfa/"
+
+	mfaMethodSubPath      = "method/"
+	mfaEnforcementSubPath = "enforcement/"
+	mfaTOTPSecretSubPath  = "totp_secret/"
+
+	mfaMethodTypeTOTP    = "totp"
+	mfaMethodTypeDuo     = "duo"
+	mfaMethodTypeWebhook = "webhook"
+
+	// defaultTOTPMaxValidationAttempts is the number of failed validations
+	// of TOTP passcodes allowed per period for methods created without
+	// max_validation_attempts
+	defaultTOTPMaxValidationAttempts = 5
+
+	// mfaWebhookSignatureHeader is the header carrying the HMAC-SHA256
+	// signature of the body of webhook MFA requests
+	mfaWebhookSignatureHeader = "X-Vault-MFA-Signature"
+)
+
+// duoUsernameDirectiveRe matches the alias and entity directives of the Duo
+// username formats
+var duoUsernameDirectiveRe = regexp.MustCompile(`\{\{\s*(alias|entity)\.`)
+
+// newDuoMFAClient creates the Duo Auth API client of a Duo MFA method. It is
+// a variable so that it can be replaced in tests.
+var newDuoMFAClient = func(config *duoMFAConfig) duo.AuthClient {
+	client := duoapi.NewDuoApi(config.IntegrationKey, config.SecretKey, config.APIHostname, "vault")
+	return authapi.NewAuthApi(*client)
+}
+
+// mfaMethod is the storage entry of a login MFA method. Exactly one of the
+// type specific configurations is set.
+type mfaMethod struct {
+	ID      string            `json:"id"`
+	Name    string            `json:"name"`
+	Type    string            `json:"type"`
+	TOTP    *totpMFAConfig    `json:"totp,omitempty"`
+	Duo     *duoMFAConfig     `json:"duo,omitempty"`
+	Webhook *webhookMFAConfig `json:"webhook,omitempty"`
+}
+
+type totpMFAConfig struct {
+	Issuer    string        `json:"issuer"`
+	Period    time.Duration `json:"period"`
+	KeySize   int           `json:"key_size"`
+	QRSize    int           `json:"qr_size"`
+	Algorithm string        `json:"algorithm"`
+	Digits    int           `json:"digits"`
+	Skew      int           `json:"skew"`
+
+	// MaxValidationAttempts is the number of failed validations allowed for
+	// an entity per period
+	MaxValidationAttempts int `json:"max_validation_attempts"`
+}
+
+// totpMFAUsage tracks the use of the TOTP passcodes of an entity for a method
+type totpMFAUsage struct {
+	// lastStep is the time step of the last accepted passcode. Passcodes of
+	// this step or earlier ones are not accepted again.
+	lastStep int64
+
+	// failures is the number of failed validations during failureStep
+	failures    int
+	failureStep int64
+}
+
+type duoMFAConfig struct {
+	MountAccessor  string `json:"mount_accessor"`
+	IntegrationKey string `json:"integration_key"`
+	SecretKey      string `json:"secret_key"`
+	APIHostname    string `json:"api_hostname"`
+	UsernameFormat string `json:"username_format"`
+	PushInfo       string `json:"push_info"`
+}
+
+type webhookMFAConfig struct {
+	URL     string        `json:"url"`
+	Secret  string        `json:"secret"`
+	Timeout time.Duration `json:"timeout"`
+}
+
+// mfaEnforcement is the storage entry of a login enforcement. The methods of
+// the enforcement are required on the logins matching any of its selectors.
+type mfaEnforcement struct {
+	Name                string   `json:"name"`
+	MFAMethodNames      []string `json:"mfa_method_names"`
+	AuthMethodAccessors []string `json:"auth_method_accessors"`
+	AuthMethodTypes     []string `json:"auth_method_types"`
+	IdentityEntityIDs   []string `json:"identity_entity_ids"`
+	IdentityGroupIDs    []string `json:"identity_group_ids"`
+}
+
+// totpMFASecret is the storage entry of the TOTP secret of an entity
+type totpMFASecret struct {
+	Secret string `json:"secret"`
+}
+
+// mfaWebhookRequest is the body of the requests sent to the webhook MFA
+// methods
+type mfaWebhookRequest struct {
+	MethodName    string `json:"method_name"`
+	Path          string `json:"path"`
+	MountAccessor string `json:"mount_accessor"`
+	MountType     string `json:"mount_type"`
+	EntityID      string `json:"entity_id"`
+	EntityName    string `json:"entity_name"`
+	RemoteAddr    string `json:"remote_addr"`
+	Credential    string `json:"credential"`
+}
+
+// mfaWebhookResponse is the body of the responses of the webhook MFA methods
+type mfaWebhookResponse struct {
+	Approved bool   `json:"approved"`
+	Reason   string `json:"reason"`
+}
+
+func (c *Core) mfaMethodView() *BarrierView {
+	return c.systemBarrierView.SubView(loginMFASubPath + mfaMethodSubPath)
+}
+
+func (c *Core) mfaEnforcementView() *BarrierView {
+	return c.systemBarrierView.SubView(loginMFASubPath + mfaEnforcementSubPath)
+}
+
+func (c *Core) mfaTOTPSecretView(methodName string) *BarrierView {
+	return c.systemBarrierView.SubView(loginMFASubPath + mfaTOTPSecretSubPath + methodName + "/")
+}
+
+// getMFAMethod returns the MFA method with the given name, or nil if there is
+// no such method
+func (c *Core) getMFAMethod(ctx context.Context, name string) (*mfaMethod, error) {
+	entry, err := c.mfaMethodView().Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var method mfaMethod
+	if err := entry.DecodeJSON(&method); err != nil {
+		return nil, err
+	}
+
+	return &method, nil
+}
+
+func (c *Core) setMFAMethod(ctx context.Context, method *mfaMethod) error {
+	entry, err := logical.StorageEntryJSON(method.Name, method)
+	if err != nil {
+		return err
+	}
+
+	return c.mfaMethodView().Put(ctx, entry)
+}
+
+// getMFAEnforcement returns the login enforcement with the given name, or nil
+// if there is no such enforcement
+func (c *Core) getMFAEnforcement(ctx context.Context, name string) (*mfaEnforcement, error) {
+	entry, err := c.mfaEnforcementView().Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var enforcement mfaEnforcement
+	if err := entry.DecodeJSON(&enforcement); err != nil {
+		return nil, err
+	}
+
+	return &enforcement, nil
+}
+
+func (c *Core) setMFAEnforcement(ctx context.Context, enforcement *mfaEnforcement) error {
+	entry, err := logical.StorageEntryJSON(enforcement.Name, enforcement)
+	if err != nil {
+		return err
+	}
+
+	return c.mfaEnforcementView().Put(ctx, entry)
+}
+
+// listMFAEnforcements returns all the login enforcements
+func (c *Core) listMFAEnforcements(ctx context.Context) ([]*mfaEnforcement, error) {
+	names, err := c.mfaEnforcementView().List(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	enforcements := make([]*mfaEnforcement, 0, len(names))
+	for _, name := range names {
+		enforcement, err := c.getMFAEnforcement(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if enforcement != nil {
+			enforcements = append(enforcements, enforcement)
+		}
+	}
+
+	return enforcements, nil
+}
+
+// getTOTPMFASecret returns the TOTP secret of the entity for the method, or
+// nil if the entity has no secret
+func (c *Core) getTOTPMFASecret(ctx context.Context, methodName, entityID string) (*totpMFASecret, error) {
+	entry, err := c.mfaTOTPSecretView(methodName).Get(ctx, entityID)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var secret totpMFASecret
+	if err := entry.DecodeJSON(&secret); err != nil {
+		return nil, err
+	}
+
+	return &secret, nil
+}
+
+// generateTOTPMFASecret generates and stores a TOTP secret for the entity. It
+// returns the key URL and the base64 encoded PNG QR code of the secret.
+func (c *Core) generateTOTPMFASecret(ctx context.Context, method *mfaMethod, entity *identity.Entity) (*logical.Response, error) {
+	config := method.TOTP
+
+	accountName := entity.Name
+	if accountName == "" {
+		accountName = entity.ID
+	}
+
+	key, err := totplib.Generate(totplib.GenerateOpts{
+		Issuer:      config.Issuer,
+		AccountName: accountName,
+		Period:      uint(config.Period.Seconds()),
+		SecretSize:  uint(config.KeySize),
+		Digits:      otplib.Digits(config.Digits),
+		Algorithm:   totpMFAAlgorithm(config.Algorithm),
+	})
+	if err != nil {
+		return nil, errwrap.Wrapf("failed to generate TOTP key: {{err}}", err)
+	}
+
+	entry, err := logical.StorageEntryJSON(entity.ID, &totpMFASecret{
+		Secret: key.Secret(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := c.mfaTOTPSecretView(method.Name).Put(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"url": key.String(),
+		},
+	}
+	if config.QRSize == 0 {
+		return resp, nil
+	}
+
+	barcode, err := key.Image(config.QRSize, config.QRSize)
+	if err != nil {
+		return nil, errwrap.Wrapf("failed to generate QR code image: {{err}}", err)
+	}
+	var buff bytes.Buffer
+	if err := png.Encode(&buff, barcode); err != nil {
+		return nil, errwrap.Wrapf("failed to encode QR code image: {{err}}", err)
+	}
+	resp.Data["barcode"] = base64.StdEncoding.EncodeToString(buff.Bytes())
+
+	return resp, nil
+}
+
+// validateLoginMFA enforces the login enforcements matching the login
+// request. If an MFA method of a matching enforcement fails, an error
+// response is returned along with logical.ErrPermissionDenied.
+func (c *Core) validateLoginMFA(ctx context.Context, req *logical.Request, entity *identity.Entity) (*logical.Response, error) {
+	c.loginMFALock.RLock()
+	defer c.loginMFALock.RUnlock()
+
+	enforcements, err := c.listMFAEnforcements(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(enforcements) == 0 {
+		return nil, nil
+	}
+
+	var groupIDs []string
+	if identityStore := c.identityStoreForNamespace(namespaceFromContext(ctx)); entity != nil && identityStore != nil {
+		directGroups, inheritedGroups, err := identityStore.groupsByEntityID(entity.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, group := range append(directGroups, inheritedGroups...) {
+			groupIDs = append(groupIDs, group.ID)
+		}
+	}
+
+	// Collect the methods of the matching enforcements, the methods shared
+	// by several enforcements are only validated once
+	var methodNames []string
+	for _, enforcement := range enforcements {
+		if enforcement.matches(req, entity, groupIDs) {
+			methodNames = strutil.RemoveDuplicates(append(methodNames, enforcement.MFAMethodNames...), false)
+		}
+	}
+
+	for _, name := range methodNames {
+		method, err := c.getMFAMethod(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if method == nil {
+			return logical.ErrorResponse(fmt.Sprintf("MFA method %q not found", name)), logical.ErrPermissionDenied
+		}
+
+		var credential string
+		if creds := req.MFACreds[name]; len(creds) > 0 {
+			credential = creds[0]
+		}
+
+		if err := c.validateMFAMethod(ctx, req, method, entity, credential); err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("MFA validation failed for method %q: %s", name, err)), logical.ErrPermissionDenied
+		}
+	}
+
+	return nil, nil
+}
+
+// matches returns whether the enforcement applies to the login request of
+// the entity, which is a member of the given groups
+func (e *mfaEnforcement) matches(req *logical.Request, entity *identity.Entity, groupIDs []string) bool {
+	if strutil.StrListContains(e.AuthMethodAccessors, req.MountAccessor) ||
+		strutil.StrListContains(e.AuthMethodTypes, req.MountType) {
+		return true
+	}
+
+	if entity == nil {
+		return false
+	}
+	if strutil.StrListContains(e.IdentityEntityIDs, entity.ID) {
+		return true
+	}
+	for _, groupID := range groupIDs {
+		if strutil.StrListContains(e.IdentityGroupIDs, groupID) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validateMFAMethod validates the credential supplied for the method
+func (c *Core) validateMFAMethod(ctx context.Context, req *logical.Request, method *mfaMethod, entity *identity.Entity, credential string) error {
+	switch method.Type {
+	case mfaMethodTypeTOTP:
+		return c.validateTOTPMFA(ctx, method, entity, credential)
+	case mfaMethodTypeDuo:
+		return validateDuoMFA(req, method.Duo, entity, credential)
+	case mfaMethodTypeWebhook:
+		return validateWebhookMFA(ctx, req, method, entity, credential)
+	}
+
+	return fmt.Errorf("unsupported MFA method type %q", method.Type)
+}
+
+func (c *Core) validateTOTPMFA(ctx context.Context, method *mfaMethod, entity *identity.Entity, passcode string) error {
+	if entity == nil {
+		return errors.New("TOTP requires an identity entity")
+	}
+	if passcode == "" {
+		return errors.New("missing TOTP passcode")
+	}
+
+	secret, err := c.getTOTPMFASecret(ctx, method.Name, entity.ID)
+	if err != nil {
+		return err
+	}
+	if secret == nil {
+		return errors.New("no TOTP secret was generated for the entity")
+	}
+
+	config := method.TOTP
+	opts := totplib.ValidateOpts{
+		Period:    uint(config.Period.Seconds()),
+		Digits:    otplib.Digits(config.Digits),
+		Algorithm: totpMFAAlgorithm(config.Algorithm),
+	}
+	maxAttempts := config.MaxValidationAttempts
+	if maxAttempts == 0 {
+		maxAttempts = defaultTOTPMaxValidationAttempts
+	}
+	period := int64(opts.Period)
+	step := time.Now().Unix() / period
+
+	c.totpMFAUsageLock.Lock()
+	defer c.totpMFAUsageLock.Unlock()
+
+	key := method.ID + "/" + entity.ID
+	usage, ok := c.totpMFAUsage[key]
+	if !ok {
+		if c.totpMFAUsage == nil {
+			c.totpMFAUsage = make(map[string]*totpMFAUsage)
+		}
+		usage = &totpMFAUsage{}
+		c.totpMFAUsage[key] = usage
+	}
+	if usage.failureStep != step {
+		usage.failures = 0
+		usage.failureStep = step
+	}
+	if usage.failures >= maxAttempts {
+		return errors.New("maximum TOTP validation attempts exceeded, retry in the next period")
+	}
+
+	// Find the time step of the passcode within the skew
+	var matchedStep int64
+	for i := -int64(config.Skew); i <= int64(config.Skew); i++ {
+		code, err := totplib.GenerateCodeCustom(secret.Secret, time.Unix((step+i)*period, 0), opts)
+		if err != nil {
+			return err
+		}
+		if subtle.ConstantTimeCompare([]byte(code), []byte(passcode)) == 1 {
+			matchedStep = step + i
+			break
+		}
+	}
+	if matchedStep == 0 {
+		usage.failures++
+		return errors.New("invalid TOTP passcode")
+	}
+	if matchedStep <= usage.lastStep {
+		return errors.New("code already used")
+	}
+
+	usage.lastStep = matchedStep
+	usage.failures = 0
+	return nil
+}
+
+// clearTOTPMFAUsage forgets the use of the TOTP passcodes of the method, by
+// the given entity or by all entities if entityID is empty
+func (c *Core) clearTOTPMFAUsage(methodID, entityID string) {
+	c.totpMFAUsageLock.Lock()
+	defer c.totpMFAUsageLock.Unlock()
+	for key := range c.totpMFAUsage {
+		if key == methodID+"/"+entityID || (entityID == "" && strings.HasPrefix(key, methodID+"/")) {
+			delete(c.totpMFAUsage, key)
+		}
+	}
+}
+
+// duoMFAUsername returns the Duo username of the entity. The username format
+// may reference the alias of the entity on the mount of the method and the
+// entity with the alias.name, alias.metadata.<key>, entity.name and
+// entity.metadata.<key> directives; if it is empty, the name of the alias is
+// used.
+func duoMFAUsername(config *duoMFAConfig, entity *identity.Entity) (string, error) {
+	format := config.UsernameFormat
+	if format == "" {
+		format = "{{alias.name}}"
+	}
+
+	// Translate the directives to the identity templating directives
+	format = duoUsernameDirectiveRe.ReplaceAllStringFunc(format, func(directive string) string {
+		if strings.HasSuffix(directive, "alias.") {
+			return "{{identity.entity.aliases." + config.MountAccessor + "."
+		}
+		return "{{identity.entity."
+	})
+
+	_, username, err := identity.PopulateString(&identity.PopulateStringInput{
+		Mode:   identity.ACLTemplating,
+		String: format,
+		Entity: entity,
+	})
+	if err != nil {
+		return "", errwrap.Wrapf("failed to format the Duo username: {{err}}", err)
+	}
+
+	return username, nil
+}
+
+func validateDuoMFA(req *logical.Request, config *duoMFAConfig, entity *identity.Entity, passcode string) error {
+	if entity == nil {
+		return errors.New("Duo requires an identity entity")
+	}
+
+	username, err := duoMFAUsername(config, entity)
+	if err != nil {
+		return err
+	}
+
+	var remoteAddr string
+	if req.Connection != nil {
+		remoteAddr = req.Connection.RemoteAddr
+	}
+
+	client := newDuoMFAClient(config)
+
+	preauth, err := client.Preauth(
+		authapi.PreauthUsername(username),
+		authapi.PreauthIpAddr(remoteAddr),
+	)
+	if err != nil || preauth == nil {
+		return errors.New("could not call Duo preauth")
+	}
+	if preauth.StatResult.Stat != "OK" {
+		return duoStatError("could not look up Duo user information", preauth.StatResult)
+	}
+
+	switch preauth.Response.Result {
+	case "allow":
+		return nil
+	case "auth":
+	case "enroll":
+		return fmt.Errorf("%s (%s)", preauth.Response.Status_Msg, preauth.Response.Enroll_Portal_Url)
+	case "deny":
+		return errors.New(preauth.Response.Status_Msg)
+	default:
+		return fmt.Errorf("invalid Duo preauth response %q", preauth.Response.Result)
+	}
+
+	factor := "push"
+	options := []func(*url.Values){
+		authapi.AuthUsername(username),
+		authapi.AuthIpAddr(remoteAddr),
+	}
+	if passcode != "" {
+		factor = "passcode"
+		options = append(options, authapi.AuthPasscode(passcode))
+	} else {
+		options = append(options, authapi.AuthDevice("auto"))
+		if config.PushInfo != "" {
+			options = append(options, authapi.AuthPushinfo(config.PushInfo))
+		}
+	}
+
+	result, err := client.Auth(factor, options...)
+	if err != nil || result == nil {
+		return errors.New("could not call Duo auth")
+	}
+	if result.StatResult.Stat != "OK" {
+		return duoStatError("could not authenticate Duo user", result.StatResult)
+	}
+	if result.Response.Result != "allow" {
+		return errors.New(result.Response.Status_Msg)
+	}
+
+	return nil
+}
+
+func duoStatError(msg string, stat authapi.StatResult) error {
+	if stat.Message != nil {
+		msg = msg + ": " + *stat.Message
+	}
+	if stat.Message_Detail != nil {
+		msg = msg + " (" + *stat.Message_Detail + ")"
+	}
+	return errors.New(msg)
+}
+
+// validateWebhookMFA sends the details of the login to the webhook, which
+// approves the login by responding with a 2xx status code and an approved
+// body
+func validateWebhookMFA(ctx context.Context, req *logical.Request, method *mfaMethod, entity *identity.Entity, credential string) error {
+	config := method.Webhook
+
+	payload := &mfaWebhookRequest{
+		MethodName:    method.Name,
+		Path:          req.Path,
+		MountAccessor: req.MountAccessor,
+		MountType:     req.MountType,
+		Credential:    credential,
+	}
+	if entity != nil {
+		payload.EntityID = entity.ID
+		payload.EntityName = entity.Name
+	}
+	if req.Connection != nil {
+		payload.RemoteAddr = req.Connection.RemoteAddr
+	}
+
+	body, err := jsonutil.EncodeJSON(payload)
+	if err != nil {
+		return err
+	}
+
+	httpReq, err := http.NewRequest("POST", config.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpReq = httpReq.WithContext(ctx)
+	httpReq.Header.Set("Content-Type", "application/json")
+	if config.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(config.Secret))
+		mac.Write(body)
+		httpReq.Header.Set(mfaWebhookSignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	client := cleanhttp.DefaultClient()
+	client.Timeout = config.Timeout
+
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return errors.New("could not call the MFA webhook")
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return errors.New("could not read the response of the MFA webhook")
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("the MFA webhook responded with status code %d", resp.StatusCode)
+	}
+
+	var result mfaWebhookResponse
+	if err := jsonutil.DecodeJSON(respBody, &result); err != nil {
+		return errors.New("could not decode the response of the MFA webhook")
+	}
+	if !result.Approved {
+		if result.Reason != "" {
+			return fmt.Errorf("the login was not approved: %s", result.Reason)
+		}
+		return errors.New("the login was not approved")
+	}
+
+	return nil
+}
+
+// totpMFAAlgorithm returns the TOTP library value of the validated algorithm
+func totpMFAAlgorithm(algorithm string) otplib.Algorithm {
+	switch algorithm {
+	case "SHA256":
+		return otplib.AlgorithmSHA256
+	case "SHA512":
+		return otplib.AlgorithmSHA512
+	}
+	return otplib.AlgorithmSHA1
+}
+
+// loginMFAPaths returns the paths of the login MFA methods and enforcements
+func loginMFAPaths(b *SystemBackend) []*framework.Path {
+	nameField := &framework.FieldSchema{
+		Type:        framework.TypeString,
+		Description: strings.TrimSpace(sysHelp["mfa-method-name"][0]),
+	}
+	entityIDField := &framework.FieldSchema{
+		Type:        framework.TypeString,
+		Description: "ID of the entity.",
+	}
+
+	return []*framework.Path{
+		{
+			Pattern: "mfa/method/?$",
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ListOperation: b.handleMFAMethodList,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["mfa-method-list"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["mfa-method-list"][1]),
+		},
+		{
+			Pattern: "mfa/method/totp/" + framework.GenericNameRegex("name") + "/generate$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"name": nameField,
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation: b.handleMFATOTPGenerate,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["mfa-totp-generate"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["mfa-totp-generate"][1]),
+		},
+		{
+			Pattern: "mfa/method/totp/" + framework.GenericNameRegex("name") + "/admin-generate$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"name":      nameField,
+				"entity_id": entityIDField,
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: b.handleMFATOTPAdminGenerate,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["mfa-totp-admin-generate"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["mfa-totp-admin-generate"][1]),
+		},
+		{
+			Pattern: "mfa/method/totp/" + framework.GenericNameRegex("name") + "/admin-destroy$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"name":      nameField,
+				"entity_id": entityIDField,
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: b.handleMFATOTPAdminDestroy,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["mfa-totp-admin-destroy"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["mfa-totp-admin-destroy"][1]),
+		},
+		{
+			Pattern: "mfa/method/totp/" + framework.GenericNameRegex("name") + "$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"name": nameField,
+				"issuer": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "The name of the key's issuing organization. This field is required.",
+				},
+				"period": &framework.FieldSchema{
+					Type:        framework.TypeDurationSecond,
+					Default:     30,
+					Description: "The length of time used to generate a counter for the TOTP token calculation.",
+				},
+				"key_size": &framework.FieldSchema{
+					Type:        framework.TypeInt,
+					Default:     20,
+					Description: "Determines the size in bytes of the generated key.",
+				},
+				"qr_size": &framework.FieldSchema{
+					Type:        framework.TypeInt,
+					Default:     200,
+					Description: "The pixel size of the generated square QR code. If 0, no QR code is returned.",
+				},
+				"algorithm": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Default:     "SHA1",
+					Description: "The hashing algorithm used to generate the TOTP passcodes. Options include SHA1, SHA256 and SHA512.",
+				},
+				"digits": &framework.FieldSchema{
+					Type:        framework.TypeInt,
+					Default:     6,
+					Description: "The number of digits in the generated TOTP passcodes. This value can either be 6 or 8.",
+				},
+				"skew": &framework.FieldSchema{
+					Type:        framework.TypeInt,
+					Default:     1,
+					Description: "The number of delay periods that are allowed when validating a TOTP passcode. This value can either be 0 or 1.",
+				},
+				"max_validation_attempts": &framework.FieldSchema{
+					Type:        framework.TypeInt,
+					Default:     defaultTOTPMaxValidationAttempts,
+					Description: "The number of failed validations of TOTP passcodes allowed for an entity per period.",
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation:   b.handleMFAMethodRead(mfaMethodTypeTOTP),
+				logical.UpdateOperation: b.handleMFAMethodWrite(mfaMethodTypeTOTP),
+				logical.DeleteOperation: b.handleMFAMethodDelete(mfaMethodTypeTOTP),
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["mfa-method-totp"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["mfa-method-totp"][1]),
+		},
+		{
+			Pattern: "mfa/method/duo/" + framework.GenericNameRegex("name") + "$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"name": nameField,
+				"mount_accessor": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "Accessor of the auth mount whose aliases are mapped to the Duo usernames. This field is required.",
+				},
+				"integration_key": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "Integration key of the Duo Auth API application. This field is required.",
+				},
+				"secret_key": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "Secret key of the Duo Auth API application. This field is required.",
+				},
+				"api_hostname": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "API hostname of the Duo Auth API application. This field is required.",
+				},
+				"username_format": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "Format of the Duo username, which may contain the {{alias.name}}, {{alias.metadata.<key>}}, {{entity.name}} and {{entity.metadata.<key>}} directives. Defaults to the name of the alias on the mount.",
+				},
+				"push_info": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "Additional information displayed in the push notifications, as a URL encoded string of key/value pairs.",
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation:   b.handleMFAMethodRead(mfaMethodTypeDuo),
+				logical.UpdateOperation: b.handleMFAMethodWrite(mfaMethodTypeDuo),
+				logical.DeleteOperation: b.handleMFAMethodDelete(mfaMethodTypeDuo),
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["mfa-method-duo"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["mfa-method-duo"][1]),
+		},
+		{
+			Pattern: "mfa/method/webhook/" + framework.GenericNameRegex("name") + "$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"name": nameField,
+				"url": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "URL the login details are sent to. This field is required.",
+				},
+				"secret": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "Secret used to sign the requests with HMAC-SHA256 in the X-Vault-MFA-Signature header.",
+				},
+				"timeout": &framework.FieldSchema{
+					Type:        framework.TypeDurationSecond,
+					Default:     30,
+					Description: "Time to wait for the response of the webhook.",
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation:   b.handleMFAMethodRead(mfaMethodTypeWebhook),
+				logical.UpdateOperation: b.handleMFAMethodWrite(mfaMethodTypeWebhook),
+				logical.DeleteOperation: b.handleMFAMethodDelete(mfaMethodTypeWebhook),
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["mfa-method-webhook"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["mfa-method-webhook"][1]),
+		},
+		{
+			Pattern: "mfa/login-enforcement/?$",
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ListOperation: b.handleMFAEnforcementList,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["mfa-login-enforcement-list"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["mfa-login-enforcement-list"][1]),
+		},
+		{
+			Pattern: "mfa/login-enforcement/" + framework.GenericNameRegex("name") + "$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"name": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "Name of the login enforcement.",
+				},
+				"mfa_method_names": &framework.FieldSchema{
+					Type:        framework.TypeCommaStringSlice,
+					Description: "Names of the MFA methods that are all required on the matching logins. This field is required.",
+				},
+				"auth_method_accessors": &framework.FieldSchema{
+					Type:        framework.TypeCommaStringSlice,
+					Description: "Accessors of the auth mounts whose logins are enforced.",
+				},
+				"auth_method_types": &framework.FieldSchema{
+					Type:        framework.TypeCommaStringSlice,
+					Description: "Types of the auth methods whose logins are enforced.",
+				},
+				"identity_entity_ids": &framework.FieldSchema{
+					Type:        framework.TypeCommaStringSlice,
+					Description: "IDs of the entities whose logins are enforced.",
+				},
+				"identity_group_ids": &framework.FieldSchema{
+					Type:        framework.TypeCommaStringSlice,
+					Description: "IDs of the groups whose member entities' logins are enforced.",
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation:   b.handleMFAEnforcementRead,
+				logical.UpdateOperation: b.handleMFAEnforcementWrite,
+				logical.DeleteOperation: b.handleMFAEnforcementDelete,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["mfa-login-enforcement"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["mfa-login-enforcement"][1]),
+		},
+	}
+}
+
+// handleMFAMethodList handles the "mfa/method" endpoint to list the MFA
+// methods
+func (b *SystemBackend) handleMFAMethodList(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	b.Core.loginMFALock.RLock()
+	defer b.Core.loginMFALock.RUnlock()
+
+	names, err := b.Core.mfaMethodView().List(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	keyInfo := make(map[string]interface{}, len(names))
+	for _, name := range names {
+		method, err := b.Core.getMFAMethod(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if method == nil {
+			continue
+		}
+		keyInfo[name] = map[string]interface{}{
+			"type": method.Type,
+		}
+	}
+
+	return logical.ListResponseWithInfo(names, keyInfo), nil
+}
+
+// handleMFAMethodRead returns the handler reading the MFA methods of the
+// given type
+func (b *SystemBackend) handleMFAMethodRead(methodType string) framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		b.Core.loginMFALock.RLock()
+		defer b.Core.loginMFALock.RUnlock()
+
+		method, err := b.Core.getMFAMethod(ctx, d.Get("name").(string))
+		if err != nil {
+			return nil, err
+		}
+		if method == nil || method.Type != methodType {
+			return nil, nil
+		}
+
+		data := map[string]interface{}{
+			"id":   method.ID,
+			"name": method.Name,
+			"type": method.Type,
+		}
+		switch methodType {
+		case mfaMethodTypeTOTP:
+			data["issuer"] = method.TOTP.Issuer
+			data["period"] = int64(method.TOTP.Period.Seconds())
+			data["key_size"] = method.TOTP.KeySize
+			data["qr_size"] = method.TOTP.QRSize
+			data["algorithm"] = method.TOTP.Algorithm
+			data["digits"] = method.TOTP.Digits
+			data["skew"] = method.TOTP.Skew
+			data["max_validation_attempts"] = method.TOTP.MaxValidationAttempts
+			if method.TOTP.MaxValidationAttempts == 0 {
+				data["max_validation_attempts"] = defaultTOTPMaxValidationAttempts
+			}
+		case mfaMethodTypeDuo:
+			// The secret key is not returned
+			data["mount_accessor"] = method.Duo.MountAccessor
+			data["integration_key"] = method.Duo.IntegrationKey
+			data["api_hostname"] = method.Duo.APIHostname
+			data["username_format"] = method.Duo.UsernameFormat
+			data["push_info"] = method.Duo.PushInfo
+		case mfaMethodTypeWebhook:
+			// The secret is not returned
+			data["url"] = method.Webhook.URL
+			data["timeout"] = int64(method.Webhook.Timeout.Seconds())
+		}
+
+		return &logical.Response{
+			Data: data,
+		}, nil
+	}
+}
+
+// handleMFAMethodWrite returns the handler creating or updating the MFA
+// methods of the given type
+func (b *SystemBackend) handleMFAMethodWrite(methodType string) framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		name := d.Get("name").(string)
+
+		b.Core.loginMFALock.Lock()
+		defer b.Core.loginMFALock.Unlock()
+
+		method, err := b.Core.getMFAMethod(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if method == nil {
+			id, err := uuid.GenerateUUID()
+			if err != nil {
+				return nil, err
+			}
+			method = &mfaMethod{
+				ID:   id,
+				Name: name,
+				Type: methodType,
+			}
+		}
+		if method.Type != methodType {
+			return logical.ErrorResponse(fmt.Sprintf("an MFA method of type %q named %q already exists", method.Type, name)), nil
+		}
+
+		switch methodType {
+		case mfaMethodTypeTOTP:
+			// The settings of the TOTP secrets can't change once secrets
+			// may have been generated
+			if method.TOTP != nil {
+				return logical.ErrorResponse("TOTP MFA methods cannot be updated"), nil
+			}
+			config := &totpMFAConfig{
+				Issuer:    d.Get("issuer").(string),
+				Period:    time.Duration(d.Get("period").(int)) * time.Second,
+				KeySize:   d.Get("key_size").(int),
+				QRSize:    d.Get("qr_size").(int),
+				Algorithm: d.Get("algorithm").(string),
+				Digits:    d.Get("digits").(int),
+				Skew:      d.Get("skew").(int),
+
+				MaxValidationAttempts: d.Get("max_validation_attempts").(int),
+			}
+			switch {
+			case config.Issuer == "":
+				return logical.ErrorResponse("missing issuer"), nil
+			case config.Period <= 0:
+				return logical.ErrorResponse("period must be greater than zero"), nil
+			case config.KeySize <= 0:
+				return logical.ErrorResponse("key_size must be greater than zero"), nil
+			case config.QRSize < 0:
+				return logical.ErrorResponse("qr_size cannot be negative"), nil
+			case !strutil.StrListContains([]string{"SHA1", "SHA256", "SHA512"}, config.Algorithm):
+				return logical.ErrorResponse("the algorithm value is not valid"), nil
+			case config.Digits != 6 && config.Digits != 8:
+				return logical.ErrorResponse("the digits value can only be 6 or 8"), nil
+			case config.Skew != 0 && config.Skew != 1:
+				return logical.ErrorResponse("the skew value must be 0 or 1"), nil
+			case config.MaxValidationAttempts <= 0:
+				return logical.ErrorResponse("max_validation_attempts must be greater than zero"), nil
+			}
+			method.TOTP = config
+
+		case mfaMethodTypeDuo:
+			config := method.Duo
+			if config == nil {
+				config = &duoMFAConfig{}
+			}
+			if raw, ok := d.GetOk("mount_accessor"); ok {
+				config.MountAccessor = raw.(string)
+			}
+			if raw, ok := d.GetOk("integration_key"); ok {
+				config.IntegrationKey = raw.(string)
+			}
+			if raw, ok := d.GetOk("secret_key"); ok {
+				config.SecretKey = raw.(string)
+			}
+			if raw, ok := d.GetOk("api_hostname"); ok {
+				config.APIHostname = raw.(string)
+			}
+			if raw, ok := d.GetOk("username_format"); ok {
+				config.UsernameFormat = raw.(string)
+			}
+			if raw, ok := d.GetOk("push_info"); ok {
+				config.PushInfo = raw.(string)
+			}
+			switch {
+			case config.MountAccessor == "":
+				return logical.ErrorResponse("missing mount_accessor"), nil
+			case b.Core.router.MatchingMountByAccessor(config.MountAccessor) == nil:
+				return logical.ErrorResponse(fmt.Sprintf("auth mount with accessor %q not found", config.MountAccessor)), nil
+			case config.IntegrationKey == "":
+				return logical.ErrorResponse("missing integration_key"), nil
+			case config.SecretKey == "":
+				return logical.ErrorResponse("missing secret_key"), nil
+			case config.APIHostname == "":
+				return logical.ErrorResponse("missing api_hostname"), nil
+			}
+			method.Duo = config
+
+		case mfaMethodTypeWebhook:
+			config := method.Webhook
+			if config == nil {
+				config = &webhookMFAConfig{}
+			}
+			if raw, ok := d.GetOk("url"); ok {
+				config.URL = raw.(string)
+			}
+			if raw, ok := d.GetOk("secret"); ok {
+				config.Secret = raw.(string)
+			}
+			if raw, ok := d.GetOk("timeout"); ok {
+				config.Timeout = time.Duration(raw.(int)) * time.Second
+			} else if config.Timeout == 0 {
+				config.Timeout = time.Duration(d.Get("timeout").(int)) * time.Second
+			}
+			if config.URL == "" {
+				return logical.ErrorResponse("missing url"), nil
+			}
+			u, err := url.Parse(config.URL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return logical.ErrorResponse("url must be an absolute http or https URL"), nil
+			}
+			if config.Timeout <= 0 {
+				return logical.ErrorResponse("timeout must be greater than zero"), nil
+			}
+			method.Webhook = config
+		}
+
+		if err := b.Core.setMFAMethod(ctx, method); err != nil {
+			return nil, err
+		}
+
+		return nil, nil
+	}
+}
+
+// handleMFAMethodDelete returns the handler deleting the MFA methods of the
+// given type. The methods used by login enforcements cannot be deleted.
+func (b *SystemBackend) handleMFAMethodDelete(methodType string) framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		name := d.Get("name").(string)
+
+		b.Core.loginMFALock.Lock()
+		defer b.Core.loginMFALock.Unlock()
+
+		method, err := b.Core.getMFAMethod(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if method == nil || method.Type != methodType {
+			return nil, nil
+		}
+
+		enforcements, err := b.Core.listMFAEnforcements(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, enforcement := range enforcements {
+			if strutil.StrListContains(enforcement.MFAMethodNames, name) {
+				return logical.ErrorResponse(fmt.Sprintf("unable to delete MFA method %q because it is used by login enforcement %q", name, enforcement.Name)), logical.ErrInvalidRequest
+			}
+		}
+
+		if err := logical.ClearView(ctx, b.Core.mfaTOTPSecretView(name)); err != nil {
+			return nil, err
+		}
+		if err := b.Core.mfaMethodView().Delete(ctx, name); err != nil {
+			return nil, err
+		}
+		b.Core.clearTOTPMFAUsage(method.ID, "")
+
+		return nil, nil
+	}
+}
+
+// totpMFAMethod returns the TOTP method of the request, or an error response
+// if there is no such method
+func (b *SystemBackend) totpMFAMethod(ctx context.Context, d *framework.FieldData) (*mfaMethod, *logical.Response, error) {
+	name := d.Get("name").(string)
+	method, err := b.Core.getMFAMethod(ctx, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if method == nil || method.Type != mfaMethodTypeTOTP {
+		return nil, logical.ErrorResponse(fmt.Sprintf("TOTP MFA method %q not found", name)), nil
+	}
+	return method, nil, nil
+}
+
+// handleMFATOTPGenerate handles the "mfa/method/totp/<name>/generate"
+// endpoint to generate a TOTP secret for the entity of the token making the
+// request
+func (b *SystemBackend) handleMFATOTPGenerate(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	if req.EntityID == "" {
+		return logical.ErrorResponse("the token is not tied to an entity"), nil
+	}
+
+	return b.generateTOTPMFASecret(ctx, d, req.EntityID)
+}
+
+// handleMFATOTPAdminGenerate handles the
+// "mfa/method/totp/<name>/admin-generate" endpoint to generate a TOTP secret
+// for the given entity
+func (b *SystemBackend) handleMFATOTPAdminGenerate(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	entityID := d.Get("entity_id").(string)
+	if entityID == "" {
+		return logical.ErrorResponse("missing entity_id"), nil
+	}
+
+	return b.generateTOTPMFASecret(ctx, d, entityID)
+}
+
+// generateTOTPMFASecret generates the TOTP secret of the entity, unless it
+// already has one. Secrets are never replaced, otherwise anyone holding a
+// token of the entity could bypass the second factor; they have to be
+// destroyed first.
+func (b *SystemBackend) generateTOTPMFASecret(ctx context.Context, d *framework.FieldData, entityID string) (*logical.Response, error) {
+	b.Core.loginMFALock.Lock()
+	defer b.Core.loginMFALock.Unlock()
+
+	method, resp, err := b.totpMFAMethod(ctx, d)
+	if resp != nil || err != nil {
+		return resp, err
+	}
+
+	entity, err := b.Core.identityStore.MemDBEntityByID(entityID, false)
+	if err != nil {
+		return nil, err
+	}
+	if entity == nil {
+		return logical.ErrorResponse(fmt.Sprintf("entity %q not found", entityID)), nil
+	}
+
+	secret, err := b.Core.getTOTPMFASecret(ctx, method.Name, entity.ID)
+	if err != nil {
+		return nil, err
+	}
+	if secret != nil {
+		resp := &logical.Response{}
+		resp.AddWarning("A TOTP secret was already generated for the entity")
+		return resp, nil
+	}
+
+	return b.Core.generateTOTPMFASecret(ctx, method, entity)
+}
+
+// handleMFATOTPAdminDestroy handles the
+// "mfa/method/totp/<name>/admin-destroy" endpoint to delete the TOTP secret
+// of the given entity
+func (b *SystemBackend) handleMFATOTPAdminDestroy(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	entityID := d.Get("entity_id").(string)
+	if entityID == "" {
+		return logical.ErrorResponse("missing entity_id"), nil
+	}
+
+	b.Core.loginMFALock.Lock()
+	defer b.Core.loginMFALock.Unlock()
+
+	method, resp, err := b.totpMFAMethod(ctx, d)
+	if resp != nil || err != nil {
+		return resp, err
+	}
+
+	if err := b.Core.mfaTOTPSecretView(method.Name).Delete(ctx, entityID); err != nil {
+		return nil, err
+	}
+	b.Core.clearTOTPMFAUsage(method.ID, entityID)
+
+	return nil, nil
+}
+
+// handleMFAEnforcementList handles the "mfa/login-enforcement" endpoint to
+// list the login enforcements
+func (b *SystemBackend) handleMFAEnforcementList(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	b.Core.loginMFALock.RLock()
+	defer b.Core.loginMFALock.RUnlock()
+
+	names, err := b.Core.mfaEnforcementView().List(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return logical.ListResponse(names), nil
+}
+
+// handleMFAEnforcementRead handles the "mfa/login-enforcement/<name>"
+// endpoint to read a login enforcement
+func (b *SystemBackend) handleMFAEnforcementRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	b.Core.loginMFALock.RLock()
+	defer b.Core.loginMFALock.RUnlock()
+
+	enforcement, err := b.Core.getMFAEnforcement(ctx, d.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if enforcement == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"name":                  enforcement.Name,
+			"mfa_method_names":      enforcement.MFAMethodNames,
+			"auth_method_accessors": enforcement.AuthMethodAccessors,
+			"auth_method_types":     enforcement.AuthMethodTypes,
+			"identity_entity_ids":   enforcement.IdentityEntityIDs,
+			"identity_group_ids":    enforcement.IdentityGroupIDs,
+		},
+	}, nil
+}
+
+// handleMFAEnforcementWrite handles the "mfa/login-enforcement/<name>"
+// endpoint to create or update a login enforcement
+func (b *SystemBackend) handleMFAEnforcementWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	b.Core.loginMFALock.Lock()
+	defer b.Core.loginMFALock.Unlock()
+
+	enforcement, err := b.Core.getMFAEnforcement(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if enforcement == nil {
+		enforcement = &mfaEnforcement{
+			Name: name,
+		}
+	}
+
+	if raw, ok := d.GetOk("mfa_method_names"); ok {
+		enforcement.MFAMethodNames = strutil.RemoveDuplicates(raw.([]string), false)
+	}
+	if raw, ok := d.GetOk("auth_method_accessors"); ok {
+		enforcement.AuthMethodAccessors = strutil.RemoveDuplicates(raw.([]string), false)
+	}
+	if raw, ok := d.GetOk("auth_method_types"); ok {
+		enforcement.AuthMethodTypes = strutil.RemoveDuplicates(raw.([]string), false)
+	}
+	if raw, ok := d.GetOk("identity_entity_ids"); ok {
+		enforcement.IdentityEntityIDs = strutil.RemoveDuplicates(raw.([]string), false)
+	}
+	if raw, ok := d.GetOk("identity_group_ids"); ok {
+		enforcement.IdentityGroupIDs = strutil.RemoveDuplicates(raw.([]string), false)
+	}
+
+	if len(enforcement.MFAMethodNames) == 0 {
+		return logical.ErrorResponse("missing mfa_method_names"), nil
+	}
+	for _, methodName := range enforcement.MFAMethodNames {
+		method, err := b.Core.getMFAMethod(ctx, methodName)
+		if err != nil {
+			return nil, err
+		}
+		if method == nil {
+			return logical.ErrorResponse(fmt.Sprintf("MFA method %q not found", methodName)), nil
+		}
+	}
+
+	if len(enforcement.AuthMethodAccessors) == 0 && len(enforcement.AuthMethodTypes) == 0 &&
+		len(enforcement.IdentityEntityIDs) == 0 && len(enforcement.IdentityGroupIDs) == 0 {
+		return logical.ErrorResponse("one of auth_method_accessors, auth_method_types, identity_entity_ids or identity_group_ids must be set"), nil
+	}
+	for _, accessor := range enforcement.AuthMethodAccessors {
+		if b.Core.router.MatchingMountByAccessor(accessor) == nil {
+			return logical.ErrorResponse(fmt.Sprintf("auth mount with accessor %q not found", accessor)), nil
+		}
+	}
+
+	if err := b.Core.setMFAEnforcement(ctx, enforcement); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// handleMFAEnforcementDelete handles the "mfa/login-enforcement/<name>"
+// endpoint to delete a login enforcement
+func (b *SystemBackend) handleMFAEnforcementDelete(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	b.Core.loginMFALock.Lock()
+	defer b.Core.loginMFALock.Unlock()
+
+	if err := b.Core.mfaEnforcementView().Delete(ctx, d.Get("name").(string)); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
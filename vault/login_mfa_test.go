@@ -0,0 +1,477 @@
+package vault
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/duosecurity/duo_api_golang/authapi"
+	"github.com/hashicorp/vault/helper/mfa/duo"
+	"github.com/hashicorp/vault/logical"
+	otplib "github.com/pquerna/otp"
+	totplib "github.com/pquerna/otp/totp"
+)
+
+// testLoginMFACore returns an unsealed core with a noop auth method mounted
+// at auth/foo, along with the root token and the entity ID of the login
+func testLoginMFACore(t *testing.T) (*Core, string, string) {
+	noop := &NoopBackend{
+		Login: []string{"login"},
+		Response: &logical.Response{
+			Auth: &logical.Auth{
+				Policies: []string{"foo"},
+				Alias: &logical.Alias{
+					Name: "armon",
+				},
+			},
+		},
+	}
+	c, _, root := TestCoreUnsealed(t)
+	c.credentialBackends["noop"] = func(context.Context, *logical.BackendConfig) (logical.Backend, error) {
+		return noop, nil
+	}
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "sys/auth/foo")
+	req.Data["type"] = "noop"
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	resp, err := c.HandleRequest(&logical.Request{Path: "auth/foo/login"})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Auth.EntityID == "" {
+		t.Fatalf("expected an entity: %#v", resp.Auth)
+	}
+
+	return c, root, resp.Auth.EntityID
+}
+
+func testLoginMFARequest(t *testing.T, c *Core, root, path string, data map[string]interface{}) *logical.Response {
+	req := logical.TestRequest(t, logical.UpdateOperation, path)
+	req.Data = data
+	req.ClientToken = root
+	resp, err := c.HandleRequest(req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+	return resp
+}
+
+func testLoginMFALogin(c *Core, creds logical.MFACreds) (*logical.Response, error) {
+	return c.HandleRequest(&logical.Request{
+		Path:     "auth/foo/login",
+		MFACreds: creds,
+	})
+}
+
+func TestLoginMFA_TOTP(t *testing.T) {
+	c, root, entityID := testLoginMFACore(t)
+
+	testLoginMFARequest(t, c, root, "sys/mfa/method/totp/my_totp", map[string]interface{}{
+		"issuer": "vault",
+	})
+
+	// The settings of TOTP methods cannot change
+	req := logical.TestRequest(t, logical.UpdateOperation, "sys/mfa/method/totp/my_totp")
+	req.Data["issuer"] = "other"
+	req.ClientToken = root
+	resp, err := c.HandleRequest(req)
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error updating the method: resp: %#v, err: %v", resp, err)
+	}
+
+	// A method name can only be used by one type
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/mfa/method/webhook/my_totp")
+	req.Data["url"] = "https://example.com"
+	req.ClientToken = root
+	resp, err = c.HandleRequest(req)
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error reusing the name: resp: %#v, err: %v", resp, err)
+	}
+
+	accessor := c.router.MatchingMountEntry("auth/foo/").Accessor
+	testLoginMFARequest(t, c, root, "sys/mfa/login-enforcement/foo", map[string]interface{}{
+		"mfa_method_names":      "my_totp",
+		"auth_method_accessors": accessor,
+	})
+
+	// The login is denied without a secret or a passcode
+	resp, err = testLoginMFALogin(c, nil)
+	if err != logical.ErrPermissionDenied || resp == nil || !resp.IsError() {
+		t.Fatalf("expected the login to be denied: resp: %#v, err: %v", resp, err)
+	}
+
+	resp = testLoginMFARequest(t, c, root, "sys/mfa/method/totp/my_totp/admin-generate", map[string]interface{}{
+		"entity_id": entityID,
+	})
+	if resp.Data["barcode"] == "" {
+		t.Fatalf("expected a barcode: %#v", resp.Data)
+	}
+	key, err := otplib.NewKeyFromURL(resp.Data["url"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err = testLoginMFALogin(c, logical.MFACreds{"my_totp": []string{"000000"}})
+	if err != logical.ErrPermissionDenied || !strings.Contains(resp.Data["error"].(string), "invalid TOTP passcode") {
+		t.Fatalf("expected the passcode to be invalid: resp: %#v, err: %v", resp, err)
+	}
+
+	passcode, err := totplib.GenerateCode(key.Secret(), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err = testLoginMFALogin(c, logical.MFACreds{"my_totp": []string{passcode}})
+	if err != nil || resp.Auth == nil || resp.Auth.ClientToken == "" {
+		t.Fatalf("expected the login to succeed: resp: %#v, err: %v", resp, err)
+	}
+
+	// A passcode is only accepted once
+	resp, err = testLoginMFALogin(c, logical.MFACreds{"my_totp": []string{passcode}})
+	if err != logical.ErrPermissionDenied || !strings.Contains(resp.Data["error"].(string), "code already used") {
+		t.Fatalf("expected the passcode to be rejected: resp: %#v, err: %v", resp, err)
+	}
+
+	// The method cannot be deleted while it is used by an enforcement
+	req = logical.TestRequest(t, logical.DeleteOperation, "sys/mfa/method/totp/my_totp")
+	req.ClientToken = root
+	resp, err = c.HandleRequest(req)
+	if err == nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected the deletion to fail: resp: %#v, err: %v", resp, err)
+	}
+
+	req = logical.TestRequest(t, logical.DeleteOperation, "sys/mfa/login-enforcement/foo")
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatal(err)
+	}
+	req = logical.TestRequest(t, logical.DeleteOperation, "sys/mfa/method/totp/my_totp")
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatal(err)
+	}
+
+	// The secrets of the method are deleted along with it
+	secret, err := c.getTOTPMFASecret(context.Background(), "my_totp", entityID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret != nil {
+		t.Fatal("expected the TOTP secret to be deleted")
+	}
+
+	resp, err = testLoginMFALogin(c, nil)
+	if err != nil || resp.Auth == nil {
+		t.Fatalf("expected the login to succeed: resp: %#v, err: %v", resp, err)
+	}
+}
+
+func TestLoginMFA_TOTPMaxValidationAttempts(t *testing.T) {
+	c, root, entityID := testLoginMFACore(t)
+
+	testLoginMFARequest(t, c, root, "sys/mfa/method/totp/my_totp", map[string]interface{}{
+		"issuer":                  "vault",
+		"max_validation_attempts": 2,
+	})
+	accessor := c.router.MatchingMountEntry("auth/foo/").Accessor
+	testLoginMFARequest(t, c, root, "sys/mfa/login-enforcement/foo", map[string]interface{}{
+		"mfa_method_names":      "my_totp",
+		"auth_method_accessors": accessor,
+	})
+	resp := testLoginMFARequest(t, c, root, "sys/mfa/method/totp/my_totp/admin-generate", map[string]interface{}{
+		"entity_id": entityID,
+	})
+	key, err := otplib.NewKeyFromURL(resp.Data["url"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The failed attempts are counted per period, so retry if the period
+	// changes in the middle of the test
+	for i := 0; i < 3; i++ {
+		start := time.Now().Unix() / 30
+		passcode, err := totplib.GenerateCode(key.Secret(), time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		n, err := strconv.Atoi(passcode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		invalid := fmt.Sprintf("%06d", (n+1)%1000000)
+		for j := 0; j < 2; j++ {
+			resp, err = testLoginMFALogin(c, logical.MFACreds{"my_totp": []string{invalid}})
+			if err != logical.ErrPermissionDenied || !strings.Contains(resp.Data["error"].(string), "invalid TOTP passcode") {
+				t.Fatalf("expected the passcode to be invalid: resp: %#v, err: %v", resp, err)
+			}
+		}
+
+		// The valid passcode is rejected once the attempts are exhausted
+		resp, err = testLoginMFALogin(c, logical.MFACreds{"my_totp": []string{passcode}})
+		if time.Now().Unix()/30 != start {
+			continue
+		}
+		if err != logical.ErrPermissionDenied || !strings.Contains(resp.Data["error"].(string), "maximum TOTP validation attempts exceeded") {
+			t.Fatalf("expected the attempts to be exhausted: resp: %#v, err: %v", resp, err)
+		}
+		return
+	}
+	t.Fatal("the period changed during every attempt")
+}
+
+func TestLoginMFA_TOTPGenerate(t *testing.T) {
+	c, root, entityID := testLoginMFACore(t)
+
+	testLoginMFARequest(t, c, root, "sys/mfa/method/totp/my_totp", map[string]interface{}{
+		"issuer":  "vault",
+		"qr_size": 0,
+	})
+
+	// Tokens without entities cannot generate secrets
+	req := logical.TestRequest(t, logical.ReadOperation, "sys/mfa/method/totp/my_totp/generate")
+	req.ClientToken = root
+	resp, err := c.HandleRequest(req)
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error: resp: %#v, err: %v", resp, err)
+	}
+
+	token := &TokenEntry{
+		Policies: []string{"root"},
+		EntityID: entityID,
+	}
+	if err := c.tokenStore.create(context.Background(), token); err != nil {
+		t.Fatal(err)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "sys/mfa/method/totp/my_totp/generate")
+	req.ClientToken = token.ID
+	resp, err = c.HandleRequest(req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+	if resp.Data["url"] == "" || resp.Data["barcode"] != nil {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// An existing secret is not replaced
+	req = logical.TestRequest(t, logical.ReadOperation, "sys/mfa/method/totp/my_totp/generate")
+	req.ClientToken = token.ID
+	resp, err = c.HandleRequest(req)
+	if err != nil || resp == nil || len(resp.Warnings) != 1 || resp.Data["url"] != nil {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+
+	testLoginMFARequest(t, c, root, "sys/mfa/method/totp/my_totp/admin-destroy", map[string]interface{}{
+		"entity_id": entityID,
+	})
+	secret, err := c.getTOTPMFASecret(context.Background(), "my_totp", entityID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret != nil {
+		t.Fatal("expected the TOTP secret to be deleted")
+	}
+}
+
+type testDuoAuthClient struct {
+	preauth  string
+	auth     string
+	factor   string
+	username string
+	passcode string
+}
+
+func (c *testDuoAuthClient) Preauth(options ...func(*url.Values)) (*authapi.PreauthResult, error) {
+	var result authapi.PreauthResult
+	if err := json.Unmarshal([]byte(`{"Stat": "OK", "Response": {"Result": "`+c.preauth+`"}}`), &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (c *testDuoAuthClient) Auth(factor string, options ...func(*url.Values)) (*authapi.AuthResult, error) {
+	values := url.Values{}
+	for _, o := range options {
+		o(&values)
+	}
+	c.factor = factor
+	c.username = values.Get("username")
+	c.passcode = values.Get("passcode")
+
+	var result authapi.AuthResult
+	if err := json.Unmarshal([]byte(`{"Stat": "OK", "Response": {"Result": "`+c.auth+`", "Status_Msg": "Login denied"}}`), &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func TestLoginMFA_Duo(t *testing.T) {
+	client := &testDuoAuthClient{
+		preauth: "auth",
+		auth:    "deny",
+	}
+	orig := newDuoMFAClient
+	newDuoMFAClient = func(*duoMFAConfig) duo.AuthClient {
+		return client
+	}
+	defer func() {
+		newDuoMFAClient = orig
+	}()
+
+	c, root, _ := testLoginMFACore(t)
+	accessor := c.router.MatchingMountEntry("auth/foo/").Accessor
+
+	testLoginMFARequest(t, c, root, "sys/mfa/method/duo/my_duo", map[string]interface{}{
+		"integration_key": "ikey",
+		"secret_key":      "skey",
+		"api_hostname":    "api-1234.duosecurity.com",
+		"mount_accessor":  accessor,
+		"username_format": "{{alias.name}}@example.com",
+	})
+
+	req := logical.TestRequest(t, logical.ReadOperation, "sys/mfa/method/duo/my_duo")
+	req.ClientToken = root
+	resp, err := c.HandleRequest(req)
+	if err != nil || resp == nil {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+	if _, ok := resp.Data["secret_key"]; ok {
+		t.Fatal("the secret key should not be returned")
+	}
+
+	testLoginMFARequest(t, c, root, "sys/mfa/login-enforcement/foo", map[string]interface{}{
+		"mfa_method_names":  "my_duo",
+		"auth_method_types": "noop",
+	})
+
+	resp, err = testLoginMFALogin(c, nil)
+	if err != logical.ErrPermissionDenied || !strings.Contains(resp.Data["error"].(string), "Login denied") {
+		t.Fatalf("expected the login to be denied: resp: %#v, err: %v", resp, err)
+	}
+	if client.factor != "push" || client.username != "armon@example.com" {
+		t.Fatalf("bad: %#v", client)
+	}
+
+	client.auth = "allow"
+	resp, err = testLoginMFALogin(c, logical.MFACreds{"my_duo": []string{"123456"}})
+	if err != nil || resp.Auth == nil {
+		t.Fatalf("expected the login to succeed: resp: %#v, err: %v", resp, err)
+	}
+	if client.factor != "passcode" || client.passcode != "123456" {
+		t.Fatalf("bad: %#v", client)
+	}
+}
+
+func TestLoginMFA_Webhook(t *testing.T) {
+	var received mfaWebhookRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mac := hmac.New(sha256.New, []byte("s3cr3t"))
+		mac.Write(body)
+		if r.Header.Get("X-Vault-MFA-Signature") != "sha256="+hex.EncodeToString(mac.Sum(nil)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if err := json.Unmarshal(body, &received); err != nil {
+			t.Fatal(err)
+		}
+		if received.Credential == "approve" {
+			w.Write([]byte(`{"approved": true}`))
+			return
+		}
+		w.Write([]byte(`{"approved": false, "reason": "not on call"}`))
+	}))
+	defer server.Close()
+
+	c, root, entityID := testLoginMFACore(t)
+
+	testLoginMFARequest(t, c, root, "sys/mfa/method/webhook/my_webhook", map[string]interface{}{
+		"url":    server.URL,
+		"secret": "s3cr3t",
+	})
+	testLoginMFARequest(t, c, root, "sys/mfa/login-enforcement/foo", map[string]interface{}{
+		"mfa_method_names":    "my_webhook",
+		"identity_entity_ids": entityID,
+	})
+
+	resp, err := testLoginMFALogin(c, nil)
+	if err != logical.ErrPermissionDenied || !strings.Contains(resp.Data["error"].(string), "not on call") {
+		t.Fatalf("expected the login to be denied: resp: %#v, err: %v", resp, err)
+	}
+	if received.EntityID != entityID || received.MountType != "noop" || received.Path != "auth/foo/login" {
+		t.Fatalf("bad: %#v", received)
+	}
+
+	resp, err = testLoginMFALogin(c, logical.MFACreds{"my_webhook": []string{"approve"}})
+	if err != nil || resp.Auth == nil {
+		t.Fatalf("expected the login to succeed: resp: %#v, err: %v", resp, err)
+	}
+}
+
+func TestLoginMFA_EnforcementValidation(t *testing.T) {
+	c, root, _ := testLoginMFACore(t)
+
+	testLoginMFARequest(t, c, root, "sys/mfa/method/totp/my_totp", map[string]interface{}{
+		"issuer": "vault",
+	})
+
+	cases := []map[string]interface{}{
+		// Missing methods
+		{"auth_method_types": "noop"},
+		// Unknown method
+		{"mfa_method_names": "unknown", "auth_method_types": "noop"},
+		// Missing selectors
+		{"mfa_method_names": "my_totp"},
+		// Unknown accessor
+		{"mfa_method_names": "my_totp", "auth_method_accessors": "auth_noop_unknown"},
+	}
+	for _, data := range cases {
+		req := logical.TestRequest(t, logical.UpdateOperation, "sys/mfa/login-enforcement/foo")
+		req.Data = data
+		req.ClientToken = root
+		resp, err := c.HandleRequest(req)
+		if err != nil || resp == nil || !resp.IsError() {
+			t.Fatalf("expected an error for %#v: resp: %#v, err: %v", data, resp, err)
+		}
+	}
+
+	testLoginMFARequest(t, c, root, "sys/mfa/login-enforcement/foo", map[string]interface{}{
+		"mfa_method_names":  "my_totp",
+		"auth_method_types": "noop",
+	})
+
+	req := logical.TestRequest(t, logical.ListOperation, "sys/mfa/login-enforcement")
+	req.ClientToken = root
+	resp, err := c.HandleRequest(req)
+	if err != nil || resp == nil || len(resp.Data["keys"].([]string)) != 1 {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+
+	req = logical.TestRequest(t, logical.ListOperation, "sys/mfa/method")
+	req.ClientToken = root
+	resp, err = c.HandleRequest(req)
+	if err != nil || resp == nil {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+	info := resp.Data["key_info"].(map[string]interface{})["my_totp"].(map[string]interface{})
+	if info["type"] != "totp" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+}
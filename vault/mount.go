@@ -160,17 +160,22 @@ func (t *MountTable) sortEntriesByPath() *MountTable {
 
 // MountEntry is used to represent a mount table entry
 type MountEntry struct {
-	Table       string            `json:"table"`             // The table it belongs to
-	Path        string            `json:"path"`              // Mount Path
-	Type        string            `json:"type"`              // Logical backend Type
-	Description string            `json:"description"`       // User-provided description
-	UUID        string            `json:"uuid"`              // Barrier view UUID
-	Accessor    string            `json:"accessor"`          // Unique but more human-friendly ID. Does not change, not used for any sensitive things (like as a salt, which the UUID sometimes is).
-	Config      MountConfig       `json:"config"`            // Configuration related to this mount (but not backend-derived)
-	Options     map[string]string `json:"options"`           // Backend options
-	Local       bool              `json:"local"`             // Local mounts are not replicated or affected by replication
-	SealWrap    bool              `json:"seal_wrap"`         // Whether to wrap CSPs
-	Tainted     bool              `json:"tainted,omitempty"` // Set as a Write-Ahead flag for unmount/remount
+	Table       string            `json:"table"`                  // The table it belongs to
+	Path        string            `json:"path"`                   // Mount Path
+	Type        string            `json:"type"`                   // Logical backend Type
+	Description string            `json:"description"`            // User-provided description
+	UUID        string            `json:"uuid"`                   // Barrier view UUID
+	Accessor    string            `json:"accessor"`               // Unique but more human-friendly ID. Does not change, not used for any sensitive things (like as a salt, which the UUID sometimes is).
+	Config      MountConfig       `json:"config"`                 // Configuration related to this mount (but not backend-derived)
+	Options     map[string]string `json:"options"`                // Backend options
+	Local       bool              `json:"local"`                  // Local mounts are not replicated or affected by replication
+	SealWrap    bool              `json:"seal_wrap"`              // Whether to wrap CSPs
+	Tainted     bool              `json:"tainted,omitempty"`      // Set as a Write-Ahead flag for unmount/remount
+	NamespaceID string            `json:"namespace_id,omitempty"` // ID of the namespace of the mount, empty for the root namespace
+
+	// PurgeTime is when the mount, marked for deletion, is purged. The mount
+	// is tainted and can be restored until then.
+	PurgeTime time.Time `json:"purge_time,omitempty"`
 }
 
 // MountConfig is used to hold settable options
@@ -179,14 +184,69 @@ type MountConfig struct {
 	MaxLeaseTTL     time.Duration `json:"max_lease_ttl" structs:"max_lease_ttl" mapstructure:"max_lease_ttl"`             // Override for global default
 	ForceNoCache    bool          `json:"force_no_cache" structs:"force_no_cache" mapstructure:"force_no_cache"`          // Override for global default
 	PluginName      string        `json:"plugin_name,omitempty" structs:"plugin_name,omitempty" mapstructure:"plugin_name"`
+
+	// PluginVersion pins the mount to a version of the plugin registered in
+	// the catalog, the unversioned entry of the plugin is used if empty
+	PluginVersion string `json:"plugin_version,omitempty" structs:"plugin_version,omitempty" mapstructure:"plugin_version"`
+
+	// SyncExternalGroups makes logins through an auth mount create or link
+	// the external groups of the group aliases returned by the backend
+	SyncExternalGroups bool `json:"sync_external_groups,omitempty" structs:"sync_external_groups" mapstructure:"sync_external_groups"`
+
+	// TokenType is the type of the tokens issued by logins through an auth
+	// mount, service tokens if empty
+	TokenType string `json:"token_type,omitempty" structs:"token_type" mapstructure:"token_type"`
+
+	// AuditNonHMACRequestKeys and AuditNonHMACResponseKeys are the keys of
+	// the request and response data of the mount which the audit devices log
+	// in plaintext
+	AuditNonHMACRequestKeys  []string `json:"audit_non_hmac_request_keys,omitempty" structs:"audit_non_hmac_request_keys" mapstructure:"audit_non_hmac_request_keys"`
+	AuditNonHMACResponseKeys []string `json:"audit_non_hmac_response_keys,omitempty" structs:"audit_non_hmac_response_keys" mapstructure:"audit_non_hmac_response_keys"`
+
+	// ListingVisibility is whether the mount is listed by the unauthenticated
+	// sys/internal/ui/mounts endpoint
+	ListingVisibility string `json:"listing_visibility,omitempty" structs:"listing_visibility" mapstructure:"listing_visibility"`
+
+	// PassthroughRequestHeaders are the headers of the requests passed to the
+	// backend of the mount, which sees no header otherwise
+	PassthroughRequestHeaders []string `json:"passthrough_request_headers,omitempty" structs:"passthrough_request_headers" mapstructure:"passthrough_request_headers"`
+
+	// AllowedResponseHeaders are the headers of the responses of the backend
+	// of the mount which are sent to the clients
+	AllowedResponseHeaders []string `json:"allowed_response_headers,omitempty" structs:"allowed_response_headers" mapstructure:"allowed_response_headers"`
+
+	// MaxInFlightRequests is the maximum number of requests to the mount
+	// handled at once, zero for no limit
+	MaxInFlightRequests int `json:"max_in_flight_requests,omitempty" structs:"max_in_flight_requests" mapstructure:"max_in_flight_requests"`
 }
 
+const (
+	// ListingVisibilityDefault hides the mount from the unauthenticated
+	// listing of the mounts
+	ListingVisibilityDefault = ""
+
+	// ListingVisibilityUnauth lists the mount in the unauthenticated listing
+	// of the mounts
+	ListingVisibilityUnauth = "unauth"
+)
+
 // APIMountConfig is an embedded struct of api.MountConfigInput
 type APIMountConfig struct {
 	DefaultLeaseTTL string `json:"default_lease_ttl" structs:"default_lease_ttl" mapstructure:"default_lease_ttl"`
 	MaxLeaseTTL     string `json:"max_lease_ttl" structs:"max_lease_ttl" mapstructure:"max_lease_ttl"`
 	ForceNoCache    bool   `json:"force_no_cache" structs:"force_no_cache" mapstructure:"force_no_cache"`
 	PluginName      string `json:"plugin_name,omitempty" structs:"plugin_name,omitempty" mapstructure:"plugin_name"`
+	PluginVersion   string `json:"plugin_version,omitempty" structs:"plugin_version,omitempty" mapstructure:"plugin_version"`
+
+	SyncExternalGroups bool   `json:"sync_external_groups,omitempty" structs:"sync_external_groups" mapstructure:"sync_external_groups"`
+	TokenType          string `json:"token_type,omitempty" structs:"token_type" mapstructure:"token_type"`
+
+	AuditNonHMACRequestKeys   []string `json:"audit_non_hmac_request_keys,omitempty" structs:"audit_non_hmac_request_keys" mapstructure:"audit_non_hmac_request_keys"`
+	AuditNonHMACResponseKeys  []string `json:"audit_non_hmac_response_keys,omitempty" structs:"audit_non_hmac_response_keys" mapstructure:"audit_non_hmac_response_keys"`
+	ListingVisibility         string   `json:"listing_visibility,omitempty" structs:"listing_visibility" mapstructure:"listing_visibility"`
+	PassthroughRequestHeaders []string `json:"passthrough_request_headers,omitempty" structs:"passthrough_request_headers" mapstructure:"passthrough_request_headers"`
+	AllowedResponseHeaders    []string `json:"allowed_response_headers,omitempty" structs:"allowed_response_headers" mapstructure:"allowed_response_headers"`
+	MaxInFlightRequests       int      `json:"max_in_flight_requests,omitempty" structs:"max_in_flight_requests" mapstructure:"max_in_flight_requests"`
 }
 
 // Clone returns a deep copy of the mount entry
@@ -205,9 +265,15 @@ func (c *Core) mount(ctx context.Context, entry *MountEntry) error {
 		entry.Path += "/"
 	}
 
+	ns := c.namespaceByID(entry.NamespaceID)
+	if ns == nil {
+		return logical.CodedError(404, "namespace of the mount does not exist")
+	}
+
 	// Prevent protected paths from being mounted
+	relPath := strings.TrimPrefix(entry.Path, ns.Path)
 	for _, p := range protectedMounts {
-		if strings.HasPrefix(entry.Path, p) {
+		if strings.HasPrefix(relPath, p) {
 			return logical.CodedError(403, fmt.Sprintf("cannot mount '%s'", entry.Path))
 		}
 	}
@@ -218,6 +284,11 @@ func (c *Core) mount(ctx context.Context, entry *MountEntry) error {
 			return logical.CodedError(403, fmt.Sprintf("Cannot mount more than one instance of '%s'", entry.Type))
 		}
 	}
+
+	// Mounts cannot overlap the paths of other namespaces
+	if match := c.namespaceConflict(ns, entry.Path); match != "" {
+		return logical.CodedError(409, fmt.Sprintf("existing namespace at %s", match))
+	}
 	return c.mountInternal(ctx, entry)
 }
 
@@ -297,24 +368,25 @@ func (c *Core) mountInternal(ctx context.Context, entry *MountEntry) error {
 	return nil
 }
 
-// Unmount is used to unmount a path. The boolean indicates whether the mount
-// was found.
-func (c *Core) unmount(ctx context.Context, path string) error {
+// Unmount is used to unmount a path. If force is set, the errors revoking the
+// leases of the mount are ignored.
+func (c *Core) unmount(ctx context.Context, path string, force bool) error {
 	// Ensure we end the path in a slash
 	if !strings.HasSuffix(path, "/") {
 		path += "/"
 	}
 
 	// Prevent protected paths from being unmounted
+	relPath := c.mountNamespaceRelativePath(c.router.MatchingMountEntry(path), path)
 	for _, p := range protectedMounts {
-		if strings.HasPrefix(path, p) {
+		if strings.HasPrefix(relPath, p) {
 			return fmt.Errorf("cannot unmount '%s'", path)
 		}
 	}
-	return c.unmountInternal(ctx, path)
+	return c.unmountInternal(ctx, path, force)
 }
 
-func (c *Core) unmountInternal(ctx context.Context, path string) error {
+func (c *Core) unmountInternal(ctx context.Context, path string, force bool) error {
 	// Verify exact match of the route
 	match := c.router.MatchingMount(path)
 	if match == "" || path != match {
@@ -348,7 +420,11 @@ func (c *Core) unmountInternal(ctx context.Context, path string) error {
 		}
 
 		// Revoke all the dynamic keys
-		if err := c.expiration.RevokePrefix(path); err != nil {
+		revoke := c.expiration.RevokePrefix
+		if force {
+			revoke = c.expiration.RevokeForce
+		}
+		if err := revoke(path); err != nil {
 			return err
 		}
 
@@ -446,7 +522,7 @@ func (c *Core) remountForce(ctx context.Context, path string) error {
 		return err
 	}
 
-	if err := c.unmount(ctx, path); err != nil {
+	if err := c.unmount(ctx, path, false); err != nil {
 		return err
 	}
 	return c.mount(ctx, me)
@@ -463,8 +539,10 @@ func (c *Core) remount(ctx context.Context, src, dst string) error {
 	}
 
 	// Prevent protected paths from being remounted
+	srcEntry := c.router.MatchingMountEntry(src)
+	relPath := c.mountNamespaceRelativePath(srcEntry, src)
 	for _, p := range protectedMounts {
-		if strings.HasPrefix(src, p) {
+		if strings.HasPrefix(relPath, p) {
 			return fmt.Errorf("cannot remount '%s'", src)
 		}
 	}
@@ -479,6 +557,12 @@ func (c *Core) remount(ctx context.Context, src, dst string) error {
 		return fmt.Errorf("existing mount at '%s'", match)
 	}
 
+	if ns := c.namespaceByID(srcEntry.NamespaceID); ns != nil {
+		if match := c.namespaceConflict(ns, dst); match != "" {
+			return fmt.Errorf("existing namespace at '%s'", match)
+		}
+	}
+
 	// Mark the entry as tainted
 	if err := c.taintMountEntry(ctx, src); err != nil {
 		return err
@@ -599,7 +683,7 @@ func (c *Core) loadMounts(ctx context.Context) error {
 	for _, requiredMount := range c.requiredMountTable().Entries {
 		foundRequired := false
 		for _, coreMount := range c.mounts.Entries {
-			if coreMount.Type == requiredMount.Type {
+			if coreMount.Type == requiredMount.Type && coreMount.NamespaceID == "" {
 				foundRequired = true
 				break
 			}
@@ -639,8 +723,8 @@ func (c *Core) loadMounts(ctx context.Context) error {
 	}
 
 	// Done if we have restored the mount table and we don't need
-	// to persist
-	if !needPersist {
+	// to persist. Performance standbys leave persisting to the active node.
+	if !needPersist || c.perfStandby {
 		return nil
 	}
 
@@ -745,7 +829,7 @@ func (c *Core) setupMounts(ctx context.Context) error {
 		// ensure that it is reset after. This ensures that there will be no
 		// writes during the construction of the backend.
 		view.setReadOnlyErr(logical.ErrSetupReadOnly)
-		defer view.setReadOnlyErr(nil)
+		defer view.setReadOnlyErr(c.mountReadOnlyErr())
 
 		var backend logical.Backend
 		var err error
@@ -853,10 +937,14 @@ func (c *Core) newLogicalBackend(ctx context.Context, t string, sysView logical.
 // mount-specific entries; because this should be called when setting
 // up a mountEntry, it doesn't check to ensure that me is not nil
 func (c *Core) mountEntrySysView(entry *MountEntry) logical.SystemView {
-	return dynamicSystemView{
+	sysView := dynamicSystemView{
 		core:       c,
 		mountEntry: entry,
 	}
+	if c.entropySource != nil {
+		return entropySystemView{sysView}
+	}
+	return sysView
 }
 
 // defaultMountTable creates a default mount table
@@ -963,7 +1051,7 @@ func (c *Core) singletonMountTables() (mounts, auth *MountTable) {
 
 	c.mountsLock.RLock()
 	for _, entry := range c.mounts.Entries {
-		if strutil.StrListContains(singletonMounts, entry.Type) && !entry.Local {
+		if strutil.StrListContains(singletonMounts, entry.Type) && !entry.Local && entry.NamespaceID == "" {
 			mounts.Entries = append(mounts.Entries, entry)
 		}
 	}
@@ -990,6 +1078,24 @@ func (c *Core) setCoreBackend(entry *MountEntry, backend logical.Backend, view *
 		ch.saltUUID = entry.UUID
 		ch.storageView = view
 	case "identity":
+		if entry.NamespaceID != "" {
+			c.setNamespaceIdentityStore(entry.NamespaceID, backend.(*IdentityStore))
+			return
+		}
 		c.identityStore = backend.(*IdentityStore)
 	}
 }
+
+// mountNamespaceRelativePath returns the path of a mount relative to the
+// namespace of the mount entry, which is the path itself for mounts of the
+// root namespace
+func (c *Core) mountNamespaceRelativePath(entry *MountEntry, path string) string {
+	if entry == nil || entry.NamespaceID == "" {
+		return path
+	}
+	ns := c.namespaceByID(entry.NamespaceID)
+	if ns == nil {
+		return path
+	}
+	return strings.TrimPrefix(path, ns.Path)
+}
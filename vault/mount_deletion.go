@@ -0,0 +1,252 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/helper/consts"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// mountPurgeCheckInterval is how often the active node purges the mounts
+// whose recovery window elapsed. It's var so that tests can lower it.
+var mountPurgeCheckInterval = time.Minute
+
+// setPurgeTime is used to mark the entry of the given path for deletion at
+// the purge time, or to unmark it if the purge time is zero
+func (t *MountTable) setPurgeTime(path string, purgeTime time.Time) *MountEntry {
+	n := len(t.Entries)
+	for i := 0; i < n; i++ {
+		if t.Entries[i].Path == path {
+			t.Entries[i].Tainted = !purgeTime.IsZero()
+			t.Entries[i].PurgeTime = purgeTime
+			return t.Entries[i]
+		}
+	}
+	return nil
+}
+
+// markedForDeletion returns whether the mount was marked for deletion and
+// awaits its purge
+func (e *MountEntry) markedForDeletion() bool {
+	return !e.PurgeTime.IsZero()
+}
+
+// markMountForDeletion takes the mount of the path out of service until the
+// recovery window elapses, when it is purged. Its data and its leases are
+// kept until then so that the mount can be restored.
+func (c *Core) markMountForDeletion(ctx context.Context, path string, window time.Duration) (time.Time, error) {
+	// Ensure we end the path in a slash
+	if !strings.HasSuffix(path, "/") {
+		path += "/"
+	}
+
+	// Prevent protected paths from being unmounted
+	relPath := c.mountNamespaceRelativePath(c.router.MatchingMountEntry(path), path)
+	for _, p := range protectedMounts {
+		if strings.HasPrefix(relPath, p) {
+			return time.Time{}, fmt.Errorf("cannot unmount '%s'", path)
+		}
+	}
+
+	// Verify exact match of the route
+	match := c.router.MatchingMount(path)
+	if match == "" || path != match {
+		return time.Time{}, fmt.Errorf("no matching mount")
+	}
+	if entry := c.router.MatchingMountEntry(path); entry != nil && entry.markedForDeletion() {
+		return time.Time{}, fmt.Errorf("mount '%s' is already marked for deletion", path)
+	}
+
+	purgeTime := time.Now().Add(window).UTC()
+	if err := c.setMountPurgeTime(ctx, path, purgeTime); err != nil {
+		c.logger.Error("core: failed to mark mount entry for deletion", "error", err, "path", path)
+		return time.Time{}, err
+	}
+
+	// Taint the router path to prevent routing, the leases of the mount
+	// expiring meanwhile are still revoked
+	if err := c.router.Taint(path); err != nil {
+		return time.Time{}, err
+	}
+
+	if c.logger.IsInfo() {
+		c.logger.Info("core: marked mount for deletion", "path", path, "purge_time", purgeTime)
+	}
+	return purgeTime, nil
+}
+
+// restoreMount puts back into service the mount of the path marked for
+// deletion
+func (c *Core) restoreMount(ctx context.Context, path string) error {
+	if !strings.HasSuffix(path, "/") {
+		path += "/"
+	}
+
+	match := c.router.MatchingMount(path)
+	entry := c.router.MatchingMountEntry(path)
+	if match == "" || path != match || entry == nil {
+		return fmt.Errorf("no matching mount")
+	}
+	if !entry.markedForDeletion() {
+		return fmt.Errorf("mount '%s' is not marked for deletion", path)
+	}
+
+	if err := c.setMountPurgeTime(ctx, path, time.Time{}); err != nil {
+		c.logger.Error("core: failed to restore mount entry", "error", err, "path", path)
+		return err
+	}
+	if err := c.router.Untaint(path); err != nil {
+		return err
+	}
+
+	if c.logger.IsInfo() {
+		c.logger.Info("core: successfully restored mount", "path", path)
+	}
+	return nil
+}
+
+// purgeMount unmounts the mount of the path marked for deletion before its
+// recovery window elapses
+func (c *Core) purgeMount(ctx context.Context, path string, force bool) error {
+	if !strings.HasSuffix(path, "/") {
+		path += "/"
+	}
+
+	match := c.router.MatchingMount(path)
+	entry := c.router.MatchingMountEntry(path)
+	if match == "" || path != match || entry == nil {
+		return fmt.Errorf("no matching mount")
+	}
+	if !entry.markedForDeletion() {
+		return fmt.Errorf("mount '%s' is not marked for deletion", path)
+	}
+
+	return c.unmountInternal(ctx, path, force)
+}
+
+// setMountPurgeTime is used to mark an entry in the mount table for deletion
+// at the purge time, or to unmark it if the purge time is zero
+func (c *Core) setMountPurgeTime(ctx context.Context, path string, purgeTime time.Time) error {
+	c.mountsLock.Lock()
+	defer c.mountsLock.Unlock()
+
+	// As with the taint, modifying the entry affects shallow clones so we
+	// simply use the original
+	entry := c.mounts.setPurgeTime(path, purgeTime)
+	if entry == nil {
+		c.logger.Error("core: nil entry found marking entry in mounts table", "path", path)
+		return logical.CodedError(500, "failed to mark entry in mounts table")
+	}
+
+	// Update the mount table
+	if err := c.persistMounts(ctx, c.mounts, entry.Local); err != nil {
+		c.logger.Error("core: failed to mark entry in mounts table", "error", err)
+		return logical.CodedError(500, "failed to mark entry in mounts table")
+	}
+
+	return nil
+}
+
+// startMountPurge starts purging periodically the mounts whose recovery
+// window elapsed
+func (c *Core) startMountPurge(ctx context.Context) {
+	c.mountPurgeStopCh = make(chan struct{})
+	go c.mountPurgeLoop(ctx, c.mountPurgeStopCh)
+}
+
+// stopMountPurge stops the periodic purge of the mounts
+func (c *Core) stopMountPurge() {
+	if c.mountPurgeStopCh == nil {
+		return
+	}
+	close(c.mountPurgeStopCh)
+	c.mountPurgeStopCh = nil
+}
+
+func (c *Core) mountPurgeLoop(ctx context.Context, stopCh chan struct{}) {
+	ticker := time.NewTicker(mountPurgeCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.purgeExpiredMounts(ctx, time.Now())
+		case <-stopCh:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// purgeExpiredMounts unmounts the mounts marked for deletion whose recovery
+// window elapsed at the given time
+func (c *Core) purgeExpiredMounts(ctx context.Context, now time.Time) {
+	secondary := c.ReplicationState().HasState(consts.ReplicationPerformanceSecondary)
+
+	var paths []string
+	c.mountsLock.RLock()
+	for _, entry := range c.mounts.Entries {
+		if !entry.markedForDeletion() || now.Before(entry.PurgeTime) {
+			continue
+		}
+		// The primary purges the replicated mounts
+		if secondary && !entry.Local {
+			continue
+		}
+		paths = append(paths, entry.Path)
+	}
+	c.mountsLock.RUnlock()
+
+	for _, path := range paths {
+		c.logger.Info("core: purging mount after its recovery window", "path", path)
+		if err := c.unmountInternal(ctx, path, false); err != nil {
+			c.logger.Error("core: failed to purge mount", "path", path, "error", err)
+		}
+	}
+}
+
+// handleMountRestore is used to restore a mount marked for deletion
+func (b *SystemBackend) handleMountRestore(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	ns := namespaceFromContext(ctx)
+	path := ns.Path + sanitizeMountPath(data.Get("path").(string))
+
+	entry := b.Core.router.MatchingMountEntry(path)
+	if entry == nil || entry.NamespaceID != ns.ID {
+		return logical.ErrorResponse(fmt.Sprintf("no mount at '%s'", path)), logical.ErrInvalidRequest
+	}
+	if !entry.Local && b.Core.ReplicationState().HasState(consts.ReplicationPerformanceSecondary) {
+		return logical.ErrorResponse("cannot restore a non-local mount on a replication secondary"), nil
+	}
+
+	if err := b.Core.restoreMount(ctx, path); err != nil {
+		b.Backend.Logger().Error("sys: restore failed", "path", path, "error", err)
+		return handleError(err)
+	}
+	return nil, nil
+}
+
+// handleMountPurge is used to purge a mount marked for deletion without
+// waiting for its recovery window to elapse
+func (b *SystemBackend) handleMountPurge(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	ns := namespaceFromContext(ctx)
+	path := ns.Path + sanitizeMountPath(data.Get("path").(string))
+
+	entry := b.Core.router.MatchingMountEntry(path)
+	if entry == nil || entry.NamespaceID != ns.ID {
+		return logical.ErrorResponse(fmt.Sprintf("no mount at '%s'", path)), logical.ErrInvalidRequest
+	}
+	if !entry.Local && b.Core.ReplicationState().HasState(consts.ReplicationPerformanceSecondary) {
+		return logical.ErrorResponse("cannot unmount a non-local mount on a replication secondary"), nil
+	}
+
+	if err := b.Core.purgeMount(ctx, path, data.Get("force").(bool)); err != nil {
+		b.Backend.Logger().Error("sys: purge failed", "path", path, "error", err)
+		return handleError(err)
+	}
+	return nil, nil
+}
@@ -0,0 +1,120 @@
+package vault
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestCore_MarkMountForDeletion(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	ctx := context.Background()
+
+	req := &logical.Request{
+		Operation:   logical.UpdateOperation,
+		Path:        "secret/foo",
+		Data:        map[string]interface{}{"value": "bar"},
+		ClientToken: root,
+	}
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if _, err := c.markMountForDeletion(ctx, "secret", time.Hour); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := c.markMountForDeletion(ctx, "secret", time.Hour); err == nil {
+		t.Fatal("expected an error marking the mount twice")
+	}
+
+	// The mount is kept but no longer serves requests
+	if match := c.router.MatchingMount("secret/foo"); match != "secret/" {
+		t.Fatalf("expected the mount to be kept, got %q", match)
+	}
+	req.Operation = logical.ReadOperation
+	req.Data = nil
+	if _, err := c.HandleRequest(req); err == nil {
+		t.Fatal("expected an error reading from a mount marked for deletion")
+	}
+
+	// Nothing is purged before the recovery window elapses
+	c.purgeExpiredMounts(ctx, time.Now())
+	if match := c.router.MatchingMount("secret/foo"); match != "secret/" {
+		t.Fatalf("expected the mount to be kept, got %q", match)
+	}
+
+	if err := c.restoreMount(ctx, "secret"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	resp, err := c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp == nil || resp.Data["value"] != "bar" {
+		t.Fatalf("bad: %#v", resp)
+	}
+	if err := c.restoreMount(ctx, "secret"); err == nil {
+		t.Fatal("expected an error restoring a mount not marked for deletion")
+	}
+
+	// The mount is purged once the recovery window elapses
+	if _, err := c.markMountForDeletion(ctx, "secret", time.Hour); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	c.purgeExpiredMounts(ctx, time.Now().Add(2*time.Hour))
+	if match := c.router.MatchingMount("secret/foo"); match != "" {
+		t.Fatalf("expected the mount to be purged, got %q", match)
+	}
+}
+
+func TestCore_PurgeMount(t *testing.T) {
+	c, _, _ := TestCoreUnsealed(t)
+	ctx := context.Background()
+
+	if err := c.purgeMount(ctx, "secret", false); err == nil {
+		t.Fatal("expected an error purging a mount not marked for deletion")
+	}
+
+	if _, err := c.markMountForDeletion(ctx, "secret", 24*time.Hour); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := c.purgeMount(ctx, "secret", false); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if match := c.router.MatchingMount("secret/foo"); match != "" {
+		t.Fatalf("expected the mount to be purged, got %q", match)
+	}
+}
+
+func TestSystemBackend_unmountRecoveryWindow(t *testing.T) {
+	c, b, _ := testCoreSystemBackend(t)
+
+	req := logical.TestRequest(t, logical.DeleteOperation, "mounts/secret/")
+	req.Data["recovery_window"] = 3600
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp == nil || resp.Data["purge_time"] == "" {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "mounts")
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, ok := resp.Data["secret/"].(map[string]interface{})["purge_time"]; !ok {
+		t.Fatalf("expected a purge time, got %#v", resp.Data["secret/"])
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "mounts/secret/restore")
+	if _, err := b.HandleRequest(context.Background(), req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if entry := c.router.MatchingMountEntry("secret/"); entry == nil || entry.markedForDeletion() || entry.Tainted {
+		t.Fatalf("bad: %#v", entry)
+	}
+}
@@ -206,7 +206,7 @@ func TestCore_Mount_Local(t *testing.T) {
 
 func TestCore_Unmount(t *testing.T) {
 	c, keys, _ := TestCoreUnsealed(t)
-	err := c.unmount(context.Background(), "secret")
+	err := c.unmount(context.Background(), "secret", false)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
@@ -297,7 +297,7 @@ func TestCore_Unmount_Cleanup(t *testing.T) {
 	}
 
 	// Unmount, this should cleanup
-	if err := c.unmount(context.Background(), "test/"); err != nil {
+	if err := c.unmount(context.Background(), "test/", false); err != nil {
 		t.Fatalf("err: %v", err)
 	}
 
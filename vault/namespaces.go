@@ -0,0 +1,678 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/helper/random"
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+const (
+	// coreNamespaceConfigPath is used to store the namespace table
+	coreNamespaceConfigPath = "core/namespaces"
+
+	// namespaceSubPath is the sub-path of the system view holding the data of
+	// the child namespaces, such as their policies
+	namespaceSubPath = "namespaces/"
+
+	// namespaceIDLength is the length of the generated namespace IDs
+	namespaceIDLength = 5
+)
+
+var (
+	// errLoadNamespacesFailed if loadNamespaces encounters an error
+	errLoadNamespacesFailed = errors.New("failed to setup namespace table")
+
+	// rootNamespace is the namespace of the requests that do not target a
+	// child namespace. Its ID is empty so that the mounts and tokens created
+	// before namespaces existed belong to it.
+	rootNamespace = &Namespace{}
+
+	// namespaceNameRe matches the valid path segments of namespaces
+	namespaceNameRe = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+	// reservedNamespaceNames cannot be used as namespace names, since the
+	// resulting paths would be ambiguous with the paths of the mounts
+	reservedNamespaceNames = []string{
+		"audit",
+		"auth",
+		"cubbyhole",
+		"identity",
+		"root",
+		"sys",
+	}
+
+	// namespaceSharedPaths are served by the backends of the root namespace
+	// for all the namespaces, which find the namespace of the request in its
+	// context
+	namespaceSharedPaths = []string{
+		"sys/",
+		"auth/token/",
+		"cubbyhole/",
+	}
+
+	// namespaceAllowedPaths are the paths of the shared backends that can be
+	// requested in child namespaces. The paths ending in a slash also allow
+	// the paths below them.
+	namespaceAllowedPaths = []string{
+		"sys/auth",
+		"sys/auth/",
+		"sys/capabilities",
+		"sys/capabilities-accessor",
+		"sys/capabilities-self",
+		"sys/events/subscribe",
+		"sys/leases/lookup",
+		"sys/leases/renew",
+		"sys/leases/renew/",
+		"sys/leases/revoke",
+		"sys/leases/revoke/",
+		"sys/leases/revoke-prefix/",
+		"sys/mounts",
+		"sys/mounts/",
+		"sys/namespaces",
+		"sys/namespaces/",
+		"sys/policies/acl",
+		"sys/policies/acl/",
+		"sys/policy",
+		"sys/policy/",
+		"sys/remount",
+		"sys/renew",
+		"sys/renew/",
+		"sys/revoke",
+		"sys/revoke/",
+		"sys/revoke-prefix/",
+		"sys/tools/",
+		"auth/token/create",
+		"auth/token/create-orphan",
+		"auth/token/lookup",
+		"auth/token/lookup/",
+		"auth/token/lookup-accessor",
+		"auth/token/lookup-self",
+		"auth/token/renew",
+		"auth/token/renew/",
+		"auth/token/renew-self",
+		"auth/token/revoke",
+		"auth/token/revoke/",
+		"auth/token/revoke-accessor",
+		"auth/token/revoke-orphan",
+		"auth/token/revoke-orphan/",
+		"auth/token/revoke-self",
+		"cubbyhole/",
+	}
+)
+
+// Namespace is an isolated part of Vault with its own mounts, policies,
+// tokens and identity store. Child namespaces are addressed by their path,
+// which prefixes the paths of their requests, or by the namespace header.
+type Namespace struct {
+	ID   string `json:"id"`
+	Path string `json:"path"`
+}
+
+// namespaceTable is the persisted list of the child namespaces
+type namespaceTable struct {
+	Entries []*Namespace `json:"entries"`
+}
+
+func (n *Namespace) isRoot() bool {
+	return n.ID == ""
+}
+
+// isWithin checks whether the namespace is the given namespace or one of its
+// descendants
+func (n *Namespace) isWithin(ancestor *Namespace) bool {
+	return strings.HasPrefix(n.Path, ancestor.Path)
+}
+
+type namespaceContextKey struct{}
+
+// contextWithNamespace returns a copy of the context carrying the namespace
+func contextWithNamespace(ctx context.Context, ns *Namespace) context.Context {
+	return context.WithValue(ctx, namespaceContextKey{}, ns)
+}
+
+// namespaceFromContext returns the namespace carried by the context, which is
+// the root namespace if none was set
+func namespaceFromContext(ctx context.Context) *Namespace {
+	if ctx != nil {
+		if ns, ok := ctx.Value(namespaceContextKey{}).(*Namespace); ok && ns != nil {
+			return ns
+		}
+	}
+	return rootNamespace
+}
+
+// isNamespaceSharedPath checks whether the path, relative to its namespace,
+// is served by the shared backends of the root namespace
+func isNamespaceSharedPath(path string) bool {
+	for _, p := range namespaceSharedPaths {
+		if path == strings.TrimSuffix(p, "/") || strings.HasPrefix(path, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// isNamespaceAllowedPath checks whether a shared path can be requested in a
+// child namespace
+func isNamespaceAllowedPath(path string) bool {
+	for _, p := range namespaceAllowedPaths {
+		if path == p || strings.HasSuffix(p, "/") && strings.HasPrefix(path, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// namespaceRoutePath returns the router path of the given path relative to
+// the namespace. The mounts of child namespaces are routed under the path of
+// the namespace, and their auth methods under the auth prefix followed by the
+// path of the namespace.
+func namespaceRoutePath(ns *Namespace, path string) string {
+	if ns.isRoot() || isNamespaceSharedPath(path) {
+		return path
+	}
+	if strings.HasPrefix(path, credentialRoutePrefix) {
+		return credentialRoutePrefix + ns.Path + strings.TrimPrefix(path, credentialRoutePrefix)
+	}
+	return ns.Path + path
+}
+
+// namespaceRequestPath reverses namespaceRoutePath, returning the full path of
+// a router path requested in the namespace. ACL policies apply to the full
+// paths.
+func namespaceRequestPath(ns *Namespace, routePath string) string {
+	switch {
+	case ns.isRoot():
+		return routePath
+	case strings.HasPrefix(routePath, credentialRoutePrefix+ns.Path):
+		return ns.Path + credentialRoutePrefix + strings.TrimPrefix(routePath, credentialRoutePrefix+ns.Path)
+	case strings.HasPrefix(routePath, ns.Path):
+		return routePath
+	}
+	return ns.Path + routePath
+}
+
+// resolveNamespace determines the namespace of a full request path. It
+// returns the namespace and the path relative to it.
+func (c *Core) resolveNamespace(path string) (*Namespace, string) {
+	c.namespacesLock.RLock()
+	defer c.namespacesLock.RUnlock()
+
+	// Router paths of the auth methods of the namespaces, as returned in the
+	// lease IDs and by the mount listings, resolve to their namespace too
+	if strings.HasPrefix(path, credentialRoutePrefix) {
+		rest := strings.TrimPrefix(path, credentialRoutePrefix)
+		if ns := c.longestNamespacePrefixLocked(rest); !ns.isRoot() {
+			return ns, credentialRoutePrefix + strings.TrimPrefix(rest, ns.Path)
+		}
+	}
+
+	ns := c.longestNamespacePrefixLocked(path)
+	return ns, strings.TrimPrefix(path, ns.Path)
+}
+
+func (c *Core) longestNamespacePrefixLocked(path string) *Namespace {
+	ns := rootNamespace
+	for _, entry := range c.namespaces {
+		if strings.HasPrefix(path, entry.Path) && len(entry.Path) > len(ns.Path) {
+			ns = entry
+		}
+	}
+	return ns
+}
+
+// routeRequestNamespace resolves the namespace of the request and rewrites
+// its path to the router path. The requests of child namespaces for shared
+// paths that are not available in namespaces are rejected.
+func (c *Core) routeRequestNamespace(req *logical.Request) (*Namespace, error) {
+	ns, path := c.resolveNamespace(req.Path)
+	if !ns.isRoot() && isNamespaceSharedPath(path) && !isNamespaceAllowedPath(path) {
+		return nil, logical.ErrUnsupportedPath
+	}
+	req.Path = namespaceRoutePath(ns, path)
+	return ns, nil
+}
+
+// namespaceByID returns the namespace with the given ID, or nil if it does
+// not exist
+func (c *Core) namespaceByID(id string) *Namespace {
+	if id == "" {
+		return rootNamespace
+	}
+
+	c.namespacesLock.RLock()
+	defer c.namespacesLock.RUnlock()
+	return c.namespaces[id]
+}
+
+// namespaceByPath returns the namespace with the given full path, or nil if
+// it does not exist
+func (c *Core) namespaceByPath(path string) *Namespace {
+	if path == "" {
+		return rootNamespace
+	}
+
+	c.namespacesLock.RLock()
+	defer c.namespacesLock.RUnlock()
+	return c.namespaceByPathLocked(path)
+}
+
+func (c *Core) namespaceByPathLocked(path string) *Namespace {
+	for _, ns := range c.namespaces {
+		if ns.Path == path {
+			return ns
+		}
+	}
+	return nil
+}
+
+// namespaceConflict returns the path of a namespace that overlaps the full
+// path of a mount of the given namespace, or an empty string if there is none.
+// The namespace of the mount and its ancestors do not conflict.
+func (c *Core) namespaceConflict(ns *Namespace, path string) string {
+	c.namespacesLock.RLock()
+	defer c.namespacesLock.RUnlock()
+
+	for _, entry := range c.namespaces {
+		if ns.isWithin(entry) {
+			continue
+		}
+		if strings.HasPrefix(path, entry.Path) || strings.HasPrefix(entry.Path, path) {
+			return entry.Path
+		}
+	}
+	return ""
+}
+
+// identityStoreForNamespace returns the identity store of the namespace, which
+// is nil if the namespace is being created or deleted
+func (c *Core) identityStoreForNamespace(ns *Namespace) *IdentityStore {
+	if ns.isRoot() {
+		return c.identityStore
+	}
+
+	c.namespaceIdentityStoresLock.RLock()
+	defer c.namespaceIdentityStoresLock.RUnlock()
+	return c.namespaceIdentityStores[ns.ID]
+}
+
+func (c *Core) setNamespaceIdentityStore(id string, store *IdentityStore) {
+	c.namespaceIdentityStoresLock.Lock()
+	defer c.namespaceIdentityStoresLock.Unlock()
+	if store == nil {
+		delete(c.namespaceIdentityStores, id)
+		return
+	}
+	c.namespaceIdentityStores[id] = store
+}
+
+// loadNamespaces is invoked as part of postUnseal to load the namespace table.
+// It runs before the mounts are set up so that the identity stores of the
+// namespaces can be registered.
+func (c *Core) loadNamespaces(ctx context.Context) error {
+	raw, err := c.barrier.Get(ctx, coreNamespaceConfigPath)
+	if err != nil {
+		c.logger.Error("core: failed to read namespace table", "error", err)
+		return errLoadNamespacesFailed
+	}
+
+	table := &namespaceTable{}
+	if raw != nil {
+		if err := jsonutil.DecodeJSON(raw.Value, table); err != nil {
+			c.logger.Error("core: failed to decode the namespace table", "error", err)
+			return errLoadNamespacesFailed
+		}
+	}
+
+	c.namespacesLock.Lock()
+	c.namespaces = make(map[string]*Namespace, len(table.Entries))
+	for _, ns := range table.Entries {
+		c.namespaces[ns.ID] = ns
+	}
+	c.namespacesLock.Unlock()
+
+	c.namespaceIdentityStoresLock.Lock()
+	c.namespaceIdentityStores = make(map[string]*IdentityStore)
+	c.namespaceIdentityStoresLock.Unlock()
+
+	return nil
+}
+
+// persistNamespacesLocked writes the namespace table to storage. The
+// namespaces lock must be held.
+func (c *Core) persistNamespacesLocked(ctx context.Context) error {
+	table := &namespaceTable{}
+	for _, ns := range c.namespaces {
+		table.Entries = append(table.Entries, ns)
+	}
+	sort.Slice(table.Entries, func(i, j int) bool {
+		return table.Entries[i].Path < table.Entries[j].Path
+	})
+
+	raw, err := jsonutil.EncodeJSON(table)
+	if err != nil {
+		return err
+	}
+	return c.barrier.Put(ctx, &Entry{
+		Key:   coreNamespaceConfigPath,
+		Value: raw,
+	})
+}
+
+// createNamespace creates the namespace at the given full path, along with
+// its identity store and default policy. The parent namespace must exist.
+// Creating an existing namespace returns it unchanged.
+func (c *Core) createNamespace(ctx context.Context, path string) (*Namespace, error) {
+	trimmed := strings.TrimSuffix(path, "/")
+	if trimmed == "" {
+		return nil, logical.CodedError(400, "missing namespace path")
+	}
+	segments := strings.Split(trimmed, "/")
+	for _, segment := range segments {
+		if !namespaceNameRe.MatchString(segment) {
+			return nil, logical.CodedError(400, fmt.Sprintf("invalid namespace name %q", segment))
+		}
+	}
+	name := segments[len(segments)-1]
+	if strutil.StrListContains(reservedNamespaceNames, strings.ToLower(name)) {
+		return nil, logical.CodedError(400, fmt.Sprintf("%q is a reserved namespace name", name))
+	}
+	path = trimmed + "/"
+	parentPath := strings.TrimSuffix(path, name+"/")
+
+	c.namespacesLock.Lock()
+	if ns := c.namespaceByPathLocked(path); ns != nil {
+		c.namespacesLock.Unlock()
+		return ns, nil
+	}
+	if parentPath != "" && c.namespaceByPathLocked(parentPath) == nil {
+		c.namespacesLock.Unlock()
+		return nil, logical.CodedError(400, fmt.Sprintf("parent namespace %q does not exist", parentPath))
+	}
+	if conflict := c.namespaceMountConflict(path); conflict != "" {
+		c.namespacesLock.Unlock()
+		return nil, logical.CodedError(409, fmt.Sprintf("existing mount at %s", conflict))
+	}
+
+	var id string
+	for id == "" || c.namespaces[id] != nil {
+		var err error
+		id, err = random.Base62(namespaceIDLength)
+		if err != nil {
+			c.namespacesLock.Unlock()
+			return nil, err
+		}
+	}
+	ns := &Namespace{
+		ID:   id,
+		Path: path,
+	}
+
+	c.namespaces[ns.ID] = ns
+	if err := c.persistNamespacesLocked(ctx); err != nil {
+		delete(c.namespaces, ns.ID)
+		c.namespacesLock.Unlock()
+		c.logger.Error("core: failed to update namespace table", "error", err)
+		return nil, logical.CodedError(500, "failed to update namespace table")
+	}
+	c.namespacesLock.Unlock()
+
+	// Every namespace has its own identity store and default policy
+	identityEntry := &MountEntry{
+		Table:       mountTableType,
+		Path:        ns.Path + "identity/",
+		Type:        "identity",
+		Description: "identity store",
+		NamespaceID: ns.ID,
+	}
+	if err := c.mountInternal(ctx, identityEntry); err != nil {
+		c.logger.Error("core: failed to mount the identity store of the namespace", "path", ns.Path, "error", err)
+		c.deleteNamespace(ctx, ns)
+		return nil, err
+	}
+	if err := c.policyStore.loadACLPolicy(contextWithNamespace(ctx, ns), defaultPolicyName, defaultPolicy); err != nil {
+		c.logger.Error("core: failed to create the default policy of the namespace", "path", ns.Path, "error", err)
+		c.deleteNamespace(ctx, ns)
+		return nil, err
+	}
+
+	if c.logger.IsInfo() {
+		c.logger.Info("core: created namespace", "path", ns.Path, "id", ns.ID)
+	}
+	return ns, nil
+}
+
+// namespaceMountConflict returns the path of a mount or auth method that
+// overlaps the full path of a new namespace
+func (c *Core) namespaceMountConflict(path string) string {
+	c.mountsLock.RLock()
+	for _, entry := range c.mounts.Entries {
+		if strings.HasPrefix(entry.Path, path) || strings.HasPrefix(path, entry.Path) {
+			c.mountsLock.RUnlock()
+			return entry.Path
+		}
+	}
+	c.mountsLock.RUnlock()
+
+	c.authLock.RLock()
+	defer c.authLock.RUnlock()
+	for _, entry := range c.auth.Entries {
+		if strings.HasPrefix(entry.Path, path) || strings.HasPrefix(path, entry.Path) {
+			return credentialRoutePrefix + entry.Path
+		}
+	}
+	return ""
+}
+
+// deleteNamespace removes a namespace without child namespaces, along with
+// its mounts, auth methods, identity store and policies. The tokens of the
+// namespace can no longer be used once it is deleted.
+func (c *Core) deleteNamespace(ctx context.Context, ns *Namespace) error {
+	c.namespacesLock.RLock()
+	for _, entry := range c.namespaces {
+		if entry != ns && entry.isWithin(ns) {
+			c.namespacesLock.RUnlock()
+			return logical.CodedError(400, fmt.Sprintf("namespace %q has child namespaces", ns.Path))
+		}
+	}
+	c.namespacesLock.RUnlock()
+
+	var mounts, auths []string
+	c.mountsLock.RLock()
+	for _, entry := range c.mounts.Entries {
+		if entry.NamespaceID == ns.ID {
+			mounts = append(mounts, entry.Path)
+		}
+	}
+	c.mountsLock.RUnlock()
+	c.authLock.RLock()
+	for _, entry := range c.auth.Entries {
+		if entry.NamespaceID == ns.ID {
+			auths = append(auths, entry.Path)
+		}
+	}
+	c.authLock.RUnlock()
+
+	for _, path := range mounts {
+		if err := c.unmountInternal(ctx, path, false); err != nil {
+			return err
+		}
+	}
+	for _, path := range auths {
+		if err := c.disableCredential(ctx, path); err != nil {
+			return err
+		}
+	}
+	c.setNamespaceIdentityStore(ns.ID, nil)
+
+	if err := c.policyStore.deleteNamespacePolicies(ctx, ns); err != nil {
+		return err
+	}
+
+	c.namespacesLock.Lock()
+	defer c.namespacesLock.Unlock()
+	delete(c.namespaces, ns.ID)
+	if err := c.persistNamespacesLocked(ctx); err != nil {
+		c.namespaces[ns.ID] = ns
+		c.logger.Error("core: failed to update namespace table", "error", err)
+		return logical.CodedError(500, "failed to update namespace table")
+	}
+
+	if c.logger.IsInfo() {
+		c.logger.Info("core: deleted namespace", "path", ns.Path, "id", ns.ID)
+	}
+	return nil
+}
+
+// leaseInNamespace checks whether the lease was issued by a mount of the
+// namespace of the request or of one of its descendants
+func leaseInNamespace(ctx context.Context, leaseID string) bool {
+	ns := namespaceFromContext(ctx)
+	return ns.isRoot() ||
+		strings.HasPrefix(leaseID, ns.Path) ||
+		strings.HasPrefix(leaseID, credentialRoutePrefix+ns.Path)
+}
+
+// namespaceRelativePath returns the path relative to the namespace of the
+// request of a full path, as returned in the mount listings
+func namespaceRelativePath(ns *Namespace, path string) string {
+	return strings.TrimPrefix(path, ns.Path)
+}
+
+func namespacePaths(b *SystemBackend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "namespaces/?$",
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ListOperation: b.handleNamespacesList,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["namespaces-list"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["namespaces-list"][1]),
+		},
+		{
+			Pattern: "namespaces/(?P<path>.+)",
+
+			Fields: map[string]*framework.FieldSchema{
+				"path": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: strings.TrimSpace(sysHelp["namespace-path"][0]),
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation:   b.handleNamespacesRead,
+				logical.UpdateOperation: b.handleNamespacesSet,
+				logical.DeleteOperation: b.handleNamespacesDelete,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["namespaces"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["namespaces"][1]),
+		},
+	}
+}
+
+// handleNamespacesList lists the direct children of the namespace of the
+// request
+func (b *SystemBackend) handleNamespacesList(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	parent := namespaceFromContext(ctx)
+
+	b.Core.namespacesLock.RLock()
+	defer b.Core.namespacesLock.RUnlock()
+
+	var keys []string
+	keyInfo := make(map[string]interface{})
+	for _, ns := range b.Core.namespaces {
+		if ns == parent || !ns.isWithin(parent) {
+			continue
+		}
+		rel := namespaceRelativePath(parent, ns.Path)
+		if strings.Contains(strings.TrimSuffix(rel, "/"), "/") {
+			continue
+		}
+		keys = append(keys, rel)
+		keyInfo[rel] = map[string]interface{}{
+			"id":   ns.ID,
+			"path": rel,
+		}
+	}
+	sort.Strings(keys)
+
+	return logical.ListResponseWithInfo(keys, keyInfo), nil
+}
+
+// namespaceFieldPath returns the full path of the namespace given in the
+// request, relative to the namespace of the request
+func namespaceFieldPath(ctx context.Context, d *framework.FieldData) string {
+	path := strings.Trim(d.Get("path").(string), "/")
+	if path == "" {
+		return ""
+	}
+	return namespaceFromContext(ctx).Path + path + "/"
+}
+
+func namespaceResponse(parent, ns *Namespace) *logical.Response {
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"id":   ns.ID,
+			"path": namespaceRelativePath(parent, ns.Path),
+		},
+	}
+}
+
+// handleNamespacesRead returns a namespace
+func (b *SystemBackend) handleNamespacesRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	path := namespaceFieldPath(ctx, d)
+	if path == "" {
+		return logical.ErrorResponse("missing namespace path"), logical.ErrInvalidRequest
+	}
+
+	ns := b.Core.namespaceByPath(path)
+	if ns == nil {
+		return nil, nil
+	}
+	return namespaceResponse(namespaceFromContext(ctx), ns), nil
+}
+
+// handleNamespacesSet creates a namespace
+func (b *SystemBackend) handleNamespacesSet(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	path := namespaceFieldPath(ctx, d)
+	if path == "" {
+		return logical.ErrorResponse("missing namespace path"), logical.ErrInvalidRequest
+	}
+
+	ns, err := b.Core.createNamespace(ctx, path)
+	if err != nil {
+		b.Backend.Logger().Error("sys: create namespace failed", "path", path, "error", err)
+		return handleError(err)
+	}
+	return namespaceResponse(namespaceFromContext(ctx), ns), nil
+}
+
+// handleNamespacesDelete deletes a namespace
+func (b *SystemBackend) handleNamespacesDelete(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	path := namespaceFieldPath(ctx, d)
+	if path == "" {
+		return logical.ErrorResponse("missing namespace path"), logical.ErrInvalidRequest
+	}
+
+	ns := b.Core.namespaceByPath(path)
+	if ns == nil {
+		return nil, nil
+	}
+	if err := b.Core.deleteNamespace(ctx, ns); err != nil {
+		b.Backend.Logger().Error("sys: delete namespace failed", "path", path, "error", err)
+		return handleError(err)
+	}
+	return nil, nil
+}
@@ -0,0 +1,248 @@
+package vault
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/logical"
+)
+
+// testNamespaceRequest handles a request with the given token and fails the
+// test on errors
+func testNamespaceRequest(t *testing.T, c *Core, token string, op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+	req := logical.TestRequest(t, op, path)
+	req.ClientToken = token
+	if data != nil {
+		req.Data = data
+	}
+	resp, err := c.HandleRequest(req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("%s %s: err: %v resp: %#v", op, path, err, resp)
+	}
+	return resp
+}
+
+func TestNamespaces_CreateReadListDelete(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	resp := testNamespaceRequest(t, c, root, logical.UpdateOperation, "sys/namespaces/team-a", nil)
+	if resp.Data["path"] != "team-a/" || resp.Data["id"] == "" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	id := resp.Data["id"]
+
+	// Creating the namespace again returns the existing one
+	resp = testNamespaceRequest(t, c, root, logical.UpdateOperation, "sys/namespaces/team-a", nil)
+	if resp.Data["id"] != id {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Child namespaces are relative to the namespace of the request
+	resp = testNamespaceRequest(t, c, root, logical.UpdateOperation, "team-a/sys/namespaces/dev", nil)
+	if resp.Data["path"] != "dev/" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if ns := c.namespaceByPath("team-a/dev/"); ns == nil {
+		t.Fatal("expected the child namespace")
+	}
+
+	resp = testNamespaceRequest(t, c, root, logical.ListOperation, "sys/namespaces", nil)
+	if !reflect.DeepEqual(resp.Data["keys"], []string{"team-a/"}) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	resp = testNamespaceRequest(t, c, root, logical.ReadOperation, "sys/namespaces/team-a/dev", nil)
+	if resp.Data["path"] != "team-a/dev/" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Namespaces with children cannot be deleted
+	req := logical.TestRequest(t, logical.DeleteOperation, "sys/namespaces/team-a")
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	testNamespaceRequest(t, c, root, logical.DeleteOperation, "team-a/sys/namespaces/dev", nil)
+	testNamespaceRequest(t, c, root, logical.DeleteOperation, "sys/namespaces/team-a", nil)
+	if ns := c.namespaceByPath("team-a/"); ns != nil {
+		t.Fatalf("expected the namespace to be deleted: %#v", ns)
+	}
+	if n := len(c.namespaceIdentityStores); n != 0 {
+		t.Fatalf("expected no identity stores of namespaces, got %d", n)
+	}
+}
+
+func TestNamespaces_InvalidPaths(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	for _, path := range []string{"sys", "identity", "team a", "missing/child"} {
+		req := logical.TestRequest(t, logical.UpdateOperation, "sys/namespaces/"+path)
+		req.ClientToken = root
+		if _, err := c.HandleRequest(req); err == nil {
+			t.Fatalf("expected an error creating %q", path)
+		}
+	}
+
+	// Namespaces cannot shadow existing mounts
+	req := logical.TestRequest(t, logical.UpdateOperation, "sys/namespaces/secret")
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestNamespaces_Persist(t *testing.T) {
+	c, keys, root := TestCoreUnsealed(t)
+
+	testNamespaceRequest(t, c, root, logical.UpdateOperation, "sys/namespaces/team-a", nil)
+	testNamespaceRequest(t, c, root, logical.UpdateOperation, "team-a/sys/mounts/kv", map[string]interface{}{
+		"type": "kv",
+	})
+
+	if err := c.Seal(root); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for _, key := range keys {
+		if _, err := TestCoreUnseal(c, TestKeyCopy(key)); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	if ns := c.namespaceByPath("team-a/"); ns == nil {
+		t.Fatal("expected the namespace to be loaded")
+	}
+	resp := testNamespaceRequest(t, c, root, logical.ReadOperation, "team-a/sys/mounts", nil)
+	if resp.Data["kv/"] == nil || resp.Data["identity/"] == nil {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+}
+
+func TestNamespaces_Isolation(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	testNamespaceRequest(t, c, root, logical.UpdateOperation, "sys/namespaces/team-a", nil)
+	testNamespaceRequest(t, c, root, logical.UpdateOperation, "team-a/sys/mounts/kv", map[string]interface{}{
+		"type": "kv",
+	})
+	testNamespaceRequest(t, c, root, logical.UpdateOperation, "team-a/sys/policy/kv", map[string]interface{}{
+		"policy": `path "kv/*" { capabilities = ["create", "read", "update"] }`,
+	})
+
+	// The mount table of the namespace only contains its own mounts
+	resp := testNamespaceRequest(t, c, root, logical.ReadOperation, "team-a/sys/mounts", nil)
+	if resp.Data["kv/"] == nil || resp.Data["secret/"] != nil {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	resp = testNamespaceRequest(t, c, root, logical.ReadOperation, "sys/mounts", nil)
+	if resp.Data["kv/"] != nil || resp.Data["team-a/kv/"] != nil {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// The policies of the namespace are separate from the root namespace
+	resp = testNamespaceRequest(t, c, root, logical.ListOperation, "team-a/sys/policies/acl", nil)
+	if !reflect.DeepEqual(resp.Data["keys"], []string{"default", "kv"}) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if p, err := c.policyStore.GetPolicy(context.Background(), "kv", PolicyTypeACL); err != nil || p != nil {
+		t.Fatalf("expected no kv policy in the root namespace: %v %v", p, err)
+	}
+
+	resp = testNamespaceRequest(t, c, root, logical.UpdateOperation, "team-a/auth/token/create", map[string]interface{}{
+		"policies": []string{"kv"},
+	})
+	token := resp.Auth.ClientToken
+
+	testNamespaceRequest(t, c, token, logical.UpdateOperation, "team-a/kv/foo", map[string]interface{}{
+		"bar": "baz",
+	})
+	resp = testNamespaceRequest(t, c, token, logical.ReadOperation, "team-a/kv/foo", nil)
+	if resp.Data["bar"] != "baz" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	resp = testNamespaceRequest(t, c, token, logical.ReadOperation, "team-a/auth/token/lookup-self", nil)
+	if resp.Data["namespace_path"] != "team-a/" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// The token cannot be used outside of its namespace
+	for _, path := range []string{"kv/foo", "secret/foo", "cubbyhole/foo"} {
+		req := logical.TestRequest(t, logical.ReadOperation, path)
+		req.ClientToken = token
+		if _, err := c.HandleRequest(req); err == nil || !errwrap.Contains(err, logical.ErrPermissionDenied.Error()) {
+			t.Fatalf("%s: expected permission denied, got %v", path, err)
+		}
+	}
+
+	// Cluster-wide endpoints are not available in namespaces
+	req := logical.TestRequest(t, logical.ReadOperation, "team-a/sys/audit")
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err == nil || !errwrap.Contains(err, logical.ErrUnsupportedPath.Error()) {
+		t.Fatalf("expected unsupported path, got %v", err)
+	}
+
+	// The root policy cannot be assigned in namespaces
+	req = logical.TestRequest(t, logical.UpdateOperation, "team-a/auth/token/create")
+	req.ClientToken = token
+	req.Data["policies"] = []string{"root"}
+	if resp, err := c.HandleRequest(req); err == nil && !resp.IsError() {
+		t.Fatalf("expected an error: %#v", resp)
+	}
+}
+
+func TestNamespaces_Login(t *testing.T) {
+	noop := &NoopBackend{
+		Login: []string{"login"},
+		Response: &logical.Response{
+			Auth: &logical.Auth{
+				Policies: []string{"foo"},
+				Alias: &logical.Alias{
+					Name: "armon",
+				},
+			},
+		},
+	}
+	c, _, root := TestCoreUnsealed(t)
+	c.credentialBackends["noop"] = func(context.Context, *logical.BackendConfig) (logical.Backend, error) {
+		return noop, nil
+	}
+
+	testNamespaceRequest(t, c, root, logical.UpdateOperation, "sys/namespaces/team-a", nil)
+	testNamespaceRequest(t, c, root, logical.UpdateOperation, "team-a/sys/auth/foo", map[string]interface{}{
+		"type": "noop",
+	})
+
+	resp := testNamespaceRequest(t, c, root, logical.ReadOperation, "team-a/sys/auth", nil)
+	if resp.Data["foo/"] == nil {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	resp, err := c.HandleRequest(&logical.Request{Path: "team-a/auth/foo/login"})
+	if err != nil || resp.Auth == nil {
+		t.Fatalf("err: %v resp: %#v", err, resp)
+	}
+	if resp.Auth.EntityID == "" {
+		t.Fatalf("expected an entity: %#v", resp.Auth)
+	}
+
+	ns := c.namespaceByPath("team-a/")
+	te, err := c.tokenStore.Lookup(context.Background(), resp.Auth.ClientToken)
+	if err != nil || te == nil {
+		t.Fatalf("err: %v", err)
+	}
+	if te.NamespaceID != ns.ID {
+		t.Fatalf("bad: %#v", te)
+	}
+
+	// The entity is created in the identity store of the namespace
+	entity, err := c.identityStoreForNamespace(ns).MemDBEntityByID(resp.Auth.EntityID, false)
+	if err != nil || entity == nil {
+		t.Fatalf("expected the entity in the namespace: %v", err)
+	}
+	entity, err = c.identityStore.MemDBEntityByID(resp.Auth.EntityID, false)
+	if err != nil || entity != nil {
+		t.Fatalf("expected no entity in the root namespace: %v %#v", err, entity)
+	}
+}
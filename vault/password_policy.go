@@ -0,0 +1,77 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/vault/helper/random"
+	"github.com/hashicorp/vault/logical"
+)
+
+const (
+	// passwordPolicySubPath is the sub-path used for the password policies
+	// within the system barrier view
+	passwordPolicySubPath = "password_policy/"
+)
+
+// passwordPolicyEntry is the storage entry of a password policy
+type passwordPolicyEntry struct {
+	// Policy is the HCL definition of the policy
+	Policy string `json:"policy"`
+}
+
+func (c *Core) passwordPolicyView() *BarrierView {
+	return c.systemBarrierView.SubView(passwordPolicySubPath)
+}
+
+// getPasswordPolicyEntry returns the stored password policy with the given
+// name, or nil if there is no such policy
+func (c *Core) getPasswordPolicyEntry(ctx context.Context, name string) (*passwordPolicyEntry, error) {
+	entry, err := c.passwordPolicyView().Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var policy passwordPolicyEntry
+	if err := entry.DecodeJSON(&policy); err != nil {
+		return nil, err
+	}
+
+	return &policy, nil
+}
+
+// setPasswordPolicyEntry validates and stores the password policy
+func (c *Core) setPasswordPolicyEntry(ctx context.Context, name string, policy *passwordPolicyEntry) error {
+	if _, err := random.ParsePasswordPolicy(policy.Policy); err != nil {
+		return err
+	}
+
+	entry, err := logical.StorageEntryJSON(name, policy)
+	if err != nil {
+		return err
+	}
+
+	return c.passwordPolicyView().Put(ctx, entry)
+}
+
+// generatePasswordFromPolicy generates a password from the password policy
+// with the given name
+func (c *Core) generatePasswordFromPolicy(ctx context.Context, name string) (string, error) {
+	entry, err := c.getPasswordPolicyEntry(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	if entry == nil {
+		return "", fmt.Errorf("password policy %q not found", name)
+	}
+
+	policy, err := random.ParsePasswordPolicy(entry.Policy)
+	if err != nil {
+		return "", err
+	}
+
+	return policy.Generate()
+}
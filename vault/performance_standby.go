@@ -0,0 +1,287 @@
+package vault
+
+import (
+	"context"
+	"crypto/sha256"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+// perfStandbyRefreshInterval is the interval at which performance standbys
+// reload the state written by the active node. The mount, auth and audit
+// tables are reloaded when they change, the policies, CORS configuration,
+// audited headers and quotas on every refresh.
+var perfStandbyRefreshInterval = 5 * time.Second
+
+// perfStandbyTablePaths are the paths of the tables of the core which are
+// watched by performance standbys
+var perfStandbyTablePaths = []string{
+	coreMountConfigPath,
+	coreLocalMountConfigPath,
+	coreAuthConfigPath,
+	coreLocalAuthConfigPath,
+	coreAuditConfigPath,
+	coreLocalAuditConfigPath,
+	coreNamespaceConfigPath,
+}
+
+// PerformanceStandby checks if the Vault is a standby serving the read-only
+// requests locally
+func (c *Core) PerformanceStandby() (bool, error) {
+	c.stateLock.RLock()
+	defer c.stateLock.RUnlock()
+	return c.standby && c.perfStandby, nil
+}
+
+// setupPerformanceStandby sets up the mounts, credentials, policies and
+// audit devices of a standby from storage, read-only, so that it can serve
+// the requests that do not write to storage. The stateLock must be held.
+func (c *Core) setupPerformanceStandby() error {
+	if c.sealed || !c.standby || c.perfStandby {
+		return nil
+	}
+
+	fingerprint, err := c.perfStandbyFingerprint(context.Background())
+	if err != nil {
+		return err
+	}
+
+	// Setting perfStandby first makes the tables load without persisting and
+	// the storage views of the mounts read-only
+	c.perfStandby = true
+	c.activeContext, c.activeContextCancelFunc = context.WithCancel(context.Background())
+	if err := c.loadPerfStandbyState(c.activeContext); err != nil {
+		c.unloadPerfStandbyState(c.activeContext)
+		c.activeContextCancelFunc()
+		c.perfStandby = false
+		return err
+	}
+
+	c.perfStandbyStopCh = make(chan struct{})
+	go c.perfStandbyRefresh(c.perfStandbyStopCh, fingerprint)
+
+	c.logger.Info("core: serving read-only requests as a performance standby")
+	return nil
+}
+
+// teardownPerformanceStandby reverses setupPerformanceStandby. The stateLock
+// must be held.
+func (c *Core) teardownPerformanceStandby() {
+	if !c.perfStandby {
+		return
+	}
+
+	close(c.perfStandbyStopCh)
+	c.perfStandbyStopCh = nil
+
+	if c.activeContextCancelFunc != nil {
+		c.activeContextCancelFunc()
+	}
+	if err := c.unloadPerfStandbyState(c.activeContext); err != nil {
+		c.logger.Error("core: performance standby teardown failed", "error", err)
+	}
+	c.perfStandby = false
+}
+
+// loadPerfStandbyState sets up the state needed to serve requests, without
+// the rollback and expiration of leases, which are run by the active node
+func (c *Core) loadPerfStandbyState(ctx context.Context) error {
+	if err := c.setupPluginCatalog(); err != nil {
+		return err
+	}
+	if err := c.loadNamespaces(ctx); err != nil {
+		return err
+	}
+	if err := c.loadMounts(ctx); err != nil {
+		return err
+	}
+	if err := c.setupMounts(ctx); err != nil {
+		return err
+	}
+	if err := c.setupPolicyStore(ctx); err != nil {
+		return err
+	}
+	if err := c.loadCORSConfig(ctx); err != nil {
+		return err
+	}
+	if err := c.loadCredentials(ctx); err != nil {
+		return err
+	}
+	if err := c.setupCredentials(ctx); err != nil {
+		return err
+	}
+	if err := c.setupQuotas(ctx); err != nil {
+		return err
+	}
+	c.setupPerfStandbyExpiration()
+	if err := c.loadAudits(ctx); err != nil {
+		return err
+	}
+	if err := c.setupAudits(ctx); err != nil {
+		return err
+	}
+	if err := c.setupAuditedHeadersConfig(ctx); err != nil {
+		return err
+	}
+	return nil
+}
+
+// unloadPerfStandbyState reverses loadPerfStandbyState
+func (c *Core) unloadPerfStandbyState(ctx context.Context) error {
+	var result error
+	if err := c.teardownAudits(); err != nil {
+		result = err
+	}
+	if err := c.stopExpiration(); err != nil {
+		result = err
+	}
+	c.teardownQuotas()
+	if err := c.teardownCredentials(ctx); err != nil {
+		result = err
+	}
+	if err := c.teardownPolicyStore(); err != nil {
+		result = err
+	}
+	if err := c.unloadMounts(ctx); err != nil {
+		result = err
+	}
+	return result
+}
+
+// setupPerfStandbyExpiration sets up an expiration manager which only reads
+// the leases: the leases are renewed and revoked by the active node
+func (c *Core) setupPerfStandbyExpiration() {
+	c.metricsMutex.Lock()
+	defer c.metricsMutex.Unlock()
+
+	mgr := NewExpirationManager(c, c.systemBarrierView.SubView(expirationSubPath))
+	atomic.StoreInt32(&mgr.restoreMode, 0)
+	c.expiration = mgr
+	c.tokenStore.SetExpirationManager(mgr)
+}
+
+// perfStandbyRefresh reloads the state of the performance standby until the
+// stop channel is closed
+func (c *Core) perfStandbyRefresh(stopCh chan struct{}, fingerprint []byte) {
+	ticker := time.NewTicker(perfStandbyRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+		}
+
+		newFingerprint, err := c.perfStandbyFingerprint(context.Background())
+		if err != nil {
+			c.logger.Error("core: failed to read the tables of the performance standby", "error", err)
+			continue
+		}
+
+		c.stateLock.Lock()
+		select {
+		case <-stopCh:
+			c.stateLock.Unlock()
+			return
+		default:
+		}
+
+		if string(newFingerprint) != string(fingerprint) {
+			fingerprint = newFingerprint
+			c.logger.Debug("core: tables changed, reloading the performance standby")
+			c.unloadPerfStandbyState(c.activeContext)
+			err = c.loadPerfStandbyState(c.activeContext)
+		} else {
+			err = c.refreshPerfStandbyState(c.activeContext)
+		}
+		if err != nil {
+			// Forward all the requests until the state can be loaded
+			c.logger.Error("core: failed to reload the performance standby, forwarding all requests", "error", err)
+			close(stopCh)
+			c.perfStandbyStopCh = nil
+			c.activeContextCancelFunc()
+			c.unloadPerfStandbyState(c.activeContext)
+			c.perfStandby = false
+		}
+		c.stateLock.Unlock()
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// refreshPerfStandbyState reloads the state of the performance standby which
+// is not watched by its fingerprint
+func (c *Core) refreshPerfStandbyState(ctx context.Context) error {
+	if err := c.setupPolicyStore(ctx); err != nil {
+		return err
+	}
+	if err := c.loadCORSConfig(ctx); err != nil {
+		return err
+	}
+	if err := c.setupQuotas(ctx); err != nil {
+		return err
+	}
+	c.expiration.quotas = c.quotas
+	return c.setupAuditedHeadersConfig(ctx)
+}
+
+// perfStandbyFingerprint returns the hash of the tables watched by the
+// performance standbys
+func (c *Core) perfStandbyFingerprint(ctx context.Context) ([]byte, error) {
+	h := sha256.New()
+	for _, path := range perfStandbyTablePaths {
+		entry, err := c.barrier.Get(ctx, path)
+		if err != nil {
+			return nil, err
+		}
+		h.Write([]byte(path))
+		if entry != nil {
+			h.Write(entry.Value)
+		}
+	}
+	return h.Sum(nil), nil
+}
+
+// perfStandbyCanServe checks if the request can be served on a performance
+// standby, from the properties of its operation declared by its backend.
+// Only the read-only and local-only operations are served, the other
+// requests are forwarded.
+func (c *Core) perfStandbyCanServe(req *logical.Request, props logical.OperationProperties) bool {
+	switch {
+	case props.ForwardToActive:
+		return false
+	case props.LocalOnly:
+		return true
+	case req.IndexState != "":
+		// The index state is the one of the writes on the active node
+		return false
+	}
+
+	// Wrapping the response stores it in a cubbyhole
+	if req.WrapInfo != nil && req.WrapInfo.TTL != 0 {
+		return false
+	}
+	return props.ReadOnly
+}
+
+// mountReadOnlyErr returns the error of the writes to the storage views of
+// the mounts once they are set up, which are read-only on performance
+// standbys
+func (c *Core) mountReadOnlyErr() error {
+	if c.perfStandby {
+		return logical.ErrReadOnly
+	}
+	return nil
+}
+
+// isReadOnlyErr checks if the error comes from a write to read-only storage
+func isReadOnlyErr(err error) bool {
+	return err != nil && (strings.Contains(err.Error(), logical.ErrReadOnly.Error()) ||
+		strings.Contains(err.Error(), logical.ErrSetupReadOnly.Error()))
+}
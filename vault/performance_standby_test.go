@@ -0,0 +1,114 @@
+package vault
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/helper/consts"
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestCore_PerformanceStandby(t *testing.T) {
+	defer func(interval time.Duration) {
+		perfStandbyRefreshInterval = interval
+	}(perfStandbyRefreshInterval)
+	perfStandbyRefreshInterval = 100 * time.Millisecond
+
+	cluster := NewTestCluster(t, &CoreConfig{
+		PerformanceStandby: true,
+		LogicalBackends: map[string]logical.Factory{
+			"leased": LeasedPassthroughBackendFactory,
+		},
+	}, nil)
+	cluster.Start()
+	defer cluster.Cleanup()
+
+	active := cluster.Cores[0].Core
+	standby := cluster.Cores[1].Core
+	TestWaitActive(t, active)
+
+	waitFor := func(f func() bool) {
+		t.Helper()
+		start := time.Now()
+		for !f() {
+			if time.Since(start) > 5*time.Second {
+				t.Fatal("timed out")
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+	waitFor(func() bool {
+		perfStandby, _ := standby.PerformanceStandby()
+		return perfStandby
+	})
+	if perfStandby, _ := active.PerformanceStandby(); perfStandby {
+		t.Fatal("active node should not be a performance standby")
+	}
+
+	handle := func(core *Core, op logical.Operation, path, token string, data map[string]interface{}) (*logical.Response, error) {
+		return core.HandleRequest(&logical.Request{
+			Operation:   op,
+			Path:        path,
+			ClientToken: token,
+			Data:        data,
+		})
+	}
+
+	if _, err := handle(active, logical.UpdateOperation, "secret/foo", cluster.RootToken, map[string]interface{}{"bar": "baz"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reads are served by the standby
+	resp, err := handle(standby, logical.ReadOperation, "secret/foo", cluster.RootToken, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp == nil || resp.Data["bar"] != "baz" {
+		t.Fatalf("bad: %#v", resp)
+	}
+	resp, err = handle(standby, logical.ReadOperation, "auth/token/lookup-self", cluster.RootToken, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp == nil || resp.Data["id"] != cluster.RootToken {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// So are the writes declared read-only by their backend
+	resp, err = handle(standby, logical.UpdateOperation, "auth/token/lookup", cluster.RootToken, map[string]interface{}{"token": cluster.RootToken})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp == nil || resp.Data["id"] != cluster.RootToken {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// Writes are forwarded
+	_, err = handle(standby, logical.UpdateOperation, "secret/foo", cluster.RootToken, map[string]interface{}{"bar": "qux"})
+	if err != consts.ErrPerformanceStandbyForward {
+		t.Fatalf("expected forward error, got %v", err)
+	}
+
+	// So are the requests of tokens with limited uses
+	resp, err = handle(active, logical.UpdateOperation, "auth/token/create", cluster.RootToken, map[string]interface{}{"num_uses": 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = handle(standby, logical.ReadOperation, "secret/foo", resp.Auth.ClientToken, nil)
+	if err != consts.ErrPerformanceStandbyForward {
+		t.Fatalf("expected forward error, got %v", err)
+	}
+
+	// New mounts are picked up by the standby, and the reads creating leases
+	// are forwarded
+	if _, err := handle(active, logical.UpdateOperation, "sys/mounts/leased", cluster.RootToken, map[string]interface{}{"type": "leased"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := handle(active, logical.UpdateOperation, "leased/foo", cluster.RootToken, map[string]interface{}{"bar": "baz", "ttl": "1h"}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(func() bool {
+		_, err := handle(standby, logical.ReadOperation, "leased/foo", cluster.RootToken, nil)
+		return err == consts.ErrPerformanceStandbyForward
+	})
+}
@@ -10,6 +10,7 @@ import (
 	"strings"
 	"sync"
 
+	goversion "github.com/hashicorp/go-version"
 	"github.com/hashicorp/vault/helper/builtinplugins"
 	"github.com/hashicorp/vault/helper/consts"
 	"github.com/hashicorp/vault/helper/jsonutil"
@@ -19,6 +20,7 @@ import (
 
 var (
 	pluginCatalogPath         = "core/plugin-catalog/"
+	pluginCatalogVersionsPath = "core/plugin-catalog-versions/"
 	ErrDirectoryNotConfigured = errors.New("could not set plugin, plugin directory is not configured")
 	ErrPluginNotFound         = errors.New("plugin not found in the catalog")
 )
@@ -26,17 +28,23 @@ var (
 // PluginCatalog keeps a record of plugins known to vault. External plugins need
 // to be registered to the catalog before they can be used in backends. Builtin
 // plugins are automatically detected and included in the catalog.
+//
+// External plugins can also be registered with a version, alongside the
+// unversioned entry of the plugin, so that mounts can be pinned to a version
+// while newer versions are registered.
 type PluginCatalog struct {
-	catalogView *BarrierView
-	directory   string
+	catalogView  *BarrierView
+	versionsView *BarrierView
+	directory    string
 
 	lock sync.RWMutex
 }
 
 func (c *Core) setupPluginCatalog() error {
 	c.pluginCatalog = &PluginCatalog{
-		catalogView: NewBarrierView(c.barrier, pluginCatalogPath),
-		directory:   c.pluginDirectory,
+		catalogView:  NewBarrierView(c.barrier, pluginCatalogPath),
+		versionsView: NewBarrierView(c.barrier, pluginCatalogVersionsPath),
+		directory:    c.pluginDirectory,
 	}
 
 	if c.logger.IsInfo() {
@@ -48,15 +56,24 @@ func (c *Core) setupPluginCatalog() error {
 
 // Get retrieves a plugin with the specified name from the catalog. It first
 // looks for external plugins with this name and then looks for builtin plugins.
-// It returns a PluginRunner or an error if no plugin was found.
-func (c *PluginCatalog) Get(ctx context.Context, name string) (*pluginutil.PluginRunner, error) {
+// If a version is given, only the external plugin registered with this version
+// is looked for. It returns a PluginRunner or an error if no plugin was found.
+func (c *PluginCatalog) Get(ctx context.Context, name, version string) (*pluginutil.PluginRunner, error) {
 	c.lock.RLock()
 	defer c.lock.RUnlock()
 
+	view, key := c.catalogView, name
+	if version != "" {
+		var err error
+		if view, key, err = c.versionedKey(name, version); err != nil {
+			return nil, err
+		}
+	}
+
 	// If the directory isn't set only look for builtin plugins.
 	if c.directory != "" {
 		// Look for external plugins in the barrier
-		out, err := c.catalogView.Get(ctx, name)
+		out, err := view.Get(ctx, key)
 		if err != nil {
 			return nil, fmt.Errorf("failed to retrieve plugin \"%s\": %v", name, err)
 		}
@@ -72,6 +89,10 @@ func (c *PluginCatalog) Get(ctx context.Context, name string) (*pluginutil.Plugi
 			return entry, nil
 		}
 	}
+	if version != "" {
+		return nil, nil
+	}
+
 	// Look for builtin plugins
 	if factory, ok := builtinplugins.Get(name); ok {
 		return &pluginutil.PluginRunner{
@@ -85,8 +106,9 @@ func (c *PluginCatalog) Get(ctx context.Context, name string) (*pluginutil.Plugi
 }
 
 // Set registers a new external plugin with the catalog, or updates an existing
-// external plugin. It takes the name, command and SHA256 of the plugin.
-func (c *PluginCatalog) Set(ctx context.Context, name, command string, args []string, sha256 []byte) error {
+// external plugin. It takes the name, version, command and SHA256 of the
+// plugin. The version is optional, and must be a semantic version if set.
+func (c *PluginCatalog) Set(ctx context.Context, name, version, command string, args []string, sha256 []byte) error {
 	if c.directory == "" {
 		return ErrDirectoryNotConfigured
 	}
@@ -117,8 +139,16 @@ func (c *PluginCatalog) Set(ctx context.Context, name, command string, args []st
 		return errors.New("can not execute files outside of configured plugin directory")
 	}
 
+	view, key := c.catalogView, name
+	if version != "" {
+		if view, key, err = c.versionedKey(name, version); err != nil {
+			return err
+		}
+	}
+
 	entry := &pluginutil.PluginRunner{
 		Name:    name,
+		Version: version,
 		Command: command,
 		Args:    args,
 		Sha256:  sha256,
@@ -131,22 +161,70 @@ func (c *PluginCatalog) Set(ctx context.Context, name, command string, args []st
 	}
 
 	logicalEntry := logical.StorageEntry{
-		Key:   name,
+		Key:   key,
 		Value: buf,
 	}
-	if err := c.catalogView.Put(ctx, &logicalEntry); err != nil {
+	if err := view.Put(ctx, &logicalEntry); err != nil {
 		return fmt.Errorf("failed to persist plugin entry: %v", err)
 	}
 	return nil
 }
 
-// Delete is used to remove an external plugin from the catalog. Builtin plugins
-// can not be deleted.
-func (c *PluginCatalog) Delete(ctx context.Context, name string) error {
+// Delete is used to remove an external plugin from the catalog, or one of its
+// versions if a version is given. Builtin plugins can not be deleted.
+func (c *PluginCatalog) Delete(ctx context.Context, name, version string) error {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
-	return c.catalogView.Delete(ctx, name)
+	if version == "" {
+		return c.catalogView.Delete(ctx, name)
+	}
+
+	view, key, err := c.versionedKey(name, version)
+	if err != nil {
+		return err
+	}
+	return view.Delete(ctx, key)
+}
+
+// ListVersions returns the versions registered for the external plugin with
+// the given name, sorted from the oldest to the newest.
+func (c *PluginCatalog) ListVersions(ctx context.Context, name string) ([]string, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	keys, err := c.versionsView.List(ctx, name+"/")
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []string
+	versions := make(map[string]*goversion.Version)
+	for _, key := range keys {
+		// Skip the versions of the plugins nested under this name
+		if strings.HasSuffix(key, "/") {
+			continue
+		}
+		v, err := goversion.NewVersion(key)
+		if err != nil {
+			continue
+		}
+		ret = append(ret, key)
+		versions[key] = v
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return versions[ret[i]].LessThan(versions[ret[j]])
+	})
+	return ret, nil
+}
+
+// versionedKey returns the view and the key storing the given version of a
+// plugin
+func (c *PluginCatalog) versionedKey(name, v string) (*BarrierView, string, error) {
+	if _, err := goversion.NewVersion(v); err != nil {
+		return nil, "", fmt.Errorf("invalid plugin version %q: %v", v, err)
+	}
+	return c.versionsView, name + "/" + v, nil
 }
 
 // List returns a list of all the known plugin names. If an external and builtin
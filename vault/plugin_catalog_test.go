@@ -24,7 +24,7 @@ func TestPluginCatalog_CRUD(t *testing.T) {
 	core.pluginCatalog.directory = sym
 
 	// Get builtin plugin
-	p, err := core.pluginCatalog.Get(context.Background(), "mysql-database-plugin")
+	p, err := core.pluginCatalog.Get(context.Background(), "mysql-database-plugin", "")
 	if err != nil {
 		t.Fatalf("unexpected error %v", err)
 	}
@@ -52,13 +52,13 @@ func TestPluginCatalog_CRUD(t *testing.T) {
 	defer file.Close()
 
 	command := fmt.Sprintf("%s", filepath.Base(file.Name()))
-	err = core.pluginCatalog.Set(context.Background(), "mysql-database-plugin", command, []string{"--test"}, []byte{'1'})
+	err = core.pluginCatalog.Set(context.Background(), "mysql-database-plugin", "", command, []string{"--test"}, []byte{'1'})
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	// Get the plugin
-	p, err = core.pluginCatalog.Get(context.Background(), "mysql-database-plugin")
+	p, err = core.pluginCatalog.Get(context.Background(), "mysql-database-plugin", "")
 	if err != nil {
 		t.Fatalf("unexpected error %v", err)
 	}
@@ -76,13 +76,13 @@ func TestPluginCatalog_CRUD(t *testing.T) {
 	}
 
 	// Delete the plugin
-	err = core.pluginCatalog.Delete(context.Background(), "mysql-database-plugin")
+	err = core.pluginCatalog.Delete(context.Background(), "mysql-database-plugin", "")
 	if err != nil {
 		t.Fatalf("unexpected err: %v", err)
 	}
 
 	// Get builtin plugin
-	p, err = core.pluginCatalog.Get(context.Background(), "mysql-database-plugin")
+	p, err = core.pluginCatalog.Get(context.Background(), "mysql-database-plugin", "")
 	if err != nil {
 		t.Fatalf("unexpected error %v", err)
 	}
@@ -141,13 +141,13 @@ func TestPluginCatalog_List(t *testing.T) {
 	defer file.Close()
 
 	command := filepath.Base(file.Name())
-	err = core.pluginCatalog.Set(context.Background(), "mysql-database-plugin", command, []string{"--test"}, []byte{'1'})
+	err = core.pluginCatalog.Set(context.Background(), "mysql-database-plugin", "", command, []string{"--test"}, []byte{'1'})
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	// Set another plugin
-	err = core.pluginCatalog.Set(context.Background(), "aaaaaaa", command, []string{"--test"}, []byte{'1'})
+	err = core.pluginCatalog.Set(context.Background(), "aaaaaaa", "", command, []string{"--test"}, []byte{'1'})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -175,3 +175,85 @@ func TestPluginCatalog_List(t *testing.T) {
 	}
 
 }
+
+func TestPluginCatalog_Versions(t *testing.T) {
+	core, _, _ := TestCoreUnsealed(t)
+
+	sym, err := filepath.EvalSymlinks(os.TempDir())
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	core.pluginCatalog.directory = sym
+
+	file, err := ioutil.TempFile(os.TempDir(), "temp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	command := filepath.Base(file.Name())
+
+	ctx := context.Background()
+	for _, v := range []string{"", "1.10.0", "1.2.0", "v0.9.1"} {
+		if err := core.pluginCatalog.Set(ctx, "my-plugin", v, command, []string{"--version=" + v}, []byte{'1'}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := core.pluginCatalog.Set(ctx, "my-plugin", "latest", command, nil, []byte{'1'}); err == nil {
+		t.Fatal("expected error for an invalid version")
+	}
+
+	// Versions are sorted, and do not show up as plugins
+	versions, err := core.pluginCatalog.ListVersions(ctx, "my-plugin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(versions, []string{"v0.9.1", "1.2.0", "1.10.0"}) {
+		t.Fatalf("bad versions: %v", versions)
+	}
+	plugins, err := core.pluginCatalog.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plugins) != len(builtinplugins.Keys())+1 {
+		t.Fatalf("unexpected plugins: %v", plugins)
+	}
+
+	p, err := core.pluginCatalog.Get(ctx, "my-plugin", "1.2.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p == nil || p.Version != "1.2.0" || !reflect.DeepEqual(p.Args, []string{"--version=1.2.0"}) {
+		t.Fatalf("bad: %#v", p)
+	}
+	p, err = core.pluginCatalog.Get(ctx, "my-plugin", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p == nil || p.Version != "" {
+		t.Fatalf("bad: %#v", p)
+	}
+
+	// Builtin plugins have no versions
+	p, err = core.pluginCatalog.Get(ctx, "mysql-database-plugin", "1.2.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p != nil {
+		t.Fatalf("expected no plugin, got %#v", p)
+	}
+
+	// Deleting a version keeps the others
+	if err := core.pluginCatalog.Delete(ctx, "my-plugin", "1.2.0"); err != nil {
+		t.Fatal(err)
+	}
+	if p, err = core.pluginCatalog.Get(ctx, "my-plugin", "1.2.0"); err != nil || p != nil {
+		t.Fatalf("expected deleted version, got %#v, %v", p, err)
+	}
+	versions, err = core.pluginCatalog.ListVersions(ctx, "my-plugin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(versions, []string{"v0.9.1", "1.10.0"}) {
+		t.Fatalf("bad versions: %v", versions)
+	}
+}
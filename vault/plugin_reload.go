@@ -7,6 +7,7 @@ import (
 
 	multierror "github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/plugin"
 )
 
 // reloadPluginMounts reloads provided mounts, regardless of
@@ -49,6 +50,10 @@ func (c *Core) reloadMatchingPlugin(ctx context.Context, pluginName string) erro
 	c.mountsLock.Lock()
 	defer c.mountsLock.Unlock()
 
+	// Reloaded mounts of a plugin serving several mounts from one process
+	// start a new one rather than sharing the process being reloaded
+	plugin.ForgetMultiplexedClients(pluginName)
+
 	// Filter mount entries that only matches the plugin name
 	for _, entry := range c.mounts.Entries {
 		if entry.Config.PluginName == pluginName && entry.Type == "plugin" {
@@ -10,6 +10,7 @@ import (
 	"github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/hcl"
 	"github.com/hashicorp/hcl/hcl/ast"
+	"github.com/hashicorp/vault/helper/identity"
 	"github.com/hashicorp/vault/helper/parseutil"
 	"github.com/mitchellh/copystructure"
 )
@@ -21,6 +22,7 @@ const (
 	UpdateCapability = "update"
 	DeleteCapability = "delete"
 	ListCapability   = "list"
+	PatchCapability  = "patch"
 	SudoCapability   = "sudo"
 	RootCapability   = "root"
 
@@ -39,6 +41,7 @@ const (
 	DeleteCapabilityInt
 	ListCapabilityInt
 	SudoCapabilityInt
+	PatchCapabilityInt
 )
 
 type PolicyType uint32
@@ -74,6 +77,7 @@ var (
 		DeleteCapability: DeleteCapabilityInt,
 		ListCapability:   ListCapabilityInt,
 		SudoCapability:   SudoCapabilityInt,
+		PatchCapability:  PatchCapabilityInt,
 	}
 )
 
@@ -84,6 +88,16 @@ type Policy struct {
 	Paths []*PathRules `hcl:"-"`
 	Raw   string
 	Type  PolicyType
+
+	// Templated is set if any of the paths contain identity template
+	// directives, in which case the policy is parsed again with the entity
+	// of the token when building its ACL
+	Templated bool `hcl:"-"`
+
+	// namespace is the namespace the policy belongs to, whose path prefixes
+	// the paths of the policy. It is nil for policies parsed outside of the
+	// policy store, which apply to the root namespace.
+	namespace *Namespace
 }
 
 // PathRules represents a policy for a path in the namespace.
@@ -101,6 +115,7 @@ type PathRules struct {
 	AllowedParametersHCL  map[string][]interface{} `hcl:"allowed_parameters"`
 	DeniedParametersHCL   map[string][]interface{} `hcl:"denied_parameters"`
 	RequiredParametersHCL []string                 `hcl:"required_parameters"`
+	ControlGroupHCL       *ControlGroupHCL         `hcl:"control_group"`
 }
 
 type ACLPermissions struct {
@@ -110,6 +125,7 @@ type ACLPermissions struct {
 	AllowedParameters  map[string][]interface{}
 	DeniedParameters   map[string][]interface{}
 	RequiredParameters []string
+	ControlGroup       *ControlGroup
 }
 
 func (p *ACLPermissions) Clone() (*ACLPermissions, error) {
@@ -118,6 +134,7 @@ func (p *ACLPermissions) Clone() (*ACLPermissions, error) {
 		MinWrappingTTL:     p.MinWrappingTTL,
 		MaxWrappingTTL:     p.MaxWrappingTTL,
 		RequiredParameters: p.RequiredParameters[:],
+		ControlGroup:       p.ControlGroup,
 	}
 
 	switch {
@@ -151,6 +168,14 @@ func (p *ACLPermissions) Clone() (*ACLPermissions, error) {
 // intermediary set of policies, before being compiled into
 // the ACL
 func ParseACLPolicy(rules string) (*Policy, error) {
+	return parseACLPolicyWithTemplating(rules, false, nil, nil)
+}
+
+// parseACLPolicyWithTemplating parses the ACL rules, populating the identity
+// template directives of the paths with the given entity and groups if
+// performTemplating is set. Templated paths whose directives can't be
+// populated are left out of the policy.
+func parseACLPolicyWithTemplating(rules string, performTemplating bool, entity *identity.Entity, groups []*identity.Group) (*Policy, error) {
 	// Parse the rules
 	root, err := hcl.Parse(rules)
 	if err != nil {
@@ -181,7 +206,7 @@ func ParseACLPolicy(rules string) (*Policy, error) {
 	}
 
 	if o := list.Filter("path"); len(o.Items) > 0 {
-		if err := parsePaths(&p, o); err != nil {
+		if err := parsePaths(&p, o, performTemplating, entity, groups); err != nil {
 			return nil, fmt.Errorf("Failed to parse policy: %s", err)
 		}
 	}
@@ -189,7 +214,7 @@ func ParseACLPolicy(rules string) (*Policy, error) {
 	return &p, nil
 }
 
-func parsePaths(result *Policy, list *ast.ObjectList) error {
+func parsePaths(result *Policy, list *ast.ObjectList, performTemplating bool, entity *identity.Entity, groups []*identity.Group) error {
 	paths := make([]*PathRules, 0, len(list.Items))
 	for _, item := range list.Items {
 		key := "path"
@@ -204,11 +229,42 @@ func parsePaths(result *Policy, list *ast.ObjectList) error {
 			"required_parameters",
 			"min_wrapping_ttl",
 			"max_wrapping_ttl",
+			"control_group",
 		}
 		if err := checkHCLKeys(item.Val, valid); err != nil {
 			return multierror.Prefix(err, fmt.Sprintf("path %q:", key))
 		}
 
+		if performTemplating {
+			_, templated, err := identity.PopulateString(&identity.PopulateStringInput{
+				Mode:   identity.ACLTemplating,
+				String: key,
+				Entity: entity,
+				Groups: groups,
+			})
+			if err != nil {
+				continue
+			}
+
+			// The populated values must not turn the path into a glob
+			if strings.HasSuffix(templated, "*") && !strings.HasSuffix(key, "*") {
+				continue
+			}
+			key = templated
+		} else {
+			hasTemplating, _, err := identity.PopulateString(&identity.PopulateStringInput{
+				ValidityCheckOnly: true,
+				Mode:              identity.ACLTemplating,
+				String:            key,
+			})
+			if err != nil {
+				return errwrap.Wrapf("failed to validate policy templating: {{err}}", err)
+			}
+			if hasTemplating {
+				result.Templated = true
+			}
+		}
+
 		var pc PathRules
 
 		// allocate memory so that DecodeObject can initialize the ACLPermissions struct
@@ -255,7 +311,7 @@ func parsePaths(result *Policy, list *ast.ObjectList) error {
 				pc.Capabilities = []string{DenyCapability}
 				pc.Permissions.CapabilitiesBitmap = DenyCapabilityInt
 				goto PathFinished
-			case CreateCapability, ReadCapability, UpdateCapability, DeleteCapability, ListCapability, SudoCapability:
+			case CreateCapability, ReadCapability, UpdateCapability, DeleteCapability, ListCapability, SudoCapability, PatchCapability:
 				pc.Permissions.CapabilitiesBitmap |= cap2Int[cap]
 			default:
 				return fmt.Errorf("path %q: invalid capability '%s'", key, cap)
@@ -297,6 +353,13 @@ func parsePaths(result *Policy, list *ast.ObjectList) error {
 		if len(pc.RequiredParametersHCL) > 0 {
 			pc.Permissions.RequiredParameters = pc.RequiredParametersHCL[:]
 		}
+		if pc.ControlGroupHCL != nil {
+			cg, err := parseControlGroup(pc.ControlGroupHCL)
+			if err != nil {
+				return multierror.Prefix(err, fmt.Sprintf("path %q:", key))
+			}
+			pc.Permissions.ControlGroup = cg
+		}
 
 	PathFinished:
 		paths = append(paths, &pc)
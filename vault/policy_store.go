@@ -11,6 +11,7 @@ import (
 	"github.com/hashicorp/errwrap"
 	"github.com/hashicorp/golang-lru"
 	"github.com/hashicorp/vault/helper/consts"
+	"github.com/hashicorp/vault/helper/identity"
 	"github.com/hashicorp/vault/helper/strutil"
 	"github.com/hashicorp/vault/logical"
 	log "github.com/mgutz/logxi/v1"
@@ -41,6 +42,18 @@ path "cubbyhole/response" {
     capabilities = ["create", "read"]
 }
 
+path "sys/wrapping/unwrap" {
+    capabilities = ["update"]
+}
+`
+
+	// controlGroupPolicy is the policy of control group tokens, which can
+	// only be unwrapped once the request is authorized
+	controlGroupPolicy = `
+path "cubbyhole/response" {
+    capabilities = ["create", "read"]
+}
+
 path "sys/wrapping/unwrap" {
     capabilities = ["update"]
 }
@@ -135,9 +148,12 @@ var (
 )
 
 // PolicyStore is used to provide durable storage of policy, and to
-// manage ACLs associated with them.
+// manage ACLs associated with them. The policies of the child namespaces are
+// stored separately for every namespace, which is found in the context of the
+// operations.
 type PolicyStore struct {
 	aclView          *BarrierView
+	namespacesView   *BarrierView
 	tokenPoliciesLRU *lru.TwoQueueCache
 	// This is used to ensure that writes to the store (acl/rgp) or to the egp
 	// path tree don't happen concurrently. We are okay reading stale data so
@@ -147,6 +163,9 @@ type PolicyStore struct {
 	policyTypeMap sync.Map
 	// logger is the server logger copied over from core
 	logger log.Logger
+	// identityStoreFunc returns the identity store of a namespace, used to
+	// fetch the groups of the entities when populating templated policies
+	identityStoreFunc func(*Namespace) *IdentityStore
 }
 
 // PolicyEntry is used to store a policy by name
@@ -160,9 +179,10 @@ type PolicyEntry struct {
 // using a given view. It used used to durable store and manage named policy.
 func NewPolicyStore(ctx context.Context, baseView *BarrierView, system logical.SystemView, logger log.Logger) *PolicyStore {
 	ps := &PolicyStore{
-		aclView:    baseView.SubView(policyACLSubPath),
-		modifyLock: new(sync.RWMutex),
-		logger:     logger,
+		aclView:        baseView.SubView(policyACLSubPath),
+		namespacesView: baseView.SubView(namespaceSubPath),
+		modifyLock:     new(sync.RWMutex),
+		logger:         logger,
 	}
 	if !system.CachingDisabled() {
 		cache, _ := lru.New2Q(policyCacheSize)
@@ -177,6 +197,25 @@ func NewPolicyStore(ctx context.Context, baseView *BarrierView, system logical.S
 	for _, key := range keys {
 		ps.policyTypeMap.Store(ps.sanitizeName(key), PolicyTypeACL)
 	}
+
+	// Collect the policies of the child namespaces
+	namespaceIDs, err := ps.namespacesView.List(ctx, "")
+	if err != nil {
+		ps.logger.Error("policy: error collecting namespaces", "error", err)
+		return nil
+	}
+	for _, nsID := range namespaceIDs {
+		ns := &Namespace{ID: strings.TrimSuffix(nsID, "/")}
+		keys, err := logical.CollectKeys(ctx, ps.aclViewForNamespace(ns))
+		if err != nil {
+			ps.logger.Error("policy: error collecting acl policy keys", "namespace_id", ns.ID, "error", err)
+			return nil
+		}
+		for _, key := range keys {
+			ps.policyTypeMap.Store(policyCacheKey(ns, ps.sanitizeName(key)), PolicyTypeACL)
+		}
+	}
+
 	// Special-case root; doesn't exist on disk but does need to be found
 	ps.policyTypeMap.Store("root", PolicyTypeACL)
 	return ps
@@ -188,12 +227,20 @@ func (c *Core) setupPolicyStore(ctx context.Context) error {
 	// Create the policy store
 	sysView := &dynamicSystemView{core: c}
 	c.policyStore = NewPolicyStore(ctx, c.systemBarrierView, sysView, c.logger)
+	c.policyStore.identityStoreFunc = c.identityStoreForNamespace
 
 	if c.ReplicationState().HasState(consts.ReplicationPerformanceSecondary) {
 		// Policies will sync from the primary
 		return nil
 	}
 
+	if c.perfStandby {
+		// Policies are written by the active node, and read again from
+		// storage on every use
+		c.policyStore.tokenPoliciesLRU = nil
+		return nil
+	}
+
 	// Ensure that the default policy exists, and if not, create it
 	if err := c.policyStore.loadACLPolicy(ctx, defaultPolicyName, defaultPolicy); err != nil {
 		return err
@@ -202,6 +249,10 @@ func (c *Core) setupPolicyStore(ctx context.Context) error {
 	if err := c.policyStore.loadACLPolicy(ctx, responseWrappingPolicyName, responseWrappingPolicy); err != nil {
 		return err
 	}
+	// Ensure that the control group policy exists
+	if err := c.policyStore.loadACLPolicy(ctx, controlGroupPolicyName, controlGroupPolicy); err != nil {
+		return err
+	}
 
 	return nil
 }
@@ -213,6 +264,24 @@ func (c *Core) teardownPolicyStore() error {
 	return nil
 }
 
+// policyCacheKey returns the key of a policy in the cache and type map, which
+// is prefixed with the namespace ID for the policies of child namespaces
+func policyCacheKey(ns *Namespace, name string) string {
+	if ns.isRoot() {
+		return name
+	}
+	return ns.ID + "/" + name
+}
+
+// aclViewForNamespace returns the view holding the ACL policies of the
+// namespace
+func (ps *PolicyStore) aclViewForNamespace(ns *Namespace) *BarrierView {
+	if ns.isRoot() {
+		return ps.aclView
+	}
+	return ps.namespacesView.SubView(ns.ID + "/" + policyACLSubPath)
+}
+
 func (ps *PolicyStore) invalidate(ctx context.Context, name string, policyType PolicyType) {
 	// This may come with a prefixed "/" due to joining the file path
 	saneName := strings.TrimPrefix(name, "/")
@@ -258,6 +327,11 @@ func (ps *PolicyStore) SetPolicy(ctx context.Context, p *Policy) error {
 func (ps *PolicyStore) setPolicyInternal(ctx context.Context, p *Policy) error {
 	ps.modifyLock.Lock()
 	defer ps.modifyLock.Unlock()
+
+	ns := namespaceFromContext(ctx)
+	p.namespace = ns
+	key := policyCacheKey(ns, p.Name)
+
 	// Create the entry
 	entry, err := logical.StorageEntryJSON(p.Name, &PolicyEntry{
 		Version: 2,
@@ -269,14 +343,14 @@ func (ps *PolicyStore) setPolicyInternal(ctx context.Context, p *Policy) error {
 	}
 	switch p.Type {
 	case PolicyTypeACL:
-		if err := ps.aclView.Put(ctx, entry); err != nil {
+		if err := ps.aclViewForNamespace(ns).Put(ctx, entry); err != nil {
 			return errwrap.Wrapf("failed to persist policy: {{err}}", err)
 		}
-		ps.policyTypeMap.Store(p.Name, PolicyTypeACL)
+		ps.policyTypeMap.Store(key, PolicyTypeACL)
 
 		if ps.tokenPoliciesLRU != nil {
 			// Update the LRU cache
-			ps.tokenPoliciesLRU.Add(p.Name, p)
+			ps.tokenPoliciesLRU.Add(key, p)
 		}
 
 	default:
@@ -293,15 +367,18 @@ func (ps *PolicyStore) GetPolicy(ctx context.Context, name string, policyType Po
 	// Policies are normalized to lower-case
 	name = ps.sanitizeName(name)
 
+	ns := namespaceFromContext(ctx)
+	key := policyCacheKey(ns, name)
+
 	var cache *lru.TwoQueueCache
 	var view *BarrierView
 	switch policyType {
 	case PolicyTypeACL:
 		cache = ps.tokenPoliciesLRU
-		view = ps.aclView
+		view = ps.aclViewForNamespace(ns)
 	case PolicyTypeToken:
 		cache = ps.tokenPoliciesLRU
-		val, ok := ps.policyTypeMap.Load(name)
+		val, ok := ps.policyTypeMap.Load(key)
 		if !ok {
 			// Doesn't exist
 			return nil, nil
@@ -309,7 +386,7 @@ func (ps *PolicyStore) GetPolicy(ctx context.Context, name string, policyType Po
 		policyType = val.(PolicyType)
 		switch policyType {
 		case PolicyTypeACL:
-			view = ps.aclView
+			view = ps.aclViewForNamespace(ns)
 		default:
 			return nil, fmt.Errorf("invalid type of policy in type map: %s", policyType)
 		}
@@ -317,13 +394,13 @@ func (ps *PolicyStore) GetPolicy(ctx context.Context, name string, policyType Po
 
 	if cache != nil {
 		// Check for cached policy
-		if raw, ok := cache.Get(name); ok {
+		if raw, ok := cache.Get(key); ok {
 			return raw.(*Policy), nil
 		}
 	}
 
-	// Special case the root policy
-	if policyType == PolicyTypeACL && name == "root" {
+	// Special case the root policy, which only exists in the root namespace
+	if policyType == PolicyTypeACL && name == "root" && ns.isRoot() {
 		p := &Policy{Name: "root"}
 		if cache != nil {
 			cache.Add(p.Name, p)
@@ -336,7 +413,7 @@ func (ps *PolicyStore) GetPolicy(ctx context.Context, name string, policyType Po
 
 	// See if anything has added it since we got the lock
 	if cache != nil {
-		if raw, ok := cache.Get(name); ok {
+		if raw, ok := cache.Get(key); ok {
 			return raw.(*Policy), nil
 		}
 	}
@@ -362,6 +439,7 @@ func (ps *PolicyStore) GetPolicy(ctx context.Context, name string, policyType Po
 	policy.Name = name
 	policy.Raw = policyEntry.Raw
 	policy.Type = policyEntry.Type
+	policy.namespace = ns
 	switch policyEntry.Type {
 	case PolicyTypeACL:
 		// Parse normally
@@ -370,10 +448,11 @@ func (ps *PolicyStore) GetPolicy(ctx context.Context, name string, policyType Po
 			return nil, errwrap.Wrapf("failed to parse policy: {{err}}", err)
 		}
 		policy.Paths = p.Paths
+		policy.Templated = p.Templated
 		// Reset this in case they set the name in the policy itself
 		policy.Name = name
 
-		ps.policyTypeMap.Store(name, PolicyTypeACL)
+		ps.policyTypeMap.Store(key, PolicyTypeACL)
 
 	default:
 		return nil, fmt.Errorf("unknown policy type %q", policyEntry.Type.String())
@@ -381,7 +460,7 @@ func (ps *PolicyStore) GetPolicy(ctx context.Context, name string, policyType Po
 
 	if cache != nil {
 		// Update the LRU cache
-		cache.Add(name, policy)
+		cache.Add(key, policy)
 	}
 
 	return policy, nil
@@ -396,7 +475,7 @@ func (ps *PolicyStore) ListPolicies(ctx context.Context, policyType PolicyType)
 	var err error
 	switch policyType {
 	case PolicyTypeACL:
-		keys, err = logical.CollectKeys(ctx, ps.aclView)
+		keys, err = logical.CollectKeys(ctx, ps.aclViewForNamespace(namespaceFromContext(ctx)))
 	default:
 		return nil, fmt.Errorf("unknown policy type %s", policyType)
 	}
@@ -431,6 +510,9 @@ func (ps *PolicyStore) DeletePolicy(ctx context.Context, name string, policyType
 	// Policies are normalized to lower-case
 	name = ps.sanitizeName(name)
 
+	ns := namespaceFromContext(ctx)
+	key := policyCacheKey(ns, name)
+
 	switch policyType {
 	case PolicyTypeACL:
 		if strutil.StrListContains(immutablePolicies, name) {
@@ -440,25 +522,51 @@ func (ps *PolicyStore) DeletePolicy(ctx context.Context, name string, policyType
 			return fmt.Errorf("cannot delete default policy")
 		}
 
-		err := ps.aclView.Delete(ctx, name)
+		err := ps.aclViewForNamespace(ns).Delete(ctx, name)
 		if err != nil {
 			return errwrap.Wrapf("failed to delete policy: {{err}}", err)
 		}
 
 		if ps.tokenPoliciesLRU != nil {
 			// Clear the cache
-			ps.tokenPoliciesLRU.Remove(name)
+			ps.tokenPoliciesLRU.Remove(key)
 		}
 
-		ps.policyTypeMap.Delete(name)
+		ps.policyTypeMap.Delete(key)
+
+	}
+	return nil
+}
+
+// deleteNamespacePolicies removes all the policies of a child namespace that
+// is being deleted
+func (ps *PolicyStore) deleteNamespacePolicies(ctx context.Context, ns *Namespace) error {
+	ps.modifyLock.Lock()
+	defer ps.modifyLock.Unlock()
+
+	view := ps.aclViewForNamespace(ns)
+	keys, err := logical.CollectKeys(ctx, view)
+	if err != nil {
+		return errwrap.Wrapf("failed to list policies: {{err}}", err)
+	}
+	for _, name := range keys {
+		key := policyCacheKey(ns, ps.sanitizeName(name))
+		if ps.tokenPoliciesLRU != nil {
+			ps.tokenPoliciesLRU.Remove(key)
+		}
+		ps.policyTypeMap.Delete(key)
+	}
 
+	if err := logical.ClearView(ctx, view); err != nil {
+		return errwrap.Wrapf("failed to delete policies: {{err}}", err)
 	}
 	return nil
 }
 
 // ACL is used to return an ACL which is built using the
-// named policies.
-func (ps *PolicyStore) ACL(ctx context.Context, names ...string) (*ACL, error) {
+// named policies. The identity template directives of templated policies are
+// populated with the given entity and its groups.
+func (ps *PolicyStore) ACL(ctx context.Context, entity *identity.Entity, names ...string) (*ACL, error) {
 	// Fetch the policies
 	var policies []*Policy
 	for _, name := range names {
@@ -469,6 +577,37 @@ func (ps *PolicyStore) ACL(ctx context.Context, names ...string) (*ACL, error) {
 		policies = append(policies, p)
 	}
 
+	var fetchedGroups bool
+	var groups []*identity.Group
+	for i, policy := range policies {
+		if policy == nil || policy.Type != PolicyTypeACL || !policy.Templated {
+			continue
+		}
+
+		if !fetchedGroups {
+			fetchedGroups = true
+			var identityStore *IdentityStore
+			if ps.identityStoreFunc != nil {
+				identityStore = ps.identityStoreFunc(namespaceFromContext(ctx))
+			}
+			if entity != nil && identityStore != nil {
+				directGroups, inheritedGroups, err := identityStore.groupsByEntityID(entity.ID)
+				if err != nil {
+					return nil, errwrap.Wrapf("failed to fetch group memberships: {{err}}", err)
+				}
+				groups = append(directGroups, inheritedGroups...)
+			}
+		}
+
+		p, err := parseACLPolicyWithTemplating(policy.Raw, true, entity, groups)
+		if err != nil {
+			return nil, errwrap.Wrapf(fmt.Sprintf("error parsing templated policy %q: {{err}}", policy.Name), err)
+		}
+		p.Name = policy.Name
+		p.namespace = policy.namespace
+		policies[i] = p
+	}
+
 	// Construct the ACL
 	acl, err := NewACL(policies)
 	if err != nil {
@@ -5,6 +5,7 @@ import (
 	"reflect"
 	"testing"
 
+	"github.com/hashicorp/vault/helper/identity"
 	"github.com/hashicorp/vault/helper/logformat"
 	"github.com/hashicorp/vault/logical"
 	log "github.com/mgutz/logxi/v1"
@@ -196,9 +197,89 @@ func TestPolicyStore_ACL(t *testing.T) {
 		t.Fatalf("err: %v", err)
 	}
 
-	acl, err := ps.ACL(context.Background(), "dev", "ops")
+	acl, err := ps.ACL(context.Background(), nil, "dev", "ops")
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
 	testLayeredACL(t, acl)
 }
+
+func TestPolicyStore_TemplatedACL(t *testing.T) {
+	ps := mockPolicyStore(t)
+
+	policy, err := ParseACLPolicy(`
+path "secret/{{identity.entity.name}}/*" {
+	capabilities = ["read"]
+}
+path "team/{{ identity.entity.metadata.team }}" {
+	capabilities = ["read"]
+}
+path "static/*" {
+	capabilities = ["read"]
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !policy.Templated {
+		t.Fatal("expected the policy to be templated")
+	}
+	policy.Name = "templated"
+	if err := ps.SetPolicy(context.Background(), policy); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ParseACLPolicy(`path "secret/{{identity.entity.name/*" { capabilities = ["read"] }`); err == nil {
+		t.Fatal("expected an error parsing unbalanced templating characters")
+	}
+
+	allowed := func(entity *identity.Entity, path string) bool {
+		acl, err := ps.ACL(context.Background(), entity, "templated")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return acl.AllowOperation(&logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      path,
+		}).Allowed
+	}
+
+	alice := &identity.Entity{
+		ID:   "alice-id",
+		Name: "alice",
+		Metadata: map[string]string{
+			"team": "ops",
+		},
+	}
+	for path, expected := range map[string]bool{
+		"secret/alice/foo": true,
+		"secret/bob/foo":   false,
+		"team/ops":         true,
+		"team/opsx":        false,
+		"static/foo":       true,
+	} {
+		if allowed(alice, path) != expected {
+			t.Fatalf("%s: expected allowed to be %v", path, expected)
+		}
+	}
+
+	// Populated values can't turn a path into a glob
+	mallory := &identity.Entity{
+		ID:   "mallory-id",
+		Name: "mallory",
+		Metadata: map[string]string{
+			"team": "ops*",
+		},
+	}
+	if allowed(mallory, "team/opsx") {
+		t.Fatal("expected the populated value not to be a glob")
+	}
+
+	// Templated paths don't apply to tokens without an entity
+	if allowed(nil, "secret/alice/foo") {
+		t.Fatal("expected templated path not to apply without an entity")
+	}
+	if !allowed(nil, "static/foo") {
+		t.Fatal("expected static path to apply without an entity")
+	}
+}
@@ -0,0 +1,642 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+const (
+	// coreQuotasPath is the prefix of the barrier entries of the quotas, which
+	// are stored by type and name
+	coreQuotasPath = "core/quotas/"
+
+	// quotaTypeRateLimit quotas limit the rate of the requests of each client
+	quotaTypeRateLimit = "rate-limit"
+
+	// quotaTypeLeaseCount quotas limit the number of leases
+	quotaTypeLeaseCount = "lease-count"
+
+	// rateLimitPurgeInterval is the interval after which the rate limiters of
+	// idle clients are removed
+	rateLimitPurgeInterval = time.Minute
+)
+
+var (
+	// errLoadQuotasFailed if setupQuotas encounters an error
+	errLoadQuotasFailed = errors.New("failed to setup quotas")
+
+	// quotaTypes are the supported types of quotas
+	quotaTypes = []string{quotaTypeRateLimit, quotaTypeLeaseCount}
+)
+
+// Quota limits the requests or leases of the paths below its path, which is
+// the full path of a namespace or of a mount, or empty for global quotas. Of
+// the quotas of a type, only the one with the longest matching path applies.
+type Quota struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Path string `json:"path"`
+
+	// Rate is the number of requests per second allowed for each client by
+	// rate limit quotas, and Burst the number of requests that can be made at
+	// once
+	Rate  float64 `json:"rate,omitempty"`
+	Burst int     `json:"burst,omitempty"`
+
+	// MaxLeases is the maximum number of leases of lease count quotas
+	MaxLeases int `json:"max_leases,omitempty"`
+}
+
+// quotaState is the state of an active quota
+type quotaState struct {
+	quota *Quota
+
+	lock sync.Mutex
+
+	// buckets are the token buckets of the clients of rate limit quotas, by
+	// remote address
+	buckets   map[string]*tokenBucket
+	lastPurge time.Time
+
+	// leases is the number of leases counted by lease count quotas, and
+	// reserved the number of leases being registered
+	leases   int
+	reserved int
+}
+
+// tokenBucket is the rate limiter of a client
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// quotaManager holds the active quotas by type and name
+type quotaManager struct {
+	lock   sync.RWMutex
+	quotas map[string]map[string]*quotaState
+
+	// updateLock serializes the updates of the quotas
+	updateLock sync.Mutex
+
+	// resolvePath returns the full path of a router path
+	resolvePath func(string) string
+}
+
+func newQuotaManager(resolvePath func(string) string) *quotaManager {
+	m := &quotaManager{
+		quotas:      make(map[string]map[string]*quotaState, len(quotaTypes)),
+		resolvePath: resolvePath,
+	}
+	for _, quotaType := range quotaTypes {
+		m.quotas[quotaType] = make(map[string]*quotaState)
+	}
+	return m
+}
+
+// quotaPathMatches checks whether the quota path applies to the full path
+func quotaPathMatches(quotaPath, path string) bool {
+	return strings.HasPrefix(path, quotaPath) || path == strings.TrimSuffix(quotaPath, "/")
+}
+
+// matchLocked returns the quota of the type with the longest path matching
+// the full path. The manager lock must be held.
+func (m *quotaManager) matchLocked(quotaType, path string) *quotaState {
+	var match *quotaState
+	for _, state := range m.quotas[quotaType] {
+		if !quotaPathMatches(state.quota.Path, path) {
+			continue
+		}
+		if match == nil || len(state.quota.Path) > len(match.quota.Path) {
+			match = state
+		}
+	}
+	return match
+}
+
+// allowRequest checks the rate limit quota of the full path of a request
+// for the client at the given address. When the request is rejected, it also
+// returns the time until the client is allowed another request.
+func (m *quotaManager) allowRequest(path, addr string) (bool, time.Duration) {
+	if m == nil {
+		return true, 0
+	}
+
+	m.lock.RLock()
+	state := m.matchLocked(quotaTypeRateLimit, path)
+	m.lock.RUnlock()
+	if state == nil {
+		return true, 0
+	}
+
+	now := time.Now()
+	if !state.allow(addr, now) {
+		metrics.IncrCounter([]string{"quota", "rate_limit", "violation"}, 1)
+		return false, state.retryAfter(addr, now)
+	}
+	return true, 0
+}
+
+// allow takes a token from the bucket of the client, refilling the bucket at
+// the rate of the quota
+func (s *quotaState) allow(addr string, now time.Time) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if now.Sub(s.lastPurge) > rateLimitPurgeInterval {
+		for key, bucket := range s.buckets {
+			if now.Sub(bucket.last) > rateLimitPurgeInterval {
+				delete(s.buckets, key)
+			}
+		}
+		s.lastPurge = now
+	}
+
+	burst := float64(s.quota.Burst)
+	bucket, ok := s.buckets[addr]
+	if !ok {
+		bucket = &tokenBucket{
+			tokens: burst,
+			last:   now,
+		}
+		s.buckets[addr] = bucket
+	}
+
+	bucket.tokens = math.Min(burst, bucket.tokens+now.Sub(bucket.last).Seconds()*s.quota.Rate)
+	bucket.last = now
+	if bucket.tokens < 1 {
+		return false
+	}
+	bucket.tokens--
+	return true
+}
+
+// retryAfter returns the time until the bucket of the client refills with a
+// token
+func (s *quotaState) retryAfter(addr string, now time.Time) time.Duration {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	bucket, ok := s.buckets[addr]
+	if !ok {
+		return 0
+	}
+	missing := 1 - bucket.tokens - now.Sub(bucket.last).Seconds()*s.quota.Rate
+	if missing <= 0 {
+		return 0
+	}
+	return time.Duration(missing / s.quota.Rate * float64(time.Second))
+}
+
+// reserveLease reserves a lease in the lease count quota of the full path,
+// so that concurrent requests cannot exceed the quota. It returns false when
+// the quota is exceeded, otherwise the returned function must be called to
+// release the reservation once the lease is registered, or failed to be.
+func (m *quotaManager) reserveLease(path string) (func(), bool) {
+	if m == nil {
+		return func() {}, true
+	}
+
+	m.lock.RLock()
+	state := m.matchLocked(quotaTypeLeaseCount, path)
+	m.lock.RUnlock()
+	if state == nil {
+		return func() {}, true
+	}
+
+	state.lock.Lock()
+	defer state.lock.Unlock()
+	if state.leases+state.reserved >= state.quota.MaxLeases {
+		metrics.IncrCounter([]string{"quota", "lease_count", "violation"}, 1)
+		return nil, false
+	}
+	state.reserved++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			state.lock.Lock()
+			state.reserved--
+			state.lock.Unlock()
+		})
+	}, true
+}
+
+// leaseChanged updates the lease count quotas matching the lease when it is
+// added or removed. It is called by the expiration manager with its pending
+// lock held.
+func (m *quotaManager) leaseChanged(leaseID string, delta int) {
+	if m == nil {
+		return
+	}
+
+	path := m.resolvePath(leaseID)
+
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	for _, state := range m.quotas[quotaTypeLeaseCount] {
+		if !quotaPathMatches(state.quota.Path, path) {
+			continue
+		}
+		state.lock.Lock()
+		state.leases += delta
+		state.lock.Unlock()
+	}
+}
+
+// get returns a copy of the quota and, for lease count quotas, the number of
+// leases it counts
+func (m *quotaManager) get(quotaType, name string) (*Quota, int) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	state, ok := m.quotas[quotaType][name]
+	if !ok {
+		return nil, 0
+	}
+	quota := *state.quota
+
+	state.lock.Lock()
+	defer state.lock.Unlock()
+	return &quota, state.leases
+}
+
+// list returns the sorted names of the quotas of the type
+func (m *quotaManager) list(quotaType string) []string {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	names := make([]string, 0, len(m.quotas[quotaType]))
+	for name := range m.quotas[quotaType] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// setLocked activates the quota with the given lease count. The manager lock
+// must be held.
+func (m *quotaManager) setLocked(quota *Quota, leases int) {
+	m.quotas[quota.Type][quota.Name] = &quotaState{
+		quota:   quota,
+		buckets: make(map[string]*tokenBucket),
+		leases:  leases,
+	}
+}
+
+// quotaPath returns the full path of a router path, which includes the path
+// of its namespace. Quotas are scoped by full paths.
+func (c *Core) quotaPath(routePath string) string {
+	ns, path := c.resolveNamespace(routePath)
+	return ns.Path + path
+}
+
+// isQuotaExemptPath checks whether the full path of a request is exempt from
+// the rate limit quotas, so that the quotas can always be updated
+func isQuotaExemptPath(path string) bool {
+	return path == "sys/quotas" || strings.HasPrefix(path, "sys/quotas/")
+}
+
+// setupQuotas is invoked as part of postUnseal to load the quotas. It runs
+// before the expiration manager is set up, which counts the restored leases.
+func (c *Core) setupQuotas(ctx context.Context) error {
+	m := newQuotaManager(c.quotaPath)
+
+	for _, quotaType := range quotaTypes {
+		prefix := coreQuotasPath + quotaType + "/"
+		names, err := c.barrier.List(ctx, prefix)
+		if err != nil {
+			c.logger.Error("core: failed to list quotas", "error", err)
+			return errLoadQuotasFailed
+		}
+		for _, name := range names {
+			raw, err := c.barrier.Get(ctx, prefix+name)
+			if err != nil {
+				c.logger.Error("core: failed to read quota", "name", name, "error", err)
+				return errLoadQuotasFailed
+			}
+			if raw == nil {
+				continue
+			}
+			quota := &Quota{}
+			if err := jsonutil.DecodeJSON(raw.Value, quota); err != nil {
+				c.logger.Error("core: failed to decode quota", "name", name, "error", err)
+				return errLoadQuotasFailed
+			}
+			m.setLocked(quota, 0)
+		}
+	}
+
+	c.quotas = m
+	return nil
+}
+
+// teardownQuotas is used to remove the quotas before sealing
+func (c *Core) teardownQuotas() {
+	c.quotas = nil
+}
+
+// validateQuotaPath checks that the full path of a quota is empty, or the path
+// of a namespace or of a mount
+func (c *Core) validateQuotaPath(path string) error {
+	if path == "" {
+		return nil
+	}
+	ns, rel := c.resolveNamespace(path)
+	if rel == "" {
+		return nil
+	}
+	routePath := namespaceRoutePath(ns, rel)
+	if c.router.MatchingMount(routePath) != routePath {
+		return logical.CodedError(400, fmt.Sprintf("no namespace or mount at path %q", path))
+	}
+	return nil
+}
+
+// setQuota validates, persists and activates a quota. The leases of new lease
+// count quotas are counted from the pending leases.
+func (c *Core) setQuota(ctx context.Context, quota *Quota) error {
+	if err := c.validateQuotaPath(quota.Path); err != nil {
+		return err
+	}
+
+	m := c.quotas
+	m.updateLock.Lock()
+	defer m.updateLock.Unlock()
+
+	m.lock.RLock()
+	for name, state := range m.quotas[quota.Type] {
+		if name != quota.Name && state.quota.Path == quota.Path {
+			m.lock.RUnlock()
+			return logical.CodedError(400, fmt.Sprintf("quota %q already applies to path %q", name, quota.Path))
+		}
+	}
+	m.lock.RUnlock()
+
+	raw, err := jsonutil.EncodeJSON(quota)
+	if err != nil {
+		return err
+	}
+	if err := c.barrier.Put(ctx, &Entry{
+		Key:   coreQuotasPath + quota.Type + "/" + quota.Name,
+		Value: raw,
+	}); err != nil {
+		c.logger.Error("core: failed to persist quota", "name", quota.Name, "error", err)
+		return logical.CodedError(500, "failed to persist quota")
+	}
+
+	if quota.Type != quotaTypeLeaseCount {
+		m.lock.Lock()
+		m.setLocked(quota, 0)
+		m.lock.Unlock()
+		return nil
+	}
+
+	// The pending lock is held while counting, so that no lease is added or
+	// removed before the quota is active
+	exp := c.expiration
+	exp.pendingLock.RLock()
+	defer exp.pendingLock.RUnlock()
+	leases := 0
+	for leaseID := range exp.pending {
+		if quotaPathMatches(quota.Path, c.quotaPath(leaseID)) {
+			leases++
+		}
+	}
+	m.lock.Lock()
+	m.setLocked(quota, leases)
+	m.lock.Unlock()
+	return nil
+}
+
+// deleteQuota removes a quota
+func (c *Core) deleteQuota(ctx context.Context, quotaType, name string) error {
+	m := c.quotas
+	m.updateLock.Lock()
+	defer m.updateLock.Unlock()
+
+	if err := c.barrier.Delete(ctx, coreQuotasPath+quotaType+"/"+name); err != nil {
+		c.logger.Error("core: failed to delete quota", "name", name, "error", err)
+		return logical.CodedError(500, "failed to delete quota")
+	}
+
+	m.lock.Lock()
+	delete(m.quotas[quotaType], name)
+	m.lock.Unlock()
+	return nil
+}
+
+func quotaPaths(b *SystemBackend) []*framework.Path {
+	nameField := &framework.FieldSchema{
+		Type:        framework.TypeString,
+		Description: strings.TrimSpace(sysHelp["quota-name"][0]),
+	}
+	pathField := &framework.FieldSchema{
+		Type:        framework.TypeString,
+		Description: strings.TrimSpace(sysHelp["quota-path"][0]),
+	}
+
+	return []*framework.Path{
+		{
+			Pattern: "quotas/rate-limit/?$",
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ListOperation: b.handleQuotasList(quotaTypeRateLimit),
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["rate-limit-quotas"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["rate-limit-quotas"][1]),
+		},
+		{
+			Pattern: "quotas/rate-limit/(?P<name>.+)",
+
+			Fields: map[string]*framework.FieldSchema{
+				"name": nameField,
+				"path": pathField,
+				"rate": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: strings.TrimSpace(sysHelp["quota-rate"][0]),
+				},
+				"burst": &framework.FieldSchema{
+					Type:        framework.TypeInt,
+					Description: strings.TrimSpace(sysHelp["quota-burst"][0]),
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation:   b.handleQuotasRead(quotaTypeRateLimit),
+				logical.UpdateOperation: b.handleRateLimitQuotaSet,
+				logical.DeleteOperation: b.handleQuotasDelete(quotaTypeRateLimit),
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["rate-limit-quota"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["rate-limit-quota"][1]),
+		},
+		{
+			Pattern: "quotas/lease-count/?$",
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ListOperation: b.handleQuotasList(quotaTypeLeaseCount),
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["lease-count-quotas"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["lease-count-quotas"][1]),
+		},
+		{
+			Pattern: "quotas/lease-count/(?P<name>.+)",
+
+			Fields: map[string]*framework.FieldSchema{
+				"name": nameField,
+				"path": pathField,
+				"max_leases": &framework.FieldSchema{
+					Type:        framework.TypeInt,
+					Description: strings.TrimSpace(sysHelp["quota-max-leases"][0]),
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation:   b.handleQuotasRead(quotaTypeLeaseCount),
+				logical.UpdateOperation: b.handleLeaseCountQuotaSet,
+				logical.DeleteOperation: b.handleQuotasDelete(quotaTypeLeaseCount),
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["lease-count-quota"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["lease-count-quota"][1]),
+		},
+	}
+}
+
+// sanitizeQuotaPath normalizes the path of a quota to the form of the mount
+// and namespace paths
+func sanitizeQuotaPath(path string) string {
+	path = strings.TrimPrefix(strings.TrimSpace(path), "/")
+	if path != "" && !strings.HasSuffix(path, "/") {
+		path += "/"
+	}
+	return path
+}
+
+// handleQuotasList lists the quotas of a type
+func (b *SystemBackend) handleQuotasList(quotaType string) framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		return logical.ListResponse(b.Core.quotas.list(quotaType)), nil
+	}
+}
+
+// handleQuotasRead returns a quota
+func (b *SystemBackend) handleQuotasRead(quotaType string) framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		quota, leases := b.Core.quotas.get(quotaType, d.Get("name").(string))
+		if quota == nil {
+			return nil, nil
+		}
+
+		resp := &logical.Response{
+			Data: map[string]interface{}{
+				"name": quota.Name,
+				"type": quota.Type,
+				"path": quota.Path,
+			},
+		}
+		switch quotaType {
+		case quotaTypeRateLimit:
+			resp.Data["rate"] = quota.Rate
+			resp.Data["burst"] = quota.Burst
+		case quotaTypeLeaseCount:
+			resp.Data["max_leases"] = quota.MaxLeases
+			resp.Data["leases"] = leases
+		}
+		return resp, nil
+	}
+}
+
+// handleQuotasDelete deletes a quota
+func (b *SystemBackend) handleQuotasDelete(quotaType string) framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		name := d.Get("name").(string)
+		if err := b.Core.deleteQuota(ctx, quotaType, name); err != nil {
+			b.Backend.Logger().Error("sys: delete quota failed", "name", name, "error", err)
+			return handleError(err)
+		}
+		return nil, nil
+	}
+}
+
+// quotaForUpdate returns a copy of the existing quota of the request or a new
+// quota, with the path of the request if given
+func (b *SystemBackend) quotaForUpdate(quotaType string, d *framework.FieldData) *Quota {
+	name := d.Get("name").(string)
+	quota, _ := b.Core.quotas.get(quotaType, name)
+	if quota == nil {
+		quota = &Quota{
+			Name: name,
+			Type: quotaType,
+		}
+	}
+	if path, ok := d.GetOk("path"); ok {
+		quota.Path = sanitizeQuotaPath(path.(string))
+	}
+	return quota
+}
+
+// handleRateLimitQuotaSet creates or updates a rate limit quota
+func (b *SystemBackend) handleRateLimitQuotaSet(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	quota := b.quotaForUpdate(quotaTypeRateLimit, d)
+
+	if rateRaw, ok := d.GetOk("rate"); ok {
+		rate, err := strconv.ParseFloat(rateRaw.(string), 64)
+		if err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("invalid rate: %v", err)), logical.ErrInvalidRequest
+		}
+		quota.Rate = rate
+	}
+	if quota.Rate <= 0 || math.IsInf(quota.Rate, 0) || math.IsNaN(quota.Rate) {
+		return logical.ErrorResponse("rate must be a positive number"), logical.ErrInvalidRequest
+	}
+
+	if burst, ok := d.GetOk("burst"); ok {
+		quota.Burst = burst.(int)
+	}
+	if quota.Burst < 0 {
+		return logical.ErrorResponse("burst cannot be negative"), logical.ErrInvalidRequest
+	}
+	// By default a second worth of requests can be made at once
+	if quota.Burst == 0 {
+		quota.Burst = int(math.Max(1, math.Ceil(quota.Rate)))
+	}
+
+	if err := b.Core.setQuota(ctx, quota); err != nil {
+		b.Backend.Logger().Error("sys: set quota failed", "name", quota.Name, "error", err)
+		return handleError(err)
+	}
+	return nil, nil
+}
+
+// handleLeaseCountQuotaSet creates or updates a lease count quota
+func (b *SystemBackend) handleLeaseCountQuotaSet(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	quota := b.quotaForUpdate(quotaTypeLeaseCount, d)
+
+	if maxLeases, ok := d.GetOk("max_leases"); ok {
+		quota.MaxLeases = maxLeases.(int)
+	}
+	if quota.MaxLeases <= 0 {
+		return logical.ErrorResponse("max_leases must be a positive number"), logical.ErrInvalidRequest
+	}
+
+	if err := b.Core.setQuota(ctx, quota); err != nil {
+		b.Backend.Logger().Error("sys: set quota failed", "name", quota.Name, "error", err)
+		return handleError(err)
+	}
+	return nil, nil
+}
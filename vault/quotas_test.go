@@ -0,0 +1,345 @@
+package vault
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/logical"
+)
+
+// testQuotaRequest handles a request with the root token from the given
+// address
+func testQuotaRequest(c *Core, root string, op logical.Operation, path, addr string, data map[string]interface{}) (*logical.Response, error) {
+	req := logical.TestRequest(nil, op, path)
+	req.ClientToken = root
+	req.Connection = &logical.Connection{RemoteAddr: addr}
+	if data != nil {
+		req.Data = data
+	}
+	return c.HandleRequest(req)
+}
+
+func TestQuotas_TokenBucket(t *testing.T) {
+	state := &quotaState{
+		quota: &Quota{
+			Rate:  2,
+			Burst: 2,
+		},
+		buckets: make(map[string]*tokenBucket),
+	}
+
+	now := time.Now()
+	for i := 0; i < 2; i++ {
+		if !state.allow("client", now) {
+			t.Fatalf("request %d: expected to be allowed", i)
+		}
+	}
+	if state.allow("client", now) {
+		t.Fatal("expected the burst to be exhausted")
+	}
+	if retryAfter := state.retryAfter("client", now); retryAfter != 500*time.Millisecond {
+		t.Fatalf("bad retry after: %s", retryAfter)
+	}
+
+	// The bucket refills at the rate of the quota, up to the burst
+	if !state.allow("client", now.Add(500*time.Millisecond)) {
+		t.Fatal("expected a refilled token")
+	}
+	if state.allow("client", now.Add(500*time.Millisecond)) {
+		t.Fatal("expected the bucket to be empty")
+	}
+	later := now.Add(time.Hour)
+	for i := 0; i < 2; i++ {
+		if !state.allow("client", later) {
+			t.Fatalf("request %d: expected to be allowed", i)
+		}
+	}
+	if state.allow("client", later) {
+		t.Fatal("expected the bucket to be capped at the burst")
+	}
+
+	// Clients have their own buckets, and idle buckets are purged
+	if !state.allow("other", later) {
+		t.Fatal("expected the request of another client to be allowed")
+	}
+	state.allow("other", later.Add(2*rateLimitPurgeInterval))
+	if _, ok := state.buckets["client"]; ok {
+		t.Fatal("expected the idle bucket to be purged")
+	}
+}
+
+func TestQuotas_ReserveLease(t *testing.T) {
+	m := newQuotaManager(func(path string) string { return path })
+	m.setLocked(&Quota{
+		Name:      "secret",
+		Type:      quotaTypeLeaseCount,
+		Path:      "secret/",
+		MaxLeases: 2,
+	}, 1)
+
+	// Paths without a lease count quota are not limited
+	if _, ok := m.reserveLease("other/foo"); !ok {
+		t.Fatal("expected a path without a quota to be allowed")
+	}
+
+	release, ok := m.reserveLease("secret/foo")
+	if !ok {
+		t.Fatal("expected the lease to be reserved")
+	}
+
+	// The reserved lease counts against the quota until it is released
+	if _, ok := m.reserveLease("secret/foo"); ok {
+		t.Fatal("expected the reservation to count against the quota")
+	}
+	release()
+	release()
+	if state := m.quotas[quotaTypeLeaseCount]["secret"]; state.reserved != 0 {
+		t.Fatalf("expected no reservation, got %d", state.reserved)
+	}
+
+	// A registered lease replaces the reservation
+	release, ok = m.reserveLease("secret/foo")
+	if !ok {
+		t.Fatal("expected the lease to be reserved")
+	}
+	m.leaseChanged("secret/foo/lease", 1)
+	release()
+	if _, ok := m.reserveLease("secret/foo"); ok {
+		t.Fatal("expected the quota to be exceeded")
+	}
+	if _, n := m.get(quotaTypeLeaseCount, "secret"); n != 2 {
+		t.Fatalf("expected 2 leases, got %d", n)
+	}
+}
+
+func TestQuotas_RateLimit(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	resp, err := testQuotaRequest(c, root, logical.UpdateOperation, "sys/quotas/rate-limit/global", "127.0.0.1", map[string]interface{}{
+		"rate":  "0.001",
+		"burst": 2,
+	})
+	if err != nil || resp != nil {
+		t.Fatalf("err: %v resp: %#v", err, resp)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := testQuotaRequest(c, root, logical.ReadOperation, "sys/mounts", "127.0.0.1", nil); err != nil {
+			t.Fatalf("request %d: err: %v", i, err)
+		}
+	}
+	_, err = testQuotaRequest(c, root, logical.ReadOperation, "sys/mounts", "127.0.0.1", nil)
+	if err == nil || !errwrap.Contains(err, logical.ErrRateLimitQuotaExceeded.Error()) {
+		t.Fatalf("expected the rate limit quota to be exceeded, got %v", err)
+	}
+	if quotaErr, ok := err.(*logical.RateLimitQuotaError); !ok || quotaErr.RetryAfter <= 0 || quotaErr.RetryAfter > 1000*time.Second {
+		t.Fatalf("bad: %#v", err)
+	}
+
+	// Other clients are not limited, and the quotas can still be managed
+	if _, err := testQuotaRequest(c, root, logical.ReadOperation, "sys/mounts", "127.0.0.2", nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	resp, err = testQuotaRequest(c, root, logical.ReadOperation, "sys/quotas/rate-limit/global", "127.0.0.1", nil)
+	if err != nil || resp == nil {
+		t.Fatalf("err: %v resp: %#v", err, resp)
+	}
+	if resp.Data["rate"] != 0.001 || resp.Data["burst"] != 2 || resp.Data["path"] != "" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// The quota of a mount takes precedence over the global quota
+	resp, err = testQuotaRequest(c, root, logical.UpdateOperation, "sys/quotas/rate-limit/secret", "127.0.0.1", map[string]interface{}{
+		"path": "secret",
+		"rate": 100,
+	})
+	if err != nil || resp != nil {
+		t.Fatalf("err: %v resp: %#v", err, resp)
+	}
+	if _, err := testQuotaRequest(c, root, logical.ReadOperation, "secret/foo", "127.0.0.1", nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	resp, err = testQuotaRequest(c, root, logical.ListOperation, "sys/quotas/rate-limit", "127.0.0.1", nil)
+	if err != nil || resp == nil {
+		t.Fatalf("err: %v resp: %#v", err, resp)
+	}
+	if keys := resp.Data["keys"].([]string); len(keys) != 2 || keys[0] != "global" || keys[1] != "secret" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	if _, err := testQuotaRequest(c, root, logical.DeleteOperation, "sys/quotas/rate-limit/global", "127.0.0.1", nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := testQuotaRequest(c, root, logical.ReadOperation, "sys/mounts", "127.0.0.1", nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestQuotas_InvalidQuotas(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	for name, data := range map[string]map[string]interface{}{
+		"rate-limit/missing-rate":  {},
+		"rate-limit/negative-rate": {"rate": "-1"},
+		"rate-limit/invalid-rate":  {"rate": "fast"},
+		"rate-limit/missing-mount": {"rate": 1, "path": "missing"},
+		"lease-count/missing-max":  {"path": "secret"},
+		"lease-count/missing-path": {"max_leases": 1, "path": "missing/kv"},
+	} {
+		resp, err := testQuotaRequest(c, root, logical.UpdateOperation, "sys/quotas/"+name, "127.0.0.1", data)
+		if err == nil && (resp == nil || !resp.IsError()) {
+			t.Fatalf("%s: expected an error", name)
+		}
+	}
+
+	// Only one quota of a type can apply to a path
+	if _, err := testQuotaRequest(c, root, logical.UpdateOperation, "sys/quotas/lease-count/a", "127.0.0.1", map[string]interface{}{
+		"path":       "/secret/",
+		"max_leases": 1,
+	}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := testQuotaRequest(c, root, logical.UpdateOperation, "sys/quotas/lease-count/b", "127.0.0.1", map[string]interface{}{
+		"path":       "secret",
+		"max_leases": 1,
+	}); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestQuotas_LeaseCount(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	if _, err := testQuotaRequest(c, root, logical.UpdateOperation, "secret/foo", "127.0.0.1", map[string]interface{}{
+		"foo": "bar",
+		"ttl": "1h",
+	}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := testQuotaRequest(c, root, logical.UpdateOperation, "sys/quotas/lease-count/secret", "127.0.0.1", map[string]interface{}{
+		"path":       "secret",
+		"max_leases": 1,
+	}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	resp, err := testQuotaRequest(c, root, logical.ReadOperation, "secret/foo", "127.0.0.1", nil)
+	if err != nil || resp == nil || resp.Secret == nil || resp.Secret.LeaseID == "" {
+		t.Fatalf("err: %v resp: %#v", err, resp)
+	}
+	leaseID := resp.Secret.LeaseID
+
+	_, err = testQuotaRequest(c, root, logical.ReadOperation, "secret/foo", "127.0.0.1", nil)
+	if err == nil || !errwrap.Contains(err, logical.ErrLeaseCountQuotaExceeded.Error()) {
+		t.Fatalf("expected the lease count quota to be exceeded, got %v", err)
+	}
+
+	resp, err = testQuotaRequest(c, root, logical.ReadOperation, "sys/quotas/lease-count/secret", "127.0.0.1", nil)
+	if err != nil || resp == nil {
+		t.Fatalf("err: %v resp: %#v", err, resp)
+	}
+	if resp.Data["leases"] != 1 || resp.Data["max_leases"] != 1 || resp.Data["path"] != "secret/" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Revoking the lease frees the quota
+	if _, err := testQuotaRequest(c, root, logical.UpdateOperation, "sys/leases/revoke/"+leaseID, "127.0.0.1", nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := testQuotaRequest(c, root, logical.ReadOperation, "secret/foo", "127.0.0.1", nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestQuotas_LeaseCountTokens(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	var tokens []string
+	create := func() error {
+		resp, err := testQuotaRequest(c, root, logical.UpdateOperation, "auth/token/create", "127.0.0.1", map[string]interface{}{
+			"policies": []string{"default"},
+		})
+		if err == nil {
+			tokens = append(tokens, resp.Auth.ClientToken)
+		}
+		return err
+	}
+
+	// The existing leases are counted when the quota is created
+	if err := create(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := testQuotaRequest(c, root, logical.UpdateOperation, "sys/quotas/lease-count/tokens", "127.0.0.1", map[string]interface{}{
+		"path":       "auth/token",
+		"max_leases": 2,
+	}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := create(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := create(); err == nil || !errwrap.Contains(err, logical.ErrLeaseCountQuotaExceeded.Error()) {
+		t.Fatalf("expected the lease count quota to be exceeded, got %v", err)
+	}
+	if _, n := c.quotas.get(quotaTypeLeaseCount, "tokens"); n != 2 {
+		t.Fatalf("expected 2 leases, got %d", n)
+	}
+
+	if err := c.tokenStore.Revoke(context.Background(), tokens[0]); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := create(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestQuotas_Persist(t *testing.T) {
+	c, keys, root := TestCoreUnsealed(t)
+
+	if _, err := testQuotaRequest(c, root, logical.UpdateOperation, "auth/token/create", "127.0.0.1", map[string]interface{}{
+		"policies": []string{"default"},
+	}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for name, data := range map[string]map[string]interface{}{
+		"rate-limit/global": {"rate": 10},
+		"lease-count/token": {"path": "auth/token", "max_leases": 10},
+	} {
+		if _, err := testQuotaRequest(c, root, logical.UpdateOperation, "sys/quotas/"+name, "127.0.0.1", data); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	if err := c.Seal(root); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for _, key := range keys {
+		if _, err := TestCoreUnseal(c, TestKeyCopy(key)); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	quota, _ := c.quotas.get(quotaTypeRateLimit, "global")
+	if quota == nil || quota.Rate != 10 || quota.Burst != 10 {
+		t.Fatalf("bad: %#v", quota)
+	}
+
+	// The leases are counted again as they are restored
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		quota, leases := c.quotas.get(quotaTypeLeaseCount, "token")
+		if quota == nil {
+			t.Fatal("expected the lease count quota")
+		}
+		if leases == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected 1 lease, got %d", leases)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -3,6 +3,7 @@ package vault
 import (
 	"bytes"
 	"context"
+	"crypto/subtle"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
@@ -30,10 +31,18 @@ const (
 // RekeyResult is used to provide the key parts back after
 // they are generated as part of the rekey.
 type RekeyResult struct {
-	SecretShares    [][]byte
-	PGPFingerprints []string
-	Backup          bool
-	RecoveryKey     bool
+	SecretShares         [][]byte
+	PGPFingerprints      []string
+	Backup               bool
+	RecoveryKey          bool
+	VerificationRequired bool
+	VerificationNonce    string
+}
+
+// RekeyVerifyResult is used to provide the nonce of the rekey once its new
+// key shares are verified and the new key is installed.
+type RekeyVerifyResult struct {
+	Nonce string
 }
 
 // RekeyBackup stores the backup copy of PGP-encrypted keys
@@ -97,6 +106,27 @@ func (c *Core) RekeyProgress(recovery bool) (int, error) {
 	return len(c.barrierRekeyProgress), nil
 }
 
+// RekeyVerifyProgress is used to return the progress (num shares) of the
+// verification of the new key shares.
+func (c *Core) RekeyVerifyProgress(recovery bool) (int, error) {
+	c.stateLock.RLock()
+	defer c.stateLock.RUnlock()
+	if c.sealed {
+		return 0, consts.ErrSealed
+	}
+	if c.standby {
+		return 0, consts.ErrStandby
+	}
+
+	c.rekeyLock.RLock()
+	defer c.rekeyLock.RUnlock()
+
+	if recovery {
+		return len(c.recoveryRekeyVerifyProgress), nil
+	}
+	return len(c.barrierRekeyVerifyProgress), nil
+}
+
 // RekeyConfig is used to read the rekey configuration
 func (c *Core) RekeyConfig(recovery bool) (*SealConfig, error) {
 	c.stateLock.RLock()
@@ -147,6 +177,9 @@ func (c *Core) BarrierRekeyInit(config *SealConfig) error {
 		if config.Backup {
 			return fmt.Errorf("key backup not supported when using stored keys")
 		}
+		if config.VerificationRequired {
+			return fmt.Errorf("requiring verification not supported when using stored keys")
+		}
 	}
 
 	if c.seal.RecoveryKeySupported() && c.seal.RecoveryType() == config.Type {
@@ -308,6 +341,10 @@ func (c *Core) BarrierRekeyUpdate(ctx context.Context, key []byte, nonce string)
 		return nil, fmt.Errorf("incorrect nonce supplied; nonce for this rekey operation is %s", c.barrierRekeyConfig.Nonce)
 	}
 
+	if len(c.barrierRekeyConfig.VerificationKey) > 0 {
+		return nil, fmt.Errorf("rekey operation already finished; verification must be performed; nonce for the verification operation is %s", c.barrierRekeyConfig.VerificationNonce)
+	}
+
 	// Check if we already have this piece
 	for _, existing := range c.barrierRekeyProgress {
 		if bytes.Equal(existing, key) {
@@ -428,6 +465,21 @@ func (c *Core) BarrierRekeyUpdate(ctx context.Context, key []byte, nonce string)
 		}
 	}
 
+	// If verification is required, hold the new key until the new shares
+	// are provided
+	if c.barrierRekeyConfig.VerificationRequired {
+		nonce, err := uuid.GenerateUUID()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate verification nonce: %v", err)
+		}
+		c.barrierRekeyConfig.VerificationNonce = nonce
+		c.barrierRekeyConfig.VerificationKey = newMasterKey
+
+		results.VerificationRequired = true
+		results.VerificationNonce = nonce
+		return results, nil
+	}
+
 	if keysToStore != nil {
 		if err := c.seal.SetStoredKeys(ctx, keysToStore); err != nil {
 			c.logger.Error("core: failed to store keys", "error", err)
@@ -435,17 +487,30 @@ func (c *Core) BarrierRekeyUpdate(ctx context.Context, key []byte, nonce string)
 		}
 	}
 
+	if err := c.performBarrierRekey(ctx, newMasterKey); err != nil {
+		return nil, err
+	}
+
+	// Done!
+	c.barrierRekeyProgress = nil
+	c.barrierRekeyConfig = nil
+	return results, nil
+}
+
+// performBarrierRekey installs the new master key and the barrier rekey
+// configuration. The rekey lock must be held.
+func (c *Core) performBarrierRekey(ctx context.Context, newMasterKey []byte) error {
 	// Rekey the barrier
 	if err := c.barrier.Rekey(ctx, newMasterKey); err != nil {
 		c.logger.Error("core: failed to rekey barrier", "error", err)
-		return nil, fmt.Errorf("failed to rekey barrier: %v", err)
+		return fmt.Errorf("failed to rekey barrier: %v", err)
 	}
 	if c.logger.IsInfo() {
 		c.logger.Info("core: security barrier rekeyed", "shares", c.barrierRekeyConfig.SecretShares, "threshold", c.barrierRekeyConfig.SecretThreshold)
 	}
 	if err := c.seal.SetBarrierConfig(ctx, c.barrierRekeyConfig); err != nil {
 		c.logger.Error("core: error saving rekey seal configuration", "error", err)
-		return nil, fmt.Errorf("failed to save rekey seal configuration: %v", err)
+		return fmt.Errorf("failed to save rekey seal configuration: %v", err)
 	}
 
 	// Write to the canary path, which will force a synchronous truing during
@@ -455,13 +520,10 @@ func (c *Core) BarrierRekeyUpdate(ctx context.Context, key []byte, nonce string)
 		Value: []byte(c.barrierRekeyConfig.Nonce),
 	}); err != nil {
 		c.logger.Error("core: error saving keyring canary", "error", err)
-		return nil, fmt.Errorf("failed to save keyring canary: %v", err)
+		return fmt.Errorf("failed to save keyring canary: %v", err)
 	}
 
-	// Done!
-	c.barrierRekeyProgress = nil
-	c.barrierRekeyConfig = nil
-	return results, nil
+	return nil
 }
 
 // RecoveryRekeyUpdate is used to provide a new key part
@@ -509,6 +571,10 @@ func (c *Core) RecoveryRekeyUpdate(ctx context.Context, key []byte, nonce string
 		return nil, fmt.Errorf("incorrect nonce supplied; nonce for this rekey operation is %s", c.recoveryRekeyConfig.Nonce)
 	}
 
+	if len(c.recoveryRekeyConfig.VerificationKey) > 0 {
+		return nil, fmt.Errorf("rekey operation already finished; verification must be performed; nonce for the verification operation is %s", c.recoveryRekeyConfig.VerificationNonce)
+	}
+
 	// Check if we already have this piece
 	for _, existing := range c.recoveryRekeyProgress {
 		if bytes.Equal(existing, key) {
@@ -611,14 +677,42 @@ func (c *Core) RecoveryRekeyUpdate(ctx context.Context, key []byte, nonce string
 		}
 	}
 
+	// If verification is required, hold the new key until the new shares
+	// are provided
+	if c.recoveryRekeyConfig.VerificationRequired {
+		nonce, err := uuid.GenerateUUID()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate verification nonce: %v", err)
+		}
+		c.recoveryRekeyConfig.VerificationNonce = nonce
+		c.recoveryRekeyConfig.VerificationKey = newMasterKey
+
+		results.VerificationRequired = true
+		results.VerificationNonce = nonce
+		return results, nil
+	}
+
+	if err := c.performRecoveryRekey(ctx, newMasterKey); err != nil {
+		return nil, err
+	}
+
+	// Done!
+	c.recoveryRekeyProgress = nil
+	c.recoveryRekeyConfig = nil
+	return results, nil
+}
+
+// performRecoveryRekey installs the new recovery key and the recovery rekey
+// configuration. The rekey lock must be held.
+func (c *Core) performRecoveryRekey(ctx context.Context, newMasterKey []byte) error {
 	if err := c.seal.SetRecoveryKey(ctx, newMasterKey); err != nil {
 		c.logger.Error("core: failed to set recovery key", "error", err)
-		return nil, fmt.Errorf("failed to set recovery key: %v", err)
+		return fmt.Errorf("failed to set recovery key: %v", err)
 	}
 
 	if err := c.seal.SetRecoveryConfig(ctx, c.recoveryRekeyConfig); err != nil {
 		c.logger.Error("core: error saving rekey seal configuration", "error", err)
-		return nil, fmt.Errorf("failed to save rekey seal configuration: %v", err)
+		return fmt.Errorf("failed to save rekey seal configuration: %v", err)
 	}
 
 	// Write to the canary path, which will force a synchronous truing during
@@ -628,13 +722,148 @@ func (c *Core) RecoveryRekeyUpdate(ctx context.Context, key []byte, nonce string
 		Value: []byte(c.recoveryRekeyConfig.Nonce),
 	}); err != nil {
 		c.logger.Error("core: error saving keyring canary", "error", err)
-		return nil, fmt.Errorf("failed to save keyring canary: %v", err)
+		return fmt.Errorf("failed to save keyring canary: %v", err)
 	}
 
-	// Done!
-	c.recoveryRekeyProgress = nil
-	c.recoveryRekeyConfig = nil
-	return results, nil
+	return nil
+}
+
+// RekeyVerify is used to provide a share of the new barrier or recovery key
+// of a rekey requiring verification. Once enough shares are provided and they
+// reconstruct the new key, the new key is installed.
+func (c *Core) RekeyVerify(ctx context.Context, key []byte, nonce string, recovery bool) (*RekeyVerifyResult, error) {
+	// Ensure we are already unsealed
+	c.stateLock.RLock()
+	defer c.stateLock.RUnlock()
+	if c.sealed {
+		return nil, consts.ErrSealed
+	}
+	if c.standby {
+		return nil, consts.ErrStandby
+	}
+
+	// Verify the key length
+	min, max := c.barrier.KeyLength()
+	max += shamir.ShareOverhead
+	if len(key) < min {
+		return nil, &ErrInvalidKey{fmt.Sprintf("key is shorter than minimum %d bytes", min)}
+	}
+	if len(key) > max {
+		return nil, &ErrInvalidKey{fmt.Sprintf("key is longer than maximum %d bytes", max)}
+	}
+
+	c.rekeyLock.Lock()
+	defer c.rekeyLock.Unlock()
+
+	config := c.barrierRekeyConfig
+	progress := &c.barrierRekeyVerifyProgress
+	if recovery {
+		config = c.recoveryRekeyConfig
+		progress = &c.recoveryRekeyVerifyProgress
+	}
+
+	// Ensure a verification is in progress
+	if config == nil {
+		return nil, fmt.Errorf("no rekey in progress")
+	}
+	if len(config.VerificationKey) == 0 {
+		return nil, fmt.Errorf("no rekey verification in progress")
+	}
+
+	if nonce != config.VerificationNonce {
+		return nil, fmt.Errorf("incorrect nonce supplied; nonce for this verify operation is %s", config.VerificationNonce)
+	}
+
+	// Check if we already have this piece
+	for _, existing := range *progress {
+		if bytes.Equal(existing, key) {
+			return nil, fmt.Errorf("given key has already been provided during this verify operation")
+		}
+	}
+
+	// Store this key
+	*progress = append(*progress, key)
+
+	// Check if we don't have enough keys to verify
+	if len(*progress) < config.SecretThreshold {
+		if c.logger.IsDebug() {
+			c.logger.Debug("core: cannot verify rekey yet, not enough keys", "keys", len(*progress), "threshold", config.SecretThreshold)
+		}
+		return nil, nil
+	}
+
+	// Recover the new key
+	var recoveredKey []byte
+	var err error
+	if config.SecretThreshold == 1 {
+		recoveredKey = (*progress)[0]
+	} else {
+		recoveredKey, err = shamir.Combine(*progress)
+	}
+	*progress = nil
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute new key: %v", err)
+	}
+
+	if subtle.ConstantTimeCompare(recoveredKey, config.VerificationKey) != 1 {
+		c.logger.Error("core: rekey verification failed, the provided shares do not match the new key")
+		return nil, fmt.Errorf("rekey verification failed; incorrect key shares supplied")
+	}
+
+	if recovery {
+		if err := c.performRecoveryRekey(ctx, config.VerificationKey); err != nil {
+			return nil, err
+		}
+		c.recoveryRekeyProgress = nil
+		c.recoveryRekeyConfig = nil
+	} else {
+		if err := c.performBarrierRekey(ctx, config.VerificationKey); err != nil {
+			return nil, err
+		}
+		c.barrierRekeyProgress = nil
+		c.barrierRekeyConfig = nil
+	}
+
+	return &RekeyVerifyResult{
+		Nonce: config.Nonce,
+	}, nil
+}
+
+// RekeyVerifyRestart is used to discard the shares provided so far to verify
+// a rekey, and start the verification anew with a new nonce.
+func (c *Core) RekeyVerifyRestart(recovery bool) error {
+	c.stateLock.RLock()
+	defer c.stateLock.RUnlock()
+	if c.sealed {
+		return consts.ErrSealed
+	}
+	if c.standby {
+		return consts.ErrStandby
+	}
+
+	c.rekeyLock.Lock()
+	defer c.rekeyLock.Unlock()
+
+	config := c.barrierRekeyConfig
+	if recovery {
+		config = c.recoveryRekeyConfig
+	}
+	if config == nil || len(config.VerificationKey) == 0 {
+		return fmt.Errorf("no rekey verification in progress")
+	}
+
+	nonce, err := uuid.GenerateUUID()
+	if err != nil {
+		return fmt.Errorf("failed to generate verification nonce: %v", err)
+	}
+	config.VerificationNonce = nonce
+
+	if recovery {
+		c.recoveryRekeyVerifyProgress = nil
+	} else {
+		c.barrierRekeyVerifyProgress = nil
+	}
+	return nil
 }
 
 // RekeyCancel is used to cancel an inprogress rekey
@@ -655,9 +884,11 @@ func (c *Core) RekeyCancel(recovery bool) error {
 	if recovery {
 		c.recoveryRekeyConfig = nil
 		c.recoveryRekeyProgress = nil
+		c.recoveryRekeyVerifyProgress = nil
 	} else {
 		c.barrierRekeyConfig = nil
 		c.barrierRekeyProgress = nil
+		c.barrierRekeyVerifyProgress = nil
 	}
 	return nil
 }
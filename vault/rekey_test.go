@@ -317,6 +317,145 @@ func testCore_Rekey_Update_Common(t *testing.T, c *Core, keys [][]byte, root str
 	}
 }
 
+func TestCore_Rekey_Verify(t *testing.T) {
+	bc, _ := TestSealDefConfigs()
+	bc.SecretShares = 1
+	bc.SecretThreshold = 1
+	c, masterKeys, _, root := TestCoreUnsealedWithConfigs(t, bc, nil)
+
+	newConf := &SealConfig{
+		Type:                 c.seal.BarrierType(),
+		SecretThreshold:      3,
+		SecretShares:         5,
+		VerificationRequired: true,
+	}
+	if err := c.RekeyInit(newConf, false); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	rkconf, err := c.RekeyConfig(false)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	result, err := c.RekeyUpdate(context.Background(), masterKeys[0], rkconf.Nonce, false)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if result == nil || !result.VerificationRequired || result.VerificationNonce == "" || len(result.SecretShares) != 5 {
+		t.Fatalf("bad: %#v", result)
+	}
+
+	// The old key is still active until the verification
+	if err := c.barrier.VerifyMaster(masterKeys[0]); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Further updates are refused
+	if _, err := c.RekeyUpdate(context.Background(), masterKeys[0], rkconf.Nonce, false); err == nil {
+		t.Fatal("expected error")
+	}
+
+	// A wrong nonce is refused
+	if _, err := c.RekeyVerify(context.Background(), TestKeyCopy(result.SecretShares[0]), rkconf.Nonce, false); err == nil {
+		t.Fatal("expected error")
+	}
+
+	// Restarting discards the progress and changes the nonce
+	if _, err := c.RekeyVerify(context.Background(), TestKeyCopy(result.SecretShares[0]), result.VerificationNonce, false); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := c.RekeyVerifyRestart(false); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if num, err := c.RekeyVerifyProgress(false); err != nil || num != 0 {
+		t.Fatalf("bad: %d %v", num, err)
+	}
+	rkconf, err = c.RekeyConfig(false)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if rkconf.VerificationNonce == result.VerificationNonce {
+		t.Fatal("expected a new verification nonce")
+	}
+
+	var verifyResult *RekeyVerifyResult
+	for i := 0; i < 3; i++ {
+		verifyResult, err = c.RekeyVerify(context.Background(), TestKeyCopy(result.SecretShares[i]), rkconf.VerificationNonce, false)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+	if verifyResult == nil || verifyResult.Nonce != rkconf.Nonce {
+		t.Fatalf("bad: %#v", verifyResult)
+	}
+
+	// Should be no config
+	conf, err := c.RekeyConfig(false)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if conf != nil {
+		t.Fatalf("rekey config should be nil, got: %v", conf)
+	}
+
+	// Unseal with the new shares
+	if err := c.Seal(root); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := TestCoreUnseal(c, TestKeyCopy(result.SecretShares[i])); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+	if sealed, _ := c.Sealed(); sealed {
+		t.Fatalf("should be unsealed")
+	}
+}
+
+func TestCore_Rekey_Verify_Invalid(t *testing.T) {
+	bc, _ := TestSealDefConfigs()
+	bc.SecretShares = 1
+	bc.SecretThreshold = 1
+	c, masterKeys, _, _ := TestCoreUnsealedWithConfigs(t, bc, nil)
+
+	newConf := &SealConfig{
+		Type:                 c.seal.BarrierType(),
+		SecretThreshold:      1,
+		SecretShares:         1,
+		VerificationRequired: true,
+	}
+	if err := c.RekeyInit(newConf, false); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	rkconf, err := c.RekeyConfig(false)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	result, err := c.RekeyUpdate(context.Background(), masterKeys[0], rkconf.Nonce, false)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The old key does not verify the new one, and is kept
+	if _, err := c.RekeyVerify(context.Background(), TestKeyCopy(masterKeys[0]), result.VerificationNonce, false); err == nil {
+		t.Fatal("expected error")
+	}
+	if err := c.barrier.VerifyMaster(masterKeys[0]); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Canceling discards the new key
+	if err := c.RekeyCancel(false); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := c.RekeyVerify(context.Background(), TestKeyCopy(result.SecretShares[0]), result.VerificationNonce, false); err == nil {
+		t.Fatal("expected error")
+	}
+	if err := c.barrier.VerifyMaster(masterKeys[0]); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
+
 func TestCore_Rekey_Invalid(t *testing.T) {
 	bc, _ := TestSealDefConfigs()
 	bc.StoredShares = 0
@@ -0,0 +1,1333 @@
+package vault
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/SermoDigital/jose/jws"
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/helper/consts"
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/helper/wrapping"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+	"github.com/mitchellh/mapstructure"
+)
+
+const (
+	// drReplicationALPN is the protocol of the DR replication streams on the
+	// cluster port
+	drReplicationALPN = "dr_wal_v1"
+
+	// drReplicationPrefix is the prefix of the DR replication state, which
+	// is local to each cluster of the replication set
+	drReplicationPrefix    = "core/replication/dr/"
+	drPrimaryStatePath     = drReplicationPrefix + "primary/state"
+	drSecondariesPrefix    = drReplicationPrefix + "primary/secondaries/"
+	drSecondaryStatePath   = drReplicationPrefix + "secondary/state"
+	drDefaultActivationTTL = 30 * time.Minute
+)
+
+var (
+	// DRWALMaxEntries is the number of the latest barrier changes kept in
+	// memory by a DR primary. Secondaries falling further behind are sent a
+	// snapshot of the storage. It's var so that tests can lower it.
+	DRWALMaxEntries = 16384
+
+	// drSecondaryRetryInterval is how long a DR secondary waits before
+	// connecting again to its primary. It's var so that tests can lower it.
+	drSecondaryRetryInterval = 5 * time.Second
+
+	// drLocalPaths and drLocalPrefixes are the barrier entries belonging to
+	// a single cluster, like its keyring and HA state, which are not
+	// replicated. DR secondaries keep their own seal and unseal keys.
+	drLocalPaths = []string{
+		barrierInitPath,
+		keyringPath,
+		masterKeyPath,
+		coreKeyringCanaryPath,
+		coreLocalClusterInfoPath,
+		coreLockPath,
+		poisonPillPath,
+		coreDROperationTokenPath,
+		coreBarrierUnsealKeysBackupPath,
+		coreRecoveryUnsealKeysBackupPath,
+		barrierSealConfigPath,
+		recoverySealConfigPath,
+		recoverySealConfigPlaintextPath,
+		recoveryKeyPath,
+	}
+	drLocalPrefixes = []string{
+		keyringUpgradePrefix,
+		coreLeaderPrefix,
+		knownPrimaryAddrsPrefix,
+		"core/hsm/",
+		drReplicationPrefix,
+	}
+)
+
+// drReplicatedKey returns whether the barrier entry of the key is replicated
+// to the DR secondaries
+func drReplicatedKey(key string) bool {
+	if strutil.StrListContains(drLocalPaths, key) {
+		return false
+	}
+	for _, prefix := range drLocalPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// drWALEntry is a change of the barrier recorded in the WAL
+type drWALEntry struct {
+	Index    uint64
+	Delete   bool
+	Key      string
+	Value    []byte
+	SealWrap bool
+}
+
+// drWAL is the write-ahead log of the barrier changes of a DR primary. Only
+// the latest changes are kept in memory; the epoch identifies the log, as its
+// indexes start over each time the primary is set up.
+type drWAL struct {
+	epoch string
+
+	l        sync.RWMutex
+	entries  []*drWALEntry
+	last     uint64
+	notifyCh chan struct{}
+}
+
+func newDRWAL() (*drWAL, error) {
+	epoch, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+	return &drWAL{
+		epoch:    epoch,
+		notifyCh: make(chan struct{}),
+	}, nil
+}
+
+// append records the change with the next index, and wakes up the streams
+// waiting for it
+func (w *drWAL) append(entry *drWALEntry) {
+	w.l.Lock()
+	defer w.l.Unlock()
+
+	w.last++
+	entry.Index = w.last
+	w.entries = append(w.entries, entry)
+
+	// Drop the oldest entries in bulk so that appending stays cheap
+	if len(w.entries) >= 2*DRWALMaxEntries {
+		w.entries = append([]*drWALEntry(nil), w.entries[len(w.entries)-DRWALMaxEntries:]...)
+	}
+
+	close(w.notifyCh)
+	w.notifyCh = make(chan struct{})
+}
+
+// since returns the changes after the index, along with a channel closed on
+// the next change. ok is false if the changes right after the index were
+// dropped, or the index is not of the log.
+func (w *drWAL) since(index uint64) (entries []*drWALEntry, notifyCh <-chan struct{}, ok bool) {
+	w.l.RLock()
+	defer w.l.RUnlock()
+
+	switch {
+	case index > w.last:
+		return nil, nil, false
+	case index == w.last:
+		return nil, w.notifyCh, true
+	}
+
+	first := w.last - uint64(len(w.entries)) + 1
+	if index+1 < first {
+		return nil, nil, false
+	}
+	return w.entries[index+1-first:], w.notifyCh, true
+}
+
+// lastIndex returns the index of the latest change
+func (w *drWAL) lastIndex() uint64 {
+	w.l.RLock()
+	defer w.l.RUnlock()
+
+	return w.last
+}
+
+// drWALBarrier wraps the barrier of the core to record the changes of the
+// replicated entries in the WAL of the DR primary, while it's set
+type drWALBarrier struct {
+	SecurityBarrier
+
+	// writeLock keeps the changes in the WAL in the order of the writes
+	writeLock sync.Mutex
+	wal       atomic.Value
+}
+
+func (b *drWALBarrier) setWAL(wal *drWAL) {
+	b.wal.Store(wal)
+}
+
+func (b *drWALBarrier) currentWAL() *drWAL {
+	wal, _ := b.wal.Load().(*drWAL)
+	return wal
+}
+
+func (b *drWALBarrier) Put(ctx context.Context, entry *Entry) error {
+	wal := b.currentWAL()
+	if wal == nil || !drReplicatedKey(entry.Key) {
+		return b.SecurityBarrier.Put(ctx, entry)
+	}
+
+	b.writeLock.Lock()
+	defer b.writeLock.Unlock()
+
+	if err := b.SecurityBarrier.Put(ctx, entry); err != nil {
+		return err
+	}
+	wal.append(&drWALEntry{
+		Key:      entry.Key,
+		Value:    append([]byte(nil), entry.Value...),
+		SealWrap: entry.SealWrap,
+	})
+	return nil
+}
+
+func (b *drWALBarrier) Delete(ctx context.Context, key string) error {
+	wal := b.currentWAL()
+	if wal == nil || !drReplicatedKey(key) {
+		return b.SecurityBarrier.Delete(ctx, key)
+	}
+
+	b.writeLock.Lock()
+	defer b.writeLock.Unlock()
+
+	if err := b.SecurityBarrier.Delete(ctx, key); err != nil {
+		return err
+	}
+	wal.append(&drWALEntry{
+		Delete: true,
+		Key:    key,
+	})
+	return nil
+}
+
+func (b *drWALBarrier) ListPage(ctx context.Context, prefix, after string, limit int) ([]string, error) {
+	if p, ok := b.SecurityBarrier.(paginatedBarrierStorage); ok {
+		return p.ListPage(ctx, prefix, after, limit)
+	}
+
+	keys, err := b.SecurityBarrier.List(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	return strutil.StrListPage(keys, after, limit), nil
+}
+
+// drPrimaryState is the persisted state of a DR primary. The secondaries are
+// authenticated with certificates issued by its CA.
+type drPrimaryState struct {
+	ClusterID          string `json:"cluster_id"`
+	PrimaryClusterAddr string `json:"primary_cluster_addr"`
+	CACert             []byte `json:"ca_cert"`
+	CAKey              []byte `json:"ca_key"`
+}
+
+// drSecondaryEntry is a secondary known to a DR primary
+type drSecondaryEntry struct {
+	ID           string `json:"id"`
+	SerialNumber string `json:"serial_number"`
+}
+
+// drActivation is the data wrapped in the activation token of a secondary
+type drActivation struct {
+	ID                 string `mapstructure:"id"`
+	ClusterID          string `mapstructure:"cluster_id"`
+	PrimaryClusterAddr string `mapstructure:"primary_cluster_addr"`
+	CACert             string `mapstructure:"ca_cert"`
+	ClientCert         string `mapstructure:"client_cert"`
+	ClientKey          string `mapstructure:"client_key"`
+}
+
+// drPrimary is the DR replication of the active node of a primary
+type drPrimary struct {
+	state  *drPrimaryState
+	wal    *drWAL
+	caCert *x509.Certificate
+	caKey  *ecdsa.PrivateKey
+	stopCh chan struct{}
+
+	l     sync.Mutex
+	conns map[string]*drSecondaryConn
+}
+
+// drSecondaryConn is the stream of a connected secondary
+type drSecondaryConn struct {
+	conn          net.Conn
+	connectedAt   time.Time
+	lastAck       uint64
+	lastHeartbeat time.Time
+}
+
+// register records the stream of the secondary, closing its previous one
+func (p *drPrimary) register(id string, conn net.Conn) *drSecondaryConn {
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	if previous, ok := p.conns[id]; ok {
+		previous.conn.Close()
+	}
+	sc := &drSecondaryConn{
+		conn:        conn,
+		connectedAt: time.Now(),
+	}
+	p.conns[id] = sc
+	return sc
+}
+
+func (p *drPrimary) unregister(id string, sc *drSecondaryConn) {
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	if p.conns[id] == sc {
+		delete(p.conns, id)
+	}
+}
+
+// ack records the index of the changes applied by the secondary
+func (p *drPrimary) ack(sc *drSecondaryConn, index uint64) {
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	sc.lastAck = index
+	sc.lastHeartbeat = time.Now()
+}
+
+// disconnect closes the stream of the secondary, if it's connected
+func (p *drPrimary) disconnect(id string) {
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	if sc, ok := p.conns[id]; ok {
+		sc.conn.Close()
+		delete(p.conns, id)
+	}
+}
+
+// stop closes the streams of all the secondaries
+func (p *drPrimary) stop() {
+	close(p.stopCh)
+
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	for id, sc := range p.conns {
+		sc.conn.Close()
+		delete(p.conns, id)
+	}
+}
+
+// issueClientCert issues the certificate a secondary authenticates with
+func (p *drPrimary) issueClientCert(id string) (certPEM, keyPEM []byte, serial *big.Int, err error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	serial, err = rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	template := &x509.Certificate{
+		Subject: pkix.Name{
+			CommonName: id,
+		},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyAgreement,
+		SerialNumber: serial,
+		NotBefore:    time.Now().Add(-30 * time.Second),
+		NotAfter:     time.Now().Add(262980 * time.Hour),
+	}
+	certBytes, err := x509.CreateCertificate(rand.Reader, template, p.caCert, key.Public(), p.caKey)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	keyBytes, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certBytes})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyBytes})
+	return certPEM, keyPEM, serial, nil
+}
+
+// drSecondaryState is the persisted state of a DR secondary. The credentials
+// are empty while the secondary has no primary to connect to. The epoch and
+// the index are of the last change applied from the WAL of the primary.
+type drSecondaryState struct {
+	ClusterID          string `json:"cluster_id"`
+	ID                 string `json:"id"`
+	PrimaryClusterAddr string `json:"primary_cluster_addr"`
+	CACert             string `json:"ca_cert"`
+	ClientCert         string `json:"client_cert"`
+	ClientKey          string `json:"client_key"`
+	Epoch              string `json:"epoch"`
+	Index              uint64 `json:"index"`
+}
+
+// tlsConfig returns the TLS configuration to connect to the primary with
+func (s *drSecondaryState) tlsConfig() (*tls.Config, error) {
+	block, _ := pem.Decode([]byte(s.CACert))
+	if block == nil {
+		return nil, fmt.Errorf("failed to decode the CA certificate of the primary")
+	}
+	caCert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, errwrap.Wrapf("failed to parse the CA certificate of the primary: {{err}}", err)
+	}
+	cert, err := tls.X509KeyPair([]byte(s.ClientCert), []byte(s.ClientKey))
+	if err != nil {
+		return nil, errwrap.Wrapf("failed to load the client certificate: {{err}}", err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(caCert)
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      pool,
+		ServerName:   caCert.Subject.CommonName,
+		NextProtos:   []string{drReplicationALPN},
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// drSecondary is the DR replication of the active node of a secondary
+type drSecondary struct {
+	cancel context.CancelFunc
+	doneCh chan struct{}
+
+	l             sync.RWMutex
+	state         *drSecondaryState
+	connState     string
+	lastRemoteWAL uint64
+	lastHeartbeat time.Time
+}
+
+// currentState returns a copy of the state of the secondary
+func (s *drSecondary) currentState() *drSecondaryState {
+	s.l.RLock()
+	defer s.l.RUnlock()
+
+	state := *s.state
+	return &state
+}
+
+func (s *drSecondary) setConnState(connState string) {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	s.connState = connState
+}
+
+// remoteWAL records an index of the WAL of the primary
+func (s *drSecondary) remoteWAL(index uint64, heartbeat bool) {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	if index > s.lastRemoteWAL {
+		s.lastRemoteWAL = index
+	}
+	if heartbeat {
+		s.lastHeartbeat = time.Now()
+	}
+}
+
+// stop stops the stream from the primary and waits for it to return
+func (s *drSecondary) stop() {
+	s.cancel()
+	<-s.doneCh
+}
+
+// setDRReplicationState sets the DR mode of the replication state
+func (c *Core) setDRReplicationState(mode consts.ReplicationState) {
+	state := c.ReplicationState()
+	state.ClearState(consts.ReplicationDRPrimary | consts.ReplicationDRSecondary |
+		consts.ReplicationDRBootstrapping | consts.ReplicationDRDisabled)
+	state.AddState(mode)
+	atomic.StoreUint32(c.replicationState, uint32(state))
+}
+
+// setupDRReplication sets up the DR replication of the active node from the
+// persisted state. It returns true if the cluster is a DR secondary.
+func (c *Core) setupDRReplication(ctx context.Context) (bool, error) {
+	primaryState, err := c.loadDRPrimaryState(ctx)
+	if err != nil {
+		return false, err
+	}
+	if primaryState != nil {
+		return false, c.startDRPrimary(primaryState)
+	}
+
+	secondaryState, err := c.loadDRSecondaryState(ctx)
+	if err != nil {
+		return false, err
+	}
+	if secondaryState != nil {
+		c.startDRSecondary(secondaryState)
+		return true, nil
+	}
+
+	c.setDRReplicationState(consts.ReplicationDRDisabled)
+	return false, nil
+}
+
+// teardownDRReplication stops the DR replication of the active node
+func (c *Core) teardownDRReplication() {
+	c.stopDRPrimary()
+	c.stopDRSecondary()
+}
+
+func (c *Core) loadDRPrimaryState(ctx context.Context) (*drPrimaryState, error) {
+	entry, err := c.barrier.Get(ctx, drPrimaryStatePath)
+	if err != nil {
+		return nil, errwrap.Wrapf("failed to read the DR primary state: {{err}}", err)
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var state drPrimaryState
+	if err := jsonutil.DecodeJSON(entry.Value, &state); err != nil {
+		return nil, errwrap.Wrapf("failed to decode the DR primary state: {{err}}", err)
+	}
+	return &state, nil
+}
+
+func (c *Core) loadDRSecondaryState(ctx context.Context) (*drSecondaryState, error) {
+	entry, err := c.barrier.Get(ctx, drSecondaryStatePath)
+	if err != nil {
+		return nil, errwrap.Wrapf("failed to read the DR secondary state: {{err}}", err)
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var state drSecondaryState
+	if err := jsonutil.DecodeJSON(entry.Value, &state); err != nil {
+		return nil, errwrap.Wrapf("failed to decode the DR secondary state: {{err}}", err)
+	}
+	return &state, nil
+}
+
+func (c *Core) persistDRState(ctx context.Context, path string, state interface{}) error {
+	value, err := jsonutil.EncodeJSON(state)
+	if err != nil {
+		return err
+	}
+	return c.barrier.Put(ctx, &Entry{
+		Key:   path,
+		Value: value,
+	})
+}
+
+// newDRPrimaryState creates the state of a new DR primary, with the CA of
+// the replication set
+func newDRPrimaryState(clusterID, primaryClusterAddr string) (*drPrimaryState, error) {
+	if clusterID == "" {
+		id, err := uuid.GenerateUUID()
+		if err != nil {
+			return nil, err
+		}
+		clusterID = id
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	host := fmt.Sprintf("dr-%s", clusterID)
+	template := &x509.Certificate{
+		Subject: pkix.Name{
+			CommonName: host,
+		},
+		DNSNames: []string{host},
+		ExtKeyUsage: []x509.ExtKeyUsage{
+			x509.ExtKeyUsageServerAuth,
+			x509.ExtKeyUsageClientAuth,
+		},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyAgreement | x509.KeyUsageCertSign,
+		SerialNumber:          serial,
+		NotBefore:             time.Now().Add(-30 * time.Second),
+		NotAfter:              time.Now().Add(262980 * time.Hour),
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	certBytes, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, errwrap.Wrapf("failed to generate the DR CA certificate: {{err}}", err)
+	}
+	keyBytes, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &drPrimaryState{
+		ClusterID:          clusterID,
+		PrimaryClusterAddr: primaryClusterAddr,
+		CACert:             certBytes,
+		CAKey:              keyBytes,
+	}, nil
+}
+
+// startDRPrimary starts recording the changes of the barrier in a new WAL,
+// to stream them to the secondaries
+func (c *Core) startDRPrimary(state *drPrimaryState) error {
+	caCert, err := x509.ParseCertificate(state.CACert)
+	if err != nil {
+		return errwrap.Wrapf("failed to parse the DR CA certificate: {{err}}", err)
+	}
+	caKey, err := x509.ParseECPrivateKey(state.CAKey)
+	if err != nil {
+		return errwrap.Wrapf("failed to parse the DR CA key: {{err}}", err)
+	}
+	wal, err := newDRWAL()
+	if err != nil {
+		return err
+	}
+
+	c.drReplicationLock.Lock()
+	defer c.drReplicationLock.Unlock()
+
+	c.drPrimary = &drPrimary{
+		state:  state,
+		wal:    wal,
+		caCert: caCert,
+		caKey:  caKey,
+		stopCh: make(chan struct{}),
+		conns:  make(map[string]*drSecondaryConn),
+	}
+	c.drBarrier.setWAL(wal)
+	c.setDRReplicationState(consts.ReplicationDRPrimary)
+	return nil
+}
+
+func (c *Core) stopDRPrimary() {
+	c.drReplicationLock.Lock()
+	primary := c.drPrimary
+	c.drPrimary = nil
+	c.drReplicationLock.Unlock()
+
+	if primary != nil {
+		c.drBarrier.setWAL((*drWAL)(nil))
+		primary.stop()
+	}
+}
+
+// startDRSecondary starts streaming the changes from the primary, if the
+// secondary has one
+func (c *Core) startDRSecondary(state *drSecondaryState) {
+	ctx, cancel := context.WithCancel(context.Background())
+	secondary := &drSecondary{
+		cancel:    cancel,
+		doneCh:    make(chan struct{}),
+		state:     state,
+		connState: "idle",
+	}
+
+	c.drReplicationLock.Lock()
+	c.drSecondary = secondary
+	c.drReplicationLock.Unlock()
+
+	c.setDRReplicationState(consts.ReplicationDRSecondary)
+	go c.runDRSecondary(ctx, secondary)
+}
+
+// stopDRSecondary stops the stream from the primary. The cluster remains
+// a DR secondary.
+func (c *Core) stopDRSecondary() *drSecondary {
+	c.drReplicationLock.Lock()
+	secondary := c.drSecondary
+	c.drSecondary = nil
+	c.drReplicationLock.Unlock()
+
+	if secondary != nil {
+		secondary.stop()
+	}
+	return secondary
+}
+
+// becomeDRSecondary tears down the active state of the node, which only
+// applies the changes streamed by its DR primary from now on. It's run in the
+// background by the requests making the cluster a DR secondary, as they hold
+// the state lock.
+func (c *Core) becomeDRSecondary(state *drSecondaryState) {
+	c.stateLock.Lock()
+	defer c.stateLock.Unlock()
+
+	// Another node picks up the persisted state when it becomes active
+	if c.sealed || c.standby {
+		return
+	}
+
+	c.logger.Info("core: becoming a DR secondary, tearing down the active state")
+	if err := c.preSeal(); err != nil {
+		c.logger.Error("core: failed to tear down the active state", "error", err)
+	}
+	c.activeContextCancelFunc()
+	c.activeContext, c.activeContextCancelFunc = context.WithCancel(context.Background())
+	if !c.cachingDisabled {
+		c.physicalCache.SetEnabled(true)
+	}
+
+	c.startDRSecondary(state)
+	if c.ha != nil {
+		if err := c.startClusterListener(c.activeContext); err != nil {
+			c.logger.Error("core: failed to start the cluster listener", "error", err)
+		}
+	}
+}
+
+// checkDROperationToken checks the DR operation token authorizing the
+// operations on a DR secondary
+func (c *Core) checkDROperationToken(ctx context.Context, token string) error {
+	if token == "" {
+		return logical.ErrPermissionDenied
+	}
+	entry, err := c.barrier.Get(ctx, coreDROperationTokenPath)
+	if err != nil {
+		return err
+	}
+	hash := sha256.Sum256([]byte(token))
+	if entry == nil || subtle.ConstantTimeCompare(entry.Value, hash[:]) != 1 {
+		return logical.ErrPermissionDenied
+	}
+	return nil
+}
+
+// PromoteDRSecondary promotes the DR secondary to a DR primary, setting up
+// the mounts and the rest of the active state from the replicated storage
+func (c *Core) PromoteDRSecondary(ctx context.Context, operationToken, primaryClusterAddr string) error {
+	c.stateLock.Lock()
+	defer c.stateLock.Unlock()
+	if c.sealed {
+		return consts.ErrSealed
+	}
+	if c.standby {
+		return consts.ErrStandby
+	}
+	if !c.IsDRSecondary() {
+		return logical.CodedError(400, "cluster is not a DR secondary")
+	}
+	if err := c.checkDROperationToken(ctx, operationToken); err != nil {
+		return err
+	}
+
+	var clusterID string
+	if secondary := c.stopDRSecondary(); secondary != nil {
+		clusterID = secondary.currentState().ClusterID
+	}
+	if primaryClusterAddr == "" {
+		primaryClusterAddr = c.clusterAddr
+	}
+	state, err := newDRPrimaryState(clusterID, primaryClusterAddr)
+	if err != nil {
+		return err
+	}
+	if err := c.persistDRState(ctx, drPrimaryStatePath, state); err != nil {
+		return err
+	}
+	if err := c.barrier.Delete(ctx, drSecondaryStatePath); err != nil {
+		return err
+	}
+	if err := c.barrier.Delete(ctx, coreDROperationTokenPath); err != nil {
+		return err
+	}
+
+	c.logger.Info("core: promoting the DR secondary")
+	c.stopClusterListener()
+	c.activeContextCancelFunc()
+	if err := c.postUnseal(); err != nil {
+		c.logger.Error("core: post-unseal setup of the promoted DR secondary failed", "error", err)
+		return err
+	}
+	return nil
+}
+
+// UpdateDRSecondaryPrimary makes the DR secondary connect to the primary of
+// the activation token. An empty token clears the primary, so that the
+// secondary stops connecting to it.
+func (c *Core) UpdateDRSecondaryPrimary(ctx context.Context, operationToken, token, primaryAPIAddr, caFile, caPath string) error {
+	c.stateLock.RLock()
+	defer c.stateLock.RUnlock()
+	if c.sealed {
+		return consts.ErrSealed
+	}
+	if c.standby {
+		return consts.ErrStandby
+	}
+	if !c.IsDRSecondary() {
+		return logical.CodedError(400, "cluster is not a DR secondary")
+	}
+	if err := c.checkDROperationToken(ctx, operationToken); err != nil {
+		return err
+	}
+
+	var activation *drActivation
+	if token != "" {
+		var err error
+		if activation, err = fetchDRActivation(token, primaryAPIAddr, caFile, caPath); err != nil {
+			return logical.CodedError(400, err.Error())
+		}
+	}
+
+	secondary := c.stopDRSecondary()
+	if secondary == nil {
+		return logical.CodedError(400, "DR secondary is not running")
+	}
+	state := secondary.currentState()
+	if activation != nil {
+		state.applyActivation(activation)
+	} else {
+		state.ID, state.PrimaryClusterAddr = "", ""
+		state.CACert, state.ClientCert, state.ClientKey = "", "", ""
+	}
+	if err := c.persistDRState(ctx, drSecondaryStatePath, state); err != nil {
+		c.startDRSecondary(secondary.currentState())
+		return err
+	}
+	c.startDRSecondary(state)
+	return nil
+}
+
+// DeleteDROperationToken deletes the DR operation token of the secondary
+func (c *Core) DeleteDROperationToken(ctx context.Context, operationToken string) error {
+	c.stateLock.RLock()
+	defer c.stateLock.RUnlock()
+	if c.sealed {
+		return consts.ErrSealed
+	}
+	if c.standby {
+		return consts.ErrStandby
+	}
+	if err := c.checkDROperationToken(ctx, operationToken); err != nil {
+		return err
+	}
+	return c.barrier.Delete(ctx, coreDROperationTokenPath)
+}
+
+// applyActivation sets the primary of the activation as the primary of the
+// secondary. The changes of a primary of another replication set are not
+// applicable, its storage is streamed from scratch.
+func (s *drSecondaryState) applyActivation(activation *drActivation) {
+	if s.ClusterID != activation.ClusterID {
+		s.Epoch, s.Index = "", 0
+	}
+	s.ClusterID = activation.ClusterID
+	s.ID = activation.ID
+	s.PrimaryClusterAddr = activation.PrimaryClusterAddr
+	s.CACert = activation.CACert
+	s.ClientCert = activation.ClientCert
+	s.ClientKey = activation.ClientKey
+}
+
+// fetchDRActivation unwraps the activation token of a secondary with the API
+// of the primary. The address of the API defaults to the one of the token.
+func fetchDRActivation(token, primaryAPIAddr, caFile, caPath string) (*drActivation, error) {
+	if primaryAPIAddr == "" {
+		wt, err := jws.ParseJWT([]byte(token))
+		if err != nil {
+			return nil, errwrap.Wrapf("failed to parse the activation token: {{err}}", err)
+		}
+		primaryAPIAddr, _ = wt.Claims().Get("addr").(string)
+		if primaryAPIAddr == "" {
+			return nil, fmt.Errorf("the activation token has no API address of the primary, the primary_api_addr must be set")
+		}
+	}
+
+	config := api.DefaultConfig()
+	config.Address = primaryAPIAddr
+	if caFile != "" || caPath != "" {
+		if err := config.ConfigureTLS(&api.TLSConfig{
+			CACert: caFile,
+			CAPath: caPath,
+		}); err != nil {
+			return nil, err
+		}
+	}
+	client, err := api.NewClient(config)
+	if err != nil {
+		return nil, err
+	}
+	client.ClearToken()
+
+	secret, err := client.Logical().Unwrap(token)
+	if err != nil {
+		return nil, errwrap.Wrapf("failed to unwrap the activation token: {{err}}", err)
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, fmt.Errorf("no activation data found in the token")
+	}
+
+	var activation drActivation
+	if err := mapstructure.Decode(secret.Data, &activation); err != nil {
+		return nil, err
+	}
+	if activation.ID == "" || activation.PrimaryClusterAddr == "" || activation.CACert == "" ||
+		activation.ClientCert == "" || activation.ClientKey == "" {
+		return nil, fmt.Errorf("the activation token is not of a DR primary")
+	}
+	return &activation, nil
+}
+
+// DRReplicationStatus returns the status of the DR replication of the active
+// node
+func (c *Core) DRReplicationStatus() map[string]interface{} {
+	c.drReplicationLock.RLock()
+	primary, secondary := c.drPrimary, c.drSecondary
+	c.drReplicationLock.RUnlock()
+
+	status := map[string]interface{}{
+		"mode": c.ReplicationState().GetDRString(),
+	}
+
+	switch {
+	case primary != nil:
+		lastWAL := primary.wal.lastIndex()
+		status["cluster_id"] = primary.state.ClusterID
+		status["primary_cluster_addr"] = primary.state.PrimaryClusterAddr
+		status["last_wal"] = lastWAL
+
+		known, err := c.barrier.List(context.Background(), drSecondariesPrefix)
+		if err != nil {
+			c.logger.Error("core: failed to list the DR secondaries", "error", err)
+		}
+		if known == nil {
+			known = []string{}
+		}
+		sort.Strings(known)
+		status["known_secondaries"] = known
+
+		primary.l.Lock()
+		connected := make(map[string]interface{}, len(primary.conns))
+		for id, sc := range primary.conns {
+			var lag uint64
+			if lastWAL > sc.lastAck {
+				lag = lastWAL - sc.lastAck
+			}
+			info := map[string]interface{}{
+				"connected_since": sc.connectedAt.Format(time.RFC3339Nano),
+				"last_wal":        sc.lastAck,
+				"lag":             lag,
+				"last_heartbeat":  "",
+			}
+			if !sc.lastHeartbeat.IsZero() {
+				info["last_heartbeat"] = sc.lastHeartbeat.Format(time.RFC3339Nano)
+			}
+			connected[id] = info
+		}
+		primary.l.Unlock()
+		status["connected_secondaries"] = connected
+
+	case secondary != nil:
+		secondary.l.RLock()
+		state := secondary.state
+		var lag uint64
+		if secondary.lastRemoteWAL > state.Index {
+			lag = secondary.lastRemoteWAL - state.Index
+		}
+		status["cluster_id"] = state.ClusterID
+		status["secondary_id"] = state.ID
+		status["primary_cluster_addr"] = state.PrimaryClusterAddr
+		status["state"] = secondary.connState
+		status["last_wal"] = state.Index
+		status["last_remote_wal"] = secondary.lastRemoteWAL
+		status["lag"] = lag
+		status["last_heartbeat"] = ""
+		if !secondary.lastHeartbeat.IsZero() {
+			status["last_heartbeat"] = secondary.lastHeartbeat.Format(time.RFC3339Nano)
+		}
+		secondary.l.RUnlock()
+	}
+
+	return status
+}
+
+// drPrimaryTLSConfig returns the TLS configuration of the cluster port for
+// the DR replication streams, with which the secondaries are authenticated
+func (c *Core) drPrimaryTLSConfig() (*tls.Config, error) {
+	c.drReplicationLock.RLock()
+	primary := c.drPrimary
+	c.drReplicationLock.RUnlock()
+
+	if primary == nil {
+		return nil, fmt.Errorf("got DR replication connection but not a DR primary")
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(primary.caCert)
+	return &tls.Config{
+		Certificates: []tls.Certificate{
+			{
+				Certificate: [][]byte{primary.caCert.Raw},
+				PrivateKey:  primary.caKey,
+				Leaf:        primary.caCert,
+			},
+		},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool,
+		MinVersion:   tls.VersionTLS12,
+		NextProtos:   []string{drReplicationALPN},
+		CipherSuites: c.clusterCipherSuites,
+	}, nil
+}
+
+func drReplicationPaths(b *SystemBackend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "replication/dr/status$",
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation: b.handleDRReplicationStatus,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["replication-dr-status"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["replication-dr-status"][1]),
+		},
+		{
+			Pattern: "replication/dr/primary/enable$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"primary_cluster_addr": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: strings.TrimSpace(sysHelp["replication-dr-primary-cluster-addr"][0]),
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: b.handleDRPrimaryEnable,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["replication-dr-primary-enable"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["replication-dr-primary-enable"][1]),
+		},
+		{
+			Pattern: "replication/dr/primary/demote$",
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: b.handleDRPrimaryDemote,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["replication-dr-primary-demote"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["replication-dr-primary-demote"][1]),
+		},
+		{
+			Pattern: "replication/dr/primary/disable$",
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: b.handleDRPrimaryDisable,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["replication-dr-primary-disable"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["replication-dr-primary-disable"][1]),
+		},
+		{
+			Pattern: "replication/dr/primary/secondary-token$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"id": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: strings.TrimSpace(sysHelp["replication-dr-secondary-id"][0]),
+				},
+				"ttl": &framework.FieldSchema{
+					Type:        framework.TypeDurationSecond,
+					Default:     int(drDefaultActivationTTL.Seconds()),
+					Description: "TTL of the activation token.",
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: b.handleDRPrimarySecondaryToken,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["replication-dr-primary-secondary-token"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["replication-dr-primary-secondary-token"][1]),
+		},
+		{
+			Pattern: "replication/dr/primary/revoke-secondary$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"id": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: strings.TrimSpace(sysHelp["replication-dr-secondary-id"][0]),
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: b.handleDRPrimaryRevokeSecondary,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["replication-dr-primary-revoke-secondary"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["replication-dr-primary-revoke-secondary"][1]),
+		},
+		{
+			Pattern: "replication/dr/secondary/enable$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"token": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "Activation token of the secondary, generated by the primary.",
+				},
+				"primary_api_addr": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "API address of the primary, overriding the address of the token.",
+				},
+				"ca_file": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "Path to a PEM-encoded CA file to verify the API of the primary with.",
+				},
+				"ca_path": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "Path to a directory of PEM-encoded CA files to verify the API of the primary with.",
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: b.handleDRSecondaryEnable,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["replication-dr-secondary-enable"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["replication-dr-secondary-enable"][1]),
+		},
+	}
+}
+
+// handleReplicationStatus returns the status of the replication of the
+// cluster; only DR replication is available
+func (b *SystemBackend) handleReplicationStatus(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"dr": b.Core.DRReplicationStatus(),
+			"performance": map[string]interface{}{
+				"mode": "disabled",
+			},
+		},
+	}, nil
+}
+
+func (b *SystemBackend) handleDRReplicationStatus(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	return &logical.Response{
+		Data: b.Core.DRReplicationStatus(),
+	}, nil
+}
+
+// handleDRPrimaryEnable makes the cluster a DR primary
+func (b *SystemBackend) handleDRPrimaryEnable(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	if !b.Core.ReplicationState().HasState(consts.ReplicationDRDisabled) {
+		return logical.ErrorResponse("DR replication is already enabled, secondaries must be promoted"), logical.ErrInvalidRequest
+	}
+	if b.Core.ha == nil || b.Core.clusterAddr == "" {
+		return logical.ErrorResponse("DR replication is streamed over the cluster port, which requires an HA storage backend and a cluster address"), logical.ErrInvalidRequest
+	}
+
+	primaryClusterAddr := data.Get("primary_cluster_addr").(string)
+	if primaryClusterAddr == "" {
+		primaryClusterAddr = b.Core.clusterAddr
+	}
+	state, err := newDRPrimaryState("", primaryClusterAddr)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.Core.persistDRState(ctx, drPrimaryStatePath, state); err != nil {
+		return nil, err
+	}
+	if err := b.Core.startDRPrimary(state); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// handleDRPrimaryDemote makes the primary a DR secondary without a primary,
+// keeping its storage
+func (b *SystemBackend) handleDRPrimaryDemote(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	b.Core.drReplicationLock.RLock()
+	primary := b.Core.drPrimary
+	b.Core.drReplicationLock.RUnlock()
+	if primary == nil {
+		return logical.ErrorResponse("cluster is not a DR primary"), logical.ErrInvalidRequest
+	}
+
+	state := &drSecondaryState{
+		ClusterID: primary.state.ClusterID,
+	}
+	if err := b.Core.persistDRState(ctx, drSecondaryStatePath, state); err != nil {
+		return nil, err
+	}
+	if err := b.Core.clearDRPrimaryState(ctx); err != nil {
+		return nil, err
+	}
+
+	go b.Core.becomeDRSecondary(state)
+	return nil, nil
+}
+
+// handleDRPrimaryDisable disables the DR replication of the primary, the
+// secondaries can't connect anymore
+func (b *SystemBackend) handleDRPrimaryDisable(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	if !b.Core.ReplicationState().HasState(consts.ReplicationDRPrimary) {
+		return logical.ErrorResponse("cluster is not a DR primary"), logical.ErrInvalidRequest
+	}
+
+	if err := b.Core.clearDRPrimaryState(ctx); err != nil {
+		return nil, err
+	}
+	b.Core.stopDRPrimary()
+	b.Core.setDRReplicationState(consts.ReplicationDRDisabled)
+	return nil, nil
+}
+
+// clearDRPrimaryState deletes the state and the known secondaries of the
+// primary
+func (c *Core) clearDRPrimaryState(ctx context.Context) error {
+	ids, err := c.barrier.List(ctx, drSecondariesPrefix)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := c.barrier.Delete(ctx, drSecondariesPrefix+id); err != nil {
+			return err
+		}
+	}
+	return c.barrier.Delete(ctx, drPrimaryStatePath)
+}
+
+// handleDRPrimarySecondaryToken issues the credentials of a new secondary,
+// returned wrapped in its activation token
+func (b *SystemBackend) handleDRPrimarySecondaryToken(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	b.Core.drReplicationLock.RLock()
+	primary := b.Core.drPrimary
+	b.Core.drReplicationLock.RUnlock()
+	if primary == nil {
+		return logical.ErrorResponse("cluster is not a DR primary"), logical.ErrInvalidRequest
+	}
+
+	id := data.Get("id").(string)
+	if id == "" {
+		return logical.ErrorResponse("missing id"), logical.ErrInvalidRequest
+	}
+	if strings.Contains(id, "/") {
+		return logical.ErrorResponse("id must not contain slashes"), logical.ErrInvalidRequest
+	}
+	ttl := time.Duration(data.Get("ttl").(int)) * time.Second
+	if ttl <= 0 {
+		return logical.ErrorResponse("ttl must be positive"), logical.ErrInvalidRequest
+	}
+
+	existing, err := b.Core.barrier.Get(ctx, drSecondariesPrefix+id)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return logical.ErrorResponse(fmt.Sprintf("a secondary with the id %q already exists, it must be revoked first", id)), logical.ErrInvalidRequest
+	}
+
+	certPEM, keyPEM, serial, err := primary.issueClientCert(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.Core.persistDRState(ctx, drSecondariesPrefix+id, &drSecondaryEntry{
+		ID:           id,
+		SerialNumber: serial.Text(16),
+	}); err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"id":                   id,
+			"cluster_id":           primary.state.ClusterID,
+			"primary_cluster_addr": primary.state.PrimaryClusterAddr,
+			"ca_cert":              string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: primary.state.CACert})),
+			"client_cert":          string(certPEM),
+			"client_key":           string(keyPEM),
+		},
+		WrapInfo: &wrapping.ResponseWrapInfo{
+			TTL:    ttl,
+			Format: "jwt",
+		},
+	}, nil
+}
+
+// handleDRPrimaryRevokeSecondary forgets the secondary and closes its stream
+func (b *SystemBackend) handleDRPrimaryRevokeSecondary(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	b.Core.drReplicationLock.RLock()
+	primary := b.Core.drPrimary
+	b.Core.drReplicationLock.RUnlock()
+	if primary == nil {
+		return logical.ErrorResponse("cluster is not a DR primary"), logical.ErrInvalidRequest
+	}
+
+	id := data.Get("id").(string)
+	if id == "" {
+		return logical.ErrorResponse("missing id"), logical.ErrInvalidRequest
+	}
+	if err := b.Core.barrier.Delete(ctx, drSecondariesPrefix+id); err != nil {
+		return nil, err
+	}
+	primary.disconnect(id)
+	return nil, nil
+}
+
+// handleDRSecondaryEnable makes the cluster a DR secondary of the primary of
+// the activation token. Its storage is replaced by the one of the primary.
+func (b *SystemBackend) handleDRSecondaryEnable(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	if !b.Core.ReplicationState().HasState(consts.ReplicationDRDisabled) {
+		return logical.ErrorResponse("DR replication is already enabled, a primary must be demoted first"), logical.ErrInvalidRequest
+	}
+
+	token := data.Get("token").(string)
+	if token == "" {
+		return logical.ErrorResponse("missing token"), logical.ErrInvalidRequest
+	}
+	activation, err := fetchDRActivation(token, data.Get("primary_api_addr").(string), data.Get("ca_file").(string), data.Get("ca_path").(string))
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	state := &drSecondaryState{}
+	state.applyActivation(activation)
+	if err := b.Core.persistDRState(ctx, drSecondaryStatePath, state); err != nil {
+		return nil, err
+	}
+
+	go b.Core.becomeDRSecondary(state)
+	return nil, nil
+}
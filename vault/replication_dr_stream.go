@@ -0,0 +1,472 @@
+package vault
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/logical"
+)
+
+const (
+	drMessageHello         = "hello"
+	drMessageStream        = "stream"
+	drMessageSnapshotStart = "snapshot-start"
+	drMessageSnapshotEnd   = "snapshot-end"
+	drMessagePut           = "put"
+	drMessageDelete        = "delete"
+	drMessageHeartbeat     = "heartbeat"
+	drMessageAck           = "ack"
+	drMessageError         = "error"
+)
+
+var (
+	// drStreamTimeout is how long the ends of a DR replication stream wait
+	// for each other before giving up on the stream. It's var so that tests
+	// can lower it.
+	drStreamTimeout = 30 * time.Second
+
+	// errDRWALTruncated is returned when the changes a secondary is missing
+	// were dropped from the WAL
+	errDRWALTruncated = errors.New("DR WAL truncated")
+)
+
+// drMessage is a message of a DR replication stream. The streams are made of
+// JSON messages: the secondary says hello with the last change it applied,
+// and the primary streams the changes of its WAL from there, or a snapshot of
+// its storage if they are not in the WAL anymore. The secondary acknowledges
+// the changes it applied.
+type drMessage struct {
+	Type        string `json:"type"`
+	SecondaryID string `json:"secondary_id,omitempty"`
+	ClusterID   string `json:"cluster_id,omitempty"`
+	Epoch       string `json:"epoch,omitempty"`
+	Index       uint64 `json:"index,omitempty"`
+	Key         string `json:"key,omitempty"`
+	Value       []byte `json:"value,omitempty"`
+	SealWrap    bool   `json:"seal_wrap,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// drStream reads and writes the messages of a DR replication stream
+type drStream struct {
+	conn net.Conn
+	enc  *json.Encoder
+	dec  *json.Decoder
+}
+
+func newDRStream(conn net.Conn) *drStream {
+	return &drStream{
+		conn: conn,
+		enc:  json.NewEncoder(conn),
+		dec:  json.NewDecoder(conn),
+	}
+}
+
+func (s *drStream) send(msg *drMessage) error {
+	s.conn.SetWriteDeadline(time.Now().Add(drStreamTimeout))
+	return s.enc.Encode(msg)
+}
+
+// receive reads the next message. A zero timeout waits indefinitely.
+func (s *drStream) receive(timeout time.Duration) (*drMessage, error) {
+	if timeout > 0 {
+		s.conn.SetReadDeadline(time.Now().Add(timeout))
+	} else {
+		s.conn.SetReadDeadline(time.Time{})
+	}
+	var msg drMessage
+	if err := s.dec.Decode(&msg); err != nil {
+		return nil, err
+	}
+	if msg.Type == drMessageError {
+		return nil, fmt.Errorf("error from the other end of the stream: %s", msg.Error)
+	}
+	return &msg, nil
+}
+
+// serveDRSecondary streams the changes of the WAL of the primary to the
+// secondary of the connection, until the connection is closed
+func (c *Core) serveDRSecondary(conn *tls.Conn) {
+	c.drReplicationLock.RLock()
+	primary := c.drPrimary
+	c.drReplicationLock.RUnlock()
+	if primary == nil {
+		return
+	}
+
+	stream := newDRStream(conn)
+	hello, err := stream.receive(drStreamTimeout)
+	if err != nil {
+		c.logger.Debug("core: failed to read the hello of the DR secondary", "error", err)
+		return
+	}
+	if err := c.authenticateDRSecondary(primary, conn, hello); err != nil {
+		c.logger.Warn("core: rejected DR secondary", "secondary_id", hello.SecondaryID, "error", err)
+		stream.send(&drMessage{
+			Type:  drMessageError,
+			Error: err.Error(),
+		})
+		return
+	}
+
+	id := hello.SecondaryID
+	sc := primary.register(id, conn)
+	defer primary.unregister(id, sc)
+	c.logger.Info("core: DR secondary connected", "secondary_id", id)
+
+	// The secondary only sends acks from now on
+	ackCh := make(chan struct{}, 1)
+	doneCh := make(chan struct{})
+	go func() {
+		defer close(doneCh)
+		for {
+			msg, err := stream.receive(0)
+			if err != nil {
+				return
+			}
+			if msg.Type == drMessageAck {
+				primary.ack(sc, msg.Index)
+				select {
+				case ackCh <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+
+	wal := primary.wal
+	next, resume := hello.Index, hello.Epoch == wal.epoch
+	for {
+		if resume {
+			err = stream.send(&drMessage{
+				Type:  drMessageStream,
+				Epoch: wal.epoch,
+				Index: next,
+			})
+		} else {
+			next, err = c.sendDRSnapshot(stream, wal)
+		}
+		if err == nil {
+			err = c.streamDRWAL(stream, primary, next, ackCh, doneCh)
+		}
+		if err != errDRWALTruncated {
+			break
+		}
+		resume = false
+	}
+	if err != nil {
+		c.logger.Debug("core: DR replication stream closed", "secondary_id", id, "error", err)
+	}
+	conn.Close()
+	<-doneCh
+}
+
+// authenticateDRSecondary checks that the client certificate of the
+// connection is the one issued to the secondary of the hello
+func (c *Core) authenticateDRSecondary(primary *drPrimary, conn *tls.Conn, hello *drMessage) error {
+	if hello.Type != drMessageHello {
+		return fmt.Errorf("expected %q, got %q", drMessageHello, hello.Type)
+	}
+	if hello.ClusterID != primary.state.ClusterID {
+		return fmt.Errorf("secondary is not of the cluster %q", primary.state.ClusterID)
+	}
+
+	certs := conn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return fmt.Errorf("no client certificate")
+	}
+	entry, err := c.barrier.Get(context.Background(), drSecondariesPrefix+hello.SecondaryID)
+	if err != nil {
+		return err
+	}
+	if entry == nil {
+		return fmt.Errorf("unknown secondary %q", hello.SecondaryID)
+	}
+	var secondary drSecondaryEntry
+	if err := json.Unmarshal(entry.Value, &secondary); err != nil {
+		return err
+	}
+	if certs[0].SerialNumber.Text(16) != secondary.SerialNumber || certs[0].Subject.CommonName != secondary.ID {
+		return fmt.Errorf("client certificate was not issued to the secondary %q", hello.SecondaryID)
+	}
+	return nil
+}
+
+// sendDRSnapshot sends all the replicated entries of the storage, returning
+// the index of the WAL the secondary is up to date with once it applied them.
+// The entries are read after the index, so that the changes of the WAL after
+// it make the secondary converge to the storage of the primary.
+func (c *Core) sendDRSnapshot(stream *drStream, wal *drWAL) (uint64, error) {
+	ctx := context.Background()
+	index := wal.lastIndex()
+	if err := stream.send(&drMessage{
+		Type:  drMessageSnapshotStart,
+		Epoch: wal.epoch,
+		Index: index,
+	}); err != nil {
+		return 0, err
+	}
+
+	keys, err := logical.CollectKeys(ctx, NewBarrierView(c.barrier, ""))
+	if err != nil {
+		return 0, errwrap.Wrapf("failed to list the storage: {{err}}", err)
+	}
+	for _, key := range keys {
+		if !drReplicatedKey(key) {
+			continue
+		}
+		entry, err := c.barrier.Get(ctx, key)
+		if err != nil {
+			return 0, err
+		}
+		if entry == nil {
+			continue
+		}
+		if err := stream.send(&drMessage{
+			Type:     drMessagePut,
+			Key:      entry.Key,
+			Value:    entry.Value,
+			SealWrap: entry.SealWrap,
+		}); err != nil {
+			return 0, err
+		}
+	}
+
+	return index, stream.send(&drMessage{
+		Type:  drMessageSnapshotEnd,
+		Epoch: wal.epoch,
+		Index: index,
+	})
+}
+
+// streamDRWAL sends the changes of the WAL after the index as they are
+// recorded, along with heartbeats. The stream is given up on if the secondary
+// stops acknowledging the heartbeats.
+func (c *Core) streamDRWAL(stream *drStream, primary *drPrimary, next uint64, ackCh, doneCh <-chan struct{}) error {
+	ticker := time.NewTicker(HeartbeatInterval)
+	defer ticker.Stop()
+	lastAck := time.Now()
+
+	for {
+		entries, notifyCh, ok := primary.wal.since(next)
+		if !ok {
+			return errDRWALTruncated
+		}
+		for _, entry := range entries {
+			msg := &drMessage{
+				Type:     drMessagePut,
+				Index:    entry.Index,
+				Key:      entry.Key,
+				Value:    entry.Value,
+				SealWrap: entry.SealWrap,
+			}
+			if entry.Delete {
+				msg.Type = drMessageDelete
+			}
+			if err := stream.send(msg); err != nil {
+				return err
+			}
+			next = entry.Index
+		}
+		if len(entries) > 0 {
+			continue
+		}
+
+		select {
+		case <-notifyCh:
+		case <-ackCh:
+			lastAck = time.Now()
+		case <-ticker.C:
+			if time.Since(lastAck) > drStreamTimeout {
+				return fmt.Errorf("no acknowledgement from the secondary in %s", drStreamTimeout)
+			}
+			if err := stream.send(&drMessage{
+				Type:  drMessageHeartbeat,
+				Index: next,
+			}); err != nil {
+				return err
+			}
+		case <-doneCh:
+			return nil
+		case <-primary.stopCh:
+			return nil
+		}
+	}
+}
+
+// runDRSecondary streams the changes from the primary of the secondary until
+// the context is canceled, connecting again whenever the stream fails
+func (c *Core) runDRSecondary(ctx context.Context, secondary *drSecondary) {
+	defer close(secondary.doneCh)
+
+	for {
+		state := secondary.currentState()
+		if state.PrimaryClusterAddr == "" || state.ClientCert == "" {
+			<-ctx.Done()
+			return
+		}
+
+		err := c.streamDRPrimary(ctx, secondary, state)
+		secondary.setConnState("idle")
+		if ctx.Err() != nil {
+			return
+		}
+		c.logger.Warn("core: DR replication stream from the primary failed, retrying", "primary_cluster_addr", state.PrimaryClusterAddr, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(drSecondaryRetryInterval):
+		}
+	}
+}
+
+// streamDRPrimary connects to the primary and applies the changes it streams
+// to the storage
+func (c *Core) streamDRPrimary(ctx context.Context, secondary *drSecondary, state *drSecondaryState) error {
+	tlsConfig, err := state.tlsConfig()
+	if err != nil {
+		return err
+	}
+	u, err := url.Parse(state.PrimaryClusterAddr)
+	if err != nil {
+		return errwrap.Wrapf("failed to parse the cluster address of the primary: {{err}}", err)
+	}
+
+	secondary.setConnState("connecting")
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: drStreamTimeout}, "tcp", u.Host, tlsConfig)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// Unblock the reads once the secondary is stopped
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-stopCh:
+		}
+	}()
+
+	stream := newDRStream(conn)
+	if err := stream.send(&drMessage{
+		Type:        drMessageHello,
+		SecondaryID: state.ID,
+		ClusterID:   state.ClusterID,
+		Epoch:       state.Epoch,
+		Index:       state.Index,
+	}); err != nil {
+		return err
+	}
+
+	// leftovers are the keys of the secondary not in the snapshot being
+	// applied, which are deleted at its end
+	var leftovers map[string]struct{}
+	for {
+		msg, err := stream.receive(drStreamTimeout)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		switch msg.Type {
+		case drMessageStream:
+			secondary.setConnState("stream-wals")
+			secondary.remoteWAL(msg.Index, false)
+
+		case drMessageSnapshotStart:
+			secondary.setConnState("bootstrapping")
+			// A partially applied snapshot can't be resumed
+			if err := c.applyDRIndex(ctx, secondary, "", 0); err != nil {
+				return err
+			}
+			keys, err := logical.CollectKeys(ctx, NewBarrierView(c.barrier, ""))
+			if err != nil {
+				return err
+			}
+			leftovers = make(map[string]struct{}, len(keys))
+			for _, key := range keys {
+				if drReplicatedKey(key) {
+					leftovers[key] = struct{}{}
+				}
+			}
+
+		case drMessagePut, drMessageDelete:
+			if !drReplicatedKey(msg.Key) {
+				continue
+			}
+			if msg.Type == drMessagePut {
+				err = c.barrier.Put(ctx, &Entry{
+					Key:      msg.Key,
+					Value:    msg.Value,
+					SealWrap: msg.SealWrap,
+				})
+			} else {
+				err = c.barrier.Delete(ctx, msg.Key)
+			}
+			if err != nil {
+				return errwrap.Wrapf(fmt.Sprintf("failed to apply the change of %q: {{err}}", msg.Key), err)
+			}
+
+			// Changes of a snapshot are not of the WAL
+			if leftovers != nil {
+				delete(leftovers, msg.Key)
+				continue
+			}
+			secondary.remoteWAL(msg.Index, false)
+			if err := c.applyDRIndex(ctx, secondary, state.Epoch, msg.Index); err != nil {
+				return err
+			}
+			if err := stream.send(&drMessage{Type: drMessageAck, Index: msg.Index}); err != nil {
+				return err
+			}
+
+		case drMessageSnapshotEnd:
+			for key := range leftovers {
+				if err := c.barrier.Delete(ctx, key); err != nil {
+					return err
+				}
+			}
+			leftovers = nil
+			if err := c.applyDRIndex(ctx, secondary, msg.Epoch, msg.Index); err != nil {
+				return err
+			}
+			state.Epoch = msg.Epoch
+			secondary.setConnState("stream-wals")
+			secondary.remoteWAL(msg.Index, false)
+			if err := stream.send(&drMessage{Type: drMessageAck, Index: msg.Index}); err != nil {
+				return err
+			}
+
+		case drMessageHeartbeat:
+			secondary.remoteWAL(msg.Index, true)
+			if err := stream.send(&drMessage{Type: drMessageAck, Index: secondary.currentState().Index}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// applyDRIndex persists the epoch and the index of the last change applied
+// from the WAL of the primary
+func (c *Core) applyDRIndex(ctx context.Context, secondary *drSecondary, epoch string, index uint64) error {
+	secondary.l.Lock()
+	secondary.state.Epoch = epoch
+	secondary.state.Index = index
+	state := *secondary.state
+	secondary.l.Unlock()
+
+	return c.persistDRState(ctx, drSecondaryStatePath, &state)
+}
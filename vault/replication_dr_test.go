@@ -0,0 +1,149 @@
+package vault
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestDRWAL_Since(t *testing.T) {
+	defer func(max int) { DRWALMaxEntries = max }(DRWALMaxEntries)
+	DRWALMaxEntries = 2
+
+	wal, err := newDRWAL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, notifyCh, ok := wal.since(0)
+	if !ok || len(entries) != 0 {
+		t.Fatalf("bad: %#v", entries)
+	}
+
+	wal.append(&drWALEntry{Key: "foo"})
+	select {
+	case <-notifyCh:
+	default:
+		t.Fatal("expected the append to be notified")
+	}
+	wal.append(&drWALEntry{Key: "bar"})
+	wal.append(&drWALEntry{Key: "baz"})
+
+	entries, _, ok = wal.since(1)
+	if !ok || len(entries) != 2 || entries[0].Index != 2 || entries[1].Key != "baz" {
+		t.Fatalf("bad: %#v", entries)
+	}
+	if _, _, ok := wal.since(4); ok {
+		t.Fatal("expected an index beyond the WAL to be rejected")
+	}
+
+	// The fourth entry drops the oldest ones
+	wal.append(&drWALEntry{Key: "qux"})
+	if wal.lastIndex() != 4 {
+		t.Fatalf("bad: %d", wal.lastIndex())
+	}
+	if _, _, ok := wal.since(1); ok {
+		t.Fatal("expected the truncated entries to be missing")
+	}
+	entries, _, ok = wal.since(2)
+	if !ok || len(entries) != 2 || entries[0].Key != "baz" || entries[1].Index != 4 {
+		t.Fatalf("bad: %#v", entries)
+	}
+}
+
+func TestDRReplicatedKey(t *testing.T) {
+	for key, expected := range map[string]bool{
+		"core/mounts":                   true,
+		"logical/abc/foo":               true,
+		"sys/token/id/abc":              true,
+		keyringPath:                     false,
+		coreLocalClusterInfoPath:        false,
+		coreDROperationTokenPath:        false,
+		drSecondaryStatePath:            false,
+		coreLeaderPrefix + "abc":        false,
+		keyringUpgradePrefix + "2":      false,
+		knownPrimaryAddrsPrefix + "abc": false,
+	} {
+		if actual := drReplicatedKey(key); actual != expected {
+			t.Errorf("%s: expected %t, got %t", key, expected, actual)
+		}
+	}
+}
+
+func TestDRWALBarrier(t *testing.T) {
+	c, _, _ := TestCoreUnsealed(t)
+	ctx := context.Background()
+
+	// Nothing is recorded until the WAL is set
+	if err := c.barrier.Put(ctx, &Entry{Key: "foo", Value: []byte("1")}); err != nil {
+		t.Fatal(err)
+	}
+	wal, err := newDRWAL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.drBarrier.setWAL(wal)
+
+	value := []byte("2")
+	if err := c.barrier.Put(ctx, &Entry{Key: "foo", Value: value, SealWrap: true}); err != nil {
+		t.Fatal(err)
+	}
+	value[0] = '3'
+	if err := c.barrier.Put(ctx, &Entry{Key: drSecondaryStatePath, Value: value}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.barrier.Delete(ctx, "foo"); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, _, _ := wal.since(0)
+	if len(entries) != 2 {
+		t.Fatalf("bad: %#v", entries)
+	}
+	if entries[0].Key != "foo" || string(entries[0].Value) != "2" || !entries[0].SealWrap || entries[0].Delete {
+		t.Fatalf("bad: %#v", entries[0])
+	}
+	if entries[1].Key != "foo" || !entries[1].Delete {
+		t.Fatalf("bad: %#v", entries[1])
+	}
+
+	c.drBarrier.setWAL((*drWAL)(nil))
+	if err := c.barrier.Put(ctx, &Entry{Key: "foo", Value: value}); err != nil {
+		t.Fatal(err)
+	}
+	if wal.lastIndex() != 2 {
+		t.Fatalf("bad: %d", wal.lastIndex())
+	}
+
+	// The barrier storage keeps its pagination
+	keys, err := logical.CollectKeys(ctx, NewBarrierView(c.barrier, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) == 0 {
+		t.Fatal("expected keys")
+	}
+}
+
+func TestSystemBackend_DRReplication(t *testing.T) {
+	_, b, _ := testCoreSystemBackend(t)
+
+	resp, err := b.HandleRequest(context.Background(), logical.TestRequest(t, logical.ReadOperation, "replication/status"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dr := resp.Data["dr"].(map[string]interface{})
+	if dr["mode"] != "disabled" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// The test core has no cluster address to stream over
+	_, err = b.HandleRequest(context.Background(), logical.TestRequest(t, logical.UpdateOperation, "replication/dr/primary/enable"))
+	if err != logical.ErrInvalidRequest {
+		t.Fatalf("expected an invalid request, got %v", err)
+	}
+	_, err = b.HandleRequest(context.Background(), logical.TestRequest(t, logical.UpdateOperation, "replication/dr/primary/secondary-token"))
+	if err != logical.ErrInvalidRequest {
+		t.Fatalf("expected an invalid request, got %v", err)
+	}
+}
@@ -9,7 +9,9 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"os"
 	"runtime"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -52,7 +54,7 @@ func (c *Core) startForwarding(ctx context.Context) error {
 	}
 
 	// The server supports all of the possible protos
-	tlsConfig.NextProtos = []string{"h2", requestForwardingALPN}
+	tlsConfig.NextProtos = []string{"h2", requestForwardingALPN, drReplicationALPN}
 
 	if !atomic.CompareAndSwapUint32(c.rpcServerActive, 0, 1) {
 		c.logger.Warn("core: forwarding rpc server already running")
@@ -188,6 +190,26 @@ func (c *Core) startForwarding(ctx context.Context) error {
 						shutdownWg.Done()
 					}()
 
+				case drReplicationALPN:
+					c.logger.Trace("core: got DR replication connection")
+
+					shutdownWg.Add(2)
+					quitCh := make(chan struct{})
+					go func() {
+						select {
+						case <-quitCh:
+						case <-closeCh:
+						}
+						tlsConn.Close()
+						shutdownWg.Done()
+					}()
+
+					go func() {
+						c.serveDRSecondary(tlsConn)
+						close(quitCh)
+						shutdownWg.Done()
+					}()
+
 				default:
 					c.logger.Debug("core: unknown negotiated protocol on cluster port")
 					tlsConn.Close()
@@ -421,14 +443,60 @@ func (s *forwardedRequestRPCServer) ForwardRequest(ctx context.Context, freq *fo
 
 func (s *forwardedRequestRPCServer) Echo(ctx context.Context, in *EchoRequest) (*EchoReply, error) {
 	if in.ClusterAddr != "" {
-		s.core.clusterPeerClusterAddrsCache.Set(in.ClusterAddr, nil, 0)
+		s.core.clusterPeerClusterAddrsCache.Set(in.ClusterAddr, PeerNode{
+			Hostname:       in.Hostname,
+			APIAddress:     in.ApiAddr,
+			ClusterAddress: in.ClusterAddr,
+			LastEcho:       time.Now(),
+		}, 0)
 	}
+	hostname, _ := os.Hostname()
 	return &EchoReply{
 		Message:          "pong",
 		ReplicationState: uint32(s.core.ReplicationState()),
+		Hostname:         hostname,
 	}, nil
 }
 
+// PeerNode is a standby node, as seen by the active node through its
+// heartbeats
+type PeerNode struct {
+	Hostname       string
+	APIAddress     string
+	ClusterAddress string
+	LastEcho       time.Time
+}
+
+// GetHAPeerNodesCached returns the standby nodes which sent a heartbeat to
+// the active node recently, sorted by cluster address
+func (c *Core) GetHAPeerNodesCached() []PeerNode {
+	var nodes []PeerNode
+	for _, item := range c.clusterPeerClusterAddrsCache.Items() {
+		if node, ok := item.Object.(PeerNode); ok {
+			nodes = append(nodes, node)
+		}
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].ClusterAddress < nodes[j].ClusterAddress
+	})
+	return nodes
+}
+
+// activeNodeHeartbeat is the result of the last successful heartbeat of a
+// standby to the active node
+type activeNodeHeartbeat struct {
+	hostname string
+	lastEcho time.Time
+}
+
+// ActiveNodeHeartbeat returns the hostname of the active node and the time of
+// the last successful heartbeat to it. The time is zero if the node is not a
+// standby or has not reached the active node yet.
+func (c *Core) ActiveNodeHeartbeat() (string, time.Time) {
+	heartbeat, _ := c.activeNodeHeartbeat.Load().(activeNodeHeartbeat)
+	return heartbeat.hostname, heartbeat.lastEcho
+}
+
 type forwardingClient struct {
 	RequestForwardingClient
 
@@ -446,11 +514,14 @@ func (c *forwardingClient) startHeartbeat() {
 			c.core.stateLock.RLock()
 			clusterAddr := c.core.clusterAddr
 			c.core.stateLock.RUnlock()
+			hostname, _ := os.Hostname()
 
 			ctx, cancel := context.WithTimeout(c.echoContext, 2*time.Second)
 			resp, err := c.RequestForwardingClient.Echo(ctx, &EchoRequest{
 				Message:     "ping",
 				ClusterAddr: clusterAddr,
+				Hostname:    hostname,
+				ApiAddr:     c.core.redirectAddr,
 			})
 			cancel()
 			if err != nil {
@@ -468,6 +539,10 @@ func (c *forwardingClient) startHeartbeat() {
 			// Store the active node's replication state to display in
 			// sys/health calls
 			atomic.StoreUint32(c.core.activeNodeReplicationState, resp.ReplicationState)
+			c.core.activeNodeHeartbeat.Store(activeNodeHeartbeat{
+				hostname: resp.Hostname,
+				lastEcho: time.Now(),
+			})
 			//c.core.logger.Trace("forwarding: successful heartbeat")
 		}
 
@@ -479,6 +554,7 @@ func (c *forwardingClient) startHeartbeat() {
 				c.echoTicker.Stop()
 				c.core.logger.Trace("forwarding: stopping heartbeating")
 				atomic.StoreUint32(c.core.activeNodeReplicationState, uint32(consts.ReplicationUnknown))
+				c.core.activeNodeHeartbeat.Store(activeNodeHeartbeat{})
 				return
 			case <-c.echoTicker.C:
 				tick()
@@ -42,6 +42,12 @@ type EchoRequest struct {
 	// ClusterAddrs is used to send up a list of cluster addresses to a dr
 	// primary from a dr secondary
 	ClusterAddrs []string `protobuf:"bytes,3,rep,name=cluster_addrs,json=clusterAddrs" json:"cluster_addrs,omitempty"`
+	// Hostname is used to send up a standby node's hostname to the active
+	// node upon heartbeat
+	Hostname string `protobuf:"bytes,4,opt,name=hostname" json:"hostname,omitempty"`
+	// ApiAddr is used to send up a standby node's API address to the active
+	// node upon heartbeat
+	ApiAddr string `protobuf:"bytes,5,opt,name=api_addr,json=apiAddr" json:"api_addr,omitempty"`
 }
 
 func (m *EchoRequest) Reset()                    { *m = EchoRequest{} }
@@ -70,10 +76,27 @@ func (m *EchoRequest) GetClusterAddrs() []string {
 	return nil
 }
 
+func (m *EchoRequest) GetHostname() string {
+	if m != nil {
+		return m.Hostname
+	}
+	return ""
+}
+
+func (m *EchoRequest) GetApiAddr() string {
+	if m != nil {
+		return m.ApiAddr
+	}
+	return ""
+}
+
 type EchoReply struct {
 	Message          string   `protobuf:"bytes,1,opt,name=message" json:"message,omitempty"`
 	ClusterAddrs     []string `protobuf:"bytes,2,rep,name=cluster_addrs,json=clusterAddrs" json:"cluster_addrs,omitempty"`
 	ReplicationState uint32   `protobuf:"varint,3,opt,name=replication_state,json=replicationState" json:"replication_state,omitempty"`
+	// Hostname is used to send down the active node's hostname to the
+	// standby nodes
+	Hostname string `protobuf:"bytes,4,opt,name=hostname" json:"hostname,omitempty"`
 }
 
 func (m *EchoReply) Reset()                    { *m = EchoReply{} }
@@ -102,6 +125,13 @@ func (m *EchoReply) GetReplicationState() uint32 {
 	return 0
 }
 
+func (m *EchoReply) GetHostname() string {
+	if m != nil {
+		return m.Hostname
+	}
+	return ""
+}
+
 func init() {
 	proto.RegisterType((*EchoRequest)(nil), "vault.EchoRequest")
 	proto.RegisterType((*EchoReply)(nil), "vault.EchoReply")
@@ -215,23 +245,25 @@ var _RequestForwarding_serviceDesc = grpc.ServiceDesc{
 func init() { proto.RegisterFile("request_forwarding_service.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 287 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x7c, 0x90, 0xbf, 0x4e, 0xc3, 0x30,
-	0x10, 0xc6, 0x9b, 0x96, 0x3f, 0xaa, 0xdb, 0xa2, 0xd6, 0x30, 0x44, 0x99, 0x42, 0x58, 0x22, 0x21,
-	0x39, 0x12, 0x2c, 0x2c, 0x0c, 0x0c, 0xf0, 0x00, 0xe1, 0x01, 0x22, 0xd7, 0x39, 0x12, 0x4b, 0x6e,
-	0x6c, 0x7c, 0x4e, 0xab, 0xac, 0x3c, 0x39, 0x6a, 0x92, 0xd2, 0x54, 0x95, 0x18, 0xef, 0x77, 0xa7,
-	0xef, 0xd3, 0xfd, 0x48, 0x68, 0xe1, 0xbb, 0x06, 0x74, 0xd9, 0x97, 0xb6, 0x3b, 0x6e, 0x73, 0x59,
-	0x15, 0x19, 0x82, 0xdd, 0x4a, 0x01, 0xcc, 0x58, 0xed, 0x34, 0xbd, 0xdc, 0xf2, 0x5a, 0xb9, 0xe0,
-	0xa5, 0x90, 0xae, 0xac, 0xd7, 0x4c, 0xe8, 0x4d, 0x52, 0x72, 0x2c, 0xa5, 0xd0, 0xd6, 0x24, 0xed,
-	0x2e, 0x29, 0x41, 0x19, 0xb0, 0xc9, 0x31, 0x22, 0x71, 0x8d, 0x01, 0xec, 0x02, 0x22, 0x4d, 0x66,
-	0xef, 0xa2, 0xd4, 0x69, 0x57, 0x44, 0x7d, 0x72, 0xbd, 0x01, 0x44, 0x5e, 0x80, 0xef, 0x85, 0x5e,
-	0x3c, 0x4d, 0x0f, 0x23, 0xbd, 0x27, 0x73, 0xa1, 0x6a, 0x74, 0x60, 0x33, 0x9e, 0xe7, 0xd6, 0x1f,
-	0xb7, 0xeb, 0x59, 0xcf, 0xde, 0xf2, 0xdc, 0xd2, 0x07, 0xb2, 0x18, 0x9e, 0xa0, 0x3f, 0x09, 0x27,
-	0xf1, 0x34, 0x9d, 0x0f, 0x6e, 0x30, 0xda, 0x91, 0x69, 0x57, 0x68, 0x54, 0xf3, 0x4f, 0xdd, 0x59,
-	0xd6, 0xf8, 0x3c, 0x8b, 0x3e, 0x92, 0x95, 0x05, 0xa3, 0xa4, 0xe0, 0x4e, 0xea, 0x2a, 0x43, 0xc7,
-	0x1d, 0xf8, 0x93, 0xd0, 0x8b, 0x17, 0xe9, 0x72, 0xb0, 0xf8, 0xdc, 0xf3, 0xa7, 0x1f, 0x8f, 0xac,
-	0xfa, 0x37, 0x3f, 0xfe, 0x5c, 0xd0, 0x57, 0x72, 0xd3, 0x4f, 0x07, 0x05, 0xb7, 0xec, 0xa8, 0x8a,
-	0xf5, 0x30, 0xb8, 0x3b, 0x85, 0x68, 0x74, 0x85, 0x10, 0x8d, 0x28, 0x23, 0x17, 0xfb, 0x6f, 0x28,
-	0x65, 0xad, 0x6c, 0x36, 0x70, 0x19, 0x2c, 0x4f, 0x98, 0x51, 0x4d, 0x34, 0x5a, 0x5f, 0xb5, 0xd6,
-	0x9f, 0x7f, 0x03, 0x00, 0x00, 0xff, 0xff, 0x94, 0x1d, 0xe9, 0x21, 0xda, 0x01, 0x00, 0x00,
+	// 316 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x7d, 0x51, 0x4b, 0x4e, 0xc3, 0x30,
+	0x14, 0x6c, 0xfa, 0x81, 0xd6, 0x6d, 0x51, 0x6b, 0x58, 0x84, 0xac, 0x4a, 0xd8, 0x54, 0x42, 0x72,
+	0x24, 0xd8, 0xb0, 0x61, 0xc1, 0x02, 0x0e, 0x50, 0x0e, 0x10, 0xb9, 0xc9, 0xa3, 0xb1, 0x94, 0xc6,
+	0xc6, 0xcf, 0x29, 0xea, 0x96, 0x2b, 0x70, 0x07, 0xce, 0x89, 0xf3, 0x69, 0x9b, 0x0a, 0xd4, 0xe5,
+	0xcc, 0x1b, 0xcf, 0x1b, 0xcf, 0x23, 0x33, 0x0d, 0x1f, 0x39, 0xa0, 0x09, 0xdf, 0xa5, 0xfe, 0xe4,
+	0x3a, 0x16, 0xd9, 0x2a, 0x44, 0xd0, 0x1b, 0x11, 0x01, 0x53, 0x5a, 0x1a, 0x49, 0x7b, 0x1b, 0x9e,
+	0xa7, 0xc6, 0x7b, 0x5c, 0x09, 0x93, 0xe4, 0x4b, 0x16, 0xc9, 0x75, 0x90, 0x70, 0x4c, 0x44, 0x24,
+	0xb5, 0x0a, 0xca, 0x59, 0x90, 0x40, 0xaa, 0x40, 0x07, 0x07, 0x8b, 0xc0, 0x6c, 0x15, 0x60, 0x65,
+	0xe0, 0xff, 0x38, 0x64, 0xf8, 0x12, 0x25, 0x72, 0x51, 0x6d, 0xa2, 0x2e, 0x39, 0x5f, 0x03, 0x22,
+	0x5f, 0x81, 0xeb, 0xcc, 0x9c, 0xf9, 0x60, 0xb1, 0x83, 0xf4, 0x86, 0x8c, 0xa2, 0x34, 0x47, 0x03,
+	0x3a, 0xe4, 0x71, 0xac, 0xdd, 0x76, 0x39, 0x1e, 0xd6, 0xdc, 0xb3, 0xa5, 0xe8, 0x2d, 0x19, 0x37,
+	0x25, 0xe8, 0x76, 0x66, 0x1d, 0xab, 0x19, 0x35, 0x34, 0x48, 0x3d, 0xd2, 0x4f, 0x24, 0x9a, 0x8c,
+	0xaf, 0xc1, 0xed, 0x96, 0x1e, 0x7b, 0x4c, 0xaf, 0x49, 0x9f, 0x2b, 0x51, 0xf9, 0xf7, 0xaa, 0xf5,
+	0x16, 0x17, 0xef, 0xfc, 0x6f, 0x87, 0x0c, 0xaa, 0xa0, 0x2a, 0xdd, 0x9e, 0x88, 0xf9, 0x27, 0x43,
+	0xfb, 0x9f, 0x0c, 0x77, 0x64, 0xaa, 0xad, 0x8f, 0x88, 0xb8, 0x11, 0x32, 0x0b, 0xd1, 0x70, 0x03,
+	0x36, 0xac, 0x33, 0x1f, 0x2f, 0x26, 0x8d, 0xc1, 0x5b, 0xc1, 0x9f, 0x0a, 0x7c, 0xff, 0xe5, 0x90,
+	0x69, 0x5d, 0xdd, 0xeb, 0xbe, 0x60, 0xfa, 0x44, 0x2e, 0x6a, 0xb4, 0xab, 0xf5, 0x92, 0x1d, 0xfa,
+	0x67, 0x35, 0xe9, 0x5d, 0x1d, 0x93, 0xa8, 0x64, 0x86, 0xe0, 0xb7, 0x28, 0x23, 0xdd, 0xe2, 0xa7,
+	0x94, 0xb2, 0xf2, 0x82, 0xac, 0x71, 0x1f, 0x6f, 0x72, 0xc4, 0xd9, 0x2a, 0xfc, 0xd6, 0xf2, 0xac,
+	0x3c, 0xe5, 0xc3, 0x2f, 0xbc, 0x50, 0x4c, 0xb7, 0x2f, 0x02, 0x00, 0x00,
 }
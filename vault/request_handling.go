@@ -15,12 +15,39 @@ import (
 	"github.com/hashicorp/vault/helper/strutil"
 	"github.com/hashicorp/vault/helper/wrapping"
 	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/physical"
 )
 
 const (
 	replTimeout = 10 * time.Second
 )
 
+// requestContext returns the context of the handling of a request, cancelled
+// when the core steps down, when the client making the request goes away, or
+// once the default maximum duration of the requests is over
+func (c *Core) requestContext(req *logical.Request) (context.Context, context.CancelFunc) {
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if c.defaultMaxRequestDuration > 0 {
+		ctx, cancel = context.WithTimeout(c.activeContext, c.defaultMaxRequestDuration)
+	} else {
+		ctx, cancel = context.WithCancel(c.activeContext)
+	}
+
+	clientCtx := req.Context()
+	if clientCtx.Done() == nil {
+		return ctx, cancel
+	}
+	go func() {
+		select {
+		case <-clientCtx.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
 // HandleRequest is used to handle a new incoming request
 func (c *Core) HandleRequest(req *logical.Request) (resp *logical.Response, err error) {
 	c.stateLock.RLock()
@@ -28,13 +55,43 @@ func (c *Core) HandleRequest(req *logical.Request) (resp *logical.Response, err
 	if c.sealed {
 		return nil, consts.ErrSealed
 	}
-	if c.standby {
+	if c.standby && !c.perfStandby {
 		return nil, consts.ErrStandby
 	}
+	if c.IsDRSecondary() {
+		return nil, consts.ErrDRSecondary
+	}
 
-	ctx, cancel := context.WithCancel(c.activeContext)
+	ctx, cancel := c.requestContext(req)
 	defer cancel()
 
+	// Apply the rate limit quota of the full path of the request to the
+	// client before doing any work
+	if !isQuotaExemptPath(req.Path) {
+		var addr string
+		if req.Connection != nil {
+			addr = req.Connection.RemoteAddr
+		}
+		if ok, retryAfter := c.quotas.allowRequest(req.Path, addr); !ok {
+			return nil, &logical.RateLimitQuotaError{RetryAfter: retryAfter}
+		}
+	}
+
+	// Resolve the namespace of the request and rewrite its path to the router
+	// path; the backends find the namespace in the request context
+	ns, err := c.routeRequestNamespace(req)
+	if err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("path %q is not available in namespaces", req.Path)), err
+	}
+	ctx = contextWithNamespace(ctx, ns)
+
+	// Performance standbys only serve the requests which do not write to
+	// storage, or which are declared local-only by their backend
+	props := c.router.OperationProperties(req.Path, req.Operation)
+	if c.standby && !c.perfStandbyCanServe(req, props) {
+		return nil, consts.ErrPerformanceStandbyForward
+	}
+
 	// Allowing writing to a path ending in / makes it extremely difficult to
 	// understand user intent for the filesystem-like backends (kv,
 	// cubbyhole) -- did they want a key named foo/ or did they want to write
@@ -43,17 +100,39 @@ func (c *Core) HandleRequest(req *logical.Request) (resp *logical.Response, err
 	// backends. Basically, it's all just terrible, so don't allow it.
 	if strings.HasSuffix(req.Path, "/") &&
 		(req.Operation == logical.UpdateOperation ||
-			req.Operation == logical.CreateOperation) {
+			req.Operation == logical.CreateOperation ||
+			req.Operation == logical.PatchOperation) {
 		return logical.ErrorResponse("cannot write to a path ending in '/'"), nil
 	}
 
+	entry := c.router.MatchingMountEntry(req.Path)
+
+	// Track the request until it's handled, rejecting it if its mount
+	// already handles its maximum of in-flight requests
+	done, err := c.inFlightRequests.add(req, entry)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	// The audit devices log the keys of the data the mount was tuned not to
+	// HMAC in plaintext
+	if entry != nil {
+		req.SetAuditNonHMACKeys(entry.Config.AuditNonHMACRequestKeys, entry.Config.AuditNonHMACResponseKeys)
+	}
+
 	var auth *logical.Auth
-	if c.router.LoginPath(req.Path) {
+	if c.router.LoginPath(req.Path) || props.Unauthenticated {
 		resp, auth, err = c.handleLoginRequest(ctx, req)
 	} else {
 		resp, auth, err = c.handleRequest(ctx, req)
 	}
 
+	// The request wrote to storage, which performance standbys cannot do
+	if c.standby && !props.LocalOnly && isReadOnlyErr(err) {
+		return nil, consts.ErrPerformanceStandbyForward
+	}
+
 	// Ensure we don't leak internal data
 	if resp != nil {
 		if resp.Secret != nil {
@@ -75,8 +154,12 @@ func (c *Core) HandleRequest(req *logical.Request) (resp *logical.Response, err
 		resp.WrapInfo.TTL != 0 &&
 		resp.WrapInfo.Token == ""
 
+	if wrapping && c.standby && !props.LocalOnly {
+		return nil, consts.ErrPerformanceStandbyForward
+	}
+
 	if wrapping {
-		cubbyResp, cubbyErr := c.wrapInCubbyhole(ctx, req, resp, auth)
+		cubbyResp, cubbyErr := c.wrapInCubbyhole(ctx, req, resp, auth, nil)
 		// If not successful, returns either an error response from the
 		// cubbyhole backend or an error; if either is set, set resp and err to
 		// those and continue so that that's what we audit log. Otherwise
@@ -126,7 +209,12 @@ func (c *Core) handleRequest(ctx context.Context, req *logical.Request) (retResp
 	defer metrics.MeasureSince([]string{"core", "handle_request"}, time.Now())
 
 	// Validate the token
-	auth, te, ctErr := c.checkToken(ctx, req, false)
+	auth, te, controlGroup, ctErr := c.checkToken(ctx, req, false)
+	// Performance standbys cannot use up tokens nor create control group
+	// requests
+	if c.standby && (ctErr == consts.ErrPerformanceStandbyForward || controlGroup != nil) {
+		return nil, nil, consts.ErrPerformanceStandbyForward
+	}
 	// We run this logic first because we want to decrement the use count even in the case of an error
 	if te != nil {
 		// Attempt to use the token (decrement NumUses)
@@ -188,6 +276,26 @@ func (c *Core) handleRequest(ctx context.Context, req *logical.Request) (retResp
 	// Attach the display name
 	req.DisplayName = auth.DisplayName
 
+	// Reads of the request go to the storage backend rather than the cache
+	if req.CacheBypass {
+		ctx = physical.ContextWithCacheBypass(ctx)
+	}
+
+	// Reads of the request wait for the storage backend to reflect the writes
+	// made up to the required index state
+	if req.IndexState != "" {
+		state, err := physical.ParseIndexState(req.IndexState)
+		if err != nil {
+			retErr = multierror.Append(retErr, logical.ErrInvalidRequest)
+			return logical.ErrorResponse(err.Error()), auth, retErr
+		}
+		if err := c.indexTracker.CheckState(state); err != nil {
+			retErr = multierror.Append(retErr, err)
+			return logical.ErrorResponse(err.Error()), auth, retErr
+		}
+		ctx = physical.ContextWithIndexState(ctx, state)
+	}
+
 	// Create an audit trail of the request
 	if err := c.auditBroker.LogRequest(ctx, auth, req, c.auditedHeaders, nil); err != nil {
 		c.logger.Error("core: failed to audit request", "path", req.Path, "error", err)
@@ -195,6 +303,15 @@ func (c *Core) handleRequest(ctx context.Context, req *logical.Request) (retResp
 		return nil, auth, retErr
 	}
 
+	// Batch tokens are not persisted, so they have no cubbyhole
+	if te != nil && te.Type == TokenTypeBatch && strings.HasPrefix(req.Path, "cubbyhole/") {
+		retErr = multierror.Append(retErr, logical.ErrInvalidRequest)
+		return logical.ErrorResponse("batch tokens cannot use the cubbyhole"), auth, retErr
+	}
+
+	// Record the client of the token as active on the mount of the request
+	c.activityLog.recordToken(te, c.router.MatchingMountEntry(req.Path))
+
 	// Route the request
 	resp, routeErr := c.router.Route(ctx, req)
 	if resp != nil {
@@ -279,8 +396,41 @@ func (c *Core) handleRequest(ctx context.Context, req *logical.Request) (retResp
 			}
 		}
 
+		// Performance standbys do not register leases
+		if registerLease && c.standby {
+			return nil, auth, consts.ErrPerformanceStandbyForward
+		}
+
 		if registerLease {
-			leaseID, err := c.expiration.Register(req, resp)
+			leaseReq := req
+			if te != nil && te.Type == TokenTypeBatch {
+				// Leases cannot outlive the batch token they belong to
+				if remaining := time.Until(time.Unix(te.CreationTime, 0).Add(te.TTL)); resp.Secret.TTL > remaining {
+					resp.Secret.TTL = remaining
+				}
+
+				// Batch tokens cannot be revoked, so the leases are tied to
+				// the parent token to be revoked along with it
+				if te.Parent != "" {
+					leaseReq = new(logical.Request)
+					*leaseReq = *req
+					leaseReq.ClientToken = te.Parent
+				}
+			}
+
+			// The secret was already generated when the lease count quota is
+			// exceeded, so it is revoked right away
+			release, ok := c.quotas.reserveLease(c.quotaPath(req.Path))
+			if !ok {
+				if _, err := c.router.Route(ctx, logical.RevokeRequest(req.Path, resp.Secret, resp.Data)); err != nil {
+					c.logger.Error("core: failed to revoke secret exceeding the lease count quota", "request_path", req.Path, "error", err)
+				}
+				retErr = multierror.Append(retErr, logical.ErrLeaseCountQuotaExceeded)
+				return nil, auth, retErr
+			}
+
+			leaseID, err := c.expiration.Register(leaseReq, resp)
+			release()
 			if err != nil {
 				c.logger.Error("core: failed to register lease", "request_path", req.Path, "error", err)
 				retErr = multierror.Append(retErr, ErrInternalError)
@@ -297,7 +447,20 @@ func (c *Core) handleRequest(ctx context.Context, req *logical.Request) (retResp
 		resp.Auth != nil &&
 		resp.Auth.EntityID != "" &&
 		resp.Auth.GroupAliases != nil {
-		err := c.identityStore.refreshExternalGroupMembershipsByEntityID(resp.Auth.EntityID, resp.Auth.GroupAliases)
+		// The entity belongs to the namespace of the renewed token
+		identityStore := c.identityStore
+		if te, err := c.tokenStore.Lookup(ctx, resp.Auth.ClientToken); err == nil && te != nil && te.NamespaceID != "" {
+			identityStore = nil
+			if ns := c.namespaceByID(te.NamespaceID); ns != nil {
+				identityStore = c.identityStoreForNamespace(ns)
+			}
+		}
+		if identityStore == nil {
+			c.logger.Error("core: identity store of the namespace of the token is unavailable")
+			retErr = multierror.Append(retErr, ErrInternalError)
+			return nil, auth, retErr
+		}
+		err := identityStore.refreshExternalGroupMembershipsByEntityID(resp.Auth.EntityID, resp.Auth.GroupAliases)
 		if err != nil {
 			c.logger.Error("core: failed to refresh external group memberships", "error", err)
 			retErr = multierror.Append(retErr, ErrInternalError)
@@ -309,6 +472,9 @@ func (c *Core) handleRequest(ctx context.Context, req *logical.Request) (retResp
 	// other request this is an internal error. We exclude renewal of a token,
 	// since it does not need to be re-registered
 	if resp != nil && resp.Auth != nil && !strings.HasPrefix(req.Path, "auth/token/renew") {
+		if c.standby {
+			return nil, auth, consts.ErrPerformanceStandbyForward
+		}
 		if !strings.HasPrefix(req.Path, "auth/token/") {
 			c.logger.Error("core: unexpected Auth response for non-token backend", "request_path", req.Path)
 			retErr = multierror.Append(retErr, ErrInternalError)
@@ -324,11 +490,22 @@ func (c *Core) handleRequest(ctx context.Context, req *logical.Request) (retResp
 			return nil, auth, retErr
 		}
 
-		if err := c.expiration.RegisterAuth(te.Path, resp.Auth); err != nil {
-			c.tokenStore.Revoke(ctx, te.ID)
-			c.logger.Error("core: failed to register token lease", "request_path", req.Path, "error", err)
-			retErr = multierror.Append(retErr, ErrInternalError)
-			return nil, auth, retErr
+		// Batch tokens expire on their own and have no lease
+		if te.Type != TokenTypeBatch {
+			release, ok := c.quotas.reserveLease(c.quotaPath(te.Path))
+			if !ok {
+				c.tokenStore.Revoke(ctx, te.ID)
+				retErr = multierror.Append(retErr, logical.ErrLeaseCountQuotaExceeded)
+				return nil, auth, retErr
+			}
+			err := c.expiration.RegisterAuth(te.Path, resp.Auth)
+			release()
+			if err != nil {
+				c.tokenStore.Revoke(ctx, te.ID)
+				c.logger.Error("core: failed to register token lease", "request_path", req.Path, "error", err)
+				retErr = multierror.Append(retErr, ErrInternalError)
+				return nil, auth, retErr
+			}
 		}
 	}
 
@@ -344,6 +521,18 @@ func (c *Core) handleRequest(ctx context.Context, req *logical.Request) (retResp
 		retErr = multierror.Append(retErr, routeErr)
 	}
 
+	// The responses of reads of paths with a control group are wrapped in a
+	// control group token, which can only be unwrapped once the request is
+	// authorized
+	if controlGroup != nil && req.Operation == logical.ReadOperation &&
+		resp != nil && !resp.IsError() && routeErr == nil {
+		cgResp, cgErr := c.wrapInControlGroup(ctx, req, resp, auth, controlGroup)
+		if cgErr != nil {
+			retErr = multierror.Append(retErr, cgErr)
+		}
+		return cgResp, auth, retErr
+	}
+
 	return resp, auth, retErr
 }
 
@@ -424,6 +613,9 @@ func (c *Core) handleLoginRequest(ctx context.Context, req *logical.Request) (re
 		var entity *identity.Entity
 		auth = resp.Auth
 
+		// The token and entity are created in the namespace of the auth method
+		ns := namespaceFromContext(ctx)
+
 		if auth.Alias != nil {
 			// Overwrite the mount type and mount path in the alias
 			// information
@@ -434,11 +626,17 @@ func (c *Core) handleLoginRequest(ctx context.Context, req *logical.Request) (re
 				return nil, nil, fmt.Errorf("missing name in alias")
 			}
 
+			identityStore := c.identityStoreForNamespace(ns)
+			if identityStore == nil {
+				c.logger.Error("core: identity store of the namespace is unavailable", "namespace", ns.Path)
+				return nil, nil, ErrInternalError
+			}
+
 			var err error
 
 			// Fetch the entity for the alias, or create an entity if one
 			// doesn't exist.
-			entity, err = c.identityStore.CreateOrFetchEntity(auth.Alias)
+			entity, err = identityStore.CreateOrFetchEntity(auth.Alias)
 			if err != nil {
 				return nil, nil, err
 			}
@@ -449,7 +647,7 @@ func (c *Core) handleLoginRequest(ctx context.Context, req *logical.Request) (re
 
 			auth.EntityID = entity.ID
 			if auth.GroupAliases != nil {
-				err = c.identityStore.refreshExternalGroupMembershipsByEntityID(auth.EntityID, auth.GroupAliases)
+				err = identityStore.refreshExternalGroupMembershipsByEntityID(auth.EntityID, auth.GroupAliases)
 				if err != nil {
 					return nil, nil, err
 				}
@@ -460,9 +658,15 @@ func (c *Core) handleLoginRequest(ctx context.Context, req *logical.Request) (re
 			return logical.ErrorResponse("auth methods cannot create root tokens"), nil, logical.ErrInvalidRequest
 		}
 
+		// Require the second factors of the matching login enforcements
+		// before issuing the token
+		if mfaResp, err := c.validateLoginMFA(ctx, req, entity); mfaResp != nil || err != nil {
+			return mfaResp, nil, err
+		}
+
 		// Determine the source of the login
 		source := c.router.MatchingMount(req.Path)
-		source = strings.TrimPrefix(source, credentialRoutePrefix)
+		source = strings.TrimPrefix(source, credentialRoutePrefix+ns.Path)
 		source = strings.Replace(source, "/", "-", -1)
 
 		// Prepend the source to the display name
@@ -474,6 +678,12 @@ func (c *Core) handleLoginRequest(ctx context.Context, req *logical.Request) (re
 			return nil, nil, ErrInternalError
 		}
 
+		// Auth mounts can be tuned to issue batch tokens
+		var tokenType string
+		if entry := c.router.MatchingMountEntry(req.Path); entry != nil && entry.Config.TokenType == TokenTypeBatch {
+			tokenType = TokenTypeBatch
+		}
+
 		// Start off with the sys default value, and update according to period/TTL
 		// from resp.Auth
 		tokenTTL := sysView.DefaultLeaseTTL()
@@ -497,6 +707,15 @@ func (c *Core) handleLoginRequest(ctx context.Context, req *logical.Request) (re
 			tokenTTL = auth.TTL
 		}
 
+		// Batch tokens cannot be renewed, so the period only sets their TTL
+		if tokenType == TokenTypeBatch {
+			if auth.NumUses > 0 {
+				return logical.ErrorResponse("batch tokens cannot have a limited number of uses"), nil, logical.ErrInvalidRequest
+			}
+			auth.Period = 0
+			auth.Renewable = false
+		}
+
 		// Generate a token
 		te := TokenEntry{
 			Path:         req.Path,
@@ -507,6 +726,8 @@ func (c *Core) handleLoginRequest(ctx context.Context, req *logical.Request) (re
 			TTL:          tokenTTL,
 			NumUses:      auth.NumUses,
 			EntityID:     auth.EntityID,
+			NamespaceID:  ns.ID,
+			Type:         tokenType,
 		}
 
 		te.Policies = policyutil.SanitizePolicies(te.Policies, true)
@@ -518,7 +739,19 @@ func (c *Core) handleLoginRequest(ctx context.Context, req *logical.Request) (re
 			}
 		}
 
+		// Tokens with a lease count against the lease count quotas, the
+		// lease is reserved until the token is registered
+		release := func() {}
+		if te.Type != TokenTypeBatch {
+			var ok bool
+			release, ok = c.quotas.reserveLease(c.quotaPath(te.Path))
+			if !ok {
+				return nil, nil, logical.ErrLeaseCountQuotaExceeded
+			}
+		}
+
 		if err := c.tokenStore.create(ctx, &te); err != nil {
+			release()
 			c.logger.Error("core: failed to create token", "error", err)
 			return nil, auth, ErrInternalError
 		}
@@ -529,13 +762,21 @@ func (c *Core) handleLoginRequest(ctx context.Context, req *logical.Request) (re
 		auth.Policies = te.Policies
 		auth.TTL = te.TTL
 
-		// Register with the expiration manager
-		if err := c.expiration.RegisterAuth(te.Path, auth); err != nil {
-			c.tokenStore.Revoke(ctx, te.ID)
-			c.logger.Error("core: failed to register token lease", "request_path", req.Path, "error", err)
-			return nil, auth, ErrInternalError
+		// Register with the expiration manager. Batch tokens expire on their
+		// own and have no lease.
+		if te.Type != TokenTypeBatch {
+			err := c.expiration.RegisterAuth(te.Path, auth)
+			release()
+			if err != nil {
+				c.tokenStore.Revoke(ctx, te.ID)
+				c.logger.Error("core: failed to register token lease", "request_path", req.Path, "error", err)
+				return nil, auth, ErrInternalError
+			}
 		}
 
+		// Record the client of the new token as active on the auth mount
+		c.activityLog.recordToken(&te, c.router.MatchingMountEntry(req.Path))
+
 		// Attach the display name, might be used by audit backends
 		req.DisplayName = auth.DisplayName
 	}
@@ -5,8 +5,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/hashicorp/errwrap"
 	"github.com/hashicorp/go-uuid"
 	credUserpass "github.com/hashicorp/vault/builtin/credential/userpass"
+	"github.com/hashicorp/vault/helper/consts"
 	"github.com/hashicorp/vault/logical"
 )
 
@@ -141,3 +143,90 @@ func TestRequestHandling_LoginWrapping(t *testing.T) {
 		t.Fatalf("bad: %#v", resp)
 	}
 }
+
+func TestRequestHandling_CacheBypass(t *testing.T) {
+	core, _, root := TestCoreUnsealed(t)
+
+	write := func(value string) {
+		req := logical.TestRequest(t, logical.UpdateOperation, "secret/foo")
+		req.ClientToken = root
+		req.Data["value"] = value
+		if _, err := core.HandleRequest(req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func(token string, bypass bool) (string, error) {
+		req := logical.TestRequest(t, logical.ReadOperation, "secret/foo")
+		req.ClientToken = token
+		req.CacheBypass = bypass
+		resp, err := core.HandleRequest(req)
+		if err != nil {
+			return "", err
+		}
+		return resp.Data["value"].(string), nil
+	}
+
+	write("bar")
+	if value, err := read(root, false); err != nil || value != "bar" {
+		t.Fatalf("bad: %q %v", value, err)
+	}
+
+	// Writes with the cache disabled leave a stale entry in the cache
+	core.physicalCache.SetEnabled(false)
+	write("baz")
+	core.physicalCache.SetEnabled(true)
+
+	if value, err := read(root, false); err != nil || value != "bar" {
+		t.Fatalf("expected stale value: %q %v", value, err)
+	}
+	if value, err := read(root, true); err != nil || value != "baz" {
+		t.Fatalf("expected value of the storage backend: %q %v", value, err)
+	}
+
+	// Bypassing the cache requires sudo on the path
+	req := logical.TestRequest(t, logical.UpdateOperation, "sys/policy/reader")
+	req.ClientToken = root
+	req.Data["rules"] = `path "secret/*" { capabilities = ["read"] }`
+	if _, err := core.HandleRequest(req); err != nil {
+		t.Fatal(err)
+	}
+	testCoreMakeToken(t, core, root, "reader", "", []string{"reader"})
+
+	if value, err := read("reader", false); err != nil || value != "bar" {
+		t.Fatalf("bad: %q %v", value, err)
+	}
+	if _, err := read("reader", true); err == nil || !errwrap.Contains(err, logical.ErrPermissionDenied.Error()) {
+		t.Fatalf("expected permission denied, got %v", err)
+	}
+}
+
+func TestRequestHandling_IndexState(t *testing.T) {
+	core, _, root := TestCoreUnsealed(t)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "secret/foo")
+	req.ClientToken = root
+	req.Data["value"] = "bar"
+	if _, err := core.HandleRequest(req); err != nil {
+		t.Fatal(err)
+	}
+
+	read := func(state string) (*logical.Response, error) {
+		req := logical.TestRequest(t, logical.ReadOperation, "secret/foo")
+		req.ClientToken = root
+		req.IndexState = state
+		return core.HandleRequest(req)
+	}
+
+	resp, err := read(core.IndexState())
+	if err != nil || resp == nil || resp.Data["value"] != "bar" {
+		t.Fatalf("bad: %#v %v", resp, err)
+	}
+
+	// Invalid states are rejected, and unknown ones cannot be waited for
+	if _, err := read("foo"); !errwrap.Contains(err, logical.ErrInvalidRequest.Error()) {
+		t.Fatalf("expected invalid request error: %v", err)
+	}
+	if _, err := read("foo:1"); !errwrap.Contains(err, consts.ErrUnknownIndexState.Error()) {
+		t.Fatalf("expected unknown state error: %v", err)
+	}
+}
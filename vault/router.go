@@ -3,6 +3,7 @@ package vault
 import (
 	"context"
 	"fmt"
+	"net/textproto"
 	"strings"
 	"sync"
 	"time"
@@ -10,6 +11,7 @@ import (
 	"github.com/armon/go-metrics"
 	"github.com/armon/go-radix"
 	"github.com/hashicorp/vault/helper/salt"
+	"github.com/hashicorp/vault/helper/strutil"
 	"github.com/hashicorp/vault/logical"
 )
 
@@ -52,6 +54,8 @@ type validateMountResponse struct {
 	MountType     string `json:"mount_type" structs:"mount_type" mapstructure:"mount_type"`
 	MountAccessor string `json:"mount_accessor" structs:"mount_accessor" mapstructure:"mount_accessor"`
 	MountPath     string `json:"mount_path" structs:"mount_path" mapstructure:"mount_path"`
+
+	SyncExternalGroups bool `json:"sync_external_groups" structs:"sync_external_groups" mapstructure:"sync_external_groups"`
 }
 
 // validateMountByAccessor returns the mount type and ID for a given mount
@@ -72,9 +76,10 @@ func (r *Router) validateMountByAccessor(accessor string) *validateMountResponse
 	}
 
 	return &validateMountResponse{
-		MountAccessor: mountEntry.Accessor,
-		MountType:     mountEntry.Type,
-		MountPath:     mountPath,
+		MountAccessor:      mountEntry.Accessor,
+		MountType:          mountEntry.Type,
+		MountPath:          mountPath,
+		SyncExternalGroups: mountEntry.Config.SyncExternalGroups,
 	}
 }
 
@@ -398,6 +403,10 @@ func (r *Router) routeCommon(ctx context.Context, req *logical.Request, existenc
 	req.Path = adjustedPath
 	defer metrics.MeasureSince([]string{"route", string(req.Operation),
 		strings.Replace(mount, "/", "-", -1)}, time.Now())
+	defer metrics.MeasureSinceWithLabels([]string{"route", "request"}, time.Now(), []metrics.Label{
+		{Name: "mount", Value: mount},
+		{Name: "operation", Value: string(req.Operation)},
+	})
 	re := raw.(*routeEntry)
 
 	// Filtered mounts will have a nil backend
@@ -431,9 +440,10 @@ func (r *Router) routeCommon(ctx context.Context, req *logical.Request, existenc
 
 	// Allow EntityID to passthrough to the system backend. This is required to
 	// allow clients to generate MFA credentials in respective entity objects
-	// in identity store via the system backend.
+	// in identity store via the system backend. The identity backend needs it
+	// to issue OIDC tokens for the entity of the request.
 	switch {
-	case strings.HasPrefix(originalPath, "sys/"):
+	case strings.HasPrefix(originalPath, "sys/"), strings.HasPrefix(originalPath, "identity/"):
 	default:
 		req.EntityID = ""
 	}
@@ -466,9 +476,10 @@ func (r *Router) routeCommon(ctx context.Context, req *logical.Request, existenc
 	originalClientTokenRemainingUses := req.ClientTokenRemainingUses
 	req.ClientTokenRemainingUses = 0
 
-	// Cache the headers and hide them from backends
+	// Cache the headers and only pass the ones allowed by the mount to the
+	// backend
 	headers := req.Headers
-	req.Headers = nil
+	req.Headers = filterHeaders(headers, re.mountEntry.Config.PassthroughRequestHeaders)
 
 	// Cache the wrap info of the request
 	var wrapInfo *logical.RequestWrapInfo
@@ -509,6 +520,10 @@ func (r *Router) routeCommon(ctx context.Context, req *logical.Request, existenc
 		return nil, ok, exists, err
 	} else {
 		resp, err := re.backend.HandleRequest(ctx, req)
+		// Only send the response headers allowed by the mount
+		if resp != nil {
+			resp.Headers = filterHeaders(resp.Headers, re.mountEntry.Config.AllowedResponseHeaders)
+		}
 		// When a token gets renewed, the request hits this path and reaches
 		// token store. Token store delegates the renewal to the expiration
 		// manager. Expiration manager in-turn creates a different logical
@@ -592,6 +607,24 @@ func (r *Router) LoginPath(path string) bool {
 	return match == remain
 }
 
+// OperationProperties returns the properties of the given operation on the
+// path that are declared by its backend
+func (r *Router) OperationProperties(path string, op logical.Operation) logical.OperationProperties {
+	r.l.RLock()
+	mount, raw, ok := r.root.LongestPrefix(path)
+	r.l.RUnlock()
+	if !ok {
+		return logical.OperationProperties{}
+	}
+	re := raw.(*routeEntry)
+
+	b, ok := re.backend.(logical.OperationPropertiesBackend)
+	if !ok {
+		return logical.OperationProperties{}
+	}
+	return b.OperationProperties(strings.TrimPrefix(path, mount), op)
+}
+
 // pathsToRadix converts a the mapping of special paths to a mapping
 // of special paths to radix trees.
 func pathsToRadix(paths []string) *radix.Tree {
@@ -608,3 +641,22 @@ func pathsToRadix(paths []string) *radix.Tree {
 
 	return tree
 }
+
+// filterHeaders returns the headers whose canonical names are in the given
+// list of canonical header names
+func filterHeaders(headers map[string][]string, names []string) map[string][]string {
+	if len(headers) == 0 || len(names) == 0 {
+		return nil
+	}
+
+	ret := make(map[string][]string)
+	for key, values := range headers {
+		if strutil.StrListContains(names, textproto.CanonicalMIMEHeaderKey(key)) {
+			ret[textproto.CanonicalMIMEHeaderKey(key)] = values
+		}
+	}
+	if len(ret) == 0 {
+		return nil
+	}
+	return ret
+}
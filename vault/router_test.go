@@ -13,6 +13,7 @@ import (
 	"github.com/hashicorp/go-uuid"
 	"github.com/hashicorp/vault/helper/logformat"
 	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
 	log "github.com/mgutz/logxi/v1"
 )
 
@@ -197,6 +198,87 @@ func TestRouter_Mount(t *testing.T) {
 	}
 }
 
+func TestRouter_Headers(t *testing.T) {
+	r := NewRouter()
+	_, barrier, _ := mockBarrier(t)
+	view := NewBarrierView(barrier, "logical/")
+
+	meUUID, err := uuid.GenerateUUID()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mountEntry := &MountEntry{
+		Path:     "prod/aws/",
+		UUID:     meUUID,
+		Accessor: "awsaccessor",
+		Config: MountConfig{
+			PassthroughRequestHeaders: []string{"X-Custom"},
+			AllowedResponseHeaders:    []string{"X-Custom-Response"},
+		},
+	}
+
+	n := &NoopBackend{
+		Response: &logical.Response{
+			Headers: map[string][]string{
+				"X-Custom-Response": []string{"foo"},
+				"X-Other-Response":  []string{"bar"},
+			},
+		},
+	}
+	err = r.Mount(n, "prod/aws/", mountEntry, view)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	headers := map[string][]string{
+		"X-Custom":      []string{"foo"},
+		"X-Vault-Token": []string{"bar"},
+		"X-Other":       []string{"baz"},
+	}
+	req := &logical.Request{
+		Path:    "prod/aws/foo",
+		Headers: headers,
+	}
+	resp, err := r.Route(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Only the passthrough headers reach the backend, and the request keeps
+	// all of them once routed
+	expected := map[string][]string{
+		"X-Custom": []string{"foo"},
+	}
+	if len(n.Requests) != 1 || !reflect.DeepEqual(n.Requests[0].Headers, expected) {
+		t.Fatalf("bad: %#v", n.Requests)
+	}
+	if !reflect.DeepEqual(req.Headers, headers) {
+		t.Fatalf("bad: %#v", req.Headers)
+	}
+
+	// Only the allowed headers of the response are kept
+	expected = map[string][]string{
+		"X-Custom-Response": []string{"foo"},
+	}
+	if !reflect.DeepEqual(resp.Headers, expected) {
+		t.Fatalf("bad: %#v", resp.Headers)
+	}
+
+	// Without any configured, no headers are passed either way
+	mountEntry.Config = MountConfig{}
+	n.Response.Headers = map[string][]string{
+		"X-Custom-Response": []string{"foo"},
+	}
+	resp, err = r.Route(context.Background(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if n.Requests[1].Headers != nil || resp.Headers != nil {
+		t.Fatalf("bad: %#v %#v", n.Requests[1].Headers, resp.Headers)
+	}
+}
+
 func TestRouter_MountCredential(t *testing.T) {
 	r := NewRouter()
 	_, barrier, _ := mockBarrier(t)
@@ -448,6 +530,48 @@ func TestRouter_LoginPath(t *testing.T) {
 	}
 }
 
+func TestRouter_OperationProperties(t *testing.T) {
+	r := NewRouter()
+	_, barrier, _ := mockBarrier(t)
+	view := NewBarrierView(barrier, "logical/")
+
+	b := &framework.Backend{
+		Paths: []*framework.Path{
+			&framework.Path{
+				Pattern: "public_key",
+				Properties: map[logical.Operation]logical.OperationProperties{
+					logical.ReadOperation: {Unauthenticated: true, ReadOnly: true},
+				},
+			},
+		},
+	}
+	err := r.Mount(b, "ssh/", &MountEntry{UUID: "sshuuid", Accessor: "sshaccessor"}, view)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	err = r.Mount(&NoopBackend{}, "prod/aws/", &MountEntry{UUID: "awsuuid", Accessor: "awsaccessor"}, view.SubView("aws/"))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	tcases := []struct {
+		path   string
+		op     logical.Operation
+		expect logical.OperationProperties
+	}{
+		{"random", logical.ReadOperation, logical.OperationProperties{}},
+		{"ssh/public_key", logical.ReadOperation, logical.OperationProperties{Unauthenticated: true, ReadOnly: true}},
+		{"ssh/public_key", logical.DeleteOperation, logical.OperationProperties{}},
+		{"ssh/other", logical.ReadOperation, logical.OperationProperties{}},
+		{"prod/aws/foo", logical.ReadOperation, logical.OperationProperties{}},
+	}
+	for _, tc := range tcases {
+		if out := r.OperationProperties(tc.path, tc.op); out != tc.expect {
+			t.Fatalf("bad: %s %s expect: %#v got %#v", tc.op, tc.path, tc.expect, out)
+		}
+	}
+}
+
 func TestRouter_Taint(t *testing.T) {
 	r := NewRouter()
 	_, barrier, _ := mockBarrier(t)
@@ -42,10 +42,13 @@ const (
 )
 
 const (
-	SealTypeShamir = "shamir"
-	SealTypePKCS11 = "pkcs11"
-	SealTypeAWSKMS = "awskms"
-	SealTypeTest   = "test-auto"
+	SealTypeShamir        = "shamir"
+	SealTypePKCS11        = "pkcs11"
+	SealTypeAWSKMS        = "awskms"
+	SealTypeGCPCKMS       = "gcpckms"
+	SealTypeAzureKeyVault = "azurekeyvault"
+	SealTypeTransit       = "transit"
+	SealTypeTest          = "test-auto"
 
 	RecoveryTypeUnsupported = "unsupported"
 	RecoveryTypeShamir      = "shamir"
@@ -264,6 +267,18 @@ type SealConfig struct {
 
 	// How many keys to store, for seals that support storage.
 	StoredShares int `json:"stored_shares"`
+
+	// VerificationRequired indicates that after a rekey the new shares must
+	// be provided before the new key is installed. It is omitted from JSON
+	// as the new key is never persisted before the verification.
+	VerificationRequired bool `json:"-"`
+
+	// VerificationKey is the new key installed once the new shares are
+	// verified.
+	VerificationKey []byte `json:"-"`
+
+	// VerificationNonce is the nonce of the verification of the new shares.
+	VerificationNonce string `json:"-"`
 }
 
 // Validate is used to sanity check the seal configuration
@@ -315,10 +330,17 @@ func (s *SealConfig) Clone() *SealConfig {
 		Nonce:           s.Nonce,
 		Backup:          s.Backup,
 		StoredShares:    s.StoredShares,
+
+		VerificationRequired: s.VerificationRequired,
+		VerificationNonce:    s.VerificationNonce,
 	}
 	if len(s.PGPKeys) > 0 {
 		ret.PGPKeys = make([]string, len(s.PGPKeys))
 		copy(ret.PGPKeys, s.PGPKeys)
 	}
+	if len(s.VerificationKey) > 0 {
+		ret.VerificationKey = make([]byte, len(s.VerificationKey))
+		copy(ret.VerificationKey, s.VerificationKey)
+	}
 	return ret
 }
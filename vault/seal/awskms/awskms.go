@@ -0,0 +1,209 @@
+// Package awskms is the access to AWS KMS for auto seals.
+package awskms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/vault/helper/awsutil"
+	"github.com/hashicorp/vault/vault/seal"
+)
+
+const (
+	// SealType is the type of the AWS KMS seal
+	SealType = "awskms"
+
+	// EnvAWSKMSSealKeyID is the environment variable of the ID of the key,
+	// which takes precedence over the configuration
+	EnvAWSKMSSealKeyID = "VAULT_AWSKMS_SEAL_KEY_ID"
+
+	// DefaultRegion is the region used if none is configured
+	DefaultRegion = "us-east-1"
+
+	// mechanism is the mechanism recorded in the key info of the values
+	mechanism = "aws-kms"
+)
+
+// Seal is the access to a key of AWS KMS, which encrypts the data keys of the
+// values
+type Seal struct {
+	keyID        string
+	currentKeyID *atomic.Value
+
+	client *kmsClient
+}
+
+var _ seal.Access = (*Seal)(nil)
+
+// NewSeal returns an AWS KMS seal, which must be configured with SetConfig
+func NewSeal() *Seal {
+	s := &Seal{
+		currentKeyID: new(atomic.Value),
+	}
+	s.currentKeyID.Store("")
+	return s
+}
+
+// SetConfig configures the seal from the parameters of the seal stanza,
+// returning the information about the seal for the server output
+func (s *Seal) SetConfig(config map[string]string) (map[string]string, error) {
+	if config == nil {
+		config = map[string]string{}
+	}
+
+	s.keyID = os.Getenv(EnvAWSKMSSealKeyID)
+	if s.keyID == "" {
+		s.keyID = config["kms_key_id"]
+	}
+	if s.keyID == "" {
+		return nil, errors.New("'kms_key_id' not found for AWS KMS seal configuration")
+	}
+
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if region == "" {
+		region = config["region"]
+	}
+	if region == "" {
+		region = DefaultRegion
+	}
+
+	accessKey := os.Getenv("AWS_ACCESS_KEY_ID")
+	if accessKey == "" {
+		accessKey = config["access_key"]
+	}
+	secretKey := os.Getenv("AWS_SECRET_ACCESS_KEY")
+	if secretKey == "" {
+		secretKey = config["secret_key"]
+	}
+	sessionToken := os.Getenv("AWS_SESSION_TOKEN")
+	if sessionToken == "" {
+		sessionToken = config["session_token"]
+	}
+	endpoint := os.Getenv("AWS_KMS_ENDPOINT")
+	if endpoint == "" {
+		endpoint = config["endpoint"]
+	}
+
+	credsConfig := &awsutil.CredentialsConfig{
+		AccessKey:    accessKey,
+		SecretKey:    secretKey,
+		SessionToken: sessionToken,
+		Region:       region,
+	}
+	creds, err := credsConfig.GenerateCredentialChain()
+	if err != nil {
+		return nil, err
+	}
+
+	awsConf := aws.NewConfig().
+		WithCredentials(creds).
+		WithRegion(region).
+		WithHTTPClient(cleanhttp.DefaultClient())
+	if endpoint != "" {
+		awsConf = awsConf.WithEndpoint(endpoint)
+	}
+	s.client = newKMSClient(session.New(awsConf))
+
+	info := map[string]string{
+		"AWS KMS Region": region,
+		"AWS KMS KeyID":  s.keyID,
+	}
+	if endpoint != "" {
+		info["AWS KMS Endpoint"] = endpoint
+	}
+	return info, nil
+}
+
+// Init checks that the key can be used, recording the ID of the key
+func (s *Seal) Init(ctx context.Context) error {
+	// Encrypting is the only way to check the access to the key without
+	// requiring more permissions
+	if _, err := s.Encrypt(ctx, []byte("vault")); err != nil {
+		return errwrap.Wrapf("error checking the AWS KMS key: {{err}}", err)
+	}
+	return nil
+}
+
+func (s *Seal) Finalize(context.Context) error {
+	return nil
+}
+
+func (s *Seal) SealType() string {
+	return SealType
+}
+
+// KeyID returns the ARN of the key that last encrypted a value
+func (s *Seal) KeyID() string {
+	return s.currentKeyID.Load().(string)
+}
+
+func (s *Seal) Encrypt(ctx context.Context, plaintext []byte) (*seal.EncryptedBlobInfo, error) {
+	if s.client == nil {
+		return nil, errors.New("AWS KMS seal is not configured")
+	}
+
+	env, err := seal.EnvelopeEncrypt(plaintext)
+	if err != nil {
+		return nil, errwrap.Wrapf("error wrapping data: {{err}}", err)
+	}
+
+	out, err := s.client.encrypt(ctx, &encryptInput{
+		KeyId:     aws.String(s.keyID),
+		Plaintext: env.Key,
+	})
+	if err != nil {
+		return nil, errwrap.Wrapf("error encrypting data key: {{err}}", err)
+	}
+
+	// The ARN of the key is recorded, since the configured ID may be an alias
+	keyID := aws.StringValue(out.KeyId)
+	s.currentKeyID.Store(keyID)
+
+	return &seal.EncryptedBlobInfo{
+		Ciphertext: env.Ciphertext,
+		IV:         env.IV,
+		KeyInfo: &seal.KeyInfo{
+			Mechanism:  mechanism,
+			KeyID:      keyID,
+			WrappedKey: out.CiphertextBlob,
+		},
+	}, nil
+}
+
+func (s *Seal) Decrypt(ctx context.Context, in *seal.EncryptedBlobInfo) ([]byte, error) {
+	if s.client == nil {
+		return nil, errors.New("AWS KMS seal is not configured")
+	}
+	if in == nil || in.KeyInfo == nil {
+		return nil, errors.New("missing key info")
+	}
+
+	// The ciphertext of AWS KMS identifies the key, so the key ID is not
+	// needed
+	out, err := s.client.decrypt(ctx, &decryptInput{
+		CiphertextBlob: in.KeyInfo.WrappedKey,
+	})
+	if err != nil {
+		return nil, errwrap.Wrapf("error decrypting data key: {{err}}", err)
+	}
+
+	plaintext, err := seal.EnvelopeDecrypt(&seal.EnvelopeInfo{
+		Ciphertext: in.Ciphertext,
+		Key:        out.Plaintext,
+		IV:         in.IV,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting data: %v", err)
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,91 @@
+package awskms
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+const testKeyARN = "arn:aws:kms:us-east-1:123456789012:key/test"
+
+func TestAWSKMSSeal(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			t.Errorf("request not signed")
+		}
+
+		var in struct {
+			KeyId          string
+			Plaintext      []byte
+			CiphertextBlob []byte
+		}
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			t.Errorf("bad request: %v", err)
+		}
+
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		switch r.Header.Get("X-Amz-Target") {
+		case "TrentService.Encrypt":
+			if in.KeyId != "alias/vault" {
+				t.Errorf("bad key ID: %q", in.KeyId)
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"KeyId":          testKeyARN,
+				"CiphertextBlob": append([]byte("wrapped:"), in.Plaintext...),
+			})
+		case "TrentService.Decrypt":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"KeyId":     testKeyARN,
+				"Plaintext": in.CiphertextBlob[len("wrapped:"):],
+			})
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	s := NewSeal()
+	_, err := s.SetConfig(map[string]string{
+		"kms_key_id": "alias/vault",
+		"access_key": "AKIAEXAMPLE",
+		"secret_key": "secret",
+		"endpoint":   server.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Init(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if s.KeyID() != testKeyARN {
+		t.Fatalf("bad key ID: %q", s.KeyID())
+	}
+
+	plaintext := []byte("foo")
+	blob, err := s.Encrypt(context.Background(), plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if blob.KeyInfo.KeyID != testKeyARN {
+		t.Fatalf("bad key info: %#v", blob.KeyInfo)
+	}
+
+	out, err := s.Decrypt(context.Background(), blob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, plaintext) {
+		t.Fatalf("bad decrypted value: %q", out)
+	}
+}
+
+func TestAWSKMSSeal_SetConfig(t *testing.T) {
+	s := NewSeal()
+	if _, err := s.SetConfig(map[string]string{}); err == nil {
+		t.Fatal("expected error without key ID")
+	}
+}
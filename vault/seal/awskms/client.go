@@ -0,0 +1,97 @@
+package awskms
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/client/metadata"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/signer/v4"
+	"github.com/aws/aws-sdk-go/private/protocol/jsonrpc"
+)
+
+// kmsClient is a client of the two operations of the AWS KMS API that the
+// seal uses, built like the clients of the SDK
+type kmsClient struct {
+	*client.Client
+}
+
+const kmsServiceName = "kms"
+
+func newKMSClient(p client.ConfigProvider) *kmsClient {
+	c := p.ClientConfig(kmsServiceName)
+
+	svc := &kmsClient{
+		Client: client.New(
+			*c.Config,
+			metadata.ClientInfo{
+				ServiceName:   kmsServiceName,
+				SigningName:   c.SigningName,
+				SigningRegion: c.SigningRegion,
+				Endpoint:      c.Endpoint,
+				APIVersion:    "2014-11-01",
+				JSONVersion:   "1.1",
+				TargetPrefix:  "TrentService",
+			},
+			c.Handlers,
+		),
+	}
+
+	svc.Handlers.Sign.PushBackNamed(v4.SignRequestHandler)
+	svc.Handlers.Build.PushBackNamed(jsonrpc.BuildHandler)
+	svc.Handlers.Unmarshal.PushBackNamed(jsonrpc.UnmarshalHandler)
+	svc.Handlers.UnmarshalMeta.PushBackNamed(jsonrpc.UnmarshalMetaHandler)
+	svc.Handlers.UnmarshalError.PushBackNamed(jsonrpc.UnmarshalErrorHandler)
+
+	return svc
+}
+
+type encryptInput struct {
+	_ struct{} `type:"structure"`
+
+	KeyId     *string `min:"1" type:"string" required:"true"`
+	Plaintext []byte  `min:"1" type:"blob" required:"true" sensitive:"true"`
+}
+
+type encryptOutput struct {
+	_ struct{} `type:"structure"`
+
+	CiphertextBlob []byte  `min:"1" type:"blob"`
+	KeyId          *string `min:"1" type:"string"`
+}
+
+type decryptInput struct {
+	_ struct{} `type:"structure"`
+
+	CiphertextBlob []byte `min:"1" type:"blob" required:"true"`
+}
+
+type decryptOutput struct {
+	_ struct{} `type:"structure"`
+
+	KeyId     *string `min:"1" type:"string"`
+	Plaintext []byte  `min:"1" type:"blob" sensitive:"true"`
+}
+
+func (c *kmsClient) encrypt(ctx context.Context, input *encryptInput) (*encryptOutput, error) {
+	output := &encryptOutput{}
+	req := c.NewRequest(&request.Operation{
+		Name:       "Encrypt",
+		HTTPMethod: "POST",
+		HTTPPath:   "/",
+	}, input, output)
+	req.SetContext(aws.Context(ctx))
+	return output, req.Send()
+}
+
+func (c *kmsClient) decrypt(ctx context.Context, input *decryptInput) (*decryptOutput, error) {
+	output := &decryptOutput{}
+	req := c.NewRequest(&request.Operation{
+		Name:       "Decrypt",
+		HTTPMethod: "POST",
+		HTTPPath:   "/",
+	}, input, output)
+	req.SetContext(aws.Context(ctx))
+	return output, req.Send()
+}
@@ -0,0 +1,319 @@
+// Package azurekeyvault is the access to Azure Key Vault for auto seals.
+package azurekeyvault
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"github.com/Azure/go-autorest/autorest/adal"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/vault/seal"
+)
+
+const (
+	// SealType is the type of the Azure Key Vault seal
+	SealType = "azurekeyvault"
+
+	// EnvAzureKeyVaultSealVaultName is the environment variable of the name
+	// of the key vault, which takes precedence over the configuration
+	EnvAzureKeyVaultSealVaultName = "VAULT_AZUREKEYVAULT_VAULT_NAME"
+
+	// EnvAzureKeyVaultSealKeyName is the environment variable of the name of
+	// the key, which takes precedence over the configuration
+	EnvAzureKeyVaultSealKeyName = "VAULT_AZUREKEYVAULT_KEY_NAME"
+
+	defaultEnvironment = "AzurePublicCloud"
+
+	keyVaultAPIVersion = "7.0"
+
+	// mechanism is the algorithm of the key used to wrap the data keys
+	mechanism = "RSA-OAEP-256"
+)
+
+// token is the OAuth token of the Key Vault API, which is an
+// adal.ServicePrincipalToken outside of tests
+type token interface {
+	EnsureFresh() error
+	OAuthToken() string
+}
+
+// Seal is the access to an RSA key of Azure Key Vault, which wraps the data
+// keys of the values
+type Seal struct {
+	vaultURL     string
+	keyName      string
+	currentKeyID *atomic.Value
+
+	httpClient *http.Client
+	token      token
+}
+
+var _ seal.Access = (*Seal)(nil)
+
+// NewSeal returns an Azure Key Vault seal, which must be configured with
+// SetConfig
+func NewSeal() *Seal {
+	s := &Seal{
+		currentKeyID: new(atomic.Value),
+		httpClient:   cleanhttp.DefaultClient(),
+	}
+	s.currentKeyID.Store("")
+	return s
+}
+
+// SetConfig configures the seal from the parameters of the seal stanza,
+// returning the information about the seal for the server output
+func (s *Seal) SetConfig(config map[string]string) (map[string]string, error) {
+	if config == nil {
+		config = map[string]string{}
+	}
+
+	tenantID := os.Getenv("AZURE_TENANT_ID")
+	if tenantID == "" {
+		tenantID = config["tenant_id"]
+	}
+	clientID := os.Getenv("AZURE_CLIENT_ID")
+	if clientID == "" {
+		clientID = config["client_id"]
+	}
+	clientSecret := os.Getenv("AZURE_CLIENT_SECRET")
+	if clientSecret == "" {
+		clientSecret = config["client_secret"]
+	}
+	envName := os.Getenv("AZURE_ENVIRONMENT")
+	if envName == "" {
+		envName = config["environment"]
+	}
+	if envName == "" {
+		envName = defaultEnvironment
+	}
+	env, err := azure.EnvironmentFromName(envName)
+	if err != nil {
+		return nil, err
+	}
+
+	vaultName := os.Getenv(EnvAzureKeyVaultSealVaultName)
+	if vaultName == "" {
+		vaultName = config["vault_name"]
+	}
+	if vaultName == "" {
+		return nil, errors.New("'vault_name' not found for Azure Key Vault seal configuration")
+	}
+
+	s.keyName = os.Getenv(EnvAzureKeyVaultSealKeyName)
+	if s.keyName == "" {
+		s.keyName = config["key_name"]
+	}
+	if s.keyName == "" {
+		return nil, errors.New("'key_name' not found for Azure Key Vault seal configuration")
+	}
+
+	s.vaultURL = fmt.Sprintf("https://%s.%s", vaultName, env.KeyVaultDNSSuffix)
+
+	// The managed identity of the Vault server is used if no client secret
+	// is configured
+	resource := strings.TrimSuffix(env.KeyVaultEndpoint, "/")
+	if clientSecret == "" {
+		msiEndpoint, err := adal.GetMSIVMEndpoint()
+		if err != nil {
+			return nil, err
+		}
+		if clientID != "" {
+			s.token, err = adal.NewServicePrincipalTokenFromMSIWithUserAssignedID(msiEndpoint, resource, clientID)
+		} else {
+			s.token, err = adal.NewServicePrincipalTokenFromMSI(msiEndpoint, resource)
+		}
+		if err != nil {
+			return nil, errwrap.Wrapf("error creating Azure Key Vault token: {{err}}", err)
+		}
+	} else {
+		if tenantID == "" {
+			return nil, errors.New("'tenant_id' not found for Azure Key Vault seal configuration")
+		}
+		oauthConfig, err := adal.NewOAuthConfig(env.ActiveDirectoryEndpoint, tenantID)
+		if err != nil {
+			return nil, err
+		}
+		s.token, err = adal.NewServicePrincipalToken(*oauthConfig, clientID, clientSecret, resource)
+		if err != nil {
+			return nil, errwrap.Wrapf("error creating Azure Key Vault token: {{err}}", err)
+		}
+	}
+
+	return map[string]string{
+		"Azure Environment":      env.Name,
+		"Azure Key Vault URL":    s.vaultURL,
+		"Azure Key Vault Key":    s.keyName,
+		"Azure Key Vault Tenant": tenantID,
+	}, nil
+}
+
+// Init reads the current version of the key, which wraps the data keys
+func (s *Seal) Init(ctx context.Context) error {
+	if s.token == nil {
+		return errors.New("Azure Key Vault seal is not configured")
+	}
+
+	var out struct {
+		Key struct {
+			KID string `json:"kid"`
+		} `json:"key"`
+	}
+	if err := s.call(ctx, "GET", s.vaultURL+"/keys/"+s.keyName, nil, &out); err != nil {
+		return errwrap.Wrapf("error reading the Azure Key Vault key: {{err}}", err)
+	}
+	if out.Key.KID == "" {
+		return errors.New("no version of the Azure Key Vault key returned")
+	}
+	s.currentKeyID.Store(out.Key.KID)
+	return nil
+}
+
+func (s *Seal) Finalize(context.Context) error {
+	return nil
+}
+
+func (s *Seal) SealType() string {
+	return SealType
+}
+
+// KeyID returns the ID of the current version of the key
+func (s *Seal) KeyID() string {
+	return s.currentKeyID.Load().(string)
+}
+
+type keyOperation struct {
+	Algorithm string `json:"alg,omitempty"`
+	Value     string `json:"value"`
+}
+
+func (s *Seal) Encrypt(ctx context.Context, plaintext []byte) (*seal.EncryptedBlobInfo, error) {
+	// The current version of the key is read on first use if the seal was
+	// not initialized, such as when unsealing after a restart
+	if s.KeyID() == "" {
+		if err := s.Init(ctx); err != nil {
+			return nil, err
+		}
+	}
+	keyID := s.KeyID()
+
+	env, err := seal.EnvelopeEncrypt(plaintext)
+	if err != nil {
+		return nil, errwrap.Wrapf("error wrapping data: {{err}}", err)
+	}
+
+	var out keyOperation
+	in := &keyOperation{
+		Algorithm: mechanism,
+		Value:     base64.RawURLEncoding.EncodeToString(env.Key),
+	}
+	if err := s.call(ctx, "POST", keyID+"/wrapkey", in, &out); err != nil {
+		return nil, errwrap.Wrapf("error wrapping data key: {{err}}", err)
+	}
+	wrappedKey, err := base64.RawURLEncoding.DecodeString(out.Value)
+	if err != nil {
+		return nil, errwrap.Wrapf("error decoding wrapped data key: {{err}}", err)
+	}
+
+	return &seal.EncryptedBlobInfo{
+		Ciphertext: env.Ciphertext,
+		IV:         env.IV,
+		KeyInfo: &seal.KeyInfo{
+			Mechanism:  mechanism,
+			KeyID:      keyID,
+			WrappedKey: wrappedKey,
+		},
+	}, nil
+}
+
+func (s *Seal) Decrypt(ctx context.Context, in *seal.EncryptedBlobInfo) ([]byte, error) {
+	if s.token == nil {
+		return nil, errors.New("Azure Key Vault seal is not configured")
+	}
+	if in == nil || in.KeyInfo == nil || in.KeyInfo.KeyID == "" {
+		return nil, errors.New("missing key info")
+	}
+
+	// The data key is unwrapped with the version of the key that wrapped it,
+	// so that values stay readable after the key is rotated
+	var out keyOperation
+	req := &keyOperation{
+		Algorithm: mechanism,
+		Value:     base64.RawURLEncoding.EncodeToString(in.KeyInfo.WrappedKey),
+	}
+	if err := s.call(ctx, "POST", in.KeyInfo.KeyID+"/unwrapkey", req, &out); err != nil {
+		return nil, errwrap.Wrapf("error unwrapping data key: {{err}}", err)
+	}
+	key, err := base64.RawURLEncoding.DecodeString(out.Value)
+	if err != nil {
+		return nil, errwrap.Wrapf("error decoding data key: {{err}}", err)
+	}
+
+	plaintext, err := seal.EnvelopeDecrypt(&seal.EnvelopeInfo{
+		Ciphertext: in.Ciphertext,
+		Key:        key,
+		IV:         in.IV,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting data: %v", err)
+	}
+	return plaintext, nil
+}
+
+// call calls the Key Vault API on the URL of a key
+func (s *Seal) call(ctx context.Context, method, u string, in, out interface{}) error {
+	if err := s.token.EnsureFresh(); err != nil {
+		return errwrap.Wrapf("error acquiring azure token: {{err}}", err)
+	}
+
+	var body []byte
+	if in != nil {
+		var err error
+		body, err = json.Marshal(in)
+		if err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(method, u+"?api-version="+keyVaultAPIVersion, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Authorization", "Bearer "+s.token.OAuthToken())
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		msg := http.StatusText(resp.StatusCode)
+		if err := jsonutil.DecodeJSONFromReader(resp.Body, &errResp); err == nil && errResp.Error.Message != "" {
+			msg = errResp.Error.Message
+		}
+		return fmt.Errorf("azure key vault API error %d: %s", resp.StatusCode, msg)
+	}
+
+	return jsonutil.DecodeJSONFromReader(resp.Body, out)
+}
@@ -0,0 +1,94 @@
+package azurekeyvault
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+
+	"github.com/hashicorp/go-cleanhttp"
+)
+
+type testToken string
+
+func (t testToken) EnsureFresh() error {
+	return nil
+}
+
+func (t testToken) OAuthToken() string {
+	return string(t)
+}
+
+func TestAzureKeyVaultSeal(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			t.Errorf("bad authorization: %q", r.Header.Get("Authorization"))
+		}
+		if r.URL.Query().Get("api-version") != keyVaultAPIVersion {
+			t.Errorf("bad API version: %q", r.URL.RawQuery)
+		}
+
+		switch r.URL.Path {
+		case "/keys/vault-key":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"key": map[string]string{
+					"kid": server.URL + "/keys/vault-key/v1",
+				},
+			})
+		case "/keys/vault-key/v1/wrapkey", "/keys/vault-key/v1/unwrapkey":
+			var in keyOperation
+			if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+				t.Errorf("bad request: %v", err)
+			}
+			if in.Algorithm != mechanism {
+				t.Errorf("bad algorithm: %q", in.Algorithm)
+			}
+			value, err := base64.RawURLEncoding.DecodeString(in.Value)
+			if err != nil {
+				t.Errorf("bad value: %v", err)
+			}
+			// The key is wrapped by reversing it
+			for i, j := 0, len(value)-1; i < j; i, j = i+1, j-1 {
+				value[i], value[j] = value[j], value[i]
+			}
+			json.NewEncoder(w).Encode(&keyOperation{
+				Value: base64.RawURLEncoding.EncodeToString(value),
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	s := &Seal{
+		vaultURL:     server.URL,
+		keyName:      "vault-key",
+		currentKeyID: new(atomic.Value),
+		httpClient:   cleanhttp.DefaultClient(),
+		token:        testToken("test-token"),
+	}
+	s.currentKeyID.Store("")
+
+	// The current version of the key is read on first use
+	plaintext := []byte("foo")
+	blob, err := s.Encrypt(context.Background(), plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.KeyID() != server.URL+"/keys/vault-key/v1" || blob.KeyInfo.KeyID != s.KeyID() {
+		t.Fatalf("bad key ID: %q", s.KeyID())
+	}
+
+	out, err := s.Decrypt(context.Background(), blob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, plaintext) {
+		t.Fatalf("bad decrypted value: %q", out)
+	}
+}
@@ -0,0 +1,66 @@
+package seal
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"fmt"
+
+	"github.com/hashicorp/go-uuid"
+)
+
+// EnvelopeInfo is a value encrypted with a new data key, which is left to
+// be encrypted by the key management service
+type EnvelopeInfo struct {
+	Ciphertext []byte
+	Key        []byte
+	IV         []byte
+}
+
+// EnvelopeEncrypt encrypts the plaintext with a new AES-256-GCM data key
+func EnvelopeEncrypt(plaintext []byte) (*EnvelopeInfo, error) {
+	key, err := uuid.GenerateRandomBytes(32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %v", err)
+	}
+	iv, err := uuid.GenerateRandomBytes(12)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate IV: %v", err)
+	}
+
+	aead, err := envelopeAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &EnvelopeInfo{
+		Ciphertext: aead.Seal(nil, iv, plaintext, nil),
+		Key:        key,
+		IV:         iv,
+	}, nil
+}
+
+// EnvelopeDecrypt decrypts the value with its decrypted data key
+func EnvelopeDecrypt(info *EnvelopeInfo) ([]byte, error) {
+	if info == nil {
+		return nil, errors.New("missing envelope")
+	}
+
+	aead, err := envelopeAEAD(info.Key)
+	if err != nil {
+		return nil, err
+	}
+	if len(info.IV) != aead.NonceSize() {
+		return nil, errors.New("invalid IV")
+	}
+
+	return aead.Open(nil, info.IV, info.Ciphertext, nil)
+}
+
+func envelopeAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %v", err)
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,36 @@
+package seal
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEnvelope(t *testing.T) {
+	plaintext := []byte("foo")
+
+	env, err := EnvelopeEncrypt(plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(env.Ciphertext, plaintext) {
+		t.Fatal("plaintext found in ciphertext")
+	}
+
+	out, err := EnvelopeDecrypt(env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, plaintext) {
+		t.Fatalf("bad decrypted value: %q", out)
+	}
+
+	// A value cannot be decrypted with another data key
+	other, err := EnvelopeEncrypt(plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.Key = other.Key
+	if _, err := EnvelopeDecrypt(env); err == nil {
+		t.Fatal("expected error decrypting with the wrong key")
+	}
+}
@@ -0,0 +1,279 @@
+// Package gcpckms is the access to GCP Cloud KMS for auto seals.
+package gcpckms
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/vault/seal"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+const (
+	// SealType is the type of the GCP Cloud KMS seal
+	SealType = "gcpckms"
+
+	// EnvGCPCKMSSealKeyRing is the environment variable of the key ring,
+	// which takes precedence over the configuration
+	EnvGCPCKMSSealKeyRing = "VAULT_GCPCKMS_SEAL_KEY_RING"
+
+	// EnvGCPCKMSSealCryptoKey is the environment variable of the crypto key,
+	// which takes precedence over the configuration
+	EnvGCPCKMSSealCryptoKey = "VAULT_GCPCKMS_SEAL_CRYPTO_KEY"
+
+	// DefaultRegion is the location of the key ring if none is configured
+	DefaultRegion = "global"
+
+	// DefaultEndpoint is the endpoint of the Cloud KMS API
+	DefaultEndpoint = "https://cloudkms.googleapis.com"
+
+	cloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+
+	// mechanism is the mechanism recorded in the key info of the values
+	mechanism = "gcp-ckms"
+)
+
+// Seal is the access to a crypto key of GCP Cloud KMS, which encrypts the
+// data keys of the values
+type Seal struct {
+	project   string
+	region    string
+	keyRing   string
+	cryptoKey string
+	endpoint  string
+
+	client *http.Client
+}
+
+var _ seal.Access = (*Seal)(nil)
+
+// NewSeal returns a GCP Cloud KMS seal, which must be configured with
+// SetConfig
+func NewSeal() *Seal {
+	return &Seal{}
+}
+
+// SetConfig configures the seal from the parameters of the seal stanza,
+// returning the information about the seal for the server output
+func (s *Seal) SetConfig(config map[string]string) (map[string]string, error) {
+	if config == nil {
+		config = map[string]string{}
+	}
+
+	s.project = os.Getenv("GOOGLE_PROJECT")
+	if s.project == "" {
+		s.project = config["project"]
+	}
+	if s.project == "" {
+		return nil, errors.New("'project' not found for GCP Cloud KMS seal configuration")
+	}
+
+	s.region = os.Getenv("GOOGLE_REGION")
+	if s.region == "" {
+		s.region = config["region"]
+	}
+	if s.region == "" {
+		s.region = DefaultRegion
+	}
+
+	s.keyRing = os.Getenv(EnvGCPCKMSSealKeyRing)
+	if s.keyRing == "" {
+		s.keyRing = config["key_ring"]
+	}
+	if s.keyRing == "" {
+		return nil, errors.New("'key_ring' not found for GCP Cloud KMS seal configuration")
+	}
+
+	s.cryptoKey = os.Getenv(EnvGCPCKMSSealCryptoKey)
+	if s.cryptoKey == "" {
+		s.cryptoKey = config["crypto_key"]
+	}
+	if s.cryptoKey == "" {
+		return nil, errors.New("'crypto_key' not found for GCP Cloud KMS seal configuration")
+	}
+
+	s.endpoint = config["endpoint"]
+	if s.endpoint == "" {
+		s.endpoint = DefaultEndpoint
+	}
+
+	credentials := os.Getenv("GOOGLE_CREDENTIALS")
+	if credentials == "" {
+		credentials = os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	}
+	if credentials == "" {
+		credentials = config["credentials"]
+	}
+
+	client, err := newHTTPClient(credentials)
+	if err != nil {
+		return nil, errwrap.Wrapf("error creating GCP Cloud KMS client: {{err}}", err)
+	}
+	s.client = client
+
+	return map[string]string{
+		"GCP KMS Project":    s.project,
+		"GCP KMS Region":     s.region,
+		"GCP KMS Key Ring":   s.keyRing,
+		"GCP KMS Crypto Key": s.cryptoKey,
+	}, nil
+}
+
+// newHTTPClient returns an HTTP client authenticated with the credentials in
+// the given file, or with the application default credentials if none is
+// given.
+func newHTTPClient(credentials string) (*http.Client, error) {
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, cleanhttp.DefaultClient())
+	if credentials == "" {
+		return google.DefaultClient(ctx, cloudPlatformScope)
+	}
+
+	data, err := ioutil.ReadFile(credentials)
+	if err != nil {
+		return nil, errwrap.Wrapf("error reading credentials file: {{err}}", err)
+	}
+	jwtConfig, err := google.JWTConfigFromJSON(data, cloudPlatformScope)
+	if err != nil {
+		return nil, err
+	}
+	return jwtConfig.Client(ctx), nil
+}
+
+// Init checks that the crypto key can be used
+func (s *Seal) Init(ctx context.Context) error {
+	if _, err := s.Encrypt(ctx, []byte("vault")); err != nil {
+		return errwrap.Wrapf("error checking the GCP Cloud KMS key: {{err}}", err)
+	}
+	return nil
+}
+
+func (s *Seal) Finalize(context.Context) error {
+	return nil
+}
+
+func (s *Seal) SealType() string {
+	return SealType
+}
+
+// KeyID returns the resource name of the crypto key
+func (s *Seal) KeyID() string {
+	return fmt.Sprintf("projects/%s/locations/%s/keyRings/%s/cryptoKeys/%s", s.project, s.region, s.keyRing, s.cryptoKey)
+}
+
+func (s *Seal) Encrypt(ctx context.Context, plaintext []byte) (*seal.EncryptedBlobInfo, error) {
+	if s.client == nil {
+		return nil, errors.New("GCP Cloud KMS seal is not configured")
+	}
+
+	env, err := seal.EnvelopeEncrypt(plaintext)
+	if err != nil {
+		return nil, errwrap.Wrapf("error wrapping data: {{err}}", err)
+	}
+
+	var out struct {
+		Name       string `json:"name"`
+		Ciphertext []byte `json:"ciphertext"`
+	}
+	in := map[string]interface{}{
+		"plaintext": env.Key,
+	}
+	if err := s.call(ctx, s.KeyID()+":encrypt", in, &out); err != nil {
+		return nil, errwrap.Wrapf("error encrypting data key: {{err}}", err)
+	}
+
+	// The name of the response is the version of the crypto key that
+	// encrypted the data key
+	keyID := out.Name
+	if keyID == "" {
+		keyID = s.KeyID()
+	}
+
+	return &seal.EncryptedBlobInfo{
+		Ciphertext: env.Ciphertext,
+		IV:         env.IV,
+		KeyInfo: &seal.KeyInfo{
+			Mechanism:  mechanism,
+			KeyID:      keyID,
+			WrappedKey: out.Ciphertext,
+		},
+	}, nil
+}
+
+func (s *Seal) Decrypt(ctx context.Context, in *seal.EncryptedBlobInfo) ([]byte, error) {
+	if s.client == nil {
+		return nil, errors.New("GCP Cloud KMS seal is not configured")
+	}
+	if in == nil || in.KeyInfo == nil {
+		return nil, errors.New("missing key info")
+	}
+
+	// Decryption is done with the crypto key rather than the version that
+	// encrypted, since Cloud KMS finds the version from the ciphertext
+	var out struct {
+		Plaintext []byte `json:"plaintext"`
+	}
+	req := map[string]interface{}{
+		"ciphertext": in.KeyInfo.WrappedKey,
+	}
+	if err := s.call(ctx, s.KeyID()+":decrypt", req, &out); err != nil {
+		return nil, errwrap.Wrapf("error decrypting data key: {{err}}", err)
+	}
+
+	plaintext, err := seal.EnvelopeDecrypt(&seal.EnvelopeInfo{
+		Ciphertext: in.Ciphertext,
+		Key:        out.Plaintext,
+		IV:         in.IV,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting data: %v", err)
+	}
+	return plaintext, nil
+}
+
+// call calls a method of the Cloud KMS API on the resource
+func (s *Seal) call(ctx context.Context, resource string, in, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+
+	u := strings.TrimSuffix(s.endpoint, "/") + "/v1/" + resource
+	req, err := http.NewRequest("POST", u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		msg := http.StatusText(resp.StatusCode)
+		if err := jsonutil.DecodeJSONFromReader(resp.Body, &errResp); err == nil && errResp.Error.Message != "" {
+			msg = errResp.Error.Message
+		}
+		return fmt.Errorf("cloud KMS API error %d: %s", resp.StatusCode, msg)
+	}
+
+	return jsonutil.DecodeJSONFromReader(resp.Body, out)
+}
@@ -0,0 +1,78 @@
+package gcpckms
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestGCPCKMSSeal(t *testing.T) {
+	keyName := "projects/vault-project/locations/global/keyRings/vault-keyring/cryptoKeys/vault-key"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var in struct {
+			Plaintext  []byte `json:"plaintext"`
+			Ciphertext []byte `json:"ciphertext"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			t.Errorf("bad request: %v", err)
+		}
+
+		switch r.URL.Path {
+		case "/v1/" + keyName + ":encrypt":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"name":       keyName + "/cryptoKeyVersions/1",
+				"ciphertext": append([]byte("wrapped:"), in.Plaintext...),
+			})
+		case "/v1/" + keyName + ":decrypt":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"plaintext": in.Ciphertext[len("wrapped:"):],
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": {"message": "key not found"}}`))
+		}
+	}))
+	defer server.Close()
+
+	s := &Seal{
+		project:   "vault-project",
+		region:    "global",
+		keyRing:   "vault-keyring",
+		cryptoKey: "vault-key",
+		endpoint:  server.URL,
+		client:    server.Client(),
+	}
+	if s.KeyID() != keyName {
+		t.Fatalf("bad key ID: %q", s.KeyID())
+	}
+	if err := s.Init(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	plaintext := []byte("foo")
+	blob, err := s.Encrypt(context.Background(), plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if blob.KeyInfo.KeyID != keyName+"/cryptoKeyVersions/1" {
+		t.Fatalf("bad key info: %#v", blob.KeyInfo)
+	}
+
+	out, err := s.Decrypt(context.Background(), blob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, plaintext) {
+		t.Fatalf("bad decrypted value: %q", out)
+	}
+
+	// Errors of the API are returned
+	s.cryptoKey = "missing"
+	if _, err := s.Encrypt(context.Background(), plaintext); err == nil {
+		t.Fatal("expected error encrypting with a missing key")
+	}
+}
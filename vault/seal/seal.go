@@ -0,0 +1,48 @@
+// Package seal contains the access to the key management services that auto
+// seals use to encrypt the keys of Vault.
+package seal
+
+import (
+	"context"
+)
+
+// Access is the access to a key management service, which is used by auto
+// seals to encrypt the stored barrier keys and the recovery key so that Vault
+// can be unsealed without operators.
+type Access interface {
+	// SealType is the type of the seal, matching the type of the seal stanza
+	// of the server configuration
+	SealType() string
+
+	// KeyID is the ID of the key currently used to encrypt
+	KeyID() string
+
+	Init(context.Context) error
+	Finalize(context.Context) error
+
+	Encrypt(context.Context, []byte) (*EncryptedBlobInfo, error)
+	Decrypt(context.Context, *EncryptedBlobInfo) ([]byte, error)
+}
+
+// EncryptedBlobInfo is a value encrypted by a key management service. The
+// value is encrypted locally with a data key, which is itself encrypted by the
+// key management service.
+type EncryptedBlobInfo struct {
+	Ciphertext []byte   `json:"ciphertext"`
+	IV         []byte   `json:"iv"`
+	KeyInfo    *KeyInfo `json:"key_info"`
+}
+
+// KeyInfo describes the key that encrypted the data key of a value
+type KeyInfo struct {
+	// Mechanism is the mechanism of the key management service, such as the
+	// algorithm of the key
+	Mechanism string `json:"mechanism,omitempty"`
+
+	// KeyID is the ID of the key, which is needed by some key management
+	// services to decrypt
+	KeyID string `json:"key_id"`
+
+	// WrappedKey is the encrypted data key
+	WrappedKey []byte `json:"wrapped_key"`
+}
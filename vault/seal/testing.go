@@ -0,0 +1,86 @@
+package seal
+
+import (
+	"context"
+	"errors"
+)
+
+// TestSeal is an Access for tests, which encrypts the data keys by reversing
+// them
+type TestSeal struct {
+	Type  string
+	KeyId string
+
+	// Err is returned by Encrypt and Decrypt if set, to simulate an
+	// unavailable key management service
+	Err error
+}
+
+var _ Access = (*TestSeal)(nil)
+
+// NewTestSeal returns an Access for tests of the given seal type
+func NewTestSeal(sealType string) *TestSeal {
+	return &TestSeal{
+		Type:  sealType,
+		KeyId: "static-key",
+	}
+}
+
+func (t *TestSeal) SealType() string {
+	return t.Type
+}
+
+func (t *TestSeal) KeyID() string {
+	return t.KeyId
+}
+
+func (t *TestSeal) Init(context.Context) error {
+	return nil
+}
+
+func (t *TestSeal) Finalize(context.Context) error {
+	return nil
+}
+
+func (t *TestSeal) Encrypt(ctx context.Context, plaintext []byte) (*EncryptedBlobInfo, error) {
+	if t.Err != nil {
+		return nil, t.Err
+	}
+
+	env, err := EnvelopeEncrypt(plaintext)
+	if err != nil {
+		return nil, err
+	}
+
+	return &EncryptedBlobInfo{
+		Ciphertext: env.Ciphertext,
+		IV:         env.IV,
+		KeyInfo: &KeyInfo{
+			KeyID:      t.KeyId,
+			WrappedKey: reverse(env.Key),
+		},
+	}, nil
+}
+
+func (t *TestSeal) Decrypt(ctx context.Context, in *EncryptedBlobInfo) ([]byte, error) {
+	if t.Err != nil {
+		return nil, t.Err
+	}
+	if in == nil || in.KeyInfo == nil {
+		return nil, errors.New("missing key info")
+	}
+
+	return EnvelopeDecrypt(&EnvelopeInfo{
+		Ciphertext: in.Ciphertext,
+		Key:        reverse(in.KeyInfo.WrappedKey),
+		IV:         in.IV,
+	})
+}
+
+func reverse(in []byte) []byte {
+	out := make([]byte, len(in))
+	for i, b := range in {
+		out[len(in)-1-i] = b
+	}
+	return out
+}
@@ -0,0 +1,324 @@
+// Package transit is the access to the transit secrets engine of another
+// Vault for auto seals.
+package transit
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+
+	log "github.com/mgutz/logxi/v1"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/vault/seal"
+)
+
+const (
+	// SealType is the type of the transit seal
+	SealType = "transit"
+
+	// EnvTransitSealKeyName is the environment variable of the name of the
+	// transit key, which takes precedence over the configuration
+	EnvTransitSealKeyName = "VAULT_TRANSIT_SEAL_KEY_NAME"
+
+	// EnvTransitSealMountPath is the environment variable of the mount path
+	// of the transit secrets engine, which takes precedence over the
+	// configuration
+	EnvTransitSealMountPath = "VAULT_TRANSIT_SEAL_MOUNT_PATH"
+
+	// EnvTransitSealDisableRenewal is the environment variable disabling the
+	// renewal of the token, which takes precedence over the configuration
+	EnvTransitSealDisableRenewal = "VAULT_TRANSIT_SEAL_DISABLE_RENEWAL"
+
+	// DefaultMountPath is the mount path of the transit secrets engine if
+	// none is configured
+	DefaultMountPath = "transit/"
+
+	// mechanism is the mechanism recorded in the key info of the values
+	mechanism = "vault-transit"
+)
+
+// Seal is the access to a key of the transit secrets engine of another
+// Vault, which encrypts the data keys of the values
+type Seal struct {
+	logger log.Logger
+
+	client    *api.Client
+	mountPath string
+	keyName   string
+	renew     bool
+
+	renewerLock sync.Mutex
+	renewer     *api.LifetimeWatcher
+}
+
+var _ seal.Access = (*Seal)(nil)
+
+// NewSeal returns a transit seal, which must be configured with SetConfig
+func NewSeal(logger log.Logger) *Seal {
+	return &Seal{
+		logger: logger,
+	}
+}
+
+// SetConfig configures the seal from the parameters of the seal stanza,
+// returning the information about the seal for the server output. The
+// address, token, namespace and TLS parameters may also be set by the
+// environment variables of the Vault API client, which take precedence.
+func (s *Seal) SetConfig(config map[string]string) (map[string]string, error) {
+	if config == nil {
+		config = map[string]string{}
+	}
+
+	s.keyName = os.Getenv(EnvTransitSealKeyName)
+	if s.keyName == "" {
+		s.keyName = config["key_name"]
+	}
+	if s.keyName == "" {
+		return nil, errors.New("'key_name' not found for transit seal configuration")
+	}
+
+	s.mountPath = os.Getenv(EnvTransitSealMountPath)
+	if s.mountPath == "" {
+		s.mountPath = config["mount_path"]
+	}
+	if s.mountPath == "" {
+		s.mountPath = DefaultMountPath
+	}
+	s.mountPath = strings.Trim(s.mountPath, "/") + "/"
+
+	disableRenewal := os.Getenv(EnvTransitSealDisableRenewal)
+	if disableRenewal == "" {
+		disableRenewal = config["disable_renewal"]
+	}
+	s.renew = true
+	if disableRenewal != "" {
+		disabled, err := strconv.ParseBool(disableRenewal)
+		if err != nil {
+			return nil, errwrap.Wrapf("failed parsing 'disable_renewal' parameter: {{err}}", err)
+		}
+		s.renew = !disabled
+	}
+
+	apiConfig := api.DefaultConfig()
+	if apiConfig.Error != nil {
+		return nil, apiConfig.Error
+	}
+	if address := envOrConfig(api.EnvVaultAddress, config, "address"); address != "" {
+		apiConfig.Address = address
+	}
+
+	// The TLS configuration of DefaultConfig only reads the environment, so
+	// the parameters of the configuration are merged in
+	tlsConfig := &api.TLSConfig{
+		CACert:        envOrConfig(api.EnvVaultCACert, config, "tls_ca_cert"),
+		ClientCert:    envOrConfig(api.EnvVaultClientCert, config, "tls_client_cert"),
+		ClientKey:     envOrConfig(api.EnvVaultClientKey, config, "tls_client_key"),
+		TLSServerName: envOrConfig(api.EnvVaultTLSServerName, config, "tls_server_name"),
+	}
+	if v := envOrConfig(api.EnvVaultInsecure, config, "tls_skip_verify"); v != "" {
+		skip, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, errwrap.Wrapf("failed parsing 'tls_skip_verify' parameter: {{err}}", err)
+		}
+		tlsConfig.Insecure = skip
+	}
+	if err := apiConfig.ConfigureTLS(tlsConfig); err != nil {
+		return nil, err
+	}
+
+	client, err := api.NewClient(apiConfig)
+	if err != nil {
+		return nil, err
+	}
+	if os.Getenv(api.EnvVaultToken) == "" && config["token"] != "" {
+		client.SetToken(config["token"])
+	}
+	if client.Token() == "" {
+		return nil, errors.New("missing token for transit seal configuration")
+	}
+	if os.Getenv(api.EnvVaultNamespace) == "" && config["namespace"] != "" {
+		client.SetNamespace(config["namespace"])
+	}
+	s.client = client
+
+	info := map[string]string{
+		"Transit Address":    client.Address(),
+		"Transit Mount Path": s.mountPath,
+		"Transit Key Name":   s.keyName,
+	}
+	if ns := client.Namespace(); ns != "" {
+		info["Transit Namespace"] = ns
+	}
+	return info, nil
+}
+
+// Init checks that the key can be used, and starts renewing the token unless
+// renewal is disabled
+func (s *Seal) Init(ctx context.Context) error {
+	if _, err := s.Encrypt(ctx, []byte("vault")); err != nil {
+		return errwrap.Wrapf("error checking the transit key: {{err}}", err)
+	}
+
+	if !s.renew {
+		return nil
+	}
+
+	s.renewerLock.Lock()
+	defer s.renewerLock.Unlock()
+	if s.renewer != nil {
+		return nil
+	}
+
+	secret, err := s.client.Auth().Token().LookupSelf()
+	if err != nil {
+		return errwrap.Wrapf("error looking up the transit token: {{err}}", err)
+	}
+	renewable, err := secret.TokenIsRenewable()
+	if err != nil {
+		return err
+	}
+	if !renewable {
+		return nil
+	}
+
+	// Renewing returns the token as auth, which the renewer needs
+	secret, err = s.client.Auth().Token().RenewSelf(0)
+	if err != nil {
+		return errwrap.Wrapf("error renewing the transit token: {{err}}", err)
+	}
+	renewer, err := s.client.NewLifetimeWatcher(&api.LifetimeWatcherInput{
+		Secret: secret,
+	})
+	if err != nil {
+		return err
+	}
+	s.renewer = renewer
+
+	go renewer.Start()
+	go func() {
+		for {
+			select {
+			case err := <-renewer.DoneCh():
+				if err != nil && s.logger != nil {
+					s.logger.Error("transit seal: error renewing token", "error", err)
+				}
+				return
+			case <-renewer.RenewCh():
+				if s.logger != nil {
+					s.logger.Trace("transit seal: renewed token")
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Finalize stops renewing the token
+func (s *Seal) Finalize(context.Context) error {
+	s.renewerLock.Lock()
+	defer s.renewerLock.Unlock()
+	if s.renewer != nil {
+		s.renewer.Stop()
+		s.renewer = nil
+	}
+	return nil
+}
+
+func (s *Seal) SealType() string {
+	return SealType
+}
+
+// KeyID returns the path of the transit key
+func (s *Seal) KeyID() string {
+	return s.mountPath + "keys/" + s.keyName
+}
+
+func (s *Seal) Encrypt(ctx context.Context, plaintext []byte) (*seal.EncryptedBlobInfo, error) {
+	if s.client == nil {
+		return nil, errors.New("transit seal is not configured")
+	}
+
+	env, err := seal.EnvelopeEncrypt(plaintext)
+	if err != nil {
+		return nil, errwrap.Wrapf("error wrapping data: {{err}}", err)
+	}
+
+	secret, err := s.client.Logical().Write(path.Join(s.mountPath, "encrypt", s.keyName), map[string]interface{}{
+		"plaintext": base64.StdEncoding.EncodeToString(env.Key),
+	})
+	if err != nil {
+		return nil, errwrap.Wrapf("error encrypting data key: {{err}}", err)
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, errors.New("no response encrypting data key")
+	}
+	ciphertext, ok := secret.Data["ciphertext"].(string)
+	if !ok || ciphertext == "" {
+		return nil, errors.New("no ciphertext returned encrypting data key")
+	}
+
+	return &seal.EncryptedBlobInfo{
+		Ciphertext: env.Ciphertext,
+		IV:         env.IV,
+		KeyInfo: &seal.KeyInfo{
+			Mechanism:  mechanism,
+			KeyID:      s.KeyID(),
+			WrappedKey: []byte(ciphertext),
+		},
+	}, nil
+}
+
+func (s *Seal) Decrypt(ctx context.Context, in *seal.EncryptedBlobInfo) ([]byte, error) {
+	if s.client == nil {
+		return nil, errors.New("transit seal is not configured")
+	}
+	if in == nil || in.KeyInfo == nil {
+		return nil, errors.New("missing key info")
+	}
+
+	secret, err := s.client.Logical().Write(path.Join(s.mountPath, "decrypt", s.keyName), map[string]interface{}{
+		"ciphertext": string(in.KeyInfo.WrappedKey),
+	})
+	if err != nil {
+		return nil, errwrap.Wrapf("error decrypting data key: {{err}}", err)
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, errors.New("no response decrypting data key")
+	}
+	encoded, ok := secret.Data["plaintext"].(string)
+	if !ok {
+		return nil, errors.New("no plaintext returned decrypting data key")
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errwrap.Wrapf("error decoding data key: {{err}}", err)
+	}
+
+	plaintext, err := seal.EnvelopeDecrypt(&seal.EnvelopeInfo{
+		Ciphertext: in.Ciphertext,
+		Key:        key,
+		IV:         in.IV,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting data: %v", err)
+	}
+	return plaintext, nil
+}
+
+// envOrConfig returns the value of the environment variable, or the parameter
+// of the configuration if the variable is not set
+func envOrConfig(env string, config map[string]string, key string) string {
+	if v := os.Getenv(env); v != "" {
+		return v
+	}
+	return config[key]
+}
@@ -0,0 +1,133 @@
+package transit
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/builtin/logical/transit"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/vault"
+
+	vaulthttp "github.com/hashicorp/vault/http"
+	logxi "github.com/mgutz/logxi/v1"
+)
+
+func testTransitSeal(t *testing.T) (*Seal, func()) {
+	cluster := vault.NewTestCluster(t, &vault.CoreConfig{
+		DisableMlock: true,
+		DisableCache: true,
+		Logger:       logxi.NullLog,
+		LogicalBackends: map[string]logical.Factory{
+			"transit": transit.Factory,
+		},
+	}, &vault.TestClusterOptions{
+		HandlerFunc: vaulthttp.Handler,
+	})
+	cluster.Start()
+	vault.TestWaitActive(t, cluster.Cores[0].Core)
+
+	client := cluster.Cores[0].Client
+	client.SetToken(cluster.RootToken)
+	if err := client.Sys().Mount("seal-transit", &api.MountInput{Type: "transit"}); err != nil {
+		cluster.Cleanup()
+		t.Fatal(err)
+	}
+	if _, err := client.Logical().Write("seal-transit/keys/unseal", nil); err != nil {
+		cluster.Cleanup()
+		t.Fatal(err)
+	}
+
+	s := NewSeal(logxi.NullLog)
+	s.client = client
+	s.mountPath = "seal-transit/"
+	s.keyName = "unseal"
+	return s, cluster.Cleanup
+}
+
+func TestTransitSeal(t *testing.T) {
+	s, cleanup := testTransitSeal(t)
+	defer cleanup()
+
+	if err := s.Init(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	plaintext := []byte("foo")
+	blob, err := s.Encrypt(context.Background(), plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if blob.KeyInfo.KeyID != "seal-transit/keys/unseal" {
+		t.Fatalf("bad key info: %#v", blob.KeyInfo)
+	}
+
+	out, err := s.Decrypt(context.Background(), blob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, plaintext) {
+		t.Fatalf("bad decrypted value: %q", out)
+	}
+
+	// Values are not readable with another key
+	s.keyName = "other"
+	if _, err := s.Decrypt(context.Background(), blob); err == nil {
+		t.Fatal("expected error decrypting with another key")
+	}
+}
+
+func TestTransitSeal_AutoUnseal(t *testing.T) {
+	s, cleanup := testTransitSeal(t)
+	defer cleanup()
+
+	// A second Vault is unsealed with the transit key of the first one
+	core := vault.TestCoreWithSeal(t, vault.NewAutoSeal(s), false)
+	_, err := core.Initialize(context.Background(), &vault.InitParams{
+		BarrierConfig: &vault.SealConfig{
+			SecretShares:    1,
+			SecretThreshold: 1,
+			StoredShares:    1,
+		},
+		RecoveryConfig: &vault.SealConfig{
+			SecretShares:    1,
+			SecretThreshold: 1,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Finalize(context.Background())
+
+	if err := core.UnsealWithStoredKeys(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if sealed, _ := core.Sealed(); sealed {
+		t.Fatal("should not be sealed")
+	}
+}
+
+func TestTransitSeal_SetConfig(t *testing.T) {
+	s := NewSeal(logxi.NullLog)
+	if _, err := s.SetConfig(map[string]string{"token": "foo"}); err == nil {
+		t.Fatal("expected error without key name")
+	}
+
+	info, err := s.SetConfig(map[string]string{
+		"address":    "https://vault.example.com:8200",
+		"token":      "foo",
+		"key_name":   "unseal",
+		"mount_path": "/seal-transit",
+		"namespace":  "ns1/",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.KeyID() != "seal-transit/keys/unseal" {
+		t.Fatalf("bad key ID: %q", s.KeyID())
+	}
+	if info["Transit Namespace"] != "ns1/" {
+		t.Fatalf("bad info: %#v", info)
+	}
+}
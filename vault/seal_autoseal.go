@@ -0,0 +1,340 @@
+package vault
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/physical"
+	"github.com/hashicorp/vault/vault/seal"
+)
+
+// autoSeal is a Seal that encrypts the stored barrier keys and the recovery
+// key with a key management service, so that Vault can be unsealed without
+// operators providing key shares. The recovery key replaces the unseal keys
+// for operations that need the authorization of operators.
+type autoSeal struct {
+	seal.Access
+
+	barrierConfig  *SealConfig
+	recoveryConfig *SealConfig
+	core           *Core
+}
+
+var _ Seal = (*autoSeal)(nil)
+
+// NewAutoSeal returns a Seal that uses the key management service of the
+// access to protect the keys of Vault
+func NewAutoSeal(access seal.Access) Seal {
+	return &autoSeal{
+		Access: access,
+	}
+}
+
+// AutoSealAccess returns the access to the key management service of the seal, or
+// nil if the seal is not an auto seal
+func AutoSealAccess(s Seal) seal.Access {
+	if d, ok := s.(*autoSeal); ok {
+		return d.Access
+	}
+	return nil
+}
+
+func (d *autoSeal) checkCore() error {
+	if d.core == nil {
+		return fmt.Errorf("seal does not have a core set")
+	}
+	return nil
+}
+
+func (d *autoSeal) SetCore(core *Core) {
+	d.core = core
+}
+
+func (d *autoSeal) Init(ctx context.Context) error {
+	return d.Access.Init(ctx)
+}
+
+func (d *autoSeal) Finalize(ctx context.Context) error {
+	return d.Access.Finalize(ctx)
+}
+
+func (d *autoSeal) BarrierType() string {
+	return d.SealType()
+}
+
+func (d *autoSeal) StoredKeysSupported() bool {
+	return true
+}
+
+func (d *autoSeal) RecoveryKeySupported() bool {
+	return true
+}
+
+// SetStoredKeys encrypts the keys with the seal and stores them
+func (d *autoSeal) SetStoredKeys(ctx context.Context, keys [][]byte) error {
+	if err := d.checkCore(); err != nil {
+		return err
+	}
+	if keys == nil {
+		return fmt.Errorf("keys were nil")
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("zero keys provided for storage")
+	}
+
+	buf, err := json.Marshal(keys)
+	if err != nil {
+		return fmt.Errorf("failed to encode keys for storage: %v", err)
+	}
+
+	if err := d.putEncrypted(ctx, storedBarrierKeysPath, buf); err != nil {
+		d.core.logger.Error("core: failed to write stored keys", "error", err)
+		return fmt.Errorf("failed to write stored keys: %v", err)
+	}
+
+	return nil
+}
+
+// GetStoredKeys reads the stored keys and decrypts them with the seal
+func (d *autoSeal) GetStoredKeys(ctx context.Context) ([][]byte, error) {
+	if err := d.checkCore(); err != nil {
+		return nil, err
+	}
+
+	pt, err := d.getEncrypted(ctx, storedBarrierKeysPath)
+	if err != nil {
+		d.core.logger.Error("core: failed to read stored keys", "error", err)
+		return nil, fmt.Errorf("failed to read stored keys: %v", err)
+	}
+	if pt == nil {
+		return nil, &KeyNotFoundError{Err: fmt.Errorf("no stored keys found")}
+	}
+
+	var keys [][]byte
+	if err := jsonutil.DecodeJSON(pt, &keys); err != nil {
+		return nil, fmt.Errorf("failed to decode stored keys: %v", err)
+	}
+	return keys, nil
+}
+
+func (d *autoSeal) BarrierConfig(ctx context.Context) (*SealConfig, error) {
+	if d.barrierConfig != nil {
+		return d.barrierConfig.Clone(), nil
+	}
+
+	if err := d.checkCore(); err != nil {
+		return nil, err
+	}
+
+	conf, err := d.readConfig(ctx, barrierSealConfigPath, d.BarrierType())
+	if err != nil {
+		return nil, err
+	}
+	if conf == nil {
+		d.core.logger.Info("core: seal configuration missing, not initialized")
+		return nil, nil
+	}
+
+	d.barrierConfig = conf
+	return d.barrierConfig.Clone(), nil
+}
+
+func (d *autoSeal) SetBarrierConfig(ctx context.Context, config *SealConfig) error {
+	if err := d.checkCore(); err != nil {
+		return err
+	}
+
+	// Provide a way to wipe out the cached value (also prevents actually
+	// saving a nil config)
+	if config == nil {
+		d.barrierConfig = nil
+		return nil
+	}
+
+	config.Type = d.BarrierType()
+	if err := d.writeConfig(ctx, barrierSealConfigPath, config); err != nil {
+		return err
+	}
+
+	d.barrierConfig = config.Clone()
+	return nil
+}
+
+func (d *autoSeal) RecoveryType() string {
+	return RecoveryTypeShamir
+}
+
+func (d *autoSeal) RecoveryConfig(ctx context.Context) (*SealConfig, error) {
+	if d.recoveryConfig != nil {
+		return d.recoveryConfig.Clone(), nil
+	}
+
+	if err := d.checkCore(); err != nil {
+		return nil, err
+	}
+
+	conf, err := d.readConfig(ctx, recoverySealConfigPlaintextPath, d.RecoveryType())
+	if err != nil {
+		return nil, err
+	}
+	if conf == nil {
+		d.core.logger.Info("core: recovery seal configuration missing")
+		return nil, nil
+	}
+
+	d.recoveryConfig = conf
+	return d.recoveryConfig.Clone(), nil
+}
+
+func (d *autoSeal) SetRecoveryConfig(ctx context.Context, config *SealConfig) error {
+	if err := d.checkCore(); err != nil {
+		return err
+	}
+
+	if config == nil {
+		d.recoveryConfig = nil
+		return nil
+	}
+
+	config.Type = d.RecoveryType()
+	if err := d.writeConfig(ctx, recoverySealConfigPlaintextPath, config); err != nil {
+		return err
+	}
+
+	d.recoveryConfig = config.Clone()
+	return nil
+}
+
+// SetRecoveryKey encrypts the recovery key with the seal and stores it
+func (d *autoSeal) SetRecoveryKey(ctx context.Context, key []byte) error {
+	if err := d.checkCore(); err != nil {
+		return err
+	}
+	if key == nil {
+		return fmt.Errorf("recovery key to store is empty")
+	}
+
+	if err := d.putEncrypted(ctx, recoveryKeyPath, key); err != nil {
+		d.core.logger.Error("core: failed to write recovery key", "error", err)
+		return fmt.Errorf("failed to write recovery key: %v", err)
+	}
+
+	return nil
+}
+
+// VerifyRecoveryKey checks the key against the stored recovery key
+func (d *autoSeal) VerifyRecoveryKey(ctx context.Context, key []byte) error {
+	if err := d.checkCore(); err != nil {
+		return err
+	}
+	if len(key) == 0 {
+		return fmt.Errorf("recovery key to verify is empty")
+	}
+
+	pt, err := d.getEncrypted(ctx, recoveryKeyPath)
+	if err != nil {
+		d.core.logger.Error("core: failed to read recovery key", "error", err)
+		return fmt.Errorf("failed to read recovery key: %v", err)
+	}
+	if pt == nil {
+		return fmt.Errorf("no recovery key found")
+	}
+
+	if subtle.ConstantTimeCompare(key, pt) != 1 {
+		return fmt.Errorf("recovery key does not match")
+	}
+	return nil
+}
+
+// readConfig reads a plaintext seal configuration, which must be of the
+// given type
+func (d *autoSeal) readConfig(ctx context.Context, path, sealType string) (*SealConfig, error) {
+	pe, err := d.core.physical.Get(ctx, path)
+	if err != nil {
+		d.core.logger.Error("core: failed to read seal configuration", "path", path, "error", err)
+		return nil, fmt.Errorf("failed to check seal configuration: %v", err)
+	}
+	if pe == nil {
+		return nil, nil
+	}
+
+	var conf SealConfig
+	if err := jsonutil.DecodeJSON(pe.Value, &conf); err != nil {
+		d.core.logger.Error("core: failed to decode seal configuration", "path", path, "error", err)
+		return nil, fmt.Errorf("failed to decode seal configuration: %v", err)
+	}
+
+	if conf.Type != sealType {
+		d.core.logger.Error("core: seal type does not match loaded type", "seal_type", conf.Type, "loaded_seal_type", sealType)
+		return nil, fmt.Errorf("seal type of %s does not match loaded type of %s", conf.Type, sealType)
+	}
+
+	if err := conf.Validate(); err != nil {
+		d.core.logger.Error("core: invalid seal configuration", "path", path, "error", err)
+		return nil, fmt.Errorf("seal validation failed: %v", err)
+	}
+
+	return &conf, nil
+}
+
+func (d *autoSeal) writeConfig(ctx context.Context, path string, config *SealConfig) error {
+	buf, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to encode seal configuration: %v", err)
+	}
+
+	pe := &physical.Entry{
+		Key:   path,
+		Value: buf,
+	}
+	if err := d.core.physical.Put(ctx, pe); err != nil {
+		d.core.logger.Error("core: failed to write seal configuration", "path", path, "error", err)
+		return fmt.Errorf("failed to write seal configuration: %v", err)
+	}
+	return nil
+}
+
+// putEncrypted encrypts the value with the seal and stores it outside of the
+// barrier, since it must be readable while Vault is sealed
+func (d *autoSeal) putEncrypted(ctx context.Context, path string, pt []byte) error {
+	blobInfo, err := d.Encrypt(ctx, pt)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt value: %v", err)
+	}
+
+	buf, err := json.Marshal(blobInfo)
+	if err != nil {
+		return fmt.Errorf("failed to encode value: %v", err)
+	}
+
+	return d.core.physical.Put(ctx, &physical.Entry{
+		Key:   path,
+		Value: buf,
+	})
+}
+
+// getEncrypted reads a value stored by putEncrypted and decrypts it with the
+// seal, returning nil if the value does not exist
+func (d *autoSeal) getEncrypted(ctx context.Context, path string) ([]byte, error) {
+	pe, err := d.core.physical.Get(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	if pe == nil {
+		return nil, nil
+	}
+
+	blobInfo := &seal.EncryptedBlobInfo{}
+	if err := jsonutil.DecodeJSON(pe.Value, blobInfo); err != nil {
+		return nil, fmt.Errorf("failed to decode value: %v", err)
+	}
+
+	pt, err := d.Decrypt(ctx, blobInfo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt value: %v", err)
+	}
+	return pt, nil
+}
@@ -0,0 +1,133 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/vault/seal"
+)
+
+func testCoreAutoSeal(t *testing.T) (*Core, *seal.TestSeal, *InitResult) {
+	access := seal.NewTestSeal(SealTypeTest)
+	core := TestCoreWithSeal(t, NewAutoSeal(access), false)
+
+	result, err := core.Initialize(context.Background(), &InitParams{
+		BarrierConfig: &SealConfig{
+			SecretShares:    1,
+			SecretThreshold: 1,
+			StoredShares:    1,
+		},
+		RecoveryConfig: &SealConfig{
+			SecretShares:    3,
+			SecretThreshold: 2,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.SecretShares) != 0 {
+		t.Fatalf("expected no unseal keys to be returned, got %d", len(result.SecretShares))
+	}
+	if len(result.RecoveryShares) != 3 {
+		t.Fatalf("expected 3 recovery keys, got %d", len(result.RecoveryShares))
+	}
+
+	return core, access, result
+}
+
+func TestAutoSeal_UnsealWithStoredKeys(t *testing.T) {
+	core, _, result := testCoreAutoSeal(t)
+
+	if err := core.UnsealWithStoredKeys(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if sealed, _ := core.Sealed(); sealed {
+		t.Fatal("should not be sealed")
+	}
+
+	if err := core.Seal(result.RootToken); err != nil {
+		t.Fatal(err)
+	}
+	if sealed, _ := core.Sealed(); !sealed {
+		t.Fatal("should be sealed")
+	}
+
+	// The stored keys are read from storage again, as after a restart
+	core.seal.SetBarrierConfig(context.Background(), nil)
+	if err := core.UnsealWithStoredKeys(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if sealed, _ := core.Sealed(); sealed {
+		t.Fatal("should not be sealed")
+	}
+}
+
+func TestAutoSeal_StoredKeysEncrypted(t *testing.T) {
+	core, access, _ := testCoreAutoSeal(t)
+
+	// The stored keys must not be readable without the key management service
+	access.Err = errors.New("kms unavailable")
+	err := core.UnsealWithStoredKeys(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "kms unavailable") {
+		t.Fatalf("expected error from the seal, got %v", err)
+	}
+	if sealed, _ := core.Sealed(); !sealed {
+		t.Fatal("should be sealed")
+	}
+
+	access.Err = nil
+	if err := core.UnsealWithStoredKeys(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if sealed, _ := core.Sealed(); sealed {
+		t.Fatal("should not be sealed")
+	}
+}
+
+func TestAutoSeal_RecoveryKey(t *testing.T) {
+	core, _, result := testCoreAutoSeal(t)
+	if err := core.UnsealWithStoredKeys(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	conf, err := core.seal.RecoveryConfig(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.Type != RecoveryTypeShamir || conf.SecretShares != 3 || conf.SecretThreshold != 2 {
+		t.Fatalf("bad recovery config: %#v", conf)
+	}
+
+	// Sealed Vaults are unsealed with the recovery keys when the stored keys
+	// are not used
+	if err := core.Seal(result.RootToken); err != nil {
+		t.Fatal(err)
+	}
+	for i, key := range result.RecoveryShares[:2] {
+		unsealed, err := core.UnsealWithRecoveryKeys(context.Background(), TestKeyCopy(key))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if unsealed != (i == 1) {
+			t.Fatalf("bad unseal state after %d recovery keys: %v", i+1, unsealed)
+		}
+	}
+
+	if err := core.seal.VerifyRecoveryKey(context.Background(), []byte("not the recovery key")); err == nil {
+		t.Fatal("expected error verifying a wrong recovery key")
+	}
+}
+
+func TestAutoSeal_BarrierConfigType(t *testing.T) {
+	core, _, _ := testCoreAutoSeal(t)
+
+	// A Vault initialized with one seal cannot be loaded with another
+	other := NewAutoSeal(seal.NewTestSeal("other-auto"))
+	other.SetCore(core)
+	_, err := other.BarrierConfig(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Fatalf("expected seal type mismatch, got %v", err)
+	}
+}
@@ -0,0 +1,305 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/physical"
+	"github.com/hashicorp/vault/shamir"
+)
+
+// ErrSealMigrationRequired is returned when unsealing without migrating a
+// Vault whose seal type differs from the configured seal
+var ErrSealMigrationRequired = errors.New("seal migration is pending; unseal with the migrate option to migrate to the configured seal")
+
+// CheckSealMigration checks for a pending seal migration if the storage
+// could not be read to check for it when the core was created
+func (c *Core) CheckSealMigration(ctx context.Context) error {
+	c.sealMigrationLock.Lock()
+	defer c.sealMigrationLock.Unlock()
+	if c.sealMigrationChecked {
+		return nil
+	}
+	pe, err := c.physical.Get(ctx, barrierSealConfigPath)
+	if err != nil {
+		return fmt.Errorf("failed to check seal configuration: %v", err)
+	}
+	return c.adjustForSealMigration(pe, c.unwrapSeal)
+}
+
+// adjustForSealMigration checks the type of the seal that Vault was
+// initialized with, from the stored barrier seal configuration, against the
+// configured seal. If they differ, the seal that Vault was initialized with
+// is used until Vault is unsealed with the migrate option, which re-wraps the
+// master key with the configured seal. The unwrap seal is the previous seal,
+// which is only needed when migrating away from an auto seal.
+func (c *Core) adjustForSealMigration(pe *physical.Entry, unwrapSeal Seal) error {
+	if pe == nil {
+		// Not initialized, so there is nothing to migrate
+		if unwrapSeal != nil {
+			c.logger.Warn("core: ignoring disabled seal, vault is not initialized")
+		}
+		c.sealMigrationChecked = true
+		return nil
+	}
+
+	var conf SealConfig
+	if err := jsonutil.DecodeJSON(pe.Value, &conf); err != nil {
+		return fmt.Errorf("failed to decode seal configuration: %v", err)
+	}
+	existingType := conf.Type
+	if existingType == "" {
+		existingType = SealTypeShamir
+	}
+
+	if existingType == c.seal.BarrierType() {
+		if unwrapSeal != nil {
+			c.logger.Warn("core: vault already uses the configured seal, the disabled seal can be removed from the configuration", "seal_type", existingType)
+		}
+		c.sealMigrationChecked = true
+		return nil
+	}
+
+	var existingSeal Seal
+	switch {
+	case existingType == SealTypeShamir:
+		existingSeal = &DefaultSeal{}
+	case unwrapSeal != nil && unwrapSeal.BarrierType() == existingType:
+		existingSeal = unwrapSeal
+	default:
+		return fmt.Errorf("vault was initialized with seal type %s but the configured seal type is %s; to migrate, configure the %s seal as disabled", existingType, c.seal.BarrierType(), existingType)
+	}
+
+	c.logger.Warn("core: seal migration pending, unseal with the migrate option to complete it", "from_seal_type", existingType, "to_seal_type", c.seal.BarrierType())
+
+	existingSeal.SetCore(c)
+	c.migrationSeal = c.seal
+	c.seal = existingSeal
+	c.sealMigrationChecked = true
+	return nil
+}
+
+// SealMigrationPending returns whether Vault must be unsealed with the
+// migrate option to migrate to the configured seal
+func (c *Core) SealMigrationPending() bool {
+	c.stateLock.RLock()
+	defer c.stateLock.RUnlock()
+	return c.migrationSeal != nil
+}
+
+// UnsealMigrate is used to provide one of the key parts of the current seal
+// to unseal the Vault and migrate it to the configured seal. The key parts
+// are unseal keys when migrating from Shamir, and recovery keys when
+// migrating from an auto seal. The key parts stay valid after the
+// migration: unseal keys become recovery keys when migrating to an auto
+// seal, and recovery keys become unseal keys when migrating to Shamir.
+//
+// They key given as a parameter will automatically be zerod after
+// this method is done with it. If you want to keep the key around, a copy
+// should be made.
+func (c *Core) UnsealMigrate(key []byte) (bool, error) {
+	c.stateLock.Lock()
+	defer c.stateLock.Unlock()
+
+	ctx := context.Background()
+
+	init, err := c.Initialized(ctx)
+	if err != nil {
+		return false, err
+	}
+	if !init {
+		return false, ErrNotInit
+	}
+
+	if c.migrationSeal == nil {
+		return false, errors.New("no seal migration is pending")
+	}
+
+	var config *SealConfig
+	if c.seal.RecoveryKeySupported() {
+		config, err = c.seal.RecoveryConfig(ctx)
+	} else {
+		config, err = c.seal.BarrierConfig(ctx)
+	}
+	if err != nil {
+		return false, err
+	}
+	if config == nil {
+		return false, errors.New("seal configuration missing")
+	}
+
+	// Check if already unsealed
+	if !c.sealed {
+		return true, nil
+	}
+
+	// The combined key parts are the recovery key of an auto seal, or the
+	// master key with Shamir
+	recoveredKey, err := c.unsealPart(ctx, config, key, false)
+	if err != nil {
+		return false, err
+	}
+	if recoveredKey == nil {
+		return false, nil
+	}
+	defer memzero(recoveredKey)
+
+	masterKey := recoveredKey
+	if c.seal.RecoveryKeySupported() {
+		if err := c.seal.VerifyRecoveryKey(ctx, recoveredKey); err != nil {
+			return false, err
+		}
+		masterKey, err = c.storedMasterKey(ctx, c.seal)
+		if err != nil {
+			return false, err
+		}
+		defer memzero(masterKey)
+	}
+
+	// The barrier is unsealed before the migration to check the master key
+	if err := c.barrier.Unseal(ctx, masterKey); err != nil {
+		return false, err
+	}
+
+	newMasterKey, err := c.migrateSeal(ctx, masterKey, recoveredKey)
+	if err != nil {
+		c.logger.Error("core: seal migration failed", "error", err)
+		c.barrier.Seal()
+		return false, fmt.Errorf("seal migration failed: %v", err)
+	}
+
+	if err := c.seal.Finalize(ctx); err != nil {
+		c.logger.Warn("core: failed to finalize the previous seal", "error", err)
+	}
+	c.seal = c.migrationSeal
+	c.migrationSeal = nil
+	c.logger.Info("core: seal migration complete", "seal_type", c.seal.BarrierType())
+
+	return c.unsealInternal(ctx, newMasterKey)
+}
+
+// storedMasterKey returns the master key from the stored keys of the seal
+func (c *Core) storedMasterKey(ctx context.Context, seal Seal) ([]byte, error) {
+	shares, err := seal.GetStoredKeys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve stored keys: %v", err)
+	}
+	switch len(shares) {
+	case 0:
+		return nil, errors.New("no stored keys found")
+	case 1:
+		return shares[0], nil
+	}
+	masterKey, err := shamir.Combine(shares)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute master key: %v", err)
+	}
+	return masterKey, nil
+}
+
+// migrateSeal stores the keys of Vault with the migration seal, returning
+// the master key to unseal with. The barrier configuration is written last,
+// since it records which seal protects the master key.
+// N.B.: This must be called with the state write lock held and the barrier
+// unsealed.
+func (c *Core) migrateSeal(ctx context.Context, masterKey, recoveryKey []byte) ([]byte, error) {
+	oldSeal, newSeal := c.seal, c.migrationSeal
+	newSeal.SetCore(c)
+
+	oldBarrierConfig, err := oldSeal.BarrierConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case !oldSeal.RecoveryKeySupported():
+		// From Shamir to an auto seal: the master key is stored with the new
+		// seal, and the unseal keys become the recovery keys
+		recoveryConfig := oldBarrierConfig.Clone()
+		recoveryConfig.StoredShares = 0
+		recoveryConfig.PGPKeys = nil
+		recoveryConfig.Nonce = ""
+
+		if err := newSeal.SetStoredKeys(ctx, [][]byte{masterKey}); err != nil {
+			return nil, err
+		}
+		if err := newSeal.SetRecoveryKey(ctx, masterKey); err != nil {
+			return nil, err
+		}
+		if err := newSeal.SetRecoveryConfig(ctx, recoveryConfig); err != nil {
+			return nil, err
+		}
+		if err := newSeal.SetBarrierConfig(ctx, &SealConfig{
+			SecretShares:    1,
+			SecretThreshold: 1,
+			StoredShares:    1,
+		}); err != nil {
+			return nil, err
+		}
+		return copyKey(masterKey), nil
+
+	case !newSeal.RecoveryKeySupported():
+		// From an auto seal to Shamir: the recovery key becomes the master
+		// key, so that the recovery keys become the unseal keys
+		recoveryConfig, err := oldSeal.RecoveryConfig(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if recoveryConfig == nil {
+			return nil, errors.New("recovery configuration missing")
+		}
+
+		if err := c.barrier.Rekey(ctx, recoveryKey); err != nil {
+			return nil, fmt.Errorf("failed to rekey barrier: %v", err)
+		}
+		barrierConfig := recoveryConfig.Clone()
+		barrierConfig.StoredShares = 0
+		if err := newSeal.SetBarrierConfig(ctx, barrierConfig); err != nil {
+			return nil, err
+		}
+
+		// The keys protected by the previous seal are no longer used
+		for _, path := range []string{storedBarrierKeysPath, recoveryKeyPath, recoverySealConfigPlaintextPath} {
+			if err := c.physical.Delete(ctx, path); err != nil {
+				c.logger.Warn("core: failed to delete key of the previous seal", "path", path, "error", err)
+			}
+		}
+		return copyKey(recoveryKey), nil
+
+	default:
+		// Between auto seals: the keys are stored again with the new seal
+		recoveryConfig, err := oldSeal.RecoveryConfig(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if recoveryConfig == nil {
+			return nil, errors.New("recovery configuration missing")
+		}
+
+		if err := newSeal.SetStoredKeys(ctx, [][]byte{masterKey}); err != nil {
+			return nil, err
+		}
+		if err := newSeal.SetRecoveryKey(ctx, recoveryKey); err != nil {
+			return nil, err
+		}
+		if err := newSeal.SetRecoveryConfig(ctx, recoveryConfig); err != nil {
+			return nil, err
+		}
+		if err := newSeal.SetBarrierConfig(ctx, &SealConfig{
+			SecretShares:    1,
+			SecretThreshold: 1,
+			StoredShares:    1,
+		}); err != nil {
+			return nil, err
+		}
+		return copyKey(masterKey), nil
+	}
+}
+
+func copyKey(key []byte) []byte {
+	out := make([]byte, len(key))
+	copy(out, key)
+	return out
+}
@@ -0,0 +1,250 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	log "github.com/mgutz/logxi/v1"
+
+	"github.com/hashicorp/vault/helper/logformat"
+	"github.com/hashicorp/vault/physical"
+	physInmem "github.com/hashicorp/vault/physical/inmem"
+	"github.com/hashicorp/vault/vault/seal"
+)
+
+// testCoreMigration returns a core with the given seals over the physical
+// backend, as after restarting Vault with a new seal configuration
+func testCoreMigration(t *testing.T, phys physical.Backend, newSeal, unwrapSeal Seal) *Core {
+	conf := testCoreConfig(t, phys, logformat.NewVaultLogger(log.LevelTrace))
+	conf.Seal = newSeal
+	conf.UnwrapSeal = unwrapSeal
+	core, err := NewCore(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return core
+}
+
+func testSealMigrationPhysical(t *testing.T) physical.Backend {
+	phys, err := physInmem.NewInmem(nil, logformat.NewVaultLogger(log.LevelTrace))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return phys
+}
+
+func testUnsealMigrate(t *testing.T, core *Core, keys [][]byte) {
+	if !core.SealMigrationPending() {
+		t.Fatal("expected seal migration to be pending")
+	}
+	if _, err := core.Unseal(TestKeyCopy(keys[0])); err != ErrSealMigrationRequired {
+		t.Fatalf("expected seal migration required, got %v", err)
+	}
+	if _, err := core.UnsealWithRecoveryKeys(context.Background(), TestKeyCopy(keys[0])); err != ErrSealMigrationRequired {
+		t.Fatalf("expected seal migration required, got %v", err)
+	}
+
+	for i, key := range keys {
+		unsealed, err := core.UnsealMigrate(TestKeyCopy(key))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if unsealed != (i == len(keys)-1) {
+			t.Fatalf("bad unseal state after %d keys: %v", i+1, unsealed)
+		}
+	}
+	if core.SealMigrationPending() {
+		t.Fatal("expected seal migration to be complete")
+	}
+}
+
+func TestSealMigration_ShamirToAuto(t *testing.T) {
+	phys := testSealMigrationPhysical(t)
+	core := testCoreMigration(t, phys, &DefaultSeal{}, nil)
+	keys, root := TestCoreInit(t, core)
+	for _, key := range keys {
+		if _, err := TestCoreUnseal(core, TestKeyCopy(key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := core.Seal(root); err != nil {
+		t.Fatal(err)
+	}
+
+	access := seal.NewTestSeal(SealTypeTest)
+	core = testCoreMigration(t, phys, NewAutoSeal(access), nil)
+	testUnsealMigrate(t, core, keys)
+
+	if core.seal.BarrierType() != SealTypeTest {
+		t.Fatalf("bad seal type: %s", core.seal.BarrierType())
+	}
+	if err := core.Seal(root); err != nil {
+		t.Fatal(err)
+	}
+
+	// The master key is stored with the auto seal
+	core = testCoreMigration(t, phys, NewAutoSeal(seal.NewTestSeal(SealTypeTest)), nil)
+	if core.SealMigrationPending() {
+		t.Fatal("expected no seal migration to be pending")
+	}
+	if err := core.UnsealWithStoredKeys(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if sealed, _ := core.Sealed(); sealed {
+		t.Fatal("should not be sealed")
+	}
+
+	// The unseal keys are now the recovery keys
+	if err := core.Seal(root); err != nil {
+		t.Fatal(err)
+	}
+	for i, key := range keys {
+		unsealed, err := core.UnsealWithRecoveryKeys(context.Background(), TestKeyCopy(key))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if unsealed != (i == len(keys)-1) {
+			t.Fatalf("bad unseal state after %d recovery keys: %v", i+1, unsealed)
+		}
+	}
+}
+
+// testUnavailablePhysical is a physical backend failing to read entries while
+// it is unavailable
+type testUnavailablePhysical struct {
+	physical.Backend
+	unavailable bool
+}
+
+func (p *testUnavailablePhysical) Get(ctx context.Context, key string) (*physical.Entry, error) {
+	if p.unavailable {
+		return nil, errors.New("storage unavailable")
+	}
+	return p.Backend.Get(ctx, key)
+}
+
+func TestSealMigration_StorageUnavailable(t *testing.T) {
+	phys := &testUnavailablePhysical{
+		Backend: testSealMigrationPhysical(t),
+	}
+	core := testCoreMigration(t, phys, &DefaultSeal{}, nil)
+	keys, root := TestCoreInit(t, core)
+	for _, key := range keys {
+		if _, err := TestCoreUnseal(core, TestKeyCopy(key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := core.Seal(root); err != nil {
+		t.Fatal(err)
+	}
+
+	// The core is created without the storage, and checks for the migration
+	// once the storage can be read
+	phys.unavailable = true
+	core = testCoreMigration(t, phys, NewAutoSeal(seal.NewTestSeal(SealTypeTest)), nil)
+	if core.SealMigrationPending() {
+		t.Fatal("expected the seal migration to be unknown")
+	}
+	if _, err := core.Unseal(TestKeyCopy(keys[0])); err == nil {
+		t.Fatal("expected an error without the storage")
+	}
+
+	phys.unavailable = false
+	if _, err := core.Unseal(TestKeyCopy(keys[0])); err != ErrSealMigrationRequired {
+		t.Fatalf("expected seal migration required, got %v", err)
+	}
+	testUnsealMigrate(t, core, keys)
+}
+
+func TestSealMigration_AutoToShamir(t *testing.T) {
+	phys := testSealMigrationPhysical(t)
+	access := seal.NewTestSeal(SealTypeTest)
+	core := testCoreMigration(t, phys, NewAutoSeal(access), nil)
+	_, recoveryKeys, root := TestCoreInitClusterWrapperSetup(t, core, nil, nil)
+	if err := core.UnsealWithStoredKeys(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := core.Seal(root); err != nil {
+		t.Fatal(err)
+	}
+
+	// The previous seal is required to read the stored master key
+	conf := testCoreConfig(t, phys, logformat.NewVaultLogger(log.LevelTrace))
+	conf.Seal = &DefaultSeal{}
+	if _, err := NewCore(conf); err == nil {
+		t.Fatal("expected error without the disabled seal")
+	}
+
+	core = testCoreMigration(t, phys, &DefaultSeal{}, NewAutoSeal(access))
+	testUnsealMigrate(t, core, recoveryKeys)
+	if err := core.Seal(root); err != nil {
+		t.Fatal(err)
+	}
+
+	// The recovery keys are now the unseal keys
+	core = testCoreMigration(t, phys, &DefaultSeal{}, nil)
+	if core.SealMigrationPending() {
+		t.Fatal("expected no seal migration to be pending")
+	}
+	for i, key := range recoveryKeys {
+		unsealed, err := TestCoreUnseal(core, TestKeyCopy(key))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if unsealed != (i == len(recoveryKeys)-1) {
+			t.Fatalf("bad unseal state after %d keys: %v", i+1, unsealed)
+		}
+	}
+
+	for _, path := range []string{storedBarrierKeysPath, recoveryKeyPath, recoverySealConfigPlaintextPath} {
+		entry, err := phys.Get(context.Background(), path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entry != nil {
+			t.Fatalf("expected %s to be deleted", path)
+		}
+	}
+}
+
+func TestSealMigration_AutoToAuto(t *testing.T) {
+	phys := testSealMigrationPhysical(t)
+	oldAccess := seal.NewTestSeal(SealTypeTest)
+	core := testCoreMigration(t, phys, NewAutoSeal(oldAccess), nil)
+	_, recoveryKeys, root := TestCoreInitClusterWrapperSetup(t, core, nil, nil)
+	if err := core.UnsealWithStoredKeys(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := core.Seal(root); err != nil {
+		t.Fatal(err)
+	}
+
+	newAccess := seal.NewTestSeal("other-auto")
+	core = testCoreMigration(t, phys, NewAutoSeal(newAccess), NewAutoSeal(oldAccess))
+	testUnsealMigrate(t, core, recoveryKeys)
+	if err := core.Seal(root); err != nil {
+		t.Fatal(err)
+	}
+
+	// The stored keys are only readable with the new seal
+	core = testCoreMigration(t, phys, NewAutoSeal(newAccess), nil)
+	if err := core.UnsealWithStoredKeys(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if sealed, _ := core.Sealed(); sealed {
+		t.Fatal("should not be sealed")
+	}
+	if err := core.Seal(root); err != nil {
+		t.Fatal(err)
+	}
+	for i, key := range recoveryKeys {
+		unsealed, err := core.UnsealWithRecoveryKeys(context.Background(), TestKeyCopy(key))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if unsealed != (i == len(recoveryKeys)-1) {
+			t.Fatalf("bad unseal state after %d recovery keys: %v", i+1, unsealed)
+		}
+	}
+}
@@ -407,7 +407,7 @@ func TestAddTestPlugin(t testing.T, c *Core, name, testFunc string) {
 
 	command := fmt.Sprintf("%s", filepath.Base(os.Args[0]))
 	args := []string{fmt.Sprintf("--test.run=%s", testFunc)}
-	err = c.pluginCatalog.Set(context.Background(), name, command, args, sum)
+	err = c.pluginCatalog.Set(context.Background(), name, "", command, args, sum)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -471,7 +471,7 @@ func TestAddTestPluginTempDir(t testing.T, c *Core, name, testFunc, tempDir stri
 
 	command := fmt.Sprintf("%s", filepath.Base(os.Args[0]))
 	args := []string{fmt.Sprintf("--test.run=%s", testFunc)}
-	err = c.pluginCatalog.Set(context.Background(), name, command, args, sum)
+	err = c.pluginCatalog.Set(context.Background(), name, "", command, args, sum)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -652,11 +652,20 @@ func (n *noopAudit) Salt() (*salt.Salt, error) {
 type rawHTTP struct{}
 
 func (n *rawHTTP) HandleRequest(ctx context.Context, req *logical.Request) (*logical.Response, error) {
+	// Raw request bodies are echoed back
+	body := []byte("hello world")
+	if raw, ok := req.Data[logical.HTTPRawBody].([]byte); ok {
+		body = raw
+	}
+
 	return &logical.Response{
 		Data: map[string]interface{}{
 			logical.HTTPStatusCode:  200,
 			logical.HTTPContentType: "plain/text",
-			logical.HTTPRawBody:     []byte("hello world"),
+			logical.HTTPRawBody:     body,
+			logical.HTTPRawHeaders: map[string][]string{
+				"X-Raw-Header": []string{"a", "b"},
+			},
 		},
 	}, nil
 }
@@ -1182,6 +1191,12 @@ func NewTestCluster(t testing.T, base *CoreConfig, opts *TestClusterOptions) *Te
 
 		coreConfig.ClusterCipherSuites = base.ClusterCipherSuites
 
+		coreConfig.PerformanceStandby = base.PerformanceStandby
+
+		coreConfig.MetricsHelper = base.MetricsHelper
+		coreConfig.RecentLogs = base.RecentLogs
+		coreConfig.EntropySource = base.EntropySource
+
 		coreConfig.DisableCache = base.DisableCache
 
 		coreConfig.DevToken = base.DevToken
@@ -16,6 +16,7 @@ import (
 	"github.com/armon/go-metrics"
 	"github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/helper/cidrutil"
 	"github.com/hashicorp/vault/helper/consts"
 	"github.com/hashicorp/vault/helper/jsonutil"
 	"github.com/hashicorp/vault/helper/locksutil"
@@ -63,6 +64,16 @@ const (
 	tokenRevocationFailed = -3
 )
 
+const (
+	// TokenTypeService is the type of the tokens persisted in the token
+	// store. Entries without a type are service tokens.
+	TokenTypeService = "service"
+
+	// TokenTypeBatch is the type of the tokens that are not persisted: their
+	// entry is encrypted into the token itself
+	TokenTypeBatch = "batch"
+)
+
 var (
 	// displayNameSanitize is used to sanitize a display name given to a token.
 	displayNameSanitize = regexp.MustCompile("[^a-zA-Z0-9-]")
@@ -91,7 +102,12 @@ type TokenStore struct {
 
 	cubbyholeBackend *CubbyholeBackend
 
-	policyLookupFunc func(string) (*Policy, error)
+	policyLookupFunc func(context.Context, string) (*Policy, error)
+
+	// batchTokenEncryptor encrypts the entries of batch tokens into their ID
+	batchTokenEncryptor BarrierEncryptor
+
+	namespaceLookupFunc func(string) *Namespace
 
 	tokenLocks []*locksutil.LockEntry
 
@@ -103,6 +119,12 @@ type TokenStore struct {
 	salt     *salt.Salt
 
 	tidyLock int64
+
+	// tokenCountLock is held for reading while the token entries are
+	// written or deleted, and for writing while the tokens are first counted
+	tokenCountLock   sync.RWMutex
+	tokenCount       int64
+	tokenCountLoaded bool
 }
 
 // NewTokenStore is used to construct a token store that is
@@ -120,11 +142,16 @@ func NewTokenStore(ctx context.Context, c *Core, config *logical.BackendConfig)
 		saltLock:           sync.RWMutex{},
 	}
 
+	if c.barrier != nil {
+		t.batchTokenEncryptor = c.barrier
+	}
+
 	if c.policyStore != nil {
-		t.policyLookupFunc = func(name string) (*Policy, error) {
+		t.policyLookupFunc = func(ctx context.Context, name string) (*Policy, error) {
 			return c.policyStore.GetPolicy(ctx, name, PolicyTypeToken)
 		}
 	}
+	t.namespaceLookupFunc = c.namespaceByID
 
 	// Setup the framework endpoints
 	t.Backend = &framework.Backend{
@@ -216,6 +243,17 @@ func NewTokenStore(ctx context.Context, c *Core, config *logical.BackendConfig)
 						Default:     true,
 						Description: tokenRenewableHelp,
 					},
+
+					"token_type": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Default:     TokenTypeService,
+						Description: tokenTypeHelp,
+					},
+
+					"bound_cidrs": &framework.FieldSchema{
+						Type:        framework.TypeCommaStringSlice,
+						Description: tokenBoundCIDRsHelp,
+					},
 				},
 
 				Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -290,6 +328,11 @@ func NewTokenStore(ctx context.Context, c *Core, config *logical.BackendConfig)
 					logical.UpdateOperation: t.handleLookup,
 				},
 
+				Properties: map[logical.Operation]logical.OperationProperties{
+					logical.ReadOperation:   {ReadOnly: true},
+					logical.UpdateOperation: {ReadOnly: true},
+				},
+
 				HelpSynopsis:    strings.TrimSpace(tokenLookupHelp),
 				HelpDescription: strings.TrimSpace(tokenLookupHelp),
 			},
@@ -331,6 +374,11 @@ func NewTokenStore(ctx context.Context, c *Core, config *logical.BackendConfig)
 					logical.ReadOperation:   t.handleLookupSelf,
 				},
 
+				Properties: map[logical.Operation]logical.OperationProperties{
+					logical.ReadOperation:   {ReadOnly: true},
+					logical.UpdateOperation: {ReadOnly: true},
+				},
+
 				HelpSynopsis:    strings.TrimSpace(tokenLookupHelp),
 				HelpDescription: strings.TrimSpace(tokenLookupHelp),
 			},
@@ -570,6 +618,16 @@ type TokenEntry struct {
 	ExplicitMaxTTLDeprecated time.Duration `json:"ExplicitMaxTTL" mapstructure:"ExplicitMaxTTL" structs:"ExplicitMaxTTL" sentinel:""`
 
 	EntityID string `json:"entity_id" mapstructure:"entity_id" structs:"entity_id"`
+
+	// NamespaceID is the ID of the namespace the token was created in, empty
+	// for the root namespace
+	NamespaceID string `json:"namespace_id,omitempty" mapstructure:"namespace_id" structs:"namespace_id"`
+
+	// Type is the type of the token, empty for service tokens
+	Type string `json:"type,omitempty" mapstructure:"type" structs:"type"`
+
+	// BoundCIDRs restricts the addresses the token can be used from
+	BoundCIDRs []string `json:"bound_cidrs,omitempty" mapstructure:"bound_cidrs" structs:"bound_cidrs"`
 }
 
 func (te *TokenEntry) SentinelGet(key string) (interface{}, error) {
@@ -652,6 +710,13 @@ type tsRoleEntry struct {
 	// If set, the token entry will have an explicit maximum TTL set, rather
 	// than deferring to role/mount values
 	ExplicitMaxTTL time.Duration `json:"explicit_max_ttl" mapstructure:"explicit_max_ttl" structs:"explicit_max_ttl"`
+
+	// The type of the tokens created using this role, service if empty
+	TokenType string `json:"token_type,omitempty" mapstructure:"token_type" structs:"token_type"`
+
+	// If set, tokens created using this role can only be used from these
+	// CIDR blocks
+	BoundCIDRs []string `json:"bound_cidrs,omitempty" mapstructure:"bound_cidrs" structs:"bound_cidrs"`
 }
 
 type accessorEntry struct {
@@ -757,6 +822,10 @@ func (ts *TokenStore) createAccessor(ctx context.Context, entry *TokenEntry) err
 // a newly generated ID if not provided.
 func (ts *TokenStore) create(ctx context.Context, entry *TokenEntry) error {
 	defer metrics.MeasureSince([]string{"token", "create"}, time.Now())
+	if entry.Type == TokenTypeBatch {
+		return ts.createBatchToken(ctx, entry)
+	}
+
 	// Generate an ID if necessary
 	if entry.ID == "" {
 		entryUUID, err := uuid.GenerateUUID()
@@ -782,7 +851,15 @@ func (ts *TokenStore) create(ctx context.Context, entry *TokenEntry) error {
 		return err
 	}
 
-	return ts.storeCommon(ctx, entry, true)
+	ts.tokenCountLock.RLock()
+	defer ts.tokenCountLock.RUnlock()
+	if err := ts.storeCommon(ctx, entry, true); err != nil {
+		return err
+	}
+	if ts.tokenCountLoaded {
+		atomic.AddInt64(&ts.tokenCount, 1)
+	}
+	return nil
 }
 
 // Store is used to store an updated token entry without writing the
@@ -927,6 +1004,9 @@ func (ts *TokenStore) Lookup(ctx context.Context, id string) (*TokenEntry, error
 	if id == "" {
 		return nil, fmt.Errorf("cannot lookup blank token")
 	}
+	if isBatchTokenID(id) {
+		return ts.lookupBatchToken(ctx, id)
+	}
 
 	lock := locksutil.LockForKey(ts.tokenLocks, id)
 	lock.RLock()
@@ -946,6 +1026,9 @@ func (ts *TokenStore) lookupTainted(ctx context.Context, id string) (*TokenEntry
 	if id == "" {
 		return nil, fmt.Errorf("cannot lookup blank token")
 	}
+	if isBatchTokenID(id) {
+		return ts.lookupBatchToken(ctx, id)
+	}
 
 	lock := locksutil.LockForKey(ts.tokenLocks, id)
 	lock.RLock()
@@ -958,6 +1041,31 @@ func (ts *TokenStore) lookupTainted(ctx context.Context, id string) (*TokenEntry
 	return ts.lookupSalted(ctx, saltedID, true)
 }
 
+// countTokens returns the number of stored tokens. Batch tokens are not
+// stored, so they are not counted. The tokens are listed the first time only,
+// then the count is updated as the tokens are created and revoked.
+func (ts *TokenStore) countTokens(ctx context.Context) (int, error) {
+	ts.tokenCountLock.RLock()
+	if ts.tokenCountLoaded {
+		count := atomic.LoadInt64(&ts.tokenCount)
+		ts.tokenCountLock.RUnlock()
+		return int(count), nil
+	}
+	ts.tokenCountLock.RUnlock()
+
+	ts.tokenCountLock.Lock()
+	defer ts.tokenCountLock.Unlock()
+	if !ts.tokenCountLoaded {
+		keys, err := ts.view.List(ctx, lookupPrefix)
+		if err != nil {
+			return 0, fmt.Errorf("failed to list tokens: %v", err)
+		}
+		ts.tokenCount = int64(len(keys))
+		ts.tokenCountLoaded = true
+	}
+	return int(ts.tokenCount), nil
+}
+
 // lookupSalted is used to find a token given its salted ID. If tainted is
 // true, entries that are in some revocation state (currently, indicated by num
 // uses < 0), the entry will be returned anyways
@@ -1050,6 +1158,9 @@ func (ts *TokenStore) Revoke(ctx context.Context, id string) error {
 	if id == "" {
 		return fmt.Errorf("cannot revoke blank token")
 	}
+	if isBatchTokenID(id) {
+		return errBatchTokenRevoke
+	}
 
 	saltedID, err := ts.SaltID(id)
 	if err != nil {
@@ -1171,7 +1282,13 @@ func (ts *TokenStore) revokeSalted(ctx context.Context, saltedId string) (ret er
 
 	// Now that the entry is not usable for any revocation tasks, nuke it
 	path := lookupPrefix + saltedId
-	if err = ts.view.Delete(ctx, path); err != nil {
+	ts.tokenCountLock.RLock()
+	err = ts.view.Delete(ctx, path)
+	if err == nil && ts.tokenCountLoaded {
+		atomic.AddInt64(&ts.tokenCount, -1)
+	}
+	ts.tokenCountLock.RUnlock()
+	if err != nil {
 		return fmt.Errorf("failed to delete entry: %v", err)
 	}
 
@@ -1186,6 +1303,9 @@ func (ts *TokenStore) RevokeTree(ctx context.Context, id string) error {
 	if id == "" {
 		return fmt.Errorf("cannot tree-revoke blank token")
 	}
+	if isBatchTokenID(id) {
+		return errBatchTokenRevoke
+	}
 
 	// Get the salted ID
 	saltedId, err := ts.SaltID(id)
@@ -1529,6 +1649,10 @@ func (ts *TokenStore) handleUpdateRevokeAccessor(ctx context.Context, req *logic
 		return nil, err
 	}
 
+	if err := ts.checkTokenNamespace(ctx, aEntry.TokenID); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
 	// Revoke the token and its children
 	if err := ts.RevokeTree(ctx, aEntry.TokenID); err != nil {
 		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
@@ -1570,6 +1694,22 @@ func (ts *TokenStore) handleCreateCommon(ctx context.Context, req *logical.Reque
 			logical.ErrInvalidRequest
 	}
 
+	// Batch tokens are not tracked by the token store, so the children they
+	// would create could not be revoked along with them
+	if parent.Type == TokenTypeBatch {
+		return logical.ErrorResponse("batch tokens cannot create child tokens"),
+			logical.ErrInvalidRequest
+	}
+
+	// Tokens are created in the namespace of the request. The policies of the
+	// parent are named relative to its own namespace, so only root tokens can
+	// create tokens in the descendant namespaces.
+	ns := namespaceFromContext(ctx)
+	if parent.NamespaceID != ns.ID && !strutil.StrListContains(parent.Policies, "root") {
+		return logical.ErrorResponse("tokens can only be created in the namespace of the parent token"),
+			logical.ErrInvalidRequest
+	}
+
 	// Check if the client token has sudo/root privileges for the requested path
 	isSudo := ts.System().SudoPrivilege(ctx, req.MountPoint+req.Path, req.ClientToken)
 
@@ -1587,6 +1727,7 @@ func (ts *TokenStore) handleCreateCommon(ctx context.Context, req *logical.Reque
 		DisplayName     string `mapstructure:"display_name"`
 		NumUses         int    `mapstructure:"num_uses"`
 		Period          string
+		Type            string
 	}
 	if err := mapstructure.WeakDecode(req.Data, &data); err != nil {
 		return logical.ErrorResponse(fmt.Sprintf(
@@ -1599,6 +1740,20 @@ func (ts *TokenStore) handleCreateCommon(ctx context.Context, req *logical.Reque
 			logical.ErrInvalidRequest
 	}
 
+	// The type of the role applies unless the request sets one
+	tokenType := data.Type
+	if tokenType == "" && role != nil {
+		tokenType = role.TokenType
+	}
+	switch tokenType {
+	case "", TokenTypeService:
+		tokenType = ""
+	case TokenTypeBatch:
+	default:
+		return logical.ErrorResponse(fmt.Sprintf("invalid token type %q", tokenType)),
+			logical.ErrInvalidRequest
+	}
+
 	// Setup the token entry
 	te := TokenEntry{
 		Parent: req.ClientToken,
@@ -1612,6 +1767,8 @@ func (ts *TokenStore) handleCreateCommon(ctx context.Context, req *logical.Reque
 		DisplayName:  "token",
 		NumUses:      data.NumUses,
 		CreationTime: time.Now().Unix(),
+		NamespaceID:  ns.ID,
+		Type:         tokenType,
 	}
 
 	renewable := true
@@ -1636,6 +1793,12 @@ func (ts *TokenStore) handleCreateCommon(ctx context.Context, req *logical.Reque
 		if role.PathSuffix != "" {
 			te.Path = fmt.Sprintf("%s/%s", te.Path, role.PathSuffix)
 		}
+
+		te.BoundCIDRs = role.BoundCIDRs
+	} else {
+		// Children of a CIDR-bound token are bound to the same CIDRs, so the
+		// binding can't be escaped by creating a child token
+		te.BoundCIDRs = parent.BoundCIDRs
 	}
 
 	// Attach the given display name if any
@@ -1776,6 +1939,11 @@ func (ts *TokenStore) handleCreateCommon(ctx context.Context, req *logical.Reque
 		return logical.ErrorResponse("root tokens may not be created without parent token being root"), logical.ErrInvalidRequest
 	}
 
+	// The root policy only exists in the root namespace
+	if !ns.isRoot() && strutil.StrListContains(te.Policies, "root") {
+		return logical.ErrorResponse("root tokens may not be created in a namespace"), logical.ErrInvalidRequest
+	}
+
 	//
 	// NOTE: Do not modify policies below this line. We need the checks above
 	// to be the last checks as they must look at the final policy set.
@@ -1939,6 +2107,11 @@ func (ts *TokenStore) handleCreateCommon(ctx context.Context, req *logical.Reque
 		renewable = false
 	}
 
+	// Batch tokens have no lease to renew
+	if te.Type == TokenTypeBatch {
+		renewable = false
+	}
+
 	// Create the token
 	if err := ts.create(ctx, &te); err != nil {
 		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
@@ -1961,7 +2134,7 @@ func (ts *TokenStore) handleCreateCommon(ctx context.Context, req *logical.Reque
 
 	if ts.policyLookupFunc != nil {
 		for _, p := range te.Policies {
-			policy, err := ts.policyLookupFunc(p)
+			policy, err := ts.policyLookupFunc(ctx, p)
 			if err != nil {
 				return logical.ErrorResponse(fmt.Sprintf("could not look up policy %s", p)), nil
 			}
@@ -1999,6 +2172,10 @@ func (ts *TokenStore) handleRevokeTree(ctx context.Context, req *logical.Request
 		urltoken = true
 	}
 
+	if err := ts.checkTokenNamespace(ctx, id); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
 	// Revoke the token and its children
 	if err := ts.RevokeTree(ctx, id); err != nil {
 		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
@@ -2044,6 +2221,10 @@ func (ts *TokenStore) handleRevokeOrphan(ctx context.Context, req *logical.Reque
 			logical.ErrInvalidRequest
 	}
 
+	if err := ts.checkTokenNamespace(ctx, id); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
 	// Revoke and orphan
 	if err := ts.Revoke(ctx, id); err != nil {
 		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
@@ -2081,21 +2262,13 @@ func (ts *TokenStore) handleLookup(ctx context.Context, req *logical.Request, da
 		return logical.ErrorResponse("missing token ID"), logical.ErrInvalidRequest
 	}
 
-	lock := locksutil.LockForKey(ts.tokenLocks, id)
-	lock.RLock()
-	defer lock.RUnlock()
-
 	// Lookup the token
-	saltedId, err := ts.SaltID(id)
-	if err != nil {
-		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
-	}
-	out, err := ts.lookupSalted(ctx, saltedId, true)
+	out, err := ts.lookupTainted(ctx, id)
 	if err != nil {
 		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
 	}
 
-	if out == nil {
+	if out == nil || !ts.tokenInRequestNamespace(ctx, out) {
 		return logical.ErrorResponse("bad token"), logical.ErrPermissionDenied
 	}
 
@@ -2117,6 +2290,7 @@ func (ts *TokenStore) handleLookup(ctx context.Context, req *logical.Request, da
 			"ttl":              int64(0),
 			"explicit_max_ttl": int64(out.ExplicitMaxTTL.Seconds()),
 			"entity_id":        out.EntityID,
+			"type":             TokenTypeService,
 		},
 	}
 
@@ -2130,6 +2304,28 @@ func (ts *TokenStore) handleLookup(ctx context.Context, req *logical.Request, da
 	if out.Period != 0 {
 		resp.Data["period"] = int64(out.Period.Seconds())
 	}
+	if len(out.BoundCIDRs) > 0 {
+		resp.Data["bound_cidrs"] = out.BoundCIDRs
+	}
+	if out.NamespaceID != "" && ts.namespaceLookupFunc != nil {
+		if ns := ts.namespaceLookupFunc(out.NamespaceID); ns != nil {
+			resp.Data["namespace_path"] = ns.Path
+		}
+	}
+
+	// Batch tokens have no lease, they expire at the end of their TTL
+	if out.Type == TokenTypeBatch {
+		expireTime := time.Unix(out.CreationTime, 0).Add(out.TTL)
+		resp.Data["type"] = TokenTypeBatch
+		resp.Data["expire_time"] = expireTime
+		resp.Data["ttl"] = int64(time.Until(expireTime).Seconds())
+		resp.Data["renewable"] = false
+		resp.Data["issue_time"] = time.Unix(out.CreationTime, 0)
+		if urltoken {
+			resp.AddWarning(`Using a token in the path is unsafe as the token can be logged in many places. Please use POST or PUT with the token passed in via the "token" parameter.`)
+		}
+		return resp, nil
+	}
 
 	// Fetch the last renewal time
 	leaseTimes, err := ts.expiration.FetchLeaseTimesByToken(out.Path, out.ID)
@@ -2186,9 +2382,12 @@ func (ts *TokenStore) handleRenew(ctx context.Context, req *logical.Request, dat
 	}
 
 	// Verify the token exists
-	if te == nil {
+	if te == nil || !ts.tokenInRequestNamespace(ctx, te) {
 		return logical.ErrorResponse("token not found"), logical.ErrInvalidRequest
 	}
+	if te.Type == TokenTypeBatch {
+		return logical.ErrorResponse("batch tokens cannot be renewed"), logical.ErrInvalidRequest
+	}
 
 	// Renew the token and its children
 	resp, err := ts.expiration.RenewToken(req, te.Path, te.ID, increment)
@@ -2200,6 +2399,34 @@ func (ts *TokenStore) handleRenew(ctx context.Context, req *logical.Request, dat
 	return resp, err
 }
 
+// tokenInRequestNamespace checks whether the token belongs to the namespace of
+// the request or one of its descendants
+func (ts *TokenStore) tokenInRequestNamespace(ctx context.Context, te *TokenEntry) bool {
+	ns := namespaceFromContext(ctx)
+	if ns.isRoot() || ts.namespaceLookupFunc == nil {
+		return true
+	}
+	tokenNS := ts.namespaceLookupFunc(te.NamespaceID)
+	return tokenNS != nil && tokenNS.isWithin(ns)
+}
+
+// checkTokenNamespace returns an error if the token exists outside of the
+// namespace of the request and its descendants, so that tokens cannot be
+// revoked from other namespaces
+func (ts *TokenStore) checkTokenNamespace(ctx context.Context, id string) error {
+	if namespaceFromContext(ctx).isRoot() {
+		return nil
+	}
+	te, err := ts.Lookup(ctx, id)
+	if err != nil {
+		return err
+	}
+	if te != nil && !ts.tokenInRequestNamespace(ctx, te) {
+		return fmt.Errorf("token not found")
+	}
+	return nil
+}
+
 func (ts *TokenStore) destroyCubbyhole(ctx context.Context, saltedID string) error {
 	if ts.cubbyholeBackend == nil {
 		// Should only ever happen in testing
@@ -2344,9 +2571,15 @@ func (ts *TokenStore) tokenStoreRoleRead(ctx context.Context, req *logical.Reque
 			"orphan":              role.Orphan,
 			"path_suffix":         role.PathSuffix,
 			"renewable":           role.Renewable,
+			"token_type":          TokenTypeService,
+			"bound_cidrs":         role.BoundCIDRs,
 		},
 	}
 
+	if role.TokenType != "" {
+		resp.Data["token_type"] = role.TokenType
+	}
+
 	return resp, nil
 }
 
@@ -2454,6 +2687,24 @@ func (ts *TokenStore) tokenStoreRoleCreateUpdate(ctx context.Context, req *logic
 		entry.AllowedPolicies = policyutil.SanitizePolicies(data.Get("allowed_policies").([]string), policyutil.DoNotAddDefaultPolicy)
 	}
 
+	tokenTypeRaw, ok := data.GetOk("token_type")
+	if !ok && req.Operation == logical.CreateOperation {
+		tokenTypeRaw = data.Get("token_type")
+	}
+	if tokenTypeRaw != nil {
+		switch tokenType := tokenTypeRaw.(string); tokenType {
+		case "", TokenTypeService:
+			entry.TokenType = ""
+		case TokenTypeBatch:
+			entry.TokenType = TokenTypeBatch
+		default:
+			return logical.ErrorResponse(fmt.Sprintf("invalid token type %q", tokenType)), nil
+		}
+	}
+	if entry.TokenType == TokenTypeBatch && entry.Period != 0 {
+		return logical.ErrorResponse("batch tokens cannot be periodic"), nil
+	}
+
 	disallowedPoliciesRaw, ok := data.GetOk("disallowed_policies")
 	if ok {
 		entry.DisallowedPolicies = strutil.RemoveDuplicates(disallowedPoliciesRaw.([]string), true)
@@ -2461,6 +2712,17 @@ func (ts *TokenStore) tokenStoreRoleCreateUpdate(ctx context.Context, req *logic
 		entry.DisallowedPolicies = strutil.RemoveDuplicates(data.Get("disallowed_policies").([]string), true)
 	}
 
+	boundCIDRsRaw, ok := data.GetOk("bound_cidrs")
+	if ok {
+		boundCIDRs := strutil.RemoveDuplicates(boundCIDRsRaw.([]string), false)
+		if len(boundCIDRs) > 0 {
+			if _, err := cidrutil.ValidateCIDRListSlice(boundCIDRs); err != nil {
+				return logical.ErrorResponse(fmt.Sprintf("invalid bound CIDR blocks: %v", err)), nil
+			}
+		}
+		entry.BoundCIDRs = boundCIDRs
+	}
+
 	// Store it
 	jsonEntry, err := logical.StorageEntryJSON(fmt.Sprintf("%s%s", rolesPrefix, name), entry)
 	if err != nil {
@@ -2529,6 +2791,14 @@ no effect on the token being renewed.`
 	tokenRenewableHelp = `Tokens created via this role will be
 renewable or not according to this value.
 Defaults to "true".`
+	tokenTypeHelp = `The type of the tokens created via this
+role, either "service" or "batch". Batch
+tokens are not persisted and cannot be
+renewed or revoked. Defaults to "service".`
+	tokenBoundCIDRsHelp = `If set, tokens created via this role
+can only be used from addresses within
+these CIDR blocks. The parameter is a
+comma-delimited string of CIDR blocks.`
 	tokenListAccessorsHelp = `List token accessors, which can then be
 be used to iterate and discover their properities
 or revoke them. Because this can be used to
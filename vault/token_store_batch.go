@@ -0,0 +1,163 @@
+package vault
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/vault/helper/policyutil"
+	"github.com/hashicorp/vault/helper/strutil"
+)
+
+const (
+	// batchTokenPrefix is the prefix of the IDs of batch tokens, which
+	// distinguishes them from the UUIDs of service tokens
+	batchTokenPrefix = "b."
+
+	// batchTokenEncryptionKey is the key under which the entries of batch
+	// tokens are encrypted by the barrier
+	batchTokenEncryptionKey = "token/batch"
+)
+
+// errBatchTokenRevoke is returned when revoking a batch token, which is only
+// invalidated by expiring or by the revocation of its parent
+var errBatchTokenRevoke = errors.New("batch tokens cannot be revoked")
+
+// batchTokenEntry is the part of the entry of a batch token that is encrypted
+// into its ID. Short keys keep the tokens compact.
+type batchTokenEntry struct {
+	Parent       string            `json:"pa,omitempty"`
+	Policies     []string          `json:"po,omitempty"`
+	Path         string            `json:"p,omitempty"`
+	Meta         map[string]string `json:"m,omitempty"`
+	DisplayName  string            `json:"d,omitempty"`
+	CreationTime int64             `json:"c"`
+	TTL          int64             `json:"t"`
+	Role         string            `json:"r,omitempty"`
+	EntityID     string            `json:"e,omitempty"`
+	NamespaceID  string            `json:"n,omitempty"`
+	BoundCIDRs   []string          `json:"b,omitempty"`
+}
+
+// isBatchTokenID checks whether the token ID is the ID of a batch token
+func isBatchTokenID(id string) bool {
+	return strings.HasPrefix(id, batchTokenPrefix)
+}
+
+// createBatchToken encrypts the entry into the ID of a new batch token.
+// Nothing is persisted, so batch tokens have no accessor, use count or
+// cubbyhole, and they cannot be renewed or revoked.
+func (ts *TokenStore) createBatchToken(ctx context.Context, entry *TokenEntry) error {
+	defer metrics.MeasureSince([]string{"token", "create_batch"}, time.Now())
+
+	entry.Policies = policyutil.SanitizePolicies(entry.Policies, policyutil.DoNotAddDefaultPolicy)
+
+	switch {
+	case ts.batchTokenEncryptor == nil:
+		return fmt.Errorf("batch tokens are not supported")
+	case entry.ID != "":
+		return fmt.Errorf("batch tokens cannot have a custom ID")
+	case entry.NumUses != 0:
+		return fmt.Errorf("batch tokens cannot have a limited number of uses")
+	case entry.Period != 0:
+		return fmt.Errorf("batch tokens cannot be periodic")
+	case strutil.StrListContains(entry.Policies, "root"):
+		return fmt.Errorf("batch tokens cannot be root tokens")
+	case entry.TTL <= 0:
+		return fmt.Errorf("batch tokens must have a TTL")
+	}
+
+	enc, err := json.Marshal(&batchTokenEntry{
+		Parent:       entry.Parent,
+		Policies:     entry.Policies,
+		Path:         entry.Path,
+		Meta:         entry.Meta,
+		DisplayName:  entry.DisplayName,
+		CreationTime: entry.CreationTime,
+		TTL:          int64(entry.TTL.Seconds()),
+		Role:         entry.Role,
+		EntityID:     entry.EntityID,
+		NamespaceID:  entry.NamespaceID,
+		BoundCIDRs:   entry.BoundCIDRs,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode entry: %v", err)
+	}
+
+	ciphertext, err := ts.batchTokenEncryptor.Encrypt(ctx, batchTokenEncryptionKey, enc)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt entry: %v", err)
+	}
+
+	entry.ID = batchTokenPrefix + base64.RawURLEncoding.EncodeToString(ciphertext)
+	entry.Accessor = ""
+	entry.Type = TokenTypeBatch
+	return nil
+}
+
+// lookupBatchToken decrypts the entry of a batch token. Tokens that cannot be
+// decrypted, that have expired or whose parent is no longer valid are
+// reported as not found.
+func (ts *TokenStore) lookupBatchToken(ctx context.Context, id string) (*TokenEntry, error) {
+	defer metrics.MeasureSince([]string{"token", "lookup_batch"}, time.Now())
+
+	if ts.batchTokenEncryptor == nil {
+		return nil, nil
+	}
+
+	ciphertext, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(id, batchTokenPrefix))
+	if err != nil {
+		return nil, nil
+	}
+	plaintext, err := ts.batchTokenEncryptor.Decrypt(ctx, batchTokenEncryptionKey, ciphertext)
+	if err == ErrBarrierSealed {
+		return nil, err
+	}
+	if err != nil {
+		return nil, nil
+	}
+
+	var be batchTokenEntry
+	if err := json.Unmarshal(plaintext, &be); err != nil {
+		return nil, nil
+	}
+
+	entry := &TokenEntry{
+		ID:           id,
+		Parent:       be.Parent,
+		Policies:     be.Policies,
+		Path:         be.Path,
+		Meta:         be.Meta,
+		DisplayName:  be.DisplayName,
+		CreationTime: be.CreationTime,
+		TTL:          time.Duration(be.TTL) * time.Second,
+		Role:         be.Role,
+		EntityID:     be.EntityID,
+		NamespaceID:  be.NamespaceID,
+		Type:         TokenTypeBatch,
+		BoundCIDRs:   be.BoundCIDRs,
+	}
+
+	// No lease revokes batch tokens, so their expiration is checked here
+	if !time.Now().Before(time.Unix(entry.CreationTime, 0).Add(entry.TTL)) {
+		return nil, nil
+	}
+
+	// Batch tokens are implicitly revoked along with their parent
+	if entry.Parent != "" {
+		parent, err := ts.Lookup(ctx, entry.Parent)
+		if err != nil {
+			return nil, fmt.Errorf("failed to lookup parent: %v", err)
+		}
+		if parent == nil {
+			return nil, nil
+		}
+	}
+
+	return entry, nil
+}
@@ -0,0 +1,343 @@
+package vault
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/logical"
+)
+
+// testMakeBatchToken creates a batch token through the core with the given
+// parent
+func testMakeBatchToken(t *testing.T, c *Core, parent string, data map[string]interface{}) string {
+	req := logical.TestRequest(t, logical.UpdateOperation, "auth/token/create")
+	req.ClientToken = parent
+	req.Data = data
+	req.Data["type"] = "batch"
+
+	resp, err := c.HandleRequest(req)
+	if err != nil || resp.IsError() {
+		t.Fatalf("err: %v resp: %#v", err, resp)
+	}
+	if resp.Auth.Renewable || resp.Auth.Accessor != "" {
+		t.Fatalf("bad: %#v", resp.Auth)
+	}
+	if !strings.HasPrefix(resp.Auth.ClientToken, batchTokenPrefix) {
+		t.Fatalf("expected a batch token, got %q", resp.Auth.ClientToken)
+	}
+	return resp.Auth.ClientToken
+}
+
+func TestTokenStore_BatchToken(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	before, err := c.barrier.List(context.Background(), "sys/token/id/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token := testMakeBatchToken(t, c, root, map[string]interface{}{
+		"policies": []string{"foo"},
+		"ttl":      "1h",
+		"meta":     map[string]string{"user": "armon"},
+	})
+
+	// Batch tokens create no storage entries
+	after, err := c.barrier.List(context.Background(), "sys/token/id/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(after) != len(before) {
+		t.Fatalf("expected no new token entries: %v %v", before, after)
+	}
+
+	te, err := c.tokenStore.Lookup(context.Background(), token)
+	if err != nil || te == nil {
+		t.Fatalf("err: %v", err)
+	}
+	if te.Type != TokenTypeBatch || te.Parent != root || te.TTL != time.Hour || te.Meta["user"] != "armon" {
+		t.Fatalf("bad: %#v", te)
+	}
+	if len(te.Policies) != 2 || te.Policies[0] != "default" || te.Policies[1] != "foo" {
+		t.Fatalf("bad: %#v", te.Policies)
+	}
+
+	req := logical.TestRequest(t, logical.ReadOperation, "auth/token/lookup-self")
+	req.ClientToken = token
+	resp, err := c.HandleRequest(req)
+	if err != nil || resp.IsError() {
+		t.Fatalf("err: %v resp: %#v", err, resp)
+	}
+	if resp.Data["type"] != TokenTypeBatch || resp.Data["renewable"] != false || resp.Data["ttl"].(int64) <= 0 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Tampered tokens are rejected
+	tampered := token[:len(token)-2] + "xx"
+	if te, err := c.tokenStore.Lookup(context.Background(), tampered); err != nil || te != nil {
+		t.Fatalf("expected no entry for a tampered token: %v %#v", err, te)
+	}
+	for _, id := range []string{batchTokenPrefix, batchTokenPrefix + "AAAA", batchTokenPrefix + "!"} {
+		if te, err := c.tokenStore.Lookup(context.Background(), id); err != nil || te != nil {
+			t.Fatalf("expected no entry for %q: %v %#v", id, err, te)
+		}
+	}
+}
+
+func TestTokenStore_BatchToken_Restrictions(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	for _, data := range []map[string]interface{}{
+		{"policies": []string{"root"}},
+		{"num_uses": 1},
+		{"period": "1h"},
+		{"id": "foo"},
+	} {
+		req := logical.TestRequest(t, logical.UpdateOperation, "auth/token/create")
+		req.ClientToken = root
+		req.Data = data
+		req.Data["type"] = "batch"
+		if resp, err := c.HandleRequest(req); err == nil && !resp.IsError() {
+			t.Fatalf("expected an error for %v: %#v", data, resp)
+		}
+	}
+
+	token := testMakeBatchToken(t, c, root, map[string]interface{}{
+		"policies": []string{"default"},
+	})
+
+	for _, path := range []string{"auth/token/renew-self", "auth/token/revoke-self", "auth/token/create", "cubbyhole/foo"} {
+		req := logical.TestRequest(t, logical.UpdateOperation, path)
+		req.ClientToken = token
+		req.Data["foo"] = "bar"
+		if resp, err := c.HandleRequest(req); err == nil && !resp.IsError() {
+			t.Fatalf("%s: expected an error: %#v", path, resp)
+		}
+	}
+
+	if err := c.tokenStore.Revoke(context.Background(), token); err != errBatchTokenRevoke {
+		t.Fatalf("expected an error revoking the token, got %v", err)
+	}
+}
+
+func TestTokenStore_BatchToken_ParentRevocation(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "auth/token/create")
+	req.ClientToken = root
+	req.Data["policies"] = []string{"root"}
+	resp, err := c.HandleRequest(req)
+	if err != nil || resp.IsError() {
+		t.Fatalf("err: %v resp: %#v", err, resp)
+	}
+	parent := resp.Auth.ClientToken
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/policy/leased")
+	req.ClientToken = root
+	req.Data["policy"] = `path "leased/*" { capabilities = ["read"] }`
+	if resp, err := c.HandleRequest(req); err != nil || resp.IsError() {
+		t.Fatalf("err: %v resp: %#v", err, resp)
+	}
+
+	child := testMakeBatchToken(t, c, parent, map[string]interface{}{
+		"policies": []string{"leased"},
+		"ttl":      "1h",
+	})
+	orphan := testMakeBatchToken(t, c, parent, map[string]interface{}{
+		"policies":  []string{"default"},
+		"no_parent": true,
+	})
+
+	// Leases of batch tokens belong to the parent
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/mounts/leased")
+	req.ClientToken = root
+	req.Data["type"] = "generic"
+	req.Data["options"] = map[string]string{"leased_passthrough": "true"}
+	if resp, err := c.HandleRequest(req); err != nil || resp.IsError() {
+		t.Fatalf("err: %v resp: %#v", err, resp)
+	}
+	req = logical.TestRequest(t, logical.UpdateOperation, "leased/foo")
+	req.ClientToken = root
+	req.Data["foo"] = "bar"
+	req.Data["ttl"] = "48h"
+	if resp, err := c.HandleRequest(req); err != nil || resp.IsError() {
+		t.Fatalf("err: %v resp: %#v", err, resp)
+	}
+	req = logical.TestRequest(t, logical.ReadOperation, "leased/foo")
+	req.ClientToken = child
+	resp, err = c.HandleRequest(req)
+	if err != nil || resp.IsError() {
+		t.Fatalf("err: %v resp: %#v", err, resp)
+	}
+	if resp.Secret == nil || resp.Secret.LeaseID == "" || resp.Secret.TTL > time.Hour {
+		t.Fatalf("bad: %#v", resp.Secret)
+	}
+	leaseID := resp.Secret.LeaseID
+	le, err := c.expiration.loadEntry(leaseID)
+	if err != nil || le == nil || le.ClientToken != parent {
+		t.Fatalf("expected the lease to belong to the parent: %v %#v", err, le)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "auth/token/revoke")
+	req.ClientToken = root
+	req.Data["token"] = parent
+	if resp, err := c.HandleRequest(req); err != nil || resp.IsError() {
+		t.Fatalf("err: %v resp: %#v", err, resp)
+	}
+
+	if te, err := c.tokenStore.Lookup(context.Background(), child); err != nil || te != nil {
+		t.Fatalf("expected the child to be revoked: %v %#v", err, te)
+	}
+	if te, err := c.tokenStore.Lookup(context.Background(), orphan); err != nil || te == nil {
+		t.Fatalf("expected the orphan to be valid: %v", err)
+	}
+	if le, err := c.expiration.loadEntry(leaseID); err != nil || le != nil {
+		t.Fatalf("expected the lease to be revoked: %v %#v", err, le)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "auth/token/lookup-self")
+	req.ClientToken = child
+	if _, err := c.HandleRequest(req); err == nil || !errwrap.Contains(err, logical.ErrPermissionDenied.Error()) {
+		t.Fatalf("expected permission denied, got %v", err)
+	}
+}
+
+func TestTokenStore_BatchToken_Expiration(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	te := &TokenEntry{
+		Policies:     []string{"default"},
+		Path:         "auth/token/create",
+		CreationTime: time.Now().Add(-2 * time.Hour).Unix(),
+		TTL:          time.Hour,
+		Type:         TokenTypeBatch,
+		Parent:       root,
+	}
+	if err := c.tokenStore.create(context.Background(), te); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := c.tokenStore.Lookup(context.Background(), te.ID); err != nil || out != nil {
+		t.Fatalf("expected the token to be expired: %v %#v", err, out)
+	}
+}
+
+func TestTokenStore_BatchToken_Role(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "auth/token/roles/batch")
+	req.ClientToken = root
+	req.Data["token_type"] = "batch"
+	req.Data["period"] = "1h"
+	if resp, err := c.HandleRequest(req); err == nil && !resp.IsError() {
+		t.Fatalf("expected an error for a periodic batch role: %#v", resp)
+	}
+
+	delete(req.Data, "period")
+	if resp, err := c.HandleRequest(req); err != nil || resp.IsError() {
+		t.Fatalf("err: %v resp: %#v", err, resp)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "auth/token/roles/batch")
+	req.ClientToken = root
+	resp, err := c.HandleRequest(req)
+	if err != nil || resp.IsError() {
+		t.Fatalf("err: %v resp: %#v", err, resp)
+	}
+	if resp.Data["token_type"] != TokenTypeBatch {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "auth/token/create/batch")
+	req.ClientToken = root
+	req.Data["policies"] = []string{"default"}
+	resp, err = c.HandleRequest(req)
+	if err != nil || resp.IsError() {
+		t.Fatalf("err: %v resp: %#v", err, resp)
+	}
+	if !strings.HasPrefix(resp.Auth.ClientToken, batchTokenPrefix) {
+		t.Fatalf("expected a batch token, got %q", resp.Auth.ClientToken)
+	}
+
+	// The request can still ask for a service token
+	req = logical.TestRequest(t, logical.UpdateOperation, "auth/token/create/batch")
+	req.ClientToken = root
+	req.Data["type"] = "service"
+	resp, err = c.HandleRequest(req)
+	if err != nil || resp.IsError() {
+		t.Fatalf("err: %v resp: %#v", err, resp)
+	}
+	if strings.HasPrefix(resp.Auth.ClientToken, batchTokenPrefix) || resp.Auth.Accessor == "" {
+		t.Fatalf("expected a service token: %#v", resp.Auth)
+	}
+}
+
+func TestTokenStore_BatchToken_Login(t *testing.T) {
+	noop := &NoopBackend{
+		Login: []string{"login"},
+		Response: &logical.Response{
+			Auth: &logical.Auth{
+				Policies: []string{"foo"},
+				LeaseOptions: logical.LeaseOptions{
+					TTL:       time.Hour,
+					Renewable: true,
+				},
+			},
+		},
+	}
+	c, _, root := TestCoreUnsealed(t)
+	c.credentialBackends["noop"] = func(context.Context, *logical.BackendConfig) (logical.Backend, error) {
+		return noop, nil
+	}
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "sys/auth/foo")
+	req.ClientToken = root
+	req.Data["type"] = "noop"
+	req.Data["config"] = map[string]interface{}{
+		"token_type": "batch",
+	}
+	if resp, err := c.HandleRequest(req); err != nil || resp.IsError() {
+		t.Fatalf("err: %v resp: %#v", err, resp)
+	}
+
+	resp, err := c.HandleRequest(&logical.Request{Path: "auth/foo/login"})
+	if err != nil || resp.IsError() {
+		t.Fatalf("err: %v resp: %#v", err, resp)
+	}
+	if !strings.HasPrefix(resp.Auth.ClientToken, batchTokenPrefix) || resp.Auth.Renewable {
+		t.Fatalf("expected a batch token: %#v", resp.Auth)
+	}
+
+	te, err := c.tokenStore.Lookup(context.Background(), resp.Auth.ClientToken)
+	if err != nil || te == nil {
+		t.Fatalf("err: %v", err)
+	}
+	if te.Path != "auth/foo/login" || te.Parent != "" || te.TTL != time.Hour {
+		t.Fatalf("bad: %#v", te)
+	}
+
+	// Tuning the mount back issues service tokens again
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/auth/foo/tune")
+	req.ClientToken = root
+	req.Data["token_type"] = "service"
+	if resp, err := c.HandleRequest(req); err != nil || resp.IsError() {
+		t.Fatalf("err: %v resp: %#v", err, resp)
+	}
+
+	resp, err = c.HandleRequest(&logical.Request{Path: "auth/foo/login"})
+	if err != nil || resp.IsError() {
+		t.Fatalf("err: %v resp: %#v", err, resp)
+	}
+	if strings.HasPrefix(resp.Auth.ClientToken, batchTokenPrefix) {
+		t.Fatalf("expected a service token: %#v", resp.Auth)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/auth/foo/tune")
+	req.ClientToken = root
+	req.Data["token_type"] = "bogus"
+	if resp, err := c.HandleRequest(req); err == nil && !resp.IsError() {
+		t.Fatalf("expected an error: %#v", resp)
+	}
+}
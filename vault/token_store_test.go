@@ -13,6 +13,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/hashicorp/errwrap"
 	"github.com/hashicorp/go-uuid"
 	"github.com/hashicorp/vault/helper/locksutil"
 	"github.com/hashicorp/vault/logical"
@@ -431,6 +432,33 @@ func TestTokenStore_RootToken(t *testing.T) {
 	}
 }
 
+func TestTokenStore_CountTokens(t *testing.T) {
+	_, ts, _, root := TestCoreWithTokenStore(t)
+
+	count, err := ts.countTokens(context.Background())
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("expected the root token only, got %d", count)
+	}
+
+	// The count is updated as the tokens are created and revoked
+	ent := &TokenEntry{Path: "test", Policies: []string{"dev"}}
+	if err := ts.create(context.Background(), ent); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if count, _ := ts.countTokens(context.Background()); count != 2 {
+		t.Fatalf("expected 2 tokens, got %d", count)
+	}
+	if err := ts.Revoke(context.Background(), root); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if count, _ := ts.countTokens(context.Background()); count != 1 {
+		t.Fatalf("expected 1 token, got %d", count)
+	}
+}
+
 func TestTokenStore_CreateLookup(t *testing.T) {
 	c, ts, _, _ := TestCoreWithTokenStore(t)
 
@@ -1485,6 +1513,7 @@ func TestTokenStore_HandleRequest_Lookup(t *testing.T) {
 		"creation_ttl":     int64(0),
 		"ttl":              int64(0),
 		"explicit_max_ttl": int64(0),
+		"type":             "service",
 		"expire_time":      nil,
 		"entity_id":        "",
 	}
@@ -1525,6 +1554,7 @@ func TestTokenStore_HandleRequest_Lookup(t *testing.T) {
 		"creation_ttl":     int64(3600),
 		"ttl":              int64(3600),
 		"explicit_max_ttl": int64(0),
+		"type":             "service",
 		"renewable":        true,
 		"entity_id":        "",
 	}
@@ -1576,6 +1606,7 @@ func TestTokenStore_HandleRequest_Lookup(t *testing.T) {
 		"creation_ttl":     int64(3600),
 		"ttl":              int64(3600),
 		"explicit_max_ttl": int64(0),
+		"type":             "service",
 		"renewable":        true,
 		"entity_id":        "",
 	}
@@ -1659,6 +1690,7 @@ func TestTokenStore_HandleRequest_LookupSelf(t *testing.T) {
 		"creation_ttl":     int64(3600),
 		"ttl":              int64(3600),
 		"explicit_max_ttl": int64(0),
+		"type":             "service",
 		"entity_id":        "",
 	}
 
@@ -1829,6 +1861,8 @@ func TestTokenStore_RoleCRUD(t *testing.T) {
 		"disallowed_policies": []string{},
 		"path_suffix":         "happenin",
 		"explicit_max_ttl":    int64(0),
+		"token_type":          "service",
+		"bound_cidrs":         []string(nil),
 		"renewable":           true,
 	}
 
@@ -1873,6 +1907,8 @@ func TestTokenStore_RoleCRUD(t *testing.T) {
 		"disallowed_policies": []string{},
 		"path_suffix":         "happenin",
 		"explicit_max_ttl":    int64(0),
+		"token_type":          "service",
+		"bound_cidrs":         []string(nil),
 		"renewable":           false,
 	}
 
@@ -1906,6 +1942,8 @@ func TestTokenStore_RoleCRUD(t *testing.T) {
 		"name":                "test",
 		"orphan":              true,
 		"explicit_max_ttl":    int64(5),
+		"token_type":          "service",
+		"bound_cidrs":         []string(nil),
 		"allowed_policies":    []string{"test3"},
 		"disallowed_policies": []string{},
 		"path_suffix":         "happenin",
@@ -2293,6 +2331,83 @@ func TestTokenStore_RolePathSuffix(t *testing.T) {
 	}
 }
 
+func TestTokenStore_RoleBoundCIDRs(t *testing.T) {
+	c, ts, _, root := TestCoreWithTokenStore(t)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "roles/test")
+	req.ClientToken = root
+	req.Data = map[string]interface{}{
+		"bound_cidrs": "127.0.0.1/32,not-a-cidr",
+	}
+	resp, err := ts.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error response: %v %#v", err, resp)
+	}
+
+	req.Data["bound_cidrs"] = "127.0.0.1/32,192.168.0.0/16"
+	resp, err = ts.HandleRequest(context.Background(), req)
+	if err != nil || resp != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "roles/test")
+	req.ClientToken = root
+	resp, err = ts.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	if !reflect.DeepEqual(resp.Data["bound_cidrs"], []string{"127.0.0.1/32", "192.168.0.0/16"}) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "create/test")
+	req.ClientToken = root
+	resp, err = ts.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.Auth == nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	token := resp.Auth.ClientToken
+
+	lookupSelf := func(token string, conn *logical.Connection) error {
+		req := logical.TestRequest(t, logical.ReadOperation, "auth/token/lookup-self")
+		req.ClientToken = token
+		req.Connection = conn
+		_, err := c.HandleRequest(req)
+		return err
+	}
+
+	for _, addr := range []string{"127.0.0.1", "192.168.10.20"} {
+		if err := lookupSelf(token, &logical.Connection{RemoteAddr: addr}); err != nil {
+			t.Fatalf("%s: err: %v", addr, err)
+		}
+	}
+
+	// The token cannot be used from other addresses or without a connection
+	for _, conn := range []*logical.Connection{{RemoteAddr: "10.0.0.1"}, nil} {
+		if err := lookupSelf(token, conn); err == nil || !errwrap.Contains(err, logical.ErrPermissionDenied.Error()) {
+			t.Fatalf("%#v: expected permission denied, got %v", conn, err)
+		}
+	}
+
+	// The children of the token created without a role are bound to the same
+	// CIDRs
+	req = logical.TestRequest(t, logical.UpdateOperation, "auth/token/create")
+	req.ClientToken = token
+	req.Connection = &logical.Connection{RemoteAddr: "127.0.0.1"}
+	resp, err = c.HandleRequest(req)
+	if err != nil || resp == nil || resp.Auth == nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	child := resp.Auth.ClientToken
+
+	if err := lookupSelf(child, &logical.Connection{RemoteAddr: "192.168.10.20"}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := lookupSelf(child, &logical.Connection{RemoteAddr: "10.0.0.1"}); err == nil || !errwrap.Contains(err, logical.ErrPermissionDenied.Error()) {
+		t.Fatalf("expected permission denied, got %v", err)
+	}
+}
+
 func TestTokenStore_RolePeriod(t *testing.T) {
 	core, _, _, root := TestCoreWithTokenStore(t)
 
@@ -73,7 +73,10 @@ func (c *Core) ensureWrappingKey(ctx context.Context) error {
 	return nil
 }
 
-func (c *Core) wrapInCubbyhole(ctx context.Context, req *logical.Request, resp *logical.Response, auth *logical.Auth) (*logical.Response, error) {
+// wrapInCubbyhole wraps the response in the cubbyhole of a new wrapping token.
+// If the request is gated by a control group, its state is stored along with
+// the response and the token is a control group token.
+func (c *Core) wrapInCubbyhole(ctx context.Context, req *logical.Request, resp *logical.Response, auth *logical.Auth, cgReq *controlGroupRequest) (*logical.Response, error) {
 	// Before wrapping, obey special rules for listing: if no entries are
 	// found, 404. This prevents unwrapping only to find empty data.
 	if req.Operation == logical.ListOperation {
@@ -96,6 +99,11 @@ func (c *Core) wrapInCubbyhole(ctx context.Context, req *logical.Request, resp *
 	var err error
 	sealWrap := resp.WrapInfo.SealWrap
 
+	policy := responseWrappingPolicyName
+	if cgReq != nil {
+		policy = controlGroupPolicyName
+	}
+
 	// If we are wrapping, the first part (performed in this functions) happens
 	// before auditing so that resp.WrapInfo.Token can contain the HMAC'd
 	// wrapping token ID in the audit logs, so that it can be determined from
@@ -103,7 +111,7 @@ func (c *Core) wrapInCubbyhole(ctx context.Context, req *logical.Request, resp *
 	creationTime := time.Now()
 	te := TokenEntry{
 		Path:           req.Path,
-		Policies:       []string{"response-wrapping"},
+		Policies:       []string{policy},
 		CreationTime:   creationTime.Unix(),
 		TTL:            resp.WrapInfo.TTL,
 		NumUses:        1,
@@ -239,9 +247,23 @@ func (c *Core) wrapInCubbyhole(ctx context.Context, req *logical.Request, resp *
 		return cubbyResp, nil
 	}
 
+	if cgReq != nil {
+		cubbyResp, err = c.storeControlGroupRequest(ctx, te.ID, cgReq)
+		if err != nil {
+			c.tokenStore.Revoke(ctx, te.ID)
+			c.logger.Error("core: failed to store control group request", "error", err)
+			return nil, ErrInternalError
+		}
+		if cubbyResp != nil && cubbyResp.IsError() {
+			c.tokenStore.Revoke(ctx, te.ID)
+			c.logger.Error("core: failed to store control group request", "error", cubbyResp.Data["error"])
+			return cubbyResp, nil
+		}
+	}
+
 	wAuth := &logical.Auth{
 		ClientToken: te.ID,
-		Policies:    []string{"response-wrapping"},
+		Policies:    []string{policy},
 		LeaseOptions: logical.LeaseOptions{
 			TTL:       te.TTL,
 			Renewable: false,
@@ -282,27 +304,17 @@ func (c *Core) ValidateWrappingToken(req *logical.Request) (bool, error) {
 		token = req.ClientToken
 	}
 
-	// Check for it being a JWT. If it is, and it is valid, we extract the
-	// internal client token from it and use that during lookup.
-	if strings.Count(token, ".") == 2 {
-		wt, err := jws.ParseJWT([]byte(token))
-		// If there's an error we simply fall back to attempting to use it as a regular token
-		if err == nil && wt != nil {
-			validator := &jwt.Validator{}
-			validator.SetClaim("type", "wrapping")
-			if err = wt.Validate(&c.wrappingJWTKey.PublicKey, crypto.SigningMethodES512, []*jwt.Validator{validator}...); err != nil {
-				return false, errwrap.Wrapf("wrapping token signature could not be validated: {{err}}", err)
-			}
-			token, _ = wt.Claims().JWTID()
-			// We override the given request client token so that the rest of
-			// Vault sees the real value. This also ensures audit logs are
-			// consistent with the actual token that was issued.
-			if !thirdParty {
-				req.ClientToken = token
-			} else {
-				req.Data["token"] = token
-			}
-		}
+	// We override the given request client token so that the rest of Vault
+	// sees the real value of a JWT. This also ensures audit logs are
+	// consistent with the actual token that was issued.
+	token, err = c.wrappingTokenID(token)
+	if err != nil {
+		return false, err
+	}
+	if !thirdParty {
+		req.ClientToken = token
+	} else {
+		req.Data["token"] = token
 	}
 
 	if token == "" {
@@ -318,7 +330,35 @@ func (c *Core) ValidateWrappingToken(req *logical.Request) (bool, error) {
 		return false, consts.ErrStandby
 	}
 
-	te, err := c.tokenStore.Lookup(c.activeContext, token)
+	return c.checkWrappingToken(c.activeContext, token)
+}
+
+// wrappingTokenID returns the ID of a wrapping token. If the token is a JWT,
+// and it is valid, the internal client token is extracted from it; other
+// tokens are returned as they are.
+func (c *Core) wrappingTokenID(token string) (string, error) {
+	if strings.Count(token, ".") != 2 {
+		return token, nil
+	}
+
+	wt, err := jws.ParseJWT([]byte(token))
+	// If there's an error we simply fall back to attempting to use it as a regular token
+	if err != nil || wt == nil {
+		return token, nil
+	}
+	validator := &jwt.Validator{}
+	validator.SetClaim("type", "wrapping")
+	if err = wt.Validate(&c.wrappingJWTKey.PublicKey, crypto.SigningMethodES512, []*jwt.Validator{validator}...); err != nil {
+		return "", errwrap.Wrapf("wrapping token signature could not be validated: {{err}}", err)
+	}
+	id, _ := wt.Claims().JWTID()
+	return id, nil
+}
+
+// checkWrappingToken checks whether the token is a valid wrapping token. The
+// stateLock must be held.
+func (c *Core) checkWrappingToken(ctx context.Context, token string) (bool, error) {
+	te, err := c.tokenStore.Lookup(ctx, token)
 	if err != nil {
 		return false, err
 	}